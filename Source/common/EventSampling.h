@@ -0,0 +1,31 @@
+/// Copyright 2026 North Pole Security, Inc.
+///
+/// Licensed under the Apache License, Version 2.0 (the "License");
+/// you may not use this file except in compliance with the License.
+/// You may obtain a copy of the License at
+///
+///     http://www.apache.org/licenses/LICENSE-2.0
+///
+/// Unless required by applicable law or agreed to in writing, software
+/// distributed under the License is distributed on an "AS IS" BASIS,
+/// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+/// See the License for the specific language governing permissions and
+/// limitations under the License.
+
+#ifndef SANTA_COMMON_EVENTSAMPLING_H
+#define SANTA_COMMON_EVENTSAMPLING_H
+
+#import <Foundation/Foundation.h>
+
+namespace santa {
+
+/// Returns true if an event for the given identity falls within the sample
+/// when sampling at `rate` (0.0 - 1.0). The decision is derived from a stable
+/// hash of the identity, so the same identity is always either sampled or not
+/// for a given rate, across events and restarts. A nil or empty identity is
+/// only sampled when `rate` is at least 1.0.
+bool IsEventSampled(NSString* identity, double rate);
+
+}  // namespace santa
+
+#endif  // SANTA_COMMON_EVENTSAMPLING_H
@@ -0,0 +1,41 @@
+/// Copyright 2026 North Pole Security, Inc.
+///
+/// Licensed under the Apache License, Version 2.0 (the "License");
+/// you may not use this file except in compliance with the License.
+/// You may obtain a copy of the License at
+///
+///     http://www.apache.org/licenses/LICENSE-2.0
+///
+/// Unless required by applicable law or agreed to in writing, software
+/// distributed under the License is distributed on an "AS IS" BASIS,
+/// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+/// See the License for the specific language governing permissions and
+/// limitations under the License.
+
+#include "Source/common/EventSampling.h"
+
+#include <CommonCrypto/CommonDigest.h>
+
+#include <cstdint>
+#include <cstring>
+
+namespace santa {
+
+bool IsEventSampled(NSString* identity, double rate) {
+  if (rate >= 1.0) return true;
+  if (rate <= 0.0 || identity.length == 0) return false;
+
+  // Use a cryptographic digest rather than a seeded hash so the result is
+  // stable across processes and machines.
+  const char* str = identity.UTF8String;
+  unsigned char digest[CC_SHA256_DIGEST_LENGTH];
+  CC_SHA256(str, (CC_LONG)strlen(str), digest);
+
+  uint64_t value;
+  memcpy(&value, digest, sizeof(value));
+
+  // Map the value onto [0, 1) and compare against the rate.
+  return (static_cast<double>(value >> 11) * 0x1.0p-53) < rate;
+}
+
+}  // namespace santa
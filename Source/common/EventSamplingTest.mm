@@ -0,0 +1,68 @@
+/// Copyright 2026 North Pole Security, Inc.
+///
+/// Licensed under the Apache License, Version 2.0 (the "License");
+/// you may not use this file except in compliance with the License.
+/// You may obtain a copy of the License at
+///
+///     http://www.apache.org/licenses/LICENSE-2.0
+///
+/// Unless required by applicable law or agreed to in writing, software
+/// distributed under the License is distributed on an "AS IS" BASIS,
+/// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+/// See the License for the specific language governing permissions and
+/// limitations under the License.
+
+#import <XCTest/XCTest.h>
+
+#include "Source/common/EventSampling.h"
+
+using santa::IsEventSampled;
+
+@interface EventSamplingTest : XCTestCase
+@end
+
+@implementation EventSamplingTest
+
+- (void)testBounds {
+  XCTAssertTrue(IsEventSampled(@"a", 1.0));
+  XCTAssertTrue(IsEventSampled(@"a", 1.5));
+  XCTAssertFalse(IsEventSampled(@"a", 0.0));
+  XCTAssertFalse(IsEventSampled(@"a", -1.0));
+
+  XCTAssertTrue(IsEventSampled(nil, 1.0));
+  XCTAssertFalse(IsEventSampled(nil, 0.5));
+  XCTAssertFalse(IsEventSampled(@"", 0.5));
+}
+
+- (void)testDeterministic {
+  for (int i = 0; i < 100; i++) {
+    NSString* identity = [NSString stringWithFormat:@"identity-%d", i];
+    bool first = IsEventSampled(identity, 0.5);
+    for (int j = 0; j < 5; j++) {
+      XCTAssertEqual(IsEventSampled(identity, 0.5), first);
+    }
+
+    // An identity sampled at a given rate is also sampled at every higher rate.
+    if (IsEventSampled(identity, 0.25)) {
+      XCTAssertTrue(IsEventSampled(identity, 0.5));
+      XCTAssertTrue(IsEventSampled(identity, 0.75));
+    }
+  }
+}
+
+- (void)testRateApproximatelyHonored {
+  const int kIdentities = 20000;
+  for (double rate : {0.01, 0.1, 0.25, 0.5, 0.9}) {
+    int sampled = 0;
+    for (int i = 0; i < kIdentities; i++) {
+      if (IsEventSampled([NSString stringWithFormat:@"%08x", i], rate)) {
+        sampled++;
+      }
+    }
+
+    double observed = (double)sampled / kIdentities;
+    XCTAssertEqualWithAccuracy(observed, rate, 0.02, @"Rate %f sampled %f", rate, observed);
+  }
+}
+
+@end
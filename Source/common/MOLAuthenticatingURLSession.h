@@ -14,6 +14,7 @@
 /// limitations under the License.
 
 #import <Foundation/Foundation.h>
+#include <Security/Security.h>
 
 @class MOLCertificate;
 
@@ -130,6 +131,20 @@
 */
 - (void)setServerRootsPemFile:(NSString*)serverRootsPemFile;
 
+/**  YES if server roots have been set, replacing the trusted system roots. */
+@property(readonly, nonatomic) BOOL hasServerRoots;
+
+/**
+  Evaluates a server's certificate chain the same way server trust challenges are handled: against
+  the server roots if set, otherwise the trusted system roots. This changes the anchors of the
+  passed in trust object.
+
+  @param serverTrust The trust object for the server's certificate chain.
+  @param errorMessage If the chain is not trusted, a description of why.
+  @return YES if the chain is trusted.
+*/
+- (BOOL)evaluateServerTrust:(SecTrustRef)serverTrust errorMessage:(NSString**)errorMessage;
+
 /**  Designated initializer */
 - (instancetype)initWithSessionConfiguration:(NSURLSessionConfiguration*)configuration;
 
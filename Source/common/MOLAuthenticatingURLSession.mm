@@ -113,6 +113,38 @@ using ScopedSecTrustRef = santa::ScopedCFTypeRef<SecTrustRef>;
   self.anchors = certRefs;
 }
 
+- (BOOL)hasServerRoots {
+  return self.anchors != nil;
+}
+
+#pragma mark Server Trust Evaluation
+
+- (BOOL)evaluateServerTrust:(SecTrustRef)serverTrust errorMessage:(NSString**)errorMessage {
+  if (self.anchors) {
+    // Set the anchors to be used during evaluation
+    OSStatus err = SecTrustSetAnchorCertificates(serverTrust, (__bridge CFArrayRef)self.anchors);
+    if (err != errSecSuccess) {
+      if (errorMessage) {
+        *errorMessage = [NSString stringWithFormat:@"Could not set anchor certificates: %d", err];
+      }
+      return NO;
+    }
+  }
+
+  CFErrorRef cfErrRef;
+  if (!SecTrustEvaluateWithError(serverTrust, &cfErrRef)) {
+    NSError* errRef = CFBridgingRelease(cfErrRef);
+    NSError* underlyingError = errRef.userInfo[NSUnderlyingErrorKey];
+    NSString* errMsg =
+        CFBridgingRelease(SecCopyErrorMessageString((OSStatus)underlyingError.code, NULL));
+    if (errorMessage) {
+      *errorMessage = [NSString stringWithFormat:@"%@ (%ld)", errMsg, (long)underlyingError.code];
+    }
+    return NO;
+  }
+  return YES;
+}
+
 #pragma mark NSURLSessionDelegate methods
 
 - (void)URLSession:(NSURLSession*)session
@@ -359,18 +391,6 @@ using ScopedSecTrustRef = santa::ScopedCFTypeRef<SecTrustRef>;
     return nil;
   }
 
-  OSStatus err = errSecSuccess;
-
-  if (self.anchors) {
-    // Set the anchors to be used during evaluation
-    err = SecTrustSetAnchorCertificates(protectionSpace.serverTrust,
-                                        (__bridge CFArrayRef)self.anchors);
-    if (err != errSecSuccess) {
-      [self log:@"[Server Trust] Could not set anchor certificates: %d", err];
-      return nil;
-    }
-  }
-
   // Print details about the server's leaf certificate.
   NSArray* certChain = CFBridgingRelease(SecTrustCopyCertificateChain(protectionSpace.serverTrust));
   if (certChain.firstObject) {
@@ -380,14 +400,9 @@ using ScopedSecTrustRef = santa::ScopedCFTypeRef<SecTrustRef>;
   }
 
   // Evaluate the server's cert chain.
-  CFErrorRef cfErrRef;
-  if (!SecTrustEvaluateWithError(protectionSpace.serverTrust, &cfErrRef)) {
-    NSError* errRef = CFBridgingRelease(cfErrRef);
-    NSError* underlyingError = errRef.userInfo[NSUnderlyingErrorKey];
-    NSString* errMsg =
-        CFBridgingRelease(SecCopyErrorMessageString((OSStatus)underlyingError.code, NULL));
-    [self log:@"[Server Trust] Unable to evaluate certificate chain for server: %@ (%d)", errMsg,
-              underlyingError.code];
+  NSString* errMsg;
+  if (![self evaluateServerTrust:protectionSpace.serverTrust errorMessage:&errMsg]) {
+    [self log:@"[Server Trust] Unable to evaluate certificate chain for server: %@", errMsg];
     return nil;
   }
 
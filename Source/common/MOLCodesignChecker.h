@@ -37,6 +37,13 @@
 */
 @property(readonly, nonatomic) SecRequirementRef requirement;
 
+/**
+  The designated requirement for this binary in its text form, e.g.
+  `identifier "com.example.app" and anchor apple generic`. This is the form used by
+  `REQUIREMENT` rules.
+*/
+@property(readonly, nonatomic) NSString* designatedRequirement;
+
 /**
   A dictionary of raw signing information provided by the Security framework.
 */
@@ -267,6 +267,16 @@ NSString* const kMOLCodesignCheckerErrorDomain = @"com.northpolesec.santa.molcod
   return _requirement;
 }
 
+- (NSString*)designatedRequirement {
+  SecRequirementRef requirement = self.requirement;
+  if (!requirement) return nil;
+  CFStringRef text = NULL;
+  if (SecRequirementCopyString(requirement, kSecCSDefaultFlags, &text) != errSecSuccess) {
+    return nil;
+  }
+  return CFBridgingRelease(text);
+}
+
 - (MOLCertificate*)leafCertificate {
   return [self.certificates firstObject];
 }
@@ -121,6 +121,20 @@
   XCTAssertFalse([sut1 validateWithRequirement:sut2.requirement]);
 }
 
+- (void)testDesignatedRequirement {
+  MOLCodesignChecker* sut = [[MOLCodesignChecker alloc] initWithBinaryPath:@"/sbin/launchd"];
+  XCTAssertEqualObjects(sut.designatedRequirement, @"identifier \"com.apple.xpc.launchd\" and "
+                                                   @"anchor apple");
+
+  // The text form round-trips into a requirement the binary satisfies.
+  SecRequirementRef requirement = NULL;
+  XCTAssertEqual(SecRequirementCreateWithString((__bridge CFStringRef)sut.designatedRequirement,
+                                                kSecCSDefaultFlags, &requirement),
+                 errSecSuccess);
+  XCTAssertTrue([sut validateWithRequirement:requirement]);
+  CFRelease(requirement);
+}
+
 - (void)testInitWithFileDescriptor {
   NSString* path = @"/usr/bin/yes";
   int fd = open(path.UTF8String, O_RDONLY | O_CLOEXEC);
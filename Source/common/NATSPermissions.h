@@ -0,0 +1,63 @@
+/// Copyright 2026 North Pole Security, Inc.
+///
+/// Licensed under the Apache License, Version 2.0 (the "License");
+/// you may not use this file except in compliance with the License.
+/// You may obtain a copy of the License at
+///
+///     http://www.apache.org/licenses/LICENSE-2.0
+///
+/// Unless required by applicable law or agreed to in writing, software
+/// distributed under the License is distributed on an "AS IS" BASIS,
+/// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+/// See the License for the specific language governing permissions and
+/// limitations under the License.
+
+#ifndef SANTA_COMMON_NATSPERMISSIONS_H
+#define SANTA_COMMON_NATSPERMISSIONS_H
+
+#import <Foundation/Foundation.h>
+
+#include <optional>
+#include <string>
+#include <string_view>
+#include <vector>
+
+namespace santa {
+
+// The allow and deny subject lists for either publish or subscribe.
+struct NATSPermissionList {
+  std::vector<std::string> allow;
+  std::vector<std::string> deny;
+};
+
+// The publish and subscribe permissions carried in a NATS user JWT
+// ("nats.pub" and "nats.sub" claims).
+struct NATSPermissions {
+  NATSPermissionList pub;
+  NATSPermissionList sub;
+};
+
+enum class NATSPermissionResult {
+  kAllowed,
+  // The allow list is non-empty and no entry matches the subject.
+  kNotAllowed,
+  // An entry in the deny list matches the subject.
+  kDenied,
+};
+
+// Extract the permissions from a NATS user JWT. The JWT signature is NOT
+// verified; use NKeyTokenValidator for that. Returns std::nullopt if the JWT
+// cannot be parsed.
+std::optional<NATSPermissions> NATSPermissionsFromJWT(NSString* jwt);
+
+// Returns true if `subject` matches `pattern` using NATS wildcard semantics:
+// "*" matches exactly one token and a trailing ">" matches one or more tokens.
+bool NATSSubjectMatches(std::string_view pattern, std::string_view subject);
+
+// Evaluate a subject against a permission list. Deny entries take precedence
+// over allow entries, and an empty allow list permits every subject.
+NATSPermissionResult CheckNATSPermission(const NATSPermissionList& list, std::string_view subject);
+
+}  // namespace santa
+
+#endif  // SANTA_COMMON_NATSPERMISSIONS_H
@@ -0,0 +1,131 @@
+/// Copyright 2026 North Pole Security, Inc.
+///
+/// Licensed under the Apache License, Version 2.0 (the "License");
+/// you may not use this file except in compliance with the License.
+/// You may obtain a copy of the License at
+///
+///     http://www.apache.org/licenses/LICENSE-2.0
+///
+/// Unless required by applicable law or agreed to in writing, software
+/// distributed under the License is distributed on an "AS IS" BASIS,
+/// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+/// See the License for the specific language governing permissions and
+/// limitations under the License.
+
+#include "Source/common/NATSPermissions.h"
+
+#include "Source/common/NKeyTokenValidator.h"
+#import "Source/common/String.h"
+
+namespace santa {
+
+namespace {
+
+std::vector<std::string> SubjectList(id value) {
+  std::vector<std::string> subjects;
+  if (![value isKindOfClass:[NSArray class]]) {
+    return subjects;
+  }
+
+  for (id subject in (NSArray*)value) {
+    if ([subject isKindOfClass:[NSString class]]) {
+      subjects.push_back(NSStringToUTF8String(subject));
+    }
+  }
+  return subjects;
+}
+
+NATSPermissionList PermissionList(id value) {
+  NATSPermissionList list;
+  if ([value isKindOfClass:[NSDictionary class]]) {
+    list.allow = SubjectList(value[@"allow"]);
+    list.deny = SubjectList(value[@"deny"]);
+  }
+  return list;
+}
+
+std::vector<std::string_view> Tokenize(std::string_view subject) {
+  std::vector<std::string_view> tokens;
+  size_t start = 0;
+  while (true) {
+    size_t end = subject.find('.', start);
+    if (end == std::string_view::npos) {
+      tokens.push_back(subject.substr(start));
+      break;
+    }
+    tokens.push_back(subject.substr(start, end - start));
+    start = end + 1;
+  }
+  return tokens;
+}
+
+}  // namespace
+
+std::optional<NATSPermissions> NATSPermissionsFromJWT(NSString* jwt) {
+  if (!jwt.length) {
+    return std::nullopt;
+  }
+
+  NSDictionary* payload = ParseJWTPayload(NSStringToUTF8StringView(jwt));
+  if (!payload) {
+    return std::nullopt;
+  }
+
+  NSDictionary* nats = payload[@"nats"];
+  if (nats && ![nats isKindOfClass:[NSDictionary class]]) {
+    return std::nullopt;
+  }
+
+  return NATSPermissions{
+      .pub = PermissionList(nats[@"pub"]),
+      .sub = PermissionList(nats[@"sub"]),
+  };
+}
+
+bool NATSSubjectMatches(std::string_view pattern, std::string_view subject) {
+  if (pattern.empty() || subject.empty()) {
+    return false;
+  }
+
+  std::vector<std::string_view> patternTokens = Tokenize(pattern);
+  std::vector<std::string_view> subjectTokens = Tokenize(subject);
+
+  for (size_t i = 0; i < patternTokens.size(); i++) {
+    if (patternTokens[i] == ">") {
+      // Full wildcard must be the last token and match at least one token.
+      return i == patternTokens.size() - 1 && subjectTokens.size() > i;
+    }
+
+    if (i >= subjectTokens.size()) {
+      return false;
+    }
+
+    if (patternTokens[i] != "*" && patternTokens[i] != subjectTokens[i]) {
+      return false;
+    }
+  }
+
+  return patternTokens.size() == subjectTokens.size();
+}
+
+NATSPermissionResult CheckNATSPermission(const NATSPermissionList& list, std::string_view subject) {
+  for (const auto& pattern : list.deny) {
+    if (NATSSubjectMatches(pattern, subject)) {
+      return NATSPermissionResult::kDenied;
+    }
+  }
+
+  if (list.allow.empty()) {
+    return NATSPermissionResult::kAllowed;
+  }
+
+  for (const auto& pattern : list.allow) {
+    if (NATSSubjectMatches(pattern, subject)) {
+      return NATSPermissionResult::kAllowed;
+    }
+  }
+
+  return NATSPermissionResult::kNotAllowed;
+}
+
+}  // namespace santa
@@ -0,0 +1,143 @@
+/// Copyright 2026 North Pole Security, Inc.
+///
+/// Licensed under the Apache License, Version 2.0 (the "License");
+/// you may not use this file except in compliance with the License.
+/// You may obtain a copy of the License at
+///
+///     http://www.apache.org/licenses/LICENSE-2.0
+///
+/// Unless required by applicable law or agreed to in writing, software
+/// distributed under the License is distributed on an "AS IS" BASIS,
+/// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+/// See the License for the specific language governing permissions and
+/// limitations under the License.
+
+#import <XCTest/XCTest.h>
+
+#include "Source/common/NATSPermissions.h"
+
+using santa::CheckNATSPermission;
+using santa::NATSPermissionList;
+using santa::NATSPermissionResult;
+using santa::NATSPermissions;
+using santa::NATSPermissionsFromJWT;
+using santa::NATSSubjectMatches;
+
+static NSString *Base64URLEncode(NSData *data) {
+  NSString *b64 = [data base64EncodedStringWithOptions:0];
+  b64 = [b64 stringByReplacingOccurrencesOfString:@"+" withString:@"-"];
+  b64 = [b64 stringByReplacingOccurrencesOfString:@"/" withString:@"_"];
+  return [b64 stringByReplacingOccurrencesOfString:@"=" withString:@""];
+}
+
+// Build an unsigned JWT with the given claims. The signature segment is
+// garbage, which is fine since permissions are read without verification.
+static NSString *JWTWithClaims(NSDictionary *claims) {
+  NSData *header = [@"{\"typ\":\"JWT\",\"alg\":\"ed25519-nkey\"}"
+      dataUsingEncoding:NSUTF8StringEncoding];
+  NSData *payload = [NSJSONSerialization dataWithJSONObject:claims options:0 error:nil];
+  return [NSString
+      stringWithFormat:@"%@.%@.c2lnbmF0dXJl", Base64URLEncode(header), Base64URLEncode(payload)];
+}
+
+@interface NATSPermissionsTest : XCTestCase
+@end
+
+@implementation NATSPermissionsTest
+
+- (void)testSubjectMatches {
+  XCTAssertTrue(NATSSubjectMatches("santa.host.abc.commands", "santa.host.abc.commands"));
+  XCTAssertFalse(NATSSubjectMatches("santa.host.abc.commands", "santa.host.xyz.commands"));
+
+  XCTAssertTrue(NATSSubjectMatches("santa.host.*.commands", "santa.host.abc.commands"));
+  XCTAssertFalse(NATSSubjectMatches("santa.host.*.commands", "santa.host.abc.def.commands"));
+  XCTAssertFalse(NATSSubjectMatches("santa.host.*", "santa.host"));
+
+  XCTAssertTrue(NATSSubjectMatches("santa.>", "santa.tag.global"));
+  XCTAssertTrue(NATSSubjectMatches("santa.>", "santa.host.abc.commands"));
+  XCTAssertFalse(NATSSubjectMatches("santa.>", "santa"));
+  XCTAssertTrue(NATSSubjectMatches(">", "anything"));
+
+  // A ">" that isn't the final token does not act as a wildcard.
+  XCTAssertFalse(NATSSubjectMatches("santa.>.commands", "santa.host.commands"));
+
+  XCTAssertFalse(NATSSubjectMatches("santa.tag", "santa.tag.global"));
+  XCTAssertFalse(NATSSubjectMatches("", "santa"));
+  XCTAssertFalse(NATSSubjectMatches("santa", ""));
+}
+
+- (void)testCheckPermission {
+  NATSPermissionList empty;
+  XCTAssertEqual(CheckNATSPermission(empty, "santa.tag.global"), NATSPermissionResult::kAllowed);
+
+  NATSPermissionList list{
+      .allow = {"santa.host.abc.commands", "santa.tag.*"},
+      .deny = {"santa.tag.secret"},
+  };
+  XCTAssertEqual(CheckNATSPermission(list, "santa.host.abc.commands"),
+                 NATSPermissionResult::kAllowed);
+  XCTAssertEqual(CheckNATSPermission(list, "santa.tag.global"), NATSPermissionResult::kAllowed);
+  XCTAssertEqual(CheckNATSPermission(list, "santa.host.other.commands"),
+                 NATSPermissionResult::kNotAllowed);
+  XCTAssertEqual(CheckNATSPermission(list, "santa.tag.secret"), NATSPermissionResult::kDenied);
+
+  // Deny applies even when the allow list is empty.
+  NATSPermissionList denyOnly{.deny = {"santa.>"}};
+  XCTAssertEqual(CheckNATSPermission(denyOnly, "santa.tag.global"), NATSPermissionResult::kDenied);
+  XCTAssertEqual(CheckNATSPermission(denyOnly, "other.subject"), NATSPermissionResult::kAllowed);
+}
+
+- (void)testPermissionsFromJWT {
+  NSString *jwt = JWTWithClaims(@{
+    @"sub" : @"UTESTUSER",
+    @"nats" : @{
+      @"pub" : @{@"allow" : @[ @"_INBOX.>" ]},
+      @"sub" : @{
+        @"allow" : @[ @"santa.host.abc.commands", @"santa.tag.*" ],
+        @"deny" : @[ @"santa.tag.restricted" ],
+      },
+      @"type" : @"user",
+    },
+  });
+
+  std::optional<NATSPermissions> perms = NATSPermissionsFromJWT(jwt);
+  XCTAssertTrue(perms.has_value());
+  XCTAssertEqual(perms->pub.allow.size(), 1);
+  XCTAssertEqual(perms->pub.deny.size(), 0);
+  XCTAssertEqual(perms->sub.allow.size(), 2);
+  XCTAssertEqual(perms->sub.deny.size(), 1);
+
+  XCTAssertEqual(CheckNATSPermission(perms->sub, "santa.host.abc.commands"),
+                 NATSPermissionResult::kAllowed);
+  XCTAssertEqual(CheckNATSPermission(perms->sub, "santa.tag.global"),
+                 NATSPermissionResult::kAllowed);
+
+  // Forbidden subjects must be flagged.
+  XCTAssertEqual(CheckNATSPermission(perms->sub, "santa.host.other.commands"),
+                 NATSPermissionResult::kNotAllowed);
+  XCTAssertEqual(CheckNATSPermission(perms->sub, "santa.tag.restricted"),
+                 NATSPermissionResult::kDenied);
+  XCTAssertEqual(CheckNATSPermission(perms->pub, "santa.host.abc.commands"),
+                 NATSPermissionResult::kNotAllowed);
+}
+
+- (void)testPermissionsFromJWTWithoutPermissions {
+  std::optional<NATSPermissions> perms =
+      NATSPermissionsFromJWT(JWTWithClaims(@{@"sub" : @"UTESTUSER", @"nats" : @{}}));
+  XCTAssertTrue(perms.has_value());
+  XCTAssertTrue(perms->pub.allow.empty());
+  XCTAssertTrue(perms->sub.allow.empty());
+  XCTAssertEqual(CheckNATSPermission(perms->sub, "santa.tag.global"),
+                 NATSPermissionResult::kAllowed);
+}
+
+- (void)testPermissionsFromMalformedJWT {
+  XCTAssertFalse(NATSPermissionsFromJWT(nil).has_value());
+  XCTAssertFalse(NATSPermissionsFromJWT(@"").has_value());
+  XCTAssertFalse(NATSPermissionsFromJWT(@"not-a-jwt").has_value());
+  XCTAssertFalse(NATSPermissionsFromJWT(@"a.!!!.c").has_value());
+  XCTAssertFalse(
+      NATSPermissionsFromJWT(JWTWithClaims(@{@"nats" : @"not-a-dictionary"})).has_value());
+}
+
+@end
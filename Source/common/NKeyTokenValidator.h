@@ -17,11 +17,33 @@
 
 #import <Foundation/Foundation.h>
 
+#include <optional>
 #include <set>
 #include <string>
+#include <string_view>
+#include <vector>
 
 namespace santa {
 
+// Decode the payload (claims) of a JWT without verifying its signature.
+// Returns nil if the JWT is malformed or the payload is not a JSON object.
+NSDictionary* ParseJWTPayload(std::string_view jwt);
+
+// Returns true if the JWT is well formed and its signature was made by the
+// given 32-byte Ed25519 public key. Claims, including expiry, are not checked.
+bool VerifyJWTSignature(std::string_view jwt, const std::vector<uint8_t>& ed25519Pubkey);
+
+// Decode a NATS NKey public key (e.g. "U...") into its 32-byte Ed25519 public key.
+// Returns nullopt if the key is malformed or its checksum doesn't match.
+std::optional<std::vector<uint8_t>> NKeyDecode(const std::string& nkey);
+
+// Sign the nonce a NATS server sends when a client connects with the private key in
+// an NKey seed ("SU..."), producing the 64-byte Ed25519 signature the server
+// verifies against the user's public key. The decoded seed and private key are
+// zeroed before returning. Returns nullopt if the seed is malformed.
+std::optional<std::vector<uint8_t>> SignNonceWithNKeySeed(std::string_view seed,
+                                                          std::string_view nonce);
+
 // Validates the full token chain: user JWT -> account JWT -> trusted root keys.
 //
 // Validation checks:
@@ -15,10 +15,12 @@
 #include "Source/common/NKeyTokenValidator.h"
 
 #include <dispatch/dispatch.h>
+#include <cstring>
 #include <ctime>
 #include <optional>
 
 #include <openssl/curve25519.h>
+#include <openssl/mem.h>
 
 #import "Source/common/SNTLogging.h"
 #import "Source/common/String.h"
@@ -85,6 +87,51 @@ void EnsureBase32Initialized() {
   });
 }
 
+// Decode an NKey seed into its 32-byte Ed25519 seed. nats.c's _decodeSeed is
+// internal to nkeys.c, so this follows DecodeSeed in the Go reference
+// implementation (strkey.go) instead: the first 12 bits are the seed prefix 'S'
+// and the public key type, then come the 32 bytes of the seed and a
+// little-endian CRC16 over everything before it.
+bool NKeySeedDecode(std::string_view seed, uint8_t out[32]) {
+  EnsureBase32Initialized();
+
+  std::string encoded(seed);
+  char raw[64];
+  int rawLen = 0;
+  bool ok = nats_Base32_DecodeString(encoded.c_str(), raw, sizeof(raw), &rawLen) == NATS_OK &&
+            rawLen == 36;
+
+  auto* data = reinterpret_cast<unsigned char*>(raw);
+  if (ok) {
+    uint16_t expected = static_cast<uint16_t>(data[34]) | (static_cast<uint16_t>(data[35]) << 8);
+    // 18 << 3 is the seed prefix byte in the top 5 bits.
+    ok = nats_CRC16_Validate(data, 34, expected) && (data[0] & 0xF8) == (18 << 3);
+  }
+  if (ok) {
+    memcpy(out, data + 2, 32);
+  }
+
+  OPENSSL_cleanse(raw, sizeof(raw));
+  OPENSSL_cleanse(encoded.data(), encoded.size());
+  return ok;
+}
+
+bool SplitJWT(std::string_view jwt, JWTParts& parts) {
+  auto p1 = jwt.find('.');
+  if (p1 == std::string_view::npos) return false;
+  auto p2 = jwt.find('.', p1 + 1);
+  if (p2 == std::string_view::npos) return false;
+  // Ensure no additional dots
+  if (jwt.find('.', p2 + 1) != std::string_view::npos) return false;
+
+  parts.header = jwt.substr(0, p1);
+  parts.payload = jwt.substr(p1 + 1, p2 - p1 - 1);
+  parts.signature = jwt.substr(p2 + 1);
+  return true;
+}
+
+}  // namespace
+
 // Decode a NATS NKey (public key) into its 32-byte Ed25519 public key.
 // The nats.c library only exposes seed decoding (_decodeSeed in nkeys.c) which
 // rejects public key prefixes, so we implement public key decoding ourselves.
@@ -112,18 +159,27 @@ std::optional<std::vector<uint8_t>> NKeyDecode(const std::string& nkey) {
   return std::vector<uint8_t>(data + 1, data + 33);
 }
 
-bool SplitJWT(std::string_view jwt, JWTParts& parts) {
-  auto p1 = jwt.find('.');
-  if (p1 == std::string_view::npos) return false;
-  auto p2 = jwt.find('.', p1 + 1);
-  if (p2 == std::string_view::npos) return false;
-  // Ensure no additional dots
-  if (jwt.find('.', p2 + 1) != std::string_view::npos) return false;
+std::optional<std::vector<uint8_t>> SignNonceWithNKeySeed(std::string_view seed,
+                                                          std::string_view nonce) {
+  uint8_t rawSeed[32];
+  if (!NKeySeedDecode(seed, rawSeed)) {
+    return std::nullopt;
+  }
 
-  parts.header = jwt.substr(0, p1);
-  parts.payload = jwt.substr(p1 + 1, p2 - p1 - 1);
-  parts.signature = jwt.substr(p2 + 1);
-  return true;
+  uint8_t publicKey[32];
+  uint8_t privateKey[64];
+  ED25519_keypair_from_seed(publicKey, privateKey, rawSeed);
+
+  std::vector<uint8_t> signature(64);
+  int ok = ED25519_sign(signature.data(), reinterpret_cast<const uint8_t*>(nonce.data()),
+                        nonce.size(), privateKey);
+
+  OPENSSL_cleanse(rawSeed, sizeof(rawSeed));
+  OPENSSL_cleanse(privateKey, sizeof(privateKey));
+  if (ok != 1) {
+    return std::nullopt;
+  }
+  return signature;
 }
 
 bool VerifyJWTSignature(std::string_view jwt, const std::vector<uint8_t>& ed25519Pubkey) {
@@ -176,8 +232,6 @@ NSDictionary* ParseJWTPayload(std::string_view jwt) {
   return payload;
 }
 
-}  // namespace
-
 bool NKeyTokenValidator::Validate() {
   if (!accountJWT_.length || !userJWT_.length) {
     return false;
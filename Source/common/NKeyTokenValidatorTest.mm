@@ -13,6 +13,7 @@
 /// limitations under the License.
 
 #import <XCTest/XCTest.h>
+#include <openssl/curve25519.h>
 
 #include "Source/common/NKeyTokenValidator.h"
 
@@ -22,6 +23,12 @@ static const std::string kTestOperatorNKey =
 
 static const std::set<std::string> kTrustedNKeys = {kTestOperatorNKey};
 
+// Test-only user NKey seed and the public key derived from it
+static const std::string kTestUserSeed =
+    "SUACBNSCZDJFQNXSNUMNMPHN7UY5AWS42E6VMQXVTKCU2KJYBR75MVDPJQ";
+static const std::string kTestUserNKey =
+    "UCN7Y45W5KNA7WMYMWRAUQJDCHENCZ77PRYSB2HXHCMPTA6PWEVLUTMO";
+
 // clang-format off
 // Account JWT signed by test operator
 //   iss = ODRCTBREJ7SHU24F5FECLYEVXGFYNM3KBRMDD7PFNL6CUQ6BORUIGY47
@@ -203,4 +210,44 @@ static NSString *const kUntrustedUserJWT =
   XCTAssertFalse(santa::NKeyTokenValidator({}, kValidAccountJWT, kValidUserJWT).Validate());
 }
 
+#pragma mark - SignNonceWithNKeySeed Tests
+
+- (void)testSignNonceWithNKeySeed {
+  std::string nonce = "test-nonce-1234";
+  auto signature = santa::SignNonceWithNKeySeed(kTestUserSeed, nonce);
+  XCTAssertTrue(signature.has_value());
+  XCTAssertEqual(signature->size(), 64);
+
+  // Ed25519 signatures are deterministic.
+  NSData* expected = [[NSData alloc]
+      initWithBase64EncodedString:@"BCov446hox9RpaV6NqOrlyzDiLQXAYnOallBHbiLSJy5aZoQj4Pa1GuM3Kcn9GR"
+                                  @"hu2ZGu2k8pOB6FQDJPcMICA=="
+                          options:0];
+  XCTAssertEqualObjects([NSData dataWithBytes:signature->data() length:signature->size()],
+                        expected);
+
+  auto publicKey = santa::NKeyDecode(kTestUserNKey);
+  XCTAssertTrue(publicKey.has_value());
+  XCTAssertEqual(ED25519_verify(reinterpret_cast<const uint8_t*>(nonce.data()), nonce.size(),
+                                signature->data(), publicKey->data()),
+                 1);
+
+  // The signature covers the nonce.
+  std::string otherNonce = "test-nonce-1235";
+  XCTAssertEqual(ED25519_verify(reinterpret_cast<const uint8_t*>(otherNonce.data()),
+                                otherNonce.size(), signature->data(), publicKey->data()),
+                 0);
+}
+
+- (void)testSignNonceWithMalformedSeed {
+  // A public key isn't a seed.
+  XCTAssertFalse(santa::SignNonceWithNKeySeed(kTestUserNKey, "nonce").has_value());
+  // One changed character breaks the checksum.
+  XCTAssertFalse(santa::SignNonceWithNKeySeed(
+                     "SUACBNSCZDJFQNXSNUMNAPHN7UY5AWS42E6VMQXVTKCU2KJYBR75MVDPJQ", "nonce")
+                     .has_value());
+  XCTAssertFalse(santa::SignNonceWithNKeySeed("", "nonce").has_value());
+  XCTAssertFalse(santa::SignNonceWithNKeySeed("not a seed", "nonce").has_value());
+}
+
 @end
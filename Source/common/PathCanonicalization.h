@@ -0,0 +1,39 @@
+/// Copyright 2026 North Pole Security, Inc.
+///
+/// Licensed under the Apache License, Version 2.0 (the "License");
+/// you may not use this file except in compliance with the License.
+/// You may obtain a copy of the License at
+///
+///     http://www.apache.org/licenses/LICENSE-2.0
+///
+/// Unless required by applicable law or agreed to in writing, software
+/// distributed under the License is distributed on an "AS IS" BASIS,
+/// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+/// See the License for the specific language governing permissions and
+/// limitations under the License.
+
+
+#ifndef SANTA_COMMON_PATHCANONICALIZATION_H
+#define SANTA_COMMON_PATHCANONICALIZATION_H
+
+#import <Foundation/Foundation.h>
+
+namespace santa {
+
+/// Returns the original location of a translocated path, or nil if the path
+/// is not translocated or the original location cannot be determined.
+NSString* OriginalPathForTranslocatedPath(NSString* path);
+
+/// Returns the canonical form of `path`: a translocated path is first resolved
+/// back to its original location, then all symlinks are followed to the real
+/// target. If the path cannot be resolved (e.g. it no longer exists) the
+/// translocation-resolved path, or `path` itself, is returned unchanged.
+NSString* CanonicalPath(NSString* path);
+
+/// Returns true if `re` matches `path`, or, when `resolvedPath` is non-nil and
+/// differs from `path`, if `re` matches `resolvedPath`.
+bool PathOrResolvedPathMatches(NSRegularExpression* re, NSString* path, NSString* resolvedPath);
+
+}  // namespace santa
+
+#endif  // SANTA_COMMON_PATHCANONICALIZATION_H
@@ -0,0 +1,80 @@
+/// Copyright 2026 North Pole Security, Inc.
+///
+/// Licensed under the Apache License, Version 2.0 (the "License");
+/// you may not use this file except in compliance with the License.
+/// You may obtain a copy of the License at
+///
+///     http://www.apache.org/licenses/LICENSE-2.0
+///
+/// Unless required by applicable law or agreed to in writing, software
+/// distributed under the License is distributed on an "AS IS" BASIS,
+/// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+/// See the License for the specific language governing permissions and
+/// limitations under the License.
+
+
+#include "Source/common/PathCanonicalization.h"
+
+#include <limits.h>
+#include <stdlib.h>
+
+// These functions are exported by the Security framework, but are not included in headers
+extern "C" Boolean SecTranslocateIsTranslocatedURL(CFURLRef path, bool* isTranslocated,
+                                                   CFErrorRef* __nullable error);
+extern "C" CFURLRef __nullable SecTranslocateCreateOriginalPathForURL(CFURLRef translocatedPath,
+                                                                      CFErrorRef* __nullable error);
+
+namespace santa {
+
+NSString* OriginalPathForTranslocatedPath(NSString* path) {
+  if (!path.length) {
+    return nil;
+  }
+
+  CFURLRef cfURL = (__bridge CFURLRef)[NSURL fileURLWithPath:path];
+  bool isTranslocated = false;
+  if (!SecTranslocateIsTranslocatedURL(cfURL, &isTranslocated, NULL) || !isTranslocated) {
+    return nil;
+  }
+
+  NSURL* origURL = CFBridgingRelease(SecTranslocateCreateOriginalPathForURL(cfURL, NULL));
+  return [origURL path];
+}
+
+NSString* CanonicalPath(NSString* path) {
+  if (!path.length) {
+    return path;
+  }
+
+  NSString* resolved = OriginalPathForTranslocatedPath(path) ?: path;
+
+  char buf[PATH_MAX];
+  if (realpath(resolved.fileSystemRepresentation, buf)) {
+    return @(buf);
+  }
+
+  return resolved;
+}
+
+static bool RegexMatches(NSRegularExpression* re, NSString* path) {
+  return path.length &&
+         [re rangeOfFirstMatchInString:path options:0 range:NSMakeRange(0, path.length)]
+                 .location != NSNotFound;
+}
+
+bool PathOrResolvedPathMatches(NSRegularExpression* re, NSString* path, NSString* resolvedPath) {
+  // rangeOfFirstMatchInString: returns a zeroed NSRange (location 0, not
+  // NSNotFound) when messaged on a nil regex, so the nil case must be handled
+  // explicitly.
+  if (!re) {
+    return false;
+  }
+
+  if (RegexMatches(re, path)) {
+    return true;
+  }
+
+  return resolvedPath && ![resolvedPath isEqualToString:path] && RegexMatches(re, resolvedPath);
+}
+
+}  // namespace santa
@@ -0,0 +1,147 @@
+/// Copyright 2026 North Pole Security, Inc.
+///
+/// Licensed under the Apache License, Version 2.0 (the "License");
+/// you may not use this file except in compliance with the License.
+/// You may obtain a copy of the License at
+///
+///     http://www.apache.org/licenses/LICENSE-2.0
+///
+/// Unless required by applicable law or agreed to in writing, software
+/// distributed under the License is distributed on an "AS IS" BASIS,
+/// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+/// See the License for the specific language governing permissions and
+/// limitations under the License.
+
+
+#import <XCTest/XCTest.h>
+
+#include <stdlib.h>
+#include <sys/xattr.h>
+
+#include "Source/common/PathCanonicalization.h"
+
+using santa::CanonicalPath;
+using santa::OriginalPathForTranslocatedPath;
+using santa::PathOrResolvedPathMatches;
+
+// Exported by the Security framework, but not included in headers.
+extern "C" CFURLRef __nullable SecTranslocateCreateSecureDirectoryForURL(
+    CFURLRef pathToTranslocate, CFURLRef __nullable destinationPath, CFErrorRef* __nullable error);
+extern "C" Boolean SecTranslocateDeleteSecureDirectory(CFURLRef translocatedPath,
+                                                       CFErrorRef* __nullable error);
+
+@interface PathCanonicalizationTest : XCTestCase
+@property NSString* tempDir;
+@end
+
+@implementation PathCanonicalizationTest
+
+- (void)setUp {
+  [super setUp];
+  NSString* dir =
+      [NSTemporaryDirectory() stringByAppendingPathComponent:[[NSUUID UUID] UUIDString]];
+  XCTAssertTrue([[NSFileManager defaultManager] createDirectoryAtPath:dir
+                                          withIntermediateDirectories:YES
+                                                           attributes:nil
+                                                                error:NULL]);
+  // NSTemporaryDirectory() is itself behind the /var -> /private/var symlink.
+  char buf[PATH_MAX];
+  XCTAssertNotEqual(realpath(dir.fileSystemRepresentation, buf), (char*)NULL);
+  self.tempDir = @(buf);
+}
+
+- (void)tearDown {
+  [[NSFileManager defaultManager] removeItemAtPath:self.tempDir error:NULL];
+  [super tearDown];
+}
+
+// Creates a minimal, quarantined app bundle whose executable is a copy of /bin/ls.
+- (NSString*)createAppFixture {
+  NSFileManager* fm = [NSFileManager defaultManager];
+  NSString* app = [self.tempDir stringByAppendingPathComponent:@"Fixture.app"];
+  NSString* macOS = [app stringByAppendingPathComponent:@"Contents/MacOS"];
+  XCTAssertTrue([fm createDirectoryAtPath:macOS
+              withIntermediateDirectories:YES
+                               attributes:nil
+                                    error:NULL]);
+  XCTAssertTrue([fm copyItemAtPath:@"/bin/ls"
+                            toPath:[macOS stringByAppendingPathComponent:@"Fixture"]
+                             error:NULL]);
+  NSDictionary* info = @{
+    @"CFBundleExecutable" : @"Fixture",
+    @"CFBundleIdentifier" : @"com.northpolesec.santa.PathCanonicalizationTest",
+  };
+  XCTAssertTrue([info writeToFile:[app stringByAppendingPathComponent:@"Contents/Info.plist"]
+                       atomically:YES]);
+
+  const char* quarantine = "0081;00000000;PathCanonicalizationTest;";
+  setxattr(app.fileSystemRepresentation, "com.apple.quarantine", quarantine, strlen(quarantine), 0,
+           0);
+  return app;
+}
+
+- (void)testCanonicalPathFollowsSymlinks {
+  NSString* link = [self.tempDir stringByAppendingPathComponent:@"ls-link"];
+  XCTAssertTrue([[NSFileManager defaultManager] createSymbolicLinkAtPath:link
+                                                     withDestinationPath:@"/bin/ls"
+                                                                   error:NULL]);
+
+  XCTAssertEqualObjects(CanonicalPath(link), @"/bin/ls");
+  XCTAssertNil(OriginalPathForTranslocatedPath(link));
+}
+
+- (void)testCanonicalPathFollowsSymlinkedDirectories {
+  NSString* app = [self createAppFixture];
+  NSString* linkedApp = [self.tempDir stringByAppendingPathComponent:@"Linked.app"];
+  XCTAssertTrue([[NSFileManager defaultManager] createSymbolicLinkAtPath:linkedApp
+                                                     withDestinationPath:app
+                                                                   error:NULL]);
+
+  XCTAssertEqualObjects(CanonicalPath([linkedApp stringByAppendingPathComponent:
+                                                     @"Contents/MacOS/Fixture"]),
+                        [app stringByAppendingPathComponent:@"Contents/MacOS/Fixture"]);
+}
+
+- (void)testCanonicalPathUnresolvable {
+  NSString* missing = [self.tempDir stringByAppendingPathComponent:@"does-not-exist"];
+  XCTAssertEqualObjects(CanonicalPath(missing), missing);
+  XCTAssertEqualObjects(CanonicalPath(@""), @"");
+  XCTAssertNil(CanonicalPath(nil));
+  XCTAssertNil(OriginalPathForTranslocatedPath(nil));
+}
+
+- (void)testCanonicalPathResolvesTranslocation {
+  NSString* app = [self createAppFixture];
+
+  NSURL* translocatedApp = CFBridgingRelease(SecTranslocateCreateSecureDirectoryForURL(
+      (__bridge CFURLRef)[NSURL fileURLWithPath:app], NULL, NULL));
+  if (!translocatedApp || [translocatedApp.path isEqualToString:app]) {
+    XCTSkip(@"App Translocation is not available on this host");
+  }
+
+  NSString* translocatedExec =
+      [translocatedApp.path stringByAppendingPathComponent:@"Contents/MacOS/Fixture"];
+  NSString* originalExec = [app stringByAppendingPathComponent:@"Contents/MacOS/Fixture"];
+
+  XCTAssertEqualObjects(OriginalPathForTranslocatedPath(translocatedExec), originalExec);
+  XCTAssertEqualObjects(CanonicalPath(translocatedExec), originalExec);
+
+  SecTranslocateDeleteSecureDirectory((__bridge CFURLRef)translocatedApp, NULL);
+}
+
+- (void)testPathOrResolvedPathMatches {
+  NSRegularExpression* re = [NSRegularExpression regularExpressionWithPattern:@"^/Applications/"
+                                                                      options:0
+                                                                        error:NULL];
+  NSString* translocated = @"/private/var/folders/xy/AppTranslocation/UUID/d/Foo.app/Contents/"
+                           @"MacOS/Foo";
+  NSString* original = @"/Applications/Foo.app/Contents/MacOS/Foo";
+
+  XCTAssertTrue(PathOrResolvedPathMatches(re, original, nil));
+  XCTAssertTrue(PathOrResolvedPathMatches(re, translocated, original));
+  XCTAssertFalse(PathOrResolvedPathMatches(re, translocated, nil));
+  XCTAssertFalse(PathOrResolvedPathMatches(re, translocated, translocated));
+  XCTAssertFalse(PathOrResolvedPathMatches(nil, original, original));
+}
+
+@end
@@ -0,0 +1,55 @@
+/// Copyright 2026 North Pole Security, Inc.
+///
+/// Licensed under the Apache License, Version 2.0 (the "License");
+/// you may not use this file except in compliance with the License.
+/// You may obtain a copy of the License at
+///
+///     http://www.apache.org/licenses/LICENSE-2.0
+///
+/// Unless required by applicable law or agreed to in writing, software
+/// distributed under the License is distributed on an "AS IS" BASIS,
+/// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+/// See the License for the specific language governing permissions and
+/// limitations under the License.
+
+#import <Foundation/Foundation.h>
+
+@class SNTRule;
+
+NS_ASSUME_NONNULL_BEGIN
+
+///
+///  A server-issued token that allows a single execution of one identity.
+///
+///  Tokens are JWTs signed with Ed25519 (alg "EdDSA") carrying these claims:
+///    * jti: A unique token ID, used to reject reuse.
+///    * exp: Expiration, in seconds since the Unix epoch.
+///    * rule_type: BINARY, CDHASH, SIGNINGID, CERTIFICATE or TEAMID.
+///    * identifier: The identifier for the given rule type.
+///    * machine_id: Optional. If present, the token is only valid on the
+///      machine with this machine ID.
+///
+@interface SNTAllowOnceToken : NSObject
+
+@property(readonly) NSString* tokenID;
+@property(readonly) NSDate* expirationDate;
+@property(readonly, nullable) NSString* machineID;
+
+///
+///  An allow rule for the identity named by the token.
+///
+@property(readonly) SNTRule* rule;
+
+///
+///  Verifies the token signature with the given Ed25519 public key and parses
+///  its claims. Returns nil, populating error, if the token is malformed, the
+///  signature is invalid or the token has expired as of `now`.
+///
++ (nullable instancetype)tokenWithString:(NSString*)token
+                               publicKey:(NSData*)publicKey
+                             currentDate:(NSDate*)now
+                                   error:(NSError**)error;
+
+@end
+
+NS_ASSUME_NONNULL_END
@@ -0,0 +1,104 @@
+/// Copyright 2026 North Pole Security, Inc.
+///
+/// Licensed under the Apache License, Version 2.0 (the "License");
+/// you may not use this file except in compliance with the License.
+/// You may obtain a copy of the License at
+///
+///     http://www.apache.org/licenses/LICENSE-2.0
+///
+/// Unless required by applicable law or agreed to in writing, software
+/// distributed under the License is distributed on an "AS IS" BASIS,
+/// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+/// See the License for the specific language governing permissions and
+/// limitations under the License.
+
+#import "Source/common/SNTAllowOnceToken.h"
+
+#include <openssl/curve25519.h>
+
+#include <string>
+#include <vector>
+
+#include "Source/common/NKeyTokenValidator.h"
+#import "Source/common/SNTCommonEnums.h"
+#import "Source/common/SNTError.h"
+#import "Source/common/SNTRule.h"
+#import "Source/common/SNTSyncConstants.h"
+#import "Source/common/String.h"
+
+static NSString* const kTokenIDClaim = @"jti";
+static NSString* const kExpirationClaim = @"exp";
+static NSString* const kMachineIDClaim = @"machine_id";
+
+@interface SNTAllowOnceToken ()
+@property(readwrite) NSString* tokenID;
+@property(readwrite) NSDate* expirationDate;
+@property(readwrite, nullable) NSString* machineID;
+@property(readwrite) SNTRule* rule;
+@end
+
+@implementation SNTAllowOnceToken
+
++ (instancetype)tokenWithString:(NSString*)token
+                      publicKey:(NSData*)publicKey
+                    currentDate:(NSDate*)now
+                          error:(NSError**)error {
+  if (publicKey.length != ED25519_PUBLIC_KEY_LEN) {
+    [SNTError populateError:error withFormat:@"Invalid Ed25519 public key"];
+    return nil;
+  }
+
+  std::string jwt = santa::NSStringToUTF8String(token);
+  const auto* keyBytes = static_cast<const uint8_t*>(publicKey.bytes);
+  std::vector<uint8_t> key(keyBytes, keyBytes + publicKey.length);
+  if (!santa::VerifyJWTSignature(jwt, key)) {
+    [SNTError populateError:error withFormat:@"Token signature verification failed"];
+    return nil;
+  }
+
+  NSDictionary* claims = santa::ParseJWTPayload(jwt);
+  NSString* tokenID = claims[kTokenIDClaim];
+  NSNumber* exp = claims[kExpirationClaim];
+  NSString* ruleType = claims[kRuleType];
+  NSString* identifier = claims[kRuleIdentifier];
+  NSString* machineID = claims[kMachineIDClaim];
+  if (![tokenID isKindOfClass:[NSString class]] || !tokenID.length ||
+      ![exp isKindOfClass:[NSNumber class]] || ![ruleType isKindOfClass:[NSString class]] ||
+      ![identifier isKindOfClass:[NSString class]] ||
+      (machineID && ![machineID isKindOfClass:[NSString class]])) {
+    [SNTError populateError:error withFormat:@"Token is missing required claims"];
+    return nil;
+  }
+
+  NSDate* expirationDate = [NSDate dateWithTimeIntervalSince1970:exp.doubleValue];
+  if ([expirationDate compare:now] != NSOrderedDescending) {
+    [SNTError populateError:error withFormat:@"Token expired at %@", expirationDate];
+    return nil;
+  }
+
+  SNTRule* rule = [[SNTRule alloc] initWithDictionary:@{
+    kRuleIdentifier : identifier,
+    kRulePolicy : kRulePolicyAllowlist,
+    kRuleType : ruleType,
+  }
+                                                error:error];
+  if (!rule) {
+    return nil;
+  }
+
+  // Requirement and bundle Signing ID rules describe a set of binaries rather than a single
+  // identity.
+  if (rule.type == SNTRuleTypeRequirement || rule.type == SNTRuleTypeBundleSigningID) {
+    [SNTError populateError:error withFormat:@"Token rule type %@ is not supported", ruleType];
+    return nil;
+  }
+
+  SNTAllowOnceToken* t = [[SNTAllowOnceToken alloc] init];
+  t.tokenID = tokenID;
+  t.expirationDate = expirationDate;
+  t.machineID = machineID.length ? machineID : nil;
+  t.rule = rule;
+  return t;
+}
+
+@end
@@ -0,0 +1,167 @@
+/// Copyright 2026 North Pole Security, Inc.
+///
+/// Licensed under the Apache License, Version 2.0 (the "License");
+/// you may not use this file except in compliance with the License.
+/// You may obtain a copy of the License at
+///
+///     http://www.apache.org/licenses/LICENSE-2.0
+///
+/// Unless required by applicable law or agreed to in writing, software
+/// distributed under the License is distributed on an "AS IS" BASIS,
+/// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+/// See the License for the specific language governing permissions and
+/// limitations under the License.
+
+#import <XCTest/XCTest.h>
+
+#include <openssl/curve25519.h>
+
+#import "Source/common/SNTAllowOnceToken.h"
+#import "Source/common/SNTCommonEnums.h"
+#import "Source/common/SNTRule.h"
+
+static NSString* const kTestSHA256 =
+    @"84de9c61777ca36b13228e2446d53e966096e78db7a72c632b5c185b2ffe68a6";
+
+static NSString* Base64URLEncode(NSData* data) {
+  NSString* b64 = [data base64EncodedStringWithOptions:0];
+  b64 = [b64 stringByReplacingOccurrencesOfString:@"+" withString:@"-"];
+  b64 = [b64 stringByReplacingOccurrencesOfString:@"/" withString:@"_"];
+  return [b64 stringByReplacingOccurrencesOfString:@"=" withString:@""];
+}
+
+@interface SNTAllowOnceTokenTest : XCTestCase
+@property NSData* publicKey;
+@property NSData* privateKey;
+@property NSDate* now;
+@end
+
+@implementation SNTAllowOnceTokenTest
+
+- (void)setUp {
+  uint8_t publicKey[ED25519_PUBLIC_KEY_LEN];
+  uint8_t privateKey[ED25519_PRIVATE_KEY_LEN];
+  ED25519_keypair(publicKey, privateKey);
+  self.publicKey = [NSData dataWithBytes:publicKey length:sizeof(publicKey)];
+  self.privateKey = [NSData dataWithBytes:privateKey length:sizeof(privateKey)];
+  self.now = [NSDate dateWithTimeIntervalSince1970:1800000000];
+}
+
+- (NSString*)tokenWithClaims:(NSDictionary*)claims privateKey:(NSData*)privateKey {
+  NSData* header = [NSJSONSerialization dataWithJSONObject:@{@"alg" : @"EdDSA", @"typ" : @"JWT"}
+                                                   options:0
+                                                     error:nil];
+  NSData* payload = [NSJSONSerialization dataWithJSONObject:claims options:0 error:nil];
+  NSString* signingInput =
+      [NSString stringWithFormat:@"%@.%@", Base64URLEncode(header), Base64URLEncode(payload)];
+
+  uint8_t signature[ED25519_SIGNATURE_LEN];
+  NSData* input = [signingInput dataUsingEncoding:NSUTF8StringEncoding];
+  ED25519_sign(signature, static_cast<const uint8_t*>(input.bytes), input.length,
+               static_cast<const uint8_t*>(privateKey.bytes));
+
+  return [NSString
+      stringWithFormat:@"%@.%@", signingInput,
+                       Base64URLEncode([NSData dataWithBytes:signature length:sizeof(signature)])];
+}
+
+- (NSDictionary*)claimsExpiringIn:(NSTimeInterval)interval {
+  return @{
+    @"jti" : @"token-1",
+    @"exp" : @([self.now timeIntervalSince1970] + interval),
+    @"rule_type" : @"BINARY",
+    @"identifier" : kTestSHA256,
+  };
+}
+
+- (void)testValidToken {
+  NSMutableDictionary* claims = [[self claimsExpiringIn:300] mutableCopy];
+  claims[@"machine_id"] = @"my-machine";
+  NSString* token = [self tokenWithClaims:claims privateKey:self.privateKey];
+
+  NSError* err;
+  SNTAllowOnceToken* t = [SNTAllowOnceToken tokenWithString:token
+                                                  publicKey:self.publicKey
+                                                currentDate:self.now
+                                                      error:&err];
+  XCTAssertNotNil(t);
+  XCTAssertNil(err);
+  XCTAssertEqualObjects(t.tokenID, @"token-1");
+  XCTAssertEqualObjects(t.machineID, @"my-machine");
+  XCTAssertEqualObjects(t.expirationDate, [self.now dateByAddingTimeInterval:300]);
+  XCTAssertEqualObjects(t.rule.identifier, kTestSHA256);
+  XCTAssertEqual(t.rule.type, SNTRuleTypeBinary);
+  XCTAssertEqual(t.rule.state, SNTRuleStateAllow);
+}
+
+- (void)testExpiredToken {
+  NSString* token = [self tokenWithClaims:[self claimsExpiringIn:-1] privateKey:self.privateKey];
+
+  NSError* err;
+  XCTAssertNil([SNTAllowOnceToken tokenWithString:token
+                                        publicKey:self.publicKey
+                                      currentDate:self.now
+                                            error:&err]);
+  XCTAssertTrue([err.localizedDescription containsString:@"expired"]);
+}
+
+- (void)testWrongKeyRejected {
+  uint8_t otherPublicKey[ED25519_PUBLIC_KEY_LEN];
+  uint8_t otherPrivateKey[ED25519_PRIVATE_KEY_LEN];
+  ED25519_keypair(otherPublicKey, otherPrivateKey);
+  NSString* token = [self tokenWithClaims:[self claimsExpiringIn:300]
+                               privateKey:[NSData dataWithBytes:otherPrivateKey
+                                                         length:sizeof(otherPrivateKey)]];
+
+  NSError* err;
+  XCTAssertNil([SNTAllowOnceToken tokenWithString:token
+                                        publicKey:self.publicKey
+                                      currentDate:self.now
+                                            error:&err]);
+  XCTAssertNotNil(err);
+}
+
+- (void)testTamperedClaimsRejected {
+  NSString* token = [self tokenWithClaims:[self claimsExpiringIn:300] privateKey:self.privateKey];
+  NSMutableArray* parts = [[token componentsSeparatedByString:@"."] mutableCopy];
+  NSMutableDictionary* claims = [[self claimsExpiringIn:300] mutableCopy];
+  claims[@"rule_type"] = @"TEAMID";
+  claims[@"identifier"] = @"EQHXZ8M8AV";
+  parts[1] = Base64URLEncode([NSJSONSerialization dataWithJSONObject:claims options:0 error:nil]);
+
+  NSError* err;
+  XCTAssertNil([SNTAllowOnceToken tokenWithString:[parts componentsJoinedByString:@"."]
+                                        publicKey:self.publicKey
+                                      currentDate:self.now
+                                            error:&err]);
+  XCTAssertNotNil(err);
+}
+
+- (void)testInvalidClaimsRejected {
+  NSMutableDictionary* claims = [[self claimsExpiringIn:300] mutableCopy];
+  [claims removeObjectForKey:@"jti"];
+  XCTAssertNil([SNTAllowOnceToken
+      tokenWithString:[self tokenWithClaims:claims privateKey:self.privateKey]
+            publicKey:self.publicKey
+          currentDate:self.now
+                error:nil]);
+
+  claims = [[self claimsExpiringIn:300] mutableCopy];
+  claims[@"rule_type"] = @"REQUIREMENT";
+  claims[@"identifier"] = @"identifier \"com.example\"";
+  XCTAssertNil([SNTAllowOnceToken
+      tokenWithString:[self tokenWithClaims:claims privateKey:self.privateKey]
+            publicKey:self.publicKey
+          currentDate:self.now
+                error:nil]);
+
+  claims = [[self claimsExpiringIn:300] mutableCopy];
+  claims[@"identifier"] = @"not-a-sha256";
+  XCTAssertNil([SNTAllowOnceToken
+      tokenWithString:[self tokenWithClaims:claims privateKey:self.privateKey]
+            publicKey:self.publicKey
+          currentDate:self.now
+                error:nil]);
+}
+
+@end
@@ -179,6 +179,9 @@ static id EncodedValueOrNull(id value) {
     case SNTEventStateBlockCDHash:
       reason = NSLocalizedString(@"CDHash rule", @"Block reason for CDHash rule match");
       break;
+    case SNTEventStateBlockRequirement:
+      reason = NSLocalizedString(@"Requirement rule", @"Block reason for requirement rule match");
+      break;
     case SNTEventStateBlockScope:
       reason =
           NSLocalizedString(@"Blocked path regex", @"Block reason for blocked path regex match");
@@ -190,6 +193,18 @@ static id EncodedValueOrNull(id value) {
       reason = NSLocalizedString(@"Path too long",
                                  @"Block reason when file path exceeds maximum length");
       break;
+    case SNTEventStateBlockNetworkVolume:
+      reason = NSLocalizedString(@"Network volume",
+                                 @"Block reason when the file is on a network volume");
+      break;
+    case SNTEventStateBlockDiskImage:
+      reason = NSLocalizedString(@"Disk image",
+                                 @"Block reason when the file is on a mounted disk image");
+      break;
+    case SNTEventStateBlockPathChurn:
+      reason = NSLocalizedString(@"Binary changing rapidly",
+                                 @"Block reason when the binary at a path keeps changing");
+      break;
     case SNTEventStateBlockUnknown:
       reason = NSLocalizedString(@"No matching rule",
                                  @"Block reason when no rule matched in lockdown mode");
@@ -20,6 +20,7 @@
 #import "Source/common/SantaVnode.h"
 
 @class MOLCertificate;
+@class SNTFileProvenance;
 
 ///
 ///  Store information about executions from decision making for later logging.
@@ -50,6 +51,9 @@
 @property NSString* signingID;
 @property NSString* rawSigningID;
 @property NSString* cdhash;
+// The bundle identifier from the executable's Info.plist. This is read from the file itself and
+// is set regardless of whether the code signature is valid.
+@property NSString* bundleIdentifier;
 @property NSDictionary* entitlements;
 @property NSDictionary* rawEntitlements;
 @property BOOL entitlementsFiltered;
@@ -61,6 +65,30 @@
 
 @property NSString* quarantineURL;
 
+// YES if the executable resides on a mounted disk image (e.g. a DMG).
+@property BOOL onDiskImage;
+
+// The number of times the binary at this path changed within the last PathChurnWindowSeconds.
+// Always 0 if PathChurnExecutionAction is not set.
+@property NSUInteger pathChurnCount;
+
+// The process that last wrote the executable, if it was recently written at a path watched by a
+// FileAccessPolicy rule.
+@property SNTFileProvenance* provenance;
+
+// The canonicalized executable path, set only when CanonicalizeExecutablePaths is enabled and the
+// path differs from the raw path.
+@property NSString* resolvedPath;
+
+// The SHA-256 of the script being run, set only when EnableScriptEvaluation is enabled and the
+// execution is of an interpreter via a shebang, or when EnableInterpreterScriptEvaluation is
+// enabled and the execution is of a known interpreter.
+@property NSString* scriptSHA256;
+
+// The name of the known interpreter running the script, e.g. "osascript", set only when
+// EnableInterpreterScriptEvaluation is enabled.
+@property NSString* scriptInterpreter;
+
 @property NSString* customMsg;
 @property NSString* customURL;
 @property BOOL silentBlockGUI;
@@ -70,6 +98,7 @@
 @property BOOL staticRule;
 
 @property int64_t ruleId;
+@property SNTRuleSeverity severity;
 
 @property BOOL cacheable;
 @property BOOL holdAndAsk;
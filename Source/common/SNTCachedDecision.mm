@@ -40,6 +40,7 @@
   if (self) {
     _sha256 = previous.sha256;
     _cdhash = previous.cdhash;
+    _bundleIdentifier = previous.bundleIdentifier;
     _teamID = previous.teamID;
     _signingID = previous.signingID;
     _rawSigningID = previous.rawSigningID;
@@ -68,6 +69,7 @@
   copy.signingID = _signingID;
   copy.rawSigningID = _rawSigningID;
   copy.cdhash = _cdhash;
+  copy.bundleIdentifier = _bundleIdentifier;
   copy.entitlements = _entitlements;
   copy.rawEntitlements = _rawEntitlements;
   copy.entitlementsFiltered = _entitlementsFiltered;
@@ -77,6 +79,12 @@
   copy.secureSigningTime = _secureSigningTime;
   copy.signingTime = _signingTime;
   copy.quarantineURL = _quarantineURL;
+  copy.onDiskImage = _onDiskImage;
+  copy.pathChurnCount = _pathChurnCount;
+  copy.provenance = _provenance;
+  copy.resolvedPath = _resolvedPath;
+  copy.scriptSHA256 = _scriptSHA256;
+  copy.scriptInterpreter = _scriptInterpreter;
   copy.customMsg = _customMsg;
   copy.customURL = _customURL;
   copy.silentBlockGUI = _silentBlockGUI;
@@ -84,6 +92,7 @@
   copy.seatbeltRequired = _seatbeltRequired;
   copy.staticRule = _staticRule;
   copy.ruleId = _ruleId;
+  copy.severity = _severity;
   copy.cacheable = _cacheable;
   copy.holdAndAsk = _holdAndAsk;
   copy.silentTouchID = _silentTouchID;
@@ -62,6 +62,8 @@ typedef NS_ENUM(NSInteger, SNTRuleType) {
   SNTRuleTypeSigningID = 2000,
   SNTRuleTypeCertificate = 3000,
   SNTRuleTypeTeamID = 4000,
+  SNTRuleTypeRequirement = 5000,
+  SNTRuleTypeBundleSigningID = 6000,
 };
 
 typedef NS_ENUM(NSInteger, SNTRuleState) {
@@ -88,6 +90,15 @@ typedef NS_ENUM(NSInteger, SNTRuleState) {
   SNTRuleStateSilentBlockTTY = 13,
 };
 
+// How prominently a block by a rule is surfaced. Unspecified rules behave as warnings, which is
+// how every block was handled before severities existed.
+typedef NS_ENUM(NSInteger, SNTRuleSeverity) {
+  SNTRuleSeverityUnspecified = 0,
+  SNTRuleSeverityInfo = 1,
+  SNTRuleSeverityWarning = 2,
+  SNTRuleSeverityCritical = 3,
+};
+
 typedef NS_ENUM(NSInteger, SNTClientMode) {
   SNTClientModeUnknown,
   SNTClientModeMonitor = 1,
@@ -110,6 +121,10 @@ typedef NS_ENUM(uint64_t, SNTEventState) {
   SNTEventStateBlockSigningID = 1ULL << 22,
   SNTEventStateBlockCDHash = 1ULL << 23,
   SNTEventStateBlockCELFallback = 1ULL << 24,
+  SNTEventStateBlockRequirement = 1ULL << 25,
+  SNTEventStateBlockNetworkVolume = 1ULL << 26,
+  SNTEventStateBlockDiskImage = 1ULL << 27,
+  SNTEventStateBlockPathChurn = 1ULL << 28,
 
   // Bits 40-63 store allow decision types
   SNTEventStateAllowUnknown = 1ULL << 40,
@@ -128,6 +143,8 @@ typedef NS_ENUM(uint64_t, SNTEventState) {
   SNTEventStateAllowCompilerCDHash = 1ULL << 53,
   SNTEventStateAllowCELFallback = 1ULL << 54,
   SNTEventStateAllowPlatform = 1ULL << 55,
+  SNTEventStateAllowRequirement = 1ULL << 56,
+  SNTEventStateAllowOnce = 1ULL << 57,
 
   // Block and Allow masks
   SNTEventStateBlock = 0xFFFFFFULL << 16,
@@ -189,6 +206,57 @@ typedef NS_ENUM(NSInteger, SNTOverrideFileAccessAction) {
   SNTOverrideFileAccessActionDisable,
 };
 
+typedef NS_ENUM(NSInteger, SNTClockTamperingAction) {
+  SNTClockTamperingActionNone,
+  SNTClockTamperingActionSync,
+  SNTClockTamperingActionLockdown,
+};
+
+typedef NS_ENUM(NSInteger, SNTNetworkVolumeExecutionAction) {
+  SNTNetworkVolumeExecutionActionNone,
+  SNTNetworkVolumeExecutionActionBlockUnknown,
+  SNTNetworkVolumeExecutionActionBlock,
+};
+
+typedef NS_ENUM(NSInteger, SNTDiskImageExecutionAction) {
+  SNTDiskImageExecutionActionNone,
+  SNTDiskImageExecutionActionWarn,
+  SNTDiskImageExecutionActionBlockUnknown,
+  SNTDiskImageExecutionActionBlock,
+};
+
+typedef NS_ENUM(NSInteger, SNTPathChurnExecutionAction) {
+  SNTPathChurnExecutionActionNone,
+  SNTPathChurnExecutionActionWarn,
+  SNTPathChurnExecutionActionBlockUnknown,
+  SNTPathChurnExecutionActionBlock,
+};
+
+typedef NS_ENUM(NSInteger, SNTSigningStatusExecutionAction) {
+  SNTSigningStatusExecutionActionNone,
+  SNTSigningStatusExecutionActionBlockUnknown,
+  SNTSigningStatusExecutionActionBlock,
+};
+
+typedef NS_ENUM(NSInteger, SNTCodeSignatureInvalidationResponse) {
+  SNTCodeSignatureInvalidationResponseNone,
+  SNTCodeSignatureInvalidationResponseLog,
+  SNTCodeSignatureInvalidationResponseBlock,
+  SNTCodeSignatureInvalidationResponseTerminate,
+};
+
+typedef NS_ENUM(NSInteger, SNTLaunchItemPolicy) {
+  SNTLaunchItemPolicyNone,
+  SNTLaunchItemPolicyMonitor,
+  SNTLaunchItemPolicyBlock,
+};
+
+typedef NS_ENUM(NSInteger, SNTSyncInFlightExecutionPolicy) {
+  SNTSyncInFlightExecutionPolicyUseExisting,
+  SNTSyncInFlightExecutionPolicyDefer,
+  SNTSyncInFlightExecutionPolicyFailSafe,
+};
+
 typedef NS_ENUM(NSInteger, SNTDeviceManagerStartupPreferences) {
   SNTDeviceManagerStartupPreferencesNone,
   SNTDeviceManagerStartupPreferencesUnmount,
@@ -218,6 +286,11 @@ typedef NS_ENUM(NSInteger, SNTRuleCleanup) {
   SNTRuleCleanupFileAccessRules,
 };
 
+typedef NS_ENUM(NSInteger, SNTDuplicateRuleResolution) {
+  SNTDuplicateRuleResolutionLastWins,
+  SNTDuplicateRuleResolutionHighestPrecedence,
+};
+
 typedef NS_ENUM(NSInteger, SNTSigningStatus) {
   SNTSigningStatusUnsigned,
   SNTSigningStatusInvalid,
@@ -60,6 +60,10 @@
 - (void)celFallbackRules:(void (^)(NSArray<SNTCELFallbackRule*>*))block;
 - (void)fullSyncInterval:(void (^)(NSUInteger))block;
 - (void)pushNotificationsFullSyncInterval:(void (^)(NSUInteger))block;
+- (void)telemetrySampleRate:(void (^)(double))block;
+/// Set only by PreflightConfigBundle, when the sync server reported a machine ID conflict and a
+/// new machine ID was generated.
+- (void)generatedMachineID:(void (^)(NSString*))block;
 
 ///
 ///  When set, signals the daemon to clear persisted sync state before
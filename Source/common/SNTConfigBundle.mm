@@ -55,6 +55,8 @@
 @property NSArray<SNTCELFallbackRule*>* celFallbackRules;
 @property NSNumber* fullSyncInterval;
 @property NSNumber* pushNotificationsFullSyncInterval;
+@property NSNumber* telemetrySampleRate;
+@property NSString* generatedMachineID;
 @property NSNumber* clearSyncStateBeforeApply;
 @end
 
@@ -98,6 +100,8 @@
   ENCODE(coder, celFallbackRules);
   ENCODE(coder, fullSyncInterval);
   ENCODE(coder, pushNotificationsFullSyncInterval);
+  ENCODE(coder, telemetrySampleRate);
+  ENCODE(coder, generatedMachineID);
   ENCODE(coder, clearSyncStateBeforeApply);
 }
 
@@ -137,6 +141,8 @@
     DECODE_ARRAY(decoder, celFallbackRules, SNTCELFallbackRule);
     DECODE(decoder, fullSyncInterval, NSNumber);
     DECODE(decoder, pushNotificationsFullSyncInterval, NSNumber);
+    DECODE(decoder, telemetrySampleRate, NSNumber);
+    DECODE(decoder, generatedMachineID, NSString);
     DECODE(decoder, clearSyncStateBeforeApply, NSNumber);
   }
   return self;
@@ -339,6 +345,18 @@
   }
 }
 
+- (void)telemetrySampleRate:(void (^)(double))block {
+  if (self.telemetrySampleRate) {
+    block([self.telemetrySampleRate doubleValue]);
+  }
+}
+
+- (void)generatedMachineID:(void (^)(NSString*))block {
+  if (self.generatedMachineID) {
+    block(self.generatedMachineID);
+  }
+}
+
 - (void)clearSyncStateBeforeApply:(void (^)(BOOL))block {
   if (self.clearSyncStateBeforeApply) {
     block([self.clearSyncStateBeforeApply boolValue]);
@@ -19,10 +19,12 @@
 
 @class SNTCELFallbackRule;
 @class SNTExportConfiguration;
+@class SNTMaintenanceWindow;
 @class SNTModeTransition;
 @class SNTTemporaryAdminPolicy;
 @class SNTSyncNetworkExtensionSettings;
 @class SNTRule;
+@class SNTRuleSource;
 
 ///
 ///  Singleton that provides an interface for managing configuration values on disk
@@ -34,7 +36,9 @@
 #pragma mark - Daemon Settings
 
 ///
-///  The operating mode. Defaults to MONITOR.
+///  The operating mode. Defaults to MONITOR. Temporary Monitor Mode takes precedence over the
+///  mode set by an offline grace token, which takes precedence over the sync server and local
+///  configuration.
 ///
 @property(readonly, nonatomic) SNTClientMode clientMode;
 
@@ -43,6 +47,53 @@
 ///
 - (void)setSyncServerClientMode:(SNTClientMode)newMode;
 
+///
+///  Recurring windows, in local time, during which a request from the sync server to leave
+///  Monitor mode is deferred until the window closes, so that Lockdown is not enabled in the
+///  middle of ongoing maintenance work. Requests to switch to Monitor are always applied
+///  immediately.
+///
+///  The value of this key should be an array containing dictionaries, e.g:
+///
+///  <key>MaintenanceWindows</key>
+///  <array>
+///    <dict>
+///      <key>start</key>
+///      <string>22:00</string>
+///      <key>end</key>
+///      <string>06:00</string>  (closes the next day if earlier than start)
+///      <key>weekdays</key>
+///      <array>  (optional, defaults to every day; 0 and 7 are Sunday)
+///        <integer>1</integer>
+///        <integer>2</integer>
+///      </array>
+///    </dict>
+///  </array>
+///
+///  Invalid entries are ignored.
+///
+@property(nonnull, readonly, nonatomic) NSArray<SNTMaintenanceWindow*>* maintenanceWindows;
+
+///
+///  A client mode received from the sync server during a maintenance window that has not been
+///  applied yet. SNTClientModeUnknown if there is none.
+///
+@property(readonly, nonatomic) SNTClientMode deferredClientMode;
+
+///
+///  Store a client mode received from the sync server to apply once the current maintenance
+///  window closes. Passing SNTClientModeUnknown clears it.
+///
+- (void)setSyncServerDeferredClientMode:(SNTClientMode)newMode;
+
+///
+///  The number of seconds after the client mode changes from Monitor to Lockdown during which
+///  executions that Lockdown would block are allowed and logged instead, so that work already in
+///  progress is not abruptly broken. Binaries blocked by a rule are still blocked. Defaults to 0
+///  (disabled), maximum 3600.
+///
+@property(readonly, nonatomic) NSUInteger lockdownGracePeriodSec;
+
 ///
 ///  Enable Fail Close mode. Defaults to NO.
 ///  This controls Santa's behavior when a failure occurs, such as an
@@ -80,6 +131,32 @@
 ///
 @property(nullable, readonly, nonatomic) NSArray<NSDictionary*>* staticRules;
 
+///
+///  Additional rule servers, each downloaded on its own schedule independently of the primary
+///  sync server. Rules from these sources only cover execution and sit between StaticRules and
+///  the rules from the primary sync server. When several sources have a rule that matches, the
+///  source with the highest precedence wins.
+///
+///  The value of this key should be an array containing dictionaries, e.g:
+///
+///  <key>RuleSources</key>
+///  <array>
+///    <dict>
+///      <key>name</key>
+///      <string>security-blocklist</string>
+///      <key>url</key>
+///      <string>https://blocklist.example.com/santa/</string>
+///      <key>sync_interval_seconds</key>
+///      <integer>300</integer>  (minimum 60)
+///      <key>precedence</key>
+///      <integer>10</integer>  (optional, defaults to 0)
+///    </dict>
+///  </array>
+///
+///  Invalid entries are ignored.
+///
+@property(nonnull, readonly, nonatomic) NSArray<SNTRuleSource*>* ruleSources;
+
 ///
 ///  The regex of allowed paths. Regexes are specified in ICU format.
 ///
@@ -175,6 +252,61 @@
 ///
 @property(readonly, nonatomic) BOOL enablePageZeroProtection;
 
+///
+///  Canonicalize executable paths before path-based matching, defaults to NO.
+///  When enabled, translocated apps are resolved back to their original location and
+///  symlinks are followed to their target. Both the raw and the resolved path are then
+///  matched against AllowedPathRegex, BlockedPathRegex and FileAccessPolicy BinaryPath.
+///
+@property(readonly, nonatomic) BOOL canonicalizeExecutablePaths;
+
+///
+///  Evaluate scripts run via an interpreter shebang, defaults to NO.
+///  When enabled, binary rules matching the script's SHA-256 are applied on top
+///  of the interpreter's decision. Executions of scripts and of their
+///  interpreters are no longer cached, so this increases the number of
+///  executions santad evaluates.
+///
+@property(readonly, nonatomic) BOOL enableScriptEvaluation;
+
+///
+///  Evaluate scripts passed to known interpreters such as osascript, defaults to NO.
+///  When enabled, santad parses the interpreter's arguments to find the script it
+///  will run, either inline source (e.g. `osascript -e`) or a script file, and
+///  applies binary rules matching its SHA-256 as for EnableScriptEvaluation.
+///  Executions of known interpreters are no longer cached.
+///
+@property(readonly, nonatomic) BOOL enableInterpreterScriptEvaluation;
+
+///
+///  A list of Signing IDs (e.g. "EQHXZ8M8AV:com.google.Chrome") and Team IDs whose binaries are
+///  always blocked, even if they have been re-signed. Binaries signed by a listed identity are
+///  blocked and their SHA-256 and bundle identifier are remembered. Any later binary with a
+///  remembered SHA-256, a remembered bundle identifier or a bundle identifier matching the
+///  identifier part of a listed Signing ID is blocked regardless of its code signature, so an
+///  ad-hoc re-signed copy of a listed app stays blocked.
+///
+@property(nullable, readonly, nonatomic) NSArray<NSString*>* resignProtectedBlocklist;
+
+///
+///  A hex-encoded SHA-256 of the configuration profile values currently in effect. Keys are
+///  sorted before hashing, so identical configurations always produce the same hash.
+///
+@property(readonly, nonatomic) NSString* configHash;
+
+///
+///  A hex-encoded SHA-256 of the configuration in effect: the configuration profile values
+///  along with the settings applied by the sync server. Sync bookkeeping, such as the last sync
+///  times, is excluded so the hash only changes when the configuration does.
+///
+@property(readonly, nonatomic) NSString* effectiveConfigHash;
+
+///
+///  A copy of the configuration profile values currently in effect. This can contain secrets,
+///  such as client certificate passwords, and must be redacted before leaving the host.
+///
+@property(nonnull, readonly, nonatomic) NSDictionary<NSString*, id>* configSnapshot;
+
 ///
 ///  Enable bad signature protection, defaults to NO.
 ///  When enabled, a binary that is signed but has a bad signature (cert revoked, binary
@@ -183,6 +315,27 @@
 ///
 @property(readonly, nonatomic) BOOL enableBadSignatureProtection;
 
+///
+///  Path to a Unix domain socket that is consulted before finalizing the decision for
+///  an unknown binary. The hook receives the binary's identity as JSON and replies with
+///  allow or block. The socket, its directory and the listening process must all belong to
+///  root. Defaults to nil (disabled).
+///
+@property(nullable, readonly, nonatomic) NSString* decisionHookSocketPath;
+
+///
+///  The maximum time to wait for the decision hook to reply, in milliseconds.
+///  Defaults to 250, clamped to the range [10, 5000].
+///
+@property(readonly, nonatomic) NSUInteger decisionHookTimeoutMilliseconds;
+
+///
+///  If YES, unknown binaries are blocked when the decision hook cannot be reached or
+///  fails to reply in time. If NO, the normal client mode decision is used instead.
+///  Defaults to NO.
+///
+@property(readonly, nonatomic) BOOL decisionHookFailClosed;
+
 ///
 ///  Enable anti-tamper process suspend/resume protection.
 ///  When enabled, attempts to suspend or resume the Santa daemon process will be blocked.
@@ -206,6 +359,255 @@
 ///
 @property(readonly, nonatomic) BOOL allowDelegatedSignals;
 
+///
+///  The action santad takes when it detects the system clock moved backward by
+///  more than clockTamperingThresholdSec relative to the monotonic clock. A
+///  warning is always logged.
+///
+///  Supported values are:
+///    * "Sync": Request an immediate sync with the sync server
+///    * "Lockdown": Switch the client mode to Lockdown
+///
+///  Any other value (or if unset) only logs the event.
+///
+@property(readonly, nonatomic) SNTClockTamperingAction clockTamperingAction;
+
+///
+///  The number of seconds the wall clock may move backward relative to the
+///  monotonic clock before it is considered tampering. Defaults to 300.
+///
+@property(readonly, nonatomic) NSUInteger clockTamperingThresholdSec;
+
+///
+///  The action santad takes when a binary being executed lives on a network
+///  volume (e.g. NFS, SMB, AFP or WebDAV), i.e. a volume not marked as local.
+///
+///  Supported values are:
+///    * "BlockUnknown": Block binaries that would otherwise be handled by the
+///      client mode (no rule matched). Binaries allowed by a rule still run.
+///    * "Block": Block all binaries, regardless of any matching rules.
+///
+///  Any other value (or if unset) applies no additional policy.
+///
+@property(readonly, nonatomic) SNTNetworkVolumeExecutionAction networkVolumeExecutionAction;
+
+///
+///  The action santad takes when a binary being executed lives on a mounted
+///  disk image (e.g. a DMG opened from the Finder or attached with hdiutil).
+///
+///  Supported values are:
+///    * "Warn": Allow the execution as normal but log a warning. The execution
+///      is still flagged as being on a disk image in the logs.
+///    * "BlockUnknown": Block binaries that would otherwise be handled by the
+///      client mode (no rule matched). Binaries allowed by a rule still run.
+///    * "Block": Block all binaries, regardless of any matching rules.
+///
+///  Any other value (or if unset) applies no additional policy.
+///
+@property(readonly, nonatomic) SNTDiskImageExecutionAction diskImageExecutionAction;
+
+///
+///  If YES, DiskImageExecutionAction only applies to disk images that were
+///  quarantined when mounted, i.e. images that were downloaded rather than
+///  created locally.
+///
+///  Defaults to NO.
+///
+@property(readonly, nonatomic) BOOL diskImageExecutionQuarantinedOnly;
+
+///
+///  The action santad takes when the binary at a path has changed (has a new
+///  SHA-256) at least PathChurnThreshold times within PathChurnWindowSeconds.
+///  Malware may rewrite itself at a stable path so that no hash rule matches.
+///
+///  Supported values are:
+///    * "Warn": Allow the execution as normal but log a warning.
+///    * "BlockUnknown": Block binaries that would otherwise be handled by the
+///      client mode (no rule matched). Binaries allowed by a rule still run.
+///    * "Block": Block all binaries, regardless of any matching rules.
+///
+///  While set, the number of changes is recorded in the execution logs.
+///  Any other value (or if unset) applies no additional policy.
+///
+@property(readonly, nonatomic) SNTPathChurnExecutionAction pathChurnExecutionAction;
+
+///
+///  How many times the binary at a path must change within PathChurnWindowSeconds
+///  before PathChurnExecutionAction applies.
+///
+///  Defaults to 5, clamped to the range [2, 1000].
+///
+@property(readonly, nonatomic) NSUInteger pathChurnThreshold;
+
+///
+///  The window, in seconds, over which changes to the binary at a path are counted
+///  for PathChurnExecutionAction.
+///
+///  Defaults to 3600 (1 hour), clamped to the range [60, 86400].
+///
+@property(readonly, nonatomic) NSUInteger pathChurnWindowSeconds;
+
+///
+///  Per signing status actions santad takes when a binary being executed is
+///  not validly signed. Keys are the signing status ("Invalid", "Unsigned" or
+///  "Adhoc") and values are the action:
+///    * "BlockUnknown": Block binaries that would otherwise be handled by the
+///      client mode (no rule matched). Binaries allowed by a rule still run.
+///    * "Block": Block all binaries, regardless of any matching rules.
+///
+///  Statuses that are missing (or have any other value) apply no additional
+///  policy. An invalid signature is one that is present but fails validation,
+///  e.g. because it was truncated or the binary was modified after signing.
+///
+@property(nullable, readonly, nonatomic) NSDictionary* signingStatusExecutionActions;
+
+///
+///  Returns the action configured in SigningStatusExecutionActions for @c status.
+///  Production and development signed binaries always return
+///  SNTSigningStatusExecutionActionNone.
+///
+- (SNTSigningStatusExecutionAction)executionActionForSigningStatus:(SNTSigningStatus)status;
+
+///
+///  The response santad applies when the code signature of a running process becomes invalid,
+///  which can indicate that code was injected into a binary that was allowed to execute.
+///
+///  Supported values are:
+///    * "Log": Log a warning naming the process.
+///    * "Block": Log and suspend the process so it can't run any further.
+///    * "Terminate": Log and kill the process.
+///
+///  Platform binaries and Santa's own processes are only ever logged. Any other value (or if
+///  unset) disables detection.
+///
+@property(readonly, nonatomic)
+    SNTCodeSignatureInvalidationResponse codeSignatureInvalidationResponse;
+
+///
+///  The policy santad applies when a login item, launch agent or launch daemon is registered. The
+///  item's executable is checked against TeamID and SigningID rules; platform binaries are
+///  always allowed.
+///
+///  Supported values are:
+///    * "Monitor": Log a warning and upload an event for items that aren't allowed by a rule.
+///    * "Block": As Monitor, and also unload and disable launch agents and daemons that aren't
+///      allowed by a rule. Login items and items managed by MDM are never disabled.
+///
+///  Any other value (or if unset) disables evaluation of launch items.
+///
+@property(readonly, nonatomic) SNTLaunchItemPolicy launchItemPolicy;
+
+///
+///  If YES, binaries allowed by a TeamID rule are blocked unless their code signature requires
+///  library validation, so that libraries not signed by the same team (or Apple) can't be loaded
+///  into them. Binaries allowed by any other rule type are unaffected.
+///
+///  Defaults to NO.
+///
+@property(readonly, nonatomic) BOOL requireLibraryValidationForTeamIDRules;
+
+///
+///  How executions are decided while a rule update from the sync server has been received but
+///  not yet fully applied, e.g. while a large update is written to the rules database or is held
+///  back by RuleApplicationDeferralLoadPercent. Decisions in this window use the existing rules,
+///  which are about to change.
+///
+///  Supported values are:
+///    * "UseExisting": Decide using the existing rules.
+///    * "Defer": Wait up to SyncInFlightMaxDeferralMilliseconds for the update to be applied,
+///      then decide using the existing rules if it still hasn't been.
+///    * "FailSafe": Block binaries that no existing rule allows, even in Monitor mode.
+///
+///  With "Defer" and "FailSafe", decisions made using the existing rules aren't cached. Any
+///  other value (or if unset) is treated as "UseExisting".
+///
+@property(readonly, nonatomic) SNTSyncInFlightExecutionPolicy syncInFlightExecutionPolicy;
+
+///
+///  With SyncInFlightExecutionPolicy set to "Defer", the maximum time an execution waits for an
+///  in-flight rule update to be applied. Clamped to between 10 and 5000.
+///
+///  Defaults to 1000.
+///
+@property(readonly, nonatomic) NSUInteger syncInFlightMaxDeferralMilliseconds;
+
+///
+///  If YES, santad records every execution decision in a local SQLite database that can be
+///  queried with `santactl query`. Unlike the events database, decisions are recorded regardless
+///  of event upload settings and are never uploaded.
+///
+///  Defaults to NO.
+///
+@property(readonly, nonatomic) BOOL enableDecisionDatabase;
+
+///
+///  The maximum number of decisions kept in the decision database. Once reached, the oldest
+///  decisions are deleted as new ones are recorded.
+///
+///  Defaults to 100000.
+///
+@property(readonly, nonatomic) NSUInteger decisionDatabaseMaxDecisions;
+
+///
+///  The order in which rule types are checked when more than one rule matches an execution, from
+///  highest to lowest precedence. The first matching rule wins. Must list each of "CDHASH",
+///  "BINARY", "SIGNINGID", "CERTIFICATE" and "TEAMID" exactly once, e.g. placing "TEAMID" before
+///  "BINARY" lets a Team ID block beat a Binary allow.
+///
+///  Requirement rules are not part of the ordering, they are always evaluated after the other
+///  rule types.
+///
+///  Returns an array of SNTRuleType values, or nil if unset or invalid, in which case the default
+///  order of CDHash, Binary, Signing ID, Certificate and Team ID is used.
+///
+@property(readonly, nonatomic) NSArray<NSNumber*>* rulePrecedence;
+
+///
+///  If YES, binaries signed by one of the developer toolchains in developerToolsAllowlist are
+///  allowed when no rule matched them, to ease onboarding developer machines in Lockdown mode.
+///  Rules still take precedence. Defaults to NO.
+///
+@property(readonly, nonatomic) BOOL enableDeveloperToolsAllowlist;
+
+///
+///  The developer toolchain identities allowed when enableDeveloperToolsAllowlist is set. Each
+///  entry is either a Team ID (e.g. "2ZEFAR8TH3") or a Signing ID (e.g.
+///  "UBF8T346G9:com.microsoft.VSCode"). If the DeveloperToolsAllowlist key is set it replaces the
+///  built-in list, otherwise the built-in list is returned.
+///
+@property(nonnull, readonly, nonatomic) NSArray<NSString*>* developerToolsAllowlist;
+
+///
+///  The base64-encoded Ed25519 public key used to verify one-time allow tokens
+///  presented with `santactl allow-once`. If unset, tokens are not accepted.
+///
+@property(nullable, readonly, nonatomic) NSData* allowOnceTokenPublicKey;
+
+///
+///  An offline grace token, delivered by MDM, that keeps a host in the client mode it names until
+///  the token expires. Intended for hosts that cannot sync or receive push notifications.
+///
+@property(nullable, readonly, nonatomic) NSString* offlineGraceToken;
+
+///
+///  The base64-encoded Ed25519 public key used to verify offlineGraceToken. If unset, the token
+///  is treated as invalid.
+///
+@property(nullable, readonly, nonatomic) NSData* offlineGraceTokenPublicKey;
+
+///
+///  The client mode applied when offlineGraceToken is set but has expired or is invalid.
+///  Defaults to LOCKDOWN.
+///
+@property(readonly, nonatomic) SNTClientMode offlineGraceFallbackClientMode;
+
+///
+///  How long, in seconds, since the last successful full sync before offlineGraceToken takes
+///  effect. While the host is syncing, the mode from the sync server applies. Defaults to 604800
+///  (7 days).
+///
+@property(readonly, nonatomic) NSUInteger offlineGraceSyncAgeSec;
+
 ///
 ///  Defines how event logs are stored. Options are:
 ///    SNTEventLogTypeSyslog "syslog": Sent to ASL or ULS (if built with the 10.12 SDK or later).
@@ -243,6 +645,26 @@
 ///
 @property(nonnull, readonly, nonatomic) NSString* eventLogPath;
 
+///
+///  If eventLogType is set to Filelog or JSON, eventLogBufferSizeKB sets how much log data is
+///  buffered in memory before it is written to eventLogPath. Set to 0 to write every event as it
+///  is logged.
+///  Defaults to 128.
+///
+///  @note: This property is KVO compliant, but should only be read once at santad startup.
+///
+@property(readonly, nonatomic) NSUInteger eventLogBufferSizeKB;
+
+///
+///  If eventLogType is set to Filelog or JSON, eventLogMaxFlushTimeSec sets the maximum amount
+///  of time buffered log data is held in memory before being written to eventLogPath. Buffered
+///  data is also written when santad shuts down.
+///  Defaults to 10.
+///
+///  @note: This property is KVO compliant, but should only be read once at santad startup.
+///
+@property(readonly, nonatomic) NSUInteger eventLogMaxFlushTimeSec;
+
 ///
 ///  Array of strings of telemetry events that should be logged.
 ///
@@ -460,6 +882,19 @@
 ///
 - (void)setSyncServerTelemetryFilterExpressions:(nullable NSArray<NSString*>*)expressions;
 
+///
+///  The fraction (0.0-1.0) of allowed execution events that are stored for upload when
+///  EnableAllEventUpload or unknown event upload would otherwise store them, as received from a
+///  sync server. Binaries are sampled consistently by their SHA-256. Blocked and audit events are
+///  never sampled. Defaults to 1.0.
+///
+@property(readonly, nonatomic) double telemetrySampleRate;
+
+///
+///  Set the telemetry sample rate as received from a sync server.
+///
+- (void)setSyncServerTelemetrySampleRate:(double)rate;
+
 ///
 ///  Set the binary upload filter expressions as received from a sync server.
 ///
@@ -874,6 +1309,18 @@ extern NSString* _Nonnull const kEnableMenuItemUserOverride;
 ///
 - (void)setInTemporaryMonitorMode:(BOOL)enabled;
 
+///
+///  The client mode currently imposed by the offline grace token, or SNTClientModeUnknown if no
+///  token is configured. Evaluated by santad; see offlineGraceToken.
+///
+@property(readonly) SNTClientMode offlineGraceClientMode;
+
+///
+///  Set / clear (with SNTClientModeUnknown) the in-memory client mode imposed by the offline
+///  grace token.
+///
+- (void)setOfflineGraceClientMode:(SNTClientMode)mode;
+
 #pragma mark - Timed Session State
 
 ///
@@ -904,6 +1351,31 @@ extern NSString* _Nonnull const kEnableMenuItemUserOverride;
 ///
 - (nullable NSArray<NSDictionary*>*)savedDemotedAdmins;
 
+///
+///  Persists the IDs of one-time allow tokens that have already been redeemed,
+///  mapped to each token's expiration date. Returns NO — and leaves the
+///  in-memory record unchanged — when the record cannot be written to disk;
+///  callers must not honor the token on a NO.
+///
+- (BOOL)persistConsumedAllowOnceTokens:(nonnull NSDictionary<NSString*, NSDate*>*)tokens;
+
+///
+///  Returns the persisted consumed-token record, or nil if none exists.
+///
+- (nullable NSDictionary<NSString*, NSDate*>*)savedConsumedAllowOnceTokens;
+
+///
+///  Persists the IDs of signed inline rule bundles from push notifications that have already been
+///  applied, mapped to each bundle's expiration date. As with persistConsumedAllowOnceTokens:,
+///  callers must not apply the bundle on a NO.
+///
+- (BOOL)persistConsumedPushRuleBundles:(nonnull NSDictionary<NSString*, NSDate*>*)bundles;
+
+///
+///  Returns the persisted consumed push rule bundle record, or nil if none exists.
+///
+- (nullable NSDictionary<NSString*, NSDate*>*)savedConsumedPushRuleBundles;
+
 ///
 ///  State-file key under which Temporary Admin Mode persists its session state
 ///  (an active session, or a deadline-0 demote-retry residue after a failed
@@ -1016,8 +1488,30 @@ extern NSString* _Nonnull const kStateTempAdminTargetUIDKey;
 ///
 ///  If set, this over-rides the default machine ID used for syncing.
 ///
+///  Without an explicit MachineID, a machine ID generated after the sync server reported a
+///  conflict takes precedence over MachineIDPlist and the hardware UUID.
+///
 @property(nullable, readonly, nonatomic) NSString* machineID;
 
+///
+///  The machine ID generated when the sync server reported that another host uses the same
+///  machine ID and RegenerateMachineIDOnConflict is set. Kept across clean syncs.
+///
+@property(nullable, readonly, nonatomic) NSString* generatedMachineID;
+
+///
+///  Set the machine ID generated after a machine ID conflict.
+///
+- (void)setGeneratedMachineID:(nullable NSString*)generatedMachineID;
+
+///
+///  If true and the sync server reports in the preflight response that another host uses the same
+///  machine ID, e.g. because it was cloned from the same image, a new machine ID is generated and
+///  used from the next sync. Has no effect if MachineID is set. Defaults to false, which only logs
+///  an error.
+///
+@property(readonly, nonatomic) BOOL regenerateMachineIDOnConflict;
+
 #pragma mark Transitive Allowlist Settings
 
 ///
@@ -1069,6 +1563,114 @@ extern NSString* _Nonnull const kStateTempAdminTargetUIDKey;
 ///
 @property(readonly, nonatomic) BOOL enableCleanSyncEventUpload;
 
+///
+///  If greater than zero, events older than this many seconds are dropped during event upload
+///  instead of being sent to the sync server. Dropped events are still removed from the local
+///  database. Defaults to 0 (disabled).
+///
+@property(readonly, nonatomic) NSUInteger syncMaxEventAgeSec;
+
+///
+///  If true, no events are stored locally or uploaded to the sync server. Rules and configuration
+///  continue to sync normally. Defaults to false.
+///
+@property(readonly, nonatomic) BOOL disableEventUpload;
+
+///
+///  The maximum number of event upload batches that may be in flight at once. Batches are always
+///  acknowledged in order. Values are clamped to the range [1, 8]. Defaults to 1.
+///
+@property(readonly, nonatomic) NSUInteger syncEventUploadConcurrency;
+
+///
+///  The maximum number of downloaded rule pages that may be converted concurrently while later
+///  pages are still being fetched. Rules are always applied in page order. Values are clamped to
+///  the range [1, 8]. Defaults to 1.
+///
+@property(readonly, nonatomic) NSUInteger syncRuleDownloadConcurrency;
+
+///
+///  The number of consecutive failed sync server requests after which the sync circuit breaker
+///  opens. While open, no requests are sent to the sync server until the cooldown has passed,
+///  after which a single probe request is allowed through. Set to 0 to disable the circuit
+///  breaker. Defaults to 10.
+///
+@property(readonly, nonatomic) NSUInteger syncCircuitBreakerFailureThreshold;
+
+///
+///  The number of seconds the sync circuit breaker stays open before allowing a probe request.
+///  Defaults to 300, minimum 1.
+///
+@property(readonly, nonatomic) NSUInteger syncCircuitBreakerCooldownSec;
+
+///
+///  If greater than zero, incremental rule updates containing more than this many execution
+///  rules are applied in multiple smaller transactions so that decisions are not stalled for the
+///  duration of a large sync. If a batch fails, the batches already written are undone, leaving
+///  alone any rule that has changed again since. Clean syncs are always applied in a single
+///  transaction.
+///  Defaults to 0 (all rules are applied in a single transaction).
+///
+@property(readonly, nonatomic) NSUInteger ruleApplyBatchSize;
+
+///
+///  If YES, execution decisions look up rules in an in-memory copy of the execution rules instead
+///  of the rule database. Each rule update builds a new copy and swaps it in once the update has
+///  been written, so decisions are never stalled by a sync and never see a partially written
+///  update. Uses more memory with large rule sets. Only read when santad starts.
+///  Defaults to NO.
+///
+@property(readonly, nonatomic) BOOL enableRuleSnapshot;
+
+///
+///  How execution rules for the same identifier and rule type are resolved when one update
+///  contains more than one of them. Supported values are:
+///    * "LastWins": The rule that appears last is applied.
+///    * "HighestPrecedence": The most restrictive rule is applied. Block rules win over CEL and
+///      Seatbelt rules, which win over allow rules, which win over removals. Rules with the same
+///      precedence fall back to the last one.
+///
+///  Defaults to LastWins.
+///
+@property(readonly, nonatomic) SNTDuplicateRuleResolution duplicateRuleResolution;
+
+///
+///  The number of seconds after a clean sync during which notifications and immediate uploads
+///  for executions of unknown binaries are throttled. A clean sync on a busy host can cause many
+///  unknown binaries to be seen at once; during the warm-up, each binary is only notified about
+///  once and at most cleanSyncWarmupNotificationLimit notifications and
+///  cleanSyncWarmupUploadLimit immediate uploads are made. Events that are not uploaded
+///  immediately are still stored and uploaded with the next sync. Blocks due to rules and
+///  executions held for approval are never throttled. Defaults to 0 (disabled), maximum 3600.
+///
+@property(readonly, nonatomic) NSUInteger cleanSyncWarmupSec;
+
+///
+///  The maximum number of GUI notifications shown for unknown binaries during the clean sync
+///  warm-up. Defaults to 3.
+///
+@property(readonly, nonatomic) NSUInteger cleanSyncWarmupNotificationLimit;
+
+///
+///  The maximum number of unknown binary events uploaded immediately during the clean sync
+///  warm-up. Defaults to 20.
+///
+@property(readonly, nonatomic) NSUInteger cleanSyncWarmupUploadLimit;
+
+///
+///  If greater than zero, rule updates from the sync service are deferred while the 1-minute load
+///  average, as a percentage of the number of CPUs, is above this value. Block rules in a
+///  deferred update are still applied immediately. Deferred updates are applied once the load
+///  drops or after ruleApplicationMaxDeferralSec. Defaults to 0 (disabled).
+///
+@property(readonly, nonatomic) NSUInteger ruleApplicationDeferralLoadPercent;
+
+///
+///  The maximum number of seconds a rule update is deferred due to high load before it is
+///  applied anyway. Defaults to 600, maximum 3600.
+///
+@property(readonly, nonatomic) NSUInteger ruleApplicationMaxDeferralSec;
+
 ///
 ///  If true, events will be uploaded for all executions, even those that are allowed.
 ///  Use with caution, this generates a lot of events. Defaults to false.
@@ -1127,6 +1729,81 @@ extern NSString* _Nonnull const kStateTempAdminTargetUIDKey;
 ///
 @property(readonly, nonatomic) BOOL enablePushNotifications;
 
+///
+///  The number of days before the NATS push server's TLS certificate expires at which the sync
+///  service starts warning about it. The certificate is checked each time the push client
+///  connects. Set to 0 to disable the warning. Defaults to 30.
+///
+@property(readonly, nonatomic) NSUInteger pushServerCertificateExpiryWarningDays;
+
+///
+///  SHA-256 fingerprints, as hex and optionally colon-separated, of the push server certificates
+///  the push client accepts. If set, the push server's verified chain must contain at least one
+///  of them, either the leaf or a CA certificate, in addition to passing the usual validation.
+///  Returned lowercased without separators. Malformed entries are dropped, so if none are valid
+///  the push client refuses every connection. nil if unset or empty.
+///
+@property(nullable, readonly, nonatomic) NSArray<NSString*>* pushServerPinnedCertificateSHA256;
+
+///
+///  If true, the sync service reports a push server certificate that is about to expire (see
+///  pushServerCertificateExpiryWarningDays) to the sync server on postflight. Defaults to false.
+///
+@property(readonly, nonatomic) BOOL uploadPushServerCertificateExpiryWarning;
+
+///
+///  If true, the push client's connection stats (bytes in and out, messages by subject,
+///  subscribes, reconnects and the last round trip time) are also recorded as sync service
+///  metrics and published to the sync server's metrics endpoint. The stats are always available
+///  from `santactl push stats`. Changes take effect when the sync service restarts. Defaults to
+///  false.
+///
+@property(readonly, nonatomic) BOOL exportPushConnectionMetrics;
+
+///
+///  The base64-encoded Ed25519 public key used to verify rules carried inline in an apply_rules
+///  push notification. If unset, inline rules are rejected.
+///
+@property(nullable, readonly, nonatomic) NSData* pushInlineRulesPublicKey;
+
+///
+///  The full sync interval, in seconds, used while the push server rejects the host's push
+///  credentials, e.g. because they were revoked. The push client stops reconnecting with the
+///  rejected credentials and the host polls at this interval until a preflight provides new ones.
+///  Only shortens the push notifications full sync interval, never lengthens it. Defaults to 600,
+///  the minimum is 60.
+///
+@property(readonly, nonatomic) NSUInteger pushCredentialsRejectedFullSyncInterval;
+
+///
+///  The window, in seconds, within which a repeat of a push notification is ignored. The same
+///  message can arrive on the host subject and on several tag subjects; only the first one
+///  triggers a sync. Messages are matched on their Nats-Msg-Id header, or on their headers and
+///  payload if they don't have one. Defaults to 5, 0 disables deduplication.
+///
+@property(readonly, nonatomic) NSTimeInterval pushMessageDedupeWindow;
+
+///
+///  Push notifications can trigger up to PushSyncRateLimitBurst full syncs back to back, then one
+///  every PushSyncRateLimitInterval seconds. Notifications beyond that don't trigger syncs of
+///  their own; a single sync runs once the limit allows. Defaults to 30, 0 disables the limit.
+///
+@property(readonly, nonatomic) NSTimeInterval pushSyncRateLimitInterval;
+
+///
+///  The number of full syncs push notifications can trigger back to back. See
+///  PushSyncRateLimitInterval. Defaults to 2, 0 disables the limit.
+///
+@property(readonly, nonatomic) NSUInteger pushSyncRateLimitBurst;
+
+///
+///  If true and the sync server reports a minimum OS version the host is below, a Lockdown client
+///  mode from the sync server is applied as Monitor instead, as older OS versions may lack
+///  Endpoint Security features that Lockdown relies on. Defaults to false, which only logs a
+///  warning.
+///
+@property(readonly, nonatomic) BOOL refuseLockdownBelowMinimumOSVersion;
+
 ///
 /// True if metricsFormat and metricsURL are set. False otherwise.
 ///
@@ -1224,6 +1901,42 @@ extern NSString* _Nonnull const kStateTempAdminTargetUIDKey;
 ///
 - (nullable NSArray*)validateConfiguration;
 
+///
+///  The keys and values set by the configuration profile, as written in the profile.
+///
+- (nonnull NSDictionary<NSString*, id>*)configProfile;
+
+///
+///  The configuration profile settings as parsed by this configurator, in a form that can be sent
+///  over XPC. Regular expressions are replaced by their pattern.
+///
+- (nonnull NSDictionary<NSString*, id>*)parsedConfigProfile;
+
+///
+///  Compares the settings in a configuration profile (see configProfile) with the settings a
+///  daemon parsed from it (see parsedConfigProfile). Returns one dictionary per problem found,
+///  sorted by key, holding the profile key (kConfigIssueKey), the kind of problem
+///  (kConfigIssueType) and a description (kConfigIssueDetail). The kinds of problem are:
+///    * ignored: The key is not recognized or is superseded by another key.
+///    * malformed: The value has the wrong type or failed to parse.
+///    * defaulted: The value is not one the key supports, so the default is used.
+///    * not_applied: The daemon's value differs from the profile, e.g. it has not reloaded it.
+///
+- (nonnull NSArray<NSDictionary<NSString*, NSString*>*>*)
+    configIssuesForProfile:(nonnull NSDictionary*)profile
+              parsedConfig:(nonnull NSDictionary*)parsed;
+
+///
+///  The keys and issue types of the dictionaries returned by configIssuesForProfile:parsedConfig:.
+///
+extern NSString* _Nonnull const kConfigIssueKey;
+extern NSString* _Nonnull const kConfigIssueType;
+extern NSString* _Nonnull const kConfigIssueDetail;
+extern NSString* _Nonnull const kConfigIssueTypeIgnored;
+extern NSString* _Nonnull const kConfigIssueTypeMalformed;
+extern NSString* _Nonnull const kConfigIssueTypeDefaulted;
+extern NSString* _Nonnull const kConfigIssueTypeNotApplied;
+
 ///
 /// Returns true if the system has rebooted since the last time santad was run.
 ///
@@ -15,7 +15,9 @@
 
 #import "Source/common/SNTConfigurator.h"
 
+#include <CommonCrypto/CommonDigest.h>
 #include <sys/stat.h>
+#include <algorithm>
 #include <set>
 #include <string>
 
@@ -26,8 +28,10 @@
 #import "Source/common/SNTExportConfiguration.h"
 #import "Source/common/SNTLiteDetector.h"
 #import "Source/common/SNTLogging.h"
+#import "Source/common/SNTMaintenanceWindow.h"
 #import "Source/common/SNTModeTransition.h"
 #import "Source/common/SNTRule.h"
+#import "Source/common/SNTRuleSource.h"
 #import "Source/common/SNTStrengthify.h"
 #import "Source/common/SNTSyncConstants.h"
 #import "Source/common/SNTSystemInfo.h"
@@ -56,6 +60,57 @@ static NSArray<NSString*>* EnsureArrayOfStrings(id obj) {
   return obj;
 }
 
+// Appends a canonical representation of a property list value to out. Dictionary keys are
+// sorted so that equal configurations always produce the same output.
+static void AppendCanonicalConfigValue(id value, NSMutableString* out) {
+  if ([value isKindOfClass:[NSDictionary class]]) {
+    NSDictionary* dict = value;
+    [out appendString:@"{"];
+    for (id key in [dict.allKeys sortedArrayUsingSelector:@selector(compare:)]) {
+      AppendCanonicalConfigValue(key, out);
+      [out appendString:@"="];
+      AppendCanonicalConfigValue(dict[key], out);
+      [out appendString:@";"];
+    }
+    [out appendString:@"}"];
+  } else if ([value isKindOfClass:[NSArray class]]) {
+    [out appendString:@"["];
+    for (id item in value) {
+      AppendCanonicalConfigValue(item, out);
+      [out appendString:@","];
+    }
+    [out appendString:@"]"];
+  } else if ([value isKindOfClass:[NSString class]]) {
+    [out appendFormat:@"s%lu:%@", (unsigned long)[value length], value];
+  } else if ([value isKindOfClass:[NSData class]]) {
+    [out appendFormat:@"d:%@", [value base64EncodedStringWithOptions:0]];
+  } else if ([value isKindOfClass:[NSDate class]]) {
+    [out appendFormat:@"t:%f", [value timeIntervalSince1970]];
+  } else if ([value isKindOfClass:[NSNumber class]]) {
+    [out appendFormat:@"n:%@", [value stringValue]];
+  } else if ([value isKindOfClass:[NSRegularExpression class]]) {
+    [out appendFormat:@"r:%@", [value pattern]];
+  } else {
+    [out appendFormat:@"?:%@", NSStringFromClass([value class])];
+  }
+}
+
+// Returns the hex-encoded SHA-256 of the canonical representation of value.
+static NSString* CanonicalConfigSHA256(id value) {
+  NSMutableString* canonical = [NSMutableString string];
+  AppendCanonicalConfigValue(value, canonical);
+
+  const char* str = canonical.UTF8String;
+  unsigned char digest[CC_SHA256_DIGEST_LENGTH];
+  CC_SHA256(str, (CC_LONG)strlen(str), digest);
+
+  NSMutableString* hash = [NSMutableString stringWithCapacity:CC_SHA256_DIGEST_LENGTH * 2];
+  for (int i = 0; i < CC_SHA256_DIGEST_LENGTH; ++i) {
+    [hash appendFormat:@"%02x", digest[i]];
+  }
+  return hash;
+}
+
 static SNTRemovableMediaAction ActionFromString(NSString* action) {
   if (!action) return SNTRemovableMediaActionAllow;
   if ([action caseInsensitiveCompare:@"Allow"] == NSOrderedSame) {
@@ -97,6 +152,7 @@ typedef BOOL (^StateFileAccessAuthorizer)(void);
 
 // Re-declare read/write for KVO
 @property BOOL inTemporaryMonitorMode;
+@property SNTClientMode offlineGraceClientMode;
 
 // Internal boot session tracking
 @property(nullable) NSString* lastBootUUID;
@@ -117,10 +173,21 @@ NSString* const kStateTempAdminModeKey = @"TempAdmin";
 NSString* const kStateTempAdminTargetUIDKey = @"TargetUID";
 static NSString* const kStateDemotedAdminsKey = @"DemotedAdmins";
 static NSString* const kStateLastBootUUIDKey = @"LastBootUUID";
+static NSString* const kStateConsumedAllowOnceTokensKey = @"ConsumedAllowOnceTokens";
+static NSString* const kStateConsumedPushRuleBundlesKey = @"ConsumedPushRuleBundles";
 
 /// User defaults key for user override of the menu item enabled setting.
 NSString* const kEnableMenuItemUserOverride = @"EnableMenuItemUserOverride";
 
+/// Keys and issue types of the configuration profile issues found by configIssuesForProfile:.
+NSString* const kConfigIssueKey = @"key";
+NSString* const kConfigIssueType = @"issue";
+NSString* const kConfigIssueDetail = @"detail";
+NSString* const kConfigIssueTypeIgnored = @"ignored";
+NSString* const kConfigIssueTypeMalformed = @"malformed";
+NSString* const kConfigIssueTypeDefaulted = @"defaulted";
+NSString* const kConfigIssueTypeNotApplied = @"not_applied";
+
 #ifdef DEBUG
 NSString* const kConfigOverrideFilePath = @"/var/db/santa/config-overrides.plist";
 #endif
@@ -130,11 +197,28 @@ static const CFStringRef kMobileConfigDomain = CFSTR("com.northpolesec.santa");
 
 /// The keys managed by a mobileconfig.
 static NSString* const kStaticRulesKey = @"StaticRules";
+static NSString* const kRuleSourcesKey = @"RuleSources";
 static NSString* const kSyncBaseURLKey = @"SyncBaseURL";
 static NSString* const kSyncEnableProtoTransfer = @"SyncEnableProtoTransfer";
 static NSString* const kSyncProxyConfigKey = @"SyncProxyConfiguration";
 static NSString* const kSyncExtraHeadersKey = @"SyncExtraHeaders";
 static NSString* const kSyncEnableCleanSyncEventUpload = @"SyncEnableCleanSyncEventUpload";
+static NSString* const kSyncMaxEventAgeSecKey = @"SyncMaxEventAgeSec";
+static NSString* const kDisableEventUploadKey = @"DisableEventUpload";
+static NSString* const kSyncEventUploadConcurrencyKey = @"SyncEventUploadConcurrency";
+static NSString* const kSyncRuleDownloadConcurrencyKey = @"SyncRuleDownloadConcurrency";
+static NSString* const kSyncCircuitBreakerFailureThresholdKey =
+    @"SyncCircuitBreakerFailureThreshold";
+static NSString* const kSyncCircuitBreakerCooldownSecKey = @"SyncCircuitBreakerCooldownSec";
+static NSString* const kRuleApplyBatchSizeKey = @"RuleApplyBatchSize";
+static NSString* const kEnableRuleSnapshotKey = @"EnableRuleSnapshot";
+static NSString* const kDuplicateRuleResolutionKey = @"DuplicateRuleResolution";
+static NSString* const kCleanSyncWarmupSecKey = @"CleanSyncWarmupSec";
+static NSString* const kCleanSyncWarmupNotificationLimitKey = @"CleanSyncWarmupNotificationLimit";
+static NSString* const kCleanSyncWarmupUploadLimitKey = @"CleanSyncWarmupUploadLimit";
+static NSString* const kRuleApplicationDeferralLoadPercentKey =
+    @"RuleApplicationDeferralLoadPercent";
+static NSString* const kRuleApplicationMaxDeferralSecKey = @"RuleApplicationMaxDeferralSec";
 static NSString* const kClientAuthCertificateFileKey = @"ClientAuthCertificateFile";
 static NSString* const kClientAuthCertificatePasswordKey = @"ClientAuthCertificatePassword";
 static NSString* const kClientAuthCertificateCNKey = @"ClientAuthCertificateCN";
@@ -175,18 +259,67 @@ static NSString* const kEnableMenuItem = @"EnableMenuItem";
 
 static NSString* const kEnablePageZeroProtectionKey = @"EnablePageZeroProtection";
 static NSString* const kEnableBadSignatureProtectionKey = @"EnableBadSignatureProtection";
+static NSString* const kCanonicalizeExecutablePathsKey = @"CanonicalizeExecutablePaths";
+static NSString* const kEnableScriptEvaluationKey = @"EnableScriptEvaluation";
+static NSString* const kEnableInterpreterScriptEvaluationKey =
+    @"EnableInterpreterScriptEvaluation";
+static NSString* const kResignProtectedBlocklistKey = @"ResignProtectedBlocklist";
+static NSString* const kDecisionHookSocketPathKey = @"DecisionHookSocketPath";
+static NSString* const kDecisionHookTimeoutMillisecondsKey = @"DecisionHookTimeoutMilliseconds";
+static NSString* const kDecisionHookFailClosedKey = @"DecisionHookFailClosed";
 static NSString* const kEnableAntiTamperProcessSuspendResumeKey =
     @"EnableAntiTamperProcessSuspendResume";
 static NSString* const kAntiSuspendSigningIDsKey = @"AntiSuspendSigningIDs";
 static NSString* const kAllowDelegatedSignalsKey = @"AllowDelegatedSignals";
 static NSString* const kFailClosedKey = @"FailClosed";
 static NSString* const kDisableUnknownEventUploadKey = @"DisableUnknownEventUpload";
+static NSString* const kClockTamperingActionKey = @"ClockTamperingAction";
+static NSString* const kClockTamperingThresholdSecKey = @"ClockTamperingThresholdSec";
+static NSString* const kNetworkVolumeExecutionActionKey = @"NetworkVolumeExecutionAction";
+static NSString* const kDiskImageExecutionActionKey = @"DiskImageExecutionAction";
+static NSString* const kDiskImageExecutionQuarantinedOnlyKey = @"DiskImageExecutionQuarantinedOnly";
+static NSString* const kPathChurnExecutionActionKey = @"PathChurnExecutionAction";
+static NSString* const kPathChurnThresholdKey = @"PathChurnThreshold";
+static NSString* const kPathChurnWindowSecondsKey = @"PathChurnWindowSeconds";
+static NSString* const kSigningStatusExecutionActionsKey = @"SigningStatusExecutionActions";
+static NSString* const kCodeSignatureInvalidationResponseKey =
+    @"CodeSignatureInvalidationResponse";
+static NSString* const kLaunchItemPolicyKey = @"LaunchItemPolicy";
+static NSString* const kRequireLibraryValidationForTeamIDRulesKey =
+    @"RequireLibraryValidationForTeamIDRules";
+static NSString* const kSyncInFlightExecutionPolicyKey = @"SyncInFlightExecutionPolicy";
+static NSString* const kSyncInFlightMaxDeferralMillisecondsKey =
+    @"SyncInFlightMaxDeferralMilliseconds";
+static NSString* const kEnableDecisionDatabaseKey = @"EnableDecisionDatabase";
+static NSString* const kDecisionDatabaseMaxDecisionsKey = @"DecisionDatabaseMaxDecisions";
+static NSString* const kRulePrecedenceKey = @"RulePrecedence";
+static NSString* const kEnableDeveloperToolsAllowlistKey = @"EnableDeveloperToolsAllowlist";
+static NSString* const kDeveloperToolsAllowlistKey = @"DeveloperToolsAllowlist";
+static NSString* const kPushServerPinnedCertificateSHA256Key =
+    @"PushServerPinnedCertificateSHA256";
+static NSString* const kPushServerCertificateExpiryWarningDaysKey =
+    @"PushServerCertificateExpiryWarningDays";
+static NSString* const kUploadPushServerCertificateExpiryWarningKey =
+    @"UploadPushServerCertificateExpiryWarning";
+static NSString* const kExportPushConnectionMetricsKey = @"ExportPushConnectionMetrics";
+static NSString* const kPushInlineRulesPublicKeyKey = @"PushInlineRulesPublicKey";
+static NSString* const kPushCredentialsRejectedFullSyncIntervalKey =
+    @"PushCredentialsRejectedFullSyncInterval";
+static NSString* const kPushMessageDedupeWindowKey = @"PushMessageDedupeWindow";
+static NSString* const kPushSyncRateLimitIntervalKey = @"PushSyncRateLimitInterval";
+static NSString* const kPushSyncRateLimitBurstKey = @"PushSyncRateLimitBurst";
+static NSString* const kRefuseLockdownBelowMinimumOSVersionKey =
+    @"RefuseLockdownBelowMinimumOSVersion";
+static NSString* const kAllowOnceTokenPublicKeyKey = @"AllowOnceTokenPublicKey";
+static NSString* const kRegenerateMachineIDOnConflictKey = @"RegenerateMachineIDOnConflict";
 
 static NSString* const kFileChangesRegexKey = @"FileChangesRegex";
 static NSString* const kFileChangesPrefixFiltersKey = @"FileChangesPrefixFilters";
 
 static NSString* const kEventLogType = @"EventLogType";
 static NSString* const kEventLogPath = @"EventLogPath";
+static NSString* const kEventLogBufferSizeKB = @"EventLogBufferSizeKB";
+static NSString* const kEventLogMaxFlushTimeSec = @"EventLogMaxFlushTimeSec";
 static NSString* const kSpoolDirectory = @"SpoolDirectory";
 static NSString* const kSpoolDirectoryFileSizeThresholdKB = @"SpoolDirectoryFileSizeThresholdKB";
 static NSString* const kSpoolDirectorySizeThresholdMB = @"SpoolDirectorySizeThresholdMB";
@@ -224,6 +357,7 @@ static NSString* const kEnableNATS =
 static NSString* const kEntitlementsPrefixFilterKey = @"EntitlementsPrefixFilter";
 static NSString* const kEntitlementsTeamIDFilterKey = @"EntitlementsTeamIDFilter";
 static NSString* const kTelemetryFilterExpressionsKey = @"TelemetryFilterExpressions";
+static NSString* const kTelemetrySampleRateKey = @"TelemetrySampleRate";
 static NSString* const kBinaryUploadFilterExpressionsKey = @"BinaryUploadFilterExpressions";
 static NSString* const kCELFallbackRulesKey = @"CELFallbackRules";
 
@@ -240,6 +374,12 @@ static NSString* const kAllowedSantaCommandsKey = @"AllowedSantaCommands";
 
 // The keys managed by a sync server or mobileconfig.
 static NSString* const kClientModeKey = @"ClientMode";
+static NSString* const kMaintenanceWindowsKey = @"MaintenanceWindows";
+static NSString* const kLockdownGracePeriodSecKey = @"LockdownGracePeriodSec";
+static NSString* const kOfflineGraceTokenKey = @"OfflineGraceToken";
+static NSString* const kOfflineGraceTokenPublicKeyKey = @"OfflineGraceTokenPublicKey";
+static NSString* const kOfflineGraceFallbackClientModeKey = @"OfflineGraceFallbackClientMode";
+static NSString* const kOfflineGraceSyncAgeSecKey = @"OfflineGraceSyncAgeSec";
 static NSString* const kBlockUSBMountKey = @"BlockUSBMount";
 static NSString* const kRemountUSBModeKey = @"RemountUSBMode";
 static NSString* const kRemovableMediaActionKey = @"RemovableMediaAction";
@@ -268,12 +408,14 @@ static NSString* const kFullSyncLastSuccess = @"FullSyncLastSuccess";
 static NSString* const kRuleSyncLastSuccess = @"RuleSyncLastSuccess";
 static NSString* const kSyncCleanRequiredDeprecated = @"SyncCleanRequired";
 static NSString* const kSyncTypeRequired = @"SyncTypeRequired";
+static NSString* const kDeferredClientModeKey = @"DeferredClientMode";
 static NSString* const kExportConfigurationKey = @"ExportConfiguration";
 static NSString* const kModeTransitionKey = @"ModeTransition";
 static NSString* const kTemporaryAdminPolicyKey = @"TemporaryAdminPolicy";
 static NSString* const kNetworkExtensionSettingsKey = @"NetworkExtensionSettings";
 static NSString* const kDNSUpstreamTimeoutSecondsKey = @"DNSUpstreamTimeoutSeconds";
 static NSString* const kPushTokenChainKey = @"PushTokenChain";
+static NSString* const kGeneratedMachineIDKey = @"GeneratedMachineID";
 
 - (instancetype)init {
   return [self initWithSyncStateFile:kSyncStateFilePath
@@ -322,6 +464,7 @@ static NSString* const kPushTokenChainKey = @"PushTokenChain";
       kRuleSyncLastSuccess : date,
       kSyncCleanRequiredDeprecated : number,
       kSyncTypeRequired : number,
+      kDeferredClientModeKey : number,
       kEnableAllEventUploadKey : number,
       kOverrideFileAccessActionKey : string,
       kEnableBundlesKey : number,
@@ -330,7 +473,9 @@ static NSString* const kPushTokenChainKey = @"PushTokenChain";
       kTemporaryAdminPolicyKey : data,
       kNetworkExtensionSettingsKey : data,
       kPushTokenChainKey : array,
+      kGeneratedMachineIDKey : string,
       kTelemetryFilterExpressionsKey : array,
+      kTelemetrySampleRateKey : number,
       kBinaryUploadFilterExpressionsKey : array,
       kCELFallbackRulesKey : data,
       kEventDetailURLKey : string,
@@ -342,6 +487,12 @@ static NSString* const kPushTokenChainKey = @"PushTokenChain";
     };
     _forcedConfigKeyTypes = @{
       kClientModeKey : number,
+      kMaintenanceWindowsKey : array,
+      kLockdownGracePeriodSecKey : number,
+      kOfflineGraceTokenKey : string,
+      kOfflineGraceTokenPublicKeyKey : string,
+      kOfflineGraceFallbackClientModeKey : number,
+      kOfflineGraceSyncAgeSecKey : number,
       kFailClosedKey : number,
       kEnableTransitiveRulesKey : number,
       kEnableTransitiveRulesKeyDeprecated : number,
@@ -360,9 +511,47 @@ static NSString* const kPushTokenChainKey = @"PushTokenChain";
       kOnStartUSBOptions : string,
       kEnablePageZeroProtectionKey : number,
       kEnableBadSignatureProtectionKey : number,
+      kCanonicalizeExecutablePathsKey : number,
+      kEnableScriptEvaluationKey : number,
+      kEnableInterpreterScriptEvaluationKey : number,
+      kResignProtectedBlocklistKey : array,
+      kDecisionHookSocketPathKey : string,
+      kDecisionHookTimeoutMillisecondsKey : number,
+      kDecisionHookFailClosedKey : number,
       kEnableAntiTamperProcessSuspendResumeKey : number,
       kAntiSuspendSigningIDsKey : array,
       kAllowDelegatedSignalsKey : number,
+      kClockTamperingActionKey : string,
+      kClockTamperingThresholdSecKey : number,
+      kNetworkVolumeExecutionActionKey : string,
+      kDiskImageExecutionActionKey : string,
+      kDiskImageExecutionQuarantinedOnlyKey : number,
+      kPathChurnExecutionActionKey : string,
+      kPathChurnThresholdKey : number,
+      kPathChurnWindowSecondsKey : number,
+      kSigningStatusExecutionActionsKey : dictionary,
+      kCodeSignatureInvalidationResponseKey : string,
+      kLaunchItemPolicyKey : string,
+      kRequireLibraryValidationForTeamIDRulesKey : number,
+      kSyncInFlightExecutionPolicyKey : string,
+      kSyncInFlightMaxDeferralMillisecondsKey : number,
+      kEnableDecisionDatabaseKey : number,
+      kDecisionDatabaseMaxDecisionsKey : number,
+      kRulePrecedenceKey : array,
+      kEnableDeveloperToolsAllowlistKey : number,
+      kDeveloperToolsAllowlistKey : array,
+      kPushServerPinnedCertificateSHA256Key : array,
+      kPushServerCertificateExpiryWarningDaysKey : number,
+      kUploadPushServerCertificateExpiryWarningKey : number,
+      kExportPushConnectionMetricsKey : number,
+      kPushInlineRulesPublicKeyKey : string,
+      kPushCredentialsRejectedFullSyncIntervalKey : number,
+      kPushMessageDedupeWindowKey : number,
+      kPushSyncRateLimitIntervalKey : number,
+      kPushSyncRateLimitBurstKey : number,
+      kRefuseLockdownBelowMinimumOSVersionKey : number,
+      kAllowOnceTokenPublicKeyKey : string,
+      kRegenerateMachineIDOnConflictKey : number,
       kEnableStandalonePasswordFallbackKey : number,
       kEnableSilentModeKey : number,
       kEnableSilentTTYModeKey : number,
@@ -384,9 +573,24 @@ static NSString* const kPushTokenChainKey = @"PushTokenChain";
       kFunFontsOnSpecificDays : number,
       kEnableMenuItem : number,
       kStaticRulesKey : array,
+      kRuleSourcesKey : array,
       kSyncBaseURLKey : string,
       kSyncEnableProtoTransfer : number,
       kSyncEnableCleanSyncEventUpload : number,
+      kSyncMaxEventAgeSecKey : number,
+      kDisableEventUploadKey : number,
+      kSyncEventUploadConcurrencyKey : number,
+      kSyncRuleDownloadConcurrencyKey : number,
+      kSyncCircuitBreakerFailureThresholdKey : number,
+      kSyncCircuitBreakerCooldownSecKey : number,
+      kRuleApplyBatchSizeKey : number,
+      kEnableRuleSnapshotKey : number,
+      kDuplicateRuleResolutionKey : string,
+      kCleanSyncWarmupSecKey : number,
+      kCleanSyncWarmupNotificationLimitKey : number,
+      kCleanSyncWarmupUploadLimitKey : number,
+      kRuleApplicationDeferralLoadPercentKey : number,
+      kRuleApplicationMaxDeferralSecKey : number,
       kSyncProxyConfigKey : dictionary,
       kSyncExtraHeadersKey : dictionary,
       kClientAuthCertificateFileKey : string,
@@ -406,6 +610,8 @@ static NSString* const kPushTokenChainKey = @"PushTokenChain";
       kMachineIDPlistKeyKey : string,
       kEventLogType : string,
       kEventLogPath : string,
+      kEventLogBufferSizeKB : number,
+      kEventLogMaxFlushTimeSec : number,
       kSpoolDirectory : string,
       kSpoolDirectoryFileSizeThresholdKB : number,
       kSpoolDirectorySizeThresholdMB : number,
@@ -545,8 +751,10 @@ static SNTConfigurator* sharedConfigurator = nil;
 #pragma mark KVO Dependencies
 
 + (NSSet*)keyPathsForValuesAffectingClientMode {
-  return [[self syncAndConfigStateSet]
-      setByAddingObject:NSStringFromSelector(@selector(inTemporaryMonitorMode))];
+  return [[self syncAndConfigStateSet] setByAddingObjectsFromArray:@[
+    NSStringFromSelector(@selector(inTemporaryMonitorMode)),
+    NSStringFromSelector(@selector(offlineGraceClientMode)),
+  ]];
 }
 
 + (NSSet*)keyPathsForValuesAffectingAllowedPathRegex {
@@ -569,6 +777,22 @@ static SNTConfigurator* sharedConfigurator = nil;
   return [self configStateSet];
 }
 
++ (NSSet*)keyPathsForValuesAffectingRuleSources {
+  return [self configStateSet];
+}
+
++ (NSSet*)keyPathsForValuesAffectingMaintenanceWindows {
+  return [self configStateSet];
+}
+
++ (NSSet*)keyPathsForValuesAffectingDeferredClientMode {
+  return [self syncStateSet];
+}
+
++ (NSSet*)keyPathsForValuesAffectingLockdownGracePeriodSec {
+  return [self configStateSet];
+}
+
 + (NSSet*)keyPathsForValuesAffectingSyncBaseURL {
   return [self configStateSet];
 }
@@ -589,10 +813,90 @@ static SNTConfigurator* sharedConfigurator = nil;
   return [self configStateSet];
 }
 
++ (NSSet*)keyPathsForValuesAffectingSyncMaxEventAgeSec {
+  return [self configStateSet];
+}
+
++ (NSSet*)keyPathsForValuesAffectingDisableEventUpload {
+  return [self configStateSet];
+}
+
++ (NSSet*)keyPathsForValuesAffectingSyncEventUploadConcurrency {
+  return [self configStateSet];
+}
+
++ (NSSet*)keyPathsForValuesAffectingSyncRuleDownloadConcurrency {
+  return [self configStateSet];
+}
+
++ (NSSet*)keyPathsForValuesAffectingSyncCircuitBreakerFailureThreshold {
+  return [self configStateSet];
+}
+
++ (NSSet*)keyPathsForValuesAffectingSyncCircuitBreakerCooldownSec {
+  return [self configStateSet];
+}
+
++ (NSSet*)keyPathsForValuesAffectingRuleApplyBatchSize {
+  return [self configStateSet];
+}
+
++ (NSSet*)keyPathsForValuesAffectingEnableRuleSnapshot {
+  return [self configStateSet];
+}
+
++ (NSSet*)keyPathsForValuesAffectingDuplicateRuleResolution {
+  return [self configStateSet];
+}
+
++ (NSSet*)keyPathsForValuesAffectingCleanSyncWarmupSec {
+  return [self configStateSet];
+}
+
++ (NSSet*)keyPathsForValuesAffectingCleanSyncWarmupNotificationLimit {
+  return [self configStateSet];
+}
+
++ (NSSet*)keyPathsForValuesAffectingCleanSyncWarmupUploadLimit {
+  return [self configStateSet];
+}
+
++ (NSSet*)keyPathsForValuesAffectingRuleApplicationDeferralLoadPercent {
+  return [self configStateSet];
+}
+
++ (NSSet*)keyPathsForValuesAffectingRuleApplicationMaxDeferralSec {
+  return [self configStateSet];
+}
+
 + (NSSet*)keyPathsForValuesAffectingEnablePageZeroProtection {
   return [self configStateSet];
 }
 
++ (NSSet*)keyPathsForValuesAffectingCanonicalizeExecutablePaths {
+  return [self configStateSet];
+}
+
++ (NSSet*)keyPathsForValuesAffectingEnableScriptEvaluation {
+  return [self configStateSet];
+}
+
++ (NSSet*)keyPathsForValuesAffectingEnableInterpreterScriptEvaluation {
+  return [self configStateSet];
+}
+
++ (NSSet*)keyPathsForValuesAffectingResignProtectedBlocklist {
+  return [self configStateSet];
+}
+
++ (NSSet*)keyPathsForValuesAffectingConfigHash {
+  return [self configStateSet];
+}
+
++ (NSSet*)keyPathsForValuesAffectingConfigSnapshot {
+  return [self configStateSet];
+}
+
 + (NSSet*)keyPathsForValuesAffectingEnableStandalonePasswordFallback {
   return [self configStateSet];
 }
@@ -706,7 +1010,11 @@ static SNTConfigurator* sharedConfigurator = nil;
 }
 
 + (NSSet*)keyPathsForValuesAffectingMachineID {
-  return [self configStateSet];
+  return [[self configStateSet] setByAddingObjectsFromSet:[self syncStateSet]];
+}
+
++ (NSSet*)keyPathsForValuesAffectingGeneratedMachineID {
+  return [self syncStateSet];
 }
 
 + (NSSet*)keyPathsForValuesAffectingFullSyncLastSuccess {
@@ -729,6 +1037,14 @@ static SNTConfigurator* sharedConfigurator = nil;
   return [self configStateSet];
 }
 
++ (NSSet*)keyPathsForValuesAffectingEventLogBufferSizeKB {
+  return [self configStateSet];
+}
+
++ (NSSet*)keyPathsForValuesAffectingEventLogMaxFlushTimeSec {
+  return [self configStateSet];
+}
+
 + (NSSet*)keyPathsForValuesAffectingSpoolDirectory {
   return [self configStateSet];
 }
@@ -817,6 +1133,18 @@ static SNTConfigurator* sharedConfigurator = nil;
   return [self configStateSet];
 }
 
++ (NSSet*)keyPathsForValuesAffectingDecisionHookSocketPath {
+  return [self configStateSet];
+}
+
++ (NSSet*)keyPathsForValuesAffectingDecisionHookTimeoutMilliseconds {
+  return [self configStateSet];
+}
+
++ (NSSet*)keyPathsForValuesAffectingDecisionHookFailClosed {
+  return [self configStateSet];
+}
+
 + (NSSet*)keyPathsForValuesAffectingEnableAntiTamperProcessSuspendResume {
   return [self configStateSet];
 }
@@ -829,6 +1157,146 @@ static SNTConfigurator* sharedConfigurator = nil;
   return [self configStateSet];
 }
 
++ (NSSet*)keyPathsForValuesAffectingClockTamperingAction {
+  return [self configStateSet];
+}
+
++ (NSSet*)keyPathsForValuesAffectingClockTamperingThresholdSec {
+  return [self configStateSet];
+}
+
++ (NSSet*)keyPathsForValuesAffectingNetworkVolumeExecutionAction {
+  return [self configStateSet];
+}
+
++ (NSSet*)keyPathsForValuesAffectingDiskImageExecutionAction {
+  return [self configStateSet];
+}
+
++ (NSSet*)keyPathsForValuesAffectingDiskImageExecutionQuarantinedOnly {
+  return [self configStateSet];
+}
+
++ (NSSet*)keyPathsForValuesAffectingPathChurnExecutionAction {
+  return [self configStateSet];
+}
+
++ (NSSet*)keyPathsForValuesAffectingPathChurnThreshold {
+  return [self configStateSet];
+}
+
++ (NSSet*)keyPathsForValuesAffectingPathChurnWindowSeconds {
+  return [self configStateSet];
+}
+
++ (NSSet*)keyPathsForValuesAffectingSigningStatusExecutionActions {
+  return [self configStateSet];
+}
+
++ (NSSet*)keyPathsForValuesAffectingCodeSignatureInvalidationResponse {
+  return [self configStateSet];
+}
+
++ (NSSet*)keyPathsForValuesAffectingLaunchItemPolicy {
+  return [self configStateSet];
+}
+
++ (NSSet*)keyPathsForValuesAffectingRequireLibraryValidationForTeamIDRules {
+  return [self configStateSet];
+}
+
++ (NSSet*)keyPathsForValuesAffectingSyncInFlightExecutionPolicy {
+  return [self configStateSet];
+}
+
++ (NSSet*)keyPathsForValuesAffectingSyncInFlightMaxDeferralMilliseconds {
+  return [self configStateSet];
+}
+
++ (NSSet*)keyPathsForValuesAffectingEnableDecisionDatabase {
+  return [self configStateSet];
+}
+
++ (NSSet*)keyPathsForValuesAffectingDecisionDatabaseMaxDecisions {
+  return [self configStateSet];
+}
+
++ (NSSet*)keyPathsForValuesAffectingRulePrecedence {
+  return [self configStateSet];
+}
+
++ (NSSet*)keyPathsForValuesAffectingEnableDeveloperToolsAllowlist {
+  return [self configStateSet];
+}
+
++ (NSSet*)keyPathsForValuesAffectingDeveloperToolsAllowlist {
+  return [self configStateSet];
+}
+
++ (NSSet*)keyPathsForValuesAffectingPushServerPinnedCertificateSHA256 {
+  return [self configStateSet];
+}
+
++ (NSSet*)keyPathsForValuesAffectingPushServerCertificateExpiryWarningDays {
+  return [self configStateSet];
+}
+
++ (NSSet*)keyPathsForValuesAffectingUploadPushServerCertificateExpiryWarning {
+  return [self configStateSet];
+}
+
++ (NSSet*)keyPathsForValuesAffectingExportPushConnectionMetrics {
+  return [self configStateSet];
+}
+
++ (NSSet*)keyPathsForValuesAffectingPushInlineRulesPublicKey {
+  return [self configStateSet];
+}
+
++ (NSSet*)keyPathsForValuesAffectingPushCredentialsRejectedFullSyncInterval {
+  return [self configStateSet];
+}
+
++ (NSSet*)keyPathsForValuesAffectingPushMessageDedupeWindow {
+  return [self configStateSet];
+}
+
++ (NSSet*)keyPathsForValuesAffectingPushSyncRateLimitInterval {
+  return [self configStateSet];
+}
+
++ (NSSet*)keyPathsForValuesAffectingPushSyncRateLimitBurst {
+  return [self configStateSet];
+}
+
++ (NSSet*)keyPathsForValuesAffectingRefuseLockdownBelowMinimumOSVersion {
+  return [self configStateSet];
+}
+
++ (NSSet*)keyPathsForValuesAffectingAllowOnceTokenPublicKey {
+  return [self configStateSet];
+}
+
++ (NSSet*)keyPathsForValuesAffectingRegenerateMachineIDOnConflict {
+  return [self configStateSet];
+}
+
++ (NSSet*)keyPathsForValuesAffectingOfflineGraceToken {
+  return [self configStateSet];
+}
+
++ (NSSet*)keyPathsForValuesAffectingOfflineGraceTokenPublicKey {
+  return [self configStateSet];
+}
+
++ (NSSet*)keyPathsForValuesAffectingOfflineGraceFallbackClientMode {
+  return [self configStateSet];
+}
+
++ (NSSet*)keyPathsForValuesAffectingOfflineGraceSyncAgeSec {
+  return [self configStateSet];
+}
+
 + (NSSet*)keyPathsForValuesAffectingRemovableMediaAction {
   return [self syncAndConfigStateSet];
 }
@@ -885,6 +1353,10 @@ static SNTConfigurator* sharedConfigurator = nil;
   return [self syncAndConfigStateSet];
 }
 
++ (NSSet*)keyPathsForValuesAffectingTelemetrySampleRate {
+  return [self syncStateSet];
+}
+
 + (NSSet*)keyPathsForValuesAffectingBinaryUploadFilterExpressions {
   return [self syncAndConfigStateSet];
 }
@@ -960,7 +1432,12 @@ static SNTConfigurator* sharedConfigurator = nil;
     return SNTClientModeMonitor;
   }
 
-  SNTClientMode cm = static_cast<SNTClientMode>([self.syncState[kClientModeKey] integerValue]);
+  SNTClientMode cm = self.offlineGraceClientMode;
+  if (cm == SNTClientModeMonitor || cm == SNTClientModeLockdown || cm == SNTClientModeStandalone) {
+    return cm;
+  }
+
+  cm = static_cast<SNTClientMode>([self.syncState[kClientModeKey] integerValue]);
   if (cm == SNTClientModeMonitor || cm == SNTClientModeLockdown || cm == SNTClientModeStandalone) {
     return cm;
   }
@@ -980,6 +1457,34 @@ static SNTConfigurator* sharedConfigurator = nil;
   }
 }
 
+- (NSArray<SNTMaintenanceWindow*>*)maintenanceWindows {
+  return [SNTMaintenanceWindow maintenanceWindowsFromArray:self.configState[kMaintenanceWindowsKey]
+                                                    errors:NULL];
+}
+
+- (SNTClientMode)deferredClientMode {
+  SNTClientMode cm =
+      static_cast<SNTClientMode>([self.syncState[kDeferredClientModeKey] integerValue]);
+  if (cm == SNTClientModeMonitor || cm == SNTClientModeLockdown || cm == SNTClientModeStandalone) {
+    return cm;
+  }
+  return SNTClientModeUnknown;
+}
+
+- (void)setSyncServerDeferredClientMode:(SNTClientMode)newMode {
+  if (newMode == SNTClientModeMonitor || newMode == SNTClientModeLockdown ||
+      newMode == SNTClientModeStandalone) {
+    [self updateSyncStateForKey:kDeferredClientModeKey value:@(newMode)];
+  } else {
+    [self updateSyncStateForKey:kDeferredClientModeKey value:nil];
+  }
+}
+
+- (NSUInteger)lockdownGracePeriodSec {
+  NSNumber* number = self.configState[kLockdownGracePeriodSecKey];
+  return number ? MIN([number unsignedIntegerValue], 3600) : 0;
+}
+
 - (void)persistTimedSessionState:(NSDictionary*)state forKey:(NSString*)key {
   @synchronized(self) {
     [self updateStateSynchronizedKey:key value:state];
@@ -1008,6 +1513,39 @@ static SNTConfigurator* sharedConfigurator = nil;
   return self.state[kStateDemotedAdminsKey];
 }
 
+- (BOOL)persistConsumedAllowOnceTokens:(NSDictionary<NSString*, NSDate*>*)tokens {
+  @synchronized(self) {
+    NSDictionary* previous = self.state;
+    if ([self updateStateSynchronizedKey:kStateConsumedAllowOnceTokensKey value:tokens]) {
+      return YES;
+    }
+    // Roll back so a token that could not be recorded as consumed is not
+    // treated as consumed in memory only, which would allow it again after
+    // a restart while rejecting it now.
+    self.state = previous;
+    return NO;
+  }
+}
+
+- (nullable NSDictionary<NSString*, NSDate*>*)savedConsumedAllowOnceTokens {
+  return self.state[kStateConsumedAllowOnceTokensKey];
+}
+
+- (BOOL)persistConsumedPushRuleBundles:(NSDictionary<NSString*, NSDate*>*)bundles {
+  @synchronized(self) {
+    NSDictionary* previous = self.state;
+    if ([self updateStateSynchronizedKey:kStateConsumedPushRuleBundlesKey value:bundles]) {
+      return YES;
+    }
+    self.state = previous;
+    return NO;
+  }
+}
+
+- (nullable NSDictionary<NSString*, NSDate*>*)savedConsumedPushRuleBundles {
+  return self.state[kStateConsumedPushRuleBundlesKey];
+}
+
 - (void)updateLastBootUUID:(NSString*)bootUUID {
   @synchronized(self) {
     [self updateStateSynchronizedKey:kStateLastBootUUIDKey value:bootUUID];
@@ -1015,140 +1553,487 @@ static SNTConfigurator* sharedConfigurator = nil;
   }
 }
 
-- (BOOL)failClosed {
-  NSNumber* n = self.configState[kFailClosedKey];
-  BOOL runningInLockdownClientMode =
-      self.clientMode == SNTClientModeLockdown || self.clientMode == SNTClientModeStandalone;
-  return [n boolValue] && runningInLockdownClientMode;
+- (BOOL)failClosed {
+  NSNumber* n = self.configState[kFailClosedKey];
+  BOOL runningInLockdownClientMode =
+      self.clientMode == SNTClientModeLockdown || self.clientMode == SNTClientModeStandalone;
+  return [n boolValue] && runningInLockdownClientMode;
+}
+
+- (BOOL)enableTransitiveRules {
+  NSNumber* n = self.syncState[kEnableTransitiveRulesKey];
+  if (n) return [n boolValue];
+
+  n = self.syncState[kEnableTransitiveRulesKeyDeprecated];
+  if (n) return [n boolValue];
+
+  n = self.configState[kEnableTransitiveRulesKeyDeprecated];
+  if (n) return [n boolValue];
+
+  return [self.configState[kEnableTransitiveRulesKey] boolValue];
+}
+
+- (void)setEnableTransitiveRules:(BOOL)enabled {
+  [self updateSyncStateForKey:kEnableTransitiveRulesKey value:@(enabled)];
+}
+
+- (BOOL)enableBundles {
+  return [self.syncState[kEnableBundlesKey] boolValue];
+}
+
+- (void)setEnableBundles:(BOOL)enable {
+  [self updateSyncStateForKey:kEnableBundlesKey value:@(enable)];
+}
+- (SNTExportConfiguration*)exportConfig {
+  return [SNTExportConfiguration deserialize:self.syncState[kExportConfigurationKey]];
+}
+
+- (void)setSyncServerExportConfig:(SNTExportConfiguration*)exportConfig {
+  if (exportConfig.revoke) {
+    // On revoke, remove the key to discard any stored export configuration.
+    [self updateSyncStateForKey:kExportConfigurationKey value:nil];
+  } else {
+    [self updateSyncStateForKey:kExportConfigurationKey value:[exportConfig serialize]];
+  }
+}
+
+- (SNTModeTransition*)modeTransition {
+  return [SNTModeTransition deserialize:self.syncState[kModeTransitionKey]];
+}
+
+- (void)setSyncServerModeTransition:(SNTModeTransition*)modeTransition {
+  if (modeTransition.type == SNTModeTransitionTypeRevoke) {
+    // On revoke, set the value to nil to remove the key from the dictionary
+    [self updateSyncStateForKey:kModeTransitionKey value:nil];
+  } else {
+    [self updateSyncStateForKey:kModeTransitionKey value:[modeTransition serialize]];
+  }
+}
+
+- (SNTTemporaryAdminPolicy*)temporaryAdminPolicy {
+  return [SNTTemporaryAdminPolicy deserialize:self.syncState[kTemporaryAdminPolicyKey]];
+}
+
+- (void)setSyncServerTemporaryAdminPolicy:(SNTTemporaryAdminPolicy*)temporaryAdminPolicy {
+  if (temporaryAdminPolicy.type == SNTTemporaryAdminPolicyTypeRevoke) {
+    // On revoke, set the value to nil to remove the key from the dictionary
+    [self updateSyncStateForKey:kTemporaryAdminPolicyKey value:nil];
+  } else {
+    [self updateSyncStateForKey:kTemporaryAdminPolicyKey value:[temporaryAdminPolicy serialize]];
+  }
+}
+
+- (SNTSyncNetworkExtensionSettings*)syncNetworkExtensionSettings {
+  return [SNTSyncNetworkExtensionSettings deserialize:self.syncState[kNetworkExtensionSettingsKey]];
+}
+
+- (void)setSyncServerSyncNetworkExtensionSettings:
+    (SNTSyncNetworkExtensionSettings*)syncNetworkExtensionSettings {
+  [self updateSyncStateForKey:kNetworkExtensionSettingsKey
+                        value:[syncNetworkExtensionSettings serialize]];
+}
+
+- (NSArray<NSString*>*)pushTokenChain {
+  return EnsureArrayOfStrings(self.syncState[kPushTokenChainKey]);
+}
+
+- (void)setSyncServerPushTokenChain:(NSArray<NSString*>*)pushTokenChain {
+  [self updateSyncStateForKey:kPushTokenChainKey value:EnsureArrayOfStrings(pushTokenChain)];
+}
+
+- (NSString*)generatedMachineID {
+  NSString* machineID = self.syncState[kGeneratedMachineIDKey];
+  return machineID.length ? machineID : nil;
+}
+
+- (void)setGeneratedMachineID:(NSString*)generatedMachineID {
+  [self updateSyncStateForKey:kGeneratedMachineIDKey value:generatedMachineID];
+}
+
+- (NSRegularExpression*)allowedPathRegex {
+  NSRegularExpression* r = self.syncState[kAllowedPathRegexKey];
+  if (r) return r;
+
+  r = self.syncState[kAllowedPathRegexKeyDeprecated];
+  if (r) return r;
+
+  r = self.configState[kAllowedPathRegexKey];
+  if (r) return r;
+
+  return self.configState[kAllowedPathRegexKeyDeprecated];
+}
+
+- (void)setSyncServerAllowedPathRegex:(NSRegularExpression*)re {
+  [self updateSyncStateForKey:kAllowedPathRegexKey value:re];
+}
+
+- (NSRegularExpression*)blockedPathRegex {
+  NSRegularExpression* r = self.syncState[kBlockedPathRegexKey];
+  if (r) return r;
+
+  r = self.syncState[kBlockedPathRegexKeyDeprecated];
+  if (r) return r;
+
+  r = self.configState[kBlockedPathRegexKey];
+  if (r) return r;
+
+  return self.configState[kBlockedPathRegexKeyDeprecated];
+}
+
+- (void)setSyncServerBlockedPathRegex:(NSRegularExpression*)re {
+  [self updateSyncStateForKey:kBlockedPathRegexKey value:re];
+}
+
+- (NSRegularExpression*)fileChangesRegex {
+  return self.configState[kFileChangesRegexKey];
+}
+
+- (NSArray*)fileChangesPrefixFilters {
+  NSArray* filters = self.configState[kFileChangesPrefixFiltersKey];
+  for (id filter in filters) {
+    if (![filter isKindOfClass:[NSString class]]) {
+      return nil;
+    }
+  }
+  return filters;
+}
+
+- (SNTClockTamperingAction)clockTamperingAction {
+  NSString* action = [self.configState[kClockTamperingActionKey] lowercaseString];
+
+  if ([action isEqualToString:@"sync"]) {
+    return SNTClockTamperingActionSync;
+  } else if ([action isEqualToString:@"lockdown"]) {
+    return SNTClockTamperingActionLockdown;
+  } else {
+    return SNTClockTamperingActionNone;
+  }
+}
+
+- (NSUInteger)clockTamperingThresholdSec {
+  NSNumber* number = self.configState[kClockTamperingThresholdSecKey];
+  return number ? [number unsignedIntegerValue] : 300;
+}
+
+- (SNTNetworkVolumeExecutionAction)networkVolumeExecutionAction {
+  NSString* action = [self.configState[kNetworkVolumeExecutionActionKey] lowercaseString];
+
+  if ([action isEqualToString:@"blockunknown"]) {
+    return SNTNetworkVolumeExecutionActionBlockUnknown;
+  } else if ([action isEqualToString:@"block"]) {
+    return SNTNetworkVolumeExecutionActionBlock;
+  } else {
+    return SNTNetworkVolumeExecutionActionNone;
+  }
+}
+
+- (SNTDiskImageExecutionAction)diskImageExecutionAction {
+  NSString* action = [self.configState[kDiskImageExecutionActionKey] lowercaseString];
+
+  if ([action isEqualToString:@"warn"]) {
+    return SNTDiskImageExecutionActionWarn;
+  } else if ([action isEqualToString:@"blockunknown"]) {
+    return SNTDiskImageExecutionActionBlockUnknown;
+  } else if ([action isEqualToString:@"block"]) {
+    return SNTDiskImageExecutionActionBlock;
+  } else {
+    return SNTDiskImageExecutionActionNone;
+  }
+}
+
+- (BOOL)diskImageExecutionQuarantinedOnly {
+  return [self.configState[kDiskImageExecutionQuarantinedOnlyKey] boolValue];
+}
+
+- (SNTPathChurnExecutionAction)pathChurnExecutionAction {
+  NSString* action = [self.configState[kPathChurnExecutionActionKey] lowercaseString];
+
+  if ([action isEqualToString:@"warn"]) {
+    return SNTPathChurnExecutionActionWarn;
+  } else if ([action isEqualToString:@"blockunknown"]) {
+    return SNTPathChurnExecutionActionBlockUnknown;
+  } else if ([action isEqualToString:@"block"]) {
+    return SNTPathChurnExecutionActionBlock;
+  } else {
+    return SNTPathChurnExecutionActionNone;
+  }
+}
+
+- (NSUInteger)pathChurnThreshold {
+  NSNumber* number = self.configState[kPathChurnThresholdKey];
+  NSUInteger threshold = number ? [number unsignedIntegerValue] : 5;
+  return std::clamp<NSUInteger>(threshold, 2, 1000);
+}
+
+- (NSUInteger)pathChurnWindowSeconds {
+  NSNumber* number = self.configState[kPathChurnWindowSecondsKey];
+  NSUInteger window = number ? [number unsignedIntegerValue] : 3600;
+  return std::clamp<NSUInteger>(window, 60, 86400);
+}
+
+- (NSDictionary*)signingStatusExecutionActions {
+  return self.configState[kSigningStatusExecutionActionsKey];
+}
+
+- (SNTSigningStatusExecutionAction)executionActionForSigningStatus:(SNTSigningStatus)status {
+  NSString* name;
+  switch (status) {
+    case SNTSigningStatusUnsigned: name = @"unsigned"; break;
+    case SNTSigningStatusInvalid: name = @"invalid"; break;
+    case SNTSigningStatusAdhoc: name = @"adhoc"; break;
+    // Validly signed binaries are always left to rules and the client mode.
+    case SNTSigningStatusDevelopment: [[fallthrough]];
+    case SNTSigningStatusProduction: return SNTSigningStatusExecutionActionNone;
+  }
+
+  NSString* action;
+  NSDictionary* actions = self.signingStatusExecutionActions;
+  for (NSString* key in actions) {
+    if (![key isKindOfClass:[NSString class]] || ![[key lowercaseString] isEqualToString:name]) {
+      continue;
+    }
+    id value = actions[key];
+    if ([value isKindOfClass:[NSString class]]) action = [value lowercaseString];
+    break;
+  }
+
+  if ([action isEqualToString:@"blockunknown"]) {
+    return SNTSigningStatusExecutionActionBlockUnknown;
+  } else if ([action isEqualToString:@"block"]) {
+    return SNTSigningStatusExecutionActionBlock;
+  } else {
+    return SNTSigningStatusExecutionActionNone;
+  }
+}
+
+- (SNTCodeSignatureInvalidationResponse)codeSignatureInvalidationResponse {
+  NSString* response = [self.configState[kCodeSignatureInvalidationResponseKey] lowercaseString];
+
+  if ([response isEqualToString:@"log"]) {
+    return SNTCodeSignatureInvalidationResponseLog;
+  } else if ([response isEqualToString:@"block"]) {
+    return SNTCodeSignatureInvalidationResponseBlock;
+  } else if ([response isEqualToString:@"terminate"]) {
+    return SNTCodeSignatureInvalidationResponseTerminate;
+  } else {
+    return SNTCodeSignatureInvalidationResponseNone;
+  }
+}
+
+- (SNTLaunchItemPolicy)launchItemPolicy {
+  NSString* policy = [self.configState[kLaunchItemPolicyKey] lowercaseString];
+
+  if ([policy isEqualToString:@"monitor"]) {
+    return SNTLaunchItemPolicyMonitor;
+  } else if ([policy isEqualToString:@"block"]) {
+    return SNTLaunchItemPolicyBlock;
+  } else {
+    return SNTLaunchItemPolicyNone;
+  }
+}
+
+- (BOOL)requireLibraryValidationForTeamIDRules {
+  return [self.configState[kRequireLibraryValidationForTeamIDRulesKey] boolValue];
 }
 
-- (BOOL)enableTransitiveRules {
-  NSNumber* n = self.syncState[kEnableTransitiveRulesKey];
-  if (n) return [n boolValue];
-
-  n = self.syncState[kEnableTransitiveRulesKeyDeprecated];
-  if (n) return [n boolValue];
-
-  n = self.configState[kEnableTransitiveRulesKeyDeprecated];
-  if (n) return [n boolValue];
+- (SNTSyncInFlightExecutionPolicy)syncInFlightExecutionPolicy {
+  NSString* policy = [self.configState[kSyncInFlightExecutionPolicyKey] lowercaseString];
 
-  return [self.configState[kEnableTransitiveRulesKey] boolValue];
+  if ([policy isEqualToString:@"defer"]) {
+    return SNTSyncInFlightExecutionPolicyDefer;
+  } else if ([policy isEqualToString:@"failsafe"]) {
+    return SNTSyncInFlightExecutionPolicyFailSafe;
+  } else {
+    return SNTSyncInFlightExecutionPolicyUseExisting;
+  }
 }
 
-- (void)setEnableTransitiveRules:(BOOL)enabled {
-  [self updateSyncStateForKey:kEnableTransitiveRulesKey value:@(enabled)];
+- (NSUInteger)syncInFlightMaxDeferralMilliseconds {
+  NSNumber* number = self.configState[kSyncInFlightMaxDeferralMillisecondsKey];
+  NSUInteger timeout = number ? [number unsignedIntegerValue] : 1000;
+  return std::clamp<NSUInteger>(timeout, 10, 5000);
 }
 
-- (BOOL)enableBundles {
-  return [self.syncState[kEnableBundlesKey] boolValue];
+- (BOOL)enableDecisionDatabase {
+  return [self.configState[kEnableDecisionDatabaseKey] boolValue];
 }
 
-- (void)setEnableBundles:(BOOL)enable {
-  [self updateSyncStateForKey:kEnableBundlesKey value:@(enable)];
+- (NSUInteger)decisionDatabaseMaxDecisions {
+  NSNumber* number = self.configState[kDecisionDatabaseMaxDecisionsKey];
+  return number.unsignedIntegerValue > 0 ? number.unsignedIntegerValue : 100000;
 }
-- (SNTExportConfiguration*)exportConfig {
-  return [SNTExportConfiguration deserialize:self.syncState[kExportConfigurationKey]];
+
+- (NSData*)allowOnceTokenPublicKey {
+  NSString* key = self.configState[kAllowOnceTokenPublicKeyKey];
+  if (!key.length) return nil;
+  return [[NSData alloc] initWithBase64EncodedString:key
+                                             options:NSDataBase64DecodingIgnoreUnknownCharacters];
 }
 
-- (void)setSyncServerExportConfig:(SNTExportConfiguration*)exportConfig {
-  if (exportConfig.revoke) {
-    // On revoke, remove the key to discard any stored export configuration.
-    [self updateSyncStateForKey:kExportConfigurationKey value:nil];
-  } else {
-    [self updateSyncStateForKey:kExportConfigurationKey value:[exportConfig serialize]];
-  }
+- (NSString*)offlineGraceToken {
+  NSString* token = self.configState[kOfflineGraceTokenKey];
+  return token.length ? token : nil;
 }
 
-- (SNTModeTransition*)modeTransition {
-  return [SNTModeTransition deserialize:self.syncState[kModeTransitionKey]];
+- (NSData*)offlineGraceTokenPublicKey {
+  NSString* key = self.configState[kOfflineGraceTokenPublicKeyKey];
+  if (!key.length) return nil;
+  return [[NSData alloc] initWithBase64EncodedString:key
+                                             options:NSDataBase64DecodingIgnoreUnknownCharacters];
 }
 
-- (void)setSyncServerModeTransition:(SNTModeTransition*)modeTransition {
-  if (modeTransition.type == SNTModeTransitionTypeRevoke) {
-    // On revoke, set the value to nil to remove the key from the dictionary
-    [self updateSyncStateForKey:kModeTransitionKey value:nil];
-  } else {
-    [self updateSyncStateForKey:kModeTransitionKey value:[modeTransition serialize]];
+- (SNTClientMode)offlineGraceFallbackClientMode {
+  NSNumber* number = self.configState[kOfflineGraceFallbackClientModeKey];
+  SNTClientMode cm = static_cast<SNTClientMode>([number integerValue]);
+  if (cm == SNTClientModeMonitor || cm == SNTClientModeLockdown || cm == SNTClientModeStandalone) {
+    return cm;
   }
+  return SNTClientModeLockdown;
 }
 
-- (SNTTemporaryAdminPolicy*)temporaryAdminPolicy {
-  return [SNTTemporaryAdminPolicy deserialize:self.syncState[kTemporaryAdminPolicyKey]];
+- (NSUInteger)offlineGraceSyncAgeSec {
+  NSNumber* number = self.configState[kOfflineGraceSyncAgeSecKey];
+  return number ? [number unsignedIntegerValue] : 604800;
 }
 
-- (void)setSyncServerTemporaryAdminPolicy:(SNTTemporaryAdminPolicy*)temporaryAdminPolicy {
-  if (temporaryAdminPolicy.type == SNTTemporaryAdminPolicyTypeRevoke) {
-    // On revoke, set the value to nil to remove the key from the dictionary
-    [self updateSyncStateForKey:kTemporaryAdminPolicyKey value:nil];
-  } else {
-    [self updateSyncStateForKey:kTemporaryAdminPolicyKey value:[temporaryAdminPolicy serialize]];
+- (NSArray<NSString*>*)pushServerPinnedCertificateSHA256 {
+  NSArray* pins = self.configState[kPushServerPinnedCertificateSHA256Key];
+  if (!pins.count) return nil;
+
+  // Malformed entries are dropped rather than disabling pinning, so if none are valid every
+  // connection is refused.
+  NSMutableCharacterSet* separators = [NSMutableCharacterSet whitespaceCharacterSet];
+  [separators addCharactersInString:@":"];
+  NSCharacterSet* nonHex =
+      [[NSCharacterSet characterSetWithCharactersInString:@"0123456789abcdef"] invertedSet];
+  NSMutableArray<NSString*>* fingerprints = [NSMutableArray arrayWithCapacity:pins.count];
+  for (NSString* pin in pins) {
+    if (![pin isKindOfClass:[NSString class]]) {
+      LOGE(@"Unexpected type in %@: %@", kPushServerPinnedCertificateSHA256Key, [pin class]);
+      continue;
+    }
+    NSString* hex =
+        [[pin componentsSeparatedByCharactersInSet:separators] componentsJoinedByString:@""]
+            .lowercaseString;
+    if (hex.length != 64 || [hex rangeOfCharacterFromSet:nonHex].location != NSNotFound) {
+      LOGE(@"Ignoring malformed %@ entry: %@", kPushServerPinnedCertificateSHA256Key, pin);
+      continue;
+    }
+    [fingerprints addObject:hex];
   }
+  return fingerprints;
 }
 
-- (SNTSyncNetworkExtensionSettings*)syncNetworkExtensionSettings {
-  return [SNTSyncNetworkExtensionSettings deserialize:self.syncState[kNetworkExtensionSettingsKey]];
+- (NSUInteger)pushServerCertificateExpiryWarningDays {
+  NSNumber* number = self.configState[kPushServerCertificateExpiryWarningDaysKey];
+  return number ? [number unsignedIntegerValue] : 30;
 }
 
-- (void)setSyncServerSyncNetworkExtensionSettings:
-    (SNTSyncNetworkExtensionSettings*)syncNetworkExtensionSettings {
-  [self updateSyncStateForKey:kNetworkExtensionSettingsKey
-                        value:[syncNetworkExtensionSettings serialize]];
+- (BOOL)uploadPushServerCertificateExpiryWarning {
+  NSNumber* number = self.configState[kUploadPushServerCertificateExpiryWarningKey];
+  return number ? [number boolValue] : NO;
 }
 
-- (NSArray<NSString*>*)pushTokenChain {
-  return EnsureArrayOfStrings(self.syncState[kPushTokenChainKey]);
+- (BOOL)exportPushConnectionMetrics {
+  NSNumber* number = self.configState[kExportPushConnectionMetricsKey];
+  return number ? [number boolValue] : NO;
 }
 
-- (void)setSyncServerPushTokenChain:(NSArray<NSString*>*)pushTokenChain {
-  [self updateSyncStateForKey:kPushTokenChainKey value:EnsureArrayOfStrings(pushTokenChain)];
+- (NSUInteger)pushCredentialsRejectedFullSyncInterval {
+  NSNumber* number = self.configState[kPushCredentialsRejectedFullSyncIntervalKey];
+  if (!number) return kDefaultFullSyncInterval;
+  return MAX([number unsignedIntegerValue], kMinimumFullSyncInterval);
 }
 
-- (NSRegularExpression*)allowedPathRegex {
-  NSRegularExpression* r = self.syncState[kAllowedPathRegexKey];
-  if (r) return r;
-
-  r = self.syncState[kAllowedPathRegexKeyDeprecated];
-  if (r) return r;
+- (NSTimeInterval)pushMessageDedupeWindow {
+  NSNumber* number = self.configState[kPushMessageDedupeWindowKey];
+  if (!number) return 5;
+  return MAX([number doubleValue], 0);
+}
 
-  r = self.configState[kAllowedPathRegexKey];
-  if (r) return r;
+- (NSTimeInterval)pushSyncRateLimitInterval {
+  NSNumber* number = self.configState[kPushSyncRateLimitIntervalKey];
+  if (!number) return 30;
+  return MAX([number doubleValue], 0);
+}
 
-  return self.configState[kAllowedPathRegexKeyDeprecated];
+- (NSUInteger)pushSyncRateLimitBurst {
+  NSNumber* number = self.configState[kPushSyncRateLimitBurstKey];
+  if (!number) return 2;
+  return (NSUInteger)MAX([number longLongValue], 0);
 }
 
-- (void)setSyncServerAllowedPathRegex:(NSRegularExpression*)re {
-  [self updateSyncStateForKey:kAllowedPathRegexKey value:re];
+- (NSData*)pushInlineRulesPublicKey {
+  NSString* key = self.configState[kPushInlineRulesPublicKeyKey];
+  if (!key.length) return nil;
+  return [[NSData alloc] initWithBase64EncodedString:key
+                                             options:NSDataBase64DecodingIgnoreUnknownCharacters];
 }
 
-- (NSRegularExpression*)blockedPathRegex {
-  NSRegularExpression* r = self.syncState[kBlockedPathRegexKey];
-  if (r) return r;
+- (BOOL)refuseLockdownBelowMinimumOSVersion {
+  NSNumber* number = self.configState[kRefuseLockdownBelowMinimumOSVersionKey];
+  return number ? [number boolValue] : NO;
+}
 
-  r = self.syncState[kBlockedPathRegexKeyDeprecated];
-  if (r) return r;
+- (BOOL)regenerateMachineIDOnConflict {
+  // An explicit MachineID takes precedence over a generated one, so regenerating would not help.
+  if (self.configState[kMachineIDKey]) return NO;
+  NSNumber* number = self.configState[kRegenerateMachineIDOnConflictKey];
+  return number ? [number boolValue] : NO;
+}
 
-  r = self.configState[kBlockedPathRegexKey];
-  if (r) return r;
+- (NSArray<NSNumber*>*)rulePrecedence {
+  NSArray* names = self.configState[kRulePrecedenceKey];
+  if (!names) return nil;
 
-  return self.configState[kBlockedPathRegexKeyDeprecated];
-}
+  NSDictionary<NSString*, NSNumber*>* types = @{
+    kRuleTypeCDHash : @(SNTRuleTypeCDHash),
+    kRuleTypeBinary : @(SNTRuleTypeBinary),
+    kRuleTypeSigningID : @(SNTRuleTypeSigningID),
+    kRuleTypeCertificate : @(SNTRuleTypeCertificate),
+    kRuleTypeTeamID : @(SNTRuleTypeTeamID),
+  };
 
-- (void)setSyncServerBlockedPathRegex:(NSRegularExpression*)re {
-  [self updateSyncStateForKey:kBlockedPathRegexKey value:re];
+  // The ordering must name every rule type exactly once, otherwise some rules would never match.
+  NSMutableArray<NSNumber*>* precedence = [NSMutableArray arrayWithCapacity:types.count];
+  for (id name in names) {
+    NSNumber* type = [name isKindOfClass:[NSString class]] ? types[[name uppercaseString]] : nil;
+    if (!type || [precedence containsObject:type]) {
+      LOGW(@"Ignoring RulePrecedence with invalid or duplicate rule type: %@", name);
+      return nil;
+    }
+    [precedence addObject:type];
+  }
+  if (precedence.count != types.count) {
+    LOGW(@"Ignoring RulePrecedence that does not list all %lu rule types",
+         (unsigned long)types.count);
+    return nil;
+  }
+  return precedence;
 }
 
-- (NSRegularExpression*)fileChangesRegex {
-  return self.configState[kFileChangesRegexKey];
+- (BOOL)enableDeveloperToolsAllowlist {
+  return [self.configState[kEnableDeveloperToolsAllowlistKey] boolValue];
 }
 
-- (NSArray*)fileChangesPrefixFilters {
-  NSArray* filters = self.configState[kFileChangesPrefixFiltersKey];
-  for (id filter in filters) {
-    if (![filter isKindOfClass:[NSString class]]) {
-      return nil;
+- (NSArray<NSString*>*)developerToolsAllowlist {
+  NSArray* entries = self.configState[kDeveloperToolsAllowlistKey];
+  if (entries) {
+    NSMutableArray<NSString*>* allowlist = [NSMutableArray arrayWithCapacity:entries.count];
+    for (id entry in entries) {
+      if ([entry isKindOfClass:[NSString class]] && [entry length]) [allowlist addObject:entry];
     }
+    return allowlist;
   }
-  return filters;
+
+  return @[
+    @"9BNSXJN65R",                       // Docker Inc
+    @"2ZEFAR8TH3",                       // JetBrains s.r.o.
+    @"D38WU7D763",                       // HashiCorp, Inc.
+    @"DJ3H93M7VJ",                       // Python Software Foundation
+    @"VEKTX9H2N7",                       // GitHub
+    @"UBF8T346G9:com.microsoft.VSCode",  // Visual Studio Code
+  ];
 }
 
 - (SNTDeviceManagerStartupPreferences)onStartUSBOptions {
@@ -1171,6 +2056,10 @@ static SNTConfigurator* sharedConfigurator = nil;
   return self.configState[kStaticRulesKey];
 }
 
+- (NSArray<SNTRuleSource*>*)ruleSources {
+  return [SNTRuleSource ruleSourcesFromArray:self.configState[kRuleSourcesKey] errors:NULL];
+}
+
 - (NSURL*)syncBaseURL {
   NSString* urlString = self.configState[kSyncBaseURLKey];
   if (urlString.length == 0) {
@@ -1220,11 +2109,45 @@ static SNTConfigurator* sharedConfigurator = nil;
   return number ? [number boolValue] : YES;
 }
 
+- (BOOL)canonicalizeExecutablePaths {
+  NSNumber* number = self.configState[kCanonicalizeExecutablePathsKey];
+  return number ? [number boolValue] : NO;
+}
+
+- (BOOL)enableScriptEvaluation {
+  NSNumber* number = self.configState[kEnableScriptEvaluationKey];
+  return number ? [number boolValue] : NO;
+}
+
+- (BOOL)enableInterpreterScriptEvaluation {
+  NSNumber* number = self.configState[kEnableInterpreterScriptEvaluationKey];
+  return number ? [number boolValue] : NO;
+}
+
+- (NSArray<NSString*>*)resignProtectedBlocklist {
+  return EnsureArrayOfStrings(self.configState[kResignProtectedBlocklistKey]);
+}
+
 - (BOOL)enableBadSignatureProtection {
   NSNumber* number = self.configState[kEnableBadSignatureProtectionKey];
   return number ? [number boolValue] : NO;
 }
 
+- (NSString*)decisionHookSocketPath {
+  return self.configState[kDecisionHookSocketPathKey];
+}
+
+- (NSUInteger)decisionHookTimeoutMilliseconds {
+  NSNumber* number = self.configState[kDecisionHookTimeoutMillisecondsKey];
+  NSUInteger timeout = number ? [number unsignedIntegerValue] : 250;
+  return std::clamp<NSUInteger>(timeout, 10, 5000);
+}
+
+- (BOOL)decisionHookFailClosed {
+  NSNumber* number = self.configState[kDecisionHookFailClosedKey];
+  return number ? [number boolValue] : NO;
+}
+
 - (BOOL)enableAntiTamperProcessSuspendResume {
   NSNumber* number = self.configState[kEnableAntiTamperProcessSuspendResumeKey];
   return number ? [number boolValue] : YES;
@@ -1498,6 +2421,11 @@ static SNTConfigurator* sharedConfigurator = nil;
   NSString* machineId = self.configState[kMachineIDKey];
   if (machineId) return machineId;
 
+  // An ID generated after the sync server reported a conflict replaces the derived ID, which is
+  // the one cloned hosts share.
+  machineId = self.generatedMachineID;
+  if (machineId) return machineId;
+
   NSString* plistPath = self.configState[kMachineIDPlistFileKey];
   NSString* plistKey = self.configState[kMachineIDPlistKeyKey];
 
@@ -1540,6 +2468,17 @@ static SNTConfigurator* sharedConfigurator = nil;
   return self.configState[kEventLogPath] ?: @"/var/db/santa/santa.log";
 }
 
+- (NSUInteger)eventLogBufferSizeKB {
+  return self.configState[kEventLogBufferSizeKB]
+             ? [self.configState[kEventLogBufferSizeKB] unsignedIntegerValue]
+             : 128;
+}
+
+- (NSUInteger)eventLogMaxFlushTimeSec {
+  NSUInteger sec = [self.configState[kEventLogMaxFlushTimeSec] unsignedIntegerValue];
+  return sec > 0 ? sec : 10;
+}
+
 - (NSString*)spoolDirectory {
   return self.configState[kSpoolDirectory] ?: @"/var/db/santa/spool";
 }
@@ -1635,6 +2574,80 @@ static SNTConfigurator* sharedConfigurator = nil;
   return number ? [number boolValue] : NO;
 }
 
+- (NSUInteger)syncMaxEventAgeSec {
+  NSNumber* number = self.configState[kSyncMaxEventAgeSecKey];
+  return number ? [number unsignedIntegerValue] : 0;
+}
+
+- (BOOL)disableEventUpload {
+  NSNumber* number = self.configState[kDisableEventUploadKey];
+  return number ? [number boolValue] : NO;
+}
+
+- (NSUInteger)syncEventUploadConcurrency {
+  NSNumber* number = self.configState[kSyncEventUploadConcurrencyKey];
+  NSUInteger concurrency = number ? [number unsignedIntegerValue] : 1;
+  return std::clamp<NSUInteger>(concurrency, 1, 8);
+}
+
+- (NSUInteger)syncRuleDownloadConcurrency {
+  NSNumber* number = self.configState[kSyncRuleDownloadConcurrencyKey];
+  NSUInteger concurrency = number ? [number unsignedIntegerValue] : 1;
+  return std::clamp<NSUInteger>(concurrency, 1, 8);
+}
+
+- (NSUInteger)syncCircuitBreakerFailureThreshold {
+  NSNumber* number = self.configState[kSyncCircuitBreakerFailureThresholdKey];
+  return number ? [number unsignedIntegerValue] : 10;
+}
+
+- (NSUInteger)syncCircuitBreakerCooldownSec {
+  NSNumber* number = self.configState[kSyncCircuitBreakerCooldownSecKey];
+  return number ? MAX([number unsignedIntegerValue], 1) : 300;
+}
+
+- (NSUInteger)ruleApplyBatchSize {
+  NSNumber* number = self.configState[kRuleApplyBatchSizeKey];
+  return number ? [number unsignedIntegerValue] : 0;
+}
+
+- (BOOL)enableRuleSnapshot {
+  return [self.configState[kEnableRuleSnapshotKey] boolValue];
+}
+
+- (SNTDuplicateRuleResolution)duplicateRuleResolution {
+  NSString* resolution = [self.configState[kDuplicateRuleResolutionKey] lowercaseString];
+  if ([resolution isEqualToString:@"highestprecedence"]) {
+    return SNTDuplicateRuleResolutionHighestPrecedence;
+  }
+  return SNTDuplicateRuleResolutionLastWins;
+}
+
+- (NSUInteger)cleanSyncWarmupSec {
+  NSNumber* number = self.configState[kCleanSyncWarmupSecKey];
+  return number ? MIN([number unsignedIntegerValue], 3600) : 0;
+}
+
+- (NSUInteger)cleanSyncWarmupNotificationLimit {
+  NSNumber* number = self.configState[kCleanSyncWarmupNotificationLimitKey];
+  return number ? [number unsignedIntegerValue] : 3;
+}
+
+- (NSUInteger)cleanSyncWarmupUploadLimit {
+  NSNumber* number = self.configState[kCleanSyncWarmupUploadLimitKey];
+  return number ? [number unsignedIntegerValue] : 20;
+}
+
+- (NSUInteger)ruleApplicationDeferralLoadPercent {
+  NSNumber* number = self.configState[kRuleApplicationDeferralLoadPercentKey];
+  return number ? [number unsignedIntegerValue] : 0;
+}
+
+- (NSUInteger)ruleApplicationMaxDeferralSec {
+  NSNumber* number = self.configState[kRuleApplicationMaxDeferralSecKey];
+  return number ? MIN([number unsignedIntegerValue], 3600) : 600;
+}
+
 - (BOOL)enableAllEventUpload {
   NSNumber* n = self.syncState[kEnableAllEventUploadKey];
   if (n) return [n boolValue];
@@ -1993,6 +3006,24 @@ static SNTConfigurator* sharedConfigurator = nil;
   return filtered;
 }
 
+- (NSDictionary<NSString*, id>*)configSnapshot {
+  return [self.configState copy] ?: @{};
+}
+
+- (NSString*)configHash {
+  return CanonicalConfigSHA256(self.configState ?: @{});
+}
+
+- (NSString*)effectiveConfigHash {
+  NSMutableDictionary* syncSettings =
+      [self.syncState mutableCopy] ?: [NSMutableDictionary dictionary];
+  [syncSettings removeObjectsForKeys:@[
+    kFullSyncLastSuccess, kRuleSyncLastSuccess, kSyncTypeRequired, kSyncCleanRequiredDeprecated,
+    kPushTokenChainKey
+  ]];
+  return CanonicalConfigSHA256(@{@"config" : self.configState ?: @{}, @"sync" : syncSettings});
+}
+
 #pragma mark - Private
 
 ///
@@ -2140,9 +3171,15 @@ static SNTConfigurator* sharedConfigurator = nil;
   // method precisely *because* `syncBaseURL` went to nil. Gating here would
   // make the cleanup unreachable in production. Disk removal still requires
   // root, which santad already has.
+  //
+  // Clean syncs clear the sync state inside a batch. A generated machine ID is kept there: it
+  // identifies this host rather than holding sync server settings, and clearing it would bring
+  // back the ID that conflicted.
   void (^block)(void) = ^{
     if (self.batchedSyncState) {
+      NSString* generatedMachineID = self.batchedSyncState[kGeneratedMachineIDKey];
       [self.batchedSyncState removeAllObjects];
+      self.batchedSyncState[kGeneratedMachineIDKey] = generatedMachineID;
       return;
     }
     self.syncState = [NSMutableDictionary dictionary];
@@ -2181,6 +3218,18 @@ static SNTConfigurator* sharedConfigurator = nil;
                         value:EnsureArrayOfStrings(expressions)];
 }
 
+- (double)telemetrySampleRate {
+  NSNumber* rate = self.syncState[kTelemetrySampleRateKey];
+  return rate ? std::clamp([rate doubleValue], 0.0, 1.0) : 1.0;
+}
+
+- (void)setSyncServerTelemetrySampleRate:(double)rate {
+  // A rate of 1.0 samples every event, the same as no rate at all. Clear the key rather than
+  // storing it.
+  [self updateSyncStateForKey:kTelemetrySampleRateKey
+                        value:rate < 1.0 ? @(std::max(rate, 0.0)) : nil];
+}
+
 - (NSArray*)binaryUploadFilterExpressions {
   NSMutableArray* merged = [NSMutableArray array];
 
@@ -2234,6 +3283,14 @@ static SNTConfigurator* sharedConfigurator = nil;
     newState[kStateDemotedAdminsKey] = state[kStateDemotedAdminsKey];
   }
 
+  if ([state[kStateConsumedAllowOnceTokensKey] isKindOfClass:[NSDictionary class]]) {
+    newState[kStateConsumedAllowOnceTokensKey] = state[kStateConsumedAllowOnceTokensKey];
+  }
+
+  if ([state[kStateConsumedPushRuleBundlesKey] isKindOfClass:[NSDictionary class]]) {
+    newState[kStateConsumedPushRuleBundlesKey] = state[kStateConsumedPushRuleBundlesKey];
+  }
+
   if ([state[kStateLastBootUUIDKey] isKindOfClass:[NSString class]]) {
     _lastBootUUID = state[kStateLastBootUUIDKey];
     newState[kStateLastBootUUIDKey] = _lastBootUUID;
@@ -2421,9 +3478,65 @@ static SNTConfigurator* sharedConfigurator = nil;
 
 #pragma mark - Config Validation
 
+// The values accepted by keys that fall back to a default when given a value they don't know.
+// String values are compared case-insensitively.
+static NSDictionary<NSString*, NSArray*>* AllowedConfigValues() {
+  static NSDictionary<NSString*, NSArray*>* allowedValues;
+  static dispatch_once_t onceToken;
+  dispatch_once(&onceToken, ^{
+    allowedValues = @{
+      kClientModeKey : @[ @1, @2, @3 ],
+      kOfflineGraceFallbackClientModeKey : @[ @1, @2, @3 ],
+      kClockTamperingActionKey : @[ @"none", @"sync", @"lockdown" ],
+      kNetworkVolumeExecutionActionKey : @[ @"none", @"blockunknown", @"block" ],
+      kDiskImageExecutionActionKey : @[ @"none", @"warn", @"blockunknown", @"block" ],
+      kPathChurnExecutionActionKey : @[ @"none", @"warn", @"blockunknown", @"block" ],
+      kCodeSignatureInvalidationResponseKey : @[ @"none", @"log", @"block", @"terminate" ],
+      kLaunchItemPolicyKey : @[ @"none", @"monitor", @"block" ],
+      kSyncInFlightExecutionPolicyKey : @[ @"useexisting", @"defer", @"failsafe" ],
+      kOnStartUSBOptions :
+          @[ @"none", @"unmount", @"forceunmount", @"remount", @"forceremount" ],
+      kClientContentEncoding : @[ @"deflate", @"gzip", @"none" ],
+      kEventLogType : @[
+        @"file", @"json", @"null", @"protobuf", @"protobufstream", @"protobufstreamgzip",
+        @"protobufstreamzstd", @"syslog"
+      ],
+      kDuplicateRuleResolutionKey : @[ @"lastwins", @"highestprecedence" ],
+      kMetricFormat : @[ @"rawjson", @"monarchjson", @"proto" ],
+      kOverrideFileAccessActionKey : @[ @"audit_only", @"auditonly", @"disable", @"none" ],
+    };
+  });
+  return allowedValues;
+}
+
 - (nullable NSArray*)validateConfiguration {
   NSMutableArray* errors = [NSMutableArray array];
+  NSDictionary* profile = [self configProfile];
+
+  for (NSString* key in profile) {
+    // Check that the key is known to us.
+    id type = self.forcedConfigKeyTypes[key];
+    if (!type) {
+      [errors addObject:[NSString stringWithFormat:@"The key %@ is not recognized", key]];
+      continue;
+    }
+
+    // Check that the type of the value matches the expected type.
+    id value = profile[key];
+    if (![value isKindOfClass:type] &&
+        !(type == [NSRegularExpression class] && [value isKindOfClass:[NSString class]])) {
+      [errors addObject:[NSString stringWithFormat:@"The key %@ has an unexpected type: %@", key,
+                                                   [value class]]];
+      continue;
+    }
+
+    [errors addObjectsFromArray:[self validationErrorsForKey:key value:value inProfile:profile]];
+  }
+  return errors;
+}
 
+- (NSDictionary<NSString*, id>*)configProfile {
+  NSMutableDictionary* profile = [NSMutableDictionary dictionary];
   [self.defaults.dictionaryRepresentation enumerateKeysAndObjectsUsingBlock:^(NSString* key, id obj,
                                                                               BOOL* stop) {
     // If the key is not forced it will be ignored, so we don't need to validate
@@ -2438,53 +3551,144 @@ static SNTConfigurator* sharedConfigurator = nil;
     ];
     if ([profileKeys containsObject:key]) return;
 
-    // Check that the key is known to us.
+    id value = CFBridgingRelease(
+        CFPreferencesCopyAppValue((__bridge CFStringRef)key, kMobileConfigDomain));
+    profile[key] = [self overriderValue:value forKey:key];
+  }];
+  return profile;
+}
+
+///
+///  Checks the contents of a profile value that is already known to have the expected type.
+///
+- (NSArray<NSString*>*)validationErrorsForKey:(NSString*)key
+                                        value:(id)value
+                                    inProfile:(NSDictionary*)profile {
+  NSMutableArray* errors = [NSMutableArray array];
+
+  // If the type is a regex, check that it compiles.
+  if (self.forcedConfigKeyTypes[key] == [NSRegularExpression class] &&
+      ![self expressionForPattern:value]) {
+    [errors addObject:[NSString
+                          stringWithFormat:@"The regular expression for key %@ does not compile",
+                                           key]];
+  }
+
+  // If the key is StaticRules, validate the passed in rules.
+  if ([key isEqualToString:kStaticRulesKey]) {
+    // We've already validated that `value` is an NSArray
+    [errors addObjectsFromArray:[self validateStaticRules:(NSArray*)value]];
+  }
+
+  // If the key is RuleSources, validate each source.
+  if ([key isEqualToString:kRuleSourcesKey]) {
+    NSArray<NSString*>* sourceErrors;
+    (void)[SNTRuleSource ruleSourcesFromArray:value errors:&sourceErrors];
+    [errors addObjectsFromArray:sourceErrors];
+  }
+
+  // If the key is MaintenanceWindows, validate each window.
+  if ([key isEqualToString:kMaintenanceWindowsKey]) {
+    NSArray<NSString*>* windowErrors;
+    (void)[SNTMaintenanceWindow maintenanceWindowsFromArray:value errors:&windowErrors];
+    [errors addObjectsFromArray:windowErrors];
+  }
+
+  // If the key is FileAccessPolicy, validate the FAA policy configuration.
+  if ([key isEqualToString:kFileAccessPolicy]) {
+    // We've already validated that `value` is an NSDictionary
+    [errors addObjectsFromArray:[self validateFileAccessPolicy:(NSDictionary*)value]];
+  }
+
+  // If the key is FileAccessPolicyPlist, load and validate the referenced file.
+  // Note: FileAccessPolicyPlist is ignored when FileAccessPolicy is set.
+  if ([key isEqualToString:kFileAccessPolicyPlist] && !profile[kFileAccessPolicy]) {
+    // We've already validated that `value` is an NSString
+    [errors addObjectsFromArray:[self validateFileAccessPolicyPlist:(NSString*)value]];
+  }
+
+  return errors;
+}
+
+- (NSDictionary<NSString*, id>*)parsedConfigProfile {
+  NSMutableDictionary* parsed = [NSMutableDictionary dictionary];
+  [self.configState enumerateKeysAndObjectsUsingBlock:^(NSString* key, id obj, BOOL* stop) {
+    parsed[key] = [obj isKindOfClass:[NSRegularExpression class]]
+                      ? ((NSRegularExpression*)obj).pattern
+                      : obj;
+  }];
+  return parsed;
+}
+
+- (NSArray<NSDictionary<NSString*, NSString*>*>*)configIssuesForProfile:(NSDictionary*)profile
+                                                           parsedConfig:(NSDictionary*)parsed {
+  NSMutableArray* issues = [NSMutableArray array];
+  void (^addIssue)(NSString*, NSString*, NSString*) =
+      ^(NSString* key, NSString* issue, NSString* detail) {
+        [issues addObject:@{
+          kConfigIssueKey : key,
+          kConfigIssueType : issue,
+          kConfigIssueDetail : detail,
+        }];
+      };
+
+  for (NSString* key in [profile.allKeys sortedArrayUsingSelector:@selector(compare:)]) {
+    id value = profile[key];
     id type = self.forcedConfigKeyTypes[key];
     if (!type) {
-      [errors addObject:[NSString stringWithFormat:@"The key %@ is not recognized", key]];
-      return;
+      addIssue(key, kConfigIssueTypeIgnored, @"Not a recognized key");
+      continue;
     }
 
-    // Check that the type of the value matches the expected type.
-    id value = CFBridgingRelease(
-        CFPreferencesCopyAppValue((__bridge CFStringRef)key, kMobileConfigDomain));
-    value = [self overriderValue:value forKey:key];
-    if (![value isKindOfClass:type] &&
-        !(type == [NSRegularExpression class] && [value isKindOfClass:[NSString class]])) {
-      [errors addObject:[NSString stringWithFormat:@"The key %@ has an unexpected type: %@", key,
-                                                   [value class]]];
-      return;
+    if ([key isEqualToString:kFileAccessPolicyPlist] && profile[kFileAccessPolicy]) {
+      addIssue(key, kConfigIssueTypeIgnored, @"FileAccessPolicy is also set and takes precedence");
+      continue;
+    }
+
+    Class expected = (type == [NSRegularExpression class]) ? [NSString class] : type;
+    if (![value isKindOfClass:expected]) {
+      addIssue(key, kConfigIssueTypeMalformed,
+               [NSString stringWithFormat:@"Expected %@ but found %@, the default is used",
+                                          NSStringFromClass(expected), [value class]]);
+      continue;
     }
 
-    // If the type is a regex, check that it compiles.
-    if (type == [NSRegularExpression class] && ![self expressionForPattern:value]) {
-      [errors addObject:[NSString
-                            stringWithFormat:@"The regular expression for key %@ does not compile",
-                                             key]];
+    NSArray<NSString*>* errors = [self validationErrorsForKey:key value:value inProfile:profile];
+    if (errors.count) {
+      addIssue(key, kConfigIssueTypeMalformed, [errors componentsJoinedByString:@"; "]);
+      continue;
     }
 
-    // If the key is StaticRules, validate the passed in rules.
-    if ([key isEqualToString:kStaticRulesKey]) {
-      // We've already validated that `value` is an NSArray
-      [errors addObjectsFromArray:[self validateStaticRules:(NSArray*)value]];
+    NSArray* allowed = AllowedConfigValues()[key];
+    id normalized = [value isKindOfClass:[NSString class]] ? [value lowercaseString] : value;
+    if (allowed && ![allowed containsObject:normalized]) {
+      addIssue(key, kConfigIssueTypeDefaulted,
+               [NSString stringWithFormat:@"%@ is not a supported value, the default is used",
+                                          value]);
+      continue;
     }
 
-    // If the key is FileAccessPolicy, validate the FAA policy configuration.
-    if ([key isEqualToString:kFileAccessPolicy]) {
-      // We've already validated that `value` is an NSDictionary
-      [errors addObjectsFromArray:[self validateFileAccessPolicy:(NSDictionary*)value]];
+    id applied = parsed[key];
+    if (!applied) {
+      addIssue(key, kConfigIssueTypeNotApplied,
+               @"The daemon has not loaded this key and is using the default");
+    } else if (![applied isEqual:value]) {
+      addIssue(key, kConfigIssueTypeNotApplied,
+               @"The daemon is using a different value than the profile sets");
     }
+  }
 
-    // If the key is FileAccessPolicyPlist, load and validate the referenced file.
-    // Note: FileAccessPolicyPlist is ignored when FileAccessPolicy is set.
-    if ([key isEqualToString:kFileAccessPolicyPlist] &&
-        !CFPreferencesAppValueIsForced((__bridge CFStringRef)kFileAccessPolicy,
-                                       kMobileConfigDomain)) {
-      // We've already validated that `value` is an NSString
-      [errors addObjectsFromArray:[self validateFileAccessPolicyPlist:(NSString*)value]];
+  for (NSString* key in [parsed.allKeys sortedArrayUsingSelector:@selector(compare:)]) {
+    // EnablePushNotifications is filled in from the deprecated EnableNATS key.
+    if (profile[key] ||
+        ([key isEqualToString:kEnablePushNotifications] && profile[kEnableNATS])) {
+      continue;
     }
-  }];
-  return errors;
+    addIssue(key, kConfigIssueTypeNotApplied,
+             @"The daemon is using a value that is no longer in the profile");
+  }
+
+  return issues;
 }
 
 - (NSArray*)validateStaticRules:(NSArray*)rules {
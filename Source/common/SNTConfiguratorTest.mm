@@ -242,6 +242,29 @@ typedef BOOL (^StateFileAccessAuthorizer)(void);
   XCTAssertFalse(sut.syncBaseURLConfigured);
 }
 
+- (void)testPushServerPinnedCertificateSHA256 {
+  SNTConfigurator* sut = [[SNTConfigurator alloc] init];
+  NSString* pin = @"0123456789abcdef0123456789abcdef0123456789abcdef0123456789abcdef";
+
+  // Missing and empty values disable pinning.
+  XCTAssertNil(sut.pushServerPinnedCertificateSHA256);
+  sut.configState[@"PushServerPinnedCertificateSHA256"] = @[];
+  XCTAssertNil(sut.pushServerPinnedCertificateSHA256);
+
+  // Fingerprints are normalized to lowercase hex without separators.
+  sut.configState[@"PushServerPinnedCertificateSHA256"] = @[
+    @"01:23:45:67:89:AB:CD:EF:01:23:45:67:89:AB:CD:EF:"
+    @"01:23:45:67:89:AB:CD:EF:01:23:45:67:89:AB:CD:EF",
+  ];
+  XCTAssertEqualObjects(sut.pushServerPinnedCertificateSHA256, @[ pin ]);
+
+  // Malformed entries are dropped, but pinning stays enabled.
+  sut.configState[@"PushServerPinnedCertificateSHA256"] = @[ @"abc", @YES, pin ];
+  XCTAssertEqualObjects(sut.pushServerPinnedCertificateSHA256, @[ pin ]);
+  sut.configState[@"PushServerPinnedCertificateSHA256"] = @[ @"abc" ];
+  XCTAssertEqualObjects(sut.pushServerPinnedCertificateSHA256, @[]);
+}
+
 - (void)testTelemetryFilterExpressions {
   SNTConfigurator* sut = [[SNTConfigurator alloc] init];
 
@@ -350,6 +373,34 @@ typedef BOOL (^StateFileAccessAuthorizer)(void);
   XCTAssertFalse(sut.allowDelegatedSignals);
 }
 
+- (void)testExecutionActionForSigningStatus {
+  SNTConfigurator* sut = [[SNTConfigurator alloc] init];
+  XCTAssertEqual([sut executionActionForSigningStatus:SNTSigningStatusInvalid],
+                 SNTSigningStatusExecutionActionNone);
+
+  sut.configState[@"SigningStatusExecutionActions"] = @{
+    @"Invalid" : @"Block",
+    @"unsigned" : @"blockunknown",
+    @"Adhoc" : @"Allow",
+    @"Production" : @"Block",
+  };
+  XCTAssertEqual([sut executionActionForSigningStatus:SNTSigningStatusInvalid],
+                 SNTSigningStatusExecutionActionBlock);
+  XCTAssertEqual([sut executionActionForSigningStatus:SNTSigningStatusUnsigned],
+                 SNTSigningStatusExecutionActionBlockUnknown);
+  // Unknown actions and validly signed binaries apply no policy.
+  XCTAssertEqual([sut executionActionForSigningStatus:SNTSigningStatusAdhoc],
+                 SNTSigningStatusExecutionActionNone);
+  XCTAssertEqual([sut executionActionForSigningStatus:SNTSigningStatusProduction],
+                 SNTSigningStatusExecutionActionNone);
+  XCTAssertEqual([sut executionActionForSigningStatus:SNTSigningStatusDevelopment],
+                 SNTSigningStatusExecutionActionNone);
+
+  sut.configState[@"SigningStatusExecutionActions"] = @{@"Invalid" : @YES};
+  XCTAssertEqual([sut executionActionForSigningStatus:SNTSigningStatusInvalid],
+                 SNTSigningStatusExecutionActionNone);
+}
+
 #pragma mark - performSyncStateBatch: and clearSyncState tests
 
 - (SNTConfigurator*)configuratorWithEmptySyncStateAtPath:(NSString*)plistPath {
@@ -455,6 +506,28 @@ typedef BOOL (^StateFileAccessAuthorizer)(void);
   XCTAssertTrue([self.fileMgr removeItemAtPath:plistPath error:nil]);
 }
 
+- (void)testPerformSyncStateBatchWithClearKeepsGeneratedMachineID {
+  NSString* plistPath = [NSString stringWithFormat:@"%@/batch-machineid.plist", self.testDir];
+  SNTConfigurator* cfg = [self configuratorWithEmptySyncStateAtPath:plistPath];
+
+  [cfg setGeneratedMachineID:@"6F2C1B9E-3D4A-4E5F-8A7B-9C0D1E2F3A4B"];
+  [cfg setEnableBundles:YES];
+  XCTAssertEqualObjects(cfg.machineID, @"6F2C1B9E-3D4A-4E5F-8A7B-9C0D1E2F3A4B");
+
+  // A clean sync clears the sync server's settings but not the generated machine ID.
+  [cfg performSyncStateBatch:^{
+    [cfg clearSyncState];
+  }];
+
+  XCTAssertFalse(cfg.enableBundles);
+  XCTAssertEqualObjects(cfg.generatedMachineID, @"6F2C1B9E-3D4A-4E5F-8A7B-9C0D1E2F3A4B");
+  XCTAssertEqualObjects(cfg.machineID, @"6F2C1B9E-3D4A-4E5F-8A7B-9C0D1E2F3A4B");
+  NSDictionary* onDisk = [NSDictionary dictionaryWithContentsOfFile:plistPath];
+  XCTAssertEqualObjects(onDisk[@"GeneratedMachineID"], @"6F2C1B9E-3D4A-4E5F-8A7B-9C0D1E2F3A4B");
+
+  XCTAssertTrue([self.fileMgr removeItemAtPath:plistPath error:nil]);
+}
+
 - (void)testClearSyncStateRemovesDiskFileWhenOutsideBatch {
   NSString* plistPath = [NSString stringWithFormat:@"%@/clear-remove.plist", self.testDir];
   SNTConfigurator* cfg = [self configuratorWithEmptySyncStateAtPath:plistPath];
@@ -614,6 +687,51 @@ typedef BOOL (^StateFileAccessAuthorizer)(void);
   XCTAssertEqualWithAccuracy(sut.dnsUpstreamTimeoutSecs, 30.0, 0.0001);
 }
 
+- (void)testRulePrecedence {
+  SNTConfigurator* sut = [[SNTConfigurator alloc] init];
+  XCTAssertNil(sut.rulePrecedence);
+
+  sut.configState[@"RulePrecedence"] =
+      @[ @"TEAMID", @"binary", @"CDHASH", @"SIGNINGID", @"CERTIFICATE" ];
+  NSArray* want = @[
+    @(SNTRuleTypeTeamID), @(SNTRuleTypeBinary), @(SNTRuleTypeCDHash), @(SNTRuleTypeSigningID),
+    @(SNTRuleTypeCertificate)
+  ];
+  XCTAssertEqualObjects(sut.rulePrecedence, want);
+
+  // Anything other than a permutation of the rule types is ignored.
+  sut.configState[@"RulePrecedence"] = @[ @"TEAMID", @"BINARY" ];
+  XCTAssertNil(sut.rulePrecedence);
+  sut.configState[@"RulePrecedence"] =
+      @[ @"TEAMID", @"TEAMID", @"CDHASH", @"SIGNINGID", @"CERTIFICATE" ];
+  XCTAssertNil(sut.rulePrecedence);
+  sut.configState[@"RulePrecedence"] =
+      @[ @"TEAMID", @"BINARY", @"CDHASH", @"SIGNINGID", @"CERTIFICATE", @"REQUIREMENT" ];
+  XCTAssertNil(sut.rulePrecedence);
+  sut.configState[@"RulePrecedence"] = @[ @"TEAMID", @"BINARY", @"CDHASH", @"SIGNINGID", @(3000) ];
+  XCTAssertNil(sut.rulePrecedence);
+}
+
+- (void)testDeveloperToolsAllowlist {
+  SNTConfigurator* sut = [[SNTConfigurator alloc] init];
+  XCTAssertFalse(sut.enableDeveloperToolsAllowlist);
+
+  sut.configState[@"EnableDeveloperToolsAllowlist"] = @YES;
+  XCTAssertTrue(sut.enableDeveloperToolsAllowlist);
+
+  // Without an override the built-in list is used.
+  XCTAssertTrue([sut.developerToolsAllowlist containsObject:@"2ZEFAR8TH3"]);
+  XCTAssertTrue([sut.developerToolsAllowlist containsObject:@"UBF8T346G9:com.microsoft.VSCode"]);
+
+  // An override replaces the built-in list, ignoring entries that aren't strings.
+  sut.configState[@"DeveloperToolsAllowlist"] = @[ @"EQHXZ8M8AV", @"", @42 ];
+  XCTAssertEqualObjects(sut.developerToolsAllowlist, @[ @"EQHXZ8M8AV" ]);
+
+  // An empty override disables every built-in entry.
+  sut.configState[@"DeveloperToolsAllowlist"] = @[];
+  XCTAssertEqualObjects(sut.developerToolsAllowlist, @[]);
+}
+
 - (void)testDemotedAdminsPersistReloadAndFilter {
   NSString* syncStatePath = [NSString stringWithFormat:@"%@/sync-state.plist", self.testDir];
   NSString* statePath = [NSString stringWithFormat:@"%@/state.plist", self.testDir];
@@ -676,4 +794,126 @@ typedef BOOL (^StateFileAccessAuthorizer)(void);
   XCTAssertNotNil([cfg savedDemotedAdmins]);
 }
 
+- (SNTConfigurator*)configuratorWithConfig:(NSDictionary*)config syncState:(NSDictionary*)sync {
+  SNTConfigurator* cfg = [[SNTConfigurator alloc] init];
+  cfg.configState = [config mutableCopy];
+  cfg.syncState = [sync mutableCopy];
+  return cfg;
+}
+
+- (void)testEffectiveConfigHashIsStable {
+  SNTConfigurator* cfg = [self configuratorWithConfig:@{
+    @"ClientMode" : @1,
+    @"SyncBaseURL" : @"https://sync.example.com",
+    @"FileChangesPrefixFilters" : @[ @"/tmp" ],
+  }
+                                            syncState:@{@"AllowedPathRegex" : @"a"}];
+  NSString* hash = cfg.effectiveConfigHash;
+  XCTAssertEqual(hash.length, 64);
+  XCTAssertEqualObjects(cfg.effectiveConfigHash, hash);
+
+  // The same configuration built in a different order hashes the same.
+  SNTConfigurator* other = [self configuratorWithConfig:@{
+    @"FileChangesPrefixFilters" : @[ @"/tmp" ],
+    @"SyncBaseURL" : @"https://sync.example.com",
+    @"ClientMode" : @1,
+  }
+                                              syncState:@{@"AllowedPathRegex" : @"a"}];
+  XCTAssertEqualObjects(other.effectiveConfigHash, hash);
+
+  // Sync bookkeeping does not affect the hash.
+  other.syncState[@"FullSyncLastSuccess"] = [NSDate date];
+  other.syncState[@"RuleSyncLastSuccess"] = [NSDate date];
+  other.syncState[@"SyncTypeRequired"] = @1;
+  XCTAssertEqualObjects(other.effectiveConfigHash, hash);
+}
+
+- (void)testEffectiveConfigHashChangesWithConfig {
+  NSDictionary* config = @{@"ClientMode" : @1, @"SyncBaseURL" : @"https://sync.example.com"};
+  NSString* hash = [self configuratorWithConfig:config syncState:@{}].effectiveConfigHash;
+
+  // A changed profile value.
+  NSMutableDictionary* changed = [config mutableCopy];
+  changed[@"ClientMode"] = @2;
+  XCTAssertNotEqualObjects([self configuratorWithConfig:changed syncState:@{}].effectiveConfigHash,
+                           hash);
+
+  // A setting applied by the sync server.
+  XCTAssertNotEqualObjects(
+      [self configuratorWithConfig:config syncState:@{@"ClientMode" : @2}].effectiveConfigHash,
+      hash);
+}
+
+- (NSDictionary<NSString*, NSString*>*)issueTypesForProfile:(NSDictionary*)profile
+                                            daemonConfig:(NSDictionary*)daemonConfig {
+  NSDictionary* parsed =
+      [self configuratorWithConfig:daemonConfig syncState:@{}].parsedConfigProfile;
+  NSArray* issues = [[[SNTConfigurator alloc] init] configIssuesForProfile:profile
+                                                              parsedConfig:parsed];
+  NSMutableDictionary* types = [NSMutableDictionary dictionary];
+  for (NSDictionary* issue in issues) {
+    XCTAssertGreaterThan([issue[kConfigIssueDetail] length], 0);
+    types[issue[kConfigIssueKey]] = issue[kConfigIssueType];
+  }
+  return types;
+}
+
+- (void)testConfigIssuesFlagsIgnoredAndMalformedKeys {
+  NSDictionary* badPolicy = @{@"Version" : @"v1", @"WatchItems" : @{@"Rule" : @"not-a-dict"}};
+  NSDictionary* profile = @{
+    @"FileAccesPolicy" : @{},
+    @"FileAccessPolicy" : badPolicy,
+    @"FileAccessPolicyPlist" : @"/var/db/santa/faa.plist",
+    @"ClientMode" : @"2",
+    @"AllowedPathRegex" : @"(",
+    @"DiskImageExecutionAction" : @"Blok",
+    @"EventLogType" : @"File",
+    @"SyncBaseURL" : @"https://sync.example.com",
+  };
+
+  // The daemon drops values with the wrong type or that don't parse when it reads the profile.
+  NSDictionary* daemonConfig = @{
+    @"FileAccessPolicy" : badPolicy,
+    @"FileAccessPolicyPlist" : @"/var/db/santa/faa.plist",
+    @"DiskImageExecutionAction" : @"Blok",
+    @"EventLogType" : @"File",
+    @"SyncBaseURL" : @"https://sync.example.com",
+  };
+
+  XCTAssertEqualObjects([self issueTypesForProfile:profile daemonConfig:daemonConfig], (@{
+                          @"FileAccesPolicy" : kConfigIssueTypeIgnored,
+                          @"FileAccessPolicy" : kConfigIssueTypeMalformed,
+                          @"FileAccessPolicyPlist" : kConfigIssueTypeIgnored,
+                          @"ClientMode" : kConfigIssueTypeMalformed,
+                          @"AllowedPathRegex" : kConfigIssueTypeMalformed,
+                          @"DiskImageExecutionAction" : kConfigIssueTypeDefaulted,
+                        }));
+}
+
+- (void)testConfigIssuesFlagsValuesTheDaemonHasNotApplied {
+  NSDictionary* profile = @{
+    @"ClientMode" : @2,
+    @"SyncBaseURL" : @"https://new.example.com",
+    @"AllowedPathRegex" : @"^/usr/local/",
+  };
+  NSDictionary* daemonConfig = @{
+    @"SyncBaseURL" : @"https://old.example.com",
+    @"AllowedPathRegex" : [NSRegularExpression regularExpressionWithPattern:@"^/usr/local/"
+                                                                    options:0
+                                                                      error:NULL],
+    @"BlockedPathRegex" : [NSRegularExpression regularExpressionWithPattern:@"^/tmp/"
+                                                                    options:0
+                                                                      error:NULL],
+  };
+
+  XCTAssertEqualObjects([self issueTypesForProfile:profile daemonConfig:daemonConfig], (@{
+                          @"ClientMode" : kConfigIssueTypeNotApplied,
+                          @"SyncBaseURL" : kConfigIssueTypeNotApplied,
+                          @"BlockedPathRegex" : kConfigIssueTypeNotApplied,
+                        }));
+
+  // Once the daemon has caught up nothing is flagged.
+  XCTAssertEqualObjects([self issueTypesForProfile:profile daemonConfig:profile], @{});
+}
+
 @end
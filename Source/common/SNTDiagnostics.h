@@ -0,0 +1,47 @@
+/// Copyright 2026 North Pole Security, Inc.
+///
+/// Licensed under the Apache License, Version 2.0 (the "License");
+/// you may not use this file except in compliance with the License.
+/// You may obtain a copy of the License at
+///
+///     http://www.apache.org/licenses/LICENSE-2.0
+///
+/// Unless required by applicable law or agreed to in writing, software
+/// distributed under the License is distributed on an "AS IS" BASIS,
+/// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+/// See the License for the specific language governing permissions and
+/// limitations under the License.
+
+
+#import <Foundation/Foundation.h>
+
+NS_ASSUME_NONNULL_BEGIN
+
+/// The value that replaces anything removed by +[SNTDiagnostics redact:].
+extern NSString* const kDiagnosticsRedacted;
+
+///
+///  Builds the diagnostics bundle used for remote troubleshooting: host details, the Santa
+///  version and the configuration in effect, along with any sections supplied by the caller.
+///  Everything in the bundle is run through +redact: so it is safe to send off the host.
+///
+@interface SNTDiagnostics : NSObject
+
+///
+///  Returns a JSON-serializable diagnostics bundle. Each entry in `sections` is added to the
+///  bundle under its key, after redaction.
+///
++ (NSDictionary<NSString*, id>*)bundleWithSections:(nullable NSDictionary<NSString*, id>*)sections;
+
+///
+///  Returns a copy of `value` with secrets removed. Values under keys that look like they hold a
+///  password, token, secret or private key, and all SyncExtraHeaders values, are replaced with
+///  kDiagnosticsRedacted. Credentials and query strings are stripped from URLs. Dictionaries and
+///  arrays are redacted recursively and values that cannot be represented in JSON are converted
+///  to strings.
+///
++ (id)redact:(id)value;
+
+@end
+
+NS_ASSUME_NONNULL_END
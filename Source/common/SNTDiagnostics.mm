@@ -0,0 +1,116 @@
+/// Copyright 2026 North Pole Security, Inc.
+///
+/// Licensed under the Apache License, Version 2.0 (the "License");
+/// you may not use this file except in compliance with the License.
+/// You may obtain a copy of the License at
+///
+///     http://www.apache.org/licenses/LICENSE-2.0
+///
+/// Unless required by applicable law or agreed to in writing, software
+/// distributed under the License is distributed on an "AS IS" BASIS,
+/// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+/// See the License for the specific language governing permissions and
+/// limitations under the License.
+
+
+#import "Source/common/SNTDiagnostics.h"
+
+#import "Source/common/SNTConfigurator.h"
+#import "Source/common/SNTSystemInfo.h"
+
+NSString* const kDiagnosticsRedacted = @"<redacted>";
+
+// Lowercased substrings of keys whose values are always redacted.
+static NSArray<NSString*>* SensitiveKeyFragments() {
+  static NSArray<NSString*>* fragments;
+  static dispatch_once_t onceToken;
+  dispatch_once(&onceToken, ^{
+    fragments = @[
+      @"password", @"passphrase", @"secret", @"token", @"jwt", @"privatekey", @"private_key",
+      @"apikey", @"api_key", @"authorization", @"cookie", @"credential", @"extraheaders"
+    ];
+  });
+  return fragments;
+}
+
+static BOOL IsSensitiveKey(NSString* key) {
+  NSString* lower = key.lowercaseString;
+  for (NSString* fragment in SensitiveKeyFragments()) {
+    if ([lower containsString:fragment]) return YES;
+  }
+  return NO;
+}
+
+// Strips any user, password and query from a URL string. Strings that are not URLs with a scheme
+// and host are returned unchanged.
+static NSString* RedactURLString(NSString* str) {
+  if (![str containsString:@"://"]) return str;
+  NSURLComponents* components = [NSURLComponents componentsWithString:str];
+  if (!components.scheme || !components.host) return str;
+  if (!components.user && !components.password && !components.query) return str;
+
+  components.user = nil;
+  components.password = nil;
+  components.query = nil;
+  return components.string ?: kDiagnosticsRedacted;
+}
+
+@implementation SNTDiagnostics
+
++ (NSDictionary<NSString*, id>*)bundleWithSections:(NSDictionary<NSString*, id>*)sections {
+  SNTConfigurator* config = [SNTConfigurator configurator];
+
+  NSMutableDictionary* bundle = [NSMutableDictionary dictionary];
+  bundle[@"generated_at"] = [[[NSISO8601DateFormatter alloc] init] stringFromDate:[NSDate date]];
+  bundle[@"host"] = @{
+    @"hostname" : [SNTSystemInfo longHostname] ?: @"",
+    @"serial_number" : [SNTSystemInfo serialNumber] ?: @"",
+    @"model" : [SNTSystemInfo modelIdentifier] ?: @"",
+    @"os_version" : [SNTSystemInfo osVersion] ?: @"",
+    @"os_build" : [SNTSystemInfo osBuild] ?: @"",
+    @"boot_session_uuid" : [SNTSystemInfo bootSessionUUID] ?: @"",
+  };
+  bundle[@"santa"] = @{
+    @"version" : [SNTSystemInfo santaFullVersion] ?: @"",
+  };
+  bundle[@"configuration"] = @{
+    @"hash" : config.configHash ?: @"",
+    @"values" : [self redact:config.configSnapshot],
+  };
+
+  for (NSString* section in sections) {
+    bundle[section] = [self redact:sections[section]];
+  }
+  return bundle;
+}
+
++ (id)redact:(id)value {
+  if ([value isKindOfClass:[NSDictionary class]]) {
+    NSMutableDictionary* redacted = [NSMutableDictionary dictionaryWithCapacity:[value count]];
+    for (id key in value) {
+      NSString* keyStr = [key description];
+      redacted[keyStr] = IsSensitiveKey(keyStr) ? kDiagnosticsRedacted : [self redact:value[key]];
+    }
+    return redacted;
+  } else if ([value isKindOfClass:[NSArray class]]) {
+    NSMutableArray* redacted = [NSMutableArray arrayWithCapacity:[value count]];
+    for (id v in value) {
+      [redacted addObject:[self redact:v]];
+    }
+    return redacted;
+  } else if ([value isKindOfClass:[NSString class]]) {
+    return RedactURLString(value);
+  } else if ([value isKindOfClass:[NSURL class]]) {
+    return RedactURLString([value absoluteString]);
+  } else if ([value isKindOfClass:[NSNumber class]] || [value isKindOfClass:[NSNull class]]) {
+    return value;
+  } else if ([value isKindOfClass:[NSDate class]]) {
+    return [[[NSISO8601DateFormatter alloc] init] stringFromDate:value];
+  } else if ([value isKindOfClass:[NSData class]]) {
+    // Raw data, e.g. certificates, is neither useful for troubleshooting nor safe to include.
+    return [NSString stringWithFormat:@"<%lu bytes>", [value length]];
+  }
+  return value ? [value description] : [NSNull null];
+}
+
+@end
@@ -33,6 +33,7 @@ typedef NS_ENUM(NSInteger, SNTErrorCode) {
   SNTErrorCodeFailedToParseJSON = 310,
   SNTErrorCodeFailedToParseProto = 320,
   SNTErrorCodeFailedToHTTP = 330,
+  SNTErrorCodeFailedToVerifySignature = 340,
 
   // Config validation errors
   SNTErrorCodeRuleInvalid = 410,
@@ -48,6 +49,11 @@ typedef NS_ENUM(NSInteger, SNTErrorCode) {
   SNTErrorCodeEmptyRuleArray = 510,
   SNTErrorCodeInsertOrReplaceRuleFailed = 511,
   SNTErrorCodeRemoveRuleFailed = 512,
+  SNTErrorCodeRuleUpdateDeferred = 513,
+  SNTErrorCodeQueryInvalid = 514,
+  SNTErrorCodeQueryNotReadOnly = 515,
+  SNTErrorCodeQueryFailed = 516,
+  SNTErrorCodeDecisionDatabaseDisabled = 517,
 
   // TMM errors
   SNTErrorCodeTMMNoPolicy = 610,
@@ -78,6 +84,10 @@ typedef NS_ENUM(NSInteger, SNTErrorCode) {
   SNTErrorCodeTAMNoConsoleUser = 915,
   SNTErrorCodeTAMSessionAlreadyActive = 916,
   SNTErrorCodeTAMJustificationRequired = 917,
+
+  // Push notification errors
+  SNTErrorCodePushCredentialExpired = 1010,
+  SNTErrorCodePushDrainFailed = 1011,
 };
 
 @interface SNTError : NSObject
@@ -228,6 +228,30 @@
 ///
 - (SantaVnode)vnode;
 
+///
+///  @return The name of the file system type the file resides on (e.g. apfs, smbfs, nfs), or nil
+///  if the volume could not be queried.
+///
+- (NSString*)fileSystemType;
+
+///
+///  @return YES if the file resides on a volume that is not marked as local, such as an NFS or SMB
+///  share.
+///
+- (BOOL)isOnNetworkVolume;
+
+///
+///  @return YES if the file resides on a mounted disk image, such as a DMG opened from the Finder
+///  or attached with hdiutil.
+///
+- (BOOL)isOnDiskImage;
+
+///
+///  @return YES if the file resides on a mounted disk image that was quarantined when it was
+///  mounted, e.g. because the image was downloaded.
+///
+- (BOOL)isOnQuarantinedDiskImage;
+
 ///
 ///  @return The underlying file handle.
 ///
@@ -245,4 +269,16 @@
 ///
 - (NSString*)codesignStatus;
 
+///
+///  @return The code signature flags (SecCodeSignatureFlags) of the file, or 0 if the file is
+///  unsigned or its signature could not be validated.
+///
+- (uint32_t)codeSignatureFlags;
+
+///
+///  @return The names of the flags set in a SecCodeSignatureFlags value, as used by codesign(1)
+///  (e.g. "adhoc", "library-validation", "runtime"). Unknown bits are returned in hex.
+///
++ (NSArray<NSString*>*)namesForCodeSignatureFlags:(uint32_t)flags;
+
 @end
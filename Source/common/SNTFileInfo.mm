@@ -16,12 +16,14 @@
 #import "Source/common/SNTFileInfo.h"
 
 #import <CommonCrypto/CommonDigest.h>
+#import <DiskArbitration/DiskArbitration.h>
 #import <fmdb/FMDB.h>
 
 #include <mach-o/arch.h>
 #include <mach-o/loader.h>
 #include <mach-o/swap.h>
 #include <mach-o/utils.h>
+#include <sys/mount.h>
 #include <sys/stat.h>
 #include <sys/xattr.h>
 
@@ -64,6 +66,15 @@
 @property NSDictionary* cachedHeaders;
 @property MOLCodesignChecker* cachedCodesignChecker;
 @property(nonatomic) NSError* codesignCheckerError;
+
+// Cached volume information
+@property BOOL volumeInfoLoaded;
+@property NSString* fileSystemTypeStorage;
+@property uint32_t volumeFlags;
+@property NSString* volumeDevice;
+@property fsid_t volumeFSID;
+@property BOOL diskImageLoaded;
+@property BOOL diskImage;
 @end
 
 @implementation SNTFileInfo
@@ -335,6 +346,88 @@
   return YES;
 }
 
+#pragma mark Volume Information
+
+- (NSString*)fileSystemType {
+  [self loadVolumeInfo];
+  return self.fileSystemTypeStorage;
+}
+
+- (BOOL)isOnNetworkVolume {
+  [self loadVolumeInfo];
+  return self.fileSystemTypeStorage && !(self.volumeFlags & MNT_LOCAL);
+}
+
+- (void)loadVolumeInfo {
+  if (self.volumeInfoLoaded) return;
+  self.volumeInfoLoaded = YES;
+
+  struct statfs sfs;
+  if (fstatfs(self.fileHandle.fileDescriptor, &sfs) != 0) {
+    LOGD(@"Unable to stat volume for %@: %s", self.path, strerror(errno));
+    return;
+  }
+
+  self.fileSystemTypeStorage = @(sfs.f_fstypename);
+  self.volumeFlags = sfs.f_flags;
+  self.volumeDevice = @(sfs.f_mntfromname);
+  self.volumeFSID = sfs.f_fsid;
+}
+
+- (BOOL)isOnDiskImage {
+  if (self.diskImageLoaded) return self.diskImage;
+  self.diskImageLoaded = YES;
+
+  [self loadVolumeInfo];
+
+  // Disk images are always local volumes backed by a device node. Checking this first avoids a
+  // round trip to diskarbitrationd for network and synthetic volumes.
+  if (!self.fileSystemTypeStorage || !(self.volumeFlags & MNT_LOCAL) ||
+      (self.volumeFlags & MNT_ROOTFS) || ![self.volumeDevice hasPrefix:@"/dev/"]) {
+    return NO;
+  }
+
+  // Whether a volume is a disk image can't change while it is mounted, so cache the answer per
+  // mount to avoid asking diskarbitrationd on every execution. The fsid is part of the key as
+  // device names are reused when an image is detached and another disk attached.
+  static NSCache<NSString*, NSNumber*>* diskImageCache;
+  static DASessionRef session;
+  static dispatch_once_t onceToken;
+  dispatch_once(&onceToken, ^{
+    diskImageCache = [[NSCache alloc] init];
+    diskImageCache.countLimit = 64;
+    session = DASessionCreate(NULL);
+  });
+
+  NSString* cacheKey = [NSString stringWithFormat:@"%@:%d:%d", self.volumeDevice,
+                                                  self.volumeFSID.val[0], self.volumeFSID.val[1]];
+  NSNumber* cached = [diskImageCache objectForKey:cacheKey];
+  if (cached) {
+    self.diskImage = [cached boolValue];
+    return self.diskImage;
+  }
+
+  if (!session) return NO;
+
+  DADiskRef disk = DADiskCreateFromBSDName(NULL, session, self.volumeDevice.UTF8String);
+  if (!disk) return NO;
+
+  NSDictionary* description = CFBridgingRelease(DADiskCopyDescription(disk));
+  CFRelease(disk);
+  if (!description) return NO;
+
+  self.diskImage = [description[(__bridge NSString*)kDADiskDescriptionDeviceModelKey]
+      isEqualToString:@"Disk Image"];
+  [diskImageCache setObject:@(self.diskImage) forKey:cacheKey];
+  return self.diskImage;
+}
+
+- (BOOL)isOnQuarantinedDiskImage {
+  // The kernel marks volumes mounted from a quarantined image with MNT_QUARANTINE so that every
+  // file on the volume inherits the quarantine.
+  return self.isOnDiskImage && (self.volumeFlags & MNT_QUARANTINE);
+}
+
 #pragma mark Bundle Information
 
 ///
@@ -847,4 +940,40 @@
   return @"Yes";
 }
 
+- (uint32_t)codeSignatureFlags {
+  NSError* error;
+  MOLCodesignChecker* csc = [self codesignCheckerWithError:&error];
+  return error ? 0 : csc.signatureFlags;
+}
+
++ (NSArray<NSString*>*)namesForCodeSignatureFlags:(uint32_t)flags {
+  static const struct {
+    uint32_t flag;
+    NSString* name;
+  } kFlagNames[] = {
+      {kSecCodeSignatureHost, @"host"},
+      {kSecCodeSignatureAdhoc, @"adhoc"},
+      {kSecCodeSignatureForceHard, @"hard"},
+      {kSecCodeSignatureForceKill, @"kill"},
+      {kSecCodeSignatureForceExpiration, @"expires"},
+      {kSecCodeSignatureRestrict, @"restrict"},
+      {kSecCodeSignatureEnforcement, @"enforcement"},
+      {kSecCodeSignatureLibraryValidation, @"library-validation"},
+      {kSecCodeSignatureRuntime, @"runtime"},
+      {kSecCodeSignatureLinkerSigned, @"linker-signed"},
+  };
+
+  NSMutableArray<NSString*>* names = [NSMutableArray array];
+  for (const auto& f : kFlagNames) {
+    if (flags & f.flag) {
+      [names addObject:f.name];
+      flags &= ~f.flag;
+    }
+  }
+  if (flags) {
+    [names addObject:[NSString stringWithFormat:@"0x%x", flags]];
+  }
+  return names;
+}
+
 @end
@@ -276,4 +276,43 @@
   }
 }
 
+- (void)testCodeSignatureFlags {
+  NSBundle* bundle = [NSBundle bundleForClass:[self class]];
+
+  SNTFileInfo* sut =
+      [[SNTFileInfo alloc] initWithPath:[bundle pathForResource:@"yikes-universal_adhoc"
+                                                        ofType:@""]];
+  XCTAssertTrue(sut.codeSignatureFlags & kSecCodeSignatureAdhoc);
+  XCTAssertTrue([[SNTFileInfo namesForCodeSignatureFlags:sut.codeSignatureFlags]
+      containsObject:@"adhoc"]);
+
+  sut = [[SNTFileInfo alloc] initWithPath:[bundle pathForResource:@"yikes-universal_signed"
+                                                            ofType:@""]];
+  XCTAssertFalse(sut.codeSignatureFlags & kSecCodeSignatureAdhoc);
+
+  // Unsigned files have no flags.
+  sut = [[SNTFileInfo alloc] initWithPath:[bundle pathForResource:@"32bitplist" ofType:@""]];
+  XCTAssertEqual(sut.codeSignatureFlags, 0);
+}
+
+- (void)testNamesForCodeSignatureFlags {
+  XCTAssertEqualObjects([SNTFileInfo namesForCodeSignatureFlags:0], @[]);
+  XCTAssertEqualObjects([SNTFileInfo namesForCodeSignatureFlags:kSecCodeSignatureAdhoc],
+                        @[ @"adhoc" ]);
+  XCTAssertEqualObjects(
+      [SNTFileInfo namesForCodeSignatureFlags:kSecCodeSignatureRuntime |
+                                              kSecCodeSignatureLibraryValidation],
+      (@[ @"library-validation", @"runtime" ]));
+  XCTAssertEqualObjects([SNTFileInfo namesForCodeSignatureFlags:kSecCodeSignatureForceHard |
+                                                                kSecCodeSignatureForceKill |
+                                                                kSecCodeSignatureRuntime],
+                        (@[ @"hard", @"kill", @"runtime" ]));
+  XCTAssertEqualObjects([SNTFileInfo namesForCodeSignatureFlags:kSecCodeSignatureAdhoc |
+                                                                kSecCodeSignatureLinkerSigned],
+                        (@[ @"adhoc", @"linker-signed" ]));
+  XCTAssertEqualObjects(
+      [SNTFileInfo namesForCodeSignatureFlags:kSecCodeSignatureRuntime | 0x80000000],
+      (@[ @"runtime", @"0x80000000" ]));
+}
+
 @end
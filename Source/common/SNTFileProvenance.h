@@ -0,0 +1,37 @@
+/// Copyright 2026 North Pole Security, Inc.
+///
+/// Licensed under the Apache License, Version 2.0 (the "License");
+/// you may not use this file except in compliance with the License.
+/// You may obtain a copy of the License at
+///
+///     http://www.apache.org/licenses/LICENSE-2.0
+///
+/// Unless required by applicable law or agreed to in writing, software
+/// distributed under the License is distributed on an "AS IS" BASIS,
+/// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+/// See the License for the specific language governing permissions and
+/// limitations under the License.
+
+#import <Foundation/Foundation.h>
+
+NS_ASSUME_NONNULL_BEGIN
+
+///
+///  The process that last wrote a file at a path watched by a FileAccessPolicy rule.
+///
+@interface SNTFileProvenance : NSObject
+
+@property(nullable) NSString* writerPath;
+@property(nullable) NSNumber* writerPID;
+@property(nullable) NSString* writerSigningID;
+@property(nullable) NSString* writerTeamID;
+@property(nullable) NSString* writerCDHash;
+
+// The name of the FileAccessPolicy rule that watched the write.
+@property(nullable) NSString* ruleName;
+
+@property NSDate* writeTime;
+
+@end
+
+NS_ASSUME_NONNULL_END
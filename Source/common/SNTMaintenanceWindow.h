@@ -0,0 +1,65 @@
+/// Copyright 2026 North Pole Security, Inc.
+///
+/// Licensed under the Apache License, Version 2.0 (the "License");
+/// you may not use this file except in compliance with the License.
+/// You may obtain a copy of the License at
+///
+///     http://www.apache.org/licenses/LICENSE-2.0
+///
+/// Unless required by applicable law or agreed to in writing, software
+/// distributed under the License is distributed on an "AS IS" BASIS,
+/// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+/// See the License for the specific language governing permissions and
+/// limitations under the License.
+
+#import <Foundation/Foundation.h>
+
+/// Keys of each dictionary in the MaintenanceWindows configuration array.
+extern NSString* const kMaintenanceWindowStart;
+extern NSString* const kMaintenanceWindowEnd;
+extern NSString* const kMaintenanceWindowWeekdays;
+
+///
+///  A recurring period, configured with the MaintenanceWindows key, during which a request from
+///  the sync server to move from Monitor mode to an enforcing mode is deferred until the period
+///  ends. Times are in the local time zone of the machine.
+///
+@interface SNTMaintenanceWindow : NSObject
+
+/// Minutes after midnight at which the window opens.
+@property(readonly) NSInteger startMinute;
+
+/// Minutes after midnight at which the window closes. When this is less than startMinute the
+/// window closes on the following day.
+@property(readonly) NSInteger endMinute;
+
+/// The days the window opens on, using NSCalendar weekday numbers (1 is Sunday). An empty set
+/// means every day.
+@property(readonly, copy) NSIndexSet* weekdays;
+
+- (instancetype)initWithStartMinute:(NSInteger)startMinute
+                          endMinute:(NSInteger)endMinute
+                           weekdays:(NSIndexSet*)weekdays;
+
+///
+///  If date falls inside an occurrence of this window, return the time that occurrence closes.
+///  Otherwise return nil.
+///
+- (NSDate*)endOfWindowContainingDate:(NSDate*)date calendar:(NSCalendar*)calendar;
+
+///
+///  Return the time the last of the windows containing date closes, or nil if date is outside
+///  all windows.
+///
++ (NSDate*)endOfWindowsContainingDate:(NSDate*)date
+                            inWindows:(NSArray<SNTMaintenanceWindow*>*)windows
+                             calendar:(NSCalendar*)calendar;
+
+///
+///  Parse the value of the MaintenanceWindows configuration key. Invalid entries are skipped
+///  and described in `errors`.
+///
++ (NSArray<SNTMaintenanceWindow*>*)maintenanceWindowsFromArray:(NSArray*)array
+                                                        errors:(NSArray<NSString*>**)errors;
+
+@end
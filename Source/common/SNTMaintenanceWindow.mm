@@ -0,0 +1,167 @@
+/// Copyright 2026 North Pole Security, Inc.
+///
+/// Licensed under the Apache License, Version 2.0 (the "License");
+/// you may not use this file except in compliance with the License.
+/// You may obtain a copy of the License at
+///
+///     http://www.apache.org/licenses/LICENSE-2.0
+///
+/// Unless required by applicable law or agreed to in writing, software
+/// distributed under the License is distributed on an "AS IS" BASIS,
+/// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+/// See the License for the specific language governing permissions and
+/// limitations under the License.
+
+#import "Source/common/SNTMaintenanceWindow.h"
+
+NSString* const kMaintenanceWindowStart = @"start";
+NSString* const kMaintenanceWindowEnd = @"end";
+NSString* const kMaintenanceWindowWeekdays = @"weekdays";
+
+static const NSInteger kMinutesPerDay = 24 * 60;
+
+// Parse a 24-hour "HH:MM" time into minutes after midnight. Returns -1 if invalid.
+static NSInteger MinuteOfDay(id value) {
+  if (![value isKindOfClass:[NSString class]]) return -1;
+  NSArray<NSString*>* parts = [value componentsSeparatedByString:@":"];
+  if (parts.count != 2 || parts[0].length < 1 || parts[0].length > 2 || parts[1].length != 2) {
+    return -1;
+  }
+
+  NSCharacterSet* nonDigits = [[NSCharacterSet decimalDigitCharacterSet] invertedSet];
+  for (NSString* part in parts) {
+    if ([part rangeOfCharacterFromSet:nonDigits].location != NSNotFound) return -1;
+  }
+
+  NSInteger hour = parts[0].integerValue;
+  NSInteger minute = parts[1].integerValue;
+  if (hour > 23 || minute > 59) return -1;
+  return hour * 60 + minute;
+}
+
+@implementation SNTMaintenanceWindow
+
+- (instancetype)initWithStartMinute:(NSInteger)startMinute
+                          endMinute:(NSInteger)endMinute
+                           weekdays:(NSIndexSet*)weekdays {
+  self = [super init];
+  if (self) {
+    _startMinute = startMinute;
+    _endMinute = endMinute;
+    _weekdays = [weekdays copy] ?: [NSIndexSet indexSet];
+  }
+  return self;
+}
+
+- (NSString*)description {
+  return [NSString stringWithFormat:@"%02ld:%02ld-%02ld:%02ld", self.startMinute / 60,
+                                    self.startMinute % 60, self.endMinute / 60,
+                                    self.endMinute % 60];
+}
+
+- (NSDate*)endOfWindowContainingDate:(NSDate*)date calendar:(NSCalendar*)calendar {
+  NSInteger duration = self.endMinute - self.startMinute;
+  if (duration <= 0) duration += kMinutesPerDay;
+
+  // Windows are at most a day long, so only an occurrence that opened today or yesterday can
+  // contain date.
+  NSDate* today = [calendar startOfDayForDate:date];
+  for (NSInteger daysAgo = 0; daysAgo <= 1; ++daysAgo) {
+    NSDate* day = [calendar dateByAddingUnit:NSCalendarUnitDay
+                                       value:-daysAgo
+                                      toDate:today
+                                     options:0];
+    if (self.weekdays.count &&
+        ![self.weekdays containsIndex:[calendar component:NSCalendarUnitWeekday fromDate:day]]) {
+      continue;
+    }
+
+    NSDate* start = [calendar dateBySettingHour:self.startMinute / 60
+                                         minute:self.startMinute % 60
+                                         second:0
+                                         ofDate:day
+                                        options:0];
+    NSDate* end = [calendar dateByAddingUnit:NSCalendarUnitMinute
+                                       value:duration
+                                      toDate:start
+                                     options:0];
+    if (start && end && [date compare:start] != NSOrderedAscending &&
+        [date compare:end] == NSOrderedAscending) {
+      return end;
+    }
+  }
+
+  return nil;
+}
+
++ (NSDate*)endOfWindowsContainingDate:(NSDate*)date
+                            inWindows:(NSArray<SNTMaintenanceWindow*>*)windows
+                             calendar:(NSCalendar*)calendar {
+  NSDate* latest;
+  for (SNTMaintenanceWindow* window in windows) {
+    NSDate* end = [window endOfWindowContainingDate:date calendar:calendar];
+    if (end && (!latest || [end compare:latest] == NSOrderedDescending)) {
+      latest = end;
+    }
+  }
+  return latest;
+}
+
++ (NSArray<SNTMaintenanceWindow*>*)maintenanceWindowsFromArray:(NSArray*)array
+                                                        errors:(NSArray<NSString*>**)errors {
+  NSMutableArray<SNTMaintenanceWindow*>* windows = [NSMutableArray array];
+  NSMutableArray<NSString*>* errs = [NSMutableArray array];
+
+  if (![array isKindOfClass:[NSArray class]]) array = nil;
+  [array enumerateObjectsUsingBlock:^(id obj, NSUInteger idx, BOOL* stop) {
+    if (![obj isKindOfClass:[NSDictionary class]]) {
+      [errs addObject:[NSString stringWithFormat:@"MaintenanceWindow at index %lu has bad type: %@",
+                                                 idx, [obj class]]];
+      return;
+    }
+    NSDictionary* dict = obj;
+
+    NSInteger start = MinuteOfDay(dict[kMaintenanceWindowStart]);
+    NSInteger end = MinuteOfDay(dict[kMaintenanceWindowEnd]);
+    if (start < 0 || end < 0) {
+      [errs addObject:[NSString stringWithFormat:@"MaintenanceWindow at index %lu must have a %@ "
+                                                 @"and %@ in HH:MM format",
+                                                 idx, kMaintenanceWindowStart,
+                                                 kMaintenanceWindowEnd]];
+      return;
+    }
+    if (start == end) {
+      [errs addObject:[NSString stringWithFormat:@"MaintenanceWindow at index %lu is empty", idx]];
+      return;
+    }
+
+    // Weekdays use the same numbering as launchd's StartCalendarInterval: 0 and 7 are Sunday.
+    NSMutableIndexSet* weekdays = [NSMutableIndexSet indexSet];
+    id days = dict[kMaintenanceWindowWeekdays];
+    if (days && ![days isKindOfClass:[NSArray class]]) {
+      [errs addObject:[NSString stringWithFormat:@"MaintenanceWindow at index %lu has a "
+                                                 @"non-array %@",
+                                                 idx, kMaintenanceWindowWeekdays]];
+      return;
+    }
+    for (id day in days) {
+      if (![day isKindOfClass:[NSNumber class]] || [day integerValue] < 0 ||
+          [day integerValue] > 7) {
+        [errs addObject:[NSString stringWithFormat:@"MaintenanceWindow at index %lu has an "
+                                                   @"invalid weekday: %@",
+                                                   idx, day]];
+        return;
+      }
+      [weekdays addIndex:[day integerValue] % 7 + 1];
+    }
+
+    [windows addObject:[[SNTMaintenanceWindow alloc] initWithStartMinute:start
+                                                               endMinute:end
+                                                                weekdays:weekdays]];
+  }];
+
+  if (errors) *errors = errs;
+  return windows;
+}
+
+@end
@@ -0,0 +1,158 @@
+/// Copyright 2026 North Pole Security, Inc.
+///
+/// Licensed under the Apache License, Version 2.0 (the "License");
+/// you may not use this file except in compliance with the License.
+/// You may obtain a copy of the License at
+///
+///     http://www.apache.org/licenses/LICENSE-2.0
+///
+/// Unless required by applicable law or agreed to in writing, software
+/// distributed under the License is distributed on an "AS IS" BASIS,
+/// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+/// See the License for the specific language governing permissions and
+/// limitations under the License.
+
+#import "Source/common/SNTMaintenanceWindow.h"
+
+#import <Foundation/Foundation.h>
+#import <XCTest/XCTest.h>
+
+@interface SNTMaintenanceWindowTest : XCTestCase
+@property NSCalendar* calendar;
+@property NSISO8601DateFormatter* formatter;
+@end
+
+@implementation SNTMaintenanceWindowTest
+
+- (void)setUp {
+  self.calendar = [NSCalendar calendarWithIdentifier:NSCalendarIdentifierGregorian];
+  self.calendar.timeZone = [NSTimeZone timeZoneWithName:@"UTC"];
+  self.formatter = [[NSISO8601DateFormatter alloc] init];
+}
+
+- (NSDate*)date:(NSString*)str {
+  return [self.formatter dateFromString:str];
+}
+
+- (void)testParsesValidWindows {
+  NSArray* errors;
+  NSArray* config = @[
+    @{kMaintenanceWindowStart : @"22:00", kMaintenanceWindowEnd : @"06:30"},
+    @{
+      kMaintenanceWindowStart : @"9:15",
+      kMaintenanceWindowEnd : @"10:00",
+      kMaintenanceWindowWeekdays : @[ @0, @6, @7 ],
+    },
+  ];
+  NSArray<SNTMaintenanceWindow*>* windows =
+      [SNTMaintenanceWindow maintenanceWindowsFromArray:config errors:&errors];
+
+  XCTAssertEqual(errors.count, 0);
+  XCTAssertEqual(windows.count, 2);
+
+  XCTAssertEqual(windows[0].startMinute, 22 * 60);
+  XCTAssertEqual(windows[0].endMinute, 6 * 60 + 30);
+  XCTAssertEqual(windows[0].weekdays.count, 0);
+
+  // Weekdays 0 and 7 are both Sunday (NSCalendar weekday 1), 6 is Saturday (7).
+  XCTAssertEqual(windows[1].startMinute, 9 * 60 + 15);
+  XCTAssertEqual(windows[1].endMinute, 10 * 60);
+  NSMutableIndexSet* expected = [NSMutableIndexSet indexSetWithIndex:1];
+  [expected addIndex:7];
+  XCTAssertEqualObjects(windows[1].weekdays, expected);
+}
+
+- (void)testSkipsInvalidWindows {
+  NSArray* errors;
+  NSArray* config = @[
+    @"22:00-06:00",
+    @{kMaintenanceWindowStart : @"22:00"},
+    @{kMaintenanceWindowStart : @"24:00", kMaintenanceWindowEnd : @"06:00"},
+    @{kMaintenanceWindowStart : @"10:5", kMaintenanceWindowEnd : @"11:00"},
+    @{kMaintenanceWindowStart : @"10:00", kMaintenanceWindowEnd : @"10:00"},
+    @{
+      kMaintenanceWindowStart : @"10:00",
+      kMaintenanceWindowEnd : @"11:00",
+      kMaintenanceWindowWeekdays : @1,
+    },
+    @{
+      kMaintenanceWindowStart : @"10:00",
+      kMaintenanceWindowEnd : @"11:00",
+      kMaintenanceWindowWeekdays : @[ @8 ],
+    },
+    @{kMaintenanceWindowStart : @"01:00", kMaintenanceWindowEnd : @"02:00"},
+  ];
+  NSArray<SNTMaintenanceWindow*>* windows =
+      [SNTMaintenanceWindow maintenanceWindowsFromArray:config errors:&errors];
+
+  XCTAssertEqual(errors.count, 7);
+  XCTAssertEqual(windows.count, 1);
+  XCTAssertEqual(windows[0].startMinute, 60);
+
+  windows = [SNTMaintenanceWindow maintenanceWindowsFromArray:(NSArray*)@"bad" errors:&errors];
+  XCTAssertEqual(windows.count, 0);
+  XCTAssertEqual(errors.count, 0);
+}
+
+- (void)testEndOfWindowSameDay {
+  SNTMaintenanceWindow* window = [[SNTMaintenanceWindow alloc] initWithStartMinute:9 * 60
+                                                                         endMinute:17 * 60
+                                                                          weekdays:nil];
+  NSDate* (^endAt)(NSString*) = ^NSDate*(NSString* str) {
+    return [window endOfWindowContainingDate:[self date:str] calendar:self.calendar];
+  };
+
+  XCTAssertNil(endAt(@"2026-03-02T08:59:59Z"));
+  XCTAssertEqualObjects(endAt(@"2026-03-02T09:00:00Z"), [self date:@"2026-03-02T17:00:00Z"]);
+  XCTAssertEqualObjects(endAt(@"2026-03-02T16:59:59Z"), [self date:@"2026-03-02T17:00:00Z"]);
+  XCTAssertNil(endAt(@"2026-03-02T17:00:00Z"));
+}
+
+- (void)testEndOfWindowOvernight {
+  SNTMaintenanceWindow* window = [[SNTMaintenanceWindow alloc] initWithStartMinute:22 * 60
+                                                                         endMinute:6 * 60
+                                                                          weekdays:nil];
+  NSDate* (^endAt)(NSString*) = ^NSDate*(NSString* str) {
+    return [window endOfWindowContainingDate:[self date:str] calendar:self.calendar];
+  };
+
+  XCTAssertEqualObjects(endAt(@"2026-03-02T23:00:00Z"), [self date:@"2026-03-03T06:00:00Z"]);
+  XCTAssertEqualObjects(endAt(@"2026-03-03T05:00:00Z"), [self date:@"2026-03-03T06:00:00Z"]);
+  XCTAssertNil(endAt(@"2026-03-03T06:00:00Z"));
+  XCTAssertNil(endAt(@"2026-03-02T21:59:00Z"));
+}
+
+- (void)testEndOfWindowHonorsWeekdays {
+  // Opens on Mondays only. 2026-03-02 is a Monday.
+  SNTMaintenanceWindow* window =
+      [[SNTMaintenanceWindow alloc] initWithStartMinute:22 * 60
+                                              endMinute:6 * 60
+                                               weekdays:[NSIndexSet indexSetWithIndex:2]];
+  NSDate* (^endAt)(NSString*) = ^NSDate*(NSString* str) {
+    return [window endOfWindowContainingDate:[self date:str] calendar:self.calendar];
+  };
+
+  XCTAssertEqualObjects(endAt(@"2026-03-02T23:00:00Z"), [self date:@"2026-03-03T06:00:00Z"]);
+  // The Monday occurrence still runs into Tuesday morning.
+  XCTAssertEqualObjects(endAt(@"2026-03-03T01:00:00Z"), [self date:@"2026-03-03T06:00:00Z"]);
+  XCTAssertNil(endAt(@"2026-03-03T23:00:00Z"));
+  XCTAssertNil(endAt(@"2026-03-02T01:00:00Z"));
+}
+
+- (void)testEndOfWindowsPicksTheLatestEnd {
+  NSArray* windows = @[
+    [[SNTMaintenanceWindow alloc] initWithStartMinute:9 * 60 endMinute:12 * 60 weekdays:nil],
+    [[SNTMaintenanceWindow alloc] initWithStartMinute:10 * 60 endMinute:14 * 60 weekdays:nil],
+  ];
+  NSDate* (^endAt)(NSString*) = ^NSDate*(NSString* str) {
+    return [SNTMaintenanceWindow endOfWindowsContainingDate:[self date:str]
+                                                  inWindows:windows
+                                                   calendar:self.calendar];
+  };
+
+  XCTAssertEqualObjects(endAt(@"2026-03-02T11:00:00Z"), [self date:@"2026-03-02T14:00:00Z"]);
+  XCTAssertEqualObjects(endAt(@"2026-03-02T09:30:00Z"), [self date:@"2026-03-02T12:00:00Z"]);
+  XCTAssertNil(endAt(@"2026-03-02T15:00:00Z"));
+}
+
+@end
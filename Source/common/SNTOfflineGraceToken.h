@@ -0,0 +1,49 @@
+/// Copyright 2026 North Pole Security, Inc.
+///
+/// Licensed under the Apache License, Version 2.0 (the "License");
+/// you may not use this file except in compliance with the License.
+/// You may obtain a copy of the License at
+///
+///     http://www.apache.org/licenses/LICENSE-2.0
+///
+/// Unless required by applicable law or agreed to in writing, software
+/// distributed under the License is distributed on an "AS IS" BASIS,
+/// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+/// See the License for the specific language governing permissions and
+/// limitations under the License.
+
+#import <Foundation/Foundation.h>
+
+#import "Source/common/SNTCommonEnums.h"
+
+NS_ASSUME_NONNULL_BEGIN
+
+///
+///  An MDM-delivered token that keeps a host that cannot sync in its assigned client mode until
+///  the token expires.
+///
+///  Tokens are JWTs signed with Ed25519 (alg "EdDSA") carrying these claims:
+///    * exp: Expiration, in seconds since the Unix epoch.
+///    * client_mode: MONITOR, LOCKDOWN or STANDALONE.
+///    * machine_id: Optional. If present, the token is only valid on the
+///      machine with this machine ID.
+///
+@interface SNTOfflineGraceToken : NSObject
+
+@property(readonly) SNTClientMode clientMode;
+@property(readonly) NSDate* expirationDate;
+@property(readonly, nullable) NSString* machineID;
+
+///
+///  Verifies the token signature with the given Ed25519 public key and parses
+///  its claims. Returns nil, populating error, if the token is malformed, the
+///  signature is invalid or the token has expired as of `now`.
+///
++ (nullable instancetype)tokenWithString:(NSString*)token
+                               publicKey:(NSData*)publicKey
+                             currentDate:(NSDate*)now
+                                   error:(NSError**)error;
+
+@end
+
+NS_ASSUME_NONNULL_END
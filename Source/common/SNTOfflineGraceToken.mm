@@ -0,0 +1,92 @@
+/// Copyright 2026 North Pole Security, Inc.
+///
+/// Licensed under the Apache License, Version 2.0 (the "License");
+/// you may not use this file except in compliance with the License.
+/// You may obtain a copy of the License at
+///
+///     http://www.apache.org/licenses/LICENSE-2.0
+///
+/// Unless required by applicable law or agreed to in writing, software
+/// distributed under the License is distributed on an "AS IS" BASIS,
+/// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+/// See the License for the specific language governing permissions and
+/// limitations under the License.
+
+#import "Source/common/SNTOfflineGraceToken.h"
+
+#include <openssl/curve25519.h>
+
+#include <string>
+#include <vector>
+
+#include "Source/common/NKeyTokenValidator.h"
+#import "Source/common/SNTError.h"
+#import "Source/common/String.h"
+
+static NSString* const kExpirationClaim = @"exp";
+static NSString* const kClientModeClaim = @"client_mode";
+static NSString* const kMachineIDClaim = @"machine_id";
+
+static SNTClientMode ClientModeFromClaim(NSString* claim) {
+  NSString* mode = [claim uppercaseString];
+  if ([mode isEqualToString:@"MONITOR"]) return SNTClientModeMonitor;
+  if ([mode isEqualToString:@"LOCKDOWN"]) return SNTClientModeLockdown;
+  if ([mode isEqualToString:@"STANDALONE"]) return SNTClientModeStandalone;
+  return SNTClientModeUnknown;
+}
+
+@interface SNTOfflineGraceToken ()
+@property(readwrite) SNTClientMode clientMode;
+@property(readwrite) NSDate* expirationDate;
+@property(readwrite, nullable) NSString* machineID;
+@end
+
+@implementation SNTOfflineGraceToken
+
++ (instancetype)tokenWithString:(NSString*)token
+                      publicKey:(NSData*)publicKey
+                    currentDate:(NSDate*)now
+                          error:(NSError**)error {
+  if (publicKey.length != ED25519_PUBLIC_KEY_LEN) {
+    [SNTError populateError:error withFormat:@"Invalid Ed25519 public key"];
+    return nil;
+  }
+
+  std::string jwt = santa::NSStringToUTF8String(token);
+  const auto* keyBytes = static_cast<const uint8_t*>(publicKey.bytes);
+  std::vector<uint8_t> key(keyBytes, keyBytes + publicKey.length);
+  if (!santa::VerifyJWTSignature(jwt, key)) {
+    [SNTError populateError:error withFormat:@"Token signature verification failed"];
+    return nil;
+  }
+
+  NSDictionary* claims = santa::ParseJWTPayload(jwt);
+  NSNumber* exp = claims[kExpirationClaim];
+  NSString* clientMode = claims[kClientModeClaim];
+  NSString* machineID = claims[kMachineIDClaim];
+  if (![exp isKindOfClass:[NSNumber class]] || ![clientMode isKindOfClass:[NSString class]] ||
+      (machineID && ![machineID isKindOfClass:[NSString class]])) {
+    [SNTError populateError:error withFormat:@"Token is missing required claims"];
+    return nil;
+  }
+
+  SNTClientMode mode = ClientModeFromClaim(clientMode);
+  if (mode == SNTClientModeUnknown) {
+    [SNTError populateError:error withFormat:@"Token client mode %@ is not supported", clientMode];
+    return nil;
+  }
+
+  NSDate* expirationDate = [NSDate dateWithTimeIntervalSince1970:exp.doubleValue];
+  if ([expirationDate compare:now] != NSOrderedDescending) {
+    [SNTError populateError:error withFormat:@"Token expired at %@", expirationDate];
+    return nil;
+  }
+
+  SNTOfflineGraceToken* t = [[SNTOfflineGraceToken alloc] init];
+  t.clientMode = mode;
+  t.expirationDate = expirationDate;
+  t.machineID = machineID.length ? machineID : nil;
+  return t;
+}
+
+@end
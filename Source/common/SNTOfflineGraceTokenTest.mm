@@ -0,0 +1,141 @@
+/// Copyright 2026 North Pole Security, Inc.
+///
+/// Licensed under the Apache License, Version 2.0 (the "License");
+/// you may not use this file except in compliance with the License.
+/// You may obtain a copy of the License at
+///
+///     http://www.apache.org/licenses/LICENSE-2.0
+///
+/// Unless required by applicable law or agreed to in writing, software
+/// distributed under the License is distributed on an "AS IS" BASIS,
+/// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+/// See the License for the specific language governing permissions and
+/// limitations under the License.
+
+#import <XCTest/XCTest.h>
+
+#include <openssl/curve25519.h>
+
+#import "Source/common/SNTCommonEnums.h"
+#import "Source/common/SNTOfflineGraceToken.h"
+
+static NSString* Base64URLEncode(NSData* data) {
+  NSString* b64 = [data base64EncodedStringWithOptions:0];
+  b64 = [b64 stringByReplacingOccurrencesOfString:@"+" withString:@"-"];
+  b64 = [b64 stringByReplacingOccurrencesOfString:@"/" withString:@"_"];
+  return [b64 stringByReplacingOccurrencesOfString:@"=" withString:@""];
+}
+
+@interface SNTOfflineGraceTokenTest : XCTestCase
+@property NSData* publicKey;
+@property NSData* privateKey;
+@property NSDate* now;
+@end
+
+@implementation SNTOfflineGraceTokenTest
+
+- (void)setUp {
+  uint8_t publicKey[ED25519_PUBLIC_KEY_LEN];
+  uint8_t privateKey[ED25519_PRIVATE_KEY_LEN];
+  ED25519_keypair(publicKey, privateKey);
+  self.publicKey = [NSData dataWithBytes:publicKey length:sizeof(publicKey)];
+  self.privateKey = [NSData dataWithBytes:privateKey length:sizeof(privateKey)];
+  self.now = [NSDate dateWithTimeIntervalSince1970:1800000000];
+}
+
+- (NSString*)tokenWithClaims:(NSDictionary*)claims privateKey:(NSData*)privateKey {
+  NSData* header = [NSJSONSerialization dataWithJSONObject:@{@"alg" : @"EdDSA", @"typ" : @"JWT"}
+                                                   options:0
+                                                     error:nil];
+  NSData* payload = [NSJSONSerialization dataWithJSONObject:claims options:0 error:nil];
+  NSString* signingInput =
+      [NSString stringWithFormat:@"%@.%@", Base64URLEncode(header), Base64URLEncode(payload)];
+
+  uint8_t signature[ED25519_SIGNATURE_LEN];
+  NSData* input = [signingInput dataUsingEncoding:NSUTF8StringEncoding];
+  ED25519_sign(signature, static_cast<const uint8_t*>(input.bytes), input.length,
+               static_cast<const uint8_t*>(privateKey.bytes));
+
+  return [NSString
+      stringWithFormat:@"%@.%@", signingInput,
+                       Base64URLEncode([NSData dataWithBytes:signature length:sizeof(signature)])];
+}
+
+- (NSDictionary*)claimsExpiringIn:(NSTimeInterval)interval {
+  return @{
+    @"exp" : @([self.now timeIntervalSince1970] + interval),
+    @"client_mode" : @"LOCKDOWN",
+  };
+}
+
+- (void)testValidToken {
+  NSMutableDictionary* claims = [[self claimsExpiringIn:86400] mutableCopy];
+  claims[@"machine_id"] = @"my-machine";
+  NSString* token = [self tokenWithClaims:claims privateKey:self.privateKey];
+
+  NSError* err;
+  SNTOfflineGraceToken* t = [SNTOfflineGraceToken tokenWithString:token
+                                                        publicKey:self.publicKey
+                                                      currentDate:self.now
+                                                            error:&err];
+  XCTAssertNotNil(t);
+  XCTAssertNil(err);
+  XCTAssertEqual(t.clientMode, SNTClientModeLockdown);
+  XCTAssertEqualObjects(t.machineID, @"my-machine");
+  XCTAssertEqualObjects(t.expirationDate, [self.now dateByAddingTimeInterval:86400]);
+}
+
+- (void)testExpiredToken {
+  NSString* token = [self tokenWithClaims:[self claimsExpiringIn:-1] privateKey:self.privateKey];
+
+  NSError* err;
+  XCTAssertNil([SNTOfflineGraceToken tokenWithString:token
+                                           publicKey:self.publicKey
+                                         currentDate:self.now
+                                               error:&err]);
+  XCTAssertTrue([err.localizedDescription containsString:@"expired"]);
+}
+
+- (void)testWrongKeyRejected {
+  uint8_t otherPublicKey[ED25519_PUBLIC_KEY_LEN];
+  uint8_t otherPrivateKey[ED25519_PRIVATE_KEY_LEN];
+  ED25519_keypair(otherPublicKey, otherPrivateKey);
+  NSString* token = [self tokenWithClaims:[self claimsExpiringIn:86400]
+                               privateKey:[NSData dataWithBytes:otherPrivateKey
+                                                         length:sizeof(otherPrivateKey)]];
+
+  NSError* err;
+  XCTAssertNil([SNTOfflineGraceToken tokenWithString:token
+                                           publicKey:self.publicKey
+                                         currentDate:self.now
+                                               error:&err]);
+  XCTAssertNotNil(err);
+}
+
+- (void)testInvalidClaimsRejected {
+  NSMutableDictionary* claims = [[self claimsExpiringIn:86400] mutableCopy];
+  [claims removeObjectForKey:@"client_mode"];
+  XCTAssertNil([SNTOfflineGraceToken
+      tokenWithString:[self tokenWithClaims:claims privateKey:self.privateKey]
+            publicKey:self.publicKey
+          currentDate:self.now
+                error:nil]);
+
+  claims = [[self claimsExpiringIn:86400] mutableCopy];
+  claims[@"client_mode"] = @"PERMISSIVE";
+  XCTAssertNil([SNTOfflineGraceToken
+      tokenWithString:[self tokenWithClaims:claims privateKey:self.privateKey]
+            publicKey:self.publicKey
+          currentDate:self.now
+                error:nil]);
+
+  claims = [[self claimsExpiringIn:86400] mutableCopy];
+  [claims removeObjectForKey:@"exp"];
+  XCTAssertNil([SNTOfflineGraceToken
+      tokenWithString:[self tokenWithClaims:claims privateKey:self.privateKey]
+            publicKey:self.publicKey
+          currentDate:self.now
+                error:nil]);
+}
+
+@end
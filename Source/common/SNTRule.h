@@ -78,9 +78,31 @@
 ///
 @property(readonly) int64_t ruleId;
 
+///
+///  How prominently a block by this rule is surfaced to the user and how quickly the resulting
+///  event is uploaded to the sync server.
+///
+@property(readonly) SNTRuleSeverity severity;
+
 ///
 ///  Designated initializer.
 ///
+- (instancetype)initWithIdentifier:(NSString*)identifier
+                             state:(SNTRuleState)state
+                              type:(SNTRuleType)type
+                         customMsg:(NSString*)customMsg
+                         customURL:(NSString*)customURL
+                         timestamp:(NSUInteger)timestamp
+                           comment:(NSString*)comment
+                           celExpr:(NSString*)celExpr
+                    seatbeltPolicy:(NSString*)seatbeltPolicy
+                            ruleId:(int64_t)ruleId
+                          severity:(SNTRuleSeverity)severity
+                             error:(NSError**)error;
+
+///
+///  Initialize with an unspecified severity.
+///
 - (instancetype)initWithIdentifier:(NSString*)identifier
                              state:(SNTRuleState)state
                               type:(SNTRuleType)type
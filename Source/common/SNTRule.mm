@@ -17,6 +17,7 @@
 
 #include <CommonCrypto/CommonCrypto.h>
 #include <Kernel/kern/cs_blobs.h>
+#include <Security/Security.h>
 #include <os/base.h>
 
 #import "Source/common/CoderMacros.h"
@@ -37,6 +38,7 @@ static const NSUInteger kExpectedTeamIDLength = 10;
 @property(readwrite) NSString* celExpr;
 @property(readwrite) NSString* seatbeltPolicy;
 @property(readwrite) int64_t ruleId;
+@property(readwrite) SNTRuleSeverity severity;
 @end
 
 @implementation SNTRule
@@ -51,6 +53,7 @@ static const NSUInteger kExpectedTeamIDLength = 10;
                            celExpr:(NSString*)celExpr
                     seatbeltPolicy:(NSString*)seatbeltPolicy
                             ruleId:(int64_t)ruleId
+                          severity:(SNTRuleSeverity)severity
                              error:(NSError**)error {
   self = [super init];
   if (self) {
@@ -98,12 +101,14 @@ static const NSUInteger kExpectedTeamIDLength = 10;
         break;
       }
 
-      case SNTRuleTypeSigningID: {
+      case SNTRuleTypeSigningID:
+      case SNTRuleTypeBundleSigningID: {
         // SigningID rules are a combination of `TeamID:SigningID`. The TeamID should
         // be forced to be uppercase, but because very loose rules exist for SigningIDs,
         // their case will be kept as-is. However, platform binaries are expected to
         // have the hardcoded string "platform" as the team ID and the case will be left
-        // as is.
+        // as is. Bundle Signing ID rules use the same format, with the Signing ID of the
+        // bundle's main executable.
         NSArray* sidComponents = [identifier componentsSeparatedByString:@":"];
         if (!sidComponents || sidComponents.count < 2) {
           [SNTError populateError:error
@@ -165,6 +170,28 @@ static const NSUInteger kExpectedTeamIDLength = 10;
         break;
       }
 
+      case SNTRuleTypeRequirement: {
+        // Requirement rules hold a code requirement in the Security framework's text form.
+        // Store it in canonical form so that differently written copies of the same
+        // requirement end up as the same rule.
+        SecRequirementRef requirement = NULL;
+        CFStringRef canonical = NULL;
+        if (SecRequirementCreateWithString((__bridge CFStringRef)identifier, kSecCSDefaultFlags,
+                                           &requirement) != errSecSuccess ||
+            SecRequirementCopyString(requirement, kSecCSDefaultFlags, &canonical) !=
+                errSecSuccess) {
+          if (requirement) CFRelease(requirement);
+          [SNTError populateError:error
+                         withCode:SNTErrorCodeRuleInvalidIdentifier
+                           format:@"Rule received with invalid identifier for its type %@",
+                                  [self invalidIdentifier:identifier forType:type]];
+          return nil;
+        }
+        CFRelease(requirement);
+        identifier = CFBridgingRelease(canonical);
+        break;
+      }
+
       default: {
         break;
       }
@@ -194,10 +221,36 @@ static const NSUInteger kExpectedTeamIDLength = 10;
     _celExpr = celExpr;
     _seatbeltPolicy = seatbeltPolicy;
     _ruleId = ruleId;
+    _severity = severity;
   }
   return self;
 }
 
+- (instancetype)initWithIdentifier:(NSString*)identifier
+                             state:(SNTRuleState)state
+                              type:(SNTRuleType)type
+                         customMsg:(NSString*)customMsg
+                         customURL:(NSString*)customURL
+                         timestamp:(NSUInteger)timestamp
+                           comment:(NSString*)comment
+                           celExpr:(NSString*)celExpr
+                    seatbeltPolicy:(NSString*)seatbeltPolicy
+                            ruleId:(int64_t)ruleId
+                             error:(NSError**)error {
+  return [self initWithIdentifier:identifier
+                            state:state
+                             type:type
+                        customMsg:customMsg
+                        customURL:customURL
+                        timestamp:timestamp
+                          comment:comment
+                          celExpr:celExpr
+                   seatbeltPolicy:seatbeltPolicy
+                           ruleId:ruleId
+                         severity:SNTRuleSeverityUnspecified
+                            error:error];
+}
+
 - (instancetype)initWithIdentifier:(NSString*)identifier
                              state:(SNTRuleState)state
                               type:(SNTRuleType)type
@@ -247,6 +300,8 @@ static const NSUInteger kExpectedTeamIDLength = 10;
     @(SNTRuleTypeSigningID) : kRuleTypeSigningID,
     @(SNTRuleTypeCertificate) : kRuleTypeCertificate,
     @(SNTRuleTypeTeamID) : kRuleTypeTeamID,
+    @(SNTRuleTypeRequirement) : kRuleTypeRequirement,
+    @(SNTRuleTypeBundleSigningID) : kRuleTypeBundleSigningID,
   };
 
   return [NSString stringWithFormat:@"(rule type: %@, identifier: %@)",
@@ -349,6 +404,10 @@ static const NSUInteger kExpectedTeamIDLength = 10;
     type = SNTRuleTypeSigningID;
   } else if ([ruleTypeString isEqual:kRuleTypeCDHash]) {
     type = SNTRuleTypeCDHash;
+  } else if ([ruleTypeString isEqual:kRuleTypeRequirement]) {
+    type = SNTRuleTypeRequirement;
+  } else if ([ruleTypeString isEqual:kRuleTypeBundleSigningID]) {
+    type = SNTRuleTypeBundleSigningID;
   } else {
     [SNTError populateError:error
                    withCode:SNTErrorCodeRuleInvalidRuleType
@@ -376,6 +435,24 @@ static const NSUInteger kExpectedTeamIDLength = 10;
     celExpr = nil;
   }
 
+  SNTRuleSeverity severity = SNTRuleSeverityUnspecified;
+  NSString* severityString = dict[kRuleSeverity];
+  if ([severityString isKindOfClass:[NSString class]] && severityString.length) {
+    severityString = [severityString lowercaseString];
+    if ([severityString isEqualToString:kRuleSeverityInfo]) {
+      severity = SNTRuleSeverityInfo;
+    } else if ([severityString isEqualToString:kRuleSeverityWarning]) {
+      severity = SNTRuleSeverityWarning;
+    } else if ([severityString isEqualToString:kRuleSeverityCritical]) {
+      severity = SNTRuleSeverityCritical;
+    } else {
+      [SNTError populateError:error
+                     withCode:SNTErrorCodeRuleInvalid
+                       format:@"Rule received with invalid severity '%@'", dict[kRuleSeverity]];
+      return nil;
+    }
+  }
+
   return [self initWithIdentifier:identifier
                             state:state
                              type:type
@@ -386,6 +463,7 @@ static const NSUInteger kExpectedTeamIDLength = 10;
                           celExpr:celExpr
                    seatbeltPolicy:nil
                            ruleId:0
+                         severity:severity
                             error:error];
 }
 
@@ -415,6 +493,7 @@ static const NSUInteger kExpectedTeamIDLength = 10;
   ENCODE(coder, seatbeltPolicy);
   ENCODE_BOXABLE(coder, staticRule);
   ENCODE_BOXABLE(coder, ruleId);
+  ENCODE_BOXABLE(coder, severity);
 }
 
 - (instancetype)initWithCoder:(NSCoder*)decoder {
@@ -431,6 +510,7 @@ static const NSUInteger kExpectedTeamIDLength = 10;
     DECODE(decoder, seatbeltPolicy, NSString);
     DECODE_SELECTOR(decoder, staticRule, NSNumber, boolValue);
     DECODE_SELECTOR(decoder, ruleId, NSNumber, longLongValue);
+    DECODE_SELECTOR(decoder, severity, NSNumber, integerValue);
   }
   return self;
 }
@@ -460,16 +540,27 @@ static const NSUInteger kExpectedTeamIDLength = 10;
     case SNTRuleTypeCertificate: return kRuleTypeCertificate;
     case SNTRuleTypeTeamID: return kRuleTypeTeamID;
     case SNTRuleTypeSigningID: return kRuleTypeSigningID;
+    case SNTRuleTypeRequirement: return kRuleTypeRequirement;
+    case SNTRuleTypeBundleSigningID: return kRuleTypeBundleSigningID;
     // This should never be hit. If we have rule types of Unknown then there's a
     // coding error somewhere.
     default: return @"Unknown";
   }
 }
 
+- (NSString*)severityToString:(SNTRuleSeverity)severity {
+  switch (severity) {
+    case SNTRuleSeverityInfo: return kRuleSeverityInfo;
+    case SNTRuleSeverityWarning: return kRuleSeverityWarning;
+    case SNTRuleSeverityCritical: return kRuleSeverityCritical;
+    default: return @"";
+  }
+}
+
 // Returns an NSDictionary representation of the rule. Primarily use for
 // exporting rules.
 - (NSDictionary*)dictionaryRepresentation {
-  return @{
+  NSMutableDictionary* dict = [@{
     kRuleIdentifier : self.identifier,
     kRulePolicy : [self ruleStateToPolicyString:self.state],
     kRuleType : [self ruleTypeToString:self.type],
@@ -477,7 +568,11 @@ static const NSUInteger kExpectedTeamIDLength = 10;
     kRuleCustomURL : self.customURL ?: @"",
     kRuleComment : self.comment ?: @"",
     kRuleCELExpr : self.celExpr ?: @"",
-  };
+  } mutableCopy];
+  // Only export a severity that was set so that existing exports are unchanged.
+  NSString* severity = [self severityToString:self.severity];
+  if (severity.length) dict[kRuleSeverity] = severity;
+  return dict;
 }
 
 - (BOOL)isEqual:(id)other {
@@ -545,6 +640,8 @@ static const NSUInteger kExpectedTeamIDLength = 10;
     case SNTRuleTypeSigningID: [output appendString:@"SigningID"]; break;
     case SNTRuleTypeCertificate: [output appendString:@"Certificate"]; break;
     case SNTRuleTypeTeamID: [output appendString:@"TeamID"]; break;
+    case SNTRuleTypeRequirement: [output appendString:@"Requirement"]; break;
+    case SNTRuleTypeBundleSigningID: [output appendString:@"BundleSigningID"]; break;
     default:
       output = [NSMutableString stringWithFormat:@"Unexpected rule type: %ld", self.type];
       break;
@@ -0,0 +1,73 @@
+/// Copyright 2026 North Pole Security, Inc.
+///
+/// Licensed under the Apache License, Version 2.0 (the "License");
+/// you may not use this file except in compliance with the License.
+/// You may obtain a copy of the License at
+///
+///     http://www.apache.org/licenses/LICENSE-2.0
+///
+/// Unless required by applicable law or agreed to in writing, software
+/// distributed under the License is distributed on an "AS IS" BASIS,
+/// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+/// See the License for the specific language governing permissions and
+/// limitations under the License.
+
+
+#import <Foundation/Foundation.h>
+
+/// Keys of each dictionary in the RuleSources configuration array.
+extern NSString* const kRuleSourceName;
+extern NSString* const kRuleSourceURL;
+extern NSString* const kRuleSourceSyncIntervalSeconds;
+extern NSString* const kRuleSourcePrecedence;
+extern NSString* const kRuleSourcePublicKey;
+
+/// Rule sources are never synced more often than this.
+extern const NSUInteger kRuleSourceMinimumSyncInterval;
+
+///
+///  An additional rule server configured with the RuleSources key. Each source is downloaded on
+///  its own schedule, independently of the primary sync server, and contributes execution rules
+///  that take precedence over the rules from the primary sync server.
+///
+@interface SNTRuleSource : NSObject
+
+/// Unique name of this source. The rules this source provided are stored under this name.
+@property(readonly, copy) NSString* name;
+
+/// Base URL of the server for this source. Rules are downloaded from the same ruledownload
+/// endpoint used by the primary sync server.
+@property(readonly) NSURL* url;
+
+/// Number of seconds between rule downloads.
+@property(readonly) NSUInteger syncInterval;
+
+/// When rules from several sources match an execution, the source with the highest precedence
+/// wins. Sources with the same precedence are ordered by name.
+@property(readonly) NSInteger precedence;
+
+/// The raw 32 byte Ed25519 public key this source signs its rule downloads with. When set, every
+/// response from this source must be a signed rule bundle verified by this key.
+@property(readonly) NSData* publicKey;
+
+- (instancetype)initWithName:(NSString*)name
+                         url:(NSURL*)url
+                syncInterval:(NSUInteger)syncInterval
+                  precedence:(NSInteger)precedence;
+
+- (instancetype)initWithName:(NSString*)name
+                         url:(NSURL*)url
+                syncInterval:(NSUInteger)syncInterval
+                  precedence:(NSInteger)precedence
+                   publicKey:(NSData*)publicKey;
+
+///
+///  Parse the value of the RuleSources configuration key. Entries that are invalid or reuse the
+///  name of an earlier entry are skipped and described in `errors`.
+///
+///  @return The valid sources, ordered from highest to lowest precedence.
+///
++ (NSArray<SNTRuleSource*>*)ruleSourcesFromArray:(NSArray*)array
+                                          errors:(NSArray<NSString*>**)errors;
+
+@end
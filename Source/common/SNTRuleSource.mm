@@ -0,0 +1,164 @@
+/// Copyright 2026 North Pole Security, Inc.
+///
+/// Licensed under the Apache License, Version 2.0 (the "License");
+/// you may not use this file except in compliance with the License.
+/// You may obtain a copy of the License at
+///
+///     http://www.apache.org/licenses/LICENSE-2.0
+///
+/// Unless required by applicable law or agreed to in writing, software
+/// distributed under the License is distributed on an "AS IS" BASIS,
+/// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+/// See the License for the specific language governing permissions and
+/// limitations under the License.
+
+
+#import "Source/common/SNTRuleSource.h"
+
+NSString* const kRuleSourceName = @"name";
+NSString* const kRuleSourceURL = @"url";
+NSString* const kRuleSourceSyncIntervalSeconds = @"sync_interval_seconds";
+NSString* const kRuleSourcePrecedence = @"precedence";
+NSString* const kRuleSourcePublicKey = @"public_key";
+
+const NSUInteger kRuleSourceMinimumSyncInterval = 60;
+
+static const NSUInteger kEd25519PublicKeyLength = 32;
+
+// Same restriction as SyncBaseURL: plain HTTP is only allowed for loopback addresses.
+static BOOL IsAllowedRuleSourceURL(NSURL* url) {
+  NSString* scheme = [url.scheme lowercaseString];
+  NSString* host = [url.host lowercaseString];
+  if (host.length == 0) return NO;
+  if ([scheme isEqualToString:@"https"]) return YES;
+  return [scheme isEqualToString:@"http"] &&
+         ([host isEqualToString:@"localhost"] || [host isEqualToString:@"127.0.0.1"] ||
+          [host isEqualToString:@"::1"]);
+}
+
+@implementation SNTRuleSource
+
+- (instancetype)initWithName:(NSString*)name
+                         url:(NSURL*)url
+                syncInterval:(NSUInteger)syncInterval
+                  precedence:(NSInteger)precedence {
+  return [self initWithName:name
+                        url:url
+               syncInterval:syncInterval
+                 precedence:precedence
+                  publicKey:nil];
+}
+
+- (instancetype)initWithName:(NSString*)name
+                         url:(NSURL*)url
+                syncInterval:(NSUInteger)syncInterval
+                  precedence:(NSInteger)precedence
+                   publicKey:(NSData*)publicKey {
+  self = [super init];
+  if (self) {
+    _name = [name copy];
+    _url = url;
+    _syncInterval = syncInterval;
+    _precedence = precedence;
+    _publicKey = [publicKey copy];
+  }
+  return self;
+}
+
+- (NSString*)description {
+  return [NSString stringWithFormat:@"%@ (%@, every %lus, precedence %ld%@)", self.name,
+                                    self.url.absoluteString, self.syncInterval, self.precedence,
+                                    self.publicKey ? @", signed" : @""];
+}
+
++ (NSArray<SNTRuleSource*>*)ruleSourcesFromArray:(NSArray*)array
+                                          errors:(NSArray<NSString*>**)errors {
+  NSMutableArray<SNTRuleSource*>* sources = [NSMutableArray array];
+  NSMutableArray<NSString*>* errs = [NSMutableArray array];
+  NSMutableSet<NSString*>* names = [NSMutableSet set];
+
+  if (![array isKindOfClass:[NSArray class]]) array = nil;
+  [array enumerateObjectsUsingBlock:^(id obj, NSUInteger idx, BOOL* stop) {
+    if (![obj isKindOfClass:[NSDictionary class]]) {
+      [errs addObject:[NSString stringWithFormat:@"RuleSource at index %lu has bad type: %@", idx,
+                                                 [obj class]]];
+      return;
+    }
+    NSDictionary* dict = obj;
+
+    NSString* name = dict[kRuleSourceName];
+    if (![name isKindOfClass:[NSString class]] || name.length == 0) {
+      [errs addObject:[NSString stringWithFormat:@"RuleSource at index %lu has no name", idx]];
+      return;
+    }
+    if ([names containsObject:name]) {
+      [errs addObject:[NSString stringWithFormat:@"RuleSource at index %lu reuses the name %@",
+                                                 idx, name]];
+      return;
+    }
+
+    NSString* urlString = dict[kRuleSourceURL];
+    if (![urlString isKindOfClass:[NSString class]]) urlString = nil;
+    if (urlString.length && ![urlString hasSuffix:@"/"]) {
+      urlString = [urlString stringByAppendingString:@"/"];
+    }
+    NSURL* url = urlString.length ? [NSURL URLWithString:urlString] : nil;
+    if (!IsAllowedRuleSourceURL(url)) {
+      [errs addObject:[NSString stringWithFormat:@"RuleSource %@ has an invalid url: %@", name,
+                                                 dict[kRuleSourceURL]]];
+      return;
+    }
+
+    NSNumber* interval = dict[kRuleSourceSyncIntervalSeconds];
+    if (![interval isKindOfClass:[NSNumber class]] ||
+        interval.unsignedIntegerValue < kRuleSourceMinimumSyncInterval) {
+      [errs addObject:[NSString stringWithFormat:@"RuleSource %@ must have a %@ of at least %lu",
+                                                 name, kRuleSourceSyncIntervalSeconds,
+                                                 kRuleSourceMinimumSyncInterval]];
+      return;
+    }
+
+    NSNumber* precedence = dict[kRuleSourcePrecedence];
+    if (precedence && ![precedence isKindOfClass:[NSNumber class]]) {
+      [errs addObject:[NSString stringWithFormat:@"RuleSource %@ has a non-integer %@", name,
+                                                 kRuleSourcePrecedence]];
+      return;
+    }
+
+    NSData* publicKey;
+    id publicKeyString = dict[kRuleSourcePublicKey];
+    if (publicKeyString) {
+      if ([publicKeyString isKindOfClass:[NSString class]]) {
+        publicKey = [[NSData alloc]
+            initWithBase64EncodedString:publicKeyString
+                                options:NSDataBase64DecodingIgnoreUnknownCharacters];
+      }
+      if (publicKey.length != kEd25519PublicKeyLength) {
+        [errs addObject:[NSString stringWithFormat:@"RuleSource %@ has an invalid %@, expected a "
+                                                   @"base64 encoded %lu byte Ed25519 key",
+                                                   name, kRuleSourcePublicKey,
+                                                   kEd25519PublicKeyLength]];
+        return;
+      }
+    }
+
+    [names addObject:name];
+    [sources addObject:[[SNTRuleSource alloc] initWithName:name
+                                                       url:url
+                                              syncInterval:interval.unsignedIntegerValue
+                                                precedence:precedence.integerValue
+                                                 publicKey:publicKey]];
+  }];
+
+  [sources sortUsingComparator:^NSComparisonResult(SNTRuleSource* a, SNTRuleSource* b) {
+    if (a.precedence != b.precedence) {
+      return a.precedence > b.precedence ? NSOrderedAscending : NSOrderedDescending;
+    }
+    return [a.name compare:b.name];
+  }];
+
+  if (errors) *errors = errs;
+  return sources;
+}
+
+@end
@@ -0,0 +1,124 @@
+/// Copyright 2026 North Pole Security, Inc.
+///
+/// Licensed under the Apache License, Version 2.0 (the "License");
+/// you may not use this file except in compliance with the License.
+/// You may obtain a copy of the License at
+///
+///     http://www.apache.org/licenses/LICENSE-2.0
+///
+/// Unless required by applicable law or agreed to in writing, software
+/// distributed under the License is distributed on an "AS IS" BASIS,
+/// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+/// See the License for the specific language governing permissions and
+/// limitations under the License.
+
+
+#import <Foundation/Foundation.h>
+#import <XCTest/XCTest.h>
+
+#import "Source/common/SNTRuleSource.h"
+
+@interface SNTRuleSourceTest : XCTestCase
+@end
+
+@implementation SNTRuleSourceTest
+
+- (void)testParsesAndOrdersByPrecedence {
+  NSArray<NSString*>* errors;
+  NSArray<SNTRuleSource*>* sources = [SNTRuleSource ruleSourcesFromArray:@[
+    @{@"name" : @"b", @"url" : @"https://b.example.com", @"sync_interval_seconds" : @600},
+    @{
+      @"name" : @"a",
+      @"url" : @"https://a.example.com/",
+      @"sync_interval_seconds" : @60,
+      @"precedence" : @10,
+    },
+    @{@"name" : @"c", @"url" : @"https://c.example.com", @"sync_interval_seconds" : @300},
+  ]
+                                                                  errors:&errors];
+
+  XCTAssertEqual(errors.count, 0);
+  XCTAssertEqual(sources.count, 3);
+  XCTAssertEqualObjects(sources[0].name, @"a");
+  XCTAssertEqual(sources[0].precedence, 10);
+  XCTAssertEqual(sources[0].syncInterval, 60);
+  XCTAssertEqualObjects(sources[0].url.absoluteString, @"https://a.example.com/");
+  // Equal precedence is ordered by name.
+  XCTAssertEqualObjects(sources[1].name, @"b");
+  XCTAssertEqualObjects(sources[1].url.absoluteString, @"https://b.example.com/");
+  XCTAssertEqual(sources[1].precedence, 0);
+  XCTAssertEqualObjects(sources[2].name, @"c");
+}
+
+- (void)testInvalidEntriesAreSkipped {
+  NSArray<NSString*>* errors;
+  NSArray<SNTRuleSource*>* sources = [SNTRuleSource ruleSourcesFromArray:@[
+    @"not a dict",
+    @{@"url" : @"https://a.example.com", @"sync_interval_seconds" : @60},
+    @{@"name" : @"http", @"url" : @"http://a.example.com", @"sync_interval_seconds" : @60},
+    @{@"name" : @"fast", @"url" : @"https://a.example.com", @"sync_interval_seconds" : @10},
+    @{
+      @"name" : @"badprecedence",
+      @"url" : @"https://a.example.com",
+      @"sync_interval_seconds" : @60,
+      @"precedence" : @"high",
+    },
+    @{@"name" : @"ok", @"url" : @"http://localhost:8080", @"sync_interval_seconds" : @60},
+    @{@"name" : @"ok", @"url" : @"https://dup.example.com", @"sync_interval_seconds" : @60},
+  ]
+                                                                  errors:&errors];
+
+  XCTAssertEqual(errors.count, 6);
+  XCTAssertEqual(sources.count, 1);
+  XCTAssertEqualObjects(sources[0].name, @"ok");
+  XCTAssertEqualObjects(sources[0].url.absoluteString, @"http://localhost:8080/");
+}
+
+- (void)testPublicKey {
+  NSString* key = [[NSMutableData dataWithLength:32] base64EncodedStringWithOptions:0];
+  NSArray<NSString*>* errors;
+  NSArray<SNTRuleSource*>* sources = [SNTRuleSource ruleSourcesFromArray:@[
+    @{
+      @"name" : @"signed",
+      @"url" : @"https://a.example.com",
+      @"sync_interval_seconds" : @60,
+      @"public_key" : key,
+    },
+    @{@"name" : @"unsigned", @"url" : @"https://b.example.com", @"sync_interval_seconds" : @60},
+    @{
+      @"name" : @"short",
+      @"url" : @"https://c.example.com",
+      @"sync_interval_seconds" : @60,
+      @"public_key" : @"AAAA",
+    },
+    @{
+      @"name" : @"notbase64",
+      @"url" : @"https://d.example.com",
+      @"sync_interval_seconds" : @60,
+      @"public_key" : @"!!!",
+    },
+    @{
+      @"name" : @"badtype",
+      @"url" : @"https://e.example.com",
+      @"sync_interval_seconds" : @60,
+      @"public_key" : @1,
+    },
+  ]
+                                                                  errors:&errors];
+
+  XCTAssertEqual(errors.count, 3);
+  XCTAssertEqual(sources.count, 2);
+  XCTAssertEqualObjects(sources[0].name, @"signed");
+  XCTAssertEqualObjects(sources[0].publicKey, [NSMutableData dataWithLength:32]);
+  XCTAssertEqualObjects(sources[1].name, @"unsigned");
+  XCTAssertNil(sources[1].publicKey);
+}
+
+- (void)testNonArrayValue {
+  NSArray<NSString*>* errors;
+  XCTAssertEqual([SNTRuleSource ruleSourcesFromArray:(NSArray*)@{} errors:&errors].count, 0);
+  XCTAssertEqual(errors.count, 0);
+  XCTAssertEqual([SNTRuleSource ruleSourcesFromArray:nil errors:NULL].count, 0);
+}
+
+@end
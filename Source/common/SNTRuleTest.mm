@@ -220,6 +220,35 @@
     XCTAssertEqualObjects(sut.identifier, ident);
   }
 
+  // Requirement rules are stored in canonical form
+  SNTRule* compact = [[SNTRule alloc] initWithDictionary:@{
+    @"identifier" : @"identifier \"com.example.app\" and anchor apple generic",
+    @"policy" : @"ALLOWLIST",
+    @"rule_type" : @"requirement",
+  }
+                                                   error:nil];
+  SNTRule* spaced = [[SNTRule alloc] initWithDictionary:@{
+    @"identifier" : @"identifier   \"com.example.app\"\n  and anchor   apple generic",
+    @"policy" : @"ALLOWLIST",
+    @"rule_type" : @"REQUIREMENT",
+  }
+                                                  error:nil];
+  XCTAssertNotNil(compact);
+  XCTAssertNotNil(spaced);
+  XCTAssertEqual(compact.type, SNTRuleTypeRequirement);
+  XCTAssertEqualObjects(compact.identifier, spaced.identifier);
+
+  // Bundle Signing ID rules follow the SigningID format.
+  sut = [[SNTRule alloc] initWithDictionary:@{
+    @"identifier" : @"abcdefghij:com.example.App",
+    @"policy" : @"ALLOWLIST",
+    @"rule_type" : @"BUNDLESIGNINGID",
+  }
+                                      error:nil];
+  XCTAssertNotNil(sut);
+  XCTAssertEqual(sut.type, SNTRuleTypeBundleSigningID);
+  XCTAssertEqualObjects(sut.identifier, @"ABCDEFGHIJ:com.example.App");
+
   // Comments are left intact
   sut = [[SNTRule alloc] initWithDictionary:@{
     @"identifier" : @"ABCDEFGHIJ",
@@ -279,6 +308,26 @@
   XCTAssertNil(sut);
   XCTAssertNotNil(error);
   XCTAssertEqual(error.code, SNTErrorCodeRuleInvalidRuleType);
+
+  sut = [[SNTRule alloc] initWithDictionary:@{
+    @"identifier" : @"identifier \"com.example.app\" and (",
+    @"policy" : @"ALLOWLIST",
+    @"rule_type" : @"REQUIREMENT",
+  }
+                                      error:&error];
+  XCTAssertNil(sut);
+  XCTAssertNotNil(error);
+  XCTAssertEqual(error.code, SNTErrorCodeRuleInvalidIdentifier);
+
+  sut = [[SNTRule alloc] initWithDictionary:@{
+    @"identifier" : @"com.example.app",
+    @"policy" : @"ALLOWLIST",
+    @"rule_type" : @"BUNDLESIGNINGID",
+  }
+                                      error:&error];
+  XCTAssertNil(sut);
+  XCTAssertNotNil(error);
+  XCTAssertEqual(error.code, SNTErrorCodeRuleInvalidIdentifier);
 }
 
 - (void)testRuleDictionaryRepresentation {
@@ -312,6 +361,39 @@
   XCTAssertEqualObjects(expectedBinary, dict);
 }
 
+- (void)testInitWithDictionarySeverity {
+  NSDictionary* base = @{
+    @"identifier" : @"84de9c61777ca36b13228e2446d53e966096e78db7a72c632b5c185b2ffe68a6",
+    @"policy" : @"BLOCKLIST",
+    @"rule_type" : @"BINARY",
+  };
+
+  SNTRule* sut = [[SNTRule alloc] initWithDictionary:base error:nil];
+  XCTAssertEqual(sut.severity, SNTRuleSeverityUnspecified);
+  XCTAssertNil([sut dictionaryRepresentation][kRuleSeverity]);
+
+  NSDictionary* expected = @{
+    @"info" : @(SNTRuleSeverityInfo),
+    @"Warning" : @(SNTRuleSeverityWarning),
+    @"CRITICAL" : @(SNTRuleSeverityCritical),
+  };
+  for (NSString* severity in expected) {
+    NSMutableDictionary* dict = [base mutableCopy];
+    dict[@"severity"] = severity;
+    sut = [[SNTRule alloc] initWithDictionary:dict error:nil];
+    XCTAssertEqual(sut.severity, [expected[severity] integerValue]);
+    XCTAssertEqualObjects([sut dictionaryRepresentation][kRuleSeverity],
+                          [severity lowercaseString]);
+  }
+
+  NSMutableDictionary* dict = [base mutableCopy];
+  dict[@"severity"] = @"urgent";
+  NSError* error;
+  sut = [[SNTRule alloc] initWithDictionary:dict error:&error];
+  XCTAssertNil(sut);
+  XCTAssertEqual(error.code, SNTErrorCodeRuleInvalid);
+}
+
 - (void)testRuleStateToPolicyString {
   NSDictionary* expected = @{
     @"identifier" : @"84de9c61777ca36b13228e2446d53e966096e78db7a72c632b5c185b2ffe68a6",
@@ -31,6 +31,10 @@
 /// The full path of the executed file.
 @property(nullable) NSString* filePath;
 
+/// The canonicalized path of the executed file, with translocation and symlinks resolved. Only set
+/// when CanonicalizeExecutablePaths is enabled and the path differs from filePath.
+@property(nullable) NSString* resolvedFilePath;
+
 /// Set to YES if the event is a part of a bundle. When an event is passed to SantaGUI this propery
 /// will be used as an indicator to to kick off bundle hashing as necessary. Default value is NO.
 @property BOOL needsBundleHash;
@@ -111,6 +115,14 @@
 /// The server-assigned rule ID that matched this event.
 @property int64_t ruleId;
 
+/// The severity of the rule that matched this event. Controls how prominently a block is shown.
+@property SNTRuleSeverity severity;
+
+/// Set on events recording that a previously blocked execution was approved, either by the user
+/// in standalone mode or by a rule from the sync server. The time in milliseconds between the
+/// block and the approval.
+@property(nullable) NSNumber* approvalLatencyMs;
+
 /// NSArray of logged in users when the decision was made.
 @property(nullable) NSArray* loggedInUsers;
 
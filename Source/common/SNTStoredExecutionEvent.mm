@@ -39,6 +39,8 @@
     _cdhash = cs.cdhash;
     _teamID = cs.teamID;
     _signingID = FormatSigningID(cs);
+    // The static signature flags use the same bits as the kernel's CS_* flags.
+    _codesigningFlags = cs.signatureFlags;
     _entitlements = cs.entitlements;
     _secureSigningTime = cs.secureSigningTime;
     _signingTime = cs.signingTime;
@@ -55,6 +57,7 @@
   [super encodeWithCoder:coder];
   ENCODE(coder, fileSHA256);
   ENCODE(coder, filePath);
+  ENCODE(coder, resolvedFilePath);
 
   ENCODE_BOXABLE(coder, needsBundleHash);
   ENCODE(coder, fileBundleHash);
@@ -86,6 +89,8 @@
   ENCODE_BOXABLE(coder, seatbeltRequired);
   ENCODE_BOXABLE(coder, staticRule);
   ENCODE_BOXABLE(coder, ruleId);
+  ENCODE_BOXABLE(coder, severity);
+  ENCODE(coder, approvalLatencyMs);
   ENCODE(coder, pid);
   ENCODE(coder, ppid);
   ENCODE(coder, parentName);
@@ -104,6 +109,7 @@
   if (self) {
     DECODE(decoder, fileSHA256, NSString);
     DECODE(decoder, filePath, NSString);
+    DECODE(decoder, resolvedFilePath, NSString);
 
     DECODE_SELECTOR(decoder, needsBundleHash, NSNumber, boolValue);
     DECODE(decoder, fileBundleHash, NSString);
@@ -135,6 +141,8 @@
     DECODE_SELECTOR(decoder, seatbeltRequired, NSNumber, boolValue);
     DECODE_SELECTOR(decoder, staticRule, NSNumber, boolValue);
     DECODE_SELECTOR(decoder, ruleId, NSNumber, longLongValue);
+    DECODE_SELECTOR(decoder, severity, NSNumber, integerValue);
+    DECODE(decoder, approvalLatencyMs, NSNumber);
     DECODE(decoder, pid, NSNumber);
     DECODE(decoder, ppid, NSNumber);
     DECODE(decoder, parentName, NSString);
@@ -187,6 +195,10 @@
   // (the events table is drained on upload) rather than one per execution --
   // which bounds table growth without needing a separate cap. The key is a pure
   // function of already-stored fields, so it is stable and thread-safe.
+  if (self.approvalLatencyMs) {
+    // Approvals are reported once per block and never merged with other events.
+    return [self.fileSHA256 stringByAppendingString:@":approval"];
+  }
   return self.auditReturn ? [self.fileSHA256 stringByAppendingString:@":audit"] : self.fileSHA256;
 }
 
@@ -196,7 +208,8 @@
   // they must not be suppressed by the storage backoff. Returning NO keeps them
   // out of the backoff path entirely (see SNTEventTable -addStoredEvents:);
   // they are instead deduped per sync cycle via -uniqueID's audit-specific key.
-  return !self.auditReturn && (self.decision & SNTEventStateAllow) != 0;
+  // Approval events are treated the same way.
+  return !self.auditReturn && !self.approvalLatencyMs && (self.decision & SNTEventStateAllow) != 0;
 }
 
 @end
@@ -0,0 +1,78 @@
+/// Copyright 2026 North Pole Security, Inc.
+///
+/// Licensed under the Apache License, Version 2.0 (the "License");
+/// you may not use this file except in compliance with the License.
+/// You may obtain a copy of the License at
+///
+///     http://www.apache.org/licenses/LICENSE-2.0
+///
+/// Unless required by applicable law or agreed to in writing, software
+/// distributed under the License is distributed on an "AS IS" BASIS,
+/// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+/// See the License for the specific language governing permissions and
+/// limitations under the License.
+
+#import <Foundation/Foundation.h>
+
+#import "Source/common/SNTProcessChain.h"
+#import "Source/common/SNTStoredEvent.h"
+
+// The kind of launch item that was registered, mirroring es_btm_item_type_t.
+typedef NS_ENUM(NSInteger, SNTStoredLaunchItemEventItemType) {
+  SNTStoredLaunchItemEventItemTypeUnknown,
+  SNTStoredLaunchItemEventItemTypeUserItem,
+  SNTStoredLaunchItemEventItemTypeApp,
+  SNTStoredLaunchItemEventItemTypeLoginItem,
+  SNTStoredLaunchItemEventItemTypeAgent,
+  SNTStoredLaunchItemEventItemTypeDaemon,
+};
+
+// How the launch item's executable was evaluated against the TeamID and SigningID rules.
+typedef NS_ENUM(NSInteger, SNTStoredLaunchItemEventDecision) {
+  // A rule, or the executable being a platform binary, allowed the item.
+  SNTStoredLaunchItemEventDecisionAllowed,
+
+  // No TeamID or SigningID rule matched the item's executable.
+  SNTStoredLaunchItemEventDecisionUnknown,
+
+  // A rule blocked the item's executable.
+  SNTStoredLaunchItemEventDecisionBlocked,
+};
+
+/// Represents a launch item (login item, launch agent or launch daemon) being registered with
+/// Background Task Management.
+@interface SNTStoredLaunchItemEvent : SNTStoredEvent <NSSecureCoding>
+
+@property NSString* uuid;
+@property SNTStoredLaunchItemEventItemType itemType;
+
+/// Whether the item is a legacy plist rather than registered through SMAppService.
+@property BOOL legacy;
+
+/// Whether the item is managed by MDM.
+@property BOOL managed;
+
+/// The user the item was registered for.
+@property NSNumber* uid;
+
+/// The item's plist (agents and daemons) or bundle (login items).
+@property NSString* itemPath;
+
+/// The app the item is attributed to, if any.
+@property NSString* appPath;
+
+/// The program the item runs, if known.
+@property NSString* executablePath;
+
+@property NSString* teamID;
+@property NSString* signingID;
+
+@property SNTStoredLaunchItemEventDecision decision;
+
+/// Whether santad disabled the item because of a LaunchItemPolicy of Block.
+@property BOOL enforced;
+
+/// The process that registered the item.
+@property SNTProcessChain* process;
+
+@end
@@ -0,0 +1,87 @@
+/// Copyright 2026 North Pole Security, Inc.
+///
+/// Licensed under the Apache License, Version 2.0 (the "License");
+/// you may not use this file except in compliance with the License.
+/// You may obtain a copy of the License at
+///
+///     http://www.apache.org/licenses/LICENSE-2.0
+///
+/// Unless required by applicable law or agreed to in writing, software
+/// distributed under the License is distributed on an "AS IS" BASIS,
+/// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+/// See the License for the specific language governing permissions and
+/// limitations under the License.
+
+#import "Source/common/SNTStoredLaunchItemEvent.h"
+
+#include "Source/common/CoderMacros.h"
+
+@implementation SNTStoredLaunchItemEvent
+
+- (instancetype)init {
+  self = [super init];
+  if (self) {
+    _uuid = [[NSUUID UUID] UUIDString];
+    _process = [[SNTProcessChain alloc] init];
+  }
+  return self;
+}
+
++ (BOOL)supportsSecureCoding {
+  return YES;
+}
+
+- (void)encodeWithCoder:(NSCoder*)coder {
+  [super encodeWithCoder:coder];
+  ENCODE(coder, uuid);
+  ENCODE_BOXABLE(coder, itemType);
+  ENCODE_BOXABLE(coder, legacy);
+  ENCODE_BOXABLE(coder, managed);
+  ENCODE(coder, uid);
+  ENCODE(coder, itemPath);
+  ENCODE(coder, appPath);
+  ENCODE(coder, executablePath);
+  ENCODE(coder, teamID);
+  ENCODE(coder, signingID);
+  ENCODE_BOXABLE(coder, decision);
+  ENCODE_BOXABLE(coder, enforced);
+  ENCODE(coder, process);
+}
+
+- (instancetype)initWithCoder:(NSCoder*)decoder {
+  self = [super initWithCoder:decoder];
+  if (self) {
+    DECODE(decoder, uuid, NSString);
+    DECODE_SELECTOR(decoder, itemType, NSNumber, integerValue);
+    DECODE_SELECTOR(decoder, legacy, NSNumber, boolValue);
+    DECODE_SELECTOR(decoder, managed, NSNumber, boolValue);
+    DECODE(decoder, uid, NSNumber);
+    DECODE(decoder, itemPath, NSString);
+    DECODE(decoder, appPath, NSString);
+    DECODE(decoder, executablePath, NSString);
+    DECODE(decoder, teamID, NSString);
+    DECODE(decoder, signingID, NSString);
+    DECODE_SELECTOR(decoder, decision, NSNumber, integerValue);
+    DECODE_SELECTOR(decoder, enforced, NSNumber, boolValue);
+    DECODE(decoder, process, SNTProcessChain);
+  }
+  return self;
+}
+
+- (NSString*)uniqueID {
+  // Re-registering the same item shouldn't be reported again while backed off.
+  return [NSString stringWithFormat:@"%@|%@", self.itemPath, self.executablePath];
+}
+
+- (BOOL)unactionableEvent {
+  // OK to be part of the backoff cache
+  return YES;
+}
+
+- (NSString*)description {
+  return [NSString stringWithFormat:@"SNTStoredLaunchItemEvent[%@]: %@ (%@), decision: %ld",
+                                    self.idx, self.itemPath, self.executablePath,
+                                    (long)self.decision];
+}
+
+@end
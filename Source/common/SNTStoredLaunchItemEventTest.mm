@@ -0,0 +1,79 @@
+/// Copyright 2026 North Pole Security, Inc.
+///
+/// Licensed under the Apache License, Version 2.0 (the "License");
+/// you may not use this file except in compliance with the License.
+/// You may obtain a copy of the License at
+///
+///     http://www.apache.org/licenses/LICENSE-2.0
+///
+/// Unless required by applicable law or agreed to in writing, software
+/// distributed under the License is distributed on an "AS IS" BASIS,
+/// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+/// See the License for the specific language governing permissions and
+/// limitations under the License.
+
+#import "Source/common/SNTStoredLaunchItemEvent.h"
+
+#import <Foundation/Foundation.h>
+#import <XCTest/XCTest.h>
+
+@interface SNTStoredLaunchItemEventTest : XCTestCase
+@end
+
+@implementation SNTStoredLaunchItemEventTest
+
+- (void)testUniqueIDAndUnactionable {
+  SNTStoredLaunchItemEvent* event = [[SNTStoredLaunchItemEvent alloc] init];
+  event.itemPath = @"/Library/LaunchAgents/com.example.agent.plist";
+  event.executablePath = @"/usr/local/bin/agent";
+
+  XCTAssertEqualObjects([event uniqueID],
+                        @"/Library/LaunchAgents/com.example.agent.plist|/usr/local/bin/agent");
+  XCTAssertTrue([event unactionableEvent]);
+}
+
+- (void)testEncodeDecode {
+  SNTStoredLaunchItemEvent* event = [[SNTStoredLaunchItemEvent alloc] init];
+  event.itemType = SNTStoredLaunchItemEventItemTypeDaemon;
+  event.legacy = YES;
+  event.managed = NO;
+  event.uid = @(0);
+  event.itemPath = @"/Library/LaunchDaemons/com.example.daemon.plist";
+  event.executablePath = @"/usr/local/bin/daemon";
+  event.teamID = @"EQHXZ8M8AV";
+  event.signingID = @"EQHXZ8M8AV:com.example.daemon";
+  event.decision = SNTStoredLaunchItemEventDecisionBlocked;
+  event.enforced = YES;
+  event.process.filePath = @"/bin/launchctl";
+  event.process.pid = @(1234);
+
+  NSData* archivedEvent = [NSKeyedArchiver archivedDataWithRootObject:event
+                                                requiringSecureCoding:YES
+                                                                error:nil];
+  XCTAssertNotNil(archivedEvent);
+
+  SNTStoredLaunchItemEvent* decodedEvent =
+      [NSKeyedUnarchiver unarchivedObjectOfClass:[SNTStoredLaunchItemEvent class]
+                                        fromData:archivedEvent
+                                           error:nil];
+  XCTAssertNotNil(decodedEvent);
+
+  XCTAssertEqualObjects(decodedEvent.idx, event.idx);
+  XCTAssertEqualObjects(decodedEvent.occurrenceDate, event.occurrenceDate);
+  XCTAssertEqualObjects(decodedEvent.uuid, event.uuid);
+  XCTAssertEqual(decodedEvent.itemType, SNTStoredLaunchItemEventItemTypeDaemon);
+  XCTAssertTrue(decodedEvent.legacy);
+  XCTAssertFalse(decodedEvent.managed);
+  XCTAssertEqualObjects(decodedEvent.uid, @(0));
+  XCTAssertEqualObjects(decodedEvent.itemPath, @"/Library/LaunchDaemons/com.example.daemon.plist");
+  XCTAssertNil(decodedEvent.appPath);
+  XCTAssertEqualObjects(decodedEvent.executablePath, @"/usr/local/bin/daemon");
+  XCTAssertEqualObjects(decodedEvent.teamID, @"EQHXZ8M8AV");
+  XCTAssertEqualObjects(decodedEvent.signingID, @"EQHXZ8M8AV:com.example.daemon");
+  XCTAssertEqual(decodedEvent.decision, SNTStoredLaunchItemEventDecisionBlocked);
+  XCTAssertTrue(decodedEvent.enforced);
+  XCTAssertEqualObjects(decodedEvent.process.filePath, @"/bin/launchctl");
+  XCTAssertEqualObjects(decodedEvent.process.pid, @(1234));
+}
+
+@end
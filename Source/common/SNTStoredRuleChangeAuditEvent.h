@@ -0,0 +1,59 @@
+/// Copyright 2026 North Pole Security, Inc.
+///
+/// Licensed under the Apache License, Version 2.0 (the "License");
+/// you may not use this file except in compliance with the License.
+/// You may obtain a copy of the License at
+///
+///     http://www.apache.org/licenses/LICENSE-2.0
+///
+/// Unless required by applicable law or agreed to in writing, software
+/// distributed under the License is distributed on an "AS IS" BASIS,
+/// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+/// See the License for the specific language governing permissions and
+/// limitations under the License.
+
+#import <Foundation/Foundation.h>
+
+#import "Source/common/SNTCommonEnums.h"
+#import "Source/common/SNTProcessChain.h"
+#import "Source/common/SNTStoredEvent.h"
+
+@class SNTRule;
+
+// The local mechanism that changed the rules database.
+typedef NS_ENUM(NSInteger, SNTRuleChangeAuditSource) {
+  // Rules were added or removed with santactl.
+  SNTRuleChangeAuditSourceSantactl,
+
+  // A user approved a blocked binary in Standalone mode.
+  SNTRuleChangeAuditSourceStandaloneApproval,
+
+  // A compiler wrote a new executable and a transitive rule was created for it.
+  SNTRuleChangeAuditSourceTransitive,
+};
+
+// Represents a change to the rules database that was made on the machine rather than by the sync
+// server. These are stored in the events database so that the sync server receives a record of
+// every local rule change.
+@interface SNTStoredRuleChangeAuditEvent : SNTStoredEvent <NSSecureCoding>
+
+@property(readonly) NSString* uuid;
+@property(readonly) SNTRuleChangeAuditSource source;
+
+// The rules that were added. Rules in the SNTRuleStateRemove state were removed.
+@property(readonly) NSArray<SNTRule*>* rules;
+
+// Any cleanup of existing rules that was requested along with the change.
+@property(readonly) SNTRuleCleanup ruleCleanup;
+
+// The process, or for standalone approvals the user, that made the change. Only the fields that
+// are known for the source are set.
+@property(readonly) SNTProcessChain* process;
+
+- (instancetype)initWithSource:(SNTRuleChangeAuditSource)source
+                         rules:(NSArray<SNTRule*>*)rules
+                   ruleCleanup:(SNTRuleCleanup)ruleCleanup
+                       process:(SNTProcessChain*)process;
+- (instancetype)init NS_UNAVAILABLE;
+
+@end
@@ -0,0 +1,76 @@
+/// Copyright 2026 North Pole Security, Inc.
+///
+/// Licensed under the Apache License, Version 2.0 (the "License");
+/// you may not use this file except in compliance with the License.
+/// You may obtain a copy of the License at
+///
+///     http://www.apache.org/licenses/LICENSE-2.0
+///
+/// Unless required by applicable law or agreed to in writing, software
+/// distributed under the License is distributed on an "AS IS" BASIS,
+/// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+/// See the License for the specific language governing permissions and
+/// limitations under the License.
+
+#import "Source/common/SNTStoredRuleChangeAuditEvent.h"
+
+#include "Source/common/CoderMacros.h"
+#import "Source/common/SNTRule.h"
+
+@implementation SNTStoredRuleChangeAuditEvent
+
+- (instancetype)initWithSource:(SNTRuleChangeAuditSource)source
+                         rules:(NSArray<SNTRule*>*)rules
+                   ruleCleanup:(SNTRuleCleanup)ruleCleanup
+                       process:(SNTProcessChain*)process {
+  self = [super init];
+  if (self) {
+    _uuid = [[NSUUID UUID] UUIDString];
+    _source = source;
+    _rules = [rules copy] ?: @[];
+    _ruleCleanup = ruleCleanup;
+    _process = process;
+  }
+  return self;
+}
+
++ (BOOL)supportsSecureCoding {
+  return YES;
+}
+
+- (void)encodeWithCoder:(NSCoder*)coder {
+  [super encodeWithCoder:coder];
+  ENCODE(coder, uuid);
+  ENCODE_BOXABLE(coder, source);
+  ENCODE(coder, rules);
+  ENCODE_BOXABLE(coder, ruleCleanup);
+  ENCODE(coder, process);
+}
+
+- (instancetype)initWithCoder:(NSCoder*)decoder {
+  self = [super initWithCoder:decoder];
+  if (self) {
+    DECODE(decoder, uuid, NSString);
+    DECODE_SELECTOR(decoder, source, NSNumber, integerValue);
+    DECODE_ARRAY(decoder, rules, SNTRule);
+    DECODE_SELECTOR(decoder, ruleCleanup, NSNumber, integerValue);
+    DECODE(decoder, process, SNTProcessChain);
+  }
+  return self;
+}
+
+- (NSString*)uniqueID {
+  return self.uuid;
+}
+
+- (BOOL)unactionableEvent {
+  // Every local rule change must reach the sync server, never drop one to the backoff cache.
+  return NO;
+}
+
+- (NSString*)description {
+  return [NSString stringWithFormat:@"SNTStoredRuleChangeAuditEvent[%@]: source: %ld, rules: %lu",
+                                    self.idx, (long)self.source, (unsigned long)self.rules.count];
+}
+
+@end
@@ -0,0 +1,87 @@
+/// Copyright 2026 North Pole Security, Inc.
+///
+/// Licensed under the Apache License, Version 2.0 (the "License");
+/// you may not use this file except in compliance with the License.
+/// You may obtain a copy of the License at
+///
+///     http://www.apache.org/licenses/LICENSE-2.0
+///
+/// Unless required by applicable law or agreed to in writing, software
+/// distributed under the License is distributed on an "AS IS" BASIS,
+/// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+/// See the License for the specific language governing permissions and
+/// limitations under the License.
+
+#import "Source/common/SNTStoredRuleChangeAuditEvent.h"
+
+#import <Foundation/Foundation.h>
+#import <XCTest/XCTest.h>
+
+#import "Source/common/SNTRule.h"
+
+@interface SNTStoredRuleChangeAuditEventTest : XCTestCase
+@end
+
+@implementation SNTStoredRuleChangeAuditEventTest
+
+- (void)testUniqueIDAndUnactionable {
+  SNTStoredRuleChangeAuditEvent* first =
+      [[SNTStoredRuleChangeAuditEvent alloc] initWithSource:SNTRuleChangeAuditSourceSantactl
+                                                      rules:@[]
+                                                ruleCleanup:SNTRuleCleanupNone
+                                                    process:nil];
+  SNTStoredRuleChangeAuditEvent* second =
+      [[SNTStoredRuleChangeAuditEvent alloc] initWithSource:SNTRuleChangeAuditSourceSantactl
+                                                      rules:@[]
+                                                ruleCleanup:SNTRuleCleanupNone
+                                                    process:nil];
+
+  // Identical changes are still separate entries in the audit trail.
+  XCTAssertEqualObjects([first uniqueID], first.uuid);
+  XCTAssertNotEqualObjects([first uniqueID], [second uniqueID]);
+  XCTAssertFalse([first unactionableEvent]);
+}
+
+- (void)testEncodeDecode {
+  SNTRule* addRule = [[SNTRule alloc] initWithIdentifier:@"EQHXZ8M8AV"
+                                                   state:SNTRuleStateAllow
+                                                    type:SNTRuleTypeTeamID];
+  SNTRule* removeRule = [[SNTRule alloc] initWithIdentifier:@"EQHXZ8M8AV:com.example.tool"
+                                                      state:SNTRuleStateRemove
+                                                       type:SNTRuleTypeSigningID];
+  SNTProcessChain* process = [[SNTProcessChain alloc] init];
+  process.filePath = @"/usr/local/bin/santactl";
+  process.pid = @(1234);
+  process.executingUserID = @(0);
+  process.executingUser = @"root";
+
+  SNTStoredRuleChangeAuditEvent* event =
+      [[SNTStoredRuleChangeAuditEvent alloc] initWithSource:SNTRuleChangeAuditSourceSantactl
+                                                      rules:@[ addRule, removeRule ]
+                                                ruleCleanup:SNTRuleCleanupNonTransitive
+                                                    process:process];
+
+  NSData* archivedEvent = [NSKeyedArchiver archivedDataWithRootObject:event
+                                                requiringSecureCoding:YES
+                                                                error:nil];
+  XCTAssertNotNil(archivedEvent);
+
+  SNTStoredRuleChangeAuditEvent* decodedEvent =
+      [NSKeyedUnarchiver unarchivedObjectOfClass:[SNTStoredRuleChangeAuditEvent class]
+                                        fromData:archivedEvent
+                                           error:nil];
+  XCTAssertNotNil(decodedEvent);
+
+  XCTAssertEqualObjects(decodedEvent.idx, event.idx);
+  XCTAssertEqualObjects(decodedEvent.occurrenceDate, event.occurrenceDate);
+  XCTAssertEqualObjects(decodedEvent.uuid, event.uuid);
+  XCTAssertEqual(decodedEvent.source, SNTRuleChangeAuditSourceSantactl);
+  XCTAssertEqual(decodedEvent.ruleCleanup, SNTRuleCleanupNonTransitive);
+  XCTAssertEqualObjects(decodedEvent.rules, (@[ addRule, removeRule ]));
+  XCTAssertEqualObjects(decodedEvent.process.filePath, @"/usr/local/bin/santactl");
+  XCTAssertEqualObjects(decodedEvent.process.pid, @(1234));
+  XCTAssertEqualObjects(decodedEvent.process.executingUserID, @(0));
+  XCTAssertEqualObjects(decodedEvent.process.executingUser, @"root");
+}
+
+@end
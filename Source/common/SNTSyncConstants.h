@@ -136,10 +136,16 @@ extern NSString* const kRuleTypeCertificate;
 extern NSString* const kRuleTypeTeamID;
 extern NSString* const kRuleTypeSigningID;
 extern NSString* const kRuleTypeCDHash;
+extern NSString* const kRuleTypeRequirement;
+extern NSString* const kRuleTypeBundleSigningID;
 extern NSString* const kRuleCustomMsg;
 extern NSString* const kRuleCustomURL;
 extern NSString* const kRuleComment;
 extern NSString* const kRuleCELExpr;
+extern NSString* const kRuleSeverity;
+extern NSString* const kRuleSeverityInfo;
+extern NSString* const kRuleSeverityWarning;
+extern NSString* const kRuleSeverityCritical;
 extern NSString* const kCursor;
 
 extern NSString* const kBackoffInterval;
@@ -154,6 +160,182 @@ extern const NSUInteger kDefaultEventBatchSize;
 extern NSString* const kPostflightRulesReceived;
 extern NSString* const kPostflightRulesProcessed;
 
+///
+///  HTTP headers carrying preflight data that the sync protocol messages have no fields for, as
+///  the message definitions are shared with sync servers. Every header is optional.
+///
+///  Sent by the client with the preflight request:
+///    kSyncConfigHashHeader: a hash of the effective configuration.
+///    kSyncHardwareUUIDHeader: the host's hardware UUID, so the server can tell apart hosts that
+///      report the same machine ID.
+///
+///  Set by the server on the preflight response:
+///    kSyncMinimumOSVersionHeader: the minimum macOS version the server requires, e.g. 14.4.
+///    kSyncEventFieldsHeader: the comma separated execution event fields to upload.
+///    kSyncTelemetrySampleRateHeader: the fraction (0.0-1.0) of allowed execution events to keep.
+///    kSyncMachineIDConflictHeader: any non-empty value if another host uses this machine ID.
+///    kSyncPushPingIntervalHeader: the interval in seconds at which the push client pings.
+///    kSyncPushMaxPingsOutHeader: the number of unanswered pings after which it reconnects.
+///
+extern NSString* const kSyncConfigHashHeader;
+extern NSString* const kSyncHardwareUUIDHeader;
+extern NSString* const kSyncMinimumOSVersionHeader;
+extern NSString* const kSyncEventFieldsHeader;
+extern NSString* const kSyncTelemetrySampleRateHeader;
+extern NSString* const kSyncMachineIDConflictHeader;
+extern NSString* const kSyncPushPingIntervalHeader;
+extern NSString* const kSyncPushMaxPingsOutHeader;
+
+///
+///  Keys of the push client diagnostics snapshot returned by the sync service. The push JWT itself
+///  is never included: kPushDiagnosticsJWTParsed is absent if there is no JWT and NO if it can't be
+///  parsed, otherwise its subject, expiry (absent if it doesn't expire) and subscribe allow and
+///  deny lists are included.
+///
+extern NSString* const kPushDiagnosticsEnabled;
+extern NSString* const kPushDiagnosticsServer;
+extern NSString* const kPushDiagnosticsJWTParsed;
+extern NSString* const kPushDiagnosticsJWTSubject;
+extern NSString* const kPushDiagnosticsJWTExpiry;
+extern NSString* const kPushDiagnosticsJWTSubscribeAllow;
+extern NSString* const kPushDiagnosticsJWTSubscribeDeny;
+extern NSString* const kPushDiagnosticsDeviceID;
+extern NSString* const kPushDiagnosticsTags;
+extern NSString* const kPushDiagnosticsConnected;
+extern NSString* const kPushDiagnosticsLastError;
+extern NSString* const kPushDiagnosticsDeniedSubject;
+extern NSString* const kPushDiagnosticsStats;
+
+///
+///  Keys of the push connection stats in the kPushDiagnosticsStats entry of the diagnostics
+///  snapshot. kPushStatsLastRTTTime is seconds since the epoch, and both it and
+///  kPushStatsLastRTTMs are absent until a round trip time has been measured. The push latency
+///  keys are absent until a message with a kPushHeaderPublishedAt header has been received.
+///  kPushStatsLatencyCount and kPushStatsLatencyMaxMs cover every such message, the percentiles
+///  only the most recent ones.
+///
+extern NSString* const kPushStatsBytesIn;
+extern NSString* const kPushStatsBytesOut;
+extern NSString* const kPushStatsSubscribes;
+extern NSString* const kPushStatsReconnects;
+extern NSString* const kPushStatsMessagesBySubject;
+extern NSString* const kPushStatsLastRTTMs;
+extern NSString* const kPushStatsLastRTTTime;
+extern NSString* const kPushStatsLatencyCount;
+extern NSString* const kPushStatsLatencyP50Ms;
+extern NSString* const kPushStatsLatencyP95Ms;
+extern NSString* const kPushStatsLatencyMaxMs;
+
+///
+///  Keys and status values of the per-phase results returned by the enrollment check.
+///
+extern NSString* const kEnrollmentTestPhase;
+extern NSString* const kEnrollmentTestStatus;
+extern NSString* const kEnrollmentTestDetail;
+extern NSString* const kEnrollmentTestStatusPassed;
+extern NSString* const kEnrollmentTestStatusFailed;
+extern NSString* const kEnrollmentTestStatusSkipped;
+
+///
+///  Keys of the report returned by a rule reconcile.
+///
+extern NSString* const kRuleReconcileAdded;
+extern NSString* const kRuleReconcileRemoved;
+extern NSString* const kRuleReconcileUnchanged;
+extern NSString* const kRuleReconcileError;
+
+///
+///  Keys of the report returned by an upload benchmark.
+///
+extern NSString* const kUploadBenchmarkEventsGenerated;
+extern NSString* const kUploadBenchmarkEventsAccepted;
+extern NSString* const kUploadBenchmarkRequests;
+extern NSString* const kUploadBenchmarkRequestsFailed;
+extern NSString* const kUploadBenchmarkBytesSent;
+extern NSString* const kUploadBenchmarkBytesReceived;
+extern NSString* const kUploadBenchmarkElapsedSeconds;
+extern NSString* const kUploadBenchmarkLatencyMedianMs;
+extern NSString* const kUploadBenchmarkLatencyP95Ms;
+extern NSString* const kUploadBenchmarkLatencyMaxMs;
+extern NSString* const kUploadBenchmarkError;
+
+///
+///  Keys of the sync circuit breaker status returned by the sync service.
+///
+extern NSString* const kSyncCircuitBreakerState;
+extern NSString* const kSyncCircuitBreakerConsecutiveFailures;
+extern NSString* const kSyncCircuitBreakerRetryAt;
+
+///
+///  Keys of each rule source status returned by the sync service. Times are seconds since the
+///  epoch.
+///
+extern NSString* const kRuleSourceStatusName;
+extern NSString* const kRuleSourceStatusURL;
+extern NSString* const kRuleSourceStatusPrecedence;
+extern NSString* const kRuleSourceStatusSyncInterval;
+extern NSString* const kRuleSourceStatusLastAttempt;
+extern NSString* const kRuleSourceStatusLastSuccess;
+extern NSString* const kRuleSourceStatusLastError;
+extern NSString* const kRuleSourceStatusRulesReceived;
+extern NSString* const kRuleSourceStatusNextSync;
+
+///
+///  NATS message headers a tag push notification may carry to temporarily
+///  override the full sync interval of every host with that tag.
+///
+extern NSString* const kPushHeaderSyncIntervalOverride;
+extern NSString* const kPushHeaderSyncIntervalOverrideDuration;
+
+///
+///  NATS message header selecting what a push notification asks the host to do. Messages
+///  without the header, or with an unrecognized value, trigger a sync.
+///
+extern NSString* const kPushHeaderType;
+extern NSString* const kPushTypeSync;
+extern NSString* const kPushTypeCollectDiagnostics;
+
+///
+///  NATS message header identifying a push notification. A message published to several subjects
+///  the host is subscribed to should carry the same ID on each, so the host only acts on it once.
+///
+extern NSString* const kPushHeaderMessageID;
+
+///
+///  NATS message header carrying the time a push notification was published, as an RFC 3339
+///  timestamp with up to nanosecond precision. Used to measure push delivery latency.
+///
+extern NSString* const kPushHeaderPublishedAt;
+
+///
+///  A host push notification with the export_decisions type asks the host to publish its recent
+///  execution decisions to the message's reply subject. The window is given in seconds since the
+///  epoch; the end defaults to now.
+///
+extern NSString* const kPushTypeExportDecisions;
+extern NSString* const kPushHeaderExportDecisionsStart;
+extern NSString* const kPushHeaderExportDecisionsEnd;
+
+///
+///  A push notification with the rotate_credentials type asks the host to run a preflight to
+///  fetch fresh push credentials, reconnecting to the push server if they changed. This lets the
+///  server rotate credentials before they expire.
+///
+extern NSString* const kPushTypeRotateCredentials;
+
+///
+///  A push notification with the report_rules type asks the host to upload a snapshot of its
+///  execution rules (identifier, rule type and policy only) to the sync server.
+///
+extern NSString* const kPushTypeReportRules;
+
+///
+///  A push notification with the apply_rules type carries a small signed rule bundle as its
+///  payload. The host verifies it with PushInlineRulesPublicKey and adds the rules immediately,
+///  without a sync. Intended for urgent updates of a handful of rules.
+///
+extern NSString* const kPushTypeApplyRules;
+
 ///
 ///  kDefaultFullSyncInterval
 ///  kDefaultFCMFullSyncInterval
@@ -137,10 +137,16 @@ NSString* const kRuleTypeCertificate = @"CERTIFICATE";
 NSString* const kRuleTypeTeamID = @"TEAMID";
 NSString* const kRuleTypeSigningID = @"SIGNINGID";
 NSString* const kRuleTypeCDHash = @"CDHASH";
+NSString* const kRuleTypeRequirement = @"REQUIREMENT";
+NSString* const kRuleTypeBundleSigningID = @"BUNDLESIGNINGID";
 NSString* const kRuleCustomMsg = @"custom_msg";
 NSString* const kRuleCustomURL = @"custom_url";
 NSString* const kRuleComment = @"comment";
 NSString* const kRuleCELExpr = @"cel_expr";
+NSString* const kRuleSeverity = @"severity";
+NSString* const kRuleSeverityInfo = @"info";
+NSString* const kRuleSeverityWarning = @"warning";
+NSString* const kRuleSeverityCritical = @"critical";
 NSString* const kCursor = @"cursor";
 
 NSString* const kBackoffInterval = @"backoff";
@@ -153,6 +159,93 @@ NSString* const kLogSync = @"log_sync";
 NSString* const kPostflightRulesReceived = @"rules_received";
 NSString* const kPostflightRulesProcessed = @"rules_processed";
 
+NSString* const kSyncConfigHashHeader = @"X-Santa-Config-Hash";
+NSString* const kSyncHardwareUUIDHeader = @"X-Santa-Hardware-UUID";
+NSString* const kSyncMinimumOSVersionHeader = @"X-Santa-Minimum-OS-Version";
+NSString* const kSyncEventFieldsHeader = @"X-Santa-Event-Fields";
+NSString* const kSyncTelemetrySampleRateHeader = @"X-Santa-Telemetry-Sample-Rate";
+NSString* const kSyncMachineIDConflictHeader = @"X-Santa-Machine-ID-Conflict";
+NSString* const kSyncPushPingIntervalHeader = @"X-Santa-Push-Ping-Interval";
+NSString* const kSyncPushMaxPingsOutHeader = @"X-Santa-Push-Max-Pings-Out";
+
+NSString* const kPushDiagnosticsEnabled = @"enabled";
+NSString* const kPushDiagnosticsServer = @"server";
+NSString* const kPushDiagnosticsJWTParsed = @"user_parsed";
+NSString* const kPushDiagnosticsJWTSubject = @"user_subject";
+NSString* const kPushDiagnosticsJWTExpiry = @"user_expiry";
+NSString* const kPushDiagnosticsJWTSubscribeAllow = @"user_subscribe_allow";
+NSString* const kPushDiagnosticsJWTSubscribeDeny = @"user_subscribe_deny";
+NSString* const kPushDiagnosticsDeviceID = @"device_id";
+NSString* const kPushDiagnosticsTags = @"tags";
+NSString* const kPushDiagnosticsConnected = @"connected";
+NSString* const kPushDiagnosticsLastError = @"last_error";
+NSString* const kPushDiagnosticsDeniedSubject = @"denied_subject";
+NSString* const kPushDiagnosticsStats = @"stats";
+
+NSString* const kPushStatsBytesIn = @"bytes_in";
+NSString* const kPushStatsBytesOut = @"bytes_out";
+NSString* const kPushStatsSubscribes = @"subscribes";
+NSString* const kPushStatsReconnects = @"reconnects";
+NSString* const kPushStatsMessagesBySubject = @"messages_by_subject";
+NSString* const kPushStatsLastRTTMs = @"last_rtt_ms";
+NSString* const kPushStatsLastRTTTime = @"last_rtt_time";
+NSString* const kPushStatsLatencyCount = @"latency_count";
+NSString* const kPushStatsLatencyP50Ms = @"latency_p50_ms";
+NSString* const kPushStatsLatencyP95Ms = @"latency_p95_ms";
+NSString* const kPushStatsLatencyMaxMs = @"latency_max_ms";
+
+NSString* const kEnrollmentTestPhase = @"phase";
+NSString* const kEnrollmentTestStatus = @"status";
+NSString* const kEnrollmentTestDetail = @"detail";
+NSString* const kEnrollmentTestStatusPassed = @"passed";
+NSString* const kEnrollmentTestStatusFailed = @"failed";
+NSString* const kEnrollmentTestStatusSkipped = @"skipped";
+
+NSString* const kRuleReconcileAdded = @"added";
+NSString* const kRuleReconcileRemoved = @"removed";
+NSString* const kRuleReconcileUnchanged = @"unchanged";
+NSString* const kRuleReconcileError = @"error";
+
+NSString* const kUploadBenchmarkEventsGenerated = @"events_generated";
+NSString* const kUploadBenchmarkEventsAccepted = @"events_accepted";
+NSString* const kUploadBenchmarkRequests = @"requests";
+NSString* const kUploadBenchmarkRequestsFailed = @"requests_failed";
+NSString* const kUploadBenchmarkBytesSent = @"bytes_sent";
+NSString* const kUploadBenchmarkBytesReceived = @"bytes_received";
+NSString* const kUploadBenchmarkElapsedSeconds = @"elapsed_seconds";
+NSString* const kUploadBenchmarkLatencyMedianMs = @"latency_median_ms";
+NSString* const kUploadBenchmarkLatencyP95Ms = @"latency_p95_ms";
+NSString* const kUploadBenchmarkLatencyMaxMs = @"latency_max_ms";
+NSString* const kUploadBenchmarkError = @"error";
+
+NSString* const kSyncCircuitBreakerState = @"state";
+NSString* const kSyncCircuitBreakerConsecutiveFailures = @"consecutive_failures";
+NSString* const kSyncCircuitBreakerRetryAt = @"retry_at";
+
+NSString* const kRuleSourceStatusName = @"name";
+NSString* const kRuleSourceStatusURL = @"url";
+NSString* const kRuleSourceStatusPrecedence = @"precedence";
+NSString* const kRuleSourceStatusSyncInterval = @"sync_interval_seconds";
+NSString* const kRuleSourceStatusLastAttempt = @"last_attempt";
+NSString* const kRuleSourceStatusLastSuccess = @"last_success";
+NSString* const kRuleSourceStatusLastError = @"last_error";
+NSString* const kRuleSourceStatusRulesReceived = @"rules_received";
+NSString* const kRuleSourceStatusNextSync = @"next_sync";
+
+NSString* const kPushHeaderSyncIntervalOverride = @"Santa-Sync-Interval-Seconds";
+NSString* const kPushHeaderSyncIntervalOverrideDuration = @"Santa-Sync-Override-Duration-Seconds";
+NSString* const kPushHeaderType = @"Santa-Push-Type";
+NSString* const kPushTypeSync = @"sync";
+NSString* const kPushTypeCollectDiagnostics = @"collect_diagnostics";
+NSString* const kPushHeaderMessageID = @"Nats-Msg-Id";
+NSString* const kPushHeaderPublishedAt = @"Published-At";
+NSString* const kPushTypeExportDecisions = @"export_decisions";
+NSString* const kPushTypeRotateCredentials = @"rotate_credentials";
+NSString* const kPushTypeReportRules = @"report_rules";
+NSString* const kPushTypeApplyRules = @"apply_rules";
+NSString* const kPushHeaderExportDecisionsStart = @"Santa-Export-Start";
+NSString* const kPushHeaderExportDecisionsEnd = @"Santa-Export-End";
+
 const NSUInteger kDefaultEventBatchSize = 50;
 const NSUInteger kMinimumFullSyncInterval = 60;
 const NSUInteger kDefaultFullSyncInterval = 600;
@@ -26,6 +26,7 @@
 @class SNDNetworkFlowDecision;
 @class SNDProcessFlows;
 @class SNTStoredEvent;
+@class SNTStoredExecutionEvent;
 @class SNTStoredSignalReport;
 
 ///
@@ -52,6 +53,11 @@ typedef NS_ENUM(NSInteger, SNTRuleAddSource) {
                           ruleCleanup:(SNTRuleCleanup)cleanupType
                                source:(SNTRuleAddSource)source
                                 reply:(void (^)(BOOL, NSArray<NSError*>* error))reply;
+// Replace the execution rules from one of the configured RuleSources.
+- (void)databaseRuleReplaceRulesForRuleSource:(NSString*)source
+                                   precedence:(NSInteger)precedence
+                                        rules:(NSArray<SNTRule*>*)rules
+                                        reply:(void (^)(BOOL, NSArray<NSError*>* error))reply;
 - (void)databaseEventsPending:(void (^)(NSArray<SNTStoredEvent*>* events))reply;
 - (void)databaseRemoveEventsWithIDs:(NSArray*)ids;
 - (void)databaseSignalReportsPending:(void (^)(NSArray<SNTStoredSignalReport*>* reports))reply;
@@ -69,6 +75,33 @@ typedef NS_ENUM(NSInteger, SNTRuleAddSource) {
 ///  Syncd Ops
 ///
 - (void)postRuleSyncNotificationForApplication:(NSString*)app reply:(void (^)(void))reply;
+// Return up to limit of the most recent execution decisions made in [start, end], newest first.
+- (void)recentDecisionsFrom:(NSDate*)start
+                         to:(NSDate*)end
+                      limit:(NSUInteger)limit
+                      reply:(void (^)(NSArray<SNTStoredExecutionEvent*>* decisions))reply;
+// Return all execution rules, even if rules are managed centrally. Used to report what a rule
+// reconcile changed and to upload a rule snapshot.
+- (void)executionRulesForReconcile:(void (^)(NSArray<SNTRule*>* rules))reply;
+// Return how many times each allowed binary has executed since the counts were last reset, keyed
+// by SHA-256. See SNTExecutionCounts.
+- (void)executionCounts:(void (^)(NSDictionary<NSString*, NSDictionary*>* counts))reply;
+// Subtract a snapshot returned by executionCounts: once the sync server has accepted it.
+- (void)resetExecutionCounts:(NSDictionary<NSString*, NSDictionary*>*)snapshot;
+// Record the ID of a signed inline rule bundle from a push notification as used until
+// expirationDate. Replies with an error if it was already used or couldn't be recorded, in which
+// case its rules must not be applied.
+- (void)redeemPushRuleBundleID:(NSString*)bundleID
+                expirationDate:(NSDate*)expirationDate
+                         reply:(void (^)(NSError*))reply;
+
+///
+///  Decision database ops
+///
+// Run a single read-only SQL statement against the decision database. See SNTDecisionTable.
+- (void)queryDecisionDatabase:(NSString*)sql
+                        reply:(void (^)(NSArray<NSString*>* columns, NSArray<NSArray*>* rows,
+                                        NSError* error))reply;
 
 ///
 /// Command ops
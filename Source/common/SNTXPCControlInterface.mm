@@ -66,6 +66,11 @@ static NSString* const kSantanetdExtensionBundleID = @"com.northpolesec.santa.ne
       argumentIndex:0
             ofReply:YES];
 
+  [r setClasses:[NSSet setWithObjects:[NSArray class], [SNTStoredExecutionEvent class], nil]
+        forSelector:@selector(recentDecisionsFrom:to:limit:reply:)
+      argumentIndex:0
+            ofReply:YES];
+
   [r setClasses:[NSSet setWithObjects:[NSArray class], [SNTRule class], nil]
         forSelector:@selector
         (databaseRuleAddExecutionRules:
@@ -101,11 +106,47 @@ static NSString* const kSantanetdExtensionBundleID = @"com.northpolesec.santa.ne
       argumentIndex:1
             ofReply:YES];
 
+  [r setClasses:[NSSet setWithObjects:[NSArray class], [SNTRule class], nil]
+        forSelector:@selector(databaseRuleReplaceRulesForRuleSource:precedence:rules:reply:)
+      argumentIndex:2
+            ofReply:NO];
+
+  [r setClasses:[NSSet setWithObjects:[NSArray class], [NSError class], nil]
+        forSelector:@selector(databaseRuleReplaceRulesForRuleSource:precedence:rules:reply:)
+      argumentIndex:1
+            ofReply:YES];
+
   [r setClasses:[NSSet setWithObjects:[NSArray class], [SNTRule class], nil]
         forSelector:@selector(retrieveAllExecutionRules:)
       argumentIndex:0
             ofReply:YES];
 
+  [r setClasses:[NSSet setWithObjects:[NSArray class], [SNTRule class], nil]
+        forSelector:@selector(executionRulesForReconcile:)
+      argumentIndex:0
+            ofReply:YES];
+
+  [r setClasses:[NSSet setWithObjects:[NSDictionary class], [NSString class], [NSNumber class], nil]
+        forSelector:@selector(executionCounts:)
+      argumentIndex:0
+            ofReply:YES];
+
+  [r setClasses:[NSSet setWithObjects:[NSDictionary class], [NSString class], [NSNumber class], nil]
+        forSelector:@selector(resetExecutionCounts:)
+      argumentIndex:0
+            ofReply:NO];
+
+  [r setClasses:[NSSet setWithObjects:[NSArray class], [NSString class], nil]
+        forSelector:@selector(queryDecisionDatabase:reply:)
+      argumentIndex:0
+            ofReply:YES];
+
+  [r setClasses:[NSSet setWithObjects:[NSArray class], [NSString class], [NSNumber class],
+                                      [NSData class], [NSNull class], nil]
+        forSelector:@selector(queryDecisionDatabase:reply:)
+      argumentIndex:1
+            ofReply:YES];
+
   [r setClasses:[NSSet setWithObjects:[NSArray class], [SNTKillResponse class],
                                       [SNTKilledProcess class], nil]
         forSelector:@selector(killProcesses:reply:)
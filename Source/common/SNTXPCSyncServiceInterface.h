@@ -71,6 +71,22 @@
 // and you want to reconnect without waiting for the normal retry backoff.
 - (void)pushNotificationReconnect;
 
+// Return a snapshot of the NATS push client's configuration and connection state, keyed by the
+// kPushDiagnostics* constants. Used by `santactl push diagnose`. If the NATS push client is not
+// active the snapshot only contains kPushDiagnosticsEnabled set to NO.
+- (void)pushNotificationDiagnostics:(void (^)(NSDictionary*))reply;
+
+// Return the state of the circuit breaker guarding requests to the sync server, keyed by the
+// kSyncCircuitBreaker* constants.
+- (void)syncCircuitBreakerStatus:(void (^)(NSDictionary*))reply;
+
+// Return the expiry date of the NATS push server's TLS certificate, as seen on the push client's
+// last successful connection, or nil if it isn't known.
+- (void)pushServerCertificateExpiry:(void (^)(NSDate*))reply;
+
+// Return the state of each configured rule source, keyed by the kRuleSourceStatus* constants.
+- (void)ruleSourcesStatus:(void (^)(NSArray<NSDictionary*>*))reply;
+
 // Check sync server connectivity by making a preflight test request using the syncservice's
 // existing session configuration (auth, certs, headers, proxy). Returns the HTTP status code
 // and a human-readable description. Status 0 indicates a connection error.
@@ -82,6 +98,32 @@
                         reply:(void (^)(NSInteger statusCode, NSString* description,
                                         MOLCertificate* clientCertificate))reply;
 
+// Simulate a fresh enrollment, under a throwaway machine ID, against syncURL: a clean sync
+// preflight, full rule download, initial event upload and, if the server provides push
+// credentials, a push connection. No postflight is sent and nothing is applied to santad. Replies
+// with one dictionary per phase, keyed by the kEnrollmentTest* constants. Used by
+// `santactl enroll-test`.
+- (void)enrollmentTestWithSyncURL:(NSURL*)syncURL
+                      logListener:(NSXPCListenerEndpoint*)logListener
+                            reply:(void (^)(NSArray<NSDictionary*>* phases))reply;
+
+// Make the local rules match the rule snapshot of the sync server at syncURL: a clean sync
+// preflight and a full rule download whose rules replace every local rule, including ones added
+// locally. Replies with a report keyed by the kRuleReconcile* constants. Used by
+// `santactl rule reconcile`.
+- (void)reconcileRulesWithSyncURL:(NSURL*)syncURL
+                      logListener:(NSXPCListenerEndpoint*)logListener
+                            reply:(void (^)(NSDictionary<NSString*, id>* report))reply;
+
+// Upload synthetic execution events to the sync server at syncURL at rate events per second for
+// duration seconds, using the regular event upload path. Replies with a report keyed by the
+// kUploadBenchmark* constants. Used by `santactl bench upload`.
+- (void)benchmarkUploadWithSyncURL:(NSURL*)syncURL
+                              rate:(NSUInteger)rate
+                          duration:(NSUInteger)duration
+                       logListener:(NSXPCListenerEndpoint*)logListener
+                             reply:(void (^)(NSDictionary<NSString*, id>* report))reply;
+
 @end
 
 @interface SNTXPCSyncServiceInterface : NSObject
@@ -15,7 +15,9 @@
 
 #import "Source/common/SNTXPCSyncServiceInterface.h"
 
+#import "Source/common/SNTStoredLaunchItemEvent.h"
 #import "Source/common/SNTStoredNetworkFlowEvent.h"
+#import "Source/common/SNTStoredRuleChangeAuditEvent.h"
 
 @implementation SNTXPCSyncServiceInterface
 
@@ -25,7 +27,9 @@
   [r setClasses:[NSSet setWithObjects:[NSArray class], [SNTStoredEvent class],
                                       [SNTStoredExecutionEvent class],
                                       [SNTStoredFileAccessEvent class],
-                                      [SNTStoredNetworkFlowEvent class], nil]
+                                      [SNTStoredLaunchItemEvent class],
+                                      [SNTStoredNetworkFlowEvent class],
+                                      [SNTStoredRuleChangeAuditEvent class], nil]
         forSelector:@selector(postEventsToSyncServer:reply:)
       argumentIndex:0
             ofReply:NO];
@@ -41,6 +45,39 @@
       argumentIndex:0
             ofReply:NO];
 
+  [r setClasses:[NSSet setWithObjects:[NSDictionary class], [NSArray class], [NSString class],
+                                      [NSNumber class], [NSDate class], nil]
+        forSelector:@selector(pushNotificationDiagnostics:)
+      argumentIndex:0
+            ofReply:YES];
+
+  [r setClasses:[NSSet setWithObjects:[NSDictionary class], [NSString class], [NSNumber class], nil]
+        forSelector:@selector(syncCircuitBreakerStatus:)
+      argumentIndex:0
+            ofReply:YES];
+
+  [r setClasses:[NSSet setWithObjects:[NSArray class], [NSDictionary class], [NSString class],
+                                      [NSNumber class], nil]
+        forSelector:@selector(ruleSourcesStatus:)
+      argumentIndex:0
+            ofReply:YES];
+
+  [r setClasses:[NSSet setWithObjects:[NSArray class], [NSDictionary class], [NSString class], nil]
+        forSelector:@selector(enrollmentTestWithSyncURL:logListener:reply:)
+      argumentIndex:0
+            ofReply:YES];
+
+  [r setClasses:[NSSet setWithObjects:[NSDictionary class], [NSArray class], [NSString class],
+                                      [NSNumber class], nil]
+        forSelector:@selector(reconcileRulesWithSyncURL:logListener:reply:)
+      argumentIndex:0
+            ofReply:YES];
+
+  [r setClasses:[NSSet setWithObjects:[NSDictionary class], [NSString class], [NSNumber class], nil]
+        forSelector:@selector(benchmarkUploadWithSyncURL:rate:duration:logListener:reply:)
+      argumentIndex:0
+            ofReply:YES];
+
   [r setClasses:[NSSet setWithObject:[MOLCertificate class]]
         forSelector:@selector(checkSyncServerStatus:reply:)
       argumentIndex:2
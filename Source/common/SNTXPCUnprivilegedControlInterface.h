@@ -76,6 +76,9 @@ struct RuleCounts {
 - (void)fullSyncLastSuccess:(void (^)(NSDate*))reply;
 - (void)ruleSyncLastSuccess:(void (^)(NSDate*))reply;
 - (void)syncTypeRequired:(void (^)(SNTSyncType))reply;
+// The machine ID santad uses. Sync state is root-only, so a machine ID generated after a conflict
+// is only known to santad.
+- (void)machineID:(void (^)(NSString*))reply;
 - (void)enableBundles:(void (^)(BOOL))reply;
 - (void)enableTransitiveRules:(void (^)(BOOL))reply;
 - (void)removableMediaAction:(void (^)(SNTRemovableMediaAction))reply;
@@ -88,6 +91,14 @@ struct RuleCounts {
 // Whether the daemon holds a (non-revoked) sync-server export config. Sync state is root-only, so
 // unprivileged callers can't read it directly and must ask the daemon.
 - (void)telemetryExportConfigured:(void (^)(BOOL))reply;
+// Whether santad has been granted Full Disk Access.
+- (void)fullDiskAccessGranted:(void (^)(BOOL))reply;
+// A hash of the configuration profile and sync server settings in effect, see
+// -[SNTConfigurator effectiveConfigHash].
+- (void)effectiveConfigHash:(void (^)(NSString*))reply;
+// The configuration profile settings as santad parsed them, see
+// -[SNTConfigurator parsedConfigProfile].
+- (void)parsedConfigProfile:(void (^)(NSDictionary*))reply;
 
 ///
 /// FAA Retrieval ops
@@ -110,6 +121,9 @@ struct RuleCounts {
 ///
 - (void)pushNotificationStatus:(void (^)(SNTPushNotificationStatus))reply;
 - (void)pushNotificationServerAddress:(void (^)(NSString*))reply;
+- (void)syncCircuitBreakerStatus:(void (^)(NSDictionary*))reply;
+- (void)pushServerCertificateExpiry:(void (^)(NSDate*))reply;
+- (void)ruleSourcesStatus:(void (^)(NSArray<NSDictionary*>*))reply;
 
 ///
 ///  Bundle Ops
@@ -141,6 +155,13 @@ struct RuleCounts {
 - (void)checkTemporaryAdminModeAvailable:(void (^)(BOOL available, BOOL alreadyAdmin))reply;
 - (void)temporaryAdminModeSessionResignedActive:(void (^)(NSError*))reply;
 
+///
+/// Allow Once Ops
+///
+/// Verifies a server-issued one-time allow token and, if valid, allows the next
+/// execution of the identity it names.
+- (void)allowOnceWithToken:(NSString*)token reply:(void (^)(NSError*))reply;
+
 ///
 /// Network Extension Ops
 ///
@@ -28,6 +28,18 @@
         forSelector:@selector(syncBundleEvent:relatedEvents:)
       argumentIndex:1
             ofReply:NO];
+
+  [r setClasses:[NSSet setWithObjects:[NSArray class], [NSDictionary class], [NSString class],
+                                      [NSNumber class], nil]
+        forSelector:@selector(ruleSourcesStatus:)
+      argumentIndex:0
+            ofReply:YES];
+
+  [r setClasses:[NSSet setWithObjects:[NSArray class], [NSData class], [NSDate class],
+                                      [NSDictionary class], [NSNumber class], [NSString class], nil]
+        forSelector:@selector(parsedConfigProfile:)
+      argumentIndex:0
+            ofReply:YES];
 }
 
 + (NSXPCInterface*)controlInterface {
@@ -0,0 +1,48 @@
+/// Copyright 2026 North Pole Security, Inc.
+///
+/// Licensed under the Apache License, Version 2.0 (the "License");
+/// you may not use this file except in compliance with the License.
+/// You may obtain a copy of the License at
+///
+///     http://www.apache.org/licenses/LICENSE-2.0
+///
+/// Unless required by applicable law or agreed to in writing, software
+/// distributed under the License is distributed on an "AS IS" BASIS,
+/// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+/// See the License for the specific language governing permissions and
+/// limitations under the License.
+
+#ifndef SANTA_COMMON_SIGNEDRULEBUNDLE_H
+#define SANTA_COMMON_SIGNEDRULEBUNDLE_H
+
+#import <Foundation/Foundation.h>
+
+namespace santa {
+
+// A signed rule bundle is a JSON object of the form:
+//   {"algorithm": "ed25519", "payload": "<base64>", "signature": "<base64>"}
+// where the signature is computed over the raw (decoded) payload bytes.
+extern NSString* const kSignedRuleBundleAlgorithmKey;
+extern NSString* const kSignedRuleBundlePayloadKey;
+extern NSString* const kSignedRuleBundleSignatureKey;
+
+// Reads an Ed25519 key from a file containing its base64 encoding. Private
+// keys may be either the 32 byte seed or the 64 byte expanded form; the
+// returned private key is always 64 bytes. Public keys must be 32 bytes.
+NSData* ReadEd25519PrivateKeyFile(NSString* path, NSError** error);
+NSData* ReadEd25519PublicKeyFile(NSString* path, NSError** error);
+
+// Returns the serialized bundle for the given payload, or nil on error.
+NSData* CreateSignedRuleBundle(NSData* payload, NSData* private_key, NSError** error);
+
+// Returns YES if the data looks like a signed rule bundle. This does not
+// perform any verification.
+BOOL IsSignedRuleBundle(NSData* data);
+
+// Verifies the bundle signature with the given public key and returns the
+// payload. Returns nil if the bundle is malformed or the signature is invalid.
+NSData* VerifySignedRuleBundle(NSData* bundle, NSData* public_key, NSError** error);
+
+}  // namespace santa
+
+#endif  // SANTA_COMMON_SIGNEDRULEBUNDLE_H
@@ -0,0 +1,170 @@
+/// Copyright 2026 North Pole Security, Inc.
+///
+/// Licensed under the Apache License, Version 2.0 (the "License");
+/// you may not use this file except in compliance with the License.
+/// You may obtain a copy of the License at
+///
+///     http://www.apache.org/licenses/LICENSE-2.0
+///
+/// Unless required by applicable law or agreed to in writing, software
+/// distributed under the License is distributed on an "AS IS" BASIS,
+/// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+/// See the License for the specific language governing permissions and
+/// limitations under the License.
+
+#include "Source/common/SignedRuleBundle.h"
+
+#include <openssl/curve25519.h>
+
+#include <cstdint>
+
+#import "Source/common/SNTError.h"
+
+namespace santa {
+
+NSString* const kSignedRuleBundleAlgorithmKey = @"algorithm";
+NSString* const kSignedRuleBundlePayloadKey = @"payload";
+NSString* const kSignedRuleBundleSignatureKey = @"signature";
+
+static NSString* const kSignedRuleBundleAlgorithmEd25519 = @"ed25519";
+
+static NSData* ReadBase64KeyFile(NSString* path, NSError** error) {
+  NSError* readError;
+  NSString* contents = [NSString stringWithContentsOfFile:path
+                                                 encoding:NSUTF8StringEncoding
+                                                    error:&readError];
+  if (!contents) {
+    [SNTError populateError:error
+                 withFormat:@"Failed to read key file %@: %@", path,
+                            readError.localizedDescription];
+    return nil;
+  }
+
+  NSString* trimmed =
+      [contents stringByTrimmingCharactersInSet:[NSCharacterSet whitespaceAndNewlineCharacterSet]];
+  NSData* key = [[NSData alloc] initWithBase64EncodedString:trimmed options:0];
+  if (!key) {
+    [SNTError populateError:error withFormat:@"Key file %@ is not valid base64", path];
+    return nil;
+  }
+
+  return key;
+}
+
+NSData* ReadEd25519PrivateKeyFile(NSString* path, NSError** error) {
+  NSData* key = ReadBase64KeyFile(path, error);
+  if (!key) {
+    return nil;
+  }
+
+  if (key.length == ED25519_PRIVATE_KEY_LEN) {
+    return key;
+  } else if (key.length == 32) {
+    // Expand the seed into the full private key
+    uint8_t public_key[ED25519_PUBLIC_KEY_LEN];
+    uint8_t private_key[ED25519_PRIVATE_KEY_LEN];
+    ED25519_keypair_from_seed(public_key, private_key, static_cast<const uint8_t*>(key.bytes));
+    return [NSData dataWithBytes:private_key length:sizeof(private_key)];
+  }
+
+  [SNTError populateError:error
+               withFormat:@"Invalid Ed25519 private key length in %@: %lu", path, key.length];
+  return nil;
+}
+
+NSData* ReadEd25519PublicKeyFile(NSString* path, NSError** error) {
+  NSData* key = ReadBase64KeyFile(path, error);
+  if (!key) {
+    return nil;
+  }
+
+  if (key.length != ED25519_PUBLIC_KEY_LEN) {
+    [SNTError populateError:error
+                 withFormat:@"Invalid Ed25519 public key length in %@: %lu", path, key.length];
+    return nil;
+  }
+
+  return key;
+}
+
+NSData* CreateSignedRuleBundle(NSData* payload, NSData* private_key, NSError** error) {
+  if (private_key.length != ED25519_PRIVATE_KEY_LEN) {
+    [SNTError populateError:error withFormat:@"Invalid Ed25519 private key"];
+    return nil;
+  }
+
+  uint8_t signature[ED25519_SIGNATURE_LEN];
+  if (ED25519_sign(signature, static_cast<const uint8_t*>(payload.bytes), payload.length,
+                   static_cast<const uint8_t*>(private_key.bytes)) != 1) {
+    [SNTError populateError:error withFormat:@"Failed to sign rule bundle"];
+    return nil;
+  }
+
+  NSDictionary* bundle = @{
+    kSignedRuleBundleAlgorithmKey : kSignedRuleBundleAlgorithmEd25519,
+    kSignedRuleBundlePayloadKey : [payload base64EncodedStringWithOptions:0],
+    kSignedRuleBundleSignatureKey : [[NSData dataWithBytes:signature length:sizeof(signature)]
+        base64EncodedStringWithOptions:0],
+  };
+
+  return [NSJSONSerialization dataWithJSONObject:bundle
+                                         options:NSJSONWritingPrettyPrinted | NSJSONWritingSortedKeys
+                                           error:error];
+}
+
+static NSDictionary* ParseBundle(NSData* data) {
+  if (!data) {
+    return nil;
+  }
+  NSDictionary* bundle = [NSJSONSerialization JSONObjectWithData:data options:0 error:nil];
+  if (![bundle isKindOfClass:[NSDictionary class]] ||
+      ![bundle[kSignedRuleBundlePayloadKey] isKindOfClass:[NSString class]] ||
+      ![bundle[kSignedRuleBundleSignatureKey] isKindOfClass:[NSString class]]) {
+    return nil;
+  }
+  return bundle;
+}
+
+BOOL IsSignedRuleBundle(NSData* data) {
+  return ParseBundle(data) != nil;
+}
+
+NSData* VerifySignedRuleBundle(NSData* data, NSData* public_key, NSError** error) {
+  if (public_key.length != ED25519_PUBLIC_KEY_LEN) {
+    [SNTError populateError:error withFormat:@"Invalid Ed25519 public key"];
+    return nil;
+  }
+
+  NSDictionary* bundle = ParseBundle(data);
+  if (!bundle) {
+    [SNTError populateError:error withFormat:@"Not a signed rule bundle"];
+    return nil;
+  }
+
+  if (![bundle[kSignedRuleBundleAlgorithmKey] isEqual:kSignedRuleBundleAlgorithmEd25519]) {
+    [SNTError populateError:error
+                 withFormat:@"Unsupported rule bundle algorithm: %@",
+                            bundle[kSignedRuleBundleAlgorithmKey]];
+    return nil;
+  }
+
+  NSData* payload = [[NSData alloc] initWithBase64EncodedString:bundle[kSignedRuleBundlePayloadKey]
+                                                        options:0];
+  NSData* signature =
+      [[NSData alloc] initWithBase64EncodedString:bundle[kSignedRuleBundleSignatureKey] options:0];
+  if (!payload || signature.length != ED25519_SIGNATURE_LEN) {
+    [SNTError populateError:error withFormat:@"Malformed signed rule bundle"];
+    return nil;
+  }
+
+  if (ED25519_verify(static_cast<const uint8_t*>(payload.bytes), payload.length,
+                     static_cast<const uint8_t*>(signature.bytes),
+                     static_cast<const uint8_t*>(public_key.bytes)) != 1) {
+    [SNTError populateError:error withFormat:@"Rule bundle signature verification failed"];
+    return nil;
+  }
+
+  return payload;
+}
+
+}  // namespace santa
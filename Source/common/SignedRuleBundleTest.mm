@@ -0,0 +1,144 @@
+/// Copyright 2026 North Pole Security, Inc.
+///
+/// Licensed under the Apache License, Version 2.0 (the "License");
+/// you may not use this file except in compliance with the License.
+/// You may obtain a copy of the License at
+///
+///     http://www.apache.org/licenses/LICENSE-2.0
+///
+/// Unless required by applicable law or agreed to in writing, software
+/// distributed under the License is distributed on an "AS IS" BASIS,
+/// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+/// See the License for the specific language governing permissions and
+/// limitations under the License.
+
+#import <XCTest/XCTest.h>
+
+#include <openssl/curve25519.h>
+
+#include "Source/common/SignedRuleBundle.h"
+
+using santa::CreateSignedRuleBundle;
+using santa::IsSignedRuleBundle;
+using santa::ReadEd25519PrivateKeyFile;
+using santa::ReadEd25519PublicKeyFile;
+using santa::VerifySignedRuleBundle;
+
+@interface SignedRuleBundleTest : XCTestCase
+@property NSData* publicKey;
+@property NSData* privateKey;
+@property NSData* payload;
+@end
+
+@implementation SignedRuleBundleTest
+
+- (void)setUp {
+  uint8_t publicKey[ED25519_PUBLIC_KEY_LEN];
+  uint8_t privateKey[ED25519_PRIVATE_KEY_LEN];
+  ED25519_keypair(publicKey, privateKey);
+  self.publicKey = [NSData dataWithBytes:publicKey length:sizeof(publicKey)];
+  self.privateKey = [NSData dataWithBytes:privateKey length:sizeof(privateKey)];
+
+  self.payload = [NSJSONSerialization dataWithJSONObject:@{
+    @"rules" : @[ @{
+      @"policy" : @"BLOCKLIST",
+      @"rule_type" : @"BINARY",
+      @"identifier" : @"84de9c61777ca36b13228e2446d53e966096e78db7a72c632b5c185b2ffe68a6",
+    } ]
+  }
+                                                 options:0
+                                                   error:nil];
+}
+
+- (void)testRoundTrip {
+  NSError* err;
+  NSData* bundle = CreateSignedRuleBundle(self.payload, self.privateKey, &err);
+  XCTAssertNotNil(bundle);
+  XCTAssertNil(err);
+  XCTAssertTrue(IsSignedRuleBundle(bundle));
+  XCTAssertFalse(IsSignedRuleBundle(self.payload));
+
+  NSData* payload = VerifySignedRuleBundle(bundle, self.publicKey, &err);
+  XCTAssertNil(err);
+  XCTAssertEqualObjects(payload, self.payload);
+}
+
+- (void)testTamperedPayloadRejected {
+  NSData* bundle = CreateSignedRuleBundle(self.payload, self.privateKey, nil);
+  NSMutableDictionary* dict = [[NSJSONSerialization JSONObjectWithData:bundle
+                                                               options:0
+                                                                 error:nil] mutableCopy];
+
+  NSMutableData* tampered = [self.payload mutableCopy];
+  ((uint8_t*)tampered.mutableBytes)[tampered.length / 2] ^= 0x1;
+  dict[@"payload"] = [tampered base64EncodedStringWithOptions:0];
+  NSData* tamperedBundle = [NSJSONSerialization dataWithJSONObject:dict options:0 error:nil];
+
+  NSError* err;
+  XCTAssertNil(VerifySignedRuleBundle(tamperedBundle, self.publicKey, &err));
+  XCTAssertNotNil(err);
+}
+
+- (void)testWrongKeyRejected {
+  NSData* bundle = CreateSignedRuleBundle(self.payload, self.privateKey, nil);
+
+  uint8_t otherPublicKey[ED25519_PUBLIC_KEY_LEN];
+  uint8_t otherPrivateKey[ED25519_PRIVATE_KEY_LEN];
+  ED25519_keypair(otherPublicKey, otherPrivateKey);
+
+  NSError* err;
+  XCTAssertNil(VerifySignedRuleBundle(
+      bundle, [NSData dataWithBytes:otherPublicKey length:sizeof(otherPublicKey)], &err));
+  XCTAssertNotNil(err);
+}
+
+- (void)testMalformedBundleRejected {
+  NSError* err;
+  XCTAssertNil(VerifySignedRuleBundle(self.payload, self.publicKey, &err));
+  XCTAssertNotNil(err);
+
+  err = nil;
+  NSData* badAlgorithm = [NSJSONSerialization
+      dataWithJSONObject:@{@"algorithm" : @"rsa", @"payload" : @"", @"signature" : @""}
+                 options:0
+                   error:nil];
+  XCTAssertNil(VerifySignedRuleBundle(badAlgorithm, self.publicKey, &err));
+  XCTAssertNotNil(err);
+}
+
+- (void)testReadKeyFiles {
+  NSString* dir = [NSTemporaryDirectory() stringByAppendingPathComponent:[NSUUID UUID].UUIDString];
+  [[NSFileManager defaultManager] createDirectoryAtPath:dir
+                            withIntermediateDirectories:YES
+                                             attributes:nil
+                                                  error:nil];
+
+  // Private keys are accepted as a 32 byte seed
+  NSString* privPath = [dir stringByAppendingPathComponent:@"key"];
+  NSString* pubPath = [dir stringByAppendingPathComponent:@"key.pub"];
+  NSData* seed = [self.privateKey subdataWithRange:NSMakeRange(0, 32)];
+  [[[seed base64EncodedStringWithOptions:0] stringByAppendingString:@"\n"]
+      writeToFile:privPath
+       atomically:YES
+         encoding:NSUTF8StringEncoding
+            error:nil];
+  [[self.publicKey base64EncodedStringWithOptions:0] writeToFile:pubPath
+                                                      atomically:YES
+                                                        encoding:NSUTF8StringEncoding
+                                                           error:nil];
+
+  NSError* err;
+  NSData* privateKey = ReadEd25519PrivateKeyFile(privPath, &err);
+  XCTAssertEqualObjects(privateKey, self.privateKey);
+  NSData* publicKey = ReadEd25519PublicKeyFile(pubPath, &err);
+  XCTAssertEqualObjects(publicKey, self.publicKey);
+  XCTAssertNil(err);
+
+  // A private key is not a valid public key
+  XCTAssertNil(ReadEd25519PublicKeyFile(privPath, &err));
+  XCTAssertNotNil(err);
+
+  [[NSFileManager defaultManager] removeItemAtPath:dir error:nil];
+}
+
+@end
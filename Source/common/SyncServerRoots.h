@@ -0,0 +1,31 @@
+/// Copyright 2026 North Pole Security, Inc.
+///
+/// Licensed under the Apache License, Version 2.0 (the "License");
+/// you may not use this file except in compliance with the License.
+/// You may obtain a copy of the License at
+///
+///     http://www.apache.org/licenses/LICENSE-2.0
+///
+/// Unless required by applicable law or agreed to in writing, software
+/// distributed under the License is distributed on an "AS IS" BASIS,
+/// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+/// See the License for the specific language governing permissions and
+/// limitations under the License.
+
+#ifndef SANTA_COMMON_SYNCSERVERROOTS_H
+#define SANTA_COMMON_SYNCSERVERROOTS_H
+
+#import <Foundation/Foundation.h>
+
+#import "Source/common/MOLAuthenticatingURLSession.h"
+
+namespace santa {
+
+// Configure the roots the session uses to verify the sync server, as the sync service does: the
+// pinned roots for pinned domains, otherwise the configured SyncServerAuthRootsFile or
+// SyncServerAuthRootsData. If none apply, the trusted system roots are left in place.
+void ConfigureSyncServerRoots(MOLAuthenticatingURLSession* session, NSURL* syncBaseURL);
+
+}  // namespace santa
+
+#endif  // SANTA_COMMON_SYNCSERVERROOTS_H
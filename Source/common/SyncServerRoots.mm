@@ -0,0 +1,35 @@
+/// Copyright 2026 North Pole Security, Inc.
+///
+/// Licensed under the Apache License, Version 2.0 (the "License");
+/// you may not use this file except in compliance with the License.
+/// You may obtain a copy of the License at
+///
+///     http://www.apache.org/licenses/LICENSE-2.0
+///
+/// Unless required by applicable law or agreed to in writing, software
+/// distributed under the License is distributed on an "AS IS" BASIS,
+/// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+/// See the License for the specific language governing permissions and
+/// limitations under the License.
+
+#import "Source/common/SyncServerRoots.h"
+
+#import "Source/common/Pinning.h"
+#import "Source/common/SNTConfigurator.h"
+
+namespace santa {
+
+void ConfigureSyncServerRoots(MOLAuthenticatingURLSession* session, NSURL* syncBaseURL) {
+  SNTConfigurator* config = [SNTConfigurator configurator];
+  if (IsDomainPinned(syncBaseURL)) {
+#ifndef DEBUG
+    session.serverRootsPemString = PinnedCertPEMs();
+#endif
+  } else if ([config syncServerAuthRootsFile]) {
+    session.serverRootsPemFile = [config syncServerAuthRootsFile];
+  } else if ([config syncServerAuthRootsData]) {
+    session.serverRootsPemData = [config syncServerAuthRootsData];
+  }
+}
+
+}  // namespace santa
@@ -0,0 +1,54 @@
+/// Copyright 2026 North Pole Security, Inc.
+///
+/// Licensed under the Apache License, Version 2.0 (the "License");
+/// you may not use this file except in compliance with the License.
+/// You may obtain a copy of the License at
+///
+///     http://www.apache.org/licenses/LICENSE-2.0
+///
+/// Unless required by applicable law or agreed to in writing, software
+/// distributed under the License is distributed on an "AS IS" BASIS,
+/// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+/// See the License for the specific language governing permissions and
+/// limitations under the License.
+
+
+#ifndef SANTA_COMMON_FAA_WATCHITEMMATCHER_H
+#define SANTA_COMMON_FAA_WATCHITEMMATCHER_H
+
+#include <EndpointSecurity/EndpointSecurity.h>
+#import <Foundation/Foundation.h>
+
+#include <string>
+
+#import "Source/common/SNTCommonEnums.h"
+#include "Source/common/faa/WatchItemPolicy.h"
+
+namespace santa {
+
+// Returns a hex encoded SHA-256 of the process' leaf signing certificate, or nil.
+using CertificateHashBlock = NSString* (^)(void);
+
+// Returns whether the process executable resolves to the same file as the
+// given policy binary path.
+using ResolvedPathMatchesBlock = bool (^)(const std::string& policy_path);
+
+// Returns whether `es_proc` satisfies every attribute set on `policy_proc`.
+// `cert_hash` is only called if the policy process has a certificate hash.
+// `resolved_path_matches` is only called if the policy binary path does not
+// match the executable path literally and may be nil.
+//
+// Note: The validity of the code signature is not checked here. Callers must
+// decide how to treat invalid signatures for the policy as a whole.
+bool WatchItemProcessMatches(const WatchItemProcess& policy_proc, const es_process_t* es_proc,
+                             CertificateHashBlock cert_hash,
+                             ResolvedPathMatchesBlock resolved_path_matches);
+
+// Turns whether an access matched a policy (one of its processes for data
+// policies, one of its paths for process policies) into a decision, applying
+// the policy's rule type and audit-only options.
+FileAccessPolicyDecision DecisionForPolicyMatch(const WatchItemPolicyBase& policy, bool matched);
+
+}  // namespace santa
+
+#endif  // SANTA_COMMON_FAA_WATCHITEMMATCHER_H
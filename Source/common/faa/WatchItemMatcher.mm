@@ -0,0 +1,134 @@
+/// Copyright 2026 North Pole Security, Inc.
+///
+/// Licensed under the Apache License, Version 2.0 (the "License");
+/// you may not use this file except in compliance with the License.
+/// You may obtain a copy of the License at
+///
+///     http://www.apache.org/licenses/LICENSE-2.0
+///
+/// Unless required by applicable law or agreed to in writing, software
+/// distributed under the License is distributed on an "AS IS" BASIS,
+/// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+/// See the License for the specific language governing permissions and
+/// limitations under the License.
+
+
+#include "Source/common/faa/WatchItemMatcher.h"
+
+#include <Kernel/kern/cs_blobs.h>
+
+#include <cstring>
+#include <string_view>
+
+namespace santa {
+
+bool WatchItemProcessMatches(const WatchItemProcess& policy_proc, const es_process_t* es_proc,
+                             CertificateHashBlock cert_hash,
+                             ResolvedPathMatchesBlock resolved_path_matches) {
+  if (es_proc->codesigning_flags & CS_SIGNED) {
+    // Check whether or not the process is a platform binary if specified by the policy.
+    if (policy_proc.platform_binary && !es_proc->is_platform_binary) {
+      return false;
+    }
+
+    // If the policy contains a team ID, check that the instigating process
+    // also has a team ID and matches the policy.
+    if (!policy_proc.team_id.empty() &&
+        (!es_proc->team_id.data || (policy_proc.team_id != es_proc->team_id.data))) {
+      // We expected a team ID to match against, but the process didn't have one.
+      return false;
+    }
+
+    // SigningID checks
+    if (!policy_proc.signing_id.empty()) {
+      if (!es_proc->signing_id.data) {
+        // Policy has SID set, but process has no SID
+        return false;
+      }
+
+      if (policy_proc.signing_id_wildcard_pos != std::string::npos) {
+        if (!policy_proc.platform_binary && policy_proc.team_id.empty()) {
+          // Policy SID is a prefix but neither Platform Binary nor Team ID were set
+          // Note: Config parsing should have ensured this isn't possible, but the runtime check
+          // here is meant as a fallback.
+          return false;
+        }
+
+        std::string_view sid_view = std::string_view(policy_proc.signing_id);
+        std::string_view prefix = sid_view.substr(0, policy_proc.signing_id_wildcard_pos);
+        std::string_view suffix = sid_view.substr(policy_proc.signing_id_wildcard_pos + 1);
+
+        // Skip comparison if the proc SID isn't long enough
+        if (es_proc->signing_id.length < (prefix.length() + suffix.length())) {
+          return false;
+        }
+
+        // Check the proc SID matches the policy SID prefix/suffix parts
+        if (strncmp(es_proc->signing_id.data, prefix.data(), prefix.length()) != 0 ||
+            strncmp(es_proc->signing_id.data + (es_proc->signing_id.length - suffix.length()),
+                    suffix.data(), suffix.length()) != 0) {
+          return false;
+        }
+      } else if (policy_proc.signing_id != es_proc->signing_id.data) {
+        // Policy SID didn't match process
+        return false;
+      }
+    }
+
+    // Check if the instigating process has an allowed CDHash
+    if (policy_proc.cdhash.size() == CS_CDHASH_LEN &&
+        std::memcmp(policy_proc.cdhash.data(), es_proc->cdhash, CS_CDHASH_LEN) != 0) {
+      return false;
+    }
+
+    // Check if the instigating process has an allowed certificate hash
+    if (!policy_proc.certificate_sha256.empty()) {
+      NSString* result = cert_hash();
+      if (!result || policy_proc.certificate_sha256 != [result UTF8String]) {
+        return false;
+      }
+    }
+  } else {
+    // If the process isn't signed, ensure the policy doesn't contain any
+    // attributes that require a signature
+    if (!policy_proc.team_id.empty() || !policy_proc.signing_id.empty() ||
+        policy_proc.cdhash.size() == CS_CDHASH_LEN || !policy_proc.certificate_sha256.empty()) {
+      return false;
+    }
+  }
+
+  // Check if the instigating process path opening the file is allowed
+  if (policy_proc.binary_path.length() > 0 &&
+      policy_proc.binary_path != es_proc->executable->path.data &&
+      !(resolved_path_matches && resolved_path_matches(policy_proc.binary_path))) {
+    return false;
+  }
+
+  return true;
+}
+
+FileAccessPolicyDecision DecisionForPolicyMatch(const WatchItemPolicyBase& policy, bool matched) {
+  FileAccessPolicyDecision decision =
+      matched ? FileAccessPolicyDecision::kAllowed : FileAccessPolicyDecision::kDenied;
+
+  // If the RuleType option was configured to contain a list of denied
+  // processes or denied paths, the decision should be inverted from allowed
+  // to denied or vice versa. Note that this inversion must be made prior to
+  // checking the policy's audit-only flag.
+  if (policy.rule_type == WatchItemRuleType::kPathsWithDeniedProcesses ||
+      policy.rule_type == WatchItemRuleType::kProcessesWithDeniedPaths) {
+    if (decision == FileAccessPolicyDecision::kAllowed) {
+      decision = FileAccessPolicyDecision::kDenied;
+    } else {
+      decision = FileAccessPolicyDecision::kAllowed;
+    }
+  }
+
+  if (decision == FileAccessPolicyDecision::kDenied && policy.audit_only) {
+    decision = FileAccessPolicyDecision::kAllowedAuditOnly;
+  }
+
+  return decision;
+}
+
+}  // namespace santa
@@ -0,0 +1,121 @@
+/// Copyright 2026 North Pole Security, Inc.
+///
+/// Licensed under the Apache License, Version 2.0 (the "License");
+/// you may not use this file except in compliance with the License.
+/// You may obtain a copy of the License at
+///
+///     http://www.apache.org/licenses/LICENSE-2.0
+///
+/// Unless required by applicable law or agreed to in writing, software
+/// distributed under the License is distributed on an "AS IS" BASIS,
+/// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+/// See the License for the specific language governing permissions and
+/// limitations under the License.
+
+
+#include "Source/common/faa/WatchItemMatcher.h"
+
+#include <EndpointSecurity/EndpointSecurity.h>
+#import <Foundation/Foundation.h>
+#include <Kernel/kern/cs_blobs.h>
+#import <XCTest/XCTest.h>
+
+#include "Source/common/TestUtils.h"
+#include "Source/common/faa/WatchItemPolicy.h"
+
+using santa::DataWatchItemPolicy;
+using santa::DecisionForPolicyMatch;
+using santa::WatchItemPathType;
+using santa::WatchItemProcess;
+using santa::WatchItemProcessMatches;
+using santa::WatchItemRuleType;
+
+@interface WatchItemMatcherTest : XCTestCase
+@end
+
+@implementation WatchItemMatcherTest
+
+- (void)testWatchItemProcessMatchesOnlyHashesCertificateWhenNeeded {
+  es_file_t esFile = MakeESFile("/usr/bin/foo");
+  es_process_t esProc = MakeESProcess(&esFile);
+  esProc.codesigning_flags = CS_SIGNED | CS_VALID;
+  esProc.team_id = MakeESStringToken("ABCDE12345");
+
+  __block int certHashCalls = 0;
+  NSString* (^certHash)(void) = ^NSString* {
+    certHashCalls++;
+    return @"abc123";
+  };
+
+  WatchItemProcess teamIDProc("", "", "ABCDE12345", {}, "", false);
+  XCTAssertTrue(WatchItemProcessMatches(teamIDProc, &esProc, certHash, nil));
+  XCTAssertEqual(certHashCalls, 0);
+
+  WatchItemProcess certProc("", "", "", {}, "abc123", false);
+  XCTAssertTrue(WatchItemProcessMatches(certProc, &esProc, certHash, nil));
+  XCTAssertEqual(certHashCalls, 1);
+
+  certProc.certificate_sha256 = "def456";
+  XCTAssertFalse(WatchItemProcessMatches(certProc, &esProc, certHash, nil));
+
+  // Unsigned processes never match signature attributes.
+  esProc.codesigning_flags = 0;
+  XCTAssertFalse(WatchItemProcessMatches(teamIDProc, &esProc, certHash, nil));
+}
+
+- (void)testWatchItemProcessMatchesFallsBackToResolvedPath {
+  es_file_t esFile = MakeESFile("/private/tmp/foo");
+  es_process_t esProc = MakeESProcess(&esFile);
+
+  WatchItemProcess pathProc("/tmp/foo", "", "", {}, "", false);
+  XCTAssertFalse(WatchItemProcessMatches(pathProc, &esProc, nil, nil));
+
+  __block std::string resolvedPolicyPath;
+  XCTAssertTrue(WatchItemProcessMatches(pathProc, &esProc, nil,
+                                        ^bool(const std::string& policy_path) {
+                                          resolvedPolicyPath = policy_path;
+                                          return true;
+                                        }));
+  XCTAssertEqual(resolvedPolicyPath, "/tmp/foo");
+
+  // The resolver is not consulted for literal matches.
+  pathProc.binary_path = "/private/tmp/foo";
+  XCTAssertTrue(WatchItemProcessMatches(pathProc, &esProc, nil, ^bool(const std::string&) {
+    XCTFail(@"Unexpected resolved path lookup");
+    return false;
+  }));
+}
+
+- (void)testDecisionForPolicyMatch {
+  struct {
+    WatchItemRuleType ruleType;
+    bool auditOnly;
+    bool matched;
+    FileAccessPolicyDecision want;
+  } tests[] = {
+      {WatchItemRuleType::kPathsWithAllowedProcesses, false, true,
+       FileAccessPolicyDecision::kAllowed},
+      {WatchItemRuleType::kPathsWithAllowedProcesses, false, false,
+       FileAccessPolicyDecision::kDenied},
+      {WatchItemRuleType::kPathsWithAllowedProcesses, true, false,
+       FileAccessPolicyDecision::kAllowedAuditOnly},
+      {WatchItemRuleType::kPathsWithDeniedProcesses, false, true,
+       FileAccessPolicyDecision::kDenied},
+      {WatchItemRuleType::kPathsWithDeniedProcesses, true, true,
+       FileAccessPolicyDecision::kAllowedAuditOnly},
+      {WatchItemRuleType::kPathsWithDeniedProcesses, false, false,
+       FileAccessPolicyDecision::kAllowed},
+      {WatchItemRuleType::kProcessesWithAllowedPaths, false, false,
+       FileAccessPolicyDecision::kDenied},
+      {WatchItemRuleType::kProcessesWithDeniedPaths, false, true,
+       FileAccessPolicyDecision::kDenied},
+  };
+
+  for (const auto& test : tests) {
+    DataWatchItemPolicy policy("rule", "v1", "/foo", WatchItemPathType::kLiteral, false,
+                               test.auditOnly, test.ruleType);
+    XCTAssertEqual(DecisionForPolicyMatch(policy, test.matched), test.want);
+  }
+}
+
+@end
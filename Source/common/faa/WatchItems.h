@@ -36,6 +36,8 @@
 
 extern NSString* const kWatchItemConfigKeyVersion;
 extern NSString* const kWatchItemConfigKeyWatchItems;
+extern NSString* const kWatchItemConfigKeyPolicies;
+extern NSString* const kWatchItemConfigKeyPoliciesEnabled;
 extern NSString* const kWatchItemConfigKeyPaths;
 extern NSString* const kWatchItemConfigKeyPathsPath;
 extern NSString* const kWatchItemConfigKeyPathsIsPrefix;
@@ -90,6 +92,22 @@ using LookupPolicyBlock =
 using IterateTargetsBlock = void (^)(LookupPolicyBlock);
 using FindPoliciesForTargetsBlock = void (^)(IterateTargetsBlock);
 
+// Records a concrete path that more than one data watch item resolved to while
+// building a DataWatchItems object. Only `kept_rule` is used for lookups.
+struct DataWatchItemOverlap {
+  enum class Kind {
+    // A path from a different rule was dropped in favor of a more specific one
+    kShadowed,
+    // A path was already covered by another path from the same rule
+    kRedundant,
+  };
+
+  Kind kind;
+  std::string path;
+  std::string kept_rule;
+  std::string dropped_rule;
+};
+
 class DataWatchItems {
  public:
   DataWatchItems()
@@ -109,16 +127,22 @@ class DataWatchItems {
   friend void swap(DataWatchItems& first, DataWatchItems& second) {
     std::swap(first.tree_, second.tree_);
     std::swap(first.paths_, second.paths_);
+    std::swap(first.overlaps_, second.overlaps_);
   }
 
+  // Expands and inserts all data policies. When multiple policies resolve to
+  // the same concrete path, the most specific one is kept deterministically. A
+  // prefix path that loses to a literal path still applies beneath it.
   bool Build(SetSharedDataWatchItemPolicy data_policies);
   size_t Count() const { return paths_.size(); }
+  const std::vector<DataWatchItemOverlap>& Overlaps() const { return overlaps_; }
 
   void FindPolicies(IterateTargetsBlock iterateTargetsBlock) const;
 
  private:
   std::unique_ptr<santa::PrefixTree<std::shared_ptr<DataWatchItemPolicy>>> tree_;
   SetPairPathAndType paths_;
+  std::vector<DataWatchItemOverlap> overlaps_;
 };
 
 class ProcessWatchItems {
@@ -28,6 +28,7 @@
 #include <optional>
 #include <set>
 #include <string>
+#include <string_view>
 #include <utility>
 #include <variant>
 #include <vector>
@@ -39,11 +40,14 @@
 #import "Source/common/String.h"
 #import "Source/common/Unit.h"
 #include "Source/common/faa/WatchItemPolicy.h"
+#include "absl/container/flat_hash_map.h"
 
 NSString* const kWatchItemConfigKeyVersion = @"Version";
 NSString* const kWatchItemConfigKeyEventDetailURL = @"EventDetailURL";
 NSString* const kWatchItemConfigKeyEventDetailText = @"EventDetailText";
 NSString* const kWatchItemConfigKeyWatchItems = @"WatchItems";
+NSString* const kWatchItemConfigKeyPolicies = @"Policies";
+NSString* const kWatchItemConfigKeyPoliciesEnabled = @"Enabled";
 NSString* const kWatchItemConfigKeyPaths = @"Paths";
 NSString* const kWatchItemConfigKeyPathsPath = @"Path";
 NSString* const kWatchItemConfigKeyPathsIsPrefix = @"IsPrefix";
@@ -635,6 +639,40 @@ bool IsWatchItemNameValid(id key, NSError** err) {
   return true;
 }
 
+/// Parse every rule in the given WatchItems dictionary. When `policy_name` is
+/// set, rule names are scoped to that policy. Returns the number of rules that
+/// were successfully parsed. Invalid rules are logged and skipped.
+static uint64_t ParseWatchItems(NSDictionary* watch_items, std::string_view policy_version,
+                                NSString* policy_name, SetSharedDataWatchItemPolicy* data_policies,
+                                SetSharedProcessWatchItemPolicy* proc_policies, NSError** err) {
+  uint64_t count = 0;
+  for (id key in watch_items) {
+    if (!IsWatchItemNameValid(key, err)) {
+      LOGE(@"Ignoring file access rule '%@': Invalid name: %@", key,
+           (err && *err) ? (*err).localizedDescription : @"Unknown failure");
+      continue;
+    }
+
+    if (![watch_items[key] isKindOfClass:[NSDictionary class]]) {
+      LOGE(@"Ignoring file access rule '%@'. Value type must be a dictionary (got %@)", key,
+           NSStringFromClass([watch_items[key] class]));
+      continue;
+    }
+
+    NSString* name = policy_name ? [NSString stringWithFormat:@"%@:%@", policy_name, key] : key;
+    if (!ParseConfigSingleWatchItem(name, policy_version, watch_items[key], data_policies,
+                                    proc_policies, err)) {
+      LOGE(@"Ignoring file access rule '%@': %@", name,
+           (err && *err) ? (*err).localizedDescription : @"Unknown failure");
+      continue;
+    }
+
+    count++;
+  }
+
+  return count;
+}
+
 bool ParseConfig(NSDictionary* config, SetSharedDataWatchItemPolicy* data_policies,
                  SetSharedProcessWatchItemPolicy* proc_policies, uint64_t* rules_loaded,
                  NSError** err) {
@@ -669,30 +707,63 @@ bool ParseConfig(NSDictionary* config, SetSharedDataWatchItemPolicy* data_polici
     return false;
   }
 
-  NSDictionary* watch_items = config[kWatchItemConfigKeyWatchItems];
+  if (config[kWatchItemConfigKeyPolicies] &&
+      ![config[kWatchItemConfigKeyPolicies] isKindOfClass:[NSDictionary class]]) {
+    [SNTError populateError:err
+                 withFormat:@"Top level key '%@' must be a dictionary", kWatchItemConfigKeyPolicies];
+    return false;
+  }
 
-  uint64_t count = 0;
-  for (id key in watch_items) {
-    if (!IsWatchItemNameValid(key, err)) {
-      LOGE(@"Ignoring file access rule '%@': Invalid name: %@", key,
+  // Rules defined directly under the top level WatchItems key are parsed first.
+  uint64_t count =
+      ParseWatchItems(config[kWatchItemConfigKeyWatchItems], policy_version, nil, data_policies,
+                      proc_policies, err);
+
+  // Named policies are then merged in sorted name order so that the resulting
+  // set of rules is deterministic regardless of dictionary ordering. Rule
+  // names are scoped by their policy name ("<policy>:<rule>") so that rules
+  // from different policies never collide and every event can be traced back
+  // to the policy that produced it. Overlapping paths between policies are
+  // resolved by DataWatchItems::Build in the same way as within a policy.
+  NSDictionary* policies = config[kWatchItemConfigKeyPolicies];
+  for (NSString* policy_name in
+       [[policies allKeys] sortedArrayUsingSelector:@selector(compare:)]) {
+    if (!IsWatchItemNameValid(policy_name, err)) {
+      LOGE(@"Ignoring file access policy '%@': Invalid name: %@", policy_name,
            (err && *err) ? (*err).localizedDescription : @"Unknown failure");
       continue;
     }
 
-    if (![watch_items[key] isKindOfClass:[NSDictionary class]]) {
-      LOGE(@"Ignoring file access rule '%@'. Value type must be a dictionary (got %@)", key,
-           NSStringFromClass([watch_items[key] class]));
+    NSDictionary* policy = policies[policy_name];
+    if (![policy isKindOfClass:[NSDictionary class]]) {
+      LOGE(@"Ignoring file access policy '%@'. Value type must be a dictionary (got %@)",
+           policy_name, NSStringFromClass([policy class]));
       continue;
     }
 
-    if (!ParseConfigSingleWatchItem(key, policy_version, watch_items[key], data_policies,
-                                    proc_policies, err)) {
-      LOGE(@"Ignoring file access rule '%@': %@", key,
+    if (!VerifyConfigKey(policy, kWatchItemConfigKeyPoliciesEnabled, [NSNumber class], err) ||
+        !VerifyConfigKey(policy, kWatchItemConfigKeyVersion, [NSString class], err, false,
+                         LenRangeValidator(1, kVersionMaxLength)) ||
+        !VerifyConfigKey(policy, kWatchItemConfigKeyWatchItems, [NSDictionary class], err)) {
+      LOGE(@"Ignoring file access policy '%@': %@", policy_name,
            (err && *err) ? (*err).localizedDescription : @"Unknown failure");
       continue;
     }
 
-    count++;
+    if (policy[kWatchItemConfigKeyPoliciesEnabled] &&
+        ![policy[kWatchItemConfigKeyPoliciesEnabled] boolValue]) {
+      LOGI(@"File access policy '%@' is disabled", policy_name);
+      continue;
+    }
+
+    std::string version = policy[kWatchItemConfigKeyVersion]
+                              ? NSStringToUTF8String(policy[kWatchItemConfigKeyVersion])
+                              : policy_version;
+
+    uint64_t policy_count = ParseWatchItems(policy[kWatchItemConfigKeyWatchItems], version,
+                                            policy_name, data_policies, proc_policies, err);
+    LOGD(@"Loaded %llu rules from file access policy '%@'", policy_count, policy_name);
+    count += policy_count;
   }
 
   if (rules_loaded) {
@@ -716,19 +787,147 @@ SetPairPathAndType DataWatchItems::operator-(const DataWatchItems& other) const
   return diff;
 }
 
+// A single concrete path produced by expanding a data policy's configured path
+struct DataWatchItemCandidate {
+  std::string path;
+  std::shared_ptr<DataWatchItemPolicy> policy;
+  bool from_glob;
+};
+
+static bool ContainsGlobChars(const std::string& path) {
+  return path.find_first_of("*?[") != std::string::npos;
+}
+
+// Orders candidates for the same concrete path from most to least specific:
+//   1. Configured paths without glob characters before glob expansions
+//   2. Literal paths before prefix paths
+//   3. Longer configured paths before shorter ones
+//   4. Rule name, then configured path, as a final deterministic tiebreaker
+static bool IsMoreSpecific(const DataWatchItemCandidate& lhs, const DataWatchItemCandidate& rhs) {
+  if (lhs.from_glob != rhs.from_glob) {
+    return !lhs.from_glob;
+  }
+  if (lhs.policy->path_type != rhs.policy->path_type) {
+    return lhs.policy->path_type == WatchItemPathType::kLiteral;
+  }
+  if (lhs.policy->path.length() != rhs.policy->path.length()) {
+    return lhs.policy->path.length() > rhs.policy->path.length();
+  }
+  if (lhs.policy->name != rhs.policy->name) {
+    return lhs.policy->name < rhs.policy->name;
+  }
+  return lhs.policy->path < rhs.policy->path;
+}
+
 bool DataWatchItems::Build(SetSharedDataWatchItemPolicy data_policies) {
+  std::vector<DataWatchItemCandidate> candidates;
   for (const std::shared_ptr<DataWatchItemPolicy>& item : data_policies) {
     std::vector<std::string> matches = FindMatches(@(item->path.c_str()));
+    bool from_glob = ContainsGlobChars(item->path);
 
-    for (const auto& match : matches) {
-      if (item->path_type == WatchItemPathType::kPrefix) {
-        tree_->InsertPrefix(match.c_str(), item);
+    for (auto& match : matches) {
+      candidates.push_back({std::move(match), item, from_glob});
+    }
+  }
+
+  // The policy set is unordered. Sort so that candidates for the same path are
+  // grouped together with the most specific first, making the result of
+  // overlapping rules independent of iteration order.
+  std::sort(candidates.begin(), candidates.end(),
+            [](const DataWatchItemCandidate& lhs, const DataWatchItemCandidate& rhs) {
+              if (lhs.path != rhs.path) {
+                return lhs.path < rhs.path;
+              }
+              return IsMoreSpecific(lhs, rhs);
+            });
+
+  std::vector<const DataWatchItemCandidate*> winners;
+  // Prefix paths that lost their own path to a literal path. A literal only
+  // matches the path itself, so these still apply to everything beneath it.
+  std::vector<const DataWatchItemCandidate*> descendant_winners;
+  for (const DataWatchItemCandidate& candidate : candidates) {
+    if (!winners.empty() && winners.back()->path == candidate.path) {
+      const DataWatchItemCandidate* kept = winners.back();
+      bool same_rule = kept->policy->name == candidate.policy->name;
+      bool keeps_descendants = kept->policy->path_type == WatchItemPathType::kLiteral &&
+                               candidate.policy->path_type == WatchItemPathType::kPrefix &&
+                               (descendant_winners.empty() ||
+                                descendant_winners.back()->path != candidate.path);
+      if (keeps_descendants) {
+        descendant_winners.push_back(&candidate);
+      }
+
+      if (same_rule) {
+        LOGW(@"File access rule '%s' contains redundant path: %s", candidate.policy->name.c_str(),
+             candidate.path.c_str());
+      } else if (keeps_descendants) {
+        LOGW(@"File access rule '%s' prefix path '%s' only applies beneath literal rule '%s'",
+             candidate.policy->name.c_str(), candidate.path.c_str(), kept->policy->name.c_str());
       } else {
-        tree_->InsertLiteral(match.c_str(), item);
+        LOGW(@"File access rule '%s' path '%s' is shadowed by more specific rule '%s'",
+             candidate.policy->name.c_str(), candidate.path.c_str(), kept->policy->name.c_str());
+      }
+      overlaps_.push_back({
+          .kind = same_rule ? DataWatchItemOverlap::Kind::kRedundant
+                            : DataWatchItemOverlap::Kind::kShadowed,
+          .path = candidate.path,
+          .kept_rule = kept->policy->name,
+          .dropped_rule = candidate.policy->name,
+      });
+      continue;
+    }
+
+    winners.push_back(&candidate);
+  }
+
+  // Winning prefix paths, keyed by path, so that each winner can look up the
+  // prefix paths covering it rather than comparing against every other winner.
+  absl::flat_hash_map<std::string, const DataWatchItemCandidate*> prefix_winners;
+  for (const DataWatchItemCandidate* winner : winners) {
+    if (winner->policy->path_type == WatchItemPathType::kPrefix) {
+      prefix_winners.emplace(winner->path, winner);
+    }
+  }
+
+  // Inserted first so that a winning path beneath the literal path replaces it.
+  for (const DataWatchItemCandidate* winner : descendant_winners) {
+    std::string descendants = winner->path;
+    if (descendants.back() != '/') {
+      descendants.push_back('/');
+    }
+    tree_->InsertPrefix(descendants.c_str(), winner->policy);
+    paths_.insert({winner->path, WatchItemPathType::kPrefix});
+  }
+
+  for (const DataWatchItemCandidate* winner : winners) {
+    // Paths nested under a prefix path of the same rule are still inserted, but
+    // have no effect on the outcome and so are flagged to help rule authors.
+    std::string_view path = winner->path;
+    for (size_t len = 1; len < path.length(); len++) {
+      auto it = prefix_winners.find(path.substr(0, len));
+      if (it == prefix_winners.end() || it->second->policy->name != winner->policy->name) {
+        continue;
       }
 
-      paths_.insert({match.c_str(), item->path_type});
+      const DataWatchItemCandidate* other = it->second;
+      LOGW(@"File access rule '%s' path '%s' is already covered by prefix path '%s'",
+           winner->policy->name.c_str(), winner->path.c_str(), other->path.c_str());
+      overlaps_.push_back({
+          .kind = DataWatchItemOverlap::Kind::kRedundant,
+          .path = winner->path,
+          .kept_rule = other->policy->name,
+          .dropped_rule = winner->policy->name,
+      });
+      break;
     }
+
+    if (winner->policy->path_type == WatchItemPathType::kPrefix) {
+      tree_->InsertPrefix(winner->path.c_str(), winner->policy);
+    } else {
+      tree_->InsertLiteral(winner->path.c_str(), winner->policy);
+    }
+
+    paths_.insert({winner->path, winner->policy->path_type});
   }
 
   return true;
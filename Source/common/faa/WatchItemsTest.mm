@@ -26,6 +26,7 @@
 #include <map>
 #include <memory>
 #include <optional>
+#include <set>
 #include <string_view>
 #include <variant>
 #include <vector>
@@ -1031,6 +1032,84 @@ BlockGenResult CreatePolicyBlockGen() {
   XCTAssertEqual(num_rules, 5);
 }
 
+- (void)testParseConfigNamedPolicies {
+  NSError* err;
+  SetSharedDataWatchItemPolicy data_policies;
+  SetSharedProcessWatchItemPolicy proc_policies;
+  uint64_t num_rules;
+
+  auto policyNames = ^std::set<std::string>(const SetSharedDataWatchItemPolicy& policies) {
+    std::set<std::string> names;
+    for (const auto& p : policies) {
+      names.insert(p->name);
+    }
+    return names;
+  };
+
+  // The Policies key must be a dictionary if it exists
+  XCTAssertFalse(
+      ParseConfig(@{kWatchItemConfigKeyVersion : @"1", kWatchItemConfigKeyPolicies : @[]},
+                  &data_policies, &proc_policies, &num_rules, &err));
+
+  NSMutableDictionary* config = [@{
+    kWatchItemConfigKeyVersion : @"1",
+    kWatchItemConfigKeyWatchItems : @{@"base" : @{kWatchItemConfigKeyPaths : @[ @"/base" ]}},
+    kWatchItemConfigKeyPolicies : @{
+      @"team_a" : @{
+        kWatchItemConfigKeyVersion : @"a1",
+        kWatchItemConfigKeyWatchItems : @{
+          @"r1" : @{kWatchItemConfigKeyPaths : @[ @"/a/one" ]},
+          @"r2" : @{kWatchItemConfigKeyPaths : @[ @"/a/two" ]},
+        },
+      },
+      @"team_b" : @{
+        kWatchItemConfigKeyPoliciesEnabled : @(YES),
+        kWatchItemConfigKeyWatchItems : @{
+          @"r1" : @{kWatchItemConfigKeyPaths : @[ @"/b/one" ]},
+        },
+      },
+    },
+  } mutableCopy];
+
+  // Rules from all enabled policies are combined, scoped by policy name
+  XCTAssertTrue(ParseConfig(config, &data_policies, &proc_policies, &num_rules, &err));
+  XCTAssertEqual(num_rules, 4);
+  XCTAssertEqual(proc_policies.size(), 0);
+  XCTAssertEqual(policyNames(data_policies),
+                 (std::set<std::string>{"base", "team_a:r1", "team_a:r2", "team_b:r1"}));
+
+  // Policy versions take precedence over the top level version
+  for (const auto& p : data_policies) {
+    if (p->name.rfind("team_a:", 0) == 0) {
+      XCTAssertCStringEqual(p->version.c_str(), "a1");
+    } else {
+      XCTAssertCStringEqual(p->version.c_str(), "1");
+    }
+  }
+
+  // Disabling a policy removes only the rules defined by that policy
+  NSMutableDictionary* policies = [config[kWatchItemConfigKeyPolicies] mutableCopy];
+  NSMutableDictionary* team_a = [policies[@"team_a"] mutableCopy];
+  team_a[kWatchItemConfigKeyPoliciesEnabled] = @(NO);
+  policies[@"team_a"] = team_a;
+  config[kWatchItemConfigKeyPolicies] = policies;
+
+  data_policies.clear();
+  XCTAssertTrue(ParseConfig(config, &data_policies, &proc_policies, &num_rules, &err));
+  XCTAssertEqual(num_rules, 2);
+  XCTAssertEqual(policyNames(data_policies), (std::set<std::string>{"base", "team_b:r1"}));
+
+  // Malformed policies are ignored without affecting the others
+  policies[@"team_c"] = @{kWatchItemConfigKeyPoliciesEnabled : @"yes"};
+  policies[@"team_d"] = @[];
+  config[kWatchItemConfigKeyPolicies] = policies;
+
+  data_policies.clear();
+  XCTAssertTrue(ParseConfig(config, &data_policies, &proc_policies, &num_rules, &err));
+  XCTAssertEqual(num_rules, 2);
+  XCTAssertEqual(policyNames(data_policies), (std::set<std::string>{"base", "team_b:r1"}));
+}
+
 - (void)testParseConfigSingleWatchItemGeneral {
   SetSharedDataWatchItemPolicy data_policies;
   SetSharedProcessWatchItemPolicy proc_policies;
@@ -1578,4 +1657,111 @@ BlockGenResult CreatePolicyBlockGen() {
   XCTAssertEqual(pathTypePairs2_1.count({"/z", WatchItemPathType::kPrefix}), 1);
 }
 
+- (void)testDataWatchItemsOverlapMostSpecificWins {
+  [self createTestDirStructure:@[
+    @{
+      @"Users" : @[ @"alice", @"bob" ],
+    },
+  ]];
+
+  std::shared_ptr<DataWatchItemPolicy> (^MakeDataPolicy)(std::string, NSString*,
+                                                         WatchItemPathType) =
+      ^std::shared_ptr<DataWatchItemPolicy>(std::string name, NSString* path,
+                                            WatchItemPathType pathType) {
+    NSString* full = [NSString stringWithFormat:@"%@%@", self.testDir, path];
+    return std::make_shared<DataWatchItemPolicy>(name, "v1", full.UTF8String, pathType);
+  };
+
+  SetSharedDataWatchItemPolicy policies{
+      MakeDataPolicy("all_users", @"/Users/*", WatchItemPathType::kPrefix),
+      MakeDataPolicy("alice", @"/Users/alice", WatchItemPathType::kLiteral),
+  };
+
+  DataWatchItems watchItems;
+  watchItems.Build(policies);
+
+  // The prefix path shadowed by the literal path is still watched beneath it
+  XCTAssertEqual(watchItems.Count(), 3);
+
+  std::string alicePath = MakePathTarget("Users/alice", self.testDir);
+  std::string bobPath = MakePathTarget("Users/bob", self.testDir);
+
+  auto [targetPolicies, blockGen] = CreatePolicyBlockGen();
+
+  // The exact path is more specific than the glob expansion
+  watchItems.FindPolicies(blockGen({alicePath, bobPath}));
+  XCTAssertEqual(targetPolicies.size(), 2);
+  XCTAssertCStringEqual(targetPolicies[0].value_or(MakeBadPolicy())->name.c_str(), "alice");
+  XCTAssertCStringEqual(targetPolicies[1].value_or(MakeBadPolicy())->name.c_str(), "all_users");
+
+  const std::vector<DataWatchItemOverlap>& overlaps = watchItems.Overlaps();
+  XCTAssertEqual(overlaps.size(), 1);
+  XCTAssertEqual(overlaps[0].kind, DataWatchItemOverlap::Kind::kShadowed);
+  XCTAssertCStringEqual(overlaps[0].path.c_str(), alicePath.c_str());
+  XCTAssertCStringEqual(overlaps[0].kept_rule.c_str(), "alice");
+  XCTAssertCStringEqual(overlaps[0].dropped_rule.c_str(), "all_users");
+}
+
+- (void)testDataWatchItemsShadowedPrefixStillCoversDescendants {
+  SetSharedDataWatchItemPolicy policies{
+      std::make_shared<DataWatchItemPolicy>("prefix_rule", "v1", "/foo",
+                                            WatchItemPathType::kPrefix),
+      std::make_shared<DataWatchItemPolicy>("literal_rule", "v1", "/foo",
+                                            WatchItemPathType::kLiteral),
+      std::make_shared<DataWatchItemPolicy>("nested_rule", "v1", "/foo/bar/baz",
+                                            WatchItemPathType::kLiteral),
+  };
+
+  DataWatchItems watchItems;
+  watchItems.Build(policies);
+
+  auto [targetPolicies, blockGen] = CreatePolicyBlockGen();
+
+  // The literal path only wins the path itself, children still match the prefix rule
+  watchItems.FindPolicies(blockGen({"/foo", "/foo/bar", "/foo/bar/baz"}));
+  XCTAssertEqual(targetPolicies.size(), 3);
+  XCTAssertCStringEqual(targetPolicies[0].value_or(MakeBadPolicy())->name.c_str(), "literal_rule");
+  XCTAssertCStringEqual(targetPolicies[1].value_or(MakeBadPolicy())->name.c_str(), "prefix_rule");
+  XCTAssertCStringEqual(targetPolicies[2].value_or(MakeBadPolicy())->name.c_str(), "nested_rule");
+
+  const std::vector<DataWatchItemOverlap>& overlaps = watchItems.Overlaps();
+  XCTAssertEqual(overlaps.size(), 1);
+  XCTAssertEqual(overlaps[0].kind, DataWatchItemOverlap::Kind::kShadowed);
+  XCTAssertCStringEqual(overlaps[0].kept_rule.c_str(), "literal_rule");
+  XCTAssertCStringEqual(overlaps[0].dropped_rule.c_str(), "prefix_rule");
+}
+
+- (void)testDataWatchItemsDuplicateAndRedundantPaths {
+  SetSharedDataWatchItemPolicy policies{
+      std::make_shared<DataWatchItemPolicy>("b_rule", "v1", "/baz", WatchItemPathType::kPrefix),
+      std::make_shared<DataWatchItemPolicy>("a_rule", "v1", "/baz", WatchItemPathType::kPrefix),
+      std::make_shared<DataWatchItemPolicy>("r", "v1", "/foo", WatchItemPathType::kPrefix),
+      std::make_shared<DataWatchItemPolicy>("r", "v1", "/foo/bar", WatchItemPathType::kPrefix),
+  };
+
+  DataWatchItems watchItems;
+  watchItems.Build(policies);
+
+  XCTAssertEqual(watchItems.Count(), 3);
+
+  auto [targetPolicies, blockGen] = CreatePolicyBlockGen();
+
+  // Identical paths from different rules are resolved by rule name
+  watchItems.FindPolicies(blockGen({"/baz/file"}));
+  XCTAssertEqual(targetPolicies.size(), 1);
+  XCTAssertCStringEqual(targetPolicies[0].value_or(MakeBadPolicy())->name.c_str(), "a_rule");
+
+  const std::vector<DataWatchItemOverlap>& overlaps = watchItems.Overlaps();
+  XCTAssertEqual(overlaps.size(), 2);
+
+  XCTAssertEqual(overlaps[0].kind, DataWatchItemOverlap::Kind::kShadowed);
+  XCTAssertCStringEqual(overlaps[0].path.c_str(), "/baz");
+  XCTAssertCStringEqual(overlaps[0].kept_rule.c_str(), "a_rule");
+  XCTAssertCStringEqual(overlaps[0].dropped_rule.c_str(), "b_rule");
+
+  XCTAssertEqual(overlaps[1].kind, DataWatchItemOverlap::Kind::kRedundant);
+  XCTAssertCStringEqual(overlaps[1].path.c_str(), "/foo/bar");
+  XCTAssertCStringEqual(overlaps[1].kept_rule.c_str(), "r");
+}
+
 @end
@@ -146,7 +146,13 @@ static NSString* const silencedNotificationsKey = @"SilencedNotifications";
     }
 
     pendingMsg.delegate = self;
-    [self.pendingNotifications addObject:pendingMsg];
+    if ([self isCriticalMessage:pendingMsg]) {
+      // Critical blocks jump the queue, landing behind only the window currently on screen.
+      [self.pendingNotifications insertObject:pendingMsg
+                                      atIndex:(self.currentWindowController ? 1 : 0)];
+    } else {
+      [self.pendingNotifications addObject:pendingMsg];
+    }
 
     if (!self.currentWindowController) {
       [self showQueuedWindow];
@@ -154,6 +160,11 @@ static NSString* const silencedNotificationsKey = @"SilencedNotifications";
   });
 }
 
+- (BOOL)isCriticalMessage:(SNTMessageWindowController*)msg {
+  if (![msg isKindOfClass:[SNTBinaryMessageWindowController class]]) return NO;
+  return ((SNTBinaryMessageWindowController*)msg).event.severity == SNTRuleSeverityCritical;
+}
+
 // For blocked execution and file access notifications, post an
 // NSDistributedNotificationCenter notification with the important details from
 // the stored event. Distributed notifications are system-wide broadcasts that
@@ -425,9 +436,52 @@ static NSString* const silencedNotificationsKey = @"SilencedNotifications";
                                                   configState:configState
                                                         reply:replyBlock];
 
+  switch (event.severity) {
+    case SNTRuleSeverityInfo:
+      // Info blocks are shown as a banner rather than a dialog, unless the process is being held
+      // for approval as that needs the dialog to respond.
+      if (!event.holdAndAsk) {
+        [self postDistributedNotification:pendingMsg];
+        [self postInfoBlockBanner:event customMessage:message];
+        return;
+      }
+      break;
+    case SNTRuleSeverityCritical:
+      // Critical blocks are always shown, even if the user silenced this binary.
+      [self queueMessage:pendingMsg enableSilences:NO];
+      return;
+    default: break;
+  }
+
   [self queueMessage:pendingMsg enableSilences:configState.enableNotificationSilences];
 }
 
+- (void)postInfoBlockBanner:(SNTStoredExecutionEvent*)event customMessage:(NSString*)message {
+  if ([SNTConfigurator configurator].enableSilentMode) return;
+
+  UNUserNotificationCenter* un = [UNUserNotificationCenter currentNotificationCenter];
+
+  UNMutableNotificationContent* content = [[UNMutableNotificationContent alloc] init];
+  content.title = @"Santa";
+  NSString* app = event.fileBundleName ?: event.filePath.lastPathComponent;
+  if (message.length) {
+    content.body = [SNTBlockMessage stringFromHTML:message];
+  } else {
+    content.body = [NSString
+        stringWithFormat:NSLocalizedString(@"%@ was blocked",
+                                           @"Banner shown when an info rule blocks an app"),
+                         app];
+  }
+
+  NSString* identifier = [NSString stringWithFormat:@"infoBlockNotification_%@", event.fileSHA256];
+
+  UNNotificationRequest* req = [UNNotificationRequest requestWithIdentifier:identifier
+                                                                    content:content
+                                                                    trigger:nil];
+
+  [un addNotificationRequest:req withCompletionHandler:nil];
+}
+
 - (void)postUSBBlockNotification:(SNTDeviceEvent*)event
                     configBundle:(SNTConfigBundle*)configBundle {
   if (!event) {
@@ -14,6 +14,7 @@
 /// limitations under the License.
 
 #import <OCMock/OCMock.h>
+#import <UserNotifications/UserNotifications.h>
 #import <XCTest/XCTest.h>
 
 #import "Source/gui/SNTMessageWindowController.h"
@@ -22,6 +23,7 @@
 #import "Source/gui/SNTStatusItemManager.h"
 
 #import "Source/common/SNTConfigBundle.h"
+#import "Source/common/SNTConfigState.h"
 #import "Source/common/SNTStoredExecutionEvent.h"
 #import "Source/common/SNTStoredNetworkFlowEvent.h"
 
@@ -94,6 +96,64 @@
                        deliverImmediately:YES]);
 }
 
+- (void)testCriticalBlockNotificationIgnoresSilences {
+  SNTStoredExecutionEvent* ev = [[SNTStoredExecutionEvent alloc] init];
+  ev.fileSHA256 = @"the-sha256";
+  ev.decision = SNTEventStateBlockBinary;
+  ev.severity = SNTRuleSeverityCritical;
+
+  id configState = OCMClassMock([SNTConfigState class]);
+  OCMStub([configState enableNotificationSilences]).andReturn(YES);
+
+  SNTNotificationManager* mgr = [[SNTNotificationManager alloc] init];
+  id mgrMock = OCMPartialMock(mgr);
+  OCMExpect([mgrMock
+        queueMessage:[OCMArg isKindOfClass:[SNTBinaryMessageWindowController class]]
+      enableSilences:NO]);
+
+  [mgr postBlockNotification:ev
+           withCustomMessage:nil
+                   customURL:nil
+                 configState:configState
+                    andReply:nil];
+
+  OCMVerifyAll(mgrMock);
+  [mgrMock stopMocking];
+}
+
+- (void)testInfoBlockNotificationPostsBannerInsteadOfWindow {
+  SNTStoredExecutionEvent* ev = [[SNTStoredExecutionEvent alloc] init];
+  ev.fileSHA256 = @"the-sha256";
+  ev.filePath = @"/usr/local/bin/tool";
+  ev.decision = SNTEventStateBlockBinary;
+  ev.severity = SNTRuleSeverityInfo;
+
+  id dncMock = OCMClassMock([NSDistributedNotificationCenter class]);
+  OCMStub([dncMock defaultCenter]).andReturn(dncMock);
+  id unMock = OCMClassMock([UNUserNotificationCenter class]);
+  OCMStub([unMock currentNotificationCenter]).andReturn(unMock);
+
+  SNTNotificationManager* mgr = [[SNTNotificationManager alloc] init];
+  id mgrMock = OCMPartialMock(mgr);
+  OCMReject([mgrMock queueMessage:OCMOCK_ANY enableSilences:YES]);
+  OCMReject([mgrMock queueMessage:OCMOCK_ANY enableSilences:NO]);
+
+  [mgr postBlockNotification:ev withCustomMessage:nil customURL:nil configState:nil andReply:nil];
+
+  OCMVerify([unMock
+      addNotificationRequest:[OCMArg checkWithBlock:^BOOL(UNNotificationRequest* req) {
+        return [req.content.body isEqualToString:@"tool was blocked"];
+      }]
+       withCompletionHandler:nil]);
+  OCMVerify([dncMock postNotificationName:@"com.northpolesec.santa.notification.blockedeexecution"
+                                   object:OCMOCK_ANY
+                                 userInfo:OCMOCK_ANY
+                       deliverImmediately:YES]);
+  [mgrMock stopMocking];
+  [unMock stopMocking];
+  [dncMock stopMocking];
+}
+
 - (void)testPostNetworkFlowBlockNotificationQueuesAWindow {
   SNTNotificationManager* mgr = [[SNTNotificationManager alloc] init];
   id mgrMock = OCMPartialMock(mgr);
@@ -0,0 +1,91 @@
+/// Copyright 2026 North Pole Security, Inc.
+///
+/// Licensed under the Apache License, Version 2.0 (the "License");
+/// you may not use this file except in compliance with the License.
+/// You may obtain a copy of the License at
+///
+///     http://www.apache.org/licenses/LICENSE-2.0
+///
+/// Unless required by applicable law or agreed to in writing, software
+/// distributed under the License is distributed on an "AS IS" BASIS,
+/// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+/// See the License for the specific language governing permissions and
+/// limitations under the License.
+
+#import <Foundation/Foundation.h>
+
+#import "Source/common/MOLXPCConnection.h"
+#import "Source/common/SNTLogging.h"
+#import "Source/common/SNTXPCControlInterface.h"
+#import "Source/santactl/SNTCommand.h"
+#import "Source/santactl/SNTCommandController.h"
+
+@interface SNTCommandAllowOnce : SNTCommand <SNTCommandProtocol>
+@end
+
+@implementation SNTCommandAllowOnce
+
+REGISTER_COMMAND_NAME(@"allow-once")
+
++ (BOOL)requiresRoot {
+  return NO;
+}
+
++ (BOOL)requiresDaemonConn {
+  return YES;
+}
+
++ (NSString*)shortHelpText {
+  return @"Allow a single execution using a one-time token.";
+}
+
++ (NSString*)longHelpText {
+  return (@"Usage: santactl allow-once --token {token}\n"
+          @"  Redeems a one-time token issued by your sync server. The next execution of the\n"
+          @"  binary named by the token is allowed; later executions are evaluated as usual.\n"
+          @"  Each token can only be used once and must be used before it expires.\n"
+          @"\n"
+          @"  Options:\n"
+          @"    --token {token}: The one-time token.\n"
+          @"\n");
+}
+
+- (void)runWithArguments:(NSArray*)arguments {
+  NSString* token;
+
+  for (NSUInteger i = 0; i < arguments.count; ++i) {
+    NSString* arg = arguments[i];
+
+    if ([arg caseInsensitiveCompare:@"--token"] == NSOrderedSame) {
+      if (++i > arguments.count - 1) {
+        [self printErrorUsageAndExit:@"--token requires an argument"];
+      }
+      token = [arguments[i] stringByTrimmingCharactersInSet:[NSCharacterSet
+                                                                whitespaceAndNewlineCharacterSet]];
+    } else {
+      [self printErrorUsageAndExit:[@"Unknown argument: " stringByAppendingString:arg]];
+    }
+  }
+
+  if (!token.length) {
+    [self printErrorUsageAndExit:@"--token is required"];
+  }
+
+  __block BOOL success = NO;
+  __block NSError* error;
+  [[self.daemonConn synchronousRemoteObjectProxy] allowOnceWithToken:token
+                                                               reply:^(NSError* err) {
+                                                                 success = (err == nil);
+                                                                 error = err;
+                                                               }];
+
+  if (!success) {
+    TEE_LOGE(@"Token rejected: %@", error.localizedDescription ?: @"No response from santad");
+    exit(EXIT_FAILURE);
+  }
+
+  TEE_LOGI(@"Token accepted. The next execution of the binary will be allowed.");
+  exit(EXIT_SUCCESS);
+}
+
+@end
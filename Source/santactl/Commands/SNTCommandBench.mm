@@ -0,0 +1,339 @@
+/// Copyright 2026 North Pole Security, Inc.
+///
+/// Licensed under the Apache License, Version 2.0 (the "License");
+/// you may not use this file except in compliance with the License.
+/// You may obtain a copy of the License at
+///
+///     http://www.apache.org/licenses/LICENSE-2.0
+///
+/// Unless required by applicable law or agreed to in writing, software
+/// distributed under the License is distributed on an "AS IS" BASIS,
+/// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+/// See the License for the specific language governing permissions and
+/// limitations under the License.
+
+
+#import <FMDB/FMDB.h>
+#import <Foundation/Foundation.h>
+#include <os/log.h>
+
+#include <algorithm>
+#include <optional>
+#include <vector>
+
+#import "Source/common/MOLXPCConnection.h"
+#import "Source/common/SNTConfigurator.h"
+#import "Source/common/SNTDropRootPrivs.h"
+#import "Source/common/SNTLogging.h"
+#import "Source/common/SNTRule.h"
+#import "Source/common/SNTRuleIdentifiers.h"
+#import "Source/common/SNTSyncConstants.h"
+#import "Source/common/SNTXPCSyncServiceInterface.h"
+#include "Source/common/SystemResources.h"
+#import "Source/santactl/SNTCommand.h"
+#import "Source/santactl/SNTCommandController.h"
+#import "Source/santad/DataLayer/SNTRuleTable.h"
+
+@interface SNTCommandBench : SNTCommand <SNTCommandProtocol, SNTSyncServiceLogReceiverXPC>
+@property BOOL enableDebugLogging;
+@end
+
+@implementation SNTCommandBench
+
+REGISTER_COMMAND_NAME(@"bench")
+
+#pragma mark SNTCommand protocol methods
+
++ (BOOL)requiresRoot {
+  return NO;
+}
+
++ (BOOL)requiresDaemonConn {
+  return NO;  // We talk directly with the syncservice.
+}
+
++ (NSString*)shortHelpText {
+  return @"Benchmarks communication with a sync server and rule storage.";
+}
+
++ (NSString*)longHelpText {
+  return (@"Usage: santactl bench upload --rate <n> --duration <d> [--sync-url <url>] [--debug]\n"
+          @"       santactl bench store --rules <n>\n\n"
+          @"upload:\n"
+          @"Uploads synthetic execution events to the sync server at the given rate and reports\n"
+          @"the bandwidth used and how long the server took to accept each request, for capacity\n"
+          @"planning on metered networks. Events are batched and encoded exactly as a sync would\n"
+          @"upload them. The server will receive the events as if they came from this host, so\n"
+          @"use a test server where possible.\n\n"
+          @"  --rate <n>: The number of events to generate per second.\n"
+          @"  --duration <d>: The number of seconds to generate events for.\n"
+          @"  --sync-url <url>: The sync server to upload to. Defaults to SyncBaseURL.\n"
+          @"  --debug: Enable verbose output.\n\n"
+          @"store:\n"
+          @"Loads synthetic execution rules into a scratch rule database, the same way a sync\n"
+          @"applies them, and reports the insert throughput, the memory and disk space used and\n"
+          @"how long lookups take. Every rule is then looked up to check that it was stored\n"
+          @"correctly. The scratch database is deleted afterwards; Santa's own rules are not\n"
+          @"touched.\n\n"
+          @"  --rules <n>: The number of rules to load.\n");
+}
+
++ (NSUInteger)positiveIntegerFromString:(NSString*)string {
+  NSScanner* scanner = [NSScanner scannerWithString:string];
+  long long value;
+  if (![scanner scanLongLong:&value] || !scanner.isAtEnd || value <= 0) return 0;
+  return (NSUInteger)value;
+}
+
+- (void)runWithArguments:(NSArray*)arguments {
+  // Ensure we have no privileges
+  if (!DropRootPrivileges()) {
+    TEE_LOGE(@"Failed to drop root privileges. Exiting.");
+    exit(1);
+  }
+
+  if ([arguments.firstObject isEqualToString:@"store"]) {
+    [self runStoreBenchmarkWithArguments:arguments];
+  } else if (![arguments.firstObject isEqualToString:@"upload"]) {
+    [self printErrorUsageAndExit:@"Missing or unknown subcommand"];
+  }
+
+  NSURL* syncURL = [[SNTConfigurator configurator] syncBaseURL];
+  NSUInteger rate = 0;
+  NSUInteger duration = 0;
+  for (NSUInteger i = 1; i < arguments.count; ++i) {
+    NSString* arg = arguments[i];
+    if ([arg caseInsensitiveCompare:@"--rate"] == NSOrderedSame) {
+      if (++i > arguments.count - 1) {
+        [self printErrorUsageAndExit:@"--rate requires an argument"];
+      }
+      rate = [[self class] positiveIntegerFromString:arguments[i]];
+      if (!rate) [self printErrorUsageAndExit:@"--rate must be a positive integer"];
+    } else if ([arg caseInsensitiveCompare:@"--duration"] == NSOrderedSame) {
+      if (++i > arguments.count - 1) {
+        [self printErrorUsageAndExit:@"--duration requires an argument"];
+      }
+      duration = [[self class] positiveIntegerFromString:arguments[i]];
+      if (!duration) [self printErrorUsageAndExit:@"--duration must be a positive integer"];
+    } else if ([arg caseInsensitiveCompare:@"--sync-url"] == NSOrderedSame) {
+      if (++i > arguments.count - 1) {
+        [self printErrorUsageAndExit:@"--sync-url requires an argument"];
+      }
+      syncURL = [NSURL URLWithString:arguments[i]];
+    } else if ([arg caseInsensitiveCompare:@"--debug"] == NSOrderedSame) {
+      self.enableDebugLogging = YES;
+    } else {
+      [self printErrorUsageAndExit:[@"Unknown argument: " stringByAppendingString:arg]];
+    }
+  }
+
+  if (!rate || !duration) {
+    [self printErrorUsageAndExit:@"--rate and --duration are required"];
+  }
+  if (!syncURL.scheme.length || !syncURL.host.length) {
+    [self printErrorUsageAndExit:@"--sync-url must be a valid URL when SyncBaseURL is not set"];
+  }
+
+  MOLXPCConnection* ss = [SNTXPCSyncServiceInterface configuredConnection];
+  ss.invalidationHandler = ^(void) {
+    TEE_LOGE(@"Failed to connect to the sync service.");
+    exit(1);
+  };
+  [ss resume];
+
+  NSXPCListener* logListener = [NSXPCListener anonymousListener];
+  MOLXPCConnection* lr = [[MOLXPCConnection alloc] initServerWithListener:logListener];
+  lr.exportedObject = self;
+  lr.unprivilegedInterface =
+      [NSXPCInterface interfaceWithProtocol:@protocol(SNTSyncServiceLogReceiverXPC)];
+  [lr resume];
+
+  [[ss remoteObjectProxy] benchmarkUploadWithSyncURL:syncURL
+                                                rate:rate
+                                            duration:duration
+                                         logListener:logListener.endpoint
+                                               reply:^(NSDictionary<NSString*, id>* report) {
+                                                 [self printReport:report];
+                                               }];
+
+  // Do not return from this scope.
+  [[NSRunLoop mainRunLoop] run];
+}
+
+- (void)printReport:(NSDictionary<NSString*, id>*)report {
+  NSString* error = report[kUploadBenchmarkError];
+  if (error.length || !report) {
+    TEE_LOGE(@"Failed to run the upload benchmark: %@", error ?: @"no reply");
+    exit(1);
+  }
+
+  uint64_t generated = [report[kUploadBenchmarkEventsGenerated] unsignedLongLongValue];
+  uint64_t accepted = [report[kUploadBenchmarkEventsAccepted] unsignedLongLongValue];
+  uint64_t requests = [report[kUploadBenchmarkRequests] unsignedLongLongValue];
+  uint64_t sent = [report[kUploadBenchmarkBytesSent] unsignedLongLongValue];
+  uint64_t received = [report[kUploadBenchmarkBytesReceived] unsignedLongLongValue];
+  double elapsed = MAX([report[kUploadBenchmarkElapsedSeconds] doubleValue], 0.001);
+
+  printf("Events accepted:   %llu of %llu\n", accepted, generated);
+  printf("Requests:          %llu (%llu failed)\n", requests,
+         [report[kUploadBenchmarkRequestsFailed] unsignedLongLongValue]);
+  printf("Elapsed:           %.1f s\n", elapsed);
+  printf("Bytes sent:        %llu (%.0f bytes/s)\n", sent, sent / elapsed);
+  printf("Bytes received:    %llu (%.0f bytes/s)\n", received, received / elapsed);
+  if (generated) {
+    printf("Bytes per event:   %.1f\n", (double)sent / generated);
+  }
+  if (report[kUploadBenchmarkLatencyMedianMs]) {
+    printf("Accept latency:    median %.0f ms, p95 %.0f ms, max %.0f ms\n",
+           [report[kUploadBenchmarkLatencyMedianMs] doubleValue],
+           [report[kUploadBenchmarkLatencyP95Ms] doubleValue],
+           [report[kUploadBenchmarkLatencyMaxMs] doubleValue]);
+  }
+  exit(accepted == generated ? 0 : 1);
+}
+
+#pragma mark store
+
+// Returns the rule with the given index. Rules cycle through the rule types that are looked up
+// by identifier so that each type's index is exercised.
++ (SNTRule*)syntheticRuleWithIndex:(NSUInteger)i {
+  unsigned long n = (unsigned long)i;
+  switch (i % 5) {
+    case 0:
+      return [[SNTRule alloc] initWithIdentifier:[NSString stringWithFormat:@"%064lx", n]
+                                           state:SNTRuleStateAllow
+                                            type:SNTRuleTypeBinary];
+    case 1:
+      return [[SNTRule alloc] initWithIdentifier:[NSString stringWithFormat:@"%064lx", n]
+                                           state:SNTRuleStateBlock
+                                            type:SNTRuleTypeCertificate];
+    case 2:
+      return [[SNTRule alloc] initWithIdentifier:[NSString stringWithFormat:@"%040lx", n]
+                                           state:SNTRuleStateAllow
+                                            type:SNTRuleTypeCDHash];
+    case 3:
+      return [[SNTRule alloc]
+          initWithIdentifier:[NSString stringWithFormat:@"ABCDE12345:com.example.bench%lu", n]
+                       state:SNTRuleStateAllow
+                        type:SNTRuleTypeSigningID];
+    default:
+      return [[SNTRule alloc] initWithIdentifier:[NSString stringWithFormat:@"%010lX", n]
+                                           state:SNTRuleStateBlock
+                                            type:SNTRuleTypeTeamID];
+  }
+}
+
++ (struct RuleIdentifiers)identifiersForRule:(SNTRule*)rule {
+  return (struct RuleIdentifiers){
+      .cdhash = (rule.type == SNTRuleTypeCDHash) ? rule.identifier : nil,
+      .binarySHA256 = (rule.type == SNTRuleTypeBinary) ? rule.identifier : nil,
+      .signingID = (rule.type == SNTRuleTypeSigningID) ? rule.identifier : nil,
+      .certificateSHA256 = (rule.type == SNTRuleTypeCertificate) ? rule.identifier : nil,
+      .teamID = (rule.type == SNTRuleTypeTeamID) ? rule.identifier : nil,
+  };
+}
+
+- (void)runStoreBenchmarkWithArguments:(NSArray*)arguments {
+  NSUInteger count = 0;
+  for (NSUInteger i = 1; i < arguments.count; ++i) {
+    NSString* arg = arguments[i];
+    if ([arg caseInsensitiveCompare:@"--rules"] == NSOrderedSame) {
+      if (++i > arguments.count - 1) {
+        [self printErrorUsageAndExit:@"--rules requires an argument"];
+      }
+      count = [[self class] positiveIntegerFromString:arguments[i]];
+      if (!count) [self printErrorUsageAndExit:@"--rules must be a positive integer"];
+    } else {
+      [self printErrorUsageAndExit:[@"Unknown argument: " stringByAppendingString:arg]];
+    }
+  }
+  if (!count) [self printErrorUsageAndExit:@"--rules is required"];
+
+  NSMutableArray<SNTRule*>* rules = [NSMutableArray arrayWithCapacity:count];
+  for (NSUInteger i = 0; i < count; ++i) {
+    [rules addObject:[[self class] syntheticRuleWithIndex:i]];
+  }
+
+  NSString* dir = [NSTemporaryDirectory()
+      stringByAppendingPathComponent:[NSString stringWithFormat:@"santactl-bench-%@",
+                                                                [NSUUID UUID].UUIDString]];
+  if (![[NSFileManager defaultManager] createDirectoryAtPath:dir
+                                 withIntermediateDirectories:YES
+                                                  attributes:@{NSFilePosixPermissions : @0700}
+                                                       error:nil]) {
+    TEE_LOGE(@"Failed to create a scratch directory in %@", NSTemporaryDirectory());
+    exit(1);
+  }
+  NSString* dbPath = [dir stringByAppendingPathComponent:@"rules.db"];
+
+  int status = 0;
+  @autoreleasepool {
+    std::optional<SantaTaskInfo> before = GetTaskInfo();
+    SNTRuleTable* table =
+        [[SNTRuleTable alloc] initWithDatabaseQueue:[FMDatabaseQueue databaseQueueWithPath:dbPath]];
+
+    uint64_t start = GetCurrentUptime();
+    NSArray<NSError*>* errors;
+    BOOL added = [table addExecutionRules:rules ruleCleanup:SNTRuleCleanupAll errors:&errors];
+    double insertSeconds = MAX((GetCurrentUptime() - start) / 1e9, 0.000001);
+    std::optional<SantaTaskInfo> after = GetTaskInfo();
+
+    if (!added) {
+      TEE_LOGE(@"Failed to add rules to the scratch database:");
+      for (NSError* e in errors) {
+        TEE_LOGE(@"\t%@", e.localizedFailureReason);
+      }
+      status = 1;
+    } else {
+      // Look up every rule, both to measure latency and to check that each one was stored.
+      std::vector<double> latencies;
+      latencies.reserve(count);
+      NSUInteger missing = 0;
+      for (SNTRule* rule in rules) {
+        struct RuleIdentifiers identifiers = [[self class] identifiersForRule:rule];
+        uint64_t lookupStart = GetCurrentUptime();
+        SNTRule* found = [table executionRuleForIdentifiers:identifiers];
+        latencies.push_back((GetCurrentUptime() - lookupStart) / 1e3);
+        if (found.type != rule.type || found.state != rule.state ||
+            ![found.identifier isEqualToString:rule.identifier]) {
+          if (missing++ < 10) TEE_LOGE(@"Rule was not retrievable: %@", rule);
+        }
+      }
+      std::sort(latencies.begin(), latencies.end());
+
+      NSDictionary* attrs = [[NSFileManager defaultManager] attributesOfItemAtPath:dbPath
+                                                                             error:nil];
+
+      printf("Rules loaded:      %llu of %lu\n", (unsigned long long)table.executionRuleCount,
+             (unsigned long)count);
+      printf("Insert:            %.2f s (%.0f rules/s)\n", insertSeconds, count / insertSeconds);
+      if (before.has_value() && after.has_value()) {
+        int64_t grown = (int64_t)after->resident_size - (int64_t)before->resident_size;
+        printf("Memory:            %.1f MiB resident after insert (%+.1f MiB)\n",
+               after->resident_size / 1048576.0, grown / 1048576.0);
+      }
+      printf("Database size:     %.1f MiB\n", [attrs fileSize] / 1048576.0);
+      printf("Lookup latency:    median %.1f us, p95 %.1f us, p99 %.1f us, max %.1f us\n",
+             latencies[latencies.size() / 2], latencies[latencies.size() * 95 / 100],
+             latencies[latencies.size() * 99 / 100], latencies.back());
+      printf("Retrievable:       %lu of %lu\n", (unsigned long)(count - missing),
+             (unsigned long)count);
+
+      if (missing || table.executionRuleCount != (int64_t)count) status = 1;
+    }
+  }
+
+  [[NSFileManager defaultManager] removeItemAtPath:dir error:nil];
+  exit(status);
+}
+
+/// Implement the SNTSyncServiceLogReceiverXPC protocol.
+- (void)didReceiveLog:(NSString*)log withType:(os_log_type_t)logType {
+  if (logType == OS_LOG_TYPE_DEBUG && !self.enableDebugLogging) {
+    return;
+  }
+  printf("%s\n", log.UTF8String);
+  fflush(stdout);
+}
+
+@end
@@ -0,0 +1,108 @@
+/// Copyright 2026 North Pole Security, Inc.
+///
+/// Licensed under the Apache License, Version 2.0 (the "License");
+/// you may not use this file except in compliance with the License.
+/// You may obtain a copy of the License at
+///
+///     http://www.apache.org/licenses/LICENSE-2.0
+///
+/// Unless required by applicable law or agreed to in writing, software
+/// distributed under the License is distributed on an "AS IS" BASIS,
+/// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+/// See the License for the specific language governing permissions and
+/// limitations under the License.
+
+#import <Foundation/Foundation.h>
+
+#import "Source/common/MOLXPCConnection.h"
+#import "Source/common/SNTConfigurator.h"
+#import "Source/common/SNTLogging.h"
+#import "Source/common/SNTXPCControlInterface.h"
+#import "Source/santactl/SNTCommand.h"
+#import "Source/santactl/SNTCommandController.h"
+
+@interface SNTCommandConfig : SNTCommand <SNTCommandProtocol>
+@end
+
+@implementation SNTCommandConfig
+
+REGISTER_COMMAND_NAME(@"config")
+
++ (BOOL)requiresRoot {
+  return NO;
+}
+
++ (BOOL)requiresDaemonConn {
+  return YES;
+}
+
++ (NSString*)shortHelpText {
+  return @"Check the configuration profile against the configuration santad applied.";
+}
+
++ (NSString*)longHelpText {
+  return (@"Usage: santactl config validate [--json]\n"
+          @"  Compares the settings in the configuration profile with the settings santad\n"
+          @"  parsed and applied, and lists each key that was:\n"
+          @"    ignored: not recognized, or superseded by another key.\n"
+          @"    malformed: the wrong type or failed to parse.\n"
+          @"    defaulted: set to a value the key doesn't support, so the default is used.\n"
+          @"    not_applied: different in santad, e.g. because it has not reloaded the profile.\n"
+          @"  Exits with a non-zero status if any problems are found.\n"
+          @"\n"
+          @"  Options:\n"
+          @"    --json: Print the problems as JSON.\n"
+          @"\n");
+}
+
+- (void)runWithArguments:(NSArray*)arguments {
+  if (!arguments.count || [arguments[0] caseInsensitiveCompare:@"validate"] != NSOrderedSame) {
+    [self printErrorUsageAndExit:@"Missing or unknown subcommand"];
+  }
+
+  BOOL json = NO;
+  for (NSUInteger i = 1; i < arguments.count; ++i) {
+    NSString* arg = arguments[i];
+    if ([arg caseInsensitiveCompare:@"--json"] == NSOrderedSame) {
+      json = YES;
+    } else {
+      [self printErrorUsageAndExit:[@"Unknown argument: " stringByAppendingString:arg]];
+    }
+  }
+
+  __block NSDictionary* parsed;
+  [[self.daemonConn synchronousRemoteObjectProxy] parsedConfigProfile:^(NSDictionary* config) {
+    parsed = config;
+  }];
+  if (!parsed) {
+    TEE_LOGE(@"Unable to retrieve the configuration from santad");
+    exit(EXIT_FAILURE);
+  }
+
+  SNTConfigurator* configurator = [SNTConfigurator configurator];
+  NSArray<NSDictionary<NSString*, NSString*>*>* issues =
+      [configurator configIssuesForProfile:[configurator configProfile] parsedConfig:parsed];
+
+  if (json) {
+    NSData* data = [NSJSONSerialization
+        dataWithJSONObject:issues
+                   options:NSJSONWritingPrettyPrinted | NSJSONWritingSortedKeys
+                     error:NULL];
+    printf("%s\n", [[NSString alloc] initWithData:data encoding:NSUTF8StringEncoding].UTF8String);
+    exit(issues.count ? EXIT_FAILURE : EXIT_SUCCESS);
+  }
+
+  if (!issues.count) {
+    printf("No problems found, santad applied every key in the configuration profile\n");
+    exit(EXIT_SUCCESS);
+  }
+
+  printf("%-40s  %-12s  %s\n", "KEY", "ISSUE", "DETAIL");
+  for (NSDictionary<NSString*, NSString*>* issue in issues) {
+    printf("%-40s  %-12s  %s\n", issue[kConfigIssueKey].UTF8String,
+           issue[kConfigIssueType].UTF8String, issue[kConfigIssueDetail].UTF8String);
+  }
+  exit(EXIT_FAILURE);
+}
+
+@end
@@ -0,0 +1,464 @@
+/// Copyright 2026 North Pole Security, Inc.
+///
+/// Licensed under the Apache License, Version 2.0 (the "License");
+/// you may not use this file except in compliance with the License.
+/// You may obtain a copy of the License at
+///
+///     http://www.apache.org/licenses/LICENSE-2.0
+///
+/// Unless required by applicable law or agreed to in writing, software
+/// distributed under the License is distributed on an "AS IS" BASIS,
+/// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+/// See the License for the specific language governing permissions and
+/// limitations under the License.
+
+#import <Foundation/Foundation.h>
+#import <Network/Network.h>
+
+#include <os/log.h>
+#include <unistd.h>
+
+#include <cstdint>
+
+#import "Source/common/MOLCertificate.h"
+#import "Source/common/MOLXPCConnection.h"
+#import "Source/common/SNTCommonEnums.h"
+#import "Source/common/SNTConfigurator.h"
+#import "Source/common/SNTSyncConstants.h"
+#import "Source/common/SNTXPCSyncServiceInterface.h"
+#import "Source/santactl/SNTCommand.h"
+#import "Source/santactl/SNTCommandController.h"
+
+static const NSTimeInterval kDefaultTimeout = 10;
+
+// The stage at which a connectivity check failed. DNS, TCP and TLS failures are network failures:
+// the endpoint could not be reached. Auth failures mean the endpoint was reached but rejected this
+// host's credentials.
+typedef NS_ENUM(NSInteger, SNTConnectivityFailure) {
+  SNTConnectivityFailureNone,
+  SNTConnectivityFailureDNS,
+  SNTConnectivityFailureTCP,
+  SNTConnectivityFailureTLS,
+  SNTConnectivityFailureAuth,
+  SNTConnectivityFailureServer,
+  SNTConnectivityFailureUnknown,
+};
+
+struct SNTConnectivityProbeResult {
+  SNTConnectivityFailure failure;
+  // Time taken for the connection to become ready, or to fail, in milliseconds.
+  double elapsedMs;
+  NSString* errorDescription;
+};
+
+// Returns YES if the failure happened before the endpoint could be reached. Exposed (non-static) so
+// it can be unit tested.
+BOOL SNTConnectivityIsNetworkFailure(SNTConnectivityFailure failure) {
+  return failure == SNTConnectivityFailureDNS || failure == SNTConnectivityFailureTCP ||
+         failure == SNTConnectivityFailureTLS;
+}
+
+// Returns a short human readable description of the failure, used in the summary. Exposed
+// (non-static) so it can be unit tested.
+NSString* SNTConnectivityFailureDescription(SNTConnectivityFailure failure) {
+  switch (failure) {
+    case SNTConnectivityFailureNone: return @"OK";
+    case SNTConnectivityFailureDNS: return @"network failure (DNS resolution)";
+    case SNTConnectivityFailureTCP: return @"network failure (TCP connect)";
+    case SNTConnectivityFailureTLS: return @"network failure (TLS handshake)";
+    case SNTConnectivityFailureAuth: return @"authentication failure";
+    case SNTConnectivityFailureServer: return @"server error";
+    case SNTConnectivityFailureUnknown: return @"unknown failure";
+  }
+}
+
+// Maps the domain of an error reported by Network.framework to the stage that failed. Exposed
+// (non-static) so it can be unit tested.
+SNTConnectivityFailure SNTConnectivityClassifyNetworkErrorDomain(nw_error_domain_t domain) {
+  switch (domain) {
+    case nw_error_domain_dns: return SNTConnectivityFailureDNS;
+    case nw_error_domain_posix: return SNTConnectivityFailureTCP;
+    case nw_error_domain_tls: return SNTConnectivityFailureTLS;
+    default: return SNTConnectivityFailureUnknown;
+  }
+}
+
+// Classifies the HTTP status code returned by the sync service's preflight check. As with
+// `santactl doctor`, a 400 is treated as success because the request carries no data. A status code
+// of 0 means the request never completed. Exposed (non-static) so it can be unit tested.
+SNTConnectivityFailure SNTConnectivityClassifySyncStatus(NSInteger statusCode) {
+  switch (statusCode) {
+    case 0: return SNTConnectivityFailureUnknown;
+    case 200:
+    case 400: return SNTConnectivityFailureNone;
+    case 401:
+    case 403: return SNTConnectivityFailureAuth;
+    default: return SNTConnectivityFailureServer;
+  }
+}
+
+// Classifies the push client's connection state. This is only consulted after the push server was
+// reached over TCP and TLS, so a disconnected client is attributed to the server rejecting its
+// credentials. Exposed (non-static) so it can be unit tested.
+SNTConnectivityFailure SNTConnectivityClassifyPushStatus(SNTPushNotificationStatus status) {
+  switch (status) {
+    case SNTPushNotificationStatusConnected:
+    case SNTPushNotificationStatusConnectedNATS: return SNTConnectivityFailureNone;
+    case SNTPushNotificationStatusDisconnected: return SNTConnectivityFailureAuth;
+    default: return SNTConnectivityFailureUnknown;
+  }
+}
+
+// Parses a sync or push server address into the host and port to probe and whether the connection
+// uses TLS. Ports default to 443 for https, 80 for http and 4222 (the NATS default) for tls and
+// nats. Returns NO if the address has no host or an unsupported scheme. Exposed (non-static) so it
+// can be unit tested.
+BOOL SNTConnectivityParseEndpoint(NSString* address, NSString** host, uint16_t* port,
+                                  BOOL* useTLS) {
+  NSURL* url = [NSURL URLWithString:address];
+  if (!url.host.length) return NO;
+
+  NSString* scheme = url.scheme.lowercaseString;
+  uint16_t defaultPort;
+  if ([scheme isEqualToString:@"https"]) {
+    defaultPort = 443;
+    *useTLS = YES;
+  } else if ([scheme isEqualToString:@"http"]) {
+    defaultPort = 80;
+    *useTLS = NO;
+  } else if ([scheme isEqualToString:@"tls"]) {
+    defaultPort = 4222;
+    *useTLS = YES;
+  } else if ([scheme isEqualToString:@"nats"]) {
+    defaultPort = 4222;
+    *useTLS = NO;
+  } else {
+    return NO;
+  }
+
+  *host = url.host;
+  *port = url.port ? url.port.unsignedShortValue : defaultPort;
+  return YES;
+}
+
+// Opens a connection to host:port, optionally performing a TLS handshake, and reports how long it
+// took to become ready or which stage failed. The connection is cancelled before returning. Exposed
+// (non-static) so it can be unit tested.
+SNTConnectivityProbeResult SNTConnectivityProbe(NSString* host, uint16_t port, BOOL useTLS,
+                                                NSTimeInterval timeout) {
+  nw_endpoint_t endpoint =
+      nw_endpoint_create_host(host.UTF8String, [@(port) stringValue].UTF8String);
+  nw_parameters_t params = nw_parameters_create_secure_tcp(
+      useTLS ? NW_PARAMETERS_DEFAULT_CONFIGURATION : NW_PARAMETERS_DISABLE_PROTOCOL,
+      NW_PARAMETERS_DEFAULT_CONFIGURATION);
+  nw_connection_t conn = nw_connection_create(endpoint, params);
+
+  dispatch_queue_t q = dispatch_queue_create("com.northpolesec.santa.santactl.connectivity",
+                                             DISPATCH_QUEUE_SERIAL);
+  dispatch_semaphore_t sema = dispatch_semaphore_create(0);
+  __block SNTConnectivityProbeResult result = {SNTConnectivityFailureUnknown, 0, nil};
+  __block BOOL done = NO;
+  CFAbsoluteTime start = CFAbsoluteTimeGetCurrent();
+
+  nw_connection_set_queue(conn, q);
+  nw_connection_set_state_changed_handler(conn, ^(nw_connection_state_t state, nw_error_t error) {
+    if (done) return;
+    if (state == nw_connection_state_ready) {
+      result.failure = SNTConnectivityFailureNone;
+    } else if (state == nw_connection_state_failed ||
+               (state == nw_connection_state_waiting && error)) {
+      // A connection that cannot currently be established (e.g. the port refused it) moves to the
+      // waiting state rather than failing, so treat that as a failure too.
+      result.failure = error ? SNTConnectivityClassifyNetworkErrorDomain(
+                                   nw_error_get_error_domain(error))
+                             : SNTConnectivityFailureUnknown;
+      if (error) {
+        NSError* err = CFBridgingRelease(nw_error_copy_cf_error(error));
+        result.errorDescription = err.localizedDescription;
+      }
+    } else {
+      return;
+    }
+    result.elapsedMs = (CFAbsoluteTimeGetCurrent() - start) * 1000;
+    done = YES;
+    dispatch_semaphore_signal(sema);
+  });
+  nw_connection_start(conn);
+
+  dispatch_semaphore_wait(sema, dispatch_time(DISPATCH_TIME_NOW, timeout * NSEC_PER_SEC));
+  dispatch_sync(q, ^{
+    if (done) return;
+    done = YES;
+    result.failure = useTLS ? SNTConnectivityFailureTLS : SNTConnectivityFailureTCP;
+    result.elapsedMs = (CFAbsoluteTimeGetCurrent() - start) * 1000;
+    result.errorDescription = [NSString stringWithFormat:@"timed out after %.0f seconds", timeout];
+  });
+  nw_connection_cancel(conn);
+
+  return result;
+}
+
+static void Print(NSString* format, ...) {
+  va_list args;
+  va_start(args, format);
+  NSString* line = [[NSString alloc] initWithFormat:format arguments:args];
+  va_end(args);
+
+  if (isatty(STDOUT_FILENO)) {
+    if ([line hasPrefix:@"[-]"]) {
+      line = [NSString stringWithFormat:@"\033[31m%@\033[0m", line];
+    } else if ([line hasPrefix:@"[+]"]) {
+      line = [NSString stringWithFormat:@"\033[32m%@\033[0m", line];
+    }
+  }
+  printf("%s\n", line.UTF8String);
+}
+
+@interface SNTCommandConnectivity : SNTCommand <SNTCommandProtocol, SNTSyncServiceLogReceiverXPC>
+@property NSTimeInterval timeout;
+@property MOLXPCConnection* syncConn;
+@end
+
+@implementation SNTCommandConnectivity
+
+REGISTER_COMMAND_NAME(@"connectivity")
+
++ (BOOL)requiresRoot {
+  // The sync service only accepts connections from root.
+  return YES;
+}
+
++ (BOOL)requiresDaemonConn {
+  return NO;  // We talk directly with the syncservice.
+}
+
++ (NSString*)shortHelpText {
+  return @"Check connectivity to the configured sync and push servers.";
+}
+
++ (NSString*)longHelpText {
+  return (@"Checks that each configured endpoint can be reached and accepts this host's\n"
+          @"credentials, and prints one report covering all of them. For the sync server and\n"
+          @"the push server this runs, in order:\n"
+          @"  - a TCP connect to the server's host and port,\n"
+          @"  - a TLS handshake (if the server uses TLS),\n"
+          @"  - an authenticated request: a preflight for the sync server, or the push client's\n"
+          @"    connection state for the push server.\n\n"
+          @"The latency of each network stage is reported. Failures are reported as either\n"
+          @"network failures (the server could not be reached) or authentication failures (the\n"
+          @"server was reached but rejected this host's credentials).\n\n"
+          @"Will exit with a non-zero exit code if any endpoint fails a check.\n\n"
+          @"Options:\n"
+          @"  --timeout <seconds>: Timeout for each network stage. Defaults to 10.\n");
+}
+
+- (void)runWithArguments:(NSArray*)arguments {
+  self.timeout = kDefaultTimeout;
+  for (NSUInteger i = 0; i < arguments.count; ++i) {
+    NSString* arg = arguments[i];
+    if ([arg caseInsensitiveCompare:@"--timeout"] == NSOrderedSame) {
+      if (++i >= arguments.count || [arguments[i] doubleValue] <= 0) {
+        [self printErrorUsageAndExit:@"--timeout requires a positive number of seconds"];
+      }
+      self.timeout = [arguments[i] doubleValue];
+    } else {
+      [self printErrorUsageAndExit:[@"Unknown argument: " stringByAppendingString:arg]];
+    }
+  }
+
+  SNTConfigurator* config = [SNTConfigurator configurator];
+  NSURL* syncBaseURL = config.syncBaseURL;
+  if (!syncBaseURL) {
+    if (config.syncBaseURLConfigured) {
+      Print(@"[-] SyncBaseURL is configured but was rejected "
+            @"(HTTP is only allowed for localhost)");
+      exit(1);
+    }
+    Print(@"[+] Sync is disabled, there are no endpoints to check");
+    exit(0);
+  }
+
+  self.syncConn = [SNTXPCSyncServiceInterface configuredConnection];
+  [self.syncConn resume];
+
+  SNTConnectivityFailure syncFailure = [self checkSyncServer:syncBaseURL];
+  NSString* pushAddress;
+  SNTConnectivityFailure pushFailure = [self checkPushServer:&pushAddress];
+
+  [self.syncConn invalidate];
+
+  Print(@"=> Summary");
+  [self printSummaryForEndpoint:@"Sync server" failure:syncFailure];
+  if (pushAddress) {
+    [self printSummaryForEndpoint:@"Push server" failure:pushFailure];
+  }
+
+  exit(syncFailure != SNTConnectivityFailureNone || pushFailure != SNTConnectivityFailureNone);
+}
+
+- (void)printSummaryForEndpoint:(NSString*)name failure:(SNTConnectivityFailure)failure {
+  Print(@"%@ %@: %@", failure == SNTConnectivityFailureNone ? @"[+]" : @"[-]", name,
+        SNTConnectivityFailureDescription(failure));
+  if (SNTConnectivityIsNetworkFailure(failure)) {
+    Print(@"    The server could not be reached. Check DNS, firewall and proxy settings between "
+          @"this host and the server.");
+  } else if (failure == SNTConnectivityFailureAuth) {
+    Print(@"    The server was reached but rejected this host's credentials.");
+  }
+}
+
+// Runs the TCP and (if applicable) TLS stages against the endpoint, printing the result of each.
+// Returns the stage that failed, or SNTConnectivityFailureNone if the endpoint was reachable.
+- (SNTConnectivityFailure)probeAddress:(NSString*)address {
+  NSString* host;
+  uint16_t port;
+  BOOL useTLS;
+  if (!SNTConnectivityParseEndpoint(address, &host, &port, &useTLS)) {
+    Print(@"[-] Unable to determine the host and port for %@", address);
+    return SNTConnectivityFailureUnknown;
+  }
+
+  SNTConnectivityProbeResult tcp = SNTConnectivityProbe(host, port, NO, self.timeout);
+  if (tcp.failure != SNTConnectivityFailureNone) {
+    Print(@"[-] Connecting to %@:%u failed after %.0f ms: %@", host, port, tcp.elapsedMs,
+          tcp.errorDescription ?: @"unknown error");
+    return tcp.failure;
+  }
+  Print(@"[+] Connected to %@:%u in %.0f ms", host, port, tcp.elapsedMs);
+
+  if (!useTLS) {
+    Print(@"[?] %@ does not use TLS", address);
+    return SNTConnectivityFailureNone;
+  }
+
+  SNTConnectivityProbeResult tls = SNTConnectivityProbe(host, port, YES, self.timeout);
+  if (tls.failure != SNTConnectivityFailureNone) {
+    Print(@"[-] TLS handshake with %@ failed after %.0f ms: %@", host, tls.elapsedMs,
+          tls.errorDescription ?: @"unknown error");
+    return tls.failure;
+  }
+  // The TLS probe opens a new connection, so subtract the TCP connect time measured above to
+  // approximate the cost of the handshake alone.
+  Print(@"[+] TLS handshake with %@ completed in %.0f ms", host,
+        MAX(tls.elapsedMs - tcp.elapsedMs, 0));
+  return SNTConnectivityFailureNone;
+}
+
+- (SNTConnectivityFailure)checkSyncServer:(NSURL*)syncBaseURL {
+  Print(@"=> Checking sync server (%@)...", syncBaseURL.absoluteString);
+
+  SNTConnectivityFailure failure = [self probeAddress:syncBaseURL.absoluteString];
+  if (failure != SNTConnectivityFailureNone) {
+    Print(@"");
+    return failure;
+  }
+
+  NSXPCListener* logListener = [NSXPCListener anonymousListener];
+  MOLXPCConnection* lr = [[MOLXPCConnection alloc] initServerWithListener:logListener];
+  lr.exportedObject = self;
+  lr.unprivilegedInterface =
+      [NSXPCInterface interfaceWithProtocol:@protocol(SNTSyncServiceLogReceiverXPC)];
+  [lr resume];
+
+  dispatch_semaphore_t sema = dispatch_semaphore_create(0);
+  __block NSInteger status = 0;
+  __block NSString* desc;
+  __block MOLCertificate* cert;
+  [self.syncConn.remoteObjectProxy
+      checkSyncServerStatus:logListener.endpoint
+                      reply:^(NSInteger statusCode, NSString* description,
+                              MOLCertificate* clientCertificate) {
+                        status = statusCode;
+                        desc = description;
+                        cert = clientCertificate;
+                        dispatch_semaphore_signal(sema);
+                      }];
+  // The sync service applies its own 30 second timeout to the preflight request.
+  if (dispatch_semaphore_wait(sema, dispatch_time(DISPATCH_TIME_NOW, 45 * NSEC_PER_SEC))) {
+    Print(@"[-] No response from the sync service");
+    Print(@"");
+    return SNTConnectivityFailureUnknown;
+  }
+
+  failure = SNTConnectivityClassifySyncStatus(status);
+  switch (failure) {
+    case SNTConnectivityFailureNone: Print(@"[+] Authenticated preflight request succeeded"); break;
+    case SNTConnectivityFailureAuth:
+      Print(@"[-] Sync server rejected this host's credentials: HTTP %ld %@", (long)status,
+            desc ?: @"");
+      if (cert) {
+        BOOL expired = [cert.validUntil compare:[NSDate date]] == NSOrderedAscending;
+        Print(@"[-] Client certificate presented: %@%@", cert.commonName ?: @"<unknown>",
+              expired ? @" (expired)" : @"");
+      }
+      break;
+    case SNTConnectivityFailureServer:
+      Print(@"[-] Sync server returned an error: HTTP %ld %@", (long)status, desc ?: @"");
+      break;
+    default: Print(@"[-] Preflight request failed: %@", desc ?: @"unknown error"); break;
+  }
+  Print(@"");
+  return failure;
+}
+
+// Checks the push server, if the NATS push client is enabled. On return, address contains the push
+// server address, or nil if push notifications are not in use.
+- (SNTConnectivityFailure)checkPushServer:(NSString**)address {
+  id<SNTSyncServiceXPC> proxy = self.syncConn.remoteObjectProxy;
+  dispatch_semaphore_t sema = dispatch_semaphore_create(0);
+  __block NSString* serverAddress;
+  __block SNTPushNotificationStatus pushStatus = SNTPushNotificationStatusUnknown;
+  // pushNotificationServerAddress: only answers while connected, so take the address from the
+  // diagnostics snapshot instead to also cover a client the server has rejected.
+  [proxy pushNotificationDiagnostics:^(NSDictionary* diagnostics) {
+    if ([diagnostics[kPushDiagnosticsEnabled] boolValue]) {
+      serverAddress = diagnostics[kPushDiagnosticsServer];
+    }
+    [proxy pushNotificationStatus:^(SNTPushNotificationStatus s) {
+      pushStatus = s;
+      dispatch_semaphore_signal(sema);
+    }];
+  }];
+  if (dispatch_semaphore_wait(sema, dispatch_time(DISPATCH_TIME_NOW, 10 * NSEC_PER_SEC))) {
+    Print(@"=> Checking push server...");
+    Print(@"[-] No response from the sync service");
+    Print(@"");
+    // The address is unknown, but push must still be included in the summary.
+    *address = @"";
+    return SNTConnectivityFailureUnknown;
+  }
+
+  *address = serverAddress;
+  if (!serverAddress.length) {
+    // Either push notifications are disabled or the push client is not using NATS, in which case
+    // there is no server address to check.
+    *address = nil;
+    return SNTConnectivityFailureNone;
+  }
+
+  Print(@"=> Checking push server (%@)...", serverAddress);
+  SNTConnectivityFailure failure = [self probeAddress:serverAddress];
+  if (failure != SNTConnectivityFailureNone) {
+    Print(@"");
+    return failure;
+  }
+
+  failure = SNTConnectivityClassifyPushStatus(pushStatus);
+  switch (failure) {
+    case SNTConnectivityFailureNone: Print(@"[+] Push client is connected"); break;
+    case SNTConnectivityFailureAuth:
+      Print(@"[-] Push client is not connected, the server may have rejected its credentials");
+      break;
+    default: Print(@"[-] Unable to determine the push client's connection state"); break;
+  }
+  Print(@"");
+  return failure;
+}
+
+/// Implement the SNTSyncServiceLogReceiverXPC protocol.
+- (void)didReceiveLog:(NSString*)log withType:(os_log_type_t)logType {
+  if (logType == OS_LOG_TYPE_DEBUG) return;
+  Print(@"    %@", log);
+}
+
+@end
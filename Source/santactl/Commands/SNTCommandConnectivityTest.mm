@@ -0,0 +1,229 @@
+/// Copyright 2026 North Pole Security, Inc.
+///
+/// Licensed under the Apache License, Version 2.0 (the "License");
+/// you may not use this file except in compliance with the License.
+/// You may obtain a copy of the License at
+///
+///     http://www.apache.org/licenses/LICENSE-2.0
+///
+/// Unless required by applicable law or agreed to in writing, software
+/// distributed under the License is distributed on an "AS IS" BASIS,
+/// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+/// See the License for the specific language governing permissions and
+/// limitations under the License.
+
+#import <Network/Network.h>
+#import <XCTest/XCTest.h>
+
+#include <arpa/inet.h>
+#include <netinet/in.h>
+#include <sys/socket.h>
+#include <unistd.h>
+
+#import "Source/common/SNTCommonEnums.h"
+
+// Defined in SNTCommandConnectivity.mm.
+typedef NS_ENUM(NSInteger, SNTConnectivityFailure) {
+  SNTConnectivityFailureNone,
+  SNTConnectivityFailureDNS,
+  SNTConnectivityFailureTCP,
+  SNTConnectivityFailureTLS,
+  SNTConnectivityFailureAuth,
+  SNTConnectivityFailureServer,
+  SNTConnectivityFailureUnknown,
+};
+
+struct SNTConnectivityProbeResult {
+  SNTConnectivityFailure failure;
+  double elapsedMs;
+  NSString* errorDescription;
+};
+
+extern BOOL SNTConnectivityIsNetworkFailure(SNTConnectivityFailure failure);
+extern NSString* SNTConnectivityFailureDescription(SNTConnectivityFailure failure);
+extern SNTConnectivityFailure SNTConnectivityClassifyNetworkErrorDomain(nw_error_domain_t domain);
+extern SNTConnectivityFailure SNTConnectivityClassifySyncStatus(NSInteger statusCode);
+extern SNTConnectivityFailure SNTConnectivityClassifyPushStatus(SNTPushNotificationStatus status);
+extern BOOL SNTConnectivityParseEndpoint(NSString* address, NSString** host, uint16_t* port,
+                                         BOOL* useTLS);
+extern SNTConnectivityProbeResult SNTConnectivityProbe(NSString* host, uint16_t port, BOOL useTLS,
+                                                       NSTimeInterval timeout);
+
+@interface SNTCommandConnectivityTest : XCTestCase
+@property int listenFD;
+@end
+
+@implementation SNTCommandConnectivityTest
+
+- (void)setUp {
+  self.listenFD = -1;
+}
+
+- (void)tearDown {
+  if (self.listenFD >= 0) close(self.listenFD);
+}
+
+// Binds a TCP socket to an ephemeral port on the loopback interface and returns the port. If
+// listening is NO the socket is closed again, leaving a port that refuses connections.
+- (uint16_t)bindLoopbackListening:(BOOL)listening {
+  int fd = socket(AF_INET, SOCK_STREAM, 0);
+  XCTAssertGreaterThanOrEqual(fd, 0);
+
+  struct sockaddr_in addr = {};
+  addr.sin_family = AF_INET;
+  addr.sin_addr.s_addr = htonl(INADDR_LOOPBACK);
+  addr.sin_port = 0;
+  XCTAssertEqual(bind(fd, (struct sockaddr*)&addr, sizeof(addr)), 0);
+
+  socklen_t len = sizeof(addr);
+  XCTAssertEqual(getsockname(fd, (struct sockaddr*)&addr, &len), 0);
+
+  if (listening) {
+    XCTAssertEqual(listen(fd, 4), 0);
+    self.listenFD = fd;
+  } else {
+    close(fd);
+  }
+  return ntohs(addr.sin_port);
+}
+
+#pragma mark Endpoint parsing
+
+- (void)testParseSyncURLUsesHTTPSDefaultPort {
+  NSString* host;
+  uint16_t port;
+  BOOL useTLS;
+  XCTAssertTrue(
+      SNTConnectivityParseEndpoint(@"https://sync.example.com/santa/", &host, &port, &useTLS));
+  XCTAssertEqualObjects(host, @"sync.example.com");
+  XCTAssertEqual(port, 443);
+  XCTAssertTrue(useTLS);
+}
+
+- (void)testParseLocalhostHTTPURL {
+  NSString* host;
+  uint16_t port;
+  BOOL useTLS;
+  XCTAssertTrue(SNTConnectivityParseEndpoint(@"http://localhost:8080/", &host, &port, &useTLS));
+  XCTAssertEqualObjects(host, @"localhost");
+  XCTAssertEqual(port, 8080);
+  XCTAssertFalse(useTLS);
+}
+
+- (void)testParsePushServerAddress {
+  NSString* host;
+  uint16_t port;
+  BOOL useTLS;
+  XCTAssertTrue(SNTConnectivityParseEndpoint(@"tls://workshop.push.northpole.security:443", &host,
+                                             &port, &useTLS));
+  XCTAssertEqualObjects(host, @"workshop.push.northpole.security");
+  XCTAssertEqual(port, 443);
+  XCTAssertTrue(useTLS);
+
+  XCTAssertTrue(SNTConnectivityParseEndpoint(@"nats://localhost", &host, &port, &useTLS));
+  XCTAssertEqual(port, 4222);
+  XCTAssertFalse(useTLS);
+}
+
+- (void)testParseRejectsInvalidAddresses {
+  NSString* host;
+  uint16_t port;
+  BOOL useTLS;
+  XCTAssertFalse(SNTConnectivityParseEndpoint(@"", &host, &port, &useTLS));
+  XCTAssertFalse(SNTConnectivityParseEndpoint(@"not a url", &host, &port, &useTLS));
+  XCTAssertFalse(SNTConnectivityParseEndpoint(@"ftp://example.com", &host, &port, &useTLS));
+}
+
+#pragma mark Failure classification
+
+- (void)testNetworkErrorDomains {
+  XCTAssertEqual(SNTConnectivityClassifyNetworkErrorDomain(nw_error_domain_dns),
+                 SNTConnectivityFailureDNS);
+  XCTAssertEqual(SNTConnectivityClassifyNetworkErrorDomain(nw_error_domain_posix),
+                 SNTConnectivityFailureTCP);
+  XCTAssertEqual(SNTConnectivityClassifyNetworkErrorDomain(nw_error_domain_tls),
+                 SNTConnectivityFailureTLS);
+  XCTAssertEqual(SNTConnectivityClassifyNetworkErrorDomain(nw_error_domain_invalid),
+                 SNTConnectivityFailureUnknown);
+}
+
+- (void)testSyncStatusCodes {
+  XCTAssertEqual(SNTConnectivityClassifySyncStatus(200), SNTConnectivityFailureNone);
+  XCTAssertEqual(SNTConnectivityClassifySyncStatus(400), SNTConnectivityFailureNone);
+  XCTAssertEqual(SNTConnectivityClassifySyncStatus(401), SNTConnectivityFailureAuth);
+  XCTAssertEqual(SNTConnectivityClassifySyncStatus(403), SNTConnectivityFailureAuth);
+  XCTAssertEqual(SNTConnectivityClassifySyncStatus(500), SNTConnectivityFailureServer);
+  XCTAssertEqual(SNTConnectivityClassifySyncStatus(301), SNTConnectivityFailureServer);
+  XCTAssertEqual(SNTConnectivityClassifySyncStatus(0), SNTConnectivityFailureUnknown);
+}
+
+- (void)testPushStatuses {
+  XCTAssertEqual(SNTConnectivityClassifyPushStatus(SNTPushNotificationStatusConnected),
+                 SNTConnectivityFailureNone);
+  XCTAssertEqual(SNTConnectivityClassifyPushStatus(SNTPushNotificationStatusConnectedNATS),
+                 SNTConnectivityFailureNone);
+  XCTAssertEqual(SNTConnectivityClassifyPushStatus(SNTPushNotificationStatusDisconnected),
+                 SNTConnectivityFailureAuth);
+  XCTAssertEqual(SNTConnectivityClassifyPushStatus(SNTPushNotificationStatusUnknown),
+                 SNTConnectivityFailureUnknown);
+}
+
+- (void)testNetworkFailuresAreSeparatedFromAuthFailures {
+  XCTAssertTrue(SNTConnectivityIsNetworkFailure(SNTConnectivityFailureDNS));
+  XCTAssertTrue(SNTConnectivityIsNetworkFailure(SNTConnectivityFailureTCP));
+  XCTAssertTrue(SNTConnectivityIsNetworkFailure(SNTConnectivityFailureTLS));
+  XCTAssertFalse(SNTConnectivityIsNetworkFailure(SNTConnectivityFailureNone));
+  XCTAssertFalse(SNTConnectivityIsNetworkFailure(SNTConnectivityFailureAuth));
+  XCTAssertFalse(SNTConnectivityIsNetworkFailure(SNTConnectivityFailureServer));
+
+  XCTAssertEqualObjects(SNTConnectivityFailureDescription(SNTConnectivityFailureTLS),
+                        @"network failure (TLS handshake)");
+  XCTAssertEqualObjects(SNTConnectivityFailureDescription(SNTConnectivityFailureAuth),
+                        @"authentication failure");
+}
+
+#pragma mark Probing
+
+- (void)testProbeSucceedsAgainstListeningPort {
+  uint16_t port = [self bindLoopbackListening:YES];
+  SNTConnectivityProbeResult result = SNTConnectivityProbe(@"127.0.0.1", port, NO, 5);
+  XCTAssertEqual(result.failure, SNTConnectivityFailureNone);
+  XCTAssertNil(result.errorDescription);
+  XCTAssertGreaterThanOrEqual(result.elapsedMs, 0);
+}
+
+- (void)testProbeReportsTCPFailureForClosedPort {
+  uint16_t port = [self bindLoopbackListening:NO];
+  SNTConnectivityProbeResult result = SNTConnectivityProbe(@"127.0.0.1", port, NO, 5);
+  XCTAssertEqual(result.failure, SNTConnectivityFailureTCP);
+  XCTAssertNotNil(result.errorDescription);
+}
+
+- (void)testProbeReportsTLSFailureForPlaintextServer {
+  uint16_t port = [self bindLoopbackListening:YES];
+
+  // Answer the TLS ClientHello with a plaintext HTTP response, as a misconfigured proxy would.
+  int listenFD = self.listenFD;
+  dispatch_async(dispatch_get_global_queue(QOS_CLASS_DEFAULT, 0), ^{
+    int fd = accept(listenFD, NULL, NULL);
+    if (fd < 0) return;
+    const char response[] = "HTTP/1.1 400 Bad Request\r\nContent-Length: 0\r\n\r\n";
+    write(fd, response, sizeof(response) - 1);
+    sleep(1);
+    close(fd);
+  });
+
+  SNTConnectivityProbeResult result = SNTConnectivityProbe(@"127.0.0.1", port, YES, 5);
+  XCTAssertEqual(result.failure, SNTConnectivityFailureTLS);
+  XCTAssertTrue(SNTConnectivityIsNetworkFailure(result.failure));
+}
+
+- (void)testProbeTimeoutIsAttributedToTheStage {
+  // The listener never accepts or responds, so the TLS handshake cannot complete.
+  uint16_t port = [self bindLoopbackListening:YES];
+  SNTConnectivityProbeResult result = SNTConnectivityProbe(@"127.0.0.1", port, YES, 1);
+  XCTAssertEqual(result.failure, SNTConnectivityFailureTLS);
+  XCTAssertEqualObjects(result.errorDescription, @"timed out after 1 seconds");
+}
+
+@end
@@ -0,0 +1,132 @@
+/// Copyright 2026 North Pole Security, Inc.
+///
+/// Licensed under the Apache License, Version 2.0 (the "License");
+/// you may not use this file except in compliance with the License.
+/// You may obtain a copy of the License at
+///
+///     http://www.apache.org/licenses/LICENSE-2.0
+///
+/// Unless required by applicable law or agreed to in writing, software
+/// distributed under the License is distributed on an "AS IS" BASIS,
+/// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+/// See the License for the specific language governing permissions and
+/// limitations under the License.
+
+
+#import <Foundation/Foundation.h>
+#include <os/log.h>
+
+#import "Source/common/MOLXPCConnection.h"
+#import "Source/common/SNTDropRootPrivs.h"
+#import "Source/common/SNTLogging.h"
+#import "Source/common/SNTSyncConstants.h"
+#import "Source/common/SNTXPCSyncServiceInterface.h"
+#import "Source/santactl/SNTCommand.h"
+#import "Source/santactl/SNTCommandController.h"
+
+@interface SNTCommandEnrollTest : SNTCommand <SNTCommandProtocol, SNTSyncServiceLogReceiverXPC>
+@property BOOL enableDebugLogging;
+@end
+
+@implementation SNTCommandEnrollTest
+
+REGISTER_COMMAND_NAME(@"enroll-test")
+
+#pragma mark SNTCommand protocol methods
+
++ (BOOL)requiresRoot {
+  return NO;
+}
+
++ (BOOL)requiresDaemonConn {
+  return NO;  // We talk directly with the syncservice.
+}
+
++ (NSString*)shortHelpText {
+  return @"Tests the enrollment flow against a sync server.";
+}
+
++ (NSString*)longHelpText {
+  return (@"Simulates the enrollment of a fresh host against the given sync server and reports\n"
+          @"the result of each phase: a clean sync preflight, a full rule download, an initial\n"
+          @"event upload and, if the server returns push credentials, a push connection. A\n"
+          @"throwaway machine ID is used and no postflight is sent, so the server's record of\n"
+          @"this host is untouched. Nothing received from the server is applied to this host\n"
+          @"and the regular sync schedule is not affected.\n\n"
+          @"Options:\n"
+          @"  --sync-url <url>: The sync server to enroll against.\n"
+          @"  --debug: Enable verbose output.\n");
+}
+
+- (void)runWithArguments:(NSArray*)arguments {
+  // Ensure we have no privileges
+  if (!DropRootPrivileges()) {
+    TEE_LOGE(@"Failed to drop root privileges. Exiting.");
+    exit(1);
+  }
+
+  NSURL* syncURL;
+  for (NSUInteger i = 0; i < arguments.count; ++i) {
+    NSString* arg = arguments[i];
+    if ([arg caseInsensitiveCompare:@"--sync-url"] == NSOrderedSame) {
+      if (++i > arguments.count - 1) {
+        [self printErrorUsageAndExit:@"--sync-url requires an argument"];
+      }
+      syncURL = [NSURL URLWithString:arguments[i]];
+    } else if ([arg caseInsensitiveCompare:@"--debug"] == NSOrderedSame) {
+      self.enableDebugLogging = YES;
+    } else {
+      [self printErrorUsageAndExit:[@"Unknown argument: " stringByAppendingString:arg]];
+    }
+  }
+
+  if (!syncURL.scheme.length || !syncURL.host.length) {
+    [self printErrorUsageAndExit:@"--sync-url must be a valid URL"];
+  }
+
+  MOLXPCConnection* ss = [SNTXPCSyncServiceInterface configuredConnection];
+  ss.invalidationHandler = ^(void) {
+    TEE_LOGE(@"Failed to connect to the sync service.");
+    exit(1);
+  };
+  [ss resume];
+
+  NSXPCListener* logListener = [NSXPCListener anonymousListener];
+  MOLXPCConnection* lr = [[MOLXPCConnection alloc] initServerWithListener:logListener];
+  lr.exportedObject = self;
+  lr.unprivilegedInterface =
+      [NSXPCInterface interfaceWithProtocol:@protocol(SNTSyncServiceLogReceiverXPC)];
+  [lr resume];
+
+  [[ss remoteObjectProxy]
+      enrollmentTestWithSyncURL:syncURL
+                    logListener:logListener.endpoint
+                          reply:^(NSArray<NSDictionary*>* phases) {
+                            BOOL failed = NO;
+                            for (NSDictionary* phase in phases) {
+                              NSString* status = phase[kEnrollmentTestStatus];
+                              if ([status isEqualToString:kEnrollmentTestStatusFailed]) {
+                                failed = YES;
+                              }
+                              NSString* detail = phase[kEnrollmentTestDetail];
+                              printf("%-12s %-8s %s\n", [phase[kEnrollmentTestPhase] UTF8String],
+                                     status.uppercaseString.UTF8String,
+                                     detail.length ? detail.UTF8String : "");
+                            }
+                            exit(failed || !phases.count ? 1 : 0);
+                          }];
+
+  // Do not return from this scope.
+  [[NSRunLoop mainRunLoop] run];
+}
+
+/// Implement the SNTSyncServiceLogReceiverXPC protocol.
+- (void)didReceiveLog:(NSString*)log withType:(os_log_type_t)logType {
+  if (logType == OS_LOG_TYPE_DEBUG && !self.enableDebugLogging) {
+    return;
+  }
+  printf("%s\n", log.UTF8String);
+  fflush(stdout);
+}
+
+@end
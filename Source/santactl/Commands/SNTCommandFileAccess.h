@@ -0,0 +1,61 @@
+/// Copyright 2026 North Pole Security, Inc.
+///
+/// Licensed under the Apache License, Version 2.0 (the "License");
+/// you may not use this file except in compliance with the License.
+/// You may obtain a copy of the License at
+///
+///     http://www.apache.org/licenses/LICENSE-2.0
+///
+/// Unless required by applicable law or agreed to in writing, software
+/// distributed under the License is distributed on an "AS IS" BASIS,
+/// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+/// See the License for the specific language governing permissions and
+/// limitations under the License.
+
+
+#import <Foundation/Foundation.h>
+
+#include "Source/common/SNTCommonEnums.h"
+#import "Source/santactl/SNTCommand.h"
+#import "Source/santactl/SNTCommandController.h"
+
+///
+///  Whether a previewed rule is a data (path-centric) or process (process-centric) rule.
+///
+typedef NS_ENUM(NSInteger, SNTFileAccessPreviewRuleKind) {
+  SNTFileAccessPreviewRuleKindData,
+  SNTFileAccessPreviewRuleKindProcess,
+};
+
+///
+///  The decision a single rule in a candidate config produced for the previewed access.
+///
+@interface SNTFileAccessPreviewRuleResult : NSObject
+@property(readonly) NSString* ruleName;
+@property(readonly) SNTFileAccessPreviewRuleKind kind;
+@property(readonly) FileAccessPolicyDecision decision;
+@end
+
+///
+///  The outcome of previewing an access against a candidate config. The overall decision is
+///  kNoPolicy if no rule applied, otherwise any denial takes precedence over an allow.
+///
+@interface SNTFileAccessPreview : NSObject
+@property(readonly) NSArray<SNTFileAccessPreviewRuleResult*>* ruleResults;
+@property(readonly) FileAccessPolicyDecision decision;
+@end
+
+@interface SNTCommandFileAccess : SNTCommand <SNTCommandProtocol>
+
+///
+///  Evaluate how the given file access policy config would handle processPath accessing
+///  accessPath, using the same matching logic as santad. If readOnly is YES the access is
+///  treated as a read, otherwise as a write. Returns nil and sets error if the config is invalid.
+///
++ (SNTFileAccessPreview*)previewConfig:(NSDictionary*)config
+                            accessPath:(NSString*)accessPath
+                           processPath:(NSString*)processPath
+                              readOnly:(BOOL)readOnly
+                                 error:(NSError**)error;
+
+@end
@@ -0,0 +1,414 @@
+/// Copyright 2026 North Pole Security, Inc.
+///
+/// Licensed under the Apache License, Version 2.0 (the "License");
+/// you may not use this file except in compliance with the License.
+/// You may obtain a copy of the License at
+///
+///     http://www.apache.org/licenses/LICENSE-2.0
+///
+/// Unless required by applicable law or agreed to in writing, software
+/// distributed under the License is distributed on an "AS IS" BASIS,
+/// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+/// See the License for the specific language governing permissions and
+/// limitations under the License.
+
+
+#import "Source/santactl/Commands/SNTCommandFileAccess.h"
+
+#include <EndpointSecurity/EndpointSecurity.h>
+#include <Kernel/kern/cs_blobs.h>
+
+#include <cstdlib>
+#include <cstring>
+#include <memory>
+#include <optional>
+#include <string>
+#include <vector>
+
+#import "Source/common/MOLCertificate.h"
+#import "Source/common/MOLCodesignChecker.h"
+#include "Source/common/PathCanonicalization.h"
+#import "Source/common/SNTConfigurator.h"
+#import "Source/common/SNTLogging.h"
+#include "Source/common/String.h"
+#include "Source/common/faa/WatchItemMatcher.h"
+#include "Source/common/faa/WatchItemPolicy.h"
+#include "Source/common/faa/WatchItems.h"
+
+using santa::DecisionForPolicyMatch;
+using santa::ProcessWatchItemPolicy;
+using santa::WatchItemPolicyBase;
+using santa::WatchItemProcess;
+using santa::WatchItemProcessMatches;
+using santa::WatchItems;
+
+static NSString* const kFileAccessPreviewErrorDomain =
+    @"com.northpolesec.santa.santactl.fileaccess";
+
+static inline bool IsDenied(FileAccessPolicyDecision decision) {
+  return decision == FileAccessPolicyDecision::kDenied ||
+         decision == FileAccessPolicyDecision::kDeniedInvalidSignature;
+}
+
+@interface SNTFileAccessPreviewRuleResult ()
+@property NSString* ruleName;
+@property SNTFileAccessPreviewRuleKind kind;
+@property FileAccessPolicyDecision decision;
+@end
+
+@implementation SNTFileAccessPreviewRuleResult
+@end
+
+@interface SNTFileAccessPreview ()
+@property NSMutableArray<SNTFileAccessPreviewRuleResult*>* ruleResults;
+@property FileAccessPolicyDecision decision;
+@end
+
+@implementation SNTFileAccessPreview
+
+- (instancetype)init {
+  self = [super init];
+  if (self) {
+    _ruleResults = [NSMutableArray array];
+    _decision = FileAccessPolicyDecision::kNoPolicy;
+  }
+  return self;
+}
+
+- (void)addRule:(const WatchItemPolicyBase&)policy
+           kind:(SNTFileAccessPreviewRuleKind)kind
+       decision:(FileAccessPolicyDecision)decision {
+  SNTFileAccessPreviewRuleResult* result = [[SNTFileAccessPreviewRuleResult alloc] init];
+  result.ruleName = santa::StringToNSString(policy.name);
+  result.kind = kind;
+  result.decision = decision;
+  [self.ruleResults addObject:result];
+
+  // A denial from any rule takes precedence, otherwise keep the first decision reached.
+  if (self.decision == FileAccessPolicyDecision::kNoPolicy ||
+      (IsDenied(decision) && !IsDenied(self.decision))) {
+    self.decision = decision;
+  }
+}
+
+@end
+
+///
+///  Holds an es_process_t describing a binary on disk, along with the storage its string
+///  tokens point into, so the shared watch item matcher can be run against it.
+///
+@interface SNTFileAccessPreviewProcess : NSObject
+@property(readonly) MOLCodesignChecker* csInfo;
+@property(readonly) NSString* path;
+- (const es_process_t*)esProcess;
+@end
+
+@implementation SNTFileAccessPreviewProcess {
+  std::string _path;
+  std::string _teamID;
+  std::string _signingID;
+  es_file_t _esFile;
+  es_process_t _esProc;
+}
+
+- (instancetype)initWithPath:(NSString*)path {
+  self = [super init];
+  if (self) {
+    _path = santa::NSStringToUTF8String(path);
+
+    NSError* error;
+    _csInfo = [[MOLCodesignChecker alloc] initWithBinaryPath:path error:&error];
+
+    _esFile = {};
+    _esFile.path = {.length = _path.length(), .data = _path.c_str()};
+
+    _esProc = {};
+    _esProc.executable = &_esFile;
+
+    if (_csInfo && error.code != errSecCSUnsigned) {
+      // Mirror what ES reports: signed processes with signature problems lack CS_VALID.
+      _esProc.codesigning_flags = CS_SIGNED | (error ? 0 : CS_VALID);
+      _esProc.is_platform_binary = _csInfo.platformBinary;
+
+      _teamID = santa::NSStringToUTF8String(_csInfo.teamID ?: @"");
+      _signingID = santa::NSStringToUTF8String(_csInfo.signingID ?: @"");
+      if (_csInfo.teamID) {
+        _esProc.team_id = {.length = _teamID.length(), .data = _teamID.c_str()};
+      }
+      if (_csInfo.signingID) {
+        _esProc.signing_id = {.length = _signingID.length(), .data = _signingID.c_str()};
+      }
+
+      std::vector<uint8_t> cdhash = santa::HexStringToBuf(_csInfo.cdhash);
+      if (cdhash.size() == CS_CDHASH_LEN) {
+        std::memcpy(_esProc.cdhash, cdhash.data(), CS_CDHASH_LEN);
+      }
+    }
+  }
+  return self;
+}
+
+- (NSString*)path {
+  return santa::StringToNSString(_path);
+}
+
+- (const es_process_t*)esProcess {
+  return &_esProc;
+}
+
+- (bool)matchesPolicyProcess:(const WatchItemProcess&)policyProc {
+  return WatchItemProcessMatches(
+      policyProc, &_esProc,
+      ^NSString* {
+        return self.csInfo.leafCertificate.SHA256;
+      },
+      ^bool(const std::string& policy_path) {
+        if (![[SNTConfigurator configurator] canonicalizeExecutablePaths]) {
+          return false;
+        }
+        return [santa::CanonicalPath(self.path)
+            isEqualToString:santa::CanonicalPath(santa::StringToNSString(policy_path))];
+      });
+}
+
+- (bool)matchesAnyPolicyProcess:(const WatchItemPolicyBase&)policy {
+  for (const WatchItemProcess& policyProc : policy.processes) {
+    if ([self matchesPolicyProcess:policyProc]) {
+      return true;
+    }
+  }
+  return false;
+}
+
+@end
+
+@implementation SNTCommandFileAccess
+
+REGISTER_COMMAND_NAME(@"fileaccess")
+
++ (BOOL)requiresRoot {
+  return NO;
+}
+
++ (BOOL)requiresDaemonConn {
+  return NO;
+}
+
++ (NSString*)shortHelpText {
+  return @"Test file access policies before deploying them.";
+}
+
++ (NSString*)longHelpText {
+  return (@"Usage: santactl fileaccess <command> [options]\n"
+          @"  One of:\n"
+          @"    preview: Evaluate a candidate file access policy against a single access\n"
+          @"             without loading it into santad.\n"
+          @"\n"
+          @"  Preview Options:\n"
+          @"    --policy {path}: A file access policy plist to evaluate. Required.\n"
+          @"    --access {path}: The path being accessed. Required.\n"
+          @"    --process {path}: The binary performing the access. Required.\n"
+          @"    --read: Treat the access as a read. By default the access is a write.\n"
+          @"\n"
+          @"  The policy is validated with the same rules santad uses and each matching\n"
+          @"  rule is reported along with the overall decision. Exits non-zero if the\n"
+          @"  policy is invalid or the access would be denied.\n");
+}
+
++ (NSString*)descriptionForDecision:(FileAccessPolicyDecision)decision {
+  switch (decision) {
+    case FileAccessPolicyDecision::kNoPolicy: return @"no policy";
+    case FileAccessPolicyDecision::kDenied: return @"denied";
+    case FileAccessPolicyDecision::kDeniedInvalidSignature: return @"denied (invalid signature)";
+    case FileAccessPolicyDecision::kAllowed: return @"allowed";
+    case FileAccessPolicyDecision::kAllowedReadAccess: return @"allowed (read access)";
+    case FileAccessPolicyDecision::kAllowedAuditOnly: return @"allowed (audit only)";
+  }
+}
+
+- (void)runWithArguments:(NSArray*)arguments {
+  if (!arguments.count) {
+    [self printErrorUsageAndExit:@"No arguments"];
+  }
+
+  enum class Operation {
+    kUnknown,
+    kPreview,
+  };
+
+  Operation operation = Operation::kUnknown;
+  NSString* arg = arguments[0];
+
+  if ([arg caseInsensitiveCompare:@"preview"] == NSOrderedSame) {
+    operation = Operation::kPreview;
+  } else {
+    [self printErrorUsageAndExit:[@"Unknown operation: " stringByAppendingString:arg]];
+  }
+
+  NSArray* operationArgs = [arguments subarrayWithRange:NSMakeRange(1, arguments.count - 1)];
+
+  switch (operation) {
+    case Operation::kPreview: {
+      [self previewWithArguments:operationArgs];
+      break;
+    }
+    default: [self printErrorUsageAndExit:@"No operation provided"];
+  }
+
+  // Individual operation handlers control exiting with success or failure
+  exit(EXIT_FAILURE);
+}
+
+#pragma mark preview
+
++ (FileAccessPolicyDecision)decisionForPolicy:(const WatchItemPolicyBase&)policy
+                                      process:(SNTFileAccessPreviewProcess*)process
+                                     readOnly:(BOOL)readOnly
+                                      matched:(bool (^)(void))matched {
+  // Apply the same precedence as santad: invalid signatures, then reads, then the rule itself.
+  if ((process.esProcess->codesigning_flags & (CS_SIGNED | CS_VALID)) == CS_SIGNED &&
+      [[SNTConfigurator configurator] enableBadSignatureProtection]) {
+    return FileAccessPolicyDecision::kDeniedInvalidSignature;
+  }
+
+  if (readOnly && policy.allow_read_access) {
+    return FileAccessPolicyDecision::kAllowedReadAccess;
+  }
+
+  return DecisionForPolicyMatch(policy, matched());
+}
+
++ (SNTFileAccessPreview*)previewConfig:(NSDictionary*)config
+                            accessPath:(NSString*)accessPath
+                           processPath:(NSString*)processPath
+                              readOnly:(BOOL)readOnly
+                                 error:(NSError**)error {
+  if (!WatchItems::IsValidConfig(config, error)) {
+    return nil;
+  }
+
+  if (![[NSFileManager defaultManager] fileExistsAtPath:processPath]) {
+    if (error) {
+      *error = [NSError
+          errorWithDomain:kFileAccessPreviewErrorDomain
+                     code:ENOENT
+                 userInfo:@{
+                   NSLocalizedDescriptionKey :
+                       [NSString stringWithFormat:@"Process binary does not exist: %@", processPath]
+                 }];
+    }
+    return nil;
+  }
+
+  // The timer is never started, rules are built synchronously by SetConfig.
+  std::shared_ptr<WatchItems> watchItems = WatchItems::CreateFromEmbeddedConfig(config, 0);
+  watchItems->SetConfig(config);
+
+  SNTFileAccessPreview* preview = [[SNTFileAccessPreview alloc] init];
+  SNTFileAccessPreviewProcess* process =
+      [[SNTFileAccessPreviewProcess alloc] initWithPath:processPath];
+  std::string target = santa::NSStringToUTF8String(accessPath);
+
+  // Data rules: find the rule watching the path, then check the process against it.
+  watchItems->FindPoliciesForTargets(^(santa::LookupPolicyBlock lookup) {
+    std::optional<std::shared_ptr<WatchItemPolicyBase>> policy = lookup(target);
+    if (!policy.has_value()) {
+      return;
+    }
+
+    FileAccessPolicyDecision decision = [self decisionForPolicy:**policy
+                                                        process:process
+                                                       readOnly:readOnly
+                                                        matched:^bool {
+                                                          return [process
+                                                              matchesAnyPolicyProcess:**policy];
+                                                        }];
+    [preview addRule:**policy kind:SNTFileAccessPreviewRuleKindData decision:decision];
+  });
+
+  // Process rules: find the rule watching the process, then check the path against it.
+  watchItems->IterateProcessPolicies(^bool(std::shared_ptr<ProcessWatchItemPolicy> policy) {
+    if (![process matchesAnyPolicyProcess:*policy]) {
+      return false;
+    }
+
+    FileAccessPolicyDecision decision = [self decisionForPolicy:*policy
+                                                        process:process
+                                                       readOnly:readOnly
+                                                        matched:^bool {
+                                                          return policy->tree->Contains(
+                                                              target.c_str());
+                                                        }];
+    [preview addRule:*policy kind:SNTFileAccessPreviewRuleKindProcess decision:decision];
+    return true;
+  });
+
+  return preview;
+}
+
+- (void)previewWithArguments:(NSArray*)arguments {
+  NSString* policyPath;
+  NSString* accessPath;
+  NSString* processPath;
+  BOOL readOnly = NO;
+
+  for (NSUInteger i = 0; i < arguments.count; ++i) {
+    NSString* arg = arguments[i];
+
+    if ([arg caseInsensitiveCompare:@"--policy"] == NSOrderedSame) {
+      if (++i > arguments.count - 1) {
+        [self printErrorUsageAndExit:@"--policy requires an argument"];
+      }
+      policyPath = arguments[i];
+    } else if ([arg caseInsensitiveCompare:@"--access"] == NSOrderedSame) {
+      if (++i > arguments.count - 1) {
+        [self printErrorUsageAndExit:@"--access requires an argument"];
+      }
+      accessPath = arguments[i];
+    } else if ([arg caseInsensitiveCompare:@"--process"] == NSOrderedSame) {
+      if (++i > arguments.count - 1) {
+        [self printErrorUsageAndExit:@"--process requires an argument"];
+      }
+      processPath = arguments[i];
+    } else if ([arg caseInsensitiveCompare:@"--read"] == NSOrderedSame) {
+      readOnly = YES;
+    } else {
+      [self printErrorUsageAndExit:[@"Unknown argument: " stringByAppendingString:arg]];
+    }
+  }
+
+  if (!policyPath.length || !accessPath.length || !processPath.length) {
+    [self printErrorUsageAndExit:@"--policy, --access and --process are required"];
+  }
+
+  NSDictionary* config = [NSDictionary dictionaryWithContentsOfFile:policyPath];
+  if (!config) {
+    TEE_LOGE(@"Unable to read policy plist: %@", policyPath);
+    exit(EXIT_FAILURE);
+  }
+
+  NSError* error;
+  SNTFileAccessPreview* preview = [[self class] previewConfig:config
+                                                   accessPath:accessPath
+                                                  processPath:processPath
+                                                     readOnly:readOnly
+                                                        error:&error];
+  if (!preview) {
+    TEE_LOGE(@"Invalid policy: %@", error.localizedDescription);
+    exit(EXIT_FAILURE);
+  }
+
+  printf("%s %s by %s\n", readOnly ? "Read of" : "Write to", accessPath.UTF8String,
+         processPath.UTF8String);
+  for (SNTFileAccessPreviewRuleResult* result in preview.ruleResults) {
+    printf("  %-7s rule %-30s %s\n",
+           result.kind == SNTFileAccessPreviewRuleKindData ? "data" : "process",
+           result.ruleName.UTF8String,
+           [[[self class] descriptionForDecision:result.decision] UTF8String]);
+  }
+  printf("Decision: %s\n", [[[self class] descriptionForDecision:preview.decision] UTF8String]);
+
+  exit(IsDenied(preview.decision) ? EXIT_FAILURE : EXIT_SUCCESS);
+}
+
+@end
@@ -0,0 +1,211 @@
+/// Copyright 2026 North Pole Security, Inc.
+///
+/// Licensed under the Apache License, Version 2.0 (the "License");
+/// you may not use this file except in compliance with the License.
+/// You may obtain a copy of the License at
+///
+///     http://www.apache.org/licenses/LICENSE-2.0
+///
+/// Unless required by applicable law or agreed to in writing, software
+/// distributed under the License is distributed on an "AS IS" BASIS,
+/// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+/// See the License for the specific language governing permissions and
+/// limitations under the License.
+
+
+#import <XCTest/XCTest.h>
+
+#include "Source/common/SNTCommonEnums.h"
+#include "Source/common/faa/WatchItems.h"
+#import "Source/santactl/Commands/SNTCommandFileAccess.h"
+
+@interface SNTCommandFileAccessTest : XCTestCase
+@property NSString* testDir;
+@property NSString* secretPath;
+@property NSString* otherPath;
+@end
+
+@implementation SNTCommandFileAccessTest
+
+- (void)setUp {
+  [super setUp];
+  self.testDir = [NSString
+      stringWithFormat:@"%@santa-fileaccess-preview-%d", NSTemporaryDirectory(), getpid()];
+  XCTAssertTrue([[NSFileManager defaultManager] createDirectoryAtPath:self.testDir
+                                          withIntermediateDirectories:YES
+                                                           attributes:nil
+                                                                error:nil]);
+
+  self.secretPath = [self.testDir stringByAppendingPathComponent:@"secret"];
+  self.otherPath = [self.testDir stringByAppendingPathComponent:@"other"];
+  XCTAssertTrue([[NSFileManager defaultManager] createFileAtPath:self.secretPath
+                                                        contents:nil
+                                                      attributes:nil]);
+  XCTAssertTrue([[NSFileManager defaultManager] createFileAtPath:self.otherPath
+                                                        contents:nil
+                                                      attributes:nil]);
+}
+
+- (void)tearDown {
+  [[NSFileManager defaultManager] removeItemAtPath:self.testDir error:nil];
+  [super tearDown];
+}
+
+- (NSDictionary*)configWithRules:(NSDictionary*)rules {
+  return @{
+    kWatchItemConfigKeyVersion : @"v0.1",
+    kWatchItemConfigKeyWatchItems : rules,
+  };
+}
+
+- (NSDictionary*)dataRuleAllowing:(NSString*)binaryPath options:(NSDictionary*)options {
+  return @{
+    kWatchItemConfigKeyPaths : @[ self.secretPath ],
+    kWatchItemConfigKeyOptions : options ?: @{},
+    kWatchItemConfigKeyProcesses : @[ @{kWatchItemConfigKeyProcessesBinaryPath : binaryPath} ],
+  };
+}
+
+- (SNTFileAccessPreview*)previewConfig:(NSDictionary*)config
+                                access:(NSString*)accessPath
+                               process:(NSString*)processPath
+                              readOnly:(BOOL)readOnly {
+  NSError* err;
+  SNTFileAccessPreview* preview = [SNTCommandFileAccess previewConfig:config
+                                                           accessPath:accessPath
+                                                          processPath:processPath
+                                                             readOnly:readOnly
+                                                                error:&err];
+  XCTAssertNotNil(preview, @"Unexpected error: %@", err);
+  return preview;
+}
+
+- (void)testInvalidConfigIsRejected {
+  NSError* err;
+  XCTAssertNil([SNTCommandFileAccess
+      previewConfig:@{kWatchItemConfigKeyWatchItems : @{}}
+         accessPath:self.secretPath
+        processPath:@"/bin/ls"
+           readOnly:NO
+              error:&err]);
+  XCTAssertNotNil(err);
+
+  err = nil;
+  XCTAssertNil([SNTCommandFileAccess
+      previewConfig:[self configWithRules:@{@"rule" : [self dataRuleAllowing:@"/bin/ls"
+                                                                     options:nil]}]
+         accessPath:self.secretPath
+        processPath:[self.testDir stringByAppendingPathComponent:@"does_not_exist"]
+           readOnly:NO
+              error:&err]);
+  XCTAssertNotNil(err);
+}
+
+- (void)testDataRule {
+  NSDictionary* config =
+      [self configWithRules:@{@"protect_secret" : [self dataRuleAllowing:@"/bin/ls" options:nil]}];
+
+  // Access by an allowed process
+  SNTFileAccessPreview* preview = [self previewConfig:config
+                                               access:self.secretPath
+                                              process:@"/bin/ls"
+                                             readOnly:NO];
+  XCTAssertEqual(preview.decision, FileAccessPolicyDecision::kAllowed);
+  XCTAssertEqual(preview.ruleResults.count, 1);
+  XCTAssertEqualObjects(preview.ruleResults[0].ruleName, @"protect_secret");
+  XCTAssertEqual(preview.ruleResults[0].kind, SNTFileAccessPreviewRuleKindData);
+
+  // Access by a process not in the rule
+  preview = [self previewConfig:config access:self.secretPath process:@"/bin/cat" readOnly:NO];
+  XCTAssertEqual(preview.decision, FileAccessPolicyDecision::kDenied);
+
+  // Access to a path no rule watches
+  preview = [self previewConfig:config access:self.otherPath process:@"/bin/cat" readOnly:NO];
+  XCTAssertEqual(preview.decision, FileAccessPolicyDecision::kNoPolicy);
+  XCTAssertEqual(preview.ruleResults.count, 0);
+}
+
+- (void)testDataRuleOptions {
+  // Reads are allowed when the rule permits read access
+  NSDictionary* config = [self configWithRules:@{
+    @"protect_secret" :
+        [self dataRuleAllowing:@"/bin/ls"
+                       options:@{kWatchItemConfigKeyOptionsAllowReadAccess : @YES}]
+  }];
+  SNTFileAccessPreview* preview = [self previewConfig:config
+                                               access:self.secretPath
+                                              process:@"/bin/cat"
+                                             readOnly:YES];
+  XCTAssertEqual(preview.decision, FileAccessPolicyDecision::kAllowedReadAccess);
+
+  preview = [self previewConfig:config access:self.secretPath process:@"/bin/cat" readOnly:NO];
+  XCTAssertEqual(preview.decision, FileAccessPolicyDecision::kDenied);
+
+  // Audit only rules never deny
+  config = [self configWithRules:@{
+    @"protect_secret" : [self dataRuleAllowing:@"/bin/ls"
+                                       options:@{kWatchItemConfigKeyOptionsAuditOnly : @YES}]
+  }];
+  preview = [self previewConfig:config access:self.secretPath process:@"/bin/cat" readOnly:NO];
+  XCTAssertEqual(preview.decision, FileAccessPolicyDecision::kAllowedAuditOnly);
+
+  // Denied process rule types invert the match
+  config = [self configWithRules:@{
+    @"protect_secret" :
+        [self dataRuleAllowing:@"/bin/ls"
+                       options:@{kWatchItemConfigKeyOptionsRuleType : @"PathsWithDeniedProcesses"}]
+  }];
+  preview = [self previewConfig:config access:self.secretPath process:@"/bin/ls" readOnly:NO];
+  XCTAssertEqual(preview.decision, FileAccessPolicyDecision::kDenied);
+  preview = [self previewConfig:config access:self.secretPath process:@"/bin/cat" readOnly:NO];
+  XCTAssertEqual(preview.decision, FileAccessPolicyDecision::kAllowed);
+}
+
+- (void)testProcessRule {
+  NSDictionary* config = [self configWithRules:@{
+    @"ls_denied_paths" : @{
+      kWatchItemConfigKeyPaths : @[ self.secretPath ],
+      kWatchItemConfigKeyOptions :
+          @{kWatchItemConfigKeyOptionsRuleType : @"ProcessesWithDeniedPaths"},
+      kWatchItemConfigKeyProcesses : @[ @{kWatchItemConfigKeyProcessesBinaryPath : @"/bin/ls"} ],
+    },
+  }];
+
+  SNTFileAccessPreview* preview = [self previewConfig:config
+                                               access:self.secretPath
+                                              process:@"/bin/ls"
+                                             readOnly:NO];
+  XCTAssertEqual(preview.decision, FileAccessPolicyDecision::kDenied);
+  XCTAssertEqual(preview.ruleResults.count, 1);
+  XCTAssertEqualObjects(preview.ruleResults[0].ruleName, @"ls_denied_paths");
+  XCTAssertEqual(preview.ruleResults[0].kind, SNTFileAccessPreviewRuleKindProcess);
+
+  // Paths outside the rule are allowed for the watched process
+  preview = [self previewConfig:config access:self.otherPath process:@"/bin/ls" readOnly:NO];
+  XCTAssertEqual(preview.decision, FileAccessPolicyDecision::kAllowed);
+
+  // Other processes aren't covered by the rule at all
+  preview = [self previewConfig:config access:self.secretPath process:@"/bin/cat" readOnly:NO];
+  XCTAssertEqual(preview.decision, FileAccessPolicyDecision::kNoPolicy);
+}
+
+- (void)testDenyTakesPrecedence {
+  NSDictionary* config = [self configWithRules:@{
+    @"protect_secret" : [self dataRuleAllowing:@"/bin/ls" options:nil],
+    @"ls_denied_paths" : @{
+      kWatchItemConfigKeyPaths : @[ self.secretPath ],
+      kWatchItemConfigKeyOptions :
+          @{kWatchItemConfigKeyOptionsRuleType : @"ProcessesWithDeniedPaths"},
+      kWatchItemConfigKeyProcesses : @[ @{kWatchItemConfigKeyProcessesBinaryPath : @"/bin/ls"} ],
+    },
+  }];
+
+  SNTFileAccessPreview* preview = [self previewConfig:config
+                                               access:self.secretPath
+                                              process:@"/bin/ls"
+                                             readOnly:NO];
+  XCTAssertEqual(preview.ruleResults.count, 2);
+  XCTAssertEqual(preview.decision, FileAccessPolicyDecision::kDenied);
+}
+
+@end
@@ -54,6 +54,8 @@ static NSString* const kUniversalSigningChain = @"Universal Signing Chain";
 static NSString* const kTeamID = @"Team ID";
 static NSString* const kSigningID = @"Signing ID";
 static NSString* const kCDHash = @"CDHash";
+static NSString* const kCodeSigningFlags = @"Code Signing Flags";
+static NSString* const kDesignatedRequirement = @"Designated Requirement";
 static NSString* const kEntitlements = @"Entitlements";
 static NSString* const kSecureSigningTime = @"Secure Signing Time";
 static NSString* const kSigningTime = @"Signing Time";
@@ -139,6 +141,7 @@ typedef id (^SNTAttributeBlock)(SNTCommandFileInfo*, SNTFileInfo*);
 @property(readonly, copy, nonatomic) SNTAttributeBlock teamID;
 @property(readonly, copy, nonatomic) SNTAttributeBlock signingID;
 @property(readonly, copy, nonatomic) SNTAttributeBlock cdhash;
+@property(readonly, copy, nonatomic) SNTAttributeBlock designatedRequirement;
 @property(readonly, copy, nonatomic) SNTAttributeBlock type;
 @property(readonly, copy, nonatomic) SNTAttributeBlock pageZero;
 @property(readonly, copy, nonatomic) SNTAttributeBlock codeSigned;
@@ -247,6 +250,8 @@ REGISTER_COMMAND_NAME(@"fileinfo")
     kTeamID,
     kSigningID,
     kCDHash,
+    kCodeSigningFlags,
+    kDesignatedRequirement,
     kType,
     kPageZero,
     kCodeSigned,
@@ -296,6 +301,8 @@ REGISTER_COMMAND_NAME(@"fileinfo")
       kTeamID : self.teamID,
       kSigningID : self.signingID,
       kCDHash : self.cdhash,
+      kCodeSigningFlags : self.codeSigningFlags,
+      kDesignatedRequirement : self.designatedRequirement,
       kEntitlements : self.entitlements,
       kSecureSigningTime : self.secureSigningTime,
       kSigningTime : self.signingTime,
@@ -669,6 +676,26 @@ static void ResumeDaemonConnection(SNTCommandFileInfo* cmd) {
   };
 }
 
+- (SNTAttributeBlock)codeSigningFlags {
+  return ^id(SNTCommandFileInfo* cmd, SNTFileInfo* fileInfo) {
+    NSError* error;
+    MOLCodesignChecker* csc = [fileInfo codesignCheckerWithError:&error];
+    if (!csc || error) return nil;
+    uint32_t flags = fileInfo.codeSignatureFlags;
+    NSArray<NSString*>* names = [SNTFileInfo namesForCodeSignatureFlags:flags];
+    return [NSString stringWithFormat:@"0x%x (%@)", flags,
+                                      names.count ? [names componentsJoinedByString:@", "]
+                                                  : @"none"];
+  };
+}
+
+- (SNTAttributeBlock)designatedRequirement {
+  return ^id(SNTCommandFileInfo* cmd, SNTFileInfo* fileInfo) {
+    MOLCodesignChecker* csc = [fileInfo codesignCheckerWithError:NULL];
+    return csc.designatedRequirement;
+  };
+}
+
 - (SNTAttributeBlock)entitlements {
   return ^id(SNTCommandFileInfo* cmd, SNTFileInfo* fileInfo) {
     MOLCodesignChecker* csc = [fileInfo codesignCheckerWithError:NULL];
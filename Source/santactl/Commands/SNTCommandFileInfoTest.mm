@@ -32,6 +32,7 @@ typedef id (^SNTAttributeBlock)(SNTCommandFileInfo*, SNTFileInfo*);
 + (NSArray*)fileInfoKeys;
 + (NSArray*)signingChainKeys;
 - (SNTAttributeBlock)codeSigned;
+- (SNTAttributeBlock)codeSigningFlags;
 - (instancetype)initWithDaemonConnection:(MOLXPCConnection*)daemonConn;
 - (NSArray*)parseArguments:(NSArray*)arguments;
 
@@ -252,6 +253,38 @@ typedef id (^SNTAttributeBlock)(SNTCommandFileInfo*, SNTFileInfo*);
   XCTAssertEqualObjects(self.cfi.codeSigned(self.cfi, self.fileInfo), expected);
 }
 
+- (void)testCodeSigningFlags {
+  OCMStub([self.cscMock initWithBinaryPath:OCMOCK_ANY error:[OCMArg setTo:nil]])
+      .andReturn(self.cscMock);
+  OCMStub([self.cscMock signatureFlags])
+      .andReturn(kSecCodeSignatureRuntime | kSecCodeSignatureLibraryValidation);
+  XCTAssertEqualObjects(self.cfi.codeSigningFlags(self.cfi, self.fileInfo),
+                        @"0x12000 (library-validation, runtime)");
+}
+
+- (void)testCodeSigningFlagsAdhoc {
+  OCMStub([self.cscMock initWithBinaryPath:OCMOCK_ANY error:[OCMArg setTo:nil]])
+      .andReturn(self.cscMock);
+  OCMStub([self.cscMock signatureFlags])
+      .andReturn(kSecCodeSignatureAdhoc | kSecCodeSignatureLinkerSigned);
+  XCTAssertEqualObjects(self.cfi.codeSigningFlags(self.cfi, self.fileInfo),
+                        @"0x20002 (adhoc, linker-signed)");
+}
+
+- (void)testCodeSigningFlagsNone {
+  OCMStub([self.cscMock initWithBinaryPath:OCMOCK_ANY error:[OCMArg setTo:nil]])
+      .andReturn(self.cscMock);
+  OCMStub([self.cscMock signatureFlags]).andReturn(0);
+  XCTAssertEqualObjects(self.cfi.codeSigningFlags(self.cfi, self.fileInfo), @"0x0 (none)");
+}
+
+- (void)testCodeSigningFlagsUnsigned {
+  NSError* err = [NSError errorWithDomain:@"" code:errSecCSUnsigned userInfo:nil];
+  OCMStub([self.cscMock initWithBinaryPath:OCMOCK_ANY error:[OCMArg setTo:err]])
+      .andReturn(self.cscMock);
+  XCTAssertNil(self.cfi.codeSigningFlags(self.cfi, self.fileInfo));
+}
+
 - (void)testParseArgumentsFilterInclusiveTrue {
   NSArray* filePaths = [self.cfi parseArguments:@[ @"--filter-inclusive", @"/usr/bin/yes" ]];
   XCTAssertTrue(self.cfi.filterInclusive);
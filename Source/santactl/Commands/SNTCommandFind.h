@@ -0,0 +1,31 @@
+/// Copyright 2026 North Pole Security, Inc.
+///
+/// Licensed under the Apache License, Version 2.0 (the "License");
+/// you may not use this file except in compliance with the License.
+/// You may obtain a copy of the License at
+///
+///     http://www.apache.org/licenses/LICENSE-2.0
+///
+/// Unless required by applicable law or agreed to in writing, software
+/// distributed under the License is distributed on an "AS IS" BASIS,
+/// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+/// See the License for the specific language governing permissions and
+/// limitations under the License.
+
+#import <Foundation/Foundation.h>
+
+#import "Source/santactl/SNTCommand.h"
+#import "Source/santactl/SNTCommandController.h"
+
+@interface SNTCommandFind : SNTCommand <SNTCommandProtocol>
+
+///
+///  Search the directory tree rooted at searchPath for a Mach-O file whose CDHash matches cdhash.
+///  Returns the first matching path, or nil if nothing matched. error is only set when the search
+///  could not be performed, e.g. cdhash is malformed or searchPath is not a directory.
+///
++ (NSString*)pathForCDHash:(NSString*)cdhash
+               inDirectory:(NSString*)searchPath
+                     error:(NSError**)error;
+
+@end
@@ -0,0 +1,149 @@
+/// Copyright 2026 North Pole Security, Inc.
+///
+/// Licensed under the Apache License, Version 2.0 (the "License");
+/// you may not use this file except in compliance with the License.
+/// You may obtain a copy of the License at
+///
+///     http://www.apache.org/licenses/LICENSE-2.0
+///
+/// Unless required by applicable law or agreed to in writing, software
+/// distributed under the License is distributed on an "AS IS" BASIS,
+/// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+/// See the License for the specific language governing permissions and
+/// limitations under the License.
+
+#import "Source/santactl/Commands/SNTCommandFind.h"
+
+#import <Foundation/Foundation.h>
+
+#include "Source/common/CodeSigningIdentifierUtils.h"
+#import "Source/common/SNTCommonEnums.h"
+#import "Source/common/SNTError.h"
+#import "Source/common/SNTFileInfo.h"
+#import "Source/common/SNTLogging.h"
+#import "Source/santactl/Commands/SNTCommandRule.h"
+
+static NSString* const kDefaultSearchPath = @"/Applications";
+
+@implementation SNTCommandFind
+
+REGISTER_COMMAND_NAME(@"find")
+
++ (BOOL)requiresRoot {
+  return NO;
+}
+
++ (BOOL)requiresDaemonConn {
+  return NO;
+}
+
++ (NSString*)shortHelpText {
+  return @"Locate the binary matching an identifier.";
+}
+
++ (NSString*)longHelpText {
+  return @"Search a directory tree for the binary matching an identifier, e.g. one referenced\n"
+         @"by a block event.\n"
+         @"\n"
+         @"Usage: santactl find --cdhash {cdhash} [--search {path}]\n"
+         @"    --cdhash {cdhash}: the CDHash to look for\n"
+         @"    --search {path}: the directory to search, defaults to /Applications\n"
+         @"\n"
+         @"The search stops at the first match. For universal binaries only the CDHash of the\n"
+         @"slice that runs on this machine is compared.\n"
+         @"\n"
+         @"Examples: santactl find --cdhash dbe8c39801f93e05fc7bc53a02af5b4d3cfc670a\n"
+         @"          santactl find --cdhash dbe8c39801f93e05fc7bc53a02af5b4d3cfc670a "
+         @"--search /usr/local";
+}
+
++ (NSString*)pathForCDHash:(NSString*)cdhash
+               inDirectory:(NSString*)searchPath
+                     error:(NSError**)error {
+  if (!santa::IsValidCDHash(cdhash)) {
+    [SNTError populateError:error withFormat:@"Invalid CDHash: %@", cdhash];
+    return nil;
+  }
+
+  BOOL isDir = NO;
+  if (![[NSFileManager defaultManager] fileExistsAtPath:searchPath isDirectory:&isDir] || !isDir) {
+    [SNTError populateError:error withFormat:@"Search path is not a directory: %@", searchPath];
+    return nil;
+  }
+
+  NSDirectoryEnumerator<NSURL*>* enumerator = [[NSFileManager defaultManager]
+                 enumeratorAtURL:[NSURL fileURLWithPath:searchPath isDirectory:YES]
+      includingPropertiesForKeys:@[ NSURLIsRegularFileKey ]
+                         options:0
+                    errorHandler:^BOOL(NSURL* url, NSError* err) {
+                      // Unreadable directories are skipped rather than ending the search.
+                      LOGD(@"Skipping %@: %@", url.path, err.localizedDescription);
+                      return YES;
+                    }];
+
+  for (NSURL* url in enumerator) {
+    @autoreleasepool {
+      NSNumber* isRegularFile;
+      [url getResourceValue:&isRegularFile forKey:NSURLIsRegularFileKey error:nil];
+      if (!isRegularFile.boolValue) continue;
+
+      // Only Mach-O files carry a CDHash, so skip everything else before the comparatively
+      // expensive code signature lookup.
+      SNTFileInfo* fi = [[SNTFileInfo alloc] initWithPath:url.path];
+      if (!fi.isMachO) continue;
+
+      NSString* candidate = [SNTCommandRule identifierForFileInfo:fi
+                                                         ruleType:SNTRuleTypeCDHash
+                                                            error:NULL];
+      if (candidate && [candidate caseInsensitiveCompare:cdhash] == NSOrderedSame) {
+        return url.path;
+      }
+    }
+  }
+
+  return nil;
+}
+
+- (void)runWithArguments:(NSArray*)arguments {
+  NSString* cdhash;
+  NSString* searchPath = kDefaultSearchPath;
+
+  for (NSUInteger i = 0; i < arguments.count; ++i) {
+    NSString* arg = arguments[i];
+
+    if ([arg caseInsensitiveCompare:@"--cdhash"] == NSOrderedSame) {
+      if (++i > arguments.count - 1) {
+        [self printErrorUsageAndExit:@"--cdhash requires an argument"];
+      }
+      cdhash = arguments[i];
+    } else if ([arg caseInsensitiveCompare:@"--search"] == NSOrderedSame) {
+      if (++i > arguments.count - 1) {
+        [self printErrorUsageAndExit:@"--search requires an argument"];
+      }
+      searchPath = arguments[i];
+    } else {
+      [self printErrorUsageAndExit:[@"Unknown argument: " stringByAppendingString:arg]];
+    }
+  }
+
+  if (!cdhash.length) {
+    [self printErrorUsageAndExit:@"--cdhash is required"];
+  }
+
+  NSError* error;
+  NSString* path = [[self class] pathForCDHash:cdhash inDirectory:searchPath error:&error];
+  if (error) {
+    TEE_LOGE(@"%@", error.localizedDescription);
+    exit(EXIT_FAILURE);
+  }
+
+  if (!path) {
+    printf("No binary with CDHash %s found in %s\n", cdhash.UTF8String, searchPath.UTF8String);
+    exit(EXIT_FAILURE);
+  }
+
+  printf("%s\n", path.UTF8String);
+  exit(EXIT_SUCCESS);
+}
+
+@end
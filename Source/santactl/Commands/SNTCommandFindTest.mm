@@ -0,0 +1,122 @@
+/// Copyright 2026 North Pole Security, Inc.
+///
+/// Licensed under the Apache License, Version 2.0 (the "License");
+/// you may not use this file except in compliance with the License.
+/// You may obtain a copy of the License at
+///
+///     http://www.apache.org/licenses/LICENSE-2.0
+///
+/// Unless required by applicable law or agreed to in writing, software
+/// distributed under the License is distributed on an "AS IS" BASIS,
+/// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+/// See the License for the specific language governing permissions and
+/// limitations under the License.
+
+#import <XCTest/XCTest.h>
+
+#import "Source/common/MOLCodesignChecker.h"
+#import "Source/santactl/Commands/SNTCommandFind.h"
+
+@interface SNTCommandFindTest : XCTestCase
+@property NSString* testDir;
+@property NSString* yesPath;
+@property NSString* yesCDHash;
+@end
+
+@implementation SNTCommandFindTest
+
+// Builds a small tree containing copies of two signed binaries, a plain file and a symlink:
+//   ls
+//   docs/readme.txt
+//   nested/deeper/yes
+//   yes-link -> nested/deeper/yes
+- (void)setUp {
+  [super setUp];
+  NSFileManager* fm = [NSFileManager defaultManager];
+  self.testDir =
+      [NSString stringWithFormat:@"%@santa-find-%d", NSTemporaryDirectory(), getpid()];
+
+  NSString* nested = [self.testDir stringByAppendingPathComponent:@"nested/deeper"];
+  NSString* docs = [self.testDir stringByAppendingPathComponent:@"docs"];
+  XCTAssertTrue([fm createDirectoryAtPath:nested
+              withIntermediateDirectories:YES
+                               attributes:nil
+                                    error:nil]);
+  XCTAssertTrue([fm createDirectoryAtPath:docs
+              withIntermediateDirectories:YES
+                               attributes:nil
+                                    error:nil]);
+
+  self.yesPath = [nested stringByAppendingPathComponent:@"yes"];
+  XCTAssertTrue([fm copyItemAtPath:@"/usr/bin/yes" toPath:self.yesPath error:nil]);
+  XCTAssertTrue([fm copyItemAtPath:@"/bin/ls"
+                            toPath:[self.testDir stringByAppendingPathComponent:@"ls"]
+                             error:nil]);
+  XCTAssertTrue([@"not a binary" writeToFile:[docs stringByAppendingPathComponent:@"readme.txt"]
+                                  atomically:YES
+                                    encoding:NSUTF8StringEncoding
+                                       error:nil]);
+  NSString* link = [self.testDir stringByAppendingPathComponent:@"yes-link"];
+  XCTAssertTrue([fm createSymbolicLinkAtPath:link withDestinationPath:self.yesPath error:nil]);
+
+  self.yesCDHash = [[MOLCodesignChecker alloc] initWithBinaryPath:@"/usr/bin/yes"].cdhash;
+  XCTAssertEqual(self.yesCDHash.length, 40);
+}
+
+- (void)tearDown {
+  [[NSFileManager defaultManager] removeItemAtPath:self.testDir error:nil];
+  [super tearDown];
+}
+
+- (void)testFindsMatchingBinary {
+  NSError* err;
+  XCTAssertEqualObjects([SNTCommandFind pathForCDHash:self.yesCDHash
+                                          inDirectory:self.testDir
+                                                error:&err],
+                        self.yesPath);
+  XCTAssertNil(err);
+}
+
+- (void)testFindIsCaseInsensitive {
+  NSError* err;
+  XCTAssertEqualObjects([SNTCommandFind pathForCDHash:[self.yesCDHash uppercaseString]
+                                          inDirectory:self.testDir
+                                                error:&err],
+                        self.yesPath);
+  XCTAssertNil(err);
+}
+
+- (void)testNoMatchReturnsNilWithoutError {
+  NSError* err;
+  XCTAssertNil([SNTCommandFind pathForCDHash:@"0000000000000000000000000000000000000000"
+                                 inDirectory:self.testDir
+                                       error:&err]);
+  XCTAssertNil(err);
+}
+
+- (void)testInvalidCDHash {
+  NSError* err;
+  XCTAssertNil([SNTCommandFind pathForCDHash:@"not-a-cdhash" inDirectory:self.testDir error:&err]);
+  XCTAssertNotNil(err);
+
+  err = nil;
+  XCTAssertNil([SNTCommandFind pathForCDHash:[self.yesCDHash substringFromIndex:2]
+                                 inDirectory:self.testDir
+                                       error:&err]);
+  XCTAssertNotNil(err);
+}
+
+- (void)testSearchPathMustBeDirectory {
+  NSError* err;
+  XCTAssertNil([SNTCommandFind pathForCDHash:self.yesCDHash inDirectory:self.yesPath error:&err]);
+  XCTAssertNotNil(err);
+
+  err = nil;
+  XCTAssertNil([SNTCommandFind
+      pathForCDHash:self.yesCDHash
+        inDirectory:[self.testDir stringByAppendingPathComponent:@"does-not-exist"]
+              error:&err]);
+  XCTAssertNotNil(err);
+}
+
+@end
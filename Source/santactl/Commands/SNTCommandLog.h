@@ -0,0 +1,54 @@
+/// Copyright 2026 North Pole Security, Inc.
+///
+/// Licensed under the Apache License, Version 2.0 (the "License");
+/// you may not use this file except in compliance with the License.
+/// You may obtain a copy of the License at
+///
+///     http://www.apache.org/licenses/LICENSE-2.0
+///
+/// Unless required by applicable law or agreed to in writing, software
+/// distributed under the License is distributed on an "AS IS" BASIS,
+/// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+/// See the License for the specific language governing permissions and
+/// limitations under the License.
+
+
+#import <Foundation/Foundation.h>
+
+#import "Source/santactl/SNTCommand.h"
+#import "Source/santactl/SNTCommandController.h"
+
+@interface SNTCommandLog : SNTCommand <SNTCommandProtocol>
+
+///
+///  Parse a file event log at path and return the blocked executions it contains with a
+///  timestamp in [since, until). Each entry holds the key/value pairs from the log line plus a
+///  "timestamp" key. When reasons is non-empty only entries with one of those reasons (e.g.
+///  "BINARY", "SIGNINGID") are returned. When signingID is non-nil only entries with that
+///  signing ID are returned. Returns nil and populates error if the log could not be read.
+///
++ (NSArray<NSDictionary<NSString*, NSString*>*>*)blocksInLogAtPath:(NSString*)path
+                                                             since:(NSDate*)since
+                                                             until:(NSDate*)until
+                                                           reasons:(NSSet<NSString*>*)reasons
+                                                         signingID:(NSString*)signingID
+                                                             error:(NSError**)error;
+
+///
+///  Parse a file event log at path and return every execution, allowed or blocked, with a
+///  timestamp in [since, until). Each entry holds the key/value pairs from the log line plus a
+///  "timestamp" key. Returns nil and populates error if the log could not be read.
+///
++ (NSArray<NSDictionary<NSString*, NSString*>*>*)executionsInLogAtPath:(NSString*)path
+                                                                 since:(NSDate*)since
+                                                                 until:(NSDate*)until
+                                                                 error:(NSError**)error;
+
+///
+///  Convert a --since/--until argument to a date. Accepts an ISO 8601 timestamp
+///  (2026-01-02T15:04:05Z), a UTC date (2026-01-02) or a duration before now such as 30m, 12h
+///  or 7d. Returns nil if the argument is not understood.
+///
++ (NSDate*)dateFromTimeArgument:(NSString*)arg relativeToDate:(NSDate*)now;
+
+@end
@@ -0,0 +1,293 @@
+/// Copyright 2026 North Pole Security, Inc.
+///
+/// Licensed under the Apache License, Version 2.0 (the "License");
+/// you may not use this file except in compliance with the License.
+/// You may obtain a copy of the License at
+///
+///     http://www.apache.org/licenses/LICENSE-2.0
+///
+/// Unless required by applicable law or agreed to in writing, software
+/// distributed under the License is distributed on an "AS IS" BASIS,
+/// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+/// See the License for the specific language governing permissions and
+/// limitations under the License.
+
+
+#import "Source/santactl/Commands/SNTCommandLog.h"
+
+#import <Foundation/Foundation.h>
+#include <stdio.h>
+#include <stdlib.h>
+
+#import "Source/common/SNTCommonEnums.h"
+#import "Source/common/SNTConfigurator.h"
+#import "Source/common/SNTError.h"
+#import "Source/common/SNTLogging.h"
+
+static NSString* const kTimestampKey = @"timestamp";
+static const NSTimeInterval kDefaultWindow = 24 * 60 * 60;
+
+@implementation SNTCommandLog
+
+REGISTER_COMMAND_NAME(@"log")
+
++ (BOOL)requiresRoot {
+  return NO;
+}
+
++ (BOOL)requiresDaemonConn {
+  return NO;
+}
+
++ (NSString*)shortHelpText {
+  return @"Query the local event log.";
+}
+
++ (NSString*)longHelpText {
+  return @"Query the local event log written when EventLogType is 'file'.\n"
+         @"\n"
+         @"Usage: santactl log blocks [options]\n"
+         @"  blocks: list blocked executions\n"
+         @"    --since {time}: only list blocks at or after this time, defaults to 24h\n"
+         @"    --until {time}: only list blocks before this time, defaults to now\n"
+         @"    --reason {reason}: only list blocks with this reason, e.g. BINARY, SIGNINGID\n"
+         @"                       or UNKNOWN. Can be given more than once.\n"
+         @"    --signingid {signingid}: only list blocks of binaries with this signing ID\n"
+         @"    --path {path}: the log file to read, defaults to the configured EventLogPath\n"
+         @"    --json: print the results as JSON\n"
+         @"\n"
+         @"Times may be an ISO 8601 timestamp (2026-01-02T15:04:05Z), a UTC date (2026-01-02)\n"
+         @"or a duration before now (30m, 12h, 7d). Rotated log files are not read, use --path\n"
+         @"to query one of them directly once it has been decompressed.\n"
+         @"\n"
+         @"Examples: santactl log blocks --since 7d --reason UNKNOWN\n"
+         @"          santactl log blocks --since 2026-01-02 --until 2026-01-03 "
+         @"--signingid platform:com.apple.ls --json";
+}
+
++ (NSDate*)dateFromTimeArgument:(NSString*)arg relativeToDate:(NSDate*)now {
+  static NSRegularExpression* durationRegex;
+  static dispatch_once_t onceToken;
+  dispatch_once(&onceToken, ^{
+    durationRegex = [NSRegularExpression regularExpressionWithPattern:@"^([0-9]+)([smhd])$"
+                                                              options:0
+                                                                error:NULL];
+  });
+
+  NSTextCheckingResult* match = [durationRegex firstMatchInString:arg
+                                                          options:0
+                                                            range:NSMakeRange(0, arg.length)];
+  if (match) {
+    NSTimeInterval value = [[arg substringWithRange:[match rangeAtIndex:1]] doubleValue];
+    NSString* unit = [arg substringWithRange:[match rangeAtIndex:2]];
+    NSDictionary<NSString*, NSNumber*>* multipliers =
+        @{@"s" : @(1), @"m" : @(60), @"h" : @(60 * 60), @"d" : @(24 * 60 * 60)};
+    return [now dateByAddingTimeInterval:-(value * multipliers[unit].doubleValue)];
+  }
+
+  NSISO8601DateFormatter* formatter = [[NSISO8601DateFormatter alloc] init];
+  for (NSNumber* options in @[
+         @(NSISO8601DateFormatWithInternetDateTime),
+         @(NSISO8601DateFormatWithInternetDateTime | NSISO8601DateFormatWithFractionalSeconds),
+         @(NSISO8601DateFormatWithFullDate),
+       ]) {
+    formatter.formatOptions = options.unsignedIntegerValue;
+    NSDate* date = [formatter dateFromString:arg];
+    if (date) return date;
+  }
+
+  return nil;
+}
+
+// Parse a single line of the file event log, e.g.:
+//   [2026-01-02T15:04:05.678Z] I santad: action=EXEC|decision=DENY|reason=BINARY|...
+// Returns nil for lines that are not executions.
++ (NSDictionary<NSString*, NSString*>*)executionFromLogLine:(NSString*)line {
+  if (![line hasPrefix:@"["]) return nil;
+
+  NSRange timestampEnd = [line rangeOfString:@"] "];
+  NSRange body = [line rangeOfString:@"action=EXEC|"];
+  if (timestampEnd.location == NSNotFound || body.location == NSNotFound ||
+      body.location < timestampEnd.location) {
+    return nil;
+  }
+
+  NSMutableDictionary<NSString*, NSString*>* fields = [NSMutableDictionary dictionary];
+  fields[kTimestampKey] = [line substringWithRange:NSMakeRange(1, timestampEnd.location - 1)];
+
+  // Values are sanitized by santad so they never contain a pipe or newline.
+  for (NSString* component in
+       [[line substringFromIndex:body.location] componentsSeparatedByString:@"|"]) {
+    NSRange separator = [component rangeOfString:@"="];
+    if (separator.location == NSNotFound) continue;
+    fields[[component substringToIndex:separator.location]] =
+        [component substringFromIndex:separator.location + 1];
+  }
+
+  return fields;
+}
+
++ (NSArray<NSDictionary<NSString*, NSString*>*>*)blocksInLogAtPath:(NSString*)path
+                                                             since:(NSDate*)since
+                                                             until:(NSDate*)until
+                                                           reasons:(NSSet<NSString*>*)reasons
+                                                         signingID:(NSString*)signingID
+                                                             error:(NSError**)error {
+  NSArray<NSDictionary<NSString*, NSString*>*>* executions =
+      [self executionsInLogAtPath:path since:since until:until error:error];
+  if (!executions) return nil;
+
+  NSMutableArray<NSDictionary<NSString*, NSString*>*>* blocks = [NSMutableArray array];
+  for (NSDictionary<NSString*, NSString*>* execution in executions) {
+    if (![execution[@"decision"] isEqualToString:@"DENY"]) continue;
+    if (reasons.count && ![reasons containsObject:execution[@"reason"]]) continue;
+    if (signingID && ![execution[@"signingid"] isEqualToString:signingID]) continue;
+    [blocks addObject:execution];
+  }
+  return blocks;
+}
+
++ (NSArray<NSDictionary<NSString*, NSString*>*>*)executionsInLogAtPath:(NSString*)path
+                                                                 since:(NSDate*)since
+                                                                 until:(NSDate*)until
+                                                                 error:(NSError**)error {
+  FILE* file = fopen(path.fileSystemRepresentation, "r");
+  if (!file) {
+    [SNTError populateError:error
+                 withFormat:@"Unable to open %@: %s", path, strerror(errno)];
+    return nil;
+  }
+
+  NSISO8601DateFormatter* formatter = [[NSISO8601DateFormatter alloc] init];
+  formatter.formatOptions =
+      NSISO8601DateFormatWithInternetDateTime | NSISO8601DateFormatWithFractionalSeconds;
+
+  NSMutableArray<NSDictionary<NSString*, NSString*>*>* executions = [NSMutableArray array];
+  char* buf = NULL;
+  size_t bufSize = 0;
+  ssize_t len;
+  while ((len = getline(&buf, &bufSize, file)) != -1) {
+    @autoreleasepool {
+      if (len > 0 && buf[len - 1] == '\n') buf[--len] = '\0';
+      NSString* line = [[NSString alloc] initWithBytes:buf
+                                                length:len
+                                              encoding:NSUTF8StringEncoding];
+      if (!line) continue;
+
+      NSDictionary<NSString*, NSString*>* execution = [self executionFromLogLine:line];
+      if (!execution) continue;
+
+      NSDate* timestamp = [formatter dateFromString:execution[kTimestampKey]];
+      if (!timestamp || [timestamp compare:since] == NSOrderedAscending ||
+          [timestamp compare:until] != NSOrderedAscending) {
+        continue;
+      }
+
+      [executions addObject:execution];
+    }
+  }
+
+  free(buf);
+  fclose(file);
+  return executions;
+}
+
+- (void)runWithArguments:(NSArray*)arguments {
+  if (!arguments.count || [arguments[0] caseInsensitiveCompare:@"blocks"] != NSOrderedSame) {
+    [self printErrorUsageAndExit:@"Missing or unknown subcommand"];
+  }
+
+  NSDate* now = [NSDate date];
+  NSDate* since = [now dateByAddingTimeInterval:-kDefaultWindow];
+  NSDate* until = now;
+  NSMutableSet<NSString*>* reasons = [NSMutableSet set];
+  NSString* signingID;
+  NSString* path;
+  BOOL json = NO;
+
+  for (NSUInteger i = 1; i < arguments.count; ++i) {
+    NSString* arg = arguments[i];
+
+    if ([arg caseInsensitiveCompare:@"--since"] == NSOrderedSame ||
+        [arg caseInsensitiveCompare:@"--until"] == NSOrderedSame) {
+      if (++i > arguments.count - 1) {
+        [self printErrorUsageAndExit:[arg stringByAppendingString:@" requires an argument"]];
+      }
+      NSDate* date = [[self class] dateFromTimeArgument:arguments[i] relativeToDate:now];
+      if (!date) {
+        [self printErrorUsageAndExit:[@"Invalid time: " stringByAppendingString:arguments[i]]];
+      }
+      if ([arg caseInsensitiveCompare:@"--since"] == NSOrderedSame) {
+        since = date;
+      } else {
+        until = date;
+      }
+    } else if ([arg caseInsensitiveCompare:@"--reason"] == NSOrderedSame) {
+      if (++i > arguments.count - 1) {
+        [self printErrorUsageAndExit:@"--reason requires an argument"];
+      }
+      [reasons addObject:[arguments[i] uppercaseString]];
+    } else if ([arg caseInsensitiveCompare:@"--signingid"] == NSOrderedSame) {
+      if (++i > arguments.count - 1) {
+        [self printErrorUsageAndExit:@"--signingid requires an argument"];
+      }
+      signingID = arguments[i];
+    } else if ([arg caseInsensitiveCompare:@"--path"] == NSOrderedSame) {
+      if (++i > arguments.count - 1) {
+        [self printErrorUsageAndExit:@"--path requires an argument"];
+      }
+      path = arguments[i];
+    } else if ([arg caseInsensitiveCompare:@"--json"] == NSOrderedSame) {
+      json = YES;
+    } else {
+      [self printErrorUsageAndExit:[@"Unknown argument: " stringByAppendingString:arg]];
+    }
+  }
+
+  if (!path) {
+    SNTConfigurator* configurator = [SNTConfigurator configurator];
+    if (configurator.eventLogType != SNTEventLogTypeFilelog) {
+      TEE_LOGE(@"EventLogType is '%@', only file event logs can be queried",
+               configurator.eventLogTypeRaw);
+      exit(EXIT_FAILURE);
+    }
+    path = configurator.eventLogPath;
+  }
+
+  NSError* error;
+  NSArray<NSDictionary<NSString*, NSString*>*>* blocks =
+      [[self class] blocksInLogAtPath:path
+                                since:since
+                                until:until
+                              reasons:reasons
+                            signingID:signingID
+                                error:&error];
+  if (!blocks) {
+    TEE_LOGE(@"%@", error.localizedDescription);
+    exit(EXIT_FAILURE);
+  }
+
+  if (json) {
+    NSData* data = [NSJSONSerialization
+        dataWithJSONObject:blocks
+                   options:NSJSONWritingPrettyPrinted | NSJSONWritingSortedKeys
+                     error:NULL];
+    printf("%s\n", [[NSString alloc] initWithData:data encoding:NSUTF8StringEncoding].UTF8String);
+    exit(EXIT_SUCCESS);
+  }
+
+  if (!blocks.count) {
+    printf("No blocked executions found\n");
+    exit(EXIT_SUCCESS);
+  }
+
+  printf("%-24s  %-12s  %-40s  %s\n", "TIME", "REASON", "SIGNING ID", "PATH");
+  for (NSDictionary<NSString*, NSString*>* block in blocks) {
+    printf("%-24s  %-12s  %-40s  %s\n", block[kTimestampKey].UTF8String,
+           (block[@"reason"] ?: @"-").UTF8String, (block[@"signingid"] ?: @"-").UTF8String,
+           (block[@"path"] ?: @"-").UTF8String);
+  }
+  exit(EXIT_SUCCESS);
+}
+
+@end
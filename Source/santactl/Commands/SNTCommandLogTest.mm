@@ -0,0 +1,147 @@
+/// Copyright 2026 North Pole Security, Inc.
+///
+/// Licensed under the Apache License, Version 2.0 (the "License");
+/// you may not use this file except in compliance with the License.
+/// You may obtain a copy of the License at
+///
+///     http://www.apache.org/licenses/LICENSE-2.0
+///
+/// Unless required by applicable law or agreed to in writing, software
+/// distributed under the License is distributed on an "AS IS" BASIS,
+/// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+/// See the License for the specific language governing permissions and
+/// limitations under the License.
+
+
+#import <XCTest/XCTest.h>
+
+#import "Source/santactl/Commands/SNTCommandLog.h"
+
+@interface SNTCommandLogTest : XCTestCase
+@property NSString* logPath;
+@property NSISO8601DateFormatter* formatter;
+@end
+
+@implementation SNTCommandLogTest
+
+- (void)setUp {
+  [super setUp];
+  self.logPath = [[[NSBundle bundleForClass:[self class]] resourcePath]
+      stringByAppendingPathComponent:@"Commands/testdata/santa-blocks.log"];
+  self.formatter = [[NSISO8601DateFormatter alloc] init];
+}
+
+- (NSArray<NSDictionary<NSString*, NSString*>*>*)blocksSince:(NSString*)since
+                                                       until:(NSString*)until
+                                                     reasons:(NSSet<NSString*>*)reasons
+                                                   signingID:(NSString*)signingID {
+  NSError* err;
+  NSArray* blocks = [SNTCommandLog blocksInLogAtPath:self.logPath
+                                               since:[self.formatter dateFromString:since]
+                                               until:[self.formatter dateFromString:until]
+                                             reasons:reasons
+                                           signingID:signingID
+                                               error:&err];
+  XCTAssertNil(err);
+  return blocks;
+}
+
+- (void)testOnlyBlockedExecutionsAreReturned {
+  NSArray* blocks = [self blocksSince:@"2026-01-01T00:00:00Z"
+                                until:@"2027-01-01T00:00:00Z"
+                              reasons:nil
+                            signingID:nil];
+  XCTAssertEqualObjects([blocks valueForKey:@"pid"], (@[ @"101", @"103", @"105", @"106" ]));
+  XCTAssertEqualObjects(blocks[0][@"timestamp"], @"2026-03-01T09:00:00.000Z");
+  XCTAssertEqualObjects(blocks[0][@"path"], @"/Users/alice/Downloads/tool");
+  XCTAssertEqualObjects(blocks[1][@"path"],
+                        @"/Applications/Google Chrome.app/Contents/MacOS/Google Chrome");
+}
+
+- (void)testTimeRangeFiltering {
+  // since is inclusive, until is exclusive.
+  NSArray* blocks = [self blocksSince:@"2026-03-01T09:00:00Z"
+                                until:@"2026-03-01T11:00:00Z"
+                              reasons:nil
+                            signingID:nil];
+  XCTAssertEqualObjects([blocks valueForKey:@"pid"], (@[ @"101", @"103" ]));
+
+  blocks = [self blocksSince:@"2026-03-01T10:15:01Z"
+                       until:@"2026-03-02T00:00:00Z"
+                     reasons:nil
+                   signingID:nil];
+  XCTAssertEqualObjects([blocks valueForKey:@"pid"], (@[ @"105" ]));
+
+  blocks = [self blocksSince:@"2026-03-03T00:00:00Z"
+                       until:@"2026-03-04T00:00:00Z"
+                     reasons:nil
+                   signingID:nil];
+  XCTAssertEqual(blocks.count, 0);
+}
+
+- (void)testReasonFiltering {
+  NSArray* blocks = [self blocksSince:@"2026-01-01T00:00:00Z"
+                                until:@"2027-01-01T00:00:00Z"
+                              reasons:[NSSet setWithObject:@"UNKNOWN"]
+                            signingID:nil];
+  XCTAssertEqualObjects([blocks valueForKey:@"pid"], (@[ @"103", @"106" ]));
+
+  blocks = [self blocksSince:@"2026-01-01T00:00:00Z"
+                       until:@"2027-01-01T00:00:00Z"
+                     reasons:[NSSet setWithObjects:@"BINARY", @"SIGNINGID", nil]
+                   signingID:nil];
+  XCTAssertEqualObjects([blocks valueForKey:@"pid"], (@[ @"101", @"105" ]));
+
+  blocks = [self blocksSince:@"2026-01-01T00:00:00Z"
+                       until:@"2027-01-01T00:00:00Z"
+                     reasons:[NSSet setWithObject:@"CERT"]
+                   signingID:nil];
+  XCTAssertEqual(blocks.count, 0);
+}
+
+- (void)testSigningIDFiltering {
+  NSArray* blocks = [self blocksSince:@"2026-01-01T00:00:00Z"
+                                until:@"2026-03-02T00:00:00Z"
+                              reasons:nil
+                            signingID:@"EQHXZ8M8AV:com.google.Chrome"];
+  XCTAssertEqualObjects([blocks valueForKey:@"pid"], (@[ @"103" ]));
+
+  // Allowed executions are never returned, even when the signing ID matches.
+  blocks = [self blocksSince:@"2026-01-01T00:00:00Z"
+                       until:@"2027-01-01T00:00:00Z"
+                     reasons:nil
+                   signingID:@"platform:com.apple.ls"];
+  XCTAssertEqual(blocks.count, 0);
+}
+
+- (void)testMissingLog {
+  NSError* err;
+  XCTAssertNil([SNTCommandLog blocksInLogAtPath:@"/nonexistent/santa.log"
+                                          since:[NSDate distantPast]
+                                          until:[NSDate distantFuture]
+                                        reasons:nil
+                                      signingID:nil
+                                          error:&err]);
+  XCTAssertNotNil(err);
+}
+
+- (void)testDateFromTimeArgument {
+  NSDate* now = [self.formatter dateFromString:@"2026-03-02T12:00:00Z"];
+
+  XCTAssertEqualObjects([SNTCommandLog dateFromTimeArgument:@"30m" relativeToDate:now],
+                        [self.formatter dateFromString:@"2026-03-02T11:30:00Z"]);
+  XCTAssertEqualObjects([SNTCommandLog dateFromTimeArgument:@"12h" relativeToDate:now],
+                        [self.formatter dateFromString:@"2026-03-02T00:00:00Z"]);
+  XCTAssertEqualObjects([SNTCommandLog dateFromTimeArgument:@"1d" relativeToDate:now],
+                        [self.formatter dateFromString:@"2026-03-01T12:00:00Z"]);
+  XCTAssertEqualObjects([SNTCommandLog dateFromTimeArgument:@"2026-03-01T09:00:00Z"
+                                             relativeToDate:now],
+                        [self.formatter dateFromString:@"2026-03-01T09:00:00Z"]);
+  XCTAssertEqualObjects([SNTCommandLog dateFromTimeArgument:@"2026-03-01" relativeToDate:now],
+                        [self.formatter dateFromString:@"2026-03-01T00:00:00Z"]);
+
+  XCTAssertNil([SNTCommandLog dateFromTimeArgument:@"yesterday" relativeToDate:now]);
+  XCTAssertNil([SNTCommandLog dateFromTimeArgument:@"10w" relativeToDate:now]);
+}
+
+@end
@@ -0,0 +1,148 @@
+/// Copyright 2026 North Pole Security, Inc.
+///
+/// Licensed under the Apache License, Version 2.0 (the "License");
+/// you may not use this file except in compliance with the License.
+/// You may obtain a copy of the License at
+///
+///     http://www.apache.org/licenses/LICENSE-2.0
+///
+/// Unless required by applicable law or agreed to in writing, software
+/// distributed under the License is distributed on an "AS IS" BASIS,
+/// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+/// See the License for the specific language governing permissions and
+/// limitations under the License.
+
+#import <Foundation/Foundation.h>
+
+#import "Source/santactl/SNTCommand.h"
+#import "Source/santactl/SNTCommandController.h"
+
+///
+///  The steps `santactl push diagnose` walks through, in order.
+///
+typedef NS_ENUM(NSInteger, SNTPushDiagnoseStep) {
+  SNTPushDiagnoseStepNone = 0,
+  SNTPushDiagnoseStepPreflight,
+  SNTPushDiagnoseStepJWT,
+  SNTPushDiagnoseStepReachability,
+  SNTPushDiagnoseStepAuthentication,
+  SNTPushDiagnoseStepSubscriptions,
+};
+
+///
+///  The outcome of diagnosing the push client. If every step passed, failedStep is
+///  SNTPushDiagnoseStepNone and detail/remediation are nil.
+///
+@interface SNTPushDiagnosis : NSObject
+@property(readonly) NSArray<NSString*>* passedSteps;
+@property(readonly) SNTPushDiagnoseStep failedStep;
+@property(readonly) NSString* detail;
+@property(readonly) NSString* remediation;
+@end
+
+///
+///  Whether `santactl push target-test` found this host would receive a push sent to a tag and,
+///  if not, the first reason it wouldn't.
+///
+typedef NS_ENUM(NSInteger, SNTPushTargetResult) {
+  SNTPushTargetResultReceives = 0,
+  SNTPushTargetResultPushNotRunning,
+  SNTPushTargetResultInvalidTag,
+  SNTPushTargetResultNotMember,
+  SNTPushTargetResultNotPermitted,
+  SNTPushTargetResultRejectedByServer,
+  SNTPushTargetResultNotConnected,
+};
+
+///
+///  The outcome of checking whether this host would receive a push sent to a tag. detail
+///  explains the result.
+///
+@interface SNTPushTargetTest : NSObject
+@property(readonly) NSString* subject;
+@property(readonly) SNTPushTargetResult result;
+@property(readonly) NSString* detail;
+@end
+
+///
+///  Returns YES if a TCP connection to host:port could be established. On failure, error may
+///  be set to a description of the problem.
+///
+typedef BOOL (^SNTPushReachabilityBlock)(NSString* host, uint16_t port, NSString** error);
+
+///
+///  The outcome of a `santactl push loadtest` run. Latencies are measured from publish to
+///  delivery on the subscribing connection and are reported in milliseconds.
+///
+@interface SNTPushLoadTestReport : NSObject
+@property(readonly) NSUInteger sent;
+@property(readonly) NSUInteger received;
+@property(readonly) NSUInteger duplicates;
+@property(readonly) NSUInteger lost;
+@property(readonly) NSTimeInterval elapsed;
+@property(readonly) double p50LatencyMs;
+@property(readonly) double p90LatencyMs;
+@property(readonly) double p99LatencyMs;
+@property(readonly) double maxLatencyMs;
+@end
+
+///
+///  Checks the live push subscriptions (the host commands subject and each subscribed tag)
+///  against the permissions in the current push JWT. Each check re-parses the JWT, so repeated
+///  checks notice subscriptions that stop being permitted, e.g. after a credential rotation.
+///
+@interface SNTPushSubscriptionValidator : NSObject
+
+///  The subscriptions rejected by the most recent successful check.
+@property(readonly) NSArray<NSString*>* invalidSubjects;
+
+///
+///  Check every live subscription in a diagnostics snapshot from the sync service. Returns the
+///  subjects that were rejected by this check but not by the previous one, which may be empty.
+///  Returns nil and sets error if push is not enabled or the JWT cannot be parsed, in which
+///  case the results of the previous check are kept.
+///
+- (NSArray<NSString*>*)checkSnapshot:(NSDictionary*)snapshot error:(NSError**)error;
+
+@end
+
+@interface SNTCommandPush : SNTCommand <SNTCommandProtocol>
+
+///
+///  Walk the push setup described by a diagnostics snapshot from the sync service (keyed by the
+///  kPushDiagnostics* constants) and stop at the first failing step.
+///
++ (SNTPushDiagnosis*)diagnoseWithSnapshot:(NSDictionary*)snapshot
+                                      now:(NSDate*)now
+                             reachability:(SNTPushReachabilityBlock)reachability;
+
+///
+///  Check whether the push client described by a diagnostics snapshot would receive a push sent
+///  to tag, given its tag membership and the subscribe permissions in its JWT. tag may be given
+///  with or without the santa.tag. prefix.
+///
++ (SNTPushTargetTest*)targetTestForTag:(NSString*)tag snapshot:(NSDictionary*)snapshot;
+
+///
+///  Format the push connection stats in a diagnostics snapshot for `santactl push stats`.
+///
++ (NSString*)statsTextForSnapshot:(NSDictionary*)snapshot;
+
+///
+///  Publish to subject on server at rate messages per second for duration seconds while a
+///  second connection subscribed to the same subject counts what is delivered. After the last
+///  publish, waits up to drainTimeout seconds for outstanding deliveries.
+///
+///  publisherCreds and subscriberCreds are paths to NATS .creds files and may be nil to
+///  connect without credentials. Returns nil and sets error if either connection fails.
+///
++ (SNTPushLoadTestReport*)loadTestWithServer:(NSString*)server
+                              publisherCreds:(NSString*)publisherCreds
+                             subscriberCreds:(NSString*)subscriberCreds
+                                     subject:(NSString*)subject
+                                        rate:(NSUInteger)rate
+                                    duration:(NSTimeInterval)duration
+                                drainTimeout:(NSTimeInterval)drainTimeout
+                                       error:(NSError**)error;
+
+@end
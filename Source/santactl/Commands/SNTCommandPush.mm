@@ -0,0 +1,1190 @@
+/// Copyright 2026 North Pole Security, Inc.
+///
+/// Licensed under the Apache License, Version 2.0 (the "License");
+/// you may not use this file except in compliance with the License.
+/// You may obtain a copy of the License at
+///
+///     http://www.apache.org/licenses/LICENSE-2.0
+///
+/// Unless required by applicable law or agreed to in writing, software
+/// distributed under the License is distributed on an "AS IS" BASIS,
+/// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+/// See the License for the specific language governing permissions and
+/// limitations under the License.
+
+#import "Source/santactl/Commands/SNTCommandPush.h"
+
+#include <fcntl.h>
+#include <netdb.h>
+#include <poll.h>
+#include <sys/cdefs.h>
+#include <sys/socket.h>
+#include <time.h>
+#include <unistd.h>
+
+#include <algorithm>
+#include <cmath>
+#include <cstdlib>
+#include <mutex>
+#include <optional>
+#include <string>
+#include <vector>
+
+#include "Source/common/NATSPermissions.h"
+#import "Source/common/MOLXPCConnection.h"
+#import "Source/common/SNTError.h"
+#import "Source/common/SNTLogging.h"
+#import "Source/common/SNTSyncConstants.h"
+#import "Source/common/SNTXPCSyncServiceInterface.h"
+#import "Source/common/String.h"
+
+__BEGIN_DECLS
+
+#import "src/nats.h"
+
+__END_DECLS
+
+using santa::CheckNATSPermission;
+using santa::NATSPermissionList;
+using santa::NATSPermissionResult;
+using santa::NATSPermissions;
+using santa::NATSPermissionsFromJWT;
+
+static NSString* const kTagSubjectPrefix = @"santa.tag.";
+static const int kReachabilityTimeoutMs = 5000;
+static NSString* const kDefaultLoadTestServer = @"nats://localhost:4222";
+static const NSTimeInterval kDefaultLoadTestDrainTimeout = 5;
+static const NSTimeInterval kDefaultValidateInterval = 60;
+
+// The subjects the push client subscribes to: the host commands subject followed by the tags.
+static NSArray<NSString*>* LiveSubscriptionSubjects(NSDictionary* snapshot) {
+  NSMutableArray<NSString*>* subjects = [NSMutableArray array];
+  NSString* deviceID = snapshot[kPushDiagnosticsDeviceID];
+  if (deviceID.length) {
+    [subjects addObject:[NSString stringWithFormat:@"santa.host.%@.commands", deviceID]];
+  }
+  for (NSString* tag in snapshot[kPushDiagnosticsTags]) {
+    [subjects addObject:tag];
+  }
+  return subjects;
+}
+
+// The subscribe permissions of the push JWT in a diagnostics snapshot, or std::nullopt if the
+// sync service couldn't parse the JWT.
+static std::optional<NATSPermissionList> SubscribePermissions(NSDictionary* snapshot) {
+  if (![snapshot[kPushDiagnosticsJWTParsed] boolValue]) return std::nullopt;
+  NATSPermissionList permissions;
+  for (NSString* subject in snapshot[kPushDiagnosticsJWTSubscribeAllow]) {
+    permissions.allow.push_back(santa::NSStringToUTF8String(subject));
+  }
+  for (NSString* subject in snapshot[kPushDiagnosticsJWTSubscribeDeny]) {
+    permissions.deny.push_back(santa::NSStringToUTF8String(subject));
+  }
+  return permissions;
+}
+
+@interface SNTPushDiagnosis ()
+@property NSMutableArray<NSString*>* passedSteps;
+@property SNTPushDiagnoseStep failedStep;
+@property NSString* detail;
+@property NSString* remediation;
+@end
+
+@implementation SNTPushDiagnosis
+
+- (instancetype)init {
+  self = [super init];
+  if (self) {
+    _passedSteps = [NSMutableArray array];
+  }
+  return self;
+}
+
+- (SNTPushDiagnosis*)failStep:(SNTPushDiagnoseStep)step
+                       detail:(NSString*)detail
+                  remediation:(NSString*)remediation {
+  self.failedStep = step;
+  self.detail = detail;
+  self.remediation = remediation;
+  return self;
+}
+
+@end
+
+@interface SNTPushTargetTest ()
+@property NSString* subject;
+@property SNTPushTargetResult result;
+@property NSString* detail;
+@end
+
+@implementation SNTPushTargetTest
+
+- (SNTPushTargetTest*)result:(SNTPushTargetResult)result detail:(NSString*)detail {
+  self.result = result;
+  self.detail = detail;
+  return self;
+}
+
+@end
+
+@interface SNTPushLoadTestReport ()
+@property NSUInteger sent;
+@property NSUInteger received;
+@property NSUInteger duplicates;
+@property NSUInteger lost;
+@property NSTimeInterval elapsed;
+@property double p50LatencyMs;
+@property double p90LatencyMs;
+@property double p99LatencyMs;
+@property double maxLatencyMs;
+@end
+
+@implementation SNTPushLoadTestReport
+@end
+
+@interface SNTPushSubscriptionValidator ()
+@property NSArray<NSString*>* invalidSubjects;
+@end
+
+@implementation SNTPushSubscriptionValidator
+
+- (instancetype)init {
+  self = [super init];
+  if (self) {
+    _invalidSubjects = @[];
+  }
+  return self;
+}
+
+- (NSArray<NSString*>*)checkSnapshot:(NSDictionary*)snapshot error:(NSError**)error {
+  if (![snapshot[kPushDiagnosticsEnabled] boolValue]) {
+    [SNTError populateError:error withFormat:@"The NPS push client is not running"];
+    return nil;
+  }
+
+  std::optional<NATSPermissionList> perms = SubscribePermissions(snapshot);
+  if (!perms.has_value()) {
+    [SNTError populateError:error withFormat:@"The push JWT could not be parsed"];
+    return nil;
+  }
+
+  NSMutableArray<NSString*>* invalid = [NSMutableArray array];
+  for (NSString* subject in LiveSubscriptionSubjects(snapshot)) {
+    if (CheckNATSPermission(*perms, santa::NSStringToUTF8StringView(subject)) !=
+        NATSPermissionResult::kAllowed) {
+      [invalid addObject:subject];
+    }
+  }
+
+  NSMutableArray<NSString*>* newlyInvalid = [invalid mutableCopy];
+  [newlyInvalid removeObjectsInArray:self.invalidSubjects];
+  self.invalidSubjects = invalid;
+  return newlyInvalid;
+}
+
+@end
+
+static BOOL TCPReachable(NSString* host, uint16_t port, NSString** error) {
+  struct addrinfo hints = {.ai_family = AF_UNSPEC, .ai_socktype = SOCK_STREAM};
+  struct addrinfo* res = NULL;
+  int ret = getaddrinfo(host.UTF8String, [@(port) stringValue].UTF8String, &hints, &res);
+  if (ret != 0) {
+    if (error) *error = [NSString stringWithFormat:@"DNS lookup failed: %s", gai_strerror(ret)];
+    return NO;
+  }
+
+  BOOL reachable = NO;
+  int lastErrno = 0;
+  for (struct addrinfo* ai = res; ai && !reachable; ai = ai->ai_next) {
+    int fd = socket(ai->ai_family, ai->ai_socktype, ai->ai_protocol);
+    if (fd < 0) continue;
+    fcntl(fd, F_SETFL, fcntl(fd, F_GETFL) | O_NONBLOCK);
+
+    if (connect(fd, ai->ai_addr, ai->ai_addrlen) == 0) {
+      reachable = YES;
+    } else if (errno == EINPROGRESS) {
+      struct pollfd pfd = {.fd = fd, .events = POLLOUT};
+      if (poll(&pfd, 1, kReachabilityTimeoutMs) > 0) {
+        int soError = 0;
+        socklen_t len = sizeof(soError);
+        getsockopt(fd, SOL_SOCKET, SO_ERROR, &soError, &len);
+        reachable = (soError == 0);
+        lastErrno = soError;
+      } else {
+        lastErrno = ETIMEDOUT;
+      }
+    } else {
+      lastErrno = errno;
+    }
+    close(fd);
+  }
+  freeaddrinfo(res);
+
+  if (!reachable && error) {
+    *error = [NSString stringWithFormat:@"connect failed: %s", strerror(lastErrno)];
+  }
+  return reachable;
+}
+
+// Deliveries seen by the load test subscriber, indexed by message sequence number.
+struct LoadTestDeliveries {
+  std::mutex lock;
+  std::vector<bool> seen;
+  std::vector<uint64_t> latenciesNs;
+  NSUInteger received = 0;
+  NSUInteger duplicates = 0;
+};
+
+static uint64_t LoadTestNowNs() {
+  return clock_gettime_nsec_np(CLOCK_UPTIME_RAW);
+}
+
+// Each payload is "<sequence> <publish time in ns>".
+static void LoadTestMessageHandler(natsConnection* nc, natsSubscription* sub, natsMsg* msg,
+                                   void* closure) {
+  uint64_t now = LoadTestNowNs();
+  auto* deliveries = static_cast<LoadTestDeliveries*>(closure);
+
+  std::string payload(natsMsg_GetData(msg), natsMsg_GetDataLength(msg));
+  natsMsg_Destroy(msg);
+
+  unsigned long long seq, sentNs;
+  if (sscanf(payload.c_str(), "%llu %llu", &seq, &sentNs) != 2) return;
+
+  std::lock_guard<std::mutex> lock(deliveries->lock);
+  if (seq >= deliveries->seen.size()) return;
+  if (deliveries->seen[seq]) {
+    deliveries->duplicates++;
+    return;
+  }
+  deliveries->seen[seq] = true;
+  deliveries->received++;
+  deliveries->latenciesNs.push_back(now > sentNs ? now - sentNs : 0);
+}
+
+static natsConnection* LoadTestConnect(NSString* server, NSString* creds, NSError** error) {
+  natsOptions* opts = NULL;
+  natsConnection* conn = NULL;
+
+  natsStatus status = natsOptions_Create(&opts);
+  if (status == NATS_OK) status = natsOptions_SetURL(opts, server.UTF8String);
+  if (status == NATS_OK) status = natsOptions_SetAllowReconnect(opts, false);
+  if (status == NATS_OK && creds.length) {
+    status = natsOptions_SetUserCredentialsFromFiles(opts, creds.UTF8String, NULL);
+  }
+  if (status == NATS_OK) status = natsConnection_Connect(&conn, opts);
+  natsOptions_Destroy(opts);
+
+  if (status != NATS_OK) {
+    [SNTError populateError:error
+                 withFormat:@"Unable to connect to %@: %s", server, natsStatus_GetText(status)];
+    return NULL;
+  }
+  return conn;
+}
+
+static void LoadTestDisconnect(natsConnection* conn) {
+  if (!conn) return;
+  natsConnection_Close(conn);
+  natsConnection_Destroy(conn);
+}
+
+// Nearest-rank percentile of an already sorted list.
+static double PercentileMs(const std::vector<uint64_t>& sortedNs, double percentile) {
+  if (sortedNs.empty()) return 0;
+  size_t rank = (size_t)std::ceil(percentile * sortedNs.size());
+  return sortedNs[std::clamp<size_t>(rank, 1, sortedNs.size()) - 1] / 1e6;
+}
+
+// Parses a duration like "90", "90s", "5m" or "1h" into seconds. Returns a negative value if
+// the duration is invalid.
+static NSTimeInterval ParseDuration(NSString* value) {
+  NSDictionary<NSString*, NSNumber*>* multipliers = @{@"s" : @1, @"m" : @60, @"h" : @3600};
+  double multiplier = 1;
+  NSString* suffix = value.length ? [value substringFromIndex:value.length - 1] : nil;
+  if (multipliers[suffix]) {
+    multiplier = [multipliers[suffix] doubleValue];
+    value = [value substringToIndex:value.length - 1];
+  }
+
+  NSScanner* scanner = [NSScanner scannerWithString:value];
+  double amount;
+  if (![scanner scanDouble:&amount] || !scanner.isAtEnd || amount <= 0) return -1;
+  return amount * multiplier;
+}
+
+@implementation SNTCommandPush
+
+REGISTER_COMMAND_NAME(@"push")
+
++ (BOOL)requiresRoot {
+  return NO;
+}
+
++ (BOOL)requiresDaemonConn {
+  return NO;
+}
+
++ (NSString*)shortHelpText {
+  return @"Inspect and troubleshoot push notification configuration.";
+}
+
++ (NSString*)longHelpText {
+  return (@"Usage: santactl push <command> [options]\n"
+          @"  One of:\n"
+          @"    check-perms: Verify the publish/subscribe permissions in a NATS user JWT\n"
+          @"                 allow the subjects Santa needs.\n"
+          @"    diagnose:    Walk through the push setup step by step and report the\n"
+          @"                 first step that fails. Requires root.\n"
+          @"    loadtest:    Publish to a subject at a fixed rate and report delivery\n"
+          @"                 latency and loss as seen by a subscribed connection.\n"
+          @"    stats:       Show the push client's connection stats: bytes in and out,\n"
+          @"                 messages by subject, subscribes, reconnects and the last\n"
+          @"                 round trip time. Requires root.\n"
+          @"    target-test: Check this host would receive a push sent to a tag and, if\n"
+          @"                 not, why. Requires root.\n"
+          @"    validate:    Check the push client's current subscriptions are permitted by\n"
+          @"                 its current JWT. Requires root.\n"
+          @"\n"
+          @"  Check Perms Options:\n"
+          @"    --jwt {jwt}: The NATS user JWT to inspect. Required.\n"
+          @"    --device-id {id}: Check the host command subject for this device.\n"
+          @"    --tag {tag}: Check the subject for this tag. May be repeated.\n"
+          @"    --sub {subject}: Check an additional subscribe subject. May be repeated.\n"
+          @"    --pub {subject}: Check an additional publish subject. May be repeated.\n"
+          @"\n"
+          @"  The JWT signature is not verified, only the permission claims are checked.\n"
+          @"  Exits non-zero if any subject would be rejected.\n"
+          @"\n"
+          @"  Load Test Options:\n"
+          @"    --subject {subject}: The subject to publish to. Required.\n"
+          @"    --rate {n}: Messages to publish per second. Required.\n"
+          @"    --duration {d}: How long to publish for, e.g. 30s, 5m. Required.\n"
+          @"    --server {url}: The NATS server. Defaults to nats://localhost:4222.\n"
+          @"    --creds {path}: A NATS .creds file with publish permissions.\n"
+          @"    --sub-creds {path}: A NATS .creds file used to subscribe. Defaults to\n"
+          @"                        the --creds file.\n"
+          @"    --drain-timeout {d}: How long to wait for outstanding deliveries after\n"
+          @"                         the last publish. Defaults to 5s.\n"
+          @"\n"
+          @"  Use a subject that no Santa clients subscribe to, clients that receive a\n"
+          @"  message on their host or tag subjects will sync.\n"
+          @"\n"
+          @"  Stats Options:\n"
+          @"    --json: Print the stats as JSON.\n"
+          @"\n"
+          @"  Validate Options:\n"
+          @"    --watch: Keep re-checking and alert on subscriptions that stop being\n"
+          @"             permitted, e.g. after a credential rotation.\n"
+          @"    --interval {d}: How often to re-check with --watch. Defaults to 60s.\n"
+          @"\n"
+          @"  Without --watch, exits non-zero if any subscription would be rejected.\n"
+          @"\n"
+          @"  Target Test Options:\n"
+          @"    --tag {tag}: The tag the push would be sent to. Required.\n"
+          @"\n"
+          @"  Exits non-zero if this host would not receive the push.\n");
+}
+
++ (NSString*)descriptionForResult:(NATSPermissionResult)result {
+  switch (result) {
+    case NATSPermissionResult::kAllowed: return @"allowed";
+    case NATSPermissionResult::kNotAllowed: return @"REJECTED (not in allow list)";
+    case NATSPermissionResult::kDenied: return @"REJECTED (matches deny list)";
+  }
+}
+
+- (void)runWithArguments:(NSArray*)arguments {
+  if (!arguments.count) {
+    [self printErrorUsageAndExit:@"No arguments"];
+  }
+
+  enum class Operation {
+    kUnknown,
+    kCheckPerms,
+    kDiagnose,
+    kLoadTest,
+    kStats,
+    kTargetTest,
+    kValidate,
+  };
+
+  Operation operation = Operation::kUnknown;
+  NSString* arg = arguments[0];
+
+  if ([arg caseInsensitiveCompare:@"check-perms"] == NSOrderedSame) {
+    operation = Operation::kCheckPerms;
+  } else if ([arg caseInsensitiveCompare:@"diagnose"] == NSOrderedSame) {
+    operation = Operation::kDiagnose;
+  } else if ([arg caseInsensitiveCompare:@"loadtest"] == NSOrderedSame) {
+    operation = Operation::kLoadTest;
+  } else if ([arg caseInsensitiveCompare:@"stats"] == NSOrderedSame) {
+    operation = Operation::kStats;
+  } else if ([arg caseInsensitiveCompare:@"target-test"] == NSOrderedSame) {
+    operation = Operation::kTargetTest;
+  } else if ([arg caseInsensitiveCompare:@"validate"] == NSOrderedSame) {
+    operation = Operation::kValidate;
+  } else {
+    [self printErrorUsageAndExit:[@"Unknown operation: " stringByAppendingString:arg]];
+  }
+
+  NSArray* operationArgs = [arguments subarrayWithRange:NSMakeRange(1, arguments.count - 1)];
+
+  switch (operation) {
+    case Operation::kCheckPerms: {
+      [self checkPermsWithArguments:operationArgs];
+      break;
+    }
+    case Operation::kDiagnose: {
+      [self diagnoseWithArguments:operationArgs];
+      break;
+    }
+    case Operation::kLoadTest: {
+      [self loadTestWithArguments:operationArgs];
+      break;
+    }
+    case Operation::kStats: {
+      [self statsWithArguments:operationArgs];
+      break;
+    }
+    case Operation::kTargetTest: {
+      [self targetTestWithArguments:operationArgs];
+      break;
+    }
+    case Operation::kValidate: {
+      [self validateWithArguments:operationArgs];
+      break;
+    }
+    default: [self printErrorUsageAndExit:@"No operation provided"];
+  }
+
+  // Individual operation handlers control exiting with success or failure
+  exit(EXIT_FAILURE);
+}
+
+- (void)checkPermsWithArguments:(NSArray*)arguments {
+  NSString* jwt;
+  NSMutableArray<NSString*>* subSubjects = [NSMutableArray array];
+  NSMutableArray<NSString*>* pubSubjects = [NSMutableArray array];
+
+  for (NSUInteger i = 0; i < arguments.count; ++i) {
+    NSString* arg = arguments[i];
+
+    if ([arg caseInsensitiveCompare:@"--jwt"] == NSOrderedSame) {
+      if (++i > arguments.count - 1) {
+        [self printErrorUsageAndExit:@"--jwt requires an argument"];
+      }
+      jwt = arguments[i];
+    } else if ([arg caseInsensitiveCompare:@"--device-id"] == NSOrderedSame) {
+      if (++i > arguments.count - 1) {
+        [self printErrorUsageAndExit:@"--device-id requires an argument"];
+      }
+      [subSubjects addObject:[NSString stringWithFormat:@"santa.host.%@.commands", arguments[i]]];
+    } else if ([arg caseInsensitiveCompare:@"--tag"] == NSOrderedSame) {
+      if (++i > arguments.count - 1) {
+        [self printErrorUsageAndExit:@"--tag requires an argument"];
+      }
+      NSString* tag = arguments[i];
+      [subSubjects addObject:[tag hasPrefix:kTagSubjectPrefix]
+                                 ? tag
+                                 : [kTagSubjectPrefix stringByAppendingString:tag]];
+    } else if ([arg caseInsensitiveCompare:@"--sub"] == NSOrderedSame) {
+      if (++i > arguments.count - 1) {
+        [self printErrorUsageAndExit:@"--sub requires an argument"];
+      }
+      [subSubjects addObject:arguments[i]];
+    } else if ([arg caseInsensitiveCompare:@"--pub"] == NSOrderedSame) {
+      if (++i > arguments.count - 1) {
+        [self printErrorUsageAndExit:@"--pub requires an argument"];
+      }
+      [pubSubjects addObject:arguments[i]];
+    } else {
+      [self printErrorUsageAndExit:[@"Unknown argument: " stringByAppendingString:arg]];
+    }
+  }
+
+  if (!jwt.length) {
+    [self printErrorUsageAndExit:@"--jwt is required"];
+  }
+
+  if (!subSubjects.count && !pubSubjects.count) {
+    [self printErrorUsageAndExit:@"No subjects to check. Use --device-id, --tag, --sub or --pub"];
+  }
+
+  std::optional<NATSPermissions> perms = NATSPermissionsFromJWT(jwt);
+  if (!perms.has_value()) {
+    TEE_LOGE(@"Unable to parse JWT");
+    exit(EXIT_FAILURE);
+  }
+
+  __block bool rejected = false;
+  void (^check)(NSString*, const NATSPermissionList&, NSArray<NSString*>*) =
+      ^(NSString* kind, const NATSPermissionList& list, NSArray<NSString*>* subjects) {
+        for (NSString* subject in subjects) {
+          NATSPermissionResult result =
+              CheckNATSPermission(list, santa::NSStringToUTF8StringView(subject));
+          if (result != NATSPermissionResult::kAllowed) {
+            rejected = true;
+          }
+          printf("%-4s %-40s %s\n", kind.UTF8String, subject.UTF8String,
+                 [[[self class] descriptionForResult:result] UTF8String]);
+        }
+      };
+
+  check(@"sub", perms->sub, subSubjects);
+  check(@"pub", perms->pub, pubSubjects);
+
+  exit(rejected ? EXIT_FAILURE : EXIT_SUCCESS);
+}
+
+// Fetch the push client diagnostics from the sync service. Returns nil if the sync service
+// doesn't respond in time.
+- (NSDictionary*)pushDiagnosticsSnapshot {
+  MOLXPCConnection* conn = [SNTXPCSyncServiceInterface configuredConnection];
+  [conn resume];
+
+  __block NSDictionary* snapshot;
+  dispatch_semaphore_t sema = dispatch_semaphore_create(0);
+  [[conn remoteObjectProxy] pushNotificationDiagnostics:^(NSDictionary* reply) {
+    snapshot = reply;
+    dispatch_semaphore_signal(sema);
+  }];
+
+  if (dispatch_semaphore_wait(sema, dispatch_time(DISPATCH_TIME_NOW, 10 * NSEC_PER_SEC)) != 0) {
+    [conn invalidate];
+    return nil;
+  }
+  [conn invalidate];
+  return snapshot;
+}
+
+#pragma mark diagnose
+
++ (SNTPushDiagnosis*)diagnoseWithSnapshot:(NSDictionary*)snapshot
+                                      now:(NSDate*)now
+                             reachability:(SNTPushReachabilityBlock)reachability {
+  SNTPushDiagnosis* diagnosis = [[SNTPushDiagnosis alloc] init];
+
+  // 1. Preflight returned a push server and credentials
+  if (![snapshot[kPushDiagnosticsEnabled] boolValue]) {
+    return [diagnosis failStep:SNTPushDiagnoseStepPreflight
+                        detail:@"The NPS push client is not running"
+                   remediation:@"Push requires sync v2 and EnablePushNotifications. Verify "
+                               @"the sync server supports push and run `santactl sync`."];
+  }
+
+  NSString* server = snapshot[kPushDiagnosticsServer];
+  NSString* deviceID = snapshot[kPushDiagnosticsDeviceID];
+  NSMutableArray* missing = [NSMutableArray array];
+  if (!server.length) [missing addObject:@"server"];
+  if (!snapshot[kPushDiagnosticsJWTParsed]) [missing addObject:@"JWT"];
+  if (!deviceID.length) [missing addObject:@"device ID"];
+  if (missing.count) {
+    return [diagnosis
+          failStep:SNTPushDiagnoseStepPreflight
+            detail:[NSString stringWithFormat:@"Preflight did not provide a push %@",
+                                              [missing componentsJoinedByString:@", "]]
+       remediation:@"Verify push is enabled for this host on the sync server and run "
+                   @"`santactl sync`."];
+  }
+  [diagnosis.passedSteps addObject:[NSString stringWithFormat:@"Preflight returned push server %@",
+                                                              server]];
+
+  // 2. The JWT is well formed and unexpired
+  if (![snapshot[kPushDiagnosticsJWTParsed] boolValue]) {
+    return [diagnosis failStep:SNTPushDiagnoseStepJWT
+                        detail:@"The push JWT could not be parsed"
+                   remediation:@"Run `santactl sync` to fetch new push credentials. If the "
+                               @"problem persists, contact your sync server administrator."];
+  }
+  NSDate* expiry = snapshot[kPushDiagnosticsJWTExpiry];
+  if (expiry && [expiry compare:now] == NSOrderedAscending) {
+    return [diagnosis failStep:SNTPushDiagnoseStepJWT
+                        detail:[NSString stringWithFormat:@"The push JWT expired at %@", expiry]
+                   remediation:@"Run `santactl sync` to fetch new push credentials. Check the "
+                               @"system clock is correct."];
+  }
+  [diagnosis.passedSteps addObject:@"Push JWT is valid and unexpired"];
+
+  // 3. The server is reachable
+  NSURL* url = [NSURL URLWithString:server];
+  NSString* host = url.host;
+  uint16_t port = url.port ? url.port.unsignedShortValue : 443;
+  if (!host.length) {
+    return [diagnosis
+          failStep:SNTPushDiagnoseStepReachability
+            detail:[NSString stringWithFormat:@"Unable to parse push server address: %@", server]
+       remediation:@"Contact your sync server administrator."];
+  }
+  NSString* reachError;
+  if (!reachability(host, port, &reachError)) {
+    NSString* suffix = reachError ? [@" - " stringByAppendingString:reachError] : @"";
+    return [diagnosis
+          failStep:SNTPushDiagnoseStepReachability
+            detail:[NSString stringWithFormat:@"Unable to reach %@:%u%@", host, port, suffix]
+       remediation:[NSString stringWithFormat:@"Ensure outbound TCP connections to %@ on port %u "
+                                              @"are allowed by any firewall, proxy or network "
+                                              @"filter.",
+                                              host, port]];
+  }
+  [diagnosis.passedSteps
+      addObject:[NSString stringWithFormat:@"Push server %@:%u is reachable", host, port]];
+
+  // 4. Authentication succeeded
+  NSString* lastError = snapshot[kPushDiagnosticsLastError];
+  if (![snapshot[kPushDiagnosticsConnected] boolValue]) {
+    if ([lastError containsString:@"AUTH_ERROR"] ||
+        [lastError rangeOfString:@"authorization" options:NSCaseInsensitiveSearch].length) {
+      return [diagnosis
+            failStep:SNTPushDiagnoseStepAuthentication
+              detail:[NSString stringWithFormat:@"The push server rejected the credentials: %@",
+                                                lastError]
+         remediation:@"Run `santactl sync` to fetch new push credentials. If the problem "
+                     @"persists, the host may have been revoked on the sync server."];
+    } else if (lastError.length) {
+      return [diagnosis
+            failStep:SNTPushDiagnoseStepAuthentication
+              detail:[NSString stringWithFormat:@"Unable to connect to the push server: %@",
+                                                lastError]
+         remediation:@"Check for TLS interception or a proxy between this host and the push "
+                     @"server."];
+    } else {
+      return [diagnosis failStep:SNTPushDiagnoseStepAuthentication
+                          detail:@"The push client has not connected yet"
+                     remediation:@"Wait a few seconds and try again, or run `santactl sync`."];
+    }
+  }
+  [diagnosis.passedSteps addObject:@"Authenticated with the push server"];
+
+  // 5. Subscriptions are permitted
+  NSString* denied = snapshot[kPushDiagnosticsDeniedSubject];
+  if (denied.length) {
+    return [diagnosis
+          failStep:SNTPushDiagnoseStepSubscriptions
+            detail:[NSString stringWithFormat:@"The push server rejected a subscription to %@",
+                                              denied]
+       remediation:@"The push credentials do not permit this subject. Contact your sync server "
+                   @"administrator."];
+  }
+
+  std::optional<NATSPermissionList> perms = SubscribePermissions(snapshot);
+  NSArray<NSString*>* subjects = LiveSubscriptionSubjects(snapshot);
+  for (NSString* subject in subjects) {
+    if (perms.has_value() &&
+        CheckNATSPermission(*perms, santa::NSStringToUTF8StringView(subject)) !=
+            NATSPermissionResult::kAllowed) {
+      return [diagnosis
+            failStep:SNTPushDiagnoseStepSubscriptions
+              detail:[NSString stringWithFormat:@"The push JWT does not permit subscribing to %@",
+                                                subject]
+         remediation:@"The push credentials do not permit this subject. Contact your sync "
+                     @"server administrator."];
+    }
+  }
+  [diagnosis.passedSteps
+      addObject:[NSString stringWithFormat:@"Subscriptions permitted for %lu subject(s)",
+                                           (unsigned long)subjects.count]];
+
+  return diagnosis;
+}
+
+- (void)diagnoseWithArguments:(NSArray*)arguments {
+  if (arguments.count) {
+    [self printErrorUsageAndExit:[@"Unknown argument: " stringByAppendingString:arguments[0]]];
+  }
+
+  if (getuid() != 0) {
+    TEE_LOGE(@"diagnose requires root privileges");
+    exit(EXIT_FAILURE);
+  }
+
+  NSDictionary* snapshot = [self pushDiagnosticsSnapshot];
+  if (!snapshot) {
+    TEE_LOGE(@"Timed out waiting for a response from the sync service");
+    exit(EXIT_FAILURE);
+  }
+
+  SNTPushDiagnosis* diagnosis = [[self class] diagnoseWithSnapshot:snapshot
+                                                               now:[NSDate date]
+                                                      reachability:^BOOL(NSString* host,
+                                                                         uint16_t port,
+                                                                         NSString** error) {
+                                                        return TCPReachable(host, port, error);
+                                                      }];
+
+  for (NSString* step in diagnosis.passedSteps) {
+    printf("[+] %s\n", step.UTF8String);
+  }
+
+  if (diagnosis.failedStep == SNTPushDiagnoseStepNone) {
+    printf("\nPush is connected and healthy.\n");
+    exit(EXIT_SUCCESS);
+  }
+
+  printf("[-] %s\n", diagnosis.detail.UTF8String);
+  printf("\nRemediation: %s\n", diagnosis.remediation.UTF8String);
+  exit(EXIT_FAILURE);
+}
+
+#pragma mark stats
+
++ (NSString*)statsTextForSnapshot:(NSDictionary*)snapshot {
+  NSDictionary* stats = snapshot[kPushDiagnosticsStats];
+  NSMutableString* text = [NSMutableString string];
+  [text appendFormat:@"%-20s | %@\n", "Connected",
+                     [snapshot[kPushDiagnosticsConnected] boolValue] ? @"Yes" : @"No"];
+  [text appendFormat:@"%-20s | %llu\n", "Bytes In",
+                     [stats[kPushStatsBytesIn] unsignedLongLongValue]];
+  [text appendFormat:@"%-20s | %llu\n", "Bytes Out",
+                     [stats[kPushStatsBytesOut] unsignedLongLongValue]];
+  [text appendFormat:@"%-20s | %llu\n", "Subscribes",
+                     [stats[kPushStatsSubscribes] unsignedLongLongValue]];
+  [text appendFormat:@"%-20s | %llu\n", "Reconnects",
+                     [stats[kPushStatsReconnects] unsignedLongLongValue]];
+  if (stats[kPushStatsLastRTTMs]) {
+    NSDate* measured =
+        [NSDate dateWithTimeIntervalSince1970:[stats[kPushStatsLastRTTTime] doubleValue]];
+    [text appendFormat:@"%-20s | %.1f ms (measured %@)\n", "Last RTT",
+                       [stats[kPushStatsLastRTTMs] doubleValue], measured];
+  } else {
+    [text appendFormat:@"%-20s | Not measured\n", "Last RTT"];
+  }
+  if (stats[kPushStatsLatencyCount]) {
+    [text appendFormat:@"%-20s | p50 %.1f ms, p95 %.1f ms, max %.1f ms (%llu messages)\n",
+                       "Delivery Latency", [stats[kPushStatsLatencyP50Ms] doubleValue],
+                       [stats[kPushStatsLatencyP95Ms] doubleValue],
+                       [stats[kPushStatsLatencyMaxMs] doubleValue],
+                       [stats[kPushStatsLatencyCount] unsignedLongLongValue]];
+  } else {
+    [text appendFormat:@"%-20s | Not measured\n", "Delivery Latency"];
+  }
+
+  NSDictionary<NSString*, NSNumber*>* messages = stats[kPushStatsMessagesBySubject];
+  [text appendFormat:@"%-20s | %llu\n", "Messages Received",
+                     [[messages.allValues valueForKeyPath:@"@sum.self"] unsignedLongLongValue]];
+  for (NSString* subject in [messages.allKeys sortedArrayUsingSelector:@selector(compare:)]) {
+    [text appendFormat:@"  %-40s %llu\n", subject.UTF8String,
+                       [messages[subject] unsignedLongLongValue]];
+  }
+  return text;
+}
+
+- (void)statsWithArguments:(NSArray*)arguments {
+  BOOL json = NO;
+  for (NSString* arg in arguments) {
+    if ([arg caseInsensitiveCompare:@"--json"] == NSOrderedSame) {
+      json = YES;
+    } else {
+      [self printErrorUsageAndExit:[@"Unknown argument: " stringByAppendingString:arg]];
+    }
+  }
+
+  if (getuid() != 0) {
+    TEE_LOGE(@"stats requires root privileges");
+    exit(EXIT_FAILURE);
+  }
+
+  NSDictionary* snapshot = [self pushDiagnosticsSnapshot];
+  if (!snapshot) {
+    TEE_LOGE(@"Timed out waiting for a response from the sync service");
+    exit(EXIT_FAILURE);
+  }
+  if (![snapshot[kPushDiagnosticsEnabled] boolValue]) {
+    TEE_LOGE(@"The NPS push client is not running");
+    exit(EXIT_FAILURE);
+  }
+
+  if (json) {
+    NSMutableDictionary* stats =
+        [snapshot[kPushDiagnosticsStats] mutableCopy] ?: [NSMutableDictionary dictionary];
+    stats[kPushDiagnosticsConnected] = snapshot[kPushDiagnosticsConnected];
+    NSData* data = [NSJSONSerialization
+        dataWithJSONObject:stats
+                   options:NSJSONWritingPrettyPrinted | NSJSONWritingSortedKeys
+                     error:NULL];
+    printf("%s\n", [[NSString alloc] initWithData:data encoding:NSUTF8StringEncoding].UTF8String);
+    exit(EXIT_SUCCESS);
+  }
+
+  printf("%s", [[self class] statsTextForSnapshot:snapshot].UTF8String);
+  exit(EXIT_SUCCESS);
+}
+
+#pragma mark target-test
+
++ (SNTPushTargetTest*)targetTestForTag:(NSString*)tag snapshot:(NSDictionary*)snapshot {
+  SNTPushTargetTest* test = [[SNTPushTargetTest alloc] init];
+  NSString* subject =
+      [tag hasPrefix:kTagSubjectPrefix] ? tag : [kTagSubjectPrefix stringByAppendingString:tag];
+  test.subject = subject;
+
+  if (![snapshot[kPushDiagnosticsEnabled] boolValue]) {
+    return [test result:SNTPushTargetResultPushNotRunning
+                 detail:@"The NPS push client is not running, so no pushes are received"];
+  }
+
+  // The push client skips tags that contain a period or hyphen after the prefix.
+  NSString* name = [subject substringFromIndex:kTagSubjectPrefix.length];
+  NSCharacterSet* invalidChars = [NSCharacterSet characterSetWithCharactersInString:@".-"];
+  if (!name.length || [name rangeOfCharacterFromSet:invalidChars].location != NSNotFound) {
+    return [test result:SNTPushTargetResultInvalidTag
+                 detail:[NSString stringWithFormat:@"%@ is not a valid tag subject: tag names "
+                                                   @"must be non-empty and can't contain a "
+                                                   @"period or hyphen",
+                                                   subject]];
+  }
+
+  NSArray<NSString*>* tags = snapshot[kPushDiagnosticsTags];
+  if (![tags containsObject:subject]) {
+    NSString* memberOf = tags.count ? [tags componentsJoinedByString:@", "] : @"no tags";
+    return [test result:SNTPushTargetResultNotMember
+                 detail:[NSString stringWithFormat:@"This host is not a member of %@. It is a "
+                                                   @"member of: %@",
+                                                   subject, memberOf]];
+  }
+
+  std::optional<NATSPermissionList> perms = SubscribePermissions(snapshot);
+  if (!perms.has_value()) {
+    return [test result:SNTPushTargetResultNotPermitted
+                 detail:@"The push JWT could not be parsed, so its permissions are unknown"];
+  }
+  NATSPermissionResult permission =
+      CheckNATSPermission(*perms, santa::NSStringToUTF8StringView(subject));
+  if (permission != NATSPermissionResult::kAllowed) {
+    NSString* reason = [self descriptionForResult:permission];
+    return [test result:SNTPushTargetResultNotPermitted
+                 detail:[NSString stringWithFormat:@"The push JWT does not permit subscribing to "
+                                                   @"%@: %@",
+                                                   subject, reason]];
+  }
+
+  if ([snapshot[kPushDiagnosticsDeniedSubject] isEqualToString:subject]) {
+    return [test result:SNTPushTargetResultRejectedByServer
+                 detail:[NSString stringWithFormat:@"The push server rejected the subscription "
+                                                   @"to %@",
+                                                   subject]];
+  }
+
+  if (![snapshot[kPushDiagnosticsConnected] boolValue]) {
+    return [test result:SNTPushTargetResultNotConnected
+                 detail:@"The push client is not connected to the push server. Run `santactl "
+                        @"push diagnose` to find out why."];
+  }
+
+  return [test result:SNTPushTargetResultReceives
+               detail:[NSString stringWithFormat:@"This host is a member of %@ and is permitted "
+                                                 @"to subscribe to it",
+                                                 subject]];
+}
+
+- (void)targetTestWithArguments:(NSArray*)arguments {
+  NSString* tag;
+
+  for (NSUInteger i = 0; i < arguments.count; ++i) {
+    NSString* arg = arguments[i];
+
+    if ([arg caseInsensitiveCompare:@"--tag"] == NSOrderedSame) {
+      if (++i > arguments.count - 1) {
+        [self printErrorUsageAndExit:@"--tag requires an argument"];
+      }
+      tag = arguments[i];
+    } else {
+      [self printErrorUsageAndExit:[@"Unknown argument: " stringByAppendingString:arg]];
+    }
+  }
+
+  if (!tag.length) {
+    [self printErrorUsageAndExit:@"--tag is required"];
+  }
+
+  if (getuid() != 0) {
+    TEE_LOGE(@"target-test requires root privileges");
+    exit(EXIT_FAILURE);
+  }
+
+  NSDictionary* snapshot = [self pushDiagnosticsSnapshot];
+  if (!snapshot) {
+    TEE_LOGE(@"Timed out waiting for a response from the sync service");
+    exit(EXIT_FAILURE);
+  }
+
+  SNTPushTargetTest* test = [[self class] targetTestForTag:tag snapshot:snapshot];
+  BOOL receives = test.result == SNTPushTargetResultReceives;
+  printf("[%s] %s\n", receives ? "+" : "-", test.detail.UTF8String);
+  printf("\nA push sent to %s %s be received by this host.\n", test.subject.UTF8String,
+         receives ? "would" : "would NOT");
+  exit(receives ? EXIT_SUCCESS : EXIT_FAILURE);
+}
+
+#pragma mark validate
+
+- (void)validateWithArguments:(NSArray*)arguments {
+  BOOL watch = NO;
+  NSTimeInterval interval = kDefaultValidateInterval;
+
+  for (NSUInteger i = 0; i < arguments.count; ++i) {
+    NSString* arg = arguments[i];
+
+    if ([arg caseInsensitiveCompare:@"--watch"] == NSOrderedSame) {
+      watch = YES;
+    } else if ([arg caseInsensitiveCompare:@"--interval"] == NSOrderedSame) {
+      if (++i > arguments.count - 1) {
+        [self printErrorUsageAndExit:@"--interval requires an argument"];
+      }
+      interval = ParseDuration(arguments[i]);
+      if (interval <= 0) {
+        [self printErrorUsageAndExit:[@"Invalid duration: " stringByAppendingString:arguments[i]]];
+      }
+    } else {
+      [self printErrorUsageAndExit:[@"Unknown argument: " stringByAppendingString:arg]];
+    }
+  }
+
+  if (getuid() != 0) {
+    TEE_LOGE(@"validate requires root privileges");
+    exit(EXIT_FAILURE);
+  }
+
+  SNTPushSubscriptionValidator* validator = [[SNTPushSubscriptionValidator alloc] init];
+
+  if (!watch) {
+    NSDictionary* snapshot = [self pushDiagnosticsSnapshot];
+    if (!snapshot) {
+      TEE_LOGE(@"Timed out waiting for a response from the sync service");
+      exit(EXIT_FAILURE);
+    }
+
+    NSError* error;
+    if (![validator checkSnapshot:snapshot error:&error]) {
+      TEE_LOGE(@"%@", error.localizedDescription);
+      exit(EXIT_FAILURE);
+    }
+    for (NSString* subject in LiveSubscriptionSubjects(snapshot)) {
+      BOOL invalid = [validator.invalidSubjects containsObject:subject];
+      printf("%-40s %s\n", subject.UTF8String, invalid ? "REJECTED" : "allowed");
+    }
+    exit(validator.invalidSubjects.count ? EXIT_FAILURE : EXIT_SUCCESS);
+  }
+
+  printf("Checking push subscriptions every %.0fs, press Ctrl-C to stop.\n", interval);
+  while (true) {
+    NSDictionary* snapshot = [self pushDiagnosticsSnapshot];
+    NSArray<NSString*>* previouslyInvalid = validator.invalidSubjects;
+    NSError* error;
+    NSArray<NSString*>* newlyInvalid;
+
+    if (!snapshot) {
+      TEE_LOGW(@"Timed out waiting for a response from the sync service");
+    } else if (!(newlyInvalid = [validator checkSnapshot:snapshot error:&error])) {
+      TEE_LOGW(@"Unable to check subscriptions: %@", error.localizedDescription);
+    } else {
+      for (NSString* subject in newlyInvalid) {
+        TEE_LOGE(@"ALERT: The push JWT no longer permits subscribing to %@", subject);
+      }
+      for (NSString* subject in previouslyInvalid) {
+        if (![validator.invalidSubjects containsObject:subject]) {
+          TEE_LOGI(@"The push JWT permits subscribing to %@ again", subject);
+        }
+      }
+    }
+
+    [NSThread sleepForTimeInterval:interval];
+  }
+}
+
+#pragma mark loadtest
+
++ (SNTPushLoadTestReport*)loadTestWithServer:(NSString*)server
+                              publisherCreds:(NSString*)publisherCreds
+                             subscriberCreds:(NSString*)subscriberCreds
+                                     subject:(NSString*)subject
+                                        rate:(NSUInteger)rate
+                                    duration:(NSTimeInterval)duration
+                                drainTimeout:(NSTimeInterval)drainTimeout
+                                       error:(NSError**)error {
+  NSUInteger total = (NSUInteger)llround(rate * duration);
+
+  LoadTestDeliveries deliveries;
+  deliveries.seen.resize(total);
+  deliveries.latenciesNs.reserve(total);
+
+  natsConnection* subConn = LoadTestConnect(server, subscriberCreds, error);
+  if (!subConn) return nil;
+
+  natsSubscription* sub = NULL;
+  natsStatus status = natsConnection_Subscribe(&sub, subConn, subject.UTF8String,
+                                               &LoadTestMessageHandler, &deliveries);
+  // Don't let the client library drop messages as a slow consumer, that would be reported as
+  // loss on the server.
+  if (status == NATS_OK) status = natsSubscription_SetPendingLimits(sub, -1, -1);
+  // Make sure the server has processed the subscription before publishing.
+  if (status == NATS_OK) status = natsConnection_Flush(subConn);
+  if (status != NATS_OK) {
+    [SNTError populateError:error
+                 withFormat:@"Unable to subscribe to %@: %s", subject, natsStatus_GetText(status)];
+    natsSubscription_Destroy(sub);
+    LoadTestDisconnect(subConn);
+    return nil;
+  }
+
+  natsConnection* pubConn = LoadTestConnect(server, publisherCreds, error);
+  if (!pubConn) {
+    natsSubscription_Destroy(sub);
+    LoadTestDisconnect(subConn);
+    return nil;
+  }
+
+  NSUInteger sent = 0;
+  uint64_t intervalNs = rate ? NSEC_PER_SEC / rate : 0;
+  uint64_t start = LoadTestNowNs();
+  for (NSUInteger seq = 0; seq < total; ++seq) {
+    uint64_t target = start + seq * intervalNs;
+    uint64_t now = LoadTestNowNs();
+    if (now < target) {
+      struct timespec ts = {.tv_sec = (time_t)((target - now) / NSEC_PER_SEC),
+                            .tv_nsec = (long)((target - now) % NSEC_PER_SEC)};
+      nanosleep(&ts, NULL);
+    }
+
+    std::string payload = std::to_string(seq) + " " + std::to_string(LoadTestNowNs());
+    if (natsConnection_Publish(pubConn, subject.UTF8String, payload.data(),
+                               (int)payload.size()) == NATS_OK) {
+      sent++;
+    }
+  }
+  natsConnection_Flush(pubConn);
+  NSTimeInterval elapsed = (LoadTestNowNs() - start) / 1e9;
+
+  uint64_t deadline = LoadTestNowNs() + (uint64_t)(drainTimeout * NSEC_PER_SEC);
+  while (LoadTestNowNs() < deadline) {
+    {
+      std::lock_guard<std::mutex> lock(deliveries.lock);
+      if (deliveries.received >= sent) break;
+    }
+    usleep(10000);
+  }
+
+  // Stop deliveries before reading the results and before deliveries goes out of scope.
+  natsSubscription_Unsubscribe(sub);
+  natsSubscription_Destroy(sub);
+  LoadTestDisconnect(pubConn);
+  LoadTestDisconnect(subConn);
+
+  std::lock_guard<std::mutex> lock(deliveries.lock);
+  std::sort(deliveries.latenciesNs.begin(), deliveries.latenciesNs.end());
+
+  SNTPushLoadTestReport* report = [[SNTPushLoadTestReport alloc] init];
+  report.sent = sent;
+  report.received = deliveries.received;
+  report.duplicates = deliveries.duplicates;
+  report.lost = sent - deliveries.received;
+  report.elapsed = elapsed;
+  report.p50LatencyMs = PercentileMs(deliveries.latenciesNs, 0.50);
+  report.p90LatencyMs = PercentileMs(deliveries.latenciesNs, 0.90);
+  report.p99LatencyMs = PercentileMs(deliveries.latenciesNs, 0.99);
+  report.maxLatencyMs = deliveries.latenciesNs.empty() ? 0 : deliveries.latenciesNs.back() / 1e6;
+  return report;
+}
+
+- (void)loadTestWithArguments:(NSArray*)arguments {
+  NSString* server = kDefaultLoadTestServer;
+  NSString* creds;
+  NSString* subCreds;
+  NSString* subject;
+  NSInteger rate = 0;
+  NSTimeInterval duration = 0;
+  NSTimeInterval drainTimeout = kDefaultLoadTestDrainTimeout;
+
+  for (NSUInteger i = 0; i < arguments.count; ++i) {
+    NSString* arg = arguments[i];
+
+    if ([arg caseInsensitiveCompare:@"--subject"] == NSOrderedSame) {
+      if (++i > arguments.count - 1) {
+        [self printErrorUsageAndExit:@"--subject requires an argument"];
+      }
+      subject = arguments[i];
+    } else if ([arg caseInsensitiveCompare:@"--rate"] == NSOrderedSame) {
+      if (++i > arguments.count - 1) {
+        [self printErrorUsageAndExit:@"--rate requires an argument"];
+      }
+      rate = [arguments[i] integerValue];
+      if (rate <= 0) {
+        [self printErrorUsageAndExit:@"--rate must be a positive number"];
+      }
+    } else if ([arg caseInsensitiveCompare:@"--duration"] == NSOrderedSame) {
+      if (++i > arguments.count - 1) {
+        [self printErrorUsageAndExit:@"--duration requires an argument"];
+      }
+      duration = ParseDuration(arguments[i]);
+      if (duration <= 0) {
+        [self printErrorUsageAndExit:[@"Invalid duration: " stringByAppendingString:arguments[i]]];
+      }
+    } else if ([arg caseInsensitiveCompare:@"--server"] == NSOrderedSame) {
+      if (++i > arguments.count - 1) {
+        [self printErrorUsageAndExit:@"--server requires an argument"];
+      }
+      server = arguments[i];
+    } else if ([arg caseInsensitiveCompare:@"--creds"] == NSOrderedSame) {
+      if (++i > arguments.count - 1) {
+        [self printErrorUsageAndExit:@"--creds requires an argument"];
+      }
+      creds = arguments[i];
+    } else if ([arg caseInsensitiveCompare:@"--sub-creds"] == NSOrderedSame) {
+      if (++i > arguments.count - 1) {
+        [self printErrorUsageAndExit:@"--sub-creds requires an argument"];
+      }
+      subCreds = arguments[i];
+    } else if ([arg caseInsensitiveCompare:@"--drain-timeout"] == NSOrderedSame) {
+      if (++i > arguments.count - 1) {
+        [self printErrorUsageAndExit:@"--drain-timeout requires an argument"];
+      }
+      drainTimeout = ParseDuration(arguments[i]);
+      if (drainTimeout <= 0) {
+        [self printErrorUsageAndExit:[@"Invalid duration: " stringByAppendingString:arguments[i]]];
+      }
+    } else {
+      [self printErrorUsageAndExit:[@"Unknown argument: " stringByAppendingString:arg]];
+    }
+  }
+
+  if (!subject.length) {
+    [self printErrorUsageAndExit:@"--subject is required"];
+  }
+  if (!rate || !duration) {
+    [self printErrorUsageAndExit:@"--rate and --duration are required"];
+  }
+
+  if ([subject hasPrefix:@"santa."]) {
+    fprintf(stderr, "Warning: Santa clients subscribed to %s will sync for every message.\n",
+            subject.UTF8String);
+  }
+
+  printf("Publishing to %s at %ld msg/s for %.0fs...\n", subject.UTF8String, (long)rate,
+         duration);
+
+  NSError* error;
+  SNTPushLoadTestReport* report = [[self class] loadTestWithServer:server
+                                                    publisherCreds:creds
+                                                   subscriberCreds:subCreds ?: creds
+                                                           subject:subject
+                                                              rate:(NSUInteger)rate
+                                                          duration:duration
+                                                      drainTimeout:drainTimeout
+                                                             error:&error];
+  if (!report) {
+    TEE_LOGE(@"%@", error.localizedDescription);
+    exit(EXIT_FAILURE);
+  }
+
+  double lossPercent = report.sent ? 100.0 * report.lost / report.sent : 0;
+  printf("Sent:       %lu (%.1f msg/s)\n", (unsigned long)report.sent,
+         report.elapsed > 0 ? report.sent / report.elapsed : 0);
+  printf("Received:   %lu\n", (unsigned long)report.received);
+  printf("Lost:       %lu (%.2f%%)\n", (unsigned long)report.lost, lossPercent);
+  printf("Duplicates: %lu\n", (unsigned long)report.duplicates);
+  printf("Latency:    p50 %.2fms, p90 %.2fms, p99 %.2fms, max %.2fms\n", report.p50LatencyMs,
+         report.p90LatencyMs, report.p99LatencyMs, report.maxLatencyMs);
+  exit(EXIT_SUCCESS);
+}
+
+@end
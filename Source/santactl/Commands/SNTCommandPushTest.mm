@@ -0,0 +1,624 @@
+/// Copyright 2026 North Pole Security, Inc.
+///
+/// Licensed under the Apache License, Version 2.0 (the "License");
+/// you may not use this file except in compliance with the License.
+/// You may obtain a copy of the License at
+///
+///     http://www.apache.org/licenses/LICENSE-2.0
+///
+/// Unless required by applicable law or agreed to in writing, software
+/// distributed under the License is distributed on an "AS IS" BASIS,
+/// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+/// See the License for the specific language governing permissions and
+/// limitations under the License.
+
+#import <XCTest/XCTest.h>
+
+#include <arpa/inet.h>
+#include <netinet/in.h>
+#include <poll.h>
+#include <sys/socket.h>
+#include <unistd.h>
+
+#include <atomic>
+#include <mutex>
+#include <sstream>
+#include <string>
+#include <thread>
+#include <vector>
+
+#import "Source/common/SNTSyncConstants.h"
+#import "Source/santactl/Commands/SNTCommandPush.h"
+
+static const NSTimeInterval kNow = 1800000000;
+
+// A minimal NATS server that speaks just enough of the client protocol for the load test.
+// Every dropEvery'th published message is not delivered and every duplicateEvery'th message
+// is delivered twice.
+class MockNATSServer {
+ public:
+  MockNATSServer(int dropEvery = 0, int duplicateEvery = 0)
+      : dropEvery_(dropEvery), duplicateEvery_(duplicateEvery) {
+    listenFd_ = socket(AF_INET, SOCK_STREAM, 0);
+    struct sockaddr_in addr = {};
+    addr.sin_family = AF_INET;
+    addr.sin_addr.s_addr = htonl(INADDR_LOOPBACK);
+    bind(listenFd_, (struct sockaddr*)&addr, sizeof(addr));
+    listen(listenFd_, 4);
+    socklen_t len = sizeof(addr);
+    getsockname(listenFd_, (struct sockaddr*)&addr, &len);
+    port_ = ntohs(addr.sin_port);
+    acceptThread_ = std::thread([this] { AcceptLoop(); });
+  }
+
+  ~MockNATSServer() {
+    stop_ = true;
+    acceptThread_.join();
+    for (auto& t : clientThreads_) t.join();
+    close(listenFd_);
+  }
+
+  NSString* URL() const { return [NSString stringWithFormat:@"nats://127.0.0.1:%u", port_]; }
+
+  int Published() {
+    std::lock_guard<std::mutex> lock(mutex_);
+    return published_;
+  }
+
+ private:
+  struct Subscription {
+    int fd;
+    std::string subject;
+    std::string sid;
+  };
+
+  void AcceptLoop() {
+    while (!stop_) {
+      struct pollfd pfd = {.fd = listenFd_, .events = POLLIN};
+      if (poll(&pfd, 1, 50) <= 0) continue;
+      int fd = accept(listenFd_, NULL, NULL);
+      if (fd < 0) continue;
+      int one = 1;
+      setsockopt(fd, SOL_SOCKET, SO_NOSIGPIPE, &one, sizeof(one));
+      clientThreads_.emplace_back([this, fd] { ClientLoop(fd); });
+    }
+  }
+
+  void ClientLoop(int fd) {
+    Send(fd, "INFO {\"server_id\":\"mock\",\"version\":\"2.10.0\",\"proto\":1,"
+             "\"max_payload\":1048576}\r\n");
+
+    std::string buf;
+    char chunk[4096];
+    while (!stop_) {
+      struct pollfd pfd = {.fd = fd, .events = POLLIN};
+      if (poll(&pfd, 1, 50) <= 0) continue;
+      ssize_t n = recv(fd, chunk, sizeof(chunk), 0);
+      if (n <= 0) break;
+      buf.append(chunk, n);
+      while (ProcessCommand(fd, buf)) {
+      }
+    }
+
+    std::lock_guard<std::mutex> lock(mutex_);
+    std::erase_if(subscriptions_, [fd](const Subscription& s) { return s.fd == fd; });
+    close(fd);
+  }
+
+  // Consumes one complete command from buf. Returns false if more data is needed.
+  bool ProcessCommand(int fd, std::string& buf) {
+    size_t eol = buf.find("\r\n");
+    if (eol == std::string::npos) return false;
+
+    std::istringstream line(buf.substr(0, eol));
+    std::string op;
+    line >> op;
+    std::vector<std::string> args;
+    for (std::string arg; line >> arg;) {
+      args.push_back(arg);
+    }
+
+    if (op == "PUB") {
+      size_t len = std::stoul(args.back());
+      if (buf.size() < eol + 2 + len + 2) return false;
+      std::string payload = buf.substr(eol + 2, len);
+      buf.erase(0, eol + 2 + len + 2);
+      Publish(args[0], payload);
+      return true;
+    }
+
+    buf.erase(0, eol + 2);
+    std::lock_guard<std::mutex> lock(mutex_);
+    if (op == "PING") {
+      SendLocked(fd, "PONG\r\n");
+    } else if (op == "SUB") {
+      subscriptions_.push_back({fd, args.front(), args.back()});
+    } else if (op == "UNSUB") {
+      std::erase_if(subscriptions_, [&](const Subscription& s) {
+        return s.fd == fd && s.sid == args.front();
+      });
+    }
+    return true;
+  }
+
+  void Publish(const std::string& subject, const std::string& payload) {
+    std::lock_guard<std::mutex> lock(mutex_);
+    ++published_;
+    if (dropEvery_ && published_ % dropEvery_ == 0) return;
+    int copies = (duplicateEvery_ && published_ % duplicateEvery_ == 0) ? 2 : 1;
+
+    for (const Subscription& s : subscriptions_) {
+      if (s.subject != subject) continue;
+      std::string msg = "MSG " + subject + " " + s.sid + " " + std::to_string(payload.size()) +
+                        "\r\n" + payload + "\r\n";
+      for (int i = 0; i < copies; ++i) {
+        SendLocked(s.fd, msg);
+      }
+    }
+  }
+
+  void Send(int fd, const std::string& data) {
+    std::lock_guard<std::mutex> lock(mutex_);
+    SendLocked(fd, data);
+  }
+
+  void SendLocked(int fd, const std::string& data) {
+    size_t off = 0;
+    while (off < data.size()) {
+      ssize_t n = send(fd, data.data() + off, data.size() - off, 0);
+      if (n <= 0) return;
+      off += n;
+    }
+  }
+
+  int dropEvery_;
+  int duplicateEvery_;
+  int listenFd_;
+  uint16_t port_;
+  std::atomic<bool> stop_ = false;
+  std::thread acceptThread_;
+  std::vector<std::thread> clientThreads_;
+  std::mutex mutex_;
+  std::vector<Subscription> subscriptions_;
+  int published_ = 0;
+};
+
+@interface SNTCommandPushTest : XCTestCase
+@property NSMutableDictionary* snapshot;
+@property SNTPushReachabilityBlock reachable;
+@end
+
+@implementation SNTCommandPushTest
+
+- (void)setUp {
+  [super setUp];
+  self.snapshot = [@{
+    kPushDiagnosticsEnabled : @YES,
+    kPushDiagnosticsServer : @"tls://test.push.northpole.security:443",
+    kPushDiagnosticsJWTParsed : @YES,
+    kPushDiagnosticsJWTSubject : @"UABC",
+    kPushDiagnosticsJWTExpiry : [NSDate dateWithTimeIntervalSince1970:kNow + 3600],
+    kPushDiagnosticsJWTSubscribeAllow : @[ @"santa.host.*.commands", @"santa.tag.>" ],
+    kPushDiagnosticsJWTSubscribeDeny : @[],
+    kPushDiagnosticsDeviceID : @"ABC123",
+    kPushDiagnosticsTags : @[ @"santa.tag.global" ],
+    kPushDiagnosticsConnected : @YES,
+  } mutableCopy];
+
+  self.reachable = ^BOOL(NSString* host, uint16_t port, NSString** error) {
+    return YES;
+  };
+}
+
+// Replace the subscribe permissions of the push JWT described by the snapshot.
+- (void)setSubscribeAllow:(NSArray<NSString*>*)allow deny:(NSArray<NSString*>*)deny {
+  self.snapshot[kPushDiagnosticsJWTSubscribeAllow] = allow;
+  self.snapshot[kPushDiagnosticsJWTSubscribeDeny] = deny;
+}
+
+- (SNTPushDiagnosis*)diagnose {
+  return [SNTCommandPush diagnoseWithSnapshot:self.snapshot
+                                          now:[NSDate dateWithTimeIntervalSince1970:kNow]
+                                 reachability:self.reachable];
+}
+
+- (void)testHealthy {
+  __block NSString* probedHost;
+  __block uint16_t probedPort = 0;
+  self.reachable = ^BOOL(NSString* host, uint16_t port, NSString** error) {
+    probedHost = host;
+    probedPort = port;
+    return YES;
+  };
+
+  SNTPushDiagnosis* diagnosis = [self diagnose];
+  XCTAssertEqual(diagnosis.failedStep, SNTPushDiagnoseStepNone);
+  XCTAssertEqual(diagnosis.passedSteps.count, 5);
+  XCTAssertNil(diagnosis.remediation);
+  XCTAssertEqualObjects(probedHost, @"test.push.northpole.security");
+  XCTAssertEqual(probedPort, 443);
+}
+
+- (void)testPushClientNotRunning {
+  self.snapshot = [@{kPushDiagnosticsEnabled : @NO} mutableCopy];
+
+  SNTPushDiagnosis* diagnosis = [self diagnose];
+  XCTAssertEqual(diagnosis.failedStep, SNTPushDiagnoseStepPreflight);
+  XCTAssertEqual(diagnosis.passedSteps.count, 0);
+  XCTAssertNotNil(diagnosis.remediation);
+}
+
+- (void)testPreflightMissingServer {
+  [self.snapshot removeObjectForKey:kPushDiagnosticsServer];
+
+  SNTPushDiagnosis* diagnosis = [self diagnose];
+  XCTAssertEqual(diagnosis.failedStep, SNTPushDiagnoseStepPreflight);
+  XCTAssertTrue([diagnosis.detail containsString:@"server"]);
+}
+
+- (void)testPreflightMissingJWT {
+  [self.snapshot removeObjectForKey:kPushDiagnosticsJWTParsed];
+
+  SNTPushDiagnosis* diagnosis = [self diagnose];
+  XCTAssertEqual(diagnosis.failedStep, SNTPushDiagnoseStepPreflight);
+  XCTAssertTrue([diagnosis.detail containsString:@"JWT"]);
+}
+
+- (void)testMalformedJWT {
+  self.snapshot[kPushDiagnosticsJWTParsed] = @NO;
+
+  SNTPushDiagnosis* diagnosis = [self diagnose];
+  XCTAssertEqual(diagnosis.failedStep, SNTPushDiagnoseStepJWT);
+  XCTAssertEqual(diagnosis.passedSteps.count, 1);
+}
+
+- (void)testExpiredJWT {
+  self.snapshot[kPushDiagnosticsJWTExpiry] = [NSDate dateWithTimeIntervalSince1970:kNow - 60];
+
+  SNTPushDiagnosis* diagnosis = [self diagnose];
+  XCTAssertEqual(diagnosis.failedStep, SNTPushDiagnoseStepJWT);
+  XCTAssertTrue([diagnosis.detail containsString:@"expired"]);
+}
+
+- (void)testUnreachable {
+  self.reachable = ^BOOL(NSString* host, uint16_t port, NSString** error) {
+    *error = @"connect failed: Operation timed out";
+    return NO;
+  };
+
+  SNTPushDiagnosis* diagnosis = [self diagnose];
+  XCTAssertEqual(diagnosis.failedStep, SNTPushDiagnoseStepReachability);
+  XCTAssertEqual(diagnosis.passedSteps.count, 2);
+  XCTAssertTrue([diagnosis.detail containsString:@"timed out"]);
+  XCTAssertTrue([diagnosis.remediation containsString:@"443"]);
+}
+
+- (void)testAuthenticationFailed {
+  self.snapshot[kPushDiagnosticsConnected] = @NO;
+  self.snapshot[kPushDiagnosticsLastError] = @"[AUTH_ERROR] Authorization Violation (code: 23)";
+
+  SNTPushDiagnosis* diagnosis = [self diagnose];
+  XCTAssertEqual(diagnosis.failedStep, SNTPushDiagnoseStepAuthentication);
+  XCTAssertEqual(diagnosis.passedSteps.count, 3);
+  XCTAssertTrue([diagnosis.detail containsString:@"rejected the credentials"]);
+}
+
+- (void)testConnectionFailedWithOtherError {
+  self.snapshot[kPushDiagnosticsConnected] = @NO;
+  self.snapshot[kPushDiagnosticsLastError] = @"[TLS_ERROR] SSL Error (code: 25)";
+
+  SNTPushDiagnosis* diagnosis = [self diagnose];
+  XCTAssertEqual(diagnosis.failedStep, SNTPushDiagnoseStepAuthentication);
+  XCTAssertTrue([diagnosis.detail containsString:@"TLS_ERROR"]);
+}
+
+- (void)testNotYetConnected {
+  self.snapshot[kPushDiagnosticsConnected] = @NO;
+
+  SNTPushDiagnosis* diagnosis = [self diagnose];
+  XCTAssertEqual(diagnosis.failedStep, SNTPushDiagnoseStepAuthentication);
+  XCTAssertTrue([diagnosis.detail containsString:@"has not connected"]);
+}
+
+- (void)testSubscriptionRejectedByServer {
+  self.snapshot[kPushDiagnosticsDeniedSubject] = @"santa.tag.global";
+
+  SNTPushDiagnosis* diagnosis = [self diagnose];
+  XCTAssertEqual(diagnosis.failedStep, SNTPushDiagnoseStepSubscriptions);
+  XCTAssertEqual(diagnosis.passedSteps.count, 4);
+  XCTAssertTrue([diagnosis.detail containsString:@"santa.tag.global"]);
+}
+
+- (void)testSubscriptionNotPermittedByJWT {
+  self.snapshot[kPushDiagnosticsTags] = @[ @"santa.tag.global", @"other.topic" ];
+
+  SNTPushDiagnosis* diagnosis = [self diagnose];
+  XCTAssertEqual(diagnosis.failedStep, SNTPushDiagnoseStepSubscriptions);
+  XCTAssertTrue([diagnosis.detail containsString:@"other.topic"]);
+}
+
+#pragma mark validate
+
+- (void)testValidateAllSubscriptionsPermitted {
+  SNTPushSubscriptionValidator* validator = [[SNTPushSubscriptionValidator alloc] init];
+
+  NSError* error;
+  NSArray<NSString*>* newlyInvalid = [validator checkSnapshot:self.snapshot error:&error];
+  XCTAssertNotNil(newlyInvalid, @"%@", error);
+  XCTAssertEqual(newlyInvalid.count, 0);
+  XCTAssertEqual(validator.invalidSubjects.count, 0);
+}
+
+- (void)testValidateAlertsWhenPermissionsNarrow {
+  SNTPushSubscriptionValidator* validator = [[SNTPushSubscriptionValidator alloc] init];
+  XCTAssertEqual([validator checkSnapshot:self.snapshot error:nil].count, 0);
+
+  // A credential rotation drops the tag permissions.
+  [self setSubscribeAllow:@[ @"santa.host.*.commands" ] deny:@[]];
+  XCTAssertEqualObjects([validator checkSnapshot:self.snapshot error:nil],
+                        @[ @"santa.tag.global" ]);
+  XCTAssertEqualObjects(validator.invalidSubjects, @[ @"santa.tag.global" ]);
+
+  // The alert is only raised once while the subscription stays invalid.
+  XCTAssertEqual([validator checkSnapshot:self.snapshot error:nil].count, 0);
+  XCTAssertEqualObjects(validator.invalidSubjects, @[ @"santa.tag.global" ]);
+
+  // A later rotation denies the host commands subject too.
+  [self setSubscribeAllow:@[ @"santa.host.*.commands" ] deny:@[ @"santa.host.>" ]];
+  XCTAssertEqualObjects([validator checkSnapshot:self.snapshot error:nil],
+                        @[ @"santa.host.ABC123.commands" ]);
+  NSArray* expected = @[ @"santa.host.ABC123.commands", @"santa.tag.global" ];
+  XCTAssertEqualObjects(validator.invalidSubjects, expected);
+}
+
+- (void)testValidateAlertsAgainAfterPermissionsRestored {
+  SNTPushSubscriptionValidator* validator = [[SNTPushSubscriptionValidator alloc] init];
+  NSArray* permitted = self.snapshot[kPushDiagnosticsJWTSubscribeAllow];
+  NSArray* narrowed = @[ @"santa.host.*.commands" ];
+
+  [self setSubscribeAllow:narrowed deny:@[]];
+  XCTAssertEqualObjects([validator checkSnapshot:self.snapshot error:nil],
+                        @[ @"santa.tag.global" ]);
+
+  [self setSubscribeAllow:permitted deny:@[]];
+  XCTAssertEqual([validator checkSnapshot:self.snapshot error:nil].count, 0);
+  XCTAssertEqual(validator.invalidSubjects.count, 0);
+
+  [self setSubscribeAllow:narrowed deny:@[]];
+  XCTAssertEqualObjects([validator checkSnapshot:self.snapshot error:nil],
+                        @[ @"santa.tag.global" ]);
+}
+
+- (void)testValidateNewTagNotPermitted {
+  SNTPushSubscriptionValidator* validator = [[SNTPushSubscriptionValidator alloc] init];
+  XCTAssertEqual([validator checkSnapshot:self.snapshot error:nil].count, 0);
+
+  self.snapshot[kPushDiagnosticsTags] = @[ @"santa.tag.global", @"other.topic" ];
+  XCTAssertEqualObjects([validator checkSnapshot:self.snapshot error:nil], @[ @"other.topic" ]);
+}
+
+- (void)testValidateUnparseableJWTKeepsPreviousResults {
+  SNTPushSubscriptionValidator* validator = [[SNTPushSubscriptionValidator alloc] init];
+  self.snapshot[kPushDiagnosticsTags] = @[ @"other.topic" ];
+  XCTAssertEqual([validator checkSnapshot:self.snapshot error:nil].count, 1);
+
+  self.snapshot[kPushDiagnosticsJWTParsed] = @NO;
+  NSError* error;
+  XCTAssertNil([validator checkSnapshot:self.snapshot error:&error]);
+  XCTAssertNotNil(error);
+  XCTAssertEqualObjects(validator.invalidSubjects, @[ @"other.topic" ]);
+
+  XCTAssertNil([validator checkSnapshot:@{kPushDiagnosticsEnabled : @NO} error:&error]);
+  XCTAssertTrue([error.localizedDescription containsString:@"not running"]);
+}
+
+#pragma mark target-test
+
+- (void)testTargetTestMemberReceives {
+  self.snapshot[kPushDiagnosticsTags] = @[ @"santa.tag.global", @"santa.tag.engineering" ];
+
+  SNTPushTargetTest* test = [SNTCommandPush targetTestForTag:@"engineering"
+                                                    snapshot:self.snapshot];
+  XCTAssertEqual(test.result, SNTPushTargetResultReceives);
+  XCTAssertEqualObjects(test.subject, @"santa.tag.engineering");
+
+  // The tag may also be given as the full subject.
+  test = [SNTCommandPush targetTestForTag:@"santa.tag.global" snapshot:self.snapshot];
+  XCTAssertEqual(test.result, SNTPushTargetResultReceives);
+  XCTAssertEqualObjects(test.subject, @"santa.tag.global");
+}
+
+- (void)testTargetTestNotMember {
+  SNTPushTargetTest* test = [SNTCommandPush targetTestForTag:@"finance" snapshot:self.snapshot];
+  XCTAssertEqual(test.result, SNTPushTargetResultNotMember);
+  XCTAssertTrue([test.detail containsString:@"santa.tag.global"]);
+
+  self.snapshot[kPushDiagnosticsTags] = @[];
+  test = [SNTCommandPush targetTestForTag:@"global" snapshot:self.snapshot];
+  XCTAssertEqual(test.result, SNTPushTargetResultNotMember);
+  XCTAssertTrue([test.detail containsString:@"no tags"]);
+}
+
+- (void)testTargetTestInvalidTag {
+  // Tags the push client would refuse to subscribe to are reported even if the host has them.
+  self.snapshot[kPushDiagnosticsTags] = @[ @"santa.tag.santa-clients" ];
+
+  SNTPushTargetTest* test = [SNTCommandPush targetTestForTag:@"santa-clients"
+                                                    snapshot:self.snapshot];
+  XCTAssertEqual(test.result, SNTPushTargetResultInvalidTag);
+  XCTAssertEqual([SNTCommandPush targetTestForTag:@"a.b" snapshot:self.snapshot].result,
+                 SNTPushTargetResultInvalidTag);
+  XCTAssertEqual([SNTCommandPush targetTestForTag:@"santa.tag." snapshot:self.snapshot].result,
+                 SNTPushTargetResultInvalidTag);
+}
+
+- (void)testTargetTestNotPermittedByJWT {
+  self.snapshot[kPushDiagnosticsTags] = @[ @"santa.tag.global", @"santa.tag.finance" ];
+
+  // Only some tags are allowed.
+  [self setSubscribeAllow:@[ @"santa.host.*.commands", @"santa.tag.global" ] deny:@[]];
+  SNTPushTargetTest* test = [SNTCommandPush targetTestForTag:@"finance" snapshot:self.snapshot];
+  XCTAssertEqual(test.result, SNTPushTargetResultNotPermitted);
+  XCTAssertTrue([test.detail containsString:@"not in allow list"]);
+  XCTAssertEqual([SNTCommandPush targetTestForTag:@"global" snapshot:self.snapshot].result,
+                 SNTPushTargetResultReceives);
+
+  // All tags are allowed except one that is denied.
+  [self setSubscribeAllow:@[ @"santa.tag.>" ] deny:@[ @"santa.tag.finance" ]];
+  test = [SNTCommandPush targetTestForTag:@"finance" snapshot:self.snapshot];
+  XCTAssertEqual(test.result, SNTPushTargetResultNotPermitted);
+  XCTAssertTrue([test.detail containsString:@"deny list"]);
+  XCTAssertEqual([SNTCommandPush targetTestForTag:@"global" snapshot:self.snapshot].result,
+                 SNTPushTargetResultReceives);
+
+  // No tags are allowed.
+  [self setSubscribeAllow:@[ @"santa.host.*.commands" ] deny:@[]];
+  XCTAssertEqual([SNTCommandPush targetTestForTag:@"global" snapshot:self.snapshot].result,
+                 SNTPushTargetResultNotPermitted);
+
+  self.snapshot[kPushDiagnosticsJWTParsed] = @NO;
+  XCTAssertEqual([SNTCommandPush targetTestForTag:@"global" snapshot:self.snapshot].result,
+                 SNTPushTargetResultNotPermitted);
+}
+
+- (void)testTargetTestMembershipCheckedBeforePermissions {
+  // A tag that is permitted but not assigned to this host is reported as a membership problem.
+  SNTPushTargetTest* test = [SNTCommandPush targetTestForTag:@"finance" snapshot:self.snapshot];
+  XCTAssertEqual(test.result, SNTPushTargetResultNotMember);
+}
+
+- (void)testTargetTestRejectedByServer {
+  self.snapshot[kPushDiagnosticsDeniedSubject] = @"santa.tag.global";
+
+  SNTPushTargetTest* test = [SNTCommandPush targetTestForTag:@"global" snapshot:self.snapshot];
+  XCTAssertEqual(test.result, SNTPushTargetResultRejectedByServer);
+}
+
+- (void)testTargetTestNotConnected {
+  self.snapshot[kPushDiagnosticsConnected] = @NO;
+
+  SNTPushTargetTest* test = [SNTCommandPush targetTestForTag:@"global" snapshot:self.snapshot];
+  XCTAssertEqual(test.result, SNTPushTargetResultNotConnected);
+}
+
+- (void)testTargetTestPushNotRunning {
+  SNTPushTargetTest* test =
+      [SNTCommandPush targetTestForTag:@"global" snapshot:@{kPushDiagnosticsEnabled : @NO}];
+  XCTAssertEqual(test.result, SNTPushTargetResultPushNotRunning);
+}
+
+#pragma mark stats
+
+- (void)testStatsText {
+  self.snapshot[kPushDiagnosticsStats] = @{
+    kPushStatsBytesIn : @120,
+    kPushStatsBytesOut : @64,
+    kPushStatsSubscribes : @2,
+    kPushStatsReconnects : @1,
+    kPushStatsMessagesBySubject : @{@"santa.tag.global" : @3, @"santa.host.ABC123.commands" : @1},
+    kPushStatsLastRTTMs : @12.5,
+    kPushStatsLastRTTTime : @(kNow),
+    kPushStatsLatencyCount : @4,
+    kPushStatsLatencyP50Ms : @40.5,
+    kPushStatsLatencyP95Ms : @80,
+    kPushStatsLatencyMaxMs : @95.5,
+  };
+
+  NSString* text = [SNTCommandPush statsTextForSnapshot:self.snapshot];
+  XCTAssertTrue([text containsString:@"Connected            | Yes\n"], @"%@", text);
+  XCTAssertTrue([text containsString:@"Bytes In             | 120\n"], @"%@", text);
+  XCTAssertTrue([text containsString:@"Bytes Out            | 64\n"], @"%@", text);
+  XCTAssertTrue([text containsString:@"Subscribes           | 2\n"], @"%@", text);
+  XCTAssertTrue([text containsString:@"Reconnects           | 1\n"], @"%@", text);
+  XCTAssertTrue([text containsString:@"Last RTT             | 12.5 ms"], @"%@", text);
+  XCTAssertTrue([text containsString:@"Delivery Latency     | p50 40.5 ms, p95 80.0 ms, "
+                                     @"max 95.5 ms (4 messages)\n"],
+                @"%@", text);
+  XCTAssertTrue([text containsString:@"Messages Received    | 4\n"], @"%@", text);
+  // Subjects are sorted.
+  NSRange host = [text rangeOfString:@"santa.host.ABC123.commands"];
+  NSRange tag = [text rangeOfString:@"santa.tag.global"];
+  XCTAssertNotEqual(host.location, NSNotFound);
+  XCTAssertLessThan(host.location, tag.location);
+}
+
+- (void)testStatsTextBeforeAnyTraffic {
+  self.snapshot[kPushDiagnosticsConnected] = @NO;
+  NSString* text = [SNTCommandPush statsTextForSnapshot:self.snapshot];
+  XCTAssertTrue([text containsString:@"Connected            | No\n"], @"%@", text);
+  XCTAssertTrue([text containsString:@"Bytes In             | 0\n"], @"%@", text);
+  XCTAssertTrue([text containsString:@"Last RTT             | Not measured\n"], @"%@", text);
+  XCTAssertTrue([text containsString:@"Delivery Latency     | Not measured\n"], @"%@", text);
+  XCTAssertTrue([text containsString:@"Messages Received    | 0\n"], @"%@", text);
+}
+
+#pragma mark loadtest
+
+- (SNTPushLoadTestReport*)loadTestAgainst:(const MockNATSServer&)server
+                                     rate:(NSUInteger)rate
+                                 duration:(NSTimeInterval)duration {
+  NSError* error;
+  SNTPushLoadTestReport* report = [SNTCommandPush loadTestWithServer:server.URL()
+                                                      publisherCreds:nil
+                                                     subscriberCreds:nil
+                                                             subject:@"loadtest.subject"
+                                                                rate:rate
+                                                            duration:duration
+                                                        drainTimeout:1
+                                                               error:&error];
+  XCTAssertNotNil(report, @"%@", error);
+  return report;
+}
+
+- (void)testLoadTestCountsDeliveries {
+  MockNATSServer server;
+
+  SNTPushLoadTestReport* report = [self loadTestAgainst:server rate:200 duration:0.25];
+  XCTAssertEqual(server.Published(), 50);
+  XCTAssertEqual(report.sent, 50);
+  XCTAssertEqual(report.received, 50);
+  XCTAssertEqual(report.lost, 0);
+  XCTAssertEqual(report.duplicates, 0);
+  XCTAssertGreaterThan(report.maxLatencyMs, 0);
+  XCTAssertLessThanOrEqual(report.p50LatencyMs, report.p90LatencyMs);
+  XCTAssertLessThanOrEqual(report.p90LatencyMs, report.p99LatencyMs);
+  XCTAssertLessThanOrEqual(report.p99LatencyMs, report.maxLatencyMs);
+  // Publishing is paced to the requested rate.
+  XCTAssertGreaterThanOrEqual(report.elapsed, 0.24);
+}
+
+- (void)testLoadTestCountsLoss {
+  MockNATSServer server(/*dropEvery=*/5);
+
+  SNTPushLoadTestReport* report = [self loadTestAgainst:server rate:200 duration:0.25];
+  XCTAssertEqual(report.sent, 50);
+  XCTAssertEqual(report.received, 40);
+  XCTAssertEqual(report.lost, 10);
+  XCTAssertEqual(report.duplicates, 0);
+}
+
+- (void)testLoadTestCountsDuplicates {
+  MockNATSServer server(/*dropEvery=*/0, /*duplicateEvery=*/10);
+
+  SNTPushLoadTestReport* report = [self loadTestAgainst:server rate:200 duration:0.25];
+  XCTAssertEqual(report.sent, 50);
+  XCTAssertEqual(report.received, 50);
+  XCTAssertEqual(report.lost, 0);
+  XCTAssertEqual(report.duplicates, 5);
+}
+
+- (void)testLoadTestConnectionFailure {
+  NSError* error;
+  SNTPushLoadTestReport* report = [SNTCommandPush loadTestWithServer:@"nats://127.0.0.1:1"
+                                                      publisherCreds:nil
+                                                     subscriberCreds:nil
+                                                             subject:@"loadtest.subject"
+                                                                rate:10
+                                                            duration:1
+                                                        drainTimeout:1
+                                                               error:&error];
+  XCTAssertNil(report);
+  XCTAssertNotNil(error);
+}
+
+@end
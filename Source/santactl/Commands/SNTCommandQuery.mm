@@ -0,0 +1,137 @@
+/// Copyright 2026 North Pole Security, Inc.
+///
+/// Licensed under the Apache License, Version 2.0 (the "License");
+/// you may not use this file except in compliance with the License.
+/// You may obtain a copy of the License at
+///
+///     http://www.apache.org/licenses/LICENSE-2.0
+///
+/// Unless required by applicable law or agreed to in writing, software
+/// distributed under the License is distributed on an "AS IS" BASIS,
+/// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+/// See the License for the specific language governing permissions and
+/// limitations under the License.
+
+
+#import <Foundation/Foundation.h>
+
+#import "Source/common/MOLXPCConnection.h"
+#import "Source/common/SNTLogging.h"
+#import "Source/common/SNTXPCControlInterface.h"
+#import "Source/santactl/SNTCommand.h"
+#import "Source/santactl/SNTCommandController.h"
+
+@interface SNTCommandQuery : SNTCommand <SNTCommandProtocol>
+@end
+
+@implementation SNTCommandQuery
+
+REGISTER_COMMAND_NAME(@"query")
+
++ (BOOL)requiresRoot {
+  return YES;
+}
+
++ (BOOL)requiresDaemonConn {
+  return YES;
+}
+
++ (NSString*)shortHelpText {
+  return @"Run a SQL query against the decision database.";
+}
+
++ (NSString*)longHelpText {
+  return @"Runs a read-only SQL query against the local decision database and prints the\n"
+         @"results. The database is only populated when EnableDecisionDatabase is set.\n"
+         @"\n"
+         @"Usage: santactl query [--json] {sql}\n"
+         @"    --json: print the results as a JSON array of objects\n"
+         @"\n"
+         @"Decisions are stored in the decisions table, indexed by file_sha256, decision\n"
+         @"and occurrence_time:\n"
+         @"    occurrence_time: the time of the decision, in seconds since the epoch\n"
+         @"    file_sha256, file_path, team_id, signing_id, cdhash, pid, ppid\n"
+         @"    decision: ALLOW, BLOCK or UNKNOWN\n"
+         @"    event_state: the detailed decision, as logged by santad\n"
+         @"\n"
+         @"Only a single statement that reads from the database is allowed and at most\n"
+         @"10000 rows are printed.\n"
+         @"\n"
+         @"Examples: santactl query \"SELECT file_path, COUNT(*) FROM decisions "
+         @"WHERE decision = 'BLOCK' GROUP BY file_path\"\n"
+         @"          santactl query --json \"SELECT * FROM decisions "
+         @"WHERE occurrence_time > strftime('%s', 'now', '-1 hour')\"";
+}
+
++ (NSString*)stringForValue:(id)value {
+  if ([value isKindOfClass:[NSNull class]]) return @"";
+  if ([value isKindOfClass:[NSData class]]) return [value base64EncodedStringWithOptions:0];
+  return [value description];
+}
+
+- (void)runWithArguments:(NSArray*)arguments {
+  BOOL json = NO;
+  NSMutableArray<NSString*>* sqlParts = [NSMutableArray array];
+  for (NSString* arg in arguments) {
+    if ([arg caseInsensitiveCompare:@"--json"] == NSOrderedSame) {
+      json = YES;
+    } else {
+      [sqlParts addObject:arg];
+    }
+  }
+
+  NSString* sql = [sqlParts componentsJoinedByString:@" "];
+  if (!sql.length) {
+    [self printErrorUsageAndExit:@"No query given"];
+  }
+
+  [[self.daemonConn remoteObjectProxy]
+      queryDecisionDatabase:sql
+                      reply:^(NSArray<NSString*>* columns, NSArray<NSArray*>* rows,
+                              NSError* error) {
+                        if (error) {
+                          TEE_LOGE(@"%@", error.localizedDescription);
+                          exit(EXIT_FAILURE);
+                        }
+
+                        if (json) {
+                          [[self class] printJSONForColumns:columns rows:rows];
+                        } else {
+                          [[self class] printColumns:columns rows:rows];
+                        }
+                        exit(EXIT_SUCCESS);
+                      }];
+}
+
++ (void)printColumns:(NSArray<NSString*>*)columns rows:(NSArray<NSArray*>*)rows {
+  printf("%s\n", [columns componentsJoinedByString:@"|"].UTF8String);
+  for (NSArray* row in rows) {
+    NSMutableArray<NSString*>* values = [NSMutableArray arrayWithCapacity:row.count];
+    for (id value in row) {
+      [values addObject:[self stringForValue:value]];
+    }
+    printf("%s\n", [values componentsJoinedByString:@"|"].UTF8String);
+  }
+}
+
++ (void)printJSONForColumns:(NSArray<NSString*>*)columns rows:(NSArray<NSArray*>*)rows {
+  NSMutableArray<NSDictionary*>* objects = [NSMutableArray arrayWithCapacity:rows.count];
+  for (NSArray* row in rows) {
+    NSMutableDictionary* object = [NSMutableDictionary dictionaryWithCapacity:columns.count];
+    for (NSUInteger i = 0; i < columns.count && i < row.count; ++i) {
+      id value = row[i];
+      if ([value isKindOfClass:[NSData class]]) {
+        value = [value base64EncodedStringWithOptions:0];
+      }
+      object[columns[i]] = value;
+    }
+    [objects addObject:object];
+  }
+
+  NSData* data = [NSJSONSerialization dataWithJSONObject:objects
+                                                 options:NSJSONWritingPrettyPrinted
+                                                   error:NULL];
+  printf("%s\n", [[NSString alloc] initWithData:data encoding:NSUTF8StringEncoding].UTF8String);
+}
+
+@end
@@ -0,0 +1,65 @@
+/// Copyright 2026 North Pole Security, Inc.
+///
+/// Licensed under the Apache License, Version 2.0 (the "License");
+/// you may not use this file except in compliance with the License.
+/// You may obtain a copy of the License at
+///
+///     http://www.apache.org/licenses/LICENSE-2.0
+///
+/// Unless required by applicable law or agreed to in writing, software
+/// distributed under the License is distributed on an "AS IS" BASIS,
+/// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+/// See the License for the specific language governing permissions and
+/// limitations under the License.
+
+#import <Foundation/Foundation.h>
+
+#import "Source/santactl/SNTCommand.h"
+#import "Source/santactl/SNTCommandController.h"
+
+///
+///  The host state `santactl readiness` checks. Values that could not be retrieved, e.g.
+///  because santad did not respond, are left nil.
+///
+@interface SNTReadinessState : NSObject
+@property BOOL daemonRunning;
+@property NSNumber* fullDiskAccessGranted;
+@property BOOL syncConfigured;
+@property NSDate* lastFullSyncSuccess;
+///  The number of execution rules, including static rules.
+@property NSNumber* ruleCount;
+@property BOOL pushConfigured;
+@property NSNumber* pushConnected;
+///  The keys santad did not apply, as returned by -[SNTConfigurator configIssuesForProfile:...].
+@property NSArray<NSDictionary<NSString*, NSString*>*>* configIssues;
+@end
+
+typedef NS_ENUM(NSInteger, SNTReadinessResult) {
+  SNTReadinessResultPass = 0,
+  SNTReadinessResultFail,
+  // The check doesn't apply to this host, e.g. push on a host without push notifications.
+  SNTReadinessResultSkipped,
+};
+
+///
+///  The outcome of a single readiness check. detail explains the result.
+///
+@interface SNTReadinessCheck : NSObject
+@property(readonly) NSString* name;
+@property(readonly) SNTReadinessResult result;
+@property(readonly) NSString* detail;
+@end
+
+@interface SNTCommandReadiness : SNTCommand <SNTCommandProtocol>
+
+///
+///  Evaluate each readiness check against state, in the order they are reported.
+///
++ (NSArray<SNTReadinessCheck*>*)checksForState:(SNTReadinessState*)state now:(NSDate*)now;
+
+///
+///  Returns YES if no check failed. Skipped checks don't affect the result.
+///
++ (BOOL)checksPassed:(NSArray<SNTReadinessCheck*>*)checks;
+
+@end
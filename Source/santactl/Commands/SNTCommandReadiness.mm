@@ -0,0 +1,319 @@
+/// Copyright 2026 North Pole Security, Inc.
+///
+/// Licensed under the Apache License, Version 2.0 (the "License");
+/// you may not use this file except in compliance with the License.
+/// You may obtain a copy of the License at
+///
+///     http://www.apache.org/licenses/LICENSE-2.0
+///
+/// Unless required by applicable law or agreed to in writing, software
+/// distributed under the License is distributed on an "AS IS" BASIS,
+/// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+/// See the License for the specific language governing permissions and
+/// limitations under the License.
+
+#import "Source/santactl/Commands/SNTCommandReadiness.h"
+
+#import <Foundation/Foundation.h>
+
+#import "Source/common/MOLXPCConnection.h"
+#import "Source/common/SNTCommonEnums.h"
+#import "Source/common/SNTConfigurator.h"
+#import "Source/common/SNTXPCControlInterface.h"
+
+// A host that hasn't completed a full sync in this long is not considered ready. This is well
+// beyond the default full sync interval, with or without push notifications.
+static const NSTimeInterval kReadinessMaxSyncAge = 24 * 60 * 60;
+
+@implementation SNTReadinessState
+@end
+
+@interface SNTReadinessCheck ()
+@property(readwrite) NSString* name;
+@property(readwrite) SNTReadinessResult result;
+@property(readwrite) NSString* detail;
+@end
+
+@implementation SNTReadinessCheck
+
++ (instancetype)checkWithName:(NSString*)name
+                       result:(SNTReadinessResult)result
+                       detail:(NSString*)detail {
+  SNTReadinessCheck* check = [[SNTReadinessCheck alloc] init];
+  check.name = name;
+  check.result = result;
+  check.detail = detail;
+  return check;
+}
+
+@end
+
+static NSString* ResultString(SNTReadinessResult result) {
+  switch (result) {
+    case SNTReadinessResultPass: return @"pass";
+    case SNTReadinessResultFail: return @"fail";
+    case SNTReadinessResultSkipped: return @"skipped";
+  }
+}
+
+static NSString* FormatAge(NSTimeInterval seconds) {
+  NSDateComponentsFormatter* formatter = [[NSDateComponentsFormatter alloc] init];
+  formatter.unitsStyle = NSDateComponentsFormatterUnitsStyleFull;
+  formatter.allowedUnits =
+      NSCalendarUnitDay | NSCalendarUnitHour | NSCalendarUnitMinute | NSCalendarUnitSecond;
+  formatter.maximumUnitCount = 2;
+  return [formatter stringFromTimeInterval:MAX(seconds, 0)];
+}
+
+@implementation SNTCommandReadiness
+
+REGISTER_COMMAND_NAME(@"readiness")
+
++ (BOOL)requiresRoot {
+  return NO;
+}
+
++ (BOOL)requiresDaemonConn {
+  // Whether santad is running is one of the checks, so don't exit if it isn't.
+  return NO;
+}
+
++ (NSString*)shortHelpText {
+  return @"Summarize whether Santa is working on this host.";
+}
+
++ (NSString*)longHelpText {
+  return (@"Usage: santactl readiness [--json]\n"
+          @"  Runs a set of checks and reports whether each passed:\n"
+          @"    daemon_running: santad is running and responding.\n"
+          @"    full_disk_access: santad has been granted Full Disk Access.\n"
+          @"    last_sync: a full sync succeeded in the last 24 hours, if sync is configured.\n"
+          @"    rule_count: at least one execution rule is loaded.\n"
+          @"    push_connected: the push client is connected, if push is configured.\n"
+          @"    config_applied: santad applied every key in the configuration profile.\n"
+          @"  Exits with a non-zero status if any check fails.\n"
+          @"\n"
+          @"  Options:\n"
+          @"    --json: Print the checks as JSON.\n"
+          @"\n");
+}
+
++ (NSArray<SNTReadinessCheck*>*)checksForState:(SNTReadinessState*)state now:(NSDate*)now {
+  NSMutableArray<SNTReadinessCheck*>* checks = [NSMutableArray array];
+
+  [checks addObject:[SNTReadinessCheck
+                        checkWithName:@"daemon_running"
+                               result:state.daemonRunning ? SNTReadinessResultPass
+                                                          : SNTReadinessResultFail
+                               detail:state.daemonRunning ? @"santad is responding"
+                                                          : @"santad did not respond"]];
+
+  if (!state.fullDiskAccessGranted) {
+    [checks addObject:[SNTReadinessCheck checkWithName:@"full_disk_access"
+                                                result:SNTReadinessResultFail
+                                                detail:@"Unable to determine Full Disk Access"]];
+  } else if (state.fullDiskAccessGranted.boolValue) {
+    [checks addObject:[SNTReadinessCheck checkWithName:@"full_disk_access"
+                                                result:SNTReadinessResultPass
+                                                detail:@"Full Disk Access is granted"]];
+  } else {
+    [checks addObject:[SNTReadinessCheck checkWithName:@"full_disk_access"
+                                                result:SNTReadinessResultFail
+                                                detail:@"Full Disk Access is not granted"]];
+  }
+
+  if (!state.syncConfigured) {
+    [checks addObject:[SNTReadinessCheck checkWithName:@"last_sync"
+                                                result:SNTReadinessResultSkipped
+                                                detail:@"Sync is not configured"]];
+  } else if (!state.lastFullSyncSuccess) {
+    [checks addObject:[SNTReadinessCheck checkWithName:@"last_sync"
+                                                result:SNTReadinessResultFail
+                                                detail:@"No full sync has succeeded"]];
+  } else {
+    NSTimeInterval age = [now timeIntervalSinceDate:state.lastFullSyncSuccess];
+    NSString* detail = [NSString
+        stringWithFormat:@"The last full sync succeeded %@ ago", FormatAge(age)];
+    [checks addObject:[SNTReadinessCheck checkWithName:@"last_sync"
+                                                result:age <= kReadinessMaxSyncAge
+                                                           ? SNTReadinessResultPass
+                                                           : SNTReadinessResultFail
+                                                detail:detail]];
+  }
+
+  if (!state.ruleCount) {
+    [checks addObject:[SNTReadinessCheck checkWithName:@"rule_count"
+                                                result:SNTReadinessResultFail
+                                                detail:@"Unable to retrieve the rule count"]];
+  } else {
+    long long count = state.ruleCount.longLongValue;
+    [checks addObject:[SNTReadinessCheck
+                          checkWithName:@"rule_count"
+                                 result:count > 0 ? SNTReadinessResultPass : SNTReadinessResultFail
+                                 detail:[NSString stringWithFormat:@"%lld rules loaded", count]]];
+  }
+
+  if (!state.pushConfigured) {
+    [checks addObject:[SNTReadinessCheck checkWithName:@"push_connected"
+                                                result:SNTReadinessResultSkipped
+                                                detail:@"Push notifications are not configured"]];
+  } else if (!state.pushConnected) {
+    [checks addObject:[SNTReadinessCheck checkWithName:@"push_connected"
+                                                result:SNTReadinessResultFail
+                                                detail:@"Unable to determine the push status"]];
+  } else if (state.pushConnected.boolValue) {
+    [checks addObject:[SNTReadinessCheck checkWithName:@"push_connected"
+                                                result:SNTReadinessResultPass
+                                                detail:@"The push client is connected"]];
+  } else {
+    [checks addObject:[SNTReadinessCheck checkWithName:@"push_connected"
+                                                result:SNTReadinessResultFail
+                                                detail:@"The push client is disconnected"]];
+  }
+
+  if (!state.configIssues) {
+    [checks addObject:[SNTReadinessCheck
+                          checkWithName:@"config_applied"
+                                 result:SNTReadinessResultFail
+                                 detail:@"Unable to retrieve the configuration from santad"]];
+  } else if (!state.configIssues.count) {
+    [checks addObject:[SNTReadinessCheck checkWithName:@"config_applied"
+                                                result:SNTReadinessResultPass
+                                                detail:@"santad applied every configured key"]];
+  } else {
+    NSMutableArray<NSString*>* keys = [NSMutableArray array];
+    for (NSDictionary<NSString*, NSString*>* issue in state.configIssues) {
+      if (issue[kConfigIssueKey]) [keys addObject:issue[kConfigIssueKey]];
+    }
+    NSString* detail =
+        [NSString stringWithFormat:@"%lu keys were not applied: %@", state.configIssues.count,
+                                   [keys componentsJoinedByString:@", "]];
+    [checks addObject:[SNTReadinessCheck checkWithName:@"config_applied"
+                                                result:SNTReadinessResultFail
+                                                detail:detail]];
+  }
+
+  return checks;
+}
+
++ (BOOL)checksPassed:(NSArray<SNTReadinessCheck*>*)checks {
+  for (SNTReadinessCheck* check in checks) {
+    if (check.result == SNTReadinessResultFail) return NO;
+  }
+  return YES;
+}
+
+- (void)runWithArguments:(NSArray*)arguments {
+  BOOL json = NO;
+  for (NSString* arg in arguments) {
+    if ([arg caseInsensitiveCompare:@"--json"] == NSOrderedSame) {
+      json = YES;
+    } else {
+      [self printErrorUsageAndExit:[@"Unknown argument: " stringByAppendingString:arg]];
+    }
+  }
+
+  NSArray<SNTReadinessCheck*>* checks =
+      [SNTCommandReadiness checksForState:[self currentState] now:[NSDate date]];
+  BOOL passed = [SNTCommandReadiness checksPassed:checks];
+
+  if (json) {
+    NSMutableArray* jsonChecks = [NSMutableArray array];
+    for (SNTReadinessCheck* check in checks) {
+      [jsonChecks addObject:@{
+        @"name" : check.name,
+        @"result" : ResultString(check.result),
+        @"detail" : check.detail,
+      }];
+    }
+    NSData* data = [NSJSONSerialization
+        dataWithJSONObject:@{@"ready" : @(passed), @"checks" : jsonChecks}
+                   options:NSJSONWritingPrettyPrinted | NSJSONWritingSortedKeys
+                     error:NULL];
+    printf("%s\n", [[NSString alloc] initWithData:data encoding:NSUTF8StringEncoding].UTF8String);
+  } else {
+    for (SNTReadinessCheck* check in checks) {
+      printf("%-8s  %-18s  %s\n", ResultString(check.result).uppercaseString.UTF8String,
+             check.name.UTF8String, check.detail.UTF8String);
+    }
+    printf("\n%s\n", passed ? "Santa is ready" : "Santa is not ready");
+  }
+  exit(passed ? EXIT_SUCCESS : EXIT_FAILURE);
+}
+
+- (SNTReadinessState*)currentState {
+  SNTReadinessState* state = [[SNTReadinessState alloc] init];
+  SNTConfigurator* configurator = [SNTConfigurator configurator];
+  state.syncConfigured = configurator.syncBaseURL != nil;
+  // Until santasyncservice answers, push is treated as configured but in an unknown state.
+  state.pushConfigured = state.syncConfigured;
+
+  [self.daemonConn resume];
+  id<SNTDaemonControlXPC> rop = [self.daemonConn synchronousRemoteObjectProxy];
+
+  // santad is running if it answers at all.
+  [rop fullDiskAccessGranted:^(BOOL granted) {
+    state.daemonRunning = YES;
+    state.fullDiskAccessGranted = @(granted);
+  }];
+  if (!state.daemonRunning) return state;
+
+  __block struct RuleCounts ruleCounts;
+  __block BOOL ruleCountsKnown = NO;
+  [rop databaseRuleCounts:^(struct RuleCounts counts) {
+    ruleCounts = counts;
+    ruleCountsKnown = YES;
+  }];
+  __block int64_t staticRuleCount = 0;
+  [rop staticRuleCount:^(int64_t count) {
+    staticRuleCount = count;
+  }];
+  if (ruleCountsKnown) {
+    state.ruleCount = @(ruleCounts.binary + ruleCounts.certificate + ruleCounts.compiler +
+                        ruleCounts.transitive + ruleCounts.teamID + ruleCounts.signingID +
+                        ruleCounts.cdhash + MAX(staticRuleCount, 0));
+  }
+
+  [rop parsedConfigProfile:^(NSDictionary* config) {
+    if (config) {
+      state.configIssues = [configurator configIssuesForProfile:[configurator configProfile]
+                                                   parsedConfig:config];
+    }
+  }];
+
+  if (!state.syncConfigured) return state;
+
+  [rop fullSyncLastSuccess:^(NSDate* date) {
+    state.lastFullSyncSuccess = date;
+  }];
+
+  // The push status is answered by santasyncservice and the request can hang if it's
+  // unavailable, so give up after 2s.
+  dispatch_semaphore_t sema = dispatch_semaphore_create(0);
+  __block SNTPushNotificationStatus pushStatus = SNTPushNotificationStatusUnknown;
+  dispatch_async(dispatch_get_global_queue(QOS_CLASS_USER_INITIATED, 0), ^{
+    [rop pushNotificationStatus:^(SNTPushNotificationStatus response) {
+      pushStatus = response;
+      dispatch_semaphore_signal(sema);
+    }];
+  });
+  dispatch_semaphore_wait(sema, dispatch_time(DISPATCH_TIME_NOW, 2 * NSEC_PER_SEC));
+
+  switch (pushStatus) {
+    case SNTPushNotificationStatusDisabled: state.pushConfigured = NO; break;
+    case SNTPushNotificationStatusDisconnected:
+      state.pushConfigured = YES;
+      state.pushConnected = @NO;
+      break;
+    case SNTPushNotificationStatusConnected:
+    case SNTPushNotificationStatusConnectedNATS:
+      state.pushConfigured = YES;
+      state.pushConnected = @YES;
+      break;
+    default: break;
+  }
+
+  return state;
+}
+
+@end
@@ -0,0 +1,142 @@
+/// Copyright 2026 North Pole Security, Inc.
+///
+/// Licensed under the Apache License, Version 2.0 (the "License");
+/// you may not use this file except in compliance with the License.
+/// You may obtain a copy of the License at
+///
+///     http://www.apache.org/licenses/LICENSE-2.0
+///
+/// Unless required by applicable law or agreed to in writing, software
+/// distributed under the License is distributed on an "AS IS" BASIS,
+/// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+/// See the License for the specific language governing permissions and
+/// limitations under the License.
+
+#import <XCTest/XCTest.h>
+
+#import "Source/common/SNTConfigurator.h"
+#import "Source/santactl/Commands/SNTCommandReadiness.h"
+
+static const NSTimeInterval kNow = 1800000000;
+
+@interface SNTCommandReadinessTest : XCTestCase
+@end
+
+@implementation SNTCommandReadinessTest
+
+// A host on which every check passes.
+- (SNTReadinessState*)healthyState {
+  SNTReadinessState* state = [[SNTReadinessState alloc] init];
+  state.daemonRunning = YES;
+  state.fullDiskAccessGranted = @YES;
+  state.syncConfigured = YES;
+  state.lastFullSyncSuccess = [NSDate dateWithTimeIntervalSince1970:kNow - 600];
+  state.ruleCount = @10;
+  state.pushConfigured = YES;
+  state.pushConnected = @YES;
+  state.configIssues = @[];
+  return state;
+}
+
+- (NSDictionary<NSString*, SNTReadinessCheck*>*)checksForState:(SNTReadinessState*)state {
+  NSMutableDictionary* checks = [NSMutableDictionary dictionary];
+  for (SNTReadinessCheck* check in
+       [SNTCommandReadiness checksForState:state
+                                       now:[NSDate dateWithTimeIntervalSince1970:kNow]]) {
+    checks[check.name] = check;
+  }
+  return checks;
+}
+
+// Asserts that the named check has the expected result and that the overall result is a pass
+// only if nothing failed.
+- (void)assertCheck:(NSString*)name
+          forState:(SNTReadinessState*)state
+            result:(SNTReadinessResult)result {
+  NSArray<SNTReadinessCheck*>* checks =
+      [SNTCommandReadiness checksForState:state now:[NSDate dateWithTimeIntervalSince1970:kNow]];
+  XCTAssertEqual([self checksForState:state][name].result, result, @"%@", name);
+  XCTAssertEqual([SNTCommandReadiness checksPassed:checks], result != SNTReadinessResultFail,
+                 @"%@", name);
+}
+
+- (void)testHealthyHostPasses {
+  NSArray<SNTReadinessCheck*>* checks =
+      [SNTCommandReadiness checksForState:[self healthyState]
+                                      now:[NSDate dateWithTimeIntervalSince1970:kNow]];
+  NSArray* names = [checks valueForKey:@"name"];
+  XCTAssertEqualObjects(names, (@[
+                          @"daemon_running", @"full_disk_access", @"last_sync", @"rule_count",
+                          @"push_connected", @"config_applied"
+                        ]));
+  for (SNTReadinessCheck* check in checks) {
+    XCTAssertEqual(check.result, SNTReadinessResultPass, @"%@: %@", check.name, check.detail);
+  }
+  XCTAssertTrue([SNTCommandReadiness checksPassed:checks]);
+}
+
+- (void)testDaemonNotRunningFails {
+  SNTReadinessState* state = [self healthyState];
+  state.daemonRunning = NO;
+  [self assertCheck:@"daemon_running" forState:state result:SNTReadinessResultFail];
+}
+
+- (void)testFullDiskAccess {
+  SNTReadinessState* state = [self healthyState];
+  state.fullDiskAccessGranted = @NO;
+  [self assertCheck:@"full_disk_access" forState:state result:SNTReadinessResultFail];
+
+  state.fullDiskAccessGranted = nil;
+  [self assertCheck:@"full_disk_access" forState:state result:SNTReadinessResultFail];
+}
+
+- (void)testLastSync {
+  SNTReadinessState* state = [self healthyState];
+  state.lastFullSyncSuccess = [NSDate dateWithTimeIntervalSince1970:kNow - 2 * 24 * 60 * 60];
+  [self assertCheck:@"last_sync" forState:state result:SNTReadinessResultFail];
+
+  state.lastFullSyncSuccess = nil;
+  [self assertCheck:@"last_sync" forState:state result:SNTReadinessResultFail];
+
+  // Hosts without sync can't have synced.
+  state.syncConfigured = NO;
+  [self assertCheck:@"last_sync" forState:state result:SNTReadinessResultSkipped];
+}
+
+- (void)testRuleCount {
+  SNTReadinessState* state = [self healthyState];
+  state.ruleCount = @0;
+  [self assertCheck:@"rule_count" forState:state result:SNTReadinessResultFail];
+
+  state.ruleCount = nil;
+  [self assertCheck:@"rule_count" forState:state result:SNTReadinessResultFail];
+}
+
+- (void)testPushConnected {
+  SNTReadinessState* state = [self healthyState];
+  state.pushConnected = @NO;
+  [self assertCheck:@"push_connected" forState:state result:SNTReadinessResultFail];
+
+  state.pushConnected = nil;
+  [self assertCheck:@"push_connected" forState:state result:SNTReadinessResultFail];
+
+  state.pushConfigured = NO;
+  [self assertCheck:@"push_connected" forState:state result:SNTReadinessResultSkipped];
+}
+
+- (void)testConfigApplied {
+  SNTReadinessState* state = [self healthyState];
+  state.configIssues = @[ @{
+    kConfigIssueKey : @"ClientMode",
+    kConfigIssueType : @"malformed",
+    kConfigIssueDetail : @"expected an integer",
+  } ];
+  [self assertCheck:@"config_applied" forState:state result:SNTReadinessResultFail];
+  XCTAssertTrue(
+      [[self checksForState:state][@"config_applied"].detail containsString:@"ClientMode"]);
+
+  state.configIssues = nil;
+  [self assertCheck:@"config_applied" forState:state result:SNTReadinessResultFail];
+}
+
+@end
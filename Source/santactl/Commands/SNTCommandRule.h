@@ -0,0 +1,67 @@
+/// Copyright 2026 North Pole Security, Inc.
+///
+/// Licensed under the Apache License, Version 2.0 (the "License");
+/// you may not use this file except in compliance with the License.
+/// You may obtain a copy of the License at
+///
+///     http://www.apache.org/licenses/LICENSE-2.0
+///
+/// Unless required by applicable law or agreed to in writing, software
+/// distributed under the License is distributed on an "AS IS" BASIS,
+/// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+/// See the License for the specific language governing permissions and
+/// limitations under the License.
+
+#import <Foundation/Foundation.h>
+
+#import "Source/common/SNTCommonEnums.h"
+#import "Source/common/SNTFileInfo.h"
+#import "Source/santactl/SNTCommand.h"
+#import "Source/santactl/SNTCommandController.h"
+
+///
+///  How a proposed rule would have affected a set of logged executions.
+///
+@interface SNTRuleImpact : NSObject
+/// Executions whose identity matches the proposed rule.
+@property NSUInteger matched;
+/// Matched executions whose decision would have changed.
+@property NSUInteger changed;
+/// Matched executions that already had the decision the rule would make.
+@property NSUInteger unchanged;
+/// Matched executions decided by a more specific rule, which would still take precedence.
+@property NSUInteger overridden;
+/// The number of changed executions for each path.
+@property NSMutableDictionary<NSString*, NSNumber*>* changedPaths;
+@end
+
+@interface SNTCommandRule : SNTCommand <SNTCommandProtocol>
+
+///
+///  Resolve the identifier a rule of the given type would use for the file at path. Returns nil
+///  and sets error if the file is not a plain file or its code signature cannot be read.
+///
++ (NSString*)identifierForPath:(NSString*)path ruleType:(SNTRuleType)type error:(NSError**)error;
+
+///
+///  As identifierForPath:ruleType:error:, for a file that has already been opened. Only the
+///  parts of the file needed for the rule type are read, e.g. a CDHash lookup does not hash the
+///  whole file.
+///
++ (NSString*)identifierForFileInfo:(SNTFileInfo*)fileInfo
+                          ruleType:(SNTRuleType)type
+                             error:(NSError**)error;
+
+///
+///  Work out how a rule with the given identifier, type and state would have affected
+///  executions read from the event log, as returned by
+///  +[SNTCommandLog executionsInLogAtPath:since:until:error:]. Only rule types whose identifier
+///  is recorded in the event log are supported: binary, certificate, team ID and signing ID.
+///  Returns nil for any other type.
+///
++ (SNTRuleImpact*)impactOfRuleWithIdentifier:(NSString*)identifier
+                                        type:(SNTRuleType)type
+                                       state:(SNTRuleState)state
+                                  executions:(NSArray<NSDictionary*>*)executions;
+
+@end
@@ -13,9 +13,12 @@
 /// See the License for the specific language governing permissions and
 /// limitations under the License.
 
+#import "Source/santactl/Commands/SNTCommandRule.h"
+
 #import <CommonCrypto/CommonDigest.h>
 #import <Foundation/Foundation.h>
 #import <Kernel/kern/cs_blobs.h>
+#include <os/log.h>
 #include <stdlib.h>
 
 #import "Source/common/MOLCertificate.h"
@@ -23,16 +26,72 @@
 #import "Source/common/MOLXPCConnection.h"
 #import "Source/common/SNTConfigurator.h"
 #import "Source/common/SNTDropRootPrivs.h"
+#import "Source/common/SNTError.h"
 #import "Source/common/SNTFileInfo.h"
 #import "Source/common/SNTLogging.h"
 #import "Source/common/SNTRule.h"
 #import "Source/common/SNTRuleIdentifiers.h"
+#import "Source/common/SNTSyncConstants.h"
 #import "Source/common/SNTXPCControlInterface.h"
+#import "Source/common/SNTXPCSyncServiceInterface.h"
+#include "Source/common/SignedRuleBundle.h"
 #include "Source/common/faa/WatchItems.h"
+#import "Source/santactl/Commands/SNTCommandLog.h"
 #import "Source/santactl/SNTCommand.h"
 #import "Source/santactl/SNTCommandController.h"
 
-@interface SNTCommandRule : SNTCommand <SNTCommandProtocol>
+static const NSTimeInterval kDefaultImpactWindow = 7 * 24 * 60 * 60;
+
+@implementation SNTRuleImpact
+
+- (instancetype)init {
+  self = [super init];
+  if (self) {
+    _changedPaths = [NSMutableDictionary dictionary];
+  }
+  return self;
+}
+
+@end
+
+// The event log key holding the identifier a rule of the given type matches on, or nil if the
+// identifier is not logged.
+static NSString* EventLogKeyForRuleType(SNTRuleType type) {
+  switch (type) {
+    case SNTRuleTypeBinary: return @"sha256";
+    case SNTRuleTypeCertificate: return @"cert_sha256";
+    case SNTRuleTypeTeamID: return @"teamid";
+    case SNTRuleTypeSigningID: return @"signingid";
+    default: return nil;
+  }
+}
+
+// Rank of the rule type that made a logged decision, following the order rules are evaluated
+// in. Decisions that were not made by one of these rule types rank 0.
+static int PrecedenceForReason(NSString* reason) {
+  static NSDictionary<NSString*, NSNumber*>* precedence = @{
+    @"CDHASH" : @5,
+    @"BINARY" : @4,
+    @"SIGNINGID" : @3,
+    @"CERT" : @2,
+    @"TEAMID" : @1,
+  };
+  return reason ? precedence[reason].intValue : 0;
+}
+
+static int PrecedenceForRuleType(SNTRuleType type) {
+  switch (type) {
+    case SNTRuleTypeCDHash: return 5;
+    case SNTRuleTypeBinary: return 4;
+    case SNTRuleTypeSigningID: return 3;
+    case SNTRuleTypeCertificate: return 2;
+    case SNTRuleTypeTeamID: return 1;
+    default: return 0;
+  }
+}
+
+@interface SNTCommandRule () <SNTSyncServiceLogReceiverXPC>
+@property BOOL enableDebugLogging;
 @end
 
 @implementation SNTCommandRule
@@ -53,6 +112,8 @@ REGISTER_COMMAND_NAME(@"rule")
 
 + (NSString*)longHelpText {
   return (@"Usage: santactl rule [options]\n"
+          @"       santactl rule reconcile [--sync-url {url}] [--debug]\n"
+          @"       santactl rule impact --identifier {id} --type {type} [options]\n"
           @"  One of:\n"
           @"    --allow: add to allow\n"
           @"    --block: add to block\n"
@@ -67,6 +128,10 @@ REGISTER_COMMAND_NAME(@"rule")
           @"    --check: check for an existing rule\n"
           @"    --import {path}: import rules from a JSON file\n"
           @"    --export {path}: export rules to a JSON file\n"
+          @"    --sign: sign exported rules, requires --export and --key\n"
+          @"    --key {path}: path to a base64 encoded Ed25519 private key used by --sign\n"
+          @"    --verify-key {path}: path to a base64 encoded Ed25519 public key used to\n"
+          @"        verify the signature of a signed rule bundle passed to --import\n"
 #ifdef DEBUG
           @"    --export-file-access {path}: export file access rules to a Plist file\n"
 #endif
@@ -75,7 +140,10 @@ REGISTER_COMMAND_NAME(@"rule")
           @"    --path {path}: path of binary/bundle to add/remove/check.\n"
           @"                   Will add an appropriate rule for the file currently at that path.\n"
           @"                   Defaults to a SHA-256 rule unless overridden with another flag.\n"
-          @"    --identifier {sha256|teamID|signingID|cdhash}: identifier to add/remove/check\n"
+          @"                   The identity is resolved when the rule is added; later changes\n"
+          @"                   to the file are not reflected in the rule.\n"
+          @"    --identifier {sha256|teamID|signingID|cdhash|requirement}: identifier to\n"
+          @"                   add/remove/check\n"
           @"    --sha256 {sha256}: hash to add/remove/check [deprecated]\n"
           @"\n"
           @"  Optionally:\n"
@@ -83,12 +151,16 @@ REGISTER_COMMAND_NAME(@"rule")
           @"    --signingid: add or check a signing ID rule instead of binary (see notes)\n"
           @"    --certificate: add or check a certificate sha256 rule instead of binary\n"
           @"    --cdhash: add or check a cdhash rule instead of binary\n"
+          @"    --requirement: add a code signing requirement rule instead of binary (see notes)\n"
+          @"    --bundle-signingid: add a bundle signing ID rule instead of binary (see notes)\n"
           @"    --file-access: Check a path for associated File Access rules. Requires --path.\n"
 #ifdef DEBUG
           @"    --force: allow manual changes even when SyncBaseUrl is set\n"
 #endif
           @"    --message {message}: custom message to show when binary is blocked\n"
           @"    --comment {comment}: comment to attach to a new rule\n"
+          @"    --severity {info|warning|critical}: how prominently blocks by a new rule are\n"
+          @"        shown and how quickly they are uploaded. Defaults to warning.\n"
           @"    --clean: clear all non-transitive rules\n"
           @"        Can be combined with --import to clear existing rules before importing.\n"
           @"    --clean-all: clear all rules\n"
@@ -105,6 +177,20 @@ REGISTER_COMMAND_NAME(@"rule")
           @"    \"platform\" (e.g. `platform:SigningID`). This allows for rules\n"
           @"    targeting Apple-signed binaries that do not have a team ID.\n"
           @"\n"
+          @"    The `identifier` of a `requirement` rule is a code signing requirement\n"
+          @"    in the Security framework's text form, e.g.\n"
+          @"\n"
+          @"      `anchor apple generic and identifier \"com.example.app\"`\n"
+          @"\n"
+          @"    When used with --path, the binary's designated requirement is used.\n"
+          @"    Requirement rules are evaluated after all other rule types.\n"
+          @"\n"
+          @"    A `bundle-signingid` rule uses the same `TeamID:SigningID` format as a\n"
+          @"    `signingid` rule and matches every binary in the bundle: binaries with\n"
+          @"    that signing ID or one starting with it followed by a \".\", and binaries\n"
+          @"    from the same team inside a bundle whose main executable has it. It is\n"
+          @"    evaluated after the rule types that match a single identifier.\n"
+          @"\n"
           @"  Importing / Exporting Rules:\n"
           @"    If santa is not configured to use a sync server one can export\n"
           @"    & import its non-static rules to and from JSON files using the \n"
@@ -121,10 +207,58 @@ REGISTER_COMMAND_NAME(@"rule")
           @"\n"
           @"    By default rules are not cleared when importing. To clear the\n"
           @"    database you must use either --clean or --clean-all\n"
+          @"\n"
+          @"    Exports can be signed with --sign --key so that rules can be\n"
+          @"    distributed to other hosts with integrity. Signed bundles are\n"
+          @"    only imported when --verify-key is given and the signature is\n"
+          @"    valid for that key.\n"
+          @"\n"
+          @"  Reconciling Rules:\n"
+          @"    `santactl rule reconcile` downloads the full rule set from the sync\n"
+          @"    server and makes the local rules match it exactly: missing rules are\n"
+          @"    added and any rules the server did not send are removed, including\n"
+          @"    rules added locally. This is for recovering a host whose rules have\n"
+          @"    drifted from the server. The rules that were added and removed are\n"
+          @"    reported.\n"
+          @"\n"
+          @"    --sync-url {url}: the sync server to reconcile with. Defaults to\n"
+          @"                      the configured SyncBaseURL.\n"
+          @"    --debug: enable verbose output.\n"
+          @"\n"
+          @"  Rule Impact:\n"
+          @"    `santactl rule impact` reads recent executions from the event log and\n"
+          @"    reports how many of them a proposed rule would have matched and how many\n"
+          @"    decisions it would have changed. Nothing is added to the rule database.\n"
+          @"    Requires EventLogType to be 'file'.\n"
+          @"\n"
+          @"    --identifier {id}: the identifier of the proposed rule\n"
+          @"    --type {binary|certificate|teamid|signingid}: the type of the proposed rule\n"
+          @"    --allow: the proposed rule allows matching executions\n"
+          @"    --block: the proposed rule blocks matching executions (the default)\n"
+          @"    --since {time}: only consider executions at or after this time, as an\n"
+          @"                    ISO 8601 timestamp or a duration such as 12h. Defaults to 7d.\n"
+          @"    --path {path}: the log file to read, defaults to the configured EventLogPath\n"
+          @"    --json: print the results as JSON\n"
+          @"\n"
+          @"    Matched executions that were decided by a more specific rule, e.g. a binary\n"
+          @"    rule when the proposed rule is a team ID rule, are reported as overridden.\n"
           @"\n");
 }
 
 - (void)runWithArguments:(NSArray*)arguments {
+  // Reconciling is how centrally managed rules are recovered, so it is not subject to the check
+  // below.
+  if ([arguments.firstObject isEqualToString:@"reconcile"]) {
+    [self reconcileWithArguments:[arguments subarrayWithRange:NSMakeRange(1, arguments.count - 1)]];
+    return;
+  }
+
+  // Impact only reads the event log so it is allowed even when rules are managed centrally.
+  if ([arguments.firstObject isEqualToString:@"impact"]) {
+    [self impactWithArguments:[arguments subarrayWithRange:NSMakeRange(1, arguments.count - 1)]];
+    return;
+  }
+
   SNTConfigurator* config = [SNTConfigurator configurator];
   if ((config.syncBaseURL || config.staticRules.count) &&
       ![arguments containsObject:@"--check"]
@@ -142,6 +276,7 @@ REGISTER_COMMAND_NAME(@"rule")
   SNTRuleState state = SNTRuleStateUnknown;
   SNTRuleType type = SNTRuleTypeBinary;
   NSString *celExpr, *customMsg, *customURL, *comment;
+  SNTRuleSeverity severity = SNTRuleSeverityUnspecified;
 
   NSString* path;
   NSString* importExportFilePath;
@@ -151,6 +286,9 @@ REGISTER_COMMAND_NAME(@"rule")
   BOOL exportRules = NO;
   BOOL exportFileAccessRules = NO;
   BOOL faaLookup = NO;
+  BOOL signExport = NO;
+  NSString* signingKeyPath;
+  NSString* verifyKeyPath;
 
   // Parse arguments
   for (NSUInteger i = 0; i < arguments.count; ++i) {
@@ -189,6 +327,10 @@ REGISTER_COMMAND_NAME(@"rule")
       type = SNTRuleTypeSigningID;
     } else if ([arg caseInsensitiveCompare:@"--cdhash"] == NSOrderedSame) {
       type = SNTRuleTypeCDHash;
+    } else if ([arg caseInsensitiveCompare:@"--requirement"] == NSOrderedSame) {
+      type = SNTRuleTypeRequirement;
+    } else if ([arg caseInsensitiveCompare:@"--bundle-signingid"] == NSOrderedSame) {
+      type = SNTRuleTypeBundleSigningID;
     } else if ([arg caseInsensitiveCompare:@"--file-access"] == NSOrderedSame) {
       faaLookup = YES;
     } else if ([arg caseInsensitiveCompare:@"--path"] == NSOrderedSame) {
@@ -216,6 +358,20 @@ REGISTER_COMMAND_NAME(@"rule")
         [self printErrorUsageAndExit:@"--comment requires an argument"];
       }
       comment = arguments[i];
+    } else if ([arg caseInsensitiveCompare:@"--severity"] == NSOrderedSame) {
+      if (++i > arguments.count - 1) {
+        [self printErrorUsageAndExit:@"--severity requires an argument"];
+      }
+      NSString* severityString = arguments[i];
+      if ([severityString caseInsensitiveCompare:kRuleSeverityInfo] == NSOrderedSame) {
+        severity = SNTRuleSeverityInfo;
+      } else if ([severityString caseInsensitiveCompare:kRuleSeverityWarning] == NSOrderedSame) {
+        severity = SNTRuleSeverityWarning;
+      } else if ([severityString caseInsensitiveCompare:kRuleSeverityCritical] == NSOrderedSame) {
+        severity = SNTRuleSeverityCritical;
+      } else {
+        [self printErrorUsageAndExit:@"--severity must be one of info, warning or critical"];
+      }
 #ifdef DEBUG
     } else if ([arg caseInsensitiveCompare:@"--force"] == NSOrderedSame) {
       // Don't do anything special.
@@ -242,6 +398,18 @@ REGISTER_COMMAND_NAME(@"rule")
         [self printErrorUsageAndExit:@"--export requires an argument"];
       }
       importExportFilePath = arguments[i];
+    } else if ([arg caseInsensitiveCompare:@"--sign"] == NSOrderedSame) {
+      signExport = YES;
+    } else if ([arg caseInsensitiveCompare:@"--key"] == NSOrderedSame) {
+      if (++i > arguments.count - 1) {
+        [self printErrorUsageAndExit:@"--key requires an argument"];
+      }
+      signingKeyPath = arguments[i];
+    } else if ([arg caseInsensitiveCompare:@"--verify-key"] == NSOrderedSame) {
+      if (++i > arguments.count - 1) {
+        [self printErrorUsageAndExit:@"--verify-key requires an argument"];
+      }
+      verifyKeyPath = arguments[i];
 #ifdef DEBUG
     } else if ([arg caseInsensitiveCompare:@"--export-file-access"] == NSOrderedSame) {
       if (importRules || exportRules) {
@@ -272,6 +440,17 @@ REGISTER_COMMAND_NAME(@"rule")
       [self printErrorUsageAndExit:@"--check and --clean/--clean-all are mutually exclusive"];
   }
 
+  if (signExport || signingKeyPath) {
+    if (!exportRules) [self printErrorUsageAndExit:@"--sign and --key require --export"];
+    if (!signExport || !signingKeyPath) {
+      [self printErrorUsageAndExit:@"--sign and --key must be used together"];
+    }
+  }
+
+  if (verifyKeyPath && !importRules) {
+    [self printErrorUsageAndExit:@"--verify-key requires --import"];
+  }
+
   if (faaLookup) {
     if (!check) [self printErrorUsageAndExit:@"--file-access can only be used with --check"];
     if (!path) [self printErrorUsageAndExit:@"--file-access requires --path"];
@@ -325,12 +504,12 @@ REGISTER_COMMAND_NAME(@"rule")
       if (identifier != nil || path != nil || check) {
         [self printErrorUsageAndExit:@"--import can only be used by itself"];
       }
-      [self importJSONFile:importExportFilePath with:cleanupType];
+      [self importJSONFile:importExportFilePath with:cleanupType verifyKeyPath:verifyKeyPath];
     } else if (exportRules) {
       if (identifier != nil || path != nil || check) {
         [self printErrorUsageAndExit:@"--export can only be used by itself"];
       }
-      [self exportExecutionRulesToJSONFile:importExportFilePath];
+      [self exportExecutionRulesToJSONFile:importExportFilePath signingKeyPath:signingKeyPath];
 #ifdef DEBUG
     } else if (exportFileAccessRules) {
       if (identifier != nil || path != nil || check) {
@@ -343,29 +522,10 @@ REGISTER_COMMAND_NAME(@"rule")
   }
 
   if (path) {
-    SNTFileInfo* fi = [[SNTFileInfo alloc] initWithPath:path];
-    if (!fi.path) {
-      [self printErrorUsageAndExit:@"Provided path was not a plain file"];
-    }
-
-    if (type == SNTRuleTypeBinary) {
-      identifier = fi.SHA256;
-    } else if (type == SNTRuleTypeCertificate) {
-      MOLCodesignChecker* cs = [fi codesignCheckerWithError:NULL];
-      identifier = cs.leafCertificate.SHA256;
-    } else if (type == SNTRuleTypeCDHash) {
-      MOLCodesignChecker* cs = [fi codesignCheckerWithError:NULL];
-      identifier = cs.cdhash;
-    } else if (type == SNTRuleTypeTeamID) {
-      MOLCodesignChecker* cs = [fi codesignCheckerWithError:NULL];
-      identifier = cs.teamID;
-    } else if (type == SNTRuleTypeSigningID) {
-      MOLCodesignChecker* cs = [fi codesignCheckerWithError:NULL];
-      if (cs.teamID.length) {
-        identifier = [NSString stringWithFormat:@"%@:%@", cs.teamID, cs.signingID];
-      } else if (cs.platformBinary) {
-        identifier = [NSString stringWithFormat:@"platform:%@", cs.signingID];
-      }
+    NSError* err;
+    identifier = [[self class] identifierForPath:path ruleType:type error:&err];
+    if (!identifier && err) {
+      [self printErrorUsageAndExit:err.localizedDescription];
     }
 
     if (!comment) {
@@ -399,9 +559,17 @@ REGISTER_COMMAND_NAME(@"rule")
                                                  celExpr:celExpr
                                           seatbeltPolicy:nil
                                                   ruleId:0
+                                                severity:severity
                                                    error:nil];
 
   if (check) {
+    if (type == SNTRuleTypeRequirement) {
+      return [self printErrorUsageAndExit:@"--check is not supported for requirement rules"];
+    }
+    if (type == SNTRuleTypeBundleSigningID) {
+      return [self
+          printErrorUsageAndExit:@"--check is not supported for bundle signing ID rules"];
+    }
     if (!newRule.identifier) return [self printErrorUsageAndExit:@"--check requires --identifier"];
     return [self printStateOfRule:newRule daemonConnection:self.daemonConn];
   }
@@ -445,6 +613,10 @@ REGISTER_COMMAND_NAME(@"rule")
                                   case SNTRuleTypeTeamID: ruleType = @"Team ID"; break;
                                   case SNTRuleTypeSigningID: ruleType = @"Signing ID"; break;
                                   case SNTRuleTypeCDHash: ruleType = @"CDHash"; break;
+                                  case SNTRuleTypeRequirement: ruleType = @"Requirement"; break;
+                                  case SNTRuleTypeBundleSigningID:
+                                    ruleType = @"Bundle Signing ID";
+                                    break;
                                   default: ruleType = @"(Unknown type)"; break;
                                 }
                                 if (newRule.state == SNTRuleStateRemove) {
@@ -454,10 +626,69 @@ REGISTER_COMMAND_NAME(@"rule")
                                   printf("Added rule for %s: %s.\n", [ruleType UTF8String],
                                          [newRule.identifier UTF8String]);
                                 }
+
+                                // Rules created from a path pin the identity of the file at the
+                                // time it was resolved. Let the admin know if the file was
+                                // modified before the rule was applied.
+                                if (path && newRule.state != SNTRuleStateRemove) {
+                                  NSString* current = [[self class] identifierForPath:path
+                                                                             ruleType:type
+                                                                                error:NULL];
+                                  if (![current isEqualToString:newRule.identifier]) {
+                                    TEE_LOGW(@"%@ changed after its %@ was resolved. The added "
+                                             @"rule applies to the previous contents (%@), not "
+                                             @"the file currently at that path (%@).",
+                                             path, ruleType, newRule.identifier,
+                                             current ?: @"none");
+                                  }
+                                }
                                 exit(EXIT_SUCCESS);
                               }];
 }
 
++ (NSString*)identifierForPath:(NSString*)path ruleType:(SNTRuleType)type error:(NSError**)error {
+  SNTFileInfo* fi = [[SNTFileInfo alloc] initWithPath:path];
+  if (!fi.path) {
+    [SNTError populateError:error withFormat:@"Provided path was not a plain file: %@", path];
+    return nil;
+  }
+
+  return [self identifierForFileInfo:fi ruleType:type error:error];
+}
+
++ (NSString*)identifierForFileInfo:(SNTFileInfo*)fi
+                          ruleType:(SNTRuleType)type
+                             error:(NSError**)error {
+  if (type == SNTRuleTypeBinary) {
+    return fi.SHA256;
+  }
+
+  NSError* csError;
+  MOLCodesignChecker* cs = [fi codesignCheckerWithError:&csError];
+  if (!cs) {
+    [SNTError populateError:error
+                 withFormat:@"Unable to read the code signature of %@: %@", fi.path,
+                            csError.localizedDescription ?: @"Unknown failure"];
+    return nil;
+  }
+
+  switch (type) {
+    case SNTRuleTypeCertificate: return cs.leafCertificate.SHA256;
+    case SNTRuleTypeCDHash: return cs.cdhash;
+    case SNTRuleTypeTeamID: return cs.teamID;
+    case SNTRuleTypeRequirement: return cs.designatedRequirement;
+    case SNTRuleTypeSigningID:
+    case SNTRuleTypeBundleSigningID:
+      if (cs.teamID.length) {
+        return [NSString stringWithFormat:@"%@:%@", cs.teamID, cs.signingID];
+      } else if (cs.platformBinary) {
+        return [NSString stringWithFormat:@"platform:%@", cs.signingID];
+      }
+      return nil;
+    default: return nil;
+  }
+}
+
 - (void)printStateOfRule:(SNTRule*)rule daemonConnection:(MOLXPCConnection*)daemonConn {
   id<SNTDaemonControlXPC> rop = [daemonConn synchronousRemoteObjectProxy];
   __block NSString* output = @"No matching rule exists";
@@ -479,7 +710,9 @@ REGISTER_COMMAND_NAME(@"rule")
   exit(0);
 }
 
-- (void)importJSONFile:(NSString*)jsonFilePath with:(SNTRuleCleanup)cleanupType {
+- (void)importJSONFile:(NSString*)jsonFilePath
+                  with:(SNTRuleCleanup)cleanupType
+         verifyKeyPath:(NSString*)verifyKeyPath {
   // If the file exists parse it and then add the rules one at a time.
   NSError* error;
   NSData* data = [NSData dataWithContentsOfFile:jsonFilePath options:0 error:&error];
@@ -488,6 +721,23 @@ REGISTER_COMMAND_NAME(@"rule")
                                                             error.localizedDescription]];
   }
 
+  if (verifyKeyPath) {
+    NSData* publicKey = santa::ReadEd25519PublicKeyFile(verifyKeyPath, &error);
+    if (!publicKey) {
+      [self printErrorUsageAndExit:error.localizedDescription];
+    }
+
+    // Replace the bundle with the verified payload, which has the same form
+    // as an unsigned export.
+    data = santa::VerifySignedRuleBundle(data, publicKey, &error);
+    if (!data) {
+      TEE_LOGE(@"Refusing to import %@: %@", jsonFilePath, error.localizedDescription);
+      exit(EXIT_FAILURE);
+    }
+  } else if (santa::IsSignedRuleBundle(data)) {
+    [self printErrorUsageAndExit:@"Importing a signed rule bundle requires --verify-key"];
+  }
+
   // We expect a JSON object with one key "rules". This is an array of rule
   // objects.
   // e.g.
@@ -537,7 +787,17 @@ REGISTER_COMMAND_NAME(@"rule")
                               }];
 }
 
-- (void)exportExecutionRulesToJSONFile:(NSString*)jsonFilePath {
+- (void)exportExecutionRulesToJSONFile:(NSString*)jsonFilePath
+                        signingKeyPath:(NSString*)signingKeyPath {
+  NSData* signingKey;
+  if (signingKeyPath) {
+    NSError* error;
+    signingKey = santa::ReadEd25519PrivateKeyFile(signingKeyPath, &error);
+    if (!signingKey) {
+      [self printErrorUsageAndExit:error.localizedDescription];
+    }
+  }
+
   // Get the rules from the daemon and then write them to the file.
   id<SNTDaemonControlXPC> rop = [self.daemonConn synchronousRemoteObjectProxy];
   [rop retrieveAllExecutionRules:^(NSArray<SNTRule*>* rules, NSError* error) {
@@ -576,6 +836,14 @@ REGISTER_COMMAND_NAME(@"rule")
       TEE_LOGE(@"Failed to jsonify rules: %@", error.localizedDescription);
       exit(1);
     }
+
+    if (signingKey) {
+      jsonData = santa::CreateSignedRuleBundle(jsonData, signingKey, &error);
+      if (!jsonData) {
+        TEE_LOGE(@"Failed to sign rules: %@", error.localizedDescription);
+        exit(1);
+      }
+    }
     // Write jsonData to the file
     [outputStream write:static_cast<const uint8_t*>(jsonData.bytes) maxLength:jsonData.length];
     [outputStream close];
@@ -583,6 +851,232 @@ REGISTER_COMMAND_NAME(@"rule")
   }];
 }
 
++ (SNTRuleImpact*)impactOfRuleWithIdentifier:(NSString*)identifier
+                                        type:(SNTRuleType)type
+                                       state:(SNTRuleState)state
+                                  executions:(NSArray<NSDictionary*>*)executions {
+  NSString* key = EventLogKeyForRuleType(type);
+  if (!key) return nil;
+
+  // Hashes are logged in lowercase, team and signing IDs are case sensitive.
+  BOOL caseInsensitive = (type == SNTRuleTypeBinary || type == SNTRuleTypeCertificate);
+  NSString* proposedDecision = (state == SNTRuleStateAllow) ? @"ALLOW" : @"DENY";
+  int proposedPrecedence = PrecedenceForRuleType(type);
+
+  SNTRuleImpact* impact = [[SNTRuleImpact alloc] init];
+  for (NSDictionary<NSString*, NSString*>* execution in executions) {
+    NSString* value = execution[key];
+    if (!value) continue;
+    if (caseInsensitive ? [value caseInsensitiveCompare:identifier] != NSOrderedSame
+                        : ![value isEqualToString:identifier]) {
+      continue;
+    }
+
+    impact.matched++;
+    if (PrecedenceForReason(execution[@"reason"]) > proposedPrecedence) {
+      impact.overridden++;
+    } else if ([execution[@"decision"] isEqualToString:proposedDecision]) {
+      impact.unchanged++;
+    } else {
+      impact.changed++;
+      NSString* path = execution[@"path"] ?: @"-";
+      impact.changedPaths[path] = @(impact.changedPaths[path].unsignedIntegerValue + 1);
+    }
+  }
+  return impact;
+}
+
+- (void)impactWithArguments:(NSArray*)arguments {
+  NSString* identifier;
+  SNTRuleType type = SNTRuleTypeUnknown;
+  SNTRuleState state = SNTRuleStateBlock;
+  NSDate* now = [NSDate date];
+  NSDate* since = [now dateByAddingTimeInterval:-kDefaultImpactWindow];
+  NSString* path;
+  BOOL json = NO;
+
+  for (NSUInteger i = 0; i < arguments.count; ++i) {
+    NSString* arg = arguments[i];
+    if ([arg caseInsensitiveCompare:@"--identifier"] == NSOrderedSame) {
+      if (++i > arguments.count - 1) {
+        [self printErrorUsageAndExit:@"--identifier requires an argument"];
+      }
+      identifier = arguments[i];
+    } else if ([arg caseInsensitiveCompare:@"--type"] == NSOrderedSame) {
+      if (++i > arguments.count - 1) {
+        [self printErrorUsageAndExit:@"--type requires an argument"];
+      }
+      NSDictionary<NSString*, NSNumber*>* types = @{
+        @"binary" : @(SNTRuleTypeBinary),
+        @"certificate" : @(SNTRuleTypeCertificate),
+        @"teamid" : @(SNTRuleTypeTeamID),
+        @"signingid" : @(SNTRuleTypeSigningID),
+      };
+      NSNumber* t = types[[arguments[i] lowercaseString]];
+      if (!t) {
+        [self printErrorUsageAndExit:
+                  @"--type must be one of binary, certificate, teamid or signingid. Other rule "
+                  @"types cannot be evaluated because their identifiers are not logged."];
+      }
+      type = (SNTRuleType)t.integerValue;
+    } else if ([arg caseInsensitiveCompare:@"--allow"] == NSOrderedSame) {
+      state = SNTRuleStateAllow;
+    } else if ([arg caseInsensitiveCompare:@"--block"] == NSOrderedSame) {
+      state = SNTRuleStateBlock;
+    } else if ([arg caseInsensitiveCompare:@"--since"] == NSOrderedSame) {
+      if (++i > arguments.count - 1) {
+        [self printErrorUsageAndExit:@"--since requires an argument"];
+      }
+      since = [SNTCommandLog dateFromTimeArgument:arguments[i] relativeToDate:now];
+      if (!since) {
+        [self printErrorUsageAndExit:[@"Invalid time: " stringByAppendingString:arguments[i]]];
+      }
+    } else if ([arg caseInsensitiveCompare:@"--path"] == NSOrderedSame) {
+      if (++i > arguments.count - 1) {
+        [self printErrorUsageAndExit:@"--path requires an argument"];
+      }
+      path = arguments[i];
+    } else if ([arg caseInsensitiveCompare:@"--json"] == NSOrderedSame) {
+      json = YES;
+    } else {
+      [self printErrorUsageAndExit:[@"Unknown argument: " stringByAppendingString:arg]];
+    }
+  }
+
+  if (!identifier.length || type == SNTRuleTypeUnknown) {
+    [self printErrorUsageAndExit:@"impact requires --identifier and --type"];
+  }
+
+  if (!path) {
+    SNTConfigurator* configurator = [SNTConfigurator configurator];
+    if (configurator.eventLogType != SNTEventLogTypeFilelog) {
+      TEE_LOGE(@"EventLogType is '%@', only file event logs can be read",
+               configurator.eventLogTypeRaw);
+      exit(EXIT_FAILURE);
+    }
+    path = configurator.eventLogPath;
+  }
+
+  NSError* error;
+  NSArray<NSDictionary<NSString*, NSString*>*>* executions =
+      [SNTCommandLog executionsInLogAtPath:path since:since until:now error:&error];
+  if (!executions) {
+    TEE_LOGE(@"%@", error.localizedDescription);
+    exit(EXIT_FAILURE);
+  }
+
+  SNTRuleImpact* impact = [[self class] impactOfRuleWithIdentifier:identifier
+                                                              type:type
+                                                             state:state
+                                                        executions:executions];
+
+  if (json) {
+    NSDictionary* result = @{
+      @"executions" : @(executions.count),
+      @"matched" : @(impact.matched),
+      @"changed" : @(impact.changed),
+      @"unchanged" : @(impact.unchanged),
+      @"overridden" : @(impact.overridden),
+      @"changed_paths" : impact.changedPaths,
+    };
+    NSData* data = [NSJSONSerialization
+        dataWithJSONObject:result
+                   options:NSJSONWritingPrettyPrinted | NSJSONWritingSortedKeys
+                     error:NULL];
+    printf("%s\n", [[NSString alloc] initWithData:data encoding:NSUTF8StringEncoding].UTF8String);
+    exit(EXIT_SUCCESS);
+  }
+
+  const char* verb = (state == SNTRuleStateAllow) ? "allowed" : "blocked";
+  printf("Executions read:  %lu\n", executions.count);
+  printf("Matched:          %lu\n", impact.matched);
+  printf("Would be %s: %lu\n", verb, impact.changed);
+  printf("Already %s:  %lu\n", verb, impact.unchanged);
+  printf("Overridden:       %lu (decided by a more specific rule)\n", impact.overridden);
+
+  NSArray<NSString*>* paths = [impact.changedPaths
+      keysSortedByValueUsingComparator:^NSComparisonResult(NSNumber* a, NSNumber* b) {
+        return [b compare:a];
+      }];
+  for (NSString* p in paths) {
+    printf("  %6lu  %s\n", impact.changedPaths[p].unsignedLongValue, p.UTF8String);
+  }
+  exit(EXIT_SUCCESS);
+}
+
+- (void)reconcileWithArguments:(NSArray*)arguments {
+  NSURL* syncURL = [[SNTConfigurator configurator] syncBaseURL];
+  for (NSUInteger i = 0; i < arguments.count; ++i) {
+    NSString* arg = arguments[i];
+    if ([arg caseInsensitiveCompare:@"--sync-url"] == NSOrderedSame) {
+      if (++i > arguments.count - 1) {
+        [self printErrorUsageAndExit:@"--sync-url requires an argument"];
+      }
+      syncURL = [NSURL URLWithString:arguments[i]];
+    } else if ([arg caseInsensitiveCompare:@"--debug"] == NSOrderedSame) {
+      self.enableDebugLogging = YES;
+    } else {
+      [self printErrorUsageAndExit:[@"Unknown argument: " stringByAppendingString:arg]];
+    }
+  }
+
+  if (!syncURL.scheme.length || !syncURL.host.length) {
+    [self printErrorUsageAndExit:@"--sync-url must be a valid URL when SyncBaseURL is not set"];
+  }
+
+  MOLXPCConnection* ss = [SNTXPCSyncServiceInterface configuredConnection];
+  ss.invalidationHandler = ^(void) {
+    TEE_LOGE(@"Failed to connect to the sync service.");
+    exit(1);
+  };
+  [ss resume];
+
+  NSXPCListener* logListener = [NSXPCListener anonymousListener];
+  MOLXPCConnection* lr = [[MOLXPCConnection alloc] initServerWithListener:logListener];
+  lr.exportedObject = self;
+  lr.unprivilegedInterface =
+      [NSXPCInterface interfaceWithProtocol:@protocol(SNTSyncServiceLogReceiverXPC)];
+  [lr resume];
+
+  [[ss remoteObjectProxy]
+      reconcileRulesWithSyncURL:syncURL
+                    logListener:logListener.endpoint
+                          reply:^(NSDictionary<NSString*, id>* report) {
+                            NSString* error = report[kRuleReconcileError];
+                            if (error.length || !report) {
+                              TEE_LOGE(@"Failed to reconcile rules: %@", error ?: @"no reply");
+                              exit(1);
+                            }
+
+                            NSArray<NSString*>* added = report[kRuleReconcileAdded];
+                            NSArray<NSString*>* removed = report[kRuleReconcileRemoved];
+                            printf("Reconciled rules with %s\n", syncURL.absoluteString.UTF8String);
+                            printf("  Added:     %lu\n", added.count);
+                            printf("  Removed:   %lu\n", removed.count);
+                            printf("  Unchanged: %lu\n",
+                                   [report[kRuleReconcileUnchanged] unsignedLongValue]);
+                            for (NSString* identifier in added) {
+                              printf("  + %s\n", identifier.UTF8String);
+                            }
+                            for (NSString* identifier in removed) {
+                              printf("  - %s\n", identifier.UTF8String);
+                            }
+                            exit(0);
+                          }];
+
+  // Do not return from this scope.
+  [[NSRunLoop mainRunLoop] run];
+}
+
+/// Implement the SNTSyncServiceLogReceiverXPC protocol.
+- (void)didReceiveLog:(NSString*)log withType:(os_log_type_t)logType {
+  if (logType == OS_LOG_TYPE_DEBUG && !self.enableDebugLogging) {
+    return;
+  }
+  printf("%s\n", log.UTF8String);
+  fflush(stdout);
+}
+
 #ifdef DEBUG
 - (void)exportFileAccessRulesToPlistFile:(NSString*)plistFilePath {
   // Get the rules from the daemon and then write them to the file.
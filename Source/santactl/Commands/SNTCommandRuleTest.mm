@@ -0,0 +1,195 @@
+/// Copyright 2026 North Pole Security, Inc.
+///
+/// Licensed under the Apache License, Version 2.0 (the "License");
+/// you may not use this file except in compliance with the License.
+/// You may obtain a copy of the License at
+///
+///     http://www.apache.org/licenses/LICENSE-2.0
+///
+/// Unless required by applicable law or agreed to in writing, software
+/// distributed under the License is distributed on an "AS IS" BASIS,
+/// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+/// See the License for the specific language governing permissions and
+/// limitations under the License.
+
+#import <XCTest/XCTest.h>
+
+#import "Source/common/MOLCertificate.h"
+#import "Source/common/MOLCodesignChecker.h"
+#import "Source/common/SNTCommonEnums.h"
+#import "Source/common/SNTFileInfo.h"
+#import "Source/santactl/Commands/SNTCommandLog.h"
+#import "Source/santactl/Commands/SNTCommandRule.h"
+
+@interface SNTCommandRuleTest : XCTestCase
+@end
+
+@implementation SNTCommandRuleTest
+
+- (void)testIdentifierForPathResolvesCurrentIdentity {
+  NSString* path = @"/usr/bin/yes";
+  SNTFileInfo* fi = [[SNTFileInfo alloc] initWithPath:path];
+  MOLCodesignChecker* cs = [fi codesignCheckerWithError:NULL];
+  XCTAssertNotNil(cs);
+
+  NSError* err;
+  XCTAssertEqualObjects([SNTCommandRule identifierForPath:path
+                                                 ruleType:SNTRuleTypeBinary
+                                                    error:&err],
+                        fi.SHA256);
+  XCTAssertNil(err);
+
+  XCTAssertEqualObjects([SNTCommandRule identifierForPath:path
+                                                 ruleType:SNTRuleTypeCDHash
+                                                    error:&err],
+                        cs.cdhash);
+  XCTAssertNil(err);
+
+  XCTAssertEqualObjects([SNTCommandRule identifierForPath:path
+                                                 ruleType:SNTRuleTypeCertificate
+                                                    error:&err],
+                        cs.leafCertificate.SHA256);
+  XCTAssertNil(err);
+
+  XCTAssertEqualObjects([SNTCommandRule identifierForPath:path
+                                                 ruleType:SNTRuleTypeSigningID
+                                                    error:&err],
+                        @"platform:com.apple.yes");
+  XCTAssertNil(err);
+}
+
+- (void)testIdentifierForPathMissingFile {
+  NSString* path = [NSTemporaryDirectory()
+      stringByAppendingPathComponent:[[NSUUID UUID] UUIDString]];
+
+  NSError* err;
+  XCTAssertNil([SNTCommandRule identifierForPath:path ruleType:SNTRuleTypeBinary error:&err]);
+  XCTAssertNotNil(err);
+  XCTAssertTrue([err.localizedDescription containsString:path]);
+
+  err = nil;
+  XCTAssertNil([SNTCommandRule identifierForPath:path ruleType:SNTRuleTypeCDHash error:&err]);
+  XCTAssertNotNil(err);
+}
+
+- (void)testIdentifierForPathUnsignedFile {
+  NSString* path = [NSTemporaryDirectory()
+      stringByAppendingPathComponent:[[NSUUID UUID] UUIDString]];
+  XCTAssertTrue([@"#!/bin/sh\n" writeToFile:path
+                                 atomically:YES
+                                   encoding:NSUTF8StringEncoding
+                                      error:nil]);
+
+  NSError* err;
+  XCTAssertNotNil([SNTCommandRule identifierForPath:path ruleType:SNTRuleTypeBinary error:&err]);
+  XCTAssertNil(err);
+
+  XCTAssertNil([SNTCommandRule identifierForPath:path ruleType:SNTRuleTypeCDHash error:&err]);
+  XCTAssertNotNil(err);
+
+  [[NSFileManager defaultManager] removeItemAtPath:path error:nil];
+}
+
+#pragma mark Rule impact
+
+- (NSArray<NSDictionary*>*)executionsSince:(NSString*)since {
+  NSString* path = [[[NSBundle bundleForClass:[self class]] resourcePath]
+      stringByAppendingPathComponent:@"Commands/testdata/santa-executions.log"];
+  NSISO8601DateFormatter* formatter = [[NSISO8601DateFormatter alloc] init];
+  NSError* err;
+  NSArray* executions =
+      [SNTCommandLog executionsInLogAtPath:path
+                                     since:[formatter dateFromString:since]
+                                     until:[formatter dateFromString:@"2027-01-01T00:00:00Z"]
+                                     error:&err];
+  XCTAssertNil(err);
+  return executions;
+}
+
+- (void)testImpactOfTeamIDBlockRule {
+  NSArray* executions = [self executionsSince:@"2026-03-01T00:00:00Z"];
+  XCTAssertEqual(executions.count, 7u);
+
+  SNTRuleImpact* impact = [SNTCommandRule impactOfRuleWithIdentifier:@"EQHXZ8M8AV"
+                                                                type:SNTRuleTypeTeamID
+                                                               state:SNTRuleStateBlock
+                                                          executions:executions];
+  XCTAssertEqual(impact.matched, 5u);
+  XCTAssertEqual(impact.changed, 3u);
+  // Already blocked as an unknown binary.
+  XCTAssertEqual(impact.unchanged, 1u);
+  // Allowed by a binary rule, which takes precedence over a team ID rule.
+  XCTAssertEqual(impact.overridden, 1u);
+  XCTAssertEqualObjects(impact.changedPaths, (@{
+                          @"/Applications/Google Chrome.app/Contents/MacOS/Google Chrome" : @2,
+                          @"/Applications/Google Chrome.app/Contents/Frameworks/"
+                          @"Google Chrome Helper" : @1,
+                        }));
+}
+
+- (void)testImpactOnlyCountsExecutionsInWindow {
+  SNTRuleImpact* impact =
+      [SNTCommandRule impactOfRuleWithIdentifier:@"EQHXZ8M8AV"
+                                            type:SNTRuleTypeTeamID
+                                           state:SNTRuleStateBlock
+                                      executions:[self executionsSince:@"2026-01-01T00:00:00Z"]];
+  XCTAssertEqual(impact.matched, 6u);
+  XCTAssertEqual(impact.changed, 4u);
+}
+
+- (void)testImpactOfBinaryAndCertificateRules {
+  NSArray* executions = [self executionsSince:@"2026-03-01T00:00:00Z"];
+  NSString* sha256 = @"4b8dc1c1bbc1dc6cf3b3e4ab8f020e12d9e32d2e47a8cb8b1bb5a4ac8bc4b4b4";
+
+  SNTRuleImpact* impact = [SNTCommandRule impactOfRuleWithIdentifier:sha256
+                                                                type:SNTRuleTypeBinary
+                                                               state:SNTRuleStateBlock
+                                                          executions:executions];
+  XCTAssertEqual(impact.matched, 1u);
+  XCTAssertEqual(impact.unchanged, 1u);
+  XCTAssertEqual(impact.changed, 0u);
+
+  impact = [SNTCommandRule impactOfRuleWithIdentifier:sha256
+                                                 type:SNTRuleTypeBinary
+                                                state:SNTRuleStateAllow
+                                           executions:executions];
+  XCTAssertEqual(impact.changed, 1u);
+  XCTAssertEqualObjects(impact.changedPaths, @{@"/Users/alice/Downloads/tool" : @1});
+
+  // Hash identifiers match regardless of case.
+  impact = [SNTCommandRule
+      impactOfRuleWithIdentifier:@"a1b2c3d4e5f60718293a4b5c6d7e8f90a1b2c3d4e5f60718293a4b5c6d7e8f90"
+                            type:SNTRuleTypeCertificate
+                           state:SNTRuleStateAllow
+                      executions:executions];
+  XCTAssertEqual(impact.matched, 1u);
+  XCTAssertEqual(impact.changed, 1u);
+}
+
+- (void)testImpactOfSigningIDRule {
+  NSArray* executions = [self executionsSince:@"2026-03-01T00:00:00Z"];
+
+  SNTRuleImpact* impact = [SNTCommandRule impactOfRuleWithIdentifier:@"platform:com.apple.ls"
+                                                                type:SNTRuleTypeSigningID
+                                                               state:SNTRuleStateBlock
+                                                          executions:executions];
+  XCTAssertEqual(impact.matched, 1u);
+  XCTAssertEqual(impact.changed, 1u);
+
+  // Signing IDs are case sensitive.
+  impact = [SNTCommandRule impactOfRuleWithIdentifier:@"platform:com.apple.LS"
+                                                 type:SNTRuleTypeSigningID
+                                                state:SNTRuleStateBlock
+                                           executions:executions];
+  XCTAssertEqual(impact.matched, 0u);
+}
+
+- (void)testImpactOfUnloggedRuleTypeIsUnsupported {
+  NSString* cdhash = @"0102030405060708090a0b0c0d0e0f1011121314";
+  XCTAssertNil([SNTCommandRule impactOfRuleWithIdentifier:cdhash
+                                                     type:SNTRuleTypeCDHash
+                                                    state:SNTRuleStateBlock
+                                               executions:@[]]);
+}
+
+@end
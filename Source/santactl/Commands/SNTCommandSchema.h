@@ -0,0 +1,30 @@
+/// Copyright 2026 North Pole Security, Inc.
+///
+/// Licensed under the Apache License, Version 2.0 (the "License");
+/// you may not use this file except in compliance with the License.
+/// You may obtain a copy of the License at
+///
+///     http://www.apache.org/licenses/LICENSE-2.0
+///
+/// Unless required by applicable law or agreed to in writing, software
+/// distributed under the License is distributed on an "AS IS" BASIS,
+/// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+/// See the License for the specific language governing permissions and
+/// limitations under the License.
+
+#import <Foundation/Foundation.h>
+
+#import "Source/santactl/SNTCommand.h"
+#import "Source/santactl/SNTCommandController.h"
+
+@interface SNTCommandSchema : SNTCommand <SNTCommandProtocol>
+
+///
+///  Return a JSON Schema describing the execution and file access event payloads, as printed by
+///  `santactl printlog`. The schema is generated from the telemetry protobuf descriptors so it
+///  always matches the fields the daemon can log. Each message type is described once under
+///  "$defs", keyed by its full protobuf name.
+///
++ (NSDictionary<NSString*, id>*)eventSchema;
+
+@end
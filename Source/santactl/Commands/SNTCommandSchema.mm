@@ -0,0 +1,169 @@
+/// Copyright 2026 North Pole Security, Inc.
+///
+/// Licensed under the Apache License, Version 2.0 (the "License");
+/// you may not use this file except in compliance with the License.
+/// You may obtain a copy of the License at
+///
+///     http://www.apache.org/licenses/LICENSE-2.0
+///
+/// Unless required by applicable law or agreed to in writing, software
+/// distributed under the License is distributed on an "AS IS" BASIS,
+/// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+/// See the License for the specific language governing permissions and
+/// limitations under the License.
+
+#import "Source/santactl/Commands/SNTCommandSchema.h"
+
+#include <string>
+
+#include "Source/common/SNTLogging.h"
+#include "Source/common/String.h"
+#include "Source/common/santa.pb.h"
+#include "google/protobuf/descriptor.h"
+
+using google::protobuf::Descriptor;
+using google::protobuf::EnumDescriptor;
+using google::protobuf::FieldDescriptor;
+namespace pbv1 = ::santa::pb::v1;
+
+static NSString* const kJSONSchemaDialect = @"https://json-schema.org/draft/2020-12/schema";
+
+static NSString* FullName(const Descriptor* desc) {
+  return santa::StringToNSString(std::string(desc->full_name()));
+}
+
+static NSString* RefForMessage(const Descriptor* desc) {
+  return [@"#/$defs/" stringByAppendingString:FullName(desc)];
+}
+
+static void AddMessageDefinition(const Descriptor* desc, NSMutableDictionary* defs);
+
+static NSDictionary* EnumSchema(const EnumDescriptor* desc) {
+  NSMutableArray* values = [NSMutableArray arrayWithCapacity:desc->value_count()];
+  for (int i = 0; i < desc->value_count(); i++) {
+    [values addObject:santa::StringToNSString(std::string(desc->value(i)->name()))];
+  }
+  return @{@"type" : @"string", @"enum" : values};
+}
+
+// Returns the schema for a single value of the field, ignoring whether it is repeated. The JSON
+// types follow the protobuf JSON mapping used by printlog, e.g. 64-bit integers are strings.
+static NSDictionary* ValueSchema(const FieldDescriptor* field, NSMutableDictionary* defs) {
+  switch (field->cpp_type()) {
+    case FieldDescriptor::CPPTYPE_INT32:
+    case FieldDescriptor::CPPTYPE_UINT32: return @{@"type" : @"integer"};
+    case FieldDescriptor::CPPTYPE_INT64: return @{@"type" : @"string", @"format" : @"int64"};
+    case FieldDescriptor::CPPTYPE_UINT64: return @{@"type" : @"string", @"format" : @"uint64"};
+    case FieldDescriptor::CPPTYPE_DOUBLE:
+    case FieldDescriptor::CPPTYPE_FLOAT: return @{@"type" : @"number"};
+    case FieldDescriptor::CPPTYPE_BOOL: return @{@"type" : @"boolean"};
+    case FieldDescriptor::CPPTYPE_ENUM: return EnumSchema(field->enum_type());
+    case FieldDescriptor::CPPTYPE_STRING:
+      if (field->type() == FieldDescriptor::TYPE_BYTES) {
+        return @{@"type" : @"string", @"contentEncoding" : @"base64"};
+      }
+      return @{@"type" : @"string"};
+    case FieldDescriptor::CPPTYPE_MESSAGE: {
+      const Descriptor* msg = field->message_type();
+      if (msg->full_name() == "google.protobuf.Timestamp") {
+        return @{@"type" : @"string", @"format" : @"date-time"};
+      } else if (msg->full_name() == "google.protobuf.Any") {
+        return @{@"type" : @"object", @"required" : @[ @"@type" ]};
+      }
+      AddMessageDefinition(msg, defs);
+      return @{@"$ref" : RefForMessage(msg)};
+    }
+  }
+  return @{};
+}
+
+static NSDictionary* FieldSchema(const FieldDescriptor* field, NSMutableDictionary* defs) {
+  if (field->is_map()) {
+    return @{
+      @"type" : @"object",
+      @"additionalProperties" : ValueSchema(field->message_type()->map_value(), defs),
+    };
+  } else if (field->is_repeated()) {
+    return @{@"type" : @"array", @"items" : ValueSchema(field, defs)};
+  }
+  return ValueSchema(field, defs);
+}
+
+static void AddMessageDefinition(const Descriptor* desc, NSMutableDictionary* defs) {
+  NSString* name = FullName(desc);
+  if (defs[name]) return;
+
+  // Insert a placeholder first so that recursive messages terminate.
+  NSMutableDictionary* properties = [NSMutableDictionary dictionary];
+  defs[name] = @{@"type" : @"object", @"properties" : properties};
+
+  for (int i = 0; i < desc->field_count(); i++) {
+    const FieldDescriptor* field = desc->field(i);
+    properties[santa::StringToNSString(std::string(field->name()))] = FieldSchema(field, defs);
+  }
+}
+
+@implementation SNTCommandSchema
+
+REGISTER_COMMAND_NAME(@"schema")
+
++ (BOOL)requiresRoot {
+  return NO;
+}
+
++ (BOOL)requiresDaemonConn {
+  return NO;
+}
+
++ (NSString*)shortHelpText {
+  return @"Prints the JSON schema of Santa's telemetry events.";
+}
+
++ (NSString*)longHelpText {
+  return @"Prints a JSON schema describing Santa's telemetry event payloads.\n"
+         @"\n"
+         @"  Usage: santactl schema events\n"
+         @"\n"
+         @"  events: Print the schema of the execution and file access events, in the\n"
+         @"          format written by the protobuf telemetry loggers and shown by\n"
+         @"          `santactl printlog`. In a SantaMessage these payloads appear under\n"
+         @"          the \"execution\" and \"file_access\" keys.";
+}
+
++ (NSDictionary<NSString*, id>*)eventSchema {
+  NSMutableDictionary* defs = [NSMutableDictionary dictionary];
+  AddMessageDefinition(pbv1::Execution::descriptor(), defs);
+  AddMessageDefinition(pbv1::FileAccess::descriptor(), defs);
+
+  return @{
+    @"$schema" : kJSONSchemaDialect,
+    @"title" : @"Santa event payloads",
+    @"oneOf" : @[
+      @{@"$ref" : RefForMessage(pbv1::Execution::descriptor())},
+      @{@"$ref" : RefForMessage(pbv1::FileAccess::descriptor())},
+    ],
+    @"$defs" : defs,
+  };
+}
+
+- (void)runWithArguments:(NSArray*)arguments {
+  if (arguments.count != 1 || ![arguments.firstObject isEqualToString:@"events"]) {
+    [self printErrorUsageAndExit:@"Expected a single argument: events"];
+  }
+
+  NSError* err;
+  NSData* json = [NSJSONSerialization
+      dataWithJSONObject:[[self class] eventSchema]
+                 options:NSJSONWritingPrettyPrinted | NSJSONWritingSortedKeys |
+                         NSJSONWritingWithoutEscapingSlashes
+                   error:&err];
+  if (!json) {
+    TEE_LOGE(@"Failed to encode schema: %@", err.localizedDescription);
+    exit(EXIT_FAILURE);
+  }
+
+  printf("%s\n", [[NSString alloc] initWithData:json encoding:NSUTF8StringEncoding].UTF8String);
+  exit(EXIT_SUCCESS);
+}
+
+@end
@@ -0,0 +1,87 @@
+/// Copyright 2026 North Pole Security, Inc.
+///
+/// Licensed under the Apache License, Version 2.0 (the "License");
+/// you may not use this file except in compliance with the License.
+/// You may obtain a copy of the License at
+///
+///     http://www.apache.org/licenses/LICENSE-2.0
+///
+/// Unless required by applicable law or agreed to in writing, software
+/// distributed under the License is distributed on an "AS IS" BASIS,
+/// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+/// See the License for the specific language governing permissions and
+/// limitations under the License.
+
+#import <XCTest/XCTest.h>
+
+#include <string>
+
+#include "Source/common/santa.pb.h"
+#import "Source/santactl/Commands/SNTCommandSchema.h"
+#include "google/protobuf/descriptor.h"
+
+using google::protobuf::Descriptor;
+namespace pbv1 = ::santa::pb::v1;
+
+@interface SNTCommandSchemaTest : XCTestCase
+@property NSDictionary* schema;
+@end
+
+@implementation SNTCommandSchemaTest
+
+- (void)setUp {
+  self.schema = [SNTCommandSchema eventSchema];
+}
+
+- (NSDictionary*)definitionFor:(const Descriptor*)desc {
+  return self.schema[@"$defs"][@(std::string(desc->full_name()).c_str())];
+}
+
+// Walks every message reachable from the event payloads and asserts that each field in the
+// descriptor has a property in the schema, so a field added to santa.proto can't be missed.
+- (void)assertAllFieldsOf:(const Descriptor*)desc visited:(NSMutableSet*)visited {
+  NSString* name = @(std::string(desc->full_name()).c_str());
+  if ([visited containsObject:name] || [name hasPrefix:@"google.protobuf."]) return;
+  [visited addObject:name];
+
+  NSDictionary* def = [self definitionFor:desc];
+  XCTAssertNotNil(def, @"Missing definition for %@", name);
+  for (int i = 0; i < desc->field_count(); i++) {
+    const google::protobuf::FieldDescriptor* field = desc->field(i);
+    NSString* fieldName = @(std::string(field->name()).c_str());
+    XCTAssertNotNil(def[@"properties"][fieldName], @"%@ is missing field %@", name, fieldName);
+    if (field->message_type() && !field->is_map()) {
+      [self assertAllFieldsOf:field->message_type() visited:visited];
+    }
+  }
+}
+
+- (void)testSchemaIncludesAllEventFields {
+  NSMutableSet* visited = [NSMutableSet set];
+  [self assertAllFieldsOf:pbv1::Execution::descriptor() visited:visited];
+  [self assertAllFieldsOf:pbv1::FileAccess::descriptor() visited:visited];
+  XCTAssertTrue([visited containsObject:@"santa.pb.v1.ProcessInfo"]);
+  XCTAssertTrue([visited containsObject:@"santa.pb.v1.FileInfo"]);
+}
+
+- (void)testTopLevelPayloads {
+  XCTAssertEqualObjects(self.schema[@"$schema"], @"https://json-schema.org/draft/2020-12/schema");
+  XCTAssertEqualObjects([self.schema[@"oneOf"] valueForKey:@"$ref"], (@[
+                          @"#/$defs/santa.pb.v1.Execution", @"#/$defs/santa.pb.v1.FileAccess"
+                        ]));
+}
+
+- (void)testFieldTypes {
+  NSDictionary* exec = [self definitionFor:pbv1::Execution::descriptor()][@"properties"];
+  XCTAssertEqualObjects(exec[@"target"], @{@"$ref" : @"#/$defs/santa.pb.v1.ProcessInfo"});
+  XCTAssertEqualObjects(exec[@"fd_list_truncated"], @{@"type" : @"boolean"});
+  XCTAssertEqualObjects(exec[@"args"][@"type"], @"array");
+  XCTAssertEqualObjects(exec[@"args"][@"items"][@"contentEncoding"], @"base64");
+  XCTAssertEqualObjects(exec[@"decision"][@"type"], @"string");
+  XCTAssertTrue([exec[@"decision"][@"enum"] containsObject:@"DECISION_DENY"]);
+
+  NSDictionary* proc = [self definitionFor:pbv1::ProcessInfo::descriptor()][@"properties"];
+  XCTAssertEqualObjects(proc[@"start_time"], (@{@"type" : @"string", @"format" : @"date-time"}));
+}
+
+@end
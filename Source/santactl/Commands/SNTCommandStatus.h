@@ -0,0 +1,29 @@
+/// Copyright 2026 North Pole Security, Inc.
+///
+/// Licensed under the Apache License, Version 2.0 (the "License");
+/// you may not use this file except in compliance with the License.
+/// You may obtain a copy of the License at
+///
+///     http://www.apache.org/licenses/LICENSE-2.0
+///
+/// Unless required by applicable law or agreed to in writing, software
+/// distributed under the License is distributed on an "AS IS" BASIS,
+/// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+/// See the License for the specific language governing permissions and
+/// limitations under the License.
+
+
+#import <Foundation/Foundation.h>
+
+#import "Source/santactl/SNTCommand.h"
+
+@interface SNTCommandStatus : SNTCommand <SNTCommandProtocol>
+
+///
+///  Query santad and print the status, as JSON if arguments contains --json. Subsystems that
+///  do not respond are listed under degraded_subsystems and their fields keep placeholder
+///  values, so the JSON output always has the same structure.
+///
+- (void)printStatusWithArguments:(NSArray*)arguments;
+
+@end
@@ -18,9 +18,10 @@
 #import "Source/common/MOLXPCConnection.h"
 #import "Source/common/SNTCommonEnums.h"
 #import "Source/common/SNTConfigurator.h"
+#import "Source/common/SNTSyncConstants.h"
 #import "Source/common/SNTXPCControlInterface.h"
 #include "Source/common/faa/WatchItems.h"
-#import "Source/santactl/SNTCommand.h"
+#import "Source/santactl/Commands/SNTCommandStatus.h"
 #import "Source/santactl/SNTCommandController.h"
 
 NSString* StartupOptionToString(SNTDeviceManagerStartupPreferences pref) {
@@ -58,9 +59,6 @@ NSString* FormatInterval(NSUInteger seconds) {
   return [formatter stringFromTimeInterval:seconds];
 }
 
-@interface SNTCommandStatus : SNTCommand <SNTCommandProtocol>
-@end
-
 @implementation SNTCommandStatus
 
 REGISTER_COMMAND_NAME(@"status")
@@ -83,8 +81,17 @@ REGISTER_COMMAND_NAME(@"status")
 }
 
 - (void)runWithArguments:(NSArray*)arguments {
+  [self printStatusWithArguments:arguments];
+  exit(0);
+}
+
+- (void)printStatusWithArguments:(NSArray*)arguments {
   id<SNTDaemonControlXPC> rop = [self.daemonConn synchronousRemoteObjectProxy];
 
+  // Subsystems that did not answer. Their fields are still reported, with
+  // placeholder values, so that consumers always see the same structure.
+  NSMutableArray<NSString*>* degradedSubsystems = [NSMutableArray array];
+
   // Daemon status
   __block NSString* clientMode;
   __block uint64_t cpuEvents, ramEvents;
@@ -119,11 +126,20 @@ REGISTER_COMMAND_NAME(@"status")
 
   SNTConfigurator* configurator = [SNTConfigurator configurator];
 
+  __block BOOL fullDiskAccess = NO;
+  __block BOOL fullDiskAccessKnown = NO;
+  [rop fullDiskAccessGranted:^(BOOL granted) {
+    fullDiskAccess = granted;
+    fullDiskAccessKnown = YES;
+  }];
+
   // Cache status
   __block uint64_t rootCacheCount = -1, nonRootCacheCount = -1;
+  __block BOOL cacheCountsKnown = NO;
   [rop cacheCounts:^(uint64_t rootCache, uint64_t nonRootCache) {
     rootCacheCount = rootCache;
     nonRootCacheCount = nonRootCache;
+    cacheCountsKnown = YES;
   }];
 
   // Database counts
@@ -139,13 +155,17 @@ REGISTER_COMMAND_NAME(@"status")
       .networkFlow = -1,
       .signals = -1,
   };
+  __block BOOL ruleCountsKnown = NO;
   [rop databaseRuleCounts:^(struct RuleCounts counts) {
     ruleCounts = counts;
+    ruleCountsKnown = YES;
   }];
 
   __block int64_t eventCount = -1;
+  __block BOOL eventCountKnown = NO;
   [rop databaseEventCount:^(int64_t count) {
     eventCount = count;
+    eventCountKnown = YES;
   }];
 
   // Static rule count
@@ -167,8 +187,10 @@ REGISTER_COMMAND_NAME(@"status")
 
   // Sync status
   __block NSDate* fullSyncLastSuccess;
+  __block BOOL syncStateKnown = NO;
   [rop fullSyncLastSuccess:^(NSDate* date) {
     fullSyncLastSuccess = date;
+    syncStateKnown = YES;
   }];
 
   __block NSDate* ruleSyncLastSuccess;
@@ -219,6 +241,51 @@ REGISTER_COMMAND_NAME(@"status")
     dispatch_semaphore_wait(sema, dispatch_time(DISPATCH_TIME_NOW, 2 * NSEC_PER_SEC));
   }
 
+  // Like the push notification status, this is answered by santasyncservice.
+  __block NSDictionary* circuitBreaker;
+  if ([configurator syncBaseURL]) {
+    dispatch_semaphore_t sema = dispatch_semaphore_create(0);
+    dispatch_async(dispatch_get_global_queue(QOS_CLASS_USER_INITIATED, 0), ^{
+      [rop syncCircuitBreakerStatus:^(NSDictionary* status) {
+        circuitBreaker = status;
+        dispatch_semaphore_signal(sema);
+      }];
+    });
+    dispatch_semaphore_wait(sema, dispatch_time(DISPATCH_TIME_NOW, 2 * NSEC_PER_SEC));
+  }
+  NSString* circuitBreakerState = circuitBreaker[kSyncCircuitBreakerState] ?: @"Unknown";
+
+  // Rule sources are scheduled by santasyncservice independently of the sync server.
+  __block NSArray<NSDictionary*>* ruleSourcesStatus;
+  if (configurator.ruleSources.count) {
+    dispatch_semaphore_t sema = dispatch_semaphore_create(0);
+    dispatch_async(dispatch_get_global_queue(QOS_CLASS_USER_INITIATED, 0), ^{
+      [rop ruleSourcesStatus:^(NSArray<NSDictionary*>* status) {
+        ruleSourcesStatus = status;
+        dispatch_semaphore_signal(sema);
+      }];
+    });
+    dispatch_semaphore_wait(sema, dispatch_time(DISPATCH_TIME_NOW, 2 * NSEC_PER_SEC));
+  }
+  NSNumber* circuitBreakerRetryAt = circuitBreaker[kSyncCircuitBreakerRetryAt];
+
+  // The push server certificate expiry is only known once the NATS client has connected.
+  __block NSDate* pushServerCertificateExpiry;
+  if ([pushNotifications isEqualToString:@"NPS Push Service"]) {
+    dispatch_semaphore_t sema = dispatch_semaphore_create(0);
+    dispatch_async(dispatch_get_global_queue(QOS_CLASS_USER_INITIATED, 0), ^{
+      [rop pushServerCertificateExpiry:^(NSDate* notAfter) {
+        pushServerCertificateExpiry = notAfter;
+        dispatch_semaphore_signal(sema);
+      }];
+    });
+    dispatch_semaphore_wait(sema, dispatch_time(DISPATCH_TIME_NOW, 2 * NSEC_PER_SEC));
+  }
+  NSUInteger pushCertWarningDays = configurator.pushServerCertificateExpiryWarningDays;
+  BOOL pushServerCertificateExpiresSoon =
+      pushServerCertificateExpiry && pushCertWarningDays > 0 &&
+      [pushServerCertificateExpiry timeIntervalSinceNow] < pushCertWarningDays * 86400;
+
   __block BOOL enableBundles = NO;
   if ([[SNTConfigurator configurator] syncBaseURL]) {
     [rop enableBundles:^(BOOL response) {
@@ -237,9 +304,11 @@ REGISTER_COMMAND_NAME(@"status")
   __block NSString* watchItemsConfigPath = nil;
   __block NSTimeInterval watchItemsLastUpdateEpoch = 0;
   __block santa::WatchItems::DataSource watchItemsDataSource;
+  __block BOOL watchItemsStateKnown = NO;
   [rop watchItemsState:^(BOOL enabled, uint64_t ruleCount, NSString* policyVersion,
                          santa::WatchItems::DataSource dataSource, NSString* configPath,
                          NSTimeInterval lastUpdateEpoch) {
+    watchItemsStateKnown = YES;
     watchItemsEnabled = enabled;
     if (enabled) {
       watchItemsRuleCount = ruleCount;
@@ -311,6 +380,18 @@ REGISTER_COMMAND_NAME(@"status")
   NSString* fullSyncLastSuccessStr = [dateFormatter stringFromDate:fullSyncLastSuccess] ?: @"Never";
   NSString* ruleSyncLastSuccessStr =
       [dateFormatter stringFromDate:ruleSyncLastSuccess] ?: fullSyncLastSuccessStr;
+  NSString* circuitBreakerRetryAtStr;
+  if (circuitBreakerRetryAt) {
+    NSDate* retryAt = [NSDate dateWithTimeIntervalSince1970:circuitBreakerRetryAt.doubleValue];
+    circuitBreakerRetryAtStr = [dateFormatter stringFromDate:retryAt];
+  }
+  NSString* pushServerCertificateExpiryStr =
+      [dateFormatter stringFromDate:pushServerCertificateExpiry];
+
+  NSString* (^EpochToString)(NSNumber*) = ^NSString*(NSNumber* epoch) {
+    if (!epoch) return nil;
+    return [dateFormatter stringFromDate:[NSDate dateWithTimeIntervalSince1970:epoch.doubleValue]];
+  };
 
   NSString* watchItemsLastUpdateStr =
       [dateFormatter
@@ -355,6 +436,34 @@ REGISTER_COMMAND_NAME(@"status")
                                    componentsJoinedByString:@", "]
                              : @"None (all blocked)";
 
+  NSString* configHash = configurator.configHash;
+
+  if (!clientMode) [degradedSubsystems addObject:@"daemon"];
+  if (!fullDiskAccessKnown) [degradedSubsystems addObject:@"full_disk_access"];
+  if (!cacheCountsKnown) [degradedSubsystems addObject:@"cache"];
+  if (!ruleCountsKnown || !eventCountKnown) [degradedSubsystems addObject:@"database"];
+  if (!watchItemsStateKnown) [degradedSubsystems addObject:@"watch_items"];
+  if (syncURLStr.length) {
+    if (!syncStateKnown) [degradedSubsystems addObject:@"sync"];
+    if ([pushNotifications isEqualToString:@"Unknown"]) {
+      [degradedSubsystems addObject:@"push_notifications"];
+    }
+    if ([circuitBreakerState isEqualToString:@"open"] ||
+        [circuitBreakerState isEqualToString:@"half-open"]) {
+      [degradedSubsystems addObject:@"sync_circuit_breaker"];
+    }
+    if (pushServerCertificateExpiresSoon) {
+      [degradedSubsystems addObject:@"push_server_certificate"];
+    }
+  }
+  if (configurator.ruleSources.count) {
+    BOOL ruleSourceFailing = NO;
+    for (NSDictionary* source in ruleSourcesStatus) {
+      if (source[kRuleSourceStatusLastError]) ruleSourceFailing = YES;
+    }
+    if (!ruleSourcesStatus || ruleSourceFailing) [degradedSubsystems addObject:@"rule_sources"];
+  }
+
   NSString* (^ActionToString)(SNTRemovableMediaAction) =
       ^NSString*(SNTRemovableMediaAction action) {
         switch (action) {
@@ -368,7 +477,9 @@ REGISTER_COMMAND_NAME(@"status")
     NSMutableDictionary* stats = [@{
       @"daemon" : @{
         @"mode" : clientMode ?: @"null",
-        @"log_type" : eventLogType,
+        @"full_disk_access" : (fullDiskAccessKnown ? @(fullDiskAccess) : @"null"),
+        @"config_hash" : configHash ?: @"null",
+        @"log_type" : eventLogType ?: @"null",
         @"file_logging" : @(fileLogging),
         @"watchdog_cpu_events" : @(cpuEvents),
         @"watchdog_ram_events" : @(ramEvents),
@@ -429,10 +540,16 @@ REGISTER_COMMAND_NAME(@"status")
         @"last_successful_rule" : ruleSyncLastSuccessStr ?: @"null",
         @"push_notifications" : pushNotifications,
         @"push_server" : (pushServerAddress ?: @"null"),
+        @"push_server_certificate_expiry" : pushServerCertificateExpiryStr ?: @"null",
         @"bundle_scanning" : @(enableBundles),
         @"events_pending_upload" : @(eventCount),
         @"execution_rules_hash" : executionRulesHash ?: @"null",
         @"full_sync_interval_seconds" : @(fullSyncInterval),
+        @"circuit_breaker" : @{
+          @"state" : circuitBreakerState,
+          @"consecutive_failures" : circuitBreaker[kSyncCircuitBreakerConsecutiveFailures] ?: @(0),
+          @"retry_at" : circuitBreakerRetryAtStr ?: @"null",
+        },
       } mutableCopy];
 
       if (configurator.fcmEnabled || (isSyncV2Enabled && configurator.enablePushNotifications)) {
@@ -452,6 +569,24 @@ REGISTER_COMMAND_NAME(@"status")
       };
     }
 
+    if (configurator.ruleSources.count) {
+      NSMutableArray* ruleSources = [NSMutableArray array];
+      for (NSDictionary* source in ruleSourcesStatus) {
+        [ruleSources addObject:@{
+          @"name" : source[kRuleSourceStatusName] ?: @"null",
+          @"url" : source[kRuleSourceStatusURL] ?: @"null",
+          @"precedence" : source[kRuleSourceStatusPrecedence] ?: @(0),
+          @"sync_interval_seconds" : source[kRuleSourceStatusSyncInterval] ?: @(0),
+          @"last_attempt" : EpochToString(source[kRuleSourceStatusLastAttempt]) ?: @"null",
+          @"last_success" : EpochToString(source[kRuleSourceStatusLastSuccess]) ?: @"null",
+          @"next_sync" : EpochToString(source[kRuleSourceStatusNextSync]) ?: @"null",
+          @"last_error" : source[kRuleSourceStatusLastError] ?: @"null",
+          @"rules_received" : source[kRuleSourceStatusRulesReceived] ?: @(0),
+        }];
+      }
+      stats[@"rule_sources"] = ruleSources;
+    }
+
     if (watchItemsEnabled) {
       stats[@"watch_items"] = [@{
         @"enabled" : @(watchItemsEnabled),
@@ -505,14 +640,23 @@ REGISTER_COMMAND_NAME(@"status")
     }
     stats[@"temporary_admin_mode"] = temporaryAdminMode;
 
+    stats[@"degraded_subsystems"] = degradedSubsystems;
+
     NSData* statsData = [NSJSONSerialization dataWithJSONObject:stats
                                                         options:NSJSONWritingPrettyPrinted
                                                           error:nil];
     NSString* statsStr = [[NSString alloc] initWithData:statsData encoding:NSUTF8StringEncoding];
     printf("%s\n", [statsStr UTF8String]);
   } else {
+    if (degradedSubsystems.count) {
+      printf(">>> Degraded: %s did not respond\n",
+             [[degradedSubsystems componentsJoinedByString:@", "] UTF8String]);
+    }
     printf(">>> Daemon Info\n");
-    printf("  %-40s | %s\n", "Mode", [clientMode UTF8String]);
+    printf("  %-40s | %s\n", "Mode", [(clientMode ?: @"Unknown") UTF8String]);
+    printf("  %-40s | %s\n", "Full Disk Access",
+           !fullDiskAccessKnown ? "Unknown" : (fullDiskAccess ? "Yes" : "No"));
+    printf("  %-40s | %s\n", "Config Hash", [configHash UTF8String]);
     printf("  %-40s | %s\n", "Log Type", [eventLogType UTF8String]);
     printf("  %-40s | %s\n", "File Logging", (fileLogging ? "Yes" : "No"));
     printf("  %-40s | %s\n", "Removable Media Action",
@@ -633,6 +777,21 @@ REGISTER_COMMAND_NAME(@"status")
             [NSString stringWithFormat:@"NPS Push Service (%@)", pushServerAddress];
       }
       printf("  %-40s | %s\n", "Push Notifications", [pushNotificationsOutput UTF8String]);
+      if (pushServerCertificateExpiryStr) {
+        NSString* pushCertOutput = pushServerCertificateExpiryStr;
+        if (pushServerCertificateExpiresSoon) {
+          pushCertOutput =
+              [NSString stringWithFormat:@"%@ (expires soon)", pushServerCertificateExpiryStr];
+        }
+        printf("  %-40s | %s\n", "Push Server Certificate Expiry", [pushCertOutput UTF8String]);
+      }
+
+      NSString* circuitBreakerOutput = circuitBreakerState;
+      if (circuitBreakerRetryAtStr) {
+        circuitBreakerOutput = [NSString
+            stringWithFormat:@"%@ (retry at %@)", circuitBreakerState, circuitBreakerRetryAtStr];
+      }
+      printf("  %-40s | %s\n", "Sync Circuit Breaker", [circuitBreakerOutput UTF8String]);
 
       printf("  %-40s | %s\n", "Bundle Scanning", (enableBundles ? "Yes" : "No"));
       printf("  %-40s | %lld\n", "Events Pending Upload", eventCount);
@@ -647,6 +806,26 @@ REGISTER_COMMAND_NAME(@"status")
       }
     }
 
+    if (configurator.ruleSources.count) {
+      printf(">>> Rule Sources\n");
+      if (!ruleSourcesStatus) printf("  %-40s | %s\n", "Status", "Unknown");
+      for (NSDictionary* source in ruleSourcesStatus) {
+        NSString* lastSuccess = EpochToString(source[kRuleSourceStatusLastSuccess]) ?: @"Never";
+        NSString* summary;
+        if (source[kRuleSourceStatusLastError]) {
+          summary = [NSString stringWithFormat:@"Failing: %@ (last success %@)",
+                                               source[kRuleSourceStatusLastError], lastSuccess];
+        } else if (source[kRuleSourceStatusLastSuccess]) {
+          summary = [NSString stringWithFormat:@"%@ rules at %@",
+                                               source[kRuleSourceStatusRulesReceived], lastSuccess];
+        } else {
+          summary = @"Pending";
+        }
+        printf("  %-40s | %s\n", [source[kRuleSourceStatusName] UTF8String],
+               [summary UTF8String]);
+      }
+    }
+
     printf(">>> Metrics\n");
     printf("  %-40s | %s\n", "Enabled", exportMetrics ? "Yes" : "No");
     if (exportMetrics) {
@@ -654,8 +833,6 @@ REGISTER_COMMAND_NAME(@"status")
       printf("  %-40s | %lu\n", "Export Interval (seconds)", metricExportInterval);
     }
   }
-
-  exit(0);
 }
 
 @end
@@ -0,0 +1,279 @@
+/// Copyright 2026 North Pole Security, Inc.
+///
+/// Licensed under the Apache License, Version 2.0 (the "License");
+/// you may not use this file except in compliance with the License.
+/// You may obtain a copy of the License at
+///
+///     http://www.apache.org/licenses/LICENSE-2.0
+///
+/// Unless required by applicable law or agreed to in writing, software
+/// distributed under the License is distributed on an "AS IS" BASIS,
+/// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+/// See the License for the specific language governing permissions and
+/// limitations under the License.
+
+
+#import <Foundation/Foundation.h>
+#import <OCMock/OCMock.h>
+#import <XCTest/XCTest.h>
+
+#import "Source/common/MOLXPCConnection.h"
+#import "Source/common/SNTCommonEnums.h"
+#import "Source/common/SNTConfigurator.h"
+#import "Source/common/SNTRuleSource.h"
+#import "Source/common/SNTSyncConstants.h"
+#import "Source/common/SNTXPCControlInterface.h"
+#include "Source/common/faa/WatchItems.h"
+#import "Source/santactl/Commands/SNTCommandStatus.h"
+
+@interface SNTCommandStatusTest : XCTestCase
+@property id mockConfigurator;
+@property id mockDaemon;
+@property SNTCommandStatus* command;
+@property NSDictionary* circuitBreakerStatus;
+@property SNTPushNotificationStatus pushStatus;
+@end
+
+@implementation SNTCommandStatusTest
+
+- (void)setUp {
+  self.mockConfigurator = OCMClassMock([SNTConfigurator class]);
+  OCMStub([self.mockConfigurator configurator]).andReturn(self.mockConfigurator);
+  OCMStub([self.mockConfigurator syncBaseURL])
+      .andReturn([NSURL URLWithString:@"https://sync.example.com/"]);
+  OCMStub([self.mockConfigurator eventLogTypeRaw]).andReturn(@"file");
+  OCMStub([self.mockConfigurator configHash]).andReturn(@"c0ffee");
+
+  self.mockDaemon = OCMProtocolMock(@protocol(SNTDaemonControlXPC));
+  id mockConn = OCMClassMock([MOLXPCConnection class]);
+  OCMStub([mockConn synchronousRemoteObjectProxy]).andReturn(self.mockDaemon);
+  self.command = [[SNTCommandStatus alloc] initWithDaemonConnection:mockConn];
+
+  self.circuitBreakerStatus = @{
+    kSyncCircuitBreakerState : @"closed",
+    kSyncCircuitBreakerConsecutiveFailures : @0,
+  };
+  self.pushStatus = SNTPushNotificationStatusDisabled;
+}
+
+- (void)tearDown {
+  [self.mockConfigurator stopMocking];
+}
+
+- (void)stubHealthyDaemon {
+  id rop = self.mockDaemon;
+  struct RuleCounts counts = {.binary = 1, .certificate = 2, .teamID = 3, .signingID = 4};
+  santa::WatchItems::DataSource dataSource = santa::WatchItems::DataSource::kDatabase;
+
+  OCMStub([rop temporaryMonitorModeSecondsRemaining:([OCMArg
+                                                        invokeBlockWithArgs:[NSNull null], nil])]);
+  OCMStub([rop clientMode:([OCMArg invokeBlockWithArgs:@(SNTClientModeLockdown), nil])]);
+  OCMStub([rop watchdogInfo:([OCMArg invokeBlockWithArgs:@(1ULL), @(2ULL), @(0.5), @(1.5), nil])]);
+  OCMStub([rop fullDiskAccessGranted:([OCMArg invokeBlockWithArgs:@YES, nil])]);
+  OCMStub([rop cacheCounts:([OCMArg invokeBlockWithArgs:@(10ULL), @(20ULL), nil])]);
+  OCMStub([rop databaseRuleCounts:([OCMArg invokeBlockWithArgs:OCMOCK_VALUE(counts), nil])]);
+  OCMStub([rop databaseEventCount:([OCMArg invokeBlockWithArgs:@(5LL), nil])]);
+  OCMStub([rop staticRuleCount:([OCMArg invokeBlockWithArgs:@(0LL), nil])]);
+  OCMStub([rop databaseRulesHash:([OCMArg invokeBlockWithArgs:@"exec", @"faa", @"nf", @"sig",
+                                                              nil])]);
+  OCMStub([rop fullSyncLastSuccess:([OCMArg invokeBlockWithArgs:[NSDate date], nil])]);
+  OCMStub([rop ruleSyncLastSuccess:([OCMArg invokeBlockWithArgs:[NSDate date], nil])]);
+  OCMStub([rop syncTypeRequired:([OCMArg invokeBlockWithArgs:@(SNTSyncTypeNormal), nil])]);
+  OCMStub([rop pushNotificationStatus:([OCMArg invokeBlockWithArgs:@(self.pushStatus), nil])]);
+  OCMStub([rop syncCircuitBreakerStatus:([OCMArg
+                                             invokeBlockWithArgs:self.circuitBreakerStatus, nil])]);
+  OCMStub([rop enableBundles:([OCMArg invokeBlockWithArgs:@NO, nil])]);
+  OCMStub([rop enableTransitiveRules:([OCMArg invokeBlockWithArgs:@NO, nil])]);
+  OCMStub([rop watchItemsState:([OCMArg invokeBlockWithArgs:@NO, @(0ULL), [NSNull null],
+                                                            OCMOCK_VALUE(dataSource),
+                                                            [NSNull null], @(0.0), nil])]);
+  OCMStub([rop removableMediaAction:([OCMArg invokeBlockWithArgs:@(SNTRemovableMediaActionAllow),
+                                                                 nil])]);
+  OCMStub([rop removableMediaRemountFlags:([OCMArg invokeBlockWithArgs:@[], nil])]);
+  OCMStub([rop encryptedRemovableMediaAction:([OCMArg invokeBlockWithArgs:
+                                                          @(SNTRemovableMediaActionAllow),
+                                                          nil])]);
+  OCMStub([rop encryptedRemovableMediaRemountFlags:([OCMArg invokeBlockWithArgs:@[], nil])]);
+  OCMStub([rop isSyncV2Enabled:([OCMArg invokeBlockWithArgs:@NO, nil])]);
+  OCMStub([rop checkTemporaryAdminModeAvailable:([OCMArg invokeBlockWithArgs:@NO, @NO, nil])]);
+  OCMStub([rop temporaryAdminModeSecondsRemaining:([OCMArg
+                                                      invokeBlockWithArgs:[NSNull null], nil])]);
+  OCMStub([rop fullSyncInterval:([OCMArg invokeBlockWithArgs:@(600UL), nil])]);
+  OCMStub([rop pushNotificationsFullSyncInterval:([OCMArg invokeBlockWithArgs:@(0UL), nil])]);
+  OCMStub([rop telemetryExportConfigured:([OCMArg invokeBlockWithArgs:@NO, nil])]);
+}
+
+- (NSDictionary*)jsonStatus {
+  NSString* outputPath = [NSTemporaryDirectory()
+      stringByAppendingPathComponent:[NSUUID UUID].UUIDString];
+
+  // redirect stdout
+  int fd = open([outputPath UTF8String], O_TRUNC | O_WRONLY | O_CREAT, 0600);
+  int saved_stdout = dup(fileno(stdout));
+  dup2(fd, fileno(stdout));
+
+  [self.command printStatusWithArguments:@[ @"--json" ]];
+
+  // restore stdout
+  fflush(stdout);
+  dup2(saved_stdout, fileno(stdout));
+  close(fd);
+
+  NSData* output = [NSData dataWithContentsOfFile:outputPath];
+  [[NSFileManager defaultManager] removeItemAtPath:outputPath error:nil];
+  XCTAssertNotNil(output);
+  return [NSJSONSerialization JSONObjectWithData:output options:0 error:nil];
+}
+
+- (void)assertAllFieldsPresent:(NSDictionary*)status {
+  XCTAssertNotNil(status, @"status output is not valid JSON");
+
+  for (NSString* section in @[
+         @"daemon", @"cache", @"transitive_allowlisting", @"rule_types", @"sync", @"watch_items",
+         @"metrics", @"telemetry", @"temporary_admin_mode", @"degraded_subsystems"
+       ]) {
+    XCTAssertNotNil(status[section], @"missing section %@", section);
+  }
+
+  for (NSString* key in @[
+         @"mode", @"full_disk_access", @"config_hash", @"log_type", @"static_rules",
+         @"watchdog_cpu_events"
+       ]) {
+    XCTAssertNotNil(status[@"daemon"][key], @"missing daemon.%@", key);
+  }
+
+  for (NSString* key in @[
+         @"binary_rules", @"certificate_rules", @"teamid_rules", @"signingid_rules",
+         @"cdhash_rules"
+       ]) {
+    XCTAssertNotNil(status[@"rule_types"][key], @"missing rule_types.%@", key);
+  }
+
+  for (NSString* key in @[
+         @"server", @"last_successful_full", @"last_successful_rule", @"push_notifications",
+         @"events_pending_upload", @"execution_rules_hash", @"full_sync_interval_seconds",
+         @"circuit_breaker"
+       ]) {
+    XCTAssertNotNil(status[@"sync"][key], @"missing sync.%@", key);
+  }
+}
+
+- (void)testJSONStatusContainsAllFields {
+  [self stubHealthyDaemon];
+
+  NSDictionary* status = [self jsonStatus];
+  [self assertAllFieldsPresent:status];
+
+  XCTAssertEqualObjects(status[@"daemon"][@"mode"], @"Lockdown");
+  XCTAssertEqualObjects(status[@"daemon"][@"full_disk_access"], @YES);
+  XCTAssertEqualObjects(status[@"daemon"][@"config_hash"], @"c0ffee");
+  XCTAssertEqualObjects(status[@"rule_types"][@"signingid_rules"], @4);
+  XCTAssertEqualObjects(status[@"sync"][@"push_notifications"], @"Disabled");
+  XCTAssertEqualObjects(status[@"sync"][@"events_pending_upload"], @5);
+  XCTAssertEqualObjects(status[@"sync"][@"circuit_breaker"][@"state"], @"closed");
+  XCTAssertEqualObjects(status[@"sync"][@"circuit_breaker"][@"retry_at"], @"null");
+  XCTAssertEqualObjects(status[@"degraded_subsystems"], @[]);
+}
+
+- (void)testJSONStatusFlagsOpenCircuitBreaker {
+  self.circuitBreakerStatus = @{
+    kSyncCircuitBreakerState : @"open",
+    kSyncCircuitBreakerConsecutiveFailures : @10,
+    kSyncCircuitBreakerRetryAt : @([[NSDate date] timeIntervalSince1970] + 300),
+  };
+  [self stubHealthyDaemon];
+
+  NSDictionary* status = [self jsonStatus];
+  [self assertAllFieldsPresent:status];
+
+  NSDictionary* circuitBreaker = status[@"sync"][@"circuit_breaker"];
+  XCTAssertEqualObjects(circuitBreaker[@"state"], @"open");
+  XCTAssertEqualObjects(circuitBreaker[@"consecutive_failures"], @10);
+  XCTAssertNotEqualObjects(circuitBreaker[@"retry_at"], @"null");
+  XCTAssertEqualObjects(status[@"degraded_subsystems"], @[ @"sync_circuit_breaker" ]);
+}
+
+- (void)testJSONStatusFlagsExpiringPushServerCertificate {
+  OCMStub([self.mockConfigurator pushServerCertificateExpiryWarningDays]).andReturn(30);
+  OCMStub([self.mockDaemon
+      pushNotificationServerAddress:([OCMArg invokeBlockWithArgs:@"tls://push.example.com:443",
+                                                                 nil])]);
+  NSDate* notAfter = [NSDate dateWithTimeIntervalSinceNow:5 * 86400];
+  OCMStub([self.mockDaemon pushServerCertificateExpiry:([OCMArg invokeBlockWithArgs:notAfter,
+                                                                                    nil])]);
+  self.pushStatus = SNTPushNotificationStatusConnectedNATS;
+  [self stubHealthyDaemon];
+
+  NSDictionary* status = [self jsonStatus];
+  [self assertAllFieldsPresent:status];
+
+  XCTAssertEqualObjects(status[@"sync"][@"push_notifications"], @"NPS Push Service");
+  XCTAssertNotEqualObjects(status[@"sync"][@"push_server_certificate_expiry"], @"null");
+  XCTAssertEqualObjects(status[@"degraded_subsystems"], @[ @"push_server_certificate" ]);
+}
+
+- (void)testJSONStatusFlagsDegradedSubsystems {
+  // Only the cache answers; every other request goes unanswered, as it would
+  // if santad or the sync service were wedged.
+  OCMStub([self.mockDaemon cacheCounts:([OCMArg invokeBlockWithArgs:@(1ULL), @(2ULL), nil])]);
+
+  NSDictionary* status = [self jsonStatus];
+  [self assertAllFieldsPresent:status];
+
+  XCTAssertEqualObjects(status[@"daemon"][@"mode"], @"null");
+  XCTAssertEqualObjects(status[@"daemon"][@"full_disk_access"], @"null");
+  XCTAssertEqualObjects(status[@"sync"][@"push_notifications"], @"Unknown");
+
+  NSArray* want = @[
+    @"daemon", @"full_disk_access", @"database", @"watch_items", @"sync", @"push_notifications"
+  ];
+  XCTAssertEqualObjects(status[@"degraded_subsystems"], want);
+}
+
+- (void)testJSONStatusReportsRuleSources {
+  NSURL* url = [NSURL URLWithString:@"https://rules.example.com/"];
+  OCMStub([self.mockConfigurator ruleSources]).andReturn((@[
+    [[SNTRuleSource alloc] initWithName:@"feed" url:url syncInterval:600 precedence:10],
+    [[SNTRuleSource alloc] initWithName:@"broken" url:url syncInterval:600 precedence:0],
+  ]));
+  NSNumber* now = @([[NSDate date] timeIntervalSince1970]);
+  NSArray* sources = @[
+    @{
+      kRuleSourceStatusName : @"feed",
+      kRuleSourceStatusURL : url.absoluteString,
+      kRuleSourceStatusPrecedence : @10,
+      kRuleSourceStatusSyncInterval : @600,
+      kRuleSourceStatusLastAttempt : now,
+      kRuleSourceStatusLastSuccess : now,
+      kRuleSourceStatusRulesReceived : @42,
+      kRuleSourceStatusNextSync : @(now.doubleValue + 600),
+    },
+    @{
+      kRuleSourceStatusName : @"broken",
+      kRuleSourceStatusURL : url.absoluteString,
+      kRuleSourceStatusPrecedence : @0,
+      kRuleSourceStatusSyncInterval : @600,
+      kRuleSourceStatusLastAttempt : now,
+      kRuleSourceStatusLastError : @"Rule download failed",
+      kRuleSourceStatusNextSync : @(now.doubleValue + 600),
+    },
+  ];
+  OCMStub([self.mockDaemon ruleSourcesStatus:([OCMArg invokeBlockWithArgs:sources, nil])]);
+  [self stubHealthyDaemon];
+
+  NSDictionary* status = [self jsonStatus];
+  [self assertAllFieldsPresent:status];
+
+  NSArray* ruleSources = status[@"rule_sources"];
+  XCTAssertEqual(ruleSources.count, 2);
+  XCTAssertEqualObjects(ruleSources[0][@"name"], @"feed");
+  XCTAssertEqualObjects(ruleSources[0][@"rules_received"], @42);
+  XCTAssertNotEqualObjects(ruleSources[0][@"last_success"], @"null");
+  XCTAssertEqualObjects(ruleSources[0][@"last_error"], @"null");
+  XCTAssertEqualObjects(ruleSources[1][@"name"], @"broken");
+  XCTAssertEqualObjects(ruleSources[1][@"last_success"], @"null");
+  XCTAssertEqualObjects(ruleSources[1][@"last_error"], @"Rule download failed");
+  XCTAssertEqualObjects(status[@"degraded_subsystems"], @[ @"rule_sources" ]);
+}
+
+@end
@@ -0,0 +1,334 @@
+/// Copyright 2026 North Pole Security, Inc.
+///
+/// Licensed under the Apache License, Version 2.0 (the "License");
+/// you may not use this file except in compliance with the License.
+/// You may obtain a copy of the License at
+///
+///     http://www.apache.org/licenses/LICENSE-2.0
+///
+/// Unless required by applicable law or agreed to in writing, software
+/// distributed under the License is distributed on an "AS IS" BASIS,
+/// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+/// See the License for the specific language governing permissions and
+/// limitations under the License.
+
+#import <Foundation/Foundation.h>
+#import <Network/Network.h>
+#include <Security/Security.h>
+
+#include <unistd.h>
+
+#include <cstdint>
+
+#import "Source/common/MOLAuthenticatingURLSession.h"
+#import "Source/common/MOLCertificate.h"
+#import "Source/common/SNTConfigurator.h"
+#include "Source/common/SyncServerRoots.h"
+#import "Source/santactl/SNTCommand.h"
+#import "Source/santactl/SNTCommandController.h"
+
+static const NSTimeInterval kDefaultTimeout = 10;
+
+// Certificates expiring within this many days are flagged in the report.
+static const NSInteger kExpiryWarningDays = 30;
+
+struct SNTTLSCheckResult {
+  // YES if the TLS handshake completed.
+  BOOL connected;
+  NSString* errorDescription;
+  double elapsedMs;
+  tls_protocol_version_t protocolVersion;
+  tls_ciphersuite_t cipherSuite;
+  // The certificate chain presented by the server, leaf first. Set even if the handshake failed
+  // after the server sent its certificates.
+  NSArray<MOLCertificate*>* certificateChain;
+  // Whether the chain passes the same trust evaluation as the sync transport.
+  BOOL trusted;
+  NSString* trustError;
+};
+
+// Returns the display name of a TLS protocol version. Exposed (non-static) so it can be unit
+// tested.
+NSString* SNTTLSCheckProtocolVersionName(tls_protocol_version_t version) {
+  switch (version) {
+    case tls_protocol_version_TLSv10: return @"TLS 1.0";
+    case tls_protocol_version_TLSv11: return @"TLS 1.1";
+    case tls_protocol_version_TLSv12: return @"TLS 1.2";
+    case tls_protocol_version_TLSv13: return @"TLS 1.3";
+    case tls_protocol_version_DTLSv10: return @"DTLS 1.0";
+    case tls_protocol_version_DTLSv12: return @"DTLS 1.2";
+  }
+  return [NSString stringWithFormat:@"unknown (0x%04x)", (unsigned)version];
+}
+
+// Returns the IANA name of a cipher suite. Exposed (non-static) so it can be unit tested.
+NSString* SNTTLSCheckCipherSuiteName(tls_ciphersuite_t suite) {
+  switch (suite) {
+    case tls_ciphersuite_AES_128_GCM_SHA256: return @"TLS_AES_128_GCM_SHA256";
+    case tls_ciphersuite_AES_256_GCM_SHA384: return @"TLS_AES_256_GCM_SHA384";
+    case tls_ciphersuite_CHACHA20_POLY1305_SHA256: return @"TLS_CHACHA20_POLY1305_SHA256";
+    case tls_ciphersuite_ECDHE_ECDSA_WITH_AES_128_GCM_SHA256:
+      return @"TLS_ECDHE_ECDSA_WITH_AES_128_GCM_SHA256";
+    case tls_ciphersuite_ECDHE_ECDSA_WITH_AES_256_GCM_SHA384:
+      return @"TLS_ECDHE_ECDSA_WITH_AES_256_GCM_SHA384";
+    case tls_ciphersuite_ECDHE_ECDSA_WITH_CHACHA20_POLY1305_SHA256:
+      return @"TLS_ECDHE_ECDSA_WITH_CHACHA20_POLY1305_SHA256";
+    case tls_ciphersuite_ECDHE_RSA_WITH_AES_128_GCM_SHA256:
+      return @"TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256";
+    case tls_ciphersuite_ECDHE_RSA_WITH_AES_256_GCM_SHA384:
+      return @"TLS_ECDHE_RSA_WITH_AES_256_GCM_SHA384";
+    case tls_ciphersuite_ECDHE_RSA_WITH_CHACHA20_POLY1305_SHA256:
+      return @"TLS_ECDHE_RSA_WITH_CHACHA20_POLY1305_SHA256";
+    case tls_ciphersuite_ECDHE_ECDSA_WITH_AES_128_CBC_SHA256:
+      return @"TLS_ECDHE_ECDSA_WITH_AES_128_CBC_SHA256";
+    case tls_ciphersuite_ECDHE_ECDSA_WITH_AES_256_CBC_SHA384:
+      return @"TLS_ECDHE_ECDSA_WITH_AES_256_CBC_SHA384";
+    case tls_ciphersuite_ECDHE_RSA_WITH_AES_128_CBC_SHA256:
+      return @"TLS_ECDHE_RSA_WITH_AES_128_CBC_SHA256";
+    case tls_ciphersuite_ECDHE_RSA_WITH_AES_256_CBC_SHA384:
+      return @"TLS_ECDHE_RSA_WITH_AES_256_CBC_SHA384";
+    case tls_ciphersuite_RSA_WITH_AES_128_GCM_SHA256: return @"TLS_RSA_WITH_AES_128_GCM_SHA256";
+    case tls_ciphersuite_RSA_WITH_AES_256_GCM_SHA384: return @"TLS_RSA_WITH_AES_256_GCM_SHA384";
+    default: return [NSString stringWithFormat:@"unknown (0x%04x)", (unsigned)suite];
+  }
+}
+
+// Parses the sync server URL into the host and port to check. Only https URLs are supported, the
+// port defaults to 443. Exposed (non-static) so it can be unit tested.
+BOOL SNTTLSCheckParseURL(NSString* address, NSString** host, uint16_t* port) {
+  NSURL* url = [NSURL URLWithString:address];
+  if (!url.host.length || [url.scheme caseInsensitiveCompare:@"https"] != NSOrderedSame) {
+    return NO;
+  }
+  *host = url.host;
+  *port = url.port ? url.port.unsignedShortValue : 443;
+  return YES;
+}
+
+// Connects to host:port and performs a TLS handshake with the sync transport's minimum protocol
+// version, evaluating the server's certificate chain with the given session's server roots. The
+// handshake is allowed to complete even if the chain is not trusted, so the negotiated parameters
+// can still be reported. Exposed (non-static) so it can be unit tested.
+SNTTLSCheckResult SNTTLSCheckRun(NSString* host, uint16_t port,
+                                 MOLAuthenticatingURLSession* trustEvaluator,
+                                 NSTimeInterval timeout) {
+  dispatch_queue_t q =
+      dispatch_queue_create("com.northpolesec.santa.santactl.tls-check", DISPATCH_QUEUE_SERIAL);
+  __block SNTTLSCheckResult result = {};
+
+  nw_endpoint_t endpoint =
+      nw_endpoint_create_host(host.UTF8String, [@(port) stringValue].UTF8String);
+  nw_parameters_t params = nw_parameters_create_secure_tcp(
+      ^(nw_protocol_options_t tlsOptions) {
+        sec_protocol_options_t secOptions = nw_tls_copy_sec_protocol_options(tlsOptions);
+        sec_protocol_options_set_min_tls_protocol_version(secOptions, tls_protocol_version_TLSv12);
+        sec_protocol_options_set_tls_server_name(secOptions, host.UTF8String);
+        sec_protocol_options_set_verify_block(
+            secOptions,
+            ^(sec_protocol_metadata_t metadata, sec_trust_t trustRef,
+              sec_protocol_verify_complete_t complete) {
+              SecTrustRef trust = sec_trust_copy_ref(trustRef);
+              NSArray* chain = CFBridgingRelease(SecTrustCopyCertificateChain(trust));
+              result.certificateChain = [MOLCertificate certificatesFromArray:chain];
+              NSString* trustError;
+              result.trusted = [trustEvaluator evaluateServerTrust:trust errorMessage:&trustError];
+              result.trustError = trustError;
+              CFRelease(trust);
+              // Complete the handshake regardless, the trust result is reported separately.
+              complete(true);
+            },
+            q);
+      },
+      NW_PARAMETERS_DEFAULT_CONFIGURATION);
+  nw_connection_t conn = nw_connection_create(endpoint, params);
+
+  dispatch_semaphore_t sema = dispatch_semaphore_create(0);
+  __block BOOL done = NO;
+  CFAbsoluteTime start = CFAbsoluteTimeGetCurrent();
+
+  nw_connection_set_queue(conn, q);
+  nw_connection_set_state_changed_handler(conn, ^(nw_connection_state_t state, nw_error_t error) {
+    if (done) return;
+    if (state == nw_connection_state_ready) {
+      nw_protocol_metadata_t metadata =
+          nw_connection_copy_protocol_metadata(conn, nw_protocol_copy_tls_definition());
+      if (metadata) {
+        sec_protocol_metadata_t secMetadata = nw_tls_copy_sec_protocol_metadata(metadata);
+        result.protocolVersion =
+            sec_protocol_metadata_get_negotiated_tls_protocol_version(secMetadata);
+        result.cipherSuite = sec_protocol_metadata_get_negotiated_tls_ciphersuite(secMetadata);
+      }
+      result.connected = YES;
+    } else if (state == nw_connection_state_failed ||
+               (state == nw_connection_state_waiting && error)) {
+      if (error) {
+        NSError* err = CFBridgingRelease(nw_error_copy_cf_error(error));
+        result.errorDescription = err.localizedDescription;
+      }
+    } else {
+      return;
+    }
+    result.elapsedMs = (CFAbsoluteTimeGetCurrent() - start) * 1000;
+    done = YES;
+    dispatch_semaphore_signal(sema);
+  });
+  nw_connection_start(conn);
+
+  dispatch_semaphore_wait(sema, dispatch_time(DISPATCH_TIME_NOW, timeout * NSEC_PER_SEC));
+  dispatch_sync(q, ^{
+    if (done) return;
+    done = YES;
+    result.elapsedMs = (CFAbsoluteTimeGetCurrent() - start) * 1000;
+    result.errorDescription = [NSString stringWithFormat:@"timed out after %.0f seconds", timeout];
+  });
+  nw_connection_cancel(conn);
+
+  return result;
+}
+
+static void Print(NSString* format, ...) {
+  va_list args;
+  va_start(args, format);
+  NSString* line = [[NSString alloc] initWithFormat:format arguments:args];
+  va_end(args);
+
+  if (isatty(STDOUT_FILENO)) {
+    if ([line hasPrefix:@"[-]"]) {
+      line = [NSString stringWithFormat:@"\033[31m%@\033[0m", line];
+    } else if ([line hasPrefix:@"[+]"]) {
+      line = [NSString stringWithFormat:@"\033[32m%@\033[0m", line];
+    }
+  }
+  printf("%s\n", line.UTF8String);
+}
+
+@interface SNTCommandTLSCheck : SNTCommand <SNTCommandProtocol>
+@end
+
+@implementation SNTCommandTLSCheck
+
+REGISTER_COMMAND_NAME(@"tls-check")
+
++ (BOOL)requiresRoot {
+  return NO;
+}
+
++ (BOOL)requiresDaemonConn {
+  return NO;
+}
+
++ (NSString*)shortHelpText {
+  return @"Check the TLS configuration of the sync server.";
+}
+
++ (NSString*)longHelpText {
+  return (@"Performs a TLS handshake with the sync server using the same minimum protocol\n"
+          @"version and server roots as the sync service, and reports:\n"
+          @"  - the negotiated TLS version and cipher suite,\n"
+          @"  - the certificate chain presented by the server and when each certificate expires,\n"
+          @"  - whether the chain passes the sync service's trust evaluation, using the pinned\n"
+          @"    or configured server roots if there are any, otherwise the system roots.\n\n"
+          @"This diagnoses TLS-layer sync failures separately from HTTP ones. No client\n"
+          @"certificate is presented; use `santactl connectivity` to check authentication.\n\n"
+          @"Will exit with a non-zero exit code if the handshake fails, the chain is not\n"
+          @"trusted or the server's certificate has expired.\n\n"
+          @"Options:\n"
+          @"  --sync-url <url>: The sync server to check. Defaults to the configured SyncBaseURL.\n"
+          @"  --timeout <seconds>: Timeout for the handshake. Defaults to 10.\n");
+}
+
+- (void)runWithArguments:(NSArray*)arguments {
+  NSTimeInterval timeout = kDefaultTimeout;
+  NSString* syncURL;
+  for (NSUInteger i = 0; i < arguments.count; ++i) {
+    NSString* arg = arguments[i];
+    if ([arg caseInsensitiveCompare:@"--sync-url"] == NSOrderedSame) {
+      if (++i >= arguments.count) {
+        [self printErrorUsageAndExit:@"--sync-url requires an argument"];
+      }
+      syncURL = arguments[i];
+    } else if ([arg caseInsensitiveCompare:@"--timeout"] == NSOrderedSame) {
+      if (++i >= arguments.count || [arguments[i] doubleValue] <= 0) {
+        [self printErrorUsageAndExit:@"--timeout requires a positive number of seconds"];
+      }
+      timeout = [arguments[i] doubleValue];
+    } else {
+      [self printErrorUsageAndExit:[@"Unknown argument: " stringByAppendingString:arg]];
+    }
+  }
+
+  if (!syncURL) {
+    syncURL = [[SNTConfigurator configurator] syncBaseURL].absoluteString;
+    if (!syncURL) {
+      [self printErrorUsageAndExit:@"No SyncBaseURL is configured, pass --sync-url"];
+    }
+  }
+
+  NSString* host;
+  uint16_t port;
+  if (!SNTTLSCheckParseURL(syncURL, &host, &port)) {
+    [self printErrorUsageAndExit:[@"Not an https URL: " stringByAppendingString:syncURL]];
+  }
+
+  MOLAuthenticatingURLSession* trustEvaluator = [[MOLAuthenticatingURLSession alloc] init];
+  santa::ConfigureSyncServerRoots(trustEvaluator, [NSURL URLWithString:syncURL]);
+
+  Print(@"=> Checking TLS for %@:%u...", host, port);
+  SNTTLSCheckResult result = SNTTLSCheckRun(host, port, trustEvaluator, timeout);
+  BOOL failed = NO;
+
+  if (result.connected) {
+    Print(@"[+] Negotiated %@ with %@ in %.0f ms",
+          SNTTLSCheckProtocolVersionName(result.protocolVersion),
+          SNTTLSCheckCipherSuiteName(result.cipherSuite), result.elapsedMs);
+  } else {
+    Print(@"[-] TLS handshake failed after %.0f ms: %@", result.elapsedMs,
+          result.errorDescription ?: @"unknown error");
+    failed = YES;
+  }
+
+  if (result.certificateChain.count) {
+    Print(@"=> Certificate chain");
+    NSDate* now = [NSDate date];
+    [result.certificateChain enumerateObjectsUsingBlock:^(MOLCertificate* cert, NSUInteger idx,
+                                                          BOOL* stop) {
+      Print(@"    %lu: %@", (unsigned long)idx, cert.commonName ?: @"<no common name>");
+      Print(@"       Issuer: %@", cert.issuerCommonName ?: @"<no common name>");
+      Print(@"       SHA-256: %@", cert.SHA256);
+      NSInteger days = (NSInteger)floor([cert.validUntil timeIntervalSinceDate:now] / 86400);
+      Print(@"       Expires: %@ (%@)", cert.validUntil,
+            days < 0 ? @"expired" : [NSString stringWithFormat:@"in %ld days", (long)days]);
+    }];
+
+    [result.certificateChain enumerateObjectsUsingBlock:^(MOLCertificate* cert, NSUInteger idx,
+                                                          BOOL* stop) {
+      NSTimeInterval remaining = [cert.validUntil timeIntervalSinceDate:now];
+      if (remaining < 0) {
+        Print(@"[-] Certificate %lu (%@) has expired", (unsigned long)idx, cert.commonName);
+      } else if (remaining < kExpiryWarningDays * 86400) {
+        Print(@"[?] Certificate %lu (%@) expires within %ld days", (unsigned long)idx,
+              cert.commonName, (long)kExpiryWarningDays);
+      }
+    }];
+    if ([result.certificateChain.firstObject.validUntil compare:now] == NSOrderedAscending) {
+      failed = YES;
+    }
+
+    NSString* roots =
+        trustEvaluator.hasServerRoots ? @"the configured server roots" : @"the system roots";
+    if (result.trusted) {
+      Print(@"[+] Certificate chain is trusted by %@", roots);
+    } else {
+      Print(@"[-] Certificate chain is not trusted by %@: %@", roots,
+            result.trustError ?: @"unknown error");
+      failed = YES;
+    }
+    if (!trustEvaluator.hasServerRoots) {
+      Print(@"[?] Pinning is not configured for this server");
+    }
+  }
+
+  exit(failed);
+}
+
+@end
@@ -0,0 +1,215 @@
+/// Copyright 2026 North Pole Security, Inc.
+///
+/// Licensed under the Apache License, Version 2.0 (the "License");
+/// you may not use this file except in compliance with the License.
+/// You may obtain a copy of the License at
+///
+///     http://www.apache.org/licenses/LICENSE-2.0
+///
+/// Unless required by applicable law or agreed to in writing, software
+/// distributed under the License is distributed on an "AS IS" BASIS,
+/// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+/// See the License for the specific language governing permissions and
+/// limitations under the License.
+
+#import <Foundation/Foundation.h>
+#import <Network/Network.h>
+#include <Security/Security.h>
+#import <XCTest/XCTest.h>
+
+#include <cstdint>
+
+#import "Source/common/MOLAuthenticatingURLSession.h"
+#import "Source/common/MOLCertificate.h"
+
+// Defined in SNTCommandTLSCheck.mm.
+struct SNTTLSCheckResult {
+  BOOL connected;
+  NSString* errorDescription;
+  double elapsedMs;
+  tls_protocol_version_t protocolVersion;
+  tls_ciphersuite_t cipherSuite;
+  NSArray<MOLCertificate*>* certificateChain;
+  BOOL trusted;
+  NSString* trustError;
+};
+
+extern NSString* SNTTLSCheckProtocolVersionName(tls_protocol_version_t version);
+extern NSString* SNTTLSCheckCipherSuiteName(tls_ciphersuite_t suite);
+extern BOOL SNTTLSCheckParseURL(NSString* address, NSString** host, uint16_t* port);
+extern SNTTLSCheckResult SNTTLSCheckRun(NSString* host, uint16_t port,
+                                        MOLAuthenticatingURLSession* trustEvaluator,
+                                        NSTimeInterval timeout);
+
+@interface SNTCommandTLSCheckTest : XCTestCase
+@property nw_listener_t listener;
+@end
+
+@implementation SNTCommandTLSCheckTest
+
+- (void)tearDown {
+  if (self.listener) nw_listener_cancel(self.listener);
+}
+
+- (NSString*)pathForResource:(NSString*)name ofType:(NSString*)type {
+  return [[NSBundle bundleForClass:[self class]] pathForResource:name ofType:type];
+}
+
+// Starts a TLS server on an ephemeral loopback port that presents the self-signed localhost
+// certificate in testdata, negotiating at most maxVersion, and returns the port.
+- (uint16_t)startTLSServerWithMaxVersion:(tls_protocol_version_t)maxVersion {
+  NSData* p12 = [NSData dataWithContentsOfFile:[self pathForResource:@"tls_check_server"
+                                                               ofType:@"p12"]];
+  XCTAssertNotNil(p12);
+  CFArrayRef cfItems = NULL;
+  OSStatus status =
+      SecPKCS12Import((__bridge CFDataRef)p12,
+                      (__bridge CFDictionaryRef) @{(id)kSecImportExportPassphrase : @"santa"},
+                      &cfItems);
+  XCTAssertEqual(status, errSecSuccess);
+  NSArray* items = CFBridgingRelease(cfItems);
+  SecIdentityRef identity =
+      (__bridge SecIdentityRef)items.firstObject[(__bridge NSString*)kSecImportItemIdentity];
+  sec_identity_t secIdentity = sec_identity_create(identity);
+
+  nw_parameters_t params = nw_parameters_create_secure_tcp(
+      ^(nw_protocol_options_t tlsOptions) {
+        sec_protocol_options_t secOptions = nw_tls_copy_sec_protocol_options(tlsOptions);
+        sec_protocol_options_set_local_identity(secOptions, secIdentity);
+        sec_protocol_options_set_max_tls_protocol_version(secOptions, maxVersion);
+      },
+      NW_PARAMETERS_DEFAULT_CONFIGURATION);
+  nw_parameters_set_required_local_endpoint(params, nw_endpoint_create_host("127.0.0.1", "0"));
+
+  dispatch_queue_t q = dispatch_queue_create("com.northpolesec.santa.santactl.tls-check-test",
+                                             DISPATCH_QUEUE_SERIAL);
+  dispatch_semaphore_t ready = dispatch_semaphore_create(0);
+  self.listener = nw_listener_create(params);
+  nw_listener_set_queue(self.listener, q);
+  nw_listener_set_new_connection_handler(self.listener, ^(nw_connection_t conn) {
+    nw_connection_set_queue(conn, q);
+    nw_connection_start(conn);
+  });
+  nw_listener_set_state_changed_handler(self.listener, ^(nw_listener_state_t state, nw_error_t) {
+    if (state == nw_listener_state_ready) dispatch_semaphore_signal(ready);
+  });
+  nw_listener_start(self.listener);
+
+  XCTAssertEqual(
+      dispatch_semaphore_wait(ready, dispatch_time(DISPATCH_TIME_NOW, 5 * NSEC_PER_SEC)), 0);
+  return nw_listener_get_port(self.listener);
+}
+
+- (MOLAuthenticatingURLSession*)trustEvaluatorWithRoots:(NSString*)rootsName {
+  MOLAuthenticatingURLSession* session = [[MOLAuthenticatingURLSession alloc] init];
+  if (rootsName) session.serverRootsPemFile = [self pathForResource:rootsName ofType:@"pem"];
+  return session;
+}
+
+#pragma mark Formatting
+
+- (void)testProtocolVersionName {
+  XCTAssertEqualObjects(SNTTLSCheckProtocolVersionName(tls_protocol_version_TLSv12), @"TLS 1.2");
+  XCTAssertEqualObjects(SNTTLSCheckProtocolVersionName(tls_protocol_version_TLSv13), @"TLS 1.3");
+  XCTAssertEqualObjects(SNTTLSCheckProtocolVersionName((tls_protocol_version_t)0x1234),
+                        @"unknown (0x1234)");
+}
+
+- (void)testCipherSuiteName {
+  XCTAssertEqualObjects(SNTTLSCheckCipherSuiteName(tls_ciphersuite_AES_128_GCM_SHA256),
+                        @"TLS_AES_128_GCM_SHA256");
+  XCTAssertEqualObjects(
+      SNTTLSCheckCipherSuiteName(tls_ciphersuite_ECDHE_RSA_WITH_AES_256_GCM_SHA384),
+      @"TLS_ECDHE_RSA_WITH_AES_256_GCM_SHA384");
+  XCTAssertEqualObjects(SNTTLSCheckCipherSuiteName((tls_ciphersuite_t)0xabcd), @"unknown (0xabcd)");
+}
+
+- (void)testParseURL {
+  NSString* host;
+  uint16_t port;
+  XCTAssertTrue(SNTTLSCheckParseURL(@"https://sync.example.com/santa/", &host, &port));
+  XCTAssertEqualObjects(host, @"sync.example.com");
+  XCTAssertEqual(port, 443);
+
+  XCTAssertTrue(SNTTLSCheckParseURL(@"https://localhost:8443/", &host, &port));
+  XCTAssertEqualObjects(host, @"localhost");
+  XCTAssertEqual(port, 8443);
+
+  XCTAssertFalse(SNTTLSCheckParseURL(@"http://localhost:8080/", &host, &port));
+  XCTAssertFalse(SNTTLSCheckParseURL(@"not a url", &host, &port));
+}
+
+#pragma mark Handshakes
+
+- (void)testReportsNegotiatedParametersAndChain {
+  uint16_t port = [self startTLSServerWithMaxVersion:tls_protocol_version_TLSv13];
+
+  SNTTLSCheckResult result =
+      SNTTLSCheckRun(@"localhost", port, [self trustEvaluatorWithRoots:@"tls_check_server"], 5);
+
+  XCTAssertTrue(result.connected, @"%@", result.errorDescription);
+  XCTAssertEqual(result.protocolVersion, tls_protocol_version_TLSv13);
+  XCTAssertTrue(result.cipherSuite == tls_ciphersuite_AES_128_GCM_SHA256 ||
+                result.cipherSuite == tls_ciphersuite_AES_256_GCM_SHA384 ||
+                result.cipherSuite == tls_ciphersuite_CHACHA20_POLY1305_SHA256);
+  XCTAssertEqual(result.certificateChain.count, 1);
+  XCTAssertEqualObjects(result.certificateChain.firstObject.commonName, @"localhost");
+  XCTAssertEqualObjects(result.certificateChain.firstObject.orgName, @"Santa Test");
+  // The test certificate is valid for 100 years from 2026.
+  XCTAssertGreaterThan([result.certificateChain.firstObject.validUntil timeIntervalSinceNow],
+                       50 * 365 * 86400.0);
+
+  // The configured roots contain the server's certificate, so pinning passes.
+  XCTAssertTrue(result.trusted, @"%@", result.trustError);
+}
+
+- (void)testReportsNegotiatedTLS12 {
+  uint16_t port = [self startTLSServerWithMaxVersion:tls_protocol_version_TLSv12];
+
+  SNTTLSCheckResult result =
+      SNTTLSCheckRun(@"localhost", port, [self trustEvaluatorWithRoots:@"tls_check_server"], 5);
+
+  XCTAssertTrue(result.connected, @"%@", result.errorDescription);
+  XCTAssertEqual(result.protocolVersion, tls_protocol_version_TLSv12);
+  XCTAssertTrue([SNTTLSCheckCipherSuiteName(result.cipherSuite) hasPrefix:@"TLS_ECDHE_RSA_WITH_"]);
+}
+
+- (void)testPinningFailsWithOtherRoots {
+  uint16_t port = [self startTLSServerWithMaxVersion:tls_protocol_version_TLSv13];
+
+  SNTTLSCheckResult result = SNTTLSCheckRun(
+      @"localhost", port, [self trustEvaluatorWithRoots:@"example_org_client_cert"], 5);
+
+  // The handshake still completes so the TLS parameters can be reported.
+  XCTAssertTrue(result.connected, @"%@", result.errorDescription);
+  XCTAssertEqual(result.certificateChain.count, 1);
+  XCTAssertFalse(result.trusted);
+  XCTAssertNotNil(result.trustError);
+}
+
+- (void)testSelfSignedServerIsNotTrustedBySystemRoots {
+  uint16_t port = [self startTLSServerWithMaxVersion:tls_protocol_version_TLSv13];
+
+  MOLAuthenticatingURLSession* evaluator = [self trustEvaluatorWithRoots:nil];
+  SNTTLSCheckResult result = SNTTLSCheckRun(@"localhost", port, evaluator, 5);
+
+  XCTAssertFalse(evaluator.hasServerRoots);
+  XCTAssertTrue(result.connected, @"%@", result.errorDescription);
+  XCTAssertFalse(result.trusted);
+}
+
+- (void)testHandshakeFailureIsReported {
+  // Nothing is listening on the port once the listener is cancelled.
+  uint16_t port = [self startTLSServerWithMaxVersion:tls_protocol_version_TLSv13];
+  nw_listener_cancel(self.listener);
+  self.listener = nil;
+
+  SNTTLSCheckResult result =
+      SNTTLSCheckRun(@"127.0.0.1", port, [self trustEvaluatorWithRoots:nil], 2);
+
+  XCTAssertFalse(result.connected);
+  XCTAssertNotNil(result.errorDescription);
+  XCTAssertEqual(result.certificateChain.count, 0);
+}
+
+@end
@@ -0,0 +1,75 @@
+/// Copyright 2026 North Pole Security, Inc.
+///
+/// Licensed under the Apache License, Version 2.0 (the "License");
+/// you may not use this file except in compliance with the License.
+/// You may obtain a copy of the License at
+///
+///     http://www.apache.org/licenses/LICENSE-2.0
+///
+/// Unless required by applicable law or agreed to in writing, software
+/// distributed under the License is distributed on an "AS IS" BASIS,
+/// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+/// See the License for the specific language governing permissions and
+/// limitations under the License.
+
+#ifndef SANTA_SANTAD_CLOCKMONITOR_H
+#define SANTA_SANTAD_CLOCKMONITOR_H
+
+#import <Foundation/Foundation.h>
+
+#include <memory>
+
+#include "Source/common/PassKey.h"
+#import "Source/common/SNTConfigurator.h"
+#include "Source/common/Timer.h"
+#import "Source/santad/SNTSyncdQueue.h"
+#include "absl/synchronization/mutex.h"
+
+namespace santa {
+
+// Periodically compares elapsed wall clock time against elapsed monotonic time
+// to detect the system clock being moved backward, e.g. in an attempt to make
+// expired rules or certificates valid again. When a backward jump larger than
+// the configured threshold is found, the configured SNTClockTamperingAction is
+// taken.
+class ClockMonitor : public Timer<ClockMonitor>, public PassKey<ClockMonitor> {
+ public:
+  // Returns the current value of a clock, in seconds.
+  using ClockBlock = NSTimeInterval (^)(void);
+  using ActionBlock = void (^)(void);
+
+  // Factory
+  static std::shared_ptr<ClockMonitor> Create(SNTConfigurator* configurator,
+                                              SNTSyncdQueue* syncd_queue);
+
+  // Construction requires a PassKey, can only be used internally / by tests.
+  ClockMonitor(PassKey, SNTConfigurator* configurator, ClockBlock wall_clock,
+               ClockBlock monotonic_clock, ActionBlock request_sync, ActionBlock enter_lockdown);
+
+  // Timer<> callback. Always re-arms.
+  bool OnTimer();
+
+  // Compare both clocks against the values seen at the previous check and take
+  // the configured action if the wall clock fell behind by more than the
+  // threshold. Returns true if tampering was detected.
+  bool CheckClocks();
+
+  friend class ClockMonitorPeer;
+
+ private:
+  void Respond(NSTimeInterval backward_seconds);
+
+  SNTConfigurator* configurator_;
+  ClockBlock wall_clock_;
+  ClockBlock monotonic_clock_;
+  ActionBlock request_sync_;
+  ActionBlock enter_lockdown_;
+
+  absl::Mutex lock_;
+  NSTimeInterval last_wall_time_ ABSL_GUARDED_BY(lock_);
+  NSTimeInterval last_monotonic_time_ ABSL_GUARDED_BY(lock_);
+};
+
+}  // namespace santa
+
+#endif  // SANTA_SANTAD_CLOCKMONITOR_H
@@ -0,0 +1,114 @@
+/// Copyright 2026 North Pole Security, Inc.
+///
+/// Licensed under the Apache License, Version 2.0 (the "License");
+/// you may not use this file except in compliance with the License.
+/// You may obtain a copy of the License at
+///
+///     http://www.apache.org/licenses/LICENSE-2.0
+///
+/// Unless required by applicable law or agreed to in writing, software
+/// distributed under the License is distributed on an "AS IS" BASIS,
+/// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+/// See the License for the specific language governing permissions and
+/// limitations under the License.
+
+#include "Source/santad/ClockMonitor.h"
+
+#include <mach/mach_time.h>
+
+#import "Source/common/SNTLogging.h"
+#include "Source/common/SystemResources.h"
+
+namespace santa {
+
+static constexpr uint32_t kClockMonitorIntervalSec = 60;
+
+std::shared_ptr<ClockMonitor> ClockMonitor::Create(SNTConfigurator* configurator,
+                                                   SNTSyncdQueue* syncd_queue) {
+  auto monitor = std::make_shared<ClockMonitor>(
+      PassKey(), configurator,
+      ^NSTimeInterval {
+        return [[NSDate date] timeIntervalSince1970];
+      },
+      ^NSTimeInterval {
+        // mach_continuous_time keeps counting while the system is asleep, so
+        // sleep/wake cycles do not register as clock skew.
+        return (NSTimeInterval)MachTimeToNanos(mach_continuous_time()) / NSEC_PER_SEC;
+      },
+      ^{
+        [syncd_queue requestSync];
+      },
+      ^{
+        [configurator setSyncServerClientMode:SNTClientModeLockdown];
+      });
+
+  monitor->StartTimer();
+
+  return monitor;
+}
+
+ClockMonitor::ClockMonitor(PassKey, SNTConfigurator* configurator, ClockBlock wall_clock,
+                           ClockBlock monotonic_clock, ActionBlock request_sync,
+                           ActionBlock enter_lockdown)
+    : Timer(kClockMonitorIntervalSec, kClockMonitorIntervalSec, Timer::OnStart::kWaitOneCycle,
+            "ClockMonitor"),
+      configurator_(configurator),
+      wall_clock_([wall_clock copy]),
+      monotonic_clock_([monotonic_clock copy]),
+      request_sync_([request_sync copy]),
+      enter_lockdown_([enter_lockdown copy]) {
+  last_wall_time_ = wall_clock_();
+  last_monotonic_time_ = monotonic_clock_();
+}
+
+bool ClockMonitor::OnTimer() {
+  CheckClocks();
+  return true;
+}
+
+bool ClockMonitor::CheckClocks() {
+  NSTimeInterval wall_elapsed;
+  NSTimeInterval monotonic_elapsed;
+  {
+    absl::MutexLock lock(lock_);
+    NSTimeInterval now_wall = wall_clock_();
+    NSTimeInterval now_monotonic = monotonic_clock_();
+
+    wall_elapsed = now_wall - last_wall_time_;
+    monotonic_elapsed = now_monotonic - last_monotonic_time_;
+
+    // Always rebase so a single jump is only reported once.
+    last_wall_time_ = now_wall;
+    last_monotonic_time_ = now_monotonic;
+  }
+
+  // Forward jumps are not a concern here, they cannot revive expired state.
+  NSTimeInterval backward_seconds = monotonic_elapsed - wall_elapsed;
+  if (backward_seconds <= configurator_.clockTamperingThresholdSec) {
+    return false;
+  }
+
+  Respond(backward_seconds);
+  return true;
+}
+
+void ClockMonitor::Respond(NSTimeInterval backward_seconds) {
+  SNTClockTamperingAction action = configurator_.clockTamperingAction;
+
+  LOGW(@"System clock moved backward by %.0f seconds", backward_seconds);
+
+  switch (action) {
+    case SNTClockTamperingActionSync:
+      LOGI(@"Requesting sync in response to system clock change");
+      request_sync_();
+      break;
+    case SNTClockTamperingActionLockdown:
+      LOGI(@"Entering Lockdown mode in response to system clock change");
+      enter_lockdown_();
+      break;
+    case SNTClockTamperingActionNone:
+    default: break;
+  }
+}
+
+}  // namespace santa
@@ -0,0 +1,139 @@
+/// Copyright 2026 North Pole Security, Inc.
+///
+/// Licensed under the Apache License, Version 2.0 (the "License");
+/// you may not use this file except in compliance with the License.
+/// You may obtain a copy of the License at
+///
+///     http://www.apache.org/licenses/LICENSE-2.0
+///
+/// Unless required by applicable law or agreed to in writing, software
+/// distributed under the License is distributed on an "AS IS" BASIS,
+/// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+/// See the License for the specific language governing permissions and
+/// limitations under the License.
+
+#include "Source/santad/ClockMonitor.h"
+
+#import <Foundation/Foundation.h>
+#import <OCMock/OCMock.h>
+#import <XCTest/XCTest.h>
+
+#include <memory>
+
+#import "Source/common/SNTCommonEnums.h"
+#import "Source/common/SNTConfigurator.h"
+
+namespace santa {
+class ClockMonitorPeer : public ClockMonitor {
+ public:
+  ClockMonitorPeer(SNTConfigurator* configurator, ClockBlock wall_clock,
+                   ClockBlock monotonic_clock, ActionBlock request_sync,
+                   ActionBlock enter_lockdown)
+      : ClockMonitor(MakeKey(), configurator, wall_clock, monotonic_clock, request_sync,
+                     enter_lockdown) {}
+};
+}  // namespace santa
+
+using santa::ClockMonitorPeer;
+
+@interface ClockMonitorTest : XCTestCase
+@property id mockConfigurator;
+@property NSTimeInterval wallTime;
+@property NSTimeInterval monotonicTime;
+@property int syncCount;
+@property int lockdownCount;
+@end
+
+@implementation ClockMonitorTest
+
+- (void)setUp {
+  self.mockConfigurator = OCMClassMock([SNTConfigurator class]);
+  OCMStub([self.mockConfigurator clockTamperingThresholdSec]).andReturn(300);
+
+  self.wallTime = 1700000000;
+  self.monotonicTime = 1000;
+  self.syncCount = 0;
+  self.lockdownCount = 0;
+}
+
+- (void)tearDown {
+  [self.mockConfigurator stopMocking];
+}
+
+- (std::shared_ptr<ClockMonitorPeer>)createMonitor {
+  return std::make_shared<ClockMonitorPeer>(
+      self.mockConfigurator,
+      ^NSTimeInterval {
+        return self.wallTime;
+      },
+      ^NSTimeInterval {
+        return self.monotonicTime;
+      },
+      ^{
+        self.syncCount++;
+      },
+      ^{
+        self.lockdownCount++;
+      });
+}
+
+// Advance the monotonic clock by `elapsed` and the wall clock by `elapsed + wallDelta`.
+- (void)advance:(NSTimeInterval)elapsed wallDelta:(NSTimeInterval)wallDelta {
+  self.monotonicTime += elapsed;
+  self.wallTime += elapsed + wallDelta;
+}
+
+- (void)testNoActionWhenClocksAgree {
+  OCMStub([self.mockConfigurator clockTamperingAction]).andReturn(SNTClockTamperingActionSync);
+  auto monitor = [self createMonitor];
+
+  [self advance:60 wallDelta:0];
+  XCTAssertFalse(monitor->CheckClocks());
+
+  // Small backward adjustments under the threshold, and any forward jump, are ignored
+  [self advance:60 wallDelta:-120];
+  XCTAssertFalse(monitor->CheckClocks());
+  [self advance:60 wallDelta:86400];
+  XCTAssertFalse(monitor->CheckClocks());
+
+  XCTAssertEqual(self.syncCount, 0);
+  XCTAssertEqual(self.lockdownCount, 0);
+}
+
+- (void)testBackwardJumpTriggersSync {
+  OCMStub([self.mockConfigurator clockTamperingAction]).andReturn(SNTClockTamperingActionSync);
+  auto monitor = [self createMonitor];
+
+  [self advance:60 wallDelta:-86400];
+  XCTAssertTrue(monitor->CheckClocks());
+  XCTAssertEqual(self.syncCount, 1);
+  XCTAssertEqual(self.lockdownCount, 0);
+
+  // The jump is only reported once
+  [self advance:60 wallDelta:0];
+  XCTAssertFalse(monitor->CheckClocks());
+  XCTAssertEqual(self.syncCount, 1);
+}
+
+- (void)testBackwardJumpTriggersLockdown {
+  OCMStub([self.mockConfigurator clockTamperingAction])
+      .andReturn(SNTClockTamperingActionLockdown);
+  auto monitor = [self createMonitor];
+
+  [self advance:60 wallDelta:-3600];
+  XCTAssertTrue(monitor->CheckClocks());
+  XCTAssertEqual(self.syncCount, 0);
+  XCTAssertEqual(self.lockdownCount, 1);
+}
+
+- (void)testBackwardJumpWithNoActionOnlyDetects {
+  OCMStub([self.mockConfigurator clockTamperingAction]).andReturn(SNTClockTamperingActionNone);
+  auto monitor = [self createMonitor];
+
+  [self advance:60 wallDelta:-3600];
+  XCTAssertTrue(monitor->CheckClocks());
+  XCTAssertEqual(self.syncCount, 0);
+  XCTAssertEqual(self.lockdownCount, 0);
+}
+
+@end
@@ -55,4 +55,10 @@
 ///
 - (uint32_t)currentVersion;
 
+///
+///  YES if the on-disk database could not be opened or failed an integrity check during
+///  initialization and had to be deleted and recreated empty.
+///
+@property(readonly) BOOL recreatedAfterCorruption;
+
 @end
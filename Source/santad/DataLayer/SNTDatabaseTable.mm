@@ -41,9 +41,10 @@
           bail = YES;
           return;
         }
-        LOGW(@"Unable to create a good connection to the database. Deleting. (%@)",
+        LOGE(@"Unable to create a good connection to the database. Deleting. (%@)",
              [db databasePath]);
         [self closeDeleteReopenDatabase:db];
+        self->_recreatedAfterCorruption = YES;
       } else if ([db userVersion] > [self currentSupportedVersion]) {
         LOGW(@"Database version newer than supported. Deleting. (%@)", [db databasePath]);
         [self closeDeleteReopenDatabase:db];
@@ -52,8 +53,9 @@
         [db executeUpdate:@"REINDEX;"];
         [db executeUpdate:@"VACUUM;"];
         if ([self isDatabaseCorrupted:db]) {
-          LOGW(@"Unable to recover corrupted database. Deleting. (%@)", [db databasePath]);
+          LOGE(@"Unable to recover corrupted database. Deleting. (%@)", [db databasePath]);
           [self closeDeleteReopenDatabase:db];
+          self->_recreatedAfterCorruption = YES;
         } else {
           LOGW(@"Repairs successful. (%@)", [db databasePath]);
         }
@@ -0,0 +1,76 @@
+/// Copyright 2026 North Pole Security, Inc.
+///
+/// Licensed under the Apache License, Version 2.0 (the "License");
+/// you may not use this file except in compliance with the License.
+/// You may obtain a copy of the License at
+///
+///     http://www.apache.org/licenses/LICENSE-2.0
+///
+/// Unless required by applicable law or agreed to in writing, software
+/// distributed under the License is distributed on an "AS IS" BASIS,
+/// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+/// See the License for the specific language governing permissions and
+/// limitations under the License.
+
+
+#import <Foundation/Foundation.h>
+
+#import "Source/santad/DataLayer/SNTDatabaseTable.h"
+
+@class SNTStoredExecutionEvent;
+
+NS_ASSUME_NONNULL_BEGIN
+
+// The maximum number of rows returned by a query.
+extern const NSUInteger kDecisionQueryMaxRows;
+
+///
+///  Records execution decisions in a local SQLite table so that admins can query them ad-hoc with
+///  `santactl query`. The table is indexed by SHA-256, decision and time and holds roughly a
+///  configured number of decisions, beyond which the oldest are deleted.
+///
+@interface SNTDecisionTable : SNTDatabaseTable
+
+///
+///  Add a decision to the database right away, deleting the oldest decisions so that at most
+///  maxDecisions remain. The event's occurrenceDate must be set.
+///
+///  @return YES if the decision was successfully stored.
+///
+- (BOOL)addDecision:(SNTStoredExecutionEvent*)event maxDecisions:(NSUInteger)maxDecisions;
+
+///
+///  Queue a decision to be added to the database and return immediately. Queued decisions are
+///  written in batches on a background queue, and the table is periodically trimmed to the
+///  maxDecisions passed with the most recent decision. Use this on the execution path.
+///
+- (void)recordDecision:(SNTStoredExecutionEvent*)event maxDecisions:(NSUInteger)maxDecisions;
+
+///
+///  Write queued decisions and trim the table, waiting until done. Queries do this first so they
+///  include every recorded decision.
+///
+- (void)flushPendingDecisions;
+
+///
+///  Retrieves number of decisions in the database.
+///
+- (NSUInteger)decisionCount;
+
+///
+///  Run a single read-only SQL statement against the database. Statements that could modify the
+///  database are rejected. At most kDecisionQueryMaxRows rows are returned.
+///
+///  @param sql The statement to run.
+///  @param columns On success, set to the names of the result columns.
+///  @param error On failure, set to an error describing why.
+///  @return The result rows, each an array of values (NSNumber, NSString, NSData or NSNull) in
+///          column order, or nil on failure.
+///
+- (nullable NSArray<NSArray*>*)executeReadOnlyQuery:(NSString*)sql
+                                            columns:(NSArray<NSString*>**)columns
+                                              error:(NSError**)error;
+
+@end
+
+NS_ASSUME_NONNULL_END
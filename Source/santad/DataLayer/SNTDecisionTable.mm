@@ -0,0 +1,319 @@
+/// Copyright 2026 North Pole Security, Inc.
+///
+/// Licensed under the Apache License, Version 2.0 (the "License");
+/// you may not use this file except in compliance with the License.
+/// You may obtain a copy of the License at
+///
+///     http://www.apache.org/licenses/LICENSE-2.0
+///
+/// Unless required by applicable law or agreed to in writing, software
+/// distributed under the License is distributed on an "AS IS" BASIS,
+/// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+/// See the License for the specific language governing permissions and
+/// limitations under the License.
+
+
+#import "Source/santad/DataLayer/SNTDecisionTable.h"
+
+#include <sqlite3.h>
+
+#import "Source/common/SNTCommonEnums.h"
+#import "Source/common/SNTError.h"
+#import "Source/common/SNTLogging.h"
+#import "Source/common/SNTStoredExecutionEvent.h"
+
+static const uint32_t kDecisionTableCurrentVersion = 1;
+
+const NSUInteger kDecisionQueryMaxRows = 10000;
+
+// Queries share the database queue with decisions being recorded, so a query that runs for
+// longer than this is interrupted rather than holding up new decisions.
+static const CFTimeInterval kDecisionQueryTimeoutSeconds = 5;
+
+// Recorded decisions are written in batches, once a batch holds kDecisionBatchSize decisions or
+// kDecisionBatchDelaySeconds after its first decision. The table is trimmed to the maximum number
+// of decisions every kDecisionPruneIntervalSeconds rather than on every write.
+static const NSUInteger kDecisionBatchSize = 100;
+static const int64_t kDecisionBatchDelaySeconds = 1;
+static const uint64_t kDecisionPruneIntervalSeconds = 60;
+
+static NSString* DecisionString(SNTEventState state) {
+  if (state & SNTEventStateAllow) return @"ALLOW";
+  if (state & SNTEventStateBlock) return @"BLOCK";
+  return @"UNKNOWN";
+}
+
+// Only allow statements that read from the database. This is checked while the statement is
+// compiled, so rejected statements never run. ATTACH and PRAGMA are rejected as they can create
+// files or change settings without writing to the database.
+static int ReadOnlyAuthorizer(void* context, int action, const char* arg1, const char* arg2,
+                              const char* db, const char* trigger) {
+  switch (action) {
+    case SQLITE_SELECT:
+    case SQLITE_READ:
+    case SQLITE_FUNCTION:
+    case SQLITE_RECURSIVE: return SQLITE_OK;
+    default: return SQLITE_DENY;
+  }
+}
+
+static int QueryTimeoutHandler(void* context) {
+  return CFAbsoluteTimeGetCurrent() > *(CFAbsoluteTime*)context;
+}
+
+static id ColumnValue(sqlite3_stmt* stmt, int column) {
+  switch (sqlite3_column_type(stmt, column)) {
+    case SQLITE_INTEGER: return @(sqlite3_column_int64(stmt, column));
+    case SQLITE_FLOAT: return @(sqlite3_column_double(stmt, column));
+    case SQLITE_TEXT: return @((const char*)sqlite3_column_text(stmt, column));
+    case SQLITE_BLOB:
+      return [NSData dataWithBytes:sqlite3_column_blob(stmt, column)
+                            length:sqlite3_column_bytes(stmt, column)];
+    default: return [NSNull null];
+  }
+}
+
+@implementation SNTDecisionTable {
+  // The members below are only accessed on _writeQueue.
+  dispatch_queue_t _writeQueue;
+  NSMutableArray<SNTStoredExecutionEvent*>* _pending;
+  BOOL _writeScheduled;
+  NSUInteger _maxDecisions;
+  dispatch_source_t _pruneTimer;
+}
+
+- (instancetype)initWithDatabaseQueue:(FMDatabaseQueue*)db {
+  self = [super initWithDatabaseQueue:db];
+  if (self) {
+    _writeQueue = dispatch_queue_create("com.northpolesec.santa.daemon.decision_table",
+                                        DISPATCH_QUEUE_SERIAL);
+    _pending = [NSMutableArray array];
+  }
+  return self;
+}
+
+- (void)dealloc {
+  if (_pruneTimer) dispatch_source_cancel(_pruneTimer);
+}
+
+- (uint32_t)currentSupportedVersion {
+  return kDecisionTableCurrentVersion;
+}
+
+- (uint32_t)initializeDatabase:(FMDatabase*)db fromVersion:(uint32_t)version {
+  int newVersion = 0;
+
+  if (version < 1) {
+    [db executeUpdate:@"CREATE TABLE 'decisions' ("
+                      @"'idx' INTEGER PRIMARY KEY,"
+                      @"'occurrence_time' REAL NOT NULL,"
+                      @"'file_sha256' TEXT,"
+                      @"'file_path' TEXT,"
+                      @"'decision' TEXT NOT NULL,"
+                      @"'event_state' INTEGER NOT NULL,"
+                      @"'team_id' TEXT,"
+                      @"'signing_id' TEXT,"
+                      @"'cdhash' TEXT,"
+                      @"'pid' INTEGER,"
+                      @"'ppid' INTEGER);"];
+    [db executeUpdate:@"CREATE INDEX decisions_file_sha256 ON decisions (file_sha256);"];
+    [db executeUpdate:@"CREATE INDEX decisions_decision ON decisions (decision);"];
+    [db executeUpdate:@"CREATE INDEX decisions_occurrence_time ON decisions (occurrence_time);"];
+    newVersion = 1;
+  }
+
+  return newVersion;
+}
+
+#pragma mark Storing
+
+- (BOOL)addDecision:(SNTStoredExecutionEvent*)event maxDecisions:(NSUInteger)maxDecisions {
+  if (!event.occurrenceDate || maxDecisions == 0) return NO;
+  return [self addDecisions:@[ event ]] && [self pruneToMaxDecisions:maxDecisions];
+}
+
+- (void)recordDecision:(SNTStoredExecutionEvent*)event maxDecisions:(NSUInteger)maxDecisions {
+  if (!event.occurrenceDate || maxDecisions == 0) return;
+
+  dispatch_async(_writeQueue, ^{
+    self->_maxDecisions = maxDecisions;
+    [self->_pending addObject:event];
+    [self startPruneTimerIfNeeded];
+
+    if (self->_pending.count >= kDecisionBatchSize) {
+      [self writePendingDecisions];
+    } else if (!self->_writeScheduled) {
+      self->_writeScheduled = YES;
+      dispatch_after(dispatch_time(DISPATCH_TIME_NOW, kDecisionBatchDelaySeconds * NSEC_PER_SEC),
+                     self->_writeQueue, ^{
+                       [self writePendingDecisions];
+                     });
+    }
+  });
+}
+
+- (void)flushPendingDecisions {
+  dispatch_sync(_writeQueue, ^{
+    [self writePendingDecisions];
+    if (self->_maxDecisions) [self pruneToMaxDecisions:self->_maxDecisions];
+  });
+}
+
+// Must be called on _writeQueue.
+- (void)startPruneTimerIfNeeded {
+  if (_pruneTimer) return;
+
+  _pruneTimer = dispatch_source_create(DISPATCH_SOURCE_TYPE_TIMER, 0, 0, _writeQueue);
+  uint64_t interval = kDecisionPruneIntervalSeconds * NSEC_PER_SEC;
+  dispatch_source_set_timer(_pruneTimer, dispatch_time(DISPATCH_TIME_NOW, interval), interval,
+                            NSEC_PER_SEC);
+  __weak SNTDecisionTable* weakSelf = self;
+  dispatch_source_set_event_handler(_pruneTimer, ^{
+    SNTDecisionTable* strongSelf = weakSelf;
+    if (!strongSelf) return;
+    [strongSelf writePendingDecisions];
+    [strongSelf pruneToMaxDecisions:strongSelf->_maxDecisions];
+  });
+  dispatch_resume(_pruneTimer);
+}
+
+// Must be called on _writeQueue.
+- (void)writePendingDecisions {
+  _writeScheduled = NO;
+  if (!_pending.count) return;
+
+  NSArray<SNTStoredExecutionEvent*>* batch = _pending;
+  _pending = [NSMutableArray array];
+  if (![self addDecisions:batch]) {
+    LOGE(@"Failed to record %lu decisions", batch.count);
+  }
+}
+
+- (BOOL)addDecisions:(NSArray<SNTStoredExecutionEvent*>*)events {
+  __block BOOL success = YES;
+  [self inTransaction:^(FMDatabase* db, BOOL* rollback) {
+    for (SNTStoredExecutionEvent* event in events) {
+      success = [db executeUpdate:@"INSERT INTO decisions (occurrence_time, file_sha256, "
+                                  @"file_path, decision, event_state, team_id, signing_id, "
+                                  @"cdhash, pid, ppid) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?)",
+                                  @(event.occurrenceDate.timeIntervalSince1970), event.fileSHA256,
+                                  event.filePath, DecisionString(event.decision), @(event.decision),
+                                  event.teamID, event.signingID, event.cdhash, event.pid,
+                                  event.ppid];
+      if (!success) break;
+    }
+    if (!success) *rollback = YES;
+  }];
+  return success;
+}
+
+- (BOOL)pruneToMaxDecisions:(NSUInteger)maxDecisions {
+  if (maxDecisions == 0) return NO;
+
+  __block BOOL success = NO;
+  [self inDatabase:^(FMDatabase* db) {
+    // Rows are only ever deleted oldest first, so the newest maxDecisions rows are the ones with
+    // the highest indexes.
+    success = [db executeUpdate:@"DELETE FROM decisions WHERE idx <= "
+                                @"(SELECT MAX(idx) FROM decisions) - ?",
+                                @(maxDecisions)];
+  }];
+  return success;
+}
+
+#pragma mark Querying
+
+- (NSUInteger)decisionCount {
+  [self flushPendingDecisions];
+
+  __block NSUInteger count = 0;
+  [self inDatabase:^(FMDatabase* db) {
+    count = [db intForQuery:@"SELECT COUNT(*) FROM decisions"];
+  }];
+  return count;
+}
+
+- (NSArray<NSArray*>*)executeReadOnlyQuery:(NSString*)sql
+                                   columns:(NSArray<NSString*>**)columns
+                                     error:(NSError**)error {
+  // Include decisions that are still waiting to be written.
+  [self flushPendingDecisions];
+
+  __block NSMutableArray<NSArray*>* rows;
+  __block NSMutableArray<NSString*>* columnNames;
+  __block NSError* err;
+
+  [self inDatabase:^(FMDatabase* db) {
+    sqlite3* handle = (sqlite3*)db.sqliteHandle;
+    sqlite3_stmt* stmt = NULL;
+    sqlite3_stmt* next = NULL;
+    const char* tail = NULL;
+
+    sqlite3_set_authorizer(handle, ReadOnlyAuthorizer, NULL);
+    int rc = sqlite3_prepare_v2(handle, sql.UTF8String, -1, &stmt, &tail);
+    // Only the first statement is compiled. Refuse input containing more than one so that the
+    // rest isn't silently ignored.
+    if (rc == SQLITE_OK && stmt && tail) {
+      rc = sqlite3_prepare_v2(handle, tail, -1, &next, NULL);
+    }
+    sqlite3_set_authorizer(handle, NULL, NULL);
+
+    if (rc == SQLITE_AUTH) {
+      err = [SNTError createErrorWithCode:SNTErrorCodeQueryNotReadOnly
+                                   format:@"Only read-only queries are allowed"];
+    } else if (rc != SQLITE_OK) {
+      err = [SNTError createErrorWithCode:SNTErrorCodeQueryInvalid
+                                   format:@"Invalid query: %s", sqlite3_errmsg(handle)];
+    } else if (!stmt) {
+      err = [SNTError createErrorWithCode:SNTErrorCodeQueryInvalid format:@"Empty query"];
+    } else if (next) {
+      err = [SNTError createErrorWithCode:SNTErrorCodeQueryInvalid
+                                   format:@"Only a single statement is allowed"];
+    }
+    if (err) {
+      sqlite3_finalize(stmt);
+      sqlite3_finalize(next);
+      return;
+    }
+
+    int columnCount = sqlite3_column_count(stmt);
+    columnNames = [NSMutableArray arrayWithCapacity:columnCount];
+    for (int i = 0; i < columnCount; ++i) {
+      [columnNames addObject:@(sqlite3_column_name(stmt, i))];
+    }
+
+    CFAbsoluteTime deadline = CFAbsoluteTimeGetCurrent() + kDecisionQueryTimeoutSeconds;
+    sqlite3_progress_handler(handle, 1000, QueryTimeoutHandler, &deadline);
+
+    rows = [NSMutableArray array];
+    while (rows.count < kDecisionQueryMaxRows && (rc = sqlite3_step(stmt)) == SQLITE_ROW) {
+      NSMutableArray* row = [NSMutableArray arrayWithCapacity:columnCount];
+      for (int i = 0; i < columnCount; ++i) {
+        [row addObject:ColumnValue(stmt, i)];
+      }
+      [rows addObject:row];
+    }
+
+    sqlite3_progress_handler(handle, 0, NULL, NULL);
+
+    if (rc == SQLITE_INTERRUPT) {
+      err = [SNTError createErrorWithCode:SNTErrorCodeQueryFailed
+                                   format:@"Query took longer than %.0f seconds",
+                                          kDecisionQueryTimeoutSeconds];
+    } else if (rc != SQLITE_ROW && rc != SQLITE_DONE) {
+      err = [SNTError createErrorWithCode:SNTErrorCodeQueryFailed
+                                   format:@"Query failed: %s", sqlite3_errmsg(handle)];
+    }
+    sqlite3_finalize(stmt);
+  }];
+
+  if (err) {
+    if (error) *error = err;
+    return nil;
+  }
+
+  if (columns) *columns = columnNames;
+  return rows;
+}
+
+@end
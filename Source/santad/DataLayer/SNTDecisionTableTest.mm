@@ -0,0 +1,180 @@
+/// Copyright 2026 North Pole Security, Inc.
+///
+/// Licensed under the Apache License, Version 2.0 (the "License");
+/// you may not use this file except in compliance with the License.
+/// You may obtain a copy of the License at
+///
+///     http://www.apache.org/licenses/LICENSE-2.0
+///
+/// Unless required by applicable law or agreed to in writing, software
+/// distributed under the License is distributed on an "AS IS" BASIS,
+/// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+/// See the License for the specific language governing permissions and
+/// limitations under the License.
+
+
+#import "Source/santad/DataLayer/SNTDecisionTable.h"
+
+#import <Foundation/Foundation.h>
+#import <XCTest/XCTest.h>
+
+#import "Source/common/SNTCommonEnums.h"
+#import "Source/common/SNTError.h"
+#import "Source/common/SNTStoredExecutionEvent.h"
+
+@interface SNTDecisionTableTest : XCTestCase
+@property SNTDecisionTable* sut;
+@property NSDate* baseDate;
+@end
+
+@implementation SNTDecisionTableTest
+
+- (void)setUp {
+  [super setUp];
+
+  self.sut = [[SNTDecisionTable alloc] initWithDatabaseQueue:[[FMDatabaseQueue alloc] init]];
+  self.baseDate = [NSDate dateWithTimeIntervalSince1970:1700000000];
+}
+
+- (SNTStoredExecutionEvent*)decisionAtOffset:(NSTimeInterval)offset
+                                    decision:(SNTEventState)decision {
+  SNTStoredExecutionEvent* se = [[SNTStoredExecutionEvent alloc] init];
+  se.occurrenceDate = [self.baseDate dateByAddingTimeInterval:offset];
+  se.fileSHA256 = [NSString stringWithFormat:@"%064d", (int)offset];
+  se.filePath = [NSString stringWithFormat:@"/usr/local/bin/tool%d", (int)offset];
+  se.decision = decision;
+  se.teamID = @"EQHXZ8M8AV";
+  se.pid = @(100 + (int)offset);
+  return se;
+}
+
+- (NSArray<NSArray*>*)query:(NSString*)sql {
+  NSError* error;
+  NSArray<NSArray*>* rows = [self.sut executeReadOnlyQuery:sql columns:NULL error:&error];
+  XCTAssertNil(error);
+  return rows;
+}
+
+- (void)testAddAndQueryDecisions {
+  XCTAssertTrue([self.sut addDecision:[self decisionAtOffset:0 decision:SNTEventStateAllowBinary]
+                         maxDecisions:100]);
+  XCTAssertTrue([self.sut addDecision:[self decisionAtOffset:1 decision:SNTEventStateBlockTeamID]
+                         maxDecisions:100]);
+  XCTAssertTrue([self.sut addDecision:[self decisionAtOffset:2 decision:SNTEventStateBlockUnknown]
+                         maxDecisions:100]);
+  XCTAssertEqual([self.sut decisionCount], 3);
+
+  NSArray<NSString*>* columns;
+  NSError* error;
+  NSArray<NSArray*>* rows =
+      [self.sut executeReadOnlyQuery:@"SELECT file_path, decision, pid, occurrence_time "
+                                     @"FROM decisions WHERE decision = 'BLOCK' ORDER BY idx"
+                             columns:&columns
+                               error:&error];
+
+  XCTAssertNil(error);
+  NSArray* wantColumns = @[ @"file_path", @"decision", @"pid", @"occurrence_time" ];
+  XCTAssertEqualObjects(columns, wantColumns);
+  XCTAssertEqual(rows.count, 2);
+  NSArray* wantRow = @[ @"/usr/local/bin/tool1", @"BLOCK", @101, @1700000001.0 ];
+  XCTAssertEqualObjects(rows[0], wantRow);
+  XCTAssertEqualObjects(rows[1][0], @"/usr/local/bin/tool2");
+
+  rows = [self query:[NSString stringWithFormat:@"SELECT event_state, signing_id FROM decisions "
+                                                @"WHERE file_sha256 = '%064d'",
+                                                0]];
+  XCTAssertEqual(rows.count, 1);
+  NSArray* wantStateRow = @[ @(SNTEventStateAllowBinary), [NSNull null] ];
+  XCTAssertEqualObjects(rows[0], wantStateRow);
+}
+
+- (void)testOldestDecisionsAreDeletedAtMaxDecisions {
+  for (int i = 0; i < 10; i++) {
+    XCTAssertTrue([self.sut addDecision:[self decisionAtOffset:i decision:SNTEventStateAllowBinary]
+                           maxDecisions:4]);
+  }
+
+  XCTAssertEqual([self.sut decisionCount], 4);
+  NSArray<NSArray*>* rows = [self query:@"SELECT pid FROM decisions ORDER BY occurrence_time"];
+  NSArray* want = @[ @[ @106 ], @[ @107 ], @[ @108 ], @[ @109 ] ];
+  XCTAssertEqualObjects(rows, want);
+
+  // Lowering the bound trims the table on the next decision.
+  XCTAssertTrue([self.sut addDecision:[self decisionAtOffset:10 decision:SNTEventStateAllowBinary]
+                         maxDecisions:2]);
+  XCTAssertEqual([self.sut decisionCount], 2);
+}
+
+- (void)testRecordedDecisionsAreWrittenInBatches {
+  for (int i = 0; i < 3; i++) {
+    [self.sut recordDecision:[self decisionAtOffset:i decision:SNTEventStateAllowBinary]
+                maxDecisions:100];
+  }
+  // Queries include decisions that haven't been written yet.
+  XCTAssertEqual([self.sut decisionCount], 3);
+
+  // The table is trimmed to the latest maxDecisions once the queued decisions are flushed.
+  for (int i = 3; i < 150; i++) {
+    [self.sut recordDecision:[self decisionAtOffset:i decision:SNTEventStateAllowBinary]
+                maxDecisions:120];
+  }
+  [self.sut flushPendingDecisions];
+  XCTAssertEqual([self.sut decisionCount], 120);
+  NSArray<NSArray*>* rows = [self query:@"SELECT MIN(pid), MAX(pid) FROM decisions"];
+  NSArray* want = @[ @[ @130, @249 ] ];
+  XCTAssertEqualObjects(rows, want);
+}
+
+- (void)testIndexesExist {
+  NSArray<NSArray*>* rows = [self query:@"SELECT name FROM sqlite_master WHERE type = 'index' "
+                                        @"AND tbl_name = 'decisions' ORDER BY name"];
+  NSArray* want = @[
+    @[ @"decisions_decision" ], @[ @"decisions_file_sha256" ], @[ @"decisions_occurrence_time" ]
+  ];
+  XCTAssertEqualObjects(rows, want);
+}
+
+- (void)testWritesAreRejected {
+  [self.sut addDecision:[self decisionAtOffset:0 decision:SNTEventStateAllowBinary]
+           maxDecisions:100];
+
+  for (NSString* sql in @[
+         @"DELETE FROM decisions", @"UPDATE decisions SET decision = 'ALLOW'",
+         @"DROP TABLE decisions", @"ATTACH DATABASE '/tmp/other.db' AS other",
+         @"PRAGMA journal_mode=DELETE"
+       ]) {
+    NSError* error;
+    XCTAssertNil([self.sut executeReadOnlyQuery:sql columns:NULL error:&error], @"%@", sql);
+    XCTAssertEqual(error.code, SNTErrorCodeQueryNotReadOnly, @"%@", sql);
+  }
+
+  XCTAssertEqual([self.sut decisionCount], 1);
+}
+
+- (void)testMultipleStatementsAreRejected {
+  [self.sut addDecision:[self decisionAtOffset:0 decision:SNTEventStateAllowBinary]
+           maxDecisions:100];
+
+  NSError* error;
+  XCTAssertNil([self.sut executeReadOnlyQuery:@"SELECT 1; SELECT 2"
+                                      columns:NULL
+                                        error:&error]);
+  XCTAssertEqual(error.code, SNTErrorCodeQueryInvalid);
+
+  // A trailing semicolon is not a second statement.
+  XCTAssertEqual([self query:@"SELECT * FROM decisions;"].count, 1);
+}
+
+- (void)testInvalidQuery {
+  NSError* error;
+  XCTAssertNil([self.sut executeReadOnlyQuery:@"SELECT * FROM nonexistent"
+                                      columns:NULL
+                                        error:&error]);
+  XCTAssertEqual(error.code, SNTErrorCodeQueryInvalid);
+
+  error = nil;
+  XCTAssertNil([self.sut executeReadOnlyQuery:@"  " columns:NULL error:&error]);
+  XCTAssertEqual(error.code, SNTErrorCodeQueryInvalid);
+}
+
+@end
@@ -18,10 +18,13 @@
 #include <memory>
 
 #import "Source/common/MOLCertificate.h"
+#import "Source/common/SNTConfigurator.h"
 #import "Source/common/SNTLogging.h"
 #import "Source/common/SNTStoredEvent.h"
 #import "Source/common/SNTStoredExecutionEvent.h"
 #import "Source/common/SNTStoredFileAccessEvent.h"
+#import "Source/common/SNTStoredLaunchItemEvent.h"
+#import "Source/common/SNTStoredRuleChangeAuditEvent.h"
 #import "Source/common/SNTStoredSignalReport.h"
 #import "Source/common/SNTStoredTemporaryAdminModeAuditEvent.h"
 #import "Source/common/SNTStoredTemporaryMonitorModeAuditEvent.h"
@@ -139,12 +142,24 @@ static const NSTimeInterval kSignalReportDedupWindowSeconds = (60 * 10);
   } else if ([event isKindOfClass:[SNTStoredTemporaryAdminModeAuditEvent class]]) {
     SNTStoredTemporaryAdminModeAuditEvent* se = (SNTStoredTemporaryAdminModeAuditEvent*)event;
     return se.uuid != nil;
+  } else if ([event isKindOfClass:[SNTStoredRuleChangeAuditEvent class]]) {
+    SNTStoredRuleChangeAuditEvent* se = (SNTStoredRuleChangeAuditEvent*)event;
+    return se.uuid != nil && se.occurrenceDate;
+  } else if ([event isKindOfClass:[SNTStoredLaunchItemEvent class]]) {
+    SNTStoredLaunchItemEvent* se = (SNTStoredLaunchItemEvent*)event;
+    return se.uuid != nil && se.itemPath.length && se.occurrenceDate;
   } else {
     return NO;
   }
 }
 
 - (BOOL)addStoredEvents:(NSArray<SNTStoredEvent*>*)events {
+  // When event upload is disabled there is nothing to drain the table, so
+  // don't let events accumulate locally.
+  if ([SNTConfigurator configurator].disableEventUpload) {
+    return YES;
+  }
+
   NSMutableDictionary* eventsData = [NSMutableDictionary dictionaryWithCapacity:events.count];
   for (SNTStoredEvent* event in events) {
     if (![self isValidStoredEvent:event]) {
@@ -233,7 +248,9 @@ static const NSTimeInterval kSignalReportDedupWindowSeconds = (60 * 10);
                             [SNTStoredTemporaryAdminModeAuditEvent class],
                             [SNTStoredTemporaryAdminModeEnterAuditEvent class],
                             [SNTStoredTemporaryAdminModeLeaveAuditEvent class],
-                            [SNTStoredTemporaryAdminModeDeniedAuditEvent class], nil];
+                            [SNTStoredTemporaryAdminModeDeniedAuditEvent class],
+                            [SNTStoredRuleChangeAuditEvent class],
+                            [SNTStoredLaunchItemEvent class], nil];
   NSError* err;
   SNTStoredEvent* event = [NSKeyedUnarchiver unarchivedObjectOfClasses:allowedClasses
                                                               fromData:eventData
@@ -23,7 +23,10 @@
 #import "Source/common/MOLCodesignChecker.h"
 #import "Source/common/SNTFileInfo.h"
 #import "Source/common/SNTStoredExecutionEvent.h"
+#import "Source/common/SNTRule.h"
 #import "Source/common/SNTStoredFileAccessEvent.h"
+#import "Source/common/SNTStoredLaunchItemEvent.h"
+#import "Source/common/SNTStoredRuleChangeAuditEvent.h"
 #import "Source/common/SNTStoredSignalReport.h"
 #import "Source/common/SNTStoredTemporaryMonitorModeAuditEvent.h"
 #include "Source/common/TestUtils.h"
@@ -325,6 +328,55 @@ NSString* GenerateRandomHexStringWithSHA256Length() {
   XCTAssertEqual(tmmLeave.reason, SNTTemporaryMonitorModeLeaveReasonSessionExpired);
 }
 
+- (void)testRetrieveRuleChangeAuditEvent {
+  SNTRule* rule = [[SNTRule alloc] initWithIdentifier:@"EQHXZ8M8AV"
+                                                state:SNTRuleStateAllow
+                                                 type:SNTRuleTypeTeamID];
+  SNTProcessChain* process = [[SNTProcessChain alloc] init];
+  process.executingUser = @"alice";
+  SNTStoredRuleChangeAuditEvent* event = [[SNTStoredRuleChangeAuditEvent alloc]
+      initWithSource:SNTRuleChangeAuditSourceStandaloneApproval
+               rules:@[ rule ]
+         ruleCleanup:SNTRuleCleanupNone
+             process:process];
+  [self.sut addStoredEvent:event];
+
+  SNTStoredEvent* storedEvent = [self.sut pendingEvents].firstObject;
+  XCTAssertTrue([storedEvent isKindOfClass:[SNTStoredRuleChangeAuditEvent class]]);
+
+  SNTStoredRuleChangeAuditEvent* ruleChange = (SNTStoredRuleChangeAuditEvent*)storedEvent;
+  XCTAssertEqualObjects(ruleChange.uuid, event.uuid);
+  XCTAssertEqual(ruleChange.source, SNTRuleChangeAuditSourceStandaloneApproval);
+  XCTAssertEqualObjects(ruleChange.rules, @[ rule ]);
+  XCTAssertEqualObjects(ruleChange.process.executingUser, @"alice");
+}
+
+- (void)testRetrieveLaunchItemEvent {
+  SNTStoredLaunchItemEvent* event = [[SNTStoredLaunchItemEvent alloc] init];
+  event.itemType = SNTStoredLaunchItemEventItemTypeAgent;
+  event.itemPath = @"/Library/LaunchAgents/com.example.agent.plist";
+  event.executablePath = @"/usr/local/bin/agent";
+  event.decision = SNTStoredLaunchItemEventDecisionUnknown;
+  [self.sut addStoredEvent:event];
+
+  SNTStoredEvent* storedEvent = [self.sut pendingEvents].firstObject;
+  XCTAssertTrue([storedEvent isKindOfClass:[SNTStoredLaunchItemEvent class]]);
+
+  SNTStoredLaunchItemEvent* launchItem = (SNTStoredLaunchItemEvent*)storedEvent;
+  XCTAssertEqualObjects(launchItem.uuid, event.uuid);
+  XCTAssertEqual(launchItem.itemType, SNTStoredLaunchItemEventItemTypeAgent);
+  XCTAssertEqualObjects(launchItem.itemPath, @"/Library/LaunchAgents/com.example.agent.plist");
+  XCTAssertEqual(launchItem.decision, SNTStoredLaunchItemEventDecisionUnknown);
+}
+
+- (void)testLaunchItemEventWithoutItemPathIsNotStored {
+  SNTStoredLaunchItemEvent* event = [[SNTStoredLaunchItemEvent alloc] init];
+  event.executablePath = @"/usr/local/bin/agent";
+  [self.sut addStoredEvent:event];
+
+  XCTAssertEqual(self.sut.pendingEventsCount, 0);
+}
+
 - (void)testDeleteEventWithId {
   SNTStoredEvent* newEvent = [self createTestEvent];
   [self.sut addStoredEvent:newEvent];
@@ -106,15 +106,43 @@
 
 ///
 ///  @return Rule for given identifiers.
-///          Currently: binary, signingID, certificate or teamID (in that order).
-///          The first matching rule found is returned.
+///          Currently: cdhash, binary, signingID, certificate or teamID (in that order, unless
+///          changed with updateRulePrecedence:). The first matching rule found is returned.
+///          Static rules are checked first, then the rules from rule sources and finally the
+///          rules from the sync server.
 ///
 - (SNTRule*)executionRuleForIdentifiers:(struct RuleIdentifiers)identifiers;
 
+///
+///  @return All requirement rules, which can't be looked up by identifier and must instead be
+///          evaluated against the binary's code signature. Static rules come first, followed
+///          by the rules from the sync server in identifier order.
+///
+- (NSArray<SNTRule*>*)requirementRules;
+
+///
+///  @return YES if there are any bundle Signing ID rules, so that callers can skip working out
+///          the Signing IDs to look up.
+///
+- (BOOL)hasBundleSigningIDRules;
+
+///
+///  @param signingIDs Signing IDs in the `TeamID:SigningID` form, in the order they should
+///                    be checked.
+///  @return The bundle Signing ID rule for the first of signingIDs that has one, or nil. Static
+///          rules are checked first, followed by the rules from the sync server.
+///
+- (SNTRule*)bundleSigningIDRuleForSigningIDs:(NSArray<NSString*>*)signingIDs;
+
 ///
 ///  Add an array of execution rules, file access rules, and network flow rules to the database.
 ///  All rules across all three types are applied within a single transaction; the transaction
-///  is aborted if any rule fails to apply.
+///  is rolled back if any rule or the requested cleanup fails to apply, leaving the database as
+///  it was before the call.
+///
+///  If RuleApplyBatchSize is configured and no cleanup is requested, execution rules are
+///  instead applied in transactions of at most that many rules. If a batch fails, the batches
+///  that were already committed are undone.
 ///
 ///  @param executionRules Array of SNTRule objects to add.
 ///  @param fileAccessRules Array of SNTFileAccessRule objects to add.
@@ -190,11 +218,39 @@
 ///
 - (NSString*)networkFlowRulesHash;
 
+///
+///  Replace all of the execution rules provided by a configured rule source. Rules from rule
+///  sources take precedence over the rules in the execution rules table and are not included in
+///  its hash. Rules for sources that are no longer configured are removed.
+///
+///  @param source The name of a source configured in RuleSources.
+///  @param precedence The precedence of the source, higher precedence sources are checked first.
+///  @param rules The full set of rules from the source.
+///  @param errors When returning NO, will be filled with an array of errors.
+///  @return YES if the rules were replaced.
+///
+- (BOOL)replaceRulesForRuleSource:(NSString*)source
+                       precedence:(NSInteger)precedence
+                            rules:(NSArray<SNTRule*>*)rules
+                           errors:(NSArray<NSError*>**)errors;
+
+///
+///  @return Number of execution rules provided by rule sources.
+///
+- (int64_t)ruleSourceRuleCount;
+
 ///
 ///  Update the static rules from the configuration.
 ///
 - (void)updateStaticRules:(NSArray<NSDictionary*>*)staticRules;
 
+///
+///  Update the order in which rule types are checked by executionRuleForIdentifiers:. Takes
+///  SNTRuleType values from highest to lowest precedence, as returned by SNTConfigurator's
+///  rulePrecedence. Passing nil restores the default ordering.
+///
+- (void)updateRulePrecedence:(NSArray<NSNumber*>*)precedence;
+
 ///
 ///  Cached static rules.
 ///
@@ -211,6 +267,18 @@
 ///
 @property(readonly, nonatomic) NSDictionary<NSString*, SNTCachedDecision*>* criticalSystemBinaries;
 
+///
+///  YES if the rule database was unreadable or its contents didn't match the stored checksum when
+///  loaded. When this happens all rules are removed and a clean sync is requested.
+///
+@property(readonly) BOOL corruptionDetected;
+
+///
+///  Recompute the rules checksum if rules changed since it was last computed. Rule updates only
+///  mark the checksum stale; it is recomputed shortly afterwards and when hashOfHashes is called.
+///
+- (void)updateRulesChecksumIfStale;
+
 ///
 /// If set, this callback is called when file access rule content is changed via
 /// addExecutionRules:fileAccessRules:ruleCleanup:error: with the latest rule count.
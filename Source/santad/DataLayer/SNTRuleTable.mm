@@ -28,6 +28,7 @@
 #import "Source/common/SNTFileAccessRule.h"
 #import "Source/common/SNTFileInfo.h"
 #import "Source/common/SNTLogging.h"
+#import "Source/common/SNTMetricSet.h"
 #import "Source/common/SNTRule.h"
 #import "Source/common/SNTSignal.h"
 #import "Source/common/SNTXxhash.h"
@@ -35,12 +36,15 @@
 #include "Source/common/String.h"
 #include "Source/common/cel/Evaluator.h"
 
-static const uint32_t kRuleTableCurrentVersion = 15;
+static const uint32_t kRuleTableCurrentVersion = 18;
 
 // How many rules must be in database before we start trying to remove transitive rules.
 static const int64_t kTransitiveRuleCullingThreshold = 500000;
 // Consider transitive rules out of date if they haven't been used in six months.
 static const NSUInteger kTransitiveRuleExpirationSeconds = 6 * 30 * 24 * 3600;
+// How long after a rule update the rules checksum is recomputed, so a burst of updates only
+// recomputes it once.
+static const int64_t kRulesChecksumUpdateDelaySeconds = 30;
 
 static void addPathsFromDefaultMuteSet(NSMutableSet* criticalPaths) {
   // Create a temporary ES client in order to grab the default set of muted paths.
@@ -73,6 +77,121 @@ static void addPathsFromDefaultMuteSet(NSMutableSet* criticalPaths) {
   es_delete_client(client);
 }
 
+// Ranks rule states for SNTDuplicateRuleResolutionHighestPrecedence, more restrictive rules
+// rank higher.
+static int DuplicateRulePrecedence(SNTRuleState state) {
+  switch (state) {
+    case SNTRuleStateBlock: [[fallthrough]];
+    case SNTRuleStateSilentBlock: [[fallthrough]];
+    case SNTRuleStateSilentBlockGUI: [[fallthrough]];
+    case SNTRuleStateSilentBlockTTY: return 3;
+    case SNTRuleStateCEL: [[fallthrough]];
+    case SNTRuleStateCELv2: [[fallthrough]];
+    case SNTRuleStateSeatbelt: return 2;
+    case SNTRuleStateAllow: [[fallthrough]];
+    case SNTRuleStateAllowCompiler: [[fallthrough]];
+    case SNTRuleStateAllowTransitive: [[fallthrough]];
+    case SNTRuleStateAllowLocalBinary: [[fallthrough]];
+    case SNTRuleStateAllowLocalSigningID: return 1;
+    case SNTRuleStateRemove: [[fallthrough]];
+    case SNTRuleStateUnknown: return 0;
+  }
+  return 0;
+}
+
+// Collapses execution rules that share an identifier and rule type down to a single rule,
+// chosen by resolution. The surviving rule takes the position of the first of its duplicates.
+// Entries that aren't valid rules are kept as-is so that they are still reported as errors.
+static NSArray<SNTRule*>* DeduplicateExecutionRules(NSArray<SNTRule*>* rules,
+                                                    SNTDuplicateRuleResolution resolution) {
+  NSMutableArray<SNTRule*>* deduplicated = [NSMutableArray arrayWithCapacity:rules.count];
+  NSMutableDictionary<NSString*, NSNumber*>* indexes = [NSMutableDictionary dictionary];
+
+  for (SNTRule* rule in rules) {
+    if (![rule isKindOfClass:[SNTRule class]] || !rule.identifier.length) {
+      [deduplicated addObject:rule];
+      continue;
+    }
+
+    NSString* key = [NSString stringWithFormat:@"%ld:%@", (long)rule.type, rule.identifier];
+    NSNumber* index = indexes[key];
+    if (!index) {
+      indexes[key] = @(deduplicated.count);
+      [deduplicated addObject:rule];
+      continue;
+    }
+
+    SNTRule* existing = deduplicated[index.unsignedIntegerValue];
+    if (resolution == SNTDuplicateRuleResolutionLastWins ||
+        DuplicateRulePrecedence(rule.state) >= DuplicateRulePrecedence(existing.state)) {
+      deduplicated[index.unsignedIntegerValue] = rule;
+    }
+  }
+
+  if (deduplicated.count != rules.count) {
+    LOGI(@"Resolved %lu duplicate execution rule(s)",
+         (unsigned long)(rules.count - deduplicated.count));
+  }
+  return deduplicated;
+}
+
+// Execution rules keyed by rule type and then identifier.
+typedef NSDictionary<NSNumber*, NSDictionary<NSString*, SNTRule*>*> SNTRulesByType;
+
+static void AddRuleToRulesByType(NSMutableDictionary<NSNumber*, NSMutableDictionary*>* rulesByType,
+                                 SNTRule* rule) {
+  NSMutableDictionary<NSString*, SNTRule*>* rules = rulesByType[@(rule.type)];
+  if (!rules) {
+    rules = [NSMutableDictionary dictionary];
+    rulesByType[@(rule.type)] = rules;
+  }
+  rules[rule.identifier] = rule;
+}
+
+// Returns the highest precedence rule in rulesByType that matches identifiers.
+static SNTRule* RuleForIdentifiersInRulesByType(SNTRulesByType* rulesByType,
+                                                const struct RuleIdentifiers& identifiers,
+                                                NSArray<NSNumber*>* precedence);
+
+// An immutable copy of the execution_rules and rule_source_rules tables. When EnableRuleSnapshot
+// is set, lookups use the current snapshot instead of querying the database, and each update
+// builds a new snapshot that is swapped in once the update has been committed. Lookups therefore
+// never wait for an update to finish and never see one partially applied.
+@interface SNTExecutionRuleSnapshot : NSObject
+@property(readonly) SNTRulesByType* rules;
+// One entry per RuleSource; ordered by precedence, highest first, then by source name.
+@property(readonly) NSArray<SNTRulesByType*>* ruleSourceRules;
+// Requirement rules from the execution_rules table, sorted by identifier.
+@property(readonly) NSArray<SNTRule*>* requirementRules;
+@end
+
+@implementation SNTExecutionRuleSnapshot
+
+- (instancetype)initWithRules:(SNTRulesByType*)rules
+              ruleSourceRules:(NSArray<SNTRulesByType*>*)ruleSourceRules {
+  self = [super init];
+  if (self) {
+    _rules = rules;
+    _ruleSourceRules = ruleSourceRules;
+    _requirementRules = [rules[@(SNTRuleTypeRequirement)].allValues
+        sortedArrayUsingComparator:^NSComparisonResult(SNTRule* a, SNTRule* b) {
+          return [a.identifier compare:b.identifier];
+        }] ?: @[];
+  }
+  return self;
+}
+
+- (SNTRule*)ruleForIdentifiers:(const struct RuleIdentifiers&)identifiers
+                    precedence:(NSArray<NSNumber*>*)precedence {
+  for (SNTRulesByType* sourceRules in self.ruleSourceRules) {
+    SNTRule* rule = RuleForIdentifiersInRulesByType(sourceRules, identifiers, precedence);
+    if (rule) return rule;
+  }
+  return RuleForIdentifiersInRulesByType(self.rules, identifiers, precedence);
+}
+
+@end
+
 @interface SNTRuleTable () {
   std::unique_ptr<santa::cel::Evaluator<false>> _celEvaluator;
   std::unique_ptr<santa::cel::Evaluator<true>> _celV2Evaluator;
@@ -93,6 +212,24 @@ static void addPathsFromDefaultMuteSet(NSMutableSet* criticalPaths) {
 @property(atomic) NSString* cachedFileAccessRulesHash;
 @property(atomic) NSString* cachedNetworkFlowRulesHash;
 @property(atomic) NSString* cachedSignalRulesHash;
+@property(readwrite) BOOL corruptionDetected;
+// Whether a background update of a stale rules checksum has been scheduled.
+@property(atomic) BOOL rulesChecksumUpdateScheduled;
+// Whether rule_source_rules has any rows, so that lookups can skip querying it when no
+// RuleSources are in use. Only written inside an inDatabase:/inTransaction: block.
+@property(atomic) BOOL hasRuleSourceRules;
+// Whether execution_rules has any requirement rules, so that lookups can skip querying for them.
+// Only written inside an inDatabase:/inTransaction: block.
+@property(atomic) BOOL hasRequirementRules;
+// Whether execution_rules has any bundle Signing ID rules. Only written inside an
+// inDatabase:/inTransaction: block.
+@property(atomic) BOOL hasBundleSigningIDRulesInDB;
+// The rule types checked by executionRuleForIdentifiers:, highest precedence first, and the
+// matching ORDER BY clause for the rule queries. See updateRulePrecedence:.
+@property(atomic) NSArray<NSNumber*>* rulePrecedence;
+@property(atomic) NSString* rulePrecedenceOrderBy;
+// The current rule snapshot, nil unless EnableRuleSnapshot was set when the table was created.
+@property(atomic) SNTExecutionRuleSnapshot* executionRuleSnapshot;
 @end
 
 @implementation SNTRuleTableRulesHash
@@ -386,6 +523,44 @@ static void addPathsFromDefaultMuteSet(NSMutableSet* criticalPaths) {
     newVersion = 15;
   }
 
+  if (version < 16) {
+    // Single row table holding a checksum over the rule contents, so that modifications made
+    // outside of Santa can be detected on load.
+    [db executeUpdate:@"CREATE TABLE 'rules_checksum' ("
+                      @"'id' INTEGER PRIMARY KEY CHECK (id = 0), "
+                      @"'checksum' TEXT NOT NULL)"];
+    newVersion = 16;
+  }
+
+  if (version < 17) {
+    // Execution rules downloaded from the configured RuleSources. Each source's rules are kept
+    // separately from the primary sync server's so that either can be replaced without touching
+    // the other.
+    [db executeUpdate:@"CREATE TABLE 'rule_source_rules' ("
+                      @"'source' TEXT NOT NULL, "
+                      @"'precedence' INTEGER NOT NULL, "
+                      @"'identifier' TEXT NOT NULL, "
+                      @"'state' INTEGER NOT NULL, "
+                      @"'type' INTEGER NOT NULL, "
+                      @"'custommsg' TEXT, "
+                      @"'customurl' TEXT, "
+                      @"'timestamp' INTEGER, "
+                      @"'comment' TEXT, "
+                      @"'cel_expr' TEXT, "
+                      @"'seatbelt_policy' TEXT, "
+                      @"'rule_id' INTEGER DEFAULT 0, "
+                      @"PRIMARY KEY (source, identifier, type))"];
+    [db executeUpdate:@"CREATE INDEX rule_source_rules_lookup ON rule_source_rules "
+                      @"(identifier, type)"];
+    newVersion = 17;
+  }
+
+  if (version < 18) {
+    [db executeUpdate:@"ALTER TABLE 'execution_rules' ADD 'severity' INTEGER DEFAULT 0"];
+    [db executeUpdate:@"ALTER TABLE 'rule_source_rules' ADD 'severity' INTEGER DEFAULT 0"];
+    newVersion = 18;
+  }
+
   // Save signing info for launchd and santad. Used to ensure they are always allowed.
   self.santadCSInfo = [[MOLCodesignChecker alloc] initWithSelf];
   self.launchdCSInfo = [[MOLCodesignChecker alloc] initWithPID:1];
@@ -409,10 +584,192 @@ static void addPathsFromDefaultMuteSet(NSMutableSet* criticalPaths) {
 
   // Prime the cached static rules.
   [self updateStaticRules:[[SNTConfigurator configurator] staticRules]];
+  [self updateRulePrecedence:[[SNTConfigurator configurator] rulePrecedence]];
+
+  [self verifyRulesChecksumInDB:db];
+
+  // Drop rules from sources that are no longer configured.
+  if ([self removeRuleSourceRulesExcept:[self configuredRuleSourceNames] inDB:db]) {
+    [self storeRulesChecksumInDB:db];
+  }
+  self.hasRuleSourceRules = [db longForQuery:@"SELECT COUNT(*) FROM rule_source_rules"] > 0;
+  [self updateRuleTypePresenceInDB:db];
+
+  if ([[SNTConfigurator configurator] enableRuleSnapshot]) {
+    self.executionRuleSnapshot = [self executionRuleSnapshotFromDB:db];
+  }
 
   return newVersion;
 }
 
+#pragma mark Corruption Detection
+
+// Detects a rule database that was recreated after failing to open, or whose contents no longer
+// match the stored checksum. Either way the rules on disk can't be trusted, so they are dropped
+// and a clean sync is requested to restore them from the server.
+- (void)verifyRulesChecksumInDB:(FMDatabase*)db {
+  NSString* reason;
+  if (self.recreatedAfterCorruption) {
+    reason = @"unreadable";
+  } else {
+    NSString* stored = [db stringForQuery:@"SELECT checksum FROM rules_checksum WHERE id = 0"];
+    // A missing checksum means the database was just created or upgraded from a version that
+    // didn't store one, an empty one that rules changed since it was last computed. Either way
+    // the current contents become the baseline.
+    if (stored.length && ![stored isEqualToString:[self rulesChecksumSerialized:db]]) {
+      reason = @"checksum_mismatch";
+    }
+  }
+
+  if (reason) {
+    LOGE(@"Rule database corruption detected (%@). Removing all rules and requesting a clean "
+         @"sync. (%@)",
+         reason, [db databasePath]);
+    self.corruptionDetected = YES;
+
+    [db executeUpdate:@"DELETE FROM execution_rules"];
+    [db executeUpdate:@"DELETE FROM file_access_rules"];
+    [db executeUpdate:@"DELETE FROM network_flow_rules"];
+    [db executeUpdate:@"DELETE FROM signal_rules"];
+    [db executeUpdate:@"DELETE FROM rule_source_rules"];
+
+    [[SNTConfigurator configurator] setSyncTypeRequired:SNTSyncTypeCleanAll];
+
+    SNTMetricCounter* corruptionCount = [[SNTMetricSet sharedInstance]
+        counterWithName:@"/santa/rules/database_corruption"
+             fieldNames:@[ @"reason" ]
+               helpText:@"Count of times the rule database was found to be corrupt on load"];
+    [corruptionCount incrementForFieldValues:@[ reason ]];
+  }
+
+  if (reason || ![db stringForQuery:@"SELECT checksum FROM rules_checksum WHERE id = 0"].length) {
+    [self storeRulesChecksumInDB:db];
+  }
+}
+
+// Checksum over the contents of every rule table. Rows are ordered explicitly so the result is
+// stable across VACUUM and query plan changes. Transitive rules are excluded as they're created
+// locally on every compiler write and culled in the background; rewriting the checksum for each
+// of those would mean a full scan per event.
+- (NSString*)rulesChecksumSerialized:(FMDatabase*)db {
+  santa::Xxhash128 hash;
+  auto updateString = [&hash](NSString* str) {
+    hash.Update(str.UTF8String, [str lengthOfBytesUsingEncoding:NSUTF8StringEncoding]);
+  };
+
+  // Columns: 0=identifier, 1=state, 2=type, 3=custommsg, 4=customurl, 5=cel_expr,
+  // 6=seatbelt_policy.
+  FMResultSet* rs = [db executeQuery:@"SELECT identifier, state, type, custommsg, customurl, "
+                                     @"cel_expr, seatbelt_policy FROM execution_rules "
+                                     @"WHERE state != ? ORDER BY identifier, type",
+                                     @(SNTRuleStateAllowTransitive)];
+  while ([rs next]) {
+    int state = [rs intForColumnIndex:1];
+    int type = [rs intForColumnIndex:2];
+    updateString([rs stringForColumnIndex:0]);
+    hash.Update(static_cast<void*>(&state), sizeof(state));
+    hash.Update(static_cast<void*>(&type), sizeof(type));
+    for (int i = 3; i <= 6; i++) {
+      updateString([rs stringForColumnIndex:i]);
+    }
+  }
+  [rs close];
+
+  // Columns: 0=source, 1=precedence, 2=identifier, 3=state, 4=type, 5=custommsg, 6=customurl,
+  // 7=cel_expr, 8=seatbelt_policy.
+  rs = [db executeQuery:@"SELECT source, precedence, identifier, state, type, custommsg, "
+                        @"customurl, cel_expr, seatbelt_policy FROM rule_source_rules "
+                        @"ORDER BY source, identifier, type"];
+  while ([rs next]) {
+    int64_t precedence = [rs longLongIntForColumnIndex:1];
+    int state = [rs intForColumnIndex:3];
+    int type = [rs intForColumnIndex:4];
+    updateString([rs stringForColumnIndex:0]);
+    hash.Update(static_cast<void*>(&precedence), sizeof(precedence));
+    updateString([rs stringForColumnIndex:2]);
+    hash.Update(static_cast<void*>(&state), sizeof(state));
+    hash.Update(static_cast<void*>(&type), sizeof(type));
+    for (int i = 5; i <= 8; i++) {
+      updateString([rs stringForColumnIndex:i]);
+    }
+  }
+  [rs close];
+
+  for (NSString* query in @[
+         @"SELECT name, rule_data FROM file_access_rules ORDER BY name",
+         @"SELECT name, rule_blob FROM network_flow_rules ORDER BY name",
+         @"SELECT name, rule_data FROM signal_rules ORDER BY name",
+       ]) {
+    rs = [db executeQuery:query];
+    while ([rs next]) {
+      NSData* blob = [rs dataNoCopyForColumnIndex:1];
+      updateString([rs stringForColumnIndex:0]);
+      hash.Update(blob.bytes, blob.length);
+    }
+    [rs close];
+  }
+
+  return santa::StringToNSString(hash.HexDigest());
+}
+
+// Transitive rules are never allowed to replace other rule types (see SNTCompilerController), so
+// an update made up only of transitive rules leaves the checksum unchanged.
+- (BOOL)rulesChangeOnlyTransitive:(NSArray<SNTRule*>*)executionRules
+                  fileAccessRules:(NSArray<SNTFileAccessRule*>*)fileAccessRules
+                 networkFlowRules:(NSArray<SNTNetworkFlowRule*>*)networkFlowRules
+                          signals:(NSArray<SNTSignal*>*)signals
+                      ruleCleanup:(SNTRuleCleanup)cleanupType {
+  if (cleanupType != SNTRuleCleanupNone || fileAccessRules.count || networkFlowRules.count ||
+      signals.count) {
+    return NO;
+  }
+  for (SNTRule* rule in executionRules) {
+    if (rule.state != SNTRuleStateAllowTransitive) return NO;
+  }
+  return YES;
+}
+
+- (BOOL)storeRulesChecksumInDB:(FMDatabase*)db {
+  return [db executeUpdate:@"INSERT OR REPLACE INTO rules_checksum (id, checksum) VALUES (0, ?)",
+                           [self rulesChecksumSerialized:db]];
+}
+
+// Computing the checksum reads every rule, so rule updates only clear it. It is recomputed a
+// little later in the background, or at the next preflight if that comes first.
+- (BOOL)markRulesChecksumStaleInDB:(FMDatabase*)db {
+  if (![db executeUpdate:@"INSERT OR REPLACE INTO rules_checksum (id, checksum) VALUES (0, '')"]) {
+    return NO;
+  }
+  [self scheduleRulesChecksumUpdate];
+  return YES;
+}
+
+- (void)scheduleRulesChecksumUpdate {
+  if (self.rulesChecksumUpdateScheduled) return;
+  self.rulesChecksumUpdateScheduled = YES;
+
+  __weak SNTRuleTable* weakSelf = self;
+  dispatch_after(dispatch_time(DISPATCH_TIME_NOW, kRulesChecksumUpdateDelaySeconds * NSEC_PER_SEC),
+                 dispatch_get_global_queue(QOS_CLASS_UTILITY, 0), ^{
+                   SNTRuleTable* strongSelf = weakSelf;
+                   strongSelf.rulesChecksumUpdateScheduled = NO;
+                   [strongSelf updateRulesChecksumIfStale];
+                 });
+}
+
+- (void)updateRulesChecksumIfStale {
+  [self inDatabase:^(FMDatabase* db) {
+    [self updateRulesChecksumIfStaleInDB:db];
+  }];
+}
+
+- (void)updateRulesChecksumIfStaleInDB:(FMDatabase*)db {
+  NSString* stored = [db stringForQuery:@"SELECT checksum FROM rules_checksum WHERE id = 0"];
+  if (stored && !stored.length && ![self storeRulesChecksumInDB:db]) {
+    LOGW(@"Failed to update the rules checksum: %@", [db lastErrorMessage]);
+  }
+}
+
 #pragma mark Entry Counts
 
 - (int64_t)executionRuleCount {
@@ -534,55 +891,124 @@ static void addPathsFromDefaultMuteSet(NSMutableSet* criticalPaths) {
                                      celExpr:[rs stringForColumn:@"cel_expr"]
                               seatbeltPolicy:[rs stringForColumn:@"seatbelt_policy"]
                                       ruleId:[rs longLongIntForColumn:@"rule_id"]
+                                    severity:static_cast<SNTRuleSeverity>(
+                                                 [rs intForColumn:@"severity"])
                                        error:nil];
 }
 
+static NSString* IdentifierForRuleType(const struct RuleIdentifiers& identifiers,
+                                       SNTRuleType type) {
+  switch (type) {
+    case SNTRuleTypeCDHash: return identifiers.cdhash;
+    case SNTRuleTypeBinary: return identifiers.binarySHA256;
+    case SNTRuleTypeSigningID: return identifiers.signingID;
+    case SNTRuleTypeCertificate: return identifiers.certificateSHA256;
+    case SNTRuleTypeTeamID: return identifiers.teamID;
+    default: return nil;
+  }
+}
+
+static SNTRule* RuleForIdentifiersInRulesByType(SNTRulesByType* rulesByType,
+                                                const struct RuleIdentifiers& identifiers,
+                                                NSArray<NSNumber*>* precedence) {
+  for (NSNumber* type in precedence) {
+    NSString* identifier = IdentifierForRuleType(identifiers, (SNTRuleType)type.integerValue);
+    if (!identifier) continue;
+    SNTRule* rule = rulesByType[type][identifier];
+    if (rule) return rule;
+  }
+  return nil;
+}
+
+- (void)updateRulePrecedence:(NSArray<NSNumber*>*)precedence {
+  if (!precedence.count) {
+    self.rulePrecedence = @[
+      @(SNTRuleTypeCDHash), @(SNTRuleTypeBinary), @(SNTRuleTypeSigningID),
+      @(SNTRuleTypeCertificate), @(SNTRuleTypeTeamID)
+    ];
+    self.rulePrecedenceOrderBy = @"type ASC";
+    return;
+  }
+
+  // The default ordering matches the numeric order of the rule types. For anything else, rank
+  // each type by its position in the configured ordering. The values are SNTRuleType numbers
+  // validated by SNTConfigurator, so they are safe to embed in the query.
+  NSMutableString* orderBy = [NSMutableString stringWithString:@"CASE type"];
+  [precedence enumerateObjectsUsingBlock:^(NSNumber* type, NSUInteger idx, BOOL* stop) {
+    [orderBy appendFormat:@" WHEN %ld THEN %lu", type.longValue, (unsigned long)idx];
+  }];
+  [orderBy appendString:@" END ASC"];
+
+  self.rulePrecedence = [precedence copy];
+  self.rulePrecedenceOrderBy = orderBy;
+}
+
 - (SNTRule*)executionRuleForIdentifiers:(struct RuleIdentifiers)identifiers {
   __block SNTRule* rule;
+  NSArray<NSNumber*>* precedence = self.rulePrecedence;
+  NSString* orderBy = self.rulePrecedenceOrderBy;
 
   // Look for a static rule that matches.
   NSDictionary* staticRules = self.cachedStaticRules;
   if (staticRules.count) {
     // IMPORTANT: The order static rules are checked here should be the same
     // order as given by the SQL query for the rules database.
-    rule = staticRules[identifiers.cdhash];
-    if (rule.type == SNTRuleTypeCDHash) {
-      return rule;
-    }
-
-    rule = staticRules[identifiers.binarySHA256];
-    if (rule.type == SNTRuleTypeBinary) {
-      return rule;
-    }
-
-    rule = staticRules[identifiers.signingID];
-    if (rule.type == SNTRuleTypeSigningID) {
-      return rule;
+    for (NSNumber* type in precedence) {
+      rule = staticRules[IdentifierForRuleType(identifiers, (SNTRuleType)type.integerValue)];
+      if (rule.type == type.integerValue) {
+        return rule;
+      }
     }
+  }
 
-    rule = staticRules[identifiers.certificateSHA256];
-    if (rule.type == SNTRuleTypeCertificate) {
-      return rule;
-    }
+  // With a rule snapshot, RuleSource and database rules are looked up in memory without waiting
+  // for any update that is being applied.
+  SNTExecutionRuleSnapshot* snapshot = self.executionRuleSnapshot;
+  if (snapshot) {
+    return [snapshot ruleForIdentifiers:identifiers precedence:precedence];
+  }
 
-    rule = staticRules[identifiers.teamID];
-    if (rule.type == SNTRuleTypeTeamID) {
-      return rule;
-    }
+  // Then the rules from any RuleSources. These take precedence over the primary sync server's
+  // rules, with higher precedence sources checked first.
+  if (self.hasRuleSourceRules) {
+    [self inDatabase:^(FMDatabase* db) {
+      NSString* query = [NSString
+          stringWithFormat:@"SELECT * FROM ("
+                           @"  SELECT * FROM rule_source_rules WHERE identifier=? AND type=500 "
+                           @"  UNION ALL "
+                           @"  SELECT * FROM rule_source_rules WHERE identifier=? AND type=1000 "
+                           @"  UNION ALL "
+                           @"  SELECT * FROM rule_source_rules WHERE identifier=? AND type=2000 "
+                           @"  UNION ALL "
+                           @"  SELECT * FROM rule_source_rules WHERE identifier=? AND type=3000 "
+                           @"  UNION ALL "
+                           @"  SELECT * FROM rule_source_rules WHERE identifier=? AND type=4000"
+                           @") ORDER BY precedence DESC, source ASC, %@ LIMIT 1",
+                           orderBy];
+      FMResultSet* rs = [db executeQuery:query, identifiers.cdhash, identifiers.binarySHA256,
+                                         identifiers.signingID, identifiers.certificateSHA256,
+                                         identifiers.teamID];
+      if ([rs next]) {
+        rule = [self executionRuleFromResultSet:rs];
+      }
+      [rs close];
+    }];
+    if (rule) return rule;
   }
 
   // Now query the database.
   //
-  // The intended order of precedence is CDHash > Binaries > Signing IDs > Certificates > Team IDs.
+  // The default order of precedence is CDHash > Binaries > Signing IDs > Certificates > Team IDs,
+  // which can be changed with the RulePrecedence configuration key.
   // The UNION ALL structure lets SQLite evaluate each sub-select independently (potentially
-  // short-circuiting via LIMIT 1), while ORDER BY type ASC guarantees the highest-priority
+  // short-circuiting via LIMIT 1), while the ORDER BY guarantees the highest-priority
   // rule is returned regardless of query planner behavior.
   //
   // There is a test for this in SNTRuleTableTests in case SQLite behavior changes in the future.
   //
   [self inDatabase:^(FMDatabase* db) {
-    FMResultSet* rs =
-        [db executeQuery:@"SELECT * FROM ("
+    NSString* query = [NSString
+        stringWithFormat:@"SELECT * FROM ("
                          @"  SELECT * FROM execution_rules WHERE identifier=? AND type=500 "
                          @"  UNION ALL "
                          @"  SELECT * FROM execution_rules WHERE identifier=? AND type=1000 "
@@ -592,9 +1018,11 @@ static void addPathsFromDefaultMuteSet(NSMutableSet* criticalPaths) {
                          @"  SELECT * FROM execution_rules WHERE identifier=? AND type=3000 "
                          @"  UNION ALL "
                          @"  SELECT * FROM execution_rules WHERE identifier=? AND type=4000"
-                         @") ORDER BY type ASC LIMIT 1",
-                         identifiers.cdhash, identifiers.binarySHA256, identifiers.signingID,
-                         identifiers.certificateSHA256, identifiers.teamID];
+                         @") ORDER BY %@ LIMIT 1",
+                         orderBy];
+    FMResultSet* rs = [db executeQuery:query, identifiers.cdhash, identifiers.binarySHA256,
+                                       identifiers.signingID, identifiers.certificateSHA256,
+                                       identifiers.teamID];
     if ([rs next]) {
       rule = [self executionRuleFromResultSet:rs];
     }
@@ -604,6 +1032,101 @@ static void addPathsFromDefaultMuteSet(NSMutableSet* criticalPaths) {
   return rule;
 }
 
+- (void)updateRuleTypePresenceInDB:(FMDatabase*)db {
+  self.hasRequirementRules =
+      [db longForQuery:@"SELECT COUNT(*) FROM execution_rules WHERE type=? LIMIT 1",
+                       @(SNTRuleTypeRequirement)] > 0;
+  self.hasBundleSigningIDRulesInDB =
+      [db longForQuery:@"SELECT COUNT(*) FROM execution_rules WHERE type=? LIMIT 1",
+                       @(SNTRuleTypeBundleSigningID)] > 0;
+}
+
+- (NSArray<SNTRule*>*)requirementRules {
+  NSMutableArray<SNTRule*>* rules = [NSMutableArray array];
+  for (SNTRule* rule in self.cachedStaticRules.allValues) {
+    if (rule.type == SNTRuleTypeRequirement) [rules addObject:rule];
+  }
+  [rules sortUsingComparator:^NSComparisonResult(SNTRule* a, SNTRule* b) {
+    return [a.identifier compare:b.identifier];
+  }];
+
+  SNTExecutionRuleSnapshot* snapshot = self.executionRuleSnapshot;
+  if (snapshot) {
+    [rules addObjectsFromArray:snapshot.requirementRules];
+  } else if (self.hasRequirementRules) {
+    [self inDatabase:^(FMDatabase* db) {
+      FMResultSet* rs = [db executeQuery:@"SELECT * FROM execution_rules WHERE type=? "
+                                         @"ORDER BY identifier ASC",
+                                         @(SNTRuleTypeRequirement)];
+      while ([rs next]) {
+        SNTRule* rule = [self executionRuleFromResultSet:rs];
+        if (rule) [rules addObject:rule];
+      }
+      [rs close];
+    }];
+  }
+
+  return rules;
+}
+
+- (BOOL)hasBundleSigningIDRules {
+  SNTExecutionRuleSnapshot* snapshot = self.executionRuleSnapshot;
+  if (snapshot ? snapshot.rules[@(SNTRuleTypeBundleSigningID)].count > 0
+               : self.hasBundleSigningIDRulesInDB) {
+    return YES;
+  }
+  for (SNTRule* rule in self.cachedStaticRules.allValues) {
+    if (rule.type == SNTRuleTypeBundleSigningID) return YES;
+  }
+  return NO;
+}
+
+- (SNTRule*)bundleSigningIDRuleForSigningIDs:(NSArray<NSString*>*)signingIDs {
+  if (!signingIDs.count) return nil;
+
+  NSDictionary<NSString*, SNTRule*>* staticRules = self.cachedStaticRules;
+  for (NSString* signingID in signingIDs) {
+    SNTRule* rule = staticRules[signingID];
+    if (rule.type == SNTRuleTypeBundleSigningID) return rule;
+  }
+
+  SNTExecutionRuleSnapshot* snapshot = self.executionRuleSnapshot;
+  if (snapshot) {
+    NSDictionary<NSString*, SNTRule*>* rules = snapshot.rules[@(SNTRuleTypeBundleSigningID)];
+    for (NSString* signingID in signingIDs) {
+      if (rules[signingID]) return rules[signingID];
+    }
+    return nil;
+  }
+
+  if (!self.hasBundleSigningIDRulesInDB) return nil;
+
+  NSMutableDictionary<NSString*, SNTRule*>* rules = [NSMutableDictionary dictionary];
+  [self inDatabase:^(FMDatabase* db) {
+    NSMutableArray* placeholders = [NSMutableArray arrayWithCapacity:signingIDs.count];
+    for (NSUInteger i = 0; i < signingIDs.count; ++i) {
+      [placeholders addObject:@"?"];
+    }
+    NSString* query = [NSString
+        stringWithFormat:@"SELECT * FROM execution_rules WHERE type=? AND identifier IN (%@)",
+                         [placeholders componentsJoinedByString:@", "]];
+    FMResultSet* rs =
+        [db executeQuery:query
+            withArgumentsInArray:[@[ @(SNTRuleTypeBundleSigningID) ]
+                                     arrayByAddingObjectsFromArray:signingIDs]];
+    while ([rs next]) {
+      SNTRule* rule = [self executionRuleFromResultSet:rs];
+      if (rule) rules[rule.identifier] = rule;
+    }
+    [rs close];
+  }];
+
+  for (NSString* signingID in signingIDs) {
+    if (rules[signingID]) return rules[signingID];
+  }
+  return nil;
+}
+
 #pragma mark Adding
 
 - (BOOL)addFileAccessRules:(NSArray<SNTFileAccessRule*>*)fileAccessRules
@@ -696,6 +1219,24 @@ static void addPathsFromDefaultMuteSet(NSMutableSet* criticalPaths) {
   return YES;
 }
 
+// Returns an error if the rule has a CEL expression that doesn't compile.
+- (NSError*)celExpressionErrorForRule:(SNTRule*)rule {
+  if (rule.state != SNTRuleStateCEL && rule.state != SNTRuleStateCELv2) return nil;
+
+  google::protobuf::Arena arena;
+  absl::StatusOr<std::unique_ptr<::google::api::expr::runtime::CelExpression>> celExpr;
+  if (rule.state == SNTRuleStateCEL && _celEvaluator != nullptr) {
+    celExpr = _celEvaluator->Compile(santa::NSStringToUTF8StringView(rule.celExpr), &arena);
+  } else if (rule.state == SNTRuleStateCELv2 && _celV2Evaluator != nullptr) {
+    celExpr = _celV2Evaluator->Compile(santa::NSStringToUTF8StringView(rule.celExpr), &arena);
+  }
+  if (celExpr.ok()) return nil;
+
+  return [SNTError createErrorWithCode:SNTErrorCodeRuleInvalidCELExpression
+                               message:@"Rule array contained rule with invalid CEL expression"
+                                detail:santa::StringToNSString(celExpr.status().message())];
+}
+
 - (BOOL)addExecutionRules:(NSArray<SNTRule*>*)executionRules
                      toDB:(FMDatabase*)db
                    errors:(NSMutableArray<NSError*>*)errors {
@@ -710,22 +1251,10 @@ static void addPathsFromDefaultMuteSet(NSMutableSet* criticalPaths) {
       return NO;
     }
 
-    if (rule.state == SNTRuleStateCEL || rule.state == SNTRuleStateCELv2) {
-      google::protobuf::Arena arena;
-      absl::StatusOr<std::unique_ptr<::google::api::expr::runtime::CelExpression>> celExpr;
-      if (rule.state == SNTRuleStateCEL && _celEvaluator != nullptr) {
-        celExpr = _celEvaluator->Compile(santa::NSStringToUTF8StringView(rule.celExpr), &arena);
-      } else if (rule.state == SNTRuleStateCELv2 && _celV2Evaluator != nullptr) {
-        celExpr = _celV2Evaluator->Compile(santa::NSStringToUTF8StringView(rule.celExpr), &arena);
-      }
-      if (!celExpr.ok()) {
-        [errors addObject:
-                    [SNTError
-                        createErrorWithCode:SNTErrorCodeRuleInvalidCELExpression
-                                    message:@"Rule array contained rule with invalid CEL expression"
-                                     detail:santa::StringToNSString(celExpr.status().message())]];
-        continue;
-      }
+    NSError* celError = [self celExpressionErrorForRule:rule];
+    if (celError) {
+      [errors addObject:celError];
+      continue;
     }
 
     if (rule.state == SNTRuleStateRemove) {
@@ -740,11 +1269,11 @@ static void addPathsFromDefaultMuteSet(NSMutableSet* criticalPaths) {
     } else {
       if (![db executeUpdate:@"INSERT OR REPLACE INTO execution_rules "
                              @"(identifier, state, type, custommsg, customurl, timestamp, "
-                             @"comment, cel_expr, seatbelt_policy, rule_id) "
-                             @"VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?);",
+                             @"comment, cel_expr, seatbelt_policy, rule_id, severity) "
+                             @"VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?);",
                              rule.identifier, @(rule.state), @(rule.type), rule.customMsg,
                              rule.customURL, @(rule.timestamp), rule.comment, rule.celExpr,
-                             rule.seatbeltPolicy, @(rule.ruleId)]) {
+                             rule.seatbeltPolicy, @(rule.ruleId), @(rule.severity)]) {
         [errors addObject:[SNTError createErrorWithCode:SNTErrorCodeInsertOrReplaceRuleFailed
                                                 message:@"A database error occurred while "
                                                         @"inserting/replacing a rule"
@@ -785,6 +1314,42 @@ static void addPathsFromDefaultMuteSet(NSMutableSet* criticalPaths) {
     return NO;
   }
 
+  executionRules = DeduplicateExecutionRules(
+      executionRules, [[SNTConfigurator configurator] duplicateRuleResolution]);
+
+  // Large incremental updates are split across multiple transactions so that
+  // rule lookups for pending decisions aren't stalled behind a single long
+  // write. Cleanup syncs are always applied atomically so that a partially
+  // applied rule set is never visible.
+  NSUInteger batchSize = [[SNTConfigurator configurator] ruleApplyBatchSize];
+  if (batchSize > 0 && cleanupType == SNTRuleCleanupNone && executionRules.count > batchSize) {
+    return [self addExecutionRulesInBatches:executionRules
+                            fileAccessRules:fileAccessRules
+                           networkFlowRules:networkFlowRules
+                                    signals:signals
+                                  batchSize:batchSize
+                                     errors:errors];
+  }
+
+  return [self addExecutionRules:executionRules
+                 fileAccessRules:fileAccessRules
+                networkFlowRules:networkFlowRules
+                         signals:signals
+                     ruleCleanup:cleanupType
+                         undoLog:nil
+                          errors:errors];
+}
+
+// Applies all of the given rules in a single transaction. If undoLog is given, it is filled in
+// with the execution_rules changes this transaction made so that they can be undone after it has
+// been committed. See recordExecutionRuleChanges:from:to:into:.
+- (BOOL)addExecutionRules:(NSArray<SNTRule*>*)executionRules
+          fileAccessRules:(NSArray<SNTFileAccessRule*>*)fileAccessRules
+         networkFlowRules:(NSArray<SNTNetworkFlowRule*>*)networkFlowRules
+                  signals:(NSArray<SNTSignal*>*)signals
+              ruleCleanup:(SNTRuleCleanup)cleanupType
+                  undoLog:(NSMutableDictionary<NSArray*, NSArray*>*)undoLog
+                   errors:(NSArray<NSError*>**)errors {
   __block BOOL failed = NO;
   __block NSMutableArray<NSError*>* blockErrors = [NSMutableArray array];
   __block NSString* faaRulesHashBefore;
@@ -797,26 +1362,27 @@ static void addPathsFromDefaultMuteSet(NSMutableSet* criticalPaths) {
   [self inTransaction:^(FMDatabase* db, BOOL* rollback) {
     faaRulesHashBefore = [self fileAccessRulesHashSerialized:db];
     signalRulesHashBefore = [self signalRulesHashSerialized:db];
+    BOOL cleanedUp = YES;
     switch (cleanupType) {
       case SNTRuleCleanupAll:
-        [db executeUpdate:@"DELETE FROM execution_rules"];
-        [db executeUpdate:@"DELETE FROM file_access_rules"];
-        [db executeUpdate:@"DELETE FROM network_flow_rules"];
-        [db executeUpdate:@"DELETE FROM signal_rules"];
+        cleanedUp = [db executeUpdate:@"DELETE FROM execution_rules"] &&
+                    [db executeUpdate:@"DELETE FROM file_access_rules"] &&
+                    [db executeUpdate:@"DELETE FROM network_flow_rules"] &&
+                    [db executeUpdate:@"DELETE FROM signal_rules"];
         break;
       case SNTRuleCleanupNonTransitive:
-        [db executeUpdate:@"DELETE FROM execution_rules WHERE state != ?",
-                          @(SNTRuleStateAllowTransitive)];
-        [db executeUpdate:@"DELETE FROM file_access_rules"];
-        [db executeUpdate:@"DELETE FROM network_flow_rules"];
-        [db executeUpdate:@"DELETE FROM signal_rules"];
+        cleanedUp = [db executeUpdate:@"DELETE FROM execution_rules WHERE state != ?",
+                                      @(SNTRuleStateAllowTransitive)] &&
+                    [db executeUpdate:@"DELETE FROM file_access_rules"] &&
+                    [db executeUpdate:@"DELETE FROM network_flow_rules"] &&
+                    [db executeUpdate:@"DELETE FROM signal_rules"];
         break;
       case SNTRuleCleanupExecutionRules:
-        [db executeUpdate:@"DELETE FROM execution_rules WHERE state != ?",
-                          @(SNTRuleStateAllowTransitive)];
+        cleanedUp = [db executeUpdate:@"DELETE FROM execution_rules WHERE state != ?",
+                                      @(SNTRuleStateAllowTransitive)];
         break;
       case SNTRuleCleanupFileAccessRules:
-        [db executeUpdate:@"DELETE FROM file_access_rules"];
+        cleanedUp = [db executeUpdate:@"DELETE FROM file_access_rules"];
         break;
       case SNTRuleCleanupNone: [[fallthrough]];
       case SNTRuleCleanupStandalone:
@@ -824,6 +1390,19 @@ static void addPathsFromDefaultMuteSet(NSMutableSet* criticalPaths) {
         break;
     }
 
+    if (!cleanedUp) {
+      [blockErrors addObject:[SNTError createErrorWithCode:SNTErrorCodeRemoveRuleFailed
+                                                   message:@"A database error occurred while "
+                                                           @"removing existing rules"
+                                                    detail:[db lastErrorMessage]]];
+      *rollback = failed = YES;
+      return;
+    }
+
+    NSArray<NSArray*>* undoKeys = undoLog ? [self rowKeysForExecutionRules:executionRules] : nil;
+    NSDictionary<NSArray*, id>* priorRows =
+        undoKeys ? [self executionRulesRowsForKeys:undoKeys inDB:db] : nil;
+
     if (![self addExecutionRules:executionRules toDB:db errors:blockErrors]) {
       *rollback = failed = YES;
       return;
@@ -844,10 +1423,34 @@ static void addPathsFromDefaultMuteSet(NSMutableSet* criticalPaths) {
       return;
     }
 
-    // Clear the rules hashes
+    if (undoKeys) {
+      [self recordExecutionRuleChanges:undoKeys
+                                  from:priorRows
+                                    to:[self executionRulesRowsForKeys:undoKeys inDB:db]
+                                  into:undoLog];
+    }
+
+    if (![self rulesChangeOnlyTransitive:executionRules
+                         fileAccessRules:fileAccessRules
+                        networkFlowRules:networkFlowRules
+                                 signals:signals
+                             ruleCleanup:cleanupType] &&
+        ![self markRulesChecksumStaleInDB:db]) {
+      [blockErrors addObject:[SNTError createErrorWithCode:SNTErrorCodeInsertOrReplaceRuleFailed
+                                                   message:@"A database error occurred while "
+                                                           @"updating the rules checksum"
+                                                    detail:[db lastErrorMessage]]];
+      *rollback = failed = YES;
+      return;
+    }
+
+    // Clear the rules hashes. This is done last so that a rolled back transaction doesn't leave
+    // hasRequirementRules or hasBundleSigningIDRulesInDB describing changes that were never
+    // committed.
     self.cachedExecutionRulesHash = nil;
     self.cachedFileAccessRulesHash = nil;
     self.cachedNetworkFlowRulesHash = nil;
+    [self updateRuleTypePresenceInDB:db];
 
     faaRulesHashAfter = [self fileAccessRulesHashSerialized:db];
     faaRuleCount = [self fileAccessRuleCountSerialized:db];
@@ -859,6 +1462,8 @@ static void addPathsFromDefaultMuteSet(NSMutableSet* criticalPaths) {
     *errors = [blockErrors copy];
   }
 
+  if (!failed) [self reloadExecutionRuleSnapshot];
+
   // If the DB updated successfully, call the "rules changed" callbacks if appropriate
   if (!failed && self.fileAccessRulesChangedCallback &&
       ![faaRulesHashBefore isEqualToString:faaRulesHashAfter]) {
@@ -872,6 +1477,154 @@ static void addPathsFromDefaultMuteSet(NSMutableSet* criticalPaths) {
   return !failed;
 }
 
+// Applies execution rules in transactions of at most batchSize rules each. The
+// remaining rule types are applied alongside the final batch. If a batch fails,
+// the rows written by the batches that were already committed are put back as
+// they were before the update.
+- (BOOL)addExecutionRulesInBatches:(NSArray<SNTRule*>*)executionRules
+                   fileAccessRules:(NSArray<SNTFileAccessRule*>*)fileAccessRules
+                  networkFlowRules:(NSArray<SNTNetworkFlowRule*>*)networkFlowRules
+                           signals:(NSArray<SNTSignal*>*)signals
+                         batchSize:(NSUInteger)batchSize
+                            errors:(NSArray<NSError*>**)errors {
+  NSMutableArray<NSError*>* allErrors = [NSMutableArray array];
+  NSMutableDictionary<NSArray*, NSArray*>* undoLog = [NSMutableDictionary dictionary];
+  BOOL success = YES;
+
+  for (NSUInteger start = 0; start < executionRules.count; start += batchSize) {
+    NSUInteger len = MIN(batchSize, executionRules.count - start);
+    BOOL lastBatch = (start + len == executionRules.count);
+
+    NSArray<NSError*>* batchErrors;
+    NSMutableDictionary<NSArray*, NSArray*>* batchUndoLog = [NSMutableDictionary dictionary];
+    success = [self addExecutionRules:[executionRules subarrayWithRange:NSMakeRange(start, len)]
+                      fileAccessRules:lastBatch ? fileAccessRules : nil
+                     networkFlowRules:lastBatch ? networkFlowRules : nil
+                              signals:lastBatch ? signals : nil
+                          ruleCleanup:SNTRuleCleanupNone
+                              undoLog:batchUndoLog
+                               errors:&batchErrors];
+    if (batchErrors) {
+      [allErrors addObjectsFromArray:batchErrors];
+    }
+    if (!success) break;
+
+    // Rules have been deduplicated, so no two batches touch the same row.
+    [undoLog addEntriesFromDictionary:batchUndoLog];
+  }
+
+  if (!success && undoLog.count > 0) {
+    [self undoExecutionRuleChanges:undoLog errors:allErrors];
+  }
+
+  if (allErrors.count > 0 && errors) {
+    *errors = [allErrors copy];
+  }
+
+  return success;
+}
+
+// Returns the execution_rules row key, identifier and type, of each valid rule.
+- (NSArray<NSArray*>*)rowKeysForExecutionRules:(NSArray<SNTRule*>*)executionRules {
+  NSMutableOrderedSet<NSArray*>* keys = [NSMutableOrderedSet orderedSet];
+  for (SNTRule* rule in executionRules) {
+    if (![rule isKindOfClass:[SNTRule class]] || rule.identifier.length == 0) continue;
+    [keys addObject:@[ rule.identifier, @(rule.type) ]];
+  }
+  return keys.array;
+}
+
+// Returns the execution_rules row for each key, or NSNull if there is none.
+- (NSDictionary<NSArray*, id>*)executionRulesRowsForKeys:(NSArray<NSArray*>*)keys
+                                                    inDB:(FMDatabase*)db {
+  NSMutableDictionary<NSArray*, id>* rows = [NSMutableDictionary dictionaryWithCapacity:keys.count];
+  for (NSArray* key in keys) {
+    FMResultSet* rs =
+        [db executeQuery:@"SELECT * FROM execution_rules WHERE identifier=? AND type=?", key[0],
+                         key[1]];
+    rows[key] = [rs next] ? [rs resultDictionary] : [NSNull null];
+    [rs close];
+  }
+  return rows;
+}
+
+// Adds an entry to undoLog for each row the transaction changed, holding the row before and after
+// the change: @[ priorRow, writtenRow ]. Either is NSNull if the row didn't exist.
+- (void)recordExecutionRuleChanges:(NSArray<NSArray*>*)keys
+                              from:(NSDictionary<NSArray*, id>*)priorRows
+                                to:(NSDictionary<NSArray*, id>*)writtenRows
+                              into:(NSMutableDictionary<NSArray*, NSArray*>*)undoLog {
+  for (NSArray* key in keys) {
+    if ([priorRows[key] isEqual:writtenRows[key]]) continue;
+    undoLog[key] = @[ priorRows[key], writtenRows[key] ];
+  }
+}
+
+// Undoes the execution_rules changes recorded by transactions that have already been committed.
+// Only rows that still hold what the update wrote are put back. A row that has been changed since,
+// for example by a transitive rule being added, is left alone rather than overwritten with its
+// state from before the update.
+- (void)undoExecutionRuleChanges:(NSDictionary<NSArray*, NSArray*>*)undoLog
+                          errors:(NSMutableArray<NSError*>*)errors {
+  __block BOOL restored = YES;
+  __block NSUInteger skipped = 0;
+  [self inTransaction:^(FMDatabase* db, BOOL* rollback) {
+    NSDictionary<NSArray*, id>* currentRows =
+        [self executionRulesRowsForKeys:undoLog.allKeys inDB:db];
+    for (NSArray* key in undoLog) {
+      if (![currentRows[key] isEqual:undoLog[key][1]]) {
+        skipped++;
+        continue;
+      }
+
+      if (![db executeUpdate:@"DELETE FROM execution_rules WHERE identifier=? AND type=?", key[0],
+                             key[1]]) {
+        *rollback = YES;
+        break;
+      }
+
+      NSDictionary* row = undoLog[key][0];
+      if (![row isKindOfClass:[NSDictionary class]]) continue;
+
+      NSMutableArray<NSString*>* placeholders = [NSMutableArray arrayWithCapacity:row.count];
+      for (NSString* column in row) {
+        [placeholders addObject:[@":" stringByAppendingString:column]];
+      }
+      NSString* sql = [NSString stringWithFormat:@"INSERT INTO execution_rules (%@) VALUES (%@)",
+                                                 [row.allKeys componentsJoinedByString:@", "],
+                                                 [placeholders componentsJoinedByString:@", "]];
+      if (![db executeUpdate:sql withParameterDictionary:row]) {
+        *rollback = YES;
+        break;
+      }
+    }
+
+    if (!*rollback && ![self markRulesChecksumStaleInDB:db]) {
+      *rollback = YES;
+    }
+
+    if (*rollback) {
+      restored = NO;
+      [errors addObject:[SNTError createErrorWithCode:SNTErrorCodeInsertOrReplaceRuleFailed
+                                              message:@"A database error occurred while "
+                                                      @"restoring rules after a failed update"
+                                               detail:[db lastErrorMessage]]];
+      return;
+    }
+
+    self.cachedExecutionRulesHash = nil;
+    [self updateRuleTypePresenceInDB:db];
+  }];
+
+  if (!restored) {
+    LOGE(@"Unable to restore rules after a failed update, the rule database may be incomplete");
+  } else if (skipped > 0) {
+    LOGW(@"Left %lu rules that changed during a failed update as they are now",
+         (unsigned long)skipped);
+  }
+  [self reloadExecutionRuleSnapshot];
+}
+
 - (BOOL)addSignals:(NSArray<SNTSignal*>*)signals
               toDB:(FMDatabase*)db
             errors:(NSMutableArray<NSError*>*)errors {
@@ -975,8 +1728,12 @@ static void addPathsFromDefaultMuteSet(NSMutableSet* criticalPaths) {
         // At this point we know the rule is an allowlist rule. Check if it's
         // overriding a compiler rule.
 
-        // Skip certificate and TeamID rules as they cannot be compiler rules.
-        if (rule.type == SNTRuleTypeCertificate || rule.type == SNTRuleTypeTeamID) continue;
+        // Skip certificate, TeamID, requirement and bundle Signing ID rules as they cannot be
+        // compiler rules.
+        if (rule.type == SNTRuleTypeCertificate || rule.type == SNTRuleTypeTeamID ||
+            rule.type == SNTRuleTypeRequirement || rule.type == SNTRuleTypeBundleSigningID) {
+          continue;
+        }
 
         if ([db longForQuery:
                     @"SELECT COUNT(*) FROM execution_rules WHERE identifier=? AND type IN (?, ?, ?)"
@@ -1024,6 +1781,7 @@ static void addPathsFromDefaultMuteSet(NSMutableSet* criticalPaths) {
       LOGE(@"Could not remove outdated transitive rules");
     }
   }];
+  [self reloadExecutionRuleSnapshot];
 
   self.lastTransitiveRuleCulling = [NSDate date];
 }
@@ -1125,6 +1883,156 @@ static void addPathsFromDefaultMuteSet(NSMutableSet* criticalPaths) {
   return digest;
 }
 
+#pragma mark Rule Sources
+
+- (NSSet<NSString*>*)configuredRuleSourceNames {
+  return [NSSet setWithArray:[[[SNTConfigurator configurator] ruleSources] valueForKey:@"name"]];
+}
+
+- (BOOL)replaceRulesForRuleSource:(NSString*)source
+                       precedence:(NSInteger)precedence
+                            rules:(NSArray<SNTRule*>*)rules
+                           errors:(NSArray<NSError*>**)errors {
+  if (source.length == 0 || ![[self configuredRuleSourceNames] containsObject:source]) {
+    if (errors) {
+      *errors = @[ [SNTError createErrorWithCode:SNTErrorCodeRuleInvalid
+                                         message:@"Unknown rule source"
+                                          detail:source] ];
+    }
+    return NO;
+  }
+
+  __block BOOL failed = NO;
+  NSMutableArray<NSError*>* blockErrors = [NSMutableArray array];
+
+  [self inTransaction:^(FMDatabase* db, BOOL* rollback) {
+    [db executeUpdate:@"DELETE FROM rule_source_rules WHERE source=?", source];
+
+    for (SNTRule* rule in rules) {
+      if (![rule isKindOfClass:[SNTRule class]] || rule.identifier.length == 0 ||
+          rule.state == SNTRuleStateUnknown || rule.type == SNTRuleTypeUnknown) {
+        [blockErrors
+            addObject:[SNTError createErrorWithCode:SNTErrorCodeRuleInvalid
+                                            message:@"Execution rule array contained invalid entry"
+                                             detail:rule.description]];
+        *rollback = failed = YES;
+        return;
+      }
+
+      // Every download replaces the source's rules, so removals are already applied.
+      if (rule.state == SNTRuleStateRemove) continue;
+
+      NSError* celError = [self celExpressionErrorForRule:rule];
+      if (celError) {
+        [blockErrors addObject:celError];
+        continue;
+      }
+
+      if (![db executeUpdate:@"INSERT OR REPLACE INTO rule_source_rules "
+                             @"(source, precedence, identifier, state, type, custommsg, "
+                             @"customurl, timestamp, comment, cel_expr, seatbelt_policy, rule_id, "
+                             @"severity) "
+                             @"VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?);",
+                             source, @(precedence), rule.identifier, @(rule.state), @(rule.type),
+                             rule.customMsg, rule.customURL, @(rule.timestamp), rule.comment,
+                             rule.celExpr, rule.seatbeltPolicy, @(rule.ruleId),
+                             @(rule.severity)]) {
+        [blockErrors addObject:[SNTError createErrorWithCode:SNTErrorCodeInsertOrReplaceRuleFailed
+                                                     message:@"A database error occurred while "
+                                                             @"inserting/replacing a rule"
+                                                      detail:[db lastErrorMessage]]];
+        *rollback = failed = YES;
+        return;
+      }
+    }
+
+    // Sources are only pruned here and on startup, so a removed source is cleaned up by the
+    // next download from any remaining source.
+    [self removeRuleSourceRulesExcept:[self configuredRuleSourceNames] inDB:db];
+
+    if (![self markRulesChecksumStaleInDB:db]) {
+      [blockErrors addObject:[SNTError createErrorWithCode:SNTErrorCodeInsertOrReplaceRuleFailed
+                                                   message:@"A database error occurred while "
+                                                           @"updating the rules checksum"
+                                                    detail:[db lastErrorMessage]]];
+      *rollback = failed = YES;
+      return;
+    }
+
+    self.hasRuleSourceRules = [db longForQuery:@"SELECT COUNT(*) FROM rule_source_rules"] > 0;
+  }];
+
+  if (!failed) [self reloadExecutionRuleSnapshot];
+
+  if (blockErrors.count > 0 && errors) {
+    *errors = [blockErrors copy];
+  }
+  return !failed;
+}
+
+- (BOOL)removeRuleSourceRulesExcept:(NSSet<NSString*>*)sources inDB:(FMDatabase*)db {
+  NSMutableArray<NSString*>* stale = [NSMutableArray array];
+  FMResultSet* rs = [db executeQuery:@"SELECT DISTINCT source FROM rule_source_rules"];
+  while ([rs next]) {
+    NSString* source = [rs stringForColumnIndex:0];
+    if (![sources containsObject:source]) [stale addObject:source];
+  }
+  [rs close];
+
+  for (NSString* source in stale) {
+    LOGI(@"Removing rules from rule source %@, it is no longer configured", source);
+    [db executeUpdate:@"DELETE FROM rule_source_rules WHERE source=?", source];
+  }
+  return stale.count > 0;
+}
+
+- (int64_t)ruleSourceRuleCount {
+  __block int64_t count = 0;
+  [self inDatabase:^(FMDatabase* db) {
+    count = [db longForQuery:@"SELECT COUNT(*) FROM rule_source_rules"];
+  }];
+  return count;
+}
+
+#pragma mark Rule Snapshot
+
+// Must be called inside an inDatabase:/inTransaction: block.
+- (SNTExecutionRuleSnapshot*)executionRuleSnapshotFromDB:(FMDatabase*)db {
+  NSMutableDictionary<NSNumber*, NSMutableDictionary*>* rules = [NSMutableDictionary dictionary];
+  FMResultSet* rs = [db executeQuery:@"SELECT * FROM execution_rules"];
+  while ([rs next]) {
+    SNTRule* rule = [self executionRuleFromResultSet:rs];
+    if (rule) AddRuleToRulesByType(rules, rule);
+  }
+  [rs close];
+
+  NSMutableArray<NSMutableDictionary*>* ruleSourceRules = [NSMutableArray array];
+  NSString* lastSource;
+  rs = [db executeQuery:@"SELECT * FROM rule_source_rules ORDER BY precedence DESC, source ASC"];
+  while ([rs next]) {
+    NSString* source = [rs stringForColumn:@"source"];
+    if (![source isEqualToString:lastSource]) {
+      [ruleSourceRules addObject:[NSMutableDictionary dictionary]];
+      lastSource = source;
+    }
+    SNTRule* rule = [self executionRuleFromResultSet:rs];
+    if (rule) AddRuleToRulesByType(ruleSourceRules.lastObject, rule);
+  }
+  [rs close];
+
+  return [[SNTExecutionRuleSnapshot alloc] initWithRules:rules ruleSourceRules:ruleSourceRules];
+}
+
+// Replaces the rule snapshot with one built from the database as it is now. Lookups continue to
+// use the previous snapshot until the new one is ready. Transitive rule timestamp updates don't
+// reload the snapshot as they don't change any decisions.
+- (void)reloadExecutionRuleSnapshot {
+  if (!self.executionRuleSnapshot) return;
+  [self inDatabase:^(FMDatabase* db) {
+    self.executionRuleSnapshot = [self executionRuleSnapshotFromDB:db];
+  }];
+}
+
 #pragma mark Caching Static Rules
 
 - (void)updateStaticRules:(NSArray<NSDictionary*>*)staticRules {
@@ -1255,6 +2163,8 @@ static void addPathsFromDefaultMuteSet(NSMutableSet* criticalPaths) {
 - (SNTRuleTableRulesHash*)hashOfHashes {
   __block SNTRuleTableRulesHash* hashes;
   [self inDatabase:^(FMDatabase* db) {
+    // Requested at preflight, which is also when a stale rules checksum is brought up to date.
+    [self updateRulesChecksumIfStaleInDB:db];
     hashes = [[SNTRuleTableRulesHash alloc]
         initWithExecutionRulesHash:[self executionRulesHashSerialized:db]
                fileAccessRulesHash:[self fileAccessRulesHashSerialized:db]
@@ -29,6 +29,7 @@
 #import "Source/common/SNTNetworkFlowRule.h"
 #import "Source/common/SNTRule.h"
 #import "Source/common/SNTRuleIdentifiers.h"
+#import "Source/common/SNTRuleSource.h"
 #import "Source/common/SigningIDHelpers.h"
 #import "Source/common/TestUtils.h"
 #import "Source/santad/DataLayer/SNTRuleTable.h"
@@ -38,6 +39,12 @@
 @property SNTRuleTable* sut;
 @property FMDatabaseQueue* dbq;
 @property id mockConfigurator;
+@property NSArray<SNTRuleSource*>* ruleSources;
+@end
+
+@interface SNTRuleTable (Testing)
+- (void)undoExecutionRuleChanges:(NSDictionary<NSArray*, NSArray*>*)undoLog
+                          errors:(NSMutableArray<NSError*>*)errors;
 @end
 
 @interface SNTRule ()
@@ -59,6 +66,11 @@
 
   self.mockConfigurator = OCMClassMock([SNTConfigurator class]);
   OCMStub([self.mockConfigurator configurator]).andReturn(self.mockConfigurator);
+  __weak __typeof(self) weakSelf = self;
+  OCMStub([self.mockConfigurator ruleSources]).andDo(^(NSInvocation* invocation) {
+    NSArray* __unsafe_unretained sources = weakSelf.ruleSources;
+    [invocation setReturnValue:&sources];
+  });
 }
 
 - (void)tearDown {
@@ -132,6 +144,14 @@
   return [[SNTFileAccessRule alloc] initRemoveRuleWithName:name];
 }
 
+- (NSString*)_storedRulesChecksum {
+  __block NSString* checksum;
+  [self.dbq inDatabase:^(FMDatabase* db) {
+    checksum = [db stringForQuery:@"SELECT checksum FROM rules_checksum WHERE id = 0"];
+  }];
+  return checksum;
+}
+
 - (void)testAddRulesNotClean {
   NSUInteger executionRuleCount = self.sut.executionRuleCount;
   NSUInteger binaryRuleCount = self.sut.binaryRuleCount;
@@ -196,6 +216,541 @@
   XCTAssertNil(errors);
 }
 
+// Generates `count` binary rules. Every seventh rule removes the rule before it so that
+// removals span batch boundaries.
+- (NSArray<SNTRule*>*)_exampleRuleSetWithCount:(NSUInteger)count {
+  NSMutableArray<SNTRule*>* rules = [NSMutableArray arrayWithCapacity:count];
+  for (NSUInteger i = 0; i < count; i++) {
+    BOOL remove = (i % 7 == 6);
+    NSString* identifier =
+        [NSString stringWithFormat:@"%064lx", (unsigned long)(remove ? i - 1 : i)];
+    [rules addObject:[[SNTRule alloc] initWithIdentifier:identifier
+                                                   state:remove ? SNTRuleStateRemove
+                                                                : SNTRuleStateAllow
+                                                    type:SNTRuleTypeBinary]];
+  }
+  return rules;
+}
+
+- (void)testBatchedApplyMatchesOneByOne {
+  NSArray<SNTRule*>* rules = [self _exampleRuleSetWithCount:250];
+
+  SNTRuleTable* oneByOne =
+      [[SNTRuleTable alloc] initWithDatabaseQueue:[[FMDatabaseQueue alloc] init]];
+  for (SNTRule* rule in rules) {
+    XCTAssertTrue([oneByOne addExecutionRules:@[ rule ]
+                                  ruleCleanup:SNTRuleCleanupNone
+                                       errors:nil]);
+  }
+
+  OCMStub([self.mockConfigurator ruleApplyBatchSize]).andReturn(16);
+
+  NSArray<NSError*>* errors;
+  XCTAssertTrue([self.sut addExecutionRules:rules
+                            fileAccessRules:@[ [self _exampleFileAccessAddRuleWithName:@"faa"] ]
+                           networkFlowRules:nil
+                                    signals:nil
+                                ruleCleanup:SNTRuleCleanupNone
+                                     errors:&errors]);
+  XCTAssertNil(errors);
+
+  XCTAssertEqual(self.sut.executionRuleCount, oneByOne.executionRuleCount);
+  XCTAssertEqualObjects(self.sut.hashOfHashes.executionRulesHash,
+                        oneByOne.hashOfHashes.executionRulesHash);
+  XCTAssertEqualObjects([NSSet setWithArray:[self.sut retrieveAllExecutionRules]],
+                        [NSSet setWithArray:[oneByOne retrieveAllExecutionRules]]);
+  XCTAssertEqual(self.sut.fileAccessRuleCount, 1);
+}
+
+- (void)testBatchedApplyUndoesCommittedBatches {
+  OCMStub([self.mockConfigurator ruleApplyBatchSize]).andReturn(2);
+
+  SNTRule* invalid = [self _exampleBinaryRule];
+  invalid.type = SNTRuleTypeUnknown;
+
+  NSArray<NSError*>* errors;
+  XCTAssertFalse([self.sut addExecutionRules:@[
+    [self _exampleCertRule], [self _exampleTeamIDRule], [self _exampleCDHashRule], invalid,
+    [self _exampleSigningIDRuleIsPlatform:NO]
+  ]
+                                 ruleCleanup:SNTRuleCleanupNone
+                                      errors:&errors]);
+  XCTAssertEqual(errors.count, 1);
+
+  // The first batch was committed and then undone, the failed batch was rolled back.
+  XCTAssertEqual(self.sut.executionRuleCount, 0);
+}
+
+// Makes any insert of a rule with the given identifier fail, as a disk error would.
+- (void)_injectInsertFailureForIdentifier:(NSString*)identifier {
+  [self.dbq inDatabase:^(FMDatabase* db) {
+    NSString* sql = [NSString stringWithFormat:@"CREATE TRIGGER inject_failure BEFORE INSERT ON "
+                                               @"execution_rules WHEN NEW.identifier = '%@' "
+                                               @"BEGIN SELECT RAISE(ABORT, 'injected'); END",
+                                               identifier];
+    XCTAssertTrue([db executeUpdate:sql]);
+  }];
+}
+
+// Seeds the database with a few rules and returns a description of its contents.
+- (NSArray*)_seedRulesForRollback {
+  SNTRule* binary = [self _exampleBinaryRule];
+  binary.state = SNTRuleStateAllow;
+  XCTAssertTrue([self.sut addExecutionRules:@[ binary, [self _exampleCertRule] ]
+                            fileAccessRules:@[ [self _exampleFileAccessAddRuleWithName:@"faa"] ]
+                           networkFlowRules:nil
+                                    signals:nil
+                                ruleCleanup:SNTRuleCleanupNone
+                                     errors:nil]);
+  return [self _rulesSnapshot];
+}
+
+- (NSArray*)_rulesSnapshot {
+  return @[
+    [NSSet setWithArray:[self.sut retrieveAllExecutionRules]],
+    self.sut.hashOfHashes.executionRulesHash, @(self.sut.fileAccessRuleCount),
+    [self _storedRulesChecksum]
+  ];
+}
+
+// A rule update that replaces the binary rule, removes the cert rule, adds a TeamID rule and
+// then fails on the CDHash rule.
+- (NSArray<SNTRule*>*)_failingRuleUpdate {
+  SNTRule* removeCert = [self _exampleCertRule];
+  removeCert.state = SNTRuleStateRemove;
+  SNTRule* failing = [self _exampleCDHashRule];
+  [self _injectInsertFailureForIdentifier:failing.identifier];
+  return @[
+    [self _exampleBinaryRule], removeCert, [self _exampleTeamIDRule], failing,
+    [self _exampleSigningIDRuleIsPlatform:NO]
+  ];
+}
+
+- (void)testMidApplyFailureLeavesDatabaseUnchanged {
+  NSArray* before = [self _seedRulesForRollback];
+
+  NSArray<NSError*>* errors;
+  XCTAssertFalse([self.sut addExecutionRules:[self _failingRuleUpdate]
+                             fileAccessRules:@[ [self _exampleFileAccessRemoveRuleWithName:@"faa"] ]
+                            networkFlowRules:nil
+                                     signals:nil
+                                 ruleCleanup:SNTRuleCleanupNone
+                                      errors:&errors]);
+  XCTAssertEqual(errors.count, 1);
+  XCTAssertEqual(errors.firstObject.code, SNTErrorCodeInsertOrReplaceRuleFailed);
+
+  XCTAssertEqualObjects([self _rulesSnapshot], before);
+}
+
+- (void)testMidApplyFailureDuringCleanSyncLeavesDatabaseUnchanged {
+  NSArray* before = [self _seedRulesForRollback];
+
+  NSArray<NSError*>* errors;
+  XCTAssertFalse([self.sut addExecutionRules:[self _failingRuleUpdate]
+                                 ruleCleanup:SNTRuleCleanupAll
+                                      errors:&errors]);
+  XCTAssertEqual(errors.firstObject.code, SNTErrorCodeInsertOrReplaceRuleFailed);
+
+  XCTAssertEqualObjects([self _rulesSnapshot], before);
+}
+
+- (void)testCleanupFailureLeavesDatabaseUnchanged {
+  NSArray* before = [self _seedRulesForRollback];
+  [self.dbq inDatabase:^(FMDatabase* db) {
+    XCTAssertTrue([db executeUpdate:@"CREATE TRIGGER inject_failure BEFORE DELETE ON "
+                                    @"file_access_rules BEGIN SELECT RAISE(ABORT, 'injected'); "
+                                    @"END"]);
+  }];
+
+  NSArray<NSError*>* errors;
+  XCTAssertFalse([self.sut addExecutionRules:@[ [self _exampleTeamIDRule] ]
+                                 ruleCleanup:SNTRuleCleanupAll
+                                      errors:&errors]);
+  XCTAssertEqual(errors.count, 1);
+  XCTAssertEqual(errors.firstObject.code, SNTErrorCodeRemoveRuleFailed);
+
+  // The execution rules deleted before the failure are back.
+  XCTAssertEqualObjects([self _rulesSnapshot], before);
+}
+
+- (void)testBatchedMidApplyFailureLeavesDatabaseUnchanged {
+  NSArray* before = [self _seedRulesForRollback];
+  OCMStub([self.mockConfigurator ruleApplyBatchSize]).andReturn(2);
+
+  NSArray<NSError*>* errors;
+  XCTAssertFalse([self.sut addExecutionRules:[self _failingRuleUpdate]
+                             fileAccessRules:@[ [self _exampleFileAccessRemoveRuleWithName:@"faa"] ]
+                            networkFlowRules:nil
+                                     signals:nil
+                                 ruleCleanup:SNTRuleCleanupNone
+                                      errors:&errors]);
+  XCTAssertEqual(errors.count, 1);
+  XCTAssertEqual(errors.firstObject.code, SNTErrorCodeInsertOrReplaceRuleFailed);
+
+  // The first batch replaced the binary rule and removed the cert rule before the second batch
+  // failed. Both changes were undone.
+  XCTAssertEqualObjects([self _rulesSnapshot], before);
+}
+
+- (void)testBatchedMidApplyFailureKeepsRowsChangedSince {
+  [self _seedRulesForRollback];
+  OCMStub([self.mockConfigurator ruleApplyBatchSize]).andReturn(2);
+
+  // Change the binary rule written by the first batch before the update is undone, as another
+  // writer could once that batch has been committed.
+  SNTRule* binary = [self _exampleBinaryRule];
+  id partialSUT = OCMPartialMock(self.sut);
+  OCMStub([partialSUT undoExecutionRuleChanges:OCMOCK_ANY errors:OCMOCK_ANY])
+      .andDo(^(NSInvocation* invocation) {
+        [self.dbq inDatabase:^(FMDatabase* db) {
+          XCTAssertTrue([db executeUpdate:@"UPDATE execution_rules SET custommsg = 'changed' "
+                                          @"WHERE identifier = ?",
+                                          binary.identifier]);
+        }];
+      })
+      .andForwardToRealObject();
+
+  XCTAssertFalse([self.sut addExecutionRules:[self _failingRuleUpdate]
+                                 ruleCleanup:SNTRuleCleanupNone
+                                      errors:nil]);
+
+  // The removed cert rule was put back, but the binary rule was left as it now is rather than
+  // reverted to its state before the update.
+  __block NSString* customMsg;
+  __block int state = 0;
+  __block NSUInteger certRules = 0;
+  [self.dbq inDatabase:^(FMDatabase* db) {
+    FMResultSet* rs = [db executeQuery:@"SELECT custommsg, state FROM execution_rules "
+                                       @"WHERE identifier = ?",
+                                       binary.identifier];
+    XCTAssertTrue([rs next]);
+    customMsg = [rs stringForColumn:@"custommsg"];
+    state = [rs intForColumn:@"state"];
+    [rs close];
+    certRules = [db intForQuery:@"SELECT COUNT(*) FROM execution_rules WHERE type = ?",
+                                @(SNTRuleTypeCertificate)];
+  }];
+  XCTAssertEqualObjects(customMsg, @"changed");
+  XCTAssertEqual(state, SNTRuleStateBlock);
+  XCTAssertEqual(certRules, 1);
+
+  [partialSUT stopMocking];
+}
+
+- (void)testCleanSyncIsNotBatched {
+  OCMStub([self.mockConfigurator ruleApplyBatchSize]).andReturn(1);
+  [self.sut addExecutionRules:@[ [self _exampleBinaryRule] ]
+                  ruleCleanup:SNTRuleCleanupNone
+                       errors:nil];
+
+  SNTRule* invalid = [self _exampleTeamIDRule];
+  invalid.type = SNTRuleTypeUnknown;
+
+  NSArray<NSError*>* errors;
+  XCTAssertFalse([self.sut addExecutionRules:@[ [self _exampleCertRule], invalid ]
+                                 ruleCleanup:SNTRuleCleanupAll
+                                      errors:&errors]);
+
+  // The whole clean sync was rolled back, including the cleanup.
+  XCTAssertEqual(self.sut.executionRuleCount, 1);
+  XCTAssertNotNil([self.sut executionRuleForIdentifiers:(struct RuleIdentifiers){
+                                .binarySHA256 = [self _exampleBinaryRule].identifier,
+                            }]);
+}
+
+// Measures the worst-case latency of decision lookups while a large incremental
+// sync is being applied. Compare against testPerformanceLookupLatencyDuringUnbatchedApply.
+// When batched, no lookup may wait for more than half of the apply, as it would if
+// the whole update still held the database.
+- (void)measureLookupLatencyDuringApplyWithBatchSize:(NSUInteger)batchSize {
+  OCMStub([self.mockConfigurator ruleApplyBatchSize]).andReturn(batchSize);
+  NSArray<SNTRule*>* rules = [self _exampleRuleSetWithCount:20000];
+  struct RuleIdentifiers lookup = {.binarySHA256 = [self _exampleBinaryRule].identifier};
+
+  [self measureBlock:^{
+    SNTRuleTable* sut = [[SNTRuleTable alloc] initWithDatabaseQueue:[[FMDatabaseQueue alloc] init]];
+    dispatch_group_t group = dispatch_group_create();
+    __block BOOL done = NO;
+    __block NSTimeInterval maxLatency = 0;
+
+    dispatch_group_async(group, dispatch_get_global_queue(QOS_CLASS_USER_INITIATED, 0), ^{
+      while (!done) {
+        NSDate* start = [NSDate date];
+        [sut executionRuleForIdentifiers:lookup];
+        maxLatency = MAX(maxLatency, -[start timeIntervalSinceNow]);
+      }
+    });
+
+    NSDate* applyStart = [NSDate date];
+    [sut addExecutionRules:rules ruleCleanup:SNTRuleCleanupNone errors:nil];
+    NSTimeInterval applyTime = -[applyStart timeIntervalSinceNow];
+    done = YES;
+    dispatch_group_wait(group, DISPATCH_TIME_FOREVER);
+
+    if (batchSize) {
+      XCTAssertLessThan(maxLatency, applyTime / 2);
+    }
+  }];
+}
+
+- (SNTRule*)_applyDuplicateRules:(NSArray<SNTRule*>*)rules
+                      resolution:(SNTDuplicateRuleResolution)resolution {
+  OCMStub([self.mockConfigurator duplicateRuleResolution]).andReturn(resolution);
+
+  NSArray<NSError*>* errors;
+  XCTAssertTrue([self.sut addExecutionRules:rules ruleCleanup:SNTRuleCleanupNone errors:&errors]);
+  XCTAssertNil(errors);
+  XCTAssertEqual(self.sut.executionRuleCount, 1);
+
+  return [self.sut executionRuleForIdentifiers:(struct RuleIdentifiers){
+                       .binarySHA256 = [self _exampleBinaryRule].identifier,
+                   }];
+}
+
+- (NSArray<SNTRule*>*)_conflictingBinaryRules {
+  SNTRule* block = [self _exampleBinaryRule];
+  SNTRule* allow = [self _exampleBinaryRule];
+  allow.state = SNTRuleStateAllow;
+  allow.customMsg = @"Allowed";
+  return @[ block, allow ];
+}
+
+- (void)testDuplicateRulesLastWins {
+  SNTRule* rule = [self _applyDuplicateRules:[self _conflictingBinaryRules]
+                                  resolution:SNTDuplicateRuleResolutionLastWins];
+  XCTAssertEqual(rule.state, SNTRuleStateAllow);
+  XCTAssertEqualObjects(rule.customMsg, @"Allowed");
+}
+
+- (void)testDuplicateRulesHighestPrecedenceWins {
+  SNTRule* rule = [self _applyDuplicateRules:[self _conflictingBinaryRules]
+                                  resolution:SNTDuplicateRuleResolutionHighestPrecedence];
+  XCTAssertEqual(rule.state, SNTRuleStateBlock);
+  XCTAssertEqualObjects(rule.customMsg, @"A rule");
+}
+
+- (void)testDuplicateRulesHighestPrecedenceTieLastWins {
+  SNTRule* first = [self _exampleBinaryRule];
+  SNTRule* second = [self _exampleBinaryRule];
+  second.state = SNTRuleStateSilentBlock;
+  second.customMsg = @"Second";
+
+  SNTRule* rule = [self _applyDuplicateRules:@[ first, second ]
+                                  resolution:SNTDuplicateRuleResolutionHighestPrecedence];
+  XCTAssertEqual(rule.state, SNTRuleStateSilentBlock);
+  XCTAssertEqualObjects(rule.customMsg, @"Second");
+}
+
+- (void)testDuplicateRulesHighestPrecedenceAddWinsOverRemove {
+  SNTRule* allow = [self _exampleBinaryRule];
+  allow.state = SNTRuleStateAllow;
+  SNTRule* remove = [self _exampleBinaryRule];
+  remove.state = SNTRuleStateRemove;
+
+  SNTRule* rule = [self _applyDuplicateRules:@[ allow, remove ]
+                                  resolution:SNTDuplicateRuleResolutionHighestPrecedence];
+  XCTAssertEqual(rule.state, SNTRuleStateAllow);
+}
+
+- (void)testDuplicateRulesOnlyMatchSameType {
+  // Rules with the same identifier but a different type are not duplicates.
+  SNTRule* binary = [self _exampleBinaryRule];
+  SNTRule* cdhash = [self _exampleBinaryRule];
+  cdhash.type = SNTRuleTypeCDHash;
+  cdhash.identifier = @"dbe8c39801f93e05fc7bc53a02af5b4d3cfc670a";
+  SNTRule* cdhashAllow = [self _exampleBinaryRule];
+  cdhashAllow.type = SNTRuleTypeCDHash;
+  cdhashAllow.identifier = cdhash.identifier;
+  cdhashAllow.state = SNTRuleStateAllow;
+
+  OCMStub([self.mockConfigurator duplicateRuleResolution])
+      .andReturn(SNTDuplicateRuleResolutionHighestPrecedence);
+  XCTAssertTrue([self.sut addExecutionRules:@[ binary, cdhashAllow, cdhash ]
+                                ruleCleanup:SNTRuleCleanupNone
+                                     errors:nil]);
+  XCTAssertEqual(self.sut.executionRuleCount, 2);
+  XCTAssertEqual(self.sut.binaryRuleCount, 1);
+  XCTAssertEqual(self.sut.cdhashRuleCount, 1);
+}
+
+- (void)testDuplicateRulesInvalidEntryStillRejected {
+  SNTRule* invalid = [self _exampleBinaryRule];
+  invalid.type = SNTRuleTypeUnknown;
+
+  NSArray<NSError*>* errors;
+  XCTAssertFalse([self.sut addExecutionRules:@[ [self _exampleBinaryRule], invalid, invalid ]
+                                 ruleCleanup:SNTRuleCleanupNone
+                                      errors:&errors]);
+  XCTAssertEqual(errors.firstObject.code, SNTErrorCodeRuleInvalid);
+  XCTAssertEqual(self.sut.executionRuleCount, 0);
+}
+
+- (void)testPerformanceLookupLatencyDuringBatchedApply {
+  [self measureLookupLatencyDuringApplyWithBatchSize:500];
+}
+
+- (void)testPerformanceLookupLatencyDuringUnbatchedApply {
+  [self measureLookupLatencyDuringApplyWithBatchSize:0];
+}
+
+// Measures loading a large rule set and then looking up every rule in it, the same work as
+// `santactl bench store`, to catch regressions as rule sets grow.
+- (void)testPerformanceLargeRuleSet {
+  const NSUInteger count = 100000;
+  NSMutableArray<SNTRule*>* rules = [NSMutableArray arrayWithCapacity:count];
+  for (NSUInteger i = 0; i < count; i++) {
+    [rules addObject:[[SNTRule alloc]
+                         initWithIdentifier:[NSString stringWithFormat:@"%064lx", (unsigned long)i]
+                                      state:SNTRuleStateAllow
+                                       type:SNTRuleTypeBinary]];
+  }
+
+  [self measureBlock:^{
+    SNTRuleTable* sut = [[SNTRuleTable alloc] initWithDatabaseQueue:[[FMDatabaseQueue alloc] init]];
+    XCTAssertTrue([sut addExecutionRules:rules ruleCleanup:SNTRuleCleanupAll errors:nil]);
+    XCTAssertEqual(sut.executionRuleCount, count);
+
+    NSUInteger missing = 0;
+    for (SNTRule* rule in rules) {
+      SNTRule* found = [sut executionRuleForIdentifiers:(struct RuleIdentifiers){
+                                                            .binarySHA256 = rule.identifier,
+                                                        }];
+      if (![found.identifier isEqualToString:rule.identifier]) missing++;
+    }
+    XCTAssertEqual(missing, 0);
+  }];
+}
+
+- (SNTRuleTable*)_ruleTableWithSnapshot {
+  OCMStub([self.mockConfigurator enableRuleSnapshot]).andReturn(YES);
+  return [[SNTRuleTable alloc] initWithDatabaseQueue:self.dbq];
+}
+
+- (void)testRuleSnapshotLookupsDoNotWaitForTheDatabase {
+  self.dbq = [[FMDatabaseQueue alloc] init];
+  SNTRuleTable* sut = [self _ruleTableWithSnapshot];
+  XCTAssertTrue([sut addExecutionRules:@[ [self _exampleBinaryRule] ]
+                           ruleCleanup:SNTRuleCleanupNone
+                                errors:nil]);
+  struct RuleIdentifiers lookup = {.binarySHA256 = [self _exampleBinaryRule].identifier};
+
+  // Hold the database queue the way a long rule update would. Lookups must still complete, and
+  // must not see changes that haven't been swapped into the snapshot.
+  [self.dbq inDatabase:^(FMDatabase* db) {
+    [db executeUpdate:@"DELETE FROM execution_rules"];
+
+    dispatch_semaphore_t sema = dispatch_semaphore_create(0);
+    __block SNTRule* rule;
+    dispatch_async(dispatch_get_global_queue(QOS_CLASS_USER_INITIATED, 0), ^{
+      rule = [sut executionRuleForIdentifiers:lookup];
+      dispatch_semaphore_signal(sema);
+    });
+    XCTAssertSemaTrue(sema, 5, "Lookup stalled while the database was busy");
+    XCTAssertEqual(rule.state, SNTRuleStateBlock);
+  }];
+}
+
+- (void)testRuleSnapshotLookupsAreConsistentDuringReloads {
+  self.dbq = [[FMDatabaseQueue alloc] init];
+  SNTRuleTable* sut = [self _ruleTableWithSnapshot];
+  NSArray<SNTRule*>* filler = [self _exampleRuleSetWithCount:2000];
+  SNTRule* blockRule = [self _exampleBinaryRule];
+  SNTRule* allowRule = [[SNTRule alloc] initWithIdentifier:blockRule.identifier
+                                                     state:SNTRuleStateAllow
+                                                      type:SNTRuleTypeBinary];
+  XCTAssertTrue([sut addExecutionRules:@[ blockRule ] ruleCleanup:SNTRuleCleanupNone errors:nil]);
+
+  struct RuleIdentifiers lookup = {.binarySHA256 = blockRule.identifier};
+  dispatch_group_t group = dispatch_group_create();
+  dispatch_queue_t resultsQueue =
+      dispatch_queue_create("com.northpolesec.santa.test.results", DISPATCH_QUEUE_SERIAL);
+  __block BOOL done = NO;
+  __block NSUInteger lookups = 0;
+  __block NSUInteger inconsistent = 0;
+
+  for (int i = 0; i < 4; i++) {
+    dispatch_group_async(group, dispatch_get_global_queue(QOS_CLASS_USER_INITIATED, 0), ^{
+      NSUInteger threadLookups = 0;
+      NSUInteger threadInconsistent = 0;
+      while (!done) {
+        // Every update replaces the rule, so it must always be found in one of its two states.
+        SNTRule* rule = [sut executionRuleForIdentifiers:lookup];
+        if (rule.state != SNTRuleStateBlock && rule.state != SNTRuleStateAllow) {
+          threadInconsistent++;
+        }
+        threadLookups++;
+      }
+      dispatch_sync(resultsQueue, ^{
+        lookups += threadLookups;
+        inconsistent += threadInconsistent;
+      });
+    });
+  }
+
+  // Clean syncs delete every rule before adding them back inside a single transaction. Without
+  // the snapshot, lookups would wait for each of these to finish.
+  for (int i = 0; i < 20; i++) {
+    SNTRule* rule = (i % 2) ? blockRule : allowRule;
+    XCTAssertTrue([sut addExecutionRules:[filler arrayByAddingObject:rule]
+                             ruleCleanup:SNTRuleCleanupAll
+                                  errors:nil]);
+  }
+  done = YES;
+  dispatch_group_wait(group, DISPATCH_TIME_FOREVER);
+
+  XCTAssertGreaterThan(lookups, 0);
+  XCTAssertEqual(inconsistent, 0);
+  XCTAssertEqual([sut executionRuleForIdentifiers:lookup].state, SNTRuleStateBlock);
+}
+
+- (void)testRuleSnapshotLookupsMatchDatabaseLookups {
+  self.dbq = [[FMDatabaseQueue alloc] init];
+  SNTRuleTable* sut = [self _ruleTableWithSnapshot];
+  self.ruleSources = @[
+    [self _ruleSourceNamed:@"high" precedence:10], [self _ruleSourceNamed:@"low" precedence:0]
+  ];
+
+  for (SNTRuleTable* table in @[ self.sut, sut ]) {
+    XCTAssertTrue([table addExecutionRules:@[
+      [self _exampleBinaryRule], [self _exampleSigningIDRuleIsPlatform:NO]
+    ]
+                               ruleCleanup:SNTRuleCleanupNone
+                                    errors:nil]);
+    XCTAssertTrue([table replaceRulesForRuleSource:@"low"
+                                        precedence:0
+                                             rules:@[ [self _exampleCDHashRule] ]
+                                            errors:nil]);
+    XCTAssertTrue([table replaceRulesForRuleSource:@"high"
+                                        precedence:10
+                                             rules:@[ [self _exampleTeamIDRule] ]
+                                            errors:nil]);
+  }
+
+  void (^check)(struct RuleIdentifiers) = ^(struct RuleIdentifiers identifiers) {
+    SNTRule* want = [self.sut executionRuleForIdentifiers:identifiers];
+    SNTRule* got = [sut executionRuleForIdentifiers:identifiers];
+    XCTAssertEqualObjects(got.identifier, want.identifier);
+    XCTAssertEqual(got.type, want.type);
+    XCTAssertEqual(got.state, want.state);
+  };
+
+  check([self _allExampleIdentifiers]);
+  check((struct RuleIdentifiers){.cdhash = [self _exampleCDHashRule].identifier});
+  check((struct RuleIdentifiers){
+      .binarySHA256 = [self _exampleBinaryRule].identifier,
+      .signingID = [self _exampleSigningIDRuleIsPlatform:NO].identifier,
+  });
+  check((struct RuleIdentifiers){.signingID = @"ABCDEFGHIJ:other"});
+
+  // Removing a rule is reflected in the snapshot.
+  SNTRule* remove = [self _exampleBinaryRule];
+  remove.state = SNTRuleStateRemove;
+  XCTAssertTrue([sut addExecutionRules:@[ remove ] ruleCleanup:SNTRuleCleanupNone errors:nil]);
+  XCTAssertNil([sut executionRuleForIdentifiers:(struct RuleIdentifiers){
+                                                    .binarySHA256 = remove.identifier,
+                                                }]);
+}
+
 - (void)testAddRulesEmptyArray {
   NSArray<NSError*>* errors;
   XCTAssertFalse([self.sut addExecutionRules:@[] ruleCleanup:SNTRuleCleanupNone errors:&errors]);
@@ -392,6 +947,38 @@
   XCTAssertNil(r);
 }
 
+- (void)testFetchRulePreservesSeverity {
+  SNTRule* rule = [[SNTRule alloc]
+      initWithIdentifier:@"b7c1e3fd640c5f211c89b02c2c6122f78ce322aa5c56eb0bb54bc422a8f8b670"
+                   state:SNTRuleStateBlock
+                    type:SNTRuleTypeBinary
+               customMsg:nil
+               customURL:nil
+               timestamp:0
+                 comment:nil
+                 celExpr:nil
+          seatbeltPolicy:nil
+                  ruleId:0
+                severity:SNTRuleSeverityCritical
+                   error:nil];
+  [self.sut addExecutionRules:@[ rule, [self _exampleCertRule] ]
+                  ruleCleanup:SNTRuleCleanupNone
+                       errors:nil];
+
+  SNTRule* r = [self.sut
+      executionRuleForIdentifiers:
+          (struct RuleIdentifiers){
+              .binarySHA256 = @"b7c1e3fd640c5f211c89b02c2c6122f78ce322aa5c56eb0bb54bc422a8f8b670",
+          }];
+  XCTAssertEqual(r.severity, SNTRuleSeverityCritical);
+
+  NSString* certSHA = [self _exampleCertRule].identifier;
+  r = [self.sut executionRuleForIdentifiers:(struct RuleIdentifiers){
+                                                .certificateSHA256 = certSHA,
+                                            }];
+  XCTAssertEqual(r.severity, SNTRuleSeverityUnspecified);
+}
+
 - (void)testFetchCertificateRule {
   [self.sut addExecutionRules:@[ [self _exampleBinaryRule], [self _exampleCertRule] ]
                   ruleCleanup:SNTRuleCleanupNone
@@ -490,6 +1077,100 @@
   XCTAssertNil(r);
 }
 
+- (void)testFetchRequirementRules {
+  [self.sut updateStaticRules:nil];
+  XCTAssertEqual([self.sut requirementRules].count, 0);
+
+  SNTRule* dbRule = [[SNTRule alloc] initWithIdentifier:@"anchor apple"
+                                                  state:SNTRuleStateAllow
+                                                   type:SNTRuleTypeRequirement];
+  XCTAssertNotNil(dbRule);
+  NSArray<NSError*>* err;
+  XCTAssertTrue([self.sut addExecutionRules:@[ dbRule, [self _exampleBinaryRule] ]
+                                ruleCleanup:SNTRuleCleanupNone
+                                     errors:&err]);
+  XCTAssertNil(err);
+
+  // Requirement rules aren't found by identifier lookups.
+  XCTAssertNil([self.sut
+      executionRuleForIdentifiers:(struct RuleIdentifiers){.binarySHA256 = @"anchor apple"}]);
+
+  [self.sut updateStaticRules:@[ @{
+              @"identifier" : @"identifier \"com.example.app\" and anchor apple generic",
+              @"policy" : @"BLOCKLIST",
+              @"rule_type" : @"REQUIREMENT",
+            } ]];
+
+  // Static rules come first.
+  NSArray<SNTRule*>* rules = [self.sut requirementRules];
+  XCTAssertEqual(rules.count, 2);
+  XCTAssertTrue(rules[0].staticRule);
+  XCTAssertEqual(rules[0].state, SNTRuleStateBlock);
+  XCTAssertEqualObjects(rules[1].identifier, dbRule.identifier);
+  XCTAssertEqual(rules[1].state, SNTRuleStateAllow);
+
+  // Removing the last database rule stops it from being returned.
+  SNTRule* removeRule = [[SNTRule alloc] initWithIdentifier:@"anchor apple"
+                                                      state:SNTRuleStateRemove
+                                                       type:SNTRuleTypeRequirement];
+  XCTAssertTrue([self.sut addExecutionRules:@[ removeRule ]
+                                ruleCleanup:SNTRuleCleanupNone
+                                     errors:nil]);
+  rules = [self.sut requirementRules];
+  XCTAssertEqual(rules.count, 1);
+  XCTAssertTrue(rules[0].staticRule);
+
+  [self.sut updateStaticRules:nil];
+}
+
+- (void)testFetchBundleSigningIDRules {
+  [self.sut updateStaticRules:nil];
+  XCTAssertFalse([self.sut hasBundleSigningIDRules]);
+
+  SNTRule* dbRule = [[SNTRule alloc] initWithIdentifier:@"ABCDEFGHIJ:com.example.app"
+                                                  state:SNTRuleStateAllow
+                                                   type:SNTRuleTypeBundleSigningID];
+  XCTAssertNotNil(dbRule);
+  NSArray<NSError*>* err;
+  XCTAssertTrue([self.sut addExecutionRules:@[ dbRule ]
+                                ruleCleanup:SNTRuleCleanupNone
+                                     errors:&err]);
+  XCTAssertNil(err);
+  XCTAssertTrue([self.sut hasBundleSigningIDRules]);
+
+  // Bundle Signing ID rules aren't found by identifier lookups.
+  XCTAssertNil([self.sut executionRuleForIdentifiers:(struct RuleIdentifiers){
+                                                         .signingID = @"ABCDEFGHIJ:com.example.app",
+                                                     }]);
+
+  SNTRule* r = [self.sut bundleSigningIDRuleForSigningIDs:@[
+    @"ABCDEFGHIJ:com.example.app.helper", @"ABCDEFGHIJ:com.example.app", @"ABCDEFGHIJ:com.example"
+  ]];
+  XCTAssertEqualObjects(r.identifier, @"ABCDEFGHIJ:com.example.app");
+  XCTAssertFalse(r.staticRule);
+  XCTAssertNil([self.sut bundleSigningIDRuleForSigningIDs:@[ @"ABCDEFGHIJ:com.example" ]]);
+
+  // Static rules come first, and the first matching Signing ID wins.
+  [self.sut updateStaticRules:@[ @{
+              @"identifier" : @"ABCDEFGHIJ:com.example.app",
+              @"policy" : @"BLOCKLIST",
+              @"rule_type" : @"BUNDLESIGNINGID",
+            } ]];
+  r = [self.sut bundleSigningIDRuleForSigningIDs:@[ @"ABCDEFGHIJ:com.example.app" ]];
+  XCTAssertTrue(r.staticRule);
+  XCTAssertEqual(r.state, SNTRuleStateBlock);
+  [self.sut updateStaticRules:nil];
+
+  SNTRule* removeRule = [[SNTRule alloc] initWithIdentifier:@"ABCDEFGHIJ:com.example.app"
+                                                      state:SNTRuleStateRemove
+                                                       type:SNTRuleTypeBundleSigningID];
+  XCTAssertTrue([self.sut addExecutionRules:@[ removeRule ]
+                                ruleCleanup:SNTRuleCleanupNone
+                                     errors:nil]);
+  XCTAssertFalse([self.sut hasBundleSigningIDRules]);
+  XCTAssertNil([self.sut bundleSigningIDRuleForSigningIDs:@[ @"ABCDEFGHIJ:com.example.app" ]]);
+}
+
 - (void)testFetchRuleOrdering {
   NSArray<NSError*>* err;
   [self.sut addExecutionRules:@[
@@ -604,6 +1285,109 @@
   [[NSFileManager defaultManager] removeItemAtPath:dbPath error:NULL];
 }
 
+- (void)testBadDatabaseIsReportedAsCorrupt {
+  NSString* dbPath = [NSTemporaryDirectory()
+      stringByAppendingPathComponent:[NSString stringWithFormat:@"%@.db", [NSUUID UUID]]];
+  [@"some text" writeToFile:dbPath atomically:YES encoding:NSUTF8StringEncoding error:NULL];
+
+  SNTRuleTable* sut =
+      [[SNTRuleTable alloc] initWithDatabaseQueue:[[FMDatabaseQueue alloc] initWithPath:dbPath]];
+
+  XCTAssertTrue(sut.recreatedAfterCorruption);
+  XCTAssertTrue(sut.corruptionDetected);
+  OCMVerify(atLeastOnce(), [self.mockConfigurator setSyncTypeRequired:SNTSyncTypeCleanAll]);
+
+  [[NSFileManager defaultManager] removeItemAtPath:dbPath error:NULL];
+}
+
+- (void)testChecksumMismatchTriggersCleanSync {
+  NSString* dbPath = [NSTemporaryDirectory()
+      stringByAppendingPathComponent:[NSString stringWithFormat:@"%@.db", [NSUUID UUID]]];
+
+  // Populate a fresh database. Creating it requests the initial clean sync.
+  FMDatabaseQueue* dbq = [[FMDatabaseQueue alloc] initWithPath:dbPath];
+  SNTRuleTable* sut = [[SNTRuleTable alloc] initWithDatabaseQueue:dbq];
+  XCTAssertTrue([sut addExecutionRules:@[ [self _exampleBinaryRule], [self _exampleCertRule] ]
+                           ruleCleanup:SNTRuleCleanupNone
+                                errors:nil]);
+  XCTAssertFalse(sut.corruptionDetected);
+  [sut updateRulesChecksumIfStale];
+  [dbq close];
+
+  // Modify a rule behind Santa's back.
+  dbq = [[FMDatabaseQueue alloc] initWithPath:dbPath];
+  [dbq inDatabase:^(FMDatabase* db) {
+    XCTAssertTrue([db executeUpdate:@"UPDATE execution_rules SET state=? WHERE identifier=?",
+                                    @(SNTRuleStateAllow), [self _exampleBinaryRule].identifier]);
+  }];
+  [dbq close];
+
+  // Reloading detects the mismatch, drops the untrusted rules and requests a clean sync.
+  dbq = [[FMDatabaseQueue alloc] initWithPath:dbPath];
+  sut = [[SNTRuleTable alloc] initWithDatabaseQueue:dbq];
+  XCTAssertTrue(sut.corruptionDetected);
+  XCTAssertFalse(sut.recreatedAfterCorruption);
+  XCTAssertEqual(sut.executionRuleCount, 0);
+  OCMVerify(times(2), [self.mockConfigurator setSyncTypeRequired:SNTSyncTypeCleanAll]);
+
+  // The clean sync restores the rules and the database loads cleanly afterwards.
+  XCTAssertTrue([sut addExecutionRules:@[ [self _exampleBinaryRule], [self _exampleCertRule] ]
+                           ruleCleanup:SNTRuleCleanupAll
+                                errors:nil]);
+  [dbq close];
+
+  dbq = [[FMDatabaseQueue alloc] initWithPath:dbPath];
+  sut = [[SNTRuleTable alloc] initWithDatabaseQueue:dbq];
+  XCTAssertFalse(sut.corruptionDetected);
+  XCTAssertEqual(sut.executionRuleCount, 2);
+  OCMVerify(times(2), [self.mockConfigurator setSyncTypeRequired:SNTSyncTypeCleanAll]);
+  [dbq close];
+
+  [[NSFileManager defaultManager] removeItemAtPath:dbPath error:NULL];
+}
+
+- (void)testRuleUpdatesDeferTheChecksum {
+  [self.sut addExecutionRules:@[ [self _exampleBinaryRule] ]
+                  ruleCleanup:SNTRuleCleanupNone
+                       errors:nil];
+  [self.sut updateRulesChecksumIfStale];
+  NSString* baseline = [self _storedRulesChecksum];
+  XCTAssertGreaterThan(baseline.length, 0);
+
+  // Rule updates only mark the checksum stale, it is recomputed when the rule hashes are read.
+  XCTAssertTrue([self.sut addExecutionRules:@[ [self _exampleCertRule] ]
+                                ruleCleanup:SNTRuleCleanupNone
+                                     errors:nil]);
+  XCTAssertEqualObjects([self _storedRulesChecksum], @"");
+  [self.sut hashOfHashes];
+  NSString* updated = [self _storedRulesChecksum];
+  XCTAssertGreaterThan(updated.length, 0);
+  XCTAssertNotEqualObjects(updated, baseline);
+}
+
+- (void)testTransitiveRulesDoNotInvalidateChecksum {
+  NSString* dbPath = [NSTemporaryDirectory()
+      stringByAppendingPathComponent:[NSString stringWithFormat:@"%@.db", [NSUUID UUID]]];
+
+  FMDatabaseQueue* dbq = [[FMDatabaseQueue alloc] initWithPath:dbPath];
+  SNTRuleTable* sut = [[SNTRuleTable alloc] initWithDatabaseQueue:dbq];
+  XCTAssertTrue([sut addExecutionRules:@[ [self _exampleBinaryRule] ]
+                           ruleCleanup:SNTRuleCleanupNone
+                                errors:nil]);
+  XCTAssertTrue([sut addExecutionRules:@[ [self _exampleTransitiveRule] ]
+                           ruleCleanup:SNTRuleCleanupNone
+                                errors:nil]);
+  [dbq close];
+
+  dbq = [[FMDatabaseQueue alloc] initWithPath:dbPath];
+  sut = [[SNTRuleTable alloc] initWithDatabaseQueue:dbq];
+  XCTAssertFalse(sut.corruptionDetected);
+  XCTAssertEqual(sut.executionRuleCount, 2);
+  [dbq close];
+
+  [[NSFileManager defaultManager] removeItemAtPath:dbPath error:NULL];
+}
+
 - (void)testRetrieveAllRulesWithEmptyDatabase {
   NSArray<SNTRule*>* rules = [self.sut retrieveAllExecutionRules];
   XCTAssertEqual(rules.count, 0);
@@ -1278,4 +2062,271 @@
   XCTAssertEqual(callbackCount, 2);
 }
 
+- (void)testCustomRulePrecedenceChangesOutcome {
+  // The sync server allows the binary and blocks its team.
+  SNTRule* allowBinary = [self _exampleBinaryRule];
+  allowBinary.state = SNTRuleStateAllow;
+  XCTAssertTrue([self.sut addExecutionRules:@[ allowBinary, [self _exampleTeamIDRule] ]
+                                ruleCleanup:SNTRuleCleanupNone
+                                     errors:nil]);
+  struct RuleIdentifiers identifiers = {
+      .binarySHA256 = allowBinary.identifier,
+      .teamID = [self _exampleTeamIDRule].identifier,
+  };
+
+  // By default the binary rule wins.
+  SNTRule* r = [self.sut executionRuleForIdentifiers:identifiers];
+  XCTAssertEqual(r.type, SNTRuleTypeBinary);
+  XCTAssertEqual(r.state, SNTRuleStateAllow);
+
+  // Ranking Team IDs above binaries lets the block win.
+  [self.sut updateRulePrecedence:@[
+    @(SNTRuleTypeCDHash), @(SNTRuleTypeTeamID), @(SNTRuleTypeBinary), @(SNTRuleTypeSigningID),
+    @(SNTRuleTypeCertificate)
+  ]];
+  r = [self.sut executionRuleForIdentifiers:identifiers];
+  XCTAssertEqual(r.type, SNTRuleTypeTeamID);
+  XCTAssertEqual(r.state, SNTRuleStateBlock);
+
+  // Rules that don't overlap are still found.
+  r = [self.sut executionRuleForIdentifiers:(struct RuleIdentifiers){
+                                                .binarySHA256 = allowBinary.identifier,
+                                            }];
+  XCTAssertEqual(r.type, SNTRuleTypeBinary);
+
+  // Resetting restores the default ordering.
+  [self.sut updateRulePrecedence:nil];
+  r = [self.sut executionRuleForIdentifiers:identifiers];
+  XCTAssertEqual(r.type, SNTRuleTypeBinary);
+}
+
+- (void)testCustomRulePrecedenceAppliesToStaticAndRuleSourceRules {
+  [self.sut updateRulePrecedence:@[
+    @(SNTRuleTypeTeamID), @(SNTRuleTypeCertificate), @(SNTRuleTypeSigningID),
+    @(SNTRuleTypeBinary), @(SNTRuleTypeCDHash)
+  ]];
+
+  [self.sut updateStaticRules:@[
+    @{
+      @"identifier" : [self _exampleBinaryRule].identifier,
+      @"rule_type" : @"BINARY",
+      @"policy" : @"ALLOWLIST",
+    },
+    @{
+      @"identifier" : [self _exampleTeamIDRule].identifier,
+      @"rule_type" : @"TEAMID",
+      @"policy" : @"BLOCKLIST",
+    },
+  ]];
+  SNTRule* r = [self.sut executionRuleForIdentifiers:[self _allExampleIdentifiers]];
+  XCTAssertEqual(r.type, SNTRuleTypeTeamID);
+  XCTAssertEqual(r.state, SNTRuleStateBlock);
+  [self.sut updateStaticRules:nil];
+
+  self.ruleSources = @[ [self _ruleSourceNamed:@"vendor" precedence:0] ];
+  SNTRule* allowCert = [self _exampleCertRule];
+  XCTAssertTrue([self.sut replaceRulesForRuleSource:@"vendor"
+                                         precedence:0
+                                              rules:@[ [self _exampleCDHashRule], allowCert ]
+                                             errors:nil]);
+  r = [self.sut executionRuleForIdentifiers:[self _allExampleIdentifiers]];
+  XCTAssertEqual(r.type, SNTRuleTypeCertificate);
+  XCTAssertEqual(r.state, SNTRuleStateAllow);
+}
+
+#pragma mark Rule Sources
+
+- (SNTRuleSource*)_ruleSourceNamed:(NSString*)name precedence:(NSInteger)precedence {
+  NSString* url = [NSString stringWithFormat:@"https://%@.example.com/", name];
+  return [[SNTRuleSource alloc] initWithName:name
+                                         url:[NSURL URLWithString:url]
+                                syncInterval:60
+                                  precedence:precedence];
+}
+
+- (struct RuleIdentifiers)_allExampleIdentifiers {
+  return (struct RuleIdentifiers){
+      .cdhash = @"dbe8c39801f93e05fc7bc53a02af5b4d3cfc670a",
+      .binarySHA256 = @"b7c1e3fd640c5f211c89b02c2c6122f78ce322aa5c56eb0bb54bc422a8f8b670",
+      .signingID = @"ABCDEFGHIJ:signingID",
+      .certificateSHA256 = @"7ae80b9ab38af0c63a9a81765f434d9a7cd8f720eb6037ef303de39d779bc258",
+      .teamID = @"ABCDEFGHIJ",
+  };
+}
+
+- (void)testRuleSourceRulesTakePrecedenceOverSyncRules {
+  self.ruleSources = @[ [self _ruleSourceNamed:@"vendor" precedence:0] ];
+
+  // The sync server allows the binary, the rule source blocks the team.
+  SNTRule* allowBinary = [self _exampleBinaryRule];
+  allowBinary.state = SNTRuleStateAllow;
+  XCTAssertTrue([self.sut addExecutionRules:@[ allowBinary ]
+                                ruleCleanup:SNTRuleCleanupNone
+                                     errors:nil]);
+  NSArray<NSError*>* errors;
+  XCTAssertTrue([self.sut replaceRulesForRuleSource:@"vendor"
+                                         precedence:0
+                                              rules:@[ [self _exampleTeamIDRule] ]
+                                             errors:&errors]);
+  XCTAssertNil(errors);
+  XCTAssertEqual(self.sut.ruleSourceRuleCount, 1);
+  XCTAssertEqual(self.sut.executionRuleCount, 1);
+
+  SNTRule* r = [self.sut executionRuleForIdentifiers:[self _allExampleIdentifiers]];
+  XCTAssertEqual(r.type, SNTRuleTypeTeamID);
+  XCTAssertEqual(r.state, SNTRuleStateBlock);
+
+  // Static rules still win over rule sources.
+  [self.sut updateStaticRules:@[ @{
+              @"identifier" : allowBinary.identifier,
+              @"rule_type" : @"BINARY",
+              @"policy" : @"ALLOWLIST",
+            } ]];
+  r = [self.sut executionRuleForIdentifiers:[self _allExampleIdentifiers]];
+  XCTAssertEqual(r.type, SNTRuleTypeBinary);
+  XCTAssertEqual(r.state, SNTRuleStateAllow);
+  [self.sut updateStaticRules:nil];
+
+  // Identifiers the source has no rule for fall through to the sync server's rules.
+  r = [self.sut executionRuleForIdentifiers:(struct RuleIdentifiers){
+                                                .binarySHA256 = allowBinary.identifier,
+                                            }];
+  XCTAssertEqual(r.state, SNTRuleStateAllow);
+}
+
+- (void)testRuleSourcePrecedenceOrdering {
+  self.ruleSources = @[
+    [self _ruleSourceNamed:@"high" precedence:10], [self _ruleSourceNamed:@"low" precedence:0]
+  ];
+
+  SNTRule* allowCert = [self _exampleCertRule];
+  XCTAssertTrue([self.sut replaceRulesForRuleSource:@"low"
+                                         precedence:0
+                                              rules:@[ [self _exampleCDHashRule] ]
+                                             errors:nil]);
+  XCTAssertTrue([self.sut replaceRulesForRuleSource:@"high"
+                                         precedence:10
+                                              rules:@[ allowCert, [self _exampleTeamIDRule] ]
+                                             errors:nil]);
+
+  // The higher precedence source wins even though the other source has a more specific rule,
+  // and within that source the normal rule type ordering applies.
+  SNTRule* r = [self.sut executionRuleForIdentifiers:[self _allExampleIdentifiers]];
+  XCTAssertEqual(r.type, SNTRuleTypeCertificate);
+  XCTAssertEqual(r.state, SNTRuleStateAllow);
+
+  // The lower precedence source is used when the higher one has no match.
+  r = [self.sut executionRuleForIdentifiers:(struct RuleIdentifiers){
+                                                .cdhash = [self _exampleCDHashRule].identifier,
+                                            }];
+  XCTAssertEqual(r.type, SNTRuleTypeCDHash);
+}
+
+- (void)testReplaceRulesForRuleSourceOnlyReplacesThatSource {
+  self.ruleSources =
+      @[ [self _ruleSourceNamed:@"a" precedence:0], [self _ruleSourceNamed:@"b" precedence:0] ];
+
+  XCTAssertTrue([self.sut replaceRulesForRuleSource:@"a"
+                                         precedence:0
+                                              rules:@[ [self _exampleTeamIDRule] ]
+                                             errors:nil]);
+  XCTAssertTrue([self.sut replaceRulesForRuleSource:@"b"
+                                         precedence:0
+                                              rules:@[ [self _exampleCDHashRule] ]
+                                             errors:nil]);
+  XCTAssertEqual(self.sut.ruleSourceRuleCount, 2);
+
+  // A new download of source a replaces only a's rules. Removals have nothing left to remove.
+  SNTRule* remove = [self _exampleBinaryRule];
+  remove.state = SNTRuleStateRemove;
+  XCTAssertTrue([self.sut replaceRulesForRuleSource:@"a"
+                                         precedence:0
+                                              rules:@[ [self _exampleCertRule], remove ]
+                                             errors:nil]);
+  XCTAssertEqual(self.sut.ruleSourceRuleCount, 2);
+  XCTAssertNil([self.sut executionRuleForIdentifiers:(struct RuleIdentifiers){
+                                                         .teamID = @"ABCDEFGHIJ",
+                                                     }]);
+  XCTAssertNotNil([self.sut executionRuleForIdentifiers:(struct RuleIdentifiers){
+                                                            .cdhash = [self _exampleCDHashRule]
+                                                                          .identifier,
+                                                        }]);
+
+  // An empty download clears the source.
+  XCTAssertTrue([self.sut replaceRulesForRuleSource:@"a" precedence:0 rules:@[] errors:nil]);
+  XCTAssertEqual(self.sut.ruleSourceRuleCount, 1);
+}
+
+- (void)testReplaceRulesForUnknownRuleSourceFails {
+  self.ruleSources = @[ [self _ruleSourceNamed:@"known" precedence:0] ];
+
+  NSArray<NSError*>* errors;
+  XCTAssertFalse([self.sut replaceRulesForRuleSource:@"unknown"
+                                          precedence:0
+                                               rules:@[ [self _exampleTeamIDRule] ]
+                                              errors:&errors]);
+  XCTAssertEqual(errors.count, 1);
+  XCTAssertEqual(self.sut.ruleSourceRuleCount, 0);
+}
+
+- (void)testRuleSourceRulesAreNotPartOfSyncState {
+  self.ruleSources = @[ [self _ruleSourceNamed:@"vendor" precedence:0] ];
+  XCTAssertTrue([self.sut addExecutionRules:@[ [self _exampleBinaryRule] ]
+                                ruleCleanup:SNTRuleCleanupNone
+                                     errors:nil]);
+  NSString* hashBefore = self.sut.hashOfHashes.executionRulesHash;
+
+  XCTAssertTrue([self.sut replaceRulesForRuleSource:@"vendor"
+                                         precedence:0
+                                              rules:@[ [self _exampleTeamIDRule] ]
+                                             errors:nil]);
+  XCTAssertEqualObjects(self.sut.hashOfHashes.executionRulesHash, hashBefore);
+  XCTAssertEqual(self.sut.retrieveAllExecutionRules.count, 1);
+
+  // A clean sync from the primary sync server leaves the rule source's rules alone.
+  XCTAssertTrue([self.sut addExecutionRules:@[ [self _exampleCertRule] ]
+                                ruleCleanup:SNTRuleCleanupAll
+                                     errors:nil]);
+  XCTAssertEqual(self.sut.ruleSourceRuleCount, 1);
+}
+
+- (void)testRuleSourceRulesArePrunedWhenSourceIsRemoved {
+  NSString* dbPath = [NSTemporaryDirectory()
+      stringByAppendingPathComponent:[NSString stringWithFormat:@"%@.db", [NSUUID UUID]]];
+
+  self.ruleSources =
+      @[ [self _ruleSourceNamed:@"a" precedence:0], [self _ruleSourceNamed:@"b" precedence:0] ];
+  FMDatabaseQueue* dbq = [[FMDatabaseQueue alloc] initWithPath:dbPath];
+  SNTRuleTable* sut = [[SNTRuleTable alloc] initWithDatabaseQueue:dbq];
+  XCTAssertTrue([sut replaceRulesForRuleSource:@"a"
+                                    precedence:0
+                                         rules:@[ [self _exampleTeamIDRule] ]
+                                        errors:nil]);
+  XCTAssertTrue([sut replaceRulesForRuleSource:@"b"
+                                    precedence:0
+                                         rules:@[ [self _exampleCDHashRule] ]
+                                        errors:nil]);
+  [dbq close];
+
+  // Source b is removed from the configuration. Its rules are dropped on the next load without
+  // the pruning being mistaken for corruption.
+  self.ruleSources = @[ [self _ruleSourceNamed:@"a" precedence:0] ];
+  dbq = [[FMDatabaseQueue alloc] initWithPath:dbPath];
+  sut = [[SNTRuleTable alloc] initWithDatabaseQueue:dbq];
+  XCTAssertFalse(sut.corruptionDetected);
+  XCTAssertEqual(sut.ruleSourceRuleCount, 1);
+  XCTAssertNil([sut executionRuleForIdentifiers:(struct RuleIdentifiers){
+                                                    .cdhash = [self _exampleCDHashRule].identifier,
+                                                }]);
+  [dbq close];
+
+  dbq = [[FMDatabaseQueue alloc] initWithPath:dbPath];
+  sut = [[SNTRuleTable alloc] initWithDatabaseQueue:dbq];
+  XCTAssertFalse(sut.corruptionDetected);
+  XCTAssertEqual(sut.ruleSourceRuleCount, 1);
+  [dbq close];
+
+  [[NSFileManager defaultManager] removeItemAtPath:dbPath error:NULL];
+}
+
 @end
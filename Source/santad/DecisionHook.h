@@ -0,0 +1,77 @@
+/// Copyright 2026 North Pole Security, Inc.
+///
+/// Licensed under the Apache License, Version 2.0 (the "License");
+/// you may not use this file except in compliance with the License.
+/// You may obtain a copy of the License at
+///
+///     http://www.apache.org/licenses/LICENSE-2.0
+///
+/// Unless required by applicable law or agreed to in writing, software
+/// distributed under the License is distributed on an "AS IS" BASIS,
+/// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+/// See the License for the specific language governing permissions and
+/// limitations under the License.
+
+#ifndef SANTA_SANTAD_DECISIONHOOK_H
+#define SANTA_SANTAD_DECISIONHOOK_H
+
+#import <Foundation/Foundation.h>
+
+#include <sys/types.h>
+
+#include <chrono>
+#include <optional>
+#include <string>
+
+namespace santa {
+
+// Consults a local process listening on a Unix domain socket before an
+// unknown binary's decision is finalized.
+//
+// The request is the binary's identity encoded as a single line of JSON. The
+// hook must reply with a single line of JSON of the form:
+//   {"decision": "allow"} or {"decision": "block"}
+//
+// Because an allow reply bypasses the normal decision, the hook is only trusted
+// if it runs as trusted_uid (root): the socket and its parent directory must be
+// owned by trusted_uid and not writable by group or others, and the connected
+// peer must be running as trusted_uid.
+//
+// The socket is connected, written and read within a single timeout. Any
+// failure (untrusted socket or peer, no listener, timeout, malformed reply)
+// results in kNoDecision when failing open or kBlock when failing closed.
+class DecisionHook {
+ public:
+  enum class Verdict {
+    // The hook did not provide a verdict, the normal decision should be used.
+    kNoDecision,
+    kAllow,
+    kBlock,
+  };
+
+  // trusted_uid is only overridden by tests.
+  DecisionHook(std::string socket_path, std::chrono::milliseconds timeout, bool fail_closed,
+               uid_t trusted_uid = 0);
+
+  // No copies or moves, instances are cheap to construct.
+  DecisionHook(const DecisionHook& other) = delete;
+  DecisionHook& operator=(const DecisionHook& other) = delete;
+  DecisionHook(DecisionHook&& other) = delete;
+  DecisionHook& operator=(DecisionHook&& rhs) = delete;
+
+  Verdict Evaluate(NSDictionary* identity) const;
+
+ private:
+  std::optional<Verdict> Query(NSData* request) const;
+  bool IsSocketPathTrusted() const;
+  bool IsPeerTrusted(int fd) const;
+
+  std::string socket_path_;
+  std::chrono::milliseconds timeout_;
+  bool fail_closed_;
+  uid_t trusted_uid_;
+};
+
+}  // namespace santa
+
+#endif  // SANTA_SANTAD_DECISIONHOOK_H
@@ -0,0 +1,245 @@
+/// Copyright 2026 North Pole Security, Inc.
+///
+/// Licensed under the Apache License, Version 2.0 (the "License");
+/// you may not use this file except in compliance with the License.
+/// You may obtain a copy of the License at
+///
+///     http://www.apache.org/licenses/LICENSE-2.0
+///
+/// Unless required by applicable law or agreed to in writing, software
+/// distributed under the License is distributed on an "AS IS" BASIS,
+/// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+/// See the License for the specific language governing permissions and
+/// limitations under the License.
+
+#include "Source/santad/DecisionHook.h"
+
+#include <errno.h>
+#include <fcntl.h>
+#include <poll.h>
+#include <libgen.h>
+#include <sys/param.h>
+#include <sys/socket.h>
+#include <sys/stat.h>
+#include <sys/un.h>
+#include <unistd.h>
+
+#include <string>
+
+#import "Source/common/SNTLogging.h"
+#include "absl/cleanup/cleanup.h"
+
+namespace santa {
+
+namespace {
+
+// Upper bound on the size of a reply, anything larger is treated as malformed.
+constexpr size_t kMaxReplySize = 4096;
+
+using Clock = std::chrono::steady_clock;
+
+// Returns the number of milliseconds remaining until deadline, or 0 if the
+// deadline has passed.
+int RemainingMs(Clock::time_point deadline) {
+  auto remaining = std::chrono::duration_cast<std::chrono::milliseconds>(deadline - Clock::now());
+  return remaining.count() > 0 ? (int)remaining.count() : 0;
+}
+
+bool WaitFor(int fd, short events, Clock::time_point deadline) {
+  struct pollfd pfd = {.fd = fd, .events = events};
+  while (true) {
+    int ms = RemainingMs(deadline);
+    if (ms == 0) {
+      return false;
+    }
+    int ret = poll(&pfd, 1, ms);
+    if (ret > 0) {
+      return true;
+    } else if (ret == 0 || errno != EINTR) {
+      return false;
+    }
+  }
+}
+
+// Returns true if the file is owned by uid and not writable by group or others.
+bool IsOwnedAndProtected(const struct stat& sb, uid_t uid) {
+  return sb.st_uid == uid && (sb.st_mode & (S_IWGRP | S_IWOTH)) == 0;
+}
+
+}  // namespace
+
+DecisionHook::DecisionHook(std::string socket_path, std::chrono::milliseconds timeout,
+                           bool fail_closed, uid_t trusted_uid)
+    : socket_path_(std::move(socket_path)),
+      timeout_(timeout),
+      fail_closed_(fail_closed),
+      trusted_uid_(trusted_uid) {}
+
+DecisionHook::Verdict DecisionHook::Evaluate(NSDictionary* identity) const {
+  NSError* error;
+  NSData* request = [NSJSONSerialization dataWithJSONObject:identity ?: @{}
+                                                    options:0
+                                                      error:&error];
+  std::optional<Verdict> verdict;
+  if (request) {
+    verdict = Query(request);
+  } else {
+    LOGE(@"Decision hook: unable to encode identity: %@", error.localizedDescription);
+  }
+
+  if (verdict.has_value()) {
+    return *verdict;
+  }
+
+  return fail_closed_ ? Verdict::kBlock : Verdict::kNoDecision;
+}
+
+std::optional<DecisionHook::Verdict> DecisionHook::Query(NSData* request) const {
+  Clock::time_point deadline = Clock::now() + timeout_;
+
+  struct sockaddr_un addr = {.sun_family = AF_UNIX};
+  if (socket_path_.empty() || socket_path_.size() >= sizeof(addr.sun_path)) {
+    LOGE(@"Decision hook: invalid socket path: %s", socket_path_.c_str());
+    return std::nullopt;
+  }
+  strlcpy(addr.sun_path, socket_path_.c_str(), sizeof(addr.sun_path));
+
+  if (!IsSocketPathTrusted()) {
+    return std::nullopt;
+  }
+
+  int fd = socket(AF_UNIX, SOCK_STREAM, 0);
+  if (fd < 0) {
+    LOGE(@"Decision hook: unable to create socket: %d", errno);
+    return std::nullopt;
+  }
+  absl::Cleanup closeFd = ^{
+    close(fd);
+  };
+
+  int on = 1;
+  setsockopt(fd, SOL_SOCKET, SO_NOSIGPIPE, &on, sizeof(on));
+  fcntl(fd, F_SETFL, fcntl(fd, F_GETFL) | O_NONBLOCK);
+
+  if (connect(fd, (struct sockaddr*)&addr, sizeof(addr)) != 0) {
+    if (errno != EINPROGRESS || !WaitFor(fd, POLLOUT, deadline)) {
+      LOGW(@"Decision hook: unable to connect to %s: %d", socket_path_.c_str(), errno);
+      return std::nullopt;
+    }
+    int soError = 0;
+    socklen_t len = sizeof(soError);
+    if (getsockopt(fd, SOL_SOCKET, SO_ERROR, &soError, &len) != 0 || soError != 0) {
+      LOGW(@"Decision hook: unable to connect to %s: %d", socket_path_.c_str(), soError);
+      return std::nullopt;
+    }
+  }
+
+  if (!IsPeerTrusted(fd)) {
+    return std::nullopt;
+  }
+
+  NSMutableData* payload = [request mutableCopy];
+  [payload appendBytes:"\n" length:1];
+
+  const uint8_t* buf = (const uint8_t*)payload.bytes;
+  size_t written = 0;
+  while (written < payload.length) {
+    if (!WaitFor(fd, POLLOUT, deadline)) {
+      LOGW(@"Decision hook: timed out sending request");
+      return std::nullopt;
+    }
+    ssize_t n = write(fd, buf + written, payload.length - written);
+    if (n < 0) {
+      if (errno == EINTR || errno == EAGAIN) continue;
+      LOGW(@"Decision hook: failed to send request: %d", errno);
+      return std::nullopt;
+    }
+    written += n;
+  }
+
+  std::string reply;
+  while (reply.find('\n') == std::string::npos) {
+    if (!WaitFor(fd, POLLIN, deadline)) {
+      LOGW(@"Decision hook: timed out waiting for reply");
+      return std::nullopt;
+    }
+    char chunk[512];
+    ssize_t n = read(fd, chunk, sizeof(chunk));
+    if (n < 0) {
+      if (errno == EINTR || errno == EAGAIN) continue;
+      LOGW(@"Decision hook: failed to read reply: %d", errno);
+      return std::nullopt;
+    } else if (n == 0) {
+      // Peer closed the connection, accept an unterminated reply.
+      break;
+    }
+    reply.append(chunk, n);
+    if (reply.size() > kMaxReplySize) {
+      LOGW(@"Decision hook: reply too large");
+      return std::nullopt;
+    }
+  }
+
+  size_t lineEnd = reply.find('\n');
+  NSData* replyData = [NSData dataWithBytes:reply.data()
+                                     length:(lineEnd == std::string::npos ? reply.size() : lineEnd)];
+  NSDictionary* response = [NSJSONSerialization JSONObjectWithData:replyData options:0 error:nil];
+  if (![response isKindOfClass:[NSDictionary class]] ||
+      ![response[@"decision"] isKindOfClass:[NSString class]]) {
+    LOGW(@"Decision hook: malformed reply");
+    return std::nullopt;
+  }
+
+  NSString* decision = response[@"decision"];
+  if ([decision caseInsensitiveCompare:@"allow"] == NSOrderedSame) {
+    return Verdict::kAllow;
+  } else if ([decision caseInsensitiveCompare:@"block"] == NSOrderedSame) {
+    return Verdict::kBlock;
+  }
+
+  LOGW(@"Decision hook: unknown decision: %@", decision);
+  return std::nullopt;
+}
+
+bool DecisionHook::IsSocketPathTrusted() const {
+  char dir[MAXPATHLEN];
+  if (!dirname_r(socket_path_.c_str(), dir)) {
+    LOGE(@"Decision hook: invalid socket path: %s", socket_path_.c_str());
+    return false;
+  }
+
+  // The directory is resolved through symlinks, the socket itself is not, so that a symlink
+  // can't stand in for it.
+  struct stat dirStat;
+  struct stat socketStat;
+  if (stat(dir, &dirStat) != 0 || lstat(socket_path_.c_str(), &socketStat) != 0) {
+    LOGW(@"Decision hook: unable to stat %s: %d", socket_path_.c_str(), errno);
+    return false;
+  }
+
+  if (!S_ISSOCK(socketStat.st_mode) || !IsOwnedAndProtected(dirStat, trusted_uid_) ||
+      !IsOwnedAndProtected(socketStat, trusted_uid_)) {
+    LOGE(@"Decision hook: ignoring %s, it and its directory must be owned by uid %d and not "
+         @"writable by group or others",
+         socket_path_.c_str(), trusted_uid_);
+    return false;
+  }
+
+  return true;
+}
+
+bool DecisionHook::IsPeerTrusted(int fd) const {
+  uid_t uid;
+  gid_t gid;
+  if (getpeereid(fd, &uid, &gid) != 0) {
+    LOGW(@"Decision hook: unable to get peer credentials: %d", errno);
+    return false;
+  }
+  if (uid != trusted_uid_) {
+    LOGE(@"Decision hook: ignoring listener on %s running as uid %d", socket_path_.c_str(), uid);
+    return false;
+  }
+  return true;
+}
+
+}  // namespace santa
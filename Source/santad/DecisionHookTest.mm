@@ -0,0 +1,193 @@
+/// Copyright 2026 North Pole Security, Inc.
+///
+/// Licensed under the Apache License, Version 2.0 (the "License");
+/// you may not use this file except in compliance with the License.
+/// You may obtain a copy of the License at
+///
+///     http://www.apache.org/licenses/LICENSE-2.0
+///
+/// Unless required by applicable law or agreed to in writing, software
+/// distributed under the License is distributed on an "AS IS" BASIS,
+/// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+/// See the License for the specific language governing permissions and
+/// limitations under the License.
+
+#include "Source/santad/DecisionHook.h"
+
+#import <XCTest/XCTest.h>
+#include <sys/socket.h>
+#include <sys/stat.h>
+#include <sys/un.h>
+#include <unistd.h>
+
+#include <string>
+
+using santa::DecisionHook;
+
+static constexpr std::chrono::milliseconds kTimeout(200);
+
+@interface DecisionHookTest : XCTestCase
+@property NSString* socketDir;
+@property NSString* socketPath;
+@property int listenFD;
+@property dispatch_queue_t serverQueue;
+@end
+
+@implementation DecisionHookTest
+
+- (void)setUp {
+  [super setUp];
+  // Keep the path short, sockaddr_un paths are limited to 104 bytes. The hook only trusts a
+  // socket in a directory that isn't writable by group or others, so /tmp itself won't do.
+  char dir[] = "/tmp/santa-hook-XXXXXX";
+  XCTAssertNotEqual(mkdtemp(dir), nullptr);
+  self.socketDir = @(dir);
+  self.socketPath = [self.socketDir stringByAppendingPathComponent:@"hook.sock"];
+  self.listenFD = -1;
+  self.serverQueue = dispatch_queue_create("com.northpolesec.santa.decisionhooktest", NULL);
+}
+
+- (void)tearDown {
+  if (self.listenFD >= 0) {
+    close(self.listenFD);
+  }
+  unlink(self.socketPath.UTF8String);
+  rmdir(self.socketDir.UTF8String);
+  [super tearDown];
+}
+
+// Start a listener that accepts a single connection, reads one request line and
+// then sends `reply` after `delay` seconds. A nil reply closes the connection
+// without responding. The received request is passed to `requestBlock`.
+- (void)startServerWithReply:(NSString*)reply
+                       delay:(NSTimeInterval)delay
+                requestBlock:(void (^)(NSDictionary*))requestBlock {
+  int fd = socket(AF_UNIX, SOCK_STREAM, 0);
+  XCTAssertGreaterThanOrEqual(fd, 0);
+
+  struct sockaddr_un addr = {.sun_family = AF_UNIX};
+  strlcpy(addr.sun_path, self.socketPath.UTF8String, sizeof(addr.sun_path));
+  XCTAssertEqual(bind(fd, (struct sockaddr*)&addr, sizeof(addr)), 0);
+  XCTAssertEqual(chmod(self.socketPath.UTF8String, 0600), 0);
+  XCTAssertEqual(listen(fd, 1), 0);
+  self.listenFD = fd;
+
+  dispatch_async(self.serverQueue, ^{
+    int conn = accept(fd, NULL, NULL);
+    if (conn < 0) return;
+
+    std::string request;
+    char c;
+    while (read(conn, &c, 1) == 1 && c != '\n') {
+      request.push_back(c);
+    }
+
+    if (requestBlock) {
+      NSData* data = [NSData dataWithBytes:request.data() length:request.size()];
+      requestBlock([NSJSONSerialization JSONObjectWithData:data options:0 error:nil]);
+    }
+
+    if (delay > 0) {
+      [NSThread sleepForTimeInterval:delay];
+    }
+
+    if (reply) {
+      NSString* line = [reply stringByAppendingString:@"\n"];
+      write(conn, line.UTF8String, strlen(line.UTF8String));
+    }
+    close(conn);
+  });
+}
+
+- (void)testAllow {
+  __block NSDictionary* received;
+  [self startServerWithReply:@"{\"decision\": \"allow\"}"
+                       delay:0
+                requestBlock:^(NSDictionary* request) {
+                  received = request;
+                }];
+
+  DecisionHook hook(self.socketPath.UTF8String, kTimeout, false, getuid());
+  XCTAssertEqual(hook.Evaluate(@{@"sha256" : @"abc", @"team_id" : @"EQHXZ8M8AV"}),
+                 DecisionHook::Verdict::kAllow);
+
+  // Wait for the server to finish before inspecting the request
+  dispatch_sync(self.serverQueue, ^{
+  });
+  XCTAssertEqualObjects(received[@"sha256"], @"abc");
+  XCTAssertEqualObjects(received[@"team_id"], @"EQHXZ8M8AV");
+}
+
+- (void)testBlock {
+  [self startServerWithReply:@"{\"decision\": \"block\"}" delay:0 requestBlock:nil];
+
+  DecisionHook hook(self.socketPath.UTF8String, kTimeout, false, getuid());
+  XCTAssertEqual(hook.Evaluate(@{@"sha256" : @"abc"}), DecisionHook::Verdict::kBlock);
+}
+
+- (void)testTimeoutFailOpen {
+  [self startServerWithReply:@"{\"decision\": \"block\"}" delay:1 requestBlock:nil];
+
+  DecisionHook hook(self.socketPath.UTF8String, kTimeout, false, getuid());
+  NSDate* start = [NSDate date];
+  XCTAssertEqual(hook.Evaluate(@{@"sha256" : @"abc"}), DecisionHook::Verdict::kNoDecision);
+  XCTAssertLessThan([[NSDate date] timeIntervalSinceDate:start], 0.9);
+}
+
+- (void)testTimeoutFailClosed {
+  [self startServerWithReply:@"{\"decision\": \"allow\"}" delay:1 requestBlock:nil];
+
+  DecisionHook hook(self.socketPath.UTF8String, kTimeout, true, getuid());
+  XCTAssertEqual(hook.Evaluate(@{@"sha256" : @"abc"}), DecisionHook::Verdict::kBlock);
+}
+
+- (void)testSocketUnavailable {
+  DecisionHook failOpen(self.socketPath.UTF8String, kTimeout, false, getuid());
+  XCTAssertEqual(failOpen.Evaluate(@{@"sha256" : @"abc"}), DecisionHook::Verdict::kNoDecision);
+
+  DecisionHook failClosed(self.socketPath.UTF8String, kTimeout, true, getuid());
+  XCTAssertEqual(failClosed.Evaluate(@{@"sha256" : @"abc"}), DecisionHook::Verdict::kBlock);
+}
+
+- (void)testMalformedReply {
+  [self startServerWithReply:@"{\"decision\": \"maybe\"}" delay:0 requestBlock:nil];
+
+  DecisionHook hook(self.socketPath.UTF8String, kTimeout, true, getuid());
+  XCTAssertEqual(hook.Evaluate(@{@"sha256" : @"abc"}), DecisionHook::Verdict::kBlock);
+}
+
+- (void)testUntrustedOwner {
+  [self startServerWithReply:@"{\"decision\": \"allow\"}" delay:0 requestBlock:nil];
+
+  // The socket and the listener belong to the test's uid, not the one the hook trusts.
+  DecisionHook failOpen(self.socketPath.UTF8String, kTimeout, false, getuid() + 1);
+  XCTAssertEqual(failOpen.Evaluate(@{@"sha256" : @"abc"}), DecisionHook::Verdict::kNoDecision);
+
+  DecisionHook failClosed(self.socketPath.UTF8String, kTimeout, true, getuid() + 1);
+  XCTAssertEqual(failClosed.Evaluate(@{@"sha256" : @"abc"}), DecisionHook::Verdict::kBlock);
+}
+
+- (void)testWritableSocketIsNotTrusted {
+  [self startServerWithReply:@"{\"decision\": \"allow\"}" delay:0 requestBlock:nil];
+  XCTAssertEqual(chmod(self.socketPath.UTF8String, 0666), 0);
+
+  DecisionHook hook(self.socketPath.UTF8String, kTimeout, true, getuid());
+  XCTAssertEqual(hook.Evaluate(@{@"sha256" : @"abc"}), DecisionHook::Verdict::kBlock);
+}
+
+- (void)testWritableDirectoryIsNotTrusted {
+  [self startServerWithReply:@"{\"decision\": \"allow\"}" delay:0 requestBlock:nil];
+  XCTAssertEqual(chmod(self.socketDir.UTF8String, 0777), 0);
+
+  DecisionHook hook(self.socketPath.UTF8String, kTimeout, true, getuid());
+  XCTAssertEqual(hook.Evaluate(@{@"sha256" : @"abc"}), DecisionHook::Verdict::kBlock);
+}
+
+- (void)testConnectionClosedWithoutReply {
+  [self startServerWithReply:nil delay:0 requestBlock:nil];
+
+  DecisionHook hook(self.socketPath.UTF8String, kTimeout, false, getuid());
+  XCTAssertEqual(hook.Evaluate(@{@"sha256" : @"abc"}), DecisionHook::Verdict::kNoDecision);
+}
+
+@end
@@ -114,12 +114,18 @@ class FAAPolicyProcessor {
   santa::SantaSetCache<std::pair<pid_t, int>, std::pair<std::string, std::string>>
       tty_message_cache_;
   SantaCache<SantaVnode, NSString*> cert_hash_cache_;
+  SantaCache<SantaVnode, NSString*> resolved_exec_path_cache_;
+  SantaCache<std::string, NSString*> resolved_policy_path_cache_;
   SNTConfigurator* configurator_;
   dispatch_queue_t queue_;
   RateLimiter rate_limiter_;
 
   virtual NSString* __strong GetCertificateHash(const es_file_t* es_file);
 
+  /// When CanonicalizeExecutablePaths is enabled, returns true if the canonical
+  /// forms of the policy binary path and the executable path are equal.
+  bool ResolvedBinaryPathMatches(const std::string& policy_path, const es_file_t* es_file);
+
   /// General flow of processing an ES message for FAA violations:
   /// 1. Client presents a vector of pairs of target paths being accessed and associated policies
   /// 2. Iterate each pair and compute a FileAccessPolicyDecision (ProcessTargetAndPolicy())
@@ -169,6 +175,9 @@ class FAAPolicyProcessor {
 
   void LogTelemetry(const WatchItemPolicyBase& policy, const Message& msg, size_t target_index,
                     FileAccessPolicyDecision decision);
+  /// Record the instigating process as the last writer of the target in SNTFileProvenanceIndex.
+  void RecordProvenance(const Message& msg, const Message::PathTarget& target,
+                        const WatchItemPolicyBase& policy);
   void LogTTY(SNTStoredFileAccessEvent* event, URLTextPair link_info, const Message& msg,
               const WatchItemPolicyBase& policy);
 };
@@ -23,12 +23,16 @@
 #include "Source/common/BranchPrediction.h"
 #import "Source/common/MOLCertificate.h"
 #import "Source/common/MOLCodesignChecker.h"
+#include "Source/common/PathCanonicalization.h"
 #import "Source/common/SNTBlockMessage.h"
 #import "Source/common/SNTFileInfo.h"
+#import "Source/common/SNTFileProvenance.h"
 #import "Source/common/SNTLogging.h"
 #import "Source/common/SNTStoredFileAccessEvent.h"
 #include "Source/common/String.h"
 #include "Source/common/es/EnrichedTypes.h"
+#include "Source/common/faa/WatchItemMatcher.h"
+#import "Source/santad/SNTFileProvenanceIndex.h"
 
 // Terminal value that will never match a valid cert hash.
 NSString* const kBadCertHash = @"BAD_CERT_HASH";
@@ -77,6 +81,22 @@ FileAccessPolicyDecision ApplyOverrideToDecision(FileAccessPolicyDecision decisi
   }
 }
 
+// Returns true if the event writes to the file at the given target index. Read-only sources of
+// clones, copies, links and renames are not writes.
+bool IsWriteTarget(const Message& msg, size_t target_index) {
+  switch (msg->event_type) {
+    case ES_EVENT_TYPE_AUTH_OPEN: return (msg->event.open.fflag & kOpenFlagsIndicatingWrite) != 0;
+    case ES_EVENT_TYPE_AUTH_CREATE: [[fallthrough]];
+    case ES_EVENT_TYPE_AUTH_EXCHANGEDATA: [[fallthrough]];
+    case ES_EVENT_TYPE_AUTH_TRUNCATE: return true;
+    case ES_EVENT_TYPE_AUTH_CLONE: [[fallthrough]];
+    case ES_EVENT_TYPE_AUTH_COPYFILE: [[fallthrough]];
+    case ES_EVENT_TYPE_AUTH_LINK: [[fallthrough]];
+    case ES_EVENT_TYPE_AUTH_RENAME: return target_index == 1;
+    default: return false;
+  }
+}
+
 bool ShouldLogDecision(FileAccessPolicyDecision decision) {
   switch (decision) {
     case FileAccessPolicyDecision::kDenied: return true;
@@ -219,85 +239,36 @@ bool FAAPolicyProcessor::PolicyMatchesProcess(const WatchItemProcess& policy_pro
   // outside of this method. This method is used to individually check each
   // configured process exception while the check for a valid code signature
   // is more broad and applies whether or not process exceptions exist.
-  if (es_proc->codesigning_flags & CS_SIGNED) {
-    // Check whether or not the process is a platform binary if specified by the policy.
-    if (policy_proc.platform_binary && !es_proc->is_platform_binary) {
-      return false;
-    }
-
-    // If the policy contains a team ID, check that the instigating process
-    // also has a team ID and matches the policy.
-    if (!policy_proc.team_id.empty() &&
-        (!es_proc->team_id.data || (policy_proc.team_id != es_proc->team_id.data))) {
-      // We expected a team ID to match against, but the process didn't have one.
-      return false;
-    }
-
-    // SigningID checks
-    if (!policy_proc.signing_id.empty()) {
-      if (!es_proc->signing_id.data) {
-        // Policy has SID set, but process has no SID
-        return false;
-      }
-
-      if (policy_proc.signing_id_wildcard_pos != std::string::npos) {
-        if (!policy_proc.platform_binary && policy_proc.team_id.empty()) {
-          // Policy SID is a prefix but neither Platform Binary nor Team ID were set
-          // Note: Config parsing should have ensured this isn't possible, but the runtime check
-          // here is meant as a fallback.
-          return false;
-        }
-
-        std::string_view sid_view = std::string_view(policy_proc.signing_id);
-        std::string_view prefix = sid_view.substr(0, policy_proc.signing_id_wildcard_pos);
-        std::string_view suffix = sid_view.substr(policy_proc.signing_id_wildcard_pos + 1);
-
-        // Skip comparison if the proc SID isn't long enough
-        if (es_proc->signing_id.length < (prefix.length() + suffix.length())) {
-          return false;
-        }
-
-        // Check the proc SID matches the policy SID prefix/suffix parts
-        if (strncmp(es_proc->signing_id.data, prefix.data(), prefix.length()) != 0 ||
-            strncmp(es_proc->signing_id.data + (es_proc->signing_id.length - suffix.length()),
-                    suffix.data(), suffix.length()) != 0) {
-          return false;
-        }
-      } else if (policy_proc.signing_id != es_proc->signing_id.data) {
-        // Policy SID didn't match process
-        return false;
-      }
-    }
+  return WatchItemProcessMatches(
+      policy_proc, es_proc,
+      ^NSString* {
+        return GetCertificateHash(es_proc->executable);
+      },
+      ^bool(const std::string& policy_path) {
+        return ResolvedBinaryPathMatches(policy_path, es_proc->executable);
+      });
+}
 
-    // Check if the instigating process has an allowed CDHash
-    if (policy_proc.cdhash.size() == CS_CDHASH_LEN &&
-        std::memcmp(policy_proc.cdhash.data(), es_proc->cdhash, CS_CDHASH_LEN) != 0) {
-      return false;
-    }
+bool FAAPolicyProcessor::ResolvedBinaryPathMatches(const std::string& policy_path,
+                                                   const es_file_t* es_file) {
+  if (![configurator_ canonicalizeExecutablePaths]) {
+    return false;
+  }
 
-    // Check if the instigating process has an allowed certificate hash
-    if (!policy_proc.certificate_sha256.empty()) {
-      NSString* result = GetCertificateHash(es_proc->executable);
-      if (!result || policy_proc.certificate_sha256 != [result UTF8String]) {
-        return false;
-      }
-    }
-  } else {
-    // If the process isn't signed, ensure the policy doesn't contain any
-    // attributes that require a signature
-    if (!policy_proc.team_id.empty() || !policy_proc.signing_id.empty() ||
-        policy_proc.cdhash.size() == CS_CDHASH_LEN || !policy_proc.certificate_sha256.empty()) {
-      return false;
-    }
+  SantaVnode vnodeID = SantaVnode::VnodeForFile(es_file);
+  NSString* resolvedExecPath = resolved_exec_path_cache_.get(vnodeID);
+  if (!resolvedExecPath) {
+    resolvedExecPath = CanonicalPath(StringTokenToNSString(es_file->path));
+    resolved_exec_path_cache_.set(vnodeID, resolvedExecPath);
   }
 
-  // Check if the instigating process path opening the file is allowed
-  if (policy_proc.binary_path.length() > 0 &&
-      policy_proc.binary_path != es_proc->executable->path.data) {
-    return false;
+  NSString* resolvedPolicyPath = resolved_policy_path_cache_.get(policy_path);
+  if (!resolvedPolicyPath) {
+    resolvedPolicyPath = CanonicalPath(@(policy_path.c_str()));
+    resolved_policy_path_cache_.set(policy_path, resolvedPolicyPath);
   }
 
-  return true;
+  return [resolvedExecPath isEqualToString:resolvedPolicyPath];
 }
 
 SNTCachedDecision* FAAPolicyProcessor::GetCachedDecision(const struct stat& stat_buf) {
@@ -374,28 +345,7 @@ FileAccessPolicyDecision FAAPolicyProcessor::ApplyPolicy(
     return FileAccessPolicyDecision::kAllowedReadAccess;
   }
 
-  FileAccessPolicyDecision decision = check_if_policy_matches_block(*policy, target, msg)
-                                          ? FileAccessPolicyDecision::kAllowed
-                                          : FileAccessPolicyDecision::kDenied;
-
-  // If the RuleType option was configured to contain a list of denied
-  // processes or denied paths, the decision should be inverted from allowed
-  // to denied or vice versa. Note that this inversion must be made prior to
-  // checking the policy's audit-only flag.
-  if (policy->rule_type == WatchItemRuleType::kPathsWithDeniedProcesses ||
-      policy->rule_type == WatchItemRuleType::kProcessesWithDeniedPaths) {
-    if (decision == FileAccessPolicyDecision::kAllowed) {
-      decision = FileAccessPolicyDecision::kDenied;
-    } else {
-      decision = FileAccessPolicyDecision::kAllowed;
-    }
-  }
-
-  if (decision == FileAccessPolicyDecision::kDenied && policy->audit_only) {
-    decision = FileAccessPolicyDecision::kAllowedAuditOnly;
-  }
-
-  return decision;
+  return DecisionForPolicyMatch(*policy, check_if_policy_matches_block(*policy, target, msg));
 }
 
 void FAAPolicyProcessor::LogTelemetry(const WatchItemPolicyBase& policy, const Message& msg,
@@ -602,6 +552,15 @@ FAAPolicyProcessor::ESResult FAAPolicyProcessor::ProcessMessage(
                                                 path_target.unsafe_file->stat.st_ino}));
     }
 
+    // Remember the writer of watched files that were allowed to be written so that a later
+    // execution of the file can be linked back to it.
+    if ((decision == FileAccessPolicyDecision::kAllowed ||
+         decision == FileAccessPolicyDecision::kAllowedAuditOnly) &&
+        !path_target.truncated && target_policy_pair.second.has_value() &&
+        IsWriteTarget(msg, target_policy_pair.first)) {
+      RecordProvenance(msg, path_target, **target_policy_pair.second);
+    }
+
     policy_result =
         CombinePolicyResults(policy_result, FileAccessPolicyDecisionToESAuthResult(decision));
 
@@ -617,6 +576,24 @@ FAAPolicyProcessor::ESResult FAAPolicyProcessor::ProcessMessage(
   return {policy_result, cacheable};
 }
 
+void FAAPolicyProcessor::RecordProvenance(const Message& msg, const Message::PathTarget& target,
+                                          const WatchItemPolicyBase& policy) {
+  SNTFileProvenance* provenance = [[SNTFileProvenance alloc] init];
+  provenance.writerPath = StringTokenToNSString(msg->process->executable->path);
+  provenance.writerPID = @(audit_token_to_pid(msg->process->audit_token));
+  provenance.writerSigningID = StringTokenToNSString(msg->process->signing_id);
+  provenance.writerTeamID = StringTokenToNSString(msg->process->team_id);
+  provenance.writerCDHash =
+      (msg->process->codesigning_flags & CS_SIGNED)
+          ? StringToNSString(BufToHexString(msg->process->cdhash, sizeof(msg->process->cdhash)))
+          : nil;
+  provenance.ruleName = StringToNSString(policy.name);
+  provenance.writeTime = [NSDate date];
+
+  [[SNTFileProvenanceIndex sharedIndex] recordProvenance:provenance
+                                                 forPath:StringToNSString(target.Path())];
+}
+
 std::optional<FAAPolicyProcessor::ESResult> FAAPolicyProcessor::ImmediateResponse(
     const Message& msg, FAAClientType client_type) {
   // Note: Some other events have readable targets, but only events where all
@@ -27,12 +27,14 @@
 #import "Source/common/MOLCodesignChecker.h"
 #import "Source/common/SNTCachedDecision.h"
 #import "Source/common/SNTFileInfo.h"
+#import "Source/common/SNTFileProvenance.h"
 #include "Source/common/TestUtils.h"
 #include "Source/common/es/Message.h"
 #include "Source/common/es/MockEndpointSecurityAPI.h"
 #include "Source/common/faa/WatchItemPolicy.h"
 #include "Source/santad/EventProviders/MockFAAPolicyProcessor.h"
 #import "Source/santad/SNTDecisionCache.h"
+#import "Source/santad/SNTFileProvenanceIndex.h"
 
 using santa::FAAPolicyProcessor;
 using santa::Message;
@@ -46,6 +48,7 @@ extern FileAccessPolicyDecision ApplyOverrideToDecision(FileAccessPolicyDecision
 extern es_auth_result_t CombinePolicyResults(es_auth_result_t result1, es_auth_result_t result2);
 extern es_auth_result_t FileAccessPolicyDecisionToESAuthResult(FileAccessPolicyDecision decision);
 extern bool IsBlockDecision(FileAccessPolicyDecision decision);
+extern bool IsWriteTarget(const Message& msg, size_t target_index);
 extern bool ShouldLogDecision(FileAccessPolicyDecision decision);
 }  // namespace santa
 
@@ -841,6 +844,98 @@ static void ClearWatchItemPolicyProcess(WatchItemProcess& proc) {
   }
 }
 
+- (void)testPolicyMatchesProcessSigningIDAcrossPaths {
+  es_file_t esFileV1 = MakeESFile("/Applications/Foo.app/Contents/MacOS/foo");
+  es_file_t esFileV2 = MakeESFile("/Applications/Foo 2.app/Contents/MacOS/foo");
+  es_process_t esProcV1 = MakeESProcess(&esFileV1);
+  es_process_t esProcV2 = MakeESProcess(&esFileV2);
+  for (es_process_t* esProc : {&esProcV1, &esProcV2}) {
+    esProc->codesigning_flags = CS_SIGNED;
+    esProc->team_id = MakeESStringToken("myvalidtid");
+    esProc->signing_id = MakeESStringToken("com.northpolesec.foo");
+  }
+
+  MockFAAPolicyProcessor faaPolicyProcessor(self.dcMock, nullptr, nullptr, nullptr, nullptr, 0, 0,
+                                            nil, nil);
+
+  EXPECT_CALL(faaPolicyProcessor, PolicyMatchesProcess)
+      .WillRepeatedly([&faaPolicyProcessor](const WatchItemProcess& policy_proc,
+                                            const es_process_t* es_proc) {
+        return faaPolicyProcessor.FAAPolicyProcessor::PolicyMatchesProcess(policy_proc, es_proc);
+      });
+
+  // The process is identified by the "TID:SID" form of the SigningID
+  // attribute, without any BinaryPath, so it matches at either location.
+  NSError* err;
+  std::optional<WatchItemProcess> policyProc = WatchItemProcess::Create(
+      nil, @"myvalidtid:com.northpolesec.foo", nil, nil, nil, false, &err);
+  XCTAssertTrue(policyProc.has_value());
+  XCTAssertNil(err);
+  XCTAssertTrue(faaPolicyProcessor.PolicyMatchesProcess(*policyProc, &esProcV1));
+  XCTAssertTrue(faaPolicyProcessor.PolicyMatchesProcess(*policyProc, &esProcV2));
+
+  // Separately specified SigningID and TeamID attributes behave the same
+  policyProc = WatchItemProcess::Create(nil, @"com.northpolesec.foo", @"myvalidtid", nil, nil,
+                                        false, &err);
+  XCTAssertTrue(policyProc.has_value());
+  XCTAssertTrue(faaPolicyProcessor.PolicyMatchesProcess(*policyProc, &esProcV1));
+  XCTAssertTrue(faaPolicyProcessor.PolicyMatchesProcess(*policyProc, &esProcV2));
+
+  // A different signing ID at the same paths does not match
+  esProcV2.signing_id = MakeESStringToken("com.northpolesec.bar");
+  XCTAssertTrue(faaPolicyProcessor.PolicyMatchesProcess(*policyProc, &esProcV1));
+  XCTAssertFalse(faaPolicyProcessor.PolicyMatchesProcess(*policyProc, &esProcV2));
+
+  // Pinning a BinaryPath in addition to the SigningID ties the rule to one path
+  esProcV2.signing_id = MakeESStringToken("com.northpolesec.foo");
+  policyProc = WatchItemProcess::Create(@"/Applications/Foo.app/Contents/MacOS/foo",
+                                        @"com.northpolesec.foo", @"myvalidtid", nil, nil, false,
+                                        &err);
+  XCTAssertTrue(policyProc.has_value());
+  XCTAssertTrue(faaPolicyProcessor.PolicyMatchesProcess(*policyProc, &esProcV1));
+  XCTAssertFalse(faaPolicyProcessor.PolicyMatchesProcess(*policyProc, &esProcV2));
+}
+
+- (void)testPolicyMatchesProcessSymlinkedBinaryPath {
+  NSString* tempDir =
+      [NSTemporaryDirectory() stringByAppendingPathComponent:[[NSUUID UUID] UUIDString]];
+  XCTAssertTrue([[NSFileManager defaultManager] createDirectoryAtPath:tempDir
+                                          withIntermediateDirectories:YES
+                                                           attributes:nil
+                                                                error:NULL]);
+  NSString* link = [tempDir stringByAppendingPathComponent:@"ls"];
+  XCTAssertTrue([[NSFileManager defaultManager] createSymbolicLinkAtPath:link
+                                                     withDestinationPath:@"/bin/ls"
+                                                                   error:NULL]);
+
+  // ES reports the real path of the executable, the policy names the symlink
+  es_file_t esFile = MakeESFile("/bin/ls");
+  es_process_t esProc = MakeESProcess(&esFile);
+
+  MockFAAPolicyProcessor faaPolicyProcessor(self.dcMock, nullptr, nullptr, nullptr, nullptr, 0, 0,
+                                            nil, nil);
+
+  EXPECT_CALL(faaPolicyProcessor, PolicyMatchesProcess)
+      .WillRepeatedly([&faaPolicyProcessor](const WatchItemProcess& policy_proc,
+                                            const es_process_t* es_proc) {
+        return faaPolicyProcessor.FAAPolicyProcessor::PolicyMatchesProcess(policy_proc, es_proc);
+      });
+
+  WatchItemProcess policyProc("", "", "", {}, "", false);
+  policyProc.binary_path = link.UTF8String;
+
+  // Without canonicalization only the exact path matches
+  XCTAssertFalse(faaPolicyProcessor.PolicyMatchesProcess(policyProc, &esProc));
+
+  OCMStub([self.mockConfigurator canonicalizeExecutablePaths]).andReturn(YES);
+  XCTAssertTrue(faaPolicyProcessor.PolicyMatchesProcess(policyProc, &esProc));
+
+  policyProc.binary_path = "/bin/cat";
+  XCTAssertFalse(faaPolicyProcessor.PolicyMatchesProcess(policyProc, &esProc));
+
+  [[NSFileManager defaultManager] removeItemAtPath:tempDir error:NULL];
+}
+
 - (void)testProcessTargetAndPolicyTriggersRehydrateOnCacheMiss {
   es_file_t esFile = MakeESFile("/proc/instigator");
   esFile.stat = MakeStat();
@@ -1073,4 +1168,91 @@ static void ClearWatchItemPolicyProcess(WatchItemProcess& proc) {
   XCTBubbleMockVerifyAndClearExpectations(mockESApi.get());
 }
 
+- (void)testIsWriteTarget {
+  es_file_t esFile = MakeESFile("/proc/instigator");
+  es_process_t esProc = MakeESProcess(&esFile);
+  auto mockESApi = std::make_shared<MockEndpointSecurityAPI>();
+  mockESApi->SetExpectationsRetainReleaseMessage();
+
+  es_message_t esMsg = MakeESMessage(ES_EVENT_TYPE_AUTH_OPEN, &esProc);
+  esMsg.event.open.fflag = FREAD;
+  XCTAssertFalse(santa::IsWriteTarget(Message(mockESApi, &esMsg), 0));
+  esMsg.event.open.fflag = FREAD | FWRITE;
+  XCTAssertTrue(santa::IsWriteTarget(Message(mockESApi, &esMsg), 0));
+
+  for (es_event_type_t eventType :
+       {ES_EVENT_TYPE_AUTH_CREATE, ES_EVENT_TYPE_AUTH_TRUNCATE, ES_EVENT_TYPE_AUTH_EXCHANGEDATA}) {
+    esMsg = MakeESMessage(eventType, &esProc);
+    XCTAssertTrue(santa::IsWriteTarget(Message(mockESApi, &esMsg), 0));
+  }
+
+  // Only the destination of these events is written
+  for (es_event_type_t eventType : {ES_EVENT_TYPE_AUTH_CLONE, ES_EVENT_TYPE_AUTH_COPYFILE,
+                                    ES_EVENT_TYPE_AUTH_LINK, ES_EVENT_TYPE_AUTH_RENAME}) {
+    esMsg = MakeESMessage(eventType, &esProc);
+    XCTAssertFalse(santa::IsWriteTarget(Message(mockESApi, &esMsg), 0));
+    XCTAssertTrue(santa::IsWriteTarget(Message(mockESApi, &esMsg), 1));
+  }
+
+  esMsg = MakeESMessage(ES_EVENT_TYPE_AUTH_UNLINK, &esProc);
+  XCTAssertFalse(santa::IsWriteTarget(Message(mockESApi, &esMsg), 0));
+
+  XCTBubbleMockVerifyAndClearExpectations(mockESApi.get());
+}
+
+- (void)testProcessMessageRecordsProvenanceOfAllowedWrites {
+  es_file_t esFile = MakeESFile("/usr/bin/curl");
+  es_process_t esProc = MakeESProcess(&esFile);
+  esProc.audit_token = MakeAuditToken(12, 34);
+  esProc.signing_id = MakeESStringToken("com.apple.curl");
+  esProc.team_id = MakeESStringToken("");
+  es_file_t srcFile = MakeESFile("/tmp/download.partial");
+  es_file_t dstFile = MakeESFile("/Users/Shared/provenance_test_tool");
+  es_message_t esMsg = MakeESMessage(ES_EVENT_TYPE_AUTH_RENAME, &esProc);
+  esMsg.event.rename.source = &srcFile;
+  esMsg.event.rename.destination_type = ES_DESTINATION_TYPE_EXISTING_FILE;
+  esMsg.event.rename.destination.existing_file = &dstFile;
+
+  auto mockESApi = std::make_shared<MockEndpointSecurityAPI>();
+  mockESApi->SetExpectationsRetainReleaseMessage();
+
+  MockFAAPolicyProcessor faaPolicyProcessor(self.dcMock, nullptr, nullptr, nullptr, nullptr, 0, 0,
+                                            nil, nil);
+  auto policy = std::make_shared<WatchItemPolicyBase>("downloads_policy", "ver", "/Users/Shared");
+  auto matcher =
+      ^bool(const santa::WatchItemPolicyBase&, const Message::PathTarget&, const Message&) {
+        return true;
+      };
+
+  SNTFileProvenanceIndex* index = [SNTFileProvenanceIndex sharedIndex];
+  Message msg(mockESApi, &esMsg);
+
+  // Writes that no policy applied to are not recorded
+  EXPECT_CALL(faaPolicyProcessor, ApplyPolicy)
+      .WillOnce(testing::Return(FileAccessPolicyDecision::kNoPolicy))
+      .WillOnce(testing::Return(FileAccessPolicyDecision::kNoPolicy));
+  faaPolicyProcessor.ProcessMessageWrapper(msg, {{0, policy}, {1, policy}}, matcher, nil,
+                                           SNTOverrideFileAccessActionNone, FAAClientType::kData);
+  XCTAssertNil([index provenanceForPath:@"/Users/Shared/provenance_test_tool" date:[NSDate date]]);
+
+  // The source of the rename isn't written, only the destination is recorded
+  EXPECT_CALL(faaPolicyProcessor, ApplyPolicy)
+      .WillRepeatedly(testing::Return(FileAccessPolicyDecision::kAllowed));
+  faaPolicyProcessor.ProcessMessageWrapper(msg, {{0, policy}, {1, policy}}, matcher, nil,
+                                           SNTOverrideFileAccessActionNone, FAAClientType::kData);
+  XCTAssertNil([index provenanceForPath:@"/tmp/download.partial" date:[NSDate date]]);
+
+  SNTFileProvenance* provenance =
+      [index provenanceForPath:@"/Users/Shared/provenance_test_tool" date:[NSDate date]];
+  XCTAssertEqualObjects(provenance.writerPath, @"/usr/bin/curl");
+  XCTAssertEqualObjects(provenance.writerPID, @(12));
+  XCTAssertEqualObjects(provenance.writerSigningID, @"com.apple.curl");
+  XCTAssertEqualObjects(provenance.ruleName, @"downloads_policy");
+  XCTAssertNil(provenance.writerCDHash);
+  XCTAssertNotNil(provenance.writeTime);
+
+  XCTBubbleMockVerifyAndClearExpectations(&faaPolicyProcessor);
+  XCTBubbleMockVerifyAndClearExpectations(mockESApi.get());
+}
+
 @end
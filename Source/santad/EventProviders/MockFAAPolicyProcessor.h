@@ -86,6 +86,16 @@ class MockFAAPolicyProcessor : public FAAPolicyProcessor {
     return FAAPolicyProcessor::ProcessTargetAndPolicy(
         msg, target_policy_pair, checkIfPolicyMatchesBlock, fileAccessDeniedBlock, overrideAction);
   }
+
+  FAAPolicyProcessor::ESResult ProcessMessageWrapper(
+      const Message& msg, std::vector<FAAPolicyProcessor::TargetPolicyPair> target_policy_pairs,
+      FAAPolicyProcessor::CheckIfPolicyMatchesBlock checkIfPolicyMatchesBlock,
+      SNTFileAccessDeniedBlock fileAccessDeniedBlock, SNTOverrideFileAccessAction overrideAction,
+      FAAClientType clientType) {
+    return FAAPolicyProcessor::ProcessMessage(msg, std::move(target_policy_pairs),
+                                              checkIfPolicyMatchesBlock, fileAccessDeniedBlock,
+                                              overrideAction, clientType);
+  }
 };
 
 }  // namespace santa
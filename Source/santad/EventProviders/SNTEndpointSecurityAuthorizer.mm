@@ -22,17 +22,35 @@
 #import "Source/common/BranchPrediction.h"
 #import "Source/common/SNTCachedDecision.h"
 #import "Source/common/SNTCommonEnums.h"
+#import "Source/common/SNTConfigurator.h"
 #import "Source/common/SNTLogging.h"
+#include "Source/common/SantaCache.h"
+#import "Source/common/SantaVnode.h"
 #include "Source/common/es/ESMetricsObserver.h"
 #include "Source/common/es/EnrichedTypes.h"
 #include "Source/common/es/Message.h"
 #include "Source/santad/EventProviders/AuthResultCache.h"
+#include "Source/santad/InterpreterScript.h"
 
 using santa::AuthResultCache;
 using santa::EndpointSecurityAPI;
 using santa::EventDisposition;
 using santa::Message;
 
+// Whether the exec is of a shebang script that EnableScriptEvaluation requires
+// to be judged on its own, or of a known interpreter whose script
+// EnableInterpreterScriptEvaluation requires to be. The exec target is then the
+// interpreter, so a decision cached for the interpreter must not answer for the
+// script and vice versa.
+static bool IsEvaluatedScriptExec(const Message& msg) {
+  SNTConfigurator* config = [SNTConfigurator configurator];
+  if (msg->version >= 2 && msg->event.exec.script && [config enableScriptEvaluation]) {
+    return true;
+  }
+  return [config enableInterpreterScriptEvaluation] &&
+         santa::KnownInterpreter(msg->event.exec.target).has_value();
+}
+
 @interface SNTEndpointSecurityAuthorizer ()
 @property SNTCompilerController* compilerController;
 @property SNTExecutionController* execController;
@@ -43,6 +61,12 @@ using santa::Message;
 @implementation SNTEndpointSecurityAuthorizer {
   std::shared_ptr<AuthResultCache> _authResultCache;
   std::shared_ptr<santa::TTYWriter> _ttyWriter;
+  // Executables that have been seen running a script while EnableScriptEvaluation
+  // or EnableInterpreterScriptEvaluation was enabled. ES caches exec results per
+  // executable, so allowing one of these with caching enabled would let the next
+  // script it runs go unevaluated. Known shebang interpreters are never cached
+  // while EnableScriptEvaluation is enabled, whether or not they are in here.
+  std::unique_ptr<SantaCache<SantaVnode, bool>> _scriptInterpreters;
 }
 
 - (instancetype)initWithESAPI:(std::shared_ptr<EndpointSecurityAPI>)esApi
@@ -62,6 +86,7 @@ using santa::Message;
     _compilerController = compilerController;
     _authResultCache = authResultCache;
     _ttyWriter = std::move(ttyWriter);
+    _scriptInterpreters = std::make_unique<SantaCache<SantaVnode, bool>>(1024);
 
     _probes = [NSMutableArray array];
 
@@ -95,7 +120,8 @@ using santa::Message;
   // update made the executable allowable, ES would continue to apply the DENY
   // cached result. Note however that the local AuthResultCache will cache
   // DENY results. The caller may also prevent caching if it has reason to so.
-  bool cacheable = (result == ES_AUTH_RESULT_ALLOW) && !forcePreventCache;
+  bool cacheable = (result == ES_AUTH_RESULT_ALLOW) && !forcePreventCache &&
+                   ![self isScriptInterpreter:msg->event.exec.target];
 
   for (id<SNTEndpointSecurityProbe> probe in self.probes) {
     santa::ProbeInterest interest = [probe probeInterest:msg];
@@ -126,7 +152,10 @@ using santa::Message;
 
   SNTCachedDecision* cd = nil;
 
-  while (true) {
+  bool scriptExec = IsEvaluatedScriptExec(msg);
+  if (scriptExec) [self recordScriptInterpreter:targetProc];
+
+  while (!scriptExec) {
     santa::CachedAuthResult cacheEntry = self->_authResultCache->CheckCache(targetProc->executable);
     SNTAction returnAction = cacheEntry.action;
     if (RESPONSE_VALID(returnAction)) {
@@ -178,7 +207,9 @@ using santa::Message;
     }
   }
 
-  self->_authResultCache->AddToCache(targetProc->executable, SNTActionRequestBinary);
+  if (!scriptExec) {
+    self->_authResultCache->AddToCache(targetProc->executable, SNTActionRequestBinary);
+  }
 
   [self.execController validateExecEvent:msg
                           cachedDecision:cd
@@ -261,7 +292,11 @@ using santa::Message;
       [NSException raise:@"Invalid post action" format:@"Invalid post action: %ld", action];
   }
 
-  self->_authResultCache->AddToCache(esMsg->event.exec.target->executable, action, cd);
+  if (IsEvaluatedScriptExec(esMsg)) {
+    cacheable = false;
+  } else {
+    self->_authResultCache->AddToCache(esMsg->event.exec.target->executable, action, cd);
+  }
 
   if (action != SNTActionHoldAllowed && action != SNTActionHoldDenied) {
     return [self respondToMessage:esMsg withAuthResult:authResult forcePreventCache:!cacheable];
@@ -270,6 +305,25 @@ using santa::Message;
   }
 }
 
+- (bool)isScriptInterpreter:(const es_process_t*)proc {
+  // A known interpreter may not have run a script yet. If ES cached its direct exec, the
+  // first script it runs would never be delivered.
+  if ([[SNTConfigurator configurator] enableScriptEvaluation] &&
+      santa::IsShebangInterpreter(proc)) {
+    return true;
+  }
+  return _scriptInterpreters->get(SantaVnode::VnodeForFile(proc->executable));
+}
+
+- (void)recordScriptInterpreter:(const es_process_t*)proc {
+  SantaVnode vnode = SantaVnode::VnodeForFile(proc->executable);
+  if (_scriptInterpreters->get(vnode)) return;
+
+  _scriptInterpreters->set(vnode, true);
+  // Drop any result ES cached for the interpreter before it was known to be one.
+  [super clearCache];
+}
+
 - (void)enable {
   self.enabled = YES;
   [super subscribeAndClearCache:{
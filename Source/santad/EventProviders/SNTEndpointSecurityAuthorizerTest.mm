@@ -25,6 +25,7 @@
 
 #import "Source/common/SNTCachedDecision.h"
 #import "Source/common/SNTCommonEnums.h"
+#import "Source/common/SNTConfigurator.h"
 #include "Source/common/TestUtils.h"
 #include "Source/common/es/Client.h"
 #include "Source/common/es/Message.h"
@@ -484,4 +485,84 @@ class MockAuthResultCache : public AuthResultCache {
   [mockAuthClient stopMocking];
 }
 
+- (void)testShebangInterpreterIsNotCachedBeforeItRunsAScript {
+  es_file_t file = MakeESFile("foo");
+  es_process_t proc = MakeESProcess(&file);
+  es_file_t zshFile = MakeESFile("/bin/zsh");
+  es_process_t zshProc = MakeESProcess(&zshFile, MakeAuditToken(12, 23), MakeAuditToken(34, 45));
+  zshProc.signing_id = MakeESStringToken("com.apple.zsh");
+  zshProc.is_platform_binary = true;
+  es_file_t scriptFile = MakeESFile("/tmp/script.sh");
+
+  // The interpreter is first executed directly, then runs a script.
+  es_message_t directMsg = MakeESMessage(ES_EVENT_TYPE_AUTH_EXEC, &proc, ActionType::Auth);
+  directMsg.event.exec.target = &zshProc;
+  es_message_t scriptMsg = MakeESMessage(ES_EVENT_TYPE_AUTH_EXEC, &proc, ActionType::Auth);
+  scriptMsg.event.exec.target = &zshProc;
+  scriptMsg.event.exec.script = &scriptFile;
+
+  auto mockESApi = std::make_shared<MockEndpointSecurityAPI>();
+  mockESApi->SetExpectationsESNewClient();
+  mockESApi->SetExpectationsRetainReleaseMessage();
+
+  // Only the direct exec goes into the AuthResultCache, script execs never do.
+  auto mockAuthCache = std::make_shared<MockAuthResultCache>(nullptr, nil);
+  EXPECT_CALL(*mockAuthCache, AddToCache(&zshFile, SNTActionRespondAllow, testing::_))
+      .Times(2)
+      .WillRepeatedly(testing::Return(true));
+
+  id mockConfigurator = OCMClassMock([SNTConfigurator class]);
+  OCMStub([mockConfigurator configurator]).andReturn(mockConfigurator);
+  __block BOOL scriptEvaluation = YES;
+  OCMStub([mockConfigurator enableScriptEvaluation]).andDo(^(NSInvocation* inv) {
+    [inv setReturnValue:&scriptEvaluation];
+  });
+
+  SNTEndpointSecurityAuthorizer* authClient =
+      [[SNTEndpointSecurityAuthorizer alloc] initWithESAPI:mockESApi
+                                                   metrics:nullptr
+                                            execController:nil
+                                        compilerController:nil
+                                           authResultCache:mockAuthCache
+                                                 ttyWriter:nullptr
+                                               processTree:nullptr];
+  id mockAuthClient = OCMPartialMock(authClient);
+
+  __block bool gotCachable;
+  OCMStub([mockAuthClient respondToMessage:Message(mockESApi, &directMsg)
+                            withAuthResult:ES_AUTH_RESULT_ALLOW
+                                 cacheable:false])
+      .ignoringNonObjectArgs()
+      .andDo(^(NSInvocation* inv) {
+        [inv getArgument:&gotCachable atIndex:4];
+      });
+
+  // If ES cached the direct exec, the script exec would never be delivered.
+  gotCachable = true;
+  [mockAuthClient postAction:SNTActionRespondAllow
+                  forMessage:Message(mockESApi, &directMsg)
+                withDecision:nil];
+  XCTAssertFalse(gotCachable);
+
+  gotCachable = true;
+  [mockAuthClient postAction:SNTActionRespondAllow
+                  forMessage:Message(mockESApi, &scriptMsg)
+                withDecision:nil];
+  XCTAssertFalse(gotCachable);
+
+  // Without script evaluation the interpreter is cached as before.
+  scriptEvaluation = NO;
+  gotCachable = false;
+  [mockAuthClient postAction:SNTActionRespondAllow
+                  forMessage:Message(mockESApi, &directMsg)
+                withDecision:nil];
+  XCTAssertTrue(gotCachable);
+
+  XCTBubbleMockVerifyAndClearExpectations(mockESApi.get());
+  XCTBubbleMockVerifyAndClearExpectations(mockAuthCache.get());
+
+  [mockAuthClient stopMocking];
+  [mockConfigurator stopMocking];
+}
+
 @end
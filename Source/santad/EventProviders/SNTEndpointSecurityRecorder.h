@@ -24,6 +24,7 @@
 #import "Source/santad/EventProviders/SNTEndpointSecurityTreeAwareClient.h"
 #include "Source/santad/Logs/EndpointSecurity/Logger.h"
 #import "Source/santad/SNTCompilerController.h"
+#import "Source/santad/SNTLaunchItemMonitor.h"
 
 @protocol SNTLoginWindowSessionHandler;
 
@@ -43,4 +44,7 @@
                   processTree:
                       (std::shared_ptr<santa::santad::process_tree::ProcessTree>)processTree;
 
+/// Evaluates launch items as they are registered. Not set when no monitor is configured.
+@property(nonatomic) SNTLaunchItemMonitor* launchItemMonitor;
+
 @end
@@ -30,7 +30,10 @@
 #include "Source/common/es/Message.h"
 #include "Source/common/processtree/process_tree.h"
 #include "Source/santad/EventProviders/AuthResultCache.h"
+#include "Source/santad/ProcessControl.h"
+#import "Source/santad/SNTCodeSignatureMonitor.h"
 #import "Source/santad/SNTDecisionCache.h"
+#import "Source/santad/SNTExecutionCounts.h"
 
 using santa::AuthResultCache;
 using santa::EndpointSecurityAPI;
@@ -60,6 +63,7 @@ es_file_t* GetTargetFileForPrefixTree(const es_message_t* msg) {
 @property SNTCompilerController* compilerController;
 @property(nonatomic, strong) id<SNTLoginWindowSessionHandler> loginWindowSessionHandler;
 @property SNTConfigurator* configurator;
+@property SNTCodeSignatureMonitor* codeSignatureMonitor;
 @end
 
 @implementation SNTEndpointSecurityRecorder {
@@ -90,6 +94,8 @@ es_file_t* GetTargetFileForPrefixTree(const es_message_t* msg) {
     _authResultCache = authResultCache;
     _prefixTree = prefixTree;
     _configurator = [SNTConfigurator configurator];
+    _codeSignatureMonitor = [[SNTCodeSignatureMonitor alloc]
+        initWithProcessControlBlock:santa::ProdSuspendResumeBlock()];
 
     [self establishClientOrDie];
   }
@@ -140,6 +146,26 @@ es_file_t* GetTargetFileForPrefixTree(const es_message_t* msg) {
                                username:santa::StringToNSString(santa::StringTokenToStringView(
                                             esMsg->event.lw_session_logout->username))];
       break;
+    case ES_EVENT_TYPE_NOTIFY_CS_INVALIDATED:
+      // Handled before the telemetry check so the configured response is applied even when
+      // CodesigningInvalidated events are not being logged.
+      [self.codeSignatureMonitor handleInvalidatedProcess:esMsg->process];
+      break;
+    case ES_EVENT_TYPE_NOTIFY_BTM_LAUNCH_ITEM_ADD:
+      // Likewise, launch items are evaluated whether or not BTM events are being logged.
+      [self.launchItemMonitor handleLaunchItemAdd:esMsg->event.btm_launch_item_add];
+      break;
+    case ES_EVENT_TYPE_NOTIFY_EXEC: {
+      // Counted before the telemetry check so the execution counts reported to the sync server
+      // don't depend on whether exec events are being logged. Executions held for a TouchID
+      // approval are counted by the execution controller once approved.
+      SNTCachedDecision* cd = [[SNTDecisionCache sharedCache]
+          cachedDecisionForFile:esMsg->event.exec.target->executable->stat];
+      if (cd && !cd.holdAndAsk) {
+        [[SNTExecutionCounts sharedCounts] recordExecution:cd];
+      }
+      break;
+    }
     default: break;
   }
 
@@ -42,6 +42,8 @@
 #include "Source/santad/Metrics.h"
 #import "Source/santad/SNTCompilerController.h"
 #import "Source/santad/SNTDecisionCache.h"
+#import "Source/santad/SNTExecutionCounts.h"
+#import "Source/santad/SNTLaunchItemMonitor.h"
 
 using santa::AuthResultCache;
 using santa::EnrichedMessage;
@@ -793,4 +795,127 @@ es_file_t targetFileMissesRegex = MakeESFile("/foo/misses");
   [mockDecisionCache stopMocking];
 }
 
+- (void)testHandleExecCountsAllowedExecutionWhenNotLogging {
+  // Allowed executions are counted for the sync server even when exec events are not logged.
+  es_file_t file = MakeESFile("foo");
+  es_process_t proc = MakeESProcess(&file);
+  es_file_t execFile = MakeESFile("bar", {.st_dev = 12, .st_ino = 34});
+  es_process_t execProc = MakeESProcess(&execFile);
+  es_message_t esMsg = MakeESMessage(ES_EVENT_TYPE_NOTIFY_EXEC, &proc, ActionType::Notify);
+  esMsg.event.exec.target = &execProc;
+
+  auto mockESApi = std::make_shared<MockEndpointSecurityAPI>();
+  mockESApi->SetExpectationsESNewClient();
+  mockESApi->SetExpectationsRetainReleaseMessage();
+
+  auto mockEnricher = std::make_shared<santa::MockEnricher>();
+  auto mockAuthCache = std::make_shared<MockAuthResultCache>(nullptr, nil);
+  auto mockLogger = std::make_shared<MockLogger>();
+  mockLogger->SetTelemetryMask(TelemetryEvent::kNone);
+  auto prefixTree = std::make_shared<PrefixTree<Unit>>();
+
+  EXPECT_CALL(*mockEnricher, Enrich).Times(0);
+  EXPECT_CALL(*mockLogger, Log).Times(0);
+
+  id mockDecisionCache = OCMClassMock([SNTDecisionCache class]);
+  OCMStub([mockDecisionCache sharedCache]).andReturn(mockDecisionCache);
+  SNTCachedDecision* cd = [[SNTCachedDecision alloc] init];
+  cd.decision = SNTEventStateAllowBinary;
+  cd.sha256 = @"aaaa";
+  OCMStub([mockDecisionCache cachedDecisionForFile:esMsg.event.exec.target->executable->stat])
+      .ignoringNonObjectArgs()
+      .andReturn(cd);
+
+  id mockCounts = OCMClassMock([SNTExecutionCounts class]);
+  OCMStub([mockCounts sharedCounts]).andReturn(mockCounts);
+  OCMExpect([mockCounts recordExecution:cd]);
+
+  id mockCC = OCMStrictClassMock([SNTCompilerController class]);
+  Message msg(mockESApi, &esMsg);
+  OCMExpect([mockCC handleEvent:msg withLogger:nullptr]).ignoringNonObjectArgs();
+
+  SNTEndpointSecurityRecorder* recorderClient =
+      [[SNTEndpointSecurityRecorder alloc] initWithESAPI:mockESApi
+                                                 metrics:nullptr
+                                                  logger:mockLogger
+                                                enricher:mockEnricher
+                                      compilerController:mockCC
+                               loginWindowSessionHandler:nil
+                                         authResultCache:mockAuthCache
+                                              prefixTree:prefixTree
+                                             processTree:nullptr];
+
+  [recorderClient handleMessage:Message(mockESApi, &esMsg)
+             recordEventMetrics:^(EventDisposition d) {
+               XCTAssertEqual(d, EventDisposition::kDropped);
+             }];
+
+  XCTAssertTrue(OCMVerifyAll(mockCounts));
+  XCTAssertTrue(OCMVerifyAll(mockCC));
+  XCTBubbleMockVerifyAndClearExpectations(mockEnricher.get());
+  XCTBubbleMockVerifyAndClearExpectations(mockLogger.get());
+  XCTBubbleMockVerifyAndClearExpectations(mockESApi.get());
+
+  [mockCC stopMocking];
+  [mockCounts stopMocking];
+  [mockDecisionCache stopMocking];
+}
+
+- (void)testHandleLaunchItemAddWhenNotLogging {
+  // Launch items are evaluated even when BTM events are not logged.
+  es_file_t file = MakeESFile("foo");
+  es_process_t proc = MakeESProcess(&file);
+  es_btm_launch_item_t item = {.item_type = ES_BTM_ITEM_TYPE_AGENT};
+  es_event_btm_launch_item_add_t btm = {.item = &item};
+  es_message_t esMsg =
+      MakeESMessage(ES_EVENT_TYPE_NOTIFY_BTM_LAUNCH_ITEM_ADD, &proc, ActionType::Notify);
+  esMsg.event.btm_launch_item_add = &btm;
+
+  auto mockESApi = std::make_shared<MockEndpointSecurityAPI>();
+  mockESApi->SetExpectationsESNewClient();
+  mockESApi->SetExpectationsRetainReleaseMessage();
+
+  auto mockEnricher = std::make_shared<santa::MockEnricher>();
+  auto mockAuthCache = std::make_shared<MockAuthResultCache>(nullptr, nil);
+  auto mockLogger = std::make_shared<MockLogger>();
+  mockLogger->SetTelemetryMask(TelemetryEvent::kNone);
+  auto prefixTree = std::make_shared<PrefixTree<Unit>>();
+
+  EXPECT_CALL(*mockEnricher, Enrich).Times(0);
+  EXPECT_CALL(*mockLogger, Log).Times(0);
+
+  id mockLaunchItemMonitor = OCMStrictClassMock([SNTLaunchItemMonitor class]);
+  OCMExpect([mockLaunchItemMonitor handleLaunchItemAdd:&btm]);
+
+  id mockCC = OCMStrictClassMock([SNTCompilerController class]);
+  Message msg(mockESApi, &esMsg);
+  OCMExpect([mockCC handleEvent:msg withLogger:nullptr]).ignoringNonObjectArgs();
+
+  SNTEndpointSecurityRecorder* recorderClient =
+      [[SNTEndpointSecurityRecorder alloc] initWithESAPI:mockESApi
+                                                 metrics:nullptr
+                                                  logger:mockLogger
+                                                enricher:mockEnricher
+                                      compilerController:mockCC
+                               loginWindowSessionHandler:nil
+                                         authResultCache:mockAuthCache
+                                              prefixTree:prefixTree
+                                             processTree:nullptr];
+  recorderClient.launchItemMonitor = mockLaunchItemMonitor;
+
+  [recorderClient handleMessage:Message(mockESApi, &esMsg)
+             recordEventMetrics:^(EventDisposition d) {
+               XCTAssertEqual(d, EventDisposition::kDropped);
+             }];
+
+  XCTAssertTrue(OCMVerifyAll(mockLaunchItemMonitor));
+  XCTAssertTrue(OCMVerifyAll(mockCC));
+  XCTBubbleMockVerifyAndClearExpectations(mockEnricher.get());
+  XCTBubbleMockVerifyAndClearExpectations(mockLogger.get());
+  XCTBubbleMockVerifyAndClearExpectations(mockESApi.get());
+
+  [mockCC stopMocking];
+  [mockLaunchItemMonitor stopMocking];
+}
+
 @end
@@ -0,0 +1,60 @@
+/// Copyright 2026 North Pole Security, Inc.
+///
+/// Licensed under the Apache License, Version 2.0 (the "License");
+/// you may not use this file except in compliance with the License.
+/// You may obtain a copy of the License at
+///
+///     http://www.apache.org/licenses/LICENSE-2.0
+///
+/// Unless required by applicable law or agreed to in writing, software
+/// distributed under the License is distributed on an "AS IS" BASIS,
+/// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+/// See the License for the specific language governing permissions and
+/// limitations under the License.
+
+#ifndef SANTA_SANTAD_INTERPRETERSCRIPT_H
+#define SANTA_SANTAD_INTERPRETERSCRIPT_H
+
+#include <EndpointSecurity/EndpointSecurity.h>
+
+#include <optional>
+#include <string>
+#include <string_view>
+#include <vector>
+
+namespace santa {
+
+// The script a known interpreter was asked to run, as given on its command line.
+// Exactly one of `source` and `path` is set.
+struct InterpreterScript {
+  // The name of the interpreter, e.g. "osascript".
+  std::string interpreter;
+  // Source passed inline, e.g. the statements of `osascript -e`, joined by newlines.
+  std::optional<std::string> source;
+  // Absolute path of the script file.
+  std::optional<std::string> path;
+};
+
+// Returns the name of the interpreter if `proc` is a platform binary known to
+// run arbitrary scripts passed as arguments, std::nullopt otherwise.
+std::optional<std::string> KnownInterpreter(const es_process_t* proc);
+
+// Returns true if `proc` is a platform binary that commonly runs shebang
+// scripts, e.g. a shell, python3 or env(1).
+bool IsShebangInterpreter(const es_process_t* proc);
+
+// Parses the arguments (including argv[0]) of an exec of `interpreter` to find
+// the script it will run. Relative script paths are resolved against `cwd`.
+// Returns std::nullopt if the interpreter is unknown, the script is read from
+// standard input, the arguments can't be parsed or a relative script path can't
+// be resolved because `cwd` is empty.
+std::optional<InterpreterScript> ParseInterpreterScript(std::string_view interpreter,
+                                                        const std::vector<std::string>& args,
+                                                        std::string_view cwd);
+
+// Returns the lowercase hex SHA-256 of `source`.
+std::string InterpreterScriptSourceSHA256(std::string_view source);
+
+}  // namespace santa
+
+#endif  // SANTA_SANTAD_INTERPRETERSCRIPT_H
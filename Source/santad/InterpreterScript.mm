@@ -0,0 +1,134 @@
+/// Copyright 2026 North Pole Security, Inc.
+///
+/// Licensed under the Apache License, Version 2.0 (the "License");
+/// you may not use this file except in compliance with the License.
+/// You may obtain a copy of the License at
+///
+///     http://www.apache.org/licenses/LICENSE-2.0
+///
+/// Unless required by applicable law or agreed to in writing, software
+/// distributed under the License is distributed on an "AS IS" BASIS,
+/// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+/// See the License for the specific language governing permissions and
+/// limitations under the License.
+
+#include "Source/santad/InterpreterScript.h"
+
+#include <CommonCrypto/CommonDigest.h>
+
+#include <utility>
+
+#include "Source/common/String.h"
+
+namespace santa {
+
+namespace {
+
+// Known interpreters, keyed by the signing ID of the platform binary.
+constexpr std::pair<std::string_view, std::string_view> kKnownInterpreters[] = {
+    {"com.apple.osascript", "osascript"},
+};
+
+// Signing IDs of platform binaries that are commonly named on a script's shebang line,
+// including env(1) for "#!/usr/bin/env <interpreter>".
+constexpr std::string_view kShebangInterpreters[] = {
+    "com.apple.bash", "com.apple.csh",  "com.apple.dash",    "com.apple.env",
+    "com.apple.ksh",  "com.apple.perl", "com.apple.python3", "com.apple.ruby",
+    "com.apple.sh",   "com.apple.tcsh", "com.apple.zsh",
+};
+
+// osascript [-l language] [-i] [-s flags] [-e statement | programfile] [argument ...]
+// Options are parsed the way getopt(3) does: they may be grouped (e.g. `-il`),
+// an option argument may be attached (e.g. `-lJavaScript`) and parsing stops at
+// the first operand or at `--`.
+std::optional<InterpreterScript> ParseOsascript(const std::vector<std::string>& args,
+                                                std::string_view cwd) {
+  std::optional<std::string> source;
+  size_t i = 1;
+  for (; i < args.size(); i++) {
+    const std::string& arg = args[i];
+    if (arg == "--") {
+      i++;
+      break;
+    }
+    if (arg.size() < 2 || arg[0] != '-') break;
+
+    for (size_t j = 1; j < arg.size(); j++) {
+      char opt = arg[j];
+      if (opt == 'i') continue;
+      if (opt != 'e' && opt != 'l' && opt != 's') return std::nullopt;
+
+      std::string value;
+      if (j + 1 < arg.size()) {
+        value = arg.substr(j + 1);
+      } else if (++i < args.size()) {
+        value = args[i];
+      } else {
+        return std::nullopt;
+      }
+
+      if (opt == 'e') {
+        source = source ? *source + "\n" + value : value;
+      }
+      break;
+    }
+  }
+
+  // With -e the remaining operands are arguments to the script.
+  if (source) return InterpreterScript{.interpreter = "osascript", .source = std::move(source)};
+
+  // No program file, or "-", means the script is read from standard input.
+  if (i >= args.size() || args[i] == "-") return std::nullopt;
+
+  std::string path = args[i];
+  if (path[0] != '/') {
+    if (cwd.empty()) return std::nullopt;
+    path = std::string(cwd) + "/" + path;
+  }
+  return InterpreterScript{.interpreter = "osascript", .path = std::move(path)};
+}
+
+}  // namespace
+
+std::optional<std::string> KnownInterpreter(const es_process_t* proc) {
+  if (!proc->is_platform_binary) return std::nullopt;
+
+  std::string_view signingID = StringTokenToStringView(proc->signing_id);
+  for (const auto& [knownSigningID, name] : kKnownInterpreters) {
+    if (signingID == knownSigningID) return std::string(name);
+  }
+  return std::nullopt;
+}
+
+bool IsShebangInterpreter(const es_process_t* proc) {
+  if (!proc->is_platform_binary) return false;
+
+  std::string_view signingID = StringTokenToStringView(proc->signing_id);
+  for (std::string_view known : kShebangInterpreters) {
+    if (signingID == known) return true;
+  }
+  return false;
+}
+
+std::optional<InterpreterScript> ParseInterpreterScript(std::string_view interpreter,
+                                                        const std::vector<std::string>& args,
+                                                        std::string_view cwd) {
+  if (interpreter == "osascript") return ParseOsascript(args, cwd);
+  return std::nullopt;
+}
+
+std::string InterpreterScriptSourceSHA256(std::string_view source) {
+  unsigned char digest[CC_SHA256_DIGEST_LENGTH];
+  CC_SHA256(source.data(), (CC_LONG)source.size(), digest);
+
+  static constexpr char kHex[] = "0123456789abcdef";
+  std::string hex;
+  hex.reserve(CC_SHA256_DIGEST_LENGTH * 2);
+  for (unsigned char c : digest) {
+    hex.push_back(kHex[c >> 4]);
+    hex.push_back(kHex[c & 0xf]);
+  }
+  return hex;
+}
+
+}  // namespace santa
@@ -0,0 +1,127 @@
+/// Copyright 2026 North Pole Security, Inc.
+///
+/// Licensed under the Apache License, Version 2.0 (the "License");
+/// you may not use this file except in compliance with the License.
+/// You may obtain a copy of the License at
+///
+///     http://www.apache.org/licenses/LICENSE-2.0
+///
+/// Unless required by applicable law or agreed to in writing, software
+/// distributed under the License is distributed on an "AS IS" BASIS,
+/// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+/// See the License for the specific language governing permissions and
+/// limitations under the License.
+
+#include "Source/santad/InterpreterScript.h"
+
+#import <XCTest/XCTest.h>
+
+#include <string>
+#include <vector>
+
+#include "Source/common/TestUtils.h"
+
+using santa::InterpreterScript;
+using santa::IsShebangInterpreter;
+using santa::KnownInterpreter;
+using santa::ParseInterpreterScript;
+
+static std::optional<InterpreterScript> ParseOsascript(std::vector<std::string> args) {
+  args.insert(args.begin(), "osascript");
+  return ParseInterpreterScript("osascript", args, "/Users/test");
+}
+
+@interface InterpreterScriptTest : XCTestCase
+@end
+
+@implementation InterpreterScriptTest
+
+- (void)testKnownInterpreter {
+  es_file_t file = MakeESFile("/usr/bin/osascript");
+  es_process_t proc = MakeESProcess(&file);
+  proc.signing_id = MakeESStringToken("com.apple.osascript");
+  proc.is_platform_binary = true;
+  XCTAssertEqual(KnownInterpreter(&proc), "osascript");
+
+  // Only the platform binary is trusted to be the interpreter it claims to be.
+  proc.is_platform_binary = false;
+  XCTAssertFalse(KnownInterpreter(&proc).has_value());
+
+  proc.is_platform_binary = true;
+  proc.signing_id = MakeESStringToken("com.apple.ls");
+  XCTAssertFalse(KnownInterpreter(&proc).has_value());
+}
+
+- (void)testIsShebangInterpreter {
+  es_file_t file = MakeESFile("/bin/zsh");
+  es_process_t proc = MakeESProcess(&file);
+  proc.signing_id = MakeESStringToken("com.apple.zsh");
+  proc.is_platform_binary = true;
+  XCTAssertTrue(IsShebangInterpreter(&proc));
+
+  proc.signing_id = MakeESStringToken("com.apple.env");
+  XCTAssertTrue(IsShebangInterpreter(&proc));
+
+  proc.is_platform_binary = false;
+  XCTAssertFalse(IsShebangInterpreter(&proc));
+
+  proc.is_platform_binary = true;
+  proc.signing_id = MakeESStringToken("com.apple.osascript");
+  XCTAssertFalse(IsShebangInterpreter(&proc));
+}
+
+- (void)testInlineStatements {
+  auto script = ParseOsascript({"-e", "display dialog \"hi\""});
+  XCTAssertTrue(script.has_value());
+  XCTAssertEqual(script->interpreter, "osascript");
+  XCTAssertEqual(script->source, "display dialog \"hi\"");
+  XCTAssertFalse(script->path.has_value());
+
+  // Multiple statements form a single script, later operands are its arguments.
+  script = ParseOsascript({"-l", "JavaScript", "-e", "a", "-eb", "arg1"});
+  XCTAssertTrue(script.has_value());
+  XCTAssertEqual(script->source, "a\nb");
+  XCTAssertFalse(script->path.has_value());
+
+  // Grouped options, with the statement as the following argument.
+  script = ParseOsascript({"-ie", "a"});
+  XCTAssertTrue(script.has_value());
+  XCTAssertEqual(script->source, "a");
+}
+
+- (void)testProgramFile {
+  auto script = ParseOsascript({"-s", "h", "script.scpt", "-e", "not an option"});
+  XCTAssertTrue(script.has_value());
+  XCTAssertEqual(script->path, "/Users/test/script.scpt");
+  XCTAssertFalse(script->source.has_value());
+
+  script = ParseOsascript({"--", "/tmp/-e"});
+  XCTAssertTrue(script.has_value());
+  XCTAssertEqual(script->path, "/tmp/-e");
+}
+
+- (void)testUnparseable {
+  // Standard input.
+  XCTAssertFalse(ParseOsascript({}).has_value());
+  XCTAssertFalse(ParseOsascript({"-i"}).has_value());
+  XCTAssertFalse(ParseOsascript({"-"}).has_value());
+
+  // Missing option argument and unknown options.
+  XCTAssertFalse(ParseOsascript({"-e"}).has_value());
+  XCTAssertFalse(ParseOsascript({"-x", "script.scpt"}).has_value());
+
+  // A relative path without a working directory.
+  XCTAssertFalse(ParseInterpreterScript("osascript", {"osascript", "script.scpt"}, "").has_value());
+
+  // Unknown interpreter.
+  XCTAssertFalse(ParseInterpreterScript("python3", {"python3", "-c", "1"}, "/").has_value());
+}
+
+- (void)testSourceSHA256 {
+  XCTAssertEqual(santa::InterpreterScriptSourceSHA256(""),
+                 "e3b0c44298fc1c149afbf4c8996fb92427ae41e4649b934ca495991b7852b855");
+  XCTAssertEqual(santa::InterpreterScriptSourceSHA256("abc"),
+                 "ba7816bf8f01cfea414140de5dae2223b00361a396177a9cb410ff61f20015ad");
+}
+
+@end
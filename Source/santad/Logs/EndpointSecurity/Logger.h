@@ -69,6 +69,7 @@ class Logger : public Timer<Logger> {
       TelemetryEvent telemetry_mask, SNTEventLogType log_type, SNTDecisionCache* decision_cache,
       NSString* event_log_path, NSString* spool_log_path, size_t spool_dir_size_threshold,
       size_t spool_file_size_threshold, uint64_t spool_flush_timeout_ms,
+      size_t event_log_batch_size_bytes, uint64_t event_log_flush_timeout_ms,
       uint32_t telemetry_export_seconds, uint32_t telemetry_export_timeout_seconds,
       uint32_t telemetry_export_batch_threshold_size_mb,
       uint32_t telemetry_export_max_files_per_batch);
@@ -108,6 +109,9 @@ class Logger : public Timer<Logger> {
 
   void Flush();
 
+  // Flushes and closes the underlying writer. Called on daemon shutdown.
+  void Close();
+
   void SetTelemetryMask(TelemetryEvent mask);
 
   inline bool ShouldLog(TelemetryEvent event) { return ((event & telemetry_mask_) == event); }
@@ -47,10 +47,6 @@
 
 namespace santa {
 
-// Flush the write buffer every 5 seconds
-static constexpr uint64_t kFlushBufferTimeoutMS = 10000;
-// Batch writes up to 128kb
-static constexpr size_t kBufferBatchSizeBytes = (1024 * 128);
 // Reserve an extra 4kb of buffer space to account for event overflow
 static constexpr size_t kMaxExpectedWriteSizeBytes = 4096;
 // Minimum/maximum allowable telemetry export frequency.
@@ -66,6 +62,7 @@ std::unique_ptr<Logger> Logger::Create(
     TelemetryEvent telemetry_mask, SNTEventLogType log_type, SNTDecisionCache* decision_cache,
     NSString* event_log_path, NSString* spool_log_path, size_t spool_dir_size_threshold,
     size_t spool_file_size_threshold, uint64_t spool_flush_timeout_ms,
+    size_t event_log_batch_size_bytes, uint64_t event_log_flush_timeout_ms,
     uint32_t telemetry_export_seconds, uint32_t telemetry_export_timeout_seconds,
     uint32_t telemetry_export_batch_threshold_size_mb,
     uint32_t telemetry_export_max_files_per_batch) {
@@ -75,7 +72,7 @@ std::unique_ptr<Logger> Logger::Create(
   switch (log_type) {
     case SNTEventLogTypeFilelog:
       serializer = BasicString::Create(esapi, std::move(decision_cache));
-      writer = File::Create(event_log_path, kFlushBufferTimeoutMS, kBufferBatchSizeBytes,
+      writer = File::Create(event_log_path, event_log_flush_timeout_ms, event_log_batch_size_bytes,
                             kMaxExpectedWriteSizeBytes);
       break;
     case SNTEventLogTypeSyslog:
@@ -119,7 +116,7 @@ std::unique_ptr<Logger> Logger::Create(
       break;
     case SNTEventLogTypeJSON:
       serializer = Protobuf::Create(esapi, std::move(decision_cache), true);
-      writer = File::Create(event_log_path, kFlushBufferTimeoutMS, kBufferBatchSizeBytes,
+      writer = File::Create(event_log_path, event_log_flush_timeout_ms, event_log_batch_size_bytes,
                             kMaxExpectedWriteSizeBytes);
       break;
     default: LOGE(@"Invalid log type: %ld", log_type); return nullptr;
@@ -363,6 +360,10 @@ void Logger::Flush() {
   writer_->Flush();
 }
 
+void Logger::Close() {
+  writer_->Close();
+}
+
 void Logger::UpdateMachineIDLogging() const {
   serializer_->UpdateMachineID();
 }
@@ -37,6 +37,7 @@
 #include "Source/common/AuditUtilities.h"
 #import "Source/common/SNTCachedDecision.h"
 #import "Source/common/SNTCommonEnums.h"
+#import "Source/common/SNTFileProvenance.h"
 #import "Source/common/SNTLogging.h"
 #import "Source/common/SNTStoredExecutionEvent.h"
 #import "Source/common/String.h"
@@ -96,6 +97,8 @@ std::string GetReasonString(SNTEventState event_state) {
     case SNTEventStateAllowCompilerCDHash: return "CDHASH";
     case SNTEventStateAllowCELFallback: return "CEL_FALLBACK";
     case SNTEventStateAllowPlatform: return "PLATFORM";
+    case SNTEventStateAllowRequirement: return "REQUIREMENT";
+    case SNTEventStateAllowOnce: return "ALLOW_ONCE";
     case SNTEventStateAllowUnknown: return "UNKNOWN";
     case SNTEventStateBlockBinary: return "BINARY";
     case SNTEventStateBlockCertificate: return "CERT";
@@ -104,7 +107,11 @@ std::string GetReasonString(SNTEventState event_state) {
     case SNTEventStateBlockSigningID: return "SIGNINGID";
     case SNTEventStateBlockCDHash: return "CDHASH";
     case SNTEventStateBlockCELFallback: return "CEL_FALLBACK";
+    case SNTEventStateBlockRequirement: return "REQUIREMENT";
     case SNTEventStateBlockLongPath: return "LONG_PATH";
+    case SNTEventStateBlockNetworkVolume: return "NETWORK_VOLUME";
+    case SNTEventStateBlockDiskImage: return "DISK_IMAGE";
+    case SNTEventStateBlockPathChurn: return "PATH_CHURN";
     case SNTEventStateBlockUnknown: return "UNKNOWN";
     case SNTEventStateUnknown: return "UNKNOWN";
     case SNTEventStateAllow: return "UNKNOWN";
@@ -342,6 +349,15 @@ std::vector<uint8_t> BasicString::SerializeMessage(const EnrichedExec& msg, SNTC
     str.append("|audit=true");
   }
 
+  if (cd.onDiskImage) {
+    str.append("|on_dmg=true");
+  }
+
+  if (cd.pathChurnCount) {
+    str.append("|path_churn=");
+    str.append(std::to_string(cd.pathChurnCount));
+  }
+
   if (cd.decisionExtra) {
     str.append("|explain=");
     str.append([cd.decisionExtra UTF8String]);
@@ -352,6 +368,16 @@ std::vector<uint8_t> BasicString::SerializeMessage(const EnrichedExec& msg, SNTC
     str.append([cd.sha256 UTF8String]);
   }
 
+  if (cd.scriptSHA256.length) {
+    str.append("|script_sha256=");
+    str.append([cd.scriptSHA256 UTF8String]);
+  }
+
+  if (cd.scriptInterpreter.length) {
+    str.append("|script_interpreter=");
+    str.append([cd.scriptInterpreter UTF8String]);
+  }
+
   if (cd.certSHA256) {
     str.append("|cert_sha256=");
     str.append([cd.certSHA256 UTF8String]);
@@ -364,6 +390,11 @@ std::vector<uint8_t> BasicString::SerializeMessage(const EnrichedExec& msg, SNTC
     str.append([NonNull(cd.teamID) UTF8String]);
   }
 
+  if (cd.signingID.length) {
+    str.append("|signingid=");
+    str.append([NonNull(cd.signingID) UTF8String]);
+  }
+
   if (cd.quarantineURL) {
     str.append("|quarantine_url=");
     str.append(SanitizableString(cd.quarantineURL).Sanitized());
@@ -390,6 +421,30 @@ std::vector<uint8_t> BasicString::SerializeMessage(const EnrichedExec& msg, SNTC
     str.append(SanitizableString(origPath).Sanitized());
   }
 
+  // The resolved path usually equals the original path of a translocated app,
+  // only log it when it adds information.
+  if (cd.resolvedPath && ![cd.resolvedPath isEqualToString:origPath]) {
+    str.append("|resolvedpath=");
+    str.append(SanitizableString(cd.resolvedPath).Sanitized());
+  }
+
+  if (cd.provenance) {
+    str.append("|writer_path=");
+    str.append(SanitizableString(cd.provenance.writerPath).Sanitized());
+    str.append("|writer_pid=");
+    str.append(std::to_string(cd.provenance.writerPID.intValue));
+    if (cd.provenance.writerSigningID.length) {
+      str.append("|writer_signingid=");
+      str.append([cd.provenance.writerSigningID UTF8String]);
+    }
+    if (cd.provenance.writerTeamID.length) {
+      str.append("|writer_teamid=");
+      str.append([cd.provenance.writerTeamID UTF8String]);
+    }
+    str.append("|write_time=");
+    str.append([[GetDateFormatter() stringFromDate:cd.provenance.writeTime] UTF8String]);
+  }
+
   uint32_t argCount = esapi_->ExecArgCount(&msg->event.exec);
   if (argCount > 0) {
     str.append("|args=");
@@ -28,6 +28,7 @@
 #import "Source/common/SNTCachedDecision.h"
 #import "Source/common/SNTCommonEnums.h"
 #import "Source/common/SNTConfigurator.h"
+#import "Source/common/SNTFileProvenance.h"
 #import "Source/common/SNTStoredExecutionEvent.h"
 #include "Source/common/TestUtils.h"
 #include "Source/common/es/EnrichedTypes.h"
@@ -179,6 +180,166 @@ std::string BasicStringSerializeMessage(es_message_t* esMsg) {
   XCTAssertCppStringEqual(got, want);
 }
 
+- (void)testSerializeMessageExecWithResolvedPath {
+  es_file_t procFile = MakeESFile("foo");
+  es_process_t proc = MakeESProcess(&procFile, MakeAuditToken(12, 34), MakeAuditToken(56, 78));
+
+  es_file_t execFile = MakeESFile("/usr/local/bin/tool");
+  es_process_t procExec = MakeESProcess(&execFile, MakeAuditToken(12, 89), MakeAuditToken(56, 78));
+
+  es_message_t esMsg = MakeESMessage(ES_EVENT_TYPE_NOTIFY_EXEC, &proc);
+  esMsg.event.exec.target = &procExec;
+
+  auto mockESApi = std::make_shared<MockEndpointSecurityAPI>();
+  EXPECT_CALL(*mockESApi, ExecArgCount).WillOnce(testing::Return(0));
+
+  self.testCachedDecision.resolvedPath = @"/opt/tool/bin/tool";
+
+  std::string got = BasicStringSerializeMessage(mockESApi, &esMsg, self.mockDecisionCache);
+  std::string want =
+      "action=EXEC|decision=ALLOW|reason=BINARY|explain=extra!|sha256=1234_hash|"
+      "cert_sha256=5678_hash|cert_cn=|quarantine_url=google.com|pid=12|pidversion="
+      "89|ppid=56|uid=-2|user=nobody|gid=-1|group=nogroup|mode=L|path=/usr/local/bin/tool|"
+      "resolvedpath=/opt/tool/bin/tool|machineid=my_id\n";
+
+  XCTAssertCppStringEqual(got, want);
+}
+
+- (void)testSerializeMessageExecInterpreterScript {
+  es_file_t procFile = MakeESFile("foo");
+  es_process_t proc = MakeESProcess(&procFile, MakeAuditToken(12, 34), MakeAuditToken(56, 78));
+
+  es_file_t execFile = MakeESFile("/usr/bin/osascript");
+  es_process_t procExec = MakeESProcess(&execFile, MakeAuditToken(12, 89), MakeAuditToken(56, 78));
+
+  es_message_t esMsg = MakeESMessage(ES_EVENT_TYPE_NOTIFY_EXEC, &proc);
+  esMsg.event.exec.target = &procExec;
+
+  auto mockESApi = std::make_shared<MockEndpointSecurityAPI>();
+  EXPECT_CALL(*mockESApi, ExecArgCount).WillOnce(testing::Return(0));
+
+  self.testCachedDecision.scriptSHA256 = @"script_hash";
+  self.testCachedDecision.scriptInterpreter = @"osascript";
+
+  std::string got = BasicStringSerializeMessage(mockESApi, &esMsg, self.mockDecisionCache);
+  std::string want =
+      "action=EXEC|decision=ALLOW|reason=BINARY|explain=extra!|sha256=1234_hash|"
+      "script_sha256=script_hash|script_interpreter=osascript|"
+      "cert_sha256=5678_hash|cert_cn=|quarantine_url=google.com|pid=12|pidversion="
+      "89|ppid=56|uid=-2|user=nobody|gid=-1|group=nogroup|mode=L|path=/usr/bin/osascript|"
+      "machineid=my_id\n";
+
+  XCTAssertCppStringEqual(got, want);
+}
+
+- (void)testSerializeMessageExecOnDiskImage {
+  es_file_t procFile = MakeESFile("foo");
+  es_process_t proc = MakeESProcess(&procFile, MakeAuditToken(12, 34), MakeAuditToken(56, 78));
+
+  es_file_t execFile = MakeESFile("/Volumes/Installer/tool");
+  es_process_t procExec = MakeESProcess(&execFile, MakeAuditToken(12, 89), MakeAuditToken(56, 78));
+
+  es_message_t esMsg = MakeESMessage(ES_EVENT_TYPE_NOTIFY_EXEC, &proc);
+  esMsg.event.exec.target = &procExec;
+
+  auto mockESApi = std::make_shared<MockEndpointSecurityAPI>();
+  EXPECT_CALL(*mockESApi, ExecArgCount).WillOnce(testing::Return(0));
+
+  self.testCachedDecision.onDiskImage = YES;
+
+  std::string got = BasicStringSerializeMessage(mockESApi, &esMsg, self.mockDecisionCache);
+  std::string want =
+      "action=EXEC|decision=ALLOW|reason=BINARY|on_dmg=true|explain=extra!|sha256=1234_hash|"
+      "cert_sha256=5678_hash|cert_cn=|quarantine_url=google.com|pid=12|pidversion="
+      "89|ppid=56|uid=-2|user=nobody|gid=-1|group=nogroup|mode=L|path=/Volumes/Installer/tool|"
+      "machineid=my_id\n";
+
+  XCTAssertCppStringEqual(got, want);
+}
+
+- (void)testSerializeMessageExecPathChurn {
+  es_file_t procFile = MakeESFile("foo");
+  es_process_t proc = MakeESProcess(&procFile, MakeAuditToken(12, 34), MakeAuditToken(56, 78));
+
+  es_file_t execFile = MakeESFile("/tmp/tool");
+  es_process_t procExec = MakeESProcess(&execFile, MakeAuditToken(12, 89), MakeAuditToken(56, 78));
+
+  es_message_t esMsg = MakeESMessage(ES_EVENT_TYPE_NOTIFY_EXEC, &proc);
+  esMsg.event.exec.target = &procExec;
+
+  auto mockESApi = std::make_shared<MockEndpointSecurityAPI>();
+  EXPECT_CALL(*mockESApi, ExecArgCount).WillOnce(testing::Return(0));
+
+  self.testCachedDecision.pathChurnCount = 7;
+
+  std::string got = BasicStringSerializeMessage(mockESApi, &esMsg, self.mockDecisionCache);
+  std::string want =
+      "action=EXEC|decision=ALLOW|reason=BINARY|path_churn=7|explain=extra!|sha256=1234_hash|"
+      "cert_sha256=5678_hash|cert_cn=|quarantine_url=google.com|pid=12|pidversion="
+      "89|ppid=56|uid=-2|user=nobody|gid=-1|group=nogroup|mode=L|path=/tmp/tool|"
+      "machineid=my_id\n";
+
+  XCTAssertCppStringEqual(got, want);
+}
+
+- (void)testSerializeMessageExecProvenance {
+  es_file_t procFile = MakeESFile("foo");
+  es_process_t proc = MakeESProcess(&procFile, MakeAuditToken(12, 34), MakeAuditToken(56, 78));
+
+  es_file_t execFile = MakeESFile("/Users/Shared/tool");
+  es_process_t procExec = MakeESProcess(&execFile, MakeAuditToken(12, 89), MakeAuditToken(56, 78));
+
+  es_message_t esMsg = MakeESMessage(ES_EVENT_TYPE_NOTIFY_EXEC, &proc);
+  esMsg.event.exec.target = &procExec;
+
+  auto mockESApi = std::make_shared<MockEndpointSecurityAPI>();
+  EXPECT_CALL(*mockESApi, ExecArgCount).WillOnce(testing::Return(0));
+
+  SNTFileProvenance* provenance = [[SNTFileProvenance alloc] init];
+  provenance.writerPath = @"/usr/bin/curl";
+  provenance.writerPID = @(321);
+  provenance.writerSigningID = @"com.apple.curl";
+  provenance.ruleName = @"downloads";
+  provenance.writeTime = [NSDate dateWithTimeIntervalSince1970:1760400000];
+  self.testCachedDecision.provenance = provenance;
+
+  std::string got = BasicStringSerializeMessage(mockESApi, &esMsg, self.mockDecisionCache);
+  std::string want =
+      "action=EXEC|decision=ALLOW|reason=BINARY|explain=extra!|sha256=1234_hash|"
+      "cert_sha256=5678_hash|cert_cn=|quarantine_url=google.com|pid=12|pidversion="
+      "89|ppid=56|uid=-2|user=nobody|gid=-1|group=nogroup|mode=L|path=/Users/Shared/tool|"
+      "writer_path=/usr/bin/curl|writer_pid=321|writer_signingid=com.apple.curl|"
+      "write_time=2025-10-14T00:00:00.000Z|machineid=my_id\n";
+
+  XCTAssertCppStringEqual(got, want);
+}
+
+- (void)testSerializeMessageExecWithSigningID {
+  es_file_t procFile = MakeESFile("foo");
+  es_process_t proc = MakeESProcess(&procFile, MakeAuditToken(12, 34), MakeAuditToken(56, 78));
+
+  es_file_t execFile = MakeESFile("/usr/local/bin/tool");
+  es_process_t procExec = MakeESProcess(&execFile, MakeAuditToken(12, 89), MakeAuditToken(56, 78));
+
+  es_message_t esMsg = MakeESMessage(ES_EVENT_TYPE_NOTIFY_EXEC, &proc);
+  esMsg.event.exec.target = &procExec;
+
+  auto mockESApi = std::make_shared<MockEndpointSecurityAPI>();
+  EXPECT_CALL(*mockESApi, ExecArgCount).WillOnce(testing::Return(0));
+
+  self.testCachedDecision.teamID = @"ABCDEF1234";
+  self.testCachedDecision.signingID = @"ABCDEF1234:com.example.tool";
+
+  std::string got = BasicStringSerializeMessage(mockESApi, &esMsg, self.mockDecisionCache);
+  std::string want =
+      "action=EXEC|decision=ALLOW|reason=BINARY|explain=extra!|sha256=1234_hash|"
+      "cert_sha256=5678_hash|cert_cn=|teamid=ABCDEF1234|signingid=ABCDEF1234:com.example.tool|"
+      "quarantine_url=google.com|pid=12|pidversion=89|ppid=56|uid=-2|user=nobody|gid=-1|"
+      "group=nogroup|mode=L|path=/usr/local/bin/tool|machineid=my_id\n";
+
+  XCTAssertCppStringEqual(got, want);
+}
+
 - (void)testSerializeMessageExit {
   es_file_t procFile = MakeESFile("foo");
   es_process_t proc = MakeESProcess(&procFile, MakeAuditToken(12, 34), MakeAuditToken(56, 78));
@@ -1439,6 +1600,10 @@ std::string BasicStringSerializeMessage(es_message_t* esMsg) {
       {SNTEventStateBlockTeamID, "DENY"},
       {SNTEventStateBlockLongPath, "DENY"},
       {SNTEventStateBlockCELFallback, "DENY"},
+      {SNTEventStateBlockRequirement, "DENY"},
+      {SNTEventStateBlockNetworkVolume, "DENY"},
+      {SNTEventStateBlockDiskImage, "DENY"},
+      {SNTEventStateBlockPathChurn, "DENY"},
       {SNTEventStateAllowUnknown, "ALLOW"},
       {SNTEventStateAllowBinary, "ALLOW"},
       {SNTEventStateAllowCertificate, "ALLOW"},
@@ -1451,6 +1616,8 @@ std::string BasicStringSerializeMessage(es_message_t* esMsg) {
       {SNTEventStateAllowTeamID, "ALLOW"},
       {SNTEventStateAllowCELFallback, "ALLOW"},
       {SNTEventStateAllowPlatform, "ALLOW"},
+      {SNTEventStateAllowRequirement, "ALLOW"},
+      {SNTEventStateAllowOnce, "ALLOW"},
   };
 
   for (const auto& kv : stateToDecision) {
@@ -1475,6 +1642,10 @@ std::string BasicStringSerializeMessage(es_message_t* esMsg) {
       case SNTEventStateBlockSigningID: want = "SIGNINGID"; break;
       case SNTEventStateBlockCDHash: want = "CDHASH"; break;
       case SNTEventStateBlockCELFallback: want = "CEL_FALLBACK"; break;
+      case SNTEventStateBlockRequirement: want = "REQUIREMENT"; break;
+      case SNTEventStateBlockNetworkVolume: want = "NETWORK_VOLUME"; break;
+      case SNTEventStateBlockDiskImage: want = "DISK_IMAGE"; break;
+      case SNTEventStateBlockPathChurn: want = "PATH_CHURN"; break;
       case SNTEventStateAllowUnknown: want = "UNKNOWN"; break;
       case SNTEventStateAllowBinary: want = "BINARY"; break;
       case SNTEventStateAllowCertificate: want = "CERT"; break;
@@ -1491,6 +1662,8 @@ std::string BasicStringSerializeMessage(es_message_t* esMsg) {
       case SNTEventStateAllowCompilerCDHash: want = "CDHASH"; break;
       case SNTEventStateAllowCELFallback: want = "CEL_FALLBACK"; break;
       case SNTEventStateAllowPlatform: want = "PLATFORM"; break;
+      case SNTEventStateAllowRequirement: want = "REQUIREMENT"; break;
+      case SNTEventStateAllowOnce: want = "ALLOW_ONCE"; break;
       case SNTEventStateBlock: want = "UNKNOWN"; break;
       case SNTEventStateAllow: want = "UNKNOWN"; break;
     }
@@ -33,6 +33,7 @@
 #include "Source/common/EncodeEntitlements.h"
 #import "Source/common/SNTCachedDecision.h"
 #import "Source/common/SNTCommonEnums.h"
+#import "Source/common/SNTFileProvenance.h"
 #include "Source/common/SNTLogging.h"
 #import "Source/common/SNTStoredExecutionEvent.h"
 #import "Source/common/SNTSystemInfo.h"
@@ -352,6 +353,8 @@ static inline void EncodeCertificateInfo(::pbv1::CertificateInfo* pb_cert_info,
     case SNTEventStateAllowCompilerCDHash: return ::pbv1::Execution::REASON_CDHASH;
     case SNTEventStateAllowCELFallback: return ::pbv1::Execution::REASON_CEL_FALLBACK;
     case SNTEventStateAllowPlatform: return ::pbv1::Execution::REASON_PLATFORM;
+    case SNTEventStateAllowRequirement: return ::pbv1::Execution::REASON_REQUIREMENT;
+    case SNTEventStateAllowOnce: return ::pbv1::Execution::REASON_ALLOW_ONCE;
     case SNTEventStateAllowUnknown: return ::pbv1::Execution::REASON_UNKNOWN;
     case SNTEventStateBlockBinary: return ::pbv1::Execution::REASON_BINARY;
     case SNTEventStateBlockCertificate: return ::pbv1::Execution::REASON_CERT;
@@ -360,7 +363,11 @@ static inline void EncodeCertificateInfo(::pbv1::CertificateInfo* pb_cert_info,
     case SNTEventStateBlockSigningID: return ::pbv1::Execution::REASON_SIGNING_ID;
     case SNTEventStateBlockCDHash: return ::pbv1::Execution::REASON_CDHASH;
     case SNTEventStateBlockCELFallback: return ::pbv1::Execution::REASON_CEL_FALLBACK;
+    case SNTEventStateBlockRequirement: return ::pbv1::Execution::REASON_REQUIREMENT;
     case SNTEventStateBlockLongPath: return ::pbv1::Execution::REASON_LONG_PATH;
+    case SNTEventStateBlockNetworkVolume: return ::pbv1::Execution::REASON_NETWORK_VOLUME;
+    case SNTEventStateBlockDiskImage: return ::pbv1::Execution::REASON_DISK_IMAGE;
+    case SNTEventStateBlockPathChurn: return ::pbv1::Execution::REASON_PATH_CHURN;
     case SNTEventStateBlockUnknown: return ::pbv1::Execution::REASON_UNKNOWN;
     case SNTEventStateUnknown: return ::pbv1::Execution::REASON_UNKNOWN;
     case SNTEventStateAllow: return ::pbv1::Execution::REASON_UNKNOWN;
@@ -624,6 +631,30 @@ std::vector<uint8_t> Protobuf::SerializeMessage(const EnrichedExec& msg, SNTCach
     pb_exec->set_audit_return(true);
   }
 
+  if (cd.onDiskImage) {
+    pb_exec->set_on_dmg(true);
+  }
+
+  if (cd.pathChurnCount) {
+    pb_exec->set_path_churn_count((uint32_t)cd.pathChurnCount);
+  }
+
+  if (cd.provenance) {
+    ::pbv1::FileProvenance* pb_provenance = pb_exec->mutable_provenance();
+    EncodeString([pb_provenance] { return pb_provenance->mutable_writer_path(); },
+                 cd.provenance.writerPath);
+    pb_provenance->set_writer_pid(cd.provenance.writerPID.intValue);
+    EncodeString([pb_provenance] { return pb_provenance->mutable_writer_signing_id(); },
+                 cd.provenance.writerSigningID);
+    EncodeString([pb_provenance] { return pb_provenance->mutable_writer_team_id(); },
+                 cd.provenance.writerTeamID);
+    EncodeString([pb_provenance] { return pb_provenance->mutable_writer_cdhash(); },
+                 cd.provenance.writerCDHash);
+    EncodeString([pb_provenance] { return pb_provenance->mutable_rule_name(); },
+                 cd.provenance.ruleName);
+    EncodeTimestamp(pb_provenance->mutable_write_time(), cd.provenance.writeTime);
+  }
+
   return FinalizeProto(santa_msg);
 }
 
@@ -700,6 +731,8 @@ std::vector<uint8_t> Protobuf::SerializeMessage(const EnrichedCSInvalidated& msg
 
   ::pbv1::CodesigningInvalidated* pb_cs_invalidated = santa_msg->mutable_codesigning_invalidated();
   EncodeProcessInfoLight(pb_cs_invalidated->mutable_instigator(), msg);
+  pb_cs_invalidated->set_code_signature_valid_runtime((msg->process->codesigning_flags &
+                                                       CS_VALID) != 0);
 
   return FinalizeProto(santa_msg);
 }
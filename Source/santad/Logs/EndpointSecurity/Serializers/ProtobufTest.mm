@@ -33,6 +33,7 @@
 #import "Source/common/SNTCachedDecision.h"
 #import "Source/common/SNTCommonEnums.h"
 #import "Source/common/SNTConfigurator.h"
+#import "Source/common/SNTFileProvenance.h"
 #import "Source/common/SNTStoredExecutionEvent.h"
 #include "Source/common/TestUtils.h"
 #include "Source/common/es/EnrichedTypes.h"
@@ -555,6 +556,10 @@ void SerializeAndCheckNonESEvents(
       {SNTEventStateBlockTeamID, ::pbv1::Execution::DECISION_DENY},
       {SNTEventStateBlockLongPath, ::pbv1::Execution::DECISION_DENY},
       {SNTEventStateBlockCELFallback, ::pbv1::Execution::DECISION_DENY},
+      {SNTEventStateBlockRequirement, ::pbv1::Execution::DECISION_DENY},
+      {SNTEventStateBlockNetworkVolume, ::pbv1::Execution::DECISION_DENY},
+      {SNTEventStateBlockDiskImage, ::pbv1::Execution::DECISION_DENY},
+      {SNTEventStateBlockPathChurn, ::pbv1::Execution::DECISION_DENY},
       {SNTEventStateAllowUnknown, ::pbv1::Execution::DECISION_ALLOW},
       {SNTEventStateAllowBinary, ::pbv1::Execution::DECISION_ALLOW},
       {SNTEventStateAllowCertificate, ::pbv1::Execution::DECISION_ALLOW},
@@ -567,6 +572,8 @@ void SerializeAndCheckNonESEvents(
       {SNTEventStateAllowTeamID, ::pbv1::Execution::DECISION_ALLOW},
       {SNTEventStateAllowCELFallback, ::pbv1::Execution::DECISION_ALLOW},
       {SNTEventStateAllowPlatform, ::pbv1::Execution::DECISION_ALLOW},
+      {SNTEventStateAllowRequirement, ::pbv1::Execution::DECISION_ALLOW},
+      {SNTEventStateAllowOnce, ::pbv1::Execution::DECISION_ALLOW},
   };
 
   for (const auto& kv : stateToDecision) {
@@ -591,6 +598,12 @@ void SerializeAndCheckNonESEvents(
       case SNTEventStateBlockSigningID: want = ::pbv1::Execution::REASON_SIGNING_ID; break;
       case SNTEventStateBlockCDHash: want = ::pbv1::Execution::REASON_CDHASH; break;
       case SNTEventStateBlockCELFallback: want = ::pbv1::Execution::REASON_CEL_FALLBACK; break;
+      case SNTEventStateBlockRequirement: want = ::pbv1::Execution::REASON_REQUIREMENT; break;
+      case SNTEventStateBlockNetworkVolume:
+        want = ::pbv1::Execution::REASON_NETWORK_VOLUME;
+        break;
+      case SNTEventStateBlockDiskImage: want = ::pbv1::Execution::REASON_DISK_IMAGE; break;
+      case SNTEventStateBlockPathChurn: want = ::pbv1::Execution::REASON_PATH_CHURN; break;
       case SNTEventStateAllowUnknown: want = ::pbv1::Execution::REASON_UNKNOWN; break;
       case SNTEventStateAllowBinary: want = ::pbv1::Execution::REASON_BINARY; break;
       case SNTEventStateAllowCertificate: want = ::pbv1::Execution::REASON_CERT; break;
@@ -609,6 +622,8 @@ void SerializeAndCheckNonESEvents(
       case SNTEventStateAllowCompilerCDHash: want = ::pbv1::Execution::REASON_CDHASH; break;
       case SNTEventStateAllowCELFallback: want = ::pbv1::Execution::REASON_CEL_FALLBACK; break;
       case SNTEventStateAllowPlatform: want = ::pbv1::Execution::REASON_PLATFORM; break;
+      case SNTEventStateAllowRequirement: want = ::pbv1::Execution::REASON_REQUIREMENT; break;
+      case SNTEventStateAllowOnce: want = ::pbv1::Execution::REASON_ALLOW_ONCE; break;
       case SNTEventStateBlock: want = ::pbv1::Execution::REASON_UNKNOWN; break;
       case SNTEventStateAllow: want = ::pbv1::Execution::REASON_UNKNOWN; break;
     }
@@ -2137,6 +2152,63 @@ void SerializeAndCheckNonESEvents(
   XCTAssertFalse(santaMsg2.execution().has_rule_id());
 }
 
+- (void)testSerializeExecProvenance {
+  auto mockESApi = std::make_shared<MockEndpointSecurityAPI>();
+
+  es_file_t procFile = MakeESFile("foo", MakeStat(100));
+  es_file_t procFileTarget = MakeESFile("fooexec", MakeStat(300));
+  es_process_t proc = MakeESProcess(&procFile, MakeAuditToken(12, 34), MakeAuditToken(56, 78));
+  es_process_t procTarget =
+      MakeESProcess(&procFileTarget, MakeAuditToken(23, 45), MakeAuditToken(67, 89));
+  es_message_t esMsg = MakeESMessage(ES_EVENT_TYPE_NOTIFY_EXEC, &proc);
+  esMsg.event.exec.target = &procTarget;
+  esMsg.event.exec.last_fd = 0;
+
+  mockESApi->SetExpectationsRetainReleaseMessage();
+  EXPECT_CALL(*mockESApi, ExecArgCount).WillRepeatedly(testing::Return(0));
+  EXPECT_CALL(*mockESApi, ExecEnvCount).WillRepeatedly(testing::Return(0));
+  if (esMsg.version >= 4) {
+    EXPECT_CALL(*mockESApi, ExecFDCount).WillRepeatedly(testing::Return(0));
+  }
+
+  SNTCachedDecision* cd = [[SNTCachedDecision alloc] init];
+  cd.decision = SNTEventStateAllowBinary;
+  cd.decisionClientMode = SNTClientModeLockdown;
+
+  std::shared_ptr<Serializer> serializer = Protobuf::Create(mockESApi, nil);
+  auto enrichedMsg = Enricher().Enrich(Message(mockESApi, &esMsg));
+  const auto& execMsg = std::get<santa::EnrichedExec>(enrichedMsg->GetEnrichedMessage());
+
+  // No provenance is logged if the binary wasn't written at a watched path
+  std::vector<uint8_t> vec = serializer->SerializeMessage(execMsg, cd);
+  ::pbv1::SantaMessage santaMsg;
+  XCTAssertTrue(santaMsg.ParseFromString(std::string(vec.begin(), vec.end())));
+  XCTAssertFalse(santaMsg.execution().has_provenance());
+
+  SNTFileProvenance* provenance = [[SNTFileProvenance alloc] init];
+  provenance.writerPath = @"/usr/bin/curl";
+  provenance.writerPID = @(321);
+  provenance.writerSigningID = @"com.apple.curl";
+  provenance.writerCDHash = @"abcdef";
+  provenance.ruleName = @"downloads";
+  provenance.writeTime = [NSDate dateWithTimeIntervalSince1970:1760400000];
+  cd.provenance = provenance;
+
+  vec = serializer->SerializeMessage(execMsg, cd);
+  ::pbv1::SantaMessage santaMsg2;
+  XCTAssertTrue(santaMsg2.ParseFromString(std::string(vec.begin(), vec.end())));
+  XCTAssertTrue(santaMsg2.execution().has_provenance());
+
+  const ::pbv1::FileProvenance& pbProvenance = santaMsg2.execution().provenance();
+  XCTAssertCppStringEqual(pbProvenance.writer_path(), "/usr/bin/curl");
+  XCTAssertEqual(pbProvenance.writer_pid(), 321);
+  XCTAssertCppStringEqual(pbProvenance.writer_signing_id(), "com.apple.curl");
+  XCTAssertFalse(pbProvenance.has_writer_team_id());
+  XCTAssertCppStringEqual(pbProvenance.writer_cdhash(), "abcdef");
+  XCTAssertCppStringEqual(pbProvenance.rule_name(), "downloads");
+  XCTAssertEqual(pbProvenance.write_time().seconds(), 1760400000);
+}
+
 - (void)testSerializeFileAccessRuleId {
   auto mockESApi = std::make_shared<MockEndpointSecurityAPI>();
   mockESApi->SetExpectationsRetainReleaseMessage();
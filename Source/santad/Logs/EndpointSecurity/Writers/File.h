@@ -45,12 +45,17 @@ class File : public Writer, public std::enable_shared_from_this<File> {
   void Write(std::vector<uint8_t>&& bytes) override;
   void Flush() override;
 
+  // Flushes any buffered data and closes the log file. Writes made after
+  // closing are dropped.
+  void Close() override;
+
   friend class santa::FilePeer;
 
  private:
   void OpenFileHandleSerialized();
   void WatchLogFile();
   void FlushSerialized();
+  void CloseSerialized();
   bool ShouldFlush();
 
   void EnsureCapacitySerialized(size_t additional_bytes);
@@ -69,6 +74,8 @@ class File : public Writer, public std::enable_shared_from_this<File> {
   // flushes, but that isn't very necessary. Instead we can manually track the
   // `end` of the buffer and skip clearing the data.
   size_t buffer_offset_ = 0;
+
+  bool closed_ = false;
 };
 
 }  // namespace santa
@@ -68,8 +68,14 @@ void File::WatchLogFile() {
   watch_source_ = dispatch_source_create(DISPATCH_SOURCE_TYPE_VNODE, file_handle_.fileDescriptor,
                                          DISPATCH_VNODE_DELETE | DISPATCH_VNODE_RENAME, q_);
 
-  auto shared_this = shared_from_this();
+  // Hold a weak reference so the watch source doesn't keep the writer alive
+  // and the destructor still gets a chance to flush.
+  std::weak_ptr<File> weak_this = weak_from_this();
   dispatch_source_set_event_handler(watch_source_, ^{
+    std::shared_ptr<File> shared_this = weak_this.lock();
+    if (!shared_this) {
+      return;
+    }
     [shared_this->file_handle_ closeFile];
     shared_this->OpenFileHandleSerialized();
     shared_this->WatchLogFile();
@@ -79,9 +85,9 @@ void File::WatchLogFile() {
 }
 
 File::~File() {
-  if (timer_source_) {
-    dispatch_source_cancel(timer_source_);
-  }
+  // Pending blocks on `q_` hold a strong reference, so nothing else can be
+  // touching the buffer once the destructor runs.
+  CloseSerialized();
 }
 
 // IMPORTANT: Not thread safe.
@@ -103,6 +109,10 @@ void File::Write(std::vector<uint8_t>&& bytes) {
   dispatch_async(q_, ^{
     std::vector<uint8_t> moved_bytes = std::move(temp_bytes);
 
+    if (unlikely(shared_this->closed_)) {
+      return;
+    }
+
     shared_this->CopyDataSerialized(moved_bytes);
 
     if (shared_this->ShouldFlush()) {
@@ -121,6 +131,33 @@ void File::Flush() {
   });
 }
 
+void File::Close() {
+  // Writes are enqueued asynchronously on `q_`, so closing on the same queue
+  // guarantees every write made before this call is flushed.
+  dispatch_sync(q_, ^{
+    CloseSerialized();
+  });
+}
+
+// IMPORTANT: Not thread safe.
+void File::CloseSerialized() {
+  if (closed_) {
+    return;
+  }
+  closed_ = true;
+
+  if (timer_source_) {
+    dispatch_source_cancel(timer_source_);
+  }
+  if (watch_source_) {
+    dispatch_source_cancel(watch_source_);
+  }
+
+  FlushSerialized();
+  [file_handle_ closeFile];
+  file_handle_ = nil;
+}
+
 // IMPORTANT: Not thread safe.
 void File::EnsureCapacitySerialized(size_t additional_bytes) {
   if ((buffer_offset_ + additional_bytes) > buffer_.capacity()) {
@@ -137,7 +174,7 @@ void File::CopyDataSerialized(const std::vector<uint8_t>& bytes) {
 
 // IMPORTANT: Not thread safe.
 void File::FlushSerialized() {
-  if (likely(buffer_offset_ > 0)) {
+  if (likely(buffer_offset_ > 0) && likely(file_handle_)) {
     write(file_handle_.fileDescriptor, buffer_.data(), buffer_offset_);
 
     // After flushing, reset the offset back to 0
@@ -200,6 +200,74 @@ bool WaitForBufferSize(std::shared_ptr<FilePeer> file, size_t expectedSize) {
   XCTAssertEqual(0, file->InternalBufferSize());
 }
 
+- (void)testCloseFlushesBuffer {
+  size_t bufferSize = 100;
+  auto file =
+      std::make_shared<FilePeer>(self.logPath, bufferSize, bufferSize * 2, self.q, self.timer);
+
+  // A write smaller than the batch size stays buffered
+  file->Write(std::vector<uint8_t>(50, 'A'));
+  XCTAssertTrue(WaitForBufferSize(file, 50));
+
+  struct stat gotSB;
+  XCTAssertEqual(stat([self.logPath UTF8String], &gotSB), 0);
+  XCTAssertEqual(0, gotSB.st_size);
+
+  // Closing writes out the buffered data, including writes that are still
+  // queued when Close is called
+  file->Write(std::vector<uint8_t>(10, 'B'));
+  file->Close();
+
+  XCTAssertEqual(stat([self.logPath UTF8String], &gotSB), 0);
+  XCTAssertEqual(60, gotSB.st_size);
+  XCTAssertEqual(0, file->InternalBufferSize());
+  XCTAssertNil(file->FileHandle());
+
+  // Writes after closing are dropped and closing again is a no-op
+  file->Write(std::vector<uint8_t>(50, 'C'));
+  file->Close();
+  XCTAssertEqual(stat([self.logPath UTF8String], &gotSB), 0);
+  XCTAssertEqual(60, gotSB.st_size);
+}
+
+- (void)testDestructorFlushesBuffer {
+  auto file = std::make_shared<FilePeer>(self.logPath, 100, 200, self.q, self.timer);
+  file->WatchLogFile();
+
+  file->Write(std::vector<uint8_t>(50, 'A'));
+  XCTAssertTrue(WaitForBufferSize(file, 50));
+
+  // The log file watcher must not keep the writer alive, so releasing the
+  // last reference flushes the buffer
+  file.reset();
+
+  XCTAssertTrue(WaitFor(^bool() {
+    struct stat sb;
+    return stat([self.logPath UTF8String], &sb) == 0 && sb.st_size == 50;
+  }));
+}
+
+- (void)testFlushOnInterval {
+  // A large batch size so that only the timer triggers a flush
+  auto file = santa::File::Create(self.logPath, 50, 4096, 1024);
+
+  file->Write(std::vector<uint8_t>(10, 'A'));
+
+  XCTAssertTrue(WaitFor(^bool() {
+    struct stat sb;
+    return stat([self.logPath UTF8String], &sb) == 0 && sb.st_size == 10;
+  }));
+
+  file->Write(std::vector<uint8_t>(20, 'B'));
+
+  XCTAssertTrue(WaitFor(^bool() {
+    struct stat sb;
+    return stat([self.logPath UTF8String], &sb) == 0 && sb.st_size == 30;
+  }));
+
+  file->Close();
+}
+
 - (void)testEnsureCapacity {
   const size_t batchSize = 100;
   auto file =
@@ -32,6 +32,11 @@ class Writer {
   virtual void Write(std::vector<uint8_t>&& bytes) = 0;
   virtual void Flush() = 0;
 
+  // Called when the daemon is shutting down. Any buffered data must be written
+  // out before this returns. Writers that don't hold resources beyond their
+  // buffer only need to flush.
+  virtual void Close() { Flush(); }
+
   virtual std::optional<absl::flat_hash_set<std::string>> GetFilesToExport(
       size_t max_count) {
     return std::nullopt;
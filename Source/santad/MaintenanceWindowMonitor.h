@@ -0,0 +1,69 @@
+/// Copyright 2026 North Pole Security, Inc.
+///
+/// Licensed under the Apache License, Version 2.0 (the "License");
+/// you may not use this file except in compliance with the License.
+/// You may obtain a copy of the License at
+///
+///     http://www.apache.org/licenses/LICENSE-2.0
+///
+/// Unless required by applicable law or agreed to in writing, software
+/// distributed under the License is distributed on an "AS IS" BASIS,
+/// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+/// See the License for the specific language governing permissions and
+/// limitations under the License.
+
+#ifndef SANTA_SANTAD_MAINTENANCEWINDOWMONITOR_H
+#define SANTA_SANTAD_MAINTENANCEWINDOWMONITOR_H
+
+#import <Foundation/Foundation.h>
+
+#include <memory>
+
+#include "Source/common/PassKey.h"
+#import "Source/common/SNTCommonEnums.h"
+#import "Source/common/SNTConfigurator.h"
+#include "Source/common/Timer.h"
+
+namespace santa {
+
+// Applies client modes received from the sync server, holding back a switch
+// from Monitor to an enforcing mode while a configured maintenance window is
+// open. The deferred mode is persisted in the sync state and applied by the
+// timer once no window contains the current time.
+class MaintenanceWindowMonitor : public Timer<MaintenanceWindowMonitor>,
+                                 public PassKey<MaintenanceWindowMonitor> {
+ public:
+  using NowBlock = NSDate* (^)(void);
+
+  // Factory
+  static std::shared_ptr<MaintenanceWindowMonitor> Create(SNTConfigurator* configurator);
+
+  // Construction requires a PassKey, can only be used internally / by tests.
+  MaintenanceWindowMonitor(PassKey, SNTConfigurator* configurator, NSCalendar* calendar,
+                           NowBlock now);
+
+  // Timer<> callback. Always re-arms.
+  bool OnTimer();
+
+  // Apply a client mode received from the sync server, or defer it if it would
+  // leave Monitor mode during a maintenance window. Must be called inside a
+  // sync state batch. Returns true if the mode was deferred.
+  bool ClientModeReceived(SNTClientMode mode);
+
+  // Apply the deferred client mode if there is one and no maintenance window
+  // is open. Returns true if a mode was applied.
+  bool ApplyDeferredClientMode();
+
+  friend class MaintenanceWindowMonitorPeer;
+
+ private:
+  bool InMaintenanceWindow();
+
+  SNTConfigurator* configurator_;
+  NSCalendar* calendar_;
+  NowBlock now_;
+};
+
+}  // namespace santa
+
+#endif  // SANTA_SANTAD_MAINTENANCEWINDOWMONITOR_H
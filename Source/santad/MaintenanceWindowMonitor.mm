@@ -0,0 +1,108 @@
+/// Copyright 2026 North Pole Security, Inc.
+///
+/// Licensed under the Apache License, Version 2.0 (the "License");
+/// you may not use this file except in compliance with the License.
+/// You may obtain a copy of the License at
+///
+///     http://www.apache.org/licenses/LICENSE-2.0
+///
+/// Unless required by applicable law or agreed to in writing, software
+/// distributed under the License is distributed on an "AS IS" BASIS,
+/// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+/// See the License for the specific language governing permissions and
+/// limitations under the License.
+
+#include "Source/santad/MaintenanceWindowMonitor.h"
+
+#import "Source/common/SNTLogging.h"
+#import "Source/common/SNTMaintenanceWindow.h"
+
+namespace santa {
+
+static constexpr uint32_t kMaintenanceWindowMonitorIntervalSec = 60;
+
+static NSString* ClientModeName(SNTClientMode mode) {
+  switch (mode) {
+    case SNTClientModeMonitor: return @"Monitor";
+    case SNTClientModeLockdown: return @"Lockdown";
+    case SNTClientModeStandalone: return @"Standalone";
+    default: return @"Unknown";
+  }
+}
+
+std::shared_ptr<MaintenanceWindowMonitor> MaintenanceWindowMonitor::Create(
+    SNTConfigurator* configurator) {
+  // Windows are configured in local time, so follow time zone changes.
+  auto monitor = std::make_shared<MaintenanceWindowMonitor>(
+      PassKey(), configurator, [NSCalendar autoupdatingCurrentCalendar], ^NSDate* {
+        return [NSDate date];
+      });
+
+  monitor->StartTimer();
+
+  return monitor;
+}
+
+MaintenanceWindowMonitor::MaintenanceWindowMonitor(PassKey, SNTConfigurator* configurator,
+                                                   NSCalendar* calendar, NowBlock now)
+    : Timer(kMaintenanceWindowMonitorIntervalSec, kMaintenanceWindowMonitorIntervalSec,
+            Timer::OnStart::kFireImmediately, "MaintenanceWindowMonitor"),
+      configurator_(configurator),
+      calendar_(calendar),
+      now_([now copy]) {}
+
+bool MaintenanceWindowMonitor::OnTimer() {
+  ApplyDeferredClientMode();
+  return true;
+}
+
+bool MaintenanceWindowMonitor::InMaintenanceWindow() {
+  return [SNTMaintenanceWindow endOfWindowsContainingDate:now_()
+                                                inWindows:configurator_.maintenanceWindows
+                                                 calendar:calendar_] != nil;
+}
+
+bool MaintenanceWindowMonitor::ClientModeReceived(SNTClientMode mode) {
+  bool escalation = configurator_.clientMode == SNTClientModeMonitor &&
+                    (mode == SNTClientModeLockdown || mode == SNTClientModeStandalone);
+
+  if (escalation && InMaintenanceWindow()) {
+    if (configurator_.deferredClientMode != mode) {
+      LOGI(@"Deferring switch to %@ mode until the maintenance window closes",
+           ClientModeName(mode));
+    }
+    [configurator_ setSyncServerDeferredClientMode:mode];
+    return true;
+  }
+
+  // The latest mode from the server always replaces one that is still deferred.
+  [configurator_ setSyncServerClientMode:mode];
+  [configurator_ setSyncServerDeferredClientMode:SNTClientModeUnknown];
+  return false;
+}
+
+bool MaintenanceWindowMonitor::ApplyDeferredClientMode() {
+  // Avoid writing the sync state every cycle when there is nothing to do.
+  if (configurator_.deferredClientMode == SNTClientModeUnknown) {
+    return false;
+  }
+
+  // Re-check inside the batch, which is serialized with the batch applying
+  // preflight results, so a newer mode from the server is never overwritten.
+  __block bool applied = false;
+  [configurator_ performSyncStateBatch:^{
+    SNTClientMode deferred = configurator_.deferredClientMode;
+    if (deferred == SNTClientModeUnknown || InMaintenanceWindow()) {
+      return;
+    }
+
+    LOGI(@"Maintenance window closed, switching to deferred %@ mode", ClientModeName(deferred));
+    [configurator_ setSyncServerClientMode:deferred];
+    [configurator_ setSyncServerDeferredClientMode:SNTClientModeUnknown];
+    applied = true;
+  }];
+
+  return applied;
+}
+
+}  // namespace santa
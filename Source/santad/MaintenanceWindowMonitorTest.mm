@@ -0,0 +1,169 @@
+/// Copyright 2026 North Pole Security, Inc.
+///
+/// Licensed under the Apache License, Version 2.0 (the "License");
+/// you may not use this file except in compliance with the License.
+/// You may obtain a copy of the License at
+///
+///     http://www.apache.org/licenses/LICENSE-2.0
+///
+/// Unless required by applicable law or agreed to in writing, software
+/// distributed under the License is distributed on an "AS IS" BASIS,
+/// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+/// See the License for the specific language governing permissions and
+/// limitations under the License.
+
+#include "Source/santad/MaintenanceWindowMonitor.h"
+
+#import <Foundation/Foundation.h>
+#import <OCMock/OCMock.h>
+#import <XCTest/XCTest.h>
+
+#include <memory>
+
+#import "Source/common/SNTCommonEnums.h"
+#import "Source/common/SNTConfigurator.h"
+#import "Source/common/SNTMaintenanceWindow.h"
+
+namespace santa {
+class MaintenanceWindowMonitorPeer : public MaintenanceWindowMonitor {
+ public:
+  MaintenanceWindowMonitorPeer(SNTConfigurator* configurator, NSCalendar* calendar, NowBlock now)
+      : MaintenanceWindowMonitor(MakeKey(), configurator, calendar, now) {}
+};
+}  // namespace santa
+
+using santa::MaintenanceWindowMonitorPeer;
+
+@interface MaintenanceWindowMonitorTest : XCTestCase
+@property id mockConfigurator;
+@property NSCalendar* calendar;
+@property NSISO8601DateFormatter* formatter;
+@property NSDate* now;
+@property SNTClientMode clientMode;
+@property SNTClientMode deferredClientMode;
+@end
+
+@implementation MaintenanceWindowMonitorTest
+
+- (void)setUp {
+  self.calendar = [NSCalendar calendarWithIdentifier:NSCalendarIdentifierGregorian];
+  self.calendar.timeZone = [NSTimeZone timeZoneWithName:@"UTC"];
+  self.formatter = [[NSISO8601DateFormatter alloc] init];
+  self.clientMode = SNTClientModeMonitor;
+  self.deferredClientMode = SNTClientModeUnknown;
+
+  // The configurator mock keeps the client mode and deferred client mode in the test's
+  // properties so the monitor sees the results of its own updates.
+  self.mockConfigurator = OCMClassMock([SNTConfigurator class]);
+  OCMStub([self.mockConfigurator maintenanceWindows]).andReturn(@[
+    [[SNTMaintenanceWindow alloc] initWithStartMinute:22 * 60 endMinute:6 * 60 weekdays:nil],
+  ]);
+  OCMStub([self.mockConfigurator performSyncStateBatch:[OCMArg invokeBlock]]).andReturn(YES);
+  OCMStub([self.mockConfigurator clientMode]).andDo(^(NSInvocation* inv) {
+    SNTClientMode mode = self.clientMode;
+    [inv setReturnValue:&mode];
+  });
+  OCMStub([self.mockConfigurator deferredClientMode]).andDo(^(NSInvocation* inv) {
+    SNTClientMode mode = self.deferredClientMode;
+    [inv setReturnValue:&mode];
+  });
+  OCMStub([self.mockConfigurator setSyncServerClientMode:SNTClientModeUnknown])
+      .ignoringNonObjectArgs()
+      .andDo(^(NSInvocation* inv) {
+        SNTClientMode mode;
+        [inv getArgument:&mode atIndex:2];
+        self.clientMode = mode;
+      });
+  OCMStub([self.mockConfigurator setSyncServerDeferredClientMode:SNTClientModeUnknown])
+      .ignoringNonObjectArgs()
+      .andDo(^(NSInvocation* inv) {
+        SNTClientMode mode;
+        [inv getArgument:&mode atIndex:2];
+        self.deferredClientMode = mode;
+      });
+}
+
+- (void)tearDown {
+  [self.mockConfigurator stopMocking];
+}
+
+- (std::shared_ptr<MaintenanceWindowMonitorPeer>)createMonitor {
+  return std::make_shared<MaintenanceWindowMonitorPeer>(self.mockConfigurator, self.calendar,
+                                                        ^NSDate* {
+                                                          return self.now;
+                                                        });
+}
+
+- (void)setTime:(NSString*)time {
+  self.now = [self.formatter dateFromString:time];
+}
+
+- (void)testLockdownOutsideWindowIsAppliedImmediately {
+  auto monitor = [self createMonitor];
+  [self setTime:@"2026-03-02T12:00:00Z"];
+
+  XCTAssertFalse(monitor->ClientModeReceived(SNTClientModeLockdown));
+  XCTAssertEqual(self.clientMode, SNTClientModeLockdown);
+  XCTAssertEqual(self.deferredClientMode, SNTClientModeUnknown);
+}
+
+- (void)testLockdownDuringWindowIsDeferredAndAppliedAfter {
+  auto monitor = [self createMonitor];
+  [self setTime:@"2026-03-02T23:00:00Z"];
+
+  XCTAssertTrue(monitor->ClientModeReceived(SNTClientModeLockdown));
+  XCTAssertEqual(self.clientMode, SNTClientModeMonitor);
+  XCTAssertEqual(self.deferredClientMode, SNTClientModeLockdown);
+
+  // A later sync inside the window repeats the request, it stays deferred.
+  [self setTime:@"2026-03-03T03:00:00Z"];
+  XCTAssertTrue(monitor->ClientModeReceived(SNTClientModeLockdown));
+  XCTAssertFalse(monitor->ApplyDeferredClientMode());
+  XCTAssertEqual(self.clientMode, SNTClientModeMonitor);
+
+  // Once the window closes the deferred mode is applied.
+  [self setTime:@"2026-03-03T06:00:00Z"];
+  XCTAssertTrue(monitor->ApplyDeferredClientMode());
+  XCTAssertEqual(self.clientMode, SNTClientModeLockdown);
+  XCTAssertEqual(self.deferredClientMode, SNTClientModeUnknown);
+
+  // Nothing left to apply.
+  XCTAssertFalse(monitor->ApplyDeferredClientMode());
+}
+
+- (void)testStandaloneDuringWindowIsDeferred {
+  auto monitor = [self createMonitor];
+  [self setTime:@"2026-03-02T23:00:00Z"];
+
+  XCTAssertTrue(monitor->ClientModeReceived(SNTClientModeStandalone));
+  XCTAssertEqual(self.clientMode, SNTClientModeMonitor);
+  XCTAssertEqual(self.deferredClientMode, SNTClientModeStandalone);
+}
+
+- (void)testMonitorDuringWindowReplacesDeferredMode {
+  auto monitor = [self createMonitor];
+  [self setTime:@"2026-03-02T23:00:00Z"];
+
+  XCTAssertTrue(monitor->ClientModeReceived(SNTClientModeLockdown));
+  XCTAssertFalse(monitor->ClientModeReceived(SNTClientModeMonitor));
+  XCTAssertEqual(self.clientMode, SNTClientModeMonitor);
+  XCTAssertEqual(self.deferredClientMode, SNTClientModeUnknown);
+
+  [self setTime:@"2026-03-03T07:00:00Z"];
+  XCTAssertFalse(monitor->ApplyDeferredClientMode());
+  XCTAssertEqual(self.clientMode, SNTClientModeMonitor);
+}
+
+- (void)testChangesBetweenEnforcingModesAreNotDeferred {
+  auto monitor = [self createMonitor];
+  [self setTime:@"2026-03-02T23:00:00Z"];
+  self.clientMode = SNTClientModeLockdown;
+
+  XCTAssertFalse(monitor->ClientModeReceived(SNTClientModeStandalone));
+  XCTAssertEqual(self.clientMode, SNTClientModeStandalone);
+
+  XCTAssertFalse(monitor->ClientModeReceived(SNTClientModeMonitor));
+  XCTAssertEqual(self.clientMode, SNTClientModeMonitor);
+}
+
+@end
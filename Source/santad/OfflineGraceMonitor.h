@@ -0,0 +1,63 @@
+/// Copyright 2026 North Pole Security, Inc.
+///
+/// Licensed under the Apache License, Version 2.0 (the "License");
+/// you may not use this file except in compliance with the License.
+/// You may obtain a copy of the License at
+///
+///     http://www.apache.org/licenses/LICENSE-2.0
+///
+/// Unless required by applicable law or agreed to in writing, software
+/// distributed under the License is distributed on an "AS IS" BASIS,
+/// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+/// See the License for the specific language governing permissions and
+/// limitations under the License.
+
+#ifndef SANTA_SANTAD_OFFLINEGRACEMONITOR_H
+#define SANTA_SANTAD_OFFLINEGRACEMONITOR_H
+
+#import <Foundation/Foundation.h>
+
+#include <memory>
+
+#include "Source/common/PassKey.h"
+#import "Source/common/SNTCommonEnums.h"
+#import "Source/common/SNTConfigurator.h"
+#include "Source/common/Timer.h"
+
+namespace santa {
+
+// Applies the client mode named by the offline grace token delivered by MDM
+// once the host hasn't completed a full sync for offlineGraceSyncAgeSec.
+// While the token is valid the host stays in that mode, regardless of the mode
+// from the sync server. Once it expires, or if it is invalid or issued for a
+// different machine, the configured fallback mode is applied instead. The
+// token is re-checked periodically so expiry takes effect without a sync.
+class OfflineGraceMonitor : public Timer<OfflineGraceMonitor>,
+                            public PassKey<OfflineGraceMonitor> {
+ public:
+  using NowBlock = NSDate* (^)(void);
+
+  // Factory
+  static std::shared_ptr<OfflineGraceMonitor> Create(SNTConfigurator* configurator);
+
+  // Construction requires a PassKey, can only be used internally / by tests.
+  OfflineGraceMonitor(PassKey, SNTConfigurator* configurator, NowBlock now);
+
+  // Timer<> callback. Always re-arms.
+  bool OnTimer();
+
+  // Check the configured token and update the configurator's offline grace
+  // client mode. Returns the mode applied, SNTClientModeUnknown if no token
+  // is configured or the host has synced recently.
+  SNTClientMode Evaluate();
+
+  friend class OfflineGraceMonitorPeer;
+
+ private:
+  SNTConfigurator* configurator_;
+  NowBlock now_;
+};
+
+}  // namespace santa
+
+#endif  // SANTA_SANTAD_OFFLINEGRACEMONITOR_H
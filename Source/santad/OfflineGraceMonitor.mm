@@ -0,0 +1,98 @@
+/// Copyright 2026 North Pole Security, Inc.
+///
+/// Licensed under the Apache License, Version 2.0 (the "License");
+/// you may not use this file except in compliance with the License.
+/// You may obtain a copy of the License at
+///
+///     http://www.apache.org/licenses/LICENSE-2.0
+///
+/// Unless required by applicable law or agreed to in writing, software
+/// distributed under the License is distributed on an "AS IS" BASIS,
+/// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+/// See the License for the specific language governing permissions and
+/// limitations under the License.
+
+#include "Source/santad/OfflineGraceMonitor.h"
+
+#import "Source/common/SNTLogging.h"
+#import "Source/common/SNTOfflineGraceToken.h"
+
+namespace santa {
+
+static constexpr uint32_t kOfflineGraceMonitorIntervalSec = 60;
+
+static NSString* ClientModeName(SNTClientMode mode) {
+  switch (mode) {
+    case SNTClientModeMonitor: return @"Monitor";
+    case SNTClientModeLockdown: return @"Lockdown";
+    case SNTClientModeStandalone: return @"Standalone";
+    default: return @"Unknown";
+  }
+}
+
+std::shared_ptr<OfflineGraceMonitor> OfflineGraceMonitor::Create(SNTConfigurator* configurator) {
+  auto monitor = std::make_shared<OfflineGraceMonitor>(PassKey(), configurator, ^NSDate* {
+    return [NSDate date];
+  });
+
+  monitor->StartTimer();
+
+  return monitor;
+}
+
+OfflineGraceMonitor::OfflineGraceMonitor(PassKey, SNTConfigurator* configurator, NowBlock now)
+    : Timer(kOfflineGraceMonitorIntervalSec, kOfflineGraceMonitorIntervalSec,
+            Timer::OnStart::kFireImmediately, "OfflineGraceMonitor"),
+      configurator_(configurator),
+      now_([now copy]) {}
+
+bool OfflineGraceMonitor::OnTimer() {
+  Evaluate();
+  return true;
+}
+
+SNTClientMode OfflineGraceMonitor::Evaluate() {
+  SNTClientMode mode = SNTClientModeUnknown;
+  NSString* tokenString = configurator_.offlineGraceToken;
+  NSDate* now = now_();
+  NSDate* lastSync = configurator_.fullSyncLastSuccess;
+  if (tokenString && lastSync &&
+      [now timeIntervalSinceDate:lastSync] < configurator_.offlineGraceSyncAgeSec) {
+    // The host is still syncing, keep the mode from the sync server.
+    if (configurator_.offlineGraceClientMode != SNTClientModeUnknown) {
+      LOGI(@"Full sync succeeded at %@, offline grace token no longer applies", lastSync);
+    }
+  } else if (tokenString) {
+    NSData* publicKey = configurator_.offlineGraceTokenPublicKey ?: [NSData data];
+    NSError* error;
+    SNTOfflineGraceToken* token = [SNTOfflineGraceToken tokenWithString:tokenString
+                                                              publicKey:publicKey
+                                                            currentDate:now
+                                                                  error:&error];
+    NSString* reason = error.localizedDescription;
+    if (token.machineID && ![token.machineID isEqualToString:configurator_.machineID]) {
+      token = nil;
+      reason = @"Token was issued for a different machine";
+    }
+
+    mode = token ? token.clientMode : configurator_.offlineGraceFallbackClientMode;
+    if (mode != configurator_.offlineGraceClientMode) {
+      if (token) {
+        LOGI(@"Offline grace token valid until %@, keeping %@ mode", token.expirationDate,
+             ClientModeName(mode));
+      } else {
+        LOGW(@"Offline grace token not accepted (%@), falling back to %@ mode", reason,
+             ClientModeName(mode));
+      }
+    }
+  } else if (configurator_.offlineGraceClientMode != SNTClientModeUnknown) {
+    LOGI(@"Offline grace token removed");
+  }
+
+  if (mode != configurator_.offlineGraceClientMode) {
+    [configurator_ setOfflineGraceClientMode:mode];
+  }
+  return mode;
+}
+
+}  // namespace santa
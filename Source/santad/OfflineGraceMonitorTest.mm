@@ -0,0 +1,201 @@
+/// Copyright 2026 North Pole Security, Inc.
+///
+/// Licensed under the Apache License, Version 2.0 (the "License");
+/// you may not use this file except in compliance with the License.
+/// You may obtain a copy of the License at
+///
+///     http://www.apache.org/licenses/LICENSE-2.0
+///
+/// Unless required by applicable law or agreed to in writing, software
+/// distributed under the License is distributed on an "AS IS" BASIS,
+/// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+/// See the License for the specific language governing permissions and
+/// limitations under the License.
+
+#include "Source/santad/OfflineGraceMonitor.h"
+
+#import <Foundation/Foundation.h>
+#import <OCMock/OCMock.h>
+#import <XCTest/XCTest.h>
+#include <openssl/curve25519.h>
+
+#include <memory>
+
+#import "Source/common/SNTCommonEnums.h"
+#import "Source/common/SNTConfigurator.h"
+
+namespace santa {
+class OfflineGraceMonitorPeer : public OfflineGraceMonitor {
+ public:
+  OfflineGraceMonitorPeer(SNTConfigurator* configurator, NowBlock now)
+      : OfflineGraceMonitor(MakeKey(), configurator, now) {}
+};
+}  // namespace santa
+
+using santa::OfflineGraceMonitorPeer;
+
+static NSString* Base64URLEncode(NSData* data) {
+  NSString* b64 = [data base64EncodedStringWithOptions:0];
+  b64 = [b64 stringByReplacingOccurrencesOfString:@"+" withString:@"-"];
+  b64 = [b64 stringByReplacingOccurrencesOfString:@"/" withString:@"_"];
+  return [b64 stringByReplacingOccurrencesOfString:@"=" withString:@""];
+}
+
+@interface OfflineGraceMonitorTest : XCTestCase
+@property id mockConfigurator;
+@property NSData* privateKey;
+@property NSDate* now;
+@property NSString* token;
+@property NSDate* lastSync;
+@property SNTClientMode offlineGraceClientMode;
+@end
+
+@implementation OfflineGraceMonitorTest
+
+- (void)setUp {
+  uint8_t publicKey[ED25519_PUBLIC_KEY_LEN];
+  uint8_t privateKey[ED25519_PRIVATE_KEY_LEN];
+  ED25519_keypair(publicKey, privateKey);
+  self.privateKey = [NSData dataWithBytes:privateKey length:sizeof(privateKey)];
+  self.now = [NSDate dateWithTimeIntervalSince1970:1800000000];
+  self.offlineGraceClientMode = SNTClientModeUnknown;
+
+  // The configurator mock keeps the token and the offline grace client mode in the test's
+  // properties so the monitor sees the results of its own updates.
+  self.mockConfigurator = OCMClassMock([SNTConfigurator class]);
+  OCMStub([self.mockConfigurator machineID]).andReturn(@"my-machine");
+  OCMStub([self.mockConfigurator offlineGraceTokenPublicKey])
+      .andReturn([NSData dataWithBytes:publicKey length:sizeof(publicKey)]);
+  OCMStub([self.mockConfigurator offlineGraceFallbackClientMode]).andReturn(SNTClientModeMonitor);
+  OCMStub([self.mockConfigurator offlineGraceSyncAgeSec]).andReturn(86400);
+  OCMStub([self.mockConfigurator fullSyncLastSuccess]).andDo(^(NSInvocation* inv) {
+    NSDate* lastSync = self.lastSync;
+    [inv setReturnValue:&lastSync];
+  });
+  OCMStub([self.mockConfigurator offlineGraceToken]).andDo(^(NSInvocation* inv) {
+    NSString* token = self.token;
+    [inv setReturnValue:&token];
+  });
+  OCMStub([self.mockConfigurator offlineGraceClientMode]).andDo(^(NSInvocation* inv) {
+    SNTClientMode mode = self.offlineGraceClientMode;
+    [inv setReturnValue:&mode];
+  });
+  OCMStub([self.mockConfigurator setOfflineGraceClientMode:SNTClientModeUnknown])
+      .ignoringNonObjectArgs()
+      .andDo(^(NSInvocation* inv) {
+        SNTClientMode mode;
+        [inv getArgument:&mode atIndex:2];
+        self.offlineGraceClientMode = mode;
+      });
+}
+
+- (void)tearDown {
+  [self.mockConfigurator stopMocking];
+}
+
+- (std::shared_ptr<OfflineGraceMonitorPeer>)createMonitor {
+  return std::make_shared<OfflineGraceMonitorPeer>(self.mockConfigurator, ^NSDate* {
+    return self.now;
+  });
+}
+
+- (NSString*)tokenWithClaims:(NSDictionary*)claims {
+  NSData* header = [NSJSONSerialization dataWithJSONObject:@{@"alg" : @"EdDSA", @"typ" : @"JWT"}
+                                                   options:0
+                                                     error:nil];
+  NSData* payload = [NSJSONSerialization dataWithJSONObject:claims options:0 error:nil];
+  NSString* signingInput =
+      [NSString stringWithFormat:@"%@.%@", Base64URLEncode(header), Base64URLEncode(payload)];
+
+  uint8_t signature[ED25519_SIGNATURE_LEN];
+  NSData* input = [signingInput dataUsingEncoding:NSUTF8StringEncoding];
+  ED25519_sign(signature, static_cast<const uint8_t*>(input.bytes), input.length,
+               static_cast<const uint8_t*>(self.privateKey.bytes));
+
+  return [NSString
+      stringWithFormat:@"%@.%@", signingInput,
+                       Base64URLEncode([NSData dataWithBytes:signature length:sizeof(signature)])];
+}
+
+- (NSString*)lockdownTokenExpiringIn:(NSTimeInterval)interval machineID:(NSString*)machineID {
+  NSMutableDictionary* claims = [@{
+    @"exp" : @([self.now timeIntervalSince1970] + interval),
+    @"client_mode" : @"LOCKDOWN",
+  } mutableCopy];
+  claims[@"machine_id"] = machineID;
+  return [self tokenWithClaims:claims];
+}
+
+- (void)testValidTokenKeepsAssignedMode {
+  auto monitor = [self createMonitor];
+  self.token = [self lockdownTokenExpiringIn:86400 machineID:@"my-machine"];
+
+  XCTAssertEqual(monitor->Evaluate(), SNTClientModeLockdown);
+  XCTAssertEqual(self.offlineGraceClientMode, SNTClientModeLockdown);
+
+  // Still inside the grace period, the mode is unchanged.
+  self.now = [self.now dateByAddingTimeInterval:86399];
+  XCTAssertEqual(monitor->Evaluate(), SNTClientModeLockdown);
+  XCTAssertEqual(self.offlineGraceClientMode, SNTClientModeLockdown);
+}
+
+- (void)testExpiredTokenAppliesFallback {
+  auto monitor = [self createMonitor];
+  self.token = [self lockdownTokenExpiringIn:3600 machineID:nil];
+  XCTAssertEqual(monitor->Evaluate(), SNTClientModeLockdown);
+
+  self.now = [self.now dateByAddingTimeInterval:3600];
+  XCTAssertEqual(monitor->Evaluate(), SNTClientModeMonitor);
+  XCTAssertEqual(self.offlineGraceClientMode, SNTClientModeMonitor);
+}
+
+- (void)testTokenForOtherMachineAppliesFallback {
+  auto monitor = [self createMonitor];
+  self.token = [self lockdownTokenExpiringIn:86400 machineID:@"other-machine"];
+
+  XCTAssertEqual(monitor->Evaluate(), SNTClientModeMonitor);
+  XCTAssertEqual(self.offlineGraceClientMode, SNTClientModeMonitor);
+}
+
+- (void)testHealthySyncKeepsServerMode {
+  auto monitor = [self createMonitor];
+  self.token = [self lockdownTokenExpiringIn:86400 machineID:nil];
+  self.lastSync = [self.now dateByAddingTimeInterval:-3600];
+
+  XCTAssertEqual(monitor->Evaluate(), SNTClientModeUnknown);
+  XCTAssertEqual(self.offlineGraceClientMode, SNTClientModeUnknown);
+
+  // An expired token doesn't apply the fallback while the host is syncing either.
+  self.now = [self.now dateByAddingTimeInterval:86400];
+  self.lastSync = [self.now dateByAddingTimeInterval:-60];
+  XCTAssertEqual(monitor->Evaluate(), SNTClientModeUnknown);
+  XCTAssertEqual(self.offlineGraceClientMode, SNTClientModeUnknown);
+}
+
+- (void)testTokenAppliesOnceSyncIsStale {
+  auto monitor = [self createMonitor];
+  self.token = [self lockdownTokenExpiringIn:86400 * 7 machineID:nil];
+  self.lastSync = [self.now dateByAddingTimeInterval:-3600];
+  XCTAssertEqual(monitor->Evaluate(), SNTClientModeUnknown);
+
+  self.now = [self.now dateByAddingTimeInterval:86400];
+  XCTAssertEqual(monitor->Evaluate(), SNTClientModeLockdown);
+  XCTAssertEqual(self.offlineGraceClientMode, SNTClientModeLockdown);
+
+  // A successful sync returns the host to the mode from the sync server.
+  self.lastSync = self.now;
+  XCTAssertEqual(monitor->Evaluate(), SNTClientModeUnknown);
+  XCTAssertEqual(self.offlineGraceClientMode, SNTClientModeUnknown);
+}
+
+- (void)testRemovingTokenClearsMode {
+  auto monitor = [self createMonitor];
+  self.token = [self lockdownTokenExpiringIn:86400 machineID:nil];
+  XCTAssertEqual(monitor->Evaluate(), SNTClientModeLockdown);
+
+  self.token = nil;
+  XCTAssertEqual(monitor->Evaluate(), SNTClientModeUnknown);
+  XCTAssertEqual(self.offlineGraceClientMode, SNTClientModeUnknown);
+}
+
+@end
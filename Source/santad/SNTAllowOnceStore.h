@@ -0,0 +1,56 @@
+/// Copyright 2026 North Pole Security, Inc.
+///
+/// Licensed under the Apache License, Version 2.0 (the "License");
+/// you may not use this file except in compliance with the License.
+/// You may obtain a copy of the License at
+///
+///     http://www.apache.org/licenses/LICENSE-2.0
+///
+/// Unless required by applicable law or agreed to in writing, software
+/// distributed under the License is distributed on an "AS IS" BASIS,
+/// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+/// See the License for the specific language governing permissions and
+/// limitations under the License.
+
+#import <Foundation/Foundation.h>
+
+#import "Source/common/SNTRuleIdentifiers.h"
+
+@class SNTConfigurator;
+@class SNTRule;
+
+NS_ASSUME_NONNULL_BEGIN
+
+///
+///  Holds the single-execution allows created from redeemed one-time allow
+///  tokens. Grants live in memory only; the IDs of redeemed tokens are
+///  persisted in the state file so a token can't be redeemed twice, even
+///  across restarts.
+///
+@interface SNTAllowOnceStore : NSObject
+
++ (instancetype)sharedStore;
+
+- (instancetype)initWithConfigurator:(SNTConfigurator*)configurator NS_DESIGNATED_INITIALIZER;
+- (instancetype)init NS_UNAVAILABLE;
+
+///
+///  Records `tokenID` as redeemed and adds a grant allowing one execution of
+///  the identity in `rule` until `expirationDate`. Returns NO, populating
+///  error, if the token was already redeemed or the redemption could not be
+///  persisted.
+///
+- (BOOL)addGrantForRule:(SNTRule*)rule
+                tokenID:(NSString*)tokenID
+         expirationDate:(NSDate*)expirationDate
+                  error:(NSError**)error;
+
+///
+///  Removes and returns the first unexpired grant matching any of the given
+///  identifiers, or nil if there is none.
+///
+- (nullable SNTRule*)consumeGrantForIdentifiers:(struct RuleIdentifiers)identifiers;
+
+@end
+
+NS_ASSUME_NONNULL_END
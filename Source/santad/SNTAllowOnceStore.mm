@@ -0,0 +1,124 @@
+/// Copyright 2026 North Pole Security, Inc.
+///
+/// Licensed under the Apache License, Version 2.0 (the "License");
+/// you may not use this file except in compliance with the License.
+/// You may obtain a copy of the License at
+///
+///     http://www.apache.org/licenses/LICENSE-2.0
+///
+/// Unless required by applicable law or agreed to in writing, software
+/// distributed under the License is distributed on an "AS IS" BASIS,
+/// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+/// See the License for the specific language governing permissions and
+/// limitations under the License.
+
+#import "Source/santad/SNTAllowOnceStore.h"
+
+#include <vector>
+
+#import "Source/common/SNTCommonEnums.h"
+#import "Source/common/SNTConfigurator.h"
+#import "Source/common/SNTError.h"
+#import "Source/common/SNTRule.h"
+
+namespace {
+
+struct Grant {
+  SNTRule* rule;
+  NSDate* expiration;
+};
+
+}  // namespace
+
+static NSString* IdentifierForRuleType(SNTRuleType type, const struct RuleIdentifiers& ids) {
+  switch (type) {
+    case SNTRuleTypeBinary: return ids.binarySHA256;
+    case SNTRuleTypeCDHash: return ids.cdhash;
+    case SNTRuleTypeSigningID: return ids.signingID;
+    case SNTRuleTypeCertificate: return ids.certificateSHA256;
+    case SNTRuleTypeTeamID: return ids.teamID;
+    default: return nil;
+  }
+}
+
+@interface SNTAllowOnceStore ()
+@property SNTConfigurator* configurator;
+@end
+
+@implementation SNTAllowOnceStore {
+  // Outstanding grants. Guarded by @synchronized(self).
+  std::vector<Grant> _grants;
+}
+
++ (instancetype)sharedStore {
+  static SNTAllowOnceStore* store;
+  static dispatch_once_t onceToken;
+  dispatch_once(&onceToken, ^{
+    store = [[SNTAllowOnceStore alloc] initWithConfigurator:[SNTConfigurator configurator]];
+  });
+  return store;
+}
+
+- (instancetype)initWithConfigurator:(SNTConfigurator*)configurator {
+  self = [super init];
+  if (self) {
+    _configurator = configurator;
+  }
+  return self;
+}
+
+- (BOOL)addGrantForRule:(SNTRule*)rule
+                tokenID:(NSString*)tokenID
+         expirationDate:(NSDate*)expirationDate
+                  error:(NSError**)error {
+  @synchronized(self) {
+    NSDate* now = [NSDate date];
+    NSMutableDictionary<NSString*, NSDate*>* consumed =
+        [[self.configurator savedConsumedAllowOnceTokens] mutableCopy]
+            ?: [NSMutableDictionary dictionary];
+
+    if (consumed[tokenID]) {
+      [SNTError populateError:error withFormat:@"Token %@ has already been used", tokenID];
+      return NO;
+    }
+
+    // Expired tokens are rejected before they get here, so there is no need
+    // to remember their IDs any longer.
+    NSSet<NSString*>* expired =
+        [consumed keysOfEntriesPassingTest:^BOOL(NSString* key, NSDate* exp, BOOL* stop) {
+          return ![exp isKindOfClass:[NSDate class]] || [exp compare:now] != NSOrderedDescending;
+        }];
+    [consumed removeObjectsForKeys:expired.allObjects];
+    consumed[tokenID] = expirationDate;
+
+    if (![self.configurator persistConsumedAllowOnceTokens:consumed]) {
+      [SNTError populateError:error withFormat:@"Unable to record token %@ as used", tokenID];
+      return NO;
+    }
+
+    _grants.push_back({rule, expirationDate});
+    return YES;
+  }
+}
+
+- (SNTRule*)consumeGrantForIdentifiers:(struct RuleIdentifiers)identifiers {
+  @synchronized(self) {
+    if (_grants.empty()) return nil;
+
+    NSDate* now = [NSDate date];
+    std::erase_if(_grants, [now](const Grant& g) {
+      return [g.expiration compare:now] != NSOrderedDescending;
+    });
+
+    for (auto it = _grants.begin(); it != _grants.end(); ++it) {
+      if ([IdentifierForRuleType(it->rule.type, identifiers) isEqualToString:it->rule.identifier]) {
+        SNTRule* rule = it->rule;
+        _grants.erase(it);
+        return rule;
+      }
+    }
+    return nil;
+  }
+}
+
+@end
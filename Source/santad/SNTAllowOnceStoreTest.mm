@@ -0,0 +1,158 @@
+/// Copyright 2026 North Pole Security, Inc.
+///
+/// Licensed under the Apache License, Version 2.0 (the "License");
+/// you may not use this file except in compliance with the License.
+/// You may obtain a copy of the License at
+///
+///     http://www.apache.org/licenses/LICENSE-2.0
+///
+/// Unless required by applicable law or agreed to in writing, software
+/// distributed under the License is distributed on an "AS IS" BASIS,
+/// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+/// See the License for the specific language governing permissions and
+/// limitations under the License.
+
+#import "Source/santad/SNTAllowOnceStore.h"
+
+#import <Foundation/Foundation.h>
+#import <OCMock/OCMock.h>
+#import <XCTest/XCTest.h>
+
+#import "Source/common/SNTCommonEnums.h"
+#import "Source/common/SNTConfigurator.h"
+#import "Source/common/SNTRule.h"
+#import "Source/common/SNTRuleIdentifiers.h"
+
+static NSString* const kSHA256 =
+    @"b7c1e3fd640c5f211c89b02c2c6122f78ce322aa5c56eb0bb54bc422a8f8b670";
+static NSString* const kTeamID = @"EQHXZ8M8AV";
+
+@interface SNTAllowOnceStoreTest : XCTestCase
+@property id mockConfigurator;
+@property NSDictionary* persisted;
+@property SNTAllowOnceStore* sut;
+@end
+
+@implementation SNTAllowOnceStoreTest
+
+- (void)setUp {
+  [super setUp];
+  self.persisted = nil;
+  self.mockConfigurator = OCMClassMock([SNTConfigurator class]);
+  OCMStub([self.mockConfigurator persistConsumedAllowOnceTokens:[OCMArg any]])
+      .andDo(^(NSInvocation* inv) {
+        __unsafe_unretained NSDictionary* tokens;
+        [inv getArgument:&tokens atIndex:2];
+        self.persisted = [tokens copy];
+        BOOL ret = YES;
+        [inv setReturnValue:&ret];
+      });
+  OCMStub([self.mockConfigurator savedConsumedAllowOnceTokens]).andDo(^(NSInvocation* inv) {
+    __unsafe_unretained NSDictionary* tokens = self.persisted;
+    [inv setReturnValue:&tokens];
+  });
+  self.sut = [[SNTAllowOnceStore alloc] initWithConfigurator:self.mockConfigurator];
+}
+
+- (void)tearDown {
+  [self.mockConfigurator stopMocking];
+  [super tearDown];
+}
+
+- (SNTRule*)binaryRule {
+  return [[SNTRule alloc] initWithIdentifier:kSHA256
+                                       state:SNTRuleStateAllow
+                                        type:SNTRuleTypeBinary];
+}
+
+- (void)testGrantIsConsumedOnce {
+  NSDate* exp = [NSDate dateWithTimeIntervalSinceNow:300];
+  XCTAssertTrue([self.sut addGrantForRule:[self binaryRule]
+                                  tokenID:@"t1"
+                           expirationDate:exp
+                                    error:nil]);
+  XCTAssertEqualObjects(self.persisted, @{@"t1" : exp});
+
+  struct RuleIdentifiers other = {.binarySHA256 = @"other", .teamID = @"ABCDE12345"};
+  XCTAssertNil([self.sut consumeGrantForIdentifiers:other]);
+
+  struct RuleIdentifiers ids = {.binarySHA256 = kSHA256, .teamID = kTeamID};
+  SNTRule* rule = [self.sut consumeGrantForIdentifiers:ids];
+  XCTAssertEqualObjects(rule.identifier, kSHA256);
+  XCTAssertNil([self.sut consumeGrantForIdentifiers:ids]);
+}
+
+- (void)testTeamIDGrantMatchesTeamID {
+  SNTRule* rule = [[SNTRule alloc] initWithIdentifier:kTeamID
+                                                state:SNTRuleStateAllow
+                                                 type:SNTRuleTypeTeamID];
+  XCTAssertTrue([self.sut addGrantForRule:rule
+                                  tokenID:@"t1"
+                           expirationDate:[NSDate dateWithTimeIntervalSinceNow:300]
+                                    error:nil]);
+
+  // A binary SHA-256 equal to the team ID string must not match.
+  XCTAssertNil([self.sut consumeGrantForIdentifiers:{.binarySHA256 = kTeamID}]);
+  XCTAssertNotNil([self.sut consumeGrantForIdentifiers:{.teamID = kTeamID}]);
+}
+
+- (void)testReuseRejected {
+  NSDate* exp = [NSDate dateWithTimeIntervalSinceNow:300];
+  XCTAssertTrue([self.sut addGrantForRule:[self binaryRule]
+                                  tokenID:@"t1"
+                           expirationDate:exp
+                                    error:nil]);
+  XCTAssertNotNil([self.sut consumeGrantForIdentifiers:{.binarySHA256 = kSHA256}]);
+
+  NSError* err;
+  XCTAssertFalse([self.sut addGrantForRule:[self binaryRule]
+                                   tokenID:@"t1"
+                            expirationDate:exp
+                                     error:&err]);
+  XCTAssertNotNil(err);
+  XCTAssertNil([self.sut consumeGrantForIdentifiers:{.binarySHA256 = kSHA256}]);
+
+  // The consumed record is persisted, so a new store (e.g. after a restart)
+  // also rejects the token.
+  SNTAllowOnceStore* restarted =
+      [[SNTAllowOnceStore alloc] initWithConfigurator:self.mockConfigurator];
+  XCTAssertFalse([restarted addGrantForRule:[self binaryRule]
+                                    tokenID:@"t1"
+                             expirationDate:exp
+                                      error:nil]);
+}
+
+- (void)testExpiredTokenIDsArePruned {
+  self.persisted = @{@"old" : [NSDate dateWithTimeIntervalSinceNow:-1]};
+
+  XCTAssertTrue([self.sut addGrantForRule:[self binaryRule]
+                                  tokenID:@"t1"
+                           expirationDate:[NSDate dateWithTimeIntervalSinceNow:300]
+                                    error:nil]);
+  XCTAssertEqualObjects(self.persisted.allKeys, @[ @"t1" ]);
+}
+
+- (void)testExpiredGrantNotHonored {
+  XCTAssertTrue([self.sut addGrantForRule:[self binaryRule]
+                                  tokenID:@"t1"
+                           expirationDate:[NSDate dateWithTimeIntervalSinceNow:-1]
+                                    error:nil]);
+  XCTAssertNil([self.sut consumeGrantForIdentifiers:{.binarySHA256 = kSHA256}]);
+}
+
+- (void)testPersistFailureAddsNoGrant {
+  id failingConfigurator = OCMClassMock([SNTConfigurator class]);
+  OCMStub([failingConfigurator persistConsumedAllowOnceTokens:[OCMArg any]]).andReturn(NO);
+  SNTAllowOnceStore* sut = [[SNTAllowOnceStore alloc] initWithConfigurator:failingConfigurator];
+
+  NSError* err;
+  XCTAssertFalse([sut addGrantForRule:[self binaryRule]
+                              tokenID:@"t1"
+                       expirationDate:[NSDate dateWithTimeIntervalSinceNow:300]
+                                error:&err]);
+  XCTAssertNotNil(err);
+  XCTAssertNil([sut consumeGrantForIdentifiers:{.binarySHA256 = kSHA256}]);
+  [failingConfigurator stopMocking];
+}
+
+@end
@@ -0,0 +1,52 @@
+/// Copyright 2026 North Pole Security, Inc.
+///
+/// Licensed under the Apache License, Version 2.0 (the "License");
+/// you may not use this file except in compliance with the License.
+/// You may obtain a copy of the License at
+///
+///     http://www.apache.org/licenses/LICENSE-2.0
+///
+/// Unless required by applicable law or agreed to in writing, software
+/// distributed under the License is distributed on an "AS IS" BASIS,
+/// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+/// See the License for the specific language governing permissions and
+/// limitations under the License.
+
+
+#import <Foundation/Foundation.h>
+
+#import "Source/common/SNTRule.h"
+#import "Source/common/SNTStoredExecutionEvent.h"
+
+NS_ASSUME_NONNULL_BEGIN
+
+///
+///  Remembers recently blocked executions so that when a block is later approved, either by the
+///  user in standalone mode or by an allow rule from the sync server, an event recording how long
+///  the approval took can be uploaded. Blocks are only kept in memory, so approvals that arrive
+///  after santad restarts are not reported.
+///
+@interface SNTApprovalTracker : NSObject
+
++ (instancetype)sharedTracker;
+
+- (instancetype)initWithCapacity:(uint64_t)capacity NS_DESIGNATED_INITIALIZER;
+- (instancetype)init NS_UNAVAILABLE;
+
+///
+///  Record a blocked execution. Any rule matching one of the event's identifiers is considered to
+///  approve it.
+///
+- (void)recordBlockedEvent:(SNTStoredExecutionEvent*)event;
+
+///
+///  For each allow rule that approves a recorded block, returns a copy of the blocked event with
+///  the allow decision for the rule, an occurrence date of approvalDate and approvalLatencyMs
+///  set. Approved blocks are forgotten so each is only reported once.
+///
+- (NSArray<SNTStoredExecutionEvent*>*)approvalEventsForRules:(NSArray<SNTRule*>*)rules
+                                                  approvedAt:(NSDate*)approvalDate;
+
+@end
+
+NS_ASSUME_NONNULL_END
@@ -0,0 +1,149 @@
+/// Copyright 2026 North Pole Security, Inc.
+///
+/// Licensed under the Apache License, Version 2.0 (the "License");
+/// you may not use this file except in compliance with the License.
+/// You may obtain a copy of the License at
+///
+///     http://www.apache.org/licenses/LICENSE-2.0
+///
+/// Unless required by applicable law or agreed to in writing, software
+/// distributed under the License is distributed on an "AS IS" BASIS,
+/// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+/// See the License for the specific language governing permissions and
+/// limitations under the License.
+
+
+#import "Source/santad/SNTApprovalTracker.h"
+
+#include <memory>
+#include <string>
+#include <vector>
+
+#import "Source/common/MOLCertificate.h"
+#import "Source/common/SNTCommonEnums.h"
+#import "Source/common/SNTLogging.h"
+#include "Source/common/SantaCache.h"
+#include "Source/common/String.h"
+
+static const uint64_t kDefaultApprovalTrackerCapacity = 1024;
+
+static std::string ApprovalKey(SNTRuleType type, NSString* identifier) {
+  return std::to_string(type) + ":" + santa::NSStringToUTF8String(identifier);
+}
+
+static SNTEventState AllowDecisionForRuleType(SNTRuleType type) {
+  switch (type) {
+    case SNTRuleTypeBinary: return SNTEventStateAllowBinary;
+    case SNTRuleTypeCertificate: return SNTEventStateAllowCertificate;
+    case SNTRuleTypeTeamID: return SNTEventStateAllowTeamID;
+    case SNTRuleTypeSigningID: return SNTEventStateAllowSigningID;
+    case SNTRuleTypeCDHash: return SNTEventStateAllowCDHash;
+    default: return SNTEventStateAllowUnknown;
+  }
+}
+
+static BOOL RuleStateApproves(SNTRuleState state) {
+  switch (state) {
+    case SNTRuleStateAllow:
+    case SNTRuleStateAllowCompiler:
+    case SNTRuleStateAllowTransitive:
+    case SNTRuleStateAllowLocalBinary:
+    case SNTRuleStateAllowLocalSigningID: return YES;
+    default: return NO;
+  }
+}
+
+@implementation SNTApprovalTracker {
+  std::unique_ptr<SantaCache<std::string, SNTStoredExecutionEvent*>> _blockedEvents;
+  dispatch_queue_t _q;
+}
+
++ (instancetype)sharedTracker {
+  static SNTApprovalTracker* tracker;
+  static dispatch_once_t onceToken;
+  dispatch_once(&onceToken, ^{
+    tracker = [[SNTApprovalTracker alloc] initWithCapacity:kDefaultApprovalTrackerCapacity];
+  });
+  return tracker;
+}
+
+- (instancetype)initWithCapacity:(uint64_t)capacity {
+  self = [super init];
+  if (self) {
+    _blockedEvents = std::make_unique<SantaCache<std::string, SNTStoredExecutionEvent*>>(capacity);
+    _q = dispatch_queue_create("com.northpolesec.santa.daemon.approval_tracker",
+                               DISPATCH_QUEUE_SERIAL_WITH_AUTORELEASE_POOL);
+  }
+  return self;
+}
+
+// The keys, one per rule type, under which a rule would match this event.
+- (std::vector<std::string>)keysForEvent:(SNTStoredExecutionEvent*)event {
+  std::vector<std::string> keys;
+  if (event.fileSHA256.length) keys.push_back(ApprovalKey(SNTRuleTypeBinary, event.fileSHA256));
+  if (event.cdhash.length) keys.push_back(ApprovalKey(SNTRuleTypeCDHash, event.cdhash));
+  if (event.signingID.length) {
+    keys.push_back(ApprovalKey(SNTRuleTypeSigningID, event.signingID));
+  }
+  NSString* certSHA256 = event.signingChain.firstObject.SHA256;
+  if (certSHA256.length) keys.push_back(ApprovalKey(SNTRuleTypeCertificate, certSHA256));
+  if (event.teamID.length) keys.push_back(ApprovalKey(SNTRuleTypeTeamID, event.teamID));
+  return keys;
+}
+
+- (void)recordBlockedEvent:(SNTStoredExecutionEvent*)event {
+  dispatch_sync(_q, ^{
+    for (const std::string& key : [self keysForEvent:event]) {
+      // Keep the earliest block so latency covers the full time the user was waiting.
+      if (!_blockedEvents->get(key)) {
+        _blockedEvents->set(key, event);
+      }
+    }
+  });
+}
+
+- (NSArray<SNTStoredExecutionEvent*>*)approvalEventsForRules:(NSArray<SNTRule*>*)rules
+                                                  approvedAt:(NSDate*)approvalDate {
+  NSMutableArray<SNTStoredExecutionEvent*>* approvals = [NSMutableArray array];
+
+  dispatch_sync(_q, ^{
+    for (SNTRule* rule in rules) {
+      if (!RuleStateApproves(rule.state) || !rule.identifier.length) continue;
+
+      SNTStoredExecutionEvent* blocked =
+          _blockedEvents->get(ApprovalKey(rule.type, rule.identifier));
+      if (!blocked) continue;
+
+      for (const std::string& key : [self keysForEvent:blocked]) {
+        _blockedEvents->remove(key);
+      }
+
+      NSError* error;
+      NSData* data = [NSKeyedArchiver archivedDataWithRootObject:blocked
+                                           requiringSecureCoding:YES
+                                                           error:&error];
+      SNTStoredExecutionEvent* approval =
+          data ? [NSKeyedUnarchiver unarchivedObjectOfClass:[SNTStoredExecutionEvent class]
+                                                   fromData:data
+                                                      error:&error]
+               : nil;
+      if (!approval) {
+        LOGW(@"Unable to create approval event for %@: %@", blocked.fileSHA256, error);
+        continue;
+      }
+
+      NSTimeInterval latency = [approvalDate timeIntervalSinceDate:blocked.occurrenceDate];
+      approval.idx = @(arc4random());
+      approval.occurrenceDate = approvalDate;
+      approval.decision = AllowDecisionForRuleType(rule.type);
+      approval.holdAndAsk = NO;
+      approval.ruleId = rule.ruleId;
+      approval.approvalLatencyMs = @((uint64_t)(MAX(latency, 0) * 1000));
+      [approvals addObject:approval];
+    }
+  });
+
+  return approvals;
+}
+
+@end
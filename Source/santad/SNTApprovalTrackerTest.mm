@@ -0,0 +1,153 @@
+/// Copyright 2026 North Pole Security, Inc.
+///
+/// Licensed under the Apache License, Version 2.0 (the "License");
+/// you may not use this file except in compliance with the License.
+/// You may obtain a copy of the License at
+///
+///     http://www.apache.org/licenses/LICENSE-2.0
+///
+/// Unless required by applicable law or agreed to in writing, software
+/// distributed under the License is distributed on an "AS IS" BASIS,
+/// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+/// See the License for the specific language governing permissions and
+/// limitations under the License.
+
+
+#import "Source/santad/SNTApprovalTracker.h"
+
+#import <Foundation/Foundation.h>
+#import <XCTest/XCTest.h>
+
+#import "Source/common/SNTCommonEnums.h"
+#import "Source/common/SNTRule.h"
+#import "Source/common/SNTStoredExecutionEvent.h"
+
+static NSString* const kSHA256 =
+    @"b7c1e3fd640c5f211c89b02c2c6122f78ce322aa5c56eb0bb54bc422a8f8b670";
+static NSString* const kOtherSHA256 =
+    @"a9c1e3fd640c5f211c89b02c2c6122f78ce322aa5c56eb0bb54bc422a8f8b670";
+static NSString* const kTeamID = @"EQHXZ8M8AV";
+static NSString* const kSigningID = @"EQHXZ8M8AV:com.northpolesec.example";
+
+@interface SNTApprovalTrackerTest : XCTestCase
+@property SNTApprovalTracker* sut;
+@property NSDate* blockDate;
+@end
+
+@implementation SNTApprovalTrackerTest
+
+- (void)setUp {
+  [super setUp];
+  self.sut = [[SNTApprovalTracker alloc] initWithCapacity:64];
+  self.blockDate = [NSDate dateWithTimeIntervalSince1970:1700000000];
+}
+
+- (SNTStoredExecutionEvent*)blockedEvent {
+  SNTStoredExecutionEvent* se = [[SNTStoredExecutionEvent alloc] init];
+  se.idx = @(1);
+  se.fileSHA256 = kSHA256;
+  se.filePath = @"/Applications/Example.app/Contents/MacOS/Example";
+  se.teamID = kTeamID;
+  se.signingID = kSigningID;
+  se.decision = SNTEventStateBlockUnknown;
+  se.holdAndAsk = YES;
+  se.occurrenceDate = self.blockDate;
+  return se;
+}
+
+- (void)testServerRuleApprovesBlock {
+  [self.sut recordBlockedEvent:[self blockedEvent]];
+
+  SNTRule* rule = [[SNTRule alloc] initWithIdentifier:kSHA256
+                                                state:SNTRuleStateAllow
+                                                 type:SNTRuleTypeBinary
+                                            customMsg:nil
+                                            customURL:nil
+                                              celExpr:nil
+                                       seatbeltPolicy:nil
+                                               ruleId:42];
+  NSDate* approvalDate = [self.blockDate dateByAddingTimeInterval:90.5];
+  NSArray<SNTStoredExecutionEvent*>* approvals = [self.sut approvalEventsForRules:@[ rule ]
+                                                                       approvedAt:approvalDate];
+
+  XCTAssertEqual(approvals.count, 1);
+  SNTStoredExecutionEvent* approval = approvals.firstObject;
+  XCTAssertEqualObjects(approval.approvalLatencyMs, @(90500));
+  XCTAssertEqualObjects(approval.fileSHA256, kSHA256);
+  XCTAssertEqualObjects(approval.occurrenceDate, approvalDate);
+  XCTAssertEqual(approval.decision, SNTEventStateAllowBinary);
+  XCTAssertEqual(approval.ruleId, 42);
+  XCTAssertFalse(approval.holdAndAsk);
+  XCTAssertNotEqualObjects(approval.idx, @(1));
+
+  // Approval events are never throttled or merged with the original block.
+  XCTAssertFalse([approval unactionableEvent]);
+  XCTAssertNotEqualObjects([approval uniqueID], [[self blockedEvent] uniqueID]);
+
+  // Each block is only reported once.
+  XCTAssertEqual([self.sut approvalEventsForRules:@[ rule ] approvedAt:approvalDate].count, 0);
+}
+
+- (void)testAnyMatchingIdentifierApprovesBlock {
+  [self.sut recordBlockedEvent:[self blockedEvent]];
+
+  // A TeamID rule approves the block and consumes it for the other identifiers too.
+  SNTRule* teamIDRule = [[SNTRule alloc] initWithIdentifier:kTeamID
+                                                      state:SNTRuleStateAllow
+                                                       type:SNTRuleTypeTeamID];
+  SNTRule* signingIDRule = [[SNTRule alloc] initWithIdentifier:kSigningID
+                                                         state:SNTRuleStateAllowLocalSigningID
+                                                          type:SNTRuleTypeSigningID];
+  NSArray<SNTStoredExecutionEvent*>* approvals =
+      [self.sut approvalEventsForRules:@[ teamIDRule, signingIDRule ]
+                            approvedAt:[self.blockDate dateByAddingTimeInterval:2]];
+
+  XCTAssertEqual(approvals.count, 1);
+  XCTAssertEqual(approvals.firstObject.decision, SNTEventStateAllowTeamID);
+  XCTAssertEqualObjects(approvals.firstObject.approvalLatencyMs, @(2000));
+}
+
+- (void)testNonApprovingRulesAreIgnored {
+  [self.sut recordBlockedEvent:[self blockedEvent]];
+
+  SNTRule* blockRule = [[SNTRule alloc] initWithIdentifier:kSHA256
+                                                     state:SNTRuleStateBlock
+                                                      type:SNTRuleTypeBinary];
+  SNTRule* otherRule = [[SNTRule alloc] initWithIdentifier:kOtherSHA256
+                                                     state:SNTRuleStateAllow
+                                                      type:SNTRuleTypeBinary];
+  // The identifier matches, but for a different rule type.
+  SNTRule* wrongTypeRule = [[SNTRule alloc] initWithIdentifier:kSHA256
+                                                         state:SNTRuleStateAllow
+                                                          type:SNTRuleTypeCertificate];
+  XCTAssertEqual([self.sut approvalEventsForRules:@[ blockRule, otherRule, wrongTypeRule ]
+                                       approvedAt:[NSDate date]]
+                     .count,
+                 0);
+
+  // The block is still tracked for a later approval.
+  SNTRule* allowRule = [[SNTRule alloc] initWithIdentifier:kSHA256
+                                                     state:SNTRuleStateAllow
+                                                      type:SNTRuleTypeBinary];
+  XCTAssertEqual([self.sut approvalEventsForRules:@[ allowRule ] approvedAt:[NSDate date]].count,
+                 1);
+}
+
+- (void)testEarliestBlockIsKept {
+  [self.sut recordBlockedEvent:[self blockedEvent]];
+
+  SNTStoredExecutionEvent* later = [self blockedEvent];
+  later.occurrenceDate = [self.blockDate dateByAddingTimeInterval:60];
+  [self.sut recordBlockedEvent:later];
+
+  SNTRule* rule = [[SNTRule alloc] initWithIdentifier:kSHA256
+                                                state:SNTRuleStateAllow
+                                                 type:SNTRuleTypeBinary];
+  NSArray<SNTStoredExecutionEvent*>* approvals =
+      [self.sut approvalEventsForRules:@[ rule ]
+                            approvedAt:[self.blockDate dateByAddingTimeInterval:120]];
+  XCTAssertEqual(approvals.count, 1);
+  XCTAssertEqualObjects(approvals.firstObject.approvalLatencyMs, @(120000));
+}
+
+@end
@@ -0,0 +1,53 @@
+/// Copyright 2026 North Pole Security, Inc.
+///
+/// Licensed under the Apache License, Version 2.0 (the "License");
+/// you may not use this file except in compliance with the License.
+/// You may obtain a copy of the License at
+///
+///     http://www.apache.org/licenses/LICENSE-2.0
+///
+/// Unless required by applicable law or agreed to in writing, software
+/// distributed under the License is distributed on an "AS IS" BASIS,
+/// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+/// See the License for the specific language governing permissions and
+/// limitations under the License.
+
+
+#import <Foundation/Foundation.h>
+
+#import "Source/common/SNTStoredExecutionEvent.h"
+
+NS_ASSUME_NONNULL_BEGIN
+
+///
+///  Throttles notifications and immediate uploads for unknown binaries for a short window after a
+///  clean sync. After a clean sync on a busy host many unknown binaries can be blocked at once;
+///  during the warm-up each binary is only notified about once and the number of notifications
+///  and immediate uploads is capped. Only blocks of unknown binaries are throttled, events that
+///  are not uploaded immediately are still stored and uploaded with the next sync.
+///
+@interface SNTCleanSyncWarmup : NSObject
+
++ (instancetype)sharedWarmup;
+
+///
+///  Begin a warm-up lasting duration seconds. Any warm-up already in progress is restarted. A
+///  duration of 0 ends any warm-up in progress.
+///
+- (void)startWithDuration:(NSTimeInterval)duration
+        notificationLimit:(NSUInteger)notificationLimit
+              uploadLimit:(NSUInteger)uploadLimit;
+
+///
+///  Returns NO if the GUI notification for the blocked event should be suppressed.
+///
+- (BOOL)shouldNotifyForEvent:(SNTStoredExecutionEvent*)event;
+
+///
+///  Returns NO if the immediate upload of the blocked event should be skipped.
+///
+- (BOOL)shouldUploadEvent:(SNTStoredExecutionEvent*)event;
+
+@end
+
+NS_ASSUME_NONNULL_END
@@ -0,0 +1,144 @@
+/// Copyright 2026 North Pole Security, Inc.
+///
+/// Licensed under the Apache License, Version 2.0 (the "License");
+/// you may not use this file except in compliance with the License.
+/// You may obtain a copy of the License at
+///
+///     http://www.apache.org/licenses/LICENSE-2.0
+///
+/// Unless required by applicable law or agreed to in writing, software
+/// distributed under the License is distributed on an "AS IS" BASIS,
+/// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+/// See the License for the specific language governing permissions and
+/// limitations under the License.
+
+
+#import "Source/santad/SNTCleanSyncWarmup.h"
+
+#import "Source/common/SNTCommonEnums.h"
+#import "Source/common/SNTLogging.h"
+
+@interface SNTCleanSyncWarmup ()
+@property dispatch_queue_t q;
+@property NSDate* endDate;
+@property NSUInteger notificationLimit;
+@property NSUInteger uploadLimit;
+@property NSUInteger notificationCount;
+@property NSUInteger uploadCount;
+@property NSUInteger suppressedNotifications;
+@property NSUInteger suppressedUploads;
+@property NSMutableSet<NSString*>* notifiedHashes;
+@end
+
+@implementation SNTCleanSyncWarmup
+
++ (instancetype)sharedWarmup {
+  static SNTCleanSyncWarmup* warmup;
+  static dispatch_once_t onceToken;
+  dispatch_once(&onceToken, ^{
+    warmup = [[SNTCleanSyncWarmup alloc] init];
+  });
+  return warmup;
+}
+
+- (instancetype)init {
+  self = [super init];
+  if (self) {
+    _q = dispatch_queue_create("com.northpolesec.santa.daemon.clean_sync_warmup",
+                               DISPATCH_QUEUE_SERIAL);
+    _notifiedHashes = [NSMutableSet set];
+  }
+  return self;
+}
+
+- (void)startWithDuration:(NSTimeInterval)duration
+        notificationLimit:(NSUInteger)notificationLimit
+              uploadLimit:(NSUInteger)uploadLimit {
+  [self startWithDuration:duration
+        notificationLimit:notificationLimit
+              uploadLimit:uploadLimit
+                      now:[NSDate date]];
+}
+
+- (void)startWithDuration:(NSTimeInterval)duration
+        notificationLimit:(NSUInteger)notificationLimit
+              uploadLimit:(NSUInteger)uploadLimit
+                      now:(NSDate*)now {
+  dispatch_sync(self.q, ^{
+    [self endSerializedIfExpired:[NSDate distantFuture]];
+    if (duration <= 0) return;
+
+    LOGI(@"Clean sync warm-up: throttling unknown binary notifications and uploads for %.0fs",
+         duration);
+    self.endDate = [now dateByAddingTimeInterval:duration];
+    self.notificationLimit = notificationLimit;
+    self.uploadLimit = uploadLimit;
+  });
+}
+
+- (BOOL)shouldNotifyForEvent:(SNTStoredExecutionEvent*)event {
+  return [self shouldNotifyForEvent:event now:[NSDate date]];
+}
+
+- (BOOL)shouldNotifyForEvent:(SNTStoredExecutionEvent*)event now:(NSDate*)now {
+  // A held execution is waiting on the user's response, it must always be shown.
+  if (event.decision != SNTEventStateBlockUnknown || event.holdAndAsk) return YES;
+
+  __block BOOL notify = YES;
+  dispatch_sync(self.q, ^{
+    if (![self activeSerialized:now]) return;
+
+    // Coalesce repeated executions of the same binary into a single notification.
+    if (event.fileSHA256 && [self.notifiedHashes containsObject:event.fileSHA256]) {
+      notify = NO;
+    } else if (self.notificationCount >= self.notificationLimit) {
+      notify = NO;
+    } else {
+      self.notificationCount++;
+      if (event.fileSHA256) [self.notifiedHashes addObject:event.fileSHA256];
+    }
+    if (!notify) self.suppressedNotifications++;
+  });
+  return notify;
+}
+
+- (BOOL)shouldUploadEvent:(SNTStoredExecutionEvent*)event {
+  return [self shouldUploadEvent:event now:[NSDate date]];
+}
+
+- (BOOL)shouldUploadEvent:(SNTStoredExecutionEvent*)event now:(NSDate*)now {
+  if (event.decision != SNTEventStateBlockUnknown) return YES;
+
+  __block BOOL upload = YES;
+  dispatch_sync(self.q, ^{
+    if (![self activeSerialized:now]) return;
+
+    if (self.uploadCount >= self.uploadLimit) {
+      upload = NO;
+      self.suppressedUploads++;
+    } else {
+      self.uploadCount++;
+    }
+  });
+  return upload;
+}
+
+- (BOOL)activeSerialized:(NSDate*)now {
+  [self endSerializedIfExpired:now];
+  return self.endDate != nil;
+}
+
+- (void)endSerializedIfExpired:(NSDate*)now {
+  if (!self.endDate || [now compare:self.endDate] == NSOrderedAscending) return;
+
+  LOGI(@"Clean sync warm-up finished: suppressed %lu notifications and %lu immediate uploads",
+       self.suppressedNotifications, self.suppressedUploads);
+  self.endDate = nil;
+  self.notificationCount = 0;
+  self.uploadCount = 0;
+  self.suppressedNotifications = 0;
+  self.suppressedUploads = 0;
+  [self.notifiedHashes removeAllObjects];
+}
+
+@end
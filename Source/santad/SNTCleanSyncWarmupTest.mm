@@ -0,0 +1,137 @@
+/// Copyright 2026 North Pole Security, Inc.
+///
+/// Licensed under the Apache License, Version 2.0 (the "License");
+/// you may not use this file except in compliance with the License.
+/// You may obtain a copy of the License at
+///
+///     http://www.apache.org/licenses/LICENSE-2.0
+///
+/// Unless required by applicable law or agreed to in writing, software
+/// distributed under the License is distributed on an "AS IS" BASIS,
+/// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+/// See the License for the specific language governing permissions and
+/// limitations under the License.
+
+
+#import <Foundation/Foundation.h>
+#import <XCTest/XCTest.h>
+
+#import "Source/common/SNTCommonEnums.h"
+#import "Source/common/SNTStoredExecutionEvent.h"
+#import "Source/santad/SNTCleanSyncWarmup.h"
+
+@interface SNTCleanSyncWarmup (Testing)
+- (void)startWithDuration:(NSTimeInterval)duration
+        notificationLimit:(NSUInteger)notificationLimit
+              uploadLimit:(NSUInteger)uploadLimit
+                      now:(NSDate*)now;
+- (BOOL)shouldNotifyForEvent:(SNTStoredExecutionEvent*)event now:(NSDate*)now;
+- (BOOL)shouldUploadEvent:(SNTStoredExecutionEvent*)event now:(NSDate*)now;
+@end
+
+@interface SNTCleanSyncWarmupTest : XCTestCase
+@property SNTCleanSyncWarmup* sut;
+@property NSDate* start;
+@end
+
+@implementation SNTCleanSyncWarmupTest
+
+- (void)setUp {
+  [super setUp];
+  self.sut = [[SNTCleanSyncWarmup alloc] init];
+  self.start = [NSDate date];
+}
+
+- (SNTStoredExecutionEvent*)eventWithHash:(NSString*)hash decision:(SNTEventState)decision {
+  SNTStoredExecutionEvent* event = [[SNTStoredExecutionEvent alloc] init];
+  event.fileSHA256 = hash;
+  event.decision = decision;
+  return event;
+}
+
+- (SNTStoredExecutionEvent*)unknownEvent:(int)i {
+  return [self eventWithHash:[NSString stringWithFormat:@"%064d", i]
+                    decision:SNTEventStateBlockUnknown];
+}
+
+- (NSDate*)after:(NSTimeInterval)seconds {
+  return [self.start dateByAddingTimeInterval:seconds];
+}
+
+- (void)testNoThrottlingWithoutWarmup {
+  for (int i = 0; i < 50; ++i) {
+    XCTAssertTrue([self.sut shouldNotifyForEvent:[self unknownEvent:0] now:[self after:i]]);
+    XCTAssertTrue([self.sut shouldUploadEvent:[self unknownEvent:i] now:[self after:i]]);
+  }
+}
+
+- (void)testNotificationsThrottledDuringWarmup {
+  [self.sut startWithDuration:60 notificationLimit:2 uploadLimit:100 now:self.start];
+
+  // Repeat executions of a notified binary are coalesced.
+  XCTAssertTrue([self.sut shouldNotifyForEvent:[self unknownEvent:1] now:[self after:1]]);
+  XCTAssertFalse([self.sut shouldNotifyForEvent:[self unknownEvent:1] now:[self after:2]]);
+  XCTAssertTrue([self.sut shouldNotifyForEvent:[self unknownEvent:2] now:[self after:3]]);
+
+  // The limit has been reached.
+  XCTAssertFalse([self.sut shouldNotifyForEvent:[self unknownEvent:3] now:[self after:4]]);
+  XCTAssertFalse([self.sut shouldNotifyForEvent:[self unknownEvent:4] now:[self after:5]]);
+
+  // Blocks due to a rule and held executions are never throttled.
+  XCTAssertTrue([self.sut
+      shouldNotifyForEvent:[self eventWithHash:@"a" decision:SNTEventStateBlockBinary]
+                       now:[self after:6]]);
+  SNTStoredExecutionEvent* held = [self unknownEvent:5];
+  held.holdAndAsk = YES;
+  XCTAssertTrue([self.sut shouldNotifyForEvent:held now:[self after:7]]);
+}
+
+- (void)testUploadsThrottledDuringWarmup {
+  [self.sut startWithDuration:60 notificationLimit:100 uploadLimit:3 now:self.start];
+
+  for (int i = 0; i < 3; ++i) {
+    XCTAssertTrue([self.sut shouldUploadEvent:[self unknownEvent:i] now:[self after:i]]);
+  }
+  for (int i = 3; i < 10; ++i) {
+    XCTAssertFalse([self.sut shouldUploadEvent:[self unknownEvent:i] now:[self after:i]]);
+  }
+
+  XCTAssertTrue([self.sut
+      shouldUploadEvent:[self eventWithHash:@"a" decision:SNTEventStateBlockCertificate]
+                    now:[self after:11]]);
+}
+
+- (void)testNormalBehaviorAfterWarmup {
+  [self.sut startWithDuration:60 notificationLimit:1 uploadLimit:1 now:self.start];
+
+  XCTAssertTrue([self.sut shouldNotifyForEvent:[self unknownEvent:1] now:[self after:1]]);
+  XCTAssertTrue([self.sut shouldUploadEvent:[self unknownEvent:1] now:[self after:1]]);
+  XCTAssertFalse([self.sut shouldNotifyForEvent:[self unknownEvent:2] now:[self after:59]]);
+  XCTAssertFalse([self.sut shouldUploadEvent:[self unknownEvent:2] now:[self after:59]]);
+
+  // Once the window has passed nothing is throttled, including binaries already notified about.
+  for (int i = 0; i < 10; ++i) {
+    XCTAssertTrue([self.sut shouldNotifyForEvent:[self unknownEvent:1] now:[self after:60 + i]]);
+    XCTAssertTrue([self.sut shouldUploadEvent:[self unknownEvent:i] now:[self after:60 + i]]);
+  }
+}
+
+- (void)testRestartResetsLimits {
+  [self.sut startWithDuration:60 notificationLimit:1 uploadLimit:1 now:self.start];
+  XCTAssertTrue([self.sut shouldNotifyForEvent:[self unknownEvent:1] now:[self after:1]]);
+  XCTAssertFalse([self.sut shouldNotifyForEvent:[self unknownEvent:2] now:[self after:2]]);
+
+  // Another clean sync restarts the window with fresh limits.
+  [self.sut startWithDuration:60 notificationLimit:1 uploadLimit:1 now:[self after:3]];
+  XCTAssertTrue([self.sut shouldNotifyForEvent:[self unknownEvent:2] now:[self after:4]]);
+  XCTAssertFalse([self.sut shouldNotifyForEvent:[self unknownEvent:3] now:[self after:62]]);
+  XCTAssertTrue([self.sut shouldNotifyForEvent:[self unknownEvent:3] now:[self after:63]]);
+
+  // A zero duration, i.e. the warm-up being disabled, ends the current window.
+  [self.sut startWithDuration:60 notificationLimit:0 uploadLimit:0 now:[self after:100]];
+  XCTAssertFalse([self.sut shouldUploadEvent:[self unknownEvent:4] now:[self after:101]]);
+  [self.sut startWithDuration:0 notificationLimit:0 uploadLimit:0 now:[self after:102]];
+  XCTAssertTrue([self.sut shouldUploadEvent:[self unknownEvent:4] now:[self after:103]]);
+}
+
+@end
@@ -0,0 +1,36 @@
+/// Copyright 2026 North Pole Security, Inc.
+///
+/// Licensed under the Apache License, Version 2.0 (the "License");
+/// you may not use this file except in compliance with the License.
+/// You may obtain a copy of the License at
+///
+///     http://www.apache.org/licenses/LICENSE-2.0
+///
+/// Unless required by applicable law or agreed to in writing, software
+/// distributed under the License is distributed on an "AS IS" BASIS,
+/// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+/// See the License for the specific language governing permissions and
+/// limitations under the License.
+
+#include <EndpointSecurity/EndpointSecurity.h>
+#import <Foundation/Foundation.h>
+
+#import "Source/common/SNTCommonEnums.h"
+#include "Source/santad/ProcessControl.h"
+
+///
+///  Responds to running processes whose code signature becomes invalid. A process that was allowed
+///  to execute losing its valid signature can mean that code was injected into it, so depending on
+///  the CodeSignatureInvalidationResponse config the process is logged, suspended or killed.
+///
+@interface SNTCodeSignatureMonitor : NSObject
+
+- (instancetype)initWithProcessControlBlock:(santa::ProcessControlBlock)processControlBlock;
+
+///
+///  Apply the configured response to a process whose code signature was invalidated, as reported
+///  by an ES_EVENT_TYPE_NOTIFY_CS_INVALIDATED event. Returns the response that was applied.
+///
+- (SNTCodeSignatureInvalidationResponse)handleInvalidatedProcess:(const es_process_t*)proc;
+
+@end
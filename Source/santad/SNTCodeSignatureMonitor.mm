@@ -0,0 +1,74 @@
+/// Copyright 2026 North Pole Security, Inc.
+///
+/// Licensed under the Apache License, Version 2.0 (the "License");
+/// you may not use this file except in compliance with the License.
+/// You may obtain a copy of the License at
+///
+///     http://www.apache.org/licenses/LICENSE-2.0
+///
+/// Unless required by applicable law or agreed to in writing, software
+/// distributed under the License is distributed on an "AS IS" BASIS,
+/// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+/// See the License for the specific language governing permissions and
+/// limitations under the License.
+
+#import "Source/santad/SNTCodeSignatureMonitor.h"
+
+#include <Kernel/kern/cs_blobs.h>
+#include <unistd.h>
+
+#include "Source/common/AuditUtilities.h"
+#import "Source/common/SNTConfigurator.h"
+#import "Source/common/SNTLogging.h"
+#include "Source/common/String.h"
+
+@interface SNTCodeSignatureMonitor ()
+@property(nonatomic) santa::ProcessControlBlock processControlBlock;
+@end
+
+@implementation SNTCodeSignatureMonitor
+
+- (instancetype)initWithProcessControlBlock:(santa::ProcessControlBlock)processControlBlock {
+  self = [super init];
+  if (self) {
+    _processControlBlock = processControlBlock;
+  }
+  return self;
+}
+
+- (SNTCodeSignatureInvalidationResponse)handleInvalidatedProcess:(const es_process_t*)proc {
+  SNTCodeSignatureInvalidationResponse response =
+      [[SNTConfigurator configurator] codeSignatureInvalidationResponse];
+  if (response == SNTCodeSignatureInvalidationResponseNone) return response;
+
+  // The event is only sent once CS_VALID has been cleared, but check the flags on the process
+  // rather than relying on the event type alone.
+  if (proc->codesigning_flags & CS_VALID) return SNTCodeSignatureInvalidationResponseNone;
+
+  pid_t pid = santa::Pid(proc->audit_token);
+  NSString* path = santa::StringTokenToNSString(proc->executable->path);
+
+  // Suspending or killing a platform binary or santad itself could take down the whole host, so
+  // these are only ever logged.
+  if (proc->is_platform_binary || pid == getpid()) {
+    response = SNTCodeSignatureInvalidationResponseLog;
+  }
+
+  switch (response) {
+    case SNTCodeSignatureInvalidationResponseBlock:
+      LOGW(@"Code signature of %@ (pid %d) became invalid at runtime, suspending process", path,
+           pid);
+      self.processControlBlock(pid, santa::ProcessControl::Suspend);
+      break;
+    case SNTCodeSignatureInvalidationResponseTerminate:
+      LOGW(@"Code signature of %@ (pid %d) became invalid at runtime, killing process", path, pid);
+      self.processControlBlock(pid, santa::ProcessControl::Kill);
+      break;
+    default:
+      LOGW(@"Code signature of %@ (pid %d) became invalid at runtime", path, pid);
+      break;
+  }
+  return response;
+}
+
+@end
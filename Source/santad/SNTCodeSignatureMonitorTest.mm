@@ -0,0 +1,127 @@
+/// Copyright 2026 North Pole Security, Inc.
+///
+/// Licensed under the Apache License, Version 2.0 (the "License");
+/// you may not use this file except in compliance with the License.
+/// You may obtain a copy of the License at
+///
+///     http://www.apache.org/licenses/LICENSE-2.0
+///
+/// Unless required by applicable law or agreed to in writing, software
+/// distributed under the License is distributed on an "AS IS" BASIS,
+/// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+/// See the License for the specific language governing permissions and
+/// limitations under the License.
+
+#include <EndpointSecurity/EndpointSecurity.h>
+#import <Foundation/Foundation.h>
+#include <Kernel/kern/cs_blobs.h>
+#import <OCMock/OCMock.h>
+#import <XCTest/XCTest.h>
+
+#include <optional>
+
+#import "Source/common/SNTCommonEnums.h"
+#import "Source/common/SNTConfigurator.h"
+#include "Source/common/TestUtils.h"
+#include "Source/santad/ProcessControl.h"
+#import "Source/santad/SNTCodeSignatureMonitor.h"
+
+@interface SNTCodeSignatureMonitorTest : XCTestCase
+@property id mockConfigurator;
+@property SNTCodeSignatureMonitor* monitor;
+@end
+
+@implementation SNTCodeSignatureMonitorTest {
+  std::optional<santa::ProcessControl> _lastControl;
+  pid_t _lastPid;
+}
+
+- (void)setUp {
+  self.mockConfigurator = OCMClassMock([SNTConfigurator class]);
+  OCMStub([self.mockConfigurator configurator]).andReturn(self.mockConfigurator);
+
+  _lastControl = std::nullopt;
+  _lastPid = 0;
+  __weak SNTCodeSignatureMonitorTest* weakSelf = self;
+  self.monitor = [[SNTCodeSignatureMonitor alloc]
+      initWithProcessControlBlock:^bool(pid_t pid, santa::ProcessControl control) {
+        SNTCodeSignatureMonitorTest* strongSelf = weakSelf;
+        strongSelf->_lastPid = pid;
+        strongSelf->_lastControl = control;
+        return true;
+      }];
+}
+
+- (void)tearDown {
+  [self.mockConfigurator stopMocking];
+}
+
+// Simulates an ES_EVENT_TYPE_NOTIFY_CS_INVALIDATED event for a signed process whose code signature
+// was valid at exec and has since become invalid, then returns the response the monitor applied.
+- (SNTCodeSignatureInvalidationResponse)invalidateWithResponse:
+                                            (SNTCodeSignatureInvalidationResponse)response
+                                                         flags:(uint32_t)flags
+                                                      platform:(BOOL)platform {
+  OCMStub([self.mockConfigurator codeSignatureInvalidationResponse]).andReturn(response);
+
+  es_file_t file = MakeESFile("/Applications/Allowed.app/Contents/MacOS/Allowed");
+  es_process_t proc = MakeESProcess(&file, MakeAuditToken(12345, 1));
+  proc.codesigning_flags = flags;
+  proc.is_platform_binary = platform;
+
+  return [self.monitor handleInvalidatedProcess:&proc];
+}
+
+- (void)testTerminateKillsProcess {
+  XCTAssertEqual([self invalidateWithResponse:SNTCodeSignatureInvalidationResponseTerminate
+                                        flags:CS_SIGNED
+                                     platform:NO],
+                 SNTCodeSignatureInvalidationResponseTerminate);
+  XCTAssertTrue(_lastControl.has_value());
+  XCTAssertEqual(*_lastControl, santa::ProcessControl::Kill);
+  XCTAssertEqual(_lastPid, 12345);
+}
+
+- (void)testBlockSuspendsProcess {
+  XCTAssertEqual([self invalidateWithResponse:SNTCodeSignatureInvalidationResponseBlock
+                                        flags:CS_SIGNED
+                                     platform:NO],
+                 SNTCodeSignatureInvalidationResponseBlock);
+  XCTAssertTrue(_lastControl.has_value());
+  XCTAssertEqual(*_lastControl, santa::ProcessControl::Suspend);
+  XCTAssertEqual(_lastPid, 12345);
+}
+
+- (void)testLogLeavesProcessRunning {
+  XCTAssertEqual([self invalidateWithResponse:SNTCodeSignatureInvalidationResponseLog
+                                        flags:CS_SIGNED
+                                     platform:NO],
+                 SNTCodeSignatureInvalidationResponseLog);
+  XCTAssertFalse(_lastControl.has_value());
+}
+
+- (void)testDisabledByDefault {
+  XCTAssertEqual([self invalidateWithResponse:SNTCodeSignatureInvalidationResponseNone
+                                        flags:CS_SIGNED
+                                     platform:NO],
+                 SNTCodeSignatureInvalidationResponseNone);
+  XCTAssertFalse(_lastControl.has_value());
+}
+
+- (void)testStillValidSignatureIsIgnored {
+  XCTAssertEqual([self invalidateWithResponse:SNTCodeSignatureInvalidationResponseTerminate
+                                        flags:CS_SIGNED | CS_VALID
+                                     platform:NO],
+                 SNTCodeSignatureInvalidationResponseNone);
+  XCTAssertFalse(_lastControl.has_value());
+}
+
+- (void)testPlatformBinaryIsOnlyLogged {
+  XCTAssertEqual([self invalidateWithResponse:SNTCodeSignatureInvalidationResponseTerminate
+                                        flags:CS_SIGNED
+                                     platform:YES],
+                 SNTCodeSignatureInvalidationResponseLog);
+  XCTAssertFalse(_lastControl.has_value());
+}
+
+@end
@@ -27,7 +27,9 @@
 #import "Source/common/SNTFileInfo.h"
 #import "Source/common/SNTLogging.h"
 #import "Source/common/SNTRule.h"
+#import "Source/common/SNTStoredRuleChangeAuditEvent.h"
 #include "Source/common/String.h"
+#import "Source/santad/DataLayer/SNTEventTable.h"
 #import "Source/santad/DataLayer/SNTRuleTable.h"
 #import "Source/santad/SNTDatabaseController.h"
 #import "Source/santad/SNTDecisionCache.h"
@@ -201,6 +203,23 @@ static constexpr std::string_view kIgnoredCompilerProcessPathPrefix = "/dev/";
   }
 }
 
+// Stores an audit event for a new transitive rule, attributed to the compiler that wrote the file.
+// The username is left for the sync server to resolve to avoid a directory lookup here.
+- (void)recordTransitiveRule:(SNTRule*)rule compiler:(const Message&)esMsg {
+  SNTProcessChain* compiler = [[SNTProcessChain alloc] init];
+  compiler.filePath = @(esMsg->process->executable->path.data);
+  compiler.pid = @(audit_token_to_pid(esMsg->process->audit_token));
+  compiler.pidversion = @(audit_token_to_pidversion(esMsg->process->audit_token));
+  compiler.executingUserID = @(audit_token_to_euid(esMsg->process->audit_token));
+
+  SNTStoredRuleChangeAuditEvent* event =
+      [[SNTStoredRuleChangeAuditEvent alloc] initWithSource:SNTRuleChangeAuditSourceTransitive
+                                                      rules:@[ rule ]
+                                                ruleCleanup:SNTRuleCleanupNone
+                                                    process:compiler];
+  [[SNTDatabaseController eventTable] addStoredEvent:event];
+}
+
 // Assume that this method is called only when we already know that the writing process is a
 // compiler.  It checks if the closed file is executable, and if so, transitively allowlists it.
 // The passed in message contains the pid of the writing process and path of closed file.
@@ -241,6 +260,11 @@ static constexpr std::string_view kIgnoredCompilerProcessPathPrefix = "/dev/";
         } else {
           logger->LogAllowlist(esMsg, santa::NSStringToUTF8StringView(targetFile.SHA256),
                                santa::NSStringToUTF8StringView(targetFile.path));
+
+          // Only new rules are audited, not timestamp updates of existing transitive rules.
+          if (!prevRule) {
+            [self recordTransitiveRule:rule compiler:esMsg];
+          }
         }
       }
     }
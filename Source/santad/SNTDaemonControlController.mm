@@ -15,10 +15,13 @@
 
 #import "Source/santad/SNTDaemonControlController.h"
 #include <errno.h>
+#include <fcntl.h>
+#include <libproc.h>
 #include <limits.h>
 #include <stdlib.h>
 #include <string.h>
 #include <sys/stat.h>
+#include <unistd.h>
 
 #import <Foundation/Foundation.h>
 #include <sys/qos.h>
@@ -28,7 +31,9 @@
 #import "Source/common/AccountLookup.h"
 #import "Source/common/MOLCodesignChecker.h"
 #import "Source/common/MOLXPCConnection.h"
+#include "Source/common/PathCanonicalization.h"
 #include "Source/common/Pinning.h"
+#import "Source/common/SNTAllowOnceToken.h"
 #import "Source/common/SNTCELFallbackRule.h"
 #import "Source/common/SNTCachedDecision.h"
 #import "Source/common/SNTCommonEnums.h"
@@ -45,6 +50,7 @@
 #import "Source/common/SNTRuleIdentifiers.h"
 #import "Source/common/SNTSandboxExecRequest.h"
 #import "Source/common/SNTStoredExecutionEvent.h"
+#import "Source/common/SNTStoredRuleChangeAuditEvent.h"
 #import "Source/common/SNTStoredTemporaryAdminModeAuditEvent.h"
 #import "Source/common/SNTStoredTemporaryMonitorModeAuditEvent.h"
 #import "Source/common/SNTStrengthify.h"
@@ -57,12 +63,20 @@
 #import "Source/common/ne/SNTSyncNetworkExtensionSettings.h"
 #include "Source/santad/AdminGroupMembership.h"
 #include "Source/santad/AdminUserState.h"
+#import "Source/santad/DataLayer/SNTDecisionTable.h"
 #import "Source/santad/DataLayer/SNTEventTable.h"
 #import "Source/santad/DataLayer/SNTRuleTable.h"
 #include "Source/santad/KillingMachine.h"
+#import "Source/santad/SNTAllowOnceStore.h"
+#import "Source/santad/SNTApprovalTracker.h"
+#import "Source/santad/SNTCleanSyncWarmup.h"
 #import "Source/santad/SNTDatabaseController.h"
+#import "Source/santad/SNTDecisionHistory.h"
+#import "Source/santad/SNTExecutionCounts.h"
 #import "Source/santad/SNTNetworkExtensionQueue.h"
 #import "Source/santad/SNTNotificationQueue.h"
+#import "Source/santad/SNTRuleApplicationDeferral.h"
+#import "Source/santad/SNTRuleSyncWindow.h"
 #import "Source/santad/SNTSyncdQueue.h"
 #include "Source/santad/TemporaryAdminMode.h"
 #include "Source/santad/TemporaryMonitorMode.h"
@@ -111,6 +125,20 @@ double watchdogRAMPeak = 0;
 
 @end
 
+// Key under which rule updates from the sync service are held back. See
+// SNTRuleApplicationDeferral deferApplication:forKey:thresholdPercent:maxDeferral:.
+static NSString* const kDeferredSyncRuleUpdateKey = @"SyncService";
+
+static BOOL IsBlockRule(SNTRule* rule) {
+  switch (rule.state) {
+    case SNTRuleStateBlock:
+    case SNTRuleStateSilentBlock:
+    case SNTRuleStateSilentBlockGUI:
+    case SNTRuleStateSilentBlockTTY: return YES;
+    default: return NO;
+  }
+}
+
 // Resolve a username from a uid for the Temporary Admin Mode audit trail. Returns
 // an empty string when the uid does not resolve. Uses the thread-safe lookup
 // because this runs on concurrent XPC handler threads.
@@ -127,6 +155,7 @@ static NSString* TAMUsernameForUID(uid_t uid) {
   std::unique_ptr<santa::AdminUserState> _adminUserState;
   std::shared_ptr<santa::SandboxExpectations> _sandboxExpectations;
   std::shared_ptr<santa::SNTBinaryUploadController> _binaryUploadController;
+  std::shared_ptr<santa::MaintenanceWindowMonitor> _maintenanceWindowMonitor;
 }
 
 - (instancetype)initWithNotificationQueue:(SNTNotificationQueue*)notQueue
@@ -142,11 +171,14 @@ static NSString* TAMUsernameForUID(uid_t uid) {
                           checkCacheBlock:(SNTAction (^)(SantaVnode))checkCacheBlock
                        metricsExportBlock:(void (^)(void (^reply)(BOOL)))metricsExportBlock
                    binaryUploadController:
-                       (std::shared_ptr<santa::SNTBinaryUploadController>)binaryUploadController {
+                       (std::shared_ptr<santa::SNTBinaryUploadController>)binaryUploadController
+                 maintenanceWindowMonitor:(std::shared_ptr<santa::MaintenanceWindowMonitor>)
+                                              maintenanceWindowMonitor {
   self = [super init];
   if (self) {
     _logger = logger;
     _binaryUploadController = std::move(binaryUploadController);
+    _maintenanceWindowMonitor = std::move(maintenanceWindowMonitor);
     _watchItems = std::move(watchItems);
     _sandboxExpectations = std::move(sandboxExpectations);
     _notQueue = notQueue;
@@ -292,25 +324,132 @@ static NSString* TAMUsernameForUID(uid_t uid) {
   }
 #endif
 
+  // On a heavily loaded host hold back updates from the sync service until the load drops. Block
+  // rules are still applied straight away so that new blocks are never delayed; they are applied
+  // again, harmlessly, with the rest of the update.
+  //
+  // The held back update is only kept in memory and may still fail, so the sync service is told it
+  // was deferred rather than applied. The sync then fails and is retried, and the server doesn't
+  // record rules the host hasn't applied. Each retry sends the update again, so it replaces the one
+  // already held back instead of queueing another copy.
+  NSUInteger thresholdPercent = [[SNTConfigurator configurator] ruleApplicationDeferralLoadPercent];
+  SNTRuleApplicationDeferral* deferral = [SNTRuleApplicationDeferral sharedDeferral];
+  if (source == SNTRuleAddSourceSyncService &&
+      [deferral shouldDeferWithThresholdPercent:thresholdPercent]) {
+    NSMutableArray<SNTRule*>* blockRules = [NSMutableArray array];
+    for (SNTRule* rule in executionRules) {
+      if (IsBlockRule(rule)) [blockRules addObject:rule];
+    }
+
+    NSArray<NSError*>* errors;
+    if (blockRules.count && ![self applyExecutionRules:blockRules
+                                       fileAccessRules:@[]
+                                      networkFlowRules:@[]
+                                               signals:@[]
+                                           ruleCleanup:SNTRuleCleanupNone
+                                                source:source
+                                                errors:&errors]) {
+      LOGE(@"Failed to apply the block rules of a deferred rule update: %@", errors);
+    }
+
+    WEAKIFY(self);
+    [deferral
+        deferApplication:^{
+          STRONGIFY(self);
+          NSArray<NSError*>* deferredErrors;
+          if (![self applyExecutionRules:executionRules
+                         fileAccessRules:fileAccessRules
+                        networkFlowRules:networkFlowRules
+                                 signals:signals
+                             ruleCleanup:cleanupType
+                                  source:source
+                                  errors:&deferredErrors]) {
+            LOGE(@"Failed to apply deferred rule update: %@", deferredErrors);
+          }
+        }
+                  forKey:kDeferredSyncRuleUpdateKey
+        thresholdPercent:thresholdPercent
+             maxDeferral:[[SNTConfigurator configurator] ruleApplicationMaxDeferralSec]];
+
+    NSError* deferredError;
+    [SNTError populateError:&deferredError
+                   withCode:SNTErrorCodeRuleUpdateDeferred
+                    message:@"Rule update deferred"
+                     detail:@"The system is heavily loaded, the update will be applied later"];
+    reply(NO, [(errors ?: @[]) arrayByAddingObject:deferredError]);
+    return;
+  }
+
+  NSArray<NSError*>* errors;
+  BOOL success = [self applyExecutionRules:executionRules
+                           fileAccessRules:fileAccessRules
+                          networkFlowRules:networkFlowRules
+                                   signals:signals
+                               ruleCleanup:cleanupType
+                                    source:source
+                                    errors:&errors];
+  reply(success, errors);
+}
+
+- (BOOL)applyExecutionRules:(NSArray<SNTRule*>*)executionRules
+            fileAccessRules:(NSArray<SNTFileAccessRule*>*)fileAccessRules
+           networkFlowRules:(NSArray<SNTNetworkFlowRule*>*)networkFlowRules
+                    signals:(NSArray<SNTSignal*>*)signals
+                ruleCleanup:(SNTRuleCleanup)cleanupType
+                     source:(SNTRuleAddSource)source
+                     errors:(NSArray<NSError*>**)errors {
   SNTRuleTable* ruleTable = [SNTDatabaseController ruleTable];
 
+  // Executions decided while an update from the sync service is being written are handled
+  // according to SyncInFlightExecutionPolicy. The window only covers applying the update, not the
+  // time it is held back by RuleApplicationDeferralLoadPercent.
+  SNTRuleSyncWindow* syncWindow =
+      (source == SNTRuleAddSourceSyncService) ? [SNTRuleSyncWindow sharedWindow] : nil;
+  [syncWindow begin];
+
   // If any rules are added that are not plain allowlist rules, then flush decision cache.
   // In particular, the addition of allowlist compiler rules should cause a cache flush.
   // We also flush cache if a allowlist compiler rule is replaced with a allowlist rule.
   BOOL flushCache = ((cleanupType != SNTRuleCleanupNone) || (fileAccessRules.count > 0) ||
                      [ruleTable addedRulesShouldFlushDecisionCache:executionRules]);
 
-  NSArray<NSError*>* errors;
   BOOL success = [ruleTable addExecutionRules:executionRules
                               fileAccessRules:fileAccessRules
                              networkFlowRules:networkFlowRules
                                       signals:signals
                                   ruleCleanup:cleanupType
-                                       errors:&errors];
+                                       errors:errors];
 
   // Whenever we add rules, we can also check for and remove outdated transitive rules.
   [ruleTable removeOutdatedTransitiveRules];
 
+  // Keep an audit trail of rules changed with santactl, they are uploaded with the next sync.
+  if (success && source == SNTRuleAddSourceSantactl &&
+      (executionRules.count || cleanupType != SNTRuleCleanupNone)) {
+    [self recordSantactlRuleChange:executionRules ruleCleanup:cleanupType];
+  }
+
+  // Record how long it took the server to approve anything that was previously blocked. The
+  // approval events are uploaded with the next sync.
+  if (success && source == SNTRuleAddSourceSyncService) {
+    NSArray<SNTStoredExecutionEvent*>* approvals =
+        [[SNTApprovalTracker sharedTracker] approvalEventsForRules:executionRules
+                                                        approvedAt:[NSDate date]];
+    if (approvals.count) {
+      [[SNTDatabaseController eventTable] addStoredEvents:approvals];
+    }
+  }
+
+  // A clean sync can leave many binaries on a busy host without a rule at once, so briefly hold
+  // back the resulting notifications and uploads.
+  if (success && source == SNTRuleAddSourceSyncService && cleanupType != SNTRuleCleanupNone) {
+    SNTConfigurator* configurator = [SNTConfigurator configurator];
+    NSUInteger notificationLimit = configurator.cleanSyncWarmupNotificationLimit;
+    [[SNTCleanSyncWarmup sharedWarmup] startWithDuration:configurator.cleanSyncWarmupSec
+                                       notificationLimit:notificationLimit
+                                             uploadLimit:configurator.cleanSyncWarmupUploadLimit];
+  }
+
   // The actual cache flushing happens after the new rules have been added to the database.
   if (flushCache) {
     LOGI(@"Flushing caches");
@@ -319,6 +458,60 @@ static NSString* TAMUsernameForUID(uid_t uid) {
     }
   }
 
+  [syncWindow end];
+  return success;
+}
+
+// Stores an audit event for a rule change made with santactl. The change is attributed to the
+// process on the other end of the XPC connection, so this must be called while handling the
+// santactl request.
+- (void)recordSantactlRuleChange:(NSArray<SNTRule*>*)rules ruleCleanup:(SNTRuleCleanup)cleanupType {
+  SNTProcessChain* process = [[SNTProcessChain alloc] init];
+  audit_token_t peer = [MOLXPCConnection currentPeerAuditToken];
+  pid_t pid = audit_token_to_pid(peer);
+  if (pid > 0) {
+    uid_t uid = audit_token_to_euid(peer);
+    process.pid = @(pid);
+    process.pidversion = @(audit_token_to_pidversion(peer));
+    process.executingUserID = @(uid);
+    std::optional<std::string> username = santa::account::UsernameForUID(uid);
+    if (username.has_value()) {
+      process.executingUser = @(username->c_str());
+    }
+
+    char path[PROC_PIDPATHINFO_MAXSIZE];
+    if (proc_pidpath(pid, path, sizeof(path)) > 0) {
+      process.filePath = @(path);
+    }
+  }
+
+  SNTStoredRuleChangeAuditEvent* event =
+      [[SNTStoredRuleChangeAuditEvent alloc] initWithSource:SNTRuleChangeAuditSourceSantactl
+                                                      rules:rules
+                                                ruleCleanup:cleanupType
+                                                    process:process];
+  [[SNTDatabaseController eventTable] addStoredEvent:event];
+  [self.syncdQueue addStoredEvent:event];
+}
+
+- (void)databaseRuleReplaceRulesForRuleSource:(NSString*)source
+                                   precedence:(NSInteger)precedence
+                                        rules:(NSArray<SNTRule*>*)rules
+                                        reply:(void (^)(BOOL, NSArray<NSError*>* error))reply {
+  NSArray<NSError*>* errors;
+  BOOL success = [[SNTDatabaseController ruleTable] replaceRulesForRuleSource:source
+                                                                    precedence:precedence
+                                                                         rules:rules
+                                                                        errors:&errors];
+
+  // Any rule from a source may override a rule the decision cache was built from.
+  if (success) {
+    LOGI(@"Flushing caches");
+    if (self.flushCacheBlock) {
+      self.flushCacheBlock(FlushCacheMode::kAllCaches, FlushCacheReason::kRulesChanged);
+    }
+  }
+
   reply(success, errors);
 }
 
@@ -385,11 +578,11 @@ static NSString* TAMUsernameForUID(uid_t uid) {
     return;
   }
 
-  // Check blocked path regex (mirrors SNTPolicyProcessor.fileIsScopeBlocked:)
-  NSRegularExpression* blockedRe = config.blockedPathRegex;
-  if (blockedRe && [blockedRe numberOfMatchesInString:filePath
-                                              options:0
-                                                range:NSMakeRange(0, filePath.length)]) {
+  NSString* resolvedPath =
+      config.canonicalizeExecutablePaths ? santa::CanonicalPath(filePath) : nil;
+
+  // Check blocked path regex (mirrors SNTPolicyProcessor.fileIsScopeBlocked:resolvedPath:)
+  if (santa::PathOrResolvedPathMatches(config.blockedPathRegex, filePath, resolvedPath)) {
     reply(nil, @"Blocked (Regex)");
     return;
   }
@@ -399,16 +592,13 @@ static NSString* TAMUsernameForUID(uid_t uid) {
   // Both require reading/validating the file at runtime. The fileinfo output
   // already has dedicated "Page Zero" and "Validation" keys for these checks.
 
-  // Check allowed path regex (mirrors SNTPolicyProcessor.fileIsScopeAllowed:)
-  NSRegularExpression* allowedRe = config.allowedPathRegex;
-  if (allowedRe && [allowedRe numberOfMatchesInString:filePath
-                                              options:0
-                                                range:NSMakeRange(0, filePath.length)]) {
+  // Check allowed path regex (mirrors SNTPolicyProcessor.fileIsScopeAllowed:resolvedPath:)
+  if (santa::PathOrResolvedPathMatches(config.allowedPathRegex, filePath, resolvedPath)) {
     reply(nil, @"Allowed (Regex)");
     return;
   }
 
-  // Note: SNTPolicyProcessor.fileIsScopeAllowed: also returns "Not a Mach-O"
+  // Note: SNTPolicyProcessor.fileIsScopeAllowed:resolvedPath: also returns "Not a Mach-O"
   // for non-Mach-O files, effectively allowing them. This check requires an
   // SNTFileInfo object (not just a path). The fileinfo "Type" key already
   // shows whether the file is a Mach-O, so users can cross-reference.
@@ -519,6 +709,10 @@ static NSString* TAMUsernameForUID(uid_t uid) {
   reply([[SNTConfigurator configurator] syncTypeRequired]);
 }
 
+- (void)machineID:(void (^)(NSString*))reply {
+  reply([[SNTConfigurator configurator] machineID]);
+}
+
 - (void)removableMediaAction:(void (^)(SNTRemovableMediaAction))reply {
   reply([[SNTConfigurator configurator] removableMediaAction]);
 }
@@ -554,6 +748,22 @@ static NSString* TAMUsernameForUID(uid_t uid) {
   reply([SNTConfigurator configurator].exportConfig != nil);
 }
 
+- (void)fullDiskAccessGranted:(void (^)(BOOL))reply {
+  // The TCC database can only be opened by processes that have been granted
+  // Full Disk Access, which makes it a reliable probe.
+  int fd = open("/Library/Application Support/com.apple.TCC/TCC.db", O_RDONLY);
+  if (fd >= 0) close(fd);
+  reply(fd >= 0);
+}
+
+- (void)effectiveConfigHash:(void (^)(NSString*))reply {
+  reply([SNTConfigurator configurator].effectiveConfigHash);
+}
+
+- (void)parsedConfigProfile:(void (^)(NSDictionary*))reply {
+  reply([[SNTConfigurator configurator] parsedConfigProfile]);
+}
+
 - (void)enableBundles:(void (^)(BOOL))reply {
   reply([SNTConfigurator configurator].enableBundles);
 }
@@ -602,8 +812,13 @@ static NSString* TAMUsernameForUID(uid_t uid) {
       [configurator setSyncServerTemporaryAdminPolicy:val];
     }];
 
+    // Switching out of Monitor mode may be deferred until a maintenance window closes.
     [result clientMode:^(SNTClientMode m) {
-      [configurator setSyncServerClientMode:m];
+      if (self->_maintenanceWindowMonitor) {
+        self->_maintenanceWindowMonitor->ClientModeReceived(m);
+      } else {
+        [configurator setSyncServerClientMode:m];
+      }
     }];
 
     [result syncType:^(SNTSyncType val) {
@@ -724,6 +939,15 @@ static NSString* TAMUsernameForUID(uid_t uid) {
     [result pushNotificationsFullSyncInterval:^(NSUInteger val) {
       [configurator setSyncServerPushNotificationsFullSyncInterval:val];
     }];
+
+    [result telemetrySampleRate:^(double val) {
+      [configurator setSyncServerTelemetrySampleRate:val];
+    }];
+
+    [result generatedMachineID:^(NSString* val) {
+      LOGI(@"Using generated machine ID %@ after a machine ID conflict", val);
+      [configurator setGeneratedMachineID:val];
+    }];
   }];
 
   // Mode-transition enforcement and GUI notification run after the batch so
@@ -828,11 +1052,105 @@ static NSString* TAMUsernameForUID(uid_t uid) {
   }];
 }
 
+- (void)syncCircuitBreakerStatus:(void (^)(NSDictionary*))reply {
+  // Like pushNotificationStatus:, use a dedicated connection so the request is not queued behind
+  // a long running sync.
+  MOLXPCConnection* conn = [SNTXPCSyncServiceInterface configuredConnection];
+  [conn resume];
+  [conn.remoteObjectProxy syncCircuitBreakerStatus:^(NSDictionary* status) {
+    reply(status);
+  }];
+}
+
+- (void)pushServerCertificateExpiry:(void (^)(NSDate*))reply {
+  MOLXPCConnection* conn = [SNTXPCSyncServiceInterface configuredConnection];
+  [conn resume];
+  [conn.remoteObjectProxy pushServerCertificateExpiry:^(NSDate* notAfter) {
+    reply(notAfter);
+  }];
+}
+
+- (void)ruleSourcesStatus:(void (^)(NSArray<NSDictionary*>*))reply {
+  MOLXPCConnection* conn = [SNTXPCSyncServiceInterface configuredConnection];
+  [conn resume];
+  [conn.remoteObjectProxy ruleSourcesStatus:^(NSArray<NSDictionary*>* status) {
+    reply(status);
+  }];
+}
+
 - (void)postRuleSyncNotificationForApplication:(NSString*)app reply:(void (^)(void))reply {
   [[self.notQueue.notifierConnection remoteObjectProxy] postRuleSyncNotificationForApplication:app];
   reply();
 }
 
+- (void)recentDecisionsFrom:(NSDate*)start
+                         to:(NSDate*)end
+                      limit:(NSUInteger)limit
+                      reply:(void (^)(NSArray<SNTStoredExecutionEvent*>*))reply {
+  reply([[SNTDecisionHistory sharedHistory] decisionsFrom:start to:end limit:limit]);
+}
+
+- (void)executionRulesForReconcile:(void (^)(NSArray<SNTRule*>*))reply {
+  reply([[SNTDatabaseController ruleTable] retrieveAllExecutionRules]);
+}
+
+- (void)executionCounts:(void (^)(NSDictionary<NSString*, NSDictionary*>*))reply {
+  reply([[SNTExecutionCounts sharedCounts] snapshot]);
+}
+
+- (void)resetExecutionCounts:(NSDictionary<NSString*, NSDictionary*>*)snapshot {
+  [[SNTExecutionCounts sharedCounts] resetWithSnapshot:snapshot];
+}
+
+- (void)redeemPushRuleBundleID:(NSString*)bundleID
+                expirationDate:(NSDate*)expirationDate
+                         reply:(void (^)(NSError*))reply {
+  SNTConfigurator* config = [SNTConfigurator configurator];
+  @synchronized(self) {
+    NSDate* now = [NSDate date];
+    NSMutableDictionary<NSString*, NSDate*>* consumed =
+        [[config savedConsumedPushRuleBundles] mutableCopy] ?: [NSMutableDictionary dictionary];
+
+    if (consumed[bundleID]) {
+      reply([SNTError createErrorWithFormat:@"Rule bundle %@ has already been applied", bundleID]);
+      return;
+    }
+
+    // Expired bundles are rejected by the sync service, so their IDs can be forgotten.
+    NSSet<NSString*>* expired =
+        [consumed keysOfEntriesPassingTest:^BOOL(NSString* key, NSDate* exp, BOOL* stop) {
+          return ![exp isKindOfClass:[NSDate class]] || [exp compare:now] != NSOrderedDescending;
+        }];
+    [consumed removeObjectsForKeys:expired.allObjects];
+    consumed[bundleID] = expirationDate;
+
+    if (![config persistConsumedPushRuleBundles:consumed]) {
+      reply([SNTError createErrorWithFormat:@"Unable to record rule bundle %@ as applied",
+                                            bundleID]);
+      return;
+    }
+  }
+  reply(nil);
+}
+
+- (void)queryDecisionDatabase:(NSString*)sql
+                        reply:(void (^)(NSArray<NSString*>*, NSArray<NSArray*>*, NSError*))reply {
+  if (![[SNTConfigurator configurator] enableDecisionDatabase]) {
+    reply(nil, nil,
+          [SNTError createErrorWithCode:SNTErrorCodeDecisionDatabaseDisabled
+                                 format:@"The decision database is not enabled. "
+                                        @"Set EnableDecisionDatabase to record decisions."]);
+    return;
+  }
+
+  NSArray<NSString*>* columns;
+  NSError* error;
+  NSArray<NSArray*>* rows = [[SNTDatabaseController decisionTable] executeReadOnlyQuery:sql
+                                                                                columns:&columns
+                                                                                  error:&error];
+  reply(columns, rows, error);
+}
+
 ///
 ///  Used by SantaGUI sync the offending event and potentially all the related events,
 ///  if the sync server has not seen them before.
@@ -1268,6 +1586,47 @@ static const char* const kAllowedCanonicalBundlePaths[] = {
   return _adminUserState.get();
 }
 
+#pragma mark Allow Once Ops
+
+- (void)allowOnceWithToken:(NSString*)tokenString reply:(void (^)(NSError*))reply {
+  SNTConfigurator* config = [SNTConfigurator configurator];
+  NSData* publicKey = config.allowOnceTokenPublicKey;
+  if (!publicKey) {
+    reply([SNTError createErrorWithFormat:@"One-time allow tokens are not configured"]);
+    return;
+  }
+
+  NSError* err;
+  SNTAllowOnceToken* token = [SNTAllowOnceToken tokenWithString:tokenString
+                                                      publicKey:publicKey
+                                                    currentDate:[NSDate date]
+                                                          error:&err];
+  if (!token) {
+    reply(err);
+    return;
+  }
+
+  if (token.machineID && ![token.machineID isEqualToString:config.machineID]) {
+    reply([SNTError createErrorWithFormat:@"Token was not issued for this machine"]);
+    return;
+  }
+
+  if (![[SNTAllowOnceStore sharedStore] addGrantForRule:token.rule
+                                                tokenID:token.tokenID
+                                         expirationDate:token.expirationDate
+                                                  error:&err]) {
+    reply(err);
+    return;
+  }
+
+  LOGI(@"Redeemed one-time allow token %@ for %@", token.tokenID,
+       [token.rule stringifyWithColor:NO]);
+
+  // A previous block of this binary may still be cached.
+  self.flushCacheBlock(FlushCacheMode::kAllCaches, FlushCacheReason::kRulesChanged);
+  reply(nil);
+}
+
 #pragma mark Network Extension Ops
 
 - (void)reportNetworkFlows:(NSArray<SNDProcessFlows*>*)processFlows
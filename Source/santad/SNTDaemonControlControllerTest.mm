@@ -23,13 +23,18 @@
 
 #import "Source/common/AuditUtilities.h"
 #import "Source/common/MOLXPCConnection.h"
+#import "Source/common/SNTConfigurator.h"
 #import "Source/common/SNTError.h"
 #import "Source/common/SNTNetworkFlowRule.h"
 #import "Source/common/SNTRule.h"
 #import "Source/common/SNTRuleIdentifiers.h"
 #import "Source/common/SNTSandboxExecRequest.h"
+#import "Source/common/SNTStoredRuleChangeAuditEvent.h"
+#import "Source/santad/DataLayer/SNTEventTable.h"
 #import "Source/santad/DataLayer/SNTRuleTable.h"
 #import "Source/santad/SNTDatabaseController.h"
+#import "Source/santad/SNTRuleApplicationDeferral.h"
+#import "Source/santad/SNTRuleSyncWindow.h"
 #include "Source/santad/SandboxExpectations.h"
 
 using santa::SandboxExpectations;
@@ -49,7 +54,12 @@ static NSString* const kBinarySHA256 =
 @property id mockDatabaseController;
 @property id mockRuleTable;
 @property id mockMOLXPC;
+@property id mockConfigurator;
+@property id mockDeferral;
 @property SNTDaemonControlController* sut;
+@property BOOL replySuccess;
+@property NSArray<NSError*>* replyErrors;
+@property NSMutableArray<NSNumber*>* inFlightDuringApply;
 @end
 
 @implementation SNTDaemonControlControllerTest {
@@ -86,7 +96,8 @@ static NSString* const kBinarySHA256 =
       }
       metricsExportBlock:^(void (^)(BOOL)) {
       }
-      binaryUploadController:nullptr];
+      binaryUploadController:nullptr
+      maintenanceWindowMonitor:nullptr];
 }
 
 - (void)tearDown {
@@ -94,6 +105,8 @@ static NSString* const kBinarySHA256 =
   [self.mockDatabaseController stopMocking];
   [self.mockRuleTable stopMocking];
   [self.mockMOLXPC stopMocking];
+  [self.mockConfigurator stopMocking];
+  [self.mockDeferral stopMocking];
   [super tearDown];
 }
 
@@ -331,6 +344,347 @@ static NSString* const kBinarySHA256 =
   XCTAssertEqual(forwarded.firstObject.ruleId, 101);
 }
 
+// ---- Rule add: deferral under high load -------------------------------
+
+// Stubs the rule table to record the execution rules of each add, then adds a block rule and an
+// allow rule from source with a deferral threshold of 100%. The deferral reads the system load
+// from *load, so a test can change it after the rules have been added. The reply is kept in
+// replySuccess and replyErrors, and whether the sync window was open during each add in
+// inFlightDuringApply.
+- (SNTRuleApplicationDeferral*)addRulesFromSource:(SNTRuleAddSource)source
+                                             load:(double*)load
+                                       recordedTo:(NSMutableArray<NSArray<SNTRule*>*>*)recorded
+                                        blockRule:(SNTRule*)blockRule
+                                        allowRule:(SNTRule*)allowRule {
+  self.mockConfigurator = OCMClassMock([SNTConfigurator class]);
+  OCMStub([self.mockConfigurator configurator]).andReturn(self.mockConfigurator);
+  OCMStub([self.mockConfigurator ruleApplicationDeferralLoadPercent]).andReturn(100);
+  OCMStub([self.mockConfigurator ruleApplicationMaxDeferralSec]).andReturn(600);
+
+  SNTRuleApplicationDeferral* deferral = [[SNTRuleApplicationDeferral alloc]
+      initWithLoadBlock:^double {
+        return *load;
+      }
+           pollInterval:0.05];
+  self.mockDeferral = OCMClassMock([SNTRuleApplicationDeferral class]);
+  OCMStub([self.mockDeferral sharedDeferral]).andReturn(deferral);
+
+  OCMStub([self.mockRuleTable addExecutionRules:OCMOCK_ANY
+                                fileAccessRules:OCMOCK_ANY
+                               networkFlowRules:OCMOCK_ANY
+                                        signals:OCMOCK_ANY
+                                    ruleCleanup:SNTRuleCleanupNone
+                                         errors:[OCMArg anyObjectRef]])
+      .andDo(^(NSInvocation* inv) {
+        __unsafe_unretained NSArray<SNTRule*>* captured = nil;
+        [inv getArgument:&captured atIndex:2];
+        @synchronized(recorded) {
+          [recorded addObject:captured];
+          [self.inFlightDuringApply addObject:@([[SNTRuleSyncWindow sharedWindow] isInFlight])];
+        }
+      })
+      .andReturn(YES);
+
+  [self.sut databaseRuleAddExecutionRules:@[ blockRule, allowRule ]
+                          fileAccessRules:@[]
+                         networkFlowRules:@[]
+                                  signals:@[]
+                              ruleCleanup:SNTRuleCleanupNone
+                                   source:source
+                                    reply:^(BOOL success, NSArray<NSError*>* errors) {
+                                      self.replySuccess = success;
+                                      self.replyErrors = errors;
+                                    }];
+  return deferral;
+}
+
+- (void)testRuleAddUnderHighLoadAppliesBlockRulesImmediately {
+  SNTRule* blockRule = [[SNTRule alloc] initWithIdentifier:kBinarySHA256
+                                                     state:SNTRuleStateBlock
+                                                      type:SNTRuleTypeBinary];
+  SNTRule* allowRule = [[SNTRule alloc] initWithIdentifier:@"EQHXZ8M8AV"
+                                                     state:SNTRuleStateAllow
+                                                      type:SNTRuleTypeTeamID];
+  double load = 500;
+  NSMutableArray<NSArray<SNTRule*>*>* recorded = [NSMutableArray array];
+
+  SNTRuleApplicationDeferral* deferral = [self addRulesFromSource:SNTRuleAddSourceSyncService
+                                                             load:&load
+                                                       recordedTo:recorded
+                                                        blockRule:blockRule
+                                                        allowRule:allowRule];
+
+  // Only the block rule has been applied, the full update is waiting. The sync service is told the
+  // update was deferred, so that it retries the sync instead of reporting it as applied.
+  @synchronized(recorded) {
+    XCTAssertEqualObjects(recorded, (@[ @[ blockRule ] ]));
+  }
+  XCTAssertFalse(self.replySuccess);
+  XCTAssertEqual(self.replyErrors.lastObject.code, SNTErrorCodeRuleUpdateDeferred);
+  XCTAssertEqual([deferral pendingCount], 1u);
+  XCTAssertFalse([[SNTRuleSyncWindow sharedWindow] isInFlight]);
+
+  // Stay above the threshold for a few polls, nothing more is applied.
+  [NSThread sleepForTimeInterval:0.2];
+  XCTAssertEqual([deferral pendingCount], 1u);
+
+  // Once the load drops the rest of the update is applied.
+  load = 50;
+  NSDate* deadline = [NSDate dateWithTimeIntervalSinceNow:5];
+  while ([deferral pendingCount] && [deadline timeIntervalSinceNow] > 0) {
+    [NSThread sleepForTimeInterval:0.05];
+  }
+  XCTAssertEqual([deferral pendingCount], 0u);
+  @synchronized(recorded) {
+    XCTAssertEqualObjects(recorded, (@[ @[ blockRule ], @[ blockRule, allowRule ] ]));
+  }
+  XCTAssertFalse([[SNTRuleSyncWindow sharedWindow] isInFlight]);
+}
+
+- (void)testDeferredRuleAddDoesNotHoldFailSafeWindowOpen {
+  // With FailSafe, SNTPolicyProcessor blocks every execution without a rule while the window is
+  // open, so it must not stay open for as long as the update is held back.
+  SNTRule* blockRule = [[SNTRule alloc] initWithIdentifier:kBinarySHA256
+                                                     state:SNTRuleStateBlock
+                                                      type:SNTRuleTypeBinary];
+  SNTRule* allowRule = [[SNTRule alloc] initWithIdentifier:@"EQHXZ8M8AV"
+                                                     state:SNTRuleStateAllow
+                                                      type:SNTRuleTypeTeamID];
+  double load = 500;
+  NSMutableArray<NSArray<SNTRule*>*>* recorded = [NSMutableArray array];
+  self.inFlightDuringApply = [NSMutableArray array];
+
+  SNTRuleApplicationDeferral* deferral = [self addRulesFromSource:SNTRuleAddSourceSyncService
+                                                             load:&load
+                                                       recordedTo:recorded
+                                                        blockRule:blockRule
+                                                        allowRule:allowRule];
+  XCTAssertEqual([deferral pendingCount], 1u);
+
+  // The window was open while the block rules were applied and is closed while the rest waits.
+  [NSThread sleepForTimeInterval:0.2];
+  XCTAssertFalse([[SNTRuleSyncWindow sharedWindow] isInFlight]);
+
+  load = 50;
+  NSDate* deadline = [NSDate dateWithTimeIntervalSinceNow:5];
+  while ([deferral pendingCount] && [deadline timeIntervalSinceNow] > 0) {
+    [NSThread sleepForTimeInterval:0.05];
+  }
+  XCTAssertEqual([deferral pendingCount], 0u);
+
+  // It was open again while the deferred update was applied.
+  @synchronized(recorded) {
+    XCTAssertEqualObjects(self.inFlightDuringApply, (@[ @YES, @YES ]));
+  }
+  XCTAssertFalse([[SNTRuleSyncWindow sharedWindow] isInFlight]);
+}
+
+- (void)testRetriedSyncReplacesDeferredRuleAdd {
+  SNTRule* blockRule = [[SNTRule alloc] initWithIdentifier:kBinarySHA256
+                                                     state:SNTRuleStateBlock
+                                                      type:SNTRuleTypeBinary];
+  SNTRule* allowRule = [[SNTRule alloc] initWithIdentifier:@"EQHXZ8M8AV"
+                                                     state:SNTRuleStateAllow
+                                                      type:SNTRuleTypeTeamID];
+  SNTRule* retriedAllowRule = [[SNTRule alloc] initWithIdentifier:@"ABCDEFGHIJ"
+                                                            state:SNTRuleStateAllow
+                                                             type:SNTRuleTypeTeamID];
+  double load = 500;
+  NSMutableArray<NSArray<SNTRule*>*>* recorded = [NSMutableArray array];
+
+  SNTRuleApplicationDeferral* deferral = [self addRulesFromSource:SNTRuleAddSourceSyncService
+                                                             load:&load
+                                                       recordedTo:recorded
+                                                        blockRule:blockRule
+                                                        allowRule:allowRule];
+  XCTAssertEqual([deferral pendingCount], 1u);
+
+  // The sync service retries while the first update is still held back.
+  [self.sut databaseRuleAddExecutionRules:@[ blockRule, retriedAllowRule ]
+                          fileAccessRules:@[]
+                         networkFlowRules:@[]
+                                  signals:@[]
+                              ruleCleanup:SNTRuleCleanupNone
+                                   source:SNTRuleAddSourceSyncService
+                                    reply:^(BOOL success, NSArray<NSError*>* errors) {
+                                      self.replySuccess = success;
+                                      self.replyErrors = errors;
+                                    }];
+  XCTAssertFalse(self.replySuccess);
+  XCTAssertEqual(self.replyErrors.lastObject.code, SNTErrorCodeRuleUpdateDeferred);
+  XCTAssertEqual([deferral pendingCount], 1u);
+
+  // Only the latest update is applied once the load drops.
+  load = 50;
+  NSDate* deadline = [NSDate dateWithTimeIntervalSinceNow:5];
+  while ([deferral pendingCount] && [deadline timeIntervalSinceNow] > 0) {
+    [NSThread sleepForTimeInterval:0.05];
+  }
+  XCTAssertEqual([deferral pendingCount], 0u);
+  @synchronized(recorded) {
+    XCTAssertEqualObjects(
+        recorded, (@[ @[ blockRule ], @[ blockRule ], @[ blockRule, retriedAllowRule ] ]));
+  }
+}
+
+- (void)testRuleAddUnderLowLoadIsNotDeferred {
+  SNTRule* blockRule = [[SNTRule alloc] initWithIdentifier:kBinarySHA256
+                                                     state:SNTRuleStateBlock
+                                                      type:SNTRuleTypeBinary];
+  SNTRule* allowRule = [[SNTRule alloc] initWithIdentifier:@"EQHXZ8M8AV"
+                                                     state:SNTRuleStateAllow
+                                                      type:SNTRuleTypeTeamID];
+  double load = 50;
+  NSMutableArray<NSArray<SNTRule*>*>* recorded = [NSMutableArray array];
+
+  SNTRuleApplicationDeferral* deferral = [self addRulesFromSource:SNTRuleAddSourceSyncService
+                                                             load:&load
+                                                       recordedTo:recorded
+                                                        blockRule:blockRule
+                                                        allowRule:allowRule];
+
+  XCTAssertEqualObjects(recorded, (@[ @[ blockRule, allowRule ] ]));
+  XCTAssertEqual([deferral pendingCount], 0u);
+  XCTAssertTrue(self.replySuccess);
+  XCTAssertFalse([[SNTRuleSyncWindow sharedWindow] isInFlight]);
+}
+
+- (void)testRuleAddFromSyncServiceIsInFlightUntilApplied {
+  self.mockConfigurator = OCMClassMock([SNTConfigurator class]);
+  OCMStub([self.mockConfigurator configurator]).andReturn(self.mockConfigurator);
+
+  __block BOOL inFlightDuringApply = NO;
+  OCMStub([self.mockRuleTable addExecutionRules:OCMOCK_ANY
+                                fileAccessRules:OCMOCK_ANY
+                               networkFlowRules:OCMOCK_ANY
+                                        signals:OCMOCK_ANY
+                                    ruleCleanup:SNTRuleCleanupNone
+                                         errors:[OCMArg anyObjectRef]])
+      .andDo(^(NSInvocation* inv) {
+        inFlightDuringApply = [[SNTRuleSyncWindow sharedWindow] isInFlight];
+      })
+      .andReturn(NO);
+
+  __block BOOL replySuccess = YES;
+  [self.sut databaseRuleAddExecutionRules:@[]
+                          fileAccessRules:@[]
+                         networkFlowRules:@[]
+                                  signals:@[]
+                              ruleCleanup:SNTRuleCleanupNone
+                                   source:SNTRuleAddSourceSyncService
+                                    reply:^(BOOL success, NSArray<NSError*>* errors) {
+                                      replySuccess = success;
+                                    }];
+
+  // The window ends even when applying the rules fails.
+  XCTAssertTrue(inFlightDuringApply);
+  XCTAssertFalse(replySuccess);
+  XCTAssertFalse([[SNTRuleSyncWindow sharedWindow] isInFlight]);
+}
+
+- (void)testRuleAddFromSantactlIsNotDeferred {
+  SNTRule* blockRule = [[SNTRule alloc] initWithIdentifier:kBinarySHA256
+                                                     state:SNTRuleStateBlock
+                                                      type:SNTRuleTypeBinary];
+  SNTRule* allowRule = [[SNTRule alloc] initWithIdentifier:@"EQHXZ8M8AV"
+                                                     state:SNTRuleStateAllow
+                                                      type:SNTRuleTypeTeamID];
+  double load = 500;
+  NSMutableArray<NSArray<SNTRule*>*>* recorded = [NSMutableArray array];
+
+  SNTRuleApplicationDeferral* deferral = [self addRulesFromSource:SNTRuleAddSourceSantactl
+                                                             load:&load
+                                                       recordedTo:recorded
+                                                        blockRule:blockRule
+                                                        allowRule:allowRule];
+
+  XCTAssertEqualObjects(recorded, (@[ @[ blockRule, allowRule ] ]));
+  XCTAssertEqual([deferral pendingCount], 0u);
+  XCTAssertTrue(self.replySuccess);
+}
+
+// ---- Rule add: audit events for local changes -------------------------
+
+- (void)testRuleAddFromSantactlStoresAuditEvent {
+  self.mockConfigurator = OCMClassMock([SNTConfigurator class]);
+  OCMStub([self.mockConfigurator configurator]).andReturn(self.mockConfigurator);
+
+  id mockEventTable = OCMClassMock([SNTEventTable class]);
+  OCMStub([self.mockDatabaseController eventTable]).andReturn(mockEventTable);
+  OCMStub([self.mockRuleTable addExecutionRules:OCMOCK_ANY
+                                fileAccessRules:OCMOCK_ANY
+                               networkFlowRules:OCMOCK_ANY
+                                        signals:OCMOCK_ANY
+                                    ruleCleanup:SNTRuleCleanupNone
+                                         errors:[OCMArg anyObjectRef]])
+      .andReturn(YES);
+  [self stubPeerAuditTokenPid:4321 pidver:2];
+
+  __block SNTStoredRuleChangeAuditEvent* stored;
+  OCMStub([mockEventTable
+              addStoredEvent:[OCMArg isKindOfClass:[SNTStoredRuleChangeAuditEvent class]]])
+      .andDo(^(NSInvocation* inv) {
+        __unsafe_unretained SNTStoredRuleChangeAuditEvent* captured = nil;
+        [inv getArgument:&captured atIndex:2];
+        stored = captured;
+      });
+
+  SNTRule* rule = [[SNTRule alloc] initWithIdentifier:kBinarySHA256
+                                                state:SNTRuleStateAllow
+                                                 type:SNTRuleTypeBinary];
+  [self.sut databaseRuleAddExecutionRules:@[ rule ]
+                          fileAccessRules:@[]
+                         networkFlowRules:@[]
+                                  signals:@[]
+                              ruleCleanup:SNTRuleCleanupNone
+                                   source:SNTRuleAddSourceSantactl
+                                    reply:^(BOOL success, NSArray<NSError*>* errors) {
+                                      XCTAssertTrue(success);
+                                    }];
+
+  XCTAssertNotNil(stored);
+  XCTAssertEqual(stored.source, SNTRuleChangeAuditSourceSantactl);
+  XCTAssertEqual(stored.ruleCleanup, SNTRuleCleanupNone);
+  XCTAssertEqualObjects(stored.rules, @[ rule ]);
+  XCTAssertEqualObjects(stored.process.pid, @(4321));
+  XCTAssertEqualObjects(stored.process.pidversion, @(2));
+  XCTAssertNotNil(stored.process.executingUserID);
+
+  [mockEventTable stopMocking];
+}
+
+- (void)testRuleAddFromSyncServiceDoesNotStoreAuditEvent {
+  self.mockConfigurator = OCMClassMock([SNTConfigurator class]);
+  OCMStub([self.mockConfigurator configurator]).andReturn(self.mockConfigurator);
+
+  id mockEventTable = OCMClassMock([SNTEventTable class]);
+  OCMStub([self.mockDatabaseController eventTable]).andReturn(mockEventTable);
+  OCMStub([self.mockRuleTable addExecutionRules:OCMOCK_ANY
+                                fileAccessRules:OCMOCK_ANY
+                               networkFlowRules:OCMOCK_ANY
+                                        signals:OCMOCK_ANY
+                                    ruleCleanup:SNTRuleCleanupNone
+                                         errors:[OCMArg anyObjectRef]])
+      .andReturn(YES);
+  OCMReject([mockEventTable
+      addStoredEvent:[OCMArg isKindOfClass:[SNTStoredRuleChangeAuditEvent class]]]);
+
+  SNTRule* rule = [[SNTRule alloc] initWithIdentifier:kBinarySHA256
+                                                state:SNTRuleStateAllow
+                                                 type:SNTRuleTypeBinary];
+  [self.sut databaseRuleAddExecutionRules:@[ rule ]
+                          fileAccessRules:@[]
+                         networkFlowRules:@[]
+                                  signals:@[]
+                              ruleCleanup:SNTRuleCleanupNone
+                                   source:SNTRuleAddSourceSyncService
+                                    reply:^(BOOL success, NSArray<NSError*>* errors) {
+                                      XCTAssertTrue(success);
+                                    }];
+
+  [mockEventTable stopMocking];
+}
+
 // ---- databaseRulesHash: returns four hashes --------------------------
 
 - (void)testDatabaseRulesHashReturnsFourHashes {
@@ -373,4 +727,43 @@ static NSString* const kBinarySHA256 =
   XCTAssertEqual(got.networkFlow, 7);
 }
 
+// ---- Push rule bundles: each ID is redeemed once -----------------------
+
+- (void)testRedeemPushRuleBundleRejectsReuse {
+  self.mockConfigurator = OCMClassMock([SNTConfigurator class]);
+  OCMStub([self.mockConfigurator configurator]).andReturn(self.mockConfigurator);
+  __block NSDictionary* persisted = @{@"expired" : [NSDate dateWithTimeIntervalSinceNow:-1]};
+  OCMStub([self.mockConfigurator savedConsumedPushRuleBundles]).andDo(^(NSInvocation* inv) {
+    NSDictionary* saved = persisted;
+    [inv setReturnValue:&saved];
+  });
+  OCMStub([self.mockConfigurator persistConsumedPushRuleBundles:[OCMArg any]])
+      .andDo(^(NSInvocation* inv) {
+        __unsafe_unretained NSDictionary* bundles;
+        [inv getArgument:&bundles atIndex:2];
+        persisted = bundles;
+        BOOL ret = YES;
+        [inv setReturnValue:&ret];
+      });
+
+  NSDate* exp = [NSDate dateWithTimeIntervalSinceNow:600];
+  __block NSError* first = [NSError errorWithDomain:@"unset" code:0 userInfo:nil];
+  [self.sut redeemPushRuleBundleID:@"bundle-1"
+                    expirationDate:exp
+                             reply:^(NSError* err) {
+                               first = err;
+                             }];
+  XCTAssertNil(first);
+  // The expired ID is pruned.
+  XCTAssertEqualObjects(persisted, @{@"bundle-1" : exp});
+
+  __block NSError* second;
+  [self.sut redeemPushRuleBundleID:@"bundle-1"
+                    expirationDate:exp
+                             reply:^(NSError* err) {
+                               second = err;
+                             }];
+  XCTAssertNotNil(second);
+}
+
 @end
@@ -19,6 +19,7 @@
 #import <fmdb/FMDB.h>
 
 @class SNTConfigTable;
+@class SNTDecisionTable;
 @class SNTEventTable;
 @class SNTRuleTable;
 
@@ -35,6 +36,12 @@
 + (SNTEventTable*)eventTable;
 + (SNTRuleTable*)ruleTable;
 
+///
+///  Returns the decision table. Unlike the other tables, the decision database uses write-ahead
+///  logging so that queries don't block decisions from being recorded.
+///
++ (SNTDecisionTable*)decisionTable;
+
 + (NSString* const)databasePath;
 
 @end
@@ -19,6 +19,7 @@
 #include <sys/types.h>
 
 #import "Source/common/SNTLogging.h"
+#import "Source/santad/DataLayer/SNTDecisionTable.h"
 #import "Source/santad/DataLayer/SNTEventTable.h"
 #import "Source/santad/DataLayer/SNTRuleTable.h"
 
@@ -27,6 +28,7 @@
 static NSString* const kDatabasePath = @"/var/db/santa";
 static NSString* const kRulesDatabaseName = @"rules.db";
 static NSString* const kEventsDatabaseName = @"events.db";
+static NSString* const kDecisionsDatabaseName = @"decisions.db";
 
 + (NSString* const)databasePath {
   return kDatabasePath;
@@ -79,6 +81,35 @@ static NSString* const kEventsDatabaseName = @"events.db";
   return ruleDatabase;
 }
 
++ (SNTDecisionTable*)decisionTable {
+  static SNTDecisionTable* decisionDatabase;
+  static dispatch_once_t decisionDatabaseToken;
+  dispatch_once(&decisionDatabaseToken, ^{
+    [self createDatabasePath];
+    NSString* fullPath = [[SNTDatabaseController databasePath]
+        stringByAppendingPathComponent:kDecisionsDatabaseName];
+    FMDatabaseQueue* dbq = [[FMDatabaseQueue alloc] initWithPath:fullPath];
+
+    [dbq inDatabase:^(FMDatabase* db) {
+#ifndef DEBUG
+      db.logsErrors = NO;
+#endif
+      // The journal mode is stored in the database file, synchronous must be set per connection.
+      [db executeStatements:@"PRAGMA journal_mode=WAL; PRAGMA synchronous=NORMAL;"];
+    }];
+
+    decisionDatabase = [[SNTDecisionTable alloc] initWithDatabaseQueue:dbq];
+
+    // The write-ahead log and shared memory files hold decisions too.
+    for (NSString* suffix in @[ @"", @"-wal", @"-shm" ]) {
+      NSString* path = [fullPath stringByAppendingString:suffix];
+      chown([path UTF8String], 0, 0);
+      chmod([path UTF8String], 0600);
+    }
+  });
+  return decisionDatabase;
+}
+
 #pragma mark - Private
 
 /// Create the folder that contains the databases
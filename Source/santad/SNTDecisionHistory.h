@@ -0,0 +1,50 @@
+/// Copyright 2026 North Pole Security, Inc.
+///
+/// Licensed under the Apache License, Version 2.0 (the "License");
+/// you may not use this file except in compliance with the License.
+/// You may obtain a copy of the License at
+///
+///     http://www.apache.org/licenses/LICENSE-2.0
+///
+/// Unless required by applicable law or agreed to in writing, software
+/// distributed under the License is distributed on an "AS IS" BASIS,
+/// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+/// See the License for the specific language governing permissions and
+/// limitations under the License.
+
+
+#import <Foundation/Foundation.h>
+
+#import "Source/common/SNTStoredExecutionEvent.h"
+
+NS_ASSUME_NONNULL_BEGIN
+
+///
+///  Remembers the most recent execution decisions, allowed or blocked, so they can be exported on
+///  demand (e.g. when requested over the push connection). Unlike the events database, every
+///  decision is kept regardless of event upload settings, but only in memory and only up to the
+///  capacity, after which the oldest decisions are dropped.
+///
+@interface SNTDecisionHistory : NSObject
+
++ (instancetype)sharedHistory;
+
+- (instancetype)initWithCapacity:(size_t)capacity NS_DESIGNATED_INITIALIZER;
+- (instancetype)init NS_UNAVAILABLE;
+
+///
+///  Record an execution decision. The event's occurrenceDate must be set.
+///
+- (void)recordDecision:(SNTStoredExecutionEvent*)event;
+
+///
+///  Returns the recorded decisions that occurred in [start, end], newest first, and at most limit
+///  of them.
+///
+- (NSArray<SNTStoredExecutionEvent*>*)decisionsFrom:(NSDate*)start
+                                                 to:(NSDate*)end
+                                              limit:(NSUInteger)limit;
+
+@end
+
+NS_ASSUME_NONNULL_END
@@ -0,0 +1,72 @@
+/// Copyright 2026 North Pole Security, Inc.
+///
+/// Licensed under the Apache License, Version 2.0 (the "License");
+/// you may not use this file except in compliance with the License.
+/// You may obtain a copy of the License at
+///
+///     http://www.apache.org/licenses/LICENSE-2.0
+///
+/// Unless required by applicable law or agreed to in writing, software
+/// distributed under the License is distributed on an "AS IS" BASIS,
+/// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+/// See the License for the specific language governing permissions and
+/// limitations under the License.
+
+
+#import "Source/santad/SNTDecisionHistory.h"
+
+#include <memory>
+
+#include "Source/common/RingBuffer.h"
+
+static const size_t kDefaultDecisionHistoryCapacity = 2048;
+
+@implementation SNTDecisionHistory {
+  std::unique_ptr<santa::RingBuffer<SNTStoredExecutionEvent*>> _decisions;
+  dispatch_queue_t _q;
+}
+
++ (instancetype)sharedHistory {
+  static SNTDecisionHistory* history;
+  static dispatch_once_t onceToken;
+  dispatch_once(&onceToken, ^{
+    history = [[SNTDecisionHistory alloc] initWithCapacity:kDefaultDecisionHistoryCapacity];
+  });
+  return history;
+}
+
+- (instancetype)initWithCapacity:(size_t)capacity {
+  self = [super init];
+  if (self) {
+    _decisions = std::make_unique<santa::RingBuffer<SNTStoredExecutionEvent*>>(capacity);
+    _q = dispatch_queue_create("com.northpolesec.santa.daemon.decision_history",
+                               DISPATCH_QUEUE_SERIAL_WITH_AUTORELEASE_POOL);
+  }
+  return self;
+}
+
+- (void)recordDecision:(SNTStoredExecutionEvent*)event {
+  dispatch_sync(_q, ^{
+    _decisions->Enqueue(event);
+  });
+}
+
+- (NSArray<SNTStoredExecutionEvent*>*)decisionsFrom:(NSDate*)start
+                                                 to:(NSDate*)end
+                                              limit:(NSUInteger)limit {
+  NSMutableArray<SNTStoredExecutionEvent*>* decisions = [NSMutableArray array];
+
+  dispatch_sync(_q, ^{
+    // Decisions are recorded in order, so walk backwards to collect the newest first.
+    for (auto it = _decisions->end(); it != _decisions->begin() && decisions.count < limit;) {
+      SNTStoredExecutionEvent* event = *--it;
+      if ([event.occurrenceDate compare:end] == NSOrderedDescending) continue;
+      if ([event.occurrenceDate compare:start] == NSOrderedAscending) break;
+      [decisions addObject:event];
+    }
+  });
+
+  return decisions;
+}
+
+@end
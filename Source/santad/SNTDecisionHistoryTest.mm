@@ -0,0 +1,88 @@
+/// Copyright 2026 North Pole Security, Inc.
+///
+/// Licensed under the Apache License, Version 2.0 (the "License");
+/// you may not use this file except in compliance with the License.
+/// You may obtain a copy of the License at
+///
+///     http://www.apache.org/licenses/LICENSE-2.0
+///
+/// Unless required by applicable law or agreed to in writing, software
+/// distributed under the License is distributed on an "AS IS" BASIS,
+/// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+/// See the License for the specific language governing permissions and
+/// limitations under the License.
+
+
+#import "Source/santad/SNTDecisionHistory.h"
+
+#import <Foundation/Foundation.h>
+#import <XCTest/XCTest.h>
+
+#import "Source/common/SNTCommonEnums.h"
+#import "Source/common/SNTStoredExecutionEvent.h"
+
+@interface SNTDecisionHistoryTest : XCTestCase
+@property NSDate* baseDate;
+@end
+
+@implementation SNTDecisionHistoryTest
+
+- (void)setUp {
+  [super setUp];
+  self.baseDate = [NSDate dateWithTimeIntervalSince1970:1700000000];
+}
+
+- (SNTStoredExecutionEvent*)decisionAtOffset:(NSTimeInterval)offset {
+  SNTStoredExecutionEvent* se = [[SNTStoredExecutionEvent alloc] init];
+  se.filePath = [NSString stringWithFormat:@"/usr/local/bin/tool%d", (int)offset];
+  se.decision = SNTEventStateAllowBinary;
+  se.occurrenceDate = [self.baseDate dateByAddingTimeInterval:offset];
+  return se;
+}
+
+- (void)testDecisionsWithinRangeNewestFirst {
+  SNTDecisionHistory* sut = [[SNTDecisionHistory alloc] initWithCapacity:16];
+  for (int i = 0; i < 10; i++) {
+    [sut recordDecision:[self decisionAtOffset:i * 60]];
+  }
+
+  NSArray<SNTStoredExecutionEvent*>* got =
+      [sut decisionsFrom:[self.baseDate dateByAddingTimeInterval:120]
+                      to:[self.baseDate dateByAddingTimeInterval:300]
+                   limit:100];
+
+  XCTAssertEqual(got.count, 4);
+  XCTAssertEqualObjects(got.firstObject.filePath, @"/usr/local/bin/tool300");
+  XCTAssertEqualObjects(got.lastObject.filePath, @"/usr/local/bin/tool120");
+}
+
+- (void)testLimitKeepsNewest {
+  SNTDecisionHistory* sut = [[SNTDecisionHistory alloc] initWithCapacity:16];
+  for (int i = 0; i < 10; i++) {
+    [sut recordDecision:[self decisionAtOffset:i]];
+  }
+
+  NSArray<SNTStoredExecutionEvent*>* got = [sut decisionsFrom:self.baseDate
+                                                           to:[NSDate distantFuture]
+                                                        limit:3];
+
+  XCTAssertEqual(got.count, 3);
+  XCTAssertEqualObjects(got[0].filePath, @"/usr/local/bin/tool9");
+  XCTAssertEqualObjects(got[2].filePath, @"/usr/local/bin/tool7");
+}
+
+- (void)testOldestDecisionsAreDroppedAtCapacity {
+  SNTDecisionHistory* sut = [[SNTDecisionHistory alloc] initWithCapacity:4];
+  for (int i = 0; i < 10; i++) {
+    [sut recordDecision:[self decisionAtOffset:i]];
+  }
+
+  NSArray<SNTStoredExecutionEvent*>* got = [sut decisionsFrom:[NSDate distantPast]
+                                                           to:[NSDate distantFuture]
+                                                        limit:100];
+
+  XCTAssertEqual(got.count, 4);
+  XCTAssertEqualObjects(got.lastObject.filePath, @"/usr/local/bin/tool6");
+}
+
+@end
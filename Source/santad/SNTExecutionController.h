@@ -52,6 +52,12 @@ const static NSString* kBlockLongPath = @"BlockLongPath";
 const static NSString* kBlockCELFallback = @"BlockCELFallback";
 const static NSString* kAllowCELFallback = @"AllowCELFallback";
 const static NSString* kAllowPlatform = @"AllowPlatform";
+const static NSString* kBlockRequirement = @"BlockRequirement";
+const static NSString* kAllowRequirement = @"AllowRequirement";
+const static NSString* kBlockNetworkVolume = @"BlockNetworkVolume";
+const static NSString* kBlockDiskImage = @"BlockDiskImage";
+const static NSString* kBlockPathChurn = @"BlockPathChurn";
+const static NSString* kAllowOnce = @"AllowOnce";
 
 @class SNTCachedDecision;
 @class SNTEventTable;
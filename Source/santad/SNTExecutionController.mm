@@ -24,6 +24,7 @@
 
 #include <cstring>
 #include <memory>
+#include <optional>
 #include <set>
 #include <string>
 #include <utility>
@@ -31,6 +32,7 @@
 #include "Source/common/AccountLookup.h"
 #include "Source/common/BranchPrediction.h"
 #include "Source/common/CodeSigningIdentifierUtils.h"
+#include "Source/common/EventSampling.h"
 #import "Source/common/MOLCodesignChecker.h"
 #include "Source/common/PrefixTree.h"
 #import "Source/common/SNTBlockMessage.h"
@@ -43,6 +45,7 @@
 #import "Source/common/SNTMetricSet.h"
 #import "Source/common/SNTRule.h"
 #import "Source/common/SNTStoredExecutionEvent.h"
+#import "Source/common/SNTStoredRuleChangeAuditEvent.h"
 #include "Source/common/SantaCache.h"
 #include "Source/common/SantaVnode.h"
 #include "Source/common/String.h"
@@ -52,9 +55,16 @@
 #include "Source/common/processtree/process.h"
 #include "Source/common/processtree/process_tree.h"
 #include "Source/santad/CELActivation.h"
+#include "Source/santad/InterpreterScript.h"
+#import "Source/santad/DataLayer/SNTDecisionTable.h"
 #import "Source/santad/DataLayer/SNTEventTable.h"
 #import "Source/santad/DataLayer/SNTRuleTable.h"
+#import "Source/santad/SNTApprovalTracker.h"
+#import "Source/santad/SNTCleanSyncWarmup.h"
+#import "Source/santad/SNTDatabaseController.h"
 #import "Source/santad/SNTDecisionCache.h"
+#import "Source/santad/SNTDecisionHistory.h"
+#import "Source/santad/SNTExecutionCounts.h"
 #import "Source/santad/SNTNotificationQueue.h"
 #import "Source/santad/SNTSyncdQueue.h"
 #include "absl/synchronization/mutex.h"
@@ -90,11 +100,26 @@ static SNTEventState BlockToAllowDecision(SNTEventState blockDecision) {
     case SNTEventStateBlockSigningID: return SNTEventStateAllowSigningID;
     case SNTEventStateBlockCDHash: return SNTEventStateAllowCDHash;
     case SNTEventStateBlockCELFallback: return SNTEventStateAllowCELFallback;
+    case SNTEventStateBlockRequirement: return SNTEventStateAllowRequirement;
     case SNTEventStateBlockLongPath: return SNTEventStateAllowUnknown;  // No direct equivalent
+    case SNTEventStateBlockNetworkVolume: return SNTEventStateAllowUnknown;  // No direct equivalent
+    case SNTEventStateBlockDiskImage: return SNTEventStateAllowUnknown;      // No direct equivalent
+    case SNTEventStateBlockPathChurn: return SNTEventStateAllowUnknown;      // No direct equivalent
     default: return SNTEventStateAllowUnknown;
   }
 }
 
+// Returns true if a blocked event should be uploaded straight away rather than with the next
+// sync. Critical blocks are always uploaded, even while a clean sync is warming up, and info
+// blocks are always left for the next sync.
+static bool ShouldUploadImmediately(SNTStoredExecutionEvent* se) {
+  switch (se.severity) {
+    case SNTRuleSeverityCritical: return true;
+    case SNTRuleSeverityInfo: return false;
+    default: return [[SNTCleanSyncWarmup sharedWarmup] shouldUploadEvent:se];
+  }
+}
+
 // Returns true if two processes execute the same binary.
 //   * Strict: when both are CdhashStrictlyEnforced the kernel-reported cdhash is
 //     authoritative (the kernel guarantees it binds to executed content).
@@ -209,6 +234,12 @@ static bool SameBinary(const es_process_t* a, NSString* aSHA256, const es_proces
     case SNTEventStateBlockCELFallback: eventTypeStr = kBlockCELFallback; break;
     case SNTEventStateAllowCELFallback: eventTypeStr = kAllowCELFallback; break;
     case SNTEventStateAllowPlatform: eventTypeStr = kAllowPlatform; break;
+    case SNTEventStateBlockRequirement: eventTypeStr = kBlockRequirement; break;
+    case SNTEventStateAllowRequirement: eventTypeStr = kAllowRequirement; break;
+    case SNTEventStateBlockNetworkVolume: eventTypeStr = kBlockNetworkVolume; break;
+    case SNTEventStateBlockDiskImage: eventTypeStr = kBlockDiskImage; break;
+    case SNTEventStateBlockPathChurn: eventTypeStr = kBlockPathChurn; break;
+    case SNTEventStateAllowOnce: eventTypeStr = kAllowOnce; break;
     default: eventTypeStr = kUnknownEventState; break;
   }
 
@@ -370,6 +401,50 @@ static bool SameBinary(const es_process_t* a, NSString* aSHA256, const es_proces
   cd.codesigningFlags = targetProc->codesigning_flags;
   cd.vnodeId = SantaVnode::VnodeForFile(targetProc->executable);
 
+  // For a shebang script the exec target is the interpreter and the script is
+  // reported alongside it. Scripts aren't Mach-O so only their hash can be
+  // matched, on top of the interpreter's own decision.
+  if (config.enableScriptEvaluation && esMsg->version >= 2 && esMsg->event.exec.script) {
+    SNTFileInfo* scriptInfo =
+        [[SNTFileInfo alloc] initWithEndpointSecurityFile:esMsg->event.exec.script error:NULL];
+    if (scriptInfo) {
+      [self.policyProcessor applyScriptRuleForScript:scriptInfo decision:cd];
+    } else {
+      LOGW(@"Failed to read script %@, using the interpreter's decision",
+           @(esMsg->event.exec.script->path.data));
+      cd.cacheable = NO;
+    }
+  }
+
+  // Known interpreters such as osascript are also given the script to run as
+  // arguments, either inline or as a file, which a shebang exec doesn't report.
+  std::optional<std::string> interpreter = santa::KnownInterpreter(targetProc);
+  if (config.enableInterpreterScriptEvaluation && interpreter && !cd.scriptSHA256) {
+    cd.scriptInterpreter = santa::StringToNSString(*interpreter);
+
+    es_file_t* cwd = esMsg->version >= 3 ? esMsg->event.exec.cwd : nullptr;
+    std::optional<santa::InterpreterScript> script = santa::ParseInterpreterScript(
+        *interpreter, esMsg.ESAPI()->ExecArgs(&esMsg->event.exec),
+        cwd ? santa::StringTokenToStringView(cwd->path) : std::string_view());
+
+    NSString* scriptSHA256;
+    if (script && script->source) {
+      scriptSHA256 = santa::StringToNSString(santa::InterpreterScriptSourceSHA256(*script->source));
+    } else if (script && script->path) {
+      SNTFileInfo* scriptInfo =
+          [[SNTFileInfo alloc] initWithPath:santa::StringToNSString(*script->path)];
+      scriptSHA256 = scriptInfo.SHA256;
+    }
+
+    if (scriptSHA256) {
+      [self.policyProcessor applyScriptRuleForScriptSHA256:scriptSHA256 decision:cd];
+    } else {
+      LOGD(@"Unable to find the script run by %@, using the interpreter's decision",
+           cd.scriptInterpreter);
+      cd.cacheable = NO;
+    }
+  }
+
   // Seatbelt expectation check: the sandboxed exec is authorized iff
   // santactl pre-registered an expectation for the caller's audit token,
   // and the expectation matches the exec target under one of two modes:
@@ -504,14 +579,36 @@ static bool SameBinary(const es_process_t* a, NSString* aSHA256, const es_proces
   // Increment metric counters
   [self incrementEventCounters:cd.decision];
 
-  // Log to database if necessary.
-  if (config.enableAllEventUpload ||
-      (cd.decision == SNTEventStateAllowUnknown && !config.disableUnknownEventUpload) ||
-      cd.auditReturn || (cd.decision & SNTEventStateAllow) == 0) {
+  // Keep a brief record of every decision so recent decisions can be exported on demand, even
+  // when they are not stored for upload below.
+  SNTStoredExecutionEvent* decisionRecord = [[SNTStoredExecutionEvent alloc] init];
+  decisionRecord.occurrenceDate = [[NSDate alloc] init];
+  decisionRecord.fileSHA256 = cd.sha256;
+  decisionRecord.filePath = binInfo.path;
+  decisionRecord.decision = cd.decision;
+  decisionRecord.teamID = cd.teamID;
+  decisionRecord.signingID = cd.signingID;
+  decisionRecord.cdhash = cd.cdhash;
+  decisionRecord.pid = @(newProcPid);
+  decisionRecord.ppid = @(audit_token_to_pid(targetProc->parent_audit_token));
+  [[SNTDecisionHistory sharedHistory] recordDecision:decisionRecord];
+  if (config.enableDecisionDatabase) {
+    [[SNTDatabaseController decisionTable] recordDecision:decisionRecord
+                                             maxDecisions:config.decisionDatabaseMaxDecisions];
+  }
+
+  // Log to database if necessary. Allowed executions are subject to sampling,
+  // blocked and audit events are always stored.
+  BOOL uploadAllowed = (config.enableAllEventUpload ||
+                        (cd.decision == SNTEventStateAllowUnknown &&
+                         !config.disableUnknownEventUpload)) &&
+                       santa::IsEventSampled(cd.sha256, config.telemetrySampleRate);
+  if (uploadAllowed || cd.auditReturn || (cd.decision & SNTEventStateAllow) == 0) {
     SNTStoredExecutionEvent* se = [[SNTStoredExecutionEvent alloc] init];
     se.occurrenceDate = [[NSDate alloc] init];
     se.fileSHA256 = cd.sha256;
     se.filePath = binInfo.path;
+    se.resolvedFilePath = cd.resolvedPath;
     se.decision = cd.decision;
     se.auditReturn = cd.auditReturn;
     se.holdAndAsk = cd.holdAndAsk;
@@ -519,6 +616,7 @@ static bool SameBinary(const es_process_t* a, NSString* aSHA256, const es_proces
     se.seatbeltRequired = cd.seatbeltRequired;
     se.staticRule = cd.staticRule;
     se.ruleId = cd.ruleId;
+    se.severity = cd.severity;
 
     se.signingChain = cd.certChain;
     se.teamID = cd.teamID;
@@ -570,13 +668,18 @@ static bool SameBinary(const es_process_t* a, NSString* aSHA256, const es_proces
     // If binary was blocked, do the needful
     if (action != SNTActionRespondAllow && action != SNTActionRespondAllowCompiler &&
         action != SNTActionRespondAllowNoCache) {
+      if (config.syncBaseURL) {
+        // Remember the block so the time until it is approved can be reported.
+        [[SNTApprovalTracker sharedTracker] recordBlockedEvent:se];
+      }
+
       if (config.enableBundles && binInfo.bundle) {
         // If the binary is part of a bundle, find and hash all the related binaries in the bundle.
         // Let the GUI know hashing is needed. Once the hashing is complete the GUI will send a
         // message to santad to perform the upload logic for bundles.
         // See syncBundleEvent:relatedEvents: for more info.
         se.needsBundleHash = YES;
-      } else if (config.syncBaseURL) {
+      } else if (config.syncBaseURL && ShouldUploadImmediately(se)) {
         // So the server has something to show the user straight away, initiate an event
         // upload for the blocked binary rather than waiting for the next sync.
         dispatch_async(_eventQueue, ^{
@@ -671,6 +774,8 @@ static bool SameBinary(const es_process_t* a, NSString* aSHA256, const es_proces
           // Clear holdAndAsk and update cache so it's recorded as a final decision
           cd.holdAndAsk = NO;
           [[SNTDecisionCache sharedCache] cacheDecision:cd];
+          // The recorder skipped this execution while it was held, count it now it is final.
+          [[SNTExecutionCounts sharedCounts] recordExecution:cd];
 
           // Log the execution event (since NOTIFY was suppressed during holdAndAsk)
           self->_logger(std::move(esMsgCopy));
@@ -683,7 +788,9 @@ static bool SameBinary(const es_process_t* a, NSString* aSHA256, const es_proces
       // Suppress the GUI for a silent-GUI block, but never when holding for
       // approval: a held process depends on the GUI reply to resume or be killed,
       // so it must always be shown even if the flags were somehow combined.
-      if (!cd.silentBlockGUI || cd.holdAndAsk) {
+      if ((!cd.silentBlockGUI || cd.holdAndAsk) &&
+          (cd.severity == SNTRuleSeverityCritical ||
+           [[SNTCleanSyncWarmup sharedWarmup] shouldNotifyForEvent:se])) {
         // Let the user know what happened in the GUI.
         [self.notifierQueue addEvent:se
                    withCustomMessage:cd.customMsg
@@ -788,6 +895,33 @@ static bool SameBinary(const es_process_t* a, NSString* aSHA256, const es_proces
     }
   }
 
+  // Keep an audit trail of the approval. The rule is attributed to the user who authorized it,
+  // which is the user that tried to execute the binary.
+  if (success) {
+    SNTProcessChain* approver = [[SNTProcessChain alloc] init];
+    approver.executingUser = se.executingUser;
+    SNTStoredRuleChangeAuditEvent* auditEvent = [[SNTStoredRuleChangeAuditEvent alloc]
+        initWithSource:SNTRuleChangeAuditSourceStandaloneApproval
+                 rules:@[ newRule ]
+           ruleCleanup:SNTRuleCleanupNone
+               process:approver];
+    dispatch_async(_eventQueue, ^{
+      [self.eventTable addStoredEvent:auditEvent];
+      [self.syncdQueue addStoredEvent:auditEvent];
+    });
+  }
+
+  if (success && [SNTConfigurator configurator].syncBaseURL) {
+    for (SNTStoredExecutionEvent* approval in
+         [[SNTApprovalTracker sharedTracker] approvalEventsForRules:@[ newRule ]
+                                                         approvedAt:[NSDate date]]) {
+      dispatch_async(_eventQueue, ^{
+        [self.eventTable addStoredEvent:approval];
+        [self.syncdQueue addStoredEvent:approval];
+      });
+    }
+  }
+
   // TODO: Notify the sync service of the new rule.
 }
 
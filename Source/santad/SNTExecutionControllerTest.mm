@@ -32,6 +32,7 @@
 #import "Source/common/SNTRule.h"
 #import "Source/common/SNTRuleIdentifiers.h"
 #import "Source/common/SNTSandboxExecRequest.h"
+#import "Source/common/SNTStoredExecutionEvent.h"
 #include "Source/common/SantaVnode.h"
 #include "Source/common/TestUtils.h"
 #include "Source/common/es/Message.h"
@@ -40,10 +41,12 @@
 #import "Source/santad/DataLayer/SNTRuleTable.h"
 #include "Source/santad/EntitlementsFilter.h"
 #include "Source/santad/ProcessControl.h"
+#import "Source/santad/SNTCleanSyncWarmup.h"
 #import "Source/santad/SNTDecisionCache.h"
 #import "Source/santad/SNTExecutionController.h"
 #import "Source/santad/SNTNotificationQueue.h"
 #import "Source/santad/SNTPolicyProcessor.h"
+#import "Source/santad/SNTSyncdQueue.h"
 #include "Source/santad/SandboxExpectations.h"
 
 using santa::Message;
@@ -93,6 +96,7 @@ static SNTSandboxExecRequest* MakeSandboxRequest(uint64_t dev, uint64_t ino, con
 @property id mockFileInfo;
 @property id mockRuleDatabase;
 @property id mockEventDatabase;
+@property double telemetrySampleRate;
 
 @property SNTExecutionController* sut;
 @end
@@ -119,6 +123,12 @@ static SNTSandboxExecRequest* MakeSandboxRequest(uint64_t dev, uint64_t ino, con
   OCMStub([self.mockConfigurator configurator]).andReturn(self.mockConfigurator);
   NSURL* url = [NSURL URLWithString:@"https://localhost/test"];
   OCMStub([self.mockConfigurator syncBaseURL]).andReturn(url);
+  self.telemetrySampleRate = 1.0;
+  __weak SNTExecutionControllerTest* weakSelf = self;
+  OCMStub([self.mockConfigurator telemetrySampleRate]).andDo(^(NSInvocation* invocation) {
+    double rate = weakSelf.telemetrySampleRate;
+    [invocation setReturnValue:&rate];
+  });
 
   self.mockFileInfo = OCMClassMock([SNTFileInfo class]);
   OCMStub([self.mockFileInfo alloc]).andReturn(self.mockFileInfo);
@@ -809,6 +819,41 @@ static SNTSandboxExecRequest* MakeSandboxRequest(uint64_t dev, uint64_t ino, con
   OCMVerifyAllWithDelay(self.mockEventDatabase, 1);
 }
 
+- (void)testAllEventUploadSampledOut {
+  OCMStub([self.mockFileInfo isMachO]).andReturn(YES);
+  OCMStub([self.mockFileInfo SHA256]).andReturn(@"a");
+
+  self.telemetrySampleRate = 0.0;
+  OCMExpect([self.mockConfigurator enableAllEventUpload]).andReturn(YES);
+
+  SNTRule* rule = [[SNTRule alloc] init];
+  rule.state = SNTRuleStateAllow;
+  rule.type = SNTRuleTypeBinary;
+
+  [self stubRule:rule forIdentifiers:{.binarySHA256 = @"a"}];
+
+  [self validateExecEvent:SNTActionRespondAllow];
+  OCMVerify(never(), [self.mockEventDatabase addStoredEvent:OCMOCK_ANY]);
+}
+
+- (void)testBlockedEventsAreNeverSampledOut {
+  OCMStub([self.mockFileInfo isMachO]).andReturn(YES);
+  OCMStub([self.mockFileInfo SHA256]).andReturn(@"a");
+
+  self.telemetrySampleRate = 0.0;
+  OCMExpect([self.mockEventDatabase addStoredEvent:OCMOCK_ANY]);
+
+  SNTRule* rule = [[SNTRule alloc] init];
+  rule.state = SNTRuleStateBlock;
+  rule.type = SNTRuleTypeBinary;
+
+  [self stubRule:rule forIdentifiers:{.binarySHA256 = @"a"}];
+
+  [self validateExecEvent:SNTActionRespondDeny];
+  OCMVerifyAllWithDelay(self.mockEventDatabase, 1);
+  [self checkMetricCounters:kBlockBinary expected:@1];
+}
+
 - (void)testDisableUnknownEventUpload {
   OCMStub([self.mockFileInfo isMachO]).andReturn(YES);
   OCMStub([self.mockFileInfo SHA256]).andReturn(@"a");
@@ -944,6 +989,130 @@ static SNTSandboxExecRequest* MakeSandboxRequest(uint64_t dev, uint64_t ino, con
                        expectedControl:santa::ProcessControl::Kill];
 }
 
+// Runs a block decision with the given severity through a controller with mocked notifier and
+// syncd queues. The clean sync warmup is mocked to return `warmupAllows` for both uploads and
+// notifications.
+- (void)validateBlockWithSeverity:(SNTRuleSeverity)severity
+                     warmupAllows:(BOOL)warmupAllows
+                    notifierQueue:(id)mockNotifierQueue
+                       syncdQueue:(id)mockSyncdQueue {
+  OCMStub([self.mockFileInfo isMachO]).andReturn(YES);
+  OCMStub([self.mockFileInfo SHA256]).andReturn(@"a");
+  OCMStub([self.mockConfigurator clientMode]).andReturn(SNTClientModeLockdown);
+
+  id mockWarmup = OCMClassMock([SNTCleanSyncWarmup class]);
+  OCMStub([mockWarmup sharedWarmup]).andReturn(mockWarmup);
+  OCMStub([mockWarmup shouldUploadEvent:OCMOCK_ANY]).andReturn(warmupAllows);
+  OCMStub([mockWarmup shouldNotifyForEvent:OCMOCK_ANY]).andReturn(warmupAllows);
+
+  id mockPolicyProcessor = OCMClassMock([SNTPolicyProcessor class]);
+  SNTCachedDecision* cd = [[SNTCachedDecision alloc] init];
+  cd.decision = SNTEventStateBlockBinary;
+  cd.decisionClientMode = SNTClientModeLockdown;
+  cd.sha256 = @"a";
+  cd.severity = severity;
+
+  es_file_t file = MakeESFile("foo");
+  es_process_t proc = MakeESProcess(&file);
+  es_file_t fileExec = MakeESFile("bar", {.st_dev = 12, .st_ino = 34});
+  es_process_t procExec = MakeESProcess(&fileExec);
+  procExec.is_platform_binary = false;
+  procExec.codesigning_flags = CS_SIGNED | CS_VALID;
+  es_message_t esMsg = MakeESMessage(ES_EVENT_TYPE_AUTH_EXEC, &proc);
+  esMsg.event.exec.target = &procExec;
+
+  OCMStub([mockPolicyProcessor decisionForFileInfo:OCMOCK_ANY
+                                     targetProcess:&procExec
+                                       configState:OCMOCK_ANY
+                                activationCallback:OCMOCK_ANY
+                                    cachedDecision:OCMOCK_ANY])
+      .ignoringNonObjectArgs()
+      .andReturn(cd);
+
+  SNTExecutionController* controller = [[SNTExecutionController alloc]
+        initWithRuleTable:self.mockRuleDatabase
+               eventTable:self.mockEventDatabase
+            notifierQueue:mockNotifierQueue
+               syncdQueue:mockSyncdQueue
+                   logger:nullptr
+                ttyWriter:santa::TTYWriter::Create(true)
+          policyProcessor:mockPolicyProcessor
+      processControlBlock:santa::ProdSuspendResumeBlock()
+              processTree:nullptr
+      sandboxExpectations:std::make_shared<santa::SandboxExpectations>()];
+
+  auto mockESApi = std::make_shared<MockEndpointSecurityAPI>();
+  mockESApi->SetExpectationsRetainReleaseMessage();
+
+  {
+    Message msg(mockESApi, &esMsg);
+    [controller validateExecEvent:msg
+                   cachedDecision:nil
+                       postAction:verifyPostAction(SNTActionRespondDeny)];
+  }
+
+  // The event is always stored so it is uploaded with the next sync.
+  OCMVerifyAllWithDelay(self.mockEventDatabase, 1);
+
+  XCTBubbleMockVerifyAndClearExpectations(mockESApi.get());
+  [mockPolicyProcessor stopMocking];
+  [mockWarmup stopMocking];
+}
+
+- (void)testCriticalBlockUploadsImmediatelyWithProminentNotification {
+  id mockNotifierQueue = OCMClassMock([SNTNotificationQueue class]);
+  id mockSyncdQueue = OCMClassMock([SNTSyncdQueue class]);
+  OCMExpect([self.mockEventDatabase addStoredEvent:OCMOCK_ANY]);
+  OCMExpect([mockSyncdQueue addStoredEvent:[OCMArg checkWithBlock:^BOOL(SNTStoredEvent* se) {
+                              return ((SNTStoredExecutionEvent*)se).severity ==
+                                     SNTRuleSeverityCritical;
+                            }]]);
+  OCMExpect([mockNotifierQueue
+                addEvent:[OCMArg checkWithBlock:^BOOL(SNTStoredExecutionEvent* se) {
+                  return se.severity == SNTRuleSeverityCritical;
+                }]
+       withCustomMessage:OCMOCK_ANY
+               customURL:OCMOCK_ANY
+             configState:OCMOCK_ANY
+                andReply:OCMOCK_ANY]);
+
+  // A clean sync warmup would hold back both the upload and the notification for a warning
+  // block, but critical blocks are never held back.
+  [self validateBlockWithSeverity:SNTRuleSeverityCritical
+                     warmupAllows:NO
+                    notifierQueue:mockNotifierQueue
+                       syncdQueue:mockSyncdQueue];
+
+  OCMVerifyAllWithDelay(mockSyncdQueue, 1);
+  OCMVerifyAll(mockNotifierQueue);
+  [mockSyncdQueue stopMocking];
+  [mockNotifierQueue stopMocking];
+}
+
+- (void)testInfoBlockIsBatchedForNextSync {
+  id mockNotifierQueue = OCMClassMock([SNTNotificationQueue class]);
+  id mockSyncdQueue = OCMClassMock([SNTSyncdQueue class]);
+  OCMExpect([self.mockEventDatabase addStoredEvent:OCMOCK_ANY]);
+  OCMExpect([mockNotifierQueue
+                addEvent:[OCMArg checkWithBlock:^BOOL(SNTStoredExecutionEvent* se) {
+                  return se.severity == SNTRuleSeverityInfo;
+                }]
+       withCustomMessage:OCMOCK_ANY
+               customURL:OCMOCK_ANY
+             configState:OCMOCK_ANY
+                andReply:OCMOCK_ANY]);
+
+  [self validateBlockWithSeverity:SNTRuleSeverityInfo
+                     warmupAllows:YES
+                    notifierQueue:mockNotifierQueue
+                       syncdQueue:mockSyncdQueue];
+
+  OCMVerifyAll(mockNotifierQueue);
+  OCMVerify(never(), [mockSyncdQueue addStoredEvent:OCMOCK_ANY]);
+  [mockSyncdQueue stopMocking];
+  [mockNotifierQueue stopMocking];
+}
+
 // Test that successful TouchID auth populates the cache, and subsequent executions
 // of the same binary skip the TouchID prompt (cache hit scenario)
 - (void)testTouchIDCacheHitSkipsPrompt {
@@ -0,0 +1,59 @@
+/// Copyright 2026 North Pole Security, Inc.
+///
+/// Licensed under the Apache License, Version 2.0 (the "License");
+/// you may not use this file except in compliance with the License.
+/// You may obtain a copy of the License at
+///
+///     http://www.apache.org/licenses/LICENSE-2.0
+///
+/// Unless required by applicable law or agreed to in writing, software
+/// distributed under the License is distributed on an "AS IS" BASIS,
+/// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+/// See the License for the specific language governing permissions and
+/// limitations under the License.
+
+#import <Foundation/Foundation.h>
+
+@class SNTCachedDecision;
+
+NS_ASSUME_NONNULL_BEGIN
+
+/// Keys of each entry in an execution counts snapshot.
+extern NSString* const kExecutionCountsCount;
+extern NSString* const kExecutionCountsSigningID;
+extern NSString* const kExecutionCountsTeamID;
+
+///
+///  Counts how many times each allowed binary has executed since the counts were last reported to
+///  the sync server. Counts are aggregated by SHA-256 and kept only in memory, so they are lost if
+///  the daemon restarts. Once the capacity is reached, executions of binaries that are not already
+///  being counted are dropped until the counts are reset.
+///
+@interface SNTExecutionCounts : NSObject
+
++ (instancetype)sharedCounts;
+
+- (instancetype)initWithCapacity:(NSUInteger)capacity NS_DESIGNATED_INITIALIZER;
+- (instancetype)init NS_UNAVAILABLE;
+
+///
+///  Count an execution. Decisions that did not allow the execution, or whose SHA-256 is unknown,
+///  are ignored.
+///
+- (void)recordExecution:(SNTCachedDecision*)cd;
+
+///
+///  Returns the current counts keyed by SHA-256. Each entry holds the count and, when known, the
+///  signing ID and team ID of the binary.
+///
+- (NSDictionary<NSString*, NSDictionary*>*)snapshot;
+
+///
+///  Subtract the counts in a snapshot previously returned by -snapshot, so executions recorded
+///  after the snapshot was taken are kept for the next report.
+///
+- (void)resetWithSnapshot:(NSDictionary<NSString*, NSDictionary*>*)snapshot;
+
+@end
+
+NS_ASSUME_NONNULL_END
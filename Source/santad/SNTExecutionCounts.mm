@@ -0,0 +1,99 @@
+/// Copyright 2026 North Pole Security, Inc.
+///
+/// Licensed under the Apache License, Version 2.0 (the "License");
+/// you may not use this file except in compliance with the License.
+/// You may obtain a copy of the License at
+///
+///     http://www.apache.org/licenses/LICENSE-2.0
+///
+/// Unless required by applicable law or agreed to in writing, software
+/// distributed under the License is distributed on an "AS IS" BASIS,
+/// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+/// See the License for the specific language governing permissions and
+/// limitations under the License.
+
+#import "Source/santad/SNTExecutionCounts.h"
+
+#import "Source/common/SNTCachedDecision.h"
+#import "Source/common/SNTCommonEnums.h"
+
+NSString* const kExecutionCountsCount = @"count";
+NSString* const kExecutionCountsSigningID = @"signing_id";
+NSString* const kExecutionCountsTeamID = @"team_id";
+
+static const NSUInteger kDefaultExecutionCountsCapacity = 10000;
+
+@implementation SNTExecutionCounts {
+  NSMutableDictionary<NSString*, NSMutableDictionary*>* _counts;
+  NSUInteger _capacity;
+  dispatch_queue_t _q;
+}
+
++ (instancetype)sharedCounts {
+  static SNTExecutionCounts* counts;
+  static dispatch_once_t onceToken;
+  dispatch_once(&onceToken, ^{
+    counts = [[SNTExecutionCounts alloc] initWithCapacity:kDefaultExecutionCountsCapacity];
+  });
+  return counts;
+}
+
+- (instancetype)initWithCapacity:(NSUInteger)capacity {
+  self = [super init];
+  if (self) {
+    _counts = [NSMutableDictionary dictionary];
+    _capacity = capacity;
+    _q = dispatch_queue_create("com.northpolesec.santa.daemon.execution_counts",
+                               DISPATCH_QUEUE_SERIAL_WITH_AUTORELEASE_POOL);
+  }
+  return self;
+}
+
+- (void)recordExecution:(SNTCachedDecision*)cd {
+  if ((cd.decision & SNTEventStateAllow) == 0 || !cd.sha256.length) return;
+
+  NSString* sha256 = cd.sha256;
+  NSString* signingID = cd.signingID;
+  NSString* teamID = cd.teamID;
+  dispatch_sync(_q, ^{
+    NSMutableDictionary* entry = _counts[sha256];
+    if (!entry) {
+      if (_counts.count >= _capacity) return;
+      entry = [NSMutableDictionary dictionaryWithObject:@0 forKey:kExecutionCountsCount];
+      if (signingID) entry[kExecutionCountsSigningID] = signingID;
+      if (teamID) entry[kExecutionCountsTeamID] = teamID;
+      _counts[sha256] = entry;
+    }
+    entry[kExecutionCountsCount] = @([entry[kExecutionCountsCount] unsignedLongLongValue] + 1);
+  });
+}
+
+- (NSDictionary<NSString*, NSDictionary*>*)snapshot {
+  NSMutableDictionary<NSString*, NSDictionary*>* snapshot = [NSMutableDictionary dictionary];
+  dispatch_sync(_q, ^{
+    [_counts enumerateKeysAndObjectsUsingBlock:^(NSString* sha256, NSMutableDictionary* entry,
+                                                 BOOL* stop) {
+      snapshot[sha256] = [entry copy];
+    }];
+  });
+  return snapshot;
+}
+
+- (void)resetWithSnapshot:(NSDictionary<NSString*, NSDictionary*>*)snapshot {
+  dispatch_sync(_q, ^{
+    [snapshot enumerateKeysAndObjectsUsingBlock:^(NSString* sha256, NSDictionary* reported,
+                                                  BOOL* stop) {
+      NSMutableDictionary* entry = _counts[sha256];
+      if (!entry) return;
+      unsigned long long current = [entry[kExecutionCountsCount] unsignedLongLongValue];
+      unsigned long long sent = [reported[kExecutionCountsCount] unsignedLongLongValue];
+      if (sent >= current) {
+        [_counts removeObjectForKey:sha256];
+      } else {
+        entry[kExecutionCountsCount] = @(current - sent);
+      }
+    }];
+  });
+}
+
+@end
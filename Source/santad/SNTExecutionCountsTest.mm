@@ -0,0 +1,111 @@
+/// Copyright 2026 North Pole Security, Inc.
+///
+/// Licensed under the Apache License, Version 2.0 (the "License");
+/// you may not use this file except in compliance with the License.
+/// You may obtain a copy of the License at
+///
+///     http://www.apache.org/licenses/LICENSE-2.0
+///
+/// Unless required by applicable law or agreed to in writing, software
+/// distributed under the License is distributed on an "AS IS" BASIS,
+/// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+/// See the License for the specific language governing permissions and
+/// limitations under the License.
+
+#import "Source/santad/SNTExecutionCounts.h"
+
+#import <Foundation/Foundation.h>
+#import <XCTest/XCTest.h>
+
+#import "Source/common/SNTCachedDecision.h"
+#import "Source/common/SNTCommonEnums.h"
+
+@interface SNTExecutionCountsTest : XCTestCase
+@end
+
+@implementation SNTExecutionCountsTest
+
+- (SNTCachedDecision*)decisionForSHA256:(NSString*)sha256 decision:(SNTEventState)decision {
+  SNTCachedDecision* cd = [[SNTCachedDecision alloc] init];
+  cd.sha256 = sha256;
+  cd.decision = decision;
+  return cd;
+}
+
+- (void)testAggregatesAllowedExecutionsByIdentity {
+  SNTExecutionCounts* sut = [[SNTExecutionCounts alloc] initWithCapacity:16];
+
+  SNTCachedDecision* signedTool = [self decisionForSHA256:@"aaaa"
+                                                 decision:SNTEventStateAllowSigningID];
+  signedTool.signingID = @"EQHXZ8M8AV:com.example.tool";
+  signedTool.teamID = @"EQHXZ8M8AV";
+  for (int i = 0; i < 3; i++) {
+    [sut recordExecution:signedTool];
+  }
+  [sut recordExecution:[self decisionForSHA256:@"bbbb" decision:SNTEventStateAllowBinary]];
+  [sut recordExecution:[self decisionForSHA256:@"bbbb" decision:SNTEventStateAllowCertificate]];
+
+  NSDictionary* expected = @{
+    @"aaaa" : @{
+      kExecutionCountsCount : @3,
+      kExecutionCountsSigningID : @"EQHXZ8M8AV:com.example.tool",
+      kExecutionCountsTeamID : @"EQHXZ8M8AV",
+    },
+    @"bbbb" : @{kExecutionCountsCount : @2},
+  };
+  XCTAssertEqualObjects([sut snapshot], expected);
+}
+
+- (void)testIgnoresBlockedAndUnidentifiedExecutions {
+  SNTExecutionCounts* sut = [[SNTExecutionCounts alloc] initWithCapacity:16];
+
+  [sut recordExecution:[self decisionForSHA256:@"aaaa" decision:SNTEventStateBlockBinary]];
+  [sut recordExecution:[self decisionForSHA256:@"bbbb" decision:SNTEventStateBlockUnknown]];
+  [sut recordExecution:[self decisionForSHA256:nil decision:SNTEventStateAllowBinary]];
+
+  XCTAssertEqual([sut snapshot].count, 0);
+}
+
+- (void)testResetKeepsExecutionsRecordedAfterSnapshot {
+  SNTExecutionCounts* sut = [[SNTExecutionCounts alloc] initWithCapacity:16];
+  SNTCachedDecision* tool = [self decisionForSHA256:@"aaaa" decision:SNTEventStateAllowBinary];
+  SNTCachedDecision* other = [self decisionForSHA256:@"bbbb" decision:SNTEventStateAllowBinary];
+
+  [sut recordExecution:tool];
+  [sut recordExecution:tool];
+  [sut recordExecution:other];
+  NSDictionary* snapshot = [sut snapshot];
+
+  // Executions that happen while the snapshot is being reported.
+  [sut recordExecution:tool];
+
+  [sut resetWithSnapshot:snapshot];
+
+  XCTAssertEqualObjects([sut snapshot], @{@"aaaa" : @{kExecutionCountsCount : @1}});
+
+  [sut resetWithSnapshot:[sut snapshot]];
+  XCTAssertEqual([sut snapshot].count, 0);
+}
+
+- (void)testNewIdentitiesAreDroppedAtCapacity {
+  SNTExecutionCounts* sut = [[SNTExecutionCounts alloc] initWithCapacity:2];
+
+  [sut recordExecution:[self decisionForSHA256:@"aaaa" decision:SNTEventStateAllowBinary]];
+  [sut recordExecution:[self decisionForSHA256:@"bbbb" decision:SNTEventStateAllowBinary]];
+  [sut recordExecution:[self decisionForSHA256:@"cccc" decision:SNTEventStateAllowBinary]];
+  // Binaries already being counted are still counted.
+  [sut recordExecution:[self decisionForSHA256:@"aaaa" decision:SNTEventStateAllowBinary]];
+
+  NSDictionary* expected = @{
+    @"aaaa" : @{kExecutionCountsCount : @2},
+    @"bbbb" : @{kExecutionCountsCount : @1},
+  };
+  XCTAssertEqualObjects([sut snapshot], expected);
+
+  // Once reported, there is room for new binaries again.
+  [sut resetWithSnapshot:[sut snapshot]];
+  [sut recordExecution:[self decisionForSHA256:@"cccc" decision:SNTEventStateAllowBinary]];
+  XCTAssertEqualObjects([sut snapshot], @{@"cccc" : @{kExecutionCountsCount : @1}});
+}
+
+@end
@@ -0,0 +1,47 @@
+/// Copyright 2026 North Pole Security, Inc.
+///
+/// Licensed under the Apache License, Version 2.0 (the "License");
+/// you may not use this file except in compliance with the License.
+/// You may obtain a copy of the License at
+///
+///     http://www.apache.org/licenses/LICENSE-2.0
+///
+/// Unless required by applicable law or agreed to in writing, software
+/// distributed under the License is distributed on an "AS IS" BASIS,
+/// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+/// See the License for the specific language governing permissions and
+/// limitations under the License.
+
+#import <Foundation/Foundation.h>
+
+#import "Source/common/SNTFileProvenance.h"
+
+NS_ASSUME_NONNULL_BEGIN
+
+///
+///  Remembers which process last wrote each path watched by a FileAccessPolicy rule, so that a
+///  later execution of the file can be linked back to the process that created it. Writes are only
+///  kept in memory. Once the capacity is reached the oldest write is forgotten to make room.
+///
+@interface SNTFileProvenanceIndex : NSObject
+
++ (instancetype)sharedIndex;
+
+- (instancetype)initWithCapacity:(NSUInteger)capacity
+                          maxAge:(NSTimeInterval)maxAge NS_DESIGNATED_INITIALIZER;
+- (instancetype)init NS_UNAVAILABLE;
+
+///
+///  Record that the process in provenance wrote to path. Replaces any earlier write to path.
+///
+- (void)recordProvenance:(SNTFileProvenance*)provenance forPath:(NSString*)path;
+
+///
+///  @return The last write recorded for path, or nil if there isn't one or it happened more than
+///          maxAge seconds before date.
+///
+- (nullable SNTFileProvenance*)provenanceForPath:(NSString*)path date:(NSDate*)date;
+
+@end
+
+NS_ASSUME_NONNULL_END
@@ -0,0 +1,77 @@
+/// Copyright 2026 North Pole Security, Inc.
+///
+/// Licensed under the Apache License, Version 2.0 (the "License");
+/// you may not use this file except in compliance with the License.
+/// You may obtain a copy of the License at
+///
+///     http://www.apache.org/licenses/LICENSE-2.0
+///
+/// Unless required by applicable law or agreed to in writing, software
+/// distributed under the License is distributed on an "AS IS" BASIS,
+/// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+/// See the License for the specific language governing permissions and
+/// limitations under the License.
+
+#import "Source/santad/SNTFileProvenanceIndex.h"
+
+static const NSUInteger kDefaultFileProvenanceIndexCapacity = 5000;
+static const NSTimeInterval kDefaultFileProvenanceMaxAge = 24 * 60 * 60;
+
+@implementation SNTFileProvenanceIndex {
+  NSMutableDictionary<NSString*, SNTFileProvenance*>* _entries;
+  // Paths in _entries, least recently written first.
+  NSMutableOrderedSet<NSString*>* _order;
+  NSUInteger _capacity;
+  NSTimeInterval _maxAge;
+  dispatch_queue_t _q;
+}
+
++ (instancetype)sharedIndex {
+  static SNTFileProvenanceIndex* index;
+  static dispatch_once_t onceToken;
+  dispatch_once(&onceToken, ^{
+    index = [[SNTFileProvenanceIndex alloc] initWithCapacity:kDefaultFileProvenanceIndexCapacity
+                                                      maxAge:kDefaultFileProvenanceMaxAge];
+  });
+  return index;
+}
+
+- (instancetype)initWithCapacity:(NSUInteger)capacity maxAge:(NSTimeInterval)maxAge {
+  self = [super init];
+  if (self) {
+    _entries = [NSMutableDictionary dictionary];
+    _order = [NSMutableOrderedSet orderedSet];
+    _capacity = capacity;
+    _maxAge = maxAge;
+    _q = dispatch_queue_create("com.northpolesec.santa.daemon.file_provenance",
+                               DISPATCH_QUEUE_SERIAL_WITH_AUTORELEASE_POOL);
+  }
+  return self;
+}
+
+- (void)recordProvenance:(SNTFileProvenance*)provenance forPath:(NSString*)path {
+  if (!path.length || !_capacity) return;
+
+  dispatch_sync(_q, ^{
+    [_order removeObject:path];
+    while (_order.count >= _capacity) {
+      [_entries removeObjectForKey:_order.firstObject];
+      [_order removeObjectAtIndex:0];
+    }
+    _entries[path] = provenance;
+    [_order addObject:path];
+  });
+}
+
+- (SNTFileProvenance*)provenanceForPath:(NSString*)path date:(NSDate*)date {
+  if (!path.length) return nil;
+
+  __block SNTFileProvenance* provenance;
+  dispatch_sync(_q, ^{
+    provenance = _entries[path];
+  });
+  if ([date timeIntervalSinceDate:provenance.writeTime] > _maxAge) return nil;
+  return provenance;
+}
+
+@end
@@ -0,0 +1,90 @@
+/// Copyright 2026 North Pole Security, Inc.
+///
+/// Licensed under the Apache License, Version 2.0 (the "License");
+/// you may not use this file except in compliance with the License.
+/// You may obtain a copy of the License at
+///
+///     http://www.apache.org/licenses/LICENSE-2.0
+///
+/// Unless required by applicable law or agreed to in writing, software
+/// distributed under the License is distributed on an "AS IS" BASIS,
+/// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+/// See the License for the specific language governing permissions and
+/// limitations under the License.
+
+#import "Source/santad/SNTFileProvenanceIndex.h"
+
+#import <Foundation/Foundation.h>
+#import <XCTest/XCTest.h>
+
+#import "Source/common/SNTFileProvenance.h"
+
+static const NSTimeInterval kMaxAge = 60;
+
+@interface SNTFileProvenanceIndexTest : XCTestCase
+@end
+
+@implementation SNTFileProvenanceIndexTest
+
+- (SNTFileProvenance*)provenanceFromWriter:(NSString*)writerPath date:(NSDate*)date {
+  SNTFileProvenance* provenance = [[SNTFileProvenance alloc] init];
+  provenance.writerPath = writerPath;
+  provenance.writerPID = @(123);
+  provenance.writeTime = date;
+  return provenance;
+}
+
+- (void)testLastWriteIsReturned {
+  SNTFileProvenanceIndex* sut = [[SNTFileProvenanceIndex alloc] initWithCapacity:16
+                                                                          maxAge:kMaxAge];
+  NSDate* start = [NSDate date];
+
+  XCTAssertNil([sut provenanceForPath:@"/tmp/tool" date:start]);
+
+  [sut recordProvenance:[self provenanceFromWriter:@"/usr/bin/curl" date:start]
+                forPath:@"/tmp/tool"];
+  XCTAssertEqualObjects([sut provenanceForPath:@"/tmp/tool" date:start].writerPath,
+                        @"/usr/bin/curl");
+
+  // A later write replaces the earlier one.
+  [sut recordProvenance:[self provenanceFromWriter:@"/bin/cp"
+                                              date:[start dateByAddingTimeInterval:1]]
+                forPath:@"/tmp/tool"];
+  XCTAssertEqualObjects([sut provenanceForPath:@"/tmp/tool" date:start].writerPath, @"/bin/cp");
+
+  // Other paths are tracked separately.
+  XCTAssertNil([sut provenanceForPath:@"/tmp/other" date:start]);
+}
+
+- (void)testOldWritesAreIgnored {
+  SNTFileProvenanceIndex* sut = [[SNTFileProvenanceIndex alloc] initWithCapacity:16
+                                                                          maxAge:kMaxAge];
+  NSDate* start = [NSDate date];
+
+  [sut recordProvenance:[self provenanceFromWriter:@"/usr/bin/curl" date:start]
+                forPath:@"/tmp/tool"];
+
+  XCTAssertNotNil([sut provenanceForPath:@"/tmp/tool"
+                                    date:[start dateByAddingTimeInterval:kMaxAge]]);
+  XCTAssertNil([sut provenanceForPath:@"/tmp/tool"
+                                 date:[start dateByAddingTimeInterval:kMaxAge + 1]]);
+}
+
+- (void)testOldestWriteIsForgottenAtCapacity {
+  SNTFileProvenanceIndex* sut = [[SNTFileProvenanceIndex alloc] initWithCapacity:2
+                                                                          maxAge:kMaxAge];
+  NSDate* start = [NSDate date];
+
+  [sut recordProvenance:[self provenanceFromWriter:@"/bin/a" date:start] forPath:@"/tmp/a"];
+  [sut recordProvenance:[self provenanceFromWriter:@"/bin/b" date:start] forPath:@"/tmp/b"];
+
+  // Rewriting /tmp/a makes /tmp/b the oldest.
+  [sut recordProvenance:[self provenanceFromWriter:@"/bin/a" date:start] forPath:@"/tmp/a"];
+  [sut recordProvenance:[self provenanceFromWriter:@"/bin/c" date:start] forPath:@"/tmp/c"];
+
+  XCTAssertNotNil([sut provenanceForPath:@"/tmp/a" date:start]);
+  XCTAssertNil([sut provenanceForPath:@"/tmp/b" date:start]);
+  XCTAssertNotNil([sut provenanceForPath:@"/tmp/c" date:start]);
+}
+
+@end
@@ -0,0 +1,68 @@
+/// Copyright 2026 North Pole Security, Inc.
+///
+/// Licensed under the Apache License, Version 2.0 (the "License");
+/// you may not use this file except in compliance with the License.
+/// You may obtain a copy of the License at
+///
+///     http://www.apache.org/licenses/LICENSE-2.0
+///
+/// Unless required by applicable law or agreed to in writing, software
+/// distributed under the License is distributed on an "AS IS" BASIS,
+/// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+/// See the License for the specific language governing permissions and
+/// limitations under the License.
+
+#include <EndpointSecurity/EndpointSecurity.h>
+#import <Foundation/Foundation.h>
+
+#import "Source/common/SNTCommonEnums.h"
+
+@class MOLCodesignChecker;
+@class SNTRuleTable;
+@class SNTStoredLaunchItemEvent;
+
+/// Unloads and disables the launchd service `label` in `domain` ("system" or "gui/<uid>") so that
+/// it isn't loaded again. Returns YES if the service was disabled.
+typedef BOOL (^SNTLaunchItemDisableBlock)(NSString* domain, NSString* label);
+
+typedef void (^SNTLaunchItemEventCallback)(SNTStoredLaunchItemEvent* event);
+
+///
+///  Evaluates login items, launch agents and launch daemons as they are registered with
+///  Background Task Management, a common way for malware to persist. The item's executable is
+///  checked against TeamID and SigningID rules and, depending on the LaunchItemPolicy config, items
+///  that aren't allowed are reported and disabled.
+///
+@interface SNTLaunchItemMonitor : NSObject
+
+- (instancetype)initWithRuleTable:(SNTRuleTable*)ruleTable
+                     disableBlock:(SNTLaunchItemDisableBlock)disableBlock;
+
+/// A disable block that runs launchctl to disable and boot out the service.
++ (SNTLaunchItemDisableBlock)launchctlDisableBlock;
+
+/// Called on a background queue with each evaluated item that should be stored and uploaded.
+@property(nonatomic) SNTLaunchItemEventCallback eventCallback;
+
+///
+///  Apply the configured policy to an item reported by an ES_EVENT_TYPE_NOTIFY_BTM_LAUNCH_ITEM_ADD
+///  event. The item is evaluated on a background queue.
+///
+- (void)handleLaunchItemAdd:(const es_event_btm_launch_item_add_t*)btm;
+
+///
+///  Set the decision on `event` and, for SNTLaunchItemPolicyBlock, disable the item if it isn't
+///  allowed. Returns YES if the event should be stored and uploaded.
+///
+- (BOOL)evaluateLaunchItem:(SNTStoredLaunchItemEvent*)event policy:(SNTLaunchItemPolicy)policy;
+
+///
+///  Returns the path of the plist the service `label` in `domain` was loaded from, or nil if no
+///  such service is loaded. Overridden in tests.
+///
+- (NSString*)plistPathOfService:(NSString*)label inDomain:(NSString*)domain;
+
+/// Returns the signing information for `path`; overridden in tests.
+- (MOLCodesignChecker*)codesignCheckerForPath:(NSString*)path;
+
+@end
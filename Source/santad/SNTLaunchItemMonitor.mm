@@ -0,0 +1,252 @@
+/// Copyright 2026 North Pole Security, Inc.
+///
+/// Licensed under the Apache License, Version 2.0 (the "License");
+/// you may not use this file except in compliance with the License.
+/// You may obtain a copy of the License at
+///
+///     http://www.apache.org/licenses/LICENSE-2.0
+///
+/// Unless required by applicable law or agreed to in writing, software
+/// distributed under the License is distributed on an "AS IS" BASIS,
+/// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+/// See the License for the specific language governing permissions and
+/// limitations under the License.
+
+#import "Source/santad/SNTLaunchItemMonitor.h"
+
+#include "Source/common/AuditUtilities.h"
+#import "Source/common/MOLCodesignChecker.h"
+#import "Source/common/SNTConfigurator.h"
+#import "Source/common/SNTLogging.h"
+#import "Source/common/SNTRule.h"
+#import "Source/common/SNTRuleIdentifiers.h"
+#import "Source/common/SNTStoredLaunchItemEvent.h"
+#import "Source/common/SigningIDHelpers.h"
+#include "Source/common/String.h"
+#import "Source/santad/DataLayer/SNTRuleTable.h"
+#include "Source/santad/Logs/EndpointSecurity/Serializers/Utilities.h"
+
+static SNTStoredLaunchItemEventItemType ItemType(es_btm_item_type_t type) {
+  switch (type) {
+    case ES_BTM_ITEM_TYPE_USER_ITEM: return SNTStoredLaunchItemEventItemTypeUserItem;
+    case ES_BTM_ITEM_TYPE_APP: return SNTStoredLaunchItemEventItemTypeApp;
+    case ES_BTM_ITEM_TYPE_LOGIN_ITEM: return SNTStoredLaunchItemEventItemTypeLoginItem;
+    case ES_BTM_ITEM_TYPE_AGENT: return SNTStoredLaunchItemEventItemTypeAgent;
+    case ES_BTM_ITEM_TYPE_DAEMON: return SNTStoredLaunchItemEventItemTypeDaemon;
+    default: return SNTStoredLaunchItemEventItemTypeUnknown;
+  }
+}
+
+static BOOL RunLaunchctl(NSArray<NSString*>* args) {
+  NSTask* task = [[NSTask alloc] init];
+  task.executableURL = [NSURL fileURLWithPath:@"/bin/launchctl"];
+  task.arguments = args;
+  task.standardOutput = [NSFileHandle fileHandleWithNullDevice];
+  task.standardError = [NSFileHandle fileHandleWithNullDevice];
+
+  NSError* error;
+  if (![task launchAndReturnError:&error]) {
+    LOGE(@"Failed to run launchctl %@: %@", args.firstObject, error.localizedDescription);
+    return NO;
+  }
+  [task waitUntilExit];
+  return task.terminationStatus == 0;
+}
+
+// Labels that must never be disabled because of a launch item plist: Santa's own services and
+// the platform's. Any user can register a plist with an arbitrary Label.
+static BOOL IsProtectedLabel(NSString* label) {
+  return [label isEqualToString:@"com.northpolesec.santa"] ||
+         [label hasPrefix:@"com.northpolesec.santa."] || [label hasPrefix:@"com.apple."];
+}
+
+@interface SNTLaunchItemMonitor ()
+@property SNTRuleTable* ruleTable;
+@property(nonatomic) SNTLaunchItemDisableBlock disableBlock;
+@property NSString* santaTeamID;
+@property dispatch_queue_t queue;
+@end
+
+@implementation SNTLaunchItemMonitor
+
+- (instancetype)initWithRuleTable:(SNTRuleTable*)ruleTable
+                     disableBlock:(SNTLaunchItemDisableBlock)disableBlock {
+  self = [super init];
+  if (self) {
+    _ruleTable = ruleTable;
+    _disableBlock = disableBlock;
+    _santaTeamID = [[MOLCodesignChecker alloc] initWithSelf].teamID;
+    _queue = dispatch_queue_create("com.northpolesec.santa.daemon.launch_items",
+                                   DISPATCH_QUEUE_SERIAL);
+  }
+  return self;
+}
+
++ (SNTLaunchItemDisableBlock)launchctlDisableBlock {
+  return ^BOOL(NSString* domain, NSString* label) {
+    NSString* target = [NSString stringWithFormat:@"%@/%@", domain, label];
+    // Disable first so launchd won't load the service again. Booting out fails if the service
+    // isn't loaded, which is fine.
+    BOOL disabled = RunLaunchctl(@[ @"disable", target ]);
+    RunLaunchctl(@[ @"bootout", target ]);
+    return disabled;
+  };
+}
+
+- (void)handleLaunchItemAdd:(const es_event_btm_launch_item_add_t*)btm {
+  SNTLaunchItemPolicy policy = [[SNTConfigurator configurator] launchItemPolicy];
+  if (policy == SNTLaunchItemPolicyNone) return;
+
+  // Copy what's needed out of the message before it is released.
+  SNTStoredLaunchItemEvent* event = [[SNTStoredLaunchItemEvent alloc] init];
+  event.occurrenceDate = [NSDate date];
+  event.itemType = ItemType(btm->item->item_type);
+  event.legacy = btm->item->legacy;
+  event.managed = btm->item->managed;
+  event.uid = @(btm->item->uid);
+  event.itemPath = santa::ConcatPrefixIfRelativePath(btm->item->item_url, btm->item->app_url);
+  if (btm->item->app_url.length > 0) {
+    event.appPath = santa::NormalizePath(btm->item->app_url);
+  }
+  event.executablePath =
+      santa::ConcatPrefixIfRelativePath(btm->executable_path, btm->item->app_url);
+  if (btm->instigator) {
+    event.process.filePath = santa::StringTokenToNSString(btm->instigator->executable->path);
+    event.process.pid = @(santa::Pid(btm->instigator->audit_token));
+    event.process.pidversion = @(santa::Pidversion(btm->instigator->audit_token));
+    event.process.executingUserID = @(santa::RealUser(btm->instigator->audit_token));
+  }
+
+  dispatch_async(self.queue, ^{
+    if ([self evaluateLaunchItem:event policy:policy] && self.eventCallback) {
+      self.eventCallback(event);
+    }
+  });
+}
+
+- (BOOL)evaluateLaunchItem:(SNTStoredLaunchItemEvent*)event policy:(SNTLaunchItemPolicy)policy {
+  // Login items don't always have an executable path, in which case the app is checked.
+  NSString* path = event.executablePath ?: event.appPath ?: event.itemPath;
+  MOLCodesignChecker* csc = path.length ? [self codesignCheckerForPath:path] : nil;
+  event.teamID = csc.teamID;
+  event.signingID = FormatSigningID(csc);
+
+  if (csc.platformBinary || (self.santaTeamID && [csc.teamID isEqualToString:self.santaTeamID])) {
+    event.decision = SNTStoredLaunchItemEventDecisionAllowed;
+  } else {
+    SNTRule* rule = [self.ruleTable executionRuleForIdentifiers:(struct RuleIdentifiers){
+                                                                    .signingID = event.signingID,
+                                                                    .teamID = event.teamID,
+                                                                }];
+    switch (rule.state) {
+      case SNTRuleStateAllow: OS_FALLTHROUGH;
+      case SNTRuleStateAllowCompiler: OS_FALLTHROUGH;
+      case SNTRuleStateAllowLocalSigningID:
+        event.decision = SNTStoredLaunchItemEventDecisionAllowed;
+        break;
+      case SNTRuleStateBlock: OS_FALLTHROUGH;
+      case SNTRuleStateSilentBlock: OS_FALLTHROUGH;
+      case SNTRuleStateSilentBlockGUI: OS_FALLTHROUGH;
+      case SNTRuleStateSilentBlockTTY:
+        event.decision = SNTStoredLaunchItemEventDecisionBlocked;
+        break;
+      default:
+        // CEL rules depend on the execution and can't be evaluated for a registration.
+        event.decision = SNTStoredLaunchItemEventDecisionUnknown;
+        break;
+    }
+  }
+
+  if (event.decision == SNTStoredLaunchItemEventDecisionAllowed) {
+    return [[SNTConfigurator configurator] enableAllEventUpload];
+  }
+
+  LOGW(@"Launch item %@ (executable: %@) registered by %@ is not allowed by a rule",
+       event.itemPath, event.executablePath ?: @"unknown", event.process.filePath ?: @"unknown");
+
+  if (policy == SNTLaunchItemPolicyBlock) {
+    event.enforced = [self disableLaunchItem:event];
+  }
+  return YES;
+}
+
+- (BOOL)disableLaunchItem:(SNTStoredLaunchItemEvent*)event {
+  if (event.managed) {
+    LOGW(@"Not disabling launch item %@ as it is managed by MDM", event.itemPath);
+    return NO;
+  }
+
+  NSString* domain;
+  switch (event.itemType) {
+    case SNTStoredLaunchItemEventItemTypeAgent:
+      domain = [NSString stringWithFormat:@"gui/%@", event.uid];
+      break;
+    case SNTStoredLaunchItemEventItemTypeDaemon: domain = @"system"; break;
+    default:
+      LOGW(@"Not disabling launch item %@ as only launch agents and daemons can be disabled",
+           event.itemPath);
+      return NO;
+  }
+
+  NSString* label = [NSDictionary dictionaryWithContentsOfFile:event.itemPath][@"Label"];
+  if (![label isKindOfClass:[NSString class]] || !label.length) {
+    LOGW(@"Not disabling launch item %@ as its plist has no label", event.itemPath);
+    return NO;
+  }
+  if (IsProtectedLabel(label)) {
+    LOGE(@"Not disabling launch item %@ as its label %@ is reserved", event.itemPath, label);
+    return NO;
+  }
+
+  // The label comes from a file the registering user controls, so only disable the job with that
+  // label if it was loaded from this plist. A job that isn't loaded yet is disabled by label so
+  // that it can't be loaded later.
+  NSString* loadedPath = [self plistPathOfService:label inDomain:domain];
+  if (loadedPath && ![loadedPath.stringByResolvingSymlinksInPath
+                        isEqualToString:event.itemPath.stringByResolvingSymlinksInPath]) {
+    LOGE(@"Not disabling launch item %@ as %@/%@ was loaded from %@", event.itemPath, domain,
+         label, loadedPath);
+    return NO;
+  }
+
+  if (!self.disableBlock(domain, label)) {
+    LOGE(@"Failed to disable launch item %@/%@", domain, label);
+    return NO;
+  }
+  LOGW(@"Disabled launch item %@/%@", domain, label);
+  return YES;
+}
+
+- (NSString*)plistPathOfService:(NSString*)label inDomain:(NSString*)domain {
+  NSTask* task = [[NSTask alloc] init];
+  task.executableURL = [NSURL fileURLWithPath:@"/bin/launchctl"];
+  task.arguments = @[ @"print", [NSString stringWithFormat:@"%@/%@", domain, label] ];
+  NSPipe* pipe = [NSPipe pipe];
+  task.standardOutput = pipe;
+  task.standardError = [NSFileHandle fileHandleWithNullDevice];
+
+  NSError* error;
+  if (![task launchAndReturnError:&error]) {
+    LOGE(@"Failed to run launchctl print: %@", error.localizedDescription);
+    return nil;
+  }
+  NSData* output = [pipe.fileHandleForReading readDataToEndOfFile];
+  [task waitUntilExit];
+  if (task.terminationStatus != 0) return nil;
+
+  // The service's own properties come first, so the first path line is the plist it was loaded
+  // from.
+  NSString* str = [[NSString alloc] initWithData:output encoding:NSUTF8StringEncoding];
+  for (NSString* line in [str componentsSeparatedByString:@"\n"]) {
+    NSString* trimmed =
+        [line stringByTrimmingCharactersInSet:[NSCharacterSet whitespaceCharacterSet]];
+    if ([trimmed hasPrefix:@"path = "]) return [trimmed substringFromIndex:7];
+  }
+  return nil;
+}
+
+- (MOLCodesignChecker*)codesignCheckerForPath:(NSString*)path {
+  return [[MOLCodesignChecker alloc] initWithBinaryPath:path];
+}
+
+@end
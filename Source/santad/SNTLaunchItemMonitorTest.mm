@@ -0,0 +1,293 @@
+/// Copyright 2026 North Pole Security, Inc.
+///
+/// Licensed under the Apache License, Version 2.0 (the "License");
+/// you may not use this file except in compliance with the License.
+/// You may obtain a copy of the License at
+///
+///     http://www.apache.org/licenses/LICENSE-2.0
+///
+/// Unless required by applicable law or agreed to in writing, software
+/// distributed under the License is distributed on an "AS IS" BASIS,
+/// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+/// See the License for the specific language governing permissions and
+/// limitations under the License.
+
+#include <EndpointSecurity/EndpointSecurity.h>
+#import <Foundation/Foundation.h>
+#import <OCMock/OCMock.h>
+#import <XCTest/XCTest.h>
+
+#import "Source/common/MOLCodesignChecker.h"
+#import "Source/common/SNTCommonEnums.h"
+#import "Source/common/SNTConfigurator.h"
+#import "Source/common/SNTRule.h"
+#import "Source/common/SNTRuleIdentifiers.h"
+#import "Source/common/SNTStoredLaunchItemEvent.h"
+#include "Source/common/TestUtils.h"
+#import "Source/santad/DataLayer/SNTRuleTable.h"
+#import "Source/santad/SNTLaunchItemMonitor.h"
+
+static NSString* const kTeamID = @"ABCDEF1234";
+static NSString* const kSigningID = @"ABCDEF1234:com.example.agent";
+
+@interface SNTLaunchItemMonitorTest : XCTestCase
+@property id mockConfigurator;
+@property id mockRuleTable;
+@property id mockCodesignChecker;
+@property id monitor;
+@property NSString* plistPath;
+@property NSString* loadedPlistPath;
+@property NSMutableArray<NSString*>* disabled;
+@end
+
+@implementation SNTLaunchItemMonitorTest
+
+- (void)setUp {
+  self.mockConfigurator = OCMClassMock([SNTConfigurator class]);
+  OCMStub([self.mockConfigurator configurator]).andReturn(self.mockConfigurator);
+
+  self.mockRuleTable = OCMClassMock([SNTRuleTable class]);
+
+  self.mockCodesignChecker = OCMClassMock([MOLCodesignChecker class]);
+  OCMStub([self.mockCodesignChecker teamID]).andReturn(kTeamID);
+  OCMStub([self.mockCodesignChecker signingID]).andReturn(@"com.example.agent");
+
+  self.disabled = [NSMutableArray array];
+  __weak SNTLaunchItemMonitorTest* weakSelf = self;
+  SNTLaunchItemMonitor* monitor = [[SNTLaunchItemMonitor alloc]
+      initWithRuleTable:self.mockRuleTable
+           disableBlock:^BOOL(NSString* domain, NSString* label) {
+             [weakSelf.disabled addObject:[NSString stringWithFormat:@"%@/%@", domain, label]];
+             return YES;
+           }];
+  self.monitor = OCMPartialMock(monitor);
+  OCMStub([self.monitor codesignCheckerForPath:OCMOCK_ANY]).andReturn(self.mockCodesignChecker);
+  OCMStub([self.monitor plistPathOfService:OCMOCK_ANY inDomain:OCMOCK_ANY])
+      .andDo(^(NSInvocation* inv) {
+        __unsafe_unretained NSString* path = weakSelf.loadedPlistPath;
+        [inv setReturnValue:&path];
+      });
+
+  self.plistPath = [NSTemporaryDirectory()
+      stringByAppendingPathComponent:[NSString stringWithFormat:@"%@.plist", NSUUID.UUID]];
+  [@{@"Label" : @"com.example.agent", @"Program" : @"/tmp/agent"} writeToFile:self.plistPath
+                                                                   atomically:YES];
+}
+
+- (void)tearDown {
+  [[NSFileManager defaultManager] removeItemAtPath:self.plistPath error:nil];
+  [self.monitor stopMocking];
+  [self.mockCodesignChecker stopMocking];
+  [self.mockRuleTable stopMocking];
+  [self.mockConfigurator stopMocking];
+}
+
+// Returns `rule` for lookups that include its identifier, nil otherwise.
+- (void)stubRule:(SNTRule*)rule {
+  OCMStub([self.mockRuleTable executionRuleForIdentifiers:(struct RuleIdentifiers){}])
+      .ignoringNonObjectArgs()
+      .andDo(^(NSInvocation* inv) {
+        struct RuleIdentifiers identifiers = {};
+        [inv getArgument:&identifiers atIndex:2];
+        NSString* want =
+            rule.type == SNTRuleTypeTeamID ? identifiers.teamID : identifiers.signingID;
+        __unsafe_unretained SNTRule* ret = [rule.identifier isEqualToString:want] ? rule : nil;
+        [inv setReturnValue:&ret];
+      });
+}
+
+- (SNTStoredLaunchItemEvent*)eventWithType:(SNTStoredLaunchItemEventItemType)type {
+  SNTStoredLaunchItemEvent* event = [[SNTStoredLaunchItemEvent alloc] init];
+  event.itemType = type;
+  event.uid = @(501);
+  event.itemPath = self.plistPath;
+  event.executablePath = @"/tmp/agent";
+  return event;
+}
+
+- (void)testAllowedByTeamIDRule {
+  [self stubRule:[[SNTRule alloc] initWithIdentifier:kTeamID
+                                               state:SNTRuleStateAllow
+                                                type:SNTRuleTypeTeamID]];
+  SNTStoredLaunchItemEvent* event = [self eventWithType:SNTStoredLaunchItemEventItemTypeAgent];
+
+  XCTAssertFalse([self.monitor evaluateLaunchItem:event policy:SNTLaunchItemPolicyBlock]);
+  XCTAssertEqual(event.decision, SNTStoredLaunchItemEventDecisionAllowed);
+  XCTAssertEqualObjects(event.teamID, kTeamID);
+  XCTAssertEqualObjects(event.signingID, kSigningID);
+  XCTAssertFalse(event.enforced);
+  XCTAssertEqual(self.disabled.count, 0);
+}
+
+- (void)testAllowedItemRecordedWithAllEventUpload {
+  OCMStub([self.mockConfigurator enableAllEventUpload]).andReturn(YES);
+  [self stubRule:[[SNTRule alloc] initWithIdentifier:kTeamID
+                                               state:SNTRuleStateAllow
+                                                type:SNTRuleTypeTeamID]];
+  SNTStoredLaunchItemEvent* event = [self eventWithType:SNTStoredLaunchItemEventItemTypeAgent];
+
+  XCTAssertTrue([self.monitor evaluateLaunchItem:event policy:SNTLaunchItemPolicyMonitor]);
+  XCTAssertEqual(event.decision, SNTStoredLaunchItemEventDecisionAllowed);
+}
+
+- (void)testBlockedBySigningIDRuleDisablesAgent {
+  [self stubRule:[[SNTRule alloc] initWithIdentifier:kSigningID
+                                               state:SNTRuleStateBlock
+                                                type:SNTRuleTypeSigningID]];
+  SNTStoredLaunchItemEvent* event = [self eventWithType:SNTStoredLaunchItemEventItemTypeAgent];
+
+  XCTAssertTrue([self.monitor evaluateLaunchItem:event policy:SNTLaunchItemPolicyBlock]);
+  XCTAssertEqual(event.decision, SNTStoredLaunchItemEventDecisionBlocked);
+  XCTAssertTrue(event.enforced);
+  XCTAssertEqualObjects(self.disabled, @[ @"gui/501/com.example.agent" ]);
+}
+
+- (void)testBlockedDaemonDisabledInSystemDomain {
+  [self stubRule:[[SNTRule alloc] initWithIdentifier:kTeamID
+                                               state:SNTRuleStateSilentBlock
+                                                type:SNTRuleTypeTeamID]];
+  SNTStoredLaunchItemEvent* event = [self eventWithType:SNTStoredLaunchItemEventItemTypeDaemon];
+
+  XCTAssertTrue([self.monitor evaluateLaunchItem:event policy:SNTLaunchItemPolicyBlock]);
+  XCTAssertEqual(event.decision, SNTStoredLaunchItemEventDecisionBlocked);
+  XCTAssertTrue(event.enforced);
+  XCTAssertEqualObjects(self.disabled, @[ @"system/com.example.agent" ]);
+}
+
+- (void)testBlockedItemOnlyReportedInMonitorPolicy {
+  [self stubRule:[[SNTRule alloc] initWithIdentifier:kSigningID
+                                               state:SNTRuleStateBlock
+                                                type:SNTRuleTypeSigningID]];
+  SNTStoredLaunchItemEvent* event = [self eventWithType:SNTStoredLaunchItemEventItemTypeAgent];
+
+  XCTAssertTrue([self.monitor evaluateLaunchItem:event policy:SNTLaunchItemPolicyMonitor]);
+  XCTAssertEqual(event.decision, SNTStoredLaunchItemEventDecisionBlocked);
+  XCTAssertFalse(event.enforced);
+  XCTAssertEqual(self.disabled.count, 0);
+}
+
+- (void)testUnknownItemDisabledInBlockPolicy {
+  SNTStoredLaunchItemEvent* event = [self eventWithType:SNTStoredLaunchItemEventItemTypeAgent];
+
+  XCTAssertTrue([self.monitor evaluateLaunchItem:event policy:SNTLaunchItemPolicyBlock]);
+  XCTAssertEqual(event.decision, SNTStoredLaunchItemEventDecisionUnknown);
+  XCTAssertTrue(event.enforced);
+  XCTAssertEqualObjects(self.disabled, @[ @"gui/501/com.example.agent" ]);
+}
+
+- (void)testItemLoadedFromThisPlistIsDisabled {
+  self.loadedPlistPath = self.plistPath;
+  SNTStoredLaunchItemEvent* event = [self eventWithType:SNTStoredLaunchItemEventItemTypeAgent];
+
+  XCTAssertTrue([self.monitor evaluateLaunchItem:event policy:SNTLaunchItemPolicyBlock]);
+  XCTAssertTrue(event.enforced);
+  XCTAssertEqualObjects(self.disabled, @[ @"gui/501/com.example.agent" ]);
+}
+
+- (void)testLabelOfJobLoadedFromAnotherPlistIsNotDisabled {
+  self.loadedPlistPath = @"/Library/LaunchAgents/com.example.agent.plist";
+  SNTStoredLaunchItemEvent* event = [self eventWithType:SNTStoredLaunchItemEventItemTypeAgent];
+
+  XCTAssertTrue([self.monitor evaluateLaunchItem:event policy:SNTLaunchItemPolicyBlock]);
+  XCTAssertEqual(event.decision, SNTStoredLaunchItemEventDecisionUnknown);
+  XCTAssertFalse(event.enforced);
+  XCTAssertEqual(self.disabled.count, 0);
+}
+
+- (void)testReservedLabelsAreNotDisabled {
+  for (NSString* label in @[ @"com.northpolesec.santa", @"com.northpolesec.santa.daemon",
+                             @"com.apple.Finder" ]) {
+    [@{@"Label" : label, @"Program" : @"/tmp/agent"} writeToFile:self.plistPath atomically:YES];
+    SNTStoredLaunchItemEvent* event = [self eventWithType:SNTStoredLaunchItemEventItemTypeAgent];
+
+    XCTAssertTrue([self.monitor evaluateLaunchItem:event policy:SNTLaunchItemPolicyBlock]);
+    XCTAssertFalse(event.enforced, @"%@", label);
+  }
+  XCTAssertEqual(self.disabled.count, 0);
+}
+
+- (void)testManagedItemIsNotDisabled {
+  SNTStoredLaunchItemEvent* event = [self eventWithType:SNTStoredLaunchItemEventItemTypeDaemon];
+  event.managed = YES;
+
+  XCTAssertTrue([self.monitor evaluateLaunchItem:event policy:SNTLaunchItemPolicyBlock]);
+  XCTAssertEqual(event.decision, SNTStoredLaunchItemEventDecisionUnknown);
+  XCTAssertFalse(event.enforced);
+  XCTAssertEqual(self.disabled.count, 0);
+}
+
+- (void)testLoginItemIsReportedButNotDisabled {
+  SNTStoredLaunchItemEvent* event = [self eventWithType:SNTStoredLaunchItemEventItemTypeLoginItem];
+
+  XCTAssertTrue([self.monitor evaluateLaunchItem:event policy:SNTLaunchItemPolicyBlock]);
+  XCTAssertEqual(event.decision, SNTStoredLaunchItemEventDecisionUnknown);
+  XCTAssertFalse(event.enforced);
+  XCTAssertEqual(self.disabled.count, 0);
+}
+
+- (void)testPlatformBinaryIsAllowed {
+  OCMStub([self.mockCodesignChecker platformBinary]).andReturn(YES);
+  SNTStoredLaunchItemEvent* event = [self eventWithType:SNTStoredLaunchItemEventItemTypeDaemon];
+
+  XCTAssertFalse([self.monitor evaluateLaunchItem:event policy:SNTLaunchItemPolicyBlock]);
+  XCTAssertEqual(event.decision, SNTStoredLaunchItemEventDecisionAllowed);
+  XCTAssertEqual(self.disabled.count, 0);
+}
+
+- (void)testHandleLaunchItemAdd {
+  OCMStub([self.mockConfigurator launchItemPolicy]).andReturn(SNTLaunchItemPolicyBlock);
+  [self stubRule:[[SNTRule alloc] initWithIdentifier:kTeamID
+                                               state:SNTRuleStateBlock
+                                                type:SNTRuleTypeTeamID]];
+
+  es_file_t file = MakeESFile("/usr/bin/installer");
+  es_process_t proc = MakeESProcess(&file, MakeAuditToken(12345, 1));
+  es_btm_launch_item_t item = {
+      .item_type = ES_BTM_ITEM_TYPE_AGENT,
+      .legacy = true,
+      .managed = false,
+      .uid = 501,
+      .item_url = MakeESStringToken(self.plistPath.UTF8String),
+      .app_url = MakeESStringToken(""),
+  };
+  es_event_btm_launch_item_add_t btm = {
+      .instigator = &proc,
+      .app = NULL,
+      .item = &item,
+      .executable_path = MakeESStringToken("/tmp/agent"),
+  };
+
+  XCTestExpectation* expectation = [self expectationWithDescription:@"Event callback"];
+  __block SNTStoredLaunchItemEvent* got;
+  ((SNTLaunchItemMonitor*)self.monitor).eventCallback = ^(SNTStoredLaunchItemEvent* event) {
+    got = event;
+    [expectation fulfill];
+  };
+
+  [self.monitor handleLaunchItemAdd:&btm];
+  [self waitForExpectationsWithTimeout:5 handler:nil];
+
+  XCTAssertEqual(got.itemType, SNTStoredLaunchItemEventItemTypeAgent);
+  XCTAssertTrue(got.legacy);
+  XCTAssertEqualObjects(got.uid, @(501));
+  XCTAssertEqualObjects(got.itemPath, self.plistPath);
+  XCTAssertNil(got.appPath);
+  XCTAssertEqualObjects(got.executablePath, @"/tmp/agent");
+  XCTAssertEqualObjects(got.process.filePath, @"/usr/bin/installer");
+  XCTAssertEqualObjects(got.process.pid, @(12345));
+  XCTAssertEqual(got.decision, SNTStoredLaunchItemEventDecisionBlocked);
+  XCTAssertTrue(got.enforced);
+  XCTAssertEqualObjects(self.disabled, @[ @"gui/501/com.example.agent" ]);
+}
+
+- (void)testHandleLaunchItemAddIgnoredWithoutPolicy {
+  OCMStub([self.mockConfigurator launchItemPolicy]).andReturn(SNTLaunchItemPolicyNone);
+  OCMReject([self.monitor evaluateLaunchItem:OCMOCK_ANY policy:SNTLaunchItemPolicyNone])
+      .ignoringNonObjectArgs();
+
+  es_btm_launch_item_t item = {.item_type = ES_BTM_ITEM_TYPE_AGENT};
+  es_event_btm_launch_item_add_t btm = {.item = &item};
+  [self.monitor handleLaunchItemAdd:&btm];
+}
+
+@end
@@ -0,0 +1,44 @@
+/// Copyright 2026 North Pole Security, Inc.
+///
+/// Licensed under the Apache License, Version 2.0 (the "License");
+/// you may not use this file except in compliance with the License.
+/// You may obtain a copy of the License at
+///
+///     http://www.apache.org/licenses/LICENSE-2.0
+///
+/// Unless required by applicable law or agreed to in writing, software
+/// distributed under the License is distributed on an "AS IS" BASIS,
+/// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+/// See the License for the specific language governing permissions and
+/// limitations under the License.
+
+
+
+#import <Foundation/Foundation.h>
+
+NS_ASSUME_NONNULL_BEGIN
+
+///
+///  Tracks the grace period that follows a change from Monitor to Lockdown mode. While the grace
+///  period is active, executions that Lockdown would block because no rule matched them are
+///  allowed and logged instead, so that work already in progress is not abruptly broken. Once it
+///  ends, Lockdown is enforced on the next execution.
+///
+@interface SNTLockdownGracePeriod : NSObject
+
++ (instancetype)sharedGracePeriod;
+
+///
+///  Begin a grace period lasting duration seconds. Any grace period already in progress is
+///  restarted. A duration of 0 ends any grace period in progress.
+///
+- (void)startWithDuration:(NSTimeInterval)duration;
+
+///
+///  Returns YES if a grace period is in progress.
+///
+- (BOOL)isActive;
+
+@end
+
+NS_ASSUME_NONNULL_END
@@ -0,0 +1,80 @@
+/// Copyright 2026 North Pole Security, Inc.
+///
+/// Licensed under the Apache License, Version 2.0 (the "License");
+/// you may not use this file except in compliance with the License.
+/// You may obtain a copy of the License at
+///
+///     http://www.apache.org/licenses/LICENSE-2.0
+///
+/// Unless required by applicable law or agreed to in writing, software
+/// distributed under the License is distributed on an "AS IS" BASIS,
+/// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+/// See the License for the specific language governing permissions and
+/// limitations under the License.
+
+
+
+#import "Source/santad/SNTLockdownGracePeriod.h"
+
+#import "Source/common/SNTLogging.h"
+
+@interface SNTLockdownGracePeriod ()
+@property dispatch_queue_t q;
+@property NSDate* endDate;
+@end
+
+@implementation SNTLockdownGracePeriod
+
++ (instancetype)sharedGracePeriod {
+  static SNTLockdownGracePeriod* gracePeriod;
+  static dispatch_once_t onceToken;
+  dispatch_once(&onceToken, ^{
+    gracePeriod = [[SNTLockdownGracePeriod alloc] init];
+  });
+  return gracePeriod;
+}
+
+- (instancetype)init {
+  self = [super init];
+  if (self) {
+    _q = dispatch_queue_create("com.northpolesec.santa.daemon.lockdown_grace_period",
+                               DISPATCH_QUEUE_SERIAL);
+  }
+  return self;
+}
+
+- (void)startWithDuration:(NSTimeInterval)duration {
+  [self startWithDuration:duration now:[NSDate date]];
+}
+
+- (void)startWithDuration:(NSTimeInterval)duration now:(NSDate*)now {
+  dispatch_sync(self.q, ^{
+    [self endSerializedIfExpired:[NSDate distantFuture]];
+    if (duration <= 0) return;
+
+    LOGI(@"Lockdown grace period: allowing and logging unknown binaries for %.0fs", duration);
+    self.endDate = [now dateByAddingTimeInterval:duration];
+  });
+}
+
+- (BOOL)isActive {
+  return [self isActiveAt:[NSDate date]];
+}
+
+- (BOOL)isActiveAt:(NSDate*)now {
+  __block BOOL active = NO;
+  dispatch_sync(self.q, ^{
+    [self endSerializedIfExpired:now];
+    active = self.endDate != nil;
+  });
+  return active;
+}
+
+- (void)endSerializedIfExpired:(NSDate*)now {
+  if (!self.endDate || [now compare:self.endDate] == NSOrderedAscending) return;
+
+  LOGI(@"Lockdown grace period finished: enforcing Lockdown");
+  self.endDate = nil;
+}
+
+@end
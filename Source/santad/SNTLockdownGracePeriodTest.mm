@@ -0,0 +1,74 @@
+/// Copyright 2026 North Pole Security, Inc.
+///
+/// Licensed under the Apache License, Version 2.0 (the "License");
+/// you may not use this file except in compliance with the License.
+/// You may obtain a copy of the License at
+///
+///     http://www.apache.org/licenses/LICENSE-2.0
+///
+/// Unless required by applicable law or agreed to in writing, software
+/// distributed under the License is distributed on an "AS IS" BASIS,
+/// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+/// See the License for the specific language governing permissions and
+/// limitations under the License.
+
+
+
+#import <Foundation/Foundation.h>
+#import <XCTest/XCTest.h>
+
+#import "Source/santad/SNTLockdownGracePeriod.h"
+
+@interface SNTLockdownGracePeriod (Testing)
+- (void)startWithDuration:(NSTimeInterval)duration now:(NSDate*)now;
+- (BOOL)isActiveAt:(NSDate*)now;
+@end
+
+@interface SNTLockdownGracePeriodTest : XCTestCase
+@property SNTLockdownGracePeriod* sut;
+@property NSDate* start;
+@end
+
+@implementation SNTLockdownGracePeriodTest
+
+- (void)setUp {
+  [super setUp];
+  self.sut = [[SNTLockdownGracePeriod alloc] init];
+  self.start = [NSDate date];
+}
+
+- (NSDate*)after:(NSTimeInterval)seconds {
+  return [self.start dateByAddingTimeInterval:seconds];
+}
+
+- (void)testInactiveByDefault {
+  XCTAssertFalse([self.sut isActiveAt:self.start]);
+}
+
+- (void)testActiveUntilDurationElapses {
+  [self.sut startWithDuration:300 now:self.start];
+
+  XCTAssertTrue([self.sut isActiveAt:self.start]);
+  XCTAssertTrue([self.sut isActiveAt:[self after:299]]);
+  XCTAssertFalse([self.sut isActiveAt:[self after:300]]);
+
+  // Once expired the grace period stays ended.
+  XCTAssertFalse([self.sut isActiveAt:[self after:1]]);
+}
+
+- (void)testRestartExtendsGracePeriod {
+  [self.sut startWithDuration:300 now:self.start];
+  [self.sut startWithDuration:300 now:[self after:200]];
+
+  XCTAssertTrue([self.sut isActiveAt:[self after:400]]);
+  XCTAssertFalse([self.sut isActiveAt:[self after:500]]);
+}
+
+- (void)testZeroDurationEndsGracePeriod {
+  [self.sut startWithDuration:300 now:self.start];
+  [self.sut startWithDuration:0 now:[self after:10]];
+
+  XCTAssertFalse([self.sut isActiveAt:[self after:20]]);
+}
+
+@end
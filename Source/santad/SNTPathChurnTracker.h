@@ -0,0 +1,46 @@
+/// Copyright 2026 North Pole Security, Inc.
+///
+/// Licensed under the Apache License, Version 2.0 (the "License");
+/// you may not use this file except in compliance with the License.
+/// You may obtain a copy of the License at
+///
+///     http://www.apache.org/licenses/LICENSE-2.0
+///
+/// Unless required by applicable law or agreed to in writing, software
+/// distributed under the License is distributed on an "AS IS" BASIS,
+/// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+/// See the License for the specific language governing permissions and
+/// limitations under the License.
+
+#import <Foundation/Foundation.h>
+
+NS_ASSUME_NONNULL_BEGIN
+
+///
+///  Tracks how often the binary at each path changes. Malware may rewrite itself at a stable path
+///  so that every execution has a new SHA-256 and no hash rule ever matches; frequent changes at
+///  one path are a sign of this. Changes are only kept in memory. Once the capacity is reached,
+///  paths that have not been seen within the window are forgotten to make room, and if there are
+///  none, new paths are not tracked.
+///
+@interface SNTPathChurnTracker : NSObject
+
++ (instancetype)sharedTracker;
+
+- (instancetype)initWithCapacity:(NSUInteger)capacity NS_DESIGNATED_INITIALIZER;
+- (instancetype)init NS_UNAVAILABLE;
+
+///
+///  Record that the binary at path had the given SHA-256 when it was evaluated at date.
+///
+///  @return The number of times the SHA-256 at path changed in the window seconds up to and
+///          including date. The first time a path is seen doesn't count as a change.
+///
+- (NSUInteger)recordSHA256:(NSString*)sha256
+                    atPath:(NSString*)path
+                      date:(NSDate*)date
+                    window:(NSTimeInterval)window;
+
+@end
+
+NS_ASSUME_NONNULL_END
@@ -0,0 +1,104 @@
+/// Copyright 2026 North Pole Security, Inc.
+///
+/// Licensed under the Apache License, Version 2.0 (the "License");
+/// you may not use this file except in compliance with the License.
+/// You may obtain a copy of the License at
+///
+///     http://www.apache.org/licenses/LICENSE-2.0
+///
+/// Unless required by applicable law or agreed to in writing, software
+/// distributed under the License is distributed on an "AS IS" BASIS,
+/// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+/// See the License for the specific language governing permissions and
+/// limitations under the License.
+
+#import "Source/santad/SNTPathChurnTracker.h"
+
+static const NSUInteger kDefaultPathChurnTrackerCapacity = 10000;
+
+// The last SHA-256 seen at a path, when it was seen and when it changed, oldest first.
+@interface SNTPathChurnEntry : NSObject
+@property NSString* sha256;
+@property NSDate* lastSeen;
+@property NSMutableArray<NSDate*>* changes;
+@end
+
+@implementation SNTPathChurnEntry
+@end
+
+@implementation SNTPathChurnTracker {
+  NSMutableDictionary<NSString*, SNTPathChurnEntry*>* _entries;
+  NSUInteger _capacity;
+  dispatch_queue_t _q;
+}
+
++ (instancetype)sharedTracker {
+  static SNTPathChurnTracker* tracker;
+  static dispatch_once_t onceToken;
+  dispatch_once(&onceToken, ^{
+    tracker = [[SNTPathChurnTracker alloc] initWithCapacity:kDefaultPathChurnTrackerCapacity];
+  });
+  return tracker;
+}
+
+- (instancetype)initWithCapacity:(NSUInteger)capacity {
+  self = [super init];
+  if (self) {
+    _entries = [NSMutableDictionary dictionary];
+    _capacity = capacity;
+    _q = dispatch_queue_create("com.northpolesec.santa.daemon.path_churn",
+                               DISPATCH_QUEUE_SERIAL_WITH_AUTORELEASE_POOL);
+  }
+  return self;
+}
+
+- (NSUInteger)recordSHA256:(NSString*)sha256
+                    atPath:(NSString*)path
+                      date:(NSDate*)date
+                    window:(NSTimeInterval)window {
+  if (!sha256.length || !path.length) return 0;
+
+  NSDate* cutoff = [date dateByAddingTimeInterval:-window];
+  __block NSUInteger count = 0;
+  dispatch_sync(_q, ^{
+    SNTPathChurnEntry* entry = _entries[path];
+    if (!entry) {
+      if (_entries.count >= _capacity) [self pruneEntriesBefore:cutoff];
+      if (_entries.count >= _capacity) return;
+      entry = [[SNTPathChurnEntry alloc] init];
+      entry.sha256 = sha256;
+      entry.changes = [NSMutableArray array];
+      _entries[path] = entry;
+    } else if (![entry.sha256 isEqualToString:sha256]) {
+      entry.sha256 = sha256;
+      [entry.changes addObject:date];
+    }
+    entry.lastSeen = date;
+
+    [self removeChangesBefore:cutoff fromEntry:entry];
+    count = entry.changes.count;
+  });
+  return count;
+}
+
+// Must be called on _q.
+- (void)removeChangesBefore:(NSDate*)cutoff fromEntry:(SNTPathChurnEntry*)entry {
+  NSUInteger expired = 0;
+  while (expired < entry.changes.count &&
+         [entry.changes[expired] compare:cutoff] == NSOrderedAscending) {
+    expired++;
+  }
+  [entry.changes removeObjectsInRange:NSMakeRange(0, expired)];
+}
+
+// Forget paths that haven't been seen since cutoff. Must be called on _q.
+- (void)pruneEntriesBefore:(NSDate*)cutoff {
+  NSMutableArray<NSString*>* stale = [NSMutableArray array];
+  [_entries enumerateKeysAndObjectsUsingBlock:^(NSString* path, SNTPathChurnEntry* entry,
+                                                BOOL* stop) {
+    if ([entry.lastSeen compare:cutoff] == NSOrderedAscending) [stale addObject:path];
+  }];
+  [_entries removeObjectsForKeys:stale];
+}
+
+@end
@@ -0,0 +1,133 @@
+/// Copyright 2026 North Pole Security, Inc.
+///
+/// Licensed under the Apache License, Version 2.0 (the "License");
+/// you may not use this file except in compliance with the License.
+/// You may obtain a copy of the License at
+///
+///     http://www.apache.org/licenses/LICENSE-2.0
+///
+/// Unless required by applicable law or agreed to in writing, software
+/// distributed under the License is distributed on an "AS IS" BASIS,
+/// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+/// See the License for the specific language governing permissions and
+/// limitations under the License.
+
+#import "Source/santad/SNTPathChurnTracker.h"
+
+#import <Foundation/Foundation.h>
+#import <XCTest/XCTest.h>
+
+static const NSTimeInterval kWindow = 60;
+
+@interface SNTPathChurnTrackerTest : XCTestCase
+@end
+
+@implementation SNTPathChurnTrackerTest
+
+- (NSString*)hashWithIndex:(int)i {
+  return [NSString stringWithFormat:@"%064x", i];
+}
+
+- (void)testFrequentRewritesAtOnePathAreCounted {
+  SNTPathChurnTracker* sut = [[SNTPathChurnTracker alloc] initWithCapacity:16];
+  NSDate* start = [NSDate date];
+
+  // The first hash seen at a path is not a change.
+  XCTAssertEqual([sut recordSHA256:[self hashWithIndex:0]
+                            atPath:@"/tmp/tool"
+                              date:start
+                            window:kWindow],
+                 0);
+
+  for (int i = 1; i <= 5; i++) {
+    XCTAssertEqual([sut recordSHA256:[self hashWithIndex:i]
+                              atPath:@"/tmp/tool"
+                                date:[start dateByAddingTimeInterval:i]
+                              window:kWindow],
+                   i);
+  }
+
+  // Executing the same binary again doesn't add a change.
+  XCTAssertEqual([sut recordSHA256:[self hashWithIndex:5]
+                            atPath:@"/tmp/tool"
+                              date:[start dateByAddingTimeInterval:6]
+                            window:kWindow],
+                 5);
+
+  // Other paths are tracked separately.
+  XCTAssertEqual([sut recordSHA256:[self hashWithIndex:0]
+                            atPath:@"/tmp/other"
+                              date:start
+                            window:kWindow],
+                 0);
+}
+
+- (void)testChangesOutsideTheWindowExpire {
+  SNTPathChurnTracker* sut = [[SNTPathChurnTracker alloc] initWithCapacity:16];
+  NSDate* start = [NSDate date];
+
+  for (int i = 0; i < 4; i++) {
+    [sut recordSHA256:[self hashWithIndex:i]
+               atPath:@"/tmp/tool"
+                 date:[start dateByAddingTimeInterval:i * 20]
+               window:kWindow];
+  }
+
+  // Changes at 20s, 40s and 60s are within 60s of 61s; the one at 20s isn't once it's 81s.
+  XCTAssertEqual([sut recordSHA256:[self hashWithIndex:3]
+                            atPath:@"/tmp/tool"
+                              date:[start dateByAddingTimeInterval:61]
+                            window:kWindow],
+                 3);
+  XCTAssertEqual([sut recordSHA256:[self hashWithIndex:3]
+                            atPath:@"/tmp/tool"
+                              date:[start dateByAddingTimeInterval:81]
+                            window:kWindow],
+                 2);
+  XCTAssertEqual([sut recordSHA256:[self hashWithIndex:4]
+                            atPath:@"/tmp/tool"
+                              date:[start dateByAddingTimeInterval:200]
+                            window:kWindow],
+                 1);
+}
+
+- (void)testCapacityForgetsIdlePaths {
+  SNTPathChurnTracker* sut = [[SNTPathChurnTracker alloc] initWithCapacity:2];
+  NSDate* start = [NSDate date];
+
+  [sut recordSHA256:[self hashWithIndex:0] atPath:@"/tmp/a" date:start window:kWindow];
+  [sut recordSHA256:[self hashWithIndex:1] atPath:@"/tmp/a" date:start window:kWindow];
+  [sut recordSHA256:[self hashWithIndex:0] atPath:@"/tmp/b" date:start window:kWindow];
+
+  // Both paths were seen within the window, so a new path isn't tracked.
+  [sut recordSHA256:[self hashWithIndex:0] atPath:@"/tmp/c" date:start window:kWindow];
+  XCTAssertEqual([sut recordSHA256:[self hashWithIndex:1]
+                            atPath:@"/tmp/c"
+                              date:start
+                            window:kWindow],
+                 0);
+  XCTAssertEqual([sut recordSHA256:[self hashWithIndex:2]
+                            atPath:@"/tmp/a"
+                              date:start
+                            window:kWindow],
+                 2);
+
+  // Once the window has passed, the idle paths make room for new ones.
+  NSDate* later = [start dateByAddingTimeInterval:kWindow * 2];
+  [sut recordSHA256:[self hashWithIndex:0] atPath:@"/tmp/c" date:later window:kWindow];
+  XCTAssertEqual([sut recordSHA256:[self hashWithIndex:1]
+                            atPath:@"/tmp/c"
+                              date:later
+                            window:kWindow],
+                 1);
+}
+
+- (void)testMissingHashOrPathIsIgnored {
+  SNTPathChurnTracker* sut = [[SNTPathChurnTracker alloc] initWithCapacity:16];
+  NSDate* now = [NSDate date];
+
+  XCTAssertEqual([sut recordSHA256:@"" atPath:@"/tmp/tool" date:now window:kWindow], 0);
+  XCTAssertEqual([sut recordSHA256:[self hashWithIndex:0] atPath:@"" date:now window:kWindow], 0);
+}
+
+@end
@@ -75,4 +75,22 @@ using ActivationCallbackBlock =
          withTransitiveRules:(BOOL)transitive
     andCELActivationCallback:(nullable ActivationCallbackBlock)activationCallback;
 
+///
+/// Applies a binary rule matching the SHA-256 of the script an interpreter was
+/// asked to run on top of the interpreter's decision. A block rule blocks the
+/// script even if the interpreter is allowed. An allow rule allows the script
+/// only if the interpreter was blocked for being unknown. The decision is
+/// marked as not cacheable either way.
+///
+/// Returns YES if a script rule changed the decision, NO otherwise.
+- (BOOL)applyScriptRuleForScript:(nonnull SNTFileInfo*)scriptInfo
+                        decision:(nonnull SNTCachedDecision*)cd;
+
+///
+/// As applyScriptRuleForScript:decision: for a script identified only by its
+/// SHA-256, e.g. source passed inline on an interpreter's command line.
+///
+- (BOOL)applyScriptRuleForScriptSHA256:(nullable NSString*)scriptSHA256
+                              decision:(nonnull SNTCachedDecision*)cd;
+
 @end
@@ -24,11 +24,13 @@
 #import "Source/common/CertificateHelpers.h"
 #include "Source/common/CodeSigningIdentifierUtils.h"
 #import "Source/common/MOLCodesignChecker.h"
+#include "Source/common/PathCanonicalization.h"
 #import "Source/common/SNTCELFallbackRule.h"
 #import "Source/common/SNTCachedDecision.h"
 #import "Source/common/SNTConfigurator.h"
 #import "Source/common/SNTDeepCopy.h"
 #import "Source/common/SNTFileInfo.h"
+#import "Source/common/SNTFileProvenance.h"
 #import "Source/common/SNTKVOManager.h"
 #import "Source/common/SNTLogging.h"
 #import "Source/common/SNTRule.h"
@@ -37,6 +39,12 @@
 #include "Source/common/cel/CELPlanCache.h"
 #include "Source/common/cel/Evaluator.h"
 #import "Source/santad/DataLayer/SNTRuleTable.h"
+#include "Source/santad/DecisionHook.h"
+#import "Source/santad/SNTAllowOnceStore.h"
+#import "Source/santad/SNTFileProvenanceIndex.h"
+#import "Source/santad/SNTLockdownGracePeriod.h"
+#import "Source/santad/SNTPathChurnTracker.h"
+#import "Source/santad/SNTRuleSyncWindow.h"
 #include "absl/container/flat_hash_map.h"
 #include "absl/status/statusor.h"
 #include "cel/v1.pb.h"
@@ -68,6 +76,36 @@ struct RuleIdentifiers CreateRuleIDs(SNTCachedDecision* cd) {
                               forSigningStatus:cd.signingStatus];
 }
 
+// Returns the Signing IDs a bundle Signing ID rule can match a binary with, most specific first.
+// These are the binary's own Signing ID followed by each of its prefixes at a "." boundary, so
+// that a rule for a bundle's Signing ID also matches helpers signed as e.g.
+// `com.example.app.helper`. If the binary is part of a bundle whose main executable is signed
+// by the same team, that executable's Signing ID and its prefixes follow.
+NSArray<NSString*>* BundleSigningIDCandidates(NSString* signingID, NSString* bundleSigningID) {
+  NSMutableOrderedSet<NSString*>* candidates = [NSMutableOrderedSet orderedSet];
+  NSString* team;
+  for (NSString* sid in @[ signingID ?: @"", bundleSigningID ?: @"" ]) {
+    NSRange sep = [sid rangeOfString:@":"];
+    if (sep.location == NSNotFound || sep.location == 0) continue;
+
+    NSString* prefix = [sid substringToIndex:sep.location];
+    if (!team) {
+      team = prefix;
+    } else if (![team isEqualToString:prefix]) {
+      continue;
+    }
+
+    NSString* identifier = [sid substringFromIndex:NSMaxRange(sep)];
+    while (identifier.length) {
+      [candidates addObject:[NSString stringWithFormat:@"%@:%@", prefix, identifier]];
+      NSRange dot = [identifier rangeOfString:@"." options:NSBackwardsSearch];
+      if (dot.location == NSNotFound) break;
+      identifier = [identifier substringToIndex:dot.location];
+    }
+  }
+  return candidates.array;
+}
+
 namespace {
 
 // A compiled CEL fallback rule: the plan plus the customMsg/URL to report when
@@ -127,7 +165,12 @@ struct FallbackBatch {
 }
 @property SNTRuleTable* ruleTable;
 @property SNTConfigurator* configurator;
+@property SNTAllowOnceStore* allowOnceStore;
 @property SNTKVOManager* celFallbackRulesObserver;
+// SHA-256s and bundle identifiers of binaries that were blocked by the
+// ResignProtectedBlocklist. Guarded by @synchronized on resignProtectedSHA256s.
+@property NSMutableSet<NSString*>* resignProtectedSHA256s;
+@property NSMutableSet<NSString*>* resignProtectedBundleIDs;
 @end
 
 @implementation SNTPolicyProcessor
@@ -136,6 +179,9 @@ struct FallbackBatch {
   self = [super init];
   if (self) {
     _configurator = [SNTConfigurator configurator];
+    _allowOnceStore = [SNTAllowOnceStore sharedStore];
+    _resignProtectedSHA256s = [NSMutableSet set];
+    _resignProtectedBundleIDs = [NSMutableSet set];
 
     auto evaluatorV1 = santa::cel::Evaluator<false>::Create();
     if (evaluatorV1.ok()) {
@@ -497,6 +543,16 @@ static void ApplySilentBlock(SNTCachedDecision* cd, SNTRuleState state) {
           {{SNTRuleTypeTeamID, SNTRuleStateSilentBlockGUI}, SNTEventStateBlockTeamID},
           {{SNTRuleTypeTeamID, SNTRuleStateSilentBlockTTY}, SNTEventStateBlockTeamID},
           {{SNTRuleTypeTeamID, SNTRuleStateBlock}, SNTEventStateBlockTeamID},
+          {{SNTRuleTypeRequirement, SNTRuleStateAllow}, SNTEventStateAllowRequirement},
+          {{SNTRuleTypeRequirement, SNTRuleStateSilentBlock}, SNTEventStateBlockRequirement},
+          {{SNTRuleTypeRequirement, SNTRuleStateSilentBlockGUI}, SNTEventStateBlockRequirement},
+          {{SNTRuleTypeRequirement, SNTRuleStateSilentBlockTTY}, SNTEventStateBlockRequirement},
+          {{SNTRuleTypeRequirement, SNTRuleStateBlock}, SNTEventStateBlockRequirement},
+          {{SNTRuleTypeBundleSigningID, SNTRuleStateAllow}, SNTEventStateAllowSigningID},
+          {{SNTRuleTypeBundleSigningID, SNTRuleStateSilentBlock}, SNTEventStateBlockSigningID},
+          {{SNTRuleTypeBundleSigningID, SNTRuleStateSilentBlockGUI}, SNTEventStateBlockSigningID},
+          {{SNTRuleTypeBundleSigningID, SNTRuleStateSilentBlockTTY}, SNTEventStateBlockSigningID},
+          {{SNTRuleTypeBundleSigningID, SNTRuleStateBlock}, SNTEventStateBlockSigningID},
           // Seatbelt rules start out as a block of the rule's type. If the
           // ancestor/sandbox check succeeds in the execution controller, the
           // decision is flipped to the matching allow state via
@@ -507,6 +563,8 @@ static void ApplySilentBlock(SNTCachedDecision* cd, SNTRuleState state) {
           {{SNTRuleTypeSigningID, SNTRuleStateSeatbelt}, SNTEventStateBlockSigningID},
           {{SNTRuleTypeCertificate, SNTRuleStateSeatbelt}, SNTEventStateBlockCertificate},
           {{SNTRuleTypeTeamID, SNTRuleStateSeatbelt}, SNTEventStateBlockTeamID},
+          {{SNTRuleTypeRequirement, SNTRuleStateSeatbelt}, SNTEventStateBlockRequirement},
+          {{SNTRuleTypeBundleSigningID, SNTRuleStateSeatbelt}, SNTEventStateBlockSigningID},
       };
 
   auto iterator = decisions.find(std::pair<SNTRuleType, SNTRuleState>{type, state});
@@ -566,7 +624,206 @@ static void ApplySilentBlock(SNTCachedDecision* cd, SNTRuleState state) {
   cd.customURL = rule.customURL;
   cd.staticRule = rule.staticRule;
   cd.ruleId = rule.ruleId;
+  cd.severity = rule.severity;
+
+  return YES;
+}
+
+// Applies the ResignProtectedBlocklist. A binary signed by one of the listed
+// identities is blocked and its SHA-256 and bundle identifier are recorded. A
+// binary whose SHA-256 or bundle identifier matches a recorded one, or whose
+// bundle identifier matches the identifier part of a listed Signing ID, is
+// blocked no matter how (or whether) it is signed.
+//
+// It returns YES if the decision was made, NO if the decision was not made.
+- (BOOL)applyResignProtectedBlocklist:(SNTCachedDecision*)cd {
+  NSArray<NSString*>* blocklist = self.configurator.resignProtectedBlocklist;
+  if (!blocklist.count) return NO;
+
+  NSMutableSet<NSString*>* signingIDs = [NSMutableSet set];
+  NSMutableSet<NSString*>* teamIDs = [NSMutableSet set];
+  NSMutableSet<NSString*>* bundleIDs = [NSMutableSet set];
+  for (NSString* entry in blocklist) {
+    NSRange sep = [entry rangeOfString:@":"];
+    if (sep.location == NSNotFound) {
+      [teamIDs addObject:entry];
+    } else {
+      [signingIDs addObject:entry];
+      [bundleIDs addObject:[entry substringFromIndex:NSMaxRange(sep)]];
+    }
+  }
+
+  // A validly signed binary from a listed identity.
+  SNTEventState identityDecision = SNTEventStateUnknown;
+  if (cd.signingID && [signingIDs containsObject:cd.signingID]) {
+    identityDecision = SNTEventStateBlockSigningID;
+  } else if (cd.teamID && [teamIDs containsObject:cd.teamID]) {
+    identityDecision = SNTEventStateBlockTeamID;
+  }
+
+  @synchronized(self.resignProtectedSHA256s) {
+    if (identityDecision != SNTEventStateUnknown) {
+      if (cd.sha256) [self.resignProtectedSHA256s addObject:cd.sha256];
+      if (cd.bundleIdentifier) [self.resignProtectedBundleIDs addObject:cd.bundleIdentifier];
+      cd.decision = identityDecision;
+      cd.decisionExtra = @"Resign protected blocklist";
+      return YES;
+    }
+
+    if ((cd.sha256 && [self.resignProtectedSHA256s containsObject:cd.sha256]) ||
+        (cd.bundleIdentifier && ([bundleIDs containsObject:cd.bundleIdentifier] ||
+                                 [self.resignProtectedBundleIDs
+                                     containsObject:cd.bundleIdentifier]))) {
+      cd.decision = SNTEventStateBlockSigningID;
+      cd.decisionExtra = @"Resign protected blocklist (bundle ID)";
+      return YES;
+    }
+  }
+
+  return NO;
+}
+
+// Applies requirement rules. These can't be looked up by identifier, so the
+// binary's code signature is checked against each rule's requirement in turn
+// and the first one it satisfies is applied. `csInfo` may be nil, in which case
+// the code signature is only checked if there are requirement rules.
+//
+// It returns YES if the decision was made, NO if the decision was not made.
+- (BOOL)applyRequirementRules:(SNTCachedDecision*)cd
+                     fileInfo:(SNTFileInfo*)fileInfo
+                       csInfo:(MOLCodesignChecker*)csInfo
+           activationCallback:(ActivationCallbackBlock)activationCallback {
+  if (cd.signingStatus == SNTSigningStatusUnsigned ||
+      cd.signingStatus == SNTSigningStatusInvalid) {
+    return NO;
+  }
+
+  NSArray<SNTRule*>* rules = [self.ruleTable requirementRules];
+  if (!rules.count) return NO;
+
+  if (!csInfo) {
+    NSError* error;
+    csInfo = [fileInfo codesignCheckerWithError:&error];
+    if (error) return NO;
+  }
+
+  for (SNTRule* rule in rules) {
+    SecRequirementRef requirement = NULL;
+    if (SecRequirementCreateWithString((__bridge CFStringRef)rule.identifier, kSecCSDefaultFlags,
+                                       &requirement) != errSecSuccess) {
+      LOGW(@"Ignoring requirement rule that failed to compile: %@", rule.identifier);
+      continue;
+    }
+    BOOL satisfied = [csInfo validateWithRequirement:requirement];
+    CFRelease(requirement);
+    if (!satisfied) continue;
+
+    if ([self decision:cd
+                             forRule:rule
+                 withTransitiveRules:self.configurator.enableTransitiveRules
+            andCELActivationCallback:activationCallback]) {
+      return YES;
+    }
+  }
+
+  return NO;
+}
+
+// Applies bundle Signing ID rules. These match the binary's Signing ID, any prefix of it at a
+// "." boundary, or the Signing ID of the bundle the binary is part of, so that a single rule
+// covers every binary in the bundle and keeps matching when an update changes their hashes.
+//
+// It returns YES if the decision was made, NO if the decision was not made.
+- (BOOL)applyBundleSigningIDRules:(SNTCachedDecision*)cd
+                         fileInfo:(SNTFileInfo*)fileInfo
+               activationCallback:(ActivationCallbackBlock)activationCallback {
+  if (!cd.signingID || ![self.ruleTable hasBundleSigningIDRules]) return NO;
+
+  NSArray<NSString*>* candidates = BundleSigningIDCandidates(cd.signingID, nil);
+  SNTRule* rule = [self.ruleTable bundleSigningIDRuleForSigningIDs:candidates];
+  if (!rule) {
+    NSString* bundleSigningID = [self bundleSigningIDForFileInfo:fileInfo];
+    if (bundleSigningID) {
+      candidates = BundleSigningIDCandidates(cd.signingID, bundleSigningID);
+      rule = [self.ruleTable bundleSigningIDRuleForSigningIDs:candidates];
+    }
+  }
+  if (!rule) return NO;
 
+  return [self decision:cd
+                           forRule:rule
+               withTransitiveRules:self.configurator.enableTransitiveRules
+          andCELActivationCallback:activationCallback];
+}
+
+// Returns the Signing ID of the main executable of the outermost bundle that fileInfo is part
+// of, or nil if it isn't part of a bundle, is the main executable itself, or the main
+// executable isn't validly signed.
+- (NSString*)bundleSigningIDForFileInfo:(SNTFileInfo*)fileInfo {
+  SNTFileInfo* bundleFileInfo = [[SNTFileInfo alloc] initWithPath:fileInfo.path];
+  bundleFileInfo.useAncestorBundle = YES;
+  NSString* executablePath = bundleFileInfo.bundle.executablePath;
+  if (!executablePath || [executablePath isEqualToString:fileInfo.path]) return nil;
+
+  NSError* error;
+  MOLCodesignChecker* csInfo = [[MOLCodesignChecker alloc] initWithBinaryPath:executablePath
+                                                                         error:&error];
+  if (error) return nil;
+  return FormatSigningID(csInfo);
+}
+
+- (BOOL)applyScriptRuleForScript:(SNTFileInfo*)scriptInfo decision:(SNTCachedDecision*)cd {
+  return [self applyScriptRuleForScriptSHA256:scriptInfo.SHA256 decision:cd];
+}
+
+- (BOOL)applyScriptRuleForScriptSHA256:(NSString*)scriptSHA256 decision:(SNTCachedDecision*)cd {
+  // The decision now depends on which script the interpreter was asked to run,
+  // so it must not be reused for the interpreter's next execution.
+  cd.cacheable = NO;
+  cd.scriptSHA256 = scriptSHA256;
+  if (!cd.scriptSHA256.length) return NO;
+
+  struct RuleIdentifiers identifiers = {.binarySHA256 = cd.scriptSHA256};
+  SNTRule* rule = [self.ruleTable executionRuleForIdentifiers:identifiers];
+  if (!rule || rule.type != SNTRuleTypeBinary) return NO;
+
+  // CEL and seatbelt rules describe a process, which a script is not.
+  switch (rule.state) {
+    case SNTRuleStateCEL:
+    case SNTRuleStateCELv2:
+    case SNTRuleStateSeatbelt: return NO;
+    default: break;
+  }
+
+  SNTCachedDecision* scriptCd = [[SNTCachedDecision alloc] init];
+  scriptCd.sha256 = cd.scriptSHA256;
+  if (![self decision:scriptCd
+                           forRule:rule
+               withTransitiveRules:self.configurator.enableTransitiveRules
+          andCELActivationCallback:nil]) {
+    return NO;
+  }
+
+  if (scriptCd.decision & SNTEventStateBlock) {
+    cd.decision = SNTEventStateBlockBinary;
+    cd.decisionExtra = @"Script blocked by rule";
+    cd.silentBlockGUI = scriptCd.silentBlockGUI;
+    cd.silentBlockTTY = scriptCd.silentBlockTTY;
+  } else if (cd.decision == SNTEventStateBlockUnknown) {
+    // An allowed script only overrides an interpreter that is merely unknown,
+    // never one that is explicitly blocked.
+    cd.decision = SNTEventStateAllowBinary;
+    cd.decisionExtra = @"Script allowed by rule";
+    cd.holdAndAsk = NO;
+  } else {
+    return NO;
+  }
+
+  cd.customMsg = scriptCd.customMsg;
+  cd.customURL = scriptCd.customURL;
+  cd.staticRule = scriptCd.staticRule;
+  cd.ruleId = scriptCd.ruleId;
+  cd.severity = scriptCd.severity;
   return YES;
 }
 
@@ -642,15 +899,43 @@ static void UpdateCachedDecisionSigningInfo(
   cd.platformBinary = (platformBinaryState == PlatformBinaryState::kRuntimeTrue);
   cd.decisionClientMode = configState.clientMode;
   cd.quarantineURL = fileInfo.quarantineDataURL;
+  cd.bundleIdentifier = fileInfo.bundleIdentifier;
+  cd.onDiskImage = fileInfo.isOnDiskImage;
+
+  if ([self diskImagePolicy:SNTDiskImageExecutionActionWarn appliesTo:cd fileInfo:fileInfo]) {
+    LOGW(@"Executing %@ from a mounted disk image", fileInfo.path);
+  }
+
+  if (self.configurator.pathChurnExecutionAction != SNTPathChurnExecutionActionNone) {
+    cd.pathChurnCount =
+        [[SNTPathChurnTracker sharedTracker] recordSHA256:cd.sha256
+                                                   atPath:fileInfo.path
+                                                     date:[NSDate date]
+                                                   window:self.configurator.pathChurnWindowSeconds];
+  }
+
+  if ([self pathChurnPolicy:SNTPathChurnExecutionActionWarn appliesTo:cd]) {
+    LOGW(@"Executing %@, which has changed %lu times recently", fileInfo.path,
+         (unsigned long)cd.pathChurnCount);
+  }
+
+  cd.provenance = [[SNTFileProvenanceIndex sharedIndex] provenanceForPath:fileInfo.path
+                                                                    date:[NSDate date]];
+
+  if (self.configurator.canonicalizeExecutablePaths) {
+    NSString* resolvedPath = santa::CanonicalPath(fileInfo.path);
+    cd.resolvedPath = [resolvedPath isEqualToString:fileInfo.path] ? nil : resolvedPath;
+  }
 
   NSError* csInfoError;
+  MOLCodesignChecker* csInfo;
   if (!cd.certSHA256.length) {
     // Grab the code signature, if there's an error don't try to capture
     // any of the signature details.
     // TODO(mlw): MOLCodesignChecker should be updated to still grab signing information
     // even if validity check fails. Once that is done, this code can be updated to grab
     // cert information so that it can still be reported to the sync server.
-    MOLCodesignChecker* csInfo = [fileInfo codesignCheckerWithError:&csInfoError];
+    csInfo = [fileInfo codesignCheckerWithError:&csInfoError];
     if (csInfoError) {
       csInfo = nil;
       cd.decisionExtra = [NSString
@@ -662,6 +947,32 @@ static void UpdateCachedDecisionSigningInfo(
     }
   }
 
+  if ([self applyResignProtectedBlocklist:cd]) {
+    return cd;
+  }
+
+  if ([self applyNetworkVolumePolicy:cd
+                            fileInfo:fileInfo
+                           forAction:SNTNetworkVolumeExecutionActionBlock]) {
+    return cd;
+  }
+
+  if ([self applyDiskImagePolicy:cd
+                        fileInfo:fileInfo
+                       forAction:SNTDiskImageExecutionActionBlock]) {
+    return cd;
+  }
+
+  if ([self applyPathChurnPolicy:cd forAction:SNTPathChurnExecutionActionBlock]) {
+    return cd;
+  }
+
+  if ([self applySigningStatusPolicy:cd forAction:SNTSigningStatusExecutionActionBlock]) {
+    return cd;
+  }
+
+  BOOL rulesInFlight = [self applySyncInFlightPolicy:cd];
+
   SNTRule* rule = [self.ruleTable executionRuleForIdentifiers:CreateRuleIDs(cd)];
   if (rule) {
     // If we have a rule match we don't need to process any further.
@@ -669,10 +980,22 @@ static void UpdateCachedDecisionSigningInfo(
                              forRule:rule
                  withTransitiveRules:self.configurator.enableTransitiveRules
             andCELActivationCallback:activationCallback]) {
+      [self applyLibraryValidationRequirement:cd];
       return cd;
     }
   }
 
+  if ([self applyBundleSigningIDRules:cd fileInfo:fileInfo activationCallback:activationCallback]) {
+    return cd;
+  }
+
+  if ([self applyRequirementRules:cd
+                         fileInfo:fileInfo
+                           csInfo:csInfo
+               activationCallback:activationCallback]) {
+    return cd;
+  }
+
   if ([self.configurator enableBadSignatureProtection] && csInfoError &&
       csInfoError.code != errSecCSUnsigned) {
     cd.decisionExtra =
@@ -691,24 +1014,79 @@ static void UpdateCachedDecisionSigningInfo(
     return cd;
   }
 
-  NSString* msg = [self fileIsScopeBlocked:fileInfo];
+  // Checked once no rule matched, so that an explicit rule of any type still wins over a token
+  // and the grant is only consumed by an execution it actually allows.
+  if ([self applyAllowOnceGrant:cd]) {
+    return cd;
+  }
+
+  NSString* msg = [self fileIsScopeBlocked:fileInfo resolvedPath:cd.resolvedPath];
   if (msg) {
     cd.decisionExtra = msg;
     cd.decision = SNTEventStateBlockScope;
     return cd;
   }
 
-  msg = [self fileIsScopeAllowed:fileInfo];
+  msg = [self fileIsScopeAllowed:fileInfo resolvedPath:cd.resolvedPath];
   if (msg) {
     cd.decisionExtra = msg;
     cd.decision = SNTEventStateAllowScope;
     return cd;
   }
 
+  if ([self applyDeveloperToolsPolicy:cd]) {
+    return cd;
+  }
+
+  if ([self applyDecisionHook:cd fileInfo:fileInfo]) {
+    return cd;
+  }
+
+  if ([self applyNetworkVolumePolicy:cd
+                            fileInfo:fileInfo
+                           forAction:SNTNetworkVolumeExecutionActionBlockUnknown]) {
+    return cd;
+  }
+
+  if ([self applyDiskImagePolicy:cd
+                        fileInfo:fileInfo
+                       forAction:SNTDiskImageExecutionActionBlockUnknown]) {
+    return cd;
+  }
+
+  if ([self applyPathChurnPolicy:cd forAction:SNTPathChurnExecutionActionBlockUnknown]) {
+    return cd;
+  }
+
+  if ([self applySigningStatusPolicy:cd forAction:SNTSigningStatusExecutionActionBlockUnknown]) {
+    return cd;
+  }
+
+  if (rulesInFlight &&
+      self.configurator.syncInFlightExecutionPolicy == SNTSyncInFlightExecutionPolicyFailSafe) {
+    // The update being applied may allow this binary, so it will be decided again once it has.
+    cd.decision = SNTEventStateBlockUnknown;
+    cd.decisionExtra = @"Blocked while a rule sync is applied";
+    return cd;
+  }
+
   switch (configState.clientMode) {
     case SNTClientModeMonitor: cd.decision = SNTEventStateAllowUnknown; return cd;
     case SNTClientModeStandalone: cd.holdAndAsk = YES; [[fallthrough]];
-    case SNTClientModeLockdown: cd.decision = SNTEventStateBlockUnknown; return cd;
+    case SNTClientModeLockdown:
+      if (configState.clientMode == SNTClientModeLockdown &&
+          [[SNTLockdownGracePeriod sharedGracePeriod] isActive]) {
+        // Log what Lockdown would have blocked without breaking work already in progress. The
+        // decision isn't cached so that Lockdown is enforced on the next execution once the
+        // grace period ends.
+        cd.decision = SNTEventStateAllowUnknown;
+        cd.decisionExtra = @"Allowed during Lockdown grace period";
+        cd.auditReturn = YES;
+        cd.cacheable = NO;
+        return cd;
+      }
+      cd.decision = SNTEventStateBlockUnknown;
+      return cd;
     default: cd.decision = SNTEventStateBlockUnknown; return cd;
   }
 }
@@ -771,6 +1149,8 @@ static void UpdateCachedDecisionSigningInfo(
     }
   }
 
+  cd.codesigningFlags = targetProc->codesigning_flags;
+
   return [self decisionForFileInfo:fileInfo
       configState:configState
       cachedDecision:cd
@@ -802,22 +1182,19 @@ static void UpdateCachedDecisionSigningInfo(
 ///    + Non Mach-O files that are not part of an installer package.
 ///    + Files in allowed path.
 ///
+///  When @c resolvedPath is non-nil the allowed path regex is also matched
+///  against it, so a translocated or symlinked binary is treated the same as
+///  the file at its original location.
+///
 ///  @return @c YES if file is in scope, @c NO otherwise.
 ///
-- (NSString*)fileIsScopeAllowed:(SNTFileInfo*)fi {
+- (NSString*)fileIsScopeAllowed:(SNTFileInfo*)fi resolvedPath:(NSString*)resolvedPath {
   if (!fi) return nil;
 
-  // Determine if file is within an allowed path. Guard on a non-nil regex:
-  // rangeOfFirstMatchInString: returns a zeroed NSRange (location 0, not
-  // NSNotFound) when messaged on a nil regex, which would otherwise read as a
-  // spurious match.
-  NSRegularExpression* re = [self.configurator allowedPathRegex];
-  if (re) {
-    NSString* path = fi.path;
-    if ([re rangeOfFirstMatchInString:path options:0 range:NSMakeRange(0, path.length)].location !=
-        NSNotFound) {
-      return @"Allowed Path Regex";
-    }
+  // Determine if file is within an allowed path.
+  if (santa::PathOrResolvedPathMatches([self.configurator allowedPathRegex], fi.path,
+                                       resolvedPath)) {
+    return @"Allowed Path Regex";
   }
 
   // If file is not a Mach-O file, we're not interested.
@@ -828,18 +1205,222 @@ static void UpdateCachedDecisionSigningInfo(
   return nil;
 }
 
-- (NSString*)fileIsScopeBlocked:(SNTFileInfo*)fi {
+///
+///  Consult the configured decision hook, if any, for a binary that would otherwise
+///  receive an unknown decision.
+///
+///  @return @c YES if the hook made a decision, @c NO if the normal client mode
+///          decision should be used.
+///
+- (BOOL)applyDecisionHook:(SNTCachedDecision*)cd fileInfo:(SNTFileInfo*)fi {
+  NSString* socketPath = self.configurator.decisionHookSocketPath;
+  if (!socketPath.length) return NO;
+
+  NSMutableDictionary* identity = [NSMutableDictionary dictionary];
+  identity[@"path"] = fi.path;
+  identity[@"sha256"] = cd.sha256;
+  identity[@"cdhash"] = cd.cdhash;
+  identity[@"signing_id"] = cd.signingID;
+  identity[@"team_id"] = cd.teamID;
+  identity[@"cert_sha256"] = cd.certSHA256;
+  identity[@"cert_common_name"] = cd.certCommonName;
+
+  santa::DecisionHook hook(
+      santa::NSStringToUTF8String(socketPath),
+      std::chrono::milliseconds(self.configurator.decisionHookTimeoutMilliseconds),
+      self.configurator.decisionHookFailClosed);
+
+  switch (hook.Evaluate(identity)) {
+    case santa::DecisionHook::Verdict::kAllow:
+      cd.decisionExtra = @"Allowed by decision hook";
+      cd.decision = SNTEventStateAllowUnknown;
+      return YES;
+    case santa::DecisionHook::Verdict::kBlock:
+      cd.decisionExtra = @"Blocked by decision hook";
+      cd.decision = SNTEventStateBlockUnknown;
+      return YES;
+    case santa::DecisionHook::Verdict::kNoDecision: return NO;
+  }
+}
+
+///
+///  Allows binaries signed by one of the developer toolchains in the configured
+///  developer tools allowlist, when it is enabled. Signing IDs are checked
+///  before Team IDs, matching the default rule precedence.
+///
+///  @return @c YES if the binary was allowed, @c NO otherwise.
+///
+- (BOOL)applyDeveloperToolsPolicy:(SNTCachedDecision*)cd {
+  if (!self.configurator.enableDeveloperToolsAllowlist) return NO;
+
+  NSArray<NSString*>* allowlist = self.configurator.developerToolsAllowlist;
+  if (cd.signingID && [allowlist containsObject:cd.signingID]) {
+    cd.decision = SNTEventStateAllowSigningID;
+  } else if (cd.teamID && [allowlist containsObject:cd.teamID]) {
+    cd.decision = SNTEventStateAllowTeamID;
+  } else {
+    return NO;
+  }
+
+  cd.decisionExtra = @"Developer Tools";
+  return YES;
+}
+
+///
+///  Blocks binaries that reside on a network volume when the configured
+///  NetworkVolumeExecutionAction matches @c action.
+///
+///  @return @c YES if the binary was blocked, @c NO otherwise.
+///
+- (BOOL)applyNetworkVolumePolicy:(SNTCachedDecision*)cd
+                        fileInfo:(SNTFileInfo*)fi
+                       forAction:(SNTNetworkVolumeExecutionAction)action {
+  if (self.configurator.networkVolumeExecutionAction != action) return NO;
+  if (!fi.isOnNetworkVolume) return NO;
+
+  cd.decisionExtra =
+      [NSString stringWithFormat:@"Executed from network volume (%@)", fi.fileSystemType];
+  cd.decision = SNTEventStateBlockNetworkVolume;
+  return YES;
+}
+
+///
+///  Applies SyncInFlightExecutionPolicy when a rule update from the sync service is being
+///  applied, waiting for it with the "Defer" policy. Decisions made using the existing rules in
+///  that window aren't cached, as the update may change them.
+///
+///  @return @c YES if the decision will be made using rules that are about to change.
+///
+- (BOOL)applySyncInFlightPolicy:(SNTCachedDecision*)cd {
+  SNTSyncInFlightExecutionPolicy policy = self.configurator.syncInFlightExecutionPolicy;
+  if (policy == SNTSyncInFlightExecutionPolicyUseExisting) return NO;
+
+  SNTRuleSyncWindow* window = [SNTRuleSyncWindow sharedWindow];
+  if (![window isInFlight]) return NO;
+
+  if (policy == SNTSyncInFlightExecutionPolicyDefer &&
+      [window waitWithTimeout:self.configurator.syncInFlightMaxDeferralMilliseconds / 1000.0]) {
+    return NO;
+  }
+
+  cd.cacheable = NO;
+  return YES;
+}
+
+///
+///  Blocks binaries allowed by a TeamID rule whose code signature doesn't require
+///  library validation, when RequireLibraryValidationForTeamIDRules is enabled.
+///  A TeamID rule trusts everything the team signs, so without library
+///  validation an allowed binary could load a library signed by anyone.
+///
+- (void)applyLibraryValidationRequirement:(SNTCachedDecision*)cd {
+  if (cd.decision != SNTEventStateAllowTeamID) return;
+  if (!self.configurator.requireLibraryValidationForTeamIDRules) return;
+  if (cd.codesigningFlags & CS_REQUIRE_LV) return;
+
+  cd.decisionExtra = @"TeamID rule requires library validation";
+  cd.decision = SNTEventStateBlockTeamID;
+}
+
+///
+///  @return @c YES if the configured DiskImageExecutionAction is @c action and
+///  the binary is on a disk image it applies to.
+///
+- (BOOL)diskImagePolicy:(SNTDiskImageExecutionAction)action
+              appliesTo:(SNTCachedDecision*)cd
+               fileInfo:(SNTFileInfo*)fi {
+  if (self.configurator.diskImageExecutionAction != action) return NO;
+  if (!cd.onDiskImage) return NO;
+  return !self.configurator.diskImageExecutionQuarantinedOnly || fi.isOnQuarantinedDiskImage;
+}
+
+///
+///  Blocks binaries that reside on a mounted disk image when the configured
+///  DiskImageExecutionAction matches @c action.
+///
+///  @return @c YES if the binary was blocked, @c NO otherwise.
+///
+- (BOOL)applyDiskImagePolicy:(SNTCachedDecision*)cd
+                    fileInfo:(SNTFileInfo*)fi
+                   forAction:(SNTDiskImageExecutionAction)action {
+  if (![self diskImagePolicy:action appliesTo:cd fileInfo:fi]) return NO;
+
+  cd.decisionExtra = fi.isOnQuarantinedDiskImage ? @"Executed from quarantined disk image"
+                                                 : @"Executed from disk image";
+  cd.decision = SNTEventStateBlockDiskImage;
+  return YES;
+}
+
+///
+///  @return @c YES if the configured PathChurnExecutionAction is @c action and
+///  the binary at this path has changed at least PathChurnThreshold times.
+///
+- (BOOL)pathChurnPolicy:(SNTPathChurnExecutionAction)action appliesTo:(SNTCachedDecision*)cd {
+  if (self.configurator.pathChurnExecutionAction != action) return NO;
+  return cd.pathChurnCount >= self.configurator.pathChurnThreshold;
+}
+
+///
+///  Blocks binaries whose path keeps changing when the configured
+///  PathChurnExecutionAction matches @c action.
+///
+///  @return @c YES if the binary was blocked, @c NO otherwise.
+///
+- (BOOL)applyPathChurnPolicy:(SNTCachedDecision*)cd forAction:(SNTPathChurnExecutionAction)action {
+  if (![self pathChurnPolicy:action appliesTo:cd]) return NO;
+
+  cd.decisionExtra = [NSString
+      stringWithFormat:@"Binary at this path changed %lu times", (unsigned long)cd.pathChurnCount];
+  cd.decision = SNTEventStateBlockPathChurn;
+  return YES;
+}
+
+///
+///  Blocks binaries whose signing status has a configured
+///  SigningStatusExecutionActions entry matching @c action.
+///
+///  @return @c YES if the binary was blocked, @c NO otherwise.
+///
+- (BOOL)applySigningStatusPolicy:(SNTCachedDecision*)cd
+                       forAction:(SNTSigningStatusExecutionAction)action {
+  if ([self.configurator executionActionForSigningStatus:cd.signingStatus] != action) return NO;
+
+  NSString* status;
+  switch (cd.signingStatus) {
+    case SNTSigningStatusUnsigned: status = @"unsigned"; break;
+    case SNTSigningStatusInvalid: status = @"invalid"; break;
+    case SNTSigningStatusAdhoc: status = @"adhoc"; break;
+    case SNTSigningStatusDevelopment: status = @"development"; break;
+    case SNTSigningStatusProduction: status = @"production"; break;
+  }
+
+  cd.decisionExtra = [NSString stringWithFormat:@"Blocked due to signing status: %@", status];
+  cd.decision = SNTEventStateBlockCertificate;
+  return YES;
+}
+
+///
+///  Allows the binary if a redeemed one-time allow token names one of its
+///  identities. The grant is consumed, so the decision must not be cached.
+///
+///  @return @c YES if the binary was allowed, @c NO otherwise.
+///
+- (BOOL)applyAllowOnceGrant:(SNTCachedDecision*)cd {
+  SNTRule* rule = [self.allowOnceStore consumeGrantForIdentifiers:CreateRuleIDs(cd)];
+  if (!rule) return NO;
+
+  cd.decisionExtra = @"Allowed once by token";
+  cd.decision = SNTEventStateAllowOnce;
+  cd.cacheable = NO;
+  return YES;
+}
+
+- (NSString*)fileIsScopeBlocked:(SNTFileInfo*)fi resolvedPath:(NSString*)resolvedPath {
   if (!fi) return nil;
 
-  // Guard on a non-nil regex; see fileIsScopeAllowed: for why the nil case
-  // must be handled explicitly with rangeOfFirstMatchInString:.
-  NSRegularExpression* re = [self.configurator blockedPathRegex];
-  if (re) {
-    NSString* path = fi.path;
-    if ([re rangeOfFirstMatchInString:path options:0 range:NSMakeRange(0, path.length)].location !=
-        NSNotFound) {
-      return @"Blocked Path Regex";
-    }
+  if (santa::PathOrResolvedPathMatches([self.configurator blockedPathRegex], fi.path,
+                                       resolvedPath)) {
+    return @"Blocked Path Regex";
   }
 
   if ([self.configurator enablePageZeroProtection] && fi.isMissingPageZero) {
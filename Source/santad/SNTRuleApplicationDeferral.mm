@@ -0,0 +1,152 @@
+/// Copyright 2026 North Pole Security, Inc.
+///
+/// Licensed under the Apache License, Version 2.0 (the "License");
+/// you may not use this file except in compliance with the License.
+/// You may obtain a copy of the License at
+///
+///     http://www.apache.org/licenses/LICENSE-2.0
+///
+/// Unless required by applicable law or agreed to in writing, software
+/// distributed under the License is distributed on an "AS IS" BASIS,
+/// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+/// See the License for the specific language governing permissions and
+/// limitations under the License.
+
+#import "Source/santad/SNTRuleApplicationDeferral.h"
+
+#include <stdlib.h>
+
+#import "Source/common/SNTLogging.h"
+
+static const NSTimeInterval kDefaultPollInterval = 15;
+
+@interface SNTPendingRuleApplication : NSObject
+@property(copy) void (^applyBlock)(void);
+@property NSString* key;
+@property NSDate* deadline;
+@end
+
+@implementation SNTPendingRuleApplication
+@end
+
+@interface SNTRuleApplicationDeferral ()
+@property dispatch_queue_t q;
+@property(copy) double (^loadBlock)(void);
+@property NSTimeInterval pollInterval;
+@property NSUInteger thresholdPercent;
+@property NSMutableArray<SNTPendingRuleApplication*>* pending;
+@property BOOL pollScheduled;
+@end
+
+@implementation SNTRuleApplicationDeferral
+
++ (instancetype)sharedDeferral {
+  static SNTRuleApplicationDeferral* deferral;
+  static dispatch_once_t onceToken;
+  dispatch_once(&onceToken, ^{
+    deferral = [[SNTRuleApplicationDeferral alloc] init];
+  });
+  return deferral;
+}
+
++ (double)currentLoadPercent {
+  double load;
+  if (getloadavg(&load, 1) != 1) return 0;
+  NSUInteger cpus = MAX([[NSProcessInfo processInfo] activeProcessorCount], 1);
+  return load * 100 / cpus;
+}
+
+- (instancetype)init {
+  return [self
+      initWithLoadBlock:^double {
+        return [SNTRuleApplicationDeferral currentLoadPercent];
+      }
+           pollInterval:kDefaultPollInterval];
+}
+
+- (instancetype)initWithLoadBlock:(double (^)(void))loadBlock
+                     pollInterval:(NSTimeInterval)pollInterval {
+  self = [super init];
+  if (self) {
+    _q = dispatch_queue_create("com.northpolesec.santa.daemon.rule_application_deferral",
+                               DISPATCH_QUEUE_SERIAL);
+    _loadBlock = loadBlock;
+    _pollInterval = pollInterval;
+    _pending = [NSMutableArray array];
+  }
+  return self;
+}
+
+- (BOOL)shouldDeferWithThresholdPercent:(NSUInteger)thresholdPercent {
+  if (thresholdPercent == 0) return NO;
+
+  __block BOOL pending;
+  dispatch_sync(self.q, ^{
+    pending = self.pending.count > 0;
+  });
+  // Updates must be applied in order, so once one is held back every later one is too.
+  return pending || self.loadBlock() > thresholdPercent;
+}
+
+- (void)deferApplication:(void (^)(void))applyBlock
+                  forKey:(NSString*)key
+        thresholdPercent:(NSUInteger)thresholdPercent
+             maxDeferral:(NSTimeInterval)maxDeferral {
+  dispatch_sync(self.q, ^{
+    self.thresholdPercent = thresholdPercent;
+
+    for (SNTPendingRuleApplication* p in self.pending) {
+      if (key && [p.key isEqualToString:key]) {
+        p.applyBlock = applyBlock;
+        LOGI(@"System load is high, replacing deferred rule update (%lu pending)",
+             self.pending.count);
+        return;
+      }
+    }
+
+    SNTPendingRuleApplication* p = [[SNTPendingRuleApplication alloc] init];
+    p.applyBlock = applyBlock;
+    p.key = key;
+    p.deadline = [NSDate dateWithTimeIntervalSinceNow:maxDeferral];
+    [self.pending addObject:p];
+    LOGI(@"System load is high, deferring rule update (%lu pending)", self.pending.count);
+    [self schedulePollSerialized];
+  });
+}
+
+- (NSUInteger)pendingCount {
+  __block NSUInteger count;
+  dispatch_sync(self.q, ^{
+    count = self.pending.count;
+  });
+  return count;
+}
+
+- (void)schedulePollSerialized {
+  if (self.pollScheduled) return;
+  self.pollScheduled = YES;
+
+  dispatch_after(dispatch_time(DISPATCH_TIME_NOW, (int64_t)(self.pollInterval * NSEC_PER_SEC)),
+                 self.q, ^{
+                   self.pollScheduled = NO;
+                   [self applyPendingSerialized];
+                 });
+}
+
+- (void)applyPendingSerialized {
+  BOOL loadAllows = self.loadBlock() <= self.thresholdPercent;
+  while (self.pending.count) {
+    SNTPendingRuleApplication* p = self.pending.firstObject;
+    if (!loadAllows && [p.deadline timeIntervalSinceNow] > 0) break;
+
+    if (!loadAllows) {
+      LOGI(@"Applying deferred rule update after reaching the maximum deferral time");
+    }
+    [self.pending removeObjectAtIndex:0];
+    p.applyBlock();
+  }
+
+  if (self.pending.count) [self schedulePollSerialized];
+}
+
+@end
@@ -0,0 +1,127 @@
+/// Copyright 2026 North Pole Security, Inc.
+///
+/// Licensed under the Apache License, Version 2.0 (the "License");
+/// you may not use this file except in compliance with the License.
+/// You may obtain a copy of the License at
+///
+///     http://www.apache.org/licenses/LICENSE-2.0
+///
+/// Unless required by applicable law or agreed to in writing, software
+/// distributed under the License is distributed on an "AS IS" BASIS,
+/// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+/// See the License for the specific language governing permissions and
+/// limitations under the License.
+
+#import <Foundation/Foundation.h>
+#import <XCTest/XCTest.h>
+
+#import "Source/santad/SNTRuleApplicationDeferral.h"
+
+@interface SNTRuleApplicationDeferralTest : XCTestCase
+@property SNTRuleApplicationDeferral* sut;
+@property(atomic) double load;
+@end
+
+@implementation SNTRuleApplicationDeferralTest
+
+- (void)setUp {
+  [super setUp];
+  self.load = 500;
+  __weak SNTRuleApplicationDeferralTest* weakSelf = self;
+  self.sut = [[SNTRuleApplicationDeferral alloc]
+      initWithLoadBlock:^double {
+        return weakSelf.load;
+      }
+           pollInterval:0.05];
+}
+
+- (void)waitForPendingApplications {
+  NSDate* deadline = [NSDate dateWithTimeIntervalSinceNow:5];
+  while ([self.sut pendingCount] && [deadline timeIntervalSinceNow] > 0) {
+    [NSThread sleepForTimeInterval:0.05];
+  }
+}
+
+- (void)testDisabledThresholdNeverDefers {
+  XCTAssertFalse([self.sut shouldDeferWithThresholdPercent:0]);
+}
+
+- (void)testDefersOnlyAboveThreshold {
+  XCTAssertTrue([self.sut shouldDeferWithThresholdPercent:100]);
+  self.load = 100;
+  XCTAssertFalse([self.sut shouldDeferWithThresholdPercent:100]);
+}
+
+- (void)testPendingUpdatesAreAppliedInOrderWhenLoadDrops {
+  NSMutableArray* applied = [NSMutableArray array];
+  [self.sut
+      deferApplication:^{
+        [applied addObject:@1];
+      }
+                forKey:nil
+      thresholdPercent:100
+           maxDeferral:600];
+
+  // While an update is pending later ones are deferred too, even if the load has dropped.
+  self.load = 10;
+  XCTAssertTrue([self.sut shouldDeferWithThresholdPercent:100]);
+  [self.sut
+      deferApplication:^{
+        [applied addObject:@2];
+      }
+                forKey:nil
+      thresholdPercent:100
+           maxDeferral:600];
+
+  [self waitForPendingApplications];
+  XCTAssertEqualObjects(applied, (@[ @1, @2 ]));
+  XCTAssertFalse([self.sut shouldDeferWithThresholdPercent:100]);
+}
+
+- (void)testPendingUpdateWithTheSameKeyIsReplaced {
+  NSMutableArray* applied = [NSMutableArray array];
+  [self.sut
+      deferApplication:^{
+        [applied addObject:@1];
+      }
+                forKey:@"sync"
+      thresholdPercent:100
+           maxDeferral:600];
+  [self.sut
+      deferApplication:^{
+        [applied addObject:@2];
+      }
+                forKey:nil
+      thresholdPercent:100
+           maxDeferral:600];
+  [self.sut
+      deferApplication:^{
+        [applied addObject:@3];
+      }
+                forKey:@"sync"
+      thresholdPercent:100
+           maxDeferral:600];
+  XCTAssertEqual([self.sut pendingCount], 2u);
+
+  // The latest update for the key is applied in the place of the first.
+  self.load = 10;
+  [self waitForPendingApplications];
+  XCTAssertEqualObjects(applied, (@[ @3, @2 ]));
+}
+
+- (void)testUpdateIsAppliedAfterMaxDeferral {
+  __block BOOL applied = NO;
+  [self.sut
+      deferApplication:^{
+        applied = YES;
+      }
+                forKey:nil
+      thresholdPercent:100
+           maxDeferral:0.1];
+
+  [self waitForPendingApplications];
+  XCTAssertTrue(applied);
+  XCTAssertEqual([self.sut pendingCount], 0u);
+}
+
+@end
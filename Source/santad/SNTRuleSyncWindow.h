@@ -0,0 +1,54 @@
+/// Copyright 2026 North Pole Security, Inc.
+///
+/// Licensed under the Apache License, Version 2.0 (the "License");
+/// you may not use this file except in compliance with the License.
+/// You may obtain a copy of the License at
+///
+///     http://www.apache.org/licenses/LICENSE-2.0
+///
+/// Unless required by applicable law or agreed to in writing, software
+/// distributed under the License is distributed on an "AS IS" BASIS,
+/// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+/// See the License for the specific language governing permissions and
+/// limitations under the License.
+
+#import <Foundation/Foundation.h>
+
+NS_ASSUME_NONNULL_BEGIN
+
+///
+///  Tracks rule updates from the sync service that have been received but not yet applied, so
+///  that execution decisions made in the meantime can be handled according to
+///  SyncInFlightExecutionPolicy.
+///
+///  Calls to begin and end must be balanced. Waiting never holds a lock that applying rules
+///  needs, so a decision waiting here can't hold up the update it is waiting for.
+///
+@interface SNTRuleSyncWindow : NSObject
+
++ (instancetype)sharedWindow;
+
+///
+///  Mark the start of applying a rule update.
+///
+- (void)begin;
+
+///
+///  Mark the end of applying a rule update, successful or not.
+///
+- (void)end;
+
+///
+///  Returns YES if any rule update has begun but not yet ended.
+///
+- (BOOL)isInFlight;
+
+///
+///  Wait up to timeout seconds for all in-flight rule updates to end. Returns YES if none are in
+///  flight when this returns.
+///
+- (BOOL)waitWithTimeout:(NSTimeInterval)timeout;
+
+@end
+
+NS_ASSUME_NONNULL_END
@@ -0,0 +1,77 @@
+/// Copyright 2026 North Pole Security, Inc.
+///
+/// Licensed under the Apache License, Version 2.0 (the "License");
+/// you may not use this file except in compliance with the License.
+/// You may obtain a copy of the License at
+///
+///     http://www.apache.org/licenses/LICENSE-2.0
+///
+/// Unless required by applicable law or agreed to in writing, software
+/// distributed under the License is distributed on an "AS IS" BASIS,
+/// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+/// See the License for the specific language governing permissions and
+/// limitations under the License.
+
+#import "Source/santad/SNTRuleSyncWindow.h"
+
+#import "Source/common/SNTLogging.h"
+
+@interface SNTRuleSyncWindow ()
+@property NSCondition* condition;
+@property NSUInteger inFlightCount;
+@end
+
+@implementation SNTRuleSyncWindow
+
++ (instancetype)sharedWindow {
+  static SNTRuleSyncWindow* window;
+  static dispatch_once_t onceToken;
+  dispatch_once(&onceToken, ^{
+    window = [[SNTRuleSyncWindow alloc] init];
+  });
+  return window;
+}
+
+- (instancetype)init {
+  self = [super init];
+  if (self) {
+    _condition = [[NSCondition alloc] init];
+  }
+  return self;
+}
+
+- (void)begin {
+  [self.condition lock];
+  self.inFlightCount++;
+  [self.condition unlock];
+}
+
+- (void)end {
+  [self.condition lock];
+  if (self.inFlightCount == 0) {
+    LOGW(@"Rule sync window ended more times than it began");
+  } else if (--self.inFlightCount == 0) {
+    [self.condition broadcast];
+  }
+  [self.condition unlock];
+}
+
+- (BOOL)isInFlight {
+  [self.condition lock];
+  BOOL inFlight = self.inFlightCount > 0;
+  [self.condition unlock];
+  return inFlight;
+}
+
+- (BOOL)waitWithTimeout:(NSTimeInterval)timeout {
+  NSDate* deadline = [NSDate dateWithTimeIntervalSinceNow:timeout];
+  [self.condition lock];
+  // Loop to handle spurious wakeups; waitUntilDate: returns NO once the deadline has passed.
+  while (self.inFlightCount > 0 && [self.condition waitUntilDate:deadline]) {
+  }
+  BOOL done = self.inFlightCount == 0;
+  [self.condition unlock];
+  return done;
+}
+
+@end
@@ -0,0 +1,105 @@
+/// Copyright 2026 North Pole Security, Inc.
+///
+/// Licensed under the Apache License, Version 2.0 (the "License");
+/// you may not use this file except in compliance with the License.
+/// You may obtain a copy of the License at
+///
+///     http://www.apache.org/licenses/LICENSE-2.0
+///
+/// Unless required by applicable law or agreed to in writing, software
+/// distributed under the License is distributed on an "AS IS" BASIS,
+/// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+/// See the License for the specific language governing permissions and
+/// limitations under the License.
+
+#import <Foundation/Foundation.h>
+#import <XCTest/XCTest.h>
+
+#import "Source/santad/SNTRuleSyncWindow.h"
+
+@interface SNTRuleSyncWindowTest : XCTestCase
+@property SNTRuleSyncWindow* sut;
+@end
+
+@implementation SNTRuleSyncWindowTest
+
+- (void)setUp {
+  [super setUp];
+  self.sut = [[SNTRuleSyncWindow alloc] init];
+}
+
+- (void)testBeginAndEnd {
+  XCTAssertFalse([self.sut isInFlight]);
+  [self.sut begin];
+  XCTAssertTrue([self.sut isInFlight]);
+  [self.sut end];
+  XCTAssertFalse([self.sut isInFlight]);
+}
+
+- (void)testOverlappingUpdates {
+  [self.sut begin];
+  [self.sut begin];
+  [self.sut end];
+  XCTAssertTrue([self.sut isInFlight]);
+  [self.sut end];
+  XCTAssertFalse([self.sut isInFlight]);
+}
+
+- (void)testUnbalancedEndIsIgnored {
+  [self.sut end];
+  XCTAssertFalse([self.sut isInFlight]);
+  [self.sut begin];
+  XCTAssertTrue([self.sut isInFlight]);
+}
+
+- (void)testWaitReturnsImmediatelyWhenIdle {
+  NSDate* start = [NSDate date];
+  XCTAssertTrue([self.sut waitWithTimeout:5]);
+  XCTAssertLessThan(-[start timeIntervalSinceNow], 1);
+}
+
+- (void)testWaitTimesOut {
+  [self.sut begin];
+  NSDate* start = [NSDate date];
+  XCTAssertFalse([self.sut waitWithTimeout:0.1]);
+  XCTAssertGreaterThanOrEqual(-[start timeIntervalSinceNow], 0.1);
+  XCTAssertTrue([self.sut isInFlight]);
+}
+
+- (void)testWaitIsReleasedByEnd {
+  [self.sut begin];
+  dispatch_after(dispatch_time(DISPATCH_TIME_NOW, 100 * NSEC_PER_MSEC),
+                 dispatch_get_global_queue(QOS_CLASS_DEFAULT, 0), ^{
+                   [self.sut end];
+                 });
+
+  NSDate* start = [NSDate date];
+  XCTAssertTrue([self.sut waitWithTimeout:5]);
+  XCTAssertLessThan(-[start timeIntervalSinceNow], 5);
+}
+
+- (void)testConcurrentWaitersAreAllReleased {
+  [self.sut begin];
+
+  dispatch_group_t group = dispatch_group_create();
+  __block int released = 0;
+  NSObject* lock = [[NSObject alloc] init];
+  for (int i = 0; i < 4; ++i) {
+    dispatch_group_async(group, dispatch_get_global_queue(QOS_CLASS_DEFAULT, 0), ^{
+      if ([self.sut waitWithTimeout:5]) {
+        @synchronized(lock) {
+          released++;
+        }
+      }
+    });
+  }
+
+  [NSThread sleepForTimeInterval:0.1];
+  [self.sut end];
+
+  XCTAssertEqual(dispatch_group_wait(group, dispatch_time(DISPATCH_TIME_NOW, 10 * NSEC_PER_SEC)),
+                 0);
+  XCTAssertEqual(released, 4);
+}
+
+@end
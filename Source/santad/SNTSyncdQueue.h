@@ -45,4 +45,8 @@
 /// and you want to reconnect without waiting for the normal retry backoff.
 - (void)pushNotificationReconnect;
 
+/// Request an out-of-band sync with the sync server. The request is dropped if the sync service is
+/// not connected.
+- (void)requestSync;
+
 @end
@@ -168,7 +168,8 @@
 }
 
 - (void)addEvents:(NSArray<SNTStoredEvent*>*)events withBackoffHashKey:(NSString*)backoffHashKey {
-  if (!events.count || [self backoffForPrimaryHash:backoffHashKey]) {
+  if (!events.count || [SNTConfigurator configurator].disableEventUpload ||
+      [self backoffForPrimaryHash:backoffHashKey]) {
     return;
   }
 
@@ -245,4 +246,20 @@
   }];
 }
 
+- (void)requestSync {
+  [self dispatchBlockOnSyncdQueue:^{
+    if (!self.syncConnection.isConnected) {
+      LOGW(@"Cannot request sync: sync service not connected");
+      return;
+    }
+
+    [[self.syncConnection remoteObjectProxy] syncWithLogListener:nil
+                                                        syncType:SNTSyncTypeNormal
+                                                           reply:^(SNTSyncStatusType status) {
+                                                             LOGD(@"Requested sync status: %ld",
+                                                                  (long)status);
+                                                           }];
+  }];
+}
+
 @end
@@ -16,6 +16,7 @@
 #include "Source/santad/Santad.h"
 #include "Source/santad/SandboxExpectations.h"
 
+#include <csignal>
 #include <cstdlib>
 #include <memory>
 
@@ -26,6 +27,7 @@
 #import "Source/common/SNTKVOManager.h"
 #import "Source/common/SNTLogging.h"
 #import "Source/common/SNTStoredFileAccessEvent.h"
+#import "Source/common/SNTStoredLaunchItemEvent.h"
 #import "Source/common/SNTStoredNetworkMountEvent.h"
 #import "Source/common/SNTStoredUSBMountEvent.h"
 #import "Source/common/SNTXPCNotifierInterface.h"
@@ -36,6 +38,7 @@
 #include "Source/common/faa/WatchItemPolicy.h"
 #include "Source/common/faa/WatchItems.h"
 #include "Source/santad/AdminUserState.h"
+#include "Source/santad/ClockMonitor.h"
 #include "Source/santad/DaemonConfigBundle.h"
 #import "Source/santad/DataLayer/SNTEventTable.h"
 #import "Source/santad/DataLayer/SNTRuleTable.h"
@@ -48,10 +51,14 @@
 #import "Source/santad/EventProviders/SNTEndpointSecurityRecorder.h"
 #import "Source/santad/EventProviders/SNTEndpointSecurityTamperResistance.h"
 #include "Source/santad/Logs/EndpointSecurity/Logger.h"
+#include "Source/santad/MaintenanceWindowMonitor.h"
+#include "Source/santad/OfflineGraceMonitor.h"
 #import "Source/santad/SNTBinaryUploadController.h"
 #import "Source/santad/SNTDaemonControlController.h"
 #import "Source/santad/SNTDatabaseController.h"
 #import "Source/santad/SNTDecisionCache.h"
+#import "Source/santad/SNTLaunchItemMonitor.h"
+#import "Source/santad/SNTLockdownGracePeriod.h"
 #import "Source/santad/SNTLoginWindowSessionHandler.h"
 #include "Source/santad/SleighLauncher.h"
 #include "Source/santad/TTYWriter.h"
@@ -102,6 +109,12 @@ void SantadMain(std::shared_ptr<EndpointSecurityAPI> esapi, std::shared_ptr<Logg
       santa::SleighLauncher::Create(std::string(santa::SleighLauncher::kDefaultSleighPath)),
       /*timeout_seconds=*/6 * 60);
 
+  // Holds back a switch out of Monitor mode received during a maintenance window.
+  auto maintenance_window_monitor = santa::MaintenanceWindowMonitor::Create(configurator);
+
+  // Keeps a host that cannot sync in the mode named by its offline grace token until it expires.
+  auto offline_grace_monitor = santa::OfflineGraceMonitor::Create(configurator);
+
   SNTDaemonControlController* dc =
       [[SNTDaemonControlController alloc] initWithNotificationQueue:notifier_queue
           syncdQueue:syncd_queue
@@ -126,7 +139,8 @@ void SantadMain(std::shared_ptr<EndpointSecurityAPI> esapi, std::shared_ptr<Logg
               if (reply) reply(NO);
             }
           }
-          binaryUploadController:binary_upload_controller];
+          binaryUploadController:binary_upload_controller
+          maintenanceWindowMonitor:maintenance_window_monitor];
 
   // Watch for the sync server being removed or replaced, and restore any
   // recorded natural admins if that already happened while the daemon was not
@@ -182,6 +196,18 @@ void SantadMain(std::shared_ptr<EndpointSecurityAPI> esapi, std::shared_ptr<Logg
                                               prefixTree:prefix_tree
                                              processTree:process_tree];
 
+  SNTLaunchItemMonitor* launch_item_monitor =
+      [[SNTLaunchItemMonitor alloc] initWithRuleTable:[SNTDatabaseController ruleTable]
+                                         disableBlock:[SNTLaunchItemMonitor launchctlDisableBlock]];
+  launch_item_monitor.eventCallback = ^(SNTStoredLaunchItemEvent* event) {
+    // Only store launch item events if a sync server is configured.
+    if (configurator.syncBaseURL) {
+      [[SNTDatabaseController eventTable] addStoredEvent:event];
+      [syncd_queue addStoredEvent:event];
+    }
+  };
+  monitor_client.launchItemMonitor = launch_item_monitor;
+
   SNTEndpointSecurityAuthorizer* authorizer_client =
       [[SNTEndpointSecurityAuthorizer alloc] initWithESAPI:esapi
                                                    metrics:metrics
@@ -298,6 +324,15 @@ void SantadMain(std::shared_ptr<EndpointSecurityAPI> esapi, std::shared_ptr<Logg
 
                 SNTClientMode clientMode = (SNTClientMode)[newValue longLongValue];
 
+                // Only a change from Monitor to Lockdown starts a grace period, any other change
+                // ends one that is still in progress.
+                NSTimeInterval gracePeriod = 0;
+                if ((SNTClientMode)[oldValue longLongValue] == SNTClientModeMonitor &&
+                    clientMode == SNTClientModeLockdown) {
+                  gracePeriod = [configurator lockdownGracePeriodSec];
+                }
+                [[SNTLockdownGracePeriod sharedGracePeriod] startWithDuration:gracePeriod];
+
                 switch (clientMode) {
                   case SNTClientModeLockdown: [[fallthrough]];
                   case SNTClientModeStandalone:
@@ -505,6 +540,43 @@ void SantadMain(std::shared_ptr<EndpointSecurityAPI> esapi, std::shared_ptr<Logg
                 auth_result_cache->FlushCache(FlushCacheMode::kAllCaches,
                                               FlushCacheReason::kStaticRulesChanged);
               }],
+    [[SNTKVOManager alloc]
+        initWithObject:configurator
+              selector:@selector(rulePrecedence)
+                  type:[NSArray class]
+              callback:^(NSArray* oldValue, NSArray* newValue) {
+                if ((!oldValue && !newValue) || [oldValue isEqualToArray:newValue]) {
+                  return;
+                }
+
+                [exec_controller.ruleTable updateRulePrecedence:newValue];
+
+                LOGI(@"RulePrecedence changed. Flushing caches.");
+                auth_result_cache->FlushCache(FlushCacheMode::kAllCaches,
+                                              FlushCacheReason::kRulesChanged);
+              }],
+    [[SNTKVOManager alloc]
+        initWithObject:configurator
+              selector:@selector(enableDeveloperToolsAllowlist)
+                  type:[NSNumber class]
+              callback:^(NSNumber* oldValue, NSNumber* newValue) {
+                if ([oldValue boolValue] == [newValue boolValue]) return;
+
+                LOGI(@"EnableDeveloperToolsAllowlist changed. Flushing caches.");
+                auth_result_cache->FlushCache(FlushCacheMode::kAllCaches,
+                                              FlushCacheReason::kRulesChanged);
+              }],
+    [[SNTKVOManager alloc]
+        initWithObject:configurator
+              selector:@selector(developerToolsAllowlist)
+                  type:[NSArray class]
+              callback:^(NSArray* oldValue, NSArray* newValue) {
+                if ([oldValue isEqualToArray:newValue]) return;
+
+                LOGI(@"DeveloperToolsAllowlist changed. Flushing caches.");
+                auth_result_cache->FlushCache(FlushCacheMode::kAllCaches,
+                                              FlushCacheReason::kRulesChanged);
+              }],
     [[SNTKVOManager alloc]
         initWithObject:configurator
               selector:@selector(eventLogType)
@@ -579,6 +651,28 @@ void SantadMain(std::shared_ptr<EndpointSecurityAPI> esapi, std::shared_ptr<Logg
                                               FlushCacheReason::kEntitlementsPrefixFilterChanged);
                 [authorizer_client clearCache];
               }],
+    [[SNTKVOManager alloc]
+        initWithObject:configurator
+              selector:@selector(enableScriptEvaluation)
+                  type:[NSNumber class]
+              callback:^(NSNumber* oldValue, NSNumber* newValue) {
+                if ([oldValue boolValue] || ![newValue boolValue]) return;
+
+                // Interpreters allowed before the change may still be in the ES cache, which
+                // would keep the scripts they run from being delivered.
+                LOGI(@"EnableScriptEvaluation enabled. Clearing the ES cache.");
+                [authorizer_client clearCache];
+              }],
+    [[SNTKVOManager alloc]
+        initWithObject:configurator
+              selector:@selector(enableInterpreterScriptEvaluation)
+                  type:[NSNumber class]
+              callback:^(NSNumber* oldValue, NSNumber* newValue) {
+                if ([oldValue boolValue] || ![newValue boolValue]) return;
+
+                LOGI(@"EnableInterpreterScriptEvaluation enabled. Clearing the ES cache.");
+                [authorizer_client clearCache];
+              }],
     [[SNTKVOManager alloc]
         initWithObject:configurator
               selector:@selector(telemetry)
@@ -648,6 +742,55 @@ void SantadMain(std::shared_ptr<EndpointSecurityAPI> esapi, std::shared_ptr<Logg
                                    LOGI(@"MachineID changed: %@ -> %@", oldValue, newValue);
 
                                    logger->UpdateMachineIDLogging();
+                                   offline_grace_monitor->Evaluate();
+                                 }],
+    [[SNTKVOManager alloc] initWithObject:configurator
+                                 selector:@selector(offlineGraceToken)
+                                     type:[NSString class]
+                                 callback:^(NSString* oldValue, NSString* newValue) {
+                                   if ((!newValue && !oldValue) ||
+                                       ([newValue isEqualToString:oldValue])) {
+                                     return;
+                                   }
+                                   offline_grace_monitor->Evaluate();
+                                 }],
+    [[SNTKVOManager alloc] initWithObject:configurator
+                                 selector:@selector(offlineGraceTokenPublicKey)
+                                     type:[NSData class]
+                                 callback:^(NSData* oldValue, NSData* newValue) {
+                                   if ((!newValue && !oldValue) ||
+                                       ([newValue isEqualToData:oldValue])) {
+                                     return;
+                                   }
+                                   offline_grace_monitor->Evaluate();
+                                 }],
+    [[SNTKVOManager alloc] initWithObject:configurator
+                                 selector:@selector(offlineGraceFallbackClientMode)
+                                     type:[NSNumber class]
+                                 callback:^(NSNumber* oldValue, NSNumber* newValue) {
+                                   if ([oldValue isEqualToNumber:newValue]) {
+                                     return;
+                                   }
+                                   offline_grace_monitor->Evaluate();
+                                 }],
+    [[SNTKVOManager alloc] initWithObject:configurator
+                                 selector:@selector(offlineGraceSyncAgeSec)
+                                     type:[NSNumber class]
+                                 callback:^(NSNumber* oldValue, NSNumber* newValue) {
+                                   if ([oldValue isEqualToNumber:newValue]) {
+                                     return;
+                                   }
+                                   offline_grace_monitor->Evaluate();
+                                 }],
+    [[SNTKVOManager alloc] initWithObject:configurator
+                                 selector:@selector(fullSyncLastSuccess)
+                                     type:[NSDate class]
+                                 callback:^(NSDate* oldValue, NSDate* newValue) {
+                                   if ((!newValue && !oldValue) ||
+                                       ([newValue isEqualToDate:oldValue])) {
+                                     return;
+                                   }
+                                   offline_grace_monitor->Evaluate();
                                  }],
     [[SNTKVOManager alloc] initWithObject:configurator
                                  selector:@selector(enableTelemetryExport)
@@ -892,6 +1035,9 @@ void SantadMain(std::shared_ptr<EndpointSecurityAPI> esapi, std::shared_ptr<Logg
     installNetworkExtension(@"First launch after boot");
   }
 
+  // Watch for the system clock being moved backward.
+  auto clock_monitor = santa::ClockMonitor::Create(configurator, syncd_queue);
+
   // Trigger network extension install/upgrade when the system wakes up.
   auto power_monitor = santa::PowerMonitor::Create(^(santa::PowerEvent event) {
     if (event == santa::PowerEvent::kHasPoweredOn) {
@@ -899,5 +1045,18 @@ void SantadMain(std::shared_ptr<EndpointSecurityAPI> esapi, std::shared_ptr<Logg
     }
   });
 
+  // launchd sends SIGTERM when stopping the daemon. Handle it so that decisions still buffered by
+  // the logger are written out before exiting rather than lost.
+  signal(SIGTERM, SIG_IGN);
+  dispatch_source_t sigterm_source = dispatch_source_create(
+      DISPATCH_SOURCE_TYPE_SIGNAL, SIGTERM, 0,
+      dispatch_get_global_queue(QOS_CLASS_USER_INITIATED, 0));
+  dispatch_source_set_event_handler(sigterm_source, ^{
+    LOGI(@"Received SIGTERM. Flushing logs before exiting...");
+    logger->Close();
+    exit(EXIT_SUCCESS);
+  });
+  dispatch_resume(sigterm_source);
+
   [[NSRunLoop mainRunLoop] run];
 }
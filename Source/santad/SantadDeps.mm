@@ -156,6 +156,8 @@ std::unique_ptr<SantadDeps> SantadDeps::Create(SNTConfigurator* configurator,
   size_t spool_file_threshold_bytes = [configurator spoolDirectoryFileSizeThresholdKB] * 1024;
   size_t spool_dir_threshold_bytes = [configurator spoolDirectorySizeThresholdMB] * 1024 * 1024;
   uint64_t spool_flush_timeout_ms = [configurator spoolDirectoryEventMaxFlushTimeSec] * 1000;
+  size_t event_log_batch_size_bytes = [configurator eventLogBufferSizeKB] * 1024;
+  uint64_t event_log_flush_timeout_ms = [configurator eventLogMaxFlushTimeSec] * 1000;
   uint32_t telemetry_export_frequency_secs = [configurator telemetryExportIntervalSec];
 
   // Signal scanner: runs a Sleigh signal scan over each closed telemetry spool file using the
@@ -186,8 +188,8 @@ std::unique_ptr<SantadDeps> SantadDeps::Create(SNTConfigurator* configurator,
       TelemetryConfigToBitmask([configurator telemetry]), [configurator eventLogType],
       [SNTDecisionCache sharedCache], [configurator eventLogPath], [configurator spoolDirectory],
       spool_dir_threshold_bytes, spool_file_threshold_bytes, spool_flush_timeout_ms,
-      telemetry_export_frequency_secs, [configurator telemetryExportTimeoutSec],
-      [configurator telemetryExportBatchThresholdSizeMB],
+      event_log_batch_size_bytes, event_log_flush_timeout_ms, telemetry_export_frequency_secs,
+      [configurator telemetryExportTimeoutSec], [configurator telemetryExportBatchThresholdSizeMB],
       [configurator telemetryExportMaxFilesPerBatch]);
   if (!logger) {
     LOGE(@"Failed to create logger.");
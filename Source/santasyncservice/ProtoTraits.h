@@ -78,6 +78,17 @@ struct ProtoTraits<false> {
   static constexpr Decision ALLOW_CEL_FALLBACK = ::santa::sync::v1::ALLOW_UNKNOWN;
   static constexpr Decision BLOCK_CEL_FALLBACK = ::santa::sync::v1::BLOCK_UNKNOWN;
   static constexpr Decision ALLOW_PLATFORM = ::santa::sync::v1::ALLOW_PLATFORM;
+  // The sync protocol doesn't have requirement decisions; fall back to UNKNOWN.
+  static constexpr Decision ALLOW_REQUIREMENT = ::santa::sync::v1::ALLOW_UNKNOWN;
+  static constexpr Decision BLOCK_REQUIREMENT = ::santa::sync::v1::BLOCK_UNKNOWN;
+  // The sync protocol doesn't have a network volume decision; fall back to UNKNOWN.
+  static constexpr Decision BLOCK_NETWORK_VOLUME = ::santa::sync::v1::BLOCK_UNKNOWN;
+  // The sync protocol doesn't have a disk image decision; fall back to UNKNOWN.
+  static constexpr Decision BLOCK_DISK_IMAGE = ::santa::sync::v1::BLOCK_UNKNOWN;
+  // The sync protocol doesn't have a path churn decision; fall back to UNKNOWN.
+  static constexpr Decision BLOCK_PATH_CHURN = ::santa::sync::v1::BLOCK_UNKNOWN;
+  // The sync protocol doesn't have an allow-once decision; fall back to UNKNOWN.
+  static constexpr Decision ALLOW_ONCE = ::santa::sync::v1::ALLOW_UNKNOWN;
 
   using FileAccessAction = ::santa::sync::v1::FileAccessAction;
   static constexpr FileAccessAction FILE_ACCESS_ACTION_UNSPECIFIED = ::santa::sync::v1::FILE_ACCESS_ACTION_UNSPECIFIED;
@@ -179,6 +190,17 @@ struct ProtoTraits<true> {
   static constexpr Decision ALLOW_CEL_FALLBACK = ::santa::sync::v2::ALLOW_CEL_FALLBACK;
   static constexpr Decision BLOCK_CEL_FALLBACK = ::santa::sync::v2::BLOCK_CEL_FALLBACK;
   static constexpr Decision ALLOW_PLATFORM = ::santa::sync::v2::ALLOW_PLATFORM;
+  // The sync protocol doesn't have requirement decisions; fall back to UNKNOWN.
+  static constexpr Decision ALLOW_REQUIREMENT = ::santa::sync::v2::ALLOW_UNKNOWN;
+  static constexpr Decision BLOCK_REQUIREMENT = ::santa::sync::v2::BLOCK_UNKNOWN;
+  // The sync protocol doesn't have a network volume decision; fall back to UNKNOWN.
+  static constexpr Decision BLOCK_NETWORK_VOLUME = ::santa::sync::v2::BLOCK_UNKNOWN;
+  // The sync protocol doesn't have a disk image decision; fall back to UNKNOWN.
+  static constexpr Decision BLOCK_DISK_IMAGE = ::santa::sync::v2::BLOCK_UNKNOWN;
+  // The sync protocol doesn't have a path churn decision; fall back to UNKNOWN.
+  static constexpr Decision BLOCK_PATH_CHURN = ::santa::sync::v2::BLOCK_UNKNOWN;
+  // The sync protocol doesn't have an allow-once decision; fall back to UNKNOWN.
+  static constexpr Decision ALLOW_ONCE = ::santa::sync::v2::ALLOW_UNKNOWN;
 
   using FileAccessAction = ::santa::sync::v2::FileAccessAction;
   static constexpr FileAccessAction FILE_ACCESS_ACTION_UNSPECIFIED = ::santa::sync::v2::FILE_ACCESS_ACTION_UNSPECIFIED;
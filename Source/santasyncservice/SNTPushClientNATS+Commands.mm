@@ -389,6 +389,8 @@ static void CommandMessageHandlerImpl(natsConnection* nc, natsSubscription* sub,
 
   NSString* msgSubject = @(natsMsg_GetSubject(msg) ?: "<unknown>");
   NSString* replyTopic = natsMsg_GetReply(msg) ? @(natsMsg_GetReply(msg)) : nil;
+  [self.connectionStats recordMessageOnSubject:msgSubject
+                                         bytes:MAX(natsMsg_GetDataLength(msg), 0)];
 
   LOGD(@"NATS: Received command message on subject '%@' with reply '%@'", msgSubject,
        replyTopic ?: @"<no reply>");
@@ -12,10 +12,98 @@
 /// See the License for the specific language governing permissions and
 /// limitations under the License.
 
+#import "Source/santasyncservice/SNTPushConnectionStats.h"
+#import "Source/santasyncservice/SNTPushLogger.h"
+#import "Source/santasyncservice/SNTPushMessageDedupe.h"
 #import "Source/santasyncservice/SNTPushNotifications.h"
+#import "Source/santasyncservice/SNTPushSyncRateLimiter.h"
+
+// Backoff between connection attempts to the push server, used both for the NATS library's
+// automatic reconnects once an established connection drops and for retrying a connection that
+// failed. The delay before attempt n is base * 2^(n-1), capped at max, then randomized by up to
+// +/- jitterFraction of itself so that clients disconnected together don't reconnect together.
+typedef struct {
+  NSTimeInterval base;
+  NSTimeInterval max;
+  double jitterFraction;
+} NATSBackoffConfig;
+
+// 1s doubling up to 60s, +/- 20%.
+extern const NATSBackoffConfig kDefaultNATSReconnectBackoff;
+
+// 1s doubling up to 5 minutes, +/- 25%.
+extern const NATSBackoffConfig kNATSConnectionRetryBackoff;
+
+// The delay before connection attempt `attempt` (starting at 1). `random` is a uniformly
+// distributed value in [0, 1] that picks the jitter, 0.5 meaning none.
+NSTimeInterval NATSReconnectDelay(NATSBackoffConfig config, int attempt, double random);
+
+// The state of the connection to the push server. A client starts out Closed.
+typedef NS_ENUM(NSInteger, SNTPushConnectionState) {
+  SNTPushConnectionStateConnecting,
+  SNTPushConnectionStateConnected,
+  // The connection dropped. Usually followed straight away by Reconnecting.
+  SNTPushConnectionStateDisconnected,
+  // The NATS library is trying to re-establish a dropped connection.
+  SNTPushConnectionStateReconnecting,
+  // There is no connection and the NATS library won't re-establish one, e.g. before the first
+  // connection, after a failed connection attempt or after disconnecting.
+  SNTPushConnectionStateClosed,
+};
+
+typedef void (^SNTPushConnectionStateObserver)(SNTPushConnectionState oldState,
+                                               SNTPushConnectionState newState);
+
+// Supplies new push credentials when the push server rejects the current ones.
+@protocol SNTPushCredentialProvider <NSObject>
+// Fetch new push credentials, e.g. by running a preflight. reply may be called on any queue, with
+// the JWT and NKey seed or with an error.
+- (void)refreshPushCredentialsWithReply:(void (^)(NSString* jwt, NSString* nkey,
+                                                  NSError* error))reply;
+@end
 
 @interface SNTPushClientNATS : NSObject <SNTPushNotificationsClientDelegate>
 - (instancetype)initWithSyncDelegate:(id<SNTPushNotificationsSyncDelegate>)syncDelegate;
+- (instancetype)initWithSyncDelegate:(id<SNTPushNotificationsSyncDelegate>)syncDelegate
+                    reconnectBackoff:(NATSBackoffConfig)reconnectBackoff;
 - (void)disconnectWithCompletion:(void (^)(void))completion;
+// Stop receiving messages and let the ones already received be handled, including the delegate
+// calls they trigger, before closing the connection. disconnectWithCompletion: drops them instead.
+// If draining takes longer than timeout seconds the connection is closed anyway. completion is
+// called on the main queue with nil if the connection drained cleanly, or with an
+// SNTErrorCodePushDrainFailed error if it was closed without draining, e.g. after the timeout.
+- (void)shutdownWithTimeout:(NSTimeInterval)timeout completion:(void (^)(NSError* error))completion;
+// Replace the tags to subscribe to. On a live connection only the subjects that were removed or
+// added are unsubscribed from or subscribed to; the connection itself is kept.
+- (void)updateTags:(NSArray<NSString*>*)tags;
+// Snapshot of the current configuration and connection state keyed by the
+// kPushDiagnostics* constants in SNTSyncConstants.h.
+- (NSDictionary*)diagnostics;
 @property(nonatomic, readonly, copy) NSString* pushServer;
+// The expiry date of the push server's TLS certificate, as seen on the last successful connection.
+// nil if the client hasn't connected yet.
+@property(atomic, readonly) NSDate* serverCertificateNotAfter;
+// YES if serverCertificateNotAfter falls within PushServerCertificateExpiryWarningDays.
+@property(atomic, readonly) BOOL serverCertificateExpiresSoon;
+// Traffic counters for the push connection, included in diagnostics under kPushDiagnosticsStats.
+@property(readonly) SNTPushConnectionStats* connectionStats;
+@property(readonly) NATSBackoffConfig reconnectBackoff;
+@property(atomic, readonly) SNTPushConnectionState connectionState;
+// Drops repeats of a push notification, e.g. one published to both the host subject and a tag
+// subject, within PushMessageDedupeWindow. Only accessed on the message queue.
+@property(readonly) SNTPushMessageDedupe* dedupeCache;
+// Limits the full syncs push notifications trigger to PushSyncRateLimitBurst back to back and one
+// per PushSyncRateLimitInterval after that. Only accessed on the message queue.
+@property(readonly) SNTPushSyncRateLimiter* syncRateLimiter;
+// If set, rejected credentials are refreshed through the provider before each reconnect attempt,
+// with the usual retry backoff. Otherwise the client stops connecting until the credentials are
+// replaced by a preflight.
+@property(atomic, weak) id<SNTPushCredentialProvider> credentialProvider;
+// Receives the connect, subscribe, disconnect and connection error logs, each with the push
+// server and machine ID among its fields. Defaults to an SNTPushOSLogger.
+@property(atomic) id<SNTPushLogger> logger;
+// Register a block to be called on each change of connectionState. Observers are called in
+// registration order on a private serial queue, never on the NATS library's threads, so they
+// can't stall the connection. They can't be removed.
+- (void)addConnectionStateObserver:(SNTPushConnectionStateObserver)observer;
 @end
@@ -19,22 +19,35 @@
 #include <string.h>
 #include <sys/cdefs.h>
 
+#include <atomic>
+
 #include <google/protobuf/descriptor.h>
 #include "commands/v1.pb.h"
 
+#include "Source/common/NATSPermissions.h"
+#include "Source/common/NKeyTokenValidator.h"
 #import "Source/common/SNTConfigurator.h"
+#import "Source/common/SNTError.h"
 #import "Source/common/SNTLogging.h"
+#import "Source/common/SNTMetricSet.h"
+#import "Source/common/SNTRule.h"
+#import "Source/common/SNTStoredExecutionEvent.h"
 #import "Source/common/SNTStrengthify.h"
 #import "Source/common/SNTSyncConstants.h"
 #import "Source/common/SNTSystemInfo.h"
+#import "Source/common/SNTXPCControlInterface.h"
+#include "Source/common/SignedRuleBundle.h"
+#include "Source/common/String.h"
 #import "Source/santasyncservice/SNTSantaCommandHandler.h"
 #import "Source/santasyncservice/SNTSyncState.h"
+#import "Source/santasyncservice/SNTSyncTelemetry.h"
 
 __BEGIN_DECLS
 
 // Include NATS C client header
 #import "src/nats.h"
 
+#include <openssl/sha.h>
 #include <openssl/x509v3.h>
 
 __END_DECLS
@@ -97,12 +110,329 @@ extern "C" bool NATSLeafCertHasPushDomain(X509* cert) {
   return matched;
 }
 
+// Returns the leaf certificate's notAfter date as seconds since the epoch, or 0 if it can't be
+// determined. Extracted from NATSSSLVerifyCallback for testability.
+extern "C" int64_t NATSCertNotAfter(X509* cert) {
+  if (!cert) return 0;
+  const ASN1_TIME* notAfter = X509_get0_notAfter(cert);
+  int64_t posix = 0;
+  if (!notAfter || !ASN1_TIME_to_posix(notAfter, &posix)) return 0;
+  return posix;
+}
+
+// Returns the lowercase hex SHA-256 of the certificate's DER encoding, or nil if it can't be
+// encoded.
+NSString* NATSCertSHA256(X509* cert) {
+  if (!cert) return nil;
+  uint8_t* der = NULL;
+  int derLen = i2d_X509(cert, &der);
+  if (derLen <= 0) return nil;
+
+  uint8_t digest[SHA256_DIGEST_LENGTH];
+  SHA256(der, (size_t)derLen, digest);
+  OPENSSL_free(der);
+
+  NSMutableString* hex = [NSMutableString stringWithCapacity:SHA256_DIGEST_LENGTH * 2];
+  for (size_t i = 0; i < SHA256_DIGEST_LENGTH; ++i) {
+    [hex appendFormat:@"%02x", digest[i]];
+  }
+  return hex;
+}
+
+// Returns YES if any certificate in the verified chain, leaf or CA, has one of the pinned
+// SHA-256 fingerprints. Extracted from NATSSSLVerifyCallback for testability.
+BOOL NATSCertChainMatchesPins(STACK_OF(X509)* chain, NSArray<NSString*>* pins) {
+  for (size_t i = 0; i < sk_X509_num(chain); i++) {
+    NSString* fingerprint = NATSCertSHA256(sk_X509_value(chain, i));
+    if (fingerprint && [pins containsObject:fingerprint]) return YES;
+  }
+  return NO;
+}
+
+const NATSBackoffConfig kDefaultNATSReconnectBackoff = {
+    .base = 1.0,
+    .max = 60.0,
+    .jitterFraction = 0.2,
+};
+
+const NATSBackoffConfig kNATSConnectionRetryBackoff = {
+    .base = 1.0,
+    .max = 300.0,
+    .jitterFraction = 0.25,
+};
+
+NSTimeInterval NATSReconnectDelay(NATSBackoffConfig config, int attempt, double random) {
+  // Cap the exponent too, so a long outage can't overflow the delay.
+  int exponent = MIN(MAX(attempt, 1) - 1, 30);
+  NSTimeInterval delay = MIN(config.base * pow(2.0, exponent), config.max);
+  double jitter = config.jitterFraction * (2.0 * MIN(MAX(random, 0.0), 1.0) - 1.0);
+  return MAX(delay * (1.0 + jitter), 0.0);
+}
+
+// Returns the expiry of a push JWT from its exp claim, or distantFuture if it has none (NATS
+// JWTs without an exp claim don't expire). The signature isn't verified. Returns nil and sets
+// error if the JWT can't be parsed or the claims are inconsistent.
+NSDate* NATSJWTExpiry(NSString* jwt, NSError** error) {
+  NSDictionary* claims = jwt.length ? santa::ParseJWTPayload(santa::NSStringToUTF8StringView(jwt))
+                                    : nil;
+  if (!claims) {
+    [SNTError populateError:error withFormat:@"The push JWT could not be parsed"];
+    return nil;
+  }
+
+  id exp = claims[@"exp"];
+  id iat = claims[@"iat"];
+  if ((exp && ![exp isKindOfClass:[NSNumber class]]) ||
+      (iat && ![iat isKindOfClass:[NSNumber class]])) {
+    [SNTError populateError:error withFormat:@"The push JWT has a malformed exp or iat claim"];
+    return nil;
+  }
+  if ([exp longLongValue] <= 0) return [NSDate distantFuture];
+  if ([iat longLongValue] > [exp longLongValue]) {
+    [SNTError populateError:error withFormat:@"The push JWT expires before it was issued"];
+    return nil;
+  }
+  return [NSDate dateWithTimeIntervalSince1970:[exp doubleValue]];
+}
+
+static NSArray<NSString*>* NSArrayFromStrings(const std::vector<std::string>& strings) {
+  NSMutableArray<NSString*>* array = [NSMutableArray arrayWithCapacity:strings.size()];
+  for (const std::string& s : strings) {
+    [array addObject:santa::StringToNSString(s)];
+  }
+  return array;
+}
+
+// Describes a push JWT for the diagnostics snapshot, keyed by the kPushDiagnosticsJWT* constants.
+// The JWT itself is left out so the snapshot can be printed and uploaded.
+static NSDictionary* NATSJWTDiagnostics(NSString* jwt) {
+  if (!jwt.length) return @{};
+
+  NSDictionary* claims = santa::ParseJWTPayload(santa::NSStringToUTF8StringView(jwt));
+  NSDate* expiry = NATSJWTExpiry(jwt, nil);
+  std::optional<santa::NATSPermissions> perms = santa::NATSPermissionsFromJWT(jwt);
+  if (!claims || !expiry || !perms.has_value()) return @{kPushDiagnosticsJWTParsed : @NO};
+
+  NSMutableDictionary* diagnostics = [NSMutableDictionary dictionary];
+  diagnostics[kPushDiagnosticsJWTParsed] = @YES;
+  if ([claims[@"sub"] isKindOfClass:[NSString class]]) {
+    diagnostics[kPushDiagnosticsJWTSubject] = claims[@"sub"];
+  }
+  if (![expiry isEqualToDate:[NSDate distantFuture]]) {
+    diagnostics[kPushDiagnosticsJWTExpiry] = expiry;
+  }
+  diagnostics[kPushDiagnosticsJWTSubscribeAllow] = NSArrayFromStrings(perms->sub.allow);
+  diagnostics[kPushDiagnosticsJWTSubscribeDeny] = NSArrayFromStrings(perms->sub.deny);
+  return diagnostics;
+}
+
+// Parses the RFC 3339 timestamp of a kPushHeaderPublishedAt header, e.g.
+// 2026-10-14T09:30:00.123456789Z. NSISO8601DateFormatter only handles up to millisecond
+// fractions, so the fraction is parsed separately. Returns nil if the value can't be parsed.
+NSDate* NATSPushPublishedAt(NSString* value) {
+  static NSRegularExpression* re = [NSRegularExpression
+      regularExpressionWithPattern:
+          @"^(\\d{4}-\\d{2}-\\d{2}T\\d{2}:\\d{2}:\\d{2})(\\.\\d{1,9})?(Z|[+-]\\d{2}:\\d{2})$"
+                           options:0
+                             error:nil];
+  static NSISO8601DateFormatter* formatter = [[NSISO8601DateFormatter alloc] init];
+  if (!value.length) return nil;
+
+  NSTextCheckingResult* match = [re firstMatchInString:value
+                                               options:0
+                                                 range:NSMakeRange(0, value.length)];
+  if (!match) return nil;
+
+  NSString* seconds = [value substringWithRange:[match rangeAtIndex:1]];
+  NSString* zone = [value substringWithRange:[match rangeAtIndex:3]];
+  NSDate* date = [formatter dateFromString:[seconds stringByAppendingString:zone]];
+  if (!date) return nil;
+
+  NSRange fraction = [match rangeAtIndex:2];
+  if (fraction.location != NSNotFound) {
+    date = [date dateByAddingTimeInterval:[[value substringWithRange:fraction] doubleValue]];
+  }
+  return date;
+}
+
+// Returns YES if a failed or closed connection means the push server rejected the host's
+// credentials, e.g. because the JWT was revoked or has expired, rather than a transient network
+// problem. Retrying with the same credentials can't succeed.
+BOOL NATSErrorIsCredentialRejection(natsStatus status, NSString* error) {
+  if (status == NATS_CONNECTION_AUTH_FAILED) return YES;
+  for (NSString* pattern in
+       @[ @"authorization violation", @"authentication expired", @"authentication revoked" ]) {
+    if ([error rangeOfString:pattern options:NSCaseInsensitiveSearch].location != NSNotFound) {
+      return YES;
+    }
+  }
+  return NO;
+}
+
+static NSError* DrainTimedOutError(NSTimeInterval timeout) {
+  return [SNTError createErrorWithCode:SNTErrorCodePushDrainFailed
+                                format:@"The push connection did not drain within %.1f seconds "
+                                       @"and was closed",
+                                       timeout];
+}
+
+// Bounds on the rules carried inline in an apply_rules push notification. Anything larger should
+// be delivered with a sync.
+static const NSUInteger kApplyRulesMaxPayloadBytes = 16 * 1024;
+static const NSUInteger kApplyRulesMaxRules = 50;
+static const NSTimeInterval kApplyRulesMaxLifetime = 3600;
+static const NSTimeInterval kApplyRulesClockSkew = 60;
+
+// Returns the rules carried inline in an apply_rules push notification. The payload is a signed
+// rule bundle (see SignedRuleBundle.h) wrapping a JSON object like the one `santactl rule --import`
+// accepts, plus claims that bind it to this use and bound its lifetime:
+//   {"purpose": "apply_rules", "jti": "...", "iat": 1800000000, "exp": 1800000600,
+//    "rules": [{"identifier": "...", "policy": "BLOCKLIST", "rule_type": "BINARY"}, ...]}
+// On success bundleID and expirationDate are set from the jti and exp claims, for the caller to
+// reject replays. Returns nil if the payload is too large, isn't signed by publicKey, isn't meant
+// for apply_rules, isn't valid at `now` or holds no rules, too many or an invalid one.
+NSArray<SNTRule*>* InlineRulesFromPushPayload(NSData* payload, NSData* publicKey, NSDate* now,
+                                              NSString** bundleID, NSDate** expirationDate,
+                                              NSError** error) {
+  if (payload.length > kApplyRulesMaxPayloadBytes) {
+    [SNTError populateError:error
+                 withFormat:@"Inline rules payload of %lu bytes exceeds the limit of %lu bytes",
+                            payload.length, kApplyRulesMaxPayloadBytes];
+    return nil;
+  }
+  if (!publicKey) {
+    [SNTError populateError:error
+                   withCode:SNTErrorCodeFailedToVerifySignature
+                     format:@"No PushInlineRulesPublicKey is configured"];
+    return nil;
+  }
+
+  NSData* data = santa::VerifySignedRuleBundle(payload, publicKey, error);
+  if (!data) return nil;
+
+  NSDictionary* dict = [NSJSONSerialization JSONObjectWithData:data options:0 error:NULL];
+  NSArray* jsonRules = [dict isKindOfClass:[NSDictionary class]] ? dict[@"rules"] : nil;
+  if (![jsonRules isKindOfClass:[NSArray class]]) {
+    [SNTError populateError:error
+                   withCode:SNTErrorCodeFailedToParseJSON
+                     format:@"Inline rules payload is not a JSON object with a rules array"];
+    return nil;
+  }
+
+  // A bundle signed for another use, e.g. `santactl rule --import`, has no apply_rules purpose.
+  if (![dict[@"purpose"] isEqual:kPushTypeApplyRules]) {
+    [SNTError populateError:error withFormat:@"Inline rules payload is not meant for apply_rules"];
+    return nil;
+  }
+  NSString* jti = dict[@"jti"];
+  NSNumber* iat = dict[@"iat"];
+  NSNumber* exp = dict[@"exp"];
+  if (![jti isKindOfClass:[NSString class]] || !jti.length ||
+      ![iat isKindOfClass:[NSNumber class]] || ![exp isKindOfClass:[NSNumber class]]) {
+    [SNTError populateError:error
+                 withFormat:@"Inline rules payload is missing its jti, iat or exp claim"];
+    return nil;
+  }
+  NSDate* issued = [NSDate dateWithTimeIntervalSince1970:[iat doubleValue]];
+  NSDate* expires = [NSDate dateWithTimeIntervalSince1970:[exp doubleValue]];
+  if ([expires timeIntervalSinceDate:issued] <= 0 ||
+      [expires timeIntervalSinceDate:issued] > kApplyRulesMaxLifetime) {
+    [SNTError populateError:error
+                 withFormat:@"Inline rules payload must expire within %.0f seconds of being issued",
+                            kApplyRulesMaxLifetime];
+    return nil;
+  }
+  if ([issued timeIntervalSinceDate:now] > kApplyRulesClockSkew ||
+      [expires compare:now] != NSOrderedDescending) {
+    [SNTError populateError:error withFormat:@"Inline rules payload %@ is not valid now", jti];
+    return nil;
+  }
+
+  if (jsonRules.count == 0 || jsonRules.count > kApplyRulesMaxRules) {
+    [SNTError populateError:error
+                 withFormat:@"Inline rules payload holds %lu rules, expected 1 to %lu",
+                            jsonRules.count, kApplyRulesMaxRules];
+    return nil;
+  }
+
+  // Reject the whole payload if any rule is invalid, rather than applying part of an urgent update.
+  NSMutableArray<SNTRule*>* rules = [NSMutableArray arrayWithCapacity:jsonRules.count];
+  for (id jsonRule in jsonRules) {
+    SNTRule* rule = [[SNTRule alloc] initWithDictionary:jsonRule error:error];
+    if (!rule) return nil;
+    [rules addObject:rule];
+  }
+  if (bundleID) *bundleID = jti;
+  if (expirationDate) *expirationDate = expires;
+  return rules;
+}
+
+// Bounds on the response to an export_decisions push notification. The size bound keeps the
+// response well under the NATS server's default 1 MiB max payload.
+static const NSUInteger kExportDecisionsMaxCount = 500;
+static const NSUInteger kExportDecisionsMaxResponseBytes = 256 * 1024;
+static const NSTimeInterval kExportDecisionsMinimumInterval = 60;
+
+static NSDictionary* ExportedDecision(SNTStoredExecutionEvent* event) {
+  static NSISO8601DateFormatter* formatter = [[NSISO8601DateFormatter alloc] init];
+  NSMutableDictionary* d = [NSMutableDictionary dictionary];
+  d[@"occurrence_date"] = [formatter stringFromDate:event.occurrenceDate];
+  d[@"decision"] = (event.decision & SNTEventStateAllow) ? @"allow" : @"block";
+  d[@"decision_state"] = @(event.decision);
+  d[@"file_path"] = event.filePath;
+  d[@"file_sha256"] = event.fileSHA256;
+  d[@"cdhash"] = event.cdhash;
+  d[@"signing_id"] = event.signingID;
+  d[@"team_id"] = event.teamID;
+  d[@"pid"] = event.pid;
+  d[@"ppid"] = event.ppid;
+  return d;
+}
+
+// Encodes the export_decisions response, dropping the oldest decisions (the end of the
+// newest-first array) until it fits in maxBytes. Extracted for testability.
+NSData* ExportDecisionsResponse(NSArray<SNTStoredExecutionEvent*>* decisions, NSUInteger maxBytes) {
+  // Size each decision on its own so the number that fits can be found without re-encoding the
+  // whole response. Allow for the surrounding object and a comma between decisions.
+  static const NSUInteger kEnvelopeBytes = sizeof("{\"decisions\":[],\"truncated\":false}");
+  NSMutableArray<NSDictionary*>* exported = [NSMutableArray arrayWithCapacity:decisions.count];
+  NSUInteger size = kEnvelopeBytes;
+  for (SNTStoredExecutionEvent* event in decisions) {
+    NSDictionary* d = ExportedDecision(event);
+    NSData* encoded = [NSJSONSerialization dataWithJSONObject:d options:0 error:nil];
+    if (!encoded || size + encoded.length + 1 > maxBytes) break;
+    size += encoded.length + 1;
+    [exported addObject:d];
+  }
+
+  NSDictionary* response = @{
+    @"decisions" : exported,
+    @"truncated" : @(exported.count < decisions.count),
+  };
+  NSError* error;
+  NSData* data = [NSJSONSerialization dataWithJSONObject:response options:0 error:&error];
+  if (!data) LOGE(@"NATS: Failed to encode decisions: %@", error);
+  return data;
+}
+
+// The notAfter date of the last leaf certificate checked by NATSSSLVerifyCallback. The callback
+// has no context pointer to reach the client, and there is only ever one NATS connection.
+static std::atomic<int64_t> gLastVerifiedLeafCertNotAfter{0};
+
+static NSDate* LastVerifiedServerCertificateNotAfter() {
+  int64_t notAfter = gLastVerifiedLeafCertNotAfter.load();
+  return notAfter ? [NSDate dateWithTimeIntervalSince1970:notAfter] : nil;
+}
+
 // SSL verification callback for production NATS connections. Enforces that the leaf
 // certificate's SAN is a hostname within push.northpole.security, in addition to the
 // standard chain validation performed by preverifyOk. This closes the MITM gap that
 // exists when hostname verification is not enabled via NATS_FORCE_HOST_VERIFICATION:
 // without this check, any certificate from any trusted CA would be accepted.
-static int NATSSSLVerifyCallback(int preverifyOk, void* ctx) {
+//
+// If PushServerPinnedCertificateSHA256 is set the verified chain must also contain one of the
+// pinned certificates, in every build configuration.
+int NATSSSLVerifyCallback(int preverifyOk, void* ctx) {
   if (!preverifyOk) return 0;
 
   X509_STORE_CTX* storeCtx = (X509_STORE_CTX*)ctx;
@@ -114,7 +444,15 @@ static int NATSSSLVerifyCallback(int preverifyOk, void* ctx) {
   X509* cert = X509_STORE_CTX_get_current_cert(storeCtx);
   if (!cert) return 0;
 
+  NSArray<NSString*>* pins = [[SNTConfigurator configurator] pushServerPinnedCertificateSHA256];
+  if (pins && !NATSCertChainMatchesPins(X509_STORE_CTX_get0_chain(storeCtx), pins)) {
+    LOGE(@"NATS: Server certificate chain does not match any pinned certificate (leaf SHA-256 %@)",
+         NATSCertSHA256(cert));
+    return 0;
+  }
+
   bool ok = NATSLeafCertHasPushDomain(cert);
+  gLastVerifiedLeafCertNotAfter.store(NATSCertNotAfter(cert));
 #ifdef DEBUG
   // DEBUG builds normally allow any otherwise-valid certificate so a local test
   // server can be used. Setting SANTA_NATS_ENFORCE_PUSH_DOMAIN opts in to the
@@ -138,7 +476,7 @@ static int NATSSSLVerifyCallback(int preverifyOk, void* ctx) {
 @property(nonatomic) SNTSantaCommandHandler* commandHandler;
 @property(nonatomic) natsConnection* conn;
 // Array of natsSubscription pointers wrapped in NSValue
-@property(nonatomic) NSMutableArray<NSValue*>* tagSubscriptions;
+@property(nonatomic) NSMutableDictionary<NSString*, NSValue*>* tagSubscriptions;
 // Commands subscription
 @property(nonatomic) natsSubscription* commandsSubscription;
 // Single queue for connection management
@@ -147,12 +485,25 @@ static int NATSSSLVerifyCallback(int preverifyOk, void* ctx) {
 @property(nonatomic) dispatch_queue_t messageQueue;
 @property(atomic, readwrite) BOOL isConnected;
 @property(nonatomic, readwrite) NSUInteger fullSyncInterval;
+// Ping settings from preflight, 0 to use the NATS library defaults.
+@property(nonatomic) NSUInteger pingInterval;
+@property(nonatomic) NSUInteger maxPingsOut;
+// From preflight. Bounds the jitter of, and the rate of, syncs after reconnecting.
+@property(nonatomic) NSUInteger globalRuleSyncDeadline;
+@property(nonatomic) NSDate* lastCatchUpSync;
 @property(atomic) BOOL isShuttingDown;
+// Set while the connection drains for shutdownWithTimeout:completion:. Only accessed on
+// connectionQueue.
+@property(nonatomic, copy) void (^drainCompletion)(NSError* error);
+@property(nonatomic) NSTimeInterval drainTimeout;
 // Push notification configuration from preflight
 @property(nonatomic, copy) NSString* pushServer;
 // nkey
 @property(nonatomic, copy) NSString* pushToken;
 @property(nonatomic, copy) NSString* jwt;
+// The credentials of the current connection, read by the NATS library's threads.
+@property(atomic, copy) NSString* connectionJWT;
+@property(atomic, copy) NSString* connectionSeed;
 @property(nonatomic, copy) NSString* pushDeviceID;
 @property(nonatomic, copy) NSArray<NSString*>* tags;
 @property(nonatomic, copy) NSData* hmacKey;
@@ -167,21 +518,58 @@ static int NATSSSLVerifyCallback(int preverifyOk, void* ctx) {
 @property(atomic) BOOL isRetrying;
 // Track the last error for better retry diagnostics
 @property(nonatomic, copy) NSString* lastConnectionError;
+// Set when the push server rejected the current credentials. No connection is attempted until
+// a preflight provides different ones.
+@property(atomic) BOOL credentialsRejected;
+// Set while the credential provider is fetching new credentials.
+@property(nonatomic) BOOL isRefreshingCredentials;
+@property(atomic, readwrite) SNTPushConnectionState connectionState;
+@property(nonatomic) NSMutableArray<SNTPushConnectionStateObserver>* stateObservers;
+// Queue that connection state observers are called on
+@property(nonatomic) dispatch_queue_t observerQueue;
+// The most recent subject the server rejected a subscription for.
+@property(atomic, copy) NSString* lastDeniedSubject;
+@property(atomic, readwrite) NSDate* serverCertificateNotAfter;
+@property(atomic, readwrite) BOOL serverCertificateExpiresSoon;
+// When decisions were last exported, used to rate limit export_decisions requests.
+// Only accessed on the messageQueue.
+@property(nonatomic) NSDate* lastDecisionExport;
 @end
 
 @implementation SNTPushClientNATS
 
 - (instancetype)initWithSyncDelegate:(id<SNTPushNotificationsSyncDelegate>)syncDelegate {
+  return [self initWithSyncDelegate:syncDelegate reconnectBackoff:kDefaultNATSReconnectBackoff];
+}
+
+- (instancetype)initWithSyncDelegate:(id<SNTPushNotificationsSyncDelegate>)syncDelegate
+                    reconnectBackoff:(NATSBackoffConfig)reconnectBackoff {
   self = [super init];
   if (self) {
     _syncDelegate = syncDelegate;
+    _reconnectBackoff = reconnectBackoff;
     _commandHandler = [[SNTSantaCommandHandler alloc] initWithSyncDelegate:syncDelegate];
     _fullSyncInterval = kDefaultPushNotificationsFullSyncInterval;
+    _globalRuleSyncDeadline = kDefaultPushNotificationsGlobalRuleSyncDeadline;
     _connectionQueue =
         dispatch_queue_create("com.northpolesec.santa.nats.connection", DISPATCH_QUEUE_SERIAL);
     _messageQueue =
         dispatch_queue_create("com.northpolesec.santa.nats.message", DISPATCH_QUEUE_SERIAL);
-    _tagSubscriptions = [NSMutableArray array];
+    _observerQueue =
+        dispatch_queue_create("com.northpolesec.santa.nats.observer", DISPATCH_QUEUE_SERIAL);
+    _connectionState = SNTPushConnectionStateClosed;
+    _stateObservers = [NSMutableArray array];
+    _tagSubscriptions = [NSMutableDictionary dictionary];
+    _logger = [[SNTPushOSLogger alloc] init];
+    _dedupeCache = [[SNTPushMessageDedupe alloc]
+        initWithWindow:[[SNTConfigurator configurator] pushMessageDedupeWindow]];
+    _syncRateLimiter = [[SNTPushSyncRateLimiter alloc]
+        initWithInterval:[[SNTConfigurator configurator] pushSyncRateLimitInterval]
+                   burst:[[SNTConfigurator configurator] pushSyncRateLimitBurst]];
+    _connectionStats = [[SNTPushConnectionStats alloc]
+        initWithMetricSet:[[SNTConfigurator configurator] exportPushConnectionMetrics]
+                              ? [SNTMetricSet sharedInstance]
+                              : nil];
 
     _currentNonces = [NSMutableSet set];
     _previousNonces = [NSMutableSet set];
@@ -282,6 +670,7 @@ static int NATSSSLVerifyCallback(int preverifyOk, void* ctx) {
 
     if (credentialsChanged) {
       LOGI(@"NATS: Credentials changed - will reconnect with new JWT/NKey");
+      self.credentialsRejected = NO;
     }
 
     // Handle configuration changes
@@ -296,12 +685,10 @@ static int NATSSSLVerifyCallback(int preverifyOk, void* ctx) {
         natsConnection_Destroy(self.conn);
         self.conn = NULL;
         self.isConnected = NO;
+        [self transitionToConnectionState:SNTPushConnectionStateClosed];
       } else if (deviceIDChanged) {
         LOGI(@"NATS: Device ID changed, resubscribing to all topics");
         [self unsubscribeAll];
-      } else {
-        LOGI(@"NATS: Tags changed, resubscribing to all topics");
-        [self unsubscribeAll];
       }
     }
 
@@ -318,9 +705,23 @@ static int NATSSSLVerifyCallback(int preverifyOk, void* ctx) {
     if (credentialsChanged) {
       // Reconnect with new credentials
       [self connect];
-    } else if ((deviceIDChanged || tagsChanged) && isConnected) {
-      // Just resubscribe with new device ID or tags
+    } else if (deviceIDChanged && isConnected) {
+      // Just resubscribe with the new device ID
       [self subscribe];
+    } else if (tagsChanged && isConnected) {
+      LOGI(@"NATS: Tags changed, updating tag subscriptions");
+      [self updateTagSubscriptions];
+    }
+  });
+}
+
+- (void)updateTags:(NSArray<NSString*>*)tags {
+  dispatch_async(self.connectionQueue, ^{
+    if (self.isShuttingDown) return;
+
+    self.tags = tags;
+    if ([self isConnectionAlive]) {
+      [self updateTagSubscriptions];
     }
   });
 }
@@ -330,6 +731,15 @@ static int NATSSSLVerifyCallback(int preverifyOk, void* ctx) {
   return self.pushServer && self.pushToken && self.jwt && self.pushDeviceID;
 }
 
+// Log through self.logger, adding the push server and machine ID to fields.
+- (void)logWithType:(os_log_type_t)type message:(NSString*)message fields:(NSDictionary*)fields {
+  NSMutableDictionary* allFields = [NSMutableDictionary dictionary];
+  allFields[kPushLogFieldServer] = self.pushServer ?: @"";
+  allFields[kPushLogFieldMachineID] = [[SNTConfigurator configurator] machineID] ?: @"";
+  [allFields addEntriesFromDictionary:fields];
+  [self.logger logWithType:type message:message fields:allFields];
+}
+
 // Check if the connection is actually alive by consulting both our flag and the NATS library.
 // This should be called from within connectionQueue for thread safety.
 - (BOOL)isConnectionAlive {
@@ -352,7 +762,7 @@ static int NATSSSLVerifyCallback(int preverifyOk, void* ctx) {
 
 - (void)connect {
   dispatch_async(self.connectionQueue, ^{
-    if (self.isShuttingDown) return;
+    if (self.isShuttingDown || self.drainCompletion) return;
 
     // Check if we already have a live connection
     if ([self isConnectionAlive]) {
@@ -374,13 +784,33 @@ static int NATSSSLVerifyCallback(int preverifyOk, void* ctx) {
       return;
     }
 
+    if (self.credentialsRejected) {
+      if (self.credentialProvider) {
+        [self refreshRejectedCredentials];
+      } else {
+        LOGD(@"NATS: Not connecting - the credentials were rejected, waiting for new ones");
+      }
+      return;
+    }
+
+    NSError* credentialsError;
+    if (![self validateCredentials:&credentialsError]) {
+      [self logWithType:OS_LOG_TYPE_DEFAULT
+                message:@"NATS: Not connecting, the push credentials are invalid"
+                 fields:@{kPushLogFieldError : credentialsError.localizedDescription ?: @""}];
+      [self stopConnectingWithRejectedCredentials:credentialsError.localizedDescription];
+      return;
+    }
+
     natsStatus status;
 
     // Create connection options
     natsOptions* opts = NULL;
     status = natsOptions_Create(&opts);
     if (status != NATS_OK) {
-      LOGE(@"NATS: Failed to create options: %s", natsStatus_GetText(status));
+      [self logWithType:OS_LOG_TYPE_ERROR
+                message:@"NATS: Failed to create options"
+                 fields:@{kPushLogFieldError : @(natsStatus_GetText(status))}];
       return;
     }
 
@@ -390,27 +820,32 @@ static int NATSSSLVerifyCallback(int preverifyOk, void* ctx) {
 #ifndef DEBUG
     // Make sure it's running on push.northpole.security and on port 443
     if (![self.pushServer hasSuffix:@".push.northpole.security:443"]) {
-      LOGE(@"NATS: Invalid push server domain. Must end with '.push.northpole.security:443', got: "
-           @"%@",
-           self.pushServer);
+      [self logWithType:OS_LOG_TYPE_ERROR
+                message:@"NATS: Invalid push server domain. Must end with "
+                        @"'.push.northpole.security:443'"
+                 fields:@{}];
       natsOptions_Destroy(opts);
       return;
     }
 
     // Production builds must use TLS
     if (![self.pushServer hasPrefix:@"tls://"]) {
-      LOGE(@"NATS: Invalid push server domain. Must start with 'tls://', got: %@", self.pushServer);
+      [self logWithType:OS_LOG_TYPE_ERROR
+                message:@"NATS: Invalid push server domain. Must start with 'tls://'"
+                 fields:@{}];
       natsOptions_Destroy(opts);
       return;
     }
 #endif
     serverURL = self.pushServer;
 
-    LOGI(@"NATS: Using connection to %@", serverURL);
+    [self logWithType:OS_LOG_TYPE_INFO message:@"NATS: Connecting" fields:@{}];
 
     status = natsOptions_SetURL(opts, [serverURL UTF8String]);
     if (status != NATS_OK) {
-      LOGE(@"NATS: Failed to set URL %@: %s", serverURL, natsStatus_GetText(status));
+      [self logWithType:OS_LOG_TYPE_ERROR
+                message:@"NATS: Failed to set URL"
+                 fields:@{kPushLogFieldError : @(natsStatus_GetText(status))}];
       natsOptions_Destroy(opts);
       return;
     }
@@ -420,7 +855,9 @@ static int NATSSSLVerifyCallback(int preverifyOk, void* ctx) {
     // rather than relying on the server's INFO to upgrade the connection.
     status = natsOptions_SetSecure(opts, true);
     if (status != NATS_OK) {
-      LOGE(@"NATS: Failed to enable TLS: %s", natsStatus_GetText(status));
+      [self logWithType:OS_LOG_TYPE_ERROR
+                message:@"NATS: Failed to enable TLS"
+                 fields:@{kPushLogFieldError : @(natsStatus_GetText(status))}];
       natsOptions_Destroy(opts);
       return;
     }
@@ -431,29 +868,32 @@ static int NATSSSLVerifyCallback(int preverifyOk, void* ctx) {
     // and the standard INFO-then-upgrade flow.
     status = natsOptions_TLSHandshakeFirst(opts);
     if (status != NATS_OK) {
-      LOGE(@"NATS: Failed to enable TLS handshake-first: %s", natsStatus_GetText(status));
+      [self logWithType:OS_LOG_TYPE_ERROR
+                message:@"NATS: Failed to enable TLS handshake-first"
+                 fields:@{kPushLogFieldError : @(natsStatus_GetText(status))}];
       natsOptions_Destroy(opts);
       return;
     }
 
     status = natsOptions_SetSSLVerificationCallback(opts, NATSSSLVerifyCallback);
     if (status != NATS_OK) {
-      LOGE(@"NATS: Failed to set SSL verification callback: %s", natsStatus_GetText(status));
+      [self logWithType:OS_LOG_TYPE_ERROR
+                message:@"NATS: Failed to set SSL verification callback"
+                 fields:@{kPushLogFieldError : @(natsStatus_GetText(status))}];
       natsOptions_Destroy(opts);
       return;
     }
 
-    // Set nkey and JWT for authentication
-    // Create a combined string with JWT and seed (nkey) separated by newlines
-    NSString* jwtAndSeed = [NSString
-        stringWithFormat:
-            @"-----BEGIN NATS USER JWT-----\n%@\n------END NATS USER JWT------\n\n-----BEGIN USER "
-            @"NKEY SEED-----\n%@\n------END USER NKEY SEED------",
-            self.jwt, self.pushToken];
-
-    status = natsOptions_SetUserCredentialsFromMemory(opts, [jwtAndSeed UTF8String]);
+    // Authenticate with the JWT, signing the server's nonce with the nkey seed. The callbacks
+    // also run on reconnects, so they read the credentials this connection was made with.
+    self.connectionJWT = self.jwt;
+    self.connectionSeed = self.pushToken;
+    status = natsOptions_SetUserCredentialsCallbacks(opts, &userJWTCallback, (__bridge void*)self,
+                                                     &signNonceCallback, (__bridge void*)self);
     if (status != NATS_OK) {
-      LOGE(@"NATS: Failed to set credentials: %s", natsStatus_GetText(status));
+      [self logWithType:OS_LOG_TYPE_ERROR
+                message:@"NATS: Failed to set credentials"
+                 fields:@{kPushLogFieldError : @(natsStatus_GetText(status))}];
       natsOptions_Destroy(opts);
       return;
     }
@@ -461,13 +901,27 @@ static int NATSSSLVerifyCallback(int preverifyOk, void* ctx) {
     // Connection options
     status = natsOptions_SetTimeout(opts, 60000);  // 60s connection timeout
     if (status != NATS_OK) {
-      LOGE(@"NATS: Failed to set connection timeout: %s", natsStatus_GetText(status));
+      [self logWithType:OS_LOG_TYPE_ERROR
+                message:@"NATS: Failed to set connection timeout"
+                 fields:@{kPushLogFieldError : @(natsStatus_GetText(status))}];
       natsOptions_Destroy(opts);
       return;
     }
 
+    // Faster pings detect a connection silently dropped by a firewall sooner. Without values from
+    // preflight the library defaults apply.
+    if (self.pingInterval > 0) {
+      natsOptions_SetPingInterval(opts, (int64_t)self.pingInterval * 1000);
+    }
+    if (self.maxPingsOut > 0) {
+      natsOptions_SetMaxPingsOut(opts, (int)MIN(self.maxPingsOut, (NSUInteger)INT_MAX));
+    }
+
     natsOptions_SetAllowReconnect(opts, true);
     natsOptions_SetMaxReconnect(opts, -1);  // Infinite reconnects
+    // Without this every client waits the same fixed time between attempts, so a push server
+    // restart would have the whole fleet reconnect in lockstep.
+    natsOptions_SetCustomReconnectDelay(opts, &reconnectDelayCallback, (__bridge void*)self);
 
     // Set error callback to catch subscription violations and other errors
     natsOptions_SetErrorHandler(opts, &errorHandler, (__bridge void*)self);
@@ -479,6 +933,8 @@ static int NATSSSLVerifyCallback(int preverifyOk, void* ctx) {
 
     // Create connection
     natsConnection* conn = NULL;
+    gLastVerifiedLeafCertNotAfter.store(0);
+    [self transitionToConnectionState:SNTPushConnectionStateConnecting];
     status = natsConnection_Connect(&conn, opts);
     natsOptions_Destroy(opts);
 
@@ -504,16 +960,20 @@ static int NATSSSLVerifyCallback(int preverifyOk, void* ctx) {
       }
 
       self.lastConnectionError = [NSString stringWithFormat:@"[%@] %@", errorCategory, errorDetail];
-      LOGE(@"NATS: Failed to connect to %@ - %@ (category: %@)", serverURL, errorDetail,
-           errorCategory);
-      [self scheduleConnectionRetry];
+      [self logWithType:OS_LOG_TYPE_ERROR
+                message:@"NATS: Failed to connect"
+                 fields:@{kPushLogFieldError : errorDetail, kPushLogFieldCategory : errorCategory}];
+      [self transitionToConnectionState:SNTPushConnectionStateClosed];
+      [self handleConnectionFailureWithStatus:status error:errorDetail];
       return;
     }
 
-    LOGI(@"NATS: Connected to %@", serverURL);
+    [self logWithType:OS_LOG_TYPE_INFO message:@"NATS: Connected" fields:@{}];
     self.conn = conn;
     self.isConnected = YES;
     self.lastConnectionError = nil;
+    [self transitionToConnectionState:SNTPushConnectionStateConnected];
+    [self checkServerCertificateExpiry:LastVerifiedServerCertificateNotAfter()];
 
     // Reset retry state on successful connection
     self.retryAttempt = 0;
@@ -525,9 +985,54 @@ static int NATSSSLVerifyCallback(int preverifyOk, void* ctx) {
 
     // Subscribe to topics
     [self subscribe];
+    [self measureRTT];
   });
 }
 
+// Must be called on connectionQueue.
+- (void)measureRTT {
+  int64_t rtt = 0;
+  if ([self isConnectionAlive] && natsConnection_GetRTT(self.conn, &rtt) == NATS_OK) {
+    [self.connectionStats recordRTT:rtt / (double)NSEC_PER_SEC];
+  }
+}
+
+- (void)checkServerCertificateExpiry:(NSDate*)notAfter {
+  self.serverCertificateNotAfter = notAfter;
+
+  NSUInteger warningDays = [[SNTConfigurator configurator] pushServerCertificateExpiryWarningDays];
+  if (!notAfter || warningDays == 0) {
+    self.serverCertificateExpiresSoon = NO;
+    return;
+  }
+
+  NSTimeInterval remaining = [notAfter timeIntervalSinceNow];
+  self.serverCertificateExpiresSoon = remaining < warningDays * 86400.0;
+  if (self.serverCertificateExpiresSoon) {
+    LOGW(@"NATS: Push server certificate for %@ expires on %@ (in %ld days). The certificate must "
+         @"be rotated before then to avoid a push notification outage.",
+         self.pushServer, notAfter, (long)MAX(remaining / 86400, 0));
+  }
+}
+
+- (NSDictionary*)diagnostics {
+  // The configuration and connection state are owned by connectionQueue, so read them there to
+  // get a consistent snapshot.
+  NSMutableDictionary* diagnostics = [NSMutableDictionary dictionary];
+  dispatch_sync(self.connectionQueue, ^{
+    diagnostics[kPushDiagnosticsEnabled] = @YES;
+    diagnostics[kPushDiagnosticsServer] = self.pushServer;
+    [diagnostics addEntriesFromDictionary:NATSJWTDiagnostics(self.jwt)];
+    diagnostics[kPushDiagnosticsDeviceID] = self.pushDeviceID;
+    diagnostics[kPushDiagnosticsTags] = self.tags;
+    diagnostics[kPushDiagnosticsConnected] = @(self.isConnected);
+    diagnostics[kPushDiagnosticsLastError] = self.lastConnectionError;
+    diagnostics[kPushDiagnosticsDeniedSubject] = self.lastDeniedSubject;
+    diagnostics[kPushDiagnosticsStats] = [self.connectionStats export];
+  });
+  return diagnostics;
+}
+
 - (void)disconnectWithCompletion:(void (^)(void))completion {
   // Mark shutting down synchronously so no further work is queued while we tear
   // down. The connection and subscription state is owned by connectionQueue, so
@@ -549,7 +1054,7 @@ static int NATSSSLVerifyCallback(int preverifyOk, void* ctx) {
       return;
     }
 
-    LOGD(@"NATS: Starting disconnect");
+    [self logWithType:OS_LOG_TYPE_DEBUG message:@"NATS: Starting disconnect" fields:@{}];
 
     // Cancel any pending retry timer
     if (self.connectionRetryTimer) {
@@ -562,15 +1067,16 @@ static int NATSSSLVerifyCallback(int preverifyOk, void* ctx) {
     [self unsubscribeAll];
 
     if (self.conn) {
-      LOGD(@"NATS: Closing connection");
+      [self logWithType:OS_LOG_TYPE_DEBUG message:@"NATS: Closing connection" fields:@{}];
       natsConnection_Close(self.conn);
-      LOGD(@"NATS: Destroying connection");
+      [self logWithType:OS_LOG_TYPE_DEBUG message:@"NATS: Destroying connection" fields:@{}];
       natsConnection_Destroy(self.conn);
       self.conn = NULL;
     }
 
     self.isConnected = NO;
-    LOGI(@"NATS: Disconnected");
+    [self transitionToConnectionState:SNTPushConnectionStateClosed];
+    [self logWithType:OS_LOG_TYPE_INFO message:@"NATS: Disconnected" fields:@{}];
 
     if (completion) {
       dispatch_async(dispatch_get_main_queue(), completion);
@@ -578,6 +1084,93 @@ static int NATSSSLVerifyCallback(int preverifyOk, void* ctx) {
   });
 }
 
+- (void)shutdownWithTimeout:(NSTimeInterval)timeout
+                 completion:(void (^)(NSError* error))completion {
+  dispatch_async(self.connectionQueue, ^{
+    if (self.drainCompletion) {
+      void (^previous)(NSError*) = self.drainCompletion;
+      self.drainCompletion = ^(NSError* error) {
+        previous(error);
+        if (completion) completion(error);
+      };
+      return;
+    }
+
+    // Nothing to drain.
+    if (self.isShuttingDown || ![self isConnectionAlive]) {
+      [self disconnectWithCompletion:^{
+        if (completion) completion(nil);
+      }];
+      return;
+    }
+
+    if (self.connectionRetryTimer) {
+      dispatch_source_cancel(self.connectionRetryTimer);
+      self.connectionRetryTimer = nil;
+    }
+    self.isRetrying = NO;
+    self.drainCompletion = completion ?: ^(NSError* error) {
+    };
+    self.drainTimeout = timeout;
+
+    int64_t timeoutMs = (int64_t)(MAX(timeout, 0) * 1000);
+    [self logWithType:OS_LOG_TYPE_INFO
+              message:@"NATS: Draining connection"
+               fields:@{kPushLogFieldDelaySeconds : [NSString stringWithFormat:@"%.1f", timeout]}];
+    // The closed callback finishes the drain once the NATS library has delivered the pending
+    // messages.
+    natsStatus status = natsConnection_DrainTimeout(self.conn, timeoutMs);
+    if (status != NATS_OK) {
+      [self logWithType:OS_LOG_TYPE_ERROR
+                message:@"NATS: Failed to drain connection, closing it"
+                 fields:@{kPushLogFieldError : @(natsStatus_GetText(status))}];
+      [self closeConnectionForShutdown];
+      [self finishDrainWithError:[SNTError createErrorWithCode:SNTErrorCodePushDrainFailed
+                                                        format:@"Unable to drain the push "
+                                                               @"connection: %s",
+                                                               natsStatus_GetText(status)]];
+      return;
+    }
+
+    dispatch_after(dispatch_time(DISPATCH_TIME_NOW, timeoutMs * NSEC_PER_MSEC),
+                   self.connectionQueue, ^{
+                     if (!self.drainCompletion) return;
+                     [self logWithType:OS_LOG_TYPE_DEFAULT
+                               message:@"NATS: Drain timed out, closing connection"
+                                fields:@{}];
+                     [self closeConnectionForShutdown];
+                     [self finishDrainWithError:DrainTimedOutError(timeout)];
+                   });
+  });
+}
+
+// Unsubscribe and destroy the connection, closing it first if it is still draining. Must be
+// called on connectionQueue.
+- (void)closeConnectionForShutdown {
+  [self unsubscribeAll];
+  if (self.conn) {
+    natsConnection_Close(self.conn);
+    natsConnection_Destroy(self.conn);
+    self.conn = NULL;
+  }
+  self.isConnected = NO;
+  [self transitionToConnectionState:SNTPushConnectionStateClosed];
+}
+
+// Must be called on connectionQueue.
+- (void)finishDrainWithError:(NSError*)error {
+  void (^completion)(NSError*) = self.drainCompletion;
+  if (!completion) return;
+  self.drainCompletion = nil;
+  self.isShuttingDown = YES;
+  if (!error) {
+    [self logWithType:OS_LOG_TYPE_INFO message:@"NATS: Connection drained" fields:@{}];
+  }
+  dispatch_async(dispatch_get_main_queue(), ^{
+    completion(error);
+  });
+}
+
 - (void)cleanupSubscription:(natsSubscription**)subscription {
   if (subscription && *subscription) {
     natsSubscription_Unsubscribe(*subscription);
@@ -589,10 +1182,10 @@ static int NATSSSLVerifyCallback(int preverifyOk, void* ctx) {
 - (void)unsubscribeAll {
   // This should only be called from within connectionQueue
   // Failure to unsubscribe is non-fatal - client continues operating
-  LOGD(@"NATS: Unsubscribing from all topics");
+  [self logWithType:OS_LOG_TYPE_DEBUG message:@"NATS: Unsubscribing from all topics" fields:@{}];
 
   // Unsubscribe all tag subscriptions
-  for (NSValue* subValue in self.tagSubscriptions) {
+  for (NSValue* subValue in self.tagSubscriptions.allValues) {
     natsSubscription* sub = (natsSubscription*)[subValue pointerValue];
     if (sub) {
       natsSubscription_Unsubscribe(sub);
@@ -610,7 +1203,7 @@ static int NATSSSLVerifyCallback(int preverifyOk, void* ctx) {
     self.commandsSubscription = NULL;
   }
 
-  LOGD(@"NATS: All topics unsubscribed");
+  [self logWithType:OS_LOG_TYPE_DEBUG message:@"NATS: All topics unsubscribed" fields:@{}];
 }
 
 - (BOOL)isValidNATSTopic:(NSString*)topic {
@@ -639,72 +1232,113 @@ static int NATSSSLVerifyCallback(int preverifyOk, void* ctx) {
 
   // Verify connection is alive before subscribing
   if (![self isConnectionAlive]) {
-    LOGW(@"NATS: Cannot subscribe - not connected");
+    [self logWithType:OS_LOG_TYPE_DEFAULT
+              message:@"NATS: Cannot subscribe, not connected"
+               fields:@{}];
     return;
   }
 
   natsStatus status;
 
-  // Use push device ID from preflight
   // Subscribe to all tags from preflight: santa.tag.<tag>
-  if (self.tags && self.tags.count > 0) {
-    LOGD(@"NATS: Processing %lu tags from preflight", (unsigned long)self.tags.count);
-
-    // Keep track of already subscribed topics to avoid duplicates
-    NSMutableSet* subscribedTopics = [NSMutableSet set];
-
-    for (NSString* tag in self.tags) {
-      LOGD(@"NATS: Processing tag: '%@'", tag);
-
-      // Skip if we've already subscribed to this topic
-      if ([subscribedTopics containsObject:tag]) {
-        LOGD(@"NATS: Skipping duplicate subscription to: %@", tag);
-        continue;
-      }
-
-      if (![self isValidNATSTopic:tag]) {
-        LOGE(@"NATS: Invalid tag: %@ - skipping", tag);
-        continue;
-      }
-
-      natsSubscription* tagSub = NULL;
-      status = natsConnection_Subscribe(&tagSub, self.conn, [tag UTF8String], &messageHandler,
-                                        (__bridge void*)self);
-
-      if (status != NATS_OK) {
-        LOGE(@"NATS: Failed to subscribe to tag topic %@: %s", tag, natsStatus_GetText(status));
-      } else {
-        LOGI(@"NATS: Subscribed to tag topic: %@", tag);
-        // Store the subscription for later cleanup
-        [self.tagSubscriptions addObject:[NSValue valueWithPointer:tagSub]];
-        [subscribedTopics addObject:tag];
-      }
-    }
-  }
+  LOGD(@"NATS: Processing %lu tags from preflight", (unsigned long)self.tags.count);
+  [self updateTagSubscriptions];
 
   // Subscribe to commands topic: santa.host.<device-id>.commands
   // Note: Failure to subscribe to commands topic is non-fatal - client continues operating
   if (self.pushDeviceID.length > 0) {
     NSString* commandsTopic =
         [NSString stringWithFormat:@"santa.host.%@.commands", self.pushDeviceID];
-    LOGD(@"NATS: Subscribing to commands topic: %@", commandsTopic);
+    [self logWithType:OS_LOG_TYPE_DEBUG
+              message:@"NATS: Subscribing to commands topic"
+               fields:@{kPushLogFieldSubject : commandsTopic}];
 
     natsSubscription* commandsSub = NULL;
     status = natsConnection_Subscribe(&commandsSub, self.conn, [commandsTopic UTF8String],
                                       &commandMessageHandler, (__bridge void*)self);
 
     if (status != NATS_OK) {
-      LOGE(@"NATS: Failed to subscribe to commands topic %@: %s (non-fatal, continuing)",
-           commandsTopic, natsStatus_GetText(status));
+      [self logWithType:OS_LOG_TYPE_ERROR
+                message:@"NATS: Failed to subscribe to commands topic (non-fatal, continuing)"
+                 fields:@{
+                   kPushLogFieldSubject : commandsTopic,
+                   kPushLogFieldError : @(natsStatus_GetText(status)),
+                 }];
       // Client continues operating even if commands subscription fails
       // Commands will simply not be received, but other subscriptions continue
     } else {
-      LOGI(@"NATS: Subscribed to commands topic: %@", commandsTopic);
+      [self logWithType:OS_LOG_TYPE_INFO
+                message:@"NATS: Subscribed to commands topic"
+                 fields:@{kPushLogFieldSubject : commandsTopic}];
       self.commandsSubscription = commandsSub;
+      [self.connectionStats recordSubscribeToSubject:commandsTopic];
     }
   } else {
-    LOGW(@"NATS: Cannot subscribe to commands topic - no device ID available (non-fatal)");
+    [self logWithType:OS_LOG_TYPE_DEFAULT
+              message:@"NATS: Cannot subscribe to commands topic, no device ID available "
+                      @"(non-fatal)"
+               fields:@{}];
+  }
+}
+
+// Bring the tag subscriptions in line with self.tags on the live connection: unsubscribe from
+// subjects no longer listed and subscribe to new ones. Subjects in both, including the host
+// subject, keep their subscription so no messages on them are missed. Must be called on
+// connectionQueue.
+- (void)updateTagSubscriptions {
+  NSMutableOrderedSet<NSString*>* subjects = [NSMutableOrderedSet orderedSet];
+  for (NSString* tag in self.tags) {
+    if (![self isValidNATSTopic:tag]) {
+      [self logWithType:OS_LOG_TYPE_ERROR
+                message:@"NATS: Invalid tag, skipping"
+                 fields:@{kPushLogFieldSubject : tag}];
+      continue;
+    }
+    [subjects addObject:tag];
+  }
+
+  for (NSString* subject in self.tagSubscriptions.allKeys) {
+    if (![subjects containsObject:subject]) {
+      [self unsubscribeFromTagSubject:subject];
+    }
+  }
+  for (NSString* subject in subjects) {
+    if (!self.tagSubscriptions[subject]) {
+      [self subscribeToTagSubject:subject];
+    }
+  }
+}
+
+- (void)subscribeToTagSubject:(NSString*)subject {
+  natsSubscription* tagSub = NULL;
+  natsStatus status = natsConnection_Subscribe(&tagSub, self.conn, [subject UTF8String],
+                                               &messageHandler, (__bridge void*)self);
+  if (status != NATS_OK) {
+    [self logWithType:OS_LOG_TYPE_ERROR
+              message:@"NATS: Failed to subscribe to tag topic"
+               fields:@{
+                 kPushLogFieldSubject : subject,
+                 kPushLogFieldError : @(natsStatus_GetText(status)),
+               }];
+    return;
   }
+
+  [self logWithType:OS_LOG_TYPE_INFO
+            message:@"NATS: Subscribed to tag topic"
+             fields:@{kPushLogFieldSubject : subject}];
+  // Store the subscription for later cleanup
+  self.tagSubscriptions[subject] = [NSValue valueWithPointer:tagSub];
+  [self.connectionStats recordSubscribeToSubject:subject];
+}
+
+// Failure to unsubscribe is non-fatal: the subscription is dropped either way.
+- (void)unsubscribeFromTagSubject:(NSString*)subject {
+  natsSubscription* sub = (natsSubscription*)[self.tagSubscriptions[subject] pointerValue];
+  [self cleanupSubscription:&sub];
+  [self.tagSubscriptions removeObjectForKey:subject];
+  [self logWithType:OS_LOG_TYPE_INFO
+            message:@"NATS: Unsubscribed from tag topic"
+             fields:@{kPushLogFieldSubject : subject}];
 }
 
 // Handle a push notification for the given subject by dispatching a sync.
@@ -716,14 +1350,101 @@ static int NATSSSLVerifyCallback(int preverifyOk, void* ctx) {
 //   - If max_jitter is not set (or the payload is absent/undecodable), the
 //     default of [0, kDefaultPushNotificationTagSyncJitterSeconds) is used.
 // Host subjects (santa.host.*) always trigger an immediate sync.
+// If the kPushHeaderType header is kPushTypeCollectDiagnostics, a diagnostics
+// upload is scheduled in the same way instead of a sync. If it is
+// kPushTypeRotateCredentials, a preflight to refresh the push credentials is.
 - (void)handlePushNotificationForSubject:(NSString*)subject withPayload:(NSData*)payload {
+  [self handlePushNotificationForSubject:subject withPayload:payload headers:nil];
+}
+
+// Tag messages may additionally carry the kPushHeaderSyncIntervalOverride and
+// kPushHeaderSyncIntervalOverrideDuration headers, asking every host with that
+// tag to use a different full sync interval for a limited time. Both headers
+// must be present and positive for the override to be applied. Overrides are
+// ignored on host subjects.
+- (void)handlePushNotificationForSubject:(NSString*)subject
+                             withPayload:(NSData*)payload
+                                 headers:(NSDictionary<NSString*, NSString*>*)headers {
+  [self handlePushNotificationForSubject:subject
+                             withPayload:payload
+                                 headers:headers
+                            replySubject:nil];
+}
+
+// Host messages with the kPushTypeExportDecisions type are answered on the
+// message's reply subject instead, see exportDecisionsWithHeaders:. Messages
+// that trigger a sync, or one of the other scheduled actions, are dropped if
+// dedupeCache has seen the same message within the dedupe window. If those
+// messages have a reply subject they are acknowledged on it, see
+// acknowledgeMessageID:type:delaySeconds:duplicate:replySubject:.
+- (void)handlePushNotificationForSubject:(NSString*)subject
+                             withPayload:(NSData*)payload
+                                 headers:(NSDictionary<NSString*, NSString*>*)headers
+                            replySubject:(NSString*)replySubject {
+  NSDate* receivedAt = [NSDate date];
   dispatch_async(self.messageQueue, ^{
     if (self.isShuttingDown) {
       return;
     }
 
+    [[SNTSyncTelemetry sharedTelemetry] incrementCounter:SNTSyncTelemetryCounterPushMessages by:1];
+
+    NSDate* publishedAt = NATSPushPublishedAt(headers[kPushHeaderPublishedAt]);
+    if (publishedAt) {
+      NSTimeInterval latency = [receivedAt timeIntervalSinceDate:publishedAt];
+      // A host clock running behind the server's gives a negative latency, which is meaningless.
+      if (latency >= 0) [self.connectionStats recordPushLatency:latency];
+    }
+
+    if ([headers[kPushHeaderType] isEqualToString:kPushTypeApplyRules]) {
+      [self applyInlineRulesFromPayload:payload subject:subject];
+      return;
+    }
+
+    if ([headers[kPushHeaderType] isEqualToString:kPushTypeExportDecisions]) {
+      if (![subject hasPrefix:@"santa.host."]) {
+        LOGW(@"NATS: Ignoring decision export request on non-host subject %@", subject);
+        return;
+      }
+      [self exportDecisionsWithHeaders:headers replySubject:replySubject];
+      return;
+    }
+
+    BOOL collectDiagnostics =
+        [headers[kPushHeaderType] isEqualToString:kPushTypeCollectDiagnostics];
+    BOOL rotateCredentials = [headers[kPushHeaderType] isEqualToString:kPushTypeRotateCredentials];
+    BOOL reportRules = [headers[kPushHeaderType] isEqualToString:kPushTypeReportRules];
+    NSString* action = @"sync";
+    NSString* type = kPushTypeSync;
+    if (collectDiagnostics) {
+      action = @"diagnostics upload";
+      type = kPushTypeCollectDiagnostics;
+    } else if (rotateCredentials) {
+      action = @"push credential refresh";
+      type = kPushTypeRotateCredentials;
+    } else if (reportRules) {
+      action = @"rule snapshot upload";
+      type = kPushTypeReportRules;
+    }
+
+    NSString* messageID = headers[kPushHeaderMessageID]
+                              ?: [SNTPushMessageDedupe messageIDForHeaders:headers payload:payload];
+    if (![self.dedupeCache shouldProcessMessageID:messageID now:[NSDate date]]) {
+      LOGD(@"NATS: Ignoring repeated message %@ on %@", messageID, subject);
+      [self acknowledgeMessageID:messageID
+                            type:type
+                    delaySeconds:0
+                       duplicate:YES
+                    replySubject:replySubject];
+      return;
+    }
+
     uint32_t jitterSeconds = 0;
     if ([subject hasPrefix:@"santa.tag."]) {
+      if (!collectDiagnostics && !rotateCredentials && !reportRules) {
+        [self applySyncIntervalOverrideFromHeaders:headers forTag:subject];
+      }
+
       // Default to the standard jitter window unless the SyncRequest overrides it.
       uint32_t maxJitter = (uint32_t)kDefaultPushNotificationTagSyncJitterSeconds;
 
@@ -736,20 +1457,236 @@ static int NATSSSLVerifyCallback(int preverifyOk, void* ctx) {
       }
 
       jitterSeconds = arc4random_uniform(maxJitter);
-      LOGI(@"NATS: Scheduling sync in %u seconds (max jitter %u) due to tag message on %@",
+      LOGI(@"NATS: Scheduling %@ in %u seconds (max jitter %u) due to tag message on %@", action,
            jitterSeconds, maxJitter, subject);
     } else {
-      LOGI(@"NATS: Triggering immediate sync due to message on %@", subject);
+      LOGI(@"NATS: Triggering immediate %@ due to message on %@", action, subject);
+    }
+
+    if (!collectDiagnostics && !rotateCredentials && !reportRules) {
+      NSTimeInterval delay = 0;
+      switch ([self.syncRateLimiter decisionForSyncAt:[NSDate date] delay:&delay]) {
+        case SNTPushSyncRateLimitDecisionAllow: break;
+        case SNTPushSyncRateLimitDecisionDefer:
+          jitterSeconds = MAX(jitterSeconds, (uint32_t)ceil(delay));
+          LOGI(@"NATS: Too many push-triggered syncs, deferring the sync for %@ to %u seconds",
+               subject, jitterSeconds);
+          break;
+        case SNTPushSyncRateLimitDecisionCoalesce:
+          LOGD(@"NATS: Too many push-triggered syncs, %@ is covered by the sync in %.0f seconds",
+               subject, ceil(delay));
+          [self acknowledgeMessageID:messageID
+                                type:type
+                        delaySeconds:(uint32_t)ceil(delay)
+                           duplicate:NO
+                        replySubject:replySubject];
+          return;
+      }
     }
 
     dispatch_async(dispatch_get_main_queue(), ^{
-      if (!self.isShuttingDown) {
-        [self.syncDelegate syncSecondsFromNow:jitterSeconds];
+      if (self.isShuttingDown) return;
+      id<SNTPushNotificationsSyncDelegate> syncDelegate = self.syncDelegate;
+      if (collectDiagnostics) {
+        if ([syncDelegate respondsToSelector:@selector(collectDiagnosticsSecondsFromNow:)]) {
+          [syncDelegate collectDiagnosticsSecondsFromNow:jitterSeconds];
+        }
+      } else if (rotateCredentials) {
+        if ([syncDelegate respondsToSelector:@selector(rotatePushCredentialsSecondsFromNow:)]) {
+          [syncDelegate rotatePushCredentialsSecondsFromNow:jitterSeconds];
+        }
+      } else if (reportRules) {
+        if ([syncDelegate respondsToSelector:@selector(reportRulesSecondsFromNow:)]) {
+          [syncDelegate reportRulesSecondsFromNow:jitterSeconds];
+        }
+      } else {
+        [syncDelegate syncSecondsFromNow:jitterSeconds];
       }
+      [self acknowledgeMessageID:messageID
+                            type:type
+                    delaySeconds:jitterSeconds
+                       duplicate:NO
+                    replySubject:replySubject];
     });
   });
 }
 
+// Answers an export_decisions request by publishing the host's recent decisions in the requested
+// window to replySubject as JSON:
+//   {"decisions": [{...}, ...], "truncated": false}
+// Decisions are newest first. At most kExportDecisionsMaxCount decisions are sent and the oldest
+// are dropped until the response fits in kExportDecisionsMaxResponseBytes, in which case
+// truncated is true. Requests within kExportDecisionsMinimumInterval of the previous export are
+// dropped. Must be called on the messageQueue.
+// Publishes an acknowledgement of a push message to its reply subject, if it has one, once the
+// requested action was scheduled. It carries the host's machine ID, the message ID, the action type
+// and the delay before the action runs. Repeats dropped by dedupeCache are acknowledged as
+// duplicates, as the host already acted on the first copy.
+- (void)acknowledgeMessageID:(NSString*)messageID
+                        type:(NSString*)type
+                delaySeconds:(uint32_t)delaySeconds
+                   duplicate:(BOOL)duplicate
+                replySubject:(NSString*)replySubject {
+  if (!replySubject.length) return;
+
+  NSDictionary* ack = @{
+    @"machine_id" : [[SNTConfigurator configurator] machineID] ?: @"",
+    @"message_id" : messageID,
+    @"type" : type,
+    @"delay_seconds" : @(delaySeconds),
+    @"duplicate" : @(duplicate),
+  };
+  NSError* error;
+  NSData* data = [NSJSONSerialization dataWithJSONObject:ack options:0 error:&error];
+  if (!data) {
+    LOGE(@"NATS: Failed to encode acknowledgement of %@: %@", messageID, error);
+    return;
+  }
+  [self publishData:data toSubject:replySubject];
+}
+
+- (void)exportDecisionsWithHeaders:(NSDictionary<NSString*, NSString*>*)headers
+                      replySubject:(NSString*)replySubject {
+  if (!replySubject.length) {
+    LOGW(@"NATS: Ignoring decision export request without a reply subject");
+    return;
+  }
+
+  NSString* startHeader = headers[kPushHeaderExportDecisionsStart];
+  NSString* endHeader = headers[kPushHeaderExportDecisionsEnd];
+  NSDate* now = [NSDate date];
+  NSDate* start = [NSDate dateWithTimeIntervalSince1970:startHeader.doubleValue];
+  NSDate* end = endHeader ? [NSDate dateWithTimeIntervalSince1970:endHeader.doubleValue] : now;
+  if (startHeader.doubleValue <= 0 || [end compare:start] == NSOrderedAscending) {
+    LOGW(@"NATS: Ignoring decision export request with invalid window (start: %@, end: %@)",
+         startHeader, endHeader);
+    return;
+  }
+
+  if (self.lastDecisionExport &&
+      [now timeIntervalSinceDate:self.lastDecisionExport] < kExportDecisionsMinimumInterval) {
+    LOGW(@"NATS: Ignoring decision export request, the last export was less than %.0f seconds ago",
+         kExportDecisionsMinimumInterval);
+    return;
+  }
+  self.lastDecisionExport = now;
+
+  id<SNTPushNotificationsSyncDelegate> syncDelegate = self.syncDelegate;
+  if (![syncDelegate respondsToSelector:@selector(exportDecisionsFrom:to:limit:reply:)]) return;
+
+  LOGI(@"NATS: Exporting decisions between %@ and %@ to %@", start, end, replySubject);
+  [syncDelegate exportDecisionsFrom:start
+                                 to:end
+                              limit:kExportDecisionsMaxCount
+                              reply:^(NSArray<SNTStoredExecutionEvent*>* decisions) {
+                                NSData* response = ExportDecisionsResponse(
+                                    decisions, kExportDecisionsMaxResponseBytes);
+                                if (response) [self publishData:response toSubject:replySubject];
+                              }];
+}
+
+// Verifies the rules carried inline in an apply_rules push notification and adds them without a
+// sync. Existing rules are kept. santad records each bundle's ID before its rules are added, so a
+// bundle is only ever applied once. Must be called on the messageQueue.
+- (void)applyInlineRulesFromPayload:(NSData*)payload subject:(NSString*)subject {
+  NSError* error;
+  NSString* bundleID;
+  NSDate* expirationDate;
+  NSArray<SNTRule*>* rules = InlineRulesFromPushPayload(
+      payload, [[SNTConfigurator configurator] pushInlineRulesPublicKey], [NSDate date], &bundleID,
+      &expirationDate, &error);
+  if (!rules) {
+    LOGW(@"NATS: Rejecting inline rules on %@: %@", subject, error.localizedDescription);
+    return;
+  }
+
+  id<SNTPushNotificationsSyncDelegate> syncDelegate = self.syncDelegate;
+  if (!syncDelegate) return;
+
+  id<SNTDaemonControlXPC> rop = [[syncDelegate daemonConnection] remoteObjectProxy];
+  [rop redeemPushRuleBundleID:bundleID
+               expirationDate:expirationDate
+                        reply:^(NSError* redeemError) {
+                          if (redeemError) {
+                            LOGW(@"NATS: Rejecting inline rules on %@: %@", subject,
+                                 redeemError.localizedDescription);
+                            return;
+                          }
+                          [self addInlineRules:rules withProxy:rop subject:subject];
+                        }];
+}
+
+- (void)addInlineRules:(NSArray<SNTRule*>*)rules
+             withProxy:(id<SNTDaemonControlXPC>)rop
+               subject:(NSString*)subject {
+  LOGI(@"NATS: Applying %lu inline rules from %@", rules.count, subject);
+  [rop databaseRuleAddExecutionRules:rules
+                     fileAccessRules:nil
+                    networkFlowRules:nil
+                             signals:nil
+                         ruleCleanup:SNTRuleCleanupNone
+                              source:SNTRuleAddSourceSyncService
+                               reply:^(BOOL success, NSArray<NSError*>* errors) {
+                                 if (!success) {
+                                   LOGE(@"NATS: Failed to apply inline rules from %@: %@", subject,
+                                        errors.firstObject.localizedFailureReason);
+                                 }
+                               }];
+}
+
+// Must be called on the messageQueue.
+- (void)applySyncIntervalOverrideFromHeaders:(NSDictionary<NSString*, NSString*>*)headers
+                                      forTag:(NSString*)tag {
+  NSString* intervalHeader = headers[kPushHeaderSyncIntervalOverride];
+  NSString* durationHeader = headers[kPushHeaderSyncIntervalOverrideDuration];
+  if (!intervalHeader || !durationHeader) return;
+
+  long long interval = intervalHeader.longLongValue;
+  long long duration = durationHeader.longLongValue;
+  if (interval <= 0 || duration <= 0) {
+    LOGW(@"NATS: Ignoring invalid sync interval override on %@ (interval: %@, duration: %@)", tag,
+         intervalHeader, durationHeader);
+    return;
+  }
+
+  LOGI(@"NATS: Overriding full sync interval to %lld seconds for %lld seconds due to %@",
+       interval, duration, tag);
+  dispatch_async(dispatch_get_main_queue(), ^{
+    id<SNTPushNotificationsSyncDelegate> syncDelegate = self.syncDelegate;
+    SEL overrideSelector = @selector(overrideFullSyncInterval:forDuration:tag:);
+    if (self.isShuttingDown || ![syncDelegate respondsToSelector:overrideSelector]) {
+      return;
+    }
+    [syncDelegate overrideFullSyncInterval:(NSUInteger)interval
+                               forDuration:(NSTimeInterval)duration
+                                       tag:tag];
+  });
+}
+
+// Copies the headers of a NATS message into a dictionary. Only the first value
+// of a repeated header is kept.
+static NSDictionary<NSString*, NSString*>* HeadersFromMessage(natsMsg* msg) {
+  const char** keys = NULL;
+  int count = 0;
+  if (natsMsgHeader_Keys(msg, &keys, &count) != NATS_OK || count == 0) {
+    free((void*)keys);
+    return nil;
+  }
+
+  NSMutableDictionary<NSString*, NSString*>* headers =
+      [NSMutableDictionary dictionaryWithCapacity:count];
+  for (int i = 0; i < count; i++) {
+    const char* value = NULL;
+    if (natsMsgHeader_Get(msg, keys[i], &value) == NATS_OK && value) {
+      NSString* key = @(keys[i]);
+      NSString* val = @(value);
+      if (key && val) headers[key] = val;
+    }
+  }
+  free((void*)keys);
+  return headers;
+}
+
 // NATS message handler
 static void messageHandler(natsConnection* nc, natsSubscription* sub, natsMsg* msg, void* closure) {
   if (!closure || !msg) {
@@ -771,8 +1708,12 @@ static void messageHandler(natsConnection* nc, natsSubscription* sub, natsMsg* m
   // Copy the (optionally present) payload out of the NATS-owned message before
   // it is destroyed. For tag subjects this is an encoded SyncRequest proto.
   NSData* payload = (data && dataLen > 0) ? [NSData dataWithBytes:data length:dataLen] : nil;
+  NSDictionary<NSString*, NSString*>* headers = HeadersFromMessage(msg);
+  const char* reply = natsMsg_GetReply(msg);
+  NSString* replySubject = reply ? @(reply) : nil;
 
   LOGD(@"NATS: Received message on subject '%@' (%d byte payload)", msgSubject, dataLen);
+  [self.connectionStats recordMessageOnSubject:msgSubject bytes:MAX(dataLen, 0)];
 
   // Process on message queue to serialize handling of messages and gurantee we
   // avoid blocking the NATS managed thread. Then call back to the main thread
@@ -781,11 +1722,32 @@ static void messageHandler(natsConnection* nc, natsSubscription* sub, natsMsg* m
   //
   // IMPORTANT: Do not touch the nats objects in this block they are owned by
   // the nats library and will be destroyed after this block.
-  [self handlePushNotificationForSubject:msgSubject withPayload:payload];
+  [self handlePushNotificationForSubject:msgSubject
+                             withPayload:payload
+                                 headers:headers
+                            replySubject:replySubject];
 
   natsMsg_Destroy(msg);
 }
 
+// Publish a raw message to the given subject. Failures are logged.
+- (void)publishData:(NSData*)data toSubject:(NSString*)subject {
+  dispatch_async(self.connectionQueue, ^{
+    if (![self isConnectionAlive]) {
+      LOGW(@"NATS: Cannot publish to %@ - not connected (non-fatal)", subject);
+      return;
+    }
+
+    natsStatus status = natsConnection_Publish(self.conn, [subject UTF8String], data.bytes,
+                                               static_cast<int>(data.length));
+    if (status != NATS_OK) {
+      LOGE(@"NATS: Failed to publish to %@: %s (non-fatal)", subject, natsStatus_GetText(status));
+    } else {
+      [self.connectionStats recordPublishOfBytes:data.length];
+    }
+  });
+}
+
 // Publish a command response to the reply topic
 - (void)publishResponse:(const ::pbv1::SantaCommandResponse&)response
            toReplyTopic:(NSString*)replyTopic {
@@ -821,6 +1783,7 @@ static void messageHandler(natsConnection* nc, natsSubscription* sub, natsMsg* m
     } else {
       LOGD(@"NATS: Sent command response to %@ (code: %@, raw: %d)", replyTopic,
            ResponseCodeToString(error), static_cast<int>(error));
+      [self.connectionStats recordPublishOfBytes:responseData.length()];
     }
   });
 }
@@ -860,8 +1823,13 @@ static void errorHandler(natsConnection* nc, natsSubscription* sub, natsStatus e
           ? [NSString stringWithFormat:@"%s - %@", statusText ?: "unknown", connLastError]
           : [NSString stringWithFormat:@"%s", statusText ?: "unknown"];
 
-  LOGE(@"NATS Error on %@: %@ (status: %d) on subscription: %s", self.pushServer ?: @"server",
-       errorDetail, err, subSubject);
+  [self logWithType:OS_LOG_TYPE_ERROR
+            message:@"NATS: Error"
+             fields:@{
+               kPushLogFieldError : [NSString stringWithFormat:@"%@ (status: %d)", errorDetail,
+                                                               err],
+               kPushLogFieldSubject : @(subSubject ?: "unknown"),
+             }];
 
   // Check for permission/subscription violations.
   // NATS doesn't expose a specific permission violation status code, so we check the error text.
@@ -876,8 +1844,12 @@ static void errorHandler(natsConnection* nc, natsSubscription* sub, natsStatus e
   if (err == NATS_ERR || (statusText && strstr(statusText, "violation")) ||
       (statusText && strstr(statusText, "Permitted")) ||
       [connLastError containsString:@"violation"] || [connLastError containsString:@"Permitted"]) {
-    LOGE(@"NATS: Permission/Subscription violation on %@ subject: %s", self.pushServer ?: @"server",
-         subSubject);
+    [self logWithType:OS_LOG_TYPE_ERROR
+              message:@"NATS: Permission/Subscription violation"
+               fields:@{kPushLogFieldSubject : @(subSubject ?: "unknown")}];
+    if (sub && subSubject) {
+      self.lastDeniedSubject = @(subSubject);
+    }
 
     // Permission errors on subscriptions don't necessarily mean the connection is dead.
     // The connection may still be alive and other subscriptions may work.
@@ -889,16 +1861,19 @@ static void errorHandler(natsConnection* nc, natsSubscription* sub, natsStatus e
     dispatch_async(self.connectionQueue, ^{
       if (self.conn && natsConnection_IsClosed(self.conn)) {
         self.lastConnectionError = @"Permission violation - connection closed by server";
-        LOGE(@"NATS: Connection to %@ was closed by server due to permissions error, "
-             @"cleaning up and scheduling reconnect",
-             self.pushServer ?: @"server");
+        [self logWithType:OS_LOG_TYPE_ERROR
+                  message:@"NATS: Connection was closed by server due to permissions error, "
+                          @"cleaning up and scheduling reconnect"
+                   fields:@{kPushLogFieldError : errorDetail}];
         self.isConnected = NO;
         natsConnection_Destroy(self.conn);
         self.conn = NULL;
-        [self scheduleConnectionRetry];
+        [self transitionToConnectionState:SNTPushConnectionStateClosed];
+        [self handleConnectionFailureWithStatus:err error:errorDetail];
       } else {
-        LOGD(@"NATS: Connection to %@ still alive despite subscription error on %s, continuing",
-             self.pushServer ?: @"server", subSubject);
+        [self logWithType:OS_LOG_TYPE_DEBUG
+                  message:@"NATS: Connection still alive despite subscription error, continuing"
+                   fields:@{kPushLogFieldSubject : @(subSubject ?: "unknown")}];
       }
     });
   }
@@ -912,9 +1887,10 @@ static void disconnectedCallback(natsConnection* nc, void* closure) {
   // Get last error from NATS (safely copied to NSString for use in async block)
   NSString* lastError = GetNATSLastError(nc);
 
-  NSString* errorInfo =
-      lastError.length > 0 ? [NSString stringWithFormat:@" - %@", lastError] : @"";
-  LOGW(@"NATS: Disconnected from %@%@", self.pushServer ?: @"server", errorInfo);
+  [self logWithType:OS_LOG_TYPE_DEFAULT
+            message:@"NATS: Disconnected"
+             fields:lastError.length > 0 ? @{kPushLogFieldError : lastError} : @{}];
+  BOOL reconnecting = natsConnection_IsReconnecting(nc);
 
   dispatch_async(self.connectionQueue, ^{
     // Ignore callbacks from a connection we have already replaced (see
@@ -926,6 +1902,10 @@ static void disconnectedCallback(natsConnection* nc, void* closure) {
       self.lastConnectionError = lastError;
     }
     self.isConnected = NO;
+    [self transitionToConnectionState:SNTPushConnectionStateDisconnected];
+    if (reconnecting) {
+      [self transitionToConnectionState:SNTPushConnectionStateReconnecting];
+    }
   });
 }
 
@@ -933,7 +1913,7 @@ static void disconnectedCallback(natsConnection* nc, void* closure) {
 static void reconnectedCallback(natsConnection* nc, void* closure) {
   if (!closure) return;
   SNTPushClientNATS* self = (__bridge SNTPushClientNATS*)closure;
-  LOGI(@"NATS: Reconnected to %@", self.pushServer ?: @"server");
+  [self logWithType:OS_LOG_TYPE_INFO message:@"NATS: Reconnected" fields:@{}];
   dispatch_async(self.connectionQueue, ^{
     // Ignore callbacks from a connection we have already replaced (see
     // closedCallback). During NATS auto-reconnect the connection object is
@@ -942,26 +1922,93 @@ static void reconnectedCallback(natsConnection* nc, void* closure) {
 
     self.isConnected = YES;
     self.lastConnectionError = nil;
+    [self transitionToConnectionState:SNTPushConnectionStateConnected];
+    [self.connectionStats recordReconnect];
+    // A reconnect may land on a different server with a different certificate.
+    [self checkServerCertificateExpiry:LastVerifiedServerCertificateNotAfter()];
+    [self measureRTT];
 
-    // Trigger sync with jitter to avoid thundering herd
     // We might have missed push notifications while disconnected
     if (!self.isShuttingDown) {
-      // Calculate jitter: random delay between 0 and 600 seconds (10 minutes)
-      uint32_t jitterSeconds = arc4random_uniform(601);
-
-      LOGI(@"NATS: Scheduling sync after reconnect with %u second jitter delay", jitterSeconds);
-
-      dispatch_after(dispatch_time(DISPATCH_TIME_NOW, jitterSeconds * NSEC_PER_SEC),
-                     dispatch_get_main_queue(), ^{
-                       if (!self.isShuttingDown && self.isConnected) {
-                         LOGI(@"NATS: Triggering sync after reconnection (jitter delay completed)");
-                         [self.syncDelegate sync];
-                       }
-                     });
+      [self scheduleCatchUpSyncAfterReconnectAt:[NSDate date]];
     }
   });
 }
 
+// Schedules a sync to catch up on push notifications missed while disconnected, with jitter to
+// avoid a thundering herd. A flapping connection gets at most one catch-up sync per global rule
+// sync deadline, which also bounds the jitter. Returns NO if the sync was skipped because of that
+// limit. Must be called on connectionQueue.
+- (BOOL)scheduleCatchUpSyncAfterReconnectAt:(NSDate*)now {
+  NSTimeInterval limit = self.globalRuleSyncDeadline;
+  if (self.lastCatchUpSync && [now timeIntervalSinceDate:self.lastCatchUpSync] < limit) {
+    LOGI(@"NATS: Skipping sync after reconnect, one was scheduled less than %.0f seconds ago",
+         limit);
+    return NO;
+  }
+  self.lastCatchUpSync = now;
+
+  uint32_t jitterSeconds = arc4random_uniform((uint32_t)limit + 1);
+  LOGI(@"NATS: Scheduling sync after reconnect with %u second jitter delay", jitterSeconds);
+
+  dispatch_after(dispatch_time(DISPATCH_TIME_NOW, jitterSeconds * NSEC_PER_SEC),
+                 dispatch_get_main_queue(), ^{
+                   if (!self.isShuttingDown && self.isConnected) {
+                     LOGI(@"NATS: Triggering sync after reconnection (jitter delay completed)");
+                     [self.syncDelegate sync];
+                   }
+                 });
+  return YES;
+}
+
+// NATS custom reconnect delay callback. Returns the delay in milliseconds before the next pass
+// over the server list.
+static int64_t reconnectDelayCallback(natsConnection* nc, int attempts, void* closure) {
+  if (!closure) return 0;
+  SNTPushClientNATS* self = (__bridge SNTPushClientNATS*)closure;
+  double random = (double)arc4random_uniform(UINT32_MAX) / UINT32_MAX;
+  NSTimeInterval delay = NATSReconnectDelay(self.reconnectBackoff, attempts, random);
+  [self logWithType:OS_LOG_TYPE_DEBUG
+            message:@"NATS: Scheduling reconnect attempt"
+             fields:@{
+               kPushLogFieldAttempt : @(attempts),
+               kPushLogFieldDelaySeconds : [NSString stringWithFormat:@"%.1f", delay],
+             }];
+  return (int64_t)(delay * 1000);
+}
+
+// NATS user JWT callback. The library frees the returned JWT.
+static natsStatus userJWTCallback(char** userJWT, char** customErrTxt, void* closure) {
+  if (!closure) return NATS_ERR;
+  SNTPushClientNATS* self = (__bridge SNTPushClientNATS*)closure;
+  NSString* jwt = self.connectionJWT;
+  if (!jwt.length) {
+    *customErrTxt = strdup("no push JWT");
+    return NATS_ERR;
+  }
+  *userJWT = strdup(jwt.UTF8String);
+  return *userJWT ? NATS_OK : NATS_NO_MEMORY;
+}
+
+// NATS signature callback, signing the nonce the server sent when connecting with the nkey seed.
+// The library frees the returned signature.
+static natsStatus signNonceCallback(char** customErrTxt, unsigned char** signature,
+                                    int* signatureLength, const char* nonce, void* closure) {
+  if (!closure) return NATS_ERR;
+  SNTPushClientNATS* self = (__bridge SNTPushClientNATS*)closure;
+  auto signed_nonce = santa::SignNonceWithNKeySeed(
+      santa::NSStringToUTF8StringView(self.connectionSeed ?: @""), nonce ?: "");
+  if (!signed_nonce) {
+    *customErrTxt = strdup("invalid push nkey seed");
+    return NATS_ERR;
+  }
+  *signature = static_cast<unsigned char*>(malloc(signed_nonce->size()));
+  if (!*signature) return NATS_NO_MEMORY;
+  memcpy(*signature, signed_nonce->data(), signed_nonce->size());
+  *signatureLength = static_cast<int>(signed_nonce->size());
+  return NATS_OK;
+}
+
 // NATS closed callback
 static void closedCallback(natsConnection* nc, void* closure) {
   if (!closure) return;
@@ -969,10 +2016,12 @@ static void closedCallback(natsConnection* nc, void* closure) {
 
   // Get last error from NATS (safely copied to NSString for use in async block)
   NSString* lastError = GetNATSLastError(nc);
+  const char* unusedErrorText = NULL;
+  natsStatus lastStatus = natsConnection_GetLastError(nc, &unusedErrorText);
 
-  NSString* errorInfo =
-      lastError.length > 0 ? [NSString stringWithFormat:@" - %@", lastError] : @"";
-  LOGI(@"NATS: Connection to %@ closed%@", self.pushServer ?: @"server", errorInfo);
+  [self logWithType:OS_LOG_TYPE_INFO
+            message:@"NATS: Connection closed"
+             fields:lastError.length > 0 ? @{kPushLogFieldError : lastError} : @{}];
 
   dispatch_async(self.connectionQueue, ^{
     // Ignore callbacks from a connection we have already replaced or destroyed;
@@ -981,7 +2030,9 @@ static void closedCallback(natsConnection* nc, void* closure) {
     // replaced connection has since been freed. Without this guard, a late
     // closed callback from a prior connection could destroy the live one.
     if (nc != self.conn) {
-      LOGD(@"NATS: Ignoring closed callback from a replaced connection");
+      [self logWithType:OS_LOG_TYPE_DEBUG
+                message:@"NATS: Ignoring closed callback from a replaced connection"
+                 fields:@{}];
       return;
     }
 
@@ -989,50 +2040,200 @@ static void closedCallback(natsConnection* nc, void* closure) {
       self.lastConnectionError = lastError;
     }
     self.isConnected = NO;
+    [self transitionToConnectionState:SNTPushConnectionStateClosed];
+
+    // A drain for shutdown ends with the connection closing. The NATS library has delivered the
+    // pending messages by now; let the message queue finish handling them, and the main queue
+    // make the delegate calls that dispatches, before reporting a clean drain.
+    if (self.drainCompletion) {
+      [self closeConnectionForShutdown];
+      if (lastStatus == NATS_TIMEOUT) {
+        [self finishDrainWithError:DrainTimedOutError(self.drainTimeout)];
+        return;
+      }
+      dispatch_async(self.messageQueue, ^{
+        dispatch_async(dispatch_get_main_queue(), ^{
+          dispatch_async(self.connectionQueue, ^{
+            [self finishDrainWithError:nil];
+          });
+        });
+      });
+      return;
+    }
 
     // If we're not shutting down, schedule a reconnection attempt.
     // The closed callback fires when the connection is permanently closed and
     // NATS won't automatically reconnect, so we handle it ourselves. (nc ==
     // self.conn here, so self.conn is the just-closed, non-NULL connection.)
     if (!self.isShuttingDown) {
-      LOGI(@"NATS: Connection to %@ closed unexpectedly, cleaning up and scheduling reconnect",
-           self.pushServer ?: @"server");
+      [self logWithType:OS_LOG_TYPE_INFO
+                message:@"NATS: Connection closed unexpectedly, cleaning up and scheduling "
+                        @"reconnect"
+                 fields:@{}];
 
       // Clean up the closed connection
       natsConnection_Destroy(self.conn);
       self.conn = NULL;
 
-      // Schedule reconnection with exponential backoff
-      [self scheduleConnectionRetry];
+      // Schedule reconnection with exponential backoff, unless the credentials were rejected
+      [self handleConnectionFailureWithStatus:NATS_CONNECTION_CLOSED error:lastError];
+    }
+  });
+}
+
+- (void)addConnectionStateObserver:(SNTPushConnectionStateObserver)observer {
+  if (!observer) return;
+  @synchronized(self.stateObservers) {
+    [self.stateObservers addObject:[observer copy]];
+  }
+}
+
+// Record a new connection state and notify the observers. Must be called on the
+// connectionQueue, which keeps the transitions in order.
+- (void)transitionToConnectionState:(SNTPushConnectionState)newState {
+  SNTPushConnectionState oldState = self.connectionState;
+  if (oldState == newState) return;
+  self.connectionState = newState;
+
+  NSArray<SNTPushConnectionStateObserver>* observers;
+  @synchronized(self.stateObservers) {
+    observers = [self.stateObservers copy];
+  }
+  if (!observers.count) return;
+  dispatch_async(self.observerQueue, ^{
+    for (SNTPushConnectionStateObserver observer in observers) {
+      observer(oldState, newState);
     }
   });
 }
 
+// Push notifications can't be received while the credentials are rejected, so fall back to
+// polling until new credentials arrive.
+- (NSUInteger)fullSyncInterval {
+  if (self.credentialsRejected) {
+    return MIN(_fullSyncInterval,
+               [[SNTConfigurator configurator] pushCredentialsRejectedFullSyncInterval]);
+  }
+  return _fullSyncInterval;
+}
+
+// Handles a failed or closed connection. Transient failures are retried with backoff. If the
+// server rejected the credentials, retrying with them can't succeed, so stop and ask the sync
+// delegate to poll and fetch new credentials instead. Must be called on the connectionQueue.
+- (void)handleConnectionFailureWithStatus:(natsStatus)status error:(NSString*)error {
+  if (!NATSErrorIsCredentialRejection(status, error)) {
+    [self scheduleConnectionRetry];
+    return;
+  }
+
+  [self logWithType:OS_LOG_TYPE_ERROR
+            message:@"NATS: Server rejected the push credentials, not reconnecting until new "
+                    @"credentials are received"
+             fields:@{kPushLogFieldError : error ?: @"", kPushLogFieldCategory : @"AUTH_ERROR"}];
+  [self stopConnectingWithRejectedCredentials:error];
+}
+
+// Returns NO and sets a SNTErrorCodePushCredentialExpired error if the push JWT has already
+// expired, in which case connecting would only fail authentication. A JWT that can't be parsed
+// is left for the server to judge.
+- (BOOL)validateCredentials:(NSError**)error {
+  NSError* parseError;
+  NSDate* expiry = NATSJWTExpiry(self.jwt, &parseError);
+  if (!expiry) {
+    LOGW(@"NATS: Unable to check the push JWT expiry: %@", parseError.localizedDescription);
+    return YES;
+  }
+  if ([expiry timeIntervalSinceNow] <= 0) {
+    [SNTError populateError:error
+                   withCode:SNTErrorCodePushCredentialExpired
+                     format:@"The push JWT expired at %@", expiry];
+    return NO;
+  }
+  return YES;
+}
+
+// Stop connecting until new credentials are received and ask the sync delegate to poll and fetch
+// them. Must be called on the connectionQueue.
+- (void)stopConnectingWithRejectedCredentials:(NSString*)error {
+  self.credentialsRejected = YES;
+  if (![self.lastConnectionError hasPrefix:@"[AUTH_ERROR]"]) {
+    self.lastConnectionError = [NSString stringWithFormat:@"[AUTH_ERROR] %@", error];
+  }
+  self.retryAttempt = 0;
+  self.isRetrying = NO;
+  if (self.connectionRetryTimer) {
+    dispatch_source_cancel(self.connectionRetryTimer);
+    self.connectionRetryTimer = nil;
+  }
+
+  // With a credential provider, keep retrying. Each attempt fetches new credentials first.
+  if (self.credentialProvider) {
+    [self scheduleConnectionRetry];
+  }
+
+  dispatch_async(dispatch_get_main_queue(), ^{
+    id<SNTPushNotificationsSyncDelegate> syncDelegate = self.syncDelegate;
+    if (self.isShuttingDown ||
+        ![syncDelegate respondsToSelector:@selector(pushCredentialsRejected)]) {
+      return;
+    }
+    [syncDelegate pushCredentialsRejected];
+  });
+}
+
+// Ask the credential provider for new credentials to replace the rejected ones and connect with
+// them. If there are none, try again after the retry backoff. Must be called on the
+// connectionQueue.
+- (void)refreshRejectedCredentials {
+  if (self.isRefreshingCredentials) return;
+  self.isRefreshingCredentials = YES;
+
+  NSString* rejectedJWT = self.jwt;
+  NSString* rejectedNKey = self.pushToken;
+  LOGI(@"NATS: Refreshing the rejected push credentials");
+  [self.credentialProvider refreshPushCredentialsWithReply:^(NSString* jwt, NSString* nkey,
+                                                             NSError* error) {
+    dispatch_async(self.connectionQueue, ^{
+      self.isRefreshingCredentials = NO;
+      // New credentials may already have arrived another way, e.g. from a sync's preflight.
+      if (self.isShuttingDown || !self.credentialsRejected) return;
+
+      if (error || !jwt.length || !nkey.length ||
+          ([jwt isEqualToString:rejectedJWT] && [nkey isEqualToString:rejectedNKey])) {
+        LOGW(@"NATS: Unable to refresh the push credentials: %@",
+             error.localizedDescription ?: @"no new credentials received");
+        [self scheduleConnectionRetry];
+        return;
+      }
+
+      // New credentials clear the rejection and reconnect.
+      [self configureWithPushServer:self.pushServer
+                          pushToken:nkey
+                                jwt:jwt
+                       pushDeviceID:self.pushDeviceID
+                               tags:self.tags];
+    });
+  }];
+}
+
 // Schedule a connection retry with exponential backoff and jitter
 - (void)scheduleConnectionRetry {
-  if (self.isShuttingDown || self.isRetrying) return;
+  if (self.isShuttingDown || self.drainCompletion || self.isRetrying) return;
 
   self.isRetrying = YES;
   self.retryAttempt++;
 
-  // Calculate exponential backoff with jitter
-  // Base delay doubles each attempt: 1s, 2s, 4s, 8s, 16s, 32s, 64s, 128s, 256s
-  NSTimeInterval baseDelay = pow(2.0, MIN(self.retryAttempt - 1, 8));  // Cap at 2^8 = 256 seconds
+  double random = (double)arc4random_uniform(UINT32_MAX) / UINT32_MAX;
+  NSTimeInterval currentRetryDelay =
+      NATSReconnectDelay(kNATSConnectionRetryBackoff, self.retryAttempt, random);
 
-  // Add jitter: ±25% randomization to prevent thundering herd
-  double jitterFactor = 0.75 + (0.5 * ((double)arc4random_uniform(UINT32_MAX) / UINT32_MAX));
-  NSTimeInterval currentRetryDelay = baseDelay * jitterFactor;
-
-  // Cap at 5-10 minutes (randomly between 300-600 seconds) after initial backoff
-  if (self.retryAttempt > 9) {
-    currentRetryDelay = 300.0 + arc4random_uniform(301);  // 5-10 minutes
-  }
-
-  LOGW(@"NATS: Connection to %@ failed%@, will retry in %.1f seconds (attempt %ld)",
-       self.pushServer ?: @"server",
-       self.lastConnectionError ? [NSString stringWithFormat:@" (%@)", self.lastConnectionError]
-                                : @"",
-       currentRetryDelay, (long)self.retryAttempt);
+  NSMutableDictionary* retryFields = [NSMutableDictionary dictionary];
+  retryFields[kPushLogFieldError] = self.lastConnectionError;
+  retryFields[kPushLogFieldAttempt] = @(self.retryAttempt);
+  retryFields[kPushLogFieldDelaySeconds] = [NSString stringWithFormat:@"%.1f", currentRetryDelay];
+  [self logWithType:OS_LOG_TYPE_DEFAULT
+            message:@"NATS: Connection failed, scheduling retry"
+             fields:retryFields];
 
   // Cancel any existing retry timer
   if (self.connectionRetryTimer) {
@@ -1044,7 +2245,7 @@ static void closedCallback(natsConnection* nc, void* closure) {
   self.connectionRetryTimer =
       dispatch_source_create(DISPATCH_SOURCE_TYPE_TIMER, 0, 0, self.connectionQueue);
   if (!self.connectionRetryTimer) {
-    LOGE(@"NATS: Failed to create retry timer");
+    [self logWithType:OS_LOG_TYPE_ERROR message:@"NATS: Failed to create retry timer" fields:@{}];
     self.isRetrying = NO;
     return;
   }
@@ -1069,7 +2270,9 @@ static void closedCallback(natsConnection* nc, void* closure) {
       self.connectionRetryTimer = nil;
     }
 
-    LOGI(@"NATS: Retrying connection (attempt %ld)", (long)self.retryAttempt);
+    [self logWithType:OS_LOG_TYPE_INFO
+              message:@"NATS: Retrying connection"
+               fields:@{kPushLogFieldAttempt : @(self.retryAttempt)}];
     [self connect];
   });
 
@@ -1080,7 +2283,7 @@ static void closedCallback(natsConnection* nc, void* closure) {
 
 - (void)forceReconnect {
   dispatch_async(self.connectionQueue, ^{
-    if (self.isShuttingDown) return;
+    if (self.isShuttingDown || self.drainCompletion) return;
 
     LOGI(@"NATS: Force reconnect requested - resetting connection state");
 
@@ -1100,6 +2303,7 @@ static void closedCallback(natsConnection* nc, void* closure) {
     }
 
     self.isConnected = NO;
+    [self transitionToConnectionState:SNTPushConnectionStateClosed];
     self.retryAttempt = 0;
     self.isRetrying = NO;
 
@@ -1120,7 +2324,11 @@ static void closedCallback(natsConnection* nc, void* closure) {
 
   // Check if we have push configuration from preflight
   if (syncState.pushServer && syncState.pushNKey && syncState.pushJWT && syncState.pushDeviceID) {
-    // Configure with preflight data
+    // Configure with preflight data. The ping settings apply from the next connection.
+    self.pingInterval = syncState.pushPingInterval.unsignedIntegerValue;
+    self.maxPingsOut = syncState.pushMaxPingsOut.unsignedIntegerValue;
+    self.globalRuleSyncDeadline = syncState.pushNotificationsGlobalRuleSyncDeadline
+                                      ?: kDefaultPushNotificationsGlobalRuleSyncDeadline;
     [self configureWithPushServer:syncState.pushServer
                         pushToken:syncState.pushNKey
                               jwt:syncState.pushJWT
@@ -16,6 +16,7 @@
 #import <XCTest/XCTest.h>
 
 #import "Source/common/SNTConfigurator.h"
+#import "Source/common/SNTError.h"
 #import "Source/common/SNTSyncConstants.h"
 #import "Source/santasyncservice/SNTPushClientNATS.h"
 #import "Source/santasyncservice/SNTPushNotifications.h"
@@ -279,6 +280,42 @@ extern "C" {
   XCTAssertEqual(syncCallCount, 5, @"Each message should trigger a sync");
 }
 
+- (void)testConnectionStatsCountSubscribesAndMessages {
+  self.syncExpectation = [self expectationWithDescription:@"Sync should be triggered"];
+  OCMStub([self.mockSyncDelegate sync]).andDo(^(NSInvocation* invocation) {
+    [self.syncExpectation fulfill];
+  });
+
+  self.client = [[SNTPushClientNATS alloc] initWithSyncDelegate:self.mockSyncDelegate];
+
+  SNTSyncState* syncState = [[SNTSyncState alloc] init];
+  syncState.pushServer = @"localhost";
+  syncState.pushNKey = TEST_NKEY;
+  syncState.pushJWT = TEST_JWT;
+  syncState.pushDeviceID = self.machineID;
+  syncState.pushTags = @[ @"santa.tag.workshop" ];
+
+  [self.client handlePreflightSyncState:syncState];
+  [NSThread sleepForTimeInterval:0.5];
+
+  // The tag and the host commands subject were subscribed and the RTT measured on connect.
+  NSDictionary* stats = [self.client.connectionStats export];
+  XCTAssertEqualObjects(stats[kPushStatsSubscribes], @2);
+  XCTAssertNotNil(stats[kPushStatsLastRTTMs]);
+  XCTAssertEqualObjects(stats[kPushStatsBytesIn], @0);
+
+  [self setupTestPublisher];
+  natsConnection_PublishString(self.testPublisher, "santa.tag.workshop", "test message");
+  natsConnection_Flush(self.testPublisher);
+  [self waitForExpectationsWithTimeout:2.0 handler:nil];
+
+  stats = [self.client.connectionStats export];
+  XCTAssertEqualObjects(stats[kPushStatsMessagesBySubject], @{@"santa.tag.workshop" : @1});
+  XCTAssertEqualObjects(stats[kPushStatsBytesIn], @(strlen("test message")));
+  XCTAssertEqualObjects(stats[kPushStatsReconnects], @0);
+  XCTAssertEqualObjects([self.client diagnostics][kPushDiagnosticsStats], stats);
+}
+
 - (void)testReconnectionAfterServerRestart {
   // Given: Client is connected
   self.client = [[SNTPushClientNATS alloc] initWithSyncDelegate:self.mockSyncDelegate];
@@ -352,6 +389,74 @@ extern "C" {
                  @"No sync should be triggered after disconnect");
 }
 
+- (void)connectAndPublishMessages:(int)count {
+  self.client = [[SNTPushClientNATS alloc] initWithSyncDelegate:self.mockSyncDelegate];
+  SNTSyncState* syncState = [[SNTSyncState alloc] init];
+  syncState.pushServer = @"localhost";
+  syncState.pushNKey = TEST_NKEY;
+  syncState.pushJWT = TEST_JWT;
+  syncState.pushDeviceID = self.machineID;
+  syncState.pushTags = @[ @"santa-clients", @"workshop" ];
+  [self.client handlePreflightSyncState:syncState];
+  [NSThread sleepForTimeInterval:0.5];
+  XCTAssertTrue(self.client.isConnected);
+
+  [self setupTestPublisher];
+  for (int i = 0; i < count; i++) {
+    natsConnection_PublishString(self.testPublisher, "santa.tag.global", "test message");
+  }
+  natsConnection_Flush(self.testPublisher);
+}
+
+- (void)testShutdownDrainsPendingMessages {
+  // Given: A slow sync delegate and messages that have been received but not handled yet
+  __block NSInteger syncCallCount = 0;
+  OCMStub([self.mockSyncDelegate sync]).andDo(^(NSInvocation* invocation) {
+    [NSThread sleepForTimeInterval:0.2];
+    syncCallCount++;
+  });
+  [self connectAndPublishMessages:5];
+
+  // When: The client shuts down with plenty of time to drain
+  XCTestExpectation* drained = [self expectationWithDescription:@"Shutdown completes"];
+  __block NSError* shutdownError;
+  [self.client shutdownWithTimeout:10
+                        completion:^(NSError* error) {
+                          shutdownError = error;
+                          [drained fulfill];
+                        }];
+  [self waitForExpectations:@[ drained ] timeout:15.0];
+
+  // Then: Every message was handled before the connection closed cleanly
+  XCTAssertNil(shutdownError);
+  XCTAssertEqual(syncCallCount, 5);
+  XCTAssertFalse(self.client.isConnected);
+  XCTAssertEqual(self.client.connectionState, SNTPushConnectionStateClosed);
+}
+
+- (void)testShutdownForcesCloseAfterTimeout {
+  // Given: A sync delegate too slow to handle the messages within the drain timeout
+  OCMStub([self.mockSyncDelegate sync]).andDo(^(NSInvocation* invocation) {
+    [NSThread sleepForTimeInterval:1.0];
+  });
+  [self connectAndPublishMessages:5];
+
+  // When: The client shuts down with a short timeout
+  XCTestExpectation* closed = [self expectationWithDescription:@"Shutdown completes"];
+  __block NSError* shutdownError;
+  [self.client shutdownWithTimeout:0.5
+                        completion:^(NSError* error) {
+                          shutdownError = error;
+                          [closed fulfill];
+                        }];
+  [self waitForExpectations:@[ closed ] timeout:15.0];
+
+  // Then: The connection was closed anyway and the error says so
+  XCTAssertEqual(shutdownError.code, SNTErrorCodePushDrainFailed);
+  XCTAssertFalse(self.client.isConnected);
+  XCTAssertEqual(self.client.connectionState, SNTPushConnectionStateClosed);
+}
+
 #pragma mark - Helper Methods
 
 - (void)setupTestPublisher {
@@ -576,6 +681,7 @@ extern "C" {
   XCTAssertEqual(syncCallCount, initialSyncCount + 1,
                  @"Exactly one additional sync should be triggered after reconnection");
   XCTAssertTrue(self.client.isConnected, @"Should be reconnected");
+  XCTAssertEqualObjects([self.client.connectionStats export][kPushStatsReconnects], @1);
 }
 
 @end
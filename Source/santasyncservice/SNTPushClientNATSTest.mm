@@ -15,9 +15,20 @@
 #import <OCMock/OCMock.h>
 #import <XCTest/XCTest.h>
 
+#include <openssl/curve25519.h>
+#include <openssl/evp.h>
+#include <openssl/sha.h>
+
+#import "Source/common/MOLXPCConnection.h"
+#import "Source/common/SNTCommonEnums.h"
 #import "Source/common/SNTConfigurator.h"
+#import "Source/common/SNTError.h"
+#import "Source/common/SNTRule.h"
+#import "Source/common/SNTStoredExecutionEvent.h"
 #import "Source/common/SNTSyncConstants.h"
 #import "Source/common/SNTSystemInfo.h"
+#import "Source/common/SNTXPCControlInterface.h"
+#include "Source/common/SignedRuleBundle.h"
 #import "Source/santasyncservice/SNTPushClientNATS.h"
 #import "Source/santasyncservice/SNTPushNotifications.h"
 #import "Source/santasyncservice/SNTSyncState.h"
@@ -33,6 +44,52 @@ namespace pbv1 = ::santa::commands::v1;
 
 // Forward declaration of the extracted domain-check function.
 extern "C" bool NATSLeafCertHasPushDomain(X509* cert);
+extern "C" int64_t NATSCertNotAfter(X509* cert);
+NSString* NATSCertSHA256(X509* cert);
+int NATSSSLVerifyCallback(int preverifyOk, void* ctx);
+NSData* ExportDecisionsResponse(NSArray<SNTStoredExecutionEvent*>* decisions, NSUInteger maxBytes);
+NSArray<SNTRule*>* InlineRulesFromPushPayload(NSData* payload, NSData* publicKey, NSDate* now,
+                                              NSString** bundleID, NSDate** expirationDate,
+                                              NSError** error);
+BOOL NATSErrorIsCredentialRejection(natsStatus status, NSString* error);
+NSDate* NATSJWTExpiry(NSString* jwt, NSError** error);
+NSDate* NATSPushPublishedAt(NSString* value);
+
+// An unsigned JWT carrying `claims`.
+static NSString* JWTWithClaims(NSDictionary* claims) {
+  NSString* (^encode)(NSData*) = ^NSString*(NSData* data) {
+    NSString* b64 = [data base64EncodedStringWithOptions:0];
+    b64 = [b64 stringByReplacingOccurrencesOfString:@"+" withString:@"-"];
+    b64 = [b64 stringByReplacingOccurrencesOfString:@"/" withString:@"_"];
+    return [b64 stringByReplacingOccurrencesOfString:@"=" withString:@""];
+  };
+  NSData* header = [@"{\"typ\":\"JWT\",\"alg\":\"ed25519-nkey\"}"
+      dataUsingEncoding:NSUTF8StringEncoding];
+  NSData* payload = [NSJSONSerialization dataWithJSONObject:claims options:0 error:nil];
+  return [NSString stringWithFormat:@"%@.%@.c2lnbmF0dXJl", encode(header), encode(payload)];
+}
+
+// The JSON body of an inline rules payload holding a Team ID block rule for each of `teamIDs`.
+static NSData* InlineRulesBodyWithClaims(NSArray<NSString*>* teamIDs, NSDictionary* claims) {
+  NSMutableArray* rules = [NSMutableArray array];
+  for (NSString* teamID in teamIDs) {
+    [rules addObject:@{@"identifier" : teamID, @"policy" : @"BLOCKLIST", @"rule_type" : @"TEAMID"}];
+  }
+  NSMutableDictionary* body = [claims mutableCopy];
+  body[@"rules"] = rules;
+  return [NSJSONSerialization dataWithJSONObject:body options:0 error:nil];
+}
+
+// An apply_rules body issued now and valid for ten minutes.
+static NSData* InlineRulesBody(NSArray<NSString*>* teamIDs) {
+  NSTimeInterval now = [[NSDate date] timeIntervalSince1970];
+  return InlineRulesBodyWithClaims(teamIDs, @{
+    @"purpose" : @"apply_rules",
+    @"jti" : [[NSUUID UUID] UUIDString],
+    @"iat" : @(now),
+    @"exp" : @(now + 600),
+  });
+}
 
 // Creates a minimal X509 certificate with a single DNS Subject Alternative Name.
 // The certificate is not signed and has no key material; it is only suitable for
@@ -72,6 +129,102 @@ static X509* CreateCertWithDNSSANBytes(const char* data, size_t len) {
   return cert;
 }
 
+// Creates a self-signed push server certificate, valid for a day, with a fresh Ed25519 key.
+static X509* CreateSelfSignedPushServerCert() {
+  EVP_PKEY* key = nullptr;
+  EVP_PKEY_CTX* keyCtx = EVP_PKEY_CTX_new_id(EVP_PKEY_ED25519, nullptr);
+  if (!keyCtx || EVP_PKEY_keygen_init(keyCtx) != 1 || EVP_PKEY_keygen(keyCtx, &key) != 1) {
+    EVP_PKEY_CTX_free(keyCtx);
+    return nullptr;
+  }
+  EVP_PKEY_CTX_free(keyCtx);
+
+  X509* cert = CreateCertWithDNSSAN("east1.push.northpole.security");
+  X509_set_version(cert, X509_VERSION_3);
+  ASN1_INTEGER_set(X509_get_serialNumber(cert), 1);
+  X509_NAME_add_entry_by_txt(X509_get_subject_name(cert), "CN", MBSTRING_ASC,
+                             (const uint8_t*)"east1.push.northpole.security", -1, -1, 0);
+  X509_set_issuer_name(cert, X509_get_subject_name(cert));
+  X509_gmtime_adj(X509_getm_notBefore(cert), -60);
+  X509_gmtime_adj(X509_getm_notAfter(cert), 86400);
+  X509_set_pubkey(cert, key);
+  X509_sign(cert, key, nullptr);
+  EVP_PKEY_free(key);
+  return cert;
+}
+
+// Verifies `cert` against a trust store holding only `cert` itself, with the push client's
+// verification callback.
+static BOOL VerifySelfSignedCertWithPushCallback(X509* cert) {
+  X509_STORE* store = X509_STORE_new();
+  X509_STORE_add_cert(store, cert);
+  X509_STORE_CTX* ctx = X509_STORE_CTX_new();
+  X509_STORE_CTX_init(ctx, store, cert, nullptr);
+  X509_STORE_CTX_set_verify_cb(ctx, [](int preverifyOk, X509_STORE_CTX* storeCtx) {
+    return NATSSSLVerifyCallback(preverifyOk, storeCtx);
+  });
+  BOOL ok = X509_verify_cert(ctx) == 1;
+  X509_STORE_CTX_free(ctx);
+  X509_STORE_free(store);
+  return ok;
+}
+
+// Creates a minimal X509 certificate for a push server that expires the given number of days from
+// now.
+static X509* CreatePushServerCertExpiringInDays(long days) {
+  X509* cert = CreateCertWithDNSSAN("east1.push.northpole.security");
+  if (!cert) return nullptr;
+  X509_gmtime_adj(X509_getm_notBefore(cert), 0);
+  X509_gmtime_adj(X509_getm_notAfter(cert), days * 86400);
+  return cert;
+}
+
+// Returns the credentials it was created with on the first refresh and fresh ones after that.
+@interface FakeCredentialProvider : NSObject <SNTPushCredentialProvider>
+@property NSString* staleJWT;
+@property NSString* staleNKey;
+@property(atomic) NSUInteger refreshCount;
+@end
+
+@implementation FakeCredentialProvider
+
+- (void)refreshPushCredentialsWithReply:(void (^)(NSString* jwt, NSString* nkey,
+                                                  NSError* error))reply {
+  self.refreshCount++;
+  if (self.refreshCount == 1) {
+    reply(self.staleJWT, self.staleNKey, nil);
+  } else {
+    reply(@"fresh-jwt", @"fresh-nkey", nil);
+  }
+}
+
+@end
+
+// Records each log message with its type and fields.
+@interface RecordingPushLogger : NSObject <SNTPushLogger>
+@property NSMutableArray<NSDictionary*>* entries;
+@end
+
+@implementation RecordingPushLogger
+
+- (instancetype)init {
+  self = [super init];
+  if (self) {
+    _entries = [NSMutableArray array];
+  }
+  return self;
+}
+
+- (void)logWithType:(os_log_type_t)type
+            message:(NSString*)message
+             fields:(NSDictionary<NSString*, id>*)fields {
+  @synchronized(self) {
+    [self.entries addObject:@{@"type" : @(type), @"message" : message, @"fields" : fields}];
+  }
+}
+
+@end
+
 // Expose private methods for testing
 @interface SNTPushClientNATS (Testing)
 @property(nonatomic) natsConnection* conn;
@@ -79,16 +232,41 @@ static X509* CreateCertWithDNSSANBytes(const char* data, size_t len) {
 @property(nonatomic) dispatch_source_t connectionRetryTimer;
 @property(nonatomic) NSInteger retryAttempt;
 @property(nonatomic) BOOL isRetrying;
+@property(nonatomic) dispatch_queue_t connectionQueue;
+@property(nonatomic) dispatch_queue_t messageQueue;
+@property(nonatomic) NSUInteger pingInterval;
+@property(nonatomic) NSMutableDictionary<NSString*, NSValue*>* tagSubscriptions;
+@property(nonatomic, copy) NSArray<NSString*>* tags;
+- (BOOL)isConnectionAlive;
+- (void)subscribeToTagSubject:(NSString*)subject;
+- (void)unsubscribeFromTagSubject:(NSString*)subject;
+- (BOOL)scheduleCatchUpSyncAfterReconnectAt:(NSDate*)now;
+@property(nonatomic) NSUInteger maxPingsOut;
+@property(atomic) BOOL credentialsRejected;
+@property(nonatomic, copy) NSString* pushToken;
+@property(nonatomic, copy) NSString* jwt;
 - (void)connect;
 - (void)disconnectWithCompletion:(void (^)(void))completion;
 - (void)subscribe;
 - (void)scheduleConnectionRetry;
+- (void)handleConnectionFailureWithStatus:(natsStatus)status error:(NSString*)error;
+- (void)transitionToConnectionState:(SNTPushConnectionState)newState;
+- (BOOL)validateCredentials:(NSError**)error;
 - (void)configureWithPushServer:(NSString*)server
                       pushToken:(NSString*)token
                             jwt:(NSString*)jwt
                    pushDeviceID:(NSString*)deviceID
                            tags:(NSArray<NSString*>*)tags;
 - (void)handlePushNotificationForSubject:(NSString*)subject withPayload:(NSData*)payload;
+- (void)handlePushNotificationForSubject:(NSString*)subject
+                             withPayload:(NSData*)payload
+                                 headers:(NSDictionary<NSString*, NSString*>*)headers
+                            replySubject:(NSString*)replySubject;
+- (void)publishData:(NSData*)data toSubject:(NSString*)subject;
+- (void)checkServerCertificateExpiry:(NSDate*)notAfter;
+- (void)handlePushNotificationForSubject:(NSString*)subject
+                             withPayload:(NSData*)payload
+                                 headers:(NSDictionary<NSString*, NSString*>*)headers;
 @end
 
 @interface SNTPushClientNATSTest : XCTestCase
@@ -295,6 +473,36 @@ static X509* CreateCertWithDNSSANBytes(const char* data, size_t len) {
   XCTAssertEqual(self.client.fullSyncInterval, originalInterval);
 }
 
+- (void)testHandlePreflightSyncStateAppliesPingSettings {
+  // Given: Client is initialized
+  self.client = [[SNTPushClientNATS alloc] initWithSyncDelegate:self.mockSyncDelegate];
+  XCTAssertEqual(self.client.pingInterval, 0u);
+  XCTAssertEqual(self.client.maxPingsOut, 0u);
+
+  // When: Preflight supplies ping settings
+  SNTSyncState* syncState = [[SNTSyncState alloc] init];
+  syncState.pushServer = @"workshop";
+  syncState.pushNKey = @"test-nkey";
+  syncState.pushJWT = @"test-jwt";
+  syncState.pushDeviceID = @"test-device-id";
+  syncState.pushPingInterval = @(30);
+  syncState.pushMaxPingsOut = @(3);
+  [self.client handlePreflightSyncState:syncState];
+
+  // Then: They are used for the next connection
+  XCTAssertEqual(self.client.pingInterval, 30u);
+  XCTAssertEqual(self.client.maxPingsOut, 3u);
+
+  // When: A later preflight no longer supplies them
+  syncState.pushPingInterval = nil;
+  syncState.pushMaxPingsOut = nil;
+  [self.client handlePreflightSyncState:syncState];
+
+  // Then: The library defaults apply again
+  XCTAssertEqual(self.client.pingInterval, 0u);
+  XCTAssertEqual(self.client.maxPingsOut, 0u);
+}
+
 #pragma mark - Full Sync Interval Tests
 
 - (void)testFullSyncIntervalDefaultValue {
@@ -355,7 +563,7 @@ static X509* CreateCertWithDNSSANBytes(const char* data, size_t len) {
   }
 }
 
-- (void)testRetryDelayCapAt5To10Minutes {
+- (void)testRetryAttemptIncrementsPastTheBackoffCap {
   // Given: Client with many retry attempts
   self.client = [[SNTPushClientNATS alloc] initWithSyncDelegate:self.mockSyncDelegate];
   self.client.retryAttempt = 9;  // Set to 9 so next attempt will be 10th
@@ -372,6 +580,406 @@ static X509* CreateCertWithDNSSANBytes(const char* data, size_t len) {
   }
 }
 
+- (void)testDiagnosticsDescribeTheJWTWithoutIncludingIt {
+  self.client = [[SNTPushClientNATS alloc] initWithSyncDelegate:self.mockSyncDelegate];
+  XCTAssertNil([self.client diagnostics][kPushDiagnosticsJWTParsed]);
+
+  self.client.jwt = JWTWithClaims(@{
+    @"sub" : @"UABC",
+    @"exp" : @1800000000,
+    @"nats" : @{@"sub" : @{@"allow" : @[ @"santa.tag.>" ], @"deny" : @[ @"santa.tag.finance" ]}},
+  });
+  NSDictionary* diagnostics = [self.client diagnostics];
+  XCTAssertEqualObjects(diagnostics[kPushDiagnosticsJWTParsed], @YES);
+  XCTAssertEqualObjects(diagnostics[kPushDiagnosticsJWTSubject], @"UABC");
+  XCTAssertEqualObjects(diagnostics[kPushDiagnosticsJWTExpiry],
+                        [NSDate dateWithTimeIntervalSince1970:1800000000]);
+  XCTAssertEqualObjects(diagnostics[kPushDiagnosticsJWTSubscribeAllow], @[ @"santa.tag.>" ]);
+  XCTAssertEqualObjects(diagnostics[kPushDiagnosticsJWTSubscribeDeny], @[ @"santa.tag.finance" ]);
+  for (id value in diagnostics.allValues) {
+    XCTAssertNotEqualObjects(value, self.client.jwt);
+  }
+
+  self.client.jwt = @"test-jwt";
+  diagnostics = [self.client diagnostics];
+  XCTAssertEqualObjects(diagnostics[kPushDiagnosticsJWTParsed], @NO);
+  XCTAssertNil(diagnostics[kPushDiagnosticsJWTSubject]);
+  XCTAssertNil(diagnostics[kPushDiagnosticsJWTSubscribeAllow]);
+}
+
+- (void)testCredentialRejectionClassification {
+  XCTAssertTrue(NATSErrorIsCredentialRejection(NATS_CONNECTION_AUTH_FAILED, nil));
+  XCTAssertTrue(NATSErrorIsCredentialRejection(NATS_CONNECTION_CLOSED, @"Authorization Violation"));
+  XCTAssertTrue(NATSErrorIsCredentialRejection(NATS_ERR, @"nats: authentication revoked"));
+  XCTAssertTrue(NATSErrorIsCredentialRejection(NATS_ERR, @"User Authentication Expired"));
+
+  XCTAssertFalse(NATSErrorIsCredentialRejection(NATS_TIMEOUT, nil));
+  XCTAssertFalse(NATSErrorIsCredentialRejection(NATS_NO_SERVER, @"No server available"));
+  XCTAssertFalse(NATSErrorIsCredentialRejection(NATS_CONNECTION_CLOSED, @"Stale Connection"));
+  XCTAssertFalse(NATSErrorIsCredentialRejection(
+      NATS_ERR, @"Permissions Violation for Subscription to \"santa.host.ABC\""));
+}
+
+- (void)testRejectedCredentialsStopRetryingAndFallBackToPolling {
+  OCMStub([self.mockConfigurator pushCredentialsRejectedFullSyncInterval]).andReturn(600);
+  XCTestExpectation* expectation = [self expectationWithDescription:@"delegate notified"];
+  OCMExpect([self.mockSyncDelegate pushCredentialsRejected]).andDo(^(NSInvocation* invocation) {
+    [expectation fulfill];
+  });
+
+  self.client = [[SNTPushClientNATS alloc] initWithSyncDelegate:self.mockSyncDelegate];
+  self.client.retryAttempt = 3;
+  dispatch_sync(self.client.connectionQueue, ^{
+    [self.client handleConnectionFailureWithStatus:NATS_CONNECTION_CLOSED
+                                             error:@"Authorization Violation"];
+  });
+
+  // No retry is scheduled with the rejected credentials and the client stops connecting.
+  [self waitForExpectations:@[ expectation ] timeout:2.0];
+  OCMVerifyAll(self.mockSyncDelegate);
+  XCTAssertTrue(self.client.credentialsRejected);
+  XCTAssertFalse(self.client.isRetrying);
+  XCTAssertEqual(self.client.retryAttempt, 0);
+  XCTAssertNil(self.client.connectionRetryTimer);
+  XCTAssertEqual(self.client.fullSyncInterval, 600);
+
+  // New credentials from a preflight clear the rejection.
+  [self.client configureWithPushServer:@"workshop"
+                             pushToken:@"new-nkey"
+                                   jwt:@"new-jwt"
+                          pushDeviceID:@"test-device-id"
+                                  tags:@[]];
+  // Wait for the configuration, which is applied on the connection queue.
+  dispatch_sync(self.client.connectionQueue, ^{});
+  XCTAssertFalse(self.client.credentialsRejected);
+  XCTAssertEqual(self.client.fullSyncInterval, kDefaultPushNotificationsFullSyncInterval);
+}
+
+- (void)testRejectedCredentialsAreRefreshedThroughProvider {
+  OCMStub([self.mockConfigurator pushCredentialsRejectedFullSyncInterval]).andReturn(600);
+  FakeCredentialProvider* provider = [[FakeCredentialProvider alloc] init];
+  provider.staleJWT = @"stale-jwt";
+  provider.staleNKey = @"stale-nkey";
+
+  self.client = [[SNTPushClientNATS alloc] initWithSyncDelegate:self.mockSyncDelegate];
+  self.client.credentialProvider = provider;
+  [self.client configureWithPushServer:@"workshop"
+                             pushToken:@"stale-nkey"
+                                   jwt:@"stale-jwt"
+                          pushDeviceID:@"test-device-id"
+                                  tags:@[]];
+  // Wait for the configuration and then for the connection attempt it starts.
+  dispatch_sync(self.client.connectionQueue, ^{});
+  dispatch_sync(self.client.connectionQueue, ^{});
+
+  dispatch_sync(self.client.connectionQueue, ^{
+    [self.client handleConnectionFailureWithStatus:NATS_CONNECTION_AUTH_FAILED error:nil];
+  });
+
+  // A retry is scheduled. The first refresh returns the rejected credentials again, so the
+  // client keeps them rejected and retries; the second returns fresh ones, which are used.
+  XCTAssertTrue(self.client.credentialsRejected);
+  XCTAssertTrue(self.client.isRetrying);
+  NSPredicate* refreshed = [NSPredicate predicateWithBlock:^BOOL(SNTPushClientNATS* client, id _) {
+    return !client.credentialsRejected && [client.jwt isEqualToString:@"fresh-jwt"];
+  }];
+  [self waitForExpectations:@[ [[XCTNSPredicateExpectation alloc] initWithPredicate:refreshed
+                                                                             object:self.client] ]
+                    timeout:10.0];
+  XCTAssertEqual(provider.refreshCount, 2);
+  XCTAssertEqualObjects(self.client.pushToken, @"fresh-nkey");
+}
+
+- (void)testCredentialRejectionLogsErrorWithServerAndMachineID {
+  OCMStub([self.mockConfigurator machineID]).andReturn(@"test-machine-id");
+  self.client = [[SNTPushClientNATS alloc] initWithSyncDelegate:self.mockSyncDelegate];
+  XCTAssertTrue([self.client.logger isKindOfClass:[SNTPushOSLogger class]]);
+  RecordingPushLogger* logger = [[RecordingPushLogger alloc] init];
+  self.client.logger = logger;
+  [self.client configureWithPushServer:@"workshop"
+                             pushToken:@"test-nkey"
+                                   jwt:@"test-jwt"
+                          pushDeviceID:@"test-device-id"
+                                  tags:@[]];
+  // Wait for the configuration and then for the connection attempt it starts.
+  dispatch_sync(self.client.connectionQueue, ^{});
+  dispatch_sync(self.client.connectionQueue, ^{});
+
+  dispatch_sync(self.client.connectionQueue, ^{
+    [self.client handleConnectionFailureWithStatus:NATS_CONNECTION_AUTH_FAILED
+                                             error:@"Authorization Violation"];
+  });
+
+  NSDictionary* rejection;
+  @synchronized(logger) {
+    for (NSDictionary* entry in logger.entries) {
+      if ([entry[@"message"] containsString:@"rejected the push credentials"]) {
+        rejection = entry;
+      }
+    }
+  }
+  XCTAssertNotNil(rejection);
+  XCTAssertEqual([rejection[@"type"] intValue], OS_LOG_TYPE_ERROR);
+  NSDictionary* fields = rejection[@"fields"];
+  XCTAssertGreaterThan([fields[kPushLogFieldServer] length], 0);
+  XCTAssertEqualObjects(fields[kPushLogFieldServer], self.client.pushServer);
+  XCTAssertEqualObjects(fields[kPushLogFieldMachineID], @"test-machine-id");
+  XCTAssertEqualObjects(fields[kPushLogFieldError], @"Authorization Violation");
+}
+
+- (void)testShutdownWithoutConnectionCompletesCleanly {
+  self.client = [[SNTPushClientNATS alloc] initWithSyncDelegate:self.mockSyncDelegate];
+
+  XCTestExpectation* done = [self expectationWithDescription:@"Shutdown completes"];
+  [self.client shutdownWithTimeout:1
+                        completion:^(NSError* error) {
+                          XCTAssertNil(error);
+                          XCTAssertTrue([NSThread isMainThread]);
+                          [done fulfill];
+                        }];
+  [self waitForExpectations:@[ done ] timeout:5.0];
+  XCTAssertEqual(self.client.connectionState, SNTPushConnectionStateClosed);
+}
+
+- (void)testTransientConnectionFailureRetries {
+  OCMReject([self.mockSyncDelegate pushCredentialsRejected]);
+
+  self.client = [[SNTPushClientNATS alloc] initWithSyncDelegate:self.mockSyncDelegate];
+  dispatch_sync(self.client.connectionQueue, ^{
+    [self.client handleConnectionFailureWithStatus:NATS_TIMEOUT error:@"timeout"];
+  });
+
+  XCTAssertFalse(self.client.credentialsRejected);
+  XCTAssertTrue(self.client.isRetrying);
+  XCTAssertEqual(self.client.retryAttempt, 1);
+  XCTAssertNotNil(self.client.connectionRetryTimer);
+  XCTAssertEqual(self.client.fullSyncInterval, kDefaultPushNotificationsFullSyncInterval);
+
+  // Cleanup
+  if (self.client.connectionRetryTimer) {
+    dispatch_source_cancel(self.client.connectionRetryTimer);
+  }
+}
+
+- (void)testReconnectDelayGrowsExponentiallyUpToMax {
+  NATSBackoffConfig config = {.base = 1.0, .max = 60.0, .jitterFraction = 0.2};
+
+  // With no jitter the delay doubles each attempt until it reaches the cap.
+  XCTAssertEqualWithAccuracy(NATSReconnectDelay(config, 1, 0.5), 1.0, 0.001);
+  XCTAssertEqualWithAccuracy(NATSReconnectDelay(config, 2, 0.5), 2.0, 0.001);
+  XCTAssertEqualWithAccuracy(NATSReconnectDelay(config, 3, 0.5), 4.0, 0.001);
+  XCTAssertEqualWithAccuracy(NATSReconnectDelay(config, 6, 0.5), 32.0, 0.001);
+  XCTAssertEqualWithAccuracy(NATSReconnectDelay(config, 7, 0.5), 60.0, 0.001);
+  XCTAssertEqualWithAccuracy(NATSReconnectDelay(config, 1000, 0.5), 60.0, 0.001);
+
+  // Attempts before the first are treated as the first.
+  XCTAssertEqualWithAccuracy(NATSReconnectDelay(config, 0, 0.5), 1.0, 0.001);
+}
+
+- (void)testReconnectDelayJitter {
+  NATSBackoffConfig config = {.base = 1.0, .max = 60.0, .jitterFraction = 0.2};
+
+  XCTAssertEqualWithAccuracy(NATSReconnectDelay(config, 3, 0.0), 3.2, 0.001);
+  XCTAssertEqualWithAccuracy(NATSReconnectDelay(config, 3, 1.0), 4.8, 0.001);
+  XCTAssertEqualWithAccuracy(NATSReconnectDelay(config, 10, 0.0), 48.0, 0.001);
+  XCTAssertEqualWithAccuracy(NATSReconnectDelay(config, 10, 1.0), 72.0, 0.001);
+
+  // Out of range random values are clamped.
+  XCTAssertEqualWithAccuracy(NATSReconnectDelay(config, 3, -1.0), 3.2, 0.001);
+  XCTAssertEqualWithAccuracy(NATSReconnectDelay(config, 3, 2.0), 4.8, 0.001);
+
+  config.jitterFraction = 0;
+  XCTAssertEqualWithAccuracy(NATSReconnectDelay(config, 3, 0.0), 4.0, 0.001);
+}
+
+- (void)testReconnectBackoffConfig {
+  self.client = [[SNTPushClientNATS alloc] initWithSyncDelegate:self.mockSyncDelegate];
+  XCTAssertEqual(self.client.reconnectBackoff.base, kDefaultNATSReconnectBackoff.base);
+  XCTAssertEqual(self.client.reconnectBackoff.max, kDefaultNATSReconnectBackoff.max);
+  XCTAssertEqual(self.client.reconnectBackoff.jitterFraction,
+                 kDefaultNATSReconnectBackoff.jitterFraction);
+
+  NATSBackoffConfig config = {.base = 0.5, .max = 10.0, .jitterFraction = 0.1};
+  self.client = [[SNTPushClientNATS alloc] initWithSyncDelegate:self.mockSyncDelegate
+                                               reconnectBackoff:config];
+  XCTAssertEqual(self.client.reconnectBackoff.base, 0.5);
+  XCTAssertEqual(self.client.reconnectBackoff.max, 10.0);
+  XCTAssertEqual(self.client.reconnectBackoff.jitterFraction, 0.1);
+}
+
+- (void)testJWTExpiry {
+  NSError* error;
+  NSDate* expiry = NATSJWTExpiry(JWTWithClaims(@{@"iat" : @1700000000, @"exp" : @1800000000}),
+                                 &error);
+  XCTAssertEqualObjects(expiry, [NSDate dateWithTimeIntervalSince1970:1800000000]);
+  XCTAssertNil(error);
+
+  // No exp claim means the JWT doesn't expire.
+  XCTAssertEqualObjects(NATSJWTExpiry(JWTWithClaims(@{@"iat" : @1700000000}), &error),
+                        [NSDate distantFuture]);
+
+  error = nil;
+  XCTAssertNil(NATSJWTExpiry(@"not-a-jwt", &error));
+  XCTAssertNotNil(error);
+
+  error = nil;
+  XCTAssertNil(NATSJWTExpiry(nil, &error));
+  XCTAssertNotNil(error);
+
+  error = nil;
+  XCTAssertNil(NATSJWTExpiry(JWTWithClaims(@{@"exp" : @"tomorrow"}), &error));
+  XCTAssertNotNil(error);
+
+  error = nil;
+  XCTAssertNil(
+      NATSJWTExpiry(JWTWithClaims(@{@"iat" : @1800000000, @"exp" : @1700000000}), &error));
+  XCTAssertNotNil(error);
+}
+
+- (void)testPushPublishedAt {
+  NSDate* base = [NSDate dateWithTimeIntervalSince1970:1791970200];  // 2026-10-14T09:30:00Z
+  XCTAssertEqualObjects(NATSPushPublishedAt(@"2026-10-14T09:30:00Z"), base);
+  XCTAssertEqualWithAccuracy(
+      [NATSPushPublishedAt(@"2026-10-14T09:30:00.123456789Z") timeIntervalSinceDate:base],
+      0.123456789, 1e-6);
+  XCTAssertEqualWithAccuracy(
+      [NATSPushPublishedAt(@"2026-10-14T11:30:00.5+02:00") timeIntervalSinceDate:base], 0.5, 1e-6);
+
+  XCTAssertNil(NATSPushPublishedAt(nil));
+  XCTAssertNil(NATSPushPublishedAt(@""));
+  XCTAssertNil(NATSPushPublishedAt(@"1791970200"));
+  XCTAssertNil(NATSPushPublishedAt(@"2026-10-14T09:30:00"));
+  XCTAssertNil(NATSPushPublishedAt(@"2026-10-14T09:30:00.1234567890Z"));
+}
+
+- (void)testValidateCredentialsRejectsExpiredJWT {
+  self.client = [[SNTPushClientNATS alloc] initWithSyncDelegate:self.mockSyncDelegate];
+  NSTimeInterval now = [[NSDate date] timeIntervalSince1970];
+
+  self.client.jwt = JWTWithClaims(@{@"exp" : @(now + 3600)});
+  XCTAssertTrue([self.client validateCredentials:NULL]);
+
+  // JWTs that can't be parsed are left for the server to judge.
+  self.client.jwt = @"test-jwt";
+  XCTAssertTrue([self.client validateCredentials:NULL]);
+
+  NSError* error;
+  self.client.jwt = JWTWithClaims(@{@"exp" : @(now - 60)});
+  XCTAssertFalse([self.client validateCredentials:&error]);
+  XCTAssertEqualObjects(error.domain, SantaErrorDomain);
+  XCTAssertEqual(error.code, SNTErrorCodePushCredentialExpired);
+}
+
+- (void)testExpiredJWTIsNotUsedToConnect {
+  XCTestExpectation* expectation = [self expectationWithDescription:@"delegate notified"];
+  OCMExpect([self.mockSyncDelegate pushCredentialsRejected]).andDo(^(NSInvocation* invocation) {
+    [expectation fulfill];
+  });
+
+  self.client = [[SNTPushClientNATS alloc] initWithSyncDelegate:self.mockSyncDelegate];
+  NSString* jwt = JWTWithClaims(@{@"exp" : @([[NSDate date] timeIntervalSince1970] - 60)});
+  [self.client configureWithPushServer:@"workshop"
+                             pushToken:@"test-nkey"
+                                   jwt:jwt
+                          pushDeviceID:@"test-device-id"
+                                  tags:@[]];
+
+  // The client falls back to a preflight for new credentials instead of connecting.
+  [self waitForExpectations:@[ expectation ] timeout:2.0];
+  OCMVerifyAll(self.mockSyncDelegate);
+  XCTAssertTrue(self.client.credentialsRejected);
+  XCTAssertFalse(self.client.isRetrying);
+  XCTAssertTrue(self.client.conn == NULL);
+  XCTAssertEqual(self.client.connectionState, SNTPushConnectionStateClosed);
+}
+
+#pragma mark - Connection State Tests
+
+- (void)testConnectionStateObservers {
+  self.client = [[SNTPushClientNATS alloc] initWithSyncDelegate:self.mockSyncDelegate];
+  XCTAssertEqual(self.client.connectionState, SNTPushConnectionStateClosed);
+
+  NSMutableArray<NSString*>* transitions = [NSMutableArray array];
+  XCTestExpectation* expectation = [self expectationWithDescription:@"observers called"];
+  expectation.expectedFulfillmentCount = 2;
+  for (NSString* name in @[ @"a", @"b" ]) {
+    [self.client addConnectionStateObserver:^(SNTPushConnectionState oldState,
+                                              SNTPushConnectionState newState) {
+      [transitions addObject:[NSString stringWithFormat:@"%@:%ld->%ld", name, (long)oldState,
+                                                        (long)newState]];
+      // The last transition.
+      if (oldState == SNTPushConnectionStateReconnecting) [expectation fulfill];
+    }];
+  }
+
+  dispatch_sync(self.client.connectionQueue, ^{
+    [self.client transitionToConnectionState:SNTPushConnectionStateConnecting];
+    [self.client transitionToConnectionState:SNTPushConnectionStateConnected];
+    // Not a change, so observers aren't called.
+    [self.client transitionToConnectionState:SNTPushConnectionStateConnected];
+    [self.client transitionToConnectionState:SNTPushConnectionStateDisconnected];
+    [self.client transitionToConnectionState:SNTPushConnectionStateReconnecting];
+    [self.client transitionToConnectionState:SNTPushConnectionStateConnected];
+  });
+
+  [self waitForExpectations:@[ expectation ] timeout:2.0];
+  XCTAssertEqual(self.client.connectionState, SNTPushConnectionStateConnected);
+
+  // Each transition is delivered to every observer, in registration order.
+  NSMutableArray<NSString*>* expected = [NSMutableArray array];
+  for (NSArray<NSNumber*>* t in @[
+         @[ @(SNTPushConnectionStateClosed), @(SNTPushConnectionStateConnecting) ],
+         @[ @(SNTPushConnectionStateConnecting), @(SNTPushConnectionStateConnected) ],
+         @[ @(SNTPushConnectionStateConnected), @(SNTPushConnectionStateDisconnected) ],
+         @[ @(SNTPushConnectionStateDisconnected), @(SNTPushConnectionStateReconnecting) ],
+         @[ @(SNTPushConnectionStateReconnecting), @(SNTPushConnectionStateConnected) ],
+       ]) {
+    for (NSString* name in @[ @"a", @"b" ]) {
+      [expected addObject:[NSString stringWithFormat:@"%@:%ld->%ld", name, t[0].longValue,
+                                                     t[1].longValue]];
+    }
+  }
+  XCTAssertEqualObjects(transitions, expected);
+}
+
+- (void)testSlowConnectionStateObserverDoesNotBlockConnectionQueue {
+  self.client = [[SNTPushClientNATS alloc] initWithSyncDelegate:self.mockSyncDelegate];
+
+  dispatch_semaphore_t release = dispatch_semaphore_create(0);
+  [self.client addConnectionStateObserver:^(SNTPushConnectionState oldState,
+                                            SNTPushConnectionState newState) {
+    dispatch_semaphore_wait(release, dispatch_time(DISPATCH_TIME_NOW, 5 * NSEC_PER_SEC));
+  }];
+
+  // Both transitions are recorded while the observer is still blocked on the first.
+  dispatch_sync(self.client.connectionQueue, ^{
+    [self.client transitionToConnectionState:SNTPushConnectionStateConnecting];
+    [self.client transitionToConnectionState:SNTPushConnectionStateConnected];
+  });
+  XCTAssertEqual(self.client.connectionState, SNTPushConnectionStateConnected);
+
+  dispatch_semaphore_signal(release);
+  dispatch_semaphore_signal(release);
+}
+
+- (void)testDisconnectClosesConnectionState {
+  self.client = [[SNTPushClientNATS alloc] initWithSyncDelegate:self.mockSyncDelegate];
+  XCTestExpectation* expectation = [self expectationWithDescription:@"closed"];
+  [self.client addConnectionStateObserver:^(SNTPushConnectionState oldState,
+                                            SNTPushConnectionState newState) {
+    if (newState == SNTPushConnectionStateClosed) [expectation fulfill];
+  }];
+
+  dispatch_sync(self.client.connectionQueue, ^{
+    [self.client transitionToConnectionState:SNTPushConnectionStateConnected];
+    self.client.isConnected = YES;
+  });
+  [self.client disconnectWithCompletion:nil];
+
+  [self waitForExpectations:@[ expectation ] timeout:2.0];
+  XCTAssertEqual(self.client.connectionState, SNTPushConnectionStateClosed);
+}
+
 #pragma mark - Topic Building Tests
 
 - (void)testHandlePreflightSyncStateFiltersHostTopics {
@@ -731,52 +1339,600 @@ static X509* CreateCertWithDNSSANBytes(const char* data, size_t len) {
   [self waitForExpectations:@[ expectation ] timeout:2.0];
 }
 
-#pragma mark - SSL Certificate Domain Verification Tests
+- (void)testRepeatedMessageTriggersSingleSync {
+  // Given: Client is initialized with the default dedupe window
+  OCMStub([self.mockConfigurator pushMessageDedupeWindow]).andReturn(5.0);
+  self.client = [[SNTPushClientNATS alloc] initWithSyncDelegate:self.mockSyncDelegate];
 
-- (void)testLeafCertHasPushDomain_validPushHost {
-  X509* cert = CreateCertWithDNSSAN("east1.push.northpole.security");
-  XCTAssertTrue(NATSLeafCertHasPushDomain(cert));
-  X509_free(cert);
+  __block int syncs = 0;
+  OCMStub([self.mockSyncDelegate syncSecondsFromNow:0])
+      .ignoringNonObjectArgs()
+      .andDo(^(NSInvocation* invocation) {
+        syncs++;
+      });
+
+  // When: The same message arrives on the host subject and a tag subject, followed by a
+  // different message
+  NSData* payload = [NSData data];
+  NSDictionary* headers = @{kPushHeaderMessageID : @"msg-1"};
+  [self.client handlePushNotificationForSubject:@"santa.host.ABC123"
+                                    withPayload:payload
+                                        headers:headers];
+  [self.client handlePushNotificationForSubject:@"santa.tag.incident"
+                                    withPayload:payload
+                                        headers:headers];
+  [self.client handlePushNotificationForSubject:@"santa.host.ABC123"
+                                    withPayload:payload
+                                        headers:@{kPushHeaderMessageID : @"msg-2"}];
+
+  // Messages without an ID are matched on their headers and payload
+  [self.client handlePushNotificationForSubject:@"santa.host.ABC123" withPayload:nil];
+  [self.client handlePushNotificationForSubject:@"santa.host.ABC123" withPayload:nil];
+
+  // Then: Only the first of each message triggers a sync
+  XCTestExpectation* expectation = [self expectationWithDescription:@"Messages handled"];
+  dispatch_async([self.client messageQueue], ^{
+    dispatch_async(dispatch_get_main_queue(), ^{
+      [expectation fulfill];
+    });
+  });
+  [self waitForExpectations:@[ expectation ] timeout:2.0];
+  XCTAssertEqual(syncs, 3);
 }
 
-- (void)testLeafCertHasPushDomain_otherValidPushHosts {
-  const char* hosts[] = {
-      "west3.push.northpole.security",
-      "central2.push.northpole.security",
-      "eu1.push.northpole.security",
-  };
-  for (const char* host : hosts) {
-    X509* cert = CreateCertWithDNSSAN(host);
-    XCTAssertTrue(NATSLeafCertHasPushDomain(cert), @"Expected match for %s", host);
-    X509_free(cert);
+- (void)testSyncsBeyondRateLimitAreCoalesced {
+  // Given: Client is initialized with the default sync rate limit
+  OCMStub([self.mockConfigurator pushSyncRateLimitInterval]).andReturn(30.0);
+  OCMStub([self.mockConfigurator pushSyncRateLimitBurst]).andReturn(2);
+  self.client = [[SNTPushClientNATS alloc] initWithSyncDelegate:self.mockSyncDelegate];
+
+  NSMutableArray<NSNumber*>* delays = [NSMutableArray array];
+  OCMStub([self.mockSyncDelegate syncSecondsFromNow:0])
+      .ignoringNonObjectArgs()
+      .andDo(^(NSInvocation* invocation) {
+        uint64_t seconds;
+        [invocation getArgument:&seconds atIndex:2];
+        [delays addObject:@(seconds)];
+      });
+
+  // When: Five distinct sync messages arrive at once, followed by a diagnostics request
+  for (int i = 0; i < 5; i++) {
+    [self.client
+        handlePushNotificationForSubject:@"santa.host.ABC123"
+                             withPayload:nil
+                                 headers:@{
+                                   kPushHeaderMessageID : [NSString stringWithFormat:@"msg-%d", i]
+                                 }];
   }
+  OCMExpect([self.mockSyncDelegate collectDiagnosticsSecondsFromNow:0]);
+  [self.client handlePushNotificationForSubject:@"santa.host.ABC123"
+                                    withPayload:nil
+                                        headers:@{kPushHeaderType : kPushTypeCollectDiagnostics}];
+
+  // Then: The burst syncs immediately, the rest collapse into one sync once a token is earned
+  // back, and other actions aren't limited
+  XCTestExpectation* expectation = [self expectationWithDescription:@"Messages handled"];
+  dispatch_async([self.client messageQueue], ^{
+    dispatch_async(dispatch_get_main_queue(), ^{
+      [expectation fulfill];
+    });
+  });
+  [self waitForExpectations:@[ expectation ] timeout:2.0];
+  XCTAssertEqualObjects(delays, (@[ @0, @0, @30 ]));
+  OCMVerifyAll(self.mockSyncDelegate);
 }
 
-- (void)testLeafCertHasPushDomain_nonPushDomain_returnsFalse {
-  const char* hosts[] = {
-      "attacker.example.com",
-      "push.northpole.security",           // exact domain, no subdomain
-      "evil.push.northpole.security.com",  // suffix after the expected domain
-      "notpush.northpole.security",        // different subdomain of northpole.security
-  };
-  for (const char* host : hosts) {
-    X509* cert = CreateCertWithDNSSAN(host);
-    XCTAssertFalse(NATSLeafCertHasPushDomain(cert), @"Expected no match for %s", host);
-    X509_free(cert);
+- (void)testMessageWithReplySubjectIsAcknowledged {
+  // Given: Client is initialized with the default dedupe window
+  OCMStub([self.mockConfigurator machineID]).andReturn(@"test-machine-id");
+  OCMStub([self.mockConfigurator pushMessageDedupeWindow]).andReturn(5.0);
+  self.client = [[SNTPushClientNATS alloc] initWithSyncDelegate:self.mockSyncDelegate];
+  id partialClient = OCMPartialMock(self.client);
+
+  __block BOOL synced = NO;
+  OCMStub([self.mockSyncDelegate syncSecondsFromNow:0])
+      .ignoringNonObjectArgs()
+      .andDo(^(NSInvocation* invocation) {
+        synced = YES;
+      });
+
+  XCTestExpectation* expectation = [self expectationWithDescription:@"Acknowledgements published"];
+  expectation.expectedFulfillmentCount = 2;
+  NSMutableArray<NSDictionary*>* acks = [NSMutableArray array];
+  OCMStub([partialClient publishData:[OCMArg any] toSubject:@"_INBOX.ack"])
+      .andDo(^(NSInvocation* invocation) {
+        __unsafe_unretained NSData* data;
+        [invocation getArgument:&data atIndex:2];
+        NSDictionary* ack = [NSJSONSerialization JSONObjectWithData:data options:0 error:nil];
+        // The sync is kicked off before the first copy is acknowledged.
+        if (![ack[@"duplicate"] boolValue]) XCTAssertTrue(synced);
+        @synchronized(acks) {
+          [acks addObject:ack];
+        }
+        [expectation fulfill];
+      });
+
+  // When: The same sync request with a reply subject arrives twice
+  NSDictionary* headers = @{kPushHeaderMessageID : @"msg-1"};
+  [self.client handlePushNotificationForSubject:@"santa.host.ABC123"
+                                    withPayload:nil
+                                        headers:headers
+                                   replySubject:@"_INBOX.ack"];
+  [self.client handlePushNotificationForSubject:@"santa.tag.incident"
+                                    withPayload:nil
+                                        headers:headers
+                                   replySubject:@"_INBOX.ack"];
+
+  // Then: Both copies are acknowledged, the second as a duplicate
+  [self waitForExpectations:@[ expectation ] timeout:2.0];
+  NSSet* duplicates = [NSSet setWithArray:[acks valueForKey:@"duplicate"]];
+  XCTAssertEqualObjects(duplicates, (NSSet*)([NSSet setWithArray:@[ @NO, @YES ]]));
+  for (NSDictionary* ack in acks) {
+    XCTAssertEqualObjects(ack[@"machine_id"], @"test-machine-id");
+    XCTAssertEqualObjects(ack[@"message_id"], @"msg-1");
+    XCTAssertEqualObjects(ack[@"type"], kPushTypeSync);
+    XCTAssertEqualObjects(ack[@"delay_seconds"], @0);
   }
+  [partialClient stopMocking];
 }
 
-- (void)testLeafCertHasPushDomain_noSAN_returnsFalse {
-  X509* cert = X509_new();
-  XCTAssertFalse(NATSLeafCertHasPushDomain(cert));
-  X509_free(cert);
-}
+#pragma mark - Sync Interval Override Tests
 
-- (void)testLeafCertHasPushDomain_multipleSANs_oneMatches_returnsTrue {
-  // Build a cert with two SANs: one non-matching, one matching.
-  X509* cert = X509_new();
+- (void)testTagMessageWithOverrideHeadersOverridesSyncInterval {
+  // Given: Client is initialized
+  self.client = [[SNTPushClientNATS alloc] initWithSyncDelegate:self.mockSyncDelegate];
 
-  GENERAL_NAMES* gens = sk_GENERAL_NAME_new_null();
+  XCTestExpectation* expectation =
+      [self expectationWithDescription:@"overrideFullSyncInterval called for tag message"];
+
+  OCMStub([self.mockSyncDelegate overrideFullSyncInterval:0
+                                              forDuration:0
+                                                      tag:@"santa.tag.incident"])
+      .ignoringNonObjectArgs()
+      .andDo(^(NSInvocation* invocation) {
+        NSUInteger seconds;
+        NSTimeInterval duration;
+        [invocation getArgument:&seconds atIndex:2];
+        [invocation getArgument:&duration atIndex:3];
+        XCTAssertEqual(seconds, 60u);
+        XCTAssertEqual(duration, 3600.0);
+        [expectation fulfill];
+      });
+
+  // When: A tag push notification carries the override headers
+  [self.client handlePushNotificationForSubject:@"santa.tag.incident"
+                                    withPayload:nil
+                                        headers:@{
+                                          kPushHeaderSyncIntervalOverride : @"60",
+                                          kPushHeaderSyncIntervalOverrideDuration : @"3600",
+                                        }];
+
+  // Then: The sync delegate is asked to override the interval for the tagged host
+  [self waitForExpectations:@[ expectation ] timeout:2.0];
+}
+
+- (void)testHostMessageWithOverrideHeadersDoesNotOverrideSyncInterval {
+  // Given: Client is initialized
+  self.client = [[SNTPushClientNATS alloc] initWithSyncDelegate:self.mockSyncDelegate];
+
+  OCMReject([self.mockSyncDelegate overrideFullSyncInterval:0 forDuration:0 tag:[OCMArg any]])
+      .ignoringNonObjectArgs();
+
+  XCTestExpectation* expectation =
+      [self expectationWithDescription:@"syncSecondsFromNow called for host message"];
+  OCMStub([self.mockSyncDelegate syncSecondsFromNow:0])
+      .ignoringNonObjectArgs()
+      .andDo(^(NSInvocation* invocation) {
+        [expectation fulfill];
+      });
+
+  // When: A host push notification carries the override headers
+  [self.client handlePushNotificationForSubject:@"santa.host.ABC123"
+                                    withPayload:nil
+                                        headers:@{
+                                          kPushHeaderSyncIntervalOverride : @"60",
+                                          kPushHeaderSyncIntervalOverrideDuration : @"3600",
+                                        }];
+
+  // Then: A sync is triggered but the interval is not overridden
+  [self waitForExpectations:@[ expectation ] timeout:2.0];
+  OCMVerifyAll(self.mockSyncDelegate);
+}
+
+- (void)testTagMessageWithInvalidOverrideHeadersIsIgnored {
+  // Given: Client is initialized
+  self.client = [[SNTPushClientNATS alloc] initWithSyncDelegate:self.mockSyncDelegate];
+
+  OCMReject([self.mockSyncDelegate overrideFullSyncInterval:0 forDuration:0 tag:[OCMArg any]])
+      .ignoringNonObjectArgs();
+
+  XCTestExpectation* expectation =
+      [self expectationWithDescription:@"syncSecondsFromNow called for tag message"];
+  OCMStub([self.mockSyncDelegate syncSecondsFromNow:0])
+      .ignoringNonObjectArgs()
+      .andDo(^(NSInvocation* invocation) {
+        [expectation fulfill];
+      });
+
+  // When: A tag push notification carries a non-numeric interval and no duration
+  [self.client handlePushNotificationForSubject:@"santa.tag.incident"
+                                    withPayload:nil
+                                        headers:@{kPushHeaderSyncIntervalOverride : @"soon"}];
+
+  // Then: The sync still happens but the interval is not overridden
+  [self waitForExpectations:@[ expectation ] timeout:2.0];
+  OCMVerifyAll(self.mockSyncDelegate);
+}
+
+- (void)testHostMessageWithDiagnosticsTypeCollectsDiagnostics {
+  // Given: Client is initialized
+  self.client = [[SNTPushClientNATS alloc] initWithSyncDelegate:self.mockSyncDelegate];
+
+  OCMReject([self.mockSyncDelegate syncSecondsFromNow:0]).ignoringNonObjectArgs();
+
+  XCTestExpectation* expectation =
+      [self expectationWithDescription:@"collectDiagnosticsSecondsFromNow called"];
+  OCMStub([self.mockSyncDelegate collectDiagnosticsSecondsFromNow:0])
+      .ignoringNonObjectArgs()
+      .andDo(^(NSInvocation* invocation) {
+        uint64_t seconds;
+        [invocation getArgument:&seconds atIndex:2];
+        XCTAssertEqual(seconds, 0u);
+        [expectation fulfill];
+      });
+
+  // When: A host push notification asks for diagnostics
+  [self.client handlePushNotificationForSubject:@"santa.host.ABC123"
+                                    withPayload:nil
+                                        headers:@{kPushHeaderType : kPushTypeCollectDiagnostics}];
+
+  // Then: Diagnostics are collected immediately instead of syncing
+  [self waitForExpectations:@[ expectation ] timeout:2.0];
+  OCMVerifyAll(self.mockSyncDelegate);
+}
+
+- (void)testTagMessageWithDiagnosticsTypeCollectsDiagnosticsWithJitter {
+  // Given: Client is initialized
+  self.client = [[SNTPushClientNATS alloc] initWithSyncDelegate:self.mockSyncDelegate];
+
+  OCMReject([self.mockSyncDelegate syncSecondsFromNow:0]).ignoringNonObjectArgs();
+  OCMReject([self.mockSyncDelegate overrideFullSyncInterval:0 forDuration:0 tag:[OCMArg any]])
+      .ignoringNonObjectArgs();
+
+  XCTestExpectation* expectation =
+      [self expectationWithDescription:@"collectDiagnosticsSecondsFromNow called"];
+  OCMStub([self.mockSyncDelegate collectDiagnosticsSecondsFromNow:0])
+      .ignoringNonObjectArgs()
+      .andDo(^(NSInvocation* invocation) {
+        uint64_t seconds;
+        [invocation getArgument:&seconds atIndex:2];
+        XCTAssertLessThan(seconds, kDefaultPushNotificationTagSyncJitterSeconds);
+        [expectation fulfill];
+      });
+
+  // When: A tag push notification asks for diagnostics
+  [self.client handlePushNotificationForSubject:@"santa.tag.incident"
+                                    withPayload:nil
+                                        headers:@{
+                                          kPushHeaderType : kPushTypeCollectDiagnostics,
+                                          kPushHeaderSyncIntervalOverride : @"60",
+                                          kPushHeaderSyncIntervalOverrideDuration : @"3600",
+                                        }];
+
+  // Then: Diagnostics are collected after the jitter, without a sync or interval override
+  [self waitForExpectations:@[ expectation ] timeout:2.0];
+  OCMVerifyAll(self.mockSyncDelegate);
+}
+
+- (void)testUnknownPushTypeTriggersSync {
+  // Given: Client is initialized
+  self.client = [[SNTPushClientNATS alloc] initWithSyncDelegate:self.mockSyncDelegate];
+
+  OCMReject([self.mockSyncDelegate collectDiagnosticsSecondsFromNow:0]).ignoringNonObjectArgs();
+
+  XCTestExpectation* expectation =
+      [self expectationWithDescription:@"syncSecondsFromNow called for unknown push type"];
+  OCMStub([self.mockSyncDelegate syncSecondsFromNow:0])
+      .ignoringNonObjectArgs()
+      .andDo(^(NSInvocation* invocation) {
+        [expectation fulfill];
+      });
+
+  [self.client handlePushNotificationForSubject:@"santa.host.ABC123"
+                                    withPayload:nil
+                                        headers:@{kPushHeaderType : @"reticulate_splines"}];
+
+  [self waitForExpectations:@[ expectation ] timeout:2.0];
+  OCMVerifyAll(self.mockSyncDelegate);
+}
+
+- (void)testHostMessageWithRotateCredentialsTypeRefreshesCredentials {
+  // Given: Client is initialized
+  self.client = [[SNTPushClientNATS alloc] initWithSyncDelegate:self.mockSyncDelegate];
+
+  OCMReject([self.mockSyncDelegate syncSecondsFromNow:0]).ignoringNonObjectArgs();
+
+  XCTestExpectation* expectation =
+      [self expectationWithDescription:@"rotatePushCredentialsSecondsFromNow called"];
+  OCMStub([self.mockSyncDelegate rotatePushCredentialsSecondsFromNow:0])
+      .ignoringNonObjectArgs()
+      .andDo(^(NSInvocation* invocation) {
+        uint64_t seconds;
+        [invocation getArgument:&seconds atIndex:2];
+        XCTAssertEqual(seconds, 0u);
+        [expectation fulfill];
+      });
+
+  // When: A host push notification asks for a credential rotation
+  [self.client handlePushNotificationForSubject:@"santa.host.ABC123"
+                                    withPayload:nil
+                                        headers:@{kPushHeaderType : kPushTypeRotateCredentials}];
+
+  // Then: The credentials are refreshed immediately instead of syncing
+  [self waitForExpectations:@[ expectation ] timeout:2.0];
+  OCMVerifyAll(self.mockSyncDelegate);
+}
+
+- (void)testTagMessageWithRotateCredentialsTypeRefreshesCredentialsWithJitter {
+  // Given: Client is initialized
+  self.client = [[SNTPushClientNATS alloc] initWithSyncDelegate:self.mockSyncDelegate];
+
+  OCMReject([self.mockSyncDelegate syncSecondsFromNow:0]).ignoringNonObjectArgs();
+  OCMReject([self.mockSyncDelegate overrideFullSyncInterval:0 forDuration:0 tag:[OCMArg any]])
+      .ignoringNonObjectArgs();
+
+  XCTestExpectation* expectation =
+      [self expectationWithDescription:@"rotatePushCredentialsSecondsFromNow called"];
+  OCMStub([self.mockSyncDelegate rotatePushCredentialsSecondsFromNow:0])
+      .ignoringNonObjectArgs()
+      .andDo(^(NSInvocation* invocation) {
+        uint64_t seconds;
+        [invocation getArgument:&seconds atIndex:2];
+        XCTAssertLessThan(seconds, kDefaultPushNotificationTagSyncJitterSeconds);
+        [expectation fulfill];
+      });
+
+  // When: A tag push notification asks for a credential rotation
+  [self.client handlePushNotificationForSubject:@"santa.tag.global"
+                                    withPayload:nil
+                                        headers:@{
+                                          kPushHeaderType : kPushTypeRotateCredentials,
+                                          kPushHeaderSyncIntervalOverride : @"60",
+                                          kPushHeaderSyncIntervalOverrideDuration : @"3600",
+                                        }];
+
+  // Then: The credentials are refreshed after the jitter, without an interval override
+  [self waitForExpectations:@[ expectation ] timeout:2.0];
+  OCMVerifyAll(self.mockSyncDelegate);
+}
+
+- (void)testHostMessageWithReportRulesTypeUploadsRuleSnapshot {
+  // Given: Client is initialized
+  self.client = [[SNTPushClientNATS alloc] initWithSyncDelegate:self.mockSyncDelegate];
+
+  OCMReject([self.mockSyncDelegate syncSecondsFromNow:0]).ignoringNonObjectArgs();
+
+  XCTestExpectation* expectation =
+      [self expectationWithDescription:@"reportRulesSecondsFromNow called"];
+  OCMStub([self.mockSyncDelegate reportRulesSecondsFromNow:0])
+      .ignoringNonObjectArgs()
+      .andDo(^(NSInvocation* invocation) {
+        uint64_t seconds;
+        [invocation getArgument:&seconds atIndex:2];
+        XCTAssertEqual(seconds, 0u);
+        [expectation fulfill];
+      });
+
+  // When: A host push notification asks for a rule snapshot
+  [self.client handlePushNotificationForSubject:@"santa.host.ABC123"
+                                    withPayload:nil
+                                        headers:@{kPushHeaderType : kPushTypeReportRules}];
+
+  // Then: The snapshot is uploaded immediately instead of syncing
+  [self waitForExpectations:@[ expectation ] timeout:2.0];
+  OCMVerifyAll(self.mockSyncDelegate);
+}
+
+- (void)testTagMessageWithReportRulesTypeUploadsRuleSnapshotWithJitter {
+  // Given: Client is initialized
+  self.client = [[SNTPushClientNATS alloc] initWithSyncDelegate:self.mockSyncDelegate];
+
+  OCMReject([self.mockSyncDelegate syncSecondsFromNow:0]).ignoringNonObjectArgs();
+  OCMReject([self.mockSyncDelegate overrideFullSyncInterval:0 forDuration:0 tag:[OCMArg any]])
+      .ignoringNonObjectArgs();
+
+  XCTestExpectation* expectation =
+      [self expectationWithDescription:@"reportRulesSecondsFromNow called"];
+  OCMStub([self.mockSyncDelegate reportRulesSecondsFromNow:0])
+      .ignoringNonObjectArgs()
+      .andDo(^(NSInvocation* invocation) {
+        uint64_t seconds;
+        [invocation getArgument:&seconds atIndex:2];
+        XCTAssertLessThan(seconds, kDefaultPushNotificationTagSyncJitterSeconds);
+        [expectation fulfill];
+      });
+
+  // When: A tag push notification asks for a rule snapshot
+  [self.client handlePushNotificationForSubject:@"santa.tag.global"
+                                    withPayload:nil
+                                        headers:@{
+                                          kPushHeaderType : kPushTypeReportRules,
+                                          kPushHeaderSyncIntervalOverride : @"60",
+                                          kPushHeaderSyncIntervalOverrideDuration : @"3600",
+                                        }];
+
+  // Then: The snapshot is uploaded after the jitter, without an interval override
+  [self waitForExpectations:@[ expectation ] timeout:2.0];
+  OCMVerifyAll(self.mockSyncDelegate);
+}
+
+- (void)testRotateCredentialsReconnectsWithRefreshedCredentials {
+  // Given: Client is configured with the credentials that are about to expire
+  self.client = [[SNTPushClientNATS alloc] initWithSyncDelegate:self.mockSyncDelegate];
+
+  SNTSyncState* initialState = [[SNTSyncState alloc] init];
+  initialState.pushServer = @"workshop";
+  initialState.pushNKey = @"old-nkey";
+  initialState.pushJWT = @"old-jwt";
+  initialState.pushDeviceID = @"test-device-id";
+  [self.client handlePreflightSyncState:initialState];
+
+  id partialClient = OCMPartialMock(self.client);
+  XCTestExpectation* reconnectExpectation =
+      [self expectationWithDescription:@"Reconnected with refreshed credentials"];
+  reconnectExpectation.assertForOverFulfill = NO;
+  OCMStub([partialClient connect]).andDo(^(NSInvocation* invocation) {
+    if ([[partialClient jwt] isEqualToString:@"new-jwt"] &&
+        [[partialClient pushToken] isEqualToString:@"new-nkey"]) {
+      [reconnectExpectation fulfill];
+    }
+  });
+
+  // The preflight triggered by the push returns rotated credentials.
+  OCMStub([self.mockSyncDelegate rotatePushCredentialsSecondsFromNow:0])
+      .ignoringNonObjectArgs()
+      .andDo(^(NSInvocation* invocation) {
+        SNTSyncState* rotatedState = [[SNTSyncState alloc] init];
+        rotatedState.pushServer = @"workshop";
+        rotatedState.pushNKey = @"new-nkey";
+        rotatedState.pushJWT = @"new-jwt";
+        rotatedState.pushDeviceID = @"test-device-id";
+        [self.client handlePreflightSyncState:rotatedState];
+      });
+
+  // When: The server asks the host to rotate its credentials
+  [self.client handlePushNotificationForSubject:@"santa.host.test-device-id"
+                                    withPayload:nil
+                                        headers:@{kPushHeaderType : kPushTypeRotateCredentials}];
+
+  // Then: The client reconnects with the new credentials
+  [self waitForExpectations:@[ reconnectExpectation ] timeout:2.0];
+  [partialClient stopMocking];
+}
+
+#pragma mark - Reconnect Tests
+
+- (void)testCatchUpSyncAfterReconnectIsRateLimited {
+  self.client = [[SNTPushClientNATS alloc] initWithSyncDelegate:self.mockSyncDelegate];
+  NSDate* now = [NSDate dateWithTimeIntervalSince1970:1700000000];
+  BOOL (^reconnectAfter)(NSTimeInterval) = ^BOOL(NSTimeInterval seconds) {
+    return [self.client scheduleCatchUpSyncAfterReconnectAt:[now dateByAddingTimeInterval:seconds]];
+  };
+
+  // The first reconnect schedules a sync, later ones within the default deadline of 600s don't.
+  XCTAssertTrue(reconnectAfter(0));
+  XCTAssertFalse(reconnectAfter(1));
+  XCTAssertFalse(reconnectAfter(599));
+  XCTAssertTrue(reconnectAfter(600));
+}
+
+- (void)testCatchUpSyncRateLimitFollowsPreflightDeadline {
+  self.client = [[SNTPushClientNATS alloc] initWithSyncDelegate:self.mockSyncDelegate];
+  SNTSyncState* syncState = [[SNTSyncState alloc] init];
+  syncState.pushServer = @"workshop";
+  syncState.pushNKey = @"test-nkey";
+  syncState.pushJWT = @"test-jwt";
+  syncState.pushDeviceID = @"test-device-id";
+  syncState.pushNotificationsGlobalRuleSyncDeadline = 900;
+  [self.client handlePreflightSyncState:syncState];
+
+  NSDate* now = [NSDate dateWithTimeIntervalSince1970:1700000000];
+  BOOL (^reconnectAfter)(NSTimeInterval) = ^BOOL(NSTimeInterval seconds) {
+    return [self.client scheduleCatchUpSyncAfterReconnectAt:[now dateByAddingTimeInterval:seconds]];
+  };
+  XCTAssertTrue(reconnectAfter(0));
+  XCTAssertFalse(reconnectAfter(899));
+  XCTAssertTrue(reconnectAfter(900));
+}
+
+#pragma mark - Tag Subscription Tests
+
+- (void)testUpdateTagsOnlyChangesAddedAndRemovedSubjects {
+  // Given: A connected client subscribed to the host subject and tags a and b
+  self.client = [[SNTPushClientNATS alloc] initWithSyncDelegate:self.mockSyncDelegate];
+  self.client.tags = @[ @"santa.host.ABC123", @"santa.tag.a", @"santa.tag.b" ];
+  for (NSString* subject in self.client.tags) {
+    self.client.tagSubscriptions[subject] = [NSValue valueWithPointer:NULL];
+  }
+
+  id partialClient = OCMPartialMock(self.client);
+  OCMStub([partialClient isConnectionAlive]).andReturn(YES);
+  NSMutableArray<NSString*>* unsubscribed = [NSMutableArray array];
+  NSMutableArray<NSString*>* subscribed = [NSMutableArray array];
+  OCMStub([partialClient unsubscribeFromTagSubject:[OCMArg any]])
+      .andDo(^(NSInvocation* invocation) {
+        __unsafe_unretained NSString* subject;
+        [invocation getArgument:&subject atIndex:2];
+        [unsubscribed addObject:subject];
+        [self.client.tagSubscriptions removeObjectForKey:subject];
+      });
+  OCMStub([partialClient subscribeToTagSubject:[OCMArg any]]).andDo(^(NSInvocation* invocation) {
+    __unsafe_unretained NSString* subject;
+    [invocation getArgument:&subject atIndex:2];
+    [subscribed addObject:subject];
+    self.client.tagSubscriptions[subject] = [NSValue valueWithPointer:NULL];
+  });
+
+  // When: The host is moved from tags {a, b} to {b, c}
+  [self.client updateTags:@[ @"santa.host.ABC123", @"santa.tag.b", @"santa.tag.c" ]];
+  dispatch_sync(self.client.connectionQueue, ^{});
+
+  // Then: Only a is unsubscribed from and only c is subscribed to
+  XCTAssertEqualObjects(unsubscribed, @[ @"santa.tag.a" ]);
+  XCTAssertEqualObjects(subscribed, @[ @"santa.tag.c" ]);
+  XCTAssertEqualObjects([NSSet setWithArray:self.client.tagSubscriptions.allKeys],
+                        (NSSet*)([NSSet setWithArray:@[
+                          @"santa.host.ABC123", @"santa.tag.b", @"santa.tag.c"
+                        ]]));
+  [partialClient stopMocking];
+}
+
+#pragma mark - SSL Certificate Domain Verification Tests
+
+- (void)testLeafCertHasPushDomain_validPushHost {
+  X509* cert = CreateCertWithDNSSAN("east1.push.northpole.security");
+  XCTAssertTrue(NATSLeafCertHasPushDomain(cert));
+  X509_free(cert);
+}
+
+- (void)testLeafCertHasPushDomain_otherValidPushHosts {
+  const char* hosts[] = {
+      "west3.push.northpole.security",
+      "central2.push.northpole.security",
+      "eu1.push.northpole.security",
+  };
+  for (const char* host : hosts) {
+    X509* cert = CreateCertWithDNSSAN(host);
+    XCTAssertTrue(NATSLeafCertHasPushDomain(cert), @"Expected match for %s", host);
+    X509_free(cert);
+  }
+}
+
+- (void)testLeafCertHasPushDomain_nonPushDomain_returnsFalse {
+  const char* hosts[] = {
+      "attacker.example.com",
+      "push.northpole.security",           // exact domain, no subdomain
+      "evil.push.northpole.security.com",  // suffix after the expected domain
+      "notpush.northpole.security",        // different subdomain of northpole.security
+  };
+  for (const char* host : hosts) {
+    X509* cert = CreateCertWithDNSSAN(host);
+    XCTAssertFalse(NATSLeafCertHasPushDomain(cert), @"Expected no match for %s", host);
+    X509_free(cert);
+  }
+}
+
+- (void)testLeafCertHasPushDomain_noSAN_returnsFalse {
+  X509* cert = X509_new();
+  XCTAssertFalse(NATSLeafCertHasPushDomain(cert));
+  X509_free(cert);
+}
+
+- (void)testLeafCertHasPushDomain_multipleSANs_oneMatches_returnsTrue {
+  // Build a cert with two SANs: one non-matching, one matching.
+  X509* cert = X509_new();
+
+  GENERAL_NAMES* gens = sk_GENERAL_NAME_new_null();
 
   auto addDNS = [&](const char* name) {
     GENERAL_NAME* gen = GENERAL_NAME_new();
@@ -835,4 +1991,487 @@ static X509* CreateCertWithDNSSANBytes(const char* data, size_t len) {
   X509_free(cert);
 }
 
+#pragma mark - Certificate Pinning Tests
+
+- (void)testCertSHA256 {
+  X509* cert = CreateSelfSignedPushServerCert();
+  XCTAssertNotEqual(cert, nullptr);
+
+  // The fingerprint is the lowercase hex SHA-256 of the DER encoding, as printed by
+  // `openssl x509 -noout -fingerprint -sha256` without the colons.
+  uint8_t* der = nullptr;
+  int derLen = i2d_X509(cert, &der);
+  uint8_t digest[SHA256_DIGEST_LENGTH];
+  SHA256(der, (size_t)derLen, digest);
+  OPENSSL_free(der);
+  NSMutableString* want = [NSMutableString string];
+  for (size_t i = 0; i < SHA256_DIGEST_LENGTH; ++i) {
+    [want appendFormat:@"%02x", digest[i]];
+  }
+
+  XCTAssertEqualObjects(NATSCertSHA256(cert), want);
+  XCTAssertNil(NATSCertSHA256(nullptr));
+  X509_free(cert);
+}
+
+- (void)testPinnedCertificateAccepted {
+  X509* cert = CreateSelfSignedPushServerCert();
+  OCMStub([self.mockConfigurator pushServerPinnedCertificateSHA256]).andReturn((@[
+    @"0000000000000000000000000000000000000000000000000000000000000000", NATSCertSHA256(cert)
+  ]));
+
+  XCTAssertTrue(VerifySelfSignedCertWithPushCallback(cert));
+  X509_free(cert);
+}
+
+- (void)testUnpinnedCertificateRejected {
+  X509* cert = CreateSelfSignedPushServerCert();
+  X509* otherCert = CreateSelfSignedPushServerCert();
+  OCMStub([self.mockConfigurator pushServerPinnedCertificateSHA256])
+      .andReturn(@[ NATSCertSHA256(otherCert) ]);
+
+  XCTAssertFalse(VerifySelfSignedCertWithPushCallback(cert));
+  X509_free(otherCert);
+  X509_free(cert);
+}
+
+- (void)testNoPinsAcceptsValidCertificate {
+  X509* cert = CreateSelfSignedPushServerCert();
+  XCTAssertTrue(VerifySelfSignedCertWithPushCallback(cert));
+  X509_free(cert);
+}
+
+#pragma mark - Server Certificate Expiry Tests
+
+- (void)testCertNotAfter {
+  X509* cert = CreatePushServerCertExpiringInDays(5);
+  int64_t want = (int64_t)[[NSDate dateWithTimeIntervalSinceNow:5 * 86400] timeIntervalSince1970];
+  XCTAssertEqualWithAccuracy(NATSCertNotAfter(cert), want, 60);
+  X509_free(cert);
+
+  XCTAssertEqual(NATSCertNotAfter(nullptr), 0);
+}
+
+- (void)testServerCertificateNearingExpiryWarns {
+  OCMStub([self.mockConfigurator pushServerCertificateExpiryWarningDays]).andReturn(30);
+  self.client = [[SNTPushClientNATS alloc] initWithSyncDelegate:self.mockSyncDelegate];
+
+  X509* cert = CreatePushServerCertExpiringInDays(5);
+  NSDate* notAfter = [NSDate dateWithTimeIntervalSince1970:NATSCertNotAfter(cert)];
+  X509_free(cert);
+
+  [self.client checkServerCertificateExpiry:notAfter];
+  XCTAssertTrue(self.client.serverCertificateExpiresSoon);
+  XCTAssertEqualObjects(self.client.serverCertificateNotAfter, notAfter);
+}
+
+- (void)testServerCertificateNotNearingExpiryDoesNotWarn {
+  OCMStub([self.mockConfigurator pushServerCertificateExpiryWarningDays]).andReturn(30);
+  self.client = [[SNTPushClientNATS alloc] initWithSyncDelegate:self.mockSyncDelegate];
+
+  X509* cert = CreatePushServerCertExpiringInDays(90);
+  NSDate* notAfter = [NSDate dateWithTimeIntervalSince1970:NATSCertNotAfter(cert)];
+  X509_free(cert);
+
+  [self.client checkServerCertificateExpiry:notAfter];
+  XCTAssertFalse(self.client.serverCertificateExpiresSoon);
+  XCTAssertEqualObjects(self.client.serverCertificateNotAfter, notAfter);
+}
+
+- (void)testServerCertificateExpiryWarningDisabled {
+  OCMStub([self.mockConfigurator pushServerCertificateExpiryWarningDays]).andReturn(0);
+  self.client = [[SNTPushClientNATS alloc] initWithSyncDelegate:self.mockSyncDelegate];
+
+  [self.client checkServerCertificateExpiry:[NSDate dateWithTimeIntervalSinceNow:86400]];
+  XCTAssertFalse(self.client.serverCertificateExpiresSoon);
+}
+
+#pragma mark - Decision Export Tests
+
+static SNTStoredExecutionEvent* ExportTestDecision(NSTimeInterval occurred,
+                                                   SNTEventState decision) {
+  SNTStoredExecutionEvent* se = [[SNTStoredExecutionEvent alloc] init];
+  se.occurrenceDate = [NSDate dateWithTimeIntervalSince1970:occurred];
+  se.filePath = [NSString stringWithFormat:@"/usr/local/bin/tool%.0f", occurred];
+  se.fileSHA256 = @"b7c1e3fd640c5f211c89b02c2c6122f78ce322aa5c56eb0bb54bc422a8f8b670";
+  se.decision = decision;
+  return se;
+}
+
+- (void)testHostMessageWithExportDecisionsTypePublishesDecisions {
+  self.client = [[SNTPushClientNATS alloc] initWithSyncDelegate:self.mockSyncDelegate];
+  id partialClient = OCMPartialMock(self.client);
+
+  NSArray* decisions = @[
+    ExportTestDecision(1700000200, SNTEventStateBlockBinary),
+    ExportTestDecision(1700000100, SNTEventStateAllowTeamID),
+  ];
+  OCMReject([self.mockSyncDelegate syncSecondsFromNow:0]).ignoringNonObjectArgs();
+  OCMExpect([self.mockSyncDelegate
+      exportDecisionsFrom:[NSDate dateWithTimeIntervalSince1970:1700000000]
+                       to:[NSDate dateWithTimeIntervalSince1970:1700000300]
+                    limit:500
+                    reply:([OCMArg invokeBlockWithArgs:decisions, nil])]);
+
+  XCTestExpectation* expectation = [self expectationWithDescription:@"decisions published"];
+  __block NSDictionary* response;
+  OCMStub([partialClient publishData:[OCMArg any] toSubject:@"_INBOX.export"])
+      .andDo(^(NSInvocation* invocation) {
+        __unsafe_unretained NSData* data;
+        [invocation getArgument:&data atIndex:2];
+        response = [NSJSONSerialization JSONObjectWithData:data options:0 error:nil];
+        [expectation fulfill];
+      });
+
+  [self.client handlePushNotificationForSubject:@"santa.host.ABC123"
+                                    withPayload:nil
+                                        headers:@{
+                                          kPushHeaderType : kPushTypeExportDecisions,
+                                          kPushHeaderExportDecisionsStart : @"1700000000",
+                                          kPushHeaderExportDecisionsEnd : @"1700000300",
+                                        }
+                                   replySubject:@"_INBOX.export"];
+
+  [self waitForExpectations:@[ expectation ] timeout:2.0];
+  OCMVerifyAll(self.mockSyncDelegate);
+
+  NSArray* got = response[@"decisions"];
+  XCTAssertEqual(got.count, 2);
+  XCTAssertEqualObjects(got[0][@"file_path"], @"/usr/local/bin/tool1700000200");
+  XCTAssertEqualObjects(got[0][@"decision"], @"block");
+  XCTAssertEqualObjects(got[0][@"occurrence_date"], @"2023-11-14T22:16:40Z");
+  XCTAssertEqualObjects(got[1][@"decision"], @"allow");
+  XCTAssertEqualObjects(response[@"truncated"], @NO);
+
+  [partialClient stopMocking];
+}
+
+- (void)testExportDecisionsIsRateLimited {
+  self.client = [[SNTPushClientNATS alloc] initWithSyncDelegate:self.mockSyncDelegate];
+  id partialClient = OCMPartialMock(self.client);
+  OCMStub([partialClient publishData:[OCMArg any] toSubject:[OCMArg any]]);
+
+  __block int exports = 0;
+  OCMStub([self.mockSyncDelegate exportDecisionsFrom:[OCMArg any]
+                                                  to:[OCMArg any]
+                                               limit:0
+                                               reply:[OCMArg any]])
+      .ignoringNonObjectArgs()
+      .andDo(^(NSInvocation* invocation) {
+        exports++;
+      });
+
+  NSDictionary* headers = @{
+    kPushHeaderType : kPushTypeExportDecisions,
+    kPushHeaderExportDecisionsStart : @"1700000000",
+  };
+  for (int i = 0; i < 3; i++) {
+    [self.client handlePushNotificationForSubject:@"santa.host.ABC123"
+                                      withPayload:nil
+                                          headers:headers
+                                     replySubject:@"_INBOX.export"];
+  }
+
+  // Wait for the message queue to drain.
+  XCTestExpectation* drained = [self expectationWithDescription:@"queue drained"];
+  dispatch_after(dispatch_time(DISPATCH_TIME_NOW, 500 * NSEC_PER_MSEC), dispatch_get_main_queue(),
+                 ^{
+                   [drained fulfill];
+                 });
+  [self waitForExpectations:@[ drained ] timeout:2.0];
+  XCTAssertEqual(exports, 1);
+
+  [partialClient stopMocking];
+}
+
+- (void)testExportDecisionsIgnoredOnTagSubjectOrWithoutReplySubject {
+  self.client = [[SNTPushClientNATS alloc] initWithSyncDelegate:self.mockSyncDelegate];
+
+  OCMReject([self.mockSyncDelegate exportDecisionsFrom:[OCMArg any]
+                                                    to:[OCMArg any]
+                                                 limit:0
+                                                 reply:[OCMArg any]])
+      .ignoringNonObjectArgs();
+  OCMReject([self.mockSyncDelegate syncSecondsFromNow:0]).ignoringNonObjectArgs();
+
+  NSDictionary* headers = @{
+    kPushHeaderType : kPushTypeExportDecisions,
+    kPushHeaderExportDecisionsStart : @"1700000000",
+  };
+  [self.client handlePushNotificationForSubject:@"santa.tag.global"
+                                    withPayload:nil
+                                        headers:headers
+                                   replySubject:@"_INBOX.export"];
+  [self.client handlePushNotificationForSubject:@"santa.host.ABC123"
+                                    withPayload:nil
+                                        headers:headers
+                                   replySubject:nil];
+
+  XCTestExpectation* drained = [self expectationWithDescription:@"queue drained"];
+  dispatch_after(dispatch_time(DISPATCH_TIME_NOW, 500 * NSEC_PER_MSEC), dispatch_get_main_queue(),
+                 ^{
+                   [drained fulfill];
+                 });
+  [self waitForExpectations:@[ drained ] timeout:2.0];
+  OCMVerifyAll(self.mockSyncDelegate);
+}
+
+- (void)testExportDecisionsResponseRespectsSizeBound {
+  NSMutableArray* decisions = [NSMutableArray array];
+  for (int i = 0; i < 100; i++) {
+    [decisions addObject:ExportTestDecision(1700000000 - i, SNTEventStateAllowBinary)];
+  }
+
+  NSData* unbounded = ExportDecisionsResponse(decisions, 1024 * 1024);
+  NSDictionary* all = [NSJSONSerialization JSONObjectWithData:unbounded options:0 error:nil];
+  XCTAssertEqual([all[@"decisions"] count], 100);
+  XCTAssertEqualObjects(all[@"truncated"], @NO);
+
+  NSData* bounded = ExportDecisionsResponse(decisions, 4096);
+  XCTAssertLessThanOrEqual(bounded.length, 4096);
+  NSDictionary* some = [NSJSONSerialization JSONObjectWithData:bounded options:0 error:nil];
+  NSArray* kept = some[@"decisions"];
+  XCTAssertGreaterThan(kept.count, 0);
+  XCTAssertLessThan(kept.count, 100);
+  XCTAssertEqualObjects(some[@"truncated"], @YES);
+  // The newest decisions are kept.
+  XCTAssertEqualObjects(kept.firstObject[@"file_path"], @"/usr/local/bin/tool1700000000");
+}
+
+#pragma mark - Inline Rules Tests
+
+- (void)generateKeyPairPublic:(NSData**)publicKey private:(NSData**)privateKey {
+  uint8_t pub[ED25519_PUBLIC_KEY_LEN], priv[ED25519_PRIVATE_KEY_LEN];
+  ED25519_keypair(pub, priv);
+  *publicKey = [NSData dataWithBytes:pub length:sizeof(pub)];
+  *privateKey = [NSData dataWithBytes:priv length:sizeof(priv)];
+}
+
+- (void)testInlineRulesFromSignedPayload {
+  NSData *publicKey, *privateKey;
+  [self generateKeyPairPublic:&publicKey private:&privateKey];
+
+  NSData* payload = santa::CreateSignedRuleBundle(
+      InlineRulesBody(@[ @"EQHXZ8M8AV", @"ABCDEFGHIJ" ]), privateKey, NULL);
+  NSError* error;
+  NSString* bundleID;
+  NSDate* expirationDate;
+  NSArray<SNTRule*>* rules = InlineRulesFromPushPayload(payload, publicKey, [NSDate date],
+                                                        &bundleID, &expirationDate, &error);
+  XCTAssertNil(error);
+  XCTAssertNotNil(bundleID);
+  XCTAssertGreaterThan([expirationDate timeIntervalSinceNow], 0);
+  XCTAssertEqual(rules.count, 2);
+  XCTAssertEqualObjects(rules[0].identifier, @"EQHXZ8M8AV");
+  XCTAssertEqual(rules[0].state, SNTRuleStateBlock);
+  XCTAssertEqual(rules[0].type, SNTRuleTypeTeamID);
+  XCTAssertEqualObjects(rules[1].identifier, @"ABCDEFGHIJ");
+}
+
+- (void)testInlineRulesRejectsUnsignedOrWronglySigned {
+  NSData *publicKey, *privateKey, *otherPublicKey, *otherPrivateKey;
+  [self generateKeyPairPublic:&publicKey private:&privateKey];
+  [self generateKeyPairPublic:&otherPublicKey private:&otherPrivateKey];
+
+  NSError* error;
+  // The bare JSON, without a signature.
+  XCTAssertNil(InlineRulesFromPushPayload(InlineRulesBody(@[ @"EQHXZ8M8AV" ]), publicKey,
+                                          [NSDate date], NULL, NULL, &error));
+  XCTAssertNotNil(error);
+
+  // Signed by a different key.
+  error = nil;
+  NSData* payload =
+      santa::CreateSignedRuleBundle(InlineRulesBody(@[ @"EQHXZ8M8AV" ]), otherPrivateKey, NULL);
+  XCTAssertNil(InlineRulesFromPushPayload(payload, publicKey, [NSDate date], NULL, NULL, &error));
+  XCTAssertNotNil(error);
+
+  // No key configured.
+  error = nil;
+  payload = santa::CreateSignedRuleBundle(InlineRulesBody(@[ @"EQHXZ8M8AV" ]), privateKey, NULL);
+  XCTAssertNil(InlineRulesFromPushPayload(payload, nil, [NSDate date], NULL, NULL, &error));
+  XCTAssertEqual(error.code, SNTErrorCodeFailedToVerifySignature);
+}
+
+- (void)testInlineRulesRejectsOversizedPayloads {
+  NSData *publicKey, *privateKey;
+  [self generateKeyPairPublic:&publicKey private:&privateKey];
+
+  // Too many rules, even though the payload is small.
+  NSMutableArray* teamIDs = [NSMutableArray array];
+  for (int i = 0; i < 51; i++) {
+    [teamIDs addObject:[NSString stringWithFormat:@"TEAMID%04d", i]];
+  }
+  NSData* payload = santa::CreateSignedRuleBundle(InlineRulesBody(teamIDs), privateKey, NULL);
+  XCTAssertLessThan(payload.length, 16 * 1024);
+  NSError* error;
+  XCTAssertNil(InlineRulesFromPushPayload(payload, publicKey, [NSDate date], NULL, NULL, &error));
+  XCTAssertNotNil(error);
+
+  // Too large, even though it holds a single validly signed rule.
+  NSDictionary* rule = @{
+    @"identifier" : @"EQHXZ8M8AV",
+    @"policy" : @"BLOCKLIST",
+    @"rule_type" : @"TEAMID",
+    @"custom_msg" : [@"" stringByPaddingToLength:16 * 1024 withString:@"x" startingAtIndex:0],
+  };
+  payload = santa::CreateSignedRuleBundle(
+      [NSJSONSerialization dataWithJSONObject:@{@"rules" : @[ rule ]} options:0 error:nil],
+      privateKey, NULL);
+  error = nil;
+  XCTAssertNil(InlineRulesFromPushPayload(payload, publicKey, [NSDate date], NULL, NULL, &error));
+  XCTAssertNotNil(error);
+
+  // No rules at all.
+  payload = santa::CreateSignedRuleBundle(InlineRulesBody(@[]), privateKey, NULL);
+  error = nil;
+  XCTAssertNil(InlineRulesFromPushPayload(payload, publicKey, [NSDate date], NULL, NULL, &error));
+  XCTAssertNotNil(error);
+}
+
+- (void)testInlineRulesRequiresPurposeAndValidityClaims {
+  NSData *publicKey, *privateKey;
+  [self generateKeyPairPublic:&publicKey private:&privateKey];
+  NSTimeInterval now = [[NSDate date] timeIntervalSince1970];
+  NSData* (^bundle)(NSDictionary*) = ^NSData*(NSDictionary* claims) {
+    return santa::CreateSignedRuleBundle(InlineRulesBodyWithClaims(@[ @"EQHXZ8M8AV" ], claims),
+                                         privateKey, NULL);
+  };
+  NSDictionary* valid =
+      @{@"purpose" : @"apply_rules", @"jti" : @"a", @"iat" : @(now), @"exp" : @(now + 600)};
+  XCTAssertNotNil(InlineRulesFromPushPayload(bundle(valid), publicKey, [NSDate date], NULL, NULL,
+                                             NULL));
+
+  NSArray<NSDictionary*>* invalid = @[
+    // A bundle signed for `santactl rule --import`, or another use.
+    @{},
+    @{@"purpose" : @"rule_import", @"jti" : @"a", @"iat" : @(now), @"exp" : @(now + 600)},
+    // Missing claims.
+    @{@"purpose" : @"apply_rules", @"iat" : @(now), @"exp" : @(now + 600)},
+    @{@"purpose" : @"apply_rules", @"jti" : @"a", @"exp" : @(now + 600)},
+    @{@"purpose" : @"apply_rules", @"jti" : @"a", @"iat" : @(now)},
+    // Expired, not yet issued or valid for too long.
+    @{@"purpose" : @"apply_rules", @"jti" : @"a", @"iat" : @(now - 600), @"exp" : @(now - 1)},
+    @{@"purpose" : @"apply_rules", @"jti" : @"a", @"iat" : @(now + 300), @"exp" : @(now + 600)},
+    @{@"purpose" : @"apply_rules", @"jti" : @"a", @"iat" : @(now), @"exp" : @(now + 86400)},
+  ];
+  for (NSDictionary* claims in invalid) {
+    NSError* error;
+    XCTAssertNil(InlineRulesFromPushPayload(bundle(claims), publicKey, [NSDate date], NULL, NULL,
+                                            &error),
+                 @"%@", claims);
+    XCTAssertNotNil(error);
+  }
+}
+
+- (void)testApplyRulesMessageAddsVerifiedRules {
+  NSData *publicKey, *privateKey;
+  [self generateKeyPairPublic:&publicKey private:&privateKey];
+  OCMStub([self.mockConfigurator pushInlineRulesPublicKey]).andReturn(publicKey);
+
+  id daemonConn = OCMClassMock([MOLXPCConnection class]);
+  id rop = OCMProtocolMock(@protocol(SNTDaemonControlXPC));
+  OCMStub([daemonConn remoteObjectProxy]).andReturn(rop);
+  OCMStub([self.mockSyncDelegate daemonConnection]).andReturn(daemonConn);
+  OCMReject([self.mockSyncDelegate syncSecondsFromNow:0]).ignoringNonObjectArgs();
+  OCMStub([rop redeemPushRuleBundleID:[OCMArg any]
+                       expirationDate:[OCMArg any]
+                                reply:([OCMArg invokeBlockWithArgs:[NSNull null], nil])]);
+
+  XCTestExpectation* expectation = [self expectationWithDescription:@"rules added"];
+  OCMStub([rop databaseRuleAddExecutionRules:[OCMArg checkWithBlock:^BOOL(NSArray* rules) {
+                                               return rules.count == 1 &&
+                                                      [[rules[0] identifier]
+                                                          isEqualToString:@"EQHXZ8M8AV"];
+                                             }]
+                             fileAccessRules:nil
+                            networkFlowRules:nil
+                                     signals:nil
+                                 ruleCleanup:SNTRuleCleanupNone
+                                      source:SNTRuleAddSourceSyncService
+                                       reply:[OCMArg any]])
+      .andDo(^(NSInvocation* invocation) {
+        [expectation fulfill];
+      });
+
+  self.client = [[SNTPushClientNATS alloc] initWithSyncDelegate:self.mockSyncDelegate];
+  NSData* payload =
+      santa::CreateSignedRuleBundle(InlineRulesBody(@[ @"EQHXZ8M8AV" ]), privateKey, NULL);
+  [self.client handlePushNotificationForSubject:@"santa.tag.global"
+                                    withPayload:payload
+                                        headers:@{kPushHeaderType : kPushTypeApplyRules}];
+
+  // The rules are added directly, without a sync.
+  [self waitForExpectations:@[ expectation ] timeout:2.0];
+  OCMVerifyAll(self.mockSyncDelegate);
+}
+
+- (void)testApplyRulesMessageRejectsUnsignedPayload {
+  NSData *publicKey, *privateKey;
+  [self generateKeyPairPublic:&publicKey private:&privateKey];
+  OCMStub([self.mockConfigurator pushInlineRulesPublicKey]).andReturn(publicKey);
+
+  id daemonConn = OCMClassMock([MOLXPCConnection class]);
+  id rop = OCMStrictProtocolMock(@protocol(SNTDaemonControlXPC));
+  OCMStub([daemonConn remoteObjectProxy]).andReturn(rop);
+  OCMStub([self.mockSyncDelegate daemonConnection]).andReturn(daemonConn);
+
+  self.client = [[SNTPushClientNATS alloc] initWithSyncDelegate:self.mockSyncDelegate];
+  [self.client handlePushNotificationForSubject:@"santa.host.ABC123"
+                                    withPayload:InlineRulesBody(@[ @"EQHXZ8M8AV" ])
+                                        headers:@{kPushHeaderType : kPushTypeApplyRules}];
+
+  // Messages are handled in order, so once a following message triggers a sync the rejected
+  // payload has been handled.
+  XCTestExpectation* expectation = [self expectationWithDescription:@"syncSecondsFromNow called"];
+  OCMStub([self.mockSyncDelegate syncSecondsFromNow:0])
+      .ignoringNonObjectArgs()
+      .andDo(^(NSInvocation* invocation) {
+        [expectation fulfill];
+      });
+  [self.client handlePushNotificationForSubject:@"santa.host.ABC123" withPayload:nil];
+
+  [self waitForExpectations:@[ expectation ] timeout:2.0];
+  OCMVerifyAll(rop);
+}
+
+- (void)testApplyRulesMessageRejectsReplayedBundle {
+  NSData *publicKey, *privateKey;
+  [self generateKeyPairPublic:&publicKey private:&privateKey];
+  OCMStub([self.mockConfigurator pushInlineRulesPublicKey]).andReturn(publicKey);
+
+  id daemonConn = OCMClassMock([MOLXPCConnection class]);
+  id rop = OCMProtocolMock(@protocol(SNTDaemonControlXPC));
+  OCMStub([daemonConn remoteObjectProxy]).andReturn(rop);
+  OCMStub([self.mockSyncDelegate daemonConnection]).andReturn(daemonConn);
+
+  // santad has already seen this bundle.
+  XCTestExpectation* expectation = [self expectationWithDescription:@"bundle redeemed"];
+  NSError* used = [NSError errorWithDomain:@"test" code:1 userInfo:nil];
+  OCMStub([rop redeemPushRuleBundleID:[OCMArg any]
+                       expirationDate:[OCMArg any]
+                                reply:([OCMArg invokeBlockWithArgs:used, nil])])
+      .andDo(^(NSInvocation* invocation) {
+        [expectation fulfill];
+      });
+  OCMReject([rop databaseRuleAddExecutionRules:[OCMArg any]
+                               fileAccessRules:[OCMArg any]
+                              networkFlowRules:[OCMArg any]
+                                       signals:[OCMArg any]
+                                   ruleCleanup:SNTRuleCleanupNone
+                                        source:SNTRuleAddSourceSyncService
+                                         reply:[OCMArg any]])
+      .ignoringNonObjectArgs();
+
+  self.client = [[SNTPushClientNATS alloc] initWithSyncDelegate:self.mockSyncDelegate];
+  NSData* payload =
+      santa::CreateSignedRuleBundle(InlineRulesBody(@[ @"EQHXZ8M8AV" ]), privateKey, NULL);
+  [self.client handlePushNotificationForSubject:@"santa.tag.global"
+                                    withPayload:payload
+                                        headers:@{kPushHeaderType : kPushTypeApplyRules}];
+
+  [self waitForExpectations:@[ expectation ] timeout:2.0];
+  OCMVerifyAll(rop);
+}
+
 @end
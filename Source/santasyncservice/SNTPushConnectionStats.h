@@ -0,0 +1,55 @@
+/// Copyright 2026 North Pole Security, Inc.
+///
+/// Licensed under the Apache License, Version 2.0 (the "License");
+/// you may not use this file except in compliance with the License.
+/// You may obtain a copy of the License at
+///
+///     http://www.apache.org/licenses/LICENSE-2.0
+///
+/// Unless required by applicable law or agreed to in writing, software
+/// distributed under the License is distributed on an "AS IS" BASIS,
+/// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+/// See the License for the specific language governing permissions and
+/// limitations under the License.
+
+#import <Foundation/Foundation.h>
+
+@class SNTMetricSet;
+
+/// The number of recent push latencies the percentiles in the stats are computed over.
+extern const NSUInteger kPushLatencySampleCount;
+
+NS_ASSUME_NONNULL_BEGIN
+
+///
+///  Counters describing the traffic on the push client's connection since the sync service
+///  started. All methods are thread-safe.
+///
+@interface SNTPushConnectionStats : NSObject
+
+///
+///  If metricSet is not nil, every update is also recorded in the /santa/sync/push/ metrics of
+///  that set so it is exported with the sync service's other metrics.
+///
+- (instancetype)initWithMetricSet:(nullable SNTMetricSet*)metricSet;
+
+- (void)recordSubscribeToSubject:(NSString*)subject;
+- (void)recordMessageOnSubject:(NSString*)subject bytes:(NSUInteger)bytes;
+- (void)recordPublishOfBytes:(NSUInteger)bytes;
+- (void)recordReconnect;
+- (void)recordRTT:(NSTimeInterval)rtt;
+
+///
+///  Record the time a push message took from being published to being received. Percentiles are
+///  computed over the most recent kPushLatencySampleCount latencies.
+///
+- (void)recordPushLatency:(NSTimeInterval)latency;
+
+///
+///  A snapshot of the counters keyed by the kPushStats* constants in SNTSyncConstants.h.
+///
+- (NSDictionary*)export;
+
+@end
+
+NS_ASSUME_NONNULL_END
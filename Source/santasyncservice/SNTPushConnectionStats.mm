@@ -0,0 +1,175 @@
+/// Copyright 2026 North Pole Security, Inc.
+///
+/// Licensed under the Apache License, Version 2.0 (the "License");
+/// you may not use this file except in compliance with the License.
+/// You may obtain a copy of the License at
+///
+///     http://www.apache.org/licenses/LICENSE-2.0
+///
+/// Unless required by applicable law or agreed to in writing, software
+/// distributed under the License is distributed on an "AS IS" BASIS,
+/// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+/// See the License for the specific language governing permissions and
+/// limitations under the License.
+
+#import "Source/santasyncservice/SNTPushConnectionStats.h"
+
+#include <math.h>
+
+#import "Source/common/SNTMetricSet.h"
+#import "Source/common/SNTSyncConstants.h"
+
+const NSUInteger kPushLatencySampleCount = 1000;
+
+@interface SNTPushConnectionStats ()
+@property uint64_t bytesIn;
+@property uint64_t bytesOut;
+@property uint64_t subscribes;
+@property uint64_t reconnects;
+@property NSMutableDictionary<NSString*, NSNumber*>* messagesBySubject;
+@property NSNumber* lastRTTMs;
+@property NSDate* lastRTTTime;
+@property uint64_t latencyCount;
+@property double maxLatencyMs;
+// The most recent latencies in milliseconds, oldest first.
+@property NSMutableArray<NSNumber*>* latencySamplesMs;
+
+@property SNTMetricCounter* bytesInCounter;
+@property SNTMetricCounter* bytesOutCounter;
+@property SNTMetricCounter* subscribesCounter;
+@property SNTMetricCounter* reconnectsCounter;
+@property SNTMetricCounter* messagesCounter;
+@property SNTMetricDoubleGauge* rttGauge;
+@property SNTMetricDoubleGauge* latencyGauge;
+@end
+
+@implementation SNTPushConnectionStats
+
+- (instancetype)initWithMetricSet:(SNTMetricSet*)metricSet {
+  self = [super init];
+  if (self) {
+    _messagesBySubject = [NSMutableDictionary dictionary];
+    _latencySamplesMs = [NSMutableArray arrayWithCapacity:kPushLatencySampleCount];
+
+    if (metricSet) {
+      _bytesInCounter = [metricSet counterWithName:@"/santa/sync/push/bytes_in"
+                                        fieldNames:@[]
+                                          helpText:@"Bytes of push message payloads received"];
+      _bytesOutCounter = [metricSet counterWithName:@"/santa/sync/push/bytes_out"
+                                         fieldNames:@[]
+                                           helpText:@"Bytes of payloads published by the push "
+                                                    @"client"];
+      _subscribesCounter = [metricSet counterWithName:@"/santa/sync/push/subscribes"
+                                           fieldNames:@[]
+                                             helpText:@"Number of push subscriptions made"];
+      _reconnectsCounter = [metricSet counterWithName:@"/santa/sync/push/reconnects"
+                                           fieldNames:@[]
+                                             helpText:@"Number of times the push client "
+                                                      @"reconnected to the push server"];
+      _messagesCounter = [metricSet counterWithName:@"/santa/sync/push/messages"
+                                         fieldNames:@[ @"subject" ]
+                                           helpText:@"Number of push messages received"];
+      _rttGauge = [metricSet doubleGaugeWithName:@"/santa/sync/push/last_rtt_ms"
+                                      fieldNames:@[]
+                                        helpText:@"Last measured round trip time to the push "
+                                                 @"server in milliseconds"];
+      _latencyGauge = [metricSet doubleGaugeWithName:@"/santa/sync/push/latency_ms"
+                                          fieldNames:@[ @"quantile" ]
+                                            helpText:@"Time from a push message being published "
+                                                     @"to it being received in milliseconds"];
+    }
+  }
+  return self;
+}
+
+- (void)recordSubscribeToSubject:(NSString*)subject {
+  @synchronized(self) {
+    self.subscribes++;
+  }
+  [self.subscribesCounter incrementForFieldValues:@[]];
+}
+
+- (void)recordMessageOnSubject:(NSString*)subject bytes:(NSUInteger)bytes {
+  @synchronized(self) {
+    self.bytesIn += bytes;
+    self.messagesBySubject[subject] = @(self.messagesBySubject[subject].unsignedLongLongValue + 1);
+  }
+  [self.bytesInCounter incrementBy:bytes forFieldValues:@[]];
+  [self.messagesCounter incrementForFieldValues:@[ subject ]];
+}
+
+- (void)recordPublishOfBytes:(NSUInteger)bytes {
+  @synchronized(self) {
+    self.bytesOut += bytes;
+  }
+  [self.bytesOutCounter incrementBy:bytes forFieldValues:@[]];
+}
+
+- (void)recordReconnect {
+  @synchronized(self) {
+    self.reconnects++;
+  }
+  [self.reconnectsCounter incrementForFieldValues:@[]];
+}
+
+- (void)recordRTT:(NSTimeInterval)rtt {
+  double ms = rtt * 1000;
+  @synchronized(self) {
+    self.lastRTTMs = @(ms);
+    self.lastRTTTime = [NSDate date];
+  }
+  [self.rttGauge set:ms forFieldValues:@[]];
+}
+
+- (void)recordPushLatency:(NSTimeInterval)latency {
+  double ms = latency * 1000;
+  double p50, p95, max;
+  @synchronized(self) {
+    self.latencyCount++;
+    self.maxLatencyMs = MAX(self.maxLatencyMs, ms);
+    if (self.latencySamplesMs.count == kPushLatencySampleCount) {
+      [self.latencySamplesMs removeObjectAtIndex:0];
+    }
+    [self.latencySamplesMs addObject:@(ms)];
+    p50 = [self latencyPercentile:0.5];
+    p95 = [self latencyPercentile:0.95];
+    max = self.maxLatencyMs;
+  }
+  [self.latencyGauge set:p50 forFieldValues:@[ @"p50" ]];
+  [self.latencyGauge set:p95 forFieldValues:@[ @"p95" ]];
+  [self.latencyGauge set:max forFieldValues:@[ @"max" ]];
+}
+
+// Nearest-rank percentile of the latency samples. Must be called while synchronized on self, with
+// at least one sample.
+- (double)latencyPercentile:(double)percentile {
+  NSArray<NSNumber*>* sorted =
+      [self.latencySamplesMs sortedArrayUsingSelector:@selector(compare:)];
+  NSUInteger rank = (NSUInteger)ceil(percentile * sorted.count);
+  return sorted[MAX(rank, 1) - 1].doubleValue;
+}
+
+- (NSDictionary*)export {
+  @synchronized(self) {
+    NSMutableDictionary* stats = [@{
+      kPushStatsBytesIn : @(self.bytesIn),
+      kPushStatsBytesOut : @(self.bytesOut),
+      kPushStatsSubscribes : @(self.subscribes),
+      kPushStatsReconnects : @(self.reconnects),
+      kPushStatsMessagesBySubject : [self.messagesBySubject copy],
+    } mutableCopy];
+    if (self.lastRTTTime) {
+      stats[kPushStatsLastRTTMs] = self.lastRTTMs;
+      stats[kPushStatsLastRTTTime] = @(self.lastRTTTime.timeIntervalSince1970);
+    }
+    if (self.latencyCount) {
+      stats[kPushStatsLatencyCount] = @(self.latencyCount);
+      stats[kPushStatsLatencyP50Ms] = @([self latencyPercentile:0.5]);
+      stats[kPushStatsLatencyP95Ms] = @([self latencyPercentile:0.95]);
+      stats[kPushStatsLatencyMaxMs] = @(self.maxLatencyMs);
+    }
+    return stats;
+  }
+}
+
+@end
@@ -0,0 +1,151 @@
+/// Copyright 2026 North Pole Security, Inc.
+///
+/// Licensed under the Apache License, Version 2.0 (the "License");
+/// you may not use this file except in compliance with the License.
+/// You may obtain a copy of the License at
+///
+///     http://www.apache.org/licenses/LICENSE-2.0
+///
+/// Unless required by applicable law or agreed to in writing, software
+/// distributed under the License is distributed on an "AS IS" BASIS,
+/// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+/// See the License for the specific language governing permissions and
+/// limitations under the License.
+
+#import <XCTest/XCTest.h>
+
+#import "Source/common/SNTMetricSet.h"
+#import "Source/common/SNTSyncConstants.h"
+#import "Source/santasyncservice/SNTPushConnectionStats.h"
+
+@interface SNTPushConnectionStatsTest : XCTestCase
+@end
+
+@implementation SNTPushConnectionStatsTest
+
+- (void)testInitialExport {
+  NSDictionary* stats = [[[SNTPushConnectionStats alloc] initWithMetricSet:nil] export];
+  XCTAssertEqualObjects(stats[kPushStatsBytesIn], @0);
+  XCTAssertEqualObjects(stats[kPushStatsBytesOut], @0);
+  XCTAssertEqualObjects(stats[kPushStatsSubscribes], @0);
+  XCTAssertEqualObjects(stats[kPushStatsReconnects], @0);
+  XCTAssertEqualObjects(stats[kPushStatsMessagesBySubject], @{});
+  XCTAssertNil(stats[kPushStatsLastRTTMs]);
+  XCTAssertNil(stats[kPushStatsLastRTTTime]);
+}
+
+- (void)testCountersUpdate {
+  SNTPushConnectionStats* sut = [[SNTPushConnectionStats alloc] initWithMetricSet:nil];
+
+  [sut recordSubscribeToSubject:@"santa.tag.global"];
+  [sut recordSubscribeToSubject:@"santa.host.ABC123.commands"];
+  XCTAssertEqualObjects([sut export][kPushStatsSubscribes], @2);
+
+  [sut recordMessageOnSubject:@"santa.tag.global" bytes:10];
+  [sut recordMessageOnSubject:@"santa.tag.global" bytes:5];
+  [sut recordMessageOnSubject:@"santa.host.ABC123.commands" bytes:0];
+  NSDictionary* stats = [sut export];
+  XCTAssertEqualObjects(stats[kPushStatsBytesIn], @15);
+  XCTAssertEqualObjects(stats[kPushStatsMessagesBySubject],
+                        (@{@"santa.tag.global" : @2, @"santa.host.ABC123.commands" : @1}));
+
+  [sut recordPublishOfBytes:42];
+  XCTAssertEqualObjects([sut export][kPushStatsBytesOut], @42);
+
+  [sut recordReconnect];
+  [sut recordReconnect];
+  XCTAssertEqualObjects([sut export][kPushStatsReconnects], @2);
+
+  NSDate* before = [NSDate date];
+  [sut recordRTT:0.0125];
+  stats = [sut export];
+  XCTAssertEqualWithAccuracy([stats[kPushStatsLastRTTMs] doubleValue], 12.5, 0.001);
+  XCTAssertGreaterThanOrEqual([stats[kPushStatsLastRTTTime] doubleValue],
+                              floor(before.timeIntervalSince1970));
+
+  // Exports are snapshots, later updates don't change them.
+  [sut recordMessageOnSubject:@"santa.tag.global" bytes:1];
+  XCTAssertEqualObjects(stats[kPushStatsMessagesBySubject][@"santa.tag.global"], @2);
+}
+
+- (void)testPushLatencyPercentiles {
+  SNTPushConnectionStats* sut = [[SNTPushConnectionStats alloc] initWithMetricSet:nil];
+  XCTAssertNil([sut export][kPushStatsLatencyCount]);
+
+  // 1ms to 100ms, shuffled so the order of samples doesn't matter.
+  NSMutableArray<NSNumber*>* latencies = [NSMutableArray array];
+  for (int ms = 1; ms <= 100; ms++) {
+    [latencies insertObject:@(ms) atIndex:arc4random_uniform((uint32_t)latencies.count + 1)];
+  }
+  for (NSNumber* ms in latencies) {
+    [sut recordPushLatency:ms.doubleValue / 1000];
+  }
+
+  NSDictionary* stats = [sut export];
+  XCTAssertEqualObjects(stats[kPushStatsLatencyCount], @100);
+  XCTAssertEqualWithAccuracy([stats[kPushStatsLatencyP50Ms] doubleValue], 50, 0.001);
+  XCTAssertEqualWithAccuracy([stats[kPushStatsLatencyP95Ms] doubleValue], 95, 0.001);
+  XCTAssertEqualWithAccuracy([stats[kPushStatsLatencyMaxMs] doubleValue], 100, 0.001);
+
+  // A single sample is every percentile.
+  sut = [[SNTPushConnectionStats alloc] initWithMetricSet:nil];
+  [sut recordPushLatency:0.25];
+  stats = [sut export];
+  XCTAssertEqualObjects(stats[kPushStatsLatencyCount], @1);
+  XCTAssertEqualWithAccuracy([stats[kPushStatsLatencyP50Ms] doubleValue], 250, 0.001);
+  XCTAssertEqualWithAccuracy([stats[kPushStatsLatencyP95Ms] doubleValue], 250, 0.001);
+  XCTAssertEqualWithAccuracy([stats[kPushStatsLatencyMaxMs] doubleValue], 250, 0.001);
+}
+
+- (void)testPushLatencyPercentilesUseRecentSamples {
+  SNTPushConnectionStats* sut = [[SNTPushConnectionStats alloc] initWithMetricSet:nil];
+  [sut recordPushLatency:10];
+  for (NSUInteger i = 0; i < kPushLatencySampleCount; i++) {
+    [sut recordPushLatency:0.001];
+  }
+
+  // The slow message has aged out of the percentiles but is still the maximum.
+  NSDictionary* stats = [sut export];
+  XCTAssertEqualObjects(stats[kPushStatsLatencyCount], @(kPushLatencySampleCount + 1));
+  XCTAssertEqualWithAccuracy([stats[kPushStatsLatencyP95Ms] doubleValue], 1, 0.001);
+  XCTAssertEqualWithAccuracy([stats[kPushStatsLatencyMaxMs] doubleValue], 10000, 0.001);
+}
+
+- (void)testMetricsRecordedWhenMetricSetGiven {
+  SNTMetricSet* metricSet = [[SNTMetricSet alloc] init];
+  SNTPushConnectionStats* sut = [[SNTPushConnectionStats alloc] initWithMetricSet:metricSet];
+
+  [sut recordSubscribeToSubject:@"santa.tag.global"];
+  [sut recordMessageOnSubject:@"santa.tag.global" bytes:7];
+  [sut recordPublishOfBytes:3];
+  [sut recordReconnect];
+  [sut recordRTT:0.02];
+  [sut recordPushLatency:0.03];
+
+  NSDictionary* metrics = [metricSet export][@"metrics"];
+  XCTAssertEqualObjects(metrics[@"/santa/sync/push/subscribes"][@"fields"][@""][0][@"data"], @1);
+  XCTAssertEqualObjects(metrics[@"/santa/sync/push/bytes_in"][@"fields"][@""][0][@"data"], @7);
+  XCTAssertEqualObjects(metrics[@"/santa/sync/push/bytes_out"][@"fields"][@""][0][@"data"], @3);
+  XCTAssertEqualObjects(metrics[@"/santa/sync/push/reconnects"][@"fields"][@""][0][@"data"], @1);
+  NSDictionary* message = metrics[@"/santa/sync/push/messages"][@"fields"][@"subject"][0];
+  XCTAssertEqualObjects(message[@"value"], @"santa.tag.global");
+  XCTAssertEqualObjects(message[@"data"], @1);
+  XCTAssertEqualWithAccuracy(
+      [metrics[@"/santa/sync/push/last_rtt_ms"][@"fields"][@""][0][@"data"] doubleValue], 20,
+      0.001);
+  NSArray* latencies = metrics[@"/santa/sync/push/latency_ms"][@"fields"][@"quantile"];
+  XCTAssertEqualObjects([NSSet setWithArray:[latencies valueForKey:@"value"]],
+                        (NSSet*)([NSSet setWithArray:@[ @"p50", @"p95", @"max" ]]));
+  for (NSDictionary* latency in latencies) {
+    XCTAssertEqualWithAccuracy([latency[@"data"] doubleValue], 30, 0.001);
+  }
+}
+
+- (void)testNoMetricsWithoutMetricSet {
+  SNTMetricSet* metricSet = [[SNTMetricSet alloc] init];
+  SNTPushConnectionStats* sut = [[SNTPushConnectionStats alloc] initWithMetricSet:nil];
+  [sut recordMessageOnSubject:@"santa.tag.global" bytes:7];
+  XCTAssertNil([metricSet export][@"metrics"][@"/santa/sync/push/messages"]);
+}
+
+@end
@@ -0,0 +1,49 @@
+/// Copyright 2026 North Pole Security, Inc.
+///
+/// Licensed under the Apache License, Version 2.0 (the "License");
+/// you may not use this file except in compliance with the License.
+/// You may obtain a copy of the License at
+///
+///     http://www.apache.org/licenses/LICENSE-2.0
+///
+/// Unless required by applicable law or agreed to in writing, software
+/// distributed under the License is distributed on an "AS IS" BASIS,
+/// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+/// See the License for the specific language governing permissions and
+/// limitations under the License.
+
+#import <Foundation/Foundation.h>
+#include <os/log.h>
+
+NS_ASSUME_NONNULL_BEGIN
+
+/// Keys for the structured fields attached to push client log messages.
+extern NSString* const kPushLogFieldServer;
+extern NSString* const kPushLogFieldMachineID;
+extern NSString* const kPushLogFieldSubject;
+extern NSString* const kPushLogFieldError;
+extern NSString* const kPushLogFieldCategory;
+extern NSString* const kPushLogFieldAttempt;
+extern NSString* const kPushLogFieldDelaySeconds;
+
+/// Receives the push client's connection, subscription and error log messages.
+/// message is a fixed description of the event; the details that vary between
+/// occurrences, e.g. the server, are passed in fields instead. May be called on
+/// any queue.
+@protocol SNTPushLogger <NSObject>
+- (void)logWithType:(os_log_type_t)type
+            message:(NSString*)message
+             fields:(NSDictionary<NSString*, id>*)fields;
+@end
+
+/// The default push logger. Writes each message to the system log followed by
+/// its fields as key=value pairs.
+@interface SNTPushOSLogger : NSObject <SNTPushLogger>
+
+/// The line logged for message and fields. Fields are sorted by key and values
+/// containing spaces, quotes or '=' are quoted.
++ (NSString*)lineForMessage:(NSString*)message fields:(NSDictionary<NSString*, id>*)fields;
+
+@end
+
+NS_ASSUME_NONNULL_END
@@ -0,0 +1,57 @@
+/// Copyright 2026 North Pole Security, Inc.
+///
+/// Licensed under the Apache License, Version 2.0 (the "License");
+/// you may not use this file except in compliance with the License.
+/// You may obtain a copy of the License at
+///
+///     http://www.apache.org/licenses/LICENSE-2.0
+///
+/// Unless required by applicable law or agreed to in writing, software
+/// distributed under the License is distributed on an "AS IS" BASIS,
+/// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+/// See the License for the specific language governing permissions and
+/// limitations under the License.
+
+#import "Source/santasyncservice/SNTPushLogger.h"
+
+#import "Source/common/SNTLogging.h"
+
+NSString* const kPushLogFieldServer = @"server";
+NSString* const kPushLogFieldMachineID = @"machine_id";
+NSString* const kPushLogFieldSubject = @"subject";
+NSString* const kPushLogFieldError = @"error";
+NSString* const kPushLogFieldCategory = @"category";
+NSString* const kPushLogFieldAttempt = @"attempt";
+NSString* const kPushLogFieldDelaySeconds = @"delay_seconds";
+
+@implementation SNTPushOSLogger
+
++ (NSString*)lineForMessage:(NSString*)message fields:(NSDictionary<NSString*, id>*)fields {
+  static NSCharacterSet* needsQuoting;
+  static dispatch_once_t onceToken;
+  dispatch_once(&onceToken, ^{
+    NSMutableCharacterSet* set = [NSMutableCharacterSet whitespaceAndNewlineCharacterSet];
+    [set addCharactersInString:@"\"="];
+    needsQuoting = [set copy];
+  });
+
+  NSMutableString* line = [message mutableCopy];
+  for (NSString* key in [fields.allKeys sortedArrayUsingSelector:@selector(compare:)]) {
+    NSString* value = [fields[key] description];
+    if (!value.length || [value rangeOfCharacterFromSet:needsQuoting].location != NSNotFound) {
+      value = [NSString
+          stringWithFormat:@"\"%@\"", [value stringByReplacingOccurrencesOfString:@"\""
+                                                                       withString:@"\\\""]];
+    }
+    [line appendFormat:@" %@=%@", key, value];
+  }
+  return line;
+}
+
+- (void)logWithType:(os_log_type_t)type
+            message:(NSString*)message
+             fields:(NSDictionary<NSString*, id>*)fields {
+  SNT_LOG_WITH_TYPE(type, @"%@", [SNTPushOSLogger lineForMessage:message fields:fields]);
+}
+
+@end
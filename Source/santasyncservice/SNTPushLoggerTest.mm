@@ -0,0 +1,50 @@
+/// Copyright 2026 North Pole Security, Inc.
+///
+/// Licensed under the Apache License, Version 2.0 (the "License");
+/// you may not use this file except in compliance with the License.
+/// You may obtain a copy of the License at
+///
+///     http://www.apache.org/licenses/LICENSE-2.0
+///
+/// Unless required by applicable law or agreed to in writing, software
+/// distributed under the License is distributed on an "AS IS" BASIS,
+/// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+/// See the License for the specific language governing permissions and
+/// limitations under the License.
+
+#import <XCTest/XCTest.h>
+
+#import "Source/santasyncservice/SNTPushLogger.h"
+
+@interface SNTPushLoggerTest : XCTestCase
+@end
+
+@implementation SNTPushLoggerTest
+
+- (void)testLineForMessage {
+  XCTAssertEqualObjects([SNTPushOSLogger lineForMessage:@"NATS: Disconnected" fields:@{}],
+                        @"NATS: Disconnected");
+
+  NSString* line = [SNTPushOSLogger lineForMessage:@"NATS: Connected"
+                                            fields:@{
+                                              kPushLogFieldServer : @"tls://a.push.example:443",
+                                              kPushLogFieldMachineID : @"ABC123",
+                                              kPushLogFieldAttempt : @3,
+                                            }];
+  XCTAssertEqualObjects(
+      line, @"NATS: Connected attempt=3 machine_id=ABC123 server=tls://a.push.example:443");
+}
+
+- (void)testLineForMessageQuotesValues {
+  NSString* line = [SNTPushOSLogger lineForMessage:@"NATS: Failed to connect"
+                                            fields:@{
+                                              kPushLogFieldError : @"Authorization Violation",
+                                              kPushLogFieldSubject : @"a=b",
+                                              kPushLogFieldServer : @"",
+                                              kPushLogFieldCategory : @"say \"hi\"",
+                                            }];
+  XCTAssertEqualObjects(line, @"NATS: Failed to connect category=\"say \\\"hi\\\"\" "
+                              @"error=\"Authorization Violation\" server=\"\" subject=\"a=b\"");
+}
+
+@end
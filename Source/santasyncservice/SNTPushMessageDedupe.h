@@ -0,0 +1,46 @@
+/// Copyright 2026 North Pole Security, Inc.
+///
+/// Licensed under the Apache License, Version 2.0 (the "License");
+/// you may not use this file except in compliance with the License.
+/// You may obtain a copy of the License at
+///
+///     http://www.apache.org/licenses/LICENSE-2.0
+///
+/// Unless required by applicable law or agreed to in writing, software
+/// distributed under the License is distributed on an "AS IS" BASIS,
+/// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+/// See the License for the specific language governing permissions and
+/// limitations under the License.
+
+#import <Foundation/Foundation.h>
+
+NS_ASSUME_NONNULL_BEGIN
+
+/// The default window, in seconds, within which a repeated push message is dropped.
+extern const NSTimeInterval kDefaultPushMessageDedupeWindow;
+
+/// Remembers recently seen push message IDs so that the same message delivered
+/// on several subjects (e.g. the host subject and a tag subject) only triggers
+/// its action once. An ID is processed again once the window has passed since it
+/// was first seen. Not thread-safe; callers are expected to serialize access.
+@interface SNTPushMessageDedupe : NSObject
+
+/// The window is clamped to be non-negative. A zero window disables deduplication.
+- (instancetype)initWithWindow:(NSTimeInterval)window NS_DESIGNATED_INITIALIZER;
+- (instancetype)init;
+
+@property(readonly) NSTimeInterval window;
+
+/// Returns YES if messageID has not been seen within the window before `now`,
+/// recording it as seen. Returns NO for a repeat, which does not extend the window.
+- (BOOL)shouldProcessMessageID:(NSString*)messageID now:(NSDate*)now;
+
+/// An ID for a message without a message ID header, derived from its headers and
+/// payload. The subject is deliberately left out so that the same message
+/// published to several subjects gets the same ID.
++ (NSString*)messageIDForHeaders:(nullable NSDictionary<NSString*, NSString*>*)headers
+                         payload:(nullable NSData*)payload;
+
+@end
+
+NS_ASSUME_NONNULL_END
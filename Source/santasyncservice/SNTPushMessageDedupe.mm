@@ -0,0 +1,92 @@
+/// Copyright 2026 North Pole Security, Inc.
+///
+/// Licensed under the Apache License, Version 2.0 (the "License");
+/// you may not use this file except in compliance with the License.
+/// You may obtain a copy of the License at
+///
+///     http://www.apache.org/licenses/LICENSE-2.0
+///
+/// Unless required by applicable law or agreed to in writing, software
+/// distributed under the License is distributed on an "AS IS" BASIS,
+/// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+/// See the License for the specific language governing permissions and
+/// limitations under the License.
+
+#import "Source/santasyncservice/SNTPushMessageDedupe.h"
+
+#include <CommonCrypto/CommonDigest.h>
+
+const NSTimeInterval kDefaultPushMessageDedupeWindow = 5;
+
+// Bounds the memory used by a flood of distinct messages. Once reached, the
+// oldest entries are dropped even if they are still within the window.
+static const NSUInteger kMaximumPushMessageDedupeEntries = 1024;
+
+@interface SNTPushMessageDedupe ()
+@property NSMutableDictionary<NSString*, NSDate*>* seen;
+@end
+
+@implementation SNTPushMessageDedupe
+
+- (instancetype)init {
+  return [self initWithWindow:kDefaultPushMessageDedupeWindow];
+}
+
+- (instancetype)initWithWindow:(NSTimeInterval)window {
+  self = [super init];
+  if (self) {
+    _window = MAX(window, 0);
+    _seen = [NSMutableDictionary dictionary];
+  }
+  return self;
+}
+
+- (BOOL)shouldProcessMessageID:(NSString*)messageID now:(NSDate*)now {
+  if (self.window <= 0) return YES;
+
+  [self purgeBefore:[now dateByAddingTimeInterval:-self.window]];
+
+  if (self.seen[messageID]) return NO;
+
+  if (self.seen.count >= kMaximumPushMessageDedupeEntries) {
+    NSArray<NSString*>* oldest = [self.seen keysSortedByValueUsingSelector:@selector(compare:)];
+    [self.seen removeObjectsForKeys:[oldest subarrayWithRange:NSMakeRange(0, oldest.count / 2)]];
+  }
+  self.seen[messageID] = now;
+  return YES;
+}
+
+- (void)purgeBefore:(NSDate*)cutoff {
+  NSSet<NSString*>* expired =
+      [self.seen keysOfEntriesPassingTest:^BOOL(NSString* key, NSDate* date, BOOL* stop) {
+        return [date compare:cutoff] != NSOrderedDescending;
+      }];
+  [self.seen removeObjectsForKeys:expired.allObjects];
+}
+
++ (NSString*)messageIDForHeaders:(NSDictionary<NSString*, NSString*>*)headers
+                         payload:(NSData*)payload {
+  CC_SHA256_CTX ctx;
+  CC_SHA256_Init(&ctx);
+  for (NSString* key in [headers.allKeys sortedArrayUsingSelector:@selector(compare:)]) {
+    // Include the lengths so that different splits of the same bytes never collide.
+    NSData* k = [key dataUsingEncoding:NSUTF8StringEncoding];
+    NSData* v = [headers[key] dataUsingEncoding:NSUTF8StringEncoding];
+    uint64_t lengths[2] = {k.length, v.length};
+    CC_SHA256_Update(&ctx, lengths, sizeof(lengths));
+    CC_SHA256_Update(&ctx, k.bytes, (CC_LONG)k.length);
+    CC_SHA256_Update(&ctx, v.bytes, (CC_LONG)v.length);
+  }
+  CC_SHA256_Update(&ctx, payload.bytes, (CC_LONG)payload.length);
+
+  unsigned char digest[CC_SHA256_DIGEST_LENGTH];
+  CC_SHA256_Final(digest, &ctx);
+
+  NSMutableString* messageID = [NSMutableString stringWithCapacity:CC_SHA256_DIGEST_LENGTH * 2];
+  for (int i = 0; i < CC_SHA256_DIGEST_LENGTH; ++i) {
+    [messageID appendFormat:@"%02x", digest[i]];
+  }
+  return messageID;
+}
+
+@end
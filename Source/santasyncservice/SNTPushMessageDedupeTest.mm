@@ -0,0 +1,80 @@
+/// Copyright 2026 North Pole Security, Inc.
+///
+/// Licensed under the Apache License, Version 2.0 (the "License");
+/// you may not use this file except in compliance with the License.
+/// You may obtain a copy of the License at
+///
+///     http://www.apache.org/licenses/LICENSE-2.0
+///
+/// Unless required by applicable law or agreed to in writing, software
+/// distributed under the License is distributed on an "AS IS" BASIS,
+/// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+/// See the License for the specific language governing permissions and
+/// limitations under the License.
+
+#import <XCTest/XCTest.h>
+
+#import "Source/santasyncservice/SNTPushMessageDedupe.h"
+
+@interface SNTPushMessageDedupeTest : XCTestCase
+@property NSDate* now;
+@end
+
+@implementation SNTPushMessageDedupeTest
+
+- (void)setUp {
+  [super setUp];
+  self.now = [NSDate dateWithTimeIntervalSince1970:1700000000];
+}
+
+- (void)testRepeatWithinWindowIsDropped {
+  SNTPushMessageDedupe* dedupe = [[SNTPushMessageDedupe alloc] init];
+  XCTAssertEqual(dedupe.window, kDefaultPushMessageDedupeWindow);
+
+  XCTAssertTrue([dedupe shouldProcessMessageID:@"a" now:self.now]);
+  XCTAssertFalse([dedupe shouldProcessMessageID:@"a" now:self.now]);
+  XCTAssertFalse([dedupe shouldProcessMessageID:@"a"
+                                            now:[self.now dateByAddingTimeInterval:4.9]]);
+
+  // Other IDs are unaffected.
+  XCTAssertTrue([dedupe shouldProcessMessageID:@"b" now:self.now]);
+}
+
+- (void)testRepeatAfterWindowIsProcessed {
+  SNTPushMessageDedupe* dedupe = [[SNTPushMessageDedupe alloc] initWithWindow:5];
+  XCTAssertTrue([dedupe shouldProcessMessageID:@"a" now:self.now]);
+
+  // Dropped repeats don't extend the window.
+  XCTAssertFalse([dedupe shouldProcessMessageID:@"a"
+                                            now:[self.now dateByAddingTimeInterval:3]]);
+  XCTAssertTrue([dedupe shouldProcessMessageID:@"a" now:[self.now dateByAddingTimeInterval:5]]);
+  XCTAssertFalse([dedupe shouldProcessMessageID:@"a" now:[self.now dateByAddingTimeInterval:6]]);
+}
+
+- (void)testZeroWindowDisablesDeduplication {
+  SNTPushMessageDedupe* dedupe = [[SNTPushMessageDedupe alloc] initWithWindow:0];
+  XCTAssertTrue([dedupe shouldProcessMessageID:@"a" now:self.now]);
+  XCTAssertTrue([dedupe shouldProcessMessageID:@"a" now:self.now]);
+}
+
+- (void)testMessageIDForHeadersAndPayload {
+  NSData* payload = [@"payload" dataUsingEncoding:NSUTF8StringEncoding];
+  NSString* messageID = [SNTPushMessageDedupe messageIDForHeaders:@{@"A" : @"1", @"B" : @"2"}
+                                                          payload:payload];
+  XCTAssertEqual(messageID.length, 64u);
+
+  XCTAssertEqualObjects(messageID,
+                        [SNTPushMessageDedupe messageIDForHeaders:@{@"B" : @"2", @"A" : @"1"}
+                                                          payload:payload]);
+
+  XCTAssertNotEqualObjects(messageID,
+                           [SNTPushMessageDedupe messageIDForHeaders:@{@"A" : @"1", @"B" : @"3"}
+                                                             payload:payload]);
+  XCTAssertNotEqualObjects(messageID, [SNTPushMessageDedupe messageIDForHeaders:@{@"AB" : @"12"}
+                                                                        payload:payload]);
+  XCTAssertNotEqualObjects(messageID,
+                           [SNTPushMessageDedupe messageIDForHeaders:@{@"A" : @"1", @"B" : @"2"}
+                                                             payload:nil]);
+}
+
+@end
@@ -17,6 +17,8 @@
 
 #import "Source/common/MOLXPCConnection.h"
 
+@class SNTStoredExecutionEvent;
+
 @protocol SNTPushNotificationsSyncDelegate <NSObject>
 - (void)sync;
 - (void)syncSecondsFromNow:(uint64_t)seconds;
@@ -26,6 +28,41 @@
 - (void)pushNotificationSyncSecondsFromNow:(uint64_t)seconds;
 - (MOLXPCConnection*)daemonConnection;
 - (void)eventUploadForPaths:(NSArray<NSString*>*)paths reply:(void (^)(NSError* error))reply;
+
+@optional
+
+/// Temporarily replace the full sync interval with `seconds` for `duration`
+/// seconds, after which the base interval applies again. Sent when a tag push
+/// notification carries sync override headers.
+- (void)overrideFullSyncInterval:(NSUInteger)seconds
+                     forDuration:(NSTimeInterval)duration
+                             tag:(NSString*)tag;
+
+/// Collect a diagnostics bundle and upload it to the sync server in `seconds`
+/// seconds. Sent when a push notification has the collect_diagnostics type.
+- (void)collectDiagnosticsSecondsFromNow:(uint64_t)seconds;
+
+/// Run a preflight in `seconds` seconds to fetch fresh push credentials. Sent
+/// when a push notification has the rotate_credentials type.
+- (void)rotatePushCredentialsSecondsFromNow:(uint64_t)seconds;
+
+/// Upload a snapshot of the local execution rules to the sync server in `seconds`
+/// seconds. Sent when a push notification has the report_rules type.
+- (void)reportRulesSecondsFromNow:(uint64_t)seconds;
+
+/// The push server rejected the push credentials, e.g. because they were revoked. Until new
+/// credentials are received the host should poll at the push client's (now shortened) full sync
+/// interval. The push client fetches new credentials itself if it has a credential provider;
+/// otherwise the host should run a preflight to fetch them.
+- (void)pushCredentialsRejected;
+
+/// Fetch up to `limit` of the most recent execution decisions made in [start, end],
+/// newest first. Sent when a host push notification has the export_decisions type.
+- (void)exportDecisionsFrom:(NSDate*)start
+                         to:(NSDate*)end
+                      limit:(NSUInteger)limit
+                      reply:(void (^)(NSArray<SNTStoredExecutionEvent*>* decisions))reply;
+
 @end
 
 @class SNTSyncState;
@@ -0,0 +1,57 @@
+/// Copyright 2026 North Pole Security, Inc.
+///
+/// Licensed under the Apache License, Version 2.0 (the "License");
+/// you may not use this file except in compliance with the License.
+/// You may obtain a copy of the License at
+///
+///     http://www.apache.org/licenses/LICENSE-2.0
+///
+/// Unless required by applicable law or agreed to in writing, software
+/// distributed under the License is distributed on an "AS IS" BASIS,
+/// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+/// See the License for the specific language governing permissions and
+/// limitations under the License.
+
+#import <Foundation/Foundation.h>
+
+NS_ASSUME_NONNULL_BEGIN
+
+/// The default time, in seconds, it takes to earn back one push-triggered sync.
+extern const NSTimeInterval kDefaultPushSyncRateLimitInterval;
+/// The default number of push-triggered syncs that can run back to back.
+extern const NSUInteger kDefaultPushSyncRateLimitBurst;
+
+typedef NS_ENUM(NSInteger, SNTPushSyncRateLimitDecision) {
+  // Sync as requested.
+  SNTPushSyncRateLimitDecisionAllow,
+  // Too many recent syncs. Sync once after the returned delay instead.
+  SNTPushSyncRateLimitDecisionDefer,
+  // Too many recent syncs and one is already deferred, which also covers this request.
+  SNTPushSyncRateLimitDecisionCoalesce,
+};
+
+/// A token bucket limiting how often push notifications can trigger a full sync.
+/// The bucket holds up to burst tokens and earns one back every interval
+/// seconds; each sync spends one. A request that finds the bucket empty isn't
+/// dropped: it reserves the next token and is deferred until it is earned, and
+/// further requests before then are coalesced into that deferred sync. Not
+/// thread-safe; callers are expected to serialize access.
+@interface SNTPushSyncRateLimiter : NSObject
+
+/// A non-positive interval or a zero burst disables rate limiting.
+- (instancetype)initWithInterval:(NSTimeInterval)interval
+                           burst:(NSUInteger)burst NS_DESIGNATED_INITIALIZER;
+- (instancetype)init;
+
+@property(readonly) NSTimeInterval interval;
+@property(readonly) NSUInteger burst;
+
+/// Decide whether a sync requested at `now` may run. For Defer, *delay is set
+/// to the seconds from `now` until the deferred sync may run. For Coalesce, it
+/// is set to the seconds until the already deferred sync runs.
+- (SNTPushSyncRateLimitDecision)decisionForSyncAt:(NSDate*)now
+                                            delay:(NSTimeInterval*)delay;
+
+@end
+
+NS_ASSUME_NONNULL_END
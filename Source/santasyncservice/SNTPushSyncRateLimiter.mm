@@ -0,0 +1,73 @@
+/// Copyright 2026 North Pole Security, Inc.
+///
+/// Licensed under the Apache License, Version 2.0 (the "License");
+/// you may not use this file except in compliance with the License.
+/// You may obtain a copy of the License at
+///
+///     http://www.apache.org/licenses/LICENSE-2.0
+///
+/// Unless required by applicable law or agreed to in writing, software
+/// distributed under the License is distributed on an "AS IS" BASIS,
+/// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+/// See the License for the specific language governing permissions and
+/// limitations under the License.
+
+#import "Source/santasyncservice/SNTPushSyncRateLimiter.h"
+
+const NSTimeInterval kDefaultPushSyncRateLimitInterval = 30;
+const NSUInteger kDefaultPushSyncRateLimitBurst = 2;
+
+@interface SNTPushSyncRateLimiter ()
+// Fractional, and negative while a deferred sync holds a reservation on the next token.
+@property double tokens;
+@property NSDate* lastRefill;
+// When the deferred sync runs, nil if there is none.
+@property NSDate* deferredSyncDate;
+@end
+
+@implementation SNTPushSyncRateLimiter
+
+- (instancetype)init {
+  return [self initWithInterval:kDefaultPushSyncRateLimitInterval
+                          burst:kDefaultPushSyncRateLimitBurst];
+}
+
+- (instancetype)initWithInterval:(NSTimeInterval)interval burst:(NSUInteger)burst {
+  self = [super init];
+  if (self) {
+    _interval = MAX(interval, 0);
+    _burst = burst;
+    _tokens = burst;
+  }
+  return self;
+}
+
+- (SNTPushSyncRateLimitDecision)decisionForSyncAt:(NSDate*)now delay:(NSTimeInterval*)delay {
+  if (delay) *delay = 0;
+  if (self.interval <= 0 || self.burst == 0) return SNTPushSyncRateLimitDecisionAllow;
+
+  if (self.lastRefill) {
+    NSTimeInterval elapsed = MAX([now timeIntervalSinceDate:self.lastRefill], 0);
+    self.tokens = MIN(self.tokens + elapsed / self.interval, (double)self.burst);
+  }
+  self.lastRefill = now;
+
+  if (self.deferredSyncDate && [now compare:self.deferredSyncDate] == NSOrderedAscending) {
+    if (delay) *delay = [self.deferredSyncDate timeIntervalSinceDate:now];
+    return SNTPushSyncRateLimitDecisionCoalesce;
+  }
+  self.deferredSyncDate = nil;
+
+  if (self.tokens >= 1) {
+    self.tokens -= 1;
+    return SNTPushSyncRateLimitDecisionAllow;
+  }
+
+  NSTimeInterval wait = (1 - self.tokens) * self.interval;
+  self.tokens -= 1;
+  self.deferredSyncDate = [now dateByAddingTimeInterval:wait];
+  if (delay) *delay = wait;
+  return SNTPushSyncRateLimitDecisionDefer;
+}
+
+@end
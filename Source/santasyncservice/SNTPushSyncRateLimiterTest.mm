@@ -0,0 +1,89 @@
+/// Copyright 2026 North Pole Security, Inc.
+///
+/// Licensed under the Apache License, Version 2.0 (the "License");
+/// you may not use this file except in compliance with the License.
+/// You may obtain a copy of the License at
+///
+///     http://www.apache.org/licenses/LICENSE-2.0
+///
+/// Unless required by applicable law or agreed to in writing, software
+/// distributed under the License is distributed on an "AS IS" BASIS,
+/// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+/// See the License for the specific language governing permissions and
+/// limitations under the License.
+
+#import <XCTest/XCTest.h>
+
+#import "Source/santasyncservice/SNTPushSyncRateLimiter.h"
+
+@interface SNTPushSyncRateLimiterTest : XCTestCase
+@property NSDate* now;
+@end
+
+@implementation SNTPushSyncRateLimiterTest
+
+- (void)setUp {
+  [super setUp];
+  self.now = [NSDate dateWithTimeIntervalSince1970:1700000000];
+}
+
+- (SNTPushSyncRateLimitDecision)decide:(SNTPushSyncRateLimiter*)limiter
+                                    at:(NSTimeInterval)offset
+                                 delay:(NSTimeInterval*)delay {
+  return [limiter decisionForSyncAt:[self.now dateByAddingTimeInterval:offset] delay:delay];
+}
+
+- (void)testBurstThenCoalesce {
+  SNTPushSyncRateLimiter* limiter = [[SNTPushSyncRateLimiter alloc] init];
+  XCTAssertEqual(limiter.interval, kDefaultPushSyncRateLimitInterval);
+  XCTAssertEqual(limiter.burst, kDefaultPushSyncRateLimitBurst);
+
+  NSTimeInterval delay;
+  XCTAssertEqual([self decide:limiter at:0 delay:&delay], SNTPushSyncRateLimitDecisionAllow);
+  XCTAssertEqual([self decide:limiter at:0 delay:&delay], SNTPushSyncRateLimitDecisionAllow);
+  XCTAssertEqual(delay, 0);
+
+  // The bucket is empty: the next request is deferred until a token is earned back...
+  XCTAssertEqual([self decide:limiter at:0 delay:&delay], SNTPushSyncRateLimitDecisionDefer);
+  XCTAssertEqualWithAccuracy(delay, 30, 0.001);
+
+  // ...and every request until then is folded into that one sync.
+  for (int i = 1; i < 30; i++) {
+    XCTAssertEqual([self decide:limiter at:i delay:&delay], SNTPushSyncRateLimitDecisionCoalesce);
+    XCTAssertEqualWithAccuracy(delay, 30 - i, 0.001);
+  }
+
+  // The deferred sync used the token earned at 30s, so the next one is deferred to 60s.
+  XCTAssertEqual([self decide:limiter at:30 delay:&delay], SNTPushSyncRateLimitDecisionDefer);
+  XCTAssertEqualWithAccuracy(delay, 30, 0.001);
+}
+
+- (void)testRefill {
+  SNTPushSyncRateLimiter* limiter = [[SNTPushSyncRateLimiter alloc] initWithInterval:10 burst:2];
+  NSTimeInterval delay;
+  XCTAssertEqual([self decide:limiter at:0 delay:&delay], SNTPushSyncRateLimitDecisionAllow);
+  XCTAssertEqual([self decide:limiter at:0 delay:&delay], SNTPushSyncRateLimitDecisionAllow);
+
+  // Half a token has been earned, so the wait is for the other half.
+  XCTAssertEqual([self decide:limiter at:5 delay:&delay], SNTPushSyncRateLimitDecisionDefer);
+  XCTAssertEqualWithAccuracy(delay, 5, 0.001);
+
+  // Long after, the bucket is full again but holds no more than burst tokens.
+  XCTAssertEqual([self decide:limiter at:1000 delay:&delay], SNTPushSyncRateLimitDecisionAllow);
+  XCTAssertEqual([self decide:limiter at:1000 delay:&delay], SNTPushSyncRateLimitDecisionAllow);
+  XCTAssertEqual([self decide:limiter at:1000 delay:&delay], SNTPushSyncRateLimitDecisionDefer);
+  XCTAssertEqualWithAccuracy(delay, 10, 0.001);
+}
+
+- (void)testDisabled {
+  for (SNTPushSyncRateLimiter* limiter in @[
+         [[SNTPushSyncRateLimiter alloc] initWithInterval:0 burst:2],
+         [[SNTPushSyncRateLimiter alloc] initWithInterval:30 burst:0],
+       ]) {
+    for (int i = 0; i < 10; i++) {
+      XCTAssertEqual([self decide:limiter at:0 delay:NULL], SNTPushSyncRateLimitDecisionAllow);
+    }
+  }
+}
+
+@end
@@ -0,0 +1,58 @@
+/// Copyright 2026 North Pole Security, Inc.
+///
+/// Licensed under the Apache License, Version 2.0 (the "License");
+/// you may not use this file except in compliance with the License.
+/// You may obtain a copy of the License at
+///
+///     http://www.apache.org/licenses/LICENSE-2.0
+///
+/// Unless required by applicable law or agreed to in writing, software
+/// distributed under the License is distributed on an "AS IS" BASIS,
+/// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+/// See the License for the specific language governing permissions and
+/// limitations under the License.
+
+
+#import <Foundation/Foundation.h>
+
+@class SNTRuleSource;
+@class SNTSyncState;
+
+///
+///  Downloads rules from each configured rule source on its own timer, independently of the
+///  primary sync server and of each other. Each source has its own serial queue so a slow or
+///  unreachable source does not delay the others.
+///
+@interface SNTRuleSourceScheduler : NSObject
+
+///
+///  Designated initializer.
+///
+///  @param syncStateBlock Creates a sync state for downloading from the given source, or returns
+///                        nil if one can't be created. Called on the source's queue before every
+///                        download.
+///
+- (instancetype)initWithSyncStateBlock:(SNTSyncState* (^)(SNTRuleSource* source))syncStateBlock
+    NS_DESIGNATED_INITIALIZER;
+
+- (instancetype)init NS_UNAVAILABLE;
+
+///
+///  Seconds to wait before the first download from a newly added source. Defaults to 15.
+///
+@property NSTimeInterval startDelay;
+
+///
+///  Match the scheduled sources to `sources`. Sources that were removed stop being downloaded.
+///  Sources that are new, or whose URL, interval or precedence changed, are (re)scheduled to
+///  start after `startDelay`. Unchanged sources keep their schedule.
+///
+- (void)updateSources:(NSArray<SNTRuleSource*>*)sources;
+
+///
+///  The state of every scheduled source, keyed by the kRuleSourceStatus* constants, ordered from
+///  highest to lowest precedence.
+///
+- (NSArray<NSDictionary*>*)status;
+
+@end
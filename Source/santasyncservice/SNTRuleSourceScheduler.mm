@@ -0,0 +1,202 @@
+/// Copyright 2026 North Pole Security, Inc.
+///
+/// Licensed under the Apache License, Version 2.0 (the "License");
+/// you may not use this file except in compliance with the License.
+/// You may obtain a copy of the License at
+///
+///     http://www.apache.org/licenses/LICENSE-2.0
+///
+/// Unless required by applicable law or agreed to in writing, software
+/// distributed under the License is distributed on an "AS IS" BASIS,
+/// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+/// See the License for the specific language governing permissions and
+/// limitations under the License.
+
+
+#import "Source/santasyncservice/SNTRuleSourceScheduler.h"
+
+#import "Source/common/SNTLogging.h"
+#import "Source/common/SNTRuleSource.h"
+#import "Source/common/SNTSyncConstants.h"
+#import "Source/santasyncservice/SNTSyncRuleDownload.h"
+#import "Source/santasyncservice/SNTSyncState.h"
+
+// A configured rule source along with its timer and the outcome of its most recent downloads.
+// The download state is only accessed while synchronized on the object.
+@interface SNTScheduledRuleSource : NSObject
+@property(readonly) SNTRuleSource* source;
+@property(readonly) dispatch_queue_t queue;
+@property dispatch_source_t timer;
+@property NSDate* lastAttempt;
+@property NSDate* lastSuccess;
+@property NSString* lastError;
+@property NSUInteger rulesReceived;
+@property NSDate* nextSync;
+@end
+
+@implementation SNTScheduledRuleSource
+
+- (instancetype)initWithSource:(SNTRuleSource*)source {
+  self = [super init];
+  if (self) {
+    _source = source;
+    NSString* label = [@"com.northpolesec.santa.syncservice.rulesource."
+        stringByAppendingString:source.name];
+    _queue = dispatch_queue_create(label.UTF8String, DISPATCH_QUEUE_SERIAL);
+  }
+  return self;
+}
+
+@end
+
+@interface SNTRuleSourceScheduler ()
+@property(readonly) SNTSyncState* (^syncStateBlock)(SNTRuleSource*);
+// Keyed by source name. Only accessed while synchronized on self.
+@property(readonly) NSMutableDictionary<NSString*, SNTScheduledRuleSource*>* sources;
+@end
+
+@implementation SNTRuleSourceScheduler
+
+- (instancetype)initWithSyncStateBlock:(SNTSyncState* (^)(SNTRuleSource*))syncStateBlock {
+  self = [super init];
+  if (self) {
+    _syncStateBlock = [syncStateBlock copy];
+    _sources = [NSMutableDictionary dictionary];
+    _startDelay = 15;
+  }
+  return self;
+}
+
+- (void)dealloc {
+  for (SNTScheduledRuleSource* entry in _sources.allValues) {
+    dispatch_source_cancel(entry.timer);
+  }
+}
+
+- (void)updateSources:(NSArray<SNTRuleSource*>*)sources {
+  @synchronized(self) {
+    NSMutableSet<NSString*>* removed = [NSMutableSet setWithArray:self.sources.allKeys];
+
+    for (SNTRuleSource* source in sources) {
+      [removed removeObject:source.name];
+
+      SNTRuleSource* existing = self.sources[source.name].source;
+      if (existing && [existing.url isEqual:source.url] &&
+          existing.syncInterval == source.syncInterval &&
+          existing.precedence == source.precedence &&
+          (existing.publicKey == source.publicKey ||
+           [existing.publicKey isEqual:source.publicKey])) {
+        continue;
+      }
+
+      if (existing) {
+        LOGI(@"Rule source %@ changed, rescheduling", source.name);
+        dispatch_source_cancel(self.sources[source.name].timer);
+      } else {
+        LOGI(@"Scheduling rule source %@", source);
+      }
+      self.sources[source.name] = [self scheduleSource:source];
+    }
+
+    for (NSString* name in removed) {
+      LOGI(@"Rule source %@ removed", name);
+      dispatch_source_cancel(self.sources[name].timer);
+      [self.sources removeObjectForKey:name];
+    }
+  }
+}
+
+- (SNTScheduledRuleSource*)scheduleSource:(SNTRuleSource*)source {
+  SNTScheduledRuleSource* entry = [[SNTScheduledRuleSource alloc] initWithSource:source];
+  entry.timer = dispatch_source_create(DISPATCH_SOURCE_TYPE_TIMER, 0, 0, entry.queue);
+
+  __weak __typeof(self) weakSelf = self;
+  __weak SNTScheduledRuleSource* weakEntry = entry;
+  dispatch_source_set_event_handler(entry.timer, ^{
+    SNTScheduledRuleSource* strongEntry = weakEntry;
+    if (strongEntry) [weakSelf syncEntry:strongEntry];
+  });
+
+  uint64_t interval = source.syncInterval * NSEC_PER_SEC;
+  uint64_t leeway = MIN(interval / 10, 5 * NSEC_PER_SEC);
+  dispatch_source_set_timer(entry.timer,
+                            dispatch_walltime(NULL, (int64_t)(self.startDelay * NSEC_PER_SEC)),
+                            interval, leeway);
+  entry.nextSync = [NSDate dateWithTimeIntervalSinceNow:self.startDelay];
+  dispatch_resume(entry.timer);
+  return entry;
+}
+
+// Runs on the entry's queue, so downloads from the same source never overlap.
+- (void)syncEntry:(SNTScheduledRuleSource*)entry {
+  SNTRuleSource* source = entry.source;
+  NSDate* start = [NSDate date];
+  @synchronized(entry) {
+    entry.lastAttempt = start;
+    entry.nextSync = [start dateByAddingTimeInterval:source.syncInterval];
+  }
+
+  NSString* error;
+  SNTSyncState* syncState = self.syncStateBlock(source);
+  if (!syncState) {
+    error = @"Unable to create sync state";
+  } else {
+    syncState.ruleSource = source;
+    if (![[[SNTSyncRuleDownload alloc] initWithState:syncState] sync]) {
+      error = @"Rule download failed";
+    }
+  }
+
+  if (error) {
+    LOGE(@"Rule source %@: %@", source.name, error);
+  } else {
+    LOGD(@"Rule source %@: downloaded %lu rules", source.name, syncState.rulesReceived);
+  }
+
+  @synchronized(entry) {
+    entry.lastError = error;
+    if (!error) {
+      entry.lastSuccess = [NSDate date];
+      entry.rulesReceived = syncState.rulesReceived;
+    }
+  }
+}
+
+- (NSArray<NSDictionary*>*)status {
+  NSArray<SNTScheduledRuleSource*>* entries;
+  @synchronized(self) {
+    entries = [self.sources.allValues
+        sortedArrayUsingComparator:^NSComparisonResult(SNTScheduledRuleSource* a,
+                                                       SNTScheduledRuleSource* b) {
+          if (a.source.precedence != b.source.precedence) {
+            return a.source.precedence > b.source.precedence ? NSOrderedAscending
+                                                             : NSOrderedDescending;
+          }
+          return [a.source.name compare:b.source.name];
+        }];
+  }
+
+  NSMutableArray<NSDictionary*>* status = [NSMutableArray arrayWithCapacity:entries.count];
+  for (SNTScheduledRuleSource* entry in entries) {
+    NSMutableDictionary* s = [NSMutableDictionary dictionary];
+    s[kRuleSourceStatusName] = entry.source.name;
+    s[kRuleSourceStatusURL] = entry.source.url.absoluteString;
+    s[kRuleSourceStatusPrecedence] = @(entry.source.precedence);
+    s[kRuleSourceStatusSyncInterval] = @(entry.source.syncInterval);
+    @synchronized(entry) {
+      if (entry.lastAttempt) {
+        s[kRuleSourceStatusLastAttempt] = @(entry.lastAttempt.timeIntervalSince1970);
+      }
+      if (entry.lastSuccess) {
+        s[kRuleSourceStatusLastSuccess] = @(entry.lastSuccess.timeIntervalSince1970);
+        s[kRuleSourceStatusRulesReceived] = @(entry.rulesReceived);
+      }
+      if (entry.lastError) s[kRuleSourceStatusLastError] = entry.lastError;
+      s[kRuleSourceStatusNextSync] = @(entry.nextSync.timeIntervalSince1970);
+    }
+    [status addObject:s];
+  }
+  return status;
+}
+
+@end
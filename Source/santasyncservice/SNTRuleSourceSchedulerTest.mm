@@ -0,0 +1,431 @@
+/// Copyright 2026 North Pole Security, Inc.
+///
+/// Licensed under the Apache License, Version 2.0 (the "License");
+/// you may not use this file except in compliance with the License.
+/// You may obtain a copy of the License at
+///
+///     http://www.apache.org/licenses/LICENSE-2.0
+///
+/// Unless required by applicable law or agreed to in writing, software
+/// distributed under the License is distributed on an "AS IS" BASIS,
+/// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+/// See the License for the specific language governing permissions and
+/// limitations under the License.
+
+
+#import <Foundation/Foundation.h>
+#import <OCMock/OCMock.h>
+#import <XCTest/XCTest.h>
+
+#include <openssl/curve25519.h>
+
+#import "Source/common/MOLXPCConnection.h"
+#import "Source/common/SNTConfigurator.h"
+#import "Source/common/SNTRule.h"
+#import "Source/common/SNTRuleSource.h"
+#import "Source/common/SNTSyncConstants.h"
+#import "Source/common/SNTXPCControlInterface.h"
+#import "Source/common/SignedRuleBundle.h"
+#import "Source/santasyncservice/SNTRuleSourceScheduler.h"
+#import "Source/santasyncservice/SNTSyncState.h"
+
+@interface SNTRuleSourceSchedulerTest : XCTestCase
+@property id configMock;
+@property id daemonConn;
+@property id daemonConnRop;
+// Source name -> mocked session serving that source.
+@property NSMutableDictionary<NSString*, NSURLSession*>* sessions;
+// Source name -> the rule sets santad was asked to apply for that source, in order.
+@property NSMutableDictionary<NSString*, NSMutableArray<NSArray<SNTRule*>*>*>* applied;
+// Source name -> expectation fulfilled each time rules from that source are applied.
+@property NSMutableDictionary<NSString*, XCTestExpectation*>* appliedExpectations;
+@property SNTRuleSourceScheduler* sut;
+@end
+
+@implementation SNTRuleSourceSchedulerTest
+
+- (void)setUp {
+  [super setUp];
+
+  self.configMock = OCMClassMock([SNTConfigurator class]);
+  OCMStub([self.configMock configurator]).andReturn(self.configMock);
+  OCMStub([self.configMock syncEnableProtoTransfer]).andReturn(NO);
+  OCMStub([self.configMock syncRuleDownloadConcurrency]).andReturn(1);
+
+  self.sessions = [NSMutableDictionary dictionary];
+  self.applied = [NSMutableDictionary dictionary];
+  self.appliedExpectations = [NSMutableDictionary dictionary];
+
+  self.daemonConn = OCMClassMock([MOLXPCConnection class]);
+  self.daemonConnRop = OCMProtocolMock(@protocol(SNTDaemonControlXPC));
+  OCMStub([self.daemonConn remoteObjectProxy]).andReturn(self.daemonConnRop);
+
+  __weak __typeof(self) weakSelf = self;
+  OCMStub([self.daemonConnRop databaseRuleReplaceRulesForRuleSource:OCMOCK_ANY
+                                                         precedence:0
+                                                              rules:OCMOCK_ANY
+                                                              reply:OCMOCK_ANY])
+      .ignoringNonObjectArgs()
+      .andDo(^(NSInvocation* invocation) {
+        __unsafe_unretained NSString* source;
+        __unsafe_unretained NSArray<SNTRule*>* rules;
+        __unsafe_unretained void (^reply)(BOOL, NSArray<NSError*>*);
+        [invocation getArgument:&source atIndex:2];
+        [invocation getArgument:&rules atIndex:4];
+        [invocation getArgument:&reply atIndex:5];
+        [weakSelf recordRules:rules forSource:source];
+        reply(YES, nil);
+      });
+
+  self.sut = [[SNTRuleSourceScheduler alloc]
+      initWithSyncStateBlock:^SNTSyncState*(SNTRuleSource* source) {
+        return [weakSelf syncStateForSource:source];
+      }];
+  self.sut.startDelay = 0;
+}
+
+- (void)tearDown {
+  // Stop all timers before the mocks go away.
+  [self.sut updateSources:@[]];
+  self.sut = nil;
+  [self.configMock stopMocking];
+  [super tearDown];
+}
+
+#pragma mark Helpers
+
+- (void)recordRules:(NSArray<SNTRule*>*)rules forSource:(NSString*)source {
+  XCTestExpectation* expectation;
+  @synchronized(self) {
+    if (!self.applied[source]) self.applied[source] = [NSMutableArray array];
+    [self.applied[source] addObject:rules];
+    expectation = self.appliedExpectations[source];
+  }
+  [expectation fulfill];
+}
+
+- (NSUInteger)appliedCountForSource:(NSString*)source {
+  @synchronized(self) {
+    return self.applied[source].count;
+  }
+}
+
+- (SNTSyncState*)syncStateForSource:(SNTRuleSource*)source {
+  SNTSyncState* syncState = [[SNTSyncState alloc] init];
+  syncState.syncBaseURL = source.url;
+  syncState.machineID = @"50C7E1EB-2EF5-42D4-A084-A7966FC45A95";
+  syncState.daemonConn = self.daemonConn;
+  @synchronized(self) {
+    syncState.session = self.sessions[source.name];
+  }
+  return syncState;
+}
+
+- (SNTRuleSource*)addSourceNamed:(NSString*)name
+                        interval:(NSUInteger)interval
+                      precedence:(NSInteger)precedence
+                      statusCode:(NSInteger)statusCode
+                           rules:(NSArray<NSDictionary*>*)rules {
+  return [self addSourceNamed:name
+                     interval:interval
+                   precedence:precedence
+                   statusCode:statusCode
+                         body:[self bodyWithRules:rules]
+                    publicKey:nil];
+}
+
+- (SNTRuleSource*)addSourceNamed:(NSString*)name
+                        interval:(NSUInteger)interval
+                      precedence:(NSInteger)precedence
+                      statusCode:(NSInteger)statusCode
+                            body:(NSData*)body
+                       publicKey:(NSData*)publicKey {
+  NSURL* url = [NSURL URLWithString:[NSString stringWithFormat:@"https://%@.example.com/", name]];
+
+  id session = OCMClassMock([NSURLSession class]);
+  OCMStub([session dataTaskWithRequest:OCMOCK_ANY completionHandler:OCMOCK_ANY])
+      .andDo(^(NSInvocation* invocation) {
+        __unsafe_unretained NSURLRequest* req;
+        __unsafe_unretained void (^handler)(NSData*, NSURLResponse*, NSError*);
+        [invocation getArgument:&req atIndex:2];
+        [invocation getArgument:&handler atIndex:3];
+        // Each source must only be sent to its own server.
+        XCTAssertEqualObjects(req.URL.host, url.host);
+        handler(body,
+                [[NSHTTPURLResponse alloc] initWithURL:req.URL
+                                            statusCode:statusCode
+                                           HTTPVersion:@"1.1"
+                                          headerFields:nil],
+                nil);
+      });
+  @synchronized(self) {
+    self.sessions[name] = session;
+  }
+
+  return [[SNTRuleSource alloc] initWithName:name
+                                         url:url
+                                syncInterval:interval
+                                  precedence:precedence
+                                   publicKey:publicKey];
+}
+
+- (NSData*)bodyWithRules:(NSArray<NSDictionary*>*)rules {
+  return [NSJSONSerialization dataWithJSONObject:@{@"rules" : rules} options:0 error:NULL];
+}
+
+- (NSDictionary*)teamIDRule:(NSString*)teamID {
+  return @{@"identifier" : teamID, @"policy" : @"BLOCKLIST", @"rule_type" : @"TEAMID"};
+}
+
+- (void)waitForAttemptBySource:(NSString*)name {
+  NSDate* deadline = [NSDate dateWithTimeIntervalSinceNow:5];
+  while (![self statusForSource:name][kRuleSourceStatusLastAttempt] &&
+         [deadline timeIntervalSinceNow] > 0) {
+    usleep(10 * 1000);
+  }
+  usleep(100 * 1000);
+}
+
+- (NSDictionary*)statusForSource:(NSString*)name {
+  for (NSDictionary* status in [self.sut status]) {
+    if ([status[kRuleSourceStatusName] isEqualToString:name]) return status;
+  }
+  return nil;
+}
+
+#pragma mark Tests
+
+- (void)testSourcesSyncOnIndependentSchedules {
+  SNTRuleSource* fast = [self addSourceNamed:@"fast"
+                                    interval:1
+                                  precedence:0
+                                  statusCode:200
+                                       rules:@[ @{
+                                         @"identifier" : @"ABCDEFGHIJ",
+                                         @"policy" : @"BLOCKLIST",
+                                         @"rule_type" : @"TEAMID",
+                                       } ]];
+  SNTRuleSource* slow = [self addSourceNamed:@"slow"
+                                    interval:10
+                                  precedence:5
+                                  statusCode:200
+                                       rules:@[
+                                         @{
+                                           @"identifier" : [@"" stringByPaddingToLength:64
+                                                                             withString:@"a"
+                                                                        startingAtIndex:0],
+                                           @"policy" : @"ALLOWLIST",
+                                           @"rule_type" : @"BINARY",
+                                         },
+                                         @{
+                                           @"identifier" : @"ZYXWVUTSRQ",
+                                           @"policy" : @"ALLOWLIST",
+                                           @"rule_type" : @"TEAMID",
+                                         },
+                                       ]];
+
+  XCTestExpectation* fastSynced = [self expectationWithDescription:@"fast source synced"];
+  fastSynced.expectedFulfillmentCount = 3;
+  fastSynced.assertForOverFulfill = NO;
+  XCTestExpectation* slowSynced = [self expectationWithDescription:@"slow source synced"];
+  slowSynced.assertForOverFulfill = NO;
+  @synchronized(self) {
+    self.appliedExpectations[@"fast"] = fastSynced;
+    self.appliedExpectations[@"slow"] = slowSynced;
+  }
+
+  [self.sut updateSources:@[ fast, slow ]];
+  [self waitForExpectations:@[ fastSynced, slowSynced ] timeout:8];
+
+  // The fast source has been downloaded every second while the slow source has only been
+  // downloaded once, when it was scheduled.
+  XCTAssertGreaterThanOrEqual([self appliedCountForSource:@"fast"], 3);
+  XCTAssertEqual([self appliedCountForSource:@"slow"], 1);
+
+  // Each source's rules are applied separately, with that source's precedence.
+  OCMVerify(atLeast(3), [self.daemonConnRop databaseRuleReplaceRulesForRuleSource:@"fast"
+                                                                       precedence:0
+                                                                            rules:OCMOCK_ANY
+                                                                            reply:OCMOCK_ANY]);
+  OCMVerify(times(1), [self.daemonConnRop databaseRuleReplaceRulesForRuleSource:@"slow"
+                                                                     precedence:5
+                                                                          rules:OCMOCK_ANY
+                                                                          reply:OCMOCK_ANY]);
+  @synchronized(self) {
+    XCTAssertEqual(self.applied[@"fast"].firstObject.count, 1);
+    XCTAssertEqualObjects(self.applied[@"fast"].firstObject.firstObject.identifier, @"ABCDEFGHIJ");
+    XCTAssertEqual(self.applied[@"slow"].firstObject.count, 2);
+  }
+
+  // Sources never touch the primary sync server's sync state.
+  OCMVerify(never(), [self.daemonConnRop updateSyncSettings:OCMOCK_ANY reply:OCMOCK_ANY]);
+
+  NSArray<NSDictionary*>* status = [self.sut status];
+  XCTAssertEqual(status.count, 2);
+  // Ordered by precedence.
+  XCTAssertEqualObjects(status[0][kRuleSourceStatusName], @"slow");
+  XCTAssertEqualObjects(status[1][kRuleSourceStatusName], @"fast");
+  XCTAssertEqualObjects(status[0][kRuleSourceStatusRulesReceived], @2);
+  XCTAssertEqualObjects(status[1][kRuleSourceStatusRulesReceived], @1);
+  XCTAssertEqualObjects(status[0][kRuleSourceStatusSyncInterval], @10);
+  XCTAssertNotNil(status[0][kRuleSourceStatusLastSuccess]);
+  XCTAssertNil(status[0][kRuleSourceStatusLastError]);
+  XCTAssertGreaterThan([status[0][kRuleSourceStatusNextSync] doubleValue],
+                       [status[1][kRuleSourceStatusNextSync] doubleValue]);
+}
+
+- (void)testFailingSourceDoesNotAffectOthers {
+  SNTRuleSource* good = [self addSourceNamed:@"good"
+                                    interval:60
+                                  precedence:0
+                                  statusCode:200
+                                       rules:@[ @{
+                                         @"identifier" : @"ABCDEFGHIJ",
+                                         @"policy" : @"BLOCKLIST",
+                                         @"rule_type" : @"TEAMID",
+                                       } ]];
+  // A 400 is used as it is not retried.
+  SNTRuleSource* bad = [self addSourceNamed:@"bad"
+                                   interval:60
+                                 precedence:0
+                                 statusCode:400
+                                      rules:@[]];
+
+  XCTestExpectation* goodSynced = [self expectationWithDescription:@"good source synced"];
+  @synchronized(self) {
+    self.appliedExpectations[@"good"] = goodSynced;
+  }
+  [self.sut updateSources:@[ good, bad ]];
+  [self waitForExpectations:@[ goodSynced ] timeout:5];
+
+  // Wait for the failing download to be recorded.
+  [self waitForAttemptBySource:@"bad"];
+
+  NSDictionary* badStatus = [self statusForSource:@"bad"];
+  XCTAssertNotNil(badStatus[kRuleSourceStatusLastAttempt]);
+  XCTAssertNil(badStatus[kRuleSourceStatusLastSuccess]);
+  XCTAssertNotNil(badStatus[kRuleSourceStatusLastError]);
+  XCTAssertEqual([self appliedCountForSource:@"bad"], 0);
+
+  NSDictionary* goodStatus = [self statusForSource:@"good"];
+  XCTAssertNotNil(goodStatus[kRuleSourceStatusLastSuccess]);
+  XCTAssertNil(goodStatus[kRuleSourceStatusLastError]);
+}
+
+- (void)testUpdateSources {
+  SNTRuleSource* a = [self addSourceNamed:@"a" interval:60 precedence:0 statusCode:200 rules:@[]];
+  SNTRuleSource* b = [self addSourceNamed:@"b" interval:60 precedence:0 statusCode:200 rules:@[]];
+
+  // Don't let anything download during this test.
+  self.sut.startDelay = 3600;
+  [self.sut updateSources:@[ a, b ]];
+  NSNumber* nextSyncA = [self statusForSource:@"a"][kRuleSourceStatusNextSync];
+  XCTAssertEqual([self.sut status].count, 2);
+
+  // An unchanged source keeps its schedule, a changed one is rescheduled, removed ones stop.
+  self.sut.startDelay = 7200;
+  SNTRuleSource* changedA = [[SNTRuleSource alloc] initWithName:@"a"
+                                                            url:a.url
+                                                   syncInterval:60
+                                                     precedence:10];
+  [self.sut updateSources:@[ a, b ]];
+  XCTAssertEqualObjects([self statusForSource:@"a"][kRuleSourceStatusNextSync], nextSyncA);
+
+  [self.sut updateSources:@[ changedA ]];
+  NSArray<NSDictionary*>* status = [self.sut status];
+  XCTAssertEqual(status.count, 1);
+  XCTAssertEqualObjects(status[0][kRuleSourceStatusPrecedence], @10);
+  XCTAssertGreaterThan([status[0][kRuleSourceStatusNextSync] doubleValue],
+                       nextSyncA.doubleValue);
+  XCTAssertNil(status[0][kRuleSourceStatusLastAttempt]);
+}
+
+- (void)testSourcesAreVerifiedWithTheirOwnKey {
+  uint8_t alphaPublic[ED25519_PUBLIC_KEY_LEN], alphaPrivate[ED25519_PRIVATE_KEY_LEN];
+  uint8_t betaPublic[ED25519_PUBLIC_KEY_LEN], betaPrivate[ED25519_PRIVATE_KEY_LEN];
+  ED25519_keypair(alphaPublic, alphaPrivate);
+  ED25519_keypair(betaPublic, betaPrivate);
+  NSData* alphaPublicKey = [NSData dataWithBytes:alphaPublic length:sizeof(alphaPublic)];
+  NSData* alphaPrivateKey = [NSData dataWithBytes:alphaPrivate length:sizeof(alphaPrivate)];
+  NSData* betaPublicKey = [NSData dataWithBytes:betaPublic length:sizeof(betaPublic)];
+  NSData* betaPrivateKey = [NSData dataWithBytes:betaPrivate length:sizeof(betaPrivate)];
+
+  NSData* alphaBundle = santa::CreateSignedRuleBundle(
+      [self bodyWithRules:@[ [self teamIDRule:@"ALPHAALPHA"] ]], alphaPrivateKey, NULL);
+  NSData* betaBundle = santa::CreateSignedRuleBundle(
+      [self bodyWithRules:@[ [self teamIDRule:@"BETABETABE"] ]], betaPrivateKey, NULL);
+  XCTAssertNotNil(alphaBundle);
+  XCTAssertNotNil(betaBundle);
+
+  SNTRuleSource* alpha = [self addSourceNamed:@"alpha"
+                                     interval:60
+                                   precedence:0
+                                   statusCode:200
+                                         body:alphaBundle
+                                    publicKey:alphaPublicKey];
+  SNTRuleSource* beta = [self addSourceNamed:@"beta"
+                                    interval:60
+                                  precedence:0
+                                  statusCode:200
+                                        body:betaBundle
+                                   publicKey:betaPublicKey];
+  // Expects alpha's key but serves a bundle signed with beta's key.
+  SNTRuleSource* crossSigned = [self addSourceNamed:@"crosssigned"
+                                           interval:60
+                                         precedence:0
+                                         statusCode:200
+                                               body:betaBundle
+                                          publicKey:alphaPublicKey];
+  // Expects a signed bundle but serves a plain response.
+  SNTRuleSource* unsignedSource =
+      [self addSourceNamed:@"unsigned"
+                  interval:60
+                precedence:0
+                statusCode:200
+                      body:[self bodyWithRules:@[ [self teamIDRule:@"ALPHAALPHA"] ]]
+                 publicKey:alphaPublicKey];
+
+  XCTestExpectation* alphaSynced = [self expectationWithDescription:@"alpha source synced"];
+  XCTestExpectation* betaSynced = [self expectationWithDescription:@"beta source synced"];
+  @synchronized(self) {
+    self.appliedExpectations[@"alpha"] = alphaSynced;
+    self.appliedExpectations[@"beta"] = betaSynced;
+  }
+  [self.sut updateSources:@[ alpha, beta, crossSigned, unsignedSource ]];
+  [self waitForExpectations:@[ alphaSynced, betaSynced ] timeout:5];
+
+  @synchronized(self) {
+    XCTAssertEqualObjects(self.applied[@"alpha"].firstObject.firstObject.identifier,
+                          @"ALPHAALPHA");
+    XCTAssertEqualObjects(self.applied[@"beta"].firstObject.firstObject.identifier,
+                          @"BETABETABE");
+  }
+
+  for (NSString* name in @[ @"crosssigned", @"unsigned" ]) {
+    [self waitForAttemptBySource:name];
+    NSDictionary* status = [self statusForSource:name];
+    XCTAssertNotNil(status[kRuleSourceStatusLastError], @"%@", name);
+    XCTAssertNil(status[kRuleSourceStatusLastSuccess], @"%@", name);
+    XCTAssertEqual([self appliedCountForSource:name], 0, @"%@", name);
+  }
+}
+
+- (void)testChangedPublicKeyReschedules {
+  SNTRuleSource* a = [self addSourceNamed:@"a" interval:60 precedence:0 statusCode:200 rules:@[]];
+  self.sut.startDelay = 3600;
+  [self.sut updateSources:@[ a ]];
+  NSNumber* nextSync = [self statusForSource:@"a"][kRuleSourceStatusNextSync];
+
+  self.sut.startDelay = 7200;
+  uint8_t key[ED25519_PUBLIC_KEY_LEN] = {};
+  [self.sut updateSources:@[ [[SNTRuleSource alloc]
+                              initWithName:@"a"
+                                       url:a.url
+                              syncInterval:60
+                                precedence:0
+                                 publicKey:[NSData dataWithBytes:key length:sizeof(key)]] ]];
+  XCTAssertGreaterThan([[self statusForSource:@"a"][kRuleSourceStatusNextSync] doubleValue],
+                       nextSync.doubleValue);
+}
+
+@end
@@ -0,0 +1,32 @@
+/// Copyright 2026 North Pole Security, Inc.
+///
+/// Licensed under the Apache License, Version 2.0 (the "License");
+/// you may not use this file except in compliance with the License.
+/// You may obtain a copy of the License at
+///
+///     http://www.apache.org/licenses/LICENSE-2.0
+///
+/// Unless required by applicable law or agreed to in writing, software
+/// distributed under the License is distributed on an "AS IS" BASIS,
+/// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+/// See the License for the specific language governing permissions and
+/// limitations under the License.
+
+#import <Foundation/Foundation.h>
+
+#import "Source/santasyncservice/SNTSyncStage.h"
+
+@class SNTStoredEvent;
+
+/// Uploads audit events for rule changes made on the machine and for launch items. The sync
+/// protocol has no message for these, so they are sent as JSON to their own endpoint rather than
+/// with the event upload.
+@interface SNTSyncAuditEventUpload : SNTSyncStage
+
+/// Returns YES for events that are uploaded by this stage rather than the event upload.
++ (BOOL)isAuditEvent:(SNTStoredEvent*)event;
+
+/// POST the events to the sync server and remove them from the events database once accepted.
+- (BOOL)uploadAuditEvents:(NSArray<SNTStoredEvent*>*)events;
+
+@end
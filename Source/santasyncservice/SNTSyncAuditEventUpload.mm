@@ -0,0 +1,180 @@
+/// Copyright 2026 North Pole Security, Inc.
+///
+/// Licensed under the Apache License, Version 2.0 (the "License");
+/// you may not use this file except in compliance with the License.
+/// You may obtain a copy of the License at
+///
+///     http://www.apache.org/licenses/LICENSE-2.0
+///
+/// Unless required by applicable law or agreed to in writing, software
+/// distributed under the License is distributed on an "AS IS" BASIS,
+/// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+/// See the License for the specific language governing permissions and
+/// limitations under the License.
+
+#import "Source/santasyncservice/SNTSyncAuditEventUpload.h"
+
+#import "Source/common/MOLXPCConnection.h"
+#import "Source/common/SNTRule.h"
+#import "Source/common/SNTStoredLaunchItemEvent.h"
+#import "Source/common/SNTStoredRuleChangeAuditEvent.h"
+#import "Source/common/SNTXPCControlInterface.h"
+#import "Source/santasyncservice/SNTSyncLogging.h"
+#import "Source/santasyncservice/SNTSyncState.h"
+
+static NSString* SourceString(SNTRuleChangeAuditSource source) {
+  switch (source) {
+    case SNTRuleChangeAuditSourceSantactl: return @"SANTACTL";
+    case SNTRuleChangeAuditSourceStandaloneApproval: return @"STANDALONE_APPROVAL";
+    case SNTRuleChangeAuditSourceTransitive: return @"TRANSITIVE";
+  }
+  return @"UNKNOWN";
+}
+
+static NSString* RuleCleanupString(SNTRuleCleanup cleanup) {
+  switch (cleanup) {
+    case SNTRuleCleanupNone: return @"NONE";
+    case SNTRuleCleanupAll: return @"ALL";
+    case SNTRuleCleanupNonTransitive: return @"NON_TRANSITIVE";
+    case SNTRuleCleanupStandalone: return @"STANDALONE";
+    case SNTRuleCleanupExecutionRules: return @"EXECUTION_RULES";
+    case SNTRuleCleanupFileAccessRules: return @"FILE_ACCESS_RULES";
+  }
+  return @"UNKNOWN";
+}
+
+static NSDictionary* ActorDictionary(SNTProcessChain* process) {
+  NSMutableDictionary* actor = [NSMutableDictionary dictionary];
+  if (process.filePath) actor[@"path"] = process.filePath;
+  if (process.pid) actor[@"pid"] = process.pid;
+  if (process.pidversion) actor[@"pidversion"] = process.pidversion;
+  if (process.executingUserID) actor[@"uid"] = process.executingUserID;
+  if (process.executingUser) actor[@"username"] = process.executingUser;
+  return actor;
+}
+
+static NSDictionary* RuleChangeDictionary(SNTStoredRuleChangeAuditEvent* event) {
+  NSMutableArray* rules = [NSMutableArray arrayWithCapacity:event.rules.count];
+  for (SNTRule* rule in event.rules) {
+    [rules addObject:[rule dictionaryRepresentation]];
+  }
+
+  return @{
+    @"rule_change" : @{
+      @"uuid" : event.uuid,
+      @"occurrence_time" : @([event.occurrenceDate timeIntervalSince1970]),
+      @"source" : SourceString(event.source),
+      @"rule_cleanup" : RuleCleanupString(event.ruleCleanup),
+      @"rules" : rules,
+      @"actor" : ActorDictionary(event.process),
+    },
+  };
+}
+
+static NSString* ItemTypeString(SNTStoredLaunchItemEventItemType type) {
+  switch (type) {
+    case SNTStoredLaunchItemEventItemTypeUnknown: return @"UNKNOWN";
+    case SNTStoredLaunchItemEventItemTypeUserItem: return @"USER_ITEM";
+    case SNTStoredLaunchItemEventItemTypeApp: return @"APP";
+    case SNTStoredLaunchItemEventItemTypeLoginItem: return @"LOGIN_ITEM";
+    case SNTStoredLaunchItemEventItemTypeAgent: return @"AGENT";
+    case SNTStoredLaunchItemEventItemTypeDaemon: return @"DAEMON";
+  }
+  return @"UNKNOWN";
+}
+
+static NSString* DecisionString(SNTStoredLaunchItemEventDecision decision) {
+  switch (decision) {
+    case SNTStoredLaunchItemEventDecisionAllowed: return @"ALLOWED";
+    case SNTStoredLaunchItemEventDecisionUnknown: return @"UNKNOWN";
+    case SNTStoredLaunchItemEventDecisionBlocked: return @"BLOCKED";
+  }
+  return @"UNKNOWN";
+}
+
+static NSDictionary* LaunchItemDictionary(SNTStoredLaunchItemEvent* event) {
+  NSMutableDictionary* item = [@{
+    @"uuid" : event.uuid,
+    @"occurrence_time" : @([event.occurrenceDate timeIntervalSince1970]),
+    @"item_type" : ItemTypeString(event.itemType),
+    @"legacy" : @(event.legacy),
+    @"managed" : @(event.managed),
+    @"item_path" : event.itemPath,
+    @"decision" : DecisionString(event.decision),
+    @"enforced" : @(event.enforced),
+    @"actor" : ActorDictionary(event.process),
+  } mutableCopy];
+  if (event.uid) item[@"uid"] = event.uid;
+  if (event.appPath) item[@"app_path"] = event.appPath;
+  if (event.executablePath) item[@"executable_path"] = event.executablePath;
+  if (event.teamID) item[@"team_id"] = event.teamID;
+  if (event.signingID) item[@"signing_id"] = event.signingID;
+
+  return @{@"launch_item" : item};
+}
+
+@implementation SNTSyncAuditEventUpload
+
++ (BOOL)isAuditEvent:(SNTStoredEvent*)event {
+  return [event isKindOfClass:[SNTStoredRuleChangeAuditEvent class]] ||
+         [event isKindOfClass:[SNTStoredLaunchItemEvent class]];
+}
+
+- (NSURL*)stageURL {
+  NSString* stageName =
+      [@"auditeventupload" stringByAppendingFormat:@"/%@", self.syncState.machineID];
+  return [NSURL URLWithString:stageName relativeToURL:self.syncState.syncBaseURL];
+}
+
+// Not used; this stage is invoked by the event upload stage with the events it was given.
+- (BOOL)sync {
+  return NO;
+}
+
+- (BOOL)uploadAuditEvents:(NSArray<SNTStoredEvent*>*)events {
+  if (!events.count) return YES;
+
+  NSMutableArray* auditEvents = [NSMutableArray arrayWithCapacity:events.count];
+  NSMutableArray* eventIds = [NSMutableArray arrayWithCapacity:events.count];
+  for (SNTStoredEvent* event in events) {
+    if ([event isKindOfClass:[SNTStoredRuleChangeAuditEvent class]]) {
+      [auditEvents addObject:RuleChangeDictionary((SNTStoredRuleChangeAuditEvent*)event)];
+    } else if ([event isKindOfClass:[SNTStoredLaunchItemEvent class]]) {
+      [auditEvents addObject:LaunchItemDictionary((SNTStoredLaunchItemEvent*)event)];
+    } else {
+      continue;
+    }
+    [eventIds addObject:event.idx];
+  }
+
+  NSDictionary* body = @{
+    @"machine_id" : self.syncState.machineID ?: @"",
+    @"audit_events" : auditEvents,
+  };
+  NSError* error;
+  NSData* data = [NSJSONSerialization dataWithJSONObject:body options:0 error:&error];
+  if (!data) {
+    SLOGE(@"Failed to encode audit events: %@", error.localizedDescription);
+    return NO;
+  }
+
+  NSMutableURLRequest* req = [self requestWithData:data contentType:@"application/json"];
+  NSInteger statusCode = 0;
+  error = [self performRequest:req intoMessage:NULL timeout:30 statusCode:&statusCode];
+  if (error) {
+    // A 404 means this sync server predates the audit event endpoint. Keep the events so they are
+    // uploaded once the server supports them, without failing the rest of the event upload.
+    if (statusCode == 404) {
+      SLOGD(@"Audit event endpoint unavailable (HTTP 404), keeping %lu events",
+            (unsigned long)events.count);
+      return YES;
+    }
+    SLOGE(@"Audit event upload failed: %@", error.localizedDescription);
+    return NO;
+  }
+
+  [[self.daemonConn remoteObjectProxy] databaseRemoveEventsWithIDs:eventIds];
+  return YES;
+}
+
+@end
@@ -0,0 +1,77 @@
+/// Copyright 2026 North Pole Security, Inc.
+///
+/// Licensed under the Apache License, Version 2.0 (the "License");
+/// you may not use this file except in compliance with the License.
+/// You may obtain a copy of the License at
+///
+///     http://www.apache.org/licenses/LICENSE-2.0
+///
+/// Unless required by applicable law or agreed to in writing, software
+/// distributed under the License is distributed on an "AS IS" BASIS,
+/// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+/// See the License for the specific language governing permissions and
+/// limitations under the License.
+
+
+#import <Foundation/Foundation.h>
+
+NS_ASSUME_NONNULL_BEGIN
+
+typedef NS_ENUM(NSInteger, SNTSyncCircuitBreakerState) {
+  // Requests flow normally.
+  SNTSyncCircuitBreakerStateClosed = 0,
+  // Too many consecutive failures; requests are rejected until the cooldown passes.
+  SNTSyncCircuitBreakerStateOpen,
+  // The cooldown has passed and a single probe request is allowed through.
+  SNTSyncCircuitBreakerStateHalfOpen,
+};
+
+/// Returns "closed", "open" or "half-open".
+NSString* SNTSyncCircuitBreakerStateName(SNTSyncCircuitBreakerState state);
+
+/// Guards the sync service's requests to the sync server. After failureThreshold
+/// consecutive failed requests the breaker opens and rejects requests for the
+/// cooldown period. Afterwards it half-opens and lets a single probe through: a
+/// successful probe closes the breaker, a failed one opens it again for another
+/// cooldown. Thread-safe.
+@interface SNTSyncCircuitBreaker : NSObject
+
+/// The number of consecutive failures that opens the breaker. 0 disables it.
+@property NSUInteger failureThreshold;
+
+/// How long the breaker stays open before allowing a probe.
+@property NSTimeInterval cooldown;
+
+@property(readonly) SNTSyncCircuitBreakerState state;
+@property(readonly) NSUInteger consecutiveFailures;
+
+/// When the breaker will next allow a probe, or nil if it is not open.
+@property(nullable, readonly) NSDate* retryAt;
+
+- (instancetype)initWithFailureThreshold:(NSUInteger)failureThreshold
+                                cooldown:(NSTimeInterval)cooldown NS_DESIGNATED_INITIALIZER;
+- (instancetype)init NS_UNAVAILABLE;
+
+/// Whether a request may be sent at `now`. An open breaker whose cooldown has
+/// passed moves to half-open and admits the caller as its probe; further
+/// callers are rejected until the probe's result is recorded.
+- (BOOL)allowRequestAt:(NSDate*)now;
+
+/// Record that a request reached the server and succeeded. Closes the breaker.
+- (void)recordSuccess;
+
+/// Record that a request failed in a way that indicates a server-side or
+/// network problem. May open the breaker as of `now`.
+- (void)recordFailureAt:(NSDate*)now;
+
+/// Record that a request ended without saying anything about the server, e.g.
+/// because the host is offline. Counts neither way, but lets another probe
+/// through if this request was the half-open probe.
+- (void)recordInconclusive;
+
+/// A snapshot of the breaker keyed by the kSyncCircuitBreaker* constants.
+- (NSDictionary*)status;
+
+@end
+
+NS_ASSUME_NONNULL_END
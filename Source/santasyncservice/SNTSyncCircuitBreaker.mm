@@ -0,0 +1,159 @@
+/// Copyright 2026 North Pole Security, Inc.
+///
+/// Licensed under the Apache License, Version 2.0 (the "License");
+/// you may not use this file except in compliance with the License.
+/// You may obtain a copy of the License at
+///
+///     http://www.apache.org/licenses/LICENSE-2.0
+///
+/// Unless required by applicable law or agreed to in writing, software
+/// distributed under the License is distributed on an "AS IS" BASIS,
+/// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+/// See the License for the specific language governing permissions and
+/// limitations under the License.
+
+
+#import "Source/santasyncservice/SNTSyncCircuitBreaker.h"
+
+#import "Source/common/SNTMetricSet.h"
+#import "Source/common/SNTSyncConstants.h"
+#import "Source/santasyncservice/SNTSyncLogging.h"
+
+NSString* SNTSyncCircuitBreakerStateName(SNTSyncCircuitBreakerState state) {
+  switch (state) {
+    case SNTSyncCircuitBreakerStateClosed: return @"closed";
+    case SNTSyncCircuitBreakerStateOpen: return @"open";
+    case SNTSyncCircuitBreakerStateHalfOpen: return @"half-open";
+  }
+  return @"unknown";
+}
+
+static SNTMetricStringGauge* CircuitBreakerStateGauge() {
+  static SNTMetricStringGauge* gauge;
+  static dispatch_once_t onceToken;
+  dispatch_once(&onceToken, ^{
+    gauge = [[SNTMetricSet sharedInstance]
+        stringGaugeWithName:@"/santa/sync/circuit_breaker/state"
+                 fieldNames:@[]
+                   helpText:@"State of the sync circuit breaker: closed, open or half-open"];
+  });
+  return gauge;
+}
+
+static SNTMetricCounter* CircuitBreakerOpenedCounter() {
+  static SNTMetricCounter* counter;
+  static dispatch_once_t onceToken;
+  dispatch_once(&onceToken, ^{
+    counter = [[SNTMetricSet sharedInstance]
+        counterWithName:@"/santa/sync/circuit_breaker/opened"
+             fieldNames:@[]
+               helpText:@"Number of times the sync circuit breaker has opened"];
+  });
+  return counter;
+}
+
+@interface SNTSyncCircuitBreaker ()
+@property(readwrite) SNTSyncCircuitBreakerState state;
+@property(readwrite) NSUInteger consecutiveFailures;
+@property(nullable) NSDate* openedAt;
+@property BOOL probeInFlight;
+@end
+
+@implementation SNTSyncCircuitBreaker
+
+- (instancetype)initWithFailureThreshold:(NSUInteger)failureThreshold
+                                cooldown:(NSTimeInterval)cooldown {
+  self = [super init];
+  if (self) {
+    _failureThreshold = failureThreshold;
+    _cooldown = cooldown;
+    _state = SNTSyncCircuitBreakerStateClosed;
+    [CircuitBreakerStateGauge() set:SNTSyncCircuitBreakerStateName(_state) forFieldValues:@[]];
+  }
+  return self;
+}
+
+- (BOOL)allowRequestAt:(NSDate*)now {
+  @synchronized(self) {
+    if (self.failureThreshold == 0) return YES;
+
+    switch (self.state) {
+      case SNTSyncCircuitBreakerStateClosed: return YES;
+      case SNTSyncCircuitBreakerStateOpen:
+        if ([now timeIntervalSinceDate:self.openedAt] < self.cooldown) return NO;
+        SLOGI(@"Sync circuit breaker half-open, probing sync server");
+        [self transitionTo:SNTSyncCircuitBreakerStateHalfOpen];
+        self.probeInFlight = YES;
+        return YES;
+      case SNTSyncCircuitBreakerStateHalfOpen:
+        if (self.probeInFlight) return NO;
+        self.probeInFlight = YES;
+        return YES;
+    }
+  }
+  return YES;
+}
+
+- (void)recordSuccess {
+  @synchronized(self) {
+    self.consecutiveFailures = 0;
+    self.probeInFlight = NO;
+    if (self.state != SNTSyncCircuitBreakerStateClosed) {
+      SLOGI(@"Sync circuit breaker closed");
+      self.openedAt = nil;
+      [self transitionTo:SNTSyncCircuitBreakerStateClosed];
+    }
+  }
+}
+
+- (void)recordFailureAt:(NSDate*)now {
+  @synchronized(self) {
+    self.consecutiveFailures++;
+    self.probeInFlight = NO;
+    if (self.failureThreshold == 0) return;
+
+    BOOL wasProbe = (self.state == SNTSyncCircuitBreakerStateHalfOpen);
+    if (wasProbe || (self.state == SNTSyncCircuitBreakerStateClosed &&
+                     self.consecutiveFailures >= self.failureThreshold)) {
+      self.openedAt = now;
+      [self transitionTo:SNTSyncCircuitBreakerStateOpen];
+      [CircuitBreakerOpenedCounter() incrementForFieldValues:@[]];
+      SLOGW(@"Sync circuit breaker opened after %lu consecutive failures, retrying in %.0fs",
+            self.consecutiveFailures, self.cooldown);
+    }
+  }
+}
+
+- (void)recordInconclusive {
+  @synchronized(self) {
+    self.probeInFlight = NO;
+  }
+}
+
+- (NSDate*)retryAt {
+  @synchronized(self) {
+    if (self.state != SNTSyncCircuitBreakerStateOpen) return nil;
+    return [self.openedAt dateByAddingTimeInterval:self.cooldown];
+  }
+}
+
+- (NSDictionary*)status {
+  @synchronized(self) {
+    NSMutableDictionary* status = [@{
+      kSyncCircuitBreakerState : SNTSyncCircuitBreakerStateName(self.state),
+      kSyncCircuitBreakerConsecutiveFailures : @(self.consecutiveFailures),
+    } mutableCopy];
+    NSDate* retryAt = self.retryAt;
+    if (retryAt) status[kSyncCircuitBreakerRetryAt] = @(retryAt.timeIntervalSince1970);
+    return status;
+  }
+}
+
+#pragma mark Internal Helpers
+
+- (void)transitionTo:(SNTSyncCircuitBreakerState)state {
+  self.state = state;
+  [CircuitBreakerStateGauge() set:SNTSyncCircuitBreakerStateName(state) forFieldValues:@[]];
+}
+
+@end
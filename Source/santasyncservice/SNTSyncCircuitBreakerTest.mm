@@ -0,0 +1,135 @@
+/// Copyright 2026 North Pole Security, Inc.
+///
+/// Licensed under the Apache License, Version 2.0 (the "License");
+/// you may not use this file except in compliance with the License.
+/// You may obtain a copy of the License at
+///
+///     http://www.apache.org/licenses/LICENSE-2.0
+///
+/// Unless required by applicable law or agreed to in writing, software
+/// distributed under the License is distributed on an "AS IS" BASIS,
+/// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+/// See the License for the specific language governing permissions and
+/// limitations under the License.
+
+
+#import <XCTest/XCTest.h>
+
+#import "Source/common/SNTSyncConstants.h"
+#import "Source/santasyncservice/SNTSyncCircuitBreaker.h"
+
+@interface SNTSyncCircuitBreakerTest : XCTestCase
+@property SNTSyncCircuitBreaker* breaker;
+@property NSDate* now;
+@end
+
+@implementation SNTSyncCircuitBreakerTest
+
+- (void)setUp {
+  [super setUp];
+  self.breaker = [[SNTSyncCircuitBreaker alloc] initWithFailureThreshold:3 cooldown:60];
+  self.now = [NSDate dateWithTimeIntervalSince1970:1700000000];
+}
+
+- (void)failTimes:(int)count {
+  for (int i = 0; i < count; ++i) {
+    XCTAssertTrue([self.breaker allowRequestAt:self.now]);
+    [self.breaker recordFailureAt:self.now];
+  }
+}
+
+- (void)testStaysClosedBelowThreshold {
+  [self failTimes:2];
+  XCTAssertEqual(self.breaker.state, SNTSyncCircuitBreakerStateClosed);
+  XCTAssertEqual(self.breaker.consecutiveFailures, 2u);
+  XCTAssertTrue([self.breaker allowRequestAt:self.now]);
+  XCTAssertNil(self.breaker.retryAt);
+}
+
+- (void)testSuccessResetsFailureCount {
+  [self failTimes:2];
+  [self.breaker recordSuccess];
+  [self failTimes:2];
+  XCTAssertEqual(self.breaker.state, SNTSyncCircuitBreakerStateClosed);
+  XCTAssertEqual(self.breaker.consecutiveFailures, 2u);
+}
+
+- (void)testOpensAfterConsecutiveFailures {
+  [self failTimes:3];
+  XCTAssertEqual(self.breaker.state, SNTSyncCircuitBreakerStateOpen);
+  XCTAssertEqualObjects(self.breaker.retryAt, [self.now dateByAddingTimeInterval:60]);
+
+  // Requests are rejected for the whole cooldown.
+  XCTAssertFalse([self.breaker allowRequestAt:self.now]);
+  XCTAssertFalse([self.breaker allowRequestAt:[self.now dateByAddingTimeInterval:59]]);
+  XCTAssertEqual(self.breaker.state, SNTSyncCircuitBreakerStateOpen);
+}
+
+- (void)testHalfOpensAfterCooldownAndClosesOnRecovery {
+  [self failTimes:3];
+
+  NSDate* afterCooldown = [self.now dateByAddingTimeInterval:60];
+  XCTAssertTrue([self.breaker allowRequestAt:afterCooldown]);
+  XCTAssertEqual(self.breaker.state, SNTSyncCircuitBreakerStateHalfOpen);
+
+  // Only a single probe is let through while half-open.
+  XCTAssertFalse([self.breaker allowRequestAt:afterCooldown]);
+
+  [self.breaker recordSuccess];
+  XCTAssertEqual(self.breaker.state, SNTSyncCircuitBreakerStateClosed);
+  XCTAssertEqual(self.breaker.consecutiveFailures, 0u);
+  XCTAssertTrue([self.breaker allowRequestAt:afterCooldown]);
+  XCTAssertTrue([self.breaker allowRequestAt:afterCooldown]);
+}
+
+- (void)testFailedProbeReopens {
+  [self failTimes:3];
+
+  NSDate* afterCooldown = [self.now dateByAddingTimeInterval:60];
+  XCTAssertTrue([self.breaker allowRequestAt:afterCooldown]);
+  [self.breaker recordFailureAt:afterCooldown];
+
+  XCTAssertEqual(self.breaker.state, SNTSyncCircuitBreakerStateOpen);
+  XCTAssertEqualObjects(self.breaker.retryAt, [afterCooldown dateByAddingTimeInterval:60]);
+  XCTAssertFalse([self.breaker allowRequestAt:[afterCooldown dateByAddingTimeInterval:30]]);
+}
+
+- (void)testInconclusiveProbeAllowsAnotherProbe {
+  [self failTimes:3];
+
+  NSDate* afterCooldown = [self.now dateByAddingTimeInterval:60];
+  XCTAssertTrue([self.breaker allowRequestAt:afterCooldown]);
+  [self.breaker recordInconclusive];
+
+  // The probe didn't reach the server, so the breaker stays half-open without counting it.
+  XCTAssertEqual(self.breaker.state, SNTSyncCircuitBreakerStateHalfOpen);
+  XCTAssertEqual(self.breaker.consecutiveFailures, 3u);
+  XCTAssertTrue([self.breaker allowRequestAt:afterCooldown]);
+  XCTAssertFalse([self.breaker allowRequestAt:afterCooldown]);
+
+  [self.breaker recordSuccess];
+  XCTAssertEqual(self.breaker.state, SNTSyncCircuitBreakerStateClosed);
+}
+
+- (void)testZeroThresholdDisablesBreaker {
+  self.breaker.failureThreshold = 0;
+  [self failTimes:20];
+  XCTAssertEqual(self.breaker.state, SNTSyncCircuitBreakerStateClosed);
+  XCTAssertTrue([self.breaker allowRequestAt:self.now]);
+}
+
+- (void)testStatus {
+  XCTAssertEqualObjects([self.breaker status], (@{
+                          kSyncCircuitBreakerState : @"closed",
+                          kSyncCircuitBreakerConsecutiveFailures : @0,
+                        }));
+
+  [self failTimes:3];
+  XCTAssertEqualObjects([self.breaker status], (@{
+                          kSyncCircuitBreakerState : @"open",
+                          kSyncCircuitBreakerConsecutiveFailures : @3,
+                          kSyncCircuitBreakerRetryAt : @(self.now.timeIntervalSince1970 + 60),
+                        }));
+}
+
+@end
@@ -55,6 +55,8 @@
 @property NSArray<SNTCELFallbackRule*>* celFallbackRules;
 @property NSNumber* fullSyncInterval;
 @property NSNumber* pushNotificationsFullSyncInterval;
+@property NSNumber* telemetrySampleRate;
+@property NSString* generatedMachineID;
 @property NSNumber* clearSyncStateBeforeApply;
 @end
 
@@ -64,6 +66,7 @@ SNTConfigBundle* PreflightConfigBundle(SNTSyncState* syncState) {
   if (syncState.pushIssuerJWT.length && syncState.pushJWT.length) {
     bundle.pushTokenChain = @[ syncState.pushIssuerJWT, syncState.pushJWT ];
   }
+  bundle.generatedMachineID = syncState.generatedMachineID;
 
   return bundle;
 }
@@ -102,6 +105,8 @@ SNTConfigBundle* PostflightConfigBundle(SNTSyncState* syncState) {
   bundle.celFallbackRules = syncState.celFallbackRules;
   bundle.fullSyncInterval = syncState.fullSyncInterval;
   bundle.pushNotificationsFullSyncInterval = syncState.pushNotificationsFullSyncInterval;
+  // Always set, so that a server that stops sending a rate goes back to storing every event.
+  bundle.telemetrySampleRate = syncState.telemetrySampleRate ?: @(1.0);
 
   bundle.fullSyncLastSuccess = [NSDate now];
 
@@ -58,6 +58,8 @@
 @property NSArray<SNTCELFallbackRule*>* celFallbackRules;
 @property NSNumber* fullSyncInterval;
 @property NSNumber* pushNotificationsFullSyncInterval;
+@property NSNumber* telemetrySampleRate;
+@property NSString* generatedMachineID;
 @property NSNumber* clearSyncStateBeforeApply;
 @end
 
@@ -100,6 +102,12 @@
   XCTAssertNil(bundle.networkExtensionSettings);
   XCTAssertNil(bundle.telemetryFilterExpressions);
   XCTAssertNil(bundle.celFallbackRules);
+  XCTAssertNil(bundle.generatedMachineID);
+  XCTAssertNil(bundle.telemetrySampleRate);
+
+  syncState.generatedMachineID = @"6F2C1B9E-3D4A-4E5F-8A7B-9C0D1E2F3A4B";
+  bundle = PreflightConfigBundle(syncState);
+  XCTAssertEqualObjects(bundle.generatedMachineID, @"6F2C1B9E-3D4A-4E5F-8A7B-9C0D1E2F3A4B");
 }
 
 - (void)testPostflightConfigBundle {
@@ -192,6 +200,11 @@
   bundle = PostflightConfigBundle(syncState);
   XCTAssertEqualObjects(bundle.pushNotificationsFullSyncInterval, @(7200));
 
+  XCTAssertEqualObjects(bundle.telemetrySampleRate, @(1.0));
+  syncState.telemetrySampleRate = @(0.25);
+  bundle = PostflightConfigBundle(syncState);
+  XCTAssertEqualObjects(bundle.telemetrySampleRate, @(0.25));
+
   // When the server doesn't set the intervals, they should be nil
   syncState.fullSyncInterval = nil;
   syncState.pushNotificationsFullSyncInterval = nil;
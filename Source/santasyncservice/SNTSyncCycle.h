@@ -0,0 +1,56 @@
+/// Copyright 2026 North Pole Security, Inc.
+///
+/// Licensed under the Apache License, Version 2.0 (the "License");
+/// you may not use this file except in compliance with the License.
+/// You may obtain a copy of the License at
+///
+///     http://www.apache.org/licenses/LICENSE-2.0
+///
+/// Unless required by applicable law or agreed to in writing, software
+/// distributed under the License is distributed on an "AS IS" BASIS,
+/// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+/// See the License for the specific language governing permissions and
+/// limitations under the License.
+
+#import <Foundation/Foundation.h>
+
+#import "Source/common/SNTCommonEnums.h"
+
+@class SNTSyncState;
+
+NS_ASSUME_NONNULL_BEGIN
+
+/// The outcome of a sync cycle, taken from the sync state once the cycle stops.
+@interface SNTSyncCycleResult : NSObject
+
+/// SNTSyncStatusTypeSuccess, or the status of the stage that failed.
+@property(readonly) SNTSyncStatusType status;
+
+/// The sync type and client mode the server set during preflight.
+@property(readonly) SNTSyncType syncType;
+@property(readonly) SNTClientMode clientMode;
+
+/// The number of rules downloaded and the number the daemon applied.
+@property(readonly) NSUInteger rulesReceived;
+@property(readonly) NSUInteger rulesProcessed;
+@property(readonly) NSUInteger fileAccessRulesReceived;
+@property(readonly) NSUInteger fileAccessRulesProcessed;
+
+@end
+
+/// Runs a full sync cycle (preflight, event upload, signal upload, rule download and postflight)
+/// in-process against the session and daemon connection in the given sync state. Unlike
+/// SNTSyncManager no timers are rescheduled, no push client is created and no queued commands are
+/// drained, so tests can drive the sync stack end to end against a stubbed server.
+@interface SNTSyncCycle : NSObject
+
+- (instancetype)initWithSyncState:(SNTSyncState*)syncState NS_DESIGNATED_INITIALIZER;
+- (instancetype)init NS_UNAVAILABLE;
+
+/// Runs each stage in order, stopping at the first stage that fails. A signal upload failure does
+/// not stop the cycle, as with a regular sync.
+- (SNTSyncCycleResult*)run;
+
+@end
+
+NS_ASSUME_NONNULL_END
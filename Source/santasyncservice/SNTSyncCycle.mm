@@ -0,0 +1,98 @@
+/// Copyright 2026 North Pole Security, Inc.
+///
+/// Licensed under the Apache License, Version 2.0 (the "License");
+/// you may not use this file except in compliance with the License.
+/// You may obtain a copy of the License at
+///
+///     http://www.apache.org/licenses/LICENSE-2.0
+///
+/// Unless required by applicable law or agreed to in writing, software
+/// distributed under the License is distributed on an "AS IS" BASIS,
+/// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+/// See the License for the specific language governing permissions and
+/// limitations under the License.
+
+#import "Source/santasyncservice/SNTSyncCycle.h"
+
+#import "Source/santasyncservice/SNTSyncEventUpload.h"
+#import "Source/santasyncservice/SNTSyncLogging.h"
+#import "Source/santasyncservice/SNTSyncPostflight.h"
+#import "Source/santasyncservice/SNTSyncPreflight.h"
+#import "Source/santasyncservice/SNTSyncRuleDownload.h"
+#import "Source/santasyncservice/SNTSyncSignalUpload.h"
+#import "Source/santasyncservice/SNTSyncState.h"
+
+@interface SNTSyncCycleResult ()
+@property(readwrite) SNTSyncStatusType status;
+@property(readwrite) SNTSyncType syncType;
+@property(readwrite) SNTClientMode clientMode;
+@property(readwrite) NSUInteger rulesReceived;
+@property(readwrite) NSUInteger rulesProcessed;
+@property(readwrite) NSUInteger fileAccessRulesReceived;
+@property(readwrite) NSUInteger fileAccessRulesProcessed;
+@end
+
+@implementation SNTSyncCycleResult
+@end
+
+@interface SNTSyncCycle ()
+@property SNTSyncState* syncState;
+@end
+
+@implementation SNTSyncCycle
+
+- (instancetype)initWithSyncState:(SNTSyncState*)syncState {
+  self = [super init];
+  if (self) {
+    _syncState = syncState;
+  }
+  return self;
+}
+
+- (SNTSyncCycleResult*)run {
+  return [self resultWithStatus:[self runStages]];
+}
+
+- (SNTSyncStatusType)runStages {
+  SLOGD(@"Sync cycle: preflight starting");
+  if (![[[SNTSyncPreflight alloc] initWithState:self.syncState] sync]) {
+    return SNTSyncStatusTypePreflightFailed;
+  }
+  if (self.syncState.preflightOnly) return SNTSyncStatusTypeSuccess;
+
+  SLOGD(@"Sync cycle: event upload starting");
+  if (![[[SNTSyncEventUpload alloc] initWithState:self.syncState] sync]) {
+    return SNTSyncStatusTypeEventUploadFailed;
+  }
+
+  // Signal report upload is only supported in sync v2 and is best-effort.
+  if (self.syncState.isSyncV2 &&
+      ![[[SNTSyncSignalUpload alloc] initWithState:self.syncState] sync]) {
+    SLOGE(@"Sync cycle: signal upload failed, continuing");
+  }
+
+  SLOGD(@"Sync cycle: rule download starting");
+  if (![[[SNTSyncRuleDownload alloc] initWithState:self.syncState] sync]) {
+    return SNTSyncStatusTypeRuleDownloadFailed;
+  }
+
+  SLOGD(@"Sync cycle: postflight starting");
+  if (![[[SNTSyncPostflight alloc] initWithState:self.syncState] sync]) {
+    return SNTSyncStatusTypePostflightFailed;
+  }
+  return SNTSyncStatusTypeSuccess;
+}
+
+- (SNTSyncCycleResult*)resultWithStatus:(SNTSyncStatusType)status {
+  SNTSyncCycleResult* result = [[SNTSyncCycleResult alloc] init];
+  result.status = status;
+  result.syncType = self.syncState.syncType;
+  result.clientMode = self.syncState.clientMode;
+  result.rulesReceived = self.syncState.rulesReceived;
+  result.rulesProcessed = self.syncState.rulesProcessed;
+  result.fileAccessRulesReceived = self.syncState.fileAccessRulesReceived;
+  result.fileAccessRulesProcessed = self.syncState.fileAccessRulesProcessed;
+  return result;
+}
+
+@end
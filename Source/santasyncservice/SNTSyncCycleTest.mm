@@ -0,0 +1,268 @@
+/// Copyright 2026 North Pole Security, Inc.
+///
+/// Licensed under the Apache License, Version 2.0 (the "License");
+/// you may not use this file except in compliance with the License.
+/// You may obtain a copy of the License at
+///
+///     http://www.apache.org/licenses/LICENSE-2.0
+///
+/// Unless required by applicable law or agreed to in writing, software
+/// distributed under the License is distributed on an "AS IS" BASIS,
+/// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+/// See the License for the specific language governing permissions and
+/// limitations under the License.
+
+#import <Foundation/Foundation.h>
+#import <OCMock/OCMock.h>
+#import <XCTest/XCTest.h>
+
+#import "Source/common/MOLXPCConnection.h"
+#import "Source/common/SNTCommonEnums.h"
+#import "Source/common/SNTConfigurator.h"
+#import "Source/common/SNTRule.h"
+#import "Source/common/SNTSIPStatus.h"
+#import "Source/common/SNTStoredEvent.h"
+#import "Source/common/SNTSystemInfo.h"
+#import "Source/common/SNTXPCControlInterface.h"
+#import "Source/santasyncservice/SNTSyncCycle.h"
+#import "Source/santasyncservice/SNTSyncState.h"
+
+@interface SNTSyncCycleTest : XCTestCase
+@property SNTSyncState* syncState;
+@property id<SNTDaemonControlXPC> daemonConnRop;
+@property id configMock;
+@property id siMock;
+@property NSMutableSet<SNTRule*>* localRules;
+
+/// The mock server's responses, keyed by stage name. Stages without a response return a 404.
+@property NSMutableDictionary<NSString*, NSHTTPURLResponse*>* responses;
+@property NSMutableDictionary<NSString*, NSData*>* bodies;
+
+/// The stages the mock server received requests for, in the order they were first requested.
+@property NSMutableOrderedSet<NSString*>* requestedStages;
+@end
+
+@implementation SNTSyncCycleTest
+
+- (void)setUp {
+  [super setUp];
+
+  self.configMock = OCMClassMock([SNTConfigurator class]);
+  OCMStub([self.configMock configurator]).andReturn(self.configMock);
+  OCMStub([self.configMock syncEnableProtoTransfer]).andReturn(NO);
+
+  self.siMock = OCMClassMock([SNTSystemInfo class]);
+  OCMStub([self.siMock serialNumber]).andReturn(@"QYGF4QM373");
+  OCMStub([self.siMock longHostname]).andReturn(@"full-hostname.example.com");
+  OCMStub([self.siMock osVersion]).andReturn(@"14.5");
+  OCMStub([self.siMock osBuild]).andReturn(@"23F79");
+  OCMStub([self.siMock modelIdentifier]).andReturn(@"MacBookPro18,3");
+  OCMStub([self.siMock santaFullVersion]).andReturn(@"2024.6.655965194");
+  OCMStub([self.siMock santanetdBundledVersion]).andReturn(nil);
+
+  id sipMock = OCMClassMock([SNTSIPStatus class]);
+  OCMStub([sipMock currentStatus]).andReturn(0x6f);
+
+  self.syncState = [[SNTSyncState alloc] init];
+  self.syncState.daemonConn = OCMClassMock([MOLXPCConnection class]);
+  self.daemonConnRop = OCMProtocolMock(@protocol(SNTDaemonControlXPC));
+  OCMStub([self.syncState.daemonConn remoteObjectProxy]).andReturn(self.daemonConnRop);
+  OCMStub([self.syncState.daemonConn synchronousRemoteObjectProxy]).andReturn(self.daemonConnRop);
+  self.syncState.session = OCMClassMock([NSURLSession class]);
+  self.syncState.syncBaseURL = [NSURL URLWithString:@"https://cycle.local/"];
+  self.syncState.machineID = @"50C7E1EB-2EF5-42D4-A084-A7966FC45A95";
+  self.syncState.machineOwner = @"username1";
+  self.syncState.eventBatchSize = 50;
+
+  self.localRules = [NSMutableSet set];
+  self.responses = [NSMutableDictionary dictionary];
+  self.bodies = [NSMutableDictionary dictionary];
+  self.requestedStages = [NSMutableOrderedSet orderedSet];
+  [self setupFakeDaemon];
+  [self setupMockServer];
+}
+
+- (void)tearDown {
+  [self.configMock stopMocking];
+  [self.siMock stopMocking];
+  [super tearDown];
+}
+
+#pragma mark Test Helpers
+
+- (NSData*)dataFromFixture:(NSString*)file {
+  NSString* path = [[NSBundle bundleForClass:[self class]] pathForResource:file ofType:nil];
+  XCTAssertNotNil(path, @"failed to load testdata: %@", file);
+  return [NSData dataWithContentsOfFile:path];
+}
+
+/// Back the daemon connection with an in-memory rule database, so that tests can check which
+/// rules the cycle applied.
+- (void)setupFakeDaemon {
+  struct RuleCounts ruleCounts = {};
+  OCMStub([self.daemonConnRop
+      databaseRuleCounts:([OCMArg invokeBlockWithArgs:OCMOCK_VALUE(ruleCounts), nil])]);
+  OCMStub([self.daemonConnRop
+      syncTypeRequired:([OCMArg invokeBlockWithArgs:OCMOCK_VALUE(SNTSyncTypeNormal), nil])]);
+  OCMStub([self.daemonConnRop
+      clientMode:([OCMArg invokeBlockWithArgs:OCMOCK_VALUE(SNTClientModeMonitor), nil])]);
+  OCMStub([self.daemonConnRop
+      databaseRulesHash:([OCMArg invokeBlockWithArgs:@"the-hash", @"the-faa-hash", @"the-nf-hash",
+                                                     @"the-signal-hash", nil])]);
+  OCMStub([self.daemonConnRop
+      effectiveConfigHash:([OCMArg invokeBlockWithArgs:@"the-config-hash", nil])]);
+  OCMStub([self.daemonConnRop
+      networkExtensionLoadedBundleVersionInfo:([OCMArg invokeBlockWithArgs:@{}, nil])]);
+  OCMStub([self.daemonConnRop updateSyncSettings:[OCMArg any] reply:([OCMArg invokeBlock])]);
+  OCMStub([self.daemonConnRop postRuleSyncNotificationForApplication:[OCMArg any]
+                                                               reply:([OCMArg invokeBlock])]);
+
+  OCMStub([[(id)self.daemonConnRop ignoringNonObjectArgs]
+              databaseRuleAddExecutionRules:[OCMArg any]
+                            fileAccessRules:[OCMArg any]
+                           networkFlowRules:[OCMArg any]
+                                    signals:[OCMArg any]
+                                ruleCleanup:SNTRuleCleanupNone
+                                     source:SNTRuleAddSourceSyncService
+                                      reply:[OCMArg any]])
+      .andDo(^(NSInvocation* invocation) {
+        __unsafe_unretained NSArray<SNTRule*>* rules;
+        void (^reply)(BOOL, NSArray<NSError*>*);
+        [invocation getArgument:&rules atIndex:2];
+        [invocation getArgument:&reply atIndex:8];
+        [self.localRules addObjectsFromArray:rules];
+        reply(YES, nil);
+      });
+}
+
+/// Route every request to the response configured for its stage and record the stage.
+- (void)setupMockServer {
+  OCMStub([self.syncState.session dataTaskWithRequest:[OCMArg any] completionHandler:[OCMArg any]])
+      .andDo(^(NSInvocation* invocation) {
+        __unsafe_unretained NSURLRequest* req;
+        void (^handler)(NSData*, NSURLResponse*, NSError*);
+        [invocation getArgument:&req atIndex:2];
+        [invocation getArgument:&handler atIndex:3];
+
+        NSString* stage = req.URL.pathComponents.count > 1 ? req.URL.pathComponents[1] : @"";
+        [self.requestedStages addObject:stage];
+        NSHTTPURLResponse* resp = self.responses[stage]
+                                      ?: [[NSHTTPURLResponse alloc] initWithURL:req.URL
+                                                                     statusCode:404
+                                                                    HTTPVersion:@"1.1"
+                                                                   headerFields:nil];
+        handler(self.bodies[stage] ?: [NSData data], resp, nil);
+      });
+}
+
+- (void)stubStage:(NSString*)stage statusCode:(NSInteger)code body:(NSData*)body {
+  self.responses[stage] = [[NSHTTPURLResponse alloc] initWithURL:self.syncState.syncBaseURL
+                                                      statusCode:code
+                                                     HTTPVersion:@"1.1"
+                                                    headerFields:nil];
+  self.bodies[stage] = body;
+}
+
+- (void)stubPendingEvents {
+  NSSet* allowedClasses = [NSSet setWithObjects:[NSArray class], [SNTStoredEvent class], nil];
+  NSArray* events = [NSKeyedUnarchiver
+      unarchivedObjectOfClasses:allowedClasses
+                       fromData:[self dataFromFixture:@"sync_eventupload_input_basic.plist"]
+                          error:nil];
+  XCTAssertGreaterThan(events.count, 0);
+  OCMStub([self.daemonConnRop databaseEventsPending:([OCMArg invokeBlockWithArgs:events, nil])]);
+}
+
+- (void)stubSuccessfulServer {
+  NSData* empty = [@"{}" dataUsingEncoding:NSUTF8StringEncoding];
+  [self stubStage:@"preflight"
+       statusCode:200
+             body:[self dataFromFixture:@"sync_preflight_lockdown.json"]];
+  [self stubStage:@"eventupload" statusCode:200 body:empty];
+  [self stubStage:@"ruledownload"
+       statusCode:200
+             body:[self dataFromFixture:@"sync_ruledownload_batch2.json"]];
+  [self stubStage:@"postflight" statusCode:200 body:empty];
+}
+
+/// The rules in sync_ruledownload_batch2.json.
+- (NSSet<SNTRule*>*)downloadedRules {
+  return [NSSet setWithArray:@[
+    [[SNTRule alloc]
+        initWithIdentifier:@"7846698e47ef41be80b83fb9e2b98fa6dc46c9188b068bff323c302955a00142"
+                     state:SNTRuleStateBlock
+                      type:SNTRuleTypeCertificate],
+    [[SNTRule alloc] initWithIdentifier:@"AAAAAAAAAA"
+                                  state:SNTRuleStateBlock
+                                   type:SNTRuleTypeTeamID],
+  ]];
+}
+
+#pragma mark Tests
+
+- (void)testCycleRunsEveryStageInOrder {
+  [self stubPendingEvents];
+  [self stubSuccessfulServer];
+
+  SNTSyncCycleResult* result = [[[SNTSyncCycle alloc] initWithSyncState:self.syncState] run];
+
+  XCTAssertEqual(result.status, SNTSyncStatusTypeSuccess);
+  XCTAssertEqualObjects(self.requestedStages.array,
+                        (@[ @"preflight", @"eventupload", @"ruledownload", @"postflight" ]));
+  XCTAssertEqual(result.syncType, SNTSyncTypeNormal);
+  XCTAssertEqual(result.clientMode, SNTClientModeLockdown);
+  XCTAssertEqual(result.rulesReceived, 2);
+  XCTAssertEqual(result.rulesProcessed, 2);
+  XCTAssertEqualObjects(self.localRules, [self downloadedRules]);
+}
+
+- (void)testCycleWithoutPendingEventsSkipsEventUploadRequest {
+  OCMStub([self.daemonConnRop databaseEventsPending:([OCMArg invokeBlockWithArgs:@[], nil])]);
+  [self stubSuccessfulServer];
+
+  SNTSyncCycleResult* result = [[[SNTSyncCycle alloc] initWithSyncState:self.syncState] run];
+
+  XCTAssertEqual(result.status, SNTSyncStatusTypeSuccess);
+  XCTAssertEqualObjects(self.requestedStages.array,
+                        (@[ @"preflight", @"ruledownload", @"postflight" ]));
+}
+
+- (void)testCyclePreflightOnlyStopsAfterPreflight {
+  OCMStub([self.daemonConnRop databaseEventsPending:([OCMArg invokeBlockWithArgs:@[], nil])]);
+  [self stubSuccessfulServer];
+  self.syncState.preflightOnly = YES;
+
+  SNTSyncCycleResult* result = [[[SNTSyncCycle alloc] initWithSyncState:self.syncState] run];
+
+  XCTAssertEqual(result.status, SNTSyncStatusTypeSuccess);
+  XCTAssertEqual(result.clientMode, SNTClientModeLockdown);
+  XCTAssertEqualObjects(self.requestedStages.array, @[ @"preflight" ]);
+  XCTAssertEqual(self.localRules.count, 0);
+}
+
+- (void)testCycleStopsAtFailedPreflight {
+  [self stubPendingEvents];
+  [self stubSuccessfulServer];
+  [self stubStage:@"preflight" statusCode:400 body:nil];
+
+  SNTSyncCycleResult* result = [[[SNTSyncCycle alloc] initWithSyncState:self.syncState] run];
+
+  XCTAssertEqual(result.status, SNTSyncStatusTypePreflightFailed);
+  XCTAssertEqualObjects(self.requestedStages.array, @[ @"preflight" ]);
+  XCTAssertEqual(self.localRules.count, 0);
+}
+
+- (void)testCycleStopsAtFailedRuleDownload {
+  OCMStub([self.daemonConnRop databaseEventsPending:([OCMArg invokeBlockWithArgs:@[], nil])]);
+  [self stubSuccessfulServer];
+  [self stubStage:@"ruledownload" statusCode:400 body:nil];
+
+  SNTSyncCycleResult* result = [[[SNTSyncCycle alloc] initWithSyncState:self.syncState] run];
+
+  XCTAssertEqual(result.status, SNTSyncStatusTypeRuleDownloadFailed);
+  XCTAssertEqualObjects(self.requestedStages.array, (@[ @"preflight", @"ruledownload" ]));
+  XCTAssertEqual(result.rulesProcessed, 0);
+  XCTAssertEqual(self.localRules.count, 0);
+}
+
+@end
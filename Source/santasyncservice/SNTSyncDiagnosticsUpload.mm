@@ -0,0 +1,52 @@
+/// Copyright 2026 North Pole Security, Inc.
+///
+/// Licensed under the Apache License, Version 2.0 (the "License");
+/// you may not use this file except in compliance with the License.
+/// You may obtain a copy of the License at
+///
+///     http://www.apache.org/licenses/LICENSE-2.0
+///
+/// Unless required by applicable law or agreed to in writing, software
+/// distributed under the License is distributed on an "AS IS" BASIS,
+/// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+/// See the License for the specific language governing permissions and
+/// limitations under the License.
+
+
+#import "Source/santasyncservice/SNTSyncDiagnosticsUpload.h"
+
+#import "Source/santasyncservice/SNTSyncLogging.h"
+#import "Source/santasyncservice/SNTSyncState.h"
+
+@implementation SNTSyncDiagnosticsUpload
+
+- (NSURL*)stageURL {
+  NSString* stageName = [@"diagnostics" stringByAppendingFormat:@"/%@", self.syncState.machineID];
+  return [NSURL URLWithString:stageName relativeToURL:self.syncState.syncBaseURL];
+}
+
+// Not used; this stage is invoked directly via uploadDiagnostics: rather than the standard sync
+// flow.
+- (BOOL)sync {
+  return NO;
+}
+
+- (BOOL)uploadDiagnostics:(NSDictionary*)bundle {
+  NSError* error;
+  NSData* data = [NSJSONSerialization dataWithJSONObject:bundle options:0 error:&error];
+  if (!data) {
+    SLOGE(@"Failed to encode diagnostics: %@", error.localizedDescription);
+    return NO;
+  }
+
+  NSMutableURLRequest* req = [self requestWithData:data contentType:@"application/json"];
+  error = [self performRequest:req intoMessage:NULL timeout:60];
+  if (error) {
+    SLOGE(@"Diagnostics upload failed: %@", error.localizedDescription);
+    return NO;
+  }
+
+  return YES;
+}
+
+@end
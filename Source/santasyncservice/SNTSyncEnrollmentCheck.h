@@ -0,0 +1,41 @@
+/// Copyright 2026 North Pole Security, Inc.
+///
+/// Licensed under the Apache License, Version 2.0 (the "License");
+/// you may not use this file except in compliance with the License.
+/// You may obtain a copy of the License at
+///
+///     http://www.apache.org/licenses/LICENSE-2.0
+///
+/// Unless required by applicable law or agreed to in writing, software
+/// distributed under the License is distributed on an "AS IS" BASIS,
+/// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+/// See the License for the specific language governing permissions and
+/// limitations under the License.
+
+
+#import <Foundation/Foundation.h>
+
+@class SNTSyncState;
+
+NS_ASSUME_NONNULL_BEGIN
+
+/// Simulates a fresh enrollment against a sync server: a clean sync preflight, a full rule
+/// download, an initial (empty) event upload and, if the server handed out push credentials, a
+/// push connection. The postflight is never sent. The sync state is put in dry run mode with a
+/// throwaway machine ID so nothing is applied to santad, the server's record for this host is
+/// left alone and the host's regular sync schedule and push client are not affected.
+@interface SNTSyncEnrollmentCheck : NSObject
+
+/// How long to wait for the push connection to come up. Defaults to 15 seconds.
+@property NSTimeInterval pushConnectTimeout;
+
+- (instancetype)initWithSyncState:(SNTSyncState*)syncState NS_DESIGNATED_INITIALIZER;
+- (instancetype)init NS_UNAVAILABLE;
+
+/// Runs each phase in order and returns one result per phase, keyed by the kEnrollmentTest*
+/// constants. Once a phase fails, all following phases are reported as skipped.
+- (NSArray<NSDictionary<NSString*, NSString*>*>*)run;
+
+@end
+
+NS_ASSUME_NONNULL_END
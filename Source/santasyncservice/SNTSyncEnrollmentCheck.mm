@@ -0,0 +1,201 @@
+/// Copyright 2026 North Pole Security, Inc.
+///
+/// Licensed under the Apache License, Version 2.0 (the "License");
+/// you may not use this file except in compliance with the License.
+/// You may obtain a copy of the License at
+///
+///     http://www.apache.org/licenses/LICENSE-2.0
+///
+/// Unless required by applicable law or agreed to in writing, software
+/// distributed under the License is distributed on an "AS IS" BASIS,
+/// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+/// See the License for the specific language governing permissions and
+/// limitations under the License.
+
+
+#import "Source/santasyncservice/SNTSyncEnrollmentCheck.h"
+
+#import "Source/common/SNTCommonEnums.h"
+#import "Source/common/SNTError.h"
+#import "Source/common/SNTSyncConstants.h"
+#import "Source/santasyncservice/SNTPushClientNATS.h"
+#import "Source/santasyncservice/SNTPushNotifications.h"
+#import "Source/santasyncservice/SNTSyncEventUpload.h"
+#import "Source/santasyncservice/SNTSyncLogging.h"
+#import "Source/santasyncservice/SNTSyncPreflight.h"
+#import "Source/santasyncservice/SNTSyncRuleDownload.h"
+#import "Source/santasyncservice/SNTSyncState.h"
+
+static NSString* SyncTypeName(SNTSyncType syncType) {
+  switch (syncType) {
+    case SNTSyncTypeNormal: return @"normal";
+    case SNTSyncTypeClean: return @"clean";
+    case SNTSyncTypeCleanAll: return @"clean all";
+  }
+  return @"unknown";
+}
+
+/// Sync delegate for the throwaway push client. Anything the server pushes during the check is
+/// ignored so that it cannot trigger a real sync or command on the host.
+@interface SNTSyncEnrollmentCheckPushDelegate : NSObject <SNTPushNotificationsSyncDelegate>
+@end
+
+@implementation SNTSyncEnrollmentCheckPushDelegate
+
+- (void)sync {
+}
+
+- (void)syncSecondsFromNow:(uint64_t)seconds {
+}
+
+- (void)ruleSync {
+}
+
+- (void)ruleSyncSecondsFromNow:(uint64_t)seconds {
+}
+
+- (void)preflightSync {
+}
+
+- (void)pushNotificationSyncSecondsFromNow:(uint64_t)seconds {
+}
+
+- (MOLXPCConnection*)daemonConnection {
+  return nil;
+}
+
+- (void)eventUploadForPaths:(NSArray<NSString*>*)paths reply:(void (^)(NSError* error))reply {
+  reply([SNTError createErrorWithFormat:@"Event uploads are ignored during an enrollment check"]);
+}
+
+@end
+
+@interface SNTSyncEnrollmentCheck ()
+@property SNTSyncState* syncState;
+@end
+
+@implementation SNTSyncEnrollmentCheck
+
+- (instancetype)initWithSyncState:(SNTSyncState*)syncState {
+  self = [super init];
+  if (self) {
+    _syncState = syncState;
+    _syncState.daemonConn = nil;
+    _syncState.dryRun = YES;
+    // Enroll as a new machine so the server doesn't reset, or attach rules to, this host's record.
+    _syncState.machineID = [[NSUUID UUID] UUIDString];
+    _syncState.syncType = SNTSyncTypeCleanAll;
+    _pushConnectTimeout = 15;
+  }
+  return self;
+}
+
+- (NSArray<NSDictionary<NSString*, NSString*>*>*)run {
+  NSMutableArray* results = [NSMutableArray array];
+  __block BOOL failed = NO;
+
+  void (^runPhase)(NSString*, NSString* (^)(NSString**)) =
+      ^(NSString* phase, NSString* (^block)(NSString** detail)) {
+        if (failed) {
+          [results addObject:@{
+            kEnrollmentTestPhase : phase,
+            kEnrollmentTestStatus : kEnrollmentTestStatusSkipped,
+            kEnrollmentTestDetail : @"A previous phase failed",
+          }];
+          return;
+        }
+
+        SLOGI(@"Enrollment check: %@ starting", phase);
+        NSString* detail;
+        NSString* status = block(&detail);
+        if ([status isEqualToString:kEnrollmentTestStatusFailed]) failed = YES;
+        SLOGI(@"Enrollment check: %@ %@", phase, status);
+        [results addObject:@{
+          kEnrollmentTestPhase : phase,
+          kEnrollmentTestStatus : status,
+          kEnrollmentTestDetail : detail ?: @"",
+        }];
+      };
+
+  runPhase(@"preflight", ^NSString*(NSString** detail) {
+    SNTSyncPreflight* p = [[SNTSyncPreflight alloc] initWithState:self.syncState];
+    if (![p sync]) {
+      *detail = @"Preflight request failed";
+      return kEnrollmentTestStatusFailed;
+    }
+    *detail = [NSString stringWithFormat:@"Sync protocol v%d, %@ sync",
+                                         self.syncState.isSyncV2 ? 2 : 1,
+                                         SyncTypeName(self.syncState.syncType)];
+    return kEnrollmentTestStatusPassed;
+  });
+
+  runPhase(@"ruledownload", ^NSString*(NSString** detail) {
+    SNTSyncRuleDownload* p = [[SNTSyncRuleDownload alloc] initWithState:self.syncState];
+    if (![p sync]) {
+      *detail = @"Rule download failed";
+      return kEnrollmentTestStatusFailed;
+    }
+    *detail = [NSString
+        stringWithFormat:@"Received %lu execution, %lu file access and %lu network flow rules",
+                         self.syncState.rulesReceived, self.syncState.fileAccessRulesReceived,
+                         self.syncState.networkFlowRulesReceived];
+    return kEnrollmentTestStatusPassed;
+  });
+
+  runPhase(@"eventupload", ^NSString*(NSString** detail) {
+    SNTSyncEventUpload* p = [[SNTSyncEventUpload alloc] initWithState:self.syncState];
+    if (![p uploadEmptyBatch]) {
+      *detail = @"Event upload request failed";
+      return kEnrollmentTestStatusFailed;
+    }
+    return kEnrollmentTestStatusPassed;
+  });
+
+  // A postflight would tell the server the clean sync was applied and report the rules it
+  // processed, neither of which happened.
+  runPhase(@"postflight", ^NSString*(NSString** detail) {
+    *detail = @"Not sent during an enrollment check";
+    return kEnrollmentTestStatusSkipped;
+  });
+
+  runPhase(@"push", ^NSString*(NSString** detail) {
+    return [self connectPushWithDetail:detail];
+  });
+
+  return results;
+}
+
+- (NSString*)connectPushWithDetail:(NSString**)detail {
+  if (!self.syncState.pushServer.length) {
+    *detail = @"Server did not provide a push server";
+    return kEnrollmentTestStatusSkipped;
+  }
+
+  SNTSyncEnrollmentCheckPushDelegate* delegate = [[SNTSyncEnrollmentCheckPushDelegate alloc] init];
+  SNTPushClientNATS* client = [[SNTPushClientNATS alloc] initWithSyncDelegate:delegate];
+  [client handlePreflightSyncState:self.syncState];
+
+  NSDate* deadline = [NSDate dateWithTimeIntervalSinceNow:self.pushConnectTimeout];
+  while (![client isConnected] && [deadline timeIntervalSinceNow] > 0) {
+    [NSThread sleepForTimeInterval:0.1];
+  }
+
+  BOOL connected = [client isConnected];
+  NSString* lastError = [client diagnostics][kPushDiagnosticsLastError];
+
+  dispatch_semaphore_t sema = dispatch_semaphore_create(0);
+  [client disconnectWithCompletion:^{
+    dispatch_semaphore_signal(sema);
+  }];
+  dispatch_semaphore_wait(sema, dispatch_time(DISPATCH_TIME_NOW, 5 * NSEC_PER_SEC));
+
+  if (!connected) {
+    *detail = [NSString stringWithFormat:@"Unable to connect to %@: %@", self.syncState.pushServer,
+                                         lastError.length ? lastError : @"timed out"];
+    return kEnrollmentTestStatusFailed;
+  }
+  *detail = [NSString stringWithFormat:@"Connected to %@", self.syncState.pushServer];
+  return kEnrollmentTestStatusPassed;
+}
+
+@end
@@ -0,0 +1,199 @@
+/// Copyright 2026 North Pole Security, Inc.
+///
+/// Licensed under the Apache License, Version 2.0 (the "License");
+/// you may not use this file except in compliance with the License.
+/// You may obtain a copy of the License at
+///
+///     http://www.apache.org/licenses/LICENSE-2.0
+///
+/// Unless required by applicable law or agreed to in writing, software
+/// distributed under the License is distributed on an "AS IS" BASIS,
+/// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+/// See the License for the specific language governing permissions and
+/// limitations under the License.
+
+
+#import <Foundation/Foundation.h>
+#import <OCMock/OCMock.h>
+#import <XCTest/XCTest.h>
+
+#import "Source/common/MOLXPCConnection.h"
+#import "Source/common/SNTConfigurator.h"
+#import "Source/common/SNTSIPStatus.h"
+#import "Source/common/SNTSyncConstants.h"
+#import "Source/common/SNTSystemInfo.h"
+#import "Source/santasyncservice/SNTSyncEnrollmentCheck.h"
+#import "Source/santasyncservice/SNTSyncState.h"
+
+@interface SNTSyncEnrollmentCheckTest : XCTestCase
+@property SNTSyncState* syncState;
+@property id daemonConn;
+@property id configMock;
+@property id siMock;
+@property NSMutableArray<NSString*>* requestedStages;
+@end
+
+@implementation SNTSyncEnrollmentCheckTest
+
+- (void)setUp {
+  [super setUp];
+
+  self.configMock = OCMClassMock([SNTConfigurator class]);
+  OCMStub([self.configMock configurator]).andReturn(self.configMock);
+  OCMStub([self.configMock syncEnableProtoTransfer]).andReturn(NO);
+
+  self.siMock = OCMClassMock([SNTSystemInfo class]);
+  OCMStub([self.siMock serialNumber]).andReturn(@"QYGF4QM373");
+  OCMStub([self.siMock longHostname]).andReturn(@"full-hostname.example.com");
+  OCMStub([self.siMock osVersion]).andReturn(@"14.5");
+  OCMStub([self.siMock osBuild]).andReturn(@"23F79");
+  OCMStub([self.siMock modelIdentifier]).andReturn(@"MacBookPro18,3");
+  OCMStub([self.siMock santaFullVersion]).andReturn(@"2024.6.655965194");
+
+  id sipMock = OCMClassMock([SNTSIPStatus class]);
+  OCMStub([sipMock currentStatus]).andReturn(0x6f);
+
+  // A strict mock fails the test if the enrollment check tries to talk to the daemon.
+  self.daemonConn = OCMStrictClassMock([MOLXPCConnection class]);
+
+  self.syncState = [[SNTSyncState alloc] init];
+  self.syncState.daemonConn = self.daemonConn;
+  self.syncState.session = OCMClassMock([NSURLSession class]);
+  self.syncState.syncBaseURL = [NSURL URLWithString:@"https://enroll-test.local/"];
+  self.syncState.machineID = @"50C7E1EB-2EF5-42D4-A084-A7966FC45A95";
+  self.syncState.machineOwner = @"username1";
+
+  self.requestedStages = [NSMutableArray array];
+}
+
+- (void)tearDown {
+  [self.configMock stopMocking];
+  [self.siMock stopMocking];
+  [self.daemonConn stopMocking];
+  [super tearDown];
+}
+
+#pragma mark Test Helpers
+
+- (NSData*)dataFromFixture:(NSString*)file {
+  NSString* path = [[NSBundle bundleForClass:[self class]] pathForResource:file ofType:nil];
+  XCTAssertNotNil(path, @"failed to load testdata: %@", file);
+  return [NSData dataWithContentsOfFile:path];
+}
+
+/// Stub the mock sync server's response for the named stage, recording each request made to it.
+- (void)stubStage:(NSString*)stage
+       statusCode:(NSInteger)code
+             body:(NSData*)body
+    validateBlock:(void (^)(NSDictionary* requestBody))validateBlock {
+  NSHTTPURLResponse* resp = [[NSHTTPURLResponse alloc] initWithURL:self.syncState.syncBaseURL
+                                                        statusCode:code
+                                                       HTTPVersion:@"1.1"
+                                                      headerFields:nil];
+  NSString* prefix = [NSString stringWithFormat:@"/%@/", stage];
+  BOOL (^matches)(id) = ^BOOL(NSURLRequest* req) {
+    if (![req.URL.path hasPrefix:prefix]) return NO;
+    [self.requestedStages addObject:stage];
+    if (validateBlock && req.HTTPBody) {
+      validateBlock([NSJSONSerialization JSONObjectWithData:req.HTTPBody options:0 error:NULL]);
+    }
+    return YES;
+  };
+
+  OCMStub([self.syncState.session
+      dataTaskWithRequest:[OCMArg checkWithBlock:matches]
+        completionHandler:([OCMArg invokeBlockWithArgs:body ?: [NSData data], resp,
+                                                       [NSNull null], nil])]);
+}
+
+- (void)stubAllStagesSucceeding {
+  [self stubStage:@"preflight"
+         statusCode:200
+               body:[self dataFromFixture:@"sync_preflight_basic.json"]
+      validateBlock:^(NSDictionary* requestBody) {
+        // A fresh enrollment always asks for a clean sync and reports no existing rules.
+        XCTAssertEqualObjects(requestBody[@"request_clean_sync"], @YES);
+        XCTAssertNil(requestBody[@"binary_rule_count"]);
+        XCTAssertNil(requestBody[@"rules_hash"]);
+      }];
+  [self stubStage:@"ruledownload"
+         statusCode:200
+               body:[self dataFromFixture:@"sync_ruledownload_batch2.json"]
+      validateBlock:nil];
+  [self stubStage:@"eventupload"
+         statusCode:200
+               body:[@"{}" dataUsingEncoding:NSUTF8StringEncoding]
+      validateBlock:^(NSDictionary* requestBody) {
+        XCTAssertEqualObjects(requestBody[@"machine_id"], self.syncState.machineID);
+        XCTAssertNil(requestBody[@"events"]);
+      }];
+  [self stubStage:@"postflight"
+         statusCode:200
+               body:[@"{}" dataUsingEncoding:NSUTF8StringEncoding]
+      validateBlock:^(NSDictionary* requestBody) {
+        XCTFail(@"A postflight was sent during the enrollment check");
+      }];
+}
+
+- (NSArray<NSString*>*)statusesForResults:(NSArray<NSDictionary*>*)results {
+  return [results valueForKey:kEnrollmentTestStatus];
+}
+
+#pragma mark Tests
+
+- (void)testAllPhasesPassAgainstMockServer {
+  [self stubAllStagesSucceeding];
+
+  NSArray<NSDictionary*>* results =
+      [[[SNTSyncEnrollmentCheck alloc] initWithSyncState:self.syncState] run];
+
+  NSArray* phases = @[ @"preflight", @"ruledownload", @"eventupload", @"postflight", @"push" ];
+  XCTAssertEqualObjects([results valueForKey:kEnrollmentTestPhase], phases);
+  XCTAssertEqualObjects([self statusesForResults:results], (@[
+                          kEnrollmentTestStatusPassed, kEnrollmentTestStatusPassed,
+                          kEnrollmentTestStatusPassed, kEnrollmentTestStatusSkipped,
+                          kEnrollmentTestStatusSkipped
+                        ]));
+  XCTAssertEqualObjects([[NSOrderedSet orderedSetWithArray:self.requestedStages] array],
+                        (@[ @"preflight", @"ruledownload", @"eventupload" ]));
+
+  XCTAssertEqualObjects(results[1][kEnrollmentTestDetail],
+                        @"Received 2 execution, 0 file access and 0 network flow rules");
+  XCTAssertEqualObjects(results[4][kEnrollmentTestDetail], @"Server did not provide a push server");
+}
+
+- (void)testDoesNotTouchDaemonState {
+  [self stubAllStagesSucceeding];
+
+  SNTSyncEnrollmentCheck* check = [[SNTSyncEnrollmentCheck alloc] initWithSyncState:self.syncState];
+  XCTAssertNil(self.syncState.daemonConn);
+  XCTAssertTrue(self.syncState.dryRun);
+  XCTAssertNotEqualObjects(self.syncState.machineID, @"50C7E1EB-2EF5-42D4-A084-A7966FC45A95");
+  XCTAssertNotNil([[NSUUID alloc] initWithUUIDString:self.syncState.machineID]);
+
+  [check run];
+
+  // The strict daemon connection mock would have thrown on any use.
+  OCMVerifyAll(self.daemonConn);
+}
+
+- (void)testFailedPhaseSkipsRemainingPhases {
+  [self stubStage:@"preflight"
+         statusCode:200
+               body:[self dataFromFixture:@"sync_preflight_basic.json"]
+      validateBlock:nil];
+  [self stubStage:@"ruledownload" statusCode:400 body:nil validateBlock:nil];
+
+  NSArray<NSDictionary*>* results =
+      [[[SNTSyncEnrollmentCheck alloc] initWithSyncState:self.syncState] run];
+
+  XCTAssertEqualObjects([self statusesForResults:results], (@[
+                          kEnrollmentTestStatusPassed, kEnrollmentTestStatusFailed,
+                          kEnrollmentTestStatusSkipped, kEnrollmentTestStatusSkipped,
+                          kEnrollmentTestStatusSkipped
+                        ]));
+  XCTAssertFalse([self.requestedStages containsObject:@"eventupload"]);
+  XCTAssertFalse([self.requestedStages containsObject:@"postflight"]);
+}
+
+@end
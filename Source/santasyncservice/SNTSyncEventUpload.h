@@ -18,8 +18,17 @@
 #import "SNTSyncStage.h"
 #import "Source/common/SNTStoredEvent.h"
 
+/// The HTTP header used to report how long blocked executions took to be approved. The value is
+/// a list of "<file_sha256>=<approval_latency_ms>" pairs separated by semicolons, covering the
+/// approval events in the request.
+extern NSString* const kApprovalLatencyHeader;
+
 @interface SNTSyncEventUpload : SNTSyncStage
 
 - (BOOL)uploadEvents:(NSArray<SNTStoredEvent*>*)events;
 
+/// Sends a single event upload request that contains no events, regardless of the sync type. Used
+/// by the enrollment check to verify the server accepts uploads from this host.
+- (BOOL)uploadEmptyBatch;
+
 @end
@@ -15,6 +15,13 @@
 
 #import "Source/santasyncservice/SNTSyncEventUpload.h"
 
+#include <algorithm>
+#include <atomic>
+#include <optional>
+#include <set>
+#include <string>
+#include <vector>
+
 #include "Source/common/EncodeEntitlements.h"
 #import "Source/common/MOLCertificate.h"
 #import "Source/common/MOLXPCConnection.h"
@@ -23,6 +30,7 @@
 #import "Source/common/SNTConfigurator.h"
 #import "Source/common/SNTFileInfo.h"
 #import "Source/common/SNTLogging.h"
+#import "Source/common/SNTMetricSet.h"
 #import "Source/common/SNTStoredEvent.h"
 #import "Source/common/SNTStoredExecutionEvent.h"
 #import "Source/common/SNTStoredFileAccessEvent.h"
@@ -36,19 +44,59 @@
 #import "Source/common/SNTXPCControlInterface.h"
 #import "Source/common/String.h"
 #include "Source/santasyncservice/ProtoTraits.h"
+#import "Source/santasyncservice/SNTSyncAuditEventUpload.h"
 #import "Source/santasyncservice/SNTSyncLogging.h"
 #import "Source/santasyncservice/SNTSyncState.h"
+#import "Source/santasyncservice/SNTSyncTelemetry.h"
 #include "google/protobuf/arena.h"
+#include "google/protobuf/descriptor.h"
+#include "google/protobuf/message.h"
 
 namespace pbv2 = ::santa::sync::v2;
 
 using santa::NSStringToUTF8String;
 using santa::NSStringToUTF8StringView;
 
+NSString* const kApprovalLatencyHeader = @"X-Santa-Approval-Latency";
+
 namespace {
 
+// Formats approval latencies as "<file_sha256>=<approval_latency_ms>" pairs separated by
+// semicolons, sorted by hash.
+NSString* ApprovalLatencyHeaderValue(NSDictionary<NSString*, NSNumber*>* approvalLatencies) {
+  NSMutableArray<NSString*>* pairs = [NSMutableArray arrayWithCapacity:approvalLatencies.count];
+  NSArray<NSString*>* hashes =
+      [approvalLatencies.allKeys sortedArrayUsingSelector:@selector(compare:)];
+  for (NSString* sha256 in hashes) {
+    [pairs addObject:[NSString stringWithFormat:@"%@=%llu", sha256,
+                                                [approvalLatencies[sha256] unsignedLongLongValue]]];
+  }
+  return [pairs componentsJoinedByString:@";"];
+}
+
+// The execution event fields that are always uploaded, even if the server didn't ask for them.
+const std::set<std::string, std::less<>> kCoreEventFields = {
+    "file_sha256", "file_path", "file_name", "execution_time", "decision",
+};
+
+// Clears every top-level field of an execution event that is neither a core field nor one of
+// the fields the server asked for.
+void TrimEventFields(google::protobuf::Message* e,
+                     const std::set<std::string, std::less<>>& eventFields) {
+  const google::protobuf::Reflection* reflection = e->GetReflection();
+  std::vector<const google::protobuf::FieldDescriptor*> fields;
+  reflection->ListFields(*e, &fields);
+  for (const google::protobuf::FieldDescriptor* field : fields) {
+    if (!kCoreEventFields.count(field->name()) && !eventFields.count(field->name())) {
+      reflection->ClearField(e, field);
+    }
+  }
+}
+
 template <bool IsV2>
-BOOL PerformRequest(SNTSyncEventUpload* self, google::protobuf::Message* req, int eventsInBatch);
+BOOL PerformRequest(SNTSyncEventUpload* self, google::protobuf::Message* req, int eventsInBatch,
+                    NSDictionary<NSString*, NSNumber*>* approvalLatencies,
+                    NSArray<NSString*>** bundleBinaryRequests);
 template <bool IsV2>
 typename santa::ProtoTraits<IsV2>::EventT* MessageForExecutionEvent(SNTStoredExecutionEvent* event,
                                                                     google::protobuf::Arena* arena);
@@ -67,7 +115,9 @@ typename santa::ProtoTraits<IsV2>::FileAccessEventT* MessageForFileAccessEvent(
                                                google::protobuf::Arena* arena);
 
 template <bool IsV2>
-BOOL PerformRequest(SNTSyncEventUpload* self, google::protobuf::Message* req, int eventsInBatch) {
+BOOL PerformRequest(SNTSyncEventUpload* self, google::protobuf::Message* req, int eventsInBatch,
+                    NSDictionary<NSString*, NSNumber*>* approvalLatencies,
+                    NSArray<NSString*>** bundleBinaryRequests) {
   using Traits = santa::ProtoTraits<IsV2>;
   if (eventsInBatch == 0) {
     return YES;
@@ -76,60 +126,191 @@ BOOL PerformRequest(SNTSyncEventUpload* self, google::protobuf::Message* req, in
   if (self.syncState.syncType == SNTSyncTypeNormal ||
       [[SNTConfigurator configurator] enableCleanSyncEventUpload]) {
     typename Traits::EventUploadResponseT response;
-    NSError* err = [self performRequest:[self requestWithMessage:req]
-                            intoMessage:&response
-                                timeout:30];
+    NSMutableURLRequest* request = [self requestWithMessage:req];
+    if (approvalLatencies.count) {
+      // The event message is shared with the server, so approval latencies travel in a header.
+      [request setValue:ApprovalLatencyHeaderValue(approvalLatencies)
+          forHTTPHeaderField:kApprovalLatencyHeader];
+    }
+    NSError* err = [self performRequest:request intoMessage:&response timeout:30];
     if (err) {
       SLOGE(@"Failed to upload events: %@", err);
       return NO;
     }
+    [[SNTSyncTelemetry sharedTelemetry] incrementCounter:SNTSyncTelemetryCounterEventsUploaded
+                                                      by:eventsInBatch];
 
     // A list of bundle hashes that require their related binary events to be uploaded.
     if (response.event_upload_bundle_binaries_size()) {
-      self.syncState.bundleBinaryRequests =
+      NSMutableArray* requests =
           [NSMutableArray arrayWithCapacity:response.event_upload_bundle_binaries_size()];
       for (const std::string& bundle_binary : response.event_upload_bundle_binaries()) {
-        [(NSMutableArray*)self.syncState.bundleBinaryRequests
-            addObject:santa::StringToNSString(bundle_binary)];
+        [requests addObject:santa::StringToNSString(bundle_binary)];
       }
+      *bundleBinaryRequests = requests;
     }
     SLOGI(@"Uploaded %d events", eventsInBatch);
   }
   return YES;
 }
 
+// A batch of events ready for upload, along with the database IDs of every
+// event that the batch covers (including events that were not serialized).
+template <bool IsV2>
+struct EventUploadBatch {
+  typename santa::ProtoTraits<IsV2>::EventUploadRequestT* request;
+  NSArray<NSNumber*>* eventIds;
+  int eventCount;
+  // Approval latency in milliseconds, keyed by file SHA-256, for approval events in the batch.
+  NSDictionary<NSString*, NSNumber*>* approvalLatencies;
+  BOOL success = NO;
+  NSArray<NSString*>* bundleBinaryRequests;
+};
+
+SNTMetricInt64Gauge* InFlightUploadsGauge() {
+  static SNTMetricInt64Gauge* gauge;
+  static dispatch_once_t onceToken;
+  dispatch_once(&onceToken, ^{
+    gauge = [[SNTMetricSet sharedInstance]
+        int64GaugeWithName:@"/santa/sync/event_upload/in_flight"
+                fieldNames:@[]
+                  helpText:@"Number of event upload requests currently in flight"];
+  });
+  return gauge;
+}
+
+std::atomic<int64_t> gInFlightUploads{0};
+
+// Upload the given batches using at most SyncEventUploadConcurrency requests
+// at a time. Batches are dispatched in order and acknowledged in order: events
+// are only removed from the database once their batch and every batch before
+// it have been accepted by the server. Once a batch fails no further batches
+// are dispatched, and any later batches that were already in flight are left
+// in the database to be uploaded again on the next sync.
+template <bool IsV2>
+BOOL UploadBatches(SNTSyncEventUpload* self, std::vector<EventUploadBatch<IsV2>>& batches) {
+  if (batches.empty()) {
+    return YES;
+  }
+
+  NSUInteger concurrency = std::clamp<NSUInteger>(
+      [[SNTConfigurator configurator] syncEventUploadConcurrency], 1, batches.size());
+  dispatch_semaphore_t slots = dispatch_semaphore_create(concurrency);
+  dispatch_group_t group = dispatch_group_create();
+  dispatch_queue_t queue = dispatch_get_global_queue(QOS_CLASS_UTILITY, 0);
+
+  // The group is waited on before returning, so it is safe for the blocks
+  // below to reference these through pointers.
+  std::atomic_bool failed{false};
+  std::atomic_bool* pFailed = &failed;
+  std::vector<EventUploadBatch<IsV2>>* pBatches = &batches;
+
+  for (size_t i = 0; i < batches.size(); i++) {
+    dispatch_semaphore_wait(slots, DISPATCH_TIME_FOREVER);
+    if (failed.load()) {
+      dispatch_semaphore_signal(slots);
+      break;
+    }
+
+    dispatch_group_async(group, queue, ^{
+      EventUploadBatch<IsV2>& batch = (*pBatches)[i];
+      [InFlightUploadsGauge() set:++gInFlightUploads forFieldValues:@[]];
+
+      NSArray<NSString*>* bundleBinaryRequests;
+      batch.success =
+          PerformRequest<IsV2>(self, batch.request, batch.eventCount, batch.approvalLatencies,
+                               &bundleBinaryRequests);
+      batch.bundleBinaryRequests = bundleBinaryRequests;
+
+      [InFlightUploadsGauge() set:--gInFlightUploads forFieldValues:@[]];
+      if (!batch.success) {
+        pFailed->store(true);
+      }
+      dispatch_semaphore_signal(slots);
+    });
+  }
+
+  dispatch_group_wait(group, DISPATCH_TIME_FOREVER);
+
+  for (const auto& batch : batches) {
+    if (!batch.success) {
+      return NO;
+    }
+
+    if (batch.bundleBinaryRequests) {
+      self.syncState.bundleBinaryRequests = batch.bundleBinaryRequests;
+    }
+
+    // Remove event IDs. For Bundle Events the ID is 0 so nothing happens.
+    if (batch.eventIds.count) {
+      [[self.daemonConn remoteObjectProxy] databaseRemoveEventsWithIDs:batch.eventIds];
+    }
+  }
+
+  return YES;
+}
+
 template <bool IsV2>
 BOOL EventUpload(SNTSyncEventUpload* self, NSArray<SNTStoredEvent*>* events) {
   using Traits = santa::ProtoTraits<IsV2>;
   google::protobuf::Arena arena;
   google::protobuf::Arena* pArena = &arena;
   NSMutableSet* eventIds = [NSMutableSet setWithCapacity:events.count];
-  auto req = google::protobuf::Arena::Create<typename Traits::EventUploadRequestT>(&arena);
-  req->set_machine_id(NSStringToUTF8String(self.syncState.machineID));
-  google::protobuf::RepeatedPtrField<typename Traits::EventT>* uploadEvents = req->mutable_events();
-  google::protobuf::RepeatedPtrField<typename Traits::FileAccessEventT>* uploadFAAEvents =
-      req->mutable_file_access_events();
-  google::protobuf::RepeatedPtrField<typename Traits::AuditEventT>* uploadAuditEvents =
-      req->mutable_audit_events();
-  google::protobuf::RepeatedPtrField<::pbv2::NetworkMountEvent>* uploadNetworkMountEvents;
-  google::protobuf::RepeatedPtrField<::pbv2::NetworkFlowEvent>* uploadNetworkFlowEvents;
-  google::protobuf::RepeatedPtrField<::pbv2::USBMountEvent>* uploadUSBMountEvents;
-  if constexpr (IsV2) {
-    uploadNetworkMountEvents = req->mutable_network_mount_events();
-    uploadNetworkFlowEvents = req->mutable_network_flow_events();
-    uploadUSBMountEvents = req->mutable_usb_mount_events();
-  }
-  __block BOOL success = YES;
+  NSMutableDictionary<NSString*, NSNumber*>* approvalLatencies = [NSMutableDictionary dictionary];
+  __block std::vector<EventUploadBatch<IsV2>> batches;
+  __block typename Traits::EventUploadRequestT* req;
+  __block google::protobuf::RepeatedPtrField<typename Traits::EventT>* uploadEvents;
+  __block google::protobuf::RepeatedPtrField<typename Traits::FileAccessEventT>* uploadFAAEvents;
+  __block google::protobuf::RepeatedPtrField<typename Traits::AuditEventT>* uploadAuditEvents;
+  __block google::protobuf::RepeatedPtrField<::pbv2::NetworkMountEvent>* uploadNetworkMountEvents;
+  __block google::protobuf::RepeatedPtrField<::pbv2::NetworkFlowEvent>* uploadNetworkFlowEvents;
+  __block google::protobuf::RepeatedPtrField<::pbv2::USBMountEvent>* uploadUSBMountEvents;
+  void (^newRequest)(void) = ^{
+    req = google::protobuf::Arena::Create<typename Traits::EventUploadRequestT>(pArena);
+    req->set_machine_id(NSStringToUTF8String(self.syncState.machineID));
+    uploadEvents = req->mutable_events();
+    uploadFAAEvents = req->mutable_file_access_events();
+    uploadAuditEvents = req->mutable_audit_events();
+    if constexpr (IsV2) {
+      uploadNetworkMountEvents = req->mutable_network_mount_events();
+      uploadNetworkFlowEvents = req->mutable_network_flow_events();
+      uploadUSBMountEvents = req->mutable_usb_mount_events();
+    }
+  };
+  newRequest();
   NSUInteger finalIdx = (events.count - 1);
 
+  // Events older than the configured maximum age are not uploaded but are still
+  // removed from the database along with the rest of the batch.
+  NSUInteger maxEventAge = [[SNTConfigurator configurator] syncMaxEventAgeSec];
+  NSDate* cutoffDate =
+      maxEventAge ? [NSDate dateWithTimeIntervalSinceNow:-(NSTimeInterval)maxEventAge] : nil;
+  __block NSUInteger droppedEventCount = 0;
+
+  std::optional<std::set<std::string, std::less<>>> eventFields;
+  if (self.syncState.eventFields) {
+    eventFields.emplace();
+    for (NSString* field in self.syncState.eventFields) {
+      eventFields->insert(NSStringToUTF8String(field));
+    }
+  }
+
   [events enumerateObjectsUsingBlock:^(SNTStoredEvent* event, NSUInteger idx, BOOL* stop) {
     // Track the idx as processed immediately so that it will always be removed
     // from the database, even if not uploaded.
     if (event.idx) [eventIds addObject:event.idx];
 
-    if ([event isKindOfClass:[SNTStoredExecutionEvent class]]) {
-      if (auto e = MessageForExecutionEvent<IsV2>((SNTStoredExecutionEvent*)event, pArena)) {
+    if (cutoffDate && event.occurrenceDate &&
+        [event.occurrenceDate compare:cutoffDate] == NSOrderedAscending) {
+      droppedEventCount++;
+    } else if ([event isKindOfClass:[SNTStoredExecutionEvent class]]) {
+      SNTStoredExecutionEvent* se = (SNTStoredExecutionEvent*)event;
+      if (auto e = MessageForExecutionEvent<IsV2>(se, pArena)) {
+        if (eventFields) TrimEventFields(e, *eventFields);
         uploadEvents->UnsafeArenaAddAllocated(e);
+        if (se.approvalLatencyMs && se.fileSHA256.length) {
+          approvalLatencies[se.fileSHA256] = se.approvalLatencyMs;
+        }
       }
     } else if ([event isKindOfClass:[SNTStoredFileAccessEvent class]]) {
       if (auto e = MessageForFileAccessEvent<IsV2>((SNTStoredFileAccessEvent*)event, pArena)) {
@@ -184,34 +365,23 @@ BOOL EventUpload(SNTSyncEventUpload* self, NSArray<SNTStoredEvent*>* events) {
     }
 
     if (totalEventCount >= self.syncState.eventBatchSize || idx == finalIdx) {
-      if (!PerformRequest<IsV2>(self, req, totalEventCount)) {
-        success = NO;
-        *stop = YES;
-        return;
-      }
-
-      // Remove event IDs. For Bundle Events the ID is 0 so nothing happens.
-      [[self.daemonConn remoteObjectProxy] databaseRemoveEventsWithIDs:[eventIds allObjects]];
-
+      batches.push_back({
+          .request = req,
+          .eventIds = [eventIds allObjects],
+          .eventCount = totalEventCount,
+          .approvalLatencies = [approvalLatencies copy],
+      });
       [eventIds removeAllObjects];
-      uploadEvents->Clear();
-      uploadFAAEvents->Clear();
-      uploadAuditEvents->Clear();
-      if constexpr (IsV2) {
-        uploadNetworkMountEvents->Clear();
-        uploadNetworkFlowEvents->Clear();
-        uploadUSBMountEvents->Clear();
-      }
+      [approvalLatencies removeAllObjects];
+      newRequest();
     }
   }];
 
-  // Handle the case where no events generated messages to send (e.g. all transitive)
-  // Note: Check for success in case there are events in the set that failed to upload.
-  if (success && eventIds.count > 0) {
-    [[self.daemonConn remoteObjectProxy] databaseRemoveEventsWithIDs:[eventIds allObjects]];
+  if (droppedEventCount) {
+    SLOGI(@"Dropped %lu events older than %lu seconds", droppedEventCount, maxEventAge);
   }
 
-  return success;
+  return UploadBatches<IsV2>(self, batches);
 }
 
 // Populates a proto Certificate from a MOLCertificate. The proto type is deduced
@@ -294,6 +464,12 @@ typename santa::ProtoTraits<IsV2>::EventT* MessageForExecutionEvent(
     case SNTEventStateBlockCELFallback: e->set_decision(Traits::BLOCK_CEL_FALLBACK); break;
     case SNTEventStateAllowCELFallback: e->set_decision(Traits::ALLOW_CEL_FALLBACK); break;
     case SNTEventStateAllowPlatform: e->set_decision(Traits::ALLOW_PLATFORM); break;
+    case SNTEventStateBlockRequirement: e->set_decision(Traits::BLOCK_REQUIREMENT); break;
+    case SNTEventStateAllowRequirement: e->set_decision(Traits::ALLOW_REQUIREMENT); break;
+    case SNTEventStateBlockNetworkVolume: e->set_decision(Traits::BLOCK_NETWORK_VOLUME); break;
+    case SNTEventStateBlockDiskImage: e->set_decision(Traits::BLOCK_DISK_IMAGE); break;
+    case SNTEventStateBlockPathChurn: e->set_decision(Traits::BLOCK_PATH_CHURN); break;
+    case SNTEventStateAllowOnce: e->set_decision(Traits::ALLOW_ONCE); break;
     case SNTEventStateAllowTransitive: return nullptr;
     case SNTEventStateAllowLocalBinary: return nullptr;
     case SNTEventStateAllowLocalSigningID: return nullptr;
@@ -700,6 +876,23 @@ void MessageForTemporaryAdminModeDeniedAuditEvent(
   return pbAudit;
 }
 
+template <bool IsV2>
+BOOL EmptyEventUpload(SNTSyncEventUpload* self) {
+  using Traits = santa::ProtoTraits<IsV2>;
+  typename Traits::EventUploadRequestT req;
+  req.set_machine_id(NSStringToUTF8String(self.syncState.machineID));
+
+  typename Traits::EventUploadResponseT response;
+  NSError* err = [self performRequest:[self requestWithMessage:&req]
+                          intoMessage:&response
+                              timeout:30];
+  if (err) {
+    SLOGE(@"Failed to upload empty event batch: %@", err);
+    return NO;
+  }
+  return YES;
+}
+
 }  // namespace
 
 @implementation SNTSyncEventUpload
@@ -710,6 +903,11 @@ void MessageForTemporaryAdminModeDeniedAuditEvent(
 }
 
 - (BOOL)sync {
+  if ([[SNTConfigurator configurator] disableEventUpload]) {
+    SLOGD(@"Event upload is disabled, skipping");
+    return YES;
+  }
+
   dispatch_semaphore_t sema = dispatch_semaphore_create(0);
   [[self.daemonConn remoteObjectProxy] databaseEventsPending:^(NSArray* events) {
     if (events.count) {
@@ -721,10 +919,43 @@ void MessageForTemporaryAdminModeDeniedAuditEvent(
 }
 
 - (BOOL)uploadEvents:(NSArray<SNTStoredEvent*>*)events {
+  if ([[SNTConfigurator configurator] disableEventUpload]) {
+    SLOGD(@"Event upload is disabled, dropping %lu events", events.count);
+    return YES;
+  }
+
+  // Rule change audit events and launch item events have no message in the sync protocol and are
+  // sent separately.
+  NSMutableArray<SNTStoredEvent*>* syncEvents = [NSMutableArray arrayWithCapacity:events.count];
+  NSMutableArray<SNTStoredEvent*>* auditEvents = [NSMutableArray array];
+  for (SNTStoredEvent* event in events) {
+    if ([SNTSyncAuditEventUpload isAuditEvent:event]) {
+      [auditEvents addObject:event];
+    } else {
+      [syncEvents addObject:event];
+    }
+  }
+
+  BOOL success;
+  if (self.syncState.isSyncV2) {
+    success = EventUpload<true>(self, syncEvents);
+  } else {
+    success = EventUpload<false>(self, syncEvents);
+  }
+
+  if (auditEvents.count) {
+    SNTSyncAuditEventUpload* auditUpload =
+        [[SNTSyncAuditEventUpload alloc] initWithState:self.syncState];
+    success = [auditUpload uploadAuditEvents:auditEvents] && success;
+  }
+  return success;
+}
+
+- (BOOL)uploadEmptyBatch {
   if (self.syncState.isSyncV2) {
-    return EventUpload<true>(self, events);
+    return EmptyEventUpload<true>(self);
   } else {
-    return EventUpload<false>(self, events);
+    return EmptyEventUpload<false>(self);
   }
 }
 
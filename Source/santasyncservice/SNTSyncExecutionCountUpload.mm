@@ -0,0 +1,75 @@
+/// Copyright 2026 North Pole Security, Inc.
+///
+/// Licensed under the Apache License, Version 2.0 (the "License");
+/// you may not use this file except in compliance with the License.
+/// You may obtain a copy of the License at
+///
+///     http://www.apache.org/licenses/LICENSE-2.0
+///
+/// Unless required by applicable law or agreed to in writing, software
+/// distributed under the License is distributed on an "AS IS" BASIS,
+/// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+/// See the License for the specific language governing permissions and
+/// limitations under the License.
+
+#import "Source/santasyncservice/SNTSyncExecutionCountUpload.h"
+
+#import "Source/common/MOLXPCConnection.h"
+#import "Source/common/SNTXPCControlInterface.h"
+#import "Source/santasyncservice/SNTSyncLogging.h"
+#import "Source/santasyncservice/SNTSyncState.h"
+
+@implementation SNTSyncExecutionCountUpload
+
+- (NSURL*)stageURL {
+  NSString* stageName =
+      [@"executioncounts" stringByAppendingFormat:@"/%@", self.syncState.machineID];
+  return [NSURL URLWithString:stageName relativeToURL:self.syncState.syncBaseURL];
+}
+
+- (BOOL)sync {
+  id<SNTDaemonControlXPC> rop = [self.daemonConn synchronousRemoteObjectProxy];
+  __block NSDictionary<NSString*, NSDictionary*>* snapshot;
+  [rop executionCounts:^(NSDictionary<NSString*, NSDictionary*>* counts) {
+    snapshot = counts;
+  }];
+  if (!snapshot.count) return YES;
+
+  NSMutableArray* executionCounts = [NSMutableArray arrayWithCapacity:snapshot.count];
+  for (NSString* sha256 in [snapshot.allKeys sortedArrayUsingSelector:@selector(compare:)]) {
+    NSMutableDictionary* entry = [snapshot[sha256] mutableCopy];
+    entry[@"file_sha256"] = sha256;
+    [executionCounts addObject:entry];
+  }
+
+  NSDictionary* body = @{
+    @"machine_id" : self.syncState.machineID ?: @"",
+    @"execution_counts" : executionCounts,
+  };
+  NSError* error;
+  NSData* data = [NSJSONSerialization dataWithJSONObject:body options:0 error:&error];
+  if (!data) {
+    SLOGE(@"Failed to encode execution counts: %@", error.localizedDescription);
+    return NO;
+  }
+
+  NSMutableURLRequest* req = [self requestWithData:data contentType:@"application/json"];
+  NSInteger statusCode = 0;
+  error = [self performRequest:req intoMessage:NULL timeout:30 statusCode:&statusCode];
+  if (error) {
+    // A 404 means this sync server predates the execution counts endpoint. Keep counting so the
+    // counts are reported once the server supports them.
+    if (statusCode == 404) {
+      SLOGD(@"Execution counts endpoint unavailable (HTTP 404), keeping counts for %lu binaries",
+            (unsigned long)snapshot.count);
+      return YES;
+    }
+    SLOGE(@"Execution counts upload failed: %@", error.localizedDescription);
+    return NO;
+  }
+
+  [[self.daemonConn remoteObjectProxy] resetExecutionCounts:snapshot];
+  return YES;
+}
+
+@end
@@ -0,0 +1,52 @@
+/// Copyright 2026 North Pole Security, Inc.
+///
+/// Licensed under the Apache License, Version 2.0 (the "License");
+/// you may not use this file except in compliance with the License.
+/// You may obtain a copy of the License at
+///
+///     http://www.apache.org/licenses/LICENSE-2.0
+///
+/// Unless required by applicable law or agreed to in writing, software
+/// distributed under the License is distributed on an "AS IS" BASIS,
+/// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+/// See the License for the specific language governing permissions and
+/// limitations under the License.
+
+#import <Foundation/Foundation.h>
+
+NS_ASSUME_NONNULL_BEGIN
+
+/// The longest a single override may remain in effect. Servers that want a longer
+/// override must re-send it before it expires.
+extern const NSTimeInterval kMaximumSyncIntervalOverrideDuration;
+
+/// A time-bounded override of the full sync interval, delivered to a subset of
+/// hosts by a tag push notification (e.g. "all incident tagged hosts sync every
+/// 60s for the next hour"). Once the override expires the base interval applies
+/// again. Not thread-safe; callers are expected to serialize access.
+@interface SNTSyncIntervalOverride : NSObject
+
+/// The tag the active override was received on, or nil if no override is active.
+@property(nullable, readonly) NSString* tag;
+
+/// Apply an override, replacing any existing one. The interval is raised to at
+/// least kMinimumFullSyncInterval and the duration is capped at
+/// kMaximumSyncIntervalOverrideDuration. A zero interval or duration clears the
+/// current override instead.
+///
+/// Returns the expiry date of the applied override, or nil if it was cleared.
+- (nullable NSDate*)applyInterval:(NSUInteger)interval
+                      forDuration:(NSTimeInterval)duration
+                              tag:(nullable NSString*)tag
+                              now:(NSDate*)now;
+
+/// Returns the override interval while an override is active at `now`, otherwise
+/// `baseInterval`. An expired override is cleared as a side effect.
+- (NSUInteger)intervalWithBase:(NSUInteger)baseInterval now:(NSDate*)now;
+
+/// Whether an override is in effect at `now`.
+- (BOOL)isActiveAt:(NSDate*)now;
+
+@end
+
+NS_ASSUME_NONNULL_END
@@ -0,0 +1,65 @@
+/// Copyright 2026 North Pole Security, Inc.
+///
+/// Licensed under the Apache License, Version 2.0 (the "License");
+/// you may not use this file except in compliance with the License.
+/// You may obtain a copy of the License at
+///
+///     http://www.apache.org/licenses/LICENSE-2.0
+///
+/// Unless required by applicable law or agreed to in writing, software
+/// distributed under the License is distributed on an "AS IS" BASIS,
+/// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+/// See the License for the specific language governing permissions and
+/// limitations under the License.
+
+#import "Source/santasyncservice/SNTSyncIntervalOverride.h"
+
+#include <algorithm>
+
+#import "Source/common/SNTSyncConstants.h"
+
+const NSTimeInterval kMaximumSyncIntervalOverrideDuration = 24 * 60 * 60;
+
+@interface SNTSyncIntervalOverride ()
+@property(nullable, readwrite) NSString* tag;
+@property NSUInteger interval;
+@property(nullable) NSDate* expiry;
+@end
+
+@implementation SNTSyncIntervalOverride
+
+- (NSDate*)applyInterval:(NSUInteger)interval
+             forDuration:(NSTimeInterval)duration
+                     tag:(NSString*)tag
+                     now:(NSDate*)now {
+  if (interval == 0 || duration <= 0) {
+    [self clear];
+    return nil;
+  }
+
+  self.interval = std::max(interval, kMinimumFullSyncInterval);
+  self.expiry = [now dateByAddingTimeInterval:std::min(duration,
+                                                       kMaximumSyncIntervalOverrideDuration)];
+  self.tag = tag;
+  return self.expiry;
+}
+
+- (NSUInteger)intervalWithBase:(NSUInteger)baseInterval now:(NSDate*)now {
+  if (![self isActiveAt:now]) {
+    [self clear];
+    return baseInterval;
+  }
+  return self.interval;
+}
+
+- (BOOL)isActiveAt:(NSDate*)now {
+  return self.expiry && [now compare:self.expiry] == NSOrderedAscending;
+}
+
+- (void)clear {
+  self.interval = 0;
+  self.expiry = nil;
+  self.tag = nil;
+}
+
+@end
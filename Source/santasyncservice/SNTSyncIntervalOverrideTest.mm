@@ -0,0 +1,98 @@
+/// Copyright 2026 North Pole Security, Inc.
+///
+/// Licensed under the Apache License, Version 2.0 (the "License");
+/// you may not use this file except in compliance with the License.
+/// You may obtain a copy of the License at
+///
+///     http://www.apache.org/licenses/LICENSE-2.0
+///
+/// Unless required by applicable law or agreed to in writing, software
+/// distributed under the License is distributed on an "AS IS" BASIS,
+/// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+/// See the License for the specific language governing permissions and
+/// limitations under the License.
+
+#import <XCTest/XCTest.h>
+
+#import "Source/common/SNTSyncConstants.h"
+#import "Source/santasyncservice/SNTSyncIntervalOverride.h"
+
+@interface SNTSyncIntervalOverrideTest : XCTestCase
+@property SNTSyncIntervalOverride* override;
+@property NSDate* now;
+@end
+
+@implementation SNTSyncIntervalOverrideTest
+
+- (void)setUp {
+  [super setUp];
+  self.override = [[SNTSyncIntervalOverride alloc] init];
+  self.now = [NSDate dateWithTimeIntervalSince1970:1700000000];
+}
+
+- (void)testNoOverrideUsesBase {
+  XCTAssertFalse([self.override isActiveAt:self.now]);
+  XCTAssertEqual([self.override intervalWithBase:600 now:self.now], 600u);
+  XCTAssertNil(self.override.tag);
+}
+
+- (void)testOverrideAppliesUntilExpiry {
+  NSDate* expiry = [self.override applyInterval:60
+                                    forDuration:3600
+                                            tag:@"santa.tag.incident"
+                                            now:self.now];
+  XCTAssertEqualObjects(expiry, [self.now dateByAddingTimeInterval:3600]);
+  XCTAssertEqualObjects(self.override.tag, @"santa.tag.incident");
+
+  NSDate* beforeExpiry = [self.now dateByAddingTimeInterval:3599];
+  XCTAssertTrue([self.override isActiveAt:beforeExpiry]);
+  XCTAssertEqual([self.override intervalWithBase:600 now:beforeExpiry], 60u);
+}
+
+- (void)testOverrideRevertsOnExpiry {
+  [self.override applyInterval:60 forDuration:3600 tag:@"santa.tag.incident" now:self.now];
+
+  NSDate* atExpiry = [self.now dateByAddingTimeInterval:3600];
+  XCTAssertFalse([self.override isActiveAt:atExpiry]);
+  XCTAssertEqual([self.override intervalWithBase:600 now:atExpiry], 600u);
+  XCTAssertNil(self.override.tag);
+
+  // Once reverted, the override stays cleared.
+  XCTAssertEqual([self.override intervalWithBase:600 now:self.now], 600u);
+}
+
+- (void)testNewOverrideReplacesExisting {
+  [self.override applyInterval:60 forDuration:60 tag:@"santa.tag.incident" now:self.now];
+  [self.override applyInterval:120 forDuration:3600 tag:@"santa.tag.triage" now:self.now];
+
+  NSDate* later = [self.now dateByAddingTimeInterval:600];
+  XCTAssertEqual([self.override intervalWithBase:600 now:later], 120u);
+  XCTAssertEqualObjects(self.override.tag, @"santa.tag.triage");
+}
+
+- (void)testIntervalRaisedToMinimum {
+  [self.override applyInterval:5 forDuration:3600 tag:@"santa.tag.incident" now:self.now];
+  XCTAssertEqual([self.override intervalWithBase:600 now:self.now], kMinimumFullSyncInterval);
+}
+
+- (void)testDurationCapped {
+  NSDate* expiry = [self.override applyInterval:60
+                                    forDuration:7 * 24 * 60 * 60
+                                            tag:@"santa.tag.incident"
+                                            now:self.now];
+  XCTAssertEqualObjects(expiry,
+                        [self.now dateByAddingTimeInterval:kMaximumSyncIntervalOverrideDuration]);
+}
+
+- (void)testZeroClearsOverride {
+  [self.override applyInterval:60 forDuration:3600 tag:@"santa.tag.incident" now:self.now];
+
+  XCTAssertNil([self.override applyInterval:0
+                                forDuration:3600
+                                        tag:@"santa.tag.incident"
+                                        now:self.now]);
+  XCTAssertFalse([self.override isActiveAt:self.now]);
+  XCTAssertEqual([self.override intervalWithBase:600 now:self.now], 600u);
+}
+
+@end
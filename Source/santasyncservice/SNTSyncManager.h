@@ -77,6 +77,18 @@
 - (void)pushNotificationStatus:(void (^)(SNTPushNotificationStatus))reply;
 - (void)pushNotificationServerAddress:(void (^)(NSString*))reply;
 - (void)pushNotificationReconnect;
+- (void)pushNotificationDiagnostics:(void (^)(NSDictionary*))reply;
+- (void)syncCircuitBreakerStatus:(void (^)(NSDictionary*))reply;
+- (void)pushServerCertificateExpiry:(void (^)(NSDate*))reply;
+- (void)ruleSourcesStatus:(void (^)(NSArray<NSDictionary*>*))reply;
+- (void)enrollmentTestWithSyncURL:(NSURL*)syncURL
+                            reply:(void (^)(NSArray<NSDictionary*>* phases))reply;
+- (void)reconcileRulesWithSyncURL:(NSURL*)syncURL
+                            reply:(void (^)(NSDictionary<NSString*, id>* report))reply;
+- (void)benchmarkUploadWithSyncURL:(NSURL*)syncURL
+                              rate:(NSUInteger)rate
+                          duration:(NSUInteger)duration
+                             reply:(void (^)(NSDictionary<NSString*, id>* report))reply;
 - (void)publishMetrics:(NSDictionary*)metrics reply:(void (^)(BOOL))reply;
 - (void)checkSyncServerStatus:(void (^)(NSInteger statusCode, NSString* description,
                                         MOLCertificate* clientCertificate))reply;
@@ -22,35 +22,47 @@
 
 #import "Source/common/MOLAuthenticatingURLSession.h"
 #import "Source/common/MOLXPCConnection.h"
-#include "Source/common/Pinning.h"
 #import "Source/common/SNTCommonEnums.h"
 #import "Source/common/SNTConfigurator.h"
+#import "Source/common/SNTDiagnostics.h"
+#import "Source/common/SNTError.h"
 #import "Source/common/SNTLogging.h"
+#import "Source/common/SNTRuleSource.h"
 #import "Source/common/SNTStoredEvent.h"
 #import "Source/common/SNTStoredSignalReport.h"
 #import "Source/common/SNTStrengthify.h"
 #import "Source/common/SNTSyncConstants.h"
 #import "Source/common/SNTXPCBundleServiceInterface.h"
 #import "Source/common/SNTXPCControlInterface.h"
+#include "Source/common/SyncServerRoots.h"
 #import "Source/santasyncservice/SNTPushClientFCM.h"
 #import "Source/santasyncservice/SNTPushClientNATS.h"
 #import "Source/santasyncservice/SNTPushNotifications.h"
+#import "Source/santasyncservice/SNTRuleSourceScheduler.h"
 #import "Source/santasyncservice/SNTSantaCommandHandler.h"
 #import "Source/santasyncservice/SNTSyncCommands.h"
 #import "Source/santasyncservice/SNTSyncConfigBundle.h"
+#import "Source/santasyncservice/SNTSyncDiagnosticsUpload.h"
+#import "Source/santasyncservice/SNTSyncEnrollmentCheck.h"
 #import "Source/santasyncservice/SNTSyncEventUpload.h"
+#import "Source/santasyncservice/SNTSyncCircuitBreaker.h"
+#import "Source/santasyncservice/SNTSyncIntervalOverride.h"
 #import "Source/santasyncservice/SNTSyncLogging.h"
 #import "Source/santasyncservice/SNTSyncPostflight.h"
 #import "Source/santasyncservice/SNTSyncPreflight.h"
 #import "Source/santasyncservice/SNTSyncPublishMetrics.h"
 #import "Source/santasyncservice/SNTSyncRuleDownload.h"
+#import "Source/santasyncservice/SNTSyncRuleReconcile.h"
+#import "Source/santasyncservice/SNTSyncRuleSnapshotUpload.h"
 #import "Source/santasyncservice/SNTSyncSignalUpload.h"
 #import "Source/santasyncservice/SNTSyncState.h"
+#import "Source/santasyncservice/SNTSyncTelemetry.h"
+#import "Source/santasyncservice/SNTSyncUploadBenchmark.h"
 #include "absl/cleanup/cleanup.h"
 
 static const uint8_t kMaxEnqueuedSyncs = 2;
 
-@interface SNTSyncManager () <SNTPushNotificationsSyncDelegate>
+@interface SNTSyncManager () <SNTPushCredentialProvider, SNTPushNotificationsSyncDelegate>
 
 @property(nonatomic) dispatch_source_t fullSyncTimer;
 @property(nonatomic) dispatch_source_t ruleSyncTimer;
@@ -89,6 +101,7 @@ static const uint8_t kMaxEnqueuedSyncs = 2;
 @property(nonatomic) SNTSantaCommandHandler* commandHandler;
 
 @property NSUInteger eventBatchSize;
+@property(copy) NSArray<NSString*>* eventFields;
 
 @property NSString* xsrfToken;
 @property NSString* xsrfTokenHeader;
@@ -98,6 +111,16 @@ static const uint8_t kMaxEnqueuedSyncs = 2;
 // Updated when the server provides a new value so the fallback stays current.
 @property NSUInteger persistedFullSyncInterval;
 
+// Time-bounded full sync interval override requested by a tag push notification.
+// Access is synchronized on the object itself.
+@property(nonatomic, readonly) SNTSyncIntervalOverride* fullSyncIntervalOverride;
+
+// Guards requests to the configured sync server across all syncs.
+@property(nonatomic, readonly) SNTSyncCircuitBreaker* circuitBreaker;
+
+// Downloads rules from the configured RuleSources.
+@property(nonatomic, readonly) SNTRuleSourceScheduler* ruleSourceScheduler;
+
 @property(nonatomic, readonly) dispatch_queue_t metricsQueue;
 
 @end
@@ -122,8 +145,14 @@ static const uint8_t kMaxEnqueuedSyncs = 2;
     _persistedFullSyncInterval = persistedFull;
     LOGD(@"Read persisted full sync interval from daemon: %lu", _persistedFullSyncInterval);
 
+    _fullSyncIntervalOverride = [[SNTSyncIntervalOverride alloc] init];
+
     SNTConfigurator* config = [SNTConfigurator configurator];
 
+    _circuitBreaker = [[SNTSyncCircuitBreaker alloc]
+        initWithFailureThreshold:config.syncCircuitBreakerFailureThreshold
+                        cooldown:config.syncCircuitBreakerCooldownSec];
+
     if (config.fcmEnabled) {
       LOGD(@"Using FCM push notifications");
       _pushNotifications = [[SNTPushClientFCM alloc] initWithSyncDelegate:self];
@@ -137,9 +166,7 @@ static const uint8_t kMaxEnqueuedSyncs = 2;
       // On failure, the failed-preflight path corrects it as well. This is just
       // a safety net for the case where the sync fails before reaching any
       // rescheduling logic.
-      NSUInteger interval = self.pushNotifications ? self.pushNotifications.fullSyncInterval
-                                                   : self.persistedFullSyncInterval;
-      [self rescheduleTimerQueue:self.fullSyncTimer secondsFromNow:interval];
+      [self rescheduleTimerQueue:self.fullSyncTimer secondsFromNow:[self currentFullSyncInterval]];
       [self syncType:SNTSyncTypeNormal withReply:NULL];
     }];
     _ruleSyncTimer = [self createSyncTimerWithBlock:^{
@@ -157,6 +184,15 @@ static const uint8_t kMaxEnqueuedSyncs = 2;
     _commandHandler = [[SNTSantaCommandHandler alloc] initWithSyncDelegate:self];
 
     _eventBatchSize = kDefaultEventBatchSize;
+
+    WEAKIFY(self);
+    _ruleSourceScheduler = [[SNTRuleSourceScheduler alloc]
+        initWithSyncStateBlock:^SNTSyncState*(SNTRuleSource* source) {
+          STRONGIFY(self);
+          return [self createRuleSourceSyncState:source];
+        }];
+    [_ruleSourceScheduler updateSources:config.ruleSources];
+
     _metricsQueue = dispatch_queue_create_with_target(
         "com.northpolesec.santa.syncservice.metrics", DISPATCH_QUEUE_SERIAL_WITH_AUTORELEASE_POOL,
         dispatch_get_global_queue(QOS_CLASS_UTILITY, 0));
@@ -175,6 +211,7 @@ static const uint8_t kMaxEnqueuedSyncs = 2;
     return;
   }
   syncState.eventBatchSize = self.eventBatchSize;
+  syncState.eventFields = self.eventFields;
   SNTSyncEventUpload* p = [[SNTSyncEventUpload alloc] initWithState:syncState];
   BOOL success;
   if (events && [p uploadEvents:events]) {
@@ -298,6 +335,30 @@ static const uint8_t kMaxEnqueuedSyncs = 2;
   reply(nil);
 }
 
+- (void)syncCircuitBreakerStatus:(void (^)(NSDictionary*))reply {
+  reply([self.circuitBreaker status]);
+}
+
+- (void)pushServerCertificateExpiry:(void (^)(NSDate*))reply {
+  if (![self.pushNotifications isKindOfClass:[SNTPushClientNATS class]]) {
+    reply(nil);
+    return;
+  }
+  reply([(SNTPushClientNATS*)self.pushNotifications serverCertificateNotAfter]);
+}
+
+- (void)ruleSourcesStatus:(void (^)(NSArray<NSDictionary*>*))reply {
+  reply([self.ruleSourceScheduler status]);
+}
+
+- (void)pushNotificationDiagnostics:(void (^)(NSDictionary*))reply {
+  if (![self.pushNotifications isKindOfClass:[SNTPushClientNATS class]]) {
+    reply(@{kPushDiagnosticsEnabled : @NO});
+    return;
+  }
+  reply([(SNTPushClientNATS*)self.pushNotifications diagnostics]);
+}
+
 - (void)pushNotificationReconnect {
   if (!self.pushNotifications) {
     LOGD(@"Push notifications not configured, nothing to reconnect");
@@ -409,6 +470,87 @@ static const uint8_t kMaxEnqueuedSyncs = 2;
   }
 }
 
+- (void)enrollmentTestWithSyncURL:(NSURL*)syncURL
+                            reply:(void (^)(NSArray<NSDictionary*>* phases))reply {
+  SNTSyncStatusType status = SNTSyncStatusTypeUnknown;
+  SNTSyncState* syncState = [self createSyncStateWithBaseURL:syncURL status:&status];
+  if (!syncState) {
+    SLOGE(@"Failed to create sync state: %ld", (long)status);
+    reply(@[ @{
+      kEnrollmentTestPhase : @"setup",
+      kEnrollmentTestStatus : kEnrollmentTestStatusFailed,
+      kEnrollmentTestDetail : [NSString stringWithFormat:@"Failed to create sync state: %ld",
+                                                         (long)status],
+    } ]);
+    return;
+  }
+
+  // The check simulates a fresh host, so it must not reuse this host's XSRF or push tokens.
+  syncState.xsrfToken = nil;
+  syncState.xsrfTokenHeader = nil;
+  syncState.pushNotificationsToken = nil;
+
+  SNTSyncEnrollmentCheck* check = [[SNTSyncEnrollmentCheck alloc] initWithSyncState:syncState];
+  reply([check run]);
+}
+
+- (void)reconcileRulesWithSyncURL:(NSURL*)syncURL
+                            reply:(void (^)(NSDictionary<NSString*, id>* report))reply {
+  // Run on syncQueue so the reconcile can't interleave with a scheduled sync's rule download.
+  dispatch_async(self.syncQueue, ^{
+    SNTSyncStatusType status = SNTSyncStatusTypeUnknown;
+    SNTSyncState* syncState = [self createSyncStateWithBaseURL:syncURL status:&status];
+    if (!syncState) {
+      SLOGE(@"Failed to create sync state: %ld", (long)status);
+      reply(@{
+        kRuleReconcileError :
+            [NSString stringWithFormat:@"Failed to create sync state: %ld", (long)status],
+      });
+      return;
+    }
+
+    // The server may not be the configured sync server, so don't hand it this host's tokens.
+    syncState.xsrfToken = nil;
+    syncState.xsrfTokenHeader = nil;
+    syncState.pushNotificationsToken = nil;
+
+    SNTSyncRuleReconcile* reconcile = [[SNTSyncRuleReconcile alloc] initWithSyncState:syncState];
+    reply([reconcile run]);
+  });
+}
+
+- (void)benchmarkUploadWithSyncURL:(NSURL*)syncURL
+                              rate:(NSUInteger)rate
+                          duration:(NSUInteger)duration
+                             reply:(void (^)(NSDictionary<NSString*, id>* report))reply {
+  // Run on eventUploadQueue so a long benchmark doesn't hold up scheduled syncs.
+  dispatch_async(self.eventUploadQueue, ^{
+    SNTSyncStatusType status = SNTSyncStatusTypeUnknown;
+    SNTSyncState* syncState = [self createSyncStateWithBaseURL:syncURL status:&status];
+    if (!syncState) {
+      SLOGE(@"Failed to create sync state: %ld", (long)status);
+      reply(@{
+        kUploadBenchmarkError :
+            [NSString stringWithFormat:@"Failed to create sync state: %ld", (long)status],
+      });
+      return;
+    }
+
+    // The server may not be the configured sync server, so don't hand it this host's tokens.
+    syncState.xsrfToken = nil;
+    syncState.xsrfTokenHeader = nil;
+    syncState.pushNotificationsToken = nil;
+
+    // Batch events the way the last sync was told to.
+    syncState.eventBatchSize = self.eventBatchSize;
+    syncState.eventFields = self.eventFields;
+
+    SNTSyncUploadBenchmark* benchmark =
+        [[SNTSyncUploadBenchmark alloc] initWithSyncState:syncState];
+    reply([benchmark runWithRate:rate duration:duration]);
+  });
+}
+
 #pragma mark sync control / SNTPushNotificationsDelegate methods
 
 - (void)sync {
@@ -429,6 +571,8 @@ static const uint8_t kMaxEnqueuedSyncs = 2;
       dispatch_semaphore_signal(self.syncLimiter);
     };
     SLOGI(@"Starting sync...");
+    // Pick up any changes to the configured rule sources.
+    [self.ruleSourceScheduler updateSources:[[SNTConfigurator configurator] ruleSources]];
     if (syncType != SNTSyncTypeNormal) {
       dispatch_semaphore_t sema = dispatch_semaphore_create(0);
       [[self.daemonConn remoteObjectProxy] updateSyncSettings:SyncTypeConfigBundle(syncType)
@@ -492,6 +636,229 @@ static const uint8_t kMaxEnqueuedSyncs = 2;
   [self preflightWithSyncState:syncState];
 }
 
+- (void)overrideFullSyncInterval:(NSUInteger)seconds
+                     forDuration:(NSTimeInterval)duration
+                             tag:(NSString*)tag {
+  NSDate* expiry;
+  @synchronized(self.fullSyncIntervalOverride) {
+    expiry = [self.fullSyncIntervalOverride applyInterval:seconds
+                                              forDuration:duration
+                                                      tag:tag
+                                                      now:[NSDate date]];
+  }
+  if (!expiry) return;
+
+  NSUInteger interval = [self currentFullSyncInterval];
+  LOGI(@"Full sync interval overridden to %lu seconds by %@ until %@", interval, tag, expiry);
+  [self rescheduleTimerQueue:self.fullSyncTimer secondsFromNow:interval];
+
+  // Revert to the base interval once the override expires. If the override was
+  // replaced in the meantime it is still active and the newer override's own
+  // expiry handles the revert.
+  dispatch_after(dispatch_walltime(NULL, (int64_t)(expiry.timeIntervalSinceNow * NSEC_PER_SEC)),
+                 dispatch_get_main_queue(), ^{
+                   @synchronized(self.fullSyncIntervalOverride) {
+                     if ([self.fullSyncIntervalOverride isActiveAt:[NSDate date]]) return;
+                   }
+                   NSUInteger baseInterval = [self currentFullSyncInterval];
+                   LOGI(@"Full sync interval override expired, reverting to %lu seconds",
+                        baseInterval);
+                   [self rescheduleTimerQueue:self.fullSyncTimer secondsFromNow:baseInterval];
+                 });
+}
+
+- (void)collectDiagnosticsSecondsFromNow:(uint64_t)seconds {
+  dispatch_after(dispatch_time(DISPATCH_TIME_NOW, (int64_t)(seconds * NSEC_PER_SEC)),
+                 self.metricsQueue, ^{
+                   [self uploadDiagnostics];
+                 });
+}
+
+- (void)reportRulesSecondsFromNow:(uint64_t)seconds {
+  dispatch_after(dispatch_time(DISPATCH_TIME_NOW, (int64_t)(seconds * NSEC_PER_SEC)),
+                 self.metricsQueue, ^{
+                   [self uploadRuleSnapshot];
+                 });
+}
+
+- (void)rotatePushCredentialsSecondsFromNow:(uint64_t)seconds {
+  // The preflight hands the new credentials to the push client, which reconnects if they changed.
+  // Run on syncQueue so the preflight can't overlap a sync's own preflight.
+  dispatch_after(dispatch_time(DISPATCH_TIME_NOW, (int64_t)(seconds * NSEC_PER_SEC)),
+                 self.syncQueue, ^{
+                   LOGI(@"Refreshing push credentials at the sync server's request");
+                   [self preflightSync];
+                 });
+}
+
+- (void)pushCredentialsRejected {
+  NSUInteger interval = [self currentFullSyncInterval];
+  LOGW(@"Push credentials were rejected, syncing every %lu seconds until new ones are received",
+       interval);
+  [self rescheduleTimerQueue:self.fullSyncTimer secondsFromNow:interval];
+  // The push client fetches new credentials through refreshPushCredentialsWithReply:.
+}
+
+- (void)refreshPushCredentialsWithReply:(void (^)(NSString* jwt, NSString* nkey,
+                                                  NSError* error))reply {
+  // Run on syncQueue so the preflight can't overlap a sync's own preflight.
+  dispatch_async(self.syncQueue, ^{
+    SNTSyncStatusType status = SNTSyncStatusTypeUnknown;
+    SNTSyncState* syncState = [self createSyncStateWithStatus:&status];
+    if (!syncState) {
+      reply(nil, nil,
+            [SNTError createErrorWithFormat:@"Unable to create sync state: %lu", status]);
+      return;
+    }
+    syncState.preflightOnly = YES;
+
+    SNTSyncPreflight* p = [[SNTSyncPreflight alloc] initWithState:syncState];
+    if (![p sync]) {
+      reply(nil, nil, [SNTError createErrorWithFormat:@"Preflight failed"]);
+      return;
+    }
+    self.xsrfToken = syncState.xsrfToken;
+    self.xsrfTokenHeader = syncState.xsrfTokenHeader;
+
+    if (!syncState.pushJWT || !syncState.pushNKey) {
+      reply(nil, nil,
+            [SNTError createErrorWithFormat:@"Preflight did not provide push credentials"]);
+      return;
+    }
+    reply(syncState.pushJWT, syncState.pushNKey, nil);
+  });
+}
+
+- (void)exportDecisionsFrom:(NSDate*)start
+                         to:(NSDate*)end
+                      limit:(NSUInteger)limit
+                      reply:(void (^)(NSArray<SNTStoredExecutionEvent*>*))reply {
+  [[self.daemonConn remoteObjectProxy] recentDecisionsFrom:start to:end limit:limit reply:reply];
+}
+
+// Must be called on the metricsQueue.
+- (void)uploadDiagnostics {
+  SNTSyncStatusType status = SNTSyncStatusTypeUnknown;
+  SNTSyncState* syncState = [self createSyncStateWithStatus:&status];
+  if (!syncState) {
+    LOGE(@"Diagnostics upload failed to create sync state: %ld", status);
+    return;
+  }
+
+  NSDictionary* bundle = [SNTDiagnostics bundleWithSections:[self diagnosticsSections]];
+  SNTSyncDiagnosticsUpload* p = [[SNTSyncDiagnosticsUpload alloc] initWithState:syncState];
+  if ([p uploadDiagnostics:bundle]) {
+    LOGI(@"Diagnostics upload complete");
+  } else {
+    LOGE(@"Diagnostics upload failed");
+  }
+  self.xsrfToken = syncState.xsrfToken;
+  self.xsrfTokenHeader = syncState.xsrfTokenHeader;
+}
+
+// Must be called on the metricsQueue.
+- (void)uploadRuleSnapshot {
+  SNTSyncStatusType status = SNTSyncStatusTypeUnknown;
+  SNTSyncState* syncState = [self createSyncStateWithStatus:&status];
+  if (!syncState) {
+    LOGE(@"Rule snapshot upload failed to create sync state: %ld", status);
+    return;
+  }
+
+  SNTSyncRuleSnapshotUpload* p = [[SNTSyncRuleSnapshotUpload alloc] initWithState:syncState];
+  if ([p uploadRuleSnapshot]) {
+    LOGI(@"Rule snapshot upload complete");
+  } else {
+    LOGE(@"Rule snapshot upload failed");
+  }
+  self.xsrfToken = syncState.xsrfToken;
+  self.xsrfTokenHeader = syncState.xsrfTokenHeader;
+}
+
+// The sync service's own state, plus what santad reports about itself, for the diagnostics
+// bundle. SNTDiagnostics redacts the result.
+- (NSDictionary<NSString*, id>*)diagnosticsSections {
+  SNTConfigurator* config = [SNTConfigurator configurator];
+
+  __block NSDictionary* pushDiagnostics;
+  [self pushNotificationDiagnostics:^(NSDictionary* diagnostics) {
+    pushDiagnostics = diagnostics;
+  }];
+
+  NSMutableDictionary* sync = [NSMutableDictionary dictionary];
+  sync[@"server"] = config.syncBaseURL;
+  sync[@"last_successful_full"] = config.fullSyncLastSuccess;
+  sync[@"last_successful_rule"] = config.ruleSyncLastSuccess;
+  sync[@"full_sync_interval_seconds"] = @([self currentFullSyncInterval]);
+  sync[@"circuit_breaker"] = [self.circuitBreaker status];
+  sync[@"push_notifications"] = pushDiagnostics;
+
+  // Whatever santad hasn't answered within the timeout is left out.
+  NSMutableDictionary* daemon = [NSMutableDictionary dictionary];
+  dispatch_group_t group = dispatch_group_create();
+  id<SNTDaemonControlXPC> rop = [self.daemonConn remoteObjectProxy];
+  dispatch_group_enter(group);
+  [rop clientMode:^(SNTClientMode mode) {
+    @synchronized(daemon) {
+      daemon[@"client_mode"] = @(mode);
+    }
+    dispatch_group_leave(group);
+  }];
+  dispatch_group_enter(group);
+  [rop databaseRuleCounts:^(struct RuleCounts counts) {
+    @synchronized(daemon) {
+      daemon[@"rule_counts"] = @{
+        @"binary" : @(counts.binary),
+        @"certificate" : @(counts.certificate),
+        @"compiler" : @(counts.compiler),
+        @"transitive" : @(counts.transitive),
+        @"teamid" : @(counts.teamID),
+        @"signingid" : @(counts.signingID),
+        @"cdhash" : @(counts.cdhash),
+        @"file_access" : @(counts.fileAccess),
+        @"network_flow" : @(counts.networkFlow),
+        @"signals" : @(counts.signals),
+      };
+    }
+    dispatch_group_leave(group);
+  }];
+  dispatch_group_enter(group);
+  [rop databaseEventCount:^(int64_t count) {
+    @synchronized(daemon) {
+      daemon[@"events_pending_upload"] = @(count);
+    }
+    dispatch_group_leave(group);
+  }];
+  if (dispatch_group_wait(group, dispatch_time(DISPATCH_TIME_NOW, 5 * NSEC_PER_SEC))) {
+    LOGW(@"Timeout waiting for daemon while collecting diagnostics");
+  }
+
+  NSDictionary* daemonCopy;
+  @synchronized(daemon) {
+    daemonCopy = [daemon copy];
+  }
+  return @{
+    @"sync" : sync,
+    @"rule_sources" : [self.ruleSourceScheduler status],
+    @"daemon" : daemonCopy,
+  };
+}
+
+// Returns `interval`, unless a sync interval override is currently in effect.
+- (NSUInteger)fullSyncIntervalWithBase:(NSUInteger)interval {
+  @synchronized(self.fullSyncIntervalOverride) {
+    return [self.fullSyncIntervalOverride intervalWithBase:interval now:[NSDate date]];
+  }
+}
+
+// The full sync interval from the most recent server configuration, taking any
+// active override into account.
+- (NSUInteger)currentFullSyncInterval {
+  NSUInteger interval = self.pushNotifications ? self.pushNotifications.fullSyncInterval
+                                               : self.persistedFullSyncInterval;
+  return [self fullSyncIntervalWithBase:interval];
+}
+
 - (void)pushNotificationSyncSecondsFromNow:(uint64_t)seconds {
   if (seconds > 0) {
     [self rescheduleTimerQueue:self.fullSyncTimer secondsFromNow:seconds];
@@ -646,6 +1013,7 @@ static const uint8_t kMaxEnqueuedSyncs = 2;
     [self stopReachability];
 
     self.eventBatchSize = syncState.eventBatchSize;
+    self.eventFields = syncState.eventFields;
 
     // Keep the persisted fallback current when the server provides a value.
     if (syncState.fullSyncInterval) {
@@ -658,7 +1026,9 @@ static const uint8_t kMaxEnqueuedSyncs = 2;
       // Check kill switch — respect explicit admin disable
       if ([[SNTConfigurator configurator] enablePushNotifications]) {
         LOGI(@"Creating NATS push client after successful sync v2 preflight");
-        self.pushNotifications = [[SNTPushClientNATS alloc] initWithSyncDelegate:self];
+        SNTPushClientNATS* client = [[SNTPushClientNATS alloc] initWithSyncDelegate:self];
+        client.credentialProvider = self;
+        self.pushNotifications = client;
       }
     } else if (!syncState.isSyncV2 && self.pushNotifications &&
                [self.pushNotifications isKindOfClass:[SNTPushClientNATS class]]) {
@@ -701,12 +1071,13 @@ static const uint8_t kMaxEnqueuedSyncs = 2;
     // is nil when the server does not set push_notification_full_sync_interval_seconds
     // (e.g. sync v1). In that case, fall back to the server's regular full_sync_interval.
     if (self.pushNotifications && syncState.pushNotificationsFullSyncInterval) {
-      [self rescheduleTimerQueue:self.fullSyncTimer
-                  secondsFromNow:self.pushNotifications.fullSyncInterval];
+      NSUInteger interval = [self fullSyncIntervalWithBase:self.pushNotifications.fullSyncInterval];
+      [self rescheduleTimerQueue:self.fullSyncTimer secondsFromNow:interval];
     } else {
       NSUInteger interval = syncState.fullSyncInterval
                                 ? syncState.fullSyncInterval.unsignedIntegerValue
                                 : self.persistedFullSyncInterval;
+      interval = [self fullSyncIntervalWithBase:interval];
       LOGD(@"Push notifications not configured by server. Sync every %lu min.", interval / 60);
       [self rescheduleTimerQueue:self.fullSyncTimer secondsFromNow:interval];
     }
@@ -718,12 +1089,15 @@ static const uint8_t kMaxEnqueuedSyncs = 2;
     // the smaller of the default sync interval (default 10 minutes) and whatever the
     // last push full sync interval was set to (default 4 hours).
     // If push notifications are not enabled, the default sync interval was already set (10m).
-    auto interval = std::min(self.pushNotifications.fullSyncInterval, kDefaultFullSyncInterval);
+    NSUInteger pushInterval =
+        [self fullSyncIntervalWithBase:self.pushNotifications.fullSyncInterval];
+    auto interval = std::min(pushInterval, kDefaultFullSyncInterval);
     [self rescheduleTimerQueue:self.fullSyncTimer secondsFromNow:interval];
   }
 
   SLOGE(@"Preflight failed, will try again once %@ is reachable",
         [[SNTConfigurator configurator] syncBaseURL].absoluteString);
+  [[SNTSyncTelemetry sharedTelemetry] incrementCounter:SNTSyncTelemetryCounterSyncErrors by:1];
   [self startReachability];
   return SNTSyncStatusTypePreflightFailed;
 }
@@ -737,6 +1111,7 @@ static const uint8_t kMaxEnqueuedSyncs = 2;
   }
 
   SLOGE(@"Event upload failed, aborting run");
+  [[SNTSyncTelemetry sharedTelemetry] incrementCounter:SNTSyncTelemetryCounterSyncErrors by:1];
   return SNTSyncStatusTypeEventUploadFailed;
 }
 
@@ -768,6 +1143,7 @@ static const uint8_t kMaxEnqueuedSyncs = 2;
   }
 
   SLOGE(@"Rule download failed, aborting run");
+  [[SNTSyncTelemetry sharedTelemetry] incrementCounter:SNTSyncTelemetryCounterSyncErrors by:1];
   return SNTSyncStatusTypeRuleDownloadFailed;
 }
 
@@ -781,6 +1157,7 @@ static const uint8_t kMaxEnqueuedSyncs = 2;
     return SNTSyncStatusTypeSuccess;
   }
   SLOGE(@"Postflight failed");
+  [[SNTSyncTelemetry sharedTelemetry] incrementCounter:SNTSyncTelemetryCounterSyncErrors by:1];
   return SNTSyncStatusTypePostflightFailed;
 }
 
@@ -832,11 +1209,42 @@ static const uint8_t kMaxEnqueuedSyncs = 2;
 }
 
 - (SNTSyncState*)createSyncStateWithStatus:(SNTSyncStatusType*)status {
+  SNTConfigurator* config = [SNTConfigurator configurator];
+  SNTSyncState* syncState = [self createSyncStateWithBaseURL:config.syncBaseURL status:status];
+
+  // Only requests to the configured sync server go through the circuit breaker. Pick up any
+  // config changes made since the last sync.
+  self.circuitBreaker.failureThreshold = config.syncCircuitBreakerFailureThreshold;
+  self.circuitBreaker.cooldown = config.syncCircuitBreakerCooldownSec;
+  syncState.circuitBreaker = self.circuitBreaker;
+
+  if (config.uploadPushServerCertificateExpiryWarning &&
+      [self.pushNotifications isKindOfClass:[SNTPushClientNATS class]]) {
+    SNTPushClientNATS* natsClient = (SNTPushClientNATS*)self.pushNotifications;
+    if (natsClient.serverCertificateExpiresSoon) {
+      syncState.pushServerCertificateExpiry = natsClient.serverCertificateNotAfter;
+    }
+  }
+  return syncState;
+}
+
+// Rule sources are separate servers, so they are not guarded by the sync server's circuit breaker
+// and must not be sent its XSRF token.
+- (SNTSyncState*)createRuleSourceSyncState:(SNTRuleSource*)source {
+  SNTSyncState* syncState = [self createSyncStateWithBaseURL:source.url status:NULL];
+  syncState.xsrfToken = nil;
+  syncState.xsrfTokenHeader = nil;
+  syncState.pushNotificationsToken = nil;
+  return syncState;
+}
+
+- (SNTSyncState*)createSyncStateWithBaseURL:(NSURL*)syncBaseURL
+                                     status:(SNTSyncStatusType*)status {
   // Gather some data needed during some sync stages
   SNTSyncState* syncState = [[SNTSyncState alloc] init];
   SNTConfigurator* config = [SNTConfigurator configurator];
 
-  syncState.syncBaseURL = config.syncBaseURL;
+  syncState.syncBaseURL = syncBaseURL;
   if (syncState.syncBaseURL.absoluteString.length == 0) {
     SLOGE(@"Missing SyncBaseURL. Can't sync without it.");
     if (status) *status = SNTSyncStatusTypeMissingSyncBaseURL;
@@ -845,7 +1253,11 @@ static const uint8_t kMaxEnqueuedSyncs = 2;
     SLOGW(@"SyncBaseURL is not over HTTPS!");
   }
 
-  syncState.machineID = config.machineID;
+  // Ask the daemon first, as only it can read a machine ID generated after a conflict.
+  [[self.daemonConn synchronousRemoteObjectProxy] machineID:^(NSString* machineID) {
+    syncState.machineID = machineID;
+  }];
+  if (syncState.machineID.length == 0) syncState.machineID = config.machineID;
   if (syncState.machineID.length == 0) {
     SLOGE(@"Missing Machine ID. Can't sync without it.");
     if (status) *status = SNTSyncStatusTypeMissingMachineID;
@@ -911,15 +1323,7 @@ static const uint8_t kMaxEnqueuedSyncs = 2;
   }];
 
   // Configure server auth
-  if (santa::IsDomainPinned(syncState.syncBaseURL)) {
-#ifndef DEBUG
-    authURLSession.serverRootsPemString = santa::PinnedCertPEMs();
-#endif
-  } else if ([config syncServerAuthRootsFile]) {
-    authURLSession.serverRootsPemFile = [config syncServerAuthRootsFile];
-  } else if ([config syncServerAuthRootsData]) {
-    authURLSession.serverRootsPemData = [config syncServerAuthRootsData];
-  }
+  santa::ConfigureSyncServerRoots(authURLSession, syncState.syncBaseURL);
 
 // Force sync v2 via compile-time define
 #ifdef SANTA_FORCE_SYNC_V2
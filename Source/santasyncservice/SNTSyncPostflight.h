@@ -16,5 +16,9 @@
 
 #import "SNTSyncStage.h"
 
+/// The postflight request header carrying the expiry date of a push server certificate that is
+/// about to expire, if the client is configured to report it.
+extern NSString* const kSyncPushServerCertificateExpiryHeader;
+
 @interface SNTSyncPostflight : SNTSyncStage
 @end
@@ -22,9 +22,13 @@
 #import "Source/common/String.h"
 #include "Source/santasyncservice/ProtoTraits.h"
 #import "Source/santasyncservice/SNTSyncConfigBundle.h"
+#import "Source/santasyncservice/SNTSyncExecutionCountUpload.h"
 #import "Source/santasyncservice/SNTSyncState.h"
+#import "Source/santasyncservice/SNTSyncTelemetry.h"
 #include "google/protobuf/arena.h"
 
+NSString* const kSyncPushServerCertificateExpiryHeader = @"X-Santa-Push-Server-Certificate-Expiry";
+
 namespace {
 
 template <bool IsV2>
@@ -66,8 +70,27 @@ BOOL Postflight(SNTSyncPostflight* self) {
     }
   }];
 
+  // The postflight message is shared with the server, so the telemetry counters travel in a
+  // header rather than in the payload. They are only reset once the server has accepted them.
+  SNTSyncTelemetry* telemetry = [SNTSyncTelemetry sharedTelemetry];
+  NSDictionary<NSString*, NSNumber*>* snapshot = [telemetry snapshot];
+  NSMutableURLRequest* request = [self requestWithMessage:req];
+  [request setValue:[SNTSyncTelemetry headerValueForSnapshot:snapshot]
+      forHTTPHeaderField:kSyncTelemetryHeader];
+  if (self.syncState.pushServerCertificateExpiry) {
+    NSISO8601DateFormatter* formatter = [[NSISO8601DateFormatter alloc] init];
+    [request setValue:[formatter stringFromDate:self.syncState.pushServerCertificateExpiry]
+        forHTTPHeaderField:kSyncPushServerCertificateExpiryHeader];
+  }
+
   typename Traits::PostflightResponseT response;
-  [self performRequest:[self requestWithMessage:req] intoMessage:&response timeout:30];
+  if (request && ![self performRequest:request intoMessage:&response timeout:30]) {
+    [telemetry resetWithSnapshot:snapshot];
+
+    // Execution counts are reported only for syncs the server has accepted. A failed upload keeps
+    // the counts for the next sync and doesn't fail this one.
+    [[[SNTSyncExecutionCountUpload alloc] initWithState:self.syncState] sync];
+  }
   [rop updateSyncSettings:PostflightConfigBundle(self.syncState)
                     reply:^{
                     }];
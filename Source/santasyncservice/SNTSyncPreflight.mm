@@ -95,6 +95,10 @@ SNTNetworkFlowDefaultAction NetworkFlowDefaultActionFromProto(
 }
 
 static NSString* LoadedSantanetdVersion(MOLXPCConnection* daemonConn) {
+  if (!daemonConn) {
+    return nil;
+  }
+
   dispatch_semaphore_t sema = dispatch_semaphore_create(0);
   __block NSString* version;
   [[daemonConn remoteObjectProxy]
@@ -108,6 +112,70 @@ static NSString* LoadedSantanetdVersion(MOLXPCConnection* daemonConn) {
   return version;
 }
 
+// Returns YES if osVersion is below minimumVersion. Versions are compared by their numeric
+// components, with missing components treated as 0, so "14" is the same as "14.0.0".
+static BOOL OSVersionIsBelow(NSString* osVersion, NSString* minimumVersion) {
+  NSArray<NSString*>* current = [osVersion componentsSeparatedByString:@"."];
+  NSArray<NSString*>* minimum = [minimumVersion componentsSeparatedByString:@"."];
+  for (NSUInteger i = 0; i < MAX(current.count, minimum.count); ++i) {
+    NSInteger c = i < current.count ? current[i].integerValue : 0;
+    NSInteger m = i < minimum.count ? minimum[i].integerValue : 0;
+    if (c != m) return c < m;
+  }
+  return NO;
+}
+
+// The settings a sync server can send in preflight response headers, see kSync*Header in
+// SNTSyncConstants.h. Missing or malformed headers are left nil (or false), which keeps the
+// client's default behavior.
+struct PreflightResponseHeaders {
+  NSString* minimumOSVersion;
+  NSArray<NSString*>* eventFields;
+  NSNumber* telemetrySampleRate;
+  bool machineIDConflict = false;
+  NSNumber* pushPingInterval;
+  NSNumber* pushMaxPingsOut;
+};
+
+PreflightResponseHeaders ParsePreflightResponseHeaders(NSHTTPURLResponse* response) {
+  PreflightResponseHeaders headers;
+
+  NSString* minimumOSVersion = [response valueForHTTPHeaderField:kSyncMinimumOSVersionHeader];
+  headers.minimumOSVersion = minimumOSVersion.length ? minimumOSVersion : nil;
+
+  NSString* fields = [response valueForHTTPHeaderField:kSyncEventFieldsHeader];
+  NSMutableArray<NSString*>* eventFields;
+  for (NSString* field in [fields componentsSeparatedByString:@","]) {
+    NSString* trimmed =
+        [field stringByTrimmingCharactersInSet:[NSCharacterSet whitespaceCharacterSet]];
+    if (!trimmed.length) continue;
+    if (!eventFields) eventFields = [NSMutableArray array];
+    [eventFields addObject:trimmed];
+  }
+  headers.eventFields = eventFields;
+
+  // Only a number from 0.0 to 1.0 is accepted, so that a malformed header can't stop event
+  // uploads.
+  NSString* sampleRate = [response valueForHTTPHeaderField:kSyncTelemetrySampleRateHeader];
+  NSScanner* scanner = sampleRate ? [NSScanner scannerWithString:sampleRate] : nil;
+  double rate;
+  if ([scanner scanDouble:&rate] && scanner.isAtEnd && rate >= 0.0 && rate <= 1.0) {
+    headers.telemetrySampleRate = @(rate);
+  }
+
+  headers.machineIDConflict =
+      [response valueForHTTPHeaderField:kSyncMachineIDConflictHeader].length > 0;
+
+  NSInteger pingInterval =
+      [[response valueForHTTPHeaderField:kSyncPushPingIntervalHeader] integerValue];
+  headers.pushPingInterval = pingInterval > 0 ? @(pingInterval) : nil;
+  NSInteger maxPingsOut =
+      [[response valueForHTTPHeaderField:kSyncPushMaxPingsOutHeader] integerValue];
+  headers.pushMaxPingsOut = maxPingsOut > 0 ? @(maxPingsOut) : nil;
+
+  return headers;
+}
+
 template <bool IsV2>
 BOOL Preflight(SNTSyncPreflight* self, google::protobuf::Arena* arena,
                SNTSyncType requestSyncType) {
@@ -187,6 +255,11 @@ BOOL Preflight(SNTSyncPreflight* self, google::protobuf::Arena* arena,
     }
   }];
 
+  __block NSString* configHash;
+  [rop effectiveConfigHash:^(NSString* hash) {
+    configHash = hash;
+  }];
+
   // If user requested it or we've never had a successful sync, try from a clean slate.
   if (requestSyncType == SNTSyncTypeClean || requestSyncType == SNTSyncTypeCleanAll) {
     SLOGD(@"%@ sync requested by client",
@@ -194,14 +267,30 @@ BOOL Preflight(SNTSyncPreflight* self, google::protobuf::Arena* arena,
     req->set_request_clean_sync(true);
   }
 
+  NSMutableURLRequest* request = [self requestWithMessage:req];
+  if (configHash.length) {
+    [request setValue:configHash forHTTPHeaderField:kSyncConfigHashHeader];
+  }
+  NSString* hardwareUUID = [SNTSystemInfo hardwareUUID];
+  if (hardwareUUID.length) {
+    [request setValue:hardwareUUID forHTTPHeaderField:kSyncHardwareUUIDHeader];
+  }
+
   typename Traits::PreflightResponseT resp;
-  NSError* err = [self performRequest:[self requestWithMessage:req] intoMessage:&resp timeout:30];
+  NSHTTPURLResponse* response;
+  NSError* err = [self performRequest:request intoMessage:&resp timeout:30 response:&response];
 
   if (err) {
     SLOGE(@"Failed preflight request: %@", err);
     return NO;
   }
 
+  PreflightResponseHeaders headers = ParsePreflightResponseHeaders(response);
+  self.syncState.eventFields = headers.eventFields;
+  self.syncState.telemetrySampleRate = headers.telemetrySampleRate;
+  self.syncState.pushPingInterval = headers.pushPingInterval;
+  self.syncState.pushMaxPingsOut = headers.pushMaxPingsOut;
+
   if (resp.has_enable_bundles()) {
     self.syncState.enableBundles = @(resp.enable_bundles());
   } else if (resp.has_deprecated_bundles_enabled()) {
@@ -260,6 +349,20 @@ BOOL Preflight(SNTSyncPreflight* self, google::protobuf::Arena* arena,
     default: break;
   }
 
+  NSString* minimumOSVersion = headers.minimumOSVersion;
+  NSString* osVersion = [SNTSystemInfo osVersion];
+  if (minimumOSVersion && OSVersionIsBelow(osVersion, minimumOSVersion)) {
+    SLOGW(@"WARNING: This host is running macOS %@, below the minimum version %@ required by the "
+          @"sync server. Endpoint Security features Santa relies on may be unavailable until the "
+          @"host is updated.",
+          osVersion, minimumOSVersion);
+    if (self.syncState.clientMode == SNTClientModeLockdown &&
+        [[SNTConfigurator configurator] refuseLockdownBelowMinimumOSVersion]) {
+      SLOGW(@"Applying Monitor mode instead of Lockdown until the host meets the minimum version");
+      self.syncState.clientMode = SNTClientModeMonitor;
+    }
+  }
+
   if (resp.has_allowed_path_regex()) {
     self.syncState.allowlistRegex = StringToNSString(resp.allowed_path_regex());
   } else if (resp.has_deprecated_whitelist_regex()) {
@@ -415,12 +518,29 @@ BOOL Preflight(SNTSyncPreflight* self, google::protobuf::Arena* arena,
     HandleV2Responses(resp, self.syncState);
   }
 
-  // Update the daemon with the push token chain.
+  // Another host reporting the same machine ID, e.g. one cloned from the same image, shares this
+  // host's push subjects and sync state on the server.
+  if (headers.machineIDConflict) {
+    SLOGE(@"ERROR: The sync server reported that another host uses machine ID %@",
+          self.syncState.machineID);
+    if ([[SNTConfigurator configurator] regenerateMachineIDOnConflict]) {
+      self.syncState.generatedMachineID = [[NSUUID UUID] UUIDString];
+    }
+  }
+
+  // Update the daemon with the push token chain and any generated machine ID.
   [rop updateSyncSettings:PreflightConfigBundle(self.syncState)
                     reply:^{
                       SLOGD(@"Preflight V%d: Updated sync settings", IsV2 ? 2 : 1);
                     }];
 
+  // The rest of this sync would still run as the conflicting machine ID.
+  if (self.syncState.generatedMachineID) {
+    SLOGE(@"Generated machine ID %@, aborting sync so the next one uses it",
+          self.syncState.generatedMachineID);
+    return NO;
+  }
+
   // Check if we need to upgrade to sync v2.
   if constexpr (!IsV2) {
     if (self.syncState.pushIssuerJWT.length && self.syncState.pushJWT.length) {
@@ -643,6 +763,12 @@ void HandleV2Responses(const ::pbv2::PreflightResponse& resp, SNTSyncState* sync
     requestSyncType = syncTypeRequired;
   }];
 
+  // A dry run stands in for a fresh enrollment, which always starts from a clean slate. A
+  // reconcile needs the server's full snapshot.
+  if (self.syncState.dryRun || self.syncState.reconcile) {
+    requestSyncType = SNTSyncTypeCleanAll;
+  }
+
   if (self.syncState.isSyncV2) {
     return Preflight<true>(self, &arena, requestSyncType);
   } else {
@@ -120,6 +120,12 @@ void PopulateRequest(PublishMetricsRequest* request, NSDictionary* metrics) {
   request.set_machine_id(santa::NSStringToUTF8StringView(self.syncState.machineID));
   PopulateRequest(&request, metrics);
 
+  // Include metrics maintained by the sync service itself, e.g. event upload state.
+  NSDictionary* syncServiceMetrics = [[SNTMetricSet sharedInstance] export][@"metrics"];
+  if ([syncServiceMetrics count]) {
+    PopulateRequest(&request, @{@"metrics" : syncServiceMetrics});
+  }
+
   NSMutableURLRequest* req = [self requestWithMessage:&request];
   if (!req) {
     SLOGE(@"Failed to create publish metrics request");
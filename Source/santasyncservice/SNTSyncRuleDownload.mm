@@ -18,10 +18,17 @@
 
 #import <Foundation/Foundation.h>
 
+#include <deque>
+#include <memory>
+#include <vector>
+
 #import "Source/common/MOLXPCConnection.h"
+#import "Source/common/SNTConfigurator.h"
+#import "Source/common/SNTError.h"
 #import "Source/common/SNTFileAccessRule.h"
 #import "Source/common/SNTNetworkFlowRule.h"
 #import "Source/common/SNTRule.h"
+#import "Source/common/SNTRuleSource.h"
 #import "Source/common/SNTSignal.h"
 #import "Source/common/SNTSyncConstants.h"
 #import "Source/common/SNTXPCControlInterface.h"
@@ -33,6 +40,7 @@
 #import "Source/santasyncservice/SNTSyncConfigBundle.h"
 #import "Source/santasyncservice/SNTSyncLogging.h"
 #import "Source/santasyncservice/SNTSyncState.h"
+#import "Source/santasyncservice/SNTSyncTelemetry.h"
 #include "google/protobuf/arena.h"
 #import "src/santanetd/NetworkFlowRuleValidator.h"
 #include "syncv2/v2.pb.h"
@@ -95,9 +103,82 @@ SNTRuleCleanup SyncTypeToRuleCleanup(SNTSyncType syncType) {
   }
 }
 
+// The rules converted from a single downloaded page. The response is kept alive until the page
+// has been merged so that bundle notifications can be processed against the proto rules.
+template <bool IsV2>
+struct DownloadedRulePage {
+  std::shared_ptr<typename santa::ProtoTraits<IsV2>::RuleDownloadResponseT> response;
+  dispatch_semaphore_t converted;
+  NSMutableArray<SNTRule*>* executionRules;
+  std::vector<const typename santa::ProtoTraits<IsV2>::RuleT*> protoRules;
+  NSMutableArray<SNTFileAccessRule*>* fileAccessRules;
+  NSMutableArray<SNTNetworkFlowRule*>* networkRules;
+  NSMutableArray<SNTSignal*>* signals;
+};
+
+// Converts the rules in a downloaded page and signals `page->converted` when done. This only
+// touches the page itself, so it is safe to run concurrently for different pages.
+template <bool IsV2>
+void ConvertRulePage(DownloadedRulePage<IsV2>* page) {
+  using Traits = santa::ProtoTraits<IsV2>;
+  const typename Traits::RuleDownloadResponseT& response = *page->response;
+
+  page->executionRules = [NSMutableArray arrayWithCapacity:response.rules_size()];
+  page->fileAccessRules = [NSMutableArray array];
+  page->networkRules = [NSMutableArray array];
+  page->signals = [NSMutableArray array];
+
+  for (const typename Traits::RuleT& rule : response.rules()) {
+    SNTRule* r = RuleFromProtoRule<IsV2>(rule);
+    if (!r) {
+      SLOGD(@"Ignoring bad rule: %s", rule.Utf8DebugString().c_str());
+      continue;
+    }
+    [page->executionRules addObject:r];
+    page->protoRules.push_back(&rule);
+  }
+
+  if constexpr (IsV2) {
+    for (const typename Traits::FileAccessRuleT& faaRule : response.file_access_rules()) {
+      SNTFileAccessRule* rule = FAARuleFromProtoFileAccessRule(faaRule);
+      if (!rule) {
+        SLOGD(@"Ignoring bad file access rule: %s", faaRule.Utf8DebugString().c_str());
+        continue;
+      }
+      [page->fileAccessRules addObject:rule];
+    }
+
+    for (const ::pbv2::NetworkFlowRule& networkRule : response.network_flow_rules()) {
+      SNTNetworkFlowRule* rule = NetworkFlowRuleFromProto(networkRule);
+      if (!rule) {
+        SLOGD(@"Ignoring bad network flow rule: %s", networkRule.Utf8DebugString().c_str());
+        continue;
+      }
+      [page->networkRules addObject:rule];
+    }
+
+    for (const ::pbv2::TelemetrySignalRule& signalRule : response.telemetry_signal_rules()) {
+      SNTSignal* s = SignalFromProtoSignalRule(signalRule);
+      if (!s) {
+        SLOGD(@"Ignoring bad telemetry signal rule: %s", signalRule.Utf8DebugString().c_str());
+        continue;
+      }
+      [page->signals addObject:s];
+    }
+  }
+
+  dispatch_semaphore_signal(page->converted);
+}
+
 // Downloads new rules from server and converts them into SNTRule.
 // Returns an array of all converted rules, or nil if there was a server problem.
 // Note that rules from the server are filtered.
+//
+// Pages are chained by cursor, so they are always requested one at a time. When
+// SyncRuleDownloadConcurrency is greater than 1, converting a page is moved to a worker queue so
+// that it overlaps with fetching the following pages. Converted pages are merged strictly in the
+// order they were received, so the returned rules (and therefore the order in which santad
+// applies them) are identical to a sequential download.
 template <bool IsV2>
 SNTDownloadedRuleSets* DownloadNewRulesFromServer(SNTSyncRuleDownload* self) {
   using Traits = santa::ProtoTraits<IsV2>;
@@ -113,6 +194,40 @@ SNTDownloadedRuleSets* DownloadNewRulesFromServer(SNTSyncRuleDownload* self) {
   NSMutableArray<SNTSignal*>* newSignals = [NSMutableArray array];
   std::string cursor;
 
+  NSUInteger concurrency = [[SNTConfigurator configurator] syncRuleDownloadConcurrency];
+  dispatch_semaphore_t slots = dispatch_semaphore_create(concurrency);
+  dispatch_group_t group = dispatch_group_create();
+  dispatch_queue_t queue = dispatch_get_global_queue(QOS_CLASS_UTILITY, 0);
+
+  // Pages that have been downloaded but not yet merged, in download order. A deque is used so
+  // that appending does not invalidate the page pointers held by in-flight conversions.
+  std::deque<DownloadedRulePage<IsV2>> pages;
+
+  // Merge converted pages from the front of the queue. If `wait` is false, stop at the first page
+  // that is still being converted.
+  auto mergeConvertedPages = [&](bool wait) {
+    while (!pages.empty()) {
+      DownloadedRulePage<IsV2>& page = pages.front();
+      if (dispatch_semaphore_wait(page.converted,
+                                  wait ? DISPATCH_TIME_FOREVER : DISPATCH_TIME_NOW)) {
+        return;
+      }
+
+      // Bundle notifications are tied to the primary sync server's rules.
+      if (!self.syncState.ruleSource) {
+        for (NSUInteger i = 0; i < page.executionRules.count; i++) {
+          ProcessBundleNotificationsForRule<IsV2>(self, page.executionRules[i],
+                                                  page.protoRules[i]);
+        }
+      }
+      [newRules addObjectsFromArray:page.executionRules];
+      [newFileAccessRules addObjectsFromArray:page.fileAccessRules];
+      [newNetworkRules addObjectsFromArray:page.networkRules];
+      [newSignals addObjectsFromArray:page.signals];
+      pages.pop_front();
+    }
+  };
+
   do {
     @autoreleasepool {
       auto req = google::protobuf::Arena::Create<typename Traits::RuleDownloadRequestT>(&arena);
@@ -121,66 +236,52 @@ SNTDownloadedRuleSets* DownloadNewRulesFromServer(SNTSyncRuleDownload* self) {
       if (!cursor.empty()) {
         req->set_cursor(cursor);
       }
-      typename Traits::RuleDownloadResponseT response;
+      auto response = std::make_shared<typename Traits::RuleDownloadResponseT>();
       NSError* err = [self performRequest:[self requestWithMessage:req]
-                              intoMessage:&response
+                              intoMessage:response.get()
                                   timeout:30];
 
       if (err) {
         SLOGE(@"Error downloading rules: %@", err);
+        // In-flight conversions reference pages owned by this function.
+        dispatch_group_wait(group, DISPATCH_TIME_FOREVER);
         return nil;
       }
 
-      for (const typename Traits::RuleT& rule : response.rules()) {
-        SNTRule* r = RuleFromProtoRule<IsV2>(rule);
-        if (!r) {
-          SLOGD(@"Ignoring bad rule: %s", rule.Utf8DebugString().c_str());
-          continue;
-        }
-        ProcessBundleNotificationsForRule<IsV2>(self, r, &rule);
-        [newRules addObject:r];
-      }
-
+      cursor = response->cursor();
+      SLOGI(@"Received %lu rules", (unsigned long)response->rules_size());
+      self.syncState.rulesReceived += response->rules_size();
+      uint64_t pageRules = response->rules_size();
       if constexpr (IsV2) {
-        for (const typename Traits::FileAccessRuleT& faaRule : response.file_access_rules()) {
-          SNTFileAccessRule* rule = FAARuleFromProtoFileAccessRule(faaRule);
-          if (!rule) {
-            SLOGD(@"Ignoring bad file access rule: %s", faaRule.Utf8DebugString().c_str());
-            continue;
-          }
-          [newFileAccessRules addObject:rule];
-        }
-
-        for (const ::pbv2::NetworkFlowRule& networkRule : response.network_flow_rules()) {
-          SNTNetworkFlowRule* rule = NetworkFlowRuleFromProto(networkRule);
-          if (!rule) {
-            SLOGD(@"Ignoring bad network flow rule: %s", networkRule.Utf8DebugString().c_str());
-            continue;
-          }
-          [newNetworkRules addObject:rule];
-        }
-
-        for (const ::pbv2::TelemetrySignalRule& signalRule : response.telemetry_signal_rules()) {
-          SNTSignal* s = SignalFromProtoSignalRule(signalRule);
-          if (!s) {
-            SLOGD(@"Ignoring bad telemetry signal rule: %s", signalRule.Utf8DebugString().c_str());
-            continue;
-          }
-          [newSignals addObject:s];
-        }
+        self.syncState.fileAccessRulesReceived += response->file_access_rules_size();
+        self.syncState.networkFlowRulesReceived += response->network_flow_rules_size();
+        self.syncState.signalsReceived += response->telemetry_signal_rules_size();
+        pageRules += response->file_access_rules_size() + response->network_flow_rules_size() +
+                     response->telemetry_signal_rules_size();
       }
-
-      cursor = response.cursor();
-      SLOGI(@"Received %lu rules", (unsigned long)response.rules_size());
-      self.syncState.rulesReceived += response.rules_size();
-      if constexpr (IsV2) {
-        self.syncState.fileAccessRulesReceived += response.file_access_rules_size();
-        self.syncState.networkFlowRulesReceived += response.network_flow_rules_size();
-        self.syncState.signalsReceived += response.telemetry_signal_rules_size();
+      [[SNTSyncTelemetry sharedTelemetry] incrementCounter:SNTSyncTelemetryCounterRulesReceived
+                                                        by:pageRules];
+
+      DownloadedRulePage<IsV2>* page = &pages.emplace_back();
+      page->response = std::move(response);
+      page->converted = dispatch_semaphore_create(0);
+
+      if (concurrency == 1) {
+        ConvertRulePage<IsV2>(page);
+      } else {
+        dispatch_semaphore_wait(slots, DISPATCH_TIME_FOREVER);
+        dispatch_group_async(group, queue, ^{
+          ConvertRulePage<IsV2>(page);
+          dispatch_semaphore_signal(slots);
+        });
       }
+
+      mergeConvertedPages(false);
     }
   } while (!cursor.empty());
 
+  mergeConvertedPages(true);
+
   self.syncState.rulesProcessed = newRules.count;
   self.syncState.fileAccessRulesProcessed = newFileAccessRules.count;
   self.syncState.networkFlowRulesProcessed = newNetworkRules.count;
@@ -556,9 +657,21 @@ void ProcessDeprecatedBundleNotificationsForRule(
     return NO;
   }
 
-  // If the request was successfully completed, but no new rules received, just return
-  if (!newRules.executionRules.count && !newRules.fileAccessRules.count &&
-      !newRules.networkRules.count && !newRules.signals.count) {
+  if (self.syncState.ruleSource) {
+    return [self applyRuleSourceRules:newRules];
+  }
+
+  // If the request was successfully completed, but no new rules received, just return. A
+  // reconcile must still apply an empty snapshot so that any local rules are removed.
+  if (!self.syncState.reconcile && !newRules.executionRules.count &&
+      !newRules.fileAccessRules.count && !newRules.networkRules.count && !newRules.signals.count) {
+    return YES;
+  }
+
+  if (self.syncState.dryRun) {
+    SLOGI(@"Dry run: received %lu execution, %lu file access and %lu network flow rules",
+          newRules.executionRules.count, newRules.fileAccessRules.count,
+          newRules.networkRules.count);
     return YES;
   }
 
@@ -584,6 +697,18 @@ void ProcessDeprecatedBundleNotificationsForRule(
     return NO;
   }
 
+  // santad holds back updates while the host is heavily loaded. Fail this sync so that it is
+  // retried rather than reported to the server as applied.
+  BOOL deferred = NO;
+  for (NSError* e in errors) {
+    if (e.code == SNTErrorCodeRuleUpdateDeferred) deferred = YES;
+  }
+  if (!success && deferred) {
+    SLOGI(@"santad deferred the rule update because the system is heavily loaded, the sync will be "
+          @"retried");
+    return NO;
+  }
+
   if (!success) {
     SLOGE(@"Failed to add rule(s) to database:");
     for (NSError* e in errors) {
@@ -627,6 +752,60 @@ void ProcessDeprecatedBundleNotificationsForRule(
   return YES;
 }
 
+// Rule sources replace their full rule set on every download, even when it is empty, and only
+// provide execution rules.
+- (BOOL)applyRuleSourceRules:(SNTDownloadedRuleSets*)newRules {
+  SNTRuleSource* source = self.syncState.ruleSource;
+  if (newRules.fileAccessRules.count || newRules.networkRules.count || newRules.signals.count) {
+    SLOGW(@"Rule source %@ sent %lu file access, %lu network flow and %lu signal rules, these "
+          @"are ignored",
+          source.name, newRules.fileAccessRules.count, newRules.networkRules.count,
+          newRules.signals.count);
+  }
+
+  if (self.syncState.dryRun) {
+    SLOGI(@"Dry run: received %lu execution rules from rule source %@",
+          newRules.executionRules.count, source.name);
+    return YES;
+  }
+
+  dispatch_semaphore_t sema = dispatch_semaphore_create(0);
+  __block NSArray<NSError*>* errors;
+  __block BOOL success;
+  [[self.daemonConn remoteObjectProxy]
+      databaseRuleReplaceRulesForRuleSource:source.name
+                                 precedence:source.precedence
+                                      rules:newRules.executionRules
+                                      reply:^(BOOL didSucceed, NSArray<NSError*>* e) {
+                                        errors = e;
+                                        success = didSucceed;
+                                        dispatch_semaphore_signal(sema);
+                                      }];
+  if (dispatch_semaphore_wait(sema, dispatch_time(DISPATCH_TIME_NOW, 300 * NSEC_PER_SEC))) {
+    SLOGE(@"Failed to add rule(s) from rule source %@: timeout sending rules to daemon",
+          source.name);
+    return NO;
+  }
+
+  if (!success) {
+    SLOGE(@"Failed to add rule(s) from rule source %@:", source.name);
+    for (NSError* e in errors) {
+      SLOGE(@"\t%@. Reason: %@", e.localizedDescription, e.localizedFailureReason);
+    }
+    return NO;
+  } else if (errors.count > 0) {
+    SLOGW(@"Added rule(s) from rule source %@ but with the following reported issues:",
+          source.name);
+    for (NSError* e in errors) {
+      SLOGW(@"\t%@. Reason: %@", e.localizedDescription, e.localizedFailureReason);
+    }
+  }
+
+  SLOGI(@"Processed %lu execution rules from rule source %@", newRules.executionRules.count,
+        source.name);
+  return YES;
+}
+
 // Send out push notifications for allowed bundles/binaries whose rule download was preceded by
 // an associated announcing FCM message.
 - (void)announceUnblockingRules:(NSArray<SNTRule*>*)newRules {
@@ -0,0 +1,38 @@
+/// Copyright 2026 North Pole Security, Inc.
+///
+/// Licensed under the Apache License, Version 2.0 (the "License");
+/// you may not use this file except in compliance with the License.
+/// You may obtain a copy of the License at
+///
+///     http://www.apache.org/licenses/LICENSE-2.0
+///
+/// Unless required by applicable law or agreed to in writing, software
+/// distributed under the License is distributed on an "AS IS" BASIS,
+/// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+/// See the License for the specific language governing permissions and
+/// limitations under the License.
+
+#import <Foundation/Foundation.h>
+
+@class SNTSyncState;
+
+NS_ASSUME_NONNULL_BEGIN
+
+/// Makes the local rule database match a sync server's rule snapshot, for recovering a host whose
+/// rules have drifted. A clean sync preflight and a full rule download are run and the downloaded
+/// rules replace every local rule, including ones that were added locally and would survive a
+/// normal or clean sync. No events are uploaded and no postflight is sent.
+@interface SNTSyncRuleReconcile : NSObject
+
+- (instancetype)initWithSyncState:(SNTSyncState*)syncState NS_DESIGNATED_INITIALIZER;
+- (instancetype)init NS_UNAVAILABLE;
+
+/// Runs the reconcile and returns a report keyed by the kRuleReconcile* constants: the identifiers
+/// of the execution rules that were added and removed and the number left unchanged. A rule whose
+/// policy changed is reported as both removed and added. If the reconcile failed the report only
+/// holds kRuleReconcileError and the local rules are left as they were.
+- (NSDictionary<NSString*, id>*)run;
+
+@end
+
+NS_ASSUME_NONNULL_END
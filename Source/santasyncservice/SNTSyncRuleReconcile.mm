@@ -0,0 +1,96 @@
+/// Copyright 2026 North Pole Security, Inc.
+///
+/// Licensed under the Apache License, Version 2.0 (the "License");
+/// you may not use this file except in compliance with the License.
+/// You may obtain a copy of the License at
+///
+///     http://www.apache.org/licenses/LICENSE-2.0
+///
+/// Unless required by applicable law or agreed to in writing, software
+/// distributed under the License is distributed on an "AS IS" BASIS,
+/// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+/// See the License for the specific language governing permissions and
+/// limitations under the License.
+
+#import "Source/santasyncservice/SNTSyncRuleReconcile.h"
+
+#import "Source/common/MOLXPCConnection.h"
+#import "Source/common/SNTCommonEnums.h"
+#import "Source/common/SNTRule.h"
+#import "Source/common/SNTSyncConstants.h"
+#import "Source/common/SNTXPCControlInterface.h"
+#import "Source/santasyncservice/SNTSyncLogging.h"
+#import "Source/santasyncservice/SNTSyncPreflight.h"
+#import "Source/santasyncservice/SNTSyncRuleDownload.h"
+#import "Source/santasyncservice/SNTSyncState.h"
+
+static NSArray<NSString*>* SortedIdentifiers(NSSet<SNTRule*>* rules) {
+  NSMutableArray<NSString*>* identifiers = [NSMutableArray arrayWithCapacity:rules.count];
+  for (SNTRule* rule in rules) {
+    [identifiers addObject:rule.identifier];
+  }
+  return [identifiers sortedArrayUsingSelector:@selector(compare:)];
+}
+
+@interface SNTSyncRuleReconcile ()
+@property SNTSyncState* syncState;
+@end
+
+@implementation SNTSyncRuleReconcile
+
+- (instancetype)initWithSyncState:(SNTSyncState*)syncState {
+  self = [super init];
+  if (self) {
+    _syncState = syncState;
+    _syncState.reconcile = YES;
+  }
+  return self;
+}
+
+- (NSDictionary<NSString*, id>*)run {
+  NSSet<SNTRule*>* before = [self localRules];
+
+  SLOGI(@"Reconcile: preflight starting");
+  SNTSyncPreflight* preflight = [[SNTSyncPreflight alloc] initWithState:self.syncState];
+  if (![preflight sync]) {
+    return @{kRuleReconcileError : @"Preflight request failed"};
+  }
+
+  // Whatever sync type the server responded with, the downloaded rules must replace all local
+  // rules.
+  self.syncState.syncType = SNTSyncTypeCleanAll;
+
+  SLOGI(@"Reconcile: rule download starting");
+  SNTSyncRuleDownload* download = [[SNTSyncRuleDownload alloc] initWithState:self.syncState];
+  if (![download sync]) {
+    return @{kRuleReconcileError : @"Rule download failed"};
+  }
+
+  NSSet<SNTRule*>* after = [self localRules];
+
+  NSMutableSet<SNTRule*>* added = [after mutableCopy];
+  [added minusSet:before];
+  NSMutableSet<SNTRule*>* removed = [before mutableCopy];
+  [removed minusSet:after];
+  NSMutableSet<SNTRule*>* unchanged = [before mutableCopy];
+  [unchanged intersectSet:after];
+
+  SLOGI(@"Reconcile: %lu rules added, %lu removed and %lu unchanged", added.count, removed.count,
+        unchanged.count);
+  return @{
+    kRuleReconcileAdded : SortedIdentifiers(added),
+    kRuleReconcileRemoved : SortedIdentifiers(removed),
+    kRuleReconcileUnchanged : @(unchanged.count),
+  };
+}
+
+- (NSSet<SNTRule*>*)localRules {
+  __block NSSet<SNTRule*>* rules;
+  [[self.syncState.daemonConn synchronousRemoteObjectProxy]
+      executionRulesForReconcile:^(NSArray<SNTRule*>* r) {
+        rules = [NSSet setWithArray:r ?: @[]];
+      }];
+  return rules ?: [NSSet set];
+}
+
+@end
@@ -0,0 +1,273 @@
+/// Copyright 2026 North Pole Security, Inc.
+///
+/// Licensed under the Apache License, Version 2.0 (the "License");
+/// you may not use this file except in compliance with the License.
+/// You may obtain a copy of the License at
+///
+///     http://www.apache.org/licenses/LICENSE-2.0
+///
+/// Unless required by applicable law or agreed to in writing, software
+/// distributed under the License is distributed on an "AS IS" BASIS,
+/// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+/// See the License for the specific language governing permissions and
+/// limitations under the License.
+
+#import <Foundation/Foundation.h>
+#import <OCMock/OCMock.h>
+#import <XCTest/XCTest.h>
+
+#import "Source/common/MOLXPCConnection.h"
+#import "Source/common/SNTCommonEnums.h"
+#import "Source/common/SNTConfigurator.h"
+#import "Source/common/SNTRule.h"
+#import "Source/common/SNTSIPStatus.h"
+#import "Source/common/SNTSyncConstants.h"
+#import "Source/common/SNTSystemInfo.h"
+#import "Source/common/SNTXPCControlInterface.h"
+#import "Source/santasyncservice/SNTSyncRuleReconcile.h"
+#import "Source/santasyncservice/SNTSyncState.h"
+
+static NSString* const kLocalOnlyHash =
+    @"b7c1e3fd640c5f211c89b02c2c6122f78ce322aa5c56eb0bb54bc422a8f8b670";
+
+@interface SNTSyncRuleReconcileTest : XCTestCase
+@property SNTSyncState* syncState;
+@property id<SNTDaemonControlXPC> daemonConnRop;
+@property id configMock;
+@property id siMock;
+@property NSMutableSet<SNTRule*>* localRules;
+@property NSMutableArray<NSNumber*>* ruleCleanups;
+@end
+
+@implementation SNTSyncRuleReconcileTest
+
+- (void)setUp {
+  [super setUp];
+
+  self.configMock = OCMClassMock([SNTConfigurator class]);
+  OCMStub([self.configMock configurator]).andReturn(self.configMock);
+  OCMStub([self.configMock syncEnableProtoTransfer]).andReturn(NO);
+
+  self.siMock = OCMClassMock([SNTSystemInfo class]);
+  OCMStub([self.siMock serialNumber]).andReturn(@"QYGF4QM373");
+  OCMStub([self.siMock longHostname]).andReturn(@"full-hostname.example.com");
+  OCMStub([self.siMock osVersion]).andReturn(@"14.5");
+  OCMStub([self.siMock osBuild]).andReturn(@"23F79");
+  OCMStub([self.siMock modelIdentifier]).andReturn(@"MacBookPro18,3");
+  OCMStub([self.siMock santaFullVersion]).andReturn(@"2024.6.655965194");
+  OCMStub([self.siMock santanetdBundledVersion]).andReturn(nil);
+
+  id sipMock = OCMClassMock([SNTSIPStatus class]);
+  OCMStub([sipMock currentStatus]).andReturn(0x6f);
+
+  self.syncState = [[SNTSyncState alloc] init];
+  self.syncState.daemonConn = OCMClassMock([MOLXPCConnection class]);
+  self.daemonConnRop = OCMProtocolMock(@protocol(SNTDaemonControlXPC));
+  OCMStub([self.syncState.daemonConn remoteObjectProxy]).andReturn(self.daemonConnRop);
+  OCMStub([self.syncState.daemonConn synchronousRemoteObjectProxy]).andReturn(self.daemonConnRop);
+  self.syncState.session = OCMClassMock([NSURLSession class]);
+  self.syncState.syncBaseURL = [NSURL URLWithString:@"https://reconcile.local/"];
+  self.syncState.machineID = @"50C7E1EB-2EF5-42D4-A084-A7966FC45A95";
+  self.syncState.machineOwner = @"username1";
+
+  self.localRules = [NSMutableSet set];
+  self.ruleCleanups = [NSMutableArray array];
+  [self setupFakeRuleDatabase];
+}
+
+- (void)tearDown {
+  [self.configMock stopMocking];
+  [self.siMock stopMocking];
+  [super tearDown];
+}
+
+#pragma mark Test Helpers
+
+- (NSData*)dataFromFixture:(NSString*)file {
+  NSString* path = [[NSBundle bundleForClass:[self class]] pathForResource:file ofType:nil];
+  XCTAssertNotNil(path, @"failed to load testdata: %@", file);
+  return [NSData dataWithContentsOfFile:path];
+}
+
+/// Back the daemon connection with an in-memory rule database that applies rule cleanups the way
+/// santad does, so that tests can check what is left locally after a reconcile.
+- (void)setupFakeRuleDatabase {
+  struct RuleCounts ruleCounts = {};
+  OCMStub([self.daemonConnRop
+      databaseRuleCounts:([OCMArg invokeBlockWithArgs:OCMOCK_VALUE(ruleCounts), nil])]);
+  OCMStub([self.daemonConnRop
+      syncTypeRequired:([OCMArg invokeBlockWithArgs:OCMOCK_VALUE(SNTSyncTypeNormal), nil])]);
+  OCMStub([self.daemonConnRop
+      clientMode:([OCMArg invokeBlockWithArgs:OCMOCK_VALUE(SNTClientModeMonitor), nil])]);
+  OCMStub([self.daemonConnRop
+      databaseRulesHash:([OCMArg invokeBlockWithArgs:@"the-hash", @"the-faa-hash", @"the-nf-hash",
+                                                     @"the-signal-hash", nil])]);
+  OCMStub([self.daemonConnRop
+      effectiveConfigHash:([OCMArg invokeBlockWithArgs:@"the-config-hash", nil])]);
+  OCMStub([self.daemonConnRop
+      networkExtensionLoadedBundleVersionInfo:([OCMArg invokeBlockWithArgs:@{}, nil])]);
+  OCMStub([self.daemonConnRop updateSyncSettings:[OCMArg any] reply:([OCMArg invokeBlock])]);
+  OCMStub([self.daemonConnRop postRuleSyncNotificationForApplication:[OCMArg any]
+                                                               reply:([OCMArg invokeBlock])]);
+
+  OCMStub([self.daemonConnRop executionRulesForReconcile:[OCMArg any]])
+      .andDo(^(NSInvocation* invocation) {
+        void (^reply)(NSArray<SNTRule*>*);
+        [invocation getArgument:&reply atIndex:2];
+        reply(self.localRules.allObjects);
+      });
+
+  OCMStub([[(id)self.daemonConnRop ignoringNonObjectArgs]
+              databaseRuleAddExecutionRules:[OCMArg any]
+                            fileAccessRules:[OCMArg any]
+                           networkFlowRules:[OCMArg any]
+                                    signals:[OCMArg any]
+                                ruleCleanup:SNTRuleCleanupNone
+                                     source:SNTRuleAddSourceSyncService
+                                      reply:[OCMArg any]])
+      .andDo(^(NSInvocation* invocation) {
+        __unsafe_unretained NSArray<SNTRule*>* rules;
+        SNTRuleCleanup cleanup;
+        void (^reply)(BOOL, NSArray<NSError*>*);
+        [invocation getArgument:&rules atIndex:2];
+        [invocation getArgument:&cleanup atIndex:6];
+        [invocation getArgument:&reply atIndex:8];
+        [self.ruleCleanups addObject:@(cleanup)];
+        if (cleanup == SNTRuleCleanupAll) [self.localRules removeAllObjects];
+        [self.localRules addObjectsFromArray:rules];
+        reply(YES, nil);
+      });
+}
+
+/// Stub the mock sync server's response for the named stage.
+- (void)stubStage:(NSString*)stage
+       statusCode:(NSInteger)code
+             body:(NSData*)body
+    validateBlock:(void (^)(NSDictionary* requestBody))validateBlock {
+  NSHTTPURLResponse* resp = [[NSHTTPURLResponse alloc] initWithURL:self.syncState.syncBaseURL
+                                                        statusCode:code
+                                                       HTTPVersion:@"1.1"
+                                                      headerFields:nil];
+  NSString* prefix = [NSString stringWithFormat:@"/%@/", stage];
+  BOOL (^matches)(id) = ^BOOL(NSURLRequest* req) {
+    if (![req.URL.path hasPrefix:prefix]) return NO;
+    if (validateBlock && req.HTTPBody) {
+      validateBlock([NSJSONSerialization JSONObjectWithData:req.HTTPBody options:0 error:NULL]);
+    }
+    return YES;
+  };
+
+  OCMStub([self.syncState.session
+      dataTaskWithRequest:[OCMArg checkWithBlock:matches]
+        completionHandler:([OCMArg invokeBlockWithArgs:body ?: [NSData data], resp,
+                                                       [NSNull null], nil])]);
+}
+
+- (void)stubPreflight {
+  [self stubStage:@"preflight"
+         statusCode:200
+               body:[self dataFromFixture:@"sync_preflight_basic.json"]
+      validateBlock:^(NSDictionary* requestBody) {
+        XCTAssertEqualObjects(requestBody[@"request_clean_sync"], @YES);
+      }];
+}
+
+/// The rules in sync_ruledownload_batch2.json.
+- (NSSet<SNTRule*>*)serverSnapshot {
+  return [NSSet setWithArray:@[
+    [[SNTRule alloc]
+        initWithIdentifier:@"7846698e47ef41be80b83fb9e2b98fa6dc46c9188b068bff323c302955a00142"
+                     state:SNTRuleStateBlock
+                      type:SNTRuleTypeCertificate],
+    [[SNTRule alloc] initWithIdentifier:@"AAAAAAAAAA"
+                                  state:SNTRuleStateBlock
+                                   type:SNTRuleTypeTeamID],
+  ]];
+}
+
+#pragma mark Tests
+
+- (void)testLocalRulesMatchServerSnapshotAfterReconcile {
+  // One rule matching the server, one whose policy drifted and one only present locally.
+  [self.localRules addObjectsFromArray:@[
+    [[SNTRule alloc]
+        initWithIdentifier:@"7846698e47ef41be80b83fb9e2b98fa6dc46c9188b068bff323c302955a00142"
+                     state:SNTRuleStateBlock
+                      type:SNTRuleTypeCertificate],
+    [[SNTRule alloc] initWithIdentifier:@"AAAAAAAAAA"
+                                  state:SNTRuleStateAllow
+                                   type:SNTRuleTypeTeamID],
+    [[SNTRule alloc] initWithIdentifier:kLocalOnlyHash
+                                  state:SNTRuleStateAllow
+                                   type:SNTRuleTypeBinary],
+  ]];
+  [self stubPreflight];
+  [self stubStage:@"ruledownload"
+         statusCode:200
+               body:[self dataFromFixture:@"sync_ruledownload_batch2.json"]
+      validateBlock:nil];
+
+  NSDictionary* report = [[[SNTSyncRuleReconcile alloc] initWithSyncState:self.syncState] run];
+
+  XCTAssertNil(report[kRuleReconcileError]);
+  XCTAssertEqualObjects(self.localRules, [self serverSnapshot]);
+  XCTAssertEqualObjects(self.ruleCleanups, @[ @(SNTRuleCleanupAll) ]);
+  XCTAssertEqualObjects(report[kRuleReconcileAdded], @[ @"AAAAAAAAAA" ]);
+  XCTAssertEqualObjects(report[kRuleReconcileRemoved], (@[ @"AAAAAAAAAA", kLocalOnlyHash ]));
+  XCTAssertEqualObjects(report[kRuleReconcileUnchanged], @1);
+}
+
+- (void)testServerCannotDowngradeReconcileToNormalSync {
+  [self stubStage:@"preflight"
+         statusCode:200
+               body:[@"{\"client_mode\": \"MONITOR\", \"sync_type\": \"NORMAL\"}"
+                        dataUsingEncoding:NSUTF8StringEncoding]
+      validateBlock:nil];
+  [self stubStage:@"ruledownload"
+         statusCode:200
+               body:[self dataFromFixture:@"sync_ruledownload_batch2.json"]
+      validateBlock:nil];
+  [self.localRules addObject:[[SNTRule alloc] initWithIdentifier:kLocalOnlyHash
+                                                           state:SNTRuleStateBlock
+                                                            type:SNTRuleTypeBinary]];
+
+  [[[SNTSyncRuleReconcile alloc] initWithSyncState:self.syncState] run];
+
+  XCTAssertEqualObjects(self.localRules, [self serverSnapshot]);
+  XCTAssertEqualObjects(self.ruleCleanups, @[ @(SNTRuleCleanupAll) ]);
+}
+
+- (void)testEmptySnapshotRemovesAllLocalRules {
+  [self.localRules addObject:[[SNTRule alloc] initWithIdentifier:kLocalOnlyHash
+                                                           state:SNTRuleStateAllow
+                                                            type:SNTRuleTypeBinary]];
+  [self stubPreflight];
+  [self stubStage:@"ruledownload"
+         statusCode:200
+               body:[@"{\"rules\": []}" dataUsingEncoding:NSUTF8StringEncoding]
+      validateBlock:nil];
+
+  NSDictionary* report = [[[SNTSyncRuleReconcile alloc] initWithSyncState:self.syncState] run];
+
+  XCTAssertEqual(self.localRules.count, 0);
+  XCTAssertEqualObjects(report[kRuleReconcileAdded], @[]);
+  XCTAssertEqualObjects(report[kRuleReconcileRemoved], @[ kLocalOnlyHash ]);
+  XCTAssertEqualObjects(report[kRuleReconcileUnchanged], @0);
+}
+
+- (void)testFailedDownloadLeavesLocalRules {
+  SNTRule* rule = [[SNTRule alloc] initWithIdentifier:kLocalOnlyHash
+                                                state:SNTRuleStateAllow
+                                                 type:SNTRuleTypeBinary];
+  [self.localRules addObject:rule];
+  [self stubPreflight];
+  [self stubStage:@"ruledownload" statusCode:400 body:nil validateBlock:nil];
+
+  NSDictionary* report = [[[SNTSyncRuleReconcile alloc] initWithSyncState:self.syncState] run];
+
+  XCTAssertEqualObjects(report, @{kRuleReconcileError : @"Rule download failed"});
+  XCTAssertEqualObjects(self.localRules, [NSSet setWithObject:rule]);
+  XCTAssertEqual(self.ruleCleanups.count, 0);
+}
+
+@end
@@ -0,0 +1,87 @@
+/// Copyright 2026 North Pole Security, Inc.
+///
+/// Licensed under the Apache License, Version 2.0 (the "License");
+/// you may not use this file except in compliance with the License.
+/// You may obtain a copy of the License at
+///
+///     http://www.apache.org/licenses/LICENSE-2.0
+///
+/// Unless required by applicable law or agreed to in writing, software
+/// distributed under the License is distributed on an "AS IS" BASIS,
+/// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+/// See the License for the specific language governing permissions and
+/// limitations under the License.
+
+
+#import "Source/santasyncservice/SNTSyncRuleSnapshotUpload.h"
+
+#import "Source/common/MOLXPCConnection.h"
+#import "Source/common/SNTRule.h"
+#import "Source/common/SNTSyncConstants.h"
+#import "Source/common/SNTXPCControlInterface.h"
+#import "Source/santasyncservice/SNTSyncLogging.h"
+#import "Source/santasyncservice/SNTSyncState.h"
+
+@implementation SNTSyncRuleSnapshotUpload
+
+- (NSURL*)stageURL {
+  NSString* stageName = [@"rulesnapshot" stringByAppendingFormat:@"/%@", self.syncState.machineID];
+  return [NSURL URLWithString:stageName relativeToURL:self.syncState.syncBaseURL];
+}
+
+// Not used; this stage is invoked directly via uploadRuleSnapshot rather than the standard sync
+// flow.
+- (BOOL)sync {
+  return NO;
+}
+
+- (BOOL)uploadRuleSnapshot {
+  __block NSArray<SNTRule*>* rules;
+  [[self.syncState.daemonConn synchronousRemoteObjectProxy]
+      executionRulesForReconcile:^(NSArray<SNTRule*>* r) {
+        rules = r;
+      }];
+  if (!rules) {
+    SLOGE(@"Failed to retrieve rules for the rule snapshot");
+    return NO;
+  }
+
+  NSMutableArray<NSDictionary*>* snapshot = [NSMutableArray arrayWithCapacity:rules.count];
+  for (SNTRule* rule in rules) {
+    // Only the fields that identify a rule; the rest may hold text the server didn't send.
+    NSDictionary* dict = [rule dictionaryRepresentation];
+    [snapshot addObject:@{
+      kRuleIdentifier : dict[kRuleIdentifier],
+      kRuleType : dict[kRuleType],
+      kRulePolicy : dict[kRulePolicy],
+    }];
+  }
+  [snapshot sortUsingDescriptors:@[
+    [NSSortDescriptor sortDescriptorWithKey:kRuleType ascending:YES],
+    [NSSortDescriptor sortDescriptorWithKey:kRuleIdentifier ascending:YES],
+  ]];
+
+  NSError* error;
+  NSData* data = [NSJSONSerialization dataWithJSONObject:@{
+    @"machine_id" : self.syncState.machineID ?: @"",
+    @"rules" : snapshot,
+  }
+                                                 options:0
+                                                   error:&error];
+  if (!data) {
+    SLOGE(@"Failed to encode rule snapshot: %@", error.localizedDescription);
+    return NO;
+  }
+
+  NSMutableURLRequest* req = [self requestWithData:data contentType:@"application/json"];
+  error = [self performRequest:req intoMessage:NULL timeout:60];
+  if (error) {
+    SLOGE(@"Rule snapshot upload failed: %@", error.localizedDescription);
+    return NO;
+  }
+
+  SLOGD(@"Uploaded a snapshot of %lu rules", (unsigned long)snapshot.count);
+  return YES;
+}
+
+@end
@@ -102,6 +102,22 @@
   [self.syncManager pushNotificationReconnect];
 }
 
+- (void)pushNotificationDiagnostics:(void (^)(NSDictionary*))reply {
+  [self.syncManager pushNotificationDiagnostics:reply];
+}
+
+- (void)syncCircuitBreakerStatus:(void (^)(NSDictionary*))reply {
+  [self.syncManager syncCircuitBreakerStatus:reply];
+}
+
+- (void)pushServerCertificateExpiry:(void (^)(NSDate*))reply {
+  [self.syncManager pushServerCertificateExpiry:reply];
+}
+
+- (void)ruleSourcesStatus:(void (^)(NSArray<NSDictionary*>*))reply {
+  [self.syncManager ruleSourcesStatus:reply];
+}
+
 - (void)publishMetrics:(NSDictionary*)metrics reply:(void (^)(BOOL))reply {
   [self.syncManager publishMetrics:metrics reply:reply];
 }
@@ -125,6 +141,74 @@
   }];
 }
 
+- (void)enrollmentTestWithSyncURL:(NSURL*)syncURL
+                      logListener:(NSXPCListenerEndpoint*)logListener
+                            reply:(void (^)(NSArray<NSDictionary*>*))reply {
+  MOLXPCConnection* ll;
+  if (logListener) {
+    ll = [[MOLXPCConnection alloc] initClientWithListener:logListener];
+    ll.remoteInterface =
+        [NSXPCInterface interfaceWithProtocol:@protocol(SNTSyncServiceLogReceiverXPC)];
+    [ll resume];
+    [[SNTSyncBroadcaster broadcaster] addLogListener:ll];
+  }
+  [self.syncManager enrollmentTestWithSyncURL:syncURL
+                                        reply:^(NSArray<NSDictionary*>* phases) {
+                                          [[SNTSyncBroadcaster broadcaster] barrier];
+                                          if (ll) {
+                                            [[SNTSyncBroadcaster broadcaster] removeLogListener:ll];
+                                          }
+                                          reply(phases);
+                                        }];
+}
+
+- (void)reconcileRulesWithSyncURL:(NSURL*)syncURL
+                      logListener:(NSXPCListenerEndpoint*)logListener
+                            reply:(void (^)(NSDictionary<NSString*, id>*))reply {
+  MOLXPCConnection* ll;
+  if (logListener) {
+    ll = [[MOLXPCConnection alloc] initClientWithListener:logListener];
+    ll.remoteInterface =
+        [NSXPCInterface interfaceWithProtocol:@protocol(SNTSyncServiceLogReceiverXPC)];
+    [ll resume];
+    [[SNTSyncBroadcaster broadcaster] addLogListener:ll];
+  }
+  [self.syncManager reconcileRulesWithSyncURL:syncURL
+                                        reply:^(NSDictionary<NSString*, id>* report) {
+                                          [[SNTSyncBroadcaster broadcaster] barrier];
+                                          if (ll) {
+                                            [[SNTSyncBroadcaster broadcaster] removeLogListener:ll];
+                                          }
+                                          reply(report);
+                                        }];
+}
+
+- (void)benchmarkUploadWithSyncURL:(NSURL*)syncURL
+                              rate:(NSUInteger)rate
+                          duration:(NSUInteger)duration
+                       logListener:(NSXPCListenerEndpoint*)logListener
+                             reply:(void (^)(NSDictionary<NSString*, id>*))reply {
+  MOLXPCConnection* ll;
+  if (logListener) {
+    ll = [[MOLXPCConnection alloc] initClientWithListener:logListener];
+    ll.remoteInterface =
+        [NSXPCInterface interfaceWithProtocol:@protocol(SNTSyncServiceLogReceiverXPC)];
+    [ll resume];
+    [[SNTSyncBroadcaster broadcaster] addLogListener:ll];
+  }
+  [self.syncManager benchmarkUploadWithSyncURL:syncURL
+                                          rate:rate
+                                      duration:duration
+                                         reply:^(NSDictionary<NSString*, id>* report) {
+                                           [[SNTSyncBroadcaster broadcaster] barrier];
+                                           if (ll) {
+                                             [[SNTSyncBroadcaster broadcaster]
+                                                 removeLogListener:ll];
+                                           }
+                                           reply(report);
+                                         }];
+}
+
 - (void)syncWithLogListener:(NSXPCListenerEndpoint*)logListener
                    syncType:(SNTSyncType)syncType
                       reply:(void (^)(SNTSyncStatusType))reply {
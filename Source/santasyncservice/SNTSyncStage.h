@@ -50,6 +50,16 @@
 
 #pragma mark Internal Helpers
 
+/**
+  Creates an NSMutableURLRequest pointing at the URL for this stage with the given body, which is
+  compressed according to the sync state's content encoding.
+
+  @param requestBody The data to POST to the server.
+  @param contentType The Content-Type of the body. Defaults to application/octet-stream.
+*/
+- (nonnull NSMutableURLRequest*)requestWithData:(nonnull NSData*)requestBody
+                                    contentType:(nullable NSString*)contentType;
+
 #ifdef __cplusplus
 /**
   Creates an NSMutableURLRequest pointing at the URL for this stage and containing the JSON-encoded
@@ -64,7 +74,7 @@
   Message.
 
   @param request The request to perform
-  @param message The message to parse the response into
+  @param message The message to parse the response into. If nil, the response body is ignored.
   @Param timeout The number of seconds to allow the request to run before timing out.
 
   @return An error if performing the request failed.
@@ -86,6 +96,18 @@
                         intoMessage:(nullable google::protobuf::Message*)message
                             timeout:(NSTimeInterval)timeout
                          statusCode:(nullable NSInteger*)statusCode;
+
+/**
+  Like performRequest:intoMessage:timeout: but also returns the final HTTP response, or nil if
+  none was received. Lets a stage read response headers the sync protocol messages have no field
+  for.
+
+  @param response Out param for the final response; pass NULL to ignore.
+*/
+- (nullable NSError*)performRequest:(nonnull NSURLRequest*)request
+                        intoMessage:(nullable google::protobuf::Message*)message
+                            timeout:(NSTimeInterval)timeout
+                           response:(NSHTTPURLResponse* _Nullable* _Nullable)response;
 #endif
 
 @end
@@ -23,9 +23,12 @@
 #import "Source/common/SNTConfigurator.h"
 #import "Source/common/SNTError.h"
 #import "Source/common/SNTLogging.h"
+#import "Source/common/SNTRuleSource.h"
 #import "Source/common/SNTSyncConstants.h"
 #import "Source/common/SNTXPCControlInterface.h"
+#import "Source/common/SignedRuleBundle.h"
 #import "Source/common/String.h"
+#import "Source/santasyncservice/SNTSyncCircuitBreaker.h"
 #import "Source/santasyncservice/SNTSyncLogging.h"
 #import "Source/santasyncservice/SNTSyncState.h"
 
@@ -45,6 +48,15 @@ using santa::NSStringToUTF8String;
 
 @end
 
+// Whether a request outcome indicates the sync server is unhealthy or unreachable, as opposed
+// to a healthy server rejecting the request. Only these outcomes count towards opening the
+// circuit breaker. Losing the local network connection says nothing about the server.
+static BOOL IsServerSideFailure(NSHTTPURLResponse* response, NSError* error) {
+  if (!response) return error.code != NSURLErrorNotConnectedToInternet;
+  NSInteger code = response.statusCode;
+  return code >= 500 || code == 408 || code == 429;
+}
+
 @implementation SNTSyncStage
 
 - (nullable instancetype)initWithState:(nonnull SNTSyncState*)syncState {
@@ -141,13 +153,17 @@ using santa::NSStringToUTF8String;
   return req;
 }
 
+// Returns the final HTTP response through `finalResponse` rather than storing it on the stage, as
+// a stage may have several requests in flight at once, e.g. concurrent event upload batches.
 - (NSData*)dataFromRequest:(NSURLRequest*)request
                    timeout:(NSTimeInterval)timeout
-                statusCode:(NSInteger*)statusCode
+                  response:(NSHTTPURLResponse**)finalResponse
                      error:(NSError**)error {
   NSHTTPURLResponse* response;
   NSError* requestError;
   NSData* data;
+  SNTSyncCircuitBreaker* circuitBreaker = self.syncState.circuitBreaker;
+  BOOL rejectedByCircuitBreaker = NO;
 
   int maxAttempts = 5;
   for (int attempt = 1; attempt <= maxAttempts; ++attempt) {
@@ -157,8 +173,20 @@ using santa::NSStringToUTF8String;
       if (ts.tv_sec > 0) nanosleep(&ts, NULL);
     }
 
+    if (circuitBreaker && ![circuitBreaker allowRequestAt:[NSDate date]]) {
+      rejectedByCircuitBreaker = YES;
+      break;
+    }
+
     SLOGD(@"Performing request, attempt %d (of %d maximum)...", attempt, maxAttempts);
     data = [self performRequest:request timeout:timeout response:&response error:&requestError];
+    if (IsServerSideFailure(response, requestError)) {
+      [circuitBreaker recordFailureAt:[NSDate date]];
+    } else if (response) {
+      [circuitBreaker recordSuccess];
+    } else {
+      [circuitBreaker recordInconclusive];
+    }
     if (response.statusCode == 200) break;
 
     // If the original request failed because of a "No network" error, break out of the loop,
@@ -169,9 +197,11 @@ using santa::NSStringToUTF8String;
     // If the original request failed because of an auth error, attempt to get a new XSRF token and
     // try again. Unfortunately some servers cause NSURLSession to return 'client cert required' or
     // 'could not parse response' when a 403 occurs and SSL cert auth is enabled.
+    NSString* sentToken =
+        [request valueForHTTPHeaderField:self.syncState.xsrfTokenHeader ?: kDefaultXSRFTokenHeader];
     if ((response.statusCode == 403 || requestError.code == NSURLErrorClientCertificateRequired ||
          requestError.code == NSURLErrorCannotParseResponse) &&
-        [self fetchXSRFToken]) {
+        [self fetchXSRFTokenReplacing:sentToken]) {
       NSMutableURLRequest* mutableRequest = [request mutableCopy];
       NSString* xsrfHeader = self.syncState.xsrfTokenHeader ?: kDefaultXSRFTokenHeader;
       [mutableRequest setValue:self.syncState.xsrfToken forHTTPHeaderField:xsrfHeader];
@@ -187,10 +217,16 @@ using santa::NSStringToUTF8String;
     }
   }
 
-  // Report the final HTTP status (0 if no HTTP response was received) so callers
-  // can special-case specific codes, e.g. a 404 for an endpoint an older server
-  // does not implement.
-  if (statusCode) *statusCode = response.statusCode;
+  if (finalResponse) *finalResponse = response;
+
+  if (rejectedByCircuitBreaker && response.statusCode != 200) {
+    NSString* errStr = [NSString
+        stringWithFormat:@"Sync circuit breaker is %@, not contacting the sync server",
+                         SNTSyncCircuitBreakerStateName(circuitBreaker.state)];
+    SLOGE(@"%@", errStr);
+    [SNTError populateError:error withCode:SNTErrorCodeFailedToHTTP format:@"%@", errStr];
+    return nil;
+  }
 
   // If the final attempt resulted in an error, log the error and return nil.
   if (response.statusCode != 200) {
@@ -217,16 +253,51 @@ using santa::NSStringToUTF8String;
                intoMessage:(google::protobuf::Message*)message
                    timeout:(NSTimeInterval)timeout
                 statusCode:(NSInteger*)statusCode {
+  NSHTTPURLResponse* response;
+  NSError* error = [self performRequest:request
+                            intoMessage:message
+                                timeout:timeout
+                               response:&response];
+  // Report the final HTTP status (0 if no HTTP response was received) so callers
+  // can special-case specific codes, e.g. a 404 for an endpoint an older server
+  // does not implement.
+  if (statusCode) *statusCode = response.statusCode;
+  return error;
+}
+
+- (NSError*)performRequest:(NSURLRequest*)request
+               intoMessage:(google::protobuf::Message*)message
+                   timeout:(NSTimeInterval)timeout
+                  response:(NSHTTPURLResponse**)response {
   NSError* error;
-  NSData* data = [self dataFromRequest:request timeout:timeout statusCode:statusCode error:&error];
+  NSData* data = [self dataFromRequest:request timeout:timeout response:response error:&error];
   if (error) {
     SLOGE(@"Error performing request: %@", error.localizedDescription);
     return error;
   }
-  if (data.length == 0) {
+  if (data.length == 0 || !message) {
     return nil;
   }
 
+  // A rule source with a signing key must only ever be trusted with payloads signed by that key,
+  // so one source can't serve rules on behalf of another.
+  NSData* publicKey = self.syncState.ruleSource.publicKey;
+  if (publicKey) {
+    NSError* verifyError;
+    data = santa::VerifySignedRuleBundle(data, publicKey, &verifyError);
+    if (!data) {
+      NSString* errStr =
+          [NSString stringWithFormat:@"Response from rule source %@ failed verification: %@",
+                                     self.syncState.ruleSource.name,
+                                     verifyError.localizedDescription];
+      SLOGE(@"%@", errStr);
+      [SNTError populateError:&error
+                     withCode:SNTErrorCodeFailedToVerifySignature
+                       format:@"%@", errStr];
+      return error;
+    }
+  }
+
 #ifndef SANTA_STORE_SYNC_JSON
   if ([[SNTConfigurator configurator] syncEnableProtoTransfer]) {
     if (!message->ParseFromString(std::string((const char*)data.bytes, data.length))) {
@@ -313,27 +384,34 @@ using santa::NSStringToUTF8String;
   return data;
 }
 
-- (BOOL)fetchXSRFToken {
-  BOOL success = NO;
-  if (!self.syncState.xsrfToken.length) {  // only fetch token once per session
+// Requests from several threads can share the sync state, e.g. concurrent event upload batches,
+// so the token is fetched under a lock. A request that was sent without the current token, because
+// another request fetched it in the meantime, is retried with it instead of fetching another.
+- (BOOL)fetchXSRFTokenReplacing:(NSString*)sentToken {
+  @synchronized(self.syncState) {
+    NSString* token = self.syncState.xsrfToken;
+    if (token.length) {  // only fetch token once per session
+      return ![token isEqualToString:sentToken];
+    }
+
     NSString* stageName = [@"xsrf" stringByAppendingFormat:@"/%@", self.syncState.machineID];
     NSURL* u = [NSURL URLWithString:stageName relativeToURL:self.syncState.syncBaseURL];
     NSMutableURLRequest* request = [NSMutableURLRequest requestWithURL:u];
     [request setHTTPMethod:@"POST"];
     NSHTTPURLResponse* response;
     [self performRequest:request timeout:10 response:&response error:NULL];
-    if (response.statusCode == 200) {
-      NSDictionary* headers = [response allHeaderFields];
-      self.syncState.xsrfToken = headers[kDefaultXSRFTokenHeader];
-      NSString* xsrfTokenHeader = headers[kXSRFTokenHeader];
-      self.syncState.xsrfTokenHeader = xsrfTokenHeader.length ? xsrfTokenHeader : nil;
-      SLOGD(@"Retrieved new XSRF token");
-      success = YES;
-    } else {
+    if (response.statusCode != 200) {
       SLOGD(@"Failed to retrieve XSRF token");
+      return NO;
     }
-  };
-  return success;
+
+    NSDictionary* headers = [response allHeaderFields];
+    self.syncState.xsrfToken = headers[kDefaultXSRFTokenHeader];
+    NSString* xsrfTokenHeader = headers[kXSRFTokenHeader];
+    self.syncState.xsrfTokenHeader = xsrfTokenHeader.length ? xsrfTokenHeader : nil;
+    SLOGD(@"Retrieved new XSRF token");
+    return YES;
+  }
 }
 
 #if defined(SANTA_STORE_SYNC_JSON) && defined(DEBUG)
@@ -22,7 +22,9 @@
 #import "Source/common/ne/SNTSyncNetworkExtensionSettings.h"
 
 @class SNTCELFallbackRule;
+@class SNTRuleSource;
 @class SNTSyncManager;
+@class SNTSyncCircuitBreaker;
 @class MOLXPCConnection;
 
 /// An instance of this class is passed to each stage of the sync process for storing data
@@ -44,6 +46,14 @@
 /// The header name to use when sending the XSRF token back to the server.
 @property NSString* xsrfTokenHeader;
 
+/// Set when downloading rules from one of the configured RuleSources instead of the sync server.
+/// Only the rule download stage is run for a rule source.
+@property SNTRuleSource* ruleSource;
+
+/// Circuit breaker guarding requests to the sync server. Shared across syncs so that
+/// consecutive failures are counted over time rather than per sync. May be nil.
+@property SNTSyncCircuitBreaker* circuitBreaker;
+
 /// Full sync interval in seconds. If push notifications are being used this interval will be
 /// ignored in favor of pushNotificationsFullSyncInterval. nil if the server did not set this field.
 @property NSNumber* fullSyncInterval;
@@ -68,11 +78,25 @@
 /// kDefaultPushNotificationsGlobalRuleSyncDeadline.
 @property NSUInteger pushNotificationsGlobalRuleSyncDeadline;
 
+/// Interval in seconds at which the push client pings the push server, and the number of
+/// unanswered pings after which it considers the connection dead. nil if the server did not set
+/// them, in which case the NATS library defaults are used.
+@property NSNumber* pushPingInterval;
+@property NSNumber* pushMaxPingsOut;
+
+/// The expiry date of the push server's TLS certificate, set if it is about to expire and the
+/// client is configured to report that to the sync server. Sent with postflight.
+@property NSDate* pushServerCertificateExpiry;
+
 /// Machine identifier and owner.
 @property(copy) NSString* machineID;
 @property(copy) NSString* machineOwner;
 @property(copy) NSArray<NSString*>* machineOwnerGroups;
 
+/// Set during preflight to a new machine ID when the server reported that another host uses
+/// machineID and RegenerateMachineIDOnConflict is set.
+@property(copy) NSString* generatedMachineID;
+
 /// Settings sent from server during preflight that are set during postflight.
 @property SNTClientMode clientMode;
 @property NSString* allowlistRegex;
@@ -106,6 +130,15 @@
 /// Batch size for uploading events.
 @property NSUInteger eventBatchSize;
 
+/// The fraction (0.0-1.0) of allowed execution events santad should store for upload, as set by
+/// the server during preflight. nil if the server did not set it, in which case every event is
+/// stored.
+@property NSNumber* telemetrySampleRate;
+
+/// The execution event fields the server asked for during preflight. Only these fields, plus the
+/// core fields that identify an event, are uploaded. nil if every field should be uploaded.
+@property(copy) NSArray<NSString*>* eventFields;
+
 /// Array of bundle IDs to find binaries for.
 @property NSArray* bundleBinaryRequests;
 
@@ -127,4 +160,14 @@
 
 @property BOOL isSyncV2;
 
+/// Set by the enrollment check. The stages exchange the usual messages with the server but run
+/// without a daemon connection and leave all local state untouched: preflight always requests a
+/// clean sync and downloaded rules are counted but not applied. Postflight is not run.
+@property BOOL dryRun;
+
+/// Set when reconciling the local rules with the server. Preflight always requests a clean sync
+/// and rule download replaces every local rule, including ones added locally, with the server's
+/// snapshot, even if that snapshot is empty.
+@property BOOL reconcile;
+
 @end
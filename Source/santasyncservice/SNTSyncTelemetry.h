@@ -0,0 +1,50 @@
+/// Copyright 2026 North Pole Security, Inc.
+///
+/// Licensed under the Apache License, Version 2.0 (the "License");
+/// you may not use this file except in compliance with the License.
+/// You may obtain a copy of the License at
+///
+///     http://www.apache.org/licenses/LICENSE-2.0
+///
+/// Unless required by applicable law or agreed to in writing, software
+/// distributed under the License is distributed on an "AS IS" BASIS,
+/// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+/// See the License for the specific language governing permissions and
+/// limitations under the License.
+
+#import <Foundation/Foundation.h>
+
+NS_ASSUME_NONNULL_BEGIN
+
+/// The HTTP header used to report the telemetry snapshot on postflight requests.
+extern NSString* const kSyncTelemetryHeader;
+
+typedef NS_ENUM(NSInteger, SNTSyncTelemetryCounter) {
+  SNTSyncTelemetryCounterSyncErrors,
+  SNTSyncTelemetryCounterRulesReceived,
+  SNTSyncTelemetryCounterPushMessages,
+  SNTSyncTelemetryCounterEventsUploaded,
+};
+
+/// Per-host counters accumulated by the sync service between successful postflights and reported
+/// to the sync server as part of the postflight request. Thread-safe.
+@interface SNTSyncTelemetry : NSObject
+
++ (instancetype)sharedTelemetry;
+
+- (void)incrementCounter:(SNTSyncTelemetryCounter)counter by:(uint64_t)value;
+
+/// The current value of every counter, keyed by its name on the wire.
+- (NSDictionary<NSString*, NSNumber*>*)snapshot;
+
+/// Subtract a previously taken snapshot from the counters. Increments made after the snapshot was
+/// taken are preserved and reported on the next postflight.
+- (void)resetWithSnapshot:(NSDictionary<NSString*, NSNumber*>*)snapshot;
+
+/// Formats a snapshot as the value of kSyncTelemetryHeader, e.g.
+/// "events_uploaded=12;push_messages=3;rules_received=40;sync_errors=1".
++ (NSString*)headerValueForSnapshot:(NSDictionary<NSString*, NSNumber*>*)snapshot;
+
+@end
+
+NS_ASSUME_NONNULL_END
@@ -0,0 +1,80 @@
+/// Copyright 2026 North Pole Security, Inc.
+///
+/// Licensed under the Apache License, Version 2.0 (the "License");
+/// you may not use this file except in compliance with the License.
+/// You may obtain a copy of the License at
+///
+///     http://www.apache.org/licenses/LICENSE-2.0
+///
+/// Unless required by applicable law or agreed to in writing, software
+/// distributed under the License is distributed on an "AS IS" BASIS,
+/// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+/// See the License for the specific language governing permissions and
+/// limitations under the License.
+
+#import "Source/santasyncservice/SNTSyncTelemetry.h"
+
+#include <atomic>
+
+NSString* const kSyncTelemetryHeader = @"X-Santa-Sync-Telemetry";
+
+namespace {
+
+constexpr size_t kNumCounters = SNTSyncTelemetryCounterEventsUploaded + 1;
+
+NSString* NameForCounter(size_t counter) {
+  switch (counter) {
+    case SNTSyncTelemetryCounterSyncErrors: return @"sync_errors";
+    case SNTSyncTelemetryCounterRulesReceived: return @"rules_received";
+    case SNTSyncTelemetryCounterPushMessages: return @"push_messages";
+    case SNTSyncTelemetryCounterEventsUploaded: return @"events_uploaded";
+    default: return nil;
+  }
+}
+
+}  // namespace
+
+@implementation SNTSyncTelemetry {
+  std::atomic<uint64_t> _counters[kNumCounters];
+}
+
++ (instancetype)sharedTelemetry {
+  static SNTSyncTelemetry* telemetry;
+  static dispatch_once_t onceToken;
+  dispatch_once(&onceToken, ^{
+    telemetry = [[SNTSyncTelemetry alloc] init];
+  });
+  return telemetry;
+}
+
+- (void)incrementCounter:(SNTSyncTelemetryCounter)counter by:(uint64_t)value {
+  if (counter < 0 || static_cast<size_t>(counter) >= kNumCounters) return;
+  _counters[counter].fetch_add(value, std::memory_order_relaxed);
+}
+
+- (NSDictionary<NSString*, NSNumber*>*)snapshot {
+  NSMutableDictionary<NSString*, NSNumber*>* snapshot =
+      [NSMutableDictionary dictionaryWithCapacity:kNumCounters];
+  for (size_t i = 0; i < kNumCounters; i++) {
+    snapshot[NameForCounter(i)] = @(_counters[i].load(std::memory_order_relaxed));
+  }
+  return snapshot;
+}
+
+- (void)resetWithSnapshot:(NSDictionary<NSString*, NSNumber*>*)snapshot {
+  for (size_t i = 0; i < kNumCounters; i++) {
+    uint64_t value = [snapshot[NameForCounter(i)] unsignedLongLongValue];
+    _counters[i].fetch_sub(value, std::memory_order_relaxed);
+  }
+}
+
++ (NSString*)headerValueForSnapshot:(NSDictionary<NSString*, NSNumber*>*)snapshot {
+  NSMutableArray<NSString*>* pairs = [NSMutableArray arrayWithCapacity:snapshot.count];
+  for (NSString* name in [snapshot.allKeys sortedArrayUsingSelector:@selector(compare:)]) {
+    [pairs addObject:[NSString stringWithFormat:@"%@=%llu", name,
+                                                [snapshot[name] unsignedLongLongValue]]];
+  }
+  return [pairs componentsJoinedByString:@";"];
+}
+
+@end
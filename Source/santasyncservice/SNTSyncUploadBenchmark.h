@@ -0,0 +1,44 @@
+/// Copyright 2026 North Pole Security, Inc.
+///
+/// Licensed under the Apache License, Version 2.0 (the "License");
+/// you may not use this file except in compliance with the License.
+/// You may obtain a copy of the License at
+///
+///     http://www.apache.org/licenses/LICENSE-2.0
+///
+/// Unless required by applicable law or agreed to in writing, software
+/// distributed under the License is distributed on an "AS IS" BASIS,
+/// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+/// See the License for the specific language governing permissions and
+/// limitations under the License.
+
+#import <Foundation/Foundation.h>
+
+@class SNTSyncState;
+
+NS_ASSUME_NONNULL_BEGIN
+
+/// The maximum events per second and duration in seconds of an upload benchmark.
+extern const NSUInteger kUploadBenchmarkMaxRate;
+extern const NSUInteger kUploadBenchmarkMaxDuration;
+
+/// Measures the bandwidth event uploads use at a given event rate, for capacity planning on
+/// metered networks. Synthetic execution events are generated at the rate and uploaded through
+/// the regular event upload stage, in batches of the sync state's event batch size. Nothing is
+/// read from or removed from the events database.
+@interface SNTSyncUploadBenchmark : NSObject
+
+- (instancetype)initWithSyncState:(SNTSyncState*)syncState NS_DESIGNATED_INITIALIZER;
+- (instancetype)init NS_UNAVAILABLE;
+
+/// Generates rate events at the start of each second for duration seconds and uploads them. Each
+/// second's events are generated on schedule even if uploading the previous second's ran over.
+/// Returns a report keyed by the kUploadBenchmark* constants. Bytes are counted for every request
+/// attempt, including retries, and are the request and response bodies as sent on the wire,
+/// without HTTP headers. Latencies are of the attempts the server accepted. If the benchmark
+/// couldn't run the report only holds kUploadBenchmarkError.
+- (NSDictionary<NSString*, id>*)runWithRate:(NSUInteger)rate duration:(NSUInteger)duration;
+
+@end
+
+NS_ASSUME_NONNULL_END
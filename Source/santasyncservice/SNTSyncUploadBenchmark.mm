@@ -0,0 +1,211 @@
+/// Copyright 2026 North Pole Security, Inc.
+///
+/// Licensed under the Apache License, Version 2.0 (the "License");
+/// you may not use this file except in compliance with the License.
+/// You may obtain a copy of the License at
+///
+///     http://www.apache.org/licenses/LICENSE-2.0
+///
+/// Unless required by applicable law or agreed to in writing, software
+/// distributed under the License is distributed on an "AS IS" BASIS,
+/// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+/// See the License for the specific language governing permissions and
+/// limitations under the License.
+
+#import "Source/santasyncservice/SNTSyncUploadBenchmark.h"
+
+#include <math.h>
+#include <stdlib.h>
+
+#import "Source/common/SNTCommonEnums.h"
+#import "Source/common/SNTConfigurator.h"
+#import "Source/common/SNTStoredExecutionEvent.h"
+#import "Source/common/SNTSyncConstants.h"
+#import "Source/santasyncservice/SNTSyncEventUpload.h"
+#import "Source/santasyncservice/SNTSyncLogging.h"
+#import "Source/santasyncservice/SNTSyncState.h"
+
+const NSUInteger kUploadBenchmarkMaxRate = 10000;
+const NSUInteger kUploadBenchmarkMaxDuration = 3600;
+
+// A single request attempt, implemented by SNTSyncStage.
+@interface SNTSyncStage (RequestAttempt)
+- (NSData*)performRequest:(NSURLRequest*)request
+                  timeout:(NSTimeInterval)timeout
+                 response:(out NSHTTPURLResponse**)response
+                    error:(out NSError**)error;
+@end
+
+// An event upload stage that records the size and latency of every request attempt.
+@interface SNTBenchmarkEventUpload : SNTSyncEventUpload
+@property uint64_t requests;
+@property uint64_t requestsFailed;
+@property uint64_t bytesSent;
+@property uint64_t bytesReceived;
+@property NSMutableArray<NSNumber*>* latenciesMs;
+@end
+
+@implementation SNTBenchmarkEventUpload
+
+- (instancetype)initWithState:(SNTSyncState*)state {
+  self = [super initWithState:state];
+  if (self) {
+    _latenciesMs = [NSMutableArray array];
+  }
+  return self;
+}
+
+- (NSData*)performRequest:(NSURLRequest*)request
+                  timeout:(NSTimeInterval)timeout
+                 response:(out NSHTTPURLResponse**)response
+                    error:(out NSError**)error {
+  NSHTTPURLResponse* resp;
+  NSDate* start = [NSDate date];
+  NSData* data = [super performRequest:request timeout:timeout response:&resp error:error];
+  double latencyMs = -[start timeIntervalSinceNow] * 1000;
+
+  @synchronized(self) {
+    self.requests++;
+    self.bytesSent += request.HTTPBody.length;
+    self.bytesReceived += data.length;
+    if (resp.statusCode == 200) {
+      [self.latenciesMs addObject:@(latencyMs)];
+    } else {
+      self.requestsFailed++;
+    }
+  }
+
+  if (response) *response = resp;
+  return data;
+}
+
+@end
+
+static NSString* RandomHex(size_t bytes) {
+  uint8_t buf[32];
+  bytes = MIN(bytes, sizeof(buf));
+  arc4random_buf(buf, bytes);
+  NSMutableString* hex = [NSMutableString stringWithCapacity:bytes * 2];
+  for (size_t i = 0; i < bytes; ++i) {
+    [hex appendFormat:@"%02x", buf[i]];
+  }
+  return hex;
+}
+
+// A blocked execution of an unknown binary, with the fields a typical event carries. Hashes are
+// random so that the uploads compress no better than real events would.
+static SNTStoredExecutionEvent* SyntheticEvent(NSUInteger n) {
+  SNTStoredExecutionEvent* event = [[SNTStoredExecutionEvent alloc] init];
+  event.occurrenceDate = [NSDate date];
+  event.fileSHA256 = RandomHex(32);
+  event.cdhash = RandomHex(20);
+  event.filePath = [NSString stringWithFormat:@"/private/tmp/santa-bench/bench-%lu", n];
+  event.teamID = @"BENCHMARK0";
+  event.signingID = @"BENCHMARK0:com.northpolesec.santa.bench";
+  event.signingStatus = SNTSigningStatusProduction;
+  event.decision = SNTEventStateBlockUnknown;
+  event.executingUser = @"santa-bench";
+  event.loggedInUsers = @[ @"santa-bench" ];
+  event.currentSessions = @[ @"santa-bench@console" ];
+  event.pid = @(1000 + n % 60000);
+  event.ppid = @1;
+  event.parentName = @"launchd";
+  return event;
+}
+
+// The nearest-rank percentile of sorted, which must not be empty.
+static NSNumber* Percentile(NSArray<NSNumber*>* sorted, double percentile) {
+  NSUInteger rank = (NSUInteger)ceil(percentile / 100 * sorted.count);
+  return sorted[MAX(rank, 1) - 1];
+}
+
+@interface SNTSyncUploadBenchmark ()
+@property SNTSyncState* syncState;
+// The length of a benchmark second. Overridable so tests don't have to wait.
+@property NSTimeInterval tickInterval;
+@end
+
+@implementation SNTSyncUploadBenchmark
+
+- (instancetype)initWithSyncState:(SNTSyncState*)syncState {
+  self = [super init];
+  if (self) {
+    _syncState = syncState;
+    _tickInterval = 1;
+  }
+  return self;
+}
+
+- (NSDictionary<NSString*, id>*)runWithRate:(NSUInteger)rate duration:(NSUInteger)duration {
+  if (rate == 0 || rate > kUploadBenchmarkMaxRate) {
+    return @{
+      kUploadBenchmarkError :
+          [NSString stringWithFormat:@"Rate must be between 1 and %lu events per second",
+                                     kUploadBenchmarkMaxRate],
+    };
+  }
+  if (duration == 0 || duration > kUploadBenchmarkMaxDuration) {
+    return @{
+      kUploadBenchmarkError :
+          [NSString stringWithFormat:@"Duration must be between 1 and %lu seconds",
+                                     kUploadBenchmarkMaxDuration],
+    };
+  }
+  if ([[SNTConfigurator configurator] disableEventUpload]) {
+    return @{kUploadBenchmarkError : @"Event upload is disabled by DisableEventUpload"};
+  }
+
+  // The synthetic events aren't in the events database, so santad has nothing to remove once
+  // they are uploaded.
+  self.syncState.daemonConn = nil;
+  self.syncState.syncType = SNTSyncTypeNormal;
+  NSUInteger batchSize = self.syncState.eventBatchSize ?: kDefaultEventBatchSize;
+  SNTBenchmarkEventUpload* upload =
+      [[SNTBenchmarkEventUpload alloc] initWithState:self.syncState];
+
+  SLOGI(@"Benchmark: uploading %lu events per second for %lu seconds in batches of %lu", rate,
+        duration, batchSize);
+  NSDate* start = [NSDate date];
+  NSUInteger generated = 0;
+  NSUInteger accepted = 0;
+  for (NSUInteger second = 0; second < duration; ++second) {
+    NSTimeInterval wait =
+        [[start dateByAddingTimeInterval:second * self.tickInterval] timeIntervalSinceNow];
+    if (wait > 0) [NSThread sleepForTimeInterval:wait];
+
+    for (NSUInteger sent = 0; sent < rate; sent += batchSize) {
+      NSUInteger count = MIN(batchSize, rate - sent);
+      NSMutableArray<SNTStoredEvent*>* events = [NSMutableArray arrayWithCapacity:count];
+      for (NSUInteger i = 0; i < count; ++i) {
+        [events addObject:SyntheticEvent(generated++)];
+      }
+      // No more than a batch is uploaded at a time, so a success means every event was accepted.
+      if ([upload uploadEvents:events]) accepted += count;
+    }
+  }
+  NSTimeInterval elapsed = -[start timeIntervalSinceNow];
+
+  NSMutableDictionary<NSString*, id>* report = [NSMutableDictionary dictionary];
+  @synchronized(upload) {
+    report[kUploadBenchmarkEventsGenerated] = @(generated);
+    report[kUploadBenchmarkEventsAccepted] = @(accepted);
+    report[kUploadBenchmarkRequests] = @(upload.requests);
+    report[kUploadBenchmarkRequestsFailed] = @(upload.requestsFailed);
+    report[kUploadBenchmarkBytesSent] = @(upload.bytesSent);
+    report[kUploadBenchmarkBytesReceived] = @(upload.bytesReceived);
+    report[kUploadBenchmarkElapsedSeconds] = @(elapsed);
+
+    NSArray<NSNumber*>* sorted = [upload.latenciesMs sortedArrayUsingSelector:@selector(compare:)];
+    if (sorted.count) {
+      report[kUploadBenchmarkLatencyMedianMs] = Percentile(sorted, 50);
+      report[kUploadBenchmarkLatencyP95Ms] = Percentile(sorted, 95);
+      report[kUploadBenchmarkLatencyMaxMs] = sorted.lastObject;
+    }
+  }
+
+  SLOGI(@"Benchmark: %lu of %lu events accepted, %@ bytes sent in %@ requests", accepted,
+        generated, report[kUploadBenchmarkBytesSent], report[kUploadBenchmarkRequests]);
+  return report;
+}
+
+@end
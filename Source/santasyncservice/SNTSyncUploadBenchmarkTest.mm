@@ -0,0 +1,195 @@
+/// Copyright 2026 North Pole Security, Inc.
+///
+/// Licensed under the Apache License, Version 2.0 (the "License");
+/// you may not use this file except in compliance with the License.
+/// You may obtain a copy of the License at
+///
+///     http://www.apache.org/licenses/LICENSE-2.0
+///
+/// Unless required by applicable law or agreed to in writing, software
+/// distributed under the License is distributed on an "AS IS" BASIS,
+/// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+/// See the License for the specific language governing permissions and
+/// limitations under the License.
+
+#import <Foundation/Foundation.h>
+#import <OCMock/OCMock.h>
+#import <XCTest/XCTest.h>
+
+#import "Source/common/MOLXPCConnection.h"
+#import "Source/common/SNTConfigurator.h"
+#import "Source/common/SNTSyncConstants.h"
+#import "Source/common/SNTXPCControlInterface.h"
+#import "Source/santasyncservice/SNTSyncState.h"
+#import "Source/santasyncservice/SNTSyncUploadBenchmark.h"
+
+@interface SNTSyncUploadBenchmark (Testing)
+@property NSTimeInterval tickInterval;
+@end
+
+@interface SNTSyncUploadBenchmarkTest : XCTestCase
+@property SNTSyncState* syncState;
+@property id<SNTDaemonControlXPC> daemonConnRop;
+@property id configMock;
+@property NSMutableArray<NSURLRequest*>* requests;
+@end
+
+@implementation SNTSyncUploadBenchmarkTest
+
+- (void)setUp {
+  [super setUp];
+
+  self.configMock = OCMClassMock([SNTConfigurator class]);
+  OCMStub([self.configMock configurator]).andReturn(self.configMock);
+  OCMStub([self.configMock syncEnableProtoTransfer]).andReturn(NO);
+
+  self.syncState = [[SNTSyncState alloc] init];
+  self.syncState.daemonConn = OCMClassMock([MOLXPCConnection class]);
+  self.daemonConnRop = OCMProtocolMock(@protocol(SNTDaemonControlXPC));
+  OCMStub([self.syncState.daemonConn remoteObjectProxy]).andReturn(self.daemonConnRop);
+  self.syncState.session = OCMClassMock([NSURLSession class]);
+  self.syncState.syncBaseURL = [NSURL URLWithString:@"https://bench.local/"];
+  self.syncState.machineID = @"50C7E1EB-2EF5-42D4-A084-A7966FC45A95";
+  self.syncState.eventBatchSize = 5;
+
+  self.requests = [NSMutableArray array];
+}
+
+- (void)tearDown {
+  [self.configMock stopMocking];
+  [super tearDown];
+}
+
+#pragma mark Test Helpers
+
+/// Stub the mock sync server to record every request and respond with code. Accepted requests get
+/// an empty JSON object as the body.
+- (void)stubServerWithStatusCode:(NSInteger)code {
+  NSHTTPURLResponse* resp = [[NSHTTPURLResponse alloc] initWithURL:self.syncState.syncBaseURL
+                                                        statusCode:code
+                                                       HTTPVersion:@"1.1"
+                                                      headerFields:nil];
+  NSData* body = code == 200 ? [@"{}" dataUsingEncoding:NSUTF8StringEncoding] : [NSData data];
+  NSMutableArray<NSURLRequest*>* requests = self.requests;
+  OCMStub([self.syncState.session dataTaskWithRequest:[OCMArg any] completionHandler:[OCMArg any]])
+      .andDo(^(NSInvocation* invocation) {
+        __unsafe_unretained NSURLRequest* request;
+        __unsafe_unretained void (^handler)(NSData*, NSURLResponse*, NSError*);
+        [invocation getArgument:&request atIndex:2];
+        [invocation getArgument:&handler atIndex:3];
+        @synchronized(requests) {
+          [requests addObject:request];
+        }
+        handler(body, resp, nil);
+      });
+}
+
+- (SNTSyncUploadBenchmark*)benchmark {
+  SNTSyncUploadBenchmark* benchmark =
+      [[SNTSyncUploadBenchmark alloc] initWithSyncState:self.syncState];
+  benchmark.tickInterval = 0;
+  return benchmark;
+}
+
+- (NSUInteger)bytesInRequests {
+  NSUInteger bytes = 0;
+  for (NSURLRequest* request in self.requests) {
+    bytes += request.HTTPBody.length;
+  }
+  return bytes;
+}
+
+- (NSArray<NSNumber*>*)eventsPerRequest {
+  NSMutableArray<NSNumber*>* counts = [NSMutableArray array];
+  for (NSURLRequest* request in self.requests) {
+    NSDictionary* body = [NSJSONSerialization JSONObjectWithData:request.HTTPBody
+                                                         options:0
+                                                           error:NULL];
+    XCTAssertEqualObjects(body[@"machine_id"], self.syncState.machineID);
+    [counts addObject:@([body[@"events"] count])];
+  }
+  return counts;
+}
+
+#pragma mark Tests
+
+- (void)testAccountsBytesAndEventsAgainstMockServer {
+  [self stubServerWithStatusCode:200];
+  OCMReject([self.daemonConnRop databaseRemoveEventsWithIDs:[OCMArg any]]);
+
+  NSDictionary* report = [[self benchmark] runWithRate:7 duration:3];
+
+  XCTAssertNil(report[kUploadBenchmarkError]);
+  // Each second's 7 events are uploaded as a batch of 5 and a batch of 2.
+  XCTAssertEqualObjects([self eventsPerRequest], (@[ @5, @2, @5, @2, @5, @2 ]));
+  for (NSURLRequest* request in self.requests) {
+    XCTAssertEqualObjects(request.URL.path,
+                          @"/eventupload/50C7E1EB-2EF5-42D4-A084-A7966FC45A95");
+  }
+
+  XCTAssertEqualObjects(report[kUploadBenchmarkEventsGenerated], @21);
+  XCTAssertEqualObjects(report[kUploadBenchmarkEventsAccepted], @21);
+  XCTAssertEqualObjects(report[kUploadBenchmarkRequests], @6);
+  XCTAssertEqualObjects(report[kUploadBenchmarkRequestsFailed], @0);
+  XCTAssertEqualObjects(report[kUploadBenchmarkBytesSent], @([self bytesInRequests]));
+  XCTAssertEqualObjects(report[kUploadBenchmarkBytesReceived], @12);
+  XCTAssertNotNil(report[kUploadBenchmarkLatencyMedianMs]);
+  XCTAssertNotNil(report[kUploadBenchmarkLatencyP95Ms]);
+  XCTAssertNotNil(report[kUploadBenchmarkLatencyMaxMs]);
+  XCTAssertGreaterThanOrEqual([report[kUploadBenchmarkLatencyMaxMs] doubleValue],
+                              [report[kUploadBenchmarkLatencyMedianMs] doubleValue]);
+}
+
+- (void)testRejectedRequestsAreCountedButNotAccepted {
+  [self stubServerWithStatusCode:400];
+
+  NSDictionary* report = [[self benchmark] runWithRate:3 duration:2];
+
+  // A 400 isn't retried, so there is one request per batch.
+  XCTAssertEqualObjects([self eventsPerRequest], (@[ @3, @3 ]));
+  XCTAssertEqualObjects(report[kUploadBenchmarkEventsGenerated], @6);
+  XCTAssertEqualObjects(report[kUploadBenchmarkEventsAccepted], @0);
+  XCTAssertEqualObjects(report[kUploadBenchmarkRequests], @2);
+  XCTAssertEqualObjects(report[kUploadBenchmarkRequestsFailed], @2);
+  XCTAssertEqualObjects(report[kUploadBenchmarkBytesSent], @([self bytesInRequests]));
+  XCTAssertEqualObjects(report[kUploadBenchmarkBytesReceived], @0);
+  XCTAssertNil(report[kUploadBenchmarkLatencyMedianMs]);
+}
+
+- (void)testEventsArePacedAtTheRate {
+  [self stubServerWithStatusCode:200];
+  SNTSyncUploadBenchmark* benchmark = [self benchmark];
+  benchmark.tickInterval = 0.05;
+
+  NSDictionary* report = [benchmark runWithRate:1 duration:3];
+
+  // The third second's events are generated two ticks after the first.
+  XCTAssertGreaterThanOrEqual([report[kUploadBenchmarkElapsedSeconds] doubleValue], 0.1);
+  XCTAssertEqualObjects(report[kUploadBenchmarkEventsAccepted], @3);
+}
+
+- (void)testRejectsOutOfRangeArguments {
+  [self stubServerWithStatusCode:200];
+
+  NSUInteger tooFast = kUploadBenchmarkMaxRate + 1;
+  NSUInteger tooLong = kUploadBenchmarkMaxDuration + 1;
+  XCTAssertNotNil([[self benchmark] runWithRate:0 duration:1][kUploadBenchmarkError]);
+  XCTAssertNotNil([[self benchmark] runWithRate:tooFast duration:1][kUploadBenchmarkError]);
+  XCTAssertNotNil([[self benchmark] runWithRate:1 duration:0][kUploadBenchmarkError]);
+  XCTAssertNotNil([[self benchmark] runWithRate:1 duration:tooLong][kUploadBenchmarkError]);
+  XCTAssertEqual(self.requests.count, 0);
+}
+
+- (void)testFailsWhenEventUploadIsDisabled {
+  [self stubServerWithStatusCode:200];
+  OCMStub([self.configMock disableEventUpload]).andReturn(YES);
+
+  NSDictionary* report = [[self benchmark] runWithRate:1 duration:1];
+
+  XCTAssertEqualObjects(report.allKeys, @[ kUploadBenchmarkError ]);
+  XCTAssertEqualObjects(report[kUploadBenchmarkError],
+                        @"Event upload is disabled by DisableEventUpload");
+  XCTAssertEqual(self.requests.count, 0);
+}
+
+@end
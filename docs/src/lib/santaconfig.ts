@@ -99,6 +99,88 @@ export const SantaConfigKeyGroups: SantaConfigGroups = {
         },
       ],
     },
+    {
+      key: "MaintenanceWindows",
+      // TODO: Remove once the config generator can support MaintenanceWindows.
+      enableIf: (data) => false,
+      description: `Recurring windows, in the machine's local time, during which a ClientMode
+        received from the sync server that would move the client out of Monitor mode is deferred.
+        The change is applied when the window closes so that Lockdown is not enabled in the middle
+        of ongoing work. Switching to Monitor, or between Lockdown and Standalone, is never deferred,
+        and a newer mode from the sync server replaces one that is still deferred.`,
+      type: "dict",
+      repeated: true,
+      versionAdded: "2026.6",
+      subFields: [
+        {
+          key: "start",
+          type: "string",
+          description: `The time the window opens, as \`HH:MM\` in 24-hour format`,
+        },
+        {
+          key: "end",
+          type: "string",
+          description: `The time the window closes, as \`HH:MM\`. If earlier than \`start\` the window
+            closes on the following day.`,
+        },
+        {
+          key: "weekdays",
+          type: "integer",
+          repeated: true,
+          description: `The days the window opens on, numbered as in launchd's
+            \`StartCalendarInterval\` (0 and 7 are Sunday). Defaults to every day.`,
+        },
+      ],
+    },
+    {
+      key: "LockdownGracePeriodSec",
+      description: `The number of seconds after the ClientMode changes from Monitor to Lockdown
+        during which executions that Lockdown would block are allowed and logged instead, so that
+        work already in progress is not abruptly broken. Binaries blocked by a rule are still
+        blocked. Set to 0 to enforce Lockdown immediately. Maximum 3600.`,
+      type: "integer",
+      defaultValue: 0,
+      versionAdded: "2026.6",
+    },
+    {
+      key: "OfflineGraceToken",
+      description: `A signed token that keeps a host that cannot sync in the ClientMode the token
+        names until the token expires, overriding the mode from the sync server. Once it has
+        expired, or if it is invalid or issued for another machine, OfflineGraceFallbackClientMode
+        is applied instead. The token only takes effect once no full sync has succeeded for
+        OfflineGraceSyncAgeSec. Deliver a new token by MDM before the current one expires.`,
+      type: "string",
+      versionAdded: "2026.6",
+    },
+    {
+      key: "OfflineGraceTokenPublicKey",
+      description: `The base64-encoded Ed25519 public key used to verify the OfflineGraceToken. If
+        not set, the token is treated as invalid.`,
+      type: "string",
+      versionAdded: "2026.6",
+    },
+    {
+      key: "OfflineGraceFallbackClientMode",
+      description: `The ClientMode applied when the OfflineGraceToken has expired or is invalid.
+        Has no effect unless OfflineGraceToken is set.`,
+      type: "integer",
+      defaultValue: 2,
+      versionAdded: "2026.6",
+      possibleValues: [
+        { value: 1, label: "Monitor" },
+        { value: 2, label: "Lockdown" },
+        { value: 3, label: "Standalone" },
+      ],
+    },
+    {
+      key: "OfflineGraceSyncAgeSec",
+      description: `How long, in seconds, since the last successful full sync before the
+        OfflineGraceToken takes effect. Until then the mode from the sync server applies, and a
+        successful sync returns the host to it.`,
+      type: "integer",
+      defaultValue: 604800,
+      versionAdded: "2026.6",
+    },
     {
       key: "FailClosed",
       description: `If true and the ClientMode is in \`LOCKDOWN\`: execution will be denied when there is an error reading
@@ -152,6 +234,311 @@ export const SantaConfigKeyGroups: SantaConfigGroups = {
       syncConfigurable: false,
       versionAdded: "2026.4",
     },
+    {
+      key: "ClockTamperingAction",
+      description: `The action to take when the system clock moves backward by more than
+        \`ClockTamperingThresholdSec\` relative to the monotonic clock. A warning is always logged.`,
+      type: "string",
+      possibleValues: [
+        { value: "Sync", description: "Request an immediate sync with the sync server" },
+        { value: "Lockdown", description: "Switch the client mode to Lockdown" },
+      ],
+    },
+    {
+      key: "ClockTamperingThresholdSec",
+      description: `The number of seconds the system clock may move backward before it is considered tampering`,
+      type: "integer",
+      defaultValue: 300,
+    },
+    {
+      key: "NetworkVolumeExecutionAction",
+      description: `The action to take when a binary being executed lives on a network volume (e.g. NFS or SMB).
+        By default no additional policy is applied.`,
+      type: "string",
+      possibleValues: [
+        {
+          value: "BlockUnknown",
+          description: "Block binaries that are not allowed by a rule, regardless of the client mode",
+        },
+        {
+          value: "Block",
+          description: "Block all binaries, even those allowed by a rule",
+        },
+      ],
+      versionAdded: "2026.6",
+    },
+    {
+      key: "DiskImageExecutionAction",
+      description: `The action to take when a binary being executed lives on a mounted disk image (e.g. a DMG).
+        Running binaries directly from a downloaded disk image is a common malware pattern.
+        By default no additional policy is applied.`,
+      type: "string",
+      possibleValues: [
+        {
+          value: "Warn",
+          description: "Allow the execution but log a warning",
+        },
+        {
+          value: "BlockUnknown",
+          description: "Block binaries that are not allowed by a rule, regardless of the client mode",
+        },
+        {
+          value: "Block",
+          description: "Block all binaries, even those allowed by a rule",
+        },
+      ],
+      versionAdded: "2026.6",
+    },
+    {
+      key: "DiskImageExecutionQuarantinedOnly",
+      description: `If true, \`DiskImageExecutionAction\` only applies to disk images that were quarantined
+        when mounted, e.g. because they were downloaded.`,
+      type: "bool",
+      defaultValue: false,
+      versionAdded: "2026.6",
+    },
+    {
+      key: "PathChurnExecutionAction",
+      description: `The action to take when the binary at a path has changed at least
+        \`PathChurnThreshold\` times within \`PathChurnWindowSeconds\`. Malware may rewrite itself
+        at a stable path so that no SHA-256 rule matches. While set, the number of changes is
+        recorded in the execution logs. By default no additional policy is applied.`,
+      type: "string",
+      possibleValues: [
+        {
+          value: "Warn",
+          description: "Allow the execution but log a warning",
+        },
+        {
+          value: "BlockUnknown",
+          description: "Block binaries that are not allowed by a rule, regardless of the client mode",
+        },
+        {
+          value: "Block",
+          description: "Block all binaries, even those allowed by a rule",
+        },
+      ],
+      versionAdded: "2026.6",
+    },
+    {
+      key: "PathChurnThreshold",
+      description: `How many times the binary at a path must change within
+        \`PathChurnWindowSeconds\` before \`PathChurnExecutionAction\` applies. Clamped to
+        between 2 and 1000.`,
+      type: "integer",
+      defaultValue: 5,
+      versionAdded: "2026.6",
+    },
+    {
+      key: "PathChurnWindowSeconds",
+      description: `The window, in seconds, over which changes to the binary at a path are counted
+        for \`PathChurnExecutionAction\`. Clamped to between 60 and 86400.`,
+      type: "integer",
+      defaultValue: 3600,
+      versionAdded: "2026.6",
+    },
+    {
+      key: "SigningStatusExecutionActions",
+      description: `A map of signing status (\`Invalid\`, \`Unsigned\` or \`Adhoc\`) to the action to take
+        when a binary with that status is executed. \`BlockUnknown\` blocks binaries that are not allowed by a
+        rule, regardless of the client mode, and \`Block\` blocks all binaries, even those allowed by a rule.
+        An \`Invalid\` signature is present but fails validation, e.g. because it was truncated.
+        By default no additional policy is applied.`,
+      type: "dict",
+      versionAdded: "2026.6",
+    },
+    {
+      key: "CodeSignatureInvalidationResponse",
+      description: `The response to take when the code signature of a running process becomes invalid, which
+        can indicate that code was injected into a binary that was allowed to execute. Platform binaries and
+        Santa's own processes are only logged. By default invalidations are not acted on.`,
+      type: "string",
+      possibleValues: [
+        {
+          value: "Log",
+          description: "Log a warning naming the process",
+        },
+        {
+          value: "Block",
+          description: "Log and suspend the process so it can't run any further",
+        },
+        {
+          value: "Terminate",
+          description: "Log and kill the process",
+        },
+      ],
+      versionAdded: "2026.6",
+    },
+    {
+      key: "LaunchItemPolicy",
+      description: `The policy to apply when a login item, launch agent or launch daemon is registered. The
+        item's executable is checked against TeamID and SigningID rules and platform binaries are always
+        allowed. Items that aren't allowed are uploaded to the sync server. By default launch items are
+        not evaluated.`,
+      type: "string",
+      possibleValues: [
+        {
+          value: "Monitor",
+          description: "Log a warning for items that aren't allowed by a rule",
+        },
+        {
+          value: "Block",
+          description: "As Monitor, and unload and disable launch agents and daemons that aren't allowed by a rule",
+        },
+      ],
+      versionAdded: "2026.6",
+    },
+    {
+      key: "RequireLibraryValidationForTeamIDRules",
+      description: `If true, binaries allowed by a TeamID rule are blocked unless their code signature
+        requires library validation, which stops libraries signed by other teams from being loaded into them.
+        Binaries allowed by other rule types are unaffected.`,
+      type: "bool",
+      defaultValue: false,
+      versionAdded: "2026.6",
+    },
+    {
+      key: "RulePrecedence",
+      description: `The order in which rule types are checked when more than one rule matches an execution,
+        from highest to lowest precedence. Must list each of \`CDHASH\`, \`BINARY\`, \`SIGNINGID\`,
+        \`CERTIFICATE\` and \`TEAMID\` exactly once, otherwise it is ignored. For example, placing
+        \`TEAMID\` before \`BINARY\` lets a Team ID block override a Binary allow. Requirement rules are
+        always checked after the other rule types. By default the order is \`CDHASH\`, \`BINARY\`,
+        \`SIGNINGID\`, \`CERTIFICATE\`, \`TEAMID\`.`,
+      type: "string",
+      repeated: true,
+      versionAdded: "2026.6",
+    },
+    {
+      key: "EnableDeveloperToolsAllowlist",
+      description: `If true, binaries signed by one of the developer toolchains in
+        DeveloperToolsAllowlist are allowed when no rule matches them, which eases onboarding
+        developer machines in Lockdown mode. Rules, including block rules, still take precedence.`,
+      type: "bool",
+      defaultValue: false,
+      versionAdded: "2026.6",
+    },
+    {
+      key: "DeveloperToolsAllowlist",
+      description: `The developer toolchains allowed when EnableDeveloperToolsAllowlist is true.
+        Each entry is a Team ID (e.g. \`2ZEFAR8TH3\`) or a Signing ID (e.g.
+        \`UBF8T346G9:com.microsoft.VSCode\`). If set, it replaces the built-in list of Docker,
+        JetBrains, HashiCorp, Python, GitHub and Visual Studio Code.`,
+      type: "string",
+      repeated: true,
+      versionAdded: "2026.6",
+    },
+    {
+      key: "PushServerCertificateExpiryWarningDays",
+      description: `The number of days before the push server's TLS certificate expires at which Santa starts
+        warning about it, so the certificate can be rotated before push notifications stop working. The
+        warning is logged, shown in \`santactl status\` and optionally reported to the sync server (see
+        \`UploadPushServerCertificateExpiryWarning\`). Set to 0 to disable the warning.`,
+      type: "integer",
+      defaultValue: 30,
+      versionAdded: "2026.6",
+    },
+    {
+      key: "PushServerPinnedCertificateSHA256",
+      description: `SHA-256 fingerprints of the push server certificates the host accepts, as hex strings that may be
+        colon-separated. If set, the push server's certificate chain must contain at least one of these
+        certificates, either the server's own certificate or an intermediate or root CA, in addition to passing
+        the usual validation. Connections to a server presenting any other chain are refused. Pin more than one
+        certificate, e.g. the current and the next intermediate, so certificates can be rotated without losing
+        push notifications. Malformed entries are ignored; if none of the entries are valid, every connection is
+        refused.`,
+      type: "string",
+      repeated: true,
+      versionAdded: "2026.6",
+    },
+    {
+      key: "UploadPushServerCertificateExpiryWarning",
+      description: `If true, a push server certificate that is about to expire is reported to the sync server.
+        The certificate's expiry date is sent in the \`X-Santa-Push-Server-Certificate-Expiry\` header of
+        the postflight request.`,
+      type: "bool",
+      defaultValue: false,
+      versionAdded: "2026.6",
+    },
+    {
+      key: "ExportPushConnectionMetrics",
+      description: `If true, the push client's connection stats (bytes in and out, messages received by
+        subject, subscribes, reconnects and the last round trip time to the push server) are recorded as
+        \`/santa/sync/push/*\` metrics and published to the sync server's metrics endpoint with the sync
+        service's other metrics. The stats are always shown by \`santactl push stats\`. Changes to this
+        key take effect when the sync service restarts.`,
+      type: "bool",
+      defaultValue: false,
+      versionAdded: "2026.6",
+    },
+    {
+      key: "PushInlineRulesPublicKey",
+      description: `The base64-encoded Ed25519 public key used to verify rules carried inline in a push
+        notification with the \`apply_rules\` type. The payload must be a signed rule bundle, as written by
+        \`santactl rule --export --sign --key\`, of at most 16 KiB holding at most 50 rules, with
+        \`purpose\`, \`jti\`, \`iat\` and \`exp\` claims. Verified rules are added immediately without a
+        sync, and each bundle is only applied once. If unset, inline rules are rejected.`,
+      type: "string",
+      versionAdded: "2026.6",
+    },
+    {
+      key: "PushCredentialsRejectedFullSyncInterval",
+      description: `The full sync interval, in seconds, used while the push server rejects the host's push
+        credentials, e.g. because they were revoked or have expired. Instead of retrying with the rejected
+        credentials the push client stops connecting, a preflight is run straight away to fetch new credentials
+        and the host syncs at this interval until it gets them. Transient network errors are still retried as
+        usual. This only shortens the push notifications full sync interval, never lengthens it. The minimum
+        is 60.`,
+      type: "integer",
+      defaultValue: 600,
+      versionAdded: "2026.6",
+    },
+    {
+      key: "PushMessageDedupeWindow",
+      description: `The window, in seconds, within which a repeat of a push notification is ignored. A host subscribed
+        to several subjects can receive the same message on each of them; only the first one triggers a sync.
+        Messages are matched on their \`Nats-Msg-Id\` header, or on their headers and payload if they don't have
+        one. Set to 0 to disable deduplication.`,
+      type: "integer",
+      defaultValue: 5,
+      versionAdded: "2026.6",
+    },
+    {
+      key: "PushSyncRateLimitInterval",
+      description: `The time, in seconds, it takes to earn back one push-triggered full sync. Push notifications can
+        trigger up to \`PushSyncRateLimitBurst\` syncs back to back, then one per interval. Notifications beyond
+        that are coalesced into a single sync that runs as soon as the limit allows. Set to 0 to disable the
+        limit.`,
+      type: "integer",
+      defaultValue: 30,
+      versionAdded: "2026.6",
+    },
+    {
+      key: "PushSyncRateLimitBurst",
+      description: `The number of full syncs push notifications can trigger back to back before
+        \`PushSyncRateLimitInterval\` applies. Set to 0 to disable the limit.`,
+      type: "integer",
+      defaultValue: 2,
+      versionAdded: "2026.6",
+    },
+    {
+      key: "RefuseLockdownBelowMinimumOSVersion",
+      description: `The sync server can send a minimum OS version in the \`X-Santa-Minimum-OS-Version\` header of the
+        preflight response. A host below it always logs a warning. If this is also true, a Lockdown client
+        mode from the sync server is applied as Monitor instead, as older OS versions may lack Endpoint
+        Security features that Lockdown relies on.`,
+      type: "bool",
+      defaultValue: false,
+      versionAdded: "2026.6",
+    },
+    {
+      key: "AllowOnceTokenPublicKey",
+      description: `The base64-encoded Ed25519 public key used to verify one-time allow tokens
+        issued by the sync server. A valid token presented with \`santactl allow-once\` allows a
+        single execution of the identity it names. If not set, tokens are rejected.`,
+      type: "string",
+      versionAdded: "2026.6",
+    },
   ],
   gui: [
     {
@@ -443,6 +830,27 @@ changes in the release notes of any future release that changes them.`,
       enableIf: (data) =>
         data.EventLogType == "file" || data.EventLogType == "json",
     },
+    {
+      key: "EventLogBufferSizeKB",
+      description: `If \`EventLogType\` is set to \`file\` or \`json\`, EventLogBufferSizeKB defines how much log data is
+        buffered in memory before being written to \`EventLogPath\`. Set to 0 to write each event as it is logged`,
+      type: "integer",
+      defaultValue: 128,
+      enableIf: (data) =>
+        data.EventLogType == "file" || data.EventLogType == "json",
+      versionAdded: "2026.6",
+    },
+    {
+      key: "EventLogMaxFlushTimeSec",
+      description: `If \`EventLogType\` is set to \`file\` or \`json\`, EventLogMaxFlushTimeSec defines the maximum amount
+        of time log data will stay buffered in memory before being written to \`EventLogPath\`. Buffered data is also
+        written when the daemon shuts down`,
+      type: "integer",
+      defaultValue: 10,
+      enableIf: (data) =>
+        data.EventLogType == "file" || data.EventLogType == "json",
+      versionAdded: "2026.6",
+    },
     {
       key: "SpoolDirectory",
       description: `If \`EventLogType\` is set to \`protobuf\`, SpoolDirectory will provide the base directory used to
@@ -477,6 +885,24 @@ changes in the release notes of any future release that changes them.`,
       defaultValue: 15,
       enableIf: (data) => data.EventLogType == "protobuf",
     },
+    {
+      key: "EnableDecisionDatabase",
+      description: `If true, every execution decision is also recorded in a local SQLite database at
+        \`/var/db/santa/decisions.db\`, which can be queried with \`santactl query\`. This is independent of
+        \`EventLogType\` and of event upload settings.`,
+      type: "bool",
+      defaultValue: false,
+      versionAdded: "2026.6",
+    },
+    {
+      key: "DecisionDatabaseMaxDecisions",
+      description: `If \`EnableDecisionDatabase\` is true, the maximum number of decisions kept in the decision
+        database. Once reached, the oldest decisions are deleted as new ones are recorded.`,
+      type: "integer",
+      defaultValue: 100000,
+      enableIf: (data) => data.EnableDecisionDatabase,
+      versionAdded: "2026.6",
+    },
     {
       key: "EnableMachineIDDecoration",
       description: `If this key is true, the \`MachineID\` will be added to each log entry.`,
@@ -946,6 +1372,29 @@ mount flags that are a superset of \`RemovableMediaRemountFlags\` or \`Encrypted
       type: "string",
       syncConfigurable: true,
     },
+    {
+      key: "DecisionHookSocketPath",
+      description: `Path to a Unix domain socket that Santa consults before finalizing the decision for a binary
+        that no rule or scope matched. Santa writes the binary's identity as a single line of JSON and expects a
+        single line reply of \`{"decision": "allow"}\` or \`{"decision": "block"}\`. The socket and its
+        directory must be owned by root and not writable by group or others, and the listening process must
+        run as root. Otherwise the hook is treated as unavailable.`,
+      type: "string",
+    },
+    {
+      key: "DecisionHookTimeoutMilliseconds",
+      description: `The maximum time, in milliseconds, to wait for the decision hook to reply. Values are clamped
+        to the range 10-5000.`,
+      type: "integer",
+      defaultValue: 250,
+    },
+    {
+      key: "DecisionHookFailClosed",
+      description: `If true, binaries are blocked when the decision hook is unavailable, times out or returns a
+        malformed reply. If false, the decision for the current client mode is used.`,
+      type: "bool",
+      defaultValue: false,
+    },
     {
       key: "EnableBadSignatureProtection",
       description: `If true, binaries with a bad signing chain will be blocked even in \`MONITOR\` mode, **unless**
@@ -953,6 +1402,50 @@ mount flags that are a superset of \`RemovableMediaRemountFlags\` or \`Encrypted
       type: "bool",
       defaultValue: false,
     },
+    {
+      key: "CanonicalizeExecutablePaths",
+      description: `If true, executable paths are canonicalized before path-based matching: translocated apps are
+        resolved back to their original location and symlinks are followed to their target.
+        \`AllowedPathRegex\`, \`BlockedPathRegex\` and \`FileAccessPolicy\` \`BinaryPath\` match if either the
+        raw or the resolved path matches, and execution events record both paths.`,
+      type: "bool",
+      defaultValue: false,
+    },
+    {
+      key: "EnableScriptEvaluation",
+      description: `If true, scripts executed via an interpreter shebang (e.g. \`#!/bin/sh\`) are evaluated in
+        addition to their interpreter. A \`BINARY\` rule matching the script's SHA-256 blocks the script even
+        though the interpreter is allowed, or allows it where the interpreter would only be blocked as
+        unknown. A script with no rule inherits the interpreter's decision. Executions of scripts, of
+        common interpreters such as shells, \`python3\` and \`env\`, and of any other interpreter that has
+        run a script are never cached, which increases the number of executions Santa evaluates.`,
+      type: "bool",
+      defaultValue: false,
+    },
+    {
+      key: "EnableInterpreterScriptEvaluation",
+      description: `If true, scripts passed to known interpreters (currently \`osascript\`) are evaluated in
+        addition to the interpreter. Santa parses the interpreter's arguments to find the script it will run:
+        inline source (e.g. the statements given with \`osascript -e\`, joined by newlines) or a script file.
+        A \`BINARY\` rule matching the SHA-256 of the script is then applied the same way as for
+        \`EnableScriptEvaluation\`. Scripts read from standard input can't be evaluated and inherit the
+        interpreter's decision. Executions of known interpreters are never cached.`,
+      type: "bool",
+      defaultValue: false,
+      versionAdded: "2026.6",
+    },
+    {
+      key: "ResignProtectedBlocklist",
+      description: `A list of Signing IDs (e.g. \`EQHXZ8M8AV:com.google.Chrome\`) and Team IDs whose binaries are
+        blocked even after they have been re-signed. Binaries signed by a listed identity are blocked
+        and their SHA-256 and bundle identifier are remembered until santad restarts. Any binary with a
+        remembered SHA-256, a remembered bundle identifier, or a bundle identifier matching the
+        identifier part of a listed Signing ID is then blocked regardless of its code signature, so an
+        ad-hoc re-signed copy of a listed app stays blocked. This applies in all client modes and takes
+        precedence over rules.`,
+      type: "string",
+      repeated: true,
+    },
     {
       key: "EnablePageZeroProtection",
       description: `If true, 32-bit binaries that are missing the \`__PAGEZERO\` segment will be blocked even in
@@ -1043,6 +1536,212 @@ thousand static rules working correctly, but we don't recommend using StaticRule
       type: "bool",
       defaultValue: false,
     },
+    {
+      key: "SyncMaxEventAgeSec",
+      description: `If greater than zero, events older than this many seconds are dropped during event
+        upload instead of being sent to the sync server`,
+      type: "integer",
+      defaultValue: 0,
+    },
+    {
+      key: "SyncEventUploadConcurrency",
+      description: `The maximum number of event upload batches that may be in flight at once.
+        Batches are always acknowledged in order, so events are only removed from the local
+        database once every earlier batch has been accepted. Values are clamped to the range 1-8.`,
+      type: "integer",
+      defaultValue: 1,
+    },
+    {
+      key: "SyncRuleDownloadConcurrency",
+      description: `The maximum number of downloaded rule pages that may be processed concurrently.
+        Pages are chained by a cursor so they are still requested one at a time, but processing of
+        a page overlaps with fetching the next one. Rules are always applied in the order the
+        server sent them, so a removal in a later page still wins over an add in an earlier page.
+        Values are clamped to the range 1-8.`,
+      type: "integer",
+      defaultValue: 1,
+    },
+    {
+      key: "SyncCircuitBreakerFailureThreshold",
+      description: `The number of consecutive failed sync server requests (network errors, timeouts,
+        5xx, 408 and 429 responses) after which the sync circuit breaker opens. While open, no
+        requests are sent to the sync server. After \`SyncCircuitBreakerCooldownSec\` a single probe
+        request is allowed through: if it succeeds the breaker closes, otherwise it opens again. Set
+        to 0 to disable the circuit breaker.`,
+      type: "integer",
+      defaultValue: 10,
+    },
+    {
+      key: "SyncCircuitBreakerCooldownSec",
+      description: `The number of seconds the sync circuit breaker stays open before a probe request
+        is allowed through. The minimum value is 1.`,
+      type: "integer",
+      defaultValue: 300,
+    },
+    {
+      key: "RuleSources",
+      // TODO: Remove once the config generator can support RuleSources.
+      enableIf: (data) => false,
+      description: `Additional rule servers, each downloaded on its own schedule independently of
+        the primary sync server. Each source serves the same \`ruledownload\` endpoint as a sync
+        server but only its execution rules are used; file access, network flow and signal rules
+        are ignored. Every download replaces the rules previously received from that source.
+
+Rules from these sources take precedence over rules from the primary sync server, and StaticRules
+take precedence over both. When several sources have a matching rule, the source with the highest
+\`precedence\` wins, with ties broken by name. Within one source the normal
+[rule precedence](/features/binary-authorization) order applies.
+
+The status of each source is shown by \`santactl status\`.`,
+      type: "dict",
+      repeated: true,
+      subFields: [
+        {
+          key: "name",
+          type: "string",
+          description: `A unique name for this source`,
+        },
+        {
+          key: "url",
+          type: "string",
+          description: `The base URL of the rule server. HTTPS is required unless the host is localhost.`,
+        },
+        {
+          key: "sync_interval_seconds",
+          type: "integer",
+          description: `How often to download rules from this source. The minimum value is 60.`,
+        },
+        {
+          key: "precedence",
+          type: "integer",
+          description: `Sources with a higher precedence win over sources with a lower one. Defaults to 0.`,
+        },
+        {
+          key: "public_key",
+          type: "string",
+          description: `The base64 encoded Ed25519 public key this source signs its responses with.
+            When set, every response from this source must be a signed rule bundle (see
+            \`santactl rule --export --sign --key\`) verified by this key, and unsigned responses
+            or responses signed with any other key are rejected. Each source has its own key.`,
+        },
+      ],
+    },
+    {
+      key: "RuleApplyBatchSize",
+      description: `If greater than zero, incremental rule updates containing more than this many
+        execution rules are written in multiple smaller transactions so that execution decisions
+        are not stalled while a large sync is applied. If a batch fails, batches that were already
+        written are undone so no part of the update is kept, except for rules that have changed
+        again since they were written. Clean syncs are always applied in a single transaction.`,
+      type: "integer",
+      defaultValue: 0,
+    },
+    {
+      key: "EnableRuleSnapshot",
+      description: `If true, execution decisions look up rules in an in-memory copy of the
+        execution rules instead of the rule database. Each rule update builds a new copy and swaps
+        it in once the update has been written, so decisions are never stalled while a sync is
+        applied and never see a partially written update. This uses more memory with large rule
+        sets. Changes to this key take effect when santad restarts.`,
+      type: "bool",
+      defaultValue: false,
+      versionAdded: "2026.6",
+    },
+    {
+      key: "DuplicateRuleResolution",
+      description: `How execution rules for the same identifier and rule type are resolved when a
+        single rule update (e.g. one sync) contains more than one of them.`,
+      type: "string",
+      possibleValues: [
+        {
+          value: "LastWins",
+          description: "The rule that appears last in the update is applied",
+        },
+        {
+          value: "HighestPrecedence",
+          description:
+            "The most restrictive rule is applied (block, CEL/Seatbelt, allow, remove), ties go to the last rule",
+        },
+      ],
+      defaultValue: "LastWins",
+      versionAdded: "2026.6",
+    },
+    {
+      key: "CleanSyncWarmupSec",
+      description: `The number of seconds after a clean sync during which notifications and
+        immediate uploads for unknown binaries are throttled, so that a busy host doesn't
+        overwhelm the user or the sync server. During the warm-up each binary is notified about at
+        most once, and no more than CleanSyncWarmupNotificationLimit notifications and
+        CleanSyncWarmupUploadLimit immediate uploads are made. Throttled events are still stored
+        and uploaded with the next sync. Blocks caused by rules are never throttled. Set to 0 to
+        disable. Maximum 3600.`,
+      type: "integer",
+      defaultValue: 0,
+    },
+    {
+      key: "CleanSyncWarmupNotificationLimit",
+      description: `The maximum number of notifications about unknown binaries shown during the
+        clean sync warm-up. See CleanSyncWarmupSec.`,
+      type: "integer",
+      defaultValue: 3,
+    },
+    {
+      key: "CleanSyncWarmupUploadLimit",
+      description: `The maximum number of unknown binary events uploaded immediately during the
+        clean sync warm-up. See CleanSyncWarmupSec.`,
+      type: "integer",
+      defaultValue: 20,
+    },
+    {
+      key: "RuleApplicationDeferralLoadPercent",
+      description: `If greater than zero, rule updates from the sync server are deferred while the
+        1-minute load average, as a percentage of the number of CPUs, is above this value. For
+        example 200 defers updates while there are more than two runnable threads per CPU. Block
+        rules are always applied immediately. Deferred updates are applied in order once the load
+        drops, or after RuleApplicationMaxDeferralSec. The sync that delivered a deferred update is
+        reported as failed and retried later, as the update is only kept in memory until it is
+        applied. Set to 0 to disable.`,
+      type: "integer",
+      defaultValue: 0,
+      versionAdded: "2026.6",
+    },
+    {
+      key: "RuleApplicationMaxDeferralSec",
+      description: `The maximum number of seconds a rule update is deferred due to high load before
+        it is applied anyway. See RuleApplicationDeferralLoadPercent. Maximum 3600.`,
+      type: "integer",
+      defaultValue: 600,
+      versionAdded: "2026.6",
+    },
+    {
+      key: "SyncInFlightExecutionPolicy",
+      description: `How executions are decided while a rule update from the sync server is being
+        applied. Time an update spends deferred by RuleApplicationDeferralLoadPercent doesn't count.
+        "UseExisting" decides using the existing rules. "Defer" waits up to
+        SyncInFlightMaxDeferralMilliseconds for the update to be applied, then decides using the
+        existing rules. "FailSafe" blocks binaries that no existing rule allows, even in Monitor
+        mode. With "Defer" and "FailSafe", decisions made using the existing rules are not
+        cached.`,
+      type: "string",
+      defaultValue: "UseExisting",
+      versionAdded: "2026.6",
+    },
+    {
+      key: "SyncInFlightMaxDeferralMilliseconds",
+      description: `With SyncInFlightExecutionPolicy set to "Defer", the maximum number of
+        milliseconds an execution waits for a rule update to be applied. Clamped to between 10 and
+        5000.`,
+      type: "integer",
+      defaultValue: 1000,
+      versionAdded: "2026.6",
+    },
+    {
+      key: "DisableEventUpload",
+      description: `If true, no events are stored locally or uploaded to the sync server. Rules and
+        configuration continue to sync normally.`,
+      type: "bool",
+      defaultValue: false,
+    },
     {
       key: "ClientAuthCertificateFile",
       description: `If set, this contains the location of a PKCS#12 certificate to be used for sync authentication`,
@@ -1125,6 +1824,16 @@ thousand static rules working correctly, but we don't recommend using StaticRule
       type: "string",
       enableIf: (data) => data.MachineIDPlist !== "",
     },
+    {
+      key: "RegenerateMachineIDOnConflict",
+      description: `If true and the sync server reports that another host uses the same machine ID, e.g. because
+        both were cloned from the same image, a new machine ID is generated and used from the next sync.
+        The generated ID takes precedence over \`MachineIDPlist\` and the hardware UUID. Has no effect
+        if \`MachineID\` is set.`,
+      type: "bool",
+      defaultValue: false,
+      versionAdded: "2026.6",
+    },
     {
       key: "EnableAllEventUpload",
       description: `If true, the client will upload all execution events to the sync server, including those that